@@ -0,0 +1,96 @@
+// Package benchmarks provides tools for running and reporting benchmark results.
+package benchmarks
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// DefaultRegressionThreshold is the slowdown, relative to the baseline,
+// above which a benchmark is reported as a regression.
+const DefaultRegressionThreshold = 0.20
+
+// Regression is a benchmark that got slower than its baseline.
+type Regression struct {
+	// Name is the benchmark name, including sub-benchmarks
+	Name string
+	// Package is the package containing the benchmark
+	Package string
+	// BaselineNsPerOp is the baseline nanoseconds per operation
+	BaselineNsPerOp float64
+	// NsPerOp is the current nanoseconds per operation
+	NsPerOp float64
+	// Change is the slowdown relative to the baseline, e.g. 0.25 for 25% slower
+	Change float64
+}
+
+// String returns a one line description of the regression.
+func (r Regression) String() string {
+	return fmt.Sprintf("%s %s: %s -> %s (+%.1f%%)",
+		r.Package, r.Name,
+		formatDuration(r.BaselineNsPerOp),
+		formatDuration(r.NsPerOp),
+		r.Change*100)
+}
+
+// LoadResults reads a file of go test -bench output.
+func LoadResults(filename string) ([]BenchmarkResult, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open benchmark results: %w", err)
+	}
+	defer f.Close()
+
+	return ParseBenchmarkOutput(f)
+}
+
+// FindRegressions compares current results with baseline results and returns
+// the benchmarks that got slower by more than threshold, sorted by package
+// and name. Benchmarks are matched by package and name; those missing from
+// either side are ignored. A benchmark run several times (-count) counts
+// with its fastest run on both sides, which keeps noise from one slow run
+// out of the comparison.
+func FindRegressions(baseline, current []BenchmarkResult, threshold float64) []Regression {
+	base := fastestRuns(baseline)
+	curr := fastestRuns(current)
+
+	var regressions []Regression
+	for key, result := range curr {
+		old, ok := base[key]
+		if !ok || old.NsPerOp <= 0 {
+			continue
+		}
+		change := result.NsPerOp/old.NsPerOp - 1
+		if change > threshold {
+			regressions = append(regressions, Regression{
+				Name:            result.Name,
+				Package:         result.Package,
+				BaselineNsPerOp: old.NsPerOp,
+				NsPerOp:         result.NsPerOp,
+				Change:          change,
+			})
+		}
+	}
+
+	sort.Slice(regressions, func(i, j int) bool {
+		if regressions[i].Package != regressions[j].Package {
+			return regressions[i].Package < regressions[j].Package
+		}
+		return regressions[i].Name < regressions[j].Name
+	})
+	return regressions
+}
+
+// fastestRuns indexes results by package and name, keeping the fastest run
+// of each benchmark.
+func fastestRuns(results []BenchmarkResult) map[string]BenchmarkResult {
+	fastest := make(map[string]BenchmarkResult, len(results))
+	for _, result := range results {
+		key := result.Package + " " + result.Name
+		if prev, ok := fastest[key]; !ok || result.NsPerOp < prev.NsPerOp {
+			fastest[key] = result
+		}
+	}
+	return fastest
+}
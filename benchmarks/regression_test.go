@@ -0,0 +1,115 @@
+// Package benchmarks provides tools for running and reporting benchmark results.
+package benchmarks
+
+import (
+	"flag"
+	"path/filepath"
+	"testing"
+)
+
+// The regression gate compares a fresh benchmark run with the recorded
+// baseline (see make bench-check):
+// go test ./benchmarks -run TestRegressionGate -current bench.txt
+var (
+	currentResults    = flag.String("current", "", "go test -bench output to compare with the baseline")
+	baselineResults   = flag.String("baseline", filepath.Join("testdata", "baseline.txt"), "go test -bench output of the baseline")
+	regressionPercent = flag.Float64("threshold", DefaultRegressionThreshold*100, "slowdown in percent above which a benchmark fails")
+)
+
+func TestFindRegressions(t *testing.T) {
+	baseline := []BenchmarkResult{
+		{Name: "BenchmarkSearch/base", Package: "pkg/a", NsPerOp: 1000},
+		{Name: "BenchmarkSearch/base", Package: "pkg/a", NsPerOp: 900},
+		{Name: "BenchmarkBind", Package: "pkg/a", NsPerOp: 2000},
+		{Name: "BenchmarkBind", Package: "pkg/b", NsPerOp: 100},
+		{Name: "BenchmarkRemoved", Package: "pkg/a", NsPerOp: 100},
+	}
+	current := []BenchmarkResult{
+		{Name: "BenchmarkSearch/base", Package: "pkg/a", NsPerOp: 1200},
+		{Name: "BenchmarkSearch/base", Package: "pkg/a", NsPerOp: 1170},
+		{Name: "BenchmarkBind", Package: "pkg/a", NsPerOp: 2300},
+		{Name: "BenchmarkBind", Package: "pkg/b", NsPerOp: 50},
+		{Name: "BenchmarkAdded", Package: "pkg/a", NsPerOp: 100},
+	}
+
+	regressions := FindRegressions(baseline, current, 0.20)
+	if len(regressions) != 1 {
+		t.Fatalf("Expected 1 regression, got %v", regressions)
+	}
+
+	r := regressions[0]
+	if r.Name != "BenchmarkSearch/base" || r.Package != "pkg/a" {
+		t.Errorf("Expected regression of pkg/a BenchmarkSearch/base, got %s %s", r.Package, r.Name)
+	}
+	if r.BaselineNsPerOp != 900 || r.NsPerOp != 1170 {
+		t.Errorf("Expected fastest runs 900 -> 1170, got %v -> %v", r.BaselineNsPerOp, r.NsPerOp)
+	}
+	if r.Change < 0.299 || r.Change > 0.301 {
+		t.Errorf("Expected change 0.30, got %v", r.Change)
+	}
+
+	if got := FindRegressions(baseline, current, 0.40); len(got) != 0 {
+		t.Errorf("Expected no regression above 40%%, got %v", got)
+	}
+}
+
+func TestParseBenchmarkOutputSubBenchmarks(t *testing.T) {
+	results, err := LoadResults(filepath.Join("testdata", "subbench.txt"))
+	if err != nil {
+		t.Fatalf("LoadResults failed: %v", err)
+	}
+
+	want := []BenchmarkResult{
+		{Name: "BenchmarkSearchScope/entries=10000/base", Package: "github.com/KilimcininKorOglu/oba/internal/backend", Iterations: 787, NsPerOp: 1516854, BytesPerOp: 3799, AllocsPerOp: 80},
+		{Name: "BenchmarkDecodeRequest/ad_paged_search", Package: "github.com/KilimcininKorOglu/oba/internal/ldap", Iterations: 398520, NsPerOp: 3004, BytesPerOp: 1120, AllocsPerOp: 29},
+		{Name: "BenchmarkLDIFImport", Package: "github.com/KilimcininKorOglu/oba/internal/backup", Iterations: 2726, NsPerOp: 910227, BytesPerOp: 282865, AllocsPerOp: 1157},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("Expected %d results, got %d: %v", len(want), len(results), results)
+	}
+	for i := range want {
+		if results[i] != want[i] {
+			t.Errorf("Result %d: expected %+v, got %+v", i, want[i], results[i])
+		}
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	results, err := LoadResults(*baselineResults)
+	if err != nil {
+		t.Fatalf("LoadResults failed: %v", err)
+	}
+	if len(results) == 0 {
+		t.Fatal("Baseline holds no benchmark results")
+	}
+	for _, result := range results {
+		if result.Package == "" || result.NsPerOp <= 0 {
+			t.Errorf("Invalid baseline result: %+v", result)
+		}
+	}
+}
+
+// TestRegressionGate fails when a benchmark of the -current results is
+// slower than its baseline by more than -threshold percent. It is skipped
+// without -current.
+func TestRegressionGate(t *testing.T) {
+	if *currentResults == "" {
+		t.Skip("no -current benchmark results given")
+	}
+
+	baseline, err := LoadResults(*baselineResults)
+	if err != nil {
+		t.Fatalf("Failed to load baseline: %v", err)
+	}
+	current, err := LoadResults(*currentResults)
+	if err != nil {
+		t.Fatalf("Failed to load current results: %v", err)
+	}
+	if len(current) == 0 {
+		t.Fatalf("No benchmark results in %s", *currentResults)
+	}
+
+	for _, r := range FindRegressions(baseline, current, *regressionPercent/100) {
+		t.Errorf("Regression: %s", r)
+	}
+}
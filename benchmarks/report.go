@@ -97,8 +97,8 @@ func ParseBenchmarkOutput(r io.Reader) ([]BenchmarkResult, error) {
 	var results []BenchmarkResult
 
 	// Regex to match benchmark output lines
-	// Format: BenchmarkName-N    iterations    ns/op    B/op    allocs/op
-	benchRegex := regexp.MustCompile(`^(Benchmark\w+)(?:-\d+)?\s+(\d+)\s+([\d.]+)\s+ns/op(?:\s+(\d+)\s+B/op)?(?:\s+(\d+)\s+allocs/op)?`)
+	// Format: BenchmarkName/sub-N    iterations    value unit    value unit ...
+	benchRegex := regexp.MustCompile(`^(Benchmark\S+?)(?:-\d+)?\s+(\d+)\s+(.*)$`)
 
 	scanner := bufio.NewScanner(r)
 	currentPkg := ""
@@ -131,23 +131,30 @@ func ParseBenchmarkOutput(r io.Reader) ([]BenchmarkResult, error) {
 			result.Iterations = iterations
 		}
 
-		// Parse ns/op
-		if nsPerOp, err := strconv.ParseFloat(matches[3], 64); err == nil {
-			result.NsPerOp = nsPerOp
-		}
-
-		// Parse B/op (optional)
-		if len(matches) > 4 && matches[4] != "" {
-			if bytesPerOp, err := strconv.ParseInt(matches[4], 10, 64); err == nil {
-				result.BytesPerOp = bytesPerOp
+		// Parse the measurements; custom metrics such as MB/s may come
+		// between ns/op and B/op
+		fields := strings.Fields(matches[3])
+		hasNsPerOp := false
+		for i := 0; i+1 < len(fields); i += 2 {
+			value, unit := fields[i], fields[i+1]
+			switch unit {
+			case "ns/op":
+				if nsPerOp, err := strconv.ParseFloat(value, 64); err == nil {
+					result.NsPerOp = nsPerOp
+					hasNsPerOp = true
+				}
+			case "B/op":
+				if bytesPerOp, err := strconv.ParseInt(value, 10, 64); err == nil {
+					result.BytesPerOp = bytesPerOp
+				}
+			case "allocs/op":
+				if allocsPerOp, err := strconv.ParseInt(value, 10, 64); err == nil {
+					result.AllocsPerOp = allocsPerOp
+				}
 			}
 		}
-
-		// Parse allocs/op (optional)
-		if len(matches) > 5 && matches[5] != "" {
-			if allocsPerOp, err := strconv.ParseInt(matches[5], 10, 64); err == nil {
-				result.AllocsPerOp = allocsPerOp
-			}
+		if !hasNsPerOp {
+			continue
 		}
 
 		results = append(results, result)
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/ber"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+)
+
+// maxMessageSize is the largest response accepted from the server.
+const maxMessageSize = 16 * 1024 * 1024
+
+// ErrInvalidResponse is returned when the server sends a malformed response.
+var ErrInvalidResponse = errors.New("benchload: invalid response")
+
+// resultError is an LDAP result other than success.
+type resultError struct {
+	code    ldap.ResultCode
+	message string
+}
+
+func (e *resultError) Error() string {
+	if e.message == "" {
+		return e.code.String()
+	}
+	return fmt.Sprintf("%s: %s", e.code.String(), e.message)
+}
+
+// request is an LDAP operation that can be sent by the client.
+type request interface {
+	Encode() ([]byte, error)
+}
+
+// client is an LDAP connection sending one request at a time.
+type client struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	timeout   time.Duration
+	messageID int
+}
+
+// dial connects to an LDAP server, over TLS for useTLS.
+func dial(addr string, useTLS, insecure bool, timeout time.Duration) (*client, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+
+	var conn net.Conn
+	var err error
+	if useTLS {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: insecure}
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &client{
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		timeout: timeout,
+	}, nil
+}
+
+// Close sends an UnbindRequest and closes the connection.
+func (c *client) Close() error {
+	c.messageID++
+	msg := &ldap.LDAPMessage{
+		MessageID: c.messageID,
+		Operation: &ldap.RawOperation{Tag: ldap.ApplicationUnbindRequest},
+	}
+	if data, err := msg.Encode(); err == nil {
+		c.conn.Write(data)
+	}
+	return c.conn.Close()
+}
+
+// Bind performs a simple bind.
+func (c *client) Bind(dn, password string) error {
+	_, err := c.do(ldap.ApplicationBindRequest, &ldap.BindRequest{
+		Version:        3,
+		Name:           dn,
+		AuthMethod:     ldap.AuthMethodSimple,
+		SimplePassword: []byte(password),
+	})
+	return err
+}
+
+// Search runs a search and returns the number of entries returned.
+func (c *client) Search(req *ldap.SearchRequest) (int, error) {
+	return c.do(ldap.ApplicationSearchRequest, req)
+}
+
+// Compare runs a compare and reports whether the assertion is true.
+func (c *client) Compare(dn, attribute, value string) (bool, error) {
+	_, err := c.do(ldap.ApplicationCompareRequest, &ldap.CompareRequest{
+		DN:        dn,
+		Attribute: attribute,
+		Value:     []byte(value),
+	})
+	var resErr *resultError
+	if errors.As(err, &resErr) {
+		switch resErr.code {
+		case ldap.ResultCompareTrue:
+			return true, nil
+		case ldap.ResultCompareFalse:
+			return false, nil
+		}
+	}
+	return false, err
+}
+
+// Modify replaces the values of an attribute.
+func (c *client) Modify(dn, attribute string, values ...string) error {
+	_, err := c.do(ldap.ApplicationModifyRequest, &ldap.ModifyRequest{
+		Object: dn,
+		Changes: []ldap.Modification{{
+			Operation: ldap.ModifyOperationReplace,
+			Attribute: ldap.Attribute{Type: attribute, Values: byteValues(values)},
+		}},
+	})
+	return err
+}
+
+// Add adds an entry.
+func (c *client) Add(dn string, attributes []ldap.Attribute) error {
+	_, err := c.do(ldap.ApplicationAddRequest, &ldap.AddRequest{Entry: dn, Attributes: attributes})
+	return err
+}
+
+// do sends a request and reads its responses up to the final result. It
+// returns the number of search result entries received.
+func (c *client) do(tag int, req request) (int, error) {
+	data, err := req.Encode()
+	if err != nil {
+		return 0, err
+	}
+
+	c.messageID++
+	msg := &ldap.LDAPMessage{
+		MessageID: c.messageID,
+		Operation: &ldap.RawOperation{Tag: tag, Data: data},
+	}
+	encoded, err := msg.Encode()
+	if err != nil {
+		return 0, err
+	}
+
+	if c.timeout > 0 {
+		if err := c.conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := c.conn.Write(encoded); err != nil {
+		return 0, err
+	}
+
+	entries := 0
+	for {
+		resp, err := readMessage(c.reader)
+		if err != nil {
+			return entries, err
+		}
+		if resp.MessageID != c.messageID || resp.Operation == nil {
+			continue
+		}
+
+		switch resp.Operation.Tag {
+		case ldap.ApplicationSearchResultEntry:
+			entries++
+		case ldap.ApplicationSearchResultReference, ldap.ApplicationIntermediateResponse:
+		default:
+			code, message, err := parseResult(resp.Operation.Data)
+			if err != nil {
+				return entries, err
+			}
+			if code != ldap.ResultSuccess {
+				return entries, &resultError{code: code, message: message}
+			}
+			return entries, nil
+		}
+	}
+}
+
+// readMessage reads a single BER-encoded LDAP message.
+func readMessage(r *bufio.Reader) (*ldap.LDAPMessage, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if tag != byte(ber.ClassUniversal|ber.TypeConstructed|ber.TagSequence) {
+		return nil, ErrInvalidResponse
+	}
+
+	first, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	header := []byte{tag, first}
+	length := int(first)
+	if first&ber.LengthLongFormBit != 0 {
+		n := int(first &^ ber.LengthLongFormBit)
+		if n == 0 || n > 4 {
+			return nil, ErrInvalidResponse
+		}
+		length = 0
+		for i := 0; i < n; i++ {
+			b, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			header = append(header, b)
+			length = length<<8 | int(b)
+		}
+	}
+	if length > maxMessageSize {
+		return nil, ErrInvalidResponse
+	}
+
+	data := make([]byte, len(header)+length)
+	copy(data, header)
+	if _, err := io.ReadFull(r, data[len(header):]); err != nil {
+		return nil, err
+	}
+	return ldap.ParseLDAPMessage(data)
+}
+
+// parseResult parses the result code and diagnostic message of an
+// LDAPResult.
+func parseResult(data []byte) (ldap.ResultCode, string, error) {
+	decoder := ber.NewBERDecoder(data)
+
+	code, err := decoder.ReadEnumerated()
+	if err != nil {
+		return 0, "", err
+	}
+	if _, err := decoder.ReadOctetString(); err != nil {
+		return 0, "", err
+	}
+	message, err := decoder.ReadOctetString()
+	if err != nil {
+		return 0, "", err
+	}
+	return ldap.ResultCode(code), string(message), nil
+}
+
+// byteValues converts attribute values to bytes.
+func byteValues(values []string) [][]byte {
+	out := make([][]byte, len(values))
+	for i, v := range values {
+		out[i] = []byte(v)
+	}
+	return out
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/filter"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+)
+
+// Operations of the load mix.
+const (
+	opBind    = "bind"
+	opSearch  = "search"
+	opCompare = "compare"
+	opModify  = "modify"
+	opAdd     = "add"
+)
+
+// operations lists the operations of the load mix in report order.
+var operations = []string{opBind, opSearch, opCompare, opModify, opAdd}
+
+// ErrInvalidMix is returned for a malformed operation mix.
+var ErrInvalidMix = errors.New("benchload: invalid operation mix")
+
+// mixEntry is an operation of the load mix with its relative weight.
+type mixEntry struct {
+	op     string
+	weight int
+}
+
+// mix is the weighted set of operations run by the workers.
+type mix struct {
+	entries []mixEntry
+	total   int
+}
+
+// parseMix parses an operation mix of the form search=80,bind=10,modify=10.
+func parseMix(s string) (*mix, error) {
+	m := &mix{}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q is not op=weight", ErrInvalidMix, part)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !isOperation(name) {
+			return nil, fmt.Errorf("%w: unknown operation %q", ErrInvalidMix, name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: operation %q given twice", ErrInvalidMix, name)
+		}
+		seen[name] = true
+		weight, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("%w: invalid weight %q for %s", ErrInvalidMix, value, name)
+		}
+		if weight == 0 {
+			continue
+		}
+		m.entries = append(m.entries, mixEntry{op: name, weight: weight})
+		m.total += weight
+	}
+	if m.total == 0 {
+		return nil, fmt.Errorf("%w: no operation with a positive weight", ErrInvalidMix)
+	}
+	return m, nil
+}
+
+// isOperation reports whether name is an operation of the load mix.
+func isOperation(name string) bool {
+	for _, op := range operations {
+		if op == name {
+			return true
+		}
+	}
+	return false
+}
+
+// has reports whether the mix runs op.
+func (m *mix) has(op string) bool {
+	for _, e := range m.entries {
+		if e.op == op {
+			return true
+		}
+	}
+	return false
+}
+
+// pick returns a random operation according to the weights.
+func (m *mix) pick(r *rand.Rand) string {
+	n := r.Intn(m.total)
+	for _, e := range m.entries {
+		if n < e.weight {
+			return e.op
+		}
+		n -= e.weight
+	}
+	return m.entries[len(m.entries)-1].op
+}
+
+// opStats collects the latencies of one operation.
+type opStats struct {
+	latencies []time.Duration
+	errors    int
+	// lastError is kept to explain failures in the report.
+	lastError error
+}
+
+// record adds the outcome of one operation.
+func (s *opStats) record(d time.Duration, err error) {
+	if err != nil {
+		s.errors++
+		s.lastError = err
+		return
+	}
+	s.latencies = append(s.latencies, d)
+}
+
+// merge adds the latencies and errors of other.
+func (s *opStats) merge(other *opStats) {
+	s.latencies = append(s.latencies, other.latencies...)
+	s.errors += other.errors
+	if other.lastError != nil {
+		s.lastError = other.lastError
+	}
+}
+
+// percentile returns the p-th percentile (0-100) of sorted latencies by
+// the nearest rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p/100*float64(len(sorted))+0.999999) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// summary is the latency distribution of one operation.
+type summary struct {
+	op                       string
+	count, errors            int
+	opsPerSec                float64
+	p50, p90, p99, p999, max time.Duration
+	lastError                error
+}
+
+// summarize sorts the latencies of s and computes its distribution over a
+// run of the given duration.
+func summarize(op string, s *opStats, elapsed time.Duration) summary {
+	sort.Slice(s.latencies, func(i, j int) bool { return s.latencies[i] < s.latencies[j] })
+	sum := summary{
+		op:        op,
+		count:     len(s.latencies),
+		errors:    s.errors,
+		p50:       percentile(s.latencies, 50),
+		p90:       percentile(s.latencies, 90),
+		p99:       percentile(s.latencies, 99),
+		p999:      percentile(s.latencies, 99.9),
+		lastError: s.lastError,
+	}
+	if len(s.latencies) > 0 {
+		sum.max = s.latencies[len(s.latencies)-1]
+	}
+	if elapsed > 0 {
+		sum.opsPerSec = float64(sum.count) / elapsed.Seconds()
+	}
+	return sum
+}
+
+// expand substitutes n into a template with at most one %d verb.
+func expand(template string, n int) string {
+	if !strings.Contains(template, "%d") {
+		return template
+	}
+	return fmt.Sprintf(template, n)
+}
+
+// toSearchFilter converts a parsed filter to its protocol form.
+func toSearchFilter(f *filter.Filter) *ldap.SearchFilter {
+	sf := &ldap.SearchFilter{Attribute: f.Attribute, Value: f.Value}
+	switch f.Type {
+	case filter.FilterAnd, filter.FilterOr:
+		sf.Type = ldap.FilterTagAnd
+		if f.Type == filter.FilterOr {
+			sf.Type = ldap.FilterTagOr
+		}
+		for _, child := range f.Children {
+			sf.Children = append(sf.Children, toSearchFilter(child))
+		}
+	case filter.FilterNot:
+		sf.Type = ldap.FilterTagNot
+		sf.Child = toSearchFilter(f.Child)
+	case filter.FilterEquality:
+		sf.Type = ldap.FilterTagEquality
+	case filter.FilterSubstring:
+		sf.Type = ldap.FilterTagSubstrings
+		sf.Substrings = &ldap.SubstringComponents{
+			Initial: f.Substring.Initial,
+			Any:     f.Substring.Any,
+			Final:   f.Substring.Final,
+		}
+	case filter.FilterGreaterOrEqual:
+		sf.Type = ldap.FilterTagGreaterOrEqual
+	case filter.FilterLessOrEqual:
+		sf.Type = ldap.FilterTagLessOrEqual
+	case filter.FilterPresent:
+		sf.Type = ldap.FilterTagPresent
+	case filter.FilterApproxMatch:
+		sf.Type = ldap.FilterTagApproxMatch
+	case filter.FilterExtensibleMatch:
+		sf.Type = ldap.FilterTagExtensibleMatch
+		sf.ExtensibleMatch = &ldap.ExtensibleMatchComponents{
+			MatchingRule: f.Extensible.MatchingRule,
+			Type:         f.Extensible.Attribute,
+			MatchValue:   f.Extensible.Value,
+			DNAttributes: f.Extensible.DNAttributes,
+		}
+	}
+	return sf
+}
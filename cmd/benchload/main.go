@@ -0,0 +1,482 @@
+// Package main provides benchload, a load generator that drives a running
+// LDAP server with a configurable operation mix and reports latency
+// percentiles, to size hardware for a directory.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/filter"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// loadConfig holds the settings of a load run.
+type loadConfig struct {
+	addr         string
+	useTLS       bool
+	insecure     bool
+	timeout      time.Duration
+	bindDN       string
+	bindPassword string
+	baseDN       string
+	scope        ldap.SearchScope
+	filter       string
+	attributes   []string
+	userDN       string
+	userPassword string
+	users        int
+	addBase      string
+	mix          *mix
+	concurrency  int
+	duration     time.Duration
+	populate     int
+}
+
+// run executes benchload and returns an exit code.
+// This is separated from main() to facilitate testing.
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("benchload", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { printUsage(stderr) }
+
+	cfg := &loadConfig{}
+	fs.StringVar(&cfg.addr, "addr", "localhost:1389", "Server address")
+	fs.BoolVar(&cfg.useTLS, "tls", false, "Connect over TLS (LDAPS)")
+	fs.BoolVar(&cfg.insecure, "insecure", false, "Skip TLS certificate verification")
+	fs.DurationVar(&cfg.timeout, "timeout", 10*time.Second, "Timeout of each operation")
+	fs.StringVar(&cfg.bindDN, "bind-dn", "", "DN the connections bind as")
+	fs.StringVar(&cfg.bindPassword, "bind-password", "", "Password of -bind-dn")
+	fs.StringVar(&cfg.baseDN, "base", "dc=example,dc=com", "Search base DN")
+	scope := fs.String("scope", "sub", "Search scope: base, one or sub")
+	fs.StringVar(&cfg.filter, "filter", "(uid=user%d)", "Search filter, %d is replaced by a random user number")
+	attrs := fs.String("attrs", "", "Comma separated attributes to return (default all)")
+	fs.StringVar(&cfg.userDN, "user-dn", "uid=user%d,ou=users,dc=example,dc=com", "DN of the users, %d is replaced by a random user number")
+	fs.StringVar(&cfg.userPassword, "user-password", "secret", "Password of the users for bind operations")
+	fs.IntVar(&cfg.users, "users", 1000, "Number of users to pick from")
+	fs.StringVar(&cfg.addBase, "add-base", "", "Parent DN of added entries (default ou=users under -base)")
+	mixSpec := fs.String("mix", "search=80,bind=10,modify=10", "Operation mix as op=weight pairs; ops are bind, search, compare, modify and add")
+	fs.IntVar(&cfg.concurrency, "concurrency", 8, "Number of concurrent connections")
+	fs.DurationVar(&cfg.duration, "duration", 30*time.Second, "Duration of the run")
+	fs.IntVar(&cfg.populate, "populate", 0, "Add this many users with -user-dn and -user-password before the run")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+
+	var err error
+	if cfg.mix, err = parseMix(*mixSpec); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	switch *scope {
+	case "base":
+		cfg.scope = ldap.ScopeBaseObject
+	case "one":
+		cfg.scope = ldap.ScopeSingleLevel
+	case "sub":
+		cfg.scope = ldap.ScopeWholeSubtree
+	default:
+		fmt.Fprintf(stderr, "Error: invalid scope %q (use base, one or sub)\n", *scope)
+		return 1
+	}
+	if _, err := filter.Parse(expand(cfg.filter, 0)); err != nil {
+		fmt.Fprintf(stderr, "Error: invalid filter %q: %v\n", cfg.filter, err)
+		return 1
+	}
+	for _, attr := range strings.Split(*attrs, ",") {
+		if attr = strings.TrimSpace(attr); attr != "" {
+			cfg.attributes = append(cfg.attributes, attr)
+		}
+	}
+	if cfg.addBase == "" {
+		cfg.addBase = "ou=users," + cfg.baseDN
+	}
+	if cfg.users <= 0 || cfg.concurrency <= 0 || cfg.duration <= 0 {
+		fmt.Fprintln(stderr, "Error: -users, -concurrency and -duration must be positive")
+		return 1
+	}
+
+	if cfg.populate > 0 {
+		start := time.Now()
+		if err := populate(cfg); err != nil {
+			fmt.Fprintf(stderr, "Error: populate failed: %v\n", err)
+			return 1
+		}
+		elapsed := time.Since(start)
+		fmt.Fprintf(stdout, "Populated %d users in %s (%.0f entries/s)\n\n",
+			cfg.populate, elapsed.Round(time.Millisecond), float64(cfg.populate)/elapsed.Seconds())
+	}
+
+	stats, elapsed, err := runLoad(cfg, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	printReport(stdout, cfg, stats, elapsed)
+	return 0
+}
+
+// connect opens a connection bound as -bind-dn, if given.
+func connect(cfg *loadConfig) (*client, error) {
+	c, err := dial(cfg.addr, cfg.useTLS, cfg.insecure, cfg.timeout)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.bindDN != "" {
+		if err := c.Bind(cfg.bindDN, cfg.bindPassword); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("bind as %s: %w", cfg.bindDN, err)
+		}
+	}
+	return c, nil
+}
+
+// populate adds users 0 to cfg.populate-1, ignoring users that exist.
+func populate(cfg *loadConfig) error {
+	next := make(chan int)
+	errs := make(chan error, cfg.concurrency)
+	var wg sync.WaitGroup
+
+	for w := 0; w < cfg.concurrency; w++ {
+		c, err := connect(cfg)
+		if err != nil {
+			close(next)
+			wg.Wait()
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer c.Close()
+			for i := range next {
+				err := c.Add(expand(cfg.userDN, i), userAttributes(expand(cfg.userDN, i), cfg.userPassword))
+				var resErr *resultError
+				if err != nil && !(errors.As(err, &resErr) && resErr.code == ldap.ResultEntryAlreadyExists) {
+					errs <- err
+					// Drain the remaining users so that the feeder does not block
+					for range next {
+					}
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < cfg.populate; i++ {
+		select {
+		case next <- i:
+		case err := <-errs:
+			close(next)
+			wg.Wait()
+			return err
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return nil
+	}
+}
+
+// userAttributes returns the attributes of a user added by benchload. The
+// naming attribute is taken from the first RDN of dn.
+func userAttributes(dn, password string) []ldap.Attribute {
+	rdn, _, _ := strings.Cut(dn, ",")
+	name, value, _ := strings.Cut(rdn, "=")
+	name = strings.TrimSpace(name)
+	value = strings.TrimSpace(value)
+
+	attrs := []ldap.Attribute{
+		{Type: "objectClass", Values: byteValues([]string{"top", "person", "organizationalPerson", "inetOrgPerson"})},
+		{Type: "sn", Values: byteValues([]string{value})},
+	}
+	if !strings.EqualFold(name, "cn") {
+		attrs = append(attrs, ldap.Attribute{Type: "cn", Values: byteValues([]string{value})})
+	}
+	if !strings.EqualFold(name, "sn") {
+		attrs = append(attrs, ldap.Attribute{Type: name, Values: byteValues([]string{value})})
+	}
+	if password != "" {
+		attrs = append(attrs, ldap.Attribute{Type: "userPassword", Values: byteValues([]string{password})})
+	}
+	return attrs
+}
+
+// worker runs operations of the mix on its own connections.
+type worker struct {
+	id       int
+	cfg      *loadConfig
+	conn     *client
+	bindConn *client
+	rng      *rand.Rand
+	stats    map[string]*opStats
+	seq      int
+}
+
+// runLoad runs the operation mix on cfg.concurrency connections for
+// cfg.duration and returns the statistics of each operation.
+func runLoad(cfg *loadConfig, stderr io.Writer) (map[string]*opStats, time.Duration, error) {
+	runID := time.Now().UnixNano()
+	workers := make([]*worker, cfg.concurrency)
+	closeAll := func() {
+		for _, w := range workers {
+			if w != nil {
+				w.close()
+			}
+		}
+	}
+
+	for i := range workers {
+		w := &worker{
+			id:    i,
+			cfg:   cfg,
+			rng:   rand.New(rand.NewSource(runID + int64(i))),
+			stats: make(map[string]*opStats),
+		}
+		var err error
+		if w.conn, err = connect(cfg); err != nil {
+			closeAll()
+			return nil, 0, err
+		}
+		// Binds go to a separate connection so that the other operations
+		// keep the identity of -bind-dn
+		if cfg.mix.has(opBind) {
+			if w.bindConn, err = dial(cfg.addr, cfg.useTLS, cfg.insecure, cfg.timeout); err != nil {
+				w.conn.Close()
+				closeAll()
+				return nil, 0, err
+			}
+		}
+		workers[i] = w
+	}
+	defer closeAll()
+
+	start := time.Now()
+	deadline := start.Add(cfg.duration)
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		wg.Add(1)
+		go func(w *worker) {
+			defer wg.Done()
+			if err := w.run(deadline, runID); err != nil {
+				fmt.Fprintf(stderr, "Warning: connection %d stopped: %v\n", w.id, err)
+			}
+		}(w)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	total := make(map[string]*opStats)
+	for _, w := range workers {
+		for op, s := range w.stats {
+			if total[op] == nil {
+				total[op] = &opStats{}
+			}
+			total[op].merge(s)
+		}
+	}
+	return total, elapsed, nil
+}
+
+// run runs operations until the deadline. It returns the error that broke
+// the connection, if any.
+func (w *worker) run(deadline time.Time, runID int64) error {
+	for time.Now().Before(deadline) {
+		op := w.cfg.mix.pick(w.rng)
+		w.seq++
+
+		start := time.Now()
+		err := w.do(op, runID)
+		elapsed := time.Since(start)
+
+		if w.stats[op] == nil {
+			w.stats[op] = &opStats{}
+		}
+		w.stats[op].record(elapsed, err)
+
+		var resErr *resultError
+		if err != nil && !errors.As(err, &resErr) {
+			return err
+		}
+	}
+	return nil
+}
+
+// do runs one operation.
+func (w *worker) do(op string, runID int64) error {
+	n := w.rng.Intn(w.cfg.users)
+	switch op {
+	case opBind:
+		return w.bindConn.Bind(expand(w.cfg.userDN, n), w.cfg.userPassword)
+
+	case opSearch:
+		f, err := filter.Parse(expand(w.cfg.filter, n))
+		if err != nil {
+			return err
+		}
+		_, err = w.conn.Search(&ldap.SearchRequest{
+			BaseObject:   w.cfg.baseDN,
+			Scope:        w.cfg.scope,
+			DerefAliases: ldap.DerefNever,
+			Filter:       toSearchFilter(f),
+			Attributes:   w.cfg.attributes,
+		})
+		return err
+
+	case opCompare:
+		_, err := w.conn.Compare(expand(w.cfg.userDN, n), "objectClass", "top")
+		return err
+
+	case opModify:
+		return w.conn.Modify(expand(w.cfg.userDN, n), "description", fmt.Sprintf("benchload %d", w.seq))
+
+	case opAdd:
+		dn := fmt.Sprintf("uid=benchload-%d-%d-%d,%s", runID, w.id, w.seq, w.cfg.addBase)
+		return w.conn.Add(dn, userAttributes(dn, ""))
+	}
+	return fmt.Errorf("%w: unknown operation %q", ErrInvalidMix, op)
+}
+
+// close closes the connections of the worker.
+func (w *worker) close() {
+	if w.conn != nil {
+		w.conn.Close()
+	}
+	if w.bindConn != nil {
+		w.bindConn.Close()
+	}
+}
+
+// printReport prints the throughput and latency percentiles of each
+// operation.
+func printReport(w io.Writer, cfg *loadConfig, stats map[string]*opStats, elapsed time.Duration) {
+	fmt.Fprintf(w, "Target:      %s\n", cfg.addr)
+	fmt.Fprintf(w, "Duration:    %s\n", elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "Connections: %d\n\n", cfg.concurrency)
+
+	fmt.Fprintf(w, "%-10s %10s %8s %10s %10s %10s %10s %10s %10s\n",
+		"Operation", "Count", "Errors", "Ops/s", "p50", "p90", "p99", "p99.9", "Max")
+	fmt.Fprintln(w, strings.Repeat("-", 98))
+
+	all := &opStats{}
+	var failures []summary
+	for _, op := range operations {
+		s, ok := stats[op]
+		if !ok {
+			continue
+		}
+		all.merge(&opStats{latencies: append([]time.Duration(nil), s.latencies...), errors: s.errors})
+		sum := summarize(op, s, elapsed)
+		printSummary(w, sum)
+		if sum.lastError != nil {
+			failures = append(failures, sum)
+		}
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 98))
+	printSummary(w, summarize("total", all, elapsed))
+
+	if len(failures) > 0 {
+		fmt.Fprintln(w)
+		for _, sum := range failures {
+			fmt.Fprintf(w, "Last %s error: %v\n", sum.op, sum.lastError)
+		}
+	}
+}
+
+// printSummary prints one row of the report.
+func printSummary(w io.Writer, s summary) {
+	fmt.Fprintf(w, "%-10s %10d %8d %10.1f %10s %10s %10s %10s %10s\n",
+		s.op, s.count, s.errors, s.opsPerSec,
+		formatLatency(s.p50), formatLatency(s.p90), formatLatency(s.p99), formatLatency(s.p999), formatLatency(s.max))
+}
+
+// formatLatency rounds a latency for display.
+func formatLatency(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	default:
+		return d.Round(time.Microsecond).String()
+	}
+}
+
+// printUsage prints the usage information to the given writer.
+func printUsage(w io.Writer) {
+	io.WriteString(w, `benchload - Load generator for LDAP servers
+
+Usage:
+  benchload [options]
+
+Runs a mix of operations against a running server on concurrent
+connections and reports the throughput and latency percentiles of each
+operation. Users are picked at random among -users, so the directory
+should hold them; -populate adds them first.
+
+Options:
+  -addr string
+        Server address (default "localhost:1389")
+  -tls
+        Connect over TLS (LDAPS)
+  -insecure
+        Skip TLS certificate verification
+  -timeout duration
+        Timeout of each operation (default 10s)
+  -bind-dn string
+        DN the connections bind as
+  -bind-password string
+        Password of -bind-dn
+  -base string
+        Search base DN (default "dc=example,dc=com")
+  -scope string
+        Search scope: base, one or sub (default "sub")
+  -filter string
+        Search filter, %d is replaced by a random user number (default "(uid=user%d)")
+  -attrs string
+        Comma separated attributes to return (default all)
+  -user-dn string
+        DN of the users, %d is replaced by a random user number
+        (default "uid=user%d,ou=users,dc=example,dc=com")
+  -user-password string
+        Password of the users for bind operations (default "secret")
+  -users int
+        Number of users to pick from (default 1000)
+  -add-base string
+        Parent DN of added entries (default ou=users under -base)
+  -mix string
+        Operation mix as op=weight pairs; ops are bind, search, compare,
+        modify and add (default "search=80,bind=10,modify=10")
+  -concurrency int
+        Number of concurrent connections (default 8)
+  -duration duration
+        Duration of the run (default 30s)
+  -populate int
+        Add this many users with -user-dn and -user-password before the run
+  -h, -help
+        Show this help message
+
+Example:
+  benchload -bind-dn cn=admin,dc=example,dc=com -bind-password admin \
+      -populate 10000 -users 10000 -mix search=90,modify=10 -duration 1m
+`)
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/ber"
+	"github.com/KilimcininKorOglu/oba/internal/filter"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+)
+
+// fakeServer answers every request with a result of the matching response
+// type. Searches return one entry before the result.
+type fakeServer struct {
+	listener net.Listener
+	// resultCode is returned for requests of the given tag instead of success.
+	resultCode map[int]ldap.ResultCode
+
+	mu       sync.Mutex
+	requests map[int]int
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	s := &fakeServer{listener: l, resultCode: make(map[int]ldap.ResultCode), requests: make(map[int]int)}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeServer) count(tag int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[tag]
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	for {
+		msg, err := readMessage(reader)
+		if err != nil || msg.Operation.Tag == ldap.ApplicationUnbindRequest {
+			return
+		}
+
+		s.mu.Lock()
+		s.requests[msg.Operation.Tag]++
+		code := s.resultCode[msg.Operation.Tag]
+		s.mu.Unlock()
+
+		var tag int
+		switch msg.Operation.Tag {
+		case ldap.ApplicationBindRequest:
+			tag = ldap.ApplicationBindResponse
+		case ldap.ApplicationSearchRequest:
+			encoder := ber.NewBEREncoder(64)
+			encoder.WriteOctetString([]byte("uid=user1,ou=users,dc=example,dc=com"))
+			encoder.EndSequence(encoder.BeginSequence())
+			entry, _ := (&ldap.LDAPMessage{
+				MessageID: msg.MessageID,
+				Operation: &ldap.RawOperation{Tag: ldap.ApplicationSearchResultEntry, Data: encoder.Bytes()},
+			}).Encode()
+			conn.Write(entry)
+			tag = ldap.ApplicationSearchResultDone
+		case ldap.ApplicationCompareRequest:
+			tag = ldap.ApplicationCompareResponse
+			if code == ldap.ResultSuccess {
+				code = ldap.ResultCompareTrue
+			}
+		case ldap.ApplicationModifyRequest:
+			tag = ldap.ApplicationModifyResponse
+		case ldap.ApplicationAddRequest:
+			tag = ldap.ApplicationAddResponse
+		default:
+			return
+		}
+
+		encoder := ber.NewBEREncoder(32)
+		encoder.WriteEnumerated(int64(code))
+		encoder.WriteOctetString(nil)
+		encoder.WriteOctetString(nil)
+		resp, _ := (&ldap.LDAPMessage{
+			MessageID: msg.MessageID,
+			Operation: &ldap.RawOperation{Tag: tag, Data: encoder.Bytes()},
+		}).Encode()
+		conn.Write(resp)
+	}
+}
+
+func TestParseMix(t *testing.T) {
+	m, err := parseMix("search=80, bind=10,modify=10,add=0")
+	if err != nil {
+		t.Fatalf("parseMix failed: %v", err)
+	}
+	if m.total != 100 || len(m.entries) != 3 {
+		t.Errorf("Expected 3 operations with total 100, got %+v", m)
+	}
+	if !m.has(opSearch) || m.has(opAdd) {
+		t.Errorf("Expected search without add, got %+v", m)
+	}
+
+	invalid := []string{"", "search", "search=x", "search=-1", "delete=10", "search=1,search=2", "add=0"}
+	for _, spec := range invalid {
+		if _, err := parseMix(spec); !errors.Is(err, ErrInvalidMix) {
+			t.Errorf("parseMix(%q): expected ErrInvalidMix, got %v", spec, err)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 1000; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{50, 500 * time.Millisecond},
+		{99, 990 * time.Millisecond},
+		{99.9, 999 * time.Millisecond},
+		{100, 1000 * time.Millisecond},
+		{0, 1 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := percentile(latencies, tt.p); got != tt.want {
+			t.Errorf("percentile(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("percentile of no latencies = %v, want 0", got)
+	}
+}
+
+func TestToSearchFilter(t *testing.T) {
+	f, err := filter.Parse("(&(uid=user1)(|(cn=a*b)(!(mail=*))))")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	sf := toSearchFilter(f)
+	if sf.Type != ldap.FilterTagAnd || len(sf.Children) != 2 {
+		t.Fatalf("Expected AND with 2 children, got %+v", sf)
+	}
+	if eq := sf.Children[0]; eq.Type != ldap.FilterTagEquality || eq.Attribute != "uid" || string(eq.Value) != "user1" {
+		t.Errorf("Expected uid=user1, got %+v", eq)
+	}
+	or := sf.Children[1]
+	if or.Type != ldap.FilterTagOr || len(or.Children) != 2 {
+		t.Fatalf("Expected OR with 2 children, got %+v", or)
+	}
+	if sub := or.Children[0]; sub.Type != ldap.FilterTagSubstrings || sub.Substrings == nil || string(sub.Substrings.Initial) != "a" {
+		t.Errorf("Expected substring cn=a*b, got %+v", sub)
+	}
+	if not := or.Children[1]; not.Type != ldap.FilterTagNot || not.Child.Type != ldap.FilterTagPresent {
+		t.Errorf("Expected NOT present, got %+v", not)
+	}
+
+	if _, err := (&ldap.SearchRequest{BaseObject: "dc=example,dc=com", Filter: sf}).Encode(); err != nil {
+		t.Errorf("Encode failed: %v", err)
+	}
+}
+
+func TestClientCompare(t *testing.T) {
+	s := newFakeServer(t)
+	c, err := dial(s.addr(), false, false, time.Second)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer c.Close()
+
+	ok, err := c.Compare("uid=user1,ou=users,dc=example,dc=com", "objectClass", "top")
+	if err != nil || !ok {
+		t.Errorf("Compare = %v, %v; want true", ok, err)
+	}
+
+	s.mu.Lock()
+	s.resultCode[ldap.ApplicationCompareRequest] = ldap.ResultNoSuchObject
+	s.mu.Unlock()
+
+	_, err = c.Compare("uid=missing,dc=example,dc=com", "objectClass", "top")
+	var resErr *resultError
+	if !errors.As(err, &resErr) || resErr.code != ldap.ResultNoSuchObject {
+		t.Errorf("Expected noSuchObject, got %v", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	s := newFakeServer(t)
+	s.resultCode[ldap.ApplicationAddRequest] = ldap.ResultEntryAlreadyExists
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{
+		"-addr", s.addr(),
+		"-bind-dn", "cn=admin,dc=example,dc=com",
+		"-bind-password", "admin",
+		"-populate", "20",
+		"-users", "20",
+		"-mix", "search=70,bind=10,compare=10,modify=10",
+		"-concurrency", "2",
+		"-duration", "200ms",
+	}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+
+	// Existing users are skipped by populate
+	if got := s.count(ldap.ApplicationAddRequest); got != 20 {
+		t.Errorf("Expected 20 add requests, got %d", got)
+	}
+	if s.count(ldap.ApplicationSearchRequest) == 0 || s.count(ldap.ApplicationModifyRequest) == 0 {
+		t.Error("Expected searches and modifies to be sent")
+	}
+
+	out := stdout.String()
+	for _, want := range []string{"Populated 20 users", "Operation", "search", "bind", "compare", "modify", "total"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected report to contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Last ") {
+		t.Errorf("Expected no errors in report:\n%s", out)
+	}
+}
+
+func TestRun_InvalidArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"invalid mix", []string{"-mix", "delete=1"}},
+		{"invalid scope", []string{"-scope", "children"}},
+		{"invalid filter", []string{"-filter", "(uid=user%d"}},
+		{"zero concurrency", []string{"-concurrency", "0"}},
+		{"unknown flag", []string{"-unknown"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, &stdout, &stderr); code != 1 {
+				t.Errorf("Expected exit code 1, got %d", code)
+			}
+		})
+	}
+}
+
+func TestRun_Help(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-h"}, &stdout, &stderr); code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "Usage:") {
+		t.Errorf("Expected usage, got %q", stderr.String())
+	}
+}
@@ -0,0 +1,140 @@
+// Package main provides the acl command for the oba LDAP server.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/KilimcininKorOglu/oba/internal/acl"
+	"github.com/KilimcininKorOglu/oba/internal/config"
+)
+
+// aclCmd handles the acl command.
+func aclCmd(args []string) int {
+	if len(args) == 0 {
+		printACLUsage(os.Stdout)
+		return 0
+	}
+
+	// Check for help flags
+	if args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		printACLUsage(os.Stdout)
+		return 0
+	}
+
+	switch args[0] {
+	case "test":
+		return aclTestCmd(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown acl subcommand: %s\n", args[0])
+		fmt.Fprintln(os.Stderr, "Run 'oba acl help' for usage.")
+		return 1
+	}
+}
+
+// aclTestCmd handles the acl test subcommand.
+func aclTestCmd(args []string) int {
+	fs := flag.NewFlagSet("acl test", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	file := fs.String("file", "", "Path to ACL file")
+	configFile := fs.String("config", "", "Path to configuration file")
+	bindDN := fs.String("bind-dn", "", "Bind DN to test (empty for anonymous)")
+	target := fs.String("target", "", "Target entry DN")
+	op := fs.String("op", "read", "Operation: read, write, add, delete, search, compare")
+	attr := fs.String("attr", "", "Attribute to test (optional)")
+	remoteIP := fs.String("remote-ip", "", "Client IP address (optional)")
+	useTLS := fs.Bool("tls", false, "Client connection uses TLS")
+	ssf := fs.Int("ssf", 0, "Security strength factor of the connection")
+	authMethod := fs.String("auth-method", "", "Bind method: simple, sasl-external (optional)")
+	help := fs.Bool("h", false, "Show help message")
+	helpLong := fs.Bool("help", false, "Show help message")
+
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	if *help || *helpLong {
+		printACLTestUsage(os.Stdout)
+		return 0
+	}
+
+	if *target == "" {
+		fmt.Fprintln(os.Stderr, "Error: -target is required")
+		return 1
+	}
+
+	operation, err := acl.ParseRights([]string{*op})
+	if err != nil || operation == acl.All {
+		fmt.Fprintf(os.Stderr, "Error: invalid operation: %s\n", *op)
+		return 1
+	}
+
+	aclConfig, err := loadACLForTest(*file, *configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	for _, w := range acl.FindShadowedRules(aclConfig) {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+
+	ctx := acl.NewAccessContext(*bindDN, *target, operation).WithConnection(acl.ConnectionInfo{
+		RemoteIP:   *remoteIP,
+		TLS:        *useTLS,
+		SSF:        *ssf,
+		AuthMethod: *authMethod,
+	})
+	if *attr != "" {
+		ctx.WithAttributes(*attr)
+	}
+
+	x := acl.NewEvaluator(aclConfig).EvaluateExplain(ctx)
+	printExplanation(os.Stdout, x)
+
+	if !x.Allowed {
+		return 1
+	}
+	return 0
+}
+
+// loadACLForTest loads the ACL from an ACL file, or from the ACL file or
+// embedded rules of a configuration file.
+func loadACLForTest(file, configFile string) (*acl.Config, error) {
+	if file == "" && configFile == "" {
+		return nil, fmt.Errorf("-file or -config is required")
+	}
+	if file != "" {
+		return acl.LoadFromFile(file)
+	}
+
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if cfg.ACLFile != "" {
+		return acl.LoadFromFile(cfg.ACLFile)
+	}
+	return convertACLConfig(&cfg.ACL)
+}
+
+// printExplanation writes the rules considered and the final decision.
+func printExplanation(w io.Writer, x *acl.Explanation) {
+	for _, re := range x.Rules {
+		marker := " "
+		if re.Matched {
+			marker = "*"
+		}
+		fmt.Fprintf(w, "%s rule %d: %s\n", marker, re.Index, re.Reason)
+		fmt.Fprintf(w, "      %s\n", formatACLRule(re.Index, re.Rule))
+	}
+
+	decision := "DENIED"
+	if x.Allowed {
+		decision = "ALLOWED"
+	}
+	fmt.Fprintf(w, "Result: %s by %s\n", decision, x.Source())
+}
@@ -2,8 +2,11 @@
 package main
 
 import (
+	"crypto/ecdsa"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -54,6 +57,10 @@ func generateBackupFilename(output string) string {
 
 // backupCmd handles the backup command.
 func backupCmd(args []string) int {
+	if len(args) > 0 && args[0] == "list" {
+		return backupListCmd(args[1:])
+	}
+
 	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
 
@@ -64,6 +71,7 @@ func backupCmd(args []string) int {
 	format := fs.String("format", "native", "Backup format: native, ldif")
 	baseDN := fs.String("base-dn", "", "Base DN for LDIF export (optional)")
 	noTimestamp := fs.Bool("no-timestamp", false, "Don't add timestamp to filename")
+	signKeyFile := fs.String("sign-key", "", "PEM file with the ECDSA P-256 key to sign the backup with")
 	help := fs.Bool("h", false, "Show help message")
 	helpLong := fs.Bool("help", false, "Show help message")
 
@@ -86,6 +94,16 @@ func backupCmd(args []string) int {
 		return 1
 	}
 
+	var signKey *ecdsa.PrivateKey
+	if *signKeyFile != "" {
+		var err error
+		signKey, err = backup.LoadSigningKey(*signKeyFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to load signing key: %v\n", err)
+			return 1
+		}
+	}
+
 	// Generate filename with timestamp unless disabled
 	outputPath := *output
 	if !*noTimestamp {
@@ -103,6 +121,7 @@ func backupCmd(args []string) int {
 		Incremental: *incremental,
 		Format:      backup.BackupFormat(*format),
 		BaseDN:      *baseDN,
+		SignKey:     signKey,
 	}
 
 	fmt.Printf("Creating backup...\n")
@@ -111,6 +130,7 @@ func backupCmd(args []string) int {
 	fmt.Printf("  Compress:    %v\n", *compress)
 	fmt.Printf("  Incremental: %v\n", *incremental)
 	fmt.Printf("  Format:      %s\n", *format)
+	fmt.Printf("  Signed:      %v\n", signKey != nil)
 
 	startTime := time.Now()
 	stats, err := bm.Backup(backupOpts)
@@ -130,6 +150,99 @@ func backupCmd(args []string) int {
 	return 0
 }
 
+// backupListCmd handles the backup list subcommand.
+func backupListCmd(args []string) int {
+	fs := flag.NewFlagSet("backup list", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	backupDir := fs.String("backup-dir", "", "Directory holding the backup files")
+	asJSON := fs.Bool("json", false, "Print the backups as JSON")
+	help := fs.Bool("h", false, "Show help message")
+	helpLong := fs.Bool("help", false, "Show help message")
+
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	if *help || *helpLong {
+		printBackupListUsage(os.Stdout)
+		return 0
+	}
+
+	if *backupDir == "" {
+		fmt.Fprintln(os.Stderr, "Error: -backup-dir is required")
+		return 1
+	}
+
+	infos, err := backup.ScanBackupDir(*backupDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to scan backups: %v\n", err)
+		return 1
+	}
+
+	if err := printBackupList(os.Stdout, infos, *asJSON); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// backupListItem is a backup as printed by backup list -json.
+type backupListItem struct {
+	Filename   string    `json:"filename"`
+	Type       string    `json:"type"`
+	Created    time.Time `json:"created"`
+	EntryCount uint64    `json:"entryCount"`
+	PageCount  uint64    `json:"pageCount"`
+	Compressed bool      `json:"compressed"`
+	Signed     bool      `json:"signed"`
+	Size       int64     `json:"size"`
+}
+
+// printBackupList prints backups as a table, or as a JSON array if asJSON
+// is set.
+func printBackupList(w io.Writer, infos []*backup.BackupInfo, asJSON bool) error {
+	items := make([]backupListItem, len(infos))
+	for i, info := range infos {
+		items[i] = backupListItem{
+			Filename:   filepath.Base(info.Path),
+			Type:       info.Type(),
+			Created:    info.Created().UTC(),
+			EntryCount: info.Header.EntryCount,
+			PageCount:  info.Header.TotalPages,
+			Compressed: info.Header.IsCompressed(),
+			Signed:     info.Header.IsSigned(),
+			Size:       info.Size,
+		}
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(items)
+	}
+
+	if len(items) == 0 {
+		_, err := fmt.Fprintln(w, "No backups found")
+		return err
+	}
+
+	nameWidth := len("Filename")
+	for _, item := range items {
+		nameWidth = max(nameWidth, len(item.Filename))
+	}
+	format := fmt.Sprintf("%%-%ds  %%-4s  %%-20s  %%10v  %%10v  %%-10v  %%12v\n", nameWidth)
+	fmt.Fprintf(w, format, "Filename", "Type", "Created", "EntryCount", "PageCount", "Compressed", "Size")
+	for _, item := range items {
+		_, err := fmt.Fprintf(w, format, item.Filename, item.Type, item.Created.Format(time.RFC3339),
+			item.EntryCount, item.PageCount, item.Compressed, item.Size)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // restoreCmd handles the restore command.
 func restoreCmd(args []string) int {
 	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
@@ -139,6 +252,7 @@ func restoreCmd(args []string) int {
 	dataDir := fs.String("data-dir", "", "Target data directory path")
 	verify := fs.Bool("verify", false, "Verify checksums before restore")
 	format := fs.String("format", "native", "Backup format: native, ldif")
+	verifyKeyFile := fs.String("verify-key", "", "PEM file with the ECDSA P-256 public key the backup must be signed with")
 	help := fs.Bool("h", false, "Show help message")
 	helpLong := fs.Bool("help", false, "Show help message")
 
@@ -161,6 +275,16 @@ func restoreCmd(args []string) int {
 		return 1
 	}
 
+	var verifyKey *ecdsa.PublicKey
+	if *verifyKeyFile != "" {
+		var err error
+		verifyKey, err = backup.LoadVerifyKey(*verifyKeyFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to load verification key: %v\n", err)
+			return 1
+		}
+	}
+
 	// Create restore manager
 	rm := backup.NewRestoreManager(*dataDir)
 
@@ -170,6 +294,7 @@ func restoreCmd(args []string) int {
 		Verify:    *verify,
 		Format:    backup.BackupFormat(*format),
 		DataDir:   *dataDir,
+		VerifyKey: verifyKey,
 	}
 
 	fmt.Printf("Restoring from backup...\n")
@@ -177,6 +302,7 @@ func restoreCmd(args []string) int {
 	fmt.Printf("  Data Dir: %s\n", *dataDir)
 	fmt.Printf("  Verify:   %v\n", *verify)
 	fmt.Printf("  Format:   %s\n", *format)
+	fmt.Printf("  Signed:   %v\n", verifyKey != nil)
 
 	startTime := time.Now()
 	stats, err := rm.Restore(opts)
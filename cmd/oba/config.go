@@ -165,6 +165,9 @@ func configShowCmd(args []string) int {
 	// Apply environment variable overrides
 	applyEnvOverrides(cfg)
 
+	// Show secret references instead of the secrets they resolve to
+	cfg = cfg.WithSecretReferences()
+
 	// Output in requested format
 	switch strings.ToLower(*format) {
 	case "json":
@@ -248,9 +251,21 @@ func marshalConfigToYAML(cfg *config.Config) string {
 	if cfg.Server.TLSKey != "" {
 		sb.WriteString(fmt.Sprintf("  tlsKey: %q\n", cfg.Server.TLSKey))
 	}
+	if cfg.Server.TLSKeyPassphrase != "" {
+		sb.WriteString(fmt.Sprintf("  tlsKeyPassphrase: %q\n", cfg.Server.TLSKeyPassphrase))
+	}
 	sb.WriteString(fmt.Sprintf("  maxConnections: %d\n", cfg.Server.MaxConnections))
 	sb.WriteString(fmt.Sprintf("  readTimeout: %s\n", formatDuration(cfg.Server.ReadTimeout)))
 	sb.WriteString(fmt.Sprintf("  writeTimeout: %s\n", formatDuration(cfg.Server.WriteTimeout)))
+	if cfg.Server.ProxyProtocol {
+		sb.WriteString("  proxyProtocol: true\n")
+	}
+	if len(cfg.Server.TrustedProxies) > 0 {
+		sb.WriteString("  trustedProxies:\n")
+		for _, cidr := range cfg.Server.TrustedProxies {
+			sb.WriteString(fmt.Sprintf("    - %q\n", cidr))
+		}
+	}
 	sb.WriteString("\n")
 
 	// Directory section
@@ -291,10 +306,28 @@ func marshalConfigToYAML(cfg *config.Config) string {
 		sb.WriteString(fmt.Sprintf("    maxAge: %s\n", formatDuration(cfg.Security.PasswordPolicy.MaxAge)))
 	}
 	sb.WriteString(fmt.Sprintf("    historyCount: %d\n", cfg.Security.PasswordPolicy.HistoryCount))
+	if cfg.Security.PasswordPolicy.GraceLogins > 0 {
+		sb.WriteString(fmt.Sprintf("    graceLogins: %d\n", cfg.Security.PasswordPolicy.GraceLogins))
+	}
 	sb.WriteString("  rateLimit:\n")
 	sb.WriteString(fmt.Sprintf("    enabled: %t\n", cfg.Security.RateLimit.Enabled))
 	sb.WriteString(fmt.Sprintf("    maxAttempts: %d\n", cfg.Security.RateLimit.MaxAttempts))
 	sb.WriteString(fmt.Sprintf("    lockoutDuration: %s\n", formatDuration(cfg.Security.RateLimit.LockoutDuration)))
+	sb.WriteString("  bindThrottle:\n")
+	sb.WriteString(fmt.Sprintf("    enabled: %t\n", cfg.Security.BindThrottle.Enabled))
+	sb.WriteString(fmt.Sprintf("    maxFailures: %d\n", cfg.Security.BindThrottle.MaxFailures))
+	sb.WriteString(fmt.Sprintf("    window: %s\n", formatDuration(cfg.Security.BindThrottle.Window)))
+	sb.WriteString(fmt.Sprintf("    baseDelay: %s\n", formatDuration(cfg.Security.BindThrottle.BaseDelay)))
+	sb.WriteString(fmt.Sprintf("    maxDelay: %s\n", formatDuration(cfg.Security.BindThrottle.MaxDelay)))
+	if len(cfg.Security.BindThrottle.Allowlist) > 0 {
+		sb.WriteString("    allowlist:\n")
+		for _, cidr := range cfg.Security.BindThrottle.Allowlist {
+			sb.WriteString(fmt.Sprintf("      - %q\n", cidr))
+		}
+	}
+	if cfg.Security.BindThrottle.StateFile != "" {
+		sb.WriteString(fmt.Sprintf("    stateFile: %q\n", cfg.Security.BindThrottle.StateFile))
+	}
 	sb.WriteString("\n")
 
 	// ACL section
@@ -317,6 +350,17 @@ func marshalConfigToYAML(cfg *config.Config) string {
 					sb.WriteString(fmt.Sprintf("        - %q\n", attr))
 				}
 			}
+			if tr := rule.TimeRange; tr != nil {
+				sb.WriteString("      timeRange:\n")
+				sb.WriteString(fmt.Sprintf("        start: %q\n", tr.Start))
+				sb.WriteString(fmt.Sprintf("        end: %q\n", tr.End))
+				if len(tr.Weekdays) > 0 {
+					sb.WriteString(fmt.Sprintf("        weekdays: [%s]\n", strings.Join(tr.Weekdays, ", ")))
+				}
+				if tr.Timezone != "" {
+					sb.WriteString(fmt.Sprintf("        timezone: %q\n", tr.Timezone))
+				}
+			}
 		}
 	}
 
@@ -0,0 +1,542 @@
+// Package main provides the cn=config subtree for the oba LDAP server.
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/acl"
+	"github.com/KilimcininKorOglu/oba/internal/backend"
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/filter"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/logging"
+	"github.com/KilimcininKorOglu/oba/internal/raft"
+	"github.com/KilimcininKorOglu/oba/internal/server"
+)
+
+// configTreeDN is the base of the synthetic configuration subtree.
+const configTreeDN = "cn=config"
+
+// settingKind is the value syntax of a configuration setting.
+type settingKind int
+
+const (
+	settingString settingKind = iota
+	settingInt
+	settingBool
+	settingDuration
+)
+
+// configSetting maps an LDAP attribute to a key of a ConfigManager section.
+type configSetting struct {
+	attr  string
+	key   string
+	kind  settingKind
+	value func(*config.Config) string
+}
+
+// configSection is an entry under cn=config whose attributes are all
+// writable settings of one ConfigManager section.
+type configSection struct {
+	rdn      string
+	section  string
+	settings []configSetting
+}
+
+// configSections lists the writable settings exposed under cn=config.
+var configSections = []configSection{
+	{
+		rdn:     "cn=logging",
+		section: "logging",
+		settings: []configSetting{
+			{"obaLogLevel", "level", settingString, func(c *config.Config) string { return c.Logging.Level }},
+			{"obaLogFormat", "format", settingString, func(c *config.Config) string { return c.Logging.Format }},
+		},
+	},
+	{
+		rdn:     "cn=passwordPolicy",
+		section: "security.passwordpolicy",
+		settings: []configSetting{
+			{"obaPwdPolicyEnabled", "enabled", settingBool, func(c *config.Config) string { return formatBool(c.Security.PasswordPolicy.Enabled) }},
+			{"obaPwdMinLength", "minLength", settingInt, func(c *config.Config) string { return strconv.Itoa(c.Security.PasswordPolicy.MinLength) }},
+			{"obaPwdRequireUppercase", "requireUppercase", settingBool, func(c *config.Config) string { return formatBool(c.Security.PasswordPolicy.RequireUppercase) }},
+			{"obaPwdRequireLowercase", "requireLowercase", settingBool, func(c *config.Config) string { return formatBool(c.Security.PasswordPolicy.RequireLowercase) }},
+			{"obaPwdRequireDigit", "requireDigit", settingBool, func(c *config.Config) string { return formatBool(c.Security.PasswordPolicy.RequireDigit) }},
+			{"obaPwdRequireSpecial", "requireSpecial", settingBool, func(c *config.Config) string { return formatBool(c.Security.PasswordPolicy.RequireSpecial) }},
+			{"obaPwdMaxAge", "maxAge", settingDuration, func(c *config.Config) string { return c.Security.PasswordPolicy.MaxAge.String() }},
+			{"obaPwdHistoryCount", "historyCount", settingInt, func(c *config.Config) string { return strconv.Itoa(c.Security.PasswordPolicy.HistoryCount) }},
+			{"obaPwdGraceLogins", "graceLogins", settingInt, func(c *config.Config) string { return strconv.Itoa(c.Security.PasswordPolicy.GraceLogins) }},
+		},
+	},
+	{
+		rdn:     "cn=rateLimit",
+		section: "security.ratelimit",
+		settings: []configSetting{
+			{"obaRateLimitEnabled", "enabled", settingBool, func(c *config.Config) string { return formatBool(c.Security.RateLimit.Enabled) }},
+			{"obaRateLimitMaxAttempts", "maxAttempts", settingInt, func(c *config.Config) string { return strconv.Itoa(c.Security.RateLimit.MaxAttempts) }},
+			{"obaRateLimitLockoutDuration", "lockoutDuration", settingDuration, func(c *config.Config) string { return c.Security.RateLimit.LockoutDuration.String() }},
+		},
+	},
+}
+
+// Attributes of the cn=indexes and cn=acl entries.
+const (
+	attrIndexedAttribute = "obaIndexedAttribute"
+	attrACLDefaultPolicy = "obaACLDefaultPolicy"
+	attrACLRule          = "obaACLRule"
+)
+
+// configTree serves the cn=config subtree. Entries are built from the
+// running configuration on every request. Only the root DN may read or
+// modify them, and only settings listed in configSections and the ACL
+// default policy are writable.
+type configTree struct {
+	rootDN string
+
+	// static is used when no ConfigManager is set; the tree is then
+	// read-only.
+	static         *config.Config
+	manager        *config.ConfigManager
+	aclManager     *acl.Manager
+	clusterBackend *raft.ClusterBackend
+	indexes        func() []string
+}
+
+// current returns the running configuration.
+func (t *configTree) current() *config.Config {
+	if t.manager != nil {
+		return t.manager.GetConfig()
+	}
+	return t.static
+}
+
+// contains reports whether dn lies in the cn=config subtree.
+func (t *configTree) contains(dn string) bool {
+	dn = normalizeConfigDN(dn)
+	return dn == configTreeDN || strings.HasSuffix(dn, ","+configTreeDN)
+}
+
+// isAdmin reports whether bindDN is the root DN.
+func (t *configTree) isAdmin(bindDN string) bool {
+	return t.rootDN != "" && normalizeConfigDN(bindDN) == normalizeConfigDN(t.rootDN)
+}
+
+// entries builds the entries of the subtree, the root first.
+func (t *configTree) entries() []*backend.Entry {
+	cfg := t.current()
+
+	root := backend.NewEntry(configTreeDN)
+	root.SetAttribute("objectClass", "top", "obaConfig")
+	root.SetAttribute("cn", "config")
+	entries := []*backend.Entry{root}
+
+	for _, s := range configSections {
+		entry := newConfigEntry(s.rdn)
+		for _, setting := range s.settings {
+			entry.SetAttribute(setting.attr, setting.value(cfg))
+		}
+		entries = append(entries, entry)
+	}
+
+	if t.indexes != nil {
+		entry := newConfigEntry("cn=indexes")
+		if attrs := t.indexes(); len(attrs) > 0 {
+			entry.SetAttribute(attrIndexedAttribute, attrs...)
+		}
+		entries = append(entries, entry)
+	}
+
+	if t.aclManager != nil {
+		entry := newConfigEntry("cn=acl")
+		entry.SetAttribute(attrACLDefaultPolicy, t.aclManager.Stats().DefaultPolicy)
+		rules := t.aclManager.GetRules()
+		if len(rules) > 0 {
+			values := make([]string, len(rules))
+			for i, rule := range rules {
+				values[i] = formatACLRule(i, rule)
+			}
+			entry.SetAttribute(attrACLRule, values...)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// newConfigEntry returns an empty child entry of cn=config.
+func newConfigEntry(rdn string) *backend.Entry {
+	entry := backend.NewEntry(rdn + "," + configTreeDN)
+	entry.SetAttribute("objectClass", "top", "obaConfigEntry")
+	entry.SetAttribute("cn", strings.TrimPrefix(rdn, "cn="))
+	return entry
+}
+
+// search answers a search based in the cn=config subtree.
+func (t *configTree) search(bindDN string, req *ldap.SearchRequest) *server.SearchResult {
+	if !t.isAdmin(bindDN) {
+		return &server.SearchResult{
+			OperationResult: server.OperationResult{
+				ResultCode:        ldap.ResultInsufficientAccessRights,
+				DiagnosticMessage: "only the root DN may read cn=config",
+			},
+		}
+	}
+
+	baseDN := normalizeConfigDN(req.BaseObject)
+	entries := t.entries()
+
+	found := false
+	for _, entry := range entries {
+		if normalizeConfigDN(entry.DN) == baseDN {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return &server.SearchResult{
+			OperationResult: server.OperationResult{
+				ResultCode:        ldap.ResultNoSuchObject,
+				DiagnosticMessage: "entry not found",
+			},
+		}
+	}
+
+	var f *filter.Filter
+	if req.Filter != nil {
+		f = convertSearchFilter(req.Filter)
+	}
+	evaluator := filter.NewEvaluator(nil)
+
+	var results []*server.SearchEntry
+	for _, entry := range entries {
+		if !inSearchScope(normalizeConfigDN(entry.DN), baseDN, req.Scope) {
+			continue
+		}
+		if f != nil && !evaluator.Evaluate(f, toFilterEntry(entry)) {
+			continue
+		}
+		results = append(results, &server.SearchEntry{
+			DN:         entry.DN,
+			Attributes: convertAttributes(entry),
+		})
+	}
+
+	return &server.SearchResult{
+		OperationResult: server.OperationResult{ResultCode: ldap.ResultSuccess},
+		Entries:         results,
+	}
+}
+
+// modify applies a modify request to an entry of the cn=config subtree.
+// Each change must replace a writable setting with a single value. Changes
+// go through ConfigManager.UpdateSection, which validates them and runs
+// the hot-reload handlers, and are then saved to the config file.
+func (t *configTree) modify(bindDN string, logger logging.Logger, req *ldap.ModifyRequest) *server.OperationResult {
+	if !t.isAdmin(bindDN) {
+		return &server.OperationResult{
+			ResultCode:        ldap.ResultInsufficientAccessRights,
+			DiagnosticMessage: "only the root DN may modify cn=config",
+		}
+	}
+
+	dn := normalizeConfigDN(req.Object)
+	if dn == "cn=acl,"+configTreeDN && t.aclManager != nil {
+		return t.modifyACL(bindDN, logger, req)
+	}
+
+	var section *configSection
+	for i := range configSections {
+		if dn == normalizeConfigDN(configSections[i].rdn+","+configTreeDN) {
+			section = &configSections[i]
+			break
+		}
+	}
+	if section == nil {
+		for _, entry := range t.entries() {
+			if normalizeConfigDN(entry.DN) == dn {
+				return unwillingToPerform("%s is read-only", entry.DN)
+			}
+		}
+		return &server.OperationResult{
+			ResultCode:        ldap.ResultNoSuchObject,
+			DiagnosticMessage: "entry not found",
+		}
+	}
+
+	if t.manager == nil {
+		return unwillingToPerform("cn=config is read-only without a configuration file")
+	}
+
+	cfg := t.current()
+	data := make(map[string]interface{}, len(req.Changes))
+	oldValues := make(map[string]string, len(req.Changes))
+	newValues := make(map[string]string, len(req.Changes))
+	for _, change := range req.Changes {
+		setting := findSetting(section.settings, change.Attribute.Type)
+		if setting == nil {
+			return unwillingToPerform("attribute %s is not modifiable", change.Attribute.Type)
+		}
+		value, err := singleReplaceValue(change)
+		if err != nil {
+			return unwillingToPerform("%s: %v", setting.attr, err)
+		}
+		parsed, err := parseSettingValue(setting.kind, value)
+		if err != nil {
+			return unwillingToPerform("%s: %v", setting.attr, err)
+		}
+		data[setting.key] = parsed
+		oldValues[setting.attr] = setting.value(cfg)
+		newValues[setting.attr] = value
+	}
+
+	if result := t.applySection(section.section, data); result != nil {
+		return result
+	}
+
+	for attr, newValue := range newValues {
+		logger.Info("config changed",
+			"dn", req.Object,
+			"bind_dn", bindDN,
+			"attribute", attr,
+			"old", oldValues[attr],
+			"new", newValue)
+	}
+
+	return &server.OperationResult{ResultCode: ldap.ResultSuccess}
+}
+
+// applySection updates a config section and persists it, replicating the
+// change through Raft in cluster mode. It returns nil on success.
+func (t *configTree) applySection(section string, data map[string]interface{}) *server.OperationResult {
+	if t.clusterBackend != nil {
+		if !t.clusterBackend.IsLeader() {
+			return unwillingToPerform("not leader, redirect to: %s", t.clusterBackend.LeaderAddr())
+		}
+		version := t.manager.GetVersion() + 1
+		if err := t.clusterBackend.ProposeConfigChange(section, config.ToStringMap(data), version); err != nil {
+			return &server.OperationResult{
+				ResultCode:        ldap.ResultOther,
+				DiagnosticMessage: err.Error(),
+			}
+		}
+		return nil
+	}
+
+	if err := t.manager.UpdateSection(section, data); err != nil {
+		return unwillingToPerform("%v", err)
+	}
+	if err := t.manager.SaveToFile(); err != nil {
+		return &server.OperationResult{
+			ResultCode:        ldap.ResultOther,
+			DiagnosticMessage: "change applied but not saved: " + err.Error(),
+		}
+	}
+	return nil
+}
+
+// modifyACL handles modifications of cn=acl,cn=config. Only the default
+// policy is writable; rules are managed through the ACL file or REST API.
+func (t *configTree) modifyACL(bindDN string, logger logging.Logger, req *ldap.ModifyRequest) *server.OperationResult {
+	if len(req.Changes) != 1 || !strings.EqualFold(req.Changes[0].Attribute.Type, attrACLDefaultPolicy) {
+		return unwillingToPerform("only %s is modifiable", attrACLDefaultPolicy)
+	}
+	policy, err := singleReplaceValue(req.Changes[0])
+	if err != nil {
+		return unwillingToPerform("%s: %v", attrACLDefaultPolicy, err)
+	}
+	policy = strings.ToLower(policy)
+	if policy != "allow" && policy != "deny" {
+		return unwillingToPerform("invalid policy: %s (must be allow or deny)", policy)
+	}
+
+	oldPolicy := t.aclManager.Stats().DefaultPolicy
+
+	if t.clusterBackend != nil {
+		if !t.clusterBackend.IsLeader() {
+			return unwillingToPerform("not leader, redirect to: %s", t.clusterBackend.LeaderAddr())
+		}
+		version := t.aclManager.GetVersion() + 1
+		if err := t.clusterBackend.ProposeACLSetDefault(policy, version); err != nil {
+			return &server.OperationResult{
+				ResultCode:        ldap.ResultOther,
+				DiagnosticMessage: err.Error(),
+			}
+		}
+	} else {
+		if err := t.aclManager.SetDefaultPolicy(policy); err != nil {
+			return unwillingToPerform("%v", err)
+		}
+		if t.aclManager.IsFileMode() {
+			if err := t.aclManager.SaveToFile(); err != nil {
+				return &server.OperationResult{
+					ResultCode:        ldap.ResultOther,
+					DiagnosticMessage: "change applied but not saved: " + err.Error(),
+				}
+			}
+		}
+	}
+
+	logger.Info("config changed",
+		"dn", req.Object,
+		"bind_dn", bindDN,
+		"attribute", attrACLDefaultPolicy,
+		"old", oldPolicy,
+		"new", policy)
+
+	return &server.OperationResult{ResultCode: ldap.ResultSuccess}
+}
+
+// findSetting returns the setting for an attribute, or nil.
+func findSetting(settings []configSetting, attr string) *configSetting {
+	for i := range settings {
+		if strings.EqualFold(settings[i].attr, attr) {
+			return &settings[i]
+		}
+	}
+	return nil
+}
+
+// singleReplaceValue returns the value of a replace change with exactly
+// one value.
+func singleReplaceValue(change ldap.Modification) (string, error) {
+	if change.Operation != ldap.ModifyOperationReplace {
+		return "", fmt.Errorf("only replace is supported, got %s", change.Operation)
+	}
+	if len(change.Attribute.Values) != 1 {
+		return "", fmt.Errorf("exactly one value is required, got %d", len(change.Attribute.Values))
+	}
+	return string(change.Attribute.Values[0]), nil
+}
+
+// parseSettingValue converts an attribute value to the type UpdateSection
+// expects for the given kind.
+func parseSettingValue(kind settingKind, value string) (interface{}, error) {
+	switch kind {
+	case settingInt:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q", value)
+		}
+		return float64(n), nil
+	case settingBool:
+		switch strings.ToUpper(value) {
+		case "TRUE":
+			return true, nil
+		case "FALSE":
+			return false, nil
+		}
+		return nil, fmt.Errorf("invalid boolean %q (must be TRUE or FALSE)", value)
+	case settingDuration:
+		if _, err := time.ParseDuration(value); err != nil {
+			return nil, fmt.Errorf("invalid duration %q", value)
+		}
+		return value, nil
+	default:
+		return value, nil
+	}
+}
+
+// formatBool formats a boolean with LDAP Boolean syntax.
+func formatBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// formatACLRule formats an ACL rule as a single attribute value, prefixed
+// with its index in the rule list.
+func formatACLRule(index int, rule *acl.ACL) string {
+	var rights []string
+	for _, r := range []acl.Right{acl.Read, acl.Write, acl.Add, acl.Delete, acl.Search, acl.Compare} {
+		if rule.Rights == acl.All {
+			rights = []string{acl.All.String()}
+			break
+		}
+		if rule.Rights.Has(r) {
+			rights = append(rights, r.String())
+		}
+	}
+
+	action := "allow"
+	if rule.Deny {
+		action = "deny"
+	}
+
+	s := fmt.Sprintf("{%d}%s target=%q scope=%s subject=%q rights=%s",
+		index, action, rule.Target, rule.Scope, rule.Subject, strings.Join(rights, ","))
+	if len(rule.Attributes) > 0 {
+		s += " attrs=" + strings.Join(rule.Attributes, ",")
+	}
+	if len(rule.SourceCIDRs) > 0 {
+		s += " sources=" + strings.Join(rule.SourceCIDRs, ",")
+	}
+	if rule.RequireTLS {
+		s += " tls=required"
+	}
+	if rule.MinSSF > 0 {
+		s += fmt.Sprintf(" ssf=%d", rule.MinSSF)
+	}
+	if rule.AuthMethod != "" {
+		s += " authmethod=" + rule.AuthMethod
+	}
+	if rule.TimeRange != nil {
+		s += " time=" + strconv.Quote(rule.TimeRange.String())
+	}
+	return s
+}
+
+// inSearchScope reports whether dn is within scope of baseDN. Both DNs
+// must be normalized. The RDNs of dn above the search depth are compared
+// with baseDN as a DN, so escaped commas in values do not count as
+// separators.
+func inSearchScope(dn, baseDN string, scope ldap.SearchScope) bool {
+	if scope == ldap.ScopeBaseObject {
+		return dn == baseDN
+	}
+
+	rdns, err := ldap.SplitDN(dn)
+	if err != nil {
+		return false
+	}
+	baseRDNs, err := ldap.SplitDN(baseDN)
+	if err != nil {
+		return false
+	}
+
+	depth := len(rdns) - len(baseRDNs)
+	if depth < 0 || (scope == ldap.ScopeSingleLevel && depth != 1) {
+		return false
+	}
+	return normalizeConfigDN(strings.Join(rdns[depth:], ",")) == baseDN
+}
+
+// toFilterEntry converts a backend entry for filter evaluation.
+func toFilterEntry(entry *backend.Entry) *filter.Entry {
+	fe := filter.NewEntry(entry.DN)
+	for name, values := range entry.Attributes {
+		fe.Attributes[name] = values
+	}
+	return fe
+}
+
+// normalizeConfigDN normalizes a DN for comparison, falling back to
+// lowercasing if it does not parse.
+func normalizeConfigDN(dn string) string {
+	if normalized, err := ldap.NormalizeDN(dn); err == nil {
+		return strings.ToLower(normalized)
+	}
+	return strings.ToLower(strings.TrimSpace(dn))
+}
+
+// unwillingToPerform returns an unwillingToPerform result with a formatted
+// diagnostic message.
+func unwillingToPerform(format string, args ...interface{}) *server.OperationResult {
+	return &server.OperationResult{
+		ResultCode:        ldap.ResultUnwillingToPerform,
+		DiagnosticMessage: fmt.Sprintf(format, args...),
+	}
+}
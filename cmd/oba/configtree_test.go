@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/acl"
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/logging"
+	"github.com/KilimcininKorOglu/oba/internal/server"
+)
+
+const configTreeAdmin = "cn=admin,dc=example,dc=com"
+
+// newTestConfigTree returns a config tree backed by a config file in a
+// temporary directory, and the path of that file.
+func newTestConfigTree(t *testing.T) (*configTree, string) {
+	t.Helper()
+
+	cfg := config.DefaultConfig()
+	cfg.Directory.BaseDN = "dc=example,dc=com"
+	cfg.Directory.RootDN = configTreeAdmin
+	cfg.Directory.RootPassword = "secret"
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	manager := config.NewConfigManager(cfg, path)
+	if err := manager.SaveToFile(); err != nil {
+		t.Fatalf("SaveToFile() error = %v", err)
+	}
+
+	aclConfig := acl.NewConfig()
+	aclConfig.AddRule(acl.NewACL("dc=example,dc=com", "authenticated", acl.Read|acl.Search).WithAttributes("cn", "mail"))
+	aclManager, err := acl.NewManager(&acl.ManagerConfig{EmbeddedConfig: aclConfig})
+	if err != nil {
+		t.Fatalf("acl.NewManager() error = %v", err)
+	}
+
+	return &configTree{
+		rootDN:     cfg.Directory.RootDN,
+		static:     cfg,
+		manager:    manager,
+		aclManager: aclManager,
+		indexes:    func() []string { return []string{"cn", "uid"} },
+	}, path
+}
+
+func replaceRequest(dn, attr string, values ...string) *ldap.ModifyRequest {
+	byteValues := make([][]byte, len(values))
+	for i, v := range values {
+		byteValues[i] = []byte(v)
+	}
+	return &ldap.ModifyRequest{
+		Object: dn,
+		Changes: []ldap.Modification{{
+			Operation: ldap.ModifyOperationReplace,
+			Attribute: ldap.Attribute{Type: attr, Values: byteValues},
+		}},
+	}
+}
+
+func findSearchEntry(entries []*server.SearchEntry, dn string) *server.SearchEntry {
+	for _, e := range entries {
+		if e.DN == dn {
+			return e
+		}
+	}
+	return nil
+}
+
+func attributeValue(entry *server.SearchEntry, name string) []string {
+	for _, attr := range entry.Attributes {
+		if strings.EqualFold(attr.Type, name) {
+			values := make([]string, len(attr.Values))
+			for i, v := range attr.Values {
+				values[i] = string(v)
+			}
+			return values
+		}
+	}
+	return nil
+}
+
+func TestConfigTreeSearch(t *testing.T) {
+	tree, _ := newTestConfigTree(t)
+
+	if !tree.contains("CN=Logging, cn=Config") || tree.contains("dc=example,dc=com") {
+		t.Error("contains() does not match the cn=config subtree")
+	}
+
+	result := tree.search(configTreeAdmin, &ldap.SearchRequest{
+		BaseObject: "cn=config",
+		Scope:      ldap.ScopeWholeSubtree,
+	})
+	if result.ResultCode != ldap.ResultSuccess {
+		t.Fatalf("search result = %v %s", result.ResultCode, result.DiagnosticMessage)
+	}
+	if len(result.Entries) != 6 {
+		t.Errorf("got %d entries, want 6", len(result.Entries))
+	}
+
+	logEntry := findSearchEntry(result.Entries, "cn=logging,cn=config")
+	if logEntry == nil {
+		t.Fatal("cn=logging,cn=config not returned")
+	}
+	if got := attributeValue(logEntry, "obaLogLevel"); len(got) != 1 || got[0] != "info" {
+		t.Errorf("obaLogLevel = %v, want [info]", got)
+	}
+
+	indexes := findSearchEntry(result.Entries, "cn=indexes,cn=config")
+	if got := attributeValue(indexes, "obaIndexedAttribute"); len(got) != 2 {
+		t.Errorf("obaIndexedAttribute = %v", got)
+	}
+
+	aclEntry := findSearchEntry(result.Entries, "cn=acl,cn=config")
+	rules := attributeValue(aclEntry, "obaACLRule")
+	if len(rules) != 1 || !strings.HasPrefix(rules[0], `{0}allow target="dc=example,dc=com"`) ||
+		!strings.Contains(rules[0], "rights=read,search attrs=cn,mail") {
+		t.Errorf("obaACLRule = %v", rules)
+	}
+
+	// Filters and scopes apply.
+	result = tree.search(configTreeAdmin, &ldap.SearchRequest{
+		BaseObject: "cn=config",
+		Scope:      ldap.ScopeSingleLevel,
+		Filter: &ldap.SearchFilter{
+			Type:      ldap.FilterTagEquality,
+			Attribute: "cn",
+			Value:     []byte("passwordPolicy"),
+		},
+	})
+	if len(result.Entries) != 1 || result.Entries[0].DN != "cn=passwordPolicy,cn=config" {
+		t.Errorf("filtered search returned %d entries", len(result.Entries))
+	}
+
+	result = tree.search(configTreeAdmin, &ldap.SearchRequest{BaseObject: "cn=missing,cn=config"})
+	if result.ResultCode != ldap.ResultNoSuchObject {
+		t.Errorf("missing entry result = %v, want noSuchObject", result.ResultCode)
+	}
+
+	result = tree.search("", &ldap.SearchRequest{BaseObject: "cn=config"})
+	if result.ResultCode != ldap.ResultInsufficientAccessRights {
+		t.Errorf("anonymous search result = %v, want insufficientAccessRights", result.ResultCode)
+	}
+}
+
+func TestInSearchScope(t *testing.T) {
+	tests := []struct {
+		dn, baseDN string
+		scope      ldap.SearchScope
+		want       bool
+	}{
+		{"cn=config", "cn=config", ldap.ScopeBaseObject, true},
+		{"cn=logging,cn=config", "cn=config", ldap.ScopeBaseObject, false},
+		{"cn=logging,cn=config", "cn=config", ldap.ScopeSingleLevel, true},
+		{"cn=config", "cn=config", ldap.ScopeSingleLevel, false},
+		{"cn=a,cn=logging,cn=config", "cn=config", ldap.ScopeSingleLevel, false},
+		{"cn=config", "cn=config", ldap.ScopeWholeSubtree, true},
+		{"cn=a,cn=logging,cn=config", "cn=config", ldap.ScopeWholeSubtree, true},
+		{"cn=logging,cn=other", "cn=config", ldap.ScopeWholeSubtree, false},
+		// An escaped comma is part of the value, not a separator
+		{`cn=a\,cn=config`, "cn=config", ldap.ScopeSingleLevel, false},
+		{`cn=a\,cn=config`, "cn=config", ldap.ScopeWholeSubtree, false},
+		{`cn=a\,b,cn=config`, "cn=config", ldap.ScopeSingleLevel, true},
+		{`cn=x,cn=a\,b,cn=config`, "cn=config", ldap.ScopeSingleLevel, false},
+	}
+	for _, tt := range tests {
+		dn, baseDN := normalizeConfigDN(tt.dn), normalizeConfigDN(tt.baseDN)
+		if got := inSearchScope(dn, baseDN, tt.scope); got != tt.want {
+			t.Errorf("inSearchScope(%q, %q, %v) = %v, want %v", tt.dn, tt.baseDN, tt.scope, got, tt.want)
+		}
+	}
+}
+
+func TestConfigTreeModify(t *testing.T) {
+	tree, path := newTestConfigTree(t)
+
+	var buf bytes.Buffer
+	logger := logging.New(logging.Config{Level: "info", Format: "json"})
+	logger.SetOutput(&buf)
+
+	updated := make(chan *config.Config, 1)
+	tree.manager.SetOnUpdate(func(_, newCfg *config.Config) { updated <- newCfg })
+
+	result := tree.modify(configTreeAdmin, logger, replaceRequest("cn=passwordPolicy,cn=config", "obaPwdMinLength", "12"))
+	if result.ResultCode != ldap.ResultSuccess {
+		t.Fatalf("modify result = %v %s", result.ResultCode, result.DiagnosticMessage)
+	}
+
+	// The change reaches the hot-reload handler and the config file.
+	if newCfg := <-updated; newCfg.Security.PasswordPolicy.MinLength != 12 {
+		t.Errorf("reloaded MinLength = %d, want 12", newCfg.Security.PasswordPolicy.MinLength)
+	}
+	saved, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if saved.Security.PasswordPolicy.MinLength != 12 {
+		t.Errorf("saved MinLength = %d, want 12", saved.Security.PasswordPolicy.MinLength)
+	}
+
+	audit := buf.String()
+	if !strings.Contains(audit, "config changed") || !strings.Contains(audit, `"old":"8"`) || !strings.Contains(audit, `"new":"12"`) {
+		t.Errorf("audit log = %s", audit)
+	}
+
+	// The ACL default policy is applied through the ACL manager.
+	result = tree.modify(configTreeAdmin, logger, replaceRequest("cn=acl,cn=config", "obaACLDefaultPolicy", "allow"))
+	if result.ResultCode != ldap.ResultSuccess {
+		t.Fatalf("ACL modify result = %v %s", result.ResultCode, result.DiagnosticMessage)
+	}
+	if got := tree.aclManager.Stats().DefaultPolicy; got != "allow" {
+		t.Errorf("default policy = %s, want allow", got)
+	}
+}
+
+func TestConfigTreeModifyRejected(t *testing.T) {
+	tree, path := newTestConfigTree(t)
+	logger := logging.NewNop()
+	before, _ := os.ReadFile(path)
+
+	tests := []struct {
+		name string
+		req  *ldap.ModifyRequest
+		want ldap.ResultCode
+		diag string
+	}{
+		{"invalid level", replaceRequest("cn=logging,cn=config", "obaLogLevel", "loud"), ldap.ResultUnwillingToPerform, "validation failed"},
+		{"invalid integer", replaceRequest("cn=passwordPolicy,cn=config", "obaPwdMinLength", "many"), ldap.ResultUnwillingToPerform, "invalid integer"},
+		{"invalid boolean", replaceRequest("cn=rateLimit,cn=config", "obaRateLimitEnabled", "yes"), ldap.ResultUnwillingToPerform, "invalid boolean"},
+		{"multiple values", replaceRequest("cn=logging,cn=config", "obaLogLevel", "info", "debug"), ldap.ResultUnwillingToPerform, "exactly one value"},
+		{"not whitelisted", replaceRequest("cn=logging,cn=config", "obaLogOutput", "stdout"), ldap.ResultUnwillingToPerform, "not modifiable"},
+		{"read-only entry", replaceRequest("cn=indexes,cn=config", "obaIndexedAttribute", "mail"), ldap.ResultUnwillingToPerform, "read-only"},
+		{"invalid policy", replaceRequest("cn=acl,cn=config", "obaACLDefaultPolicy", "maybe"), ldap.ResultUnwillingToPerform, "invalid policy"},
+		{"missing entry", replaceRequest("cn=missing,cn=config", "cn", "x"), ldap.ResultNoSuchObject, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tree.modify(configTreeAdmin, logger, tt.req)
+			if result.ResultCode != tt.want || !strings.Contains(result.DiagnosticMessage, tt.diag) {
+				t.Errorf("modify result = %v %q, want %v containing %q",
+					result.ResultCode, result.DiagnosticMessage, tt.want, tt.diag)
+			}
+		})
+	}
+
+	add := replaceRequest("cn=logging,cn=config", "obaLogLevel", "debug")
+	add.Changes[0].Operation = ldap.ModifyOperationAdd
+	if result := tree.modify(configTreeAdmin, logger, add); result.ResultCode != ldap.ResultUnwillingToPerform {
+		t.Errorf("add modification result = %v, want unwillingToPerform", result.ResultCode)
+	}
+
+	result := tree.modify("uid=alice,ou=users,dc=example,dc=com", logger, replaceRequest("cn=logging,cn=config", "obaLogLevel", "debug"))
+	if result.ResultCode != ldap.ResultInsufficientAccessRights {
+		t.Errorf("non-admin modify result = %v, want insufficientAccessRights", result.ResultCode)
+	}
+
+	if after, _ := os.ReadFile(path); !bytes.Equal(before, after) {
+		t.Error("rejected modifications changed the config file")
+	}
+	if level := tree.current().Logging.Level; level != "info" {
+		t.Errorf("log level = %s after rejected modifications", level)
+	}
+}
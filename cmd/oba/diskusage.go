@@ -0,0 +1,19 @@
+//go:build linux || darwin
+
+package main
+
+import "syscall"
+
+// diskUsagePercent returns the used share of the volume holding path, as
+// df reports it. ok is false if the volume cannot be read.
+func diskUsagePercent(path string) (used int, ok bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, false
+	}
+	usedBlocks := st.Blocks - st.Bfree
+	if usedBlocks+st.Bavail == 0 {
+		return 0, false
+	}
+	return int((usedBlocks*100 + usedBlocks + st.Bavail - 1) / (usedBlocks + st.Bavail)), true
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package main
+
+// diskUsagePercent is not implemented on this platform.
+func diskUsagePercent(path string) (used int, ok bool) {
+	return 0, false
+}
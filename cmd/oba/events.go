@@ -0,0 +1,113 @@
+package main
+
+import (
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/logging"
+	"github.com/KilimcininKorOglu/oba/internal/server"
+)
+
+// eventMonitorInterval is how often replication lag and storage use are
+// checked for events.
+const eventMonitorInterval = 30 * time.Second
+
+// snmpEventHook returns an event hook that sends each event as a trap of
+// the OBA MIB.
+func snmpEventHook(emitter *logging.SNMPTrapEmitter, logger logging.Logger) server.EventHook {
+	return func(event server.Event) {
+		var trap logging.Trap
+		switch event.Type {
+		case server.EventServerStart:
+			trap = logging.ServerStartTrap(event.Message)
+		case server.EventServerStop:
+			trap = logging.ServerStopTrap(event.Message)
+		case server.EventBindFailure:
+			trap = logging.BindFailureTrap(event.BindDN, event.ClientAddr, event.Message)
+		case server.EventReplicationLag:
+			trap = logging.ReplicationLagTrap(event.ReplicationLag)
+		case server.EventStorageFull:
+			trap = logging.StorageFullTrap(event.StorageUsedPercent)
+		default:
+			return
+		}
+		if err := emitter.Emit(trap); err != nil {
+			logger.Warn("failed to send SNMP trap", "trap", trap.Type.String(), "error", err.Error())
+		}
+	}
+}
+
+// emitEvent reports event to the event hook, if one is configured.
+func (s *LDAPServer) emitEvent(event server.Event) {
+	if s.events == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	s.events(event)
+}
+
+// monitorEvents reports replication lag and a nearly full data volume
+// until the server stops. Each condition is reported once when it starts.
+func (s *LDAPServer) monitorEvents() {
+	ticker := time.NewTicker(eventMonitorInterval)
+	defer ticker.Stop()
+
+	var lagging, full bool
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		lagging = s.checkReplicationLag(lagging)
+		full = s.checkStorageFull(full)
+	}
+}
+
+// checkReplicationLag reports EventReplicationLag when the node has more
+// committed entries left to apply than the threshold and was not lagging
+// before. It returns whether the node is lagging.
+func (s *LDAPServer) checkReplicationLag(wasLagging bool) bool {
+	threshold := s.config.Monitoring.SNMPTrap.ReplicationLagThreshold
+	if s.clusterBackend == nil || threshold == 0 {
+		return false
+	}
+
+	status := s.clusterBackend.Status()
+	var lag uint64
+	if status.CommitIndex > status.LastApplied {
+		lag = status.CommitIndex - status.LastApplied
+	}
+	lagging := lag >= threshold
+	if lagging && !wasLagging {
+		s.emitEvent(server.Event{
+			Type:           server.EventReplicationLag,
+			ReplicationLag: lag,
+		})
+	}
+	return lagging
+}
+
+// checkStorageFull reports EventStorageFull when the data volume reaches
+// the configured use and was below it before. It returns whether the
+// volume is full.
+func (s *LDAPServer) checkStorageFull(wasFull bool) bool {
+	limit := s.config.Monitoring.SNMPTrap.StorageFullPercent
+	if limit <= 0 {
+		return false
+	}
+
+	used, ok := diskUsagePercent(s.config.Storage.DataDir)
+	if !ok {
+		return false
+	}
+	full := used >= limit
+	if full && !wasFull {
+		s.emitEvent(server.Event{
+			Type:               server.EventStorageFull,
+			StorageUsedPercent: used,
+		})
+	}
+	return full
+}
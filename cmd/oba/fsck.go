@@ -0,0 +1,95 @@
+// Package main provides the fsck command for the oba LDAP server.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/KilimcininKorOglu/oba/internal/backend"
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+// fsckCmd handles the fsck command.
+func fsckCmd(args []string) int {
+	fs := flag.NewFlagSet("fsck", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	configFile := fs.String("config", "", "Path to configuration file")
+	dataDir := fs.String("data-dir", "", "Data directory path (overrides config)")
+	structure := fs.Bool("structure", false, "Report entries that violate DIT structure rules")
+	help := fs.Bool("h", false, "Show help message")
+	helpLong := fs.Bool("help", false, "Show help message")
+
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	if *help || *helpLong {
+		printFsckUsage(os.Stdout)
+		return 0
+	}
+
+	if !*structure {
+		fmt.Fprintln(os.Stderr, "Error: no check selected (use -structure)")
+		return 1
+	}
+
+	cfg := config.DefaultConfig()
+	if *configFile != "" {
+		var err error
+		cfg, err = config.LoadConfig(*configFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+			return 1
+		}
+	}
+	if *dataDir != "" {
+		cfg.Storage.DataDir = *dataDir
+	}
+
+	rules := backend.StructureRulesFromConfig(cfg.Schema.StructureRules.Rules)
+	if len(rules) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no structure rules configured (schema.structureRules.rules)")
+		return 1
+	}
+
+	opts := storage.DefaultEngineOptions().
+		WithPageSize(cfg.Storage.PageSize).
+		WithCreateIfNotExists(false)
+	if cfg.Security.Encryption.Enabled && cfg.Security.Encryption.KeyFile != "" {
+		opts = opts.WithEncryptionKeyFile(cfg.Security.Encryption.KeyFile)
+	}
+
+	db, err := engine.Open(cfg.Storage.DataDir, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to open database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	// A nil config keeps the backend from bootstrapping the directory.
+	be := backend.NewBackend(db, nil)
+	defer be.Close()
+	be.SetStructureRules(rules)
+
+	report, err := be.CheckStructureRules()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: structure check failed: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Checked %d entries against %d structure rules\n", report.ScannedEntries, len(rules))
+	if len(report.Violations) == 0 {
+		fmt.Println("No structure violations found")
+		return 0
+	}
+
+	fmt.Printf("Found %d structure violations:\n", len(report.Violations))
+	for _, v := range report.Violations {
+		fmt.Printf("  %s\n      %s\n", v.DN, v.Reason)
+	}
+	return 1
+}
@@ -18,6 +18,13 @@ Commands:
   restore     Restore from backup
   user        User management
   config      Configuration management
+  fsck        Check database consistency
+  recover     Inspect and repair a damaged database
+  storage     Storage space analysis
+  index       Index maintenance
+  migrate     Upgrade the database format
+  schema      Schema management
+  acl         Access control tools
   version     Show version information
 
 Use "oba <command> -h" for more information about a command.
@@ -59,6 +66,7 @@ func printBackupUsage(w io.Writer) {
 
 Usage:
   oba backup [options]
+  oba backup list -backup-dir <path> [-json]
 
 Options:
   -output string
@@ -75,6 +83,8 @@ Options:
         Base DN for LDIF export (optional)
   -no-timestamp
         Don't add timestamp to filename
+  -sign-key string
+        PEM file with the ECDSA P-256 private key to sign the backup with
   -h, -help
         Show this help message
 
@@ -84,6 +94,28 @@ Examples:
 
   oba backup --data-dir /var/lib/oba --output /backup/oba.bak --no-timestamp
   # Creates: /backup/oba.bak
+
+  oba backup list --backup-dir /backup
+`)
+}
+
+// printBackupListUsage prints the backup list subcommand usage.
+func printBackupListUsage(w io.Writer) {
+	fmt.Fprint(w, `List native backups and their metadata
+
+Usage:
+  oba backup list [options]
+
+Options:
+  -backup-dir string
+        Directory holding the backup files (required)
+  -json
+        Print the backups as JSON
+  -h, -help
+        Show this help message
+
+Every file in the directory is checked for a backup header; LDIF backups
+and other files are skipped. Only the headers are read.
 `)
 }
 
@@ -103,6 +135,8 @@ Options:
         Verify checksums before restore
   -format string
         Backup format: native, ldif (default "native")
+  -verify-key string
+        PEM file with the ECDSA P-256 public key the backup must be signed with
   -h, -help
         Show this help message
 `)
@@ -143,6 +177,279 @@ Use "oba config <subcommand> -h" for more information.
 `)
 }
 
+// printFsckUsage prints the fsck command usage.
+func printFsckUsage(w io.Writer) {
+	fmt.Fprint(w, `Check database consistency
+
+Usage:
+  oba fsck [options]
+
+Options:
+  -config string
+        Path to configuration file
+  -data-dir string
+        Data directory path (overrides config)
+  -structure
+        Report entries that violate DIT structure rules
+        (schema.structureRules.rules in the config file)
+  -h, -help
+        Show this help message
+
+Exit status is 1 if any violations are found.
+`)
+}
+
+// printMigrateUsage prints the migrate command usage.
+func printMigrateUsage(w io.Writer) {
+	fmt.Fprint(w, `Upgrade the database format
+
+Usage:
+  oba migrate [options]
+
+Applies the pending format migrations, including the online ones that
+oba serve would otherwise run in the background. The server must be
+stopped. An interrupted offline migration is rolled back and run again
+the next time the database is opened.
+
+Options:
+  -config string
+        Path to configuration file
+  -data-dir string
+        Data directory path (overrides config)
+  -dry-run
+        Report the pending migrations, their mode and estimated size
+        without applying them
+  -h, -help
+        Show this help message
+`)
+}
+
+// printRecoverUsage prints the recover command usage.
+func printRecoverUsage(w io.Writer) {
+	fmt.Fprint(w, `Inspect and repair a damaged database
+
+Usage:
+  oba recover [options]
+
+The server must be stopped. Take a backup of the data directory first;
+-force-checkpoint, -prune-pages and -rebuild-index modify it in place.
+-verify and -dump-wal only read it.
+
+Options:
+  -config string
+        Path to configuration file
+  -data-dir string
+        Data directory path (overrides config)
+  -verify
+        Check page headers, checksums and the free list of the data and
+        index files, the order of each index B+ tree, and that the DN
+        index and the attribute indexes agree with the stored entries.
+        Exits with status 1 if problems are found.
+  -dump-wal
+        Print each WAL record as a line of JSON. The WAL is not modified.
+  -force-checkpoint
+        Replay the WAL into the data file, write a checkpoint and empty
+        the WAL
+  -prune-pages <from> <to>
+        Free the data pages from <from> through <to>. Entries stored on
+        them are removed and all indexes are rebuilt.
+  -rebuild-index string
+        Drop the index for an attribute and recreate it from the entries
+        in the data file
+  -h, -help
+        Show this help message
+
+Actions run in the order listed above.
+`)
+}
+
+// printStorageUsage prints the storage command usage.
+func printStorageUsage(w io.Writer) {
+	fmt.Fprint(w, `Storage space analysis
+
+Usage:
+  oba storage <subcommand> [options]
+
+Subcommands:
+  analyze     Break down the pages of the data and index files by type
+
+Use "oba storage <subcommand> -h" for more information.
+`)
+}
+
+// printStorageAnalyzeUsage prints the storage analyze subcommand usage.
+func printStorageAnalyzeUsage(w io.Writer) {
+	fmt.Fprint(w, `Break down the pages of the data and index files by type
+
+Usage:
+  oba storage analyze [options]
+
+For each page type the number of pages, their size and how full they
+are on average is printed. Pages that were never written count as free.
+The files are only read, but the figures are only exact while the
+server is stopped.
+
+Options:
+  -config string
+        Path to configuration file
+  -data-dir string
+        Data directory path (overrides config)
+  -h, -help
+        Show this help message
+
+Example:
+  oba storage analyze --data-dir /var/lib/oba
+`)
+}
+
+// printIndexUsage prints the index command usage.
+func printIndexUsage(w io.Writer) {
+	fmt.Fprint(w, `Index maintenance
+
+Usage:
+  oba index <subcommand> [options]
+
+Subcommands:
+  rebuild     Rebuild the index for an attribute from the entries
+
+Use "oba index <subcommand> -h" for more information.
+`)
+}
+
+// printIndexRebuildUsage prints the index rebuild subcommand usage.
+func printIndexRebuildUsage(w io.Writer) {
+	fmt.Fprint(w, `Rebuild the index for an attribute from the entries
+
+Usage:
+  oba index rebuild -attr <attribute> [options]
+
+The server must be stopped. Every entry in the data file is scanned and
+the index is written to fresh pages, which replace the old index only
+once complete, so an interrupted rebuild leaves the old index in place.
+An attribute without an index gets an equality index. Progress is shown
+on standard error. To rebuild the index of a running server, use
+POST /api/v1/admin/indexes/{attr}/rebuild.
+
+Options:
+  -attr string
+        Attribute whose index to rebuild (required)
+  -config string
+        Path to configuration file
+  -data-dir string
+        Data directory path (overrides config)
+  -h, -help
+        Show this help message
+
+Example:
+  oba index rebuild -attr uid --data-dir /var/lib/oba
+`)
+}
+
+// printSchemaUsage prints the schema command usage.
+func printSchemaUsage(w io.Writer) {
+	fmt.Fprint(w, `Schema management
+
+Usage:
+  oba schema <subcommand> [options]
+
+Subcommands:
+  migrate     Migrate stored entries to a new schema version
+
+Use "oba schema <subcommand> -h" for more information.
+`)
+}
+
+// printSchemaMigrateUsage prints the schema migrate subcommand usage.
+func printSchemaMigrateUsage(w io.Writer) {
+	fmt.Fprint(w, `Migrate stored entries to a new schema version
+
+Usage:
+  oba schema migrate [options]
+
+Options:
+  -config string
+        Path to configuration file
+  -data-dir string
+        Data directory path (overrides config)
+  -from-version int
+        Schema version the data was written with
+  -to-version int
+        Schema version to migrate to (default: current version)
+  -from-schema string
+        LDIF schema file to migrate from (overrides -from-version)
+  -to-schema string
+        LDIF schema file to migrate to (overrides -to-version)
+  -default attr=value
+        Value for entries missing a new MUST attribute (repeatable)
+  -dry-run
+        Print the migration plan without applying it
+  -h, -help
+        Show this help message
+
+Entries that cannot be migrated are left unchanged and listed.
+Exit status is 1 if any such entries are found.
+
+Examples:
+  oba schema migrate --from-version 1 --to-version 2 --data-dir /var/lib/oba
+  oba schema migrate --from-version 1 --to-schema custom.ldif --default employeeType=staff
+`)
+}
+
+// printACLUsage prints the acl command usage.
+func printACLUsage(w io.Writer) {
+	fmt.Fprint(w, `Access control tools
+
+Usage:
+  oba acl <subcommand> [options]
+
+Subcommands:
+  test        Explain whether a request is allowed and which rule decides
+
+Use "oba acl <subcommand> -h" for more information.
+`)
+}
+
+// printACLTestUsage prints the acl test subcommand usage.
+func printACLTestUsage(w io.Writer) {
+	fmt.Fprint(w, `Explain whether a request is allowed and which rule decides
+
+Usage:
+  oba acl test [options]
+
+Options:
+  -file string
+        Path to ACL file
+  -config string
+        Path to configuration file (uses its aclFile or acl section)
+  -bind-dn string
+        Bind DN to test (empty for anonymous)
+  -target string
+        Target entry DN
+  -op string
+        Operation: read, write, add, delete, search, compare (default "read")
+  -attr string
+        Attribute to test (optional)
+  -remote-ip string
+        Client IP address (optional)
+  -tls
+        Client connection uses TLS
+  -ssf int
+        Security strength factor of the connection
+  -auth-method string
+        Bind method: simple, sasl-external (optional)
+  -h, -help
+        Show this help message
+
+Each rule up to the deciding one is listed with the reason it did or did
+not match. Unreachable rules are reported as warnings.
+Exit status is 1 if the request is denied.
+
+Examples:
+  oba acl test -file acl.yaml -bind-dn uid=alice,ou=users,dc=example,dc=com \
+      -target uid=bob,ou=users,dc=example,dc=com -op read -attr userPassword
+`)
+}
+
 // printVersionUsage prints the version command usage.
 func printVersionUsage(w io.Writer) {
 	fmt.Fprint(w, `Show version information
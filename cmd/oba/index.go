@@ -0,0 +1,112 @@
+// Package main provides the index command for the oba LDAP server.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+// indexCmd handles the index command.
+func indexCmd(args []string) int {
+	if len(args) == 0 {
+		printIndexUsage(os.Stdout)
+		return 0
+	}
+
+	// Check for help flags
+	if args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		printIndexUsage(os.Stdout)
+		return 0
+	}
+
+	switch args[0] {
+	case "rebuild":
+		return indexRebuildCmd(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown index subcommand: %s\n", args[0])
+		fmt.Fprintln(os.Stderr, "Run 'oba index help' for usage.")
+		return 1
+	}
+}
+
+// indexRebuildCmd handles the index rebuild subcommand.
+func indexRebuildCmd(args []string) int {
+	fs := flag.NewFlagSet("index rebuild", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	configFile := fs.String("config", "", "Path to configuration file")
+	dataDir := fs.String("data-dir", "", "Data directory path (overrides config)")
+	attr := fs.String("attr", "", "Attribute whose index to rebuild")
+	help := fs.Bool("h", false, "Show help message")
+	helpLong := fs.Bool("help", false, "Show help message")
+
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	if *help || *helpLong {
+		printIndexRebuildUsage(os.Stdout)
+		return 0
+	}
+
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Error: unexpected argument: %s\n", fs.Arg(0))
+		return 1
+	}
+
+	if *attr == "" {
+		fmt.Fprintln(os.Stderr, "Error: -attr is required")
+		return 1
+	}
+
+	cfg := config.DefaultConfig()
+	if *configFile != "" {
+		var err error
+		cfg, err = config.LoadConfig(*configFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+			return 1
+		}
+	}
+	if *dataDir != "" {
+		cfg.Storage.DataDir = *dataDir
+	}
+
+	opts := storage.DefaultEngineOptions().
+		WithPageSize(cfg.Storage.PageSize).
+		WithCreateIfNotExists(false)
+	if cfg.Security.Encryption.Enabled && cfg.Security.Encryption.KeyFile != "" {
+		opts = opts.WithEncryptionKeyFile(cfg.Security.Encryption.KeyFile)
+	}
+
+	tool, err := engine.NewRecoveryTool(cfg.Storage.DataDir, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	// The progress line is rewritten in place and ends with the final count
+	p, err := tool.RebuildIndexWithProgress(*attr, func(p storage.IndexRebuildProgress) {
+		printRebuildProgress(os.Stderr, p)
+	})
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to rebuild index %s: %v\n", *attr, err)
+		return 1
+	}
+	fmt.Printf("Rebuilt index %s from %d entries\n", *attr, p.EntriesIndexed)
+	return 0
+}
+
+// printRebuildProgress overwrites the progress line of a rebuild.
+func printRebuildProgress(w io.Writer, p storage.IndexRebuildProgress) {
+	fmt.Fprintf(w, "\rScanned %d entries, indexed %d, wrote %d keys in %s",
+		p.EntriesScanned, p.EntriesIndexed, p.KeysWritten, p.Elapsed.Round(time.Millisecond))
+}
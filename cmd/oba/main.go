@@ -32,6 +32,20 @@ func run(args []string) int {
 		return configCmd(args[2:])
 	case "reload":
 		return reloadCmd(args[2:])
+	case "fsck":
+		return fsckCmd(args[2:])
+	case "recover":
+		return recoverCmd(args[2:])
+	case "storage":
+		return storageCmd(args[2:])
+	case "index":
+		return indexCmd(args[2:])
+	case "migrate":
+		return migrateCmd(args[2:])
+	case "schema":
+		return schemaCmd(args[2:])
+	case "acl":
+		return aclCmd(args[2:])
 	case "version":
 		return versionCmd(args[2:])
 	case "help", "-h", "--help":
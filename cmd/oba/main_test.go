@@ -2,9 +2,15 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/backup"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
 )
 
 func TestRun_NoArgs(t *testing.T) {
@@ -128,6 +134,62 @@ func TestRun_BackupHelp(t *testing.T) {
 	}
 }
 
+func TestRun_BackupList(t *testing.T) {
+	if exitCode := run([]string{"oba", "backup", "list"}); exitCode != 1 {
+		t.Errorf("expected exit code 1 for backup list without backup-dir, got %d", exitCode)
+	}
+
+	dataDir := t.TempDir()
+	backupDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dataDir, "data.oba"), []byte("data"), 0644); err != nil {
+		t.Fatalf("failed to write data file: %v", err)
+	}
+	for _, name := range []string{"one.oba", "two.oba", "three.oba"} {
+		args := []string{"oba", "backup", "-data-dir", dataDir, "-output", filepath.Join(backupDir, name), "-no-timestamp"}
+		if name == "two.oba" {
+			args = append(args, "-compress")
+		}
+		if exitCode := run(args); exitCode != 0 {
+			t.Fatalf("backup %s failed with exit code %d", name, exitCode)
+		}
+	}
+
+	if exitCode := run([]string{"oba", "backup", "list", "-backup-dir", backupDir}); exitCode != 0 {
+		t.Errorf("expected exit code 0 for backup list, got %d", exitCode)
+	}
+
+	infos, err := backup.ScanBackupDir(backupDir)
+	if err != nil {
+		t.Fatalf("ScanBackupDir() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := printBackupList(&buf, infos, true); err != nil {
+		t.Fatalf("printBackupList() error = %v", err)
+	}
+	var items []backupListItem
+	if err := json.Unmarshal(buf.Bytes(), &items); err != nil {
+		t.Fatalf("backup list -json printed invalid JSON: %v\n%s", err, buf.String())
+	}
+	if len(items) != 3 {
+		t.Fatalf("backup list printed %d backups, want 3", len(items))
+	}
+	for _, item := range items {
+		if item.Type != "full" || item.Size == 0 || item.Compressed != (item.Filename == "two.oba") {
+			t.Errorf("unexpected backup %+v", item)
+		}
+	}
+
+	buf.Reset()
+	if err := printBackupList(&buf, infos, false); err != nil {
+		t.Fatalf("printBackupList() error = %v", err)
+	}
+	for _, want := range []string{"Filename", "one.oba", "two.oba", "three.oba"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("backup list table is missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
 func TestRun_Restore(t *testing.T) {
 	// Without required -input flag
 	exitCode := run([]string{"oba", "restore"})
@@ -388,6 +450,159 @@ func TestRun_ConfigShow(t *testing.T) {
 	}
 }
 
+func TestRun_RecoverHelp(t *testing.T) {
+	exitCode := run([]string{"oba", "recover", "-h"})
+	if exitCode != 0 {
+		t.Errorf("expected exit code 0 for recover help, got %d", exitCode)
+	}
+}
+
+func TestRun_RecoverInvalidArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"no action", []string{"oba", "recover", "-data-dir", t.TempDir()}},
+		{"prune without end", []string{"oba", "recover", "-prune-pages", "3"}},
+		{"prune with bad page", []string{"oba", "recover", "-prune-pages", "3", "x"}},
+		{"extra argument", []string{"oba", "recover", "-dump-wal", "extra"}},
+		{"missing database", []string{"oba", "recover", "-data-dir", t.TempDir(), "-force-checkpoint"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if exitCode := run(tt.args); exitCode != 1 {
+				t.Errorf("expected exit code 1, got %d", exitCode)
+			}
+		})
+	}
+}
+
+func TestRun_RecoverVerify(t *testing.T) {
+	dir := t.TempDir()
+	db, err := engine.Open(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close database: %v", err)
+	}
+
+	if exitCode := run([]string{"oba", "recover", "-data-dir", dir, "-verify"}); exitCode != 0 {
+		t.Errorf("expected exit code 0 for a healthy database, got %d", exitCode)
+	}
+
+	// Damage the header of the first page after the file header
+	f, err := os.OpenFile(filepath.Join(dir, engine.DataFileName), os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("failed to open data file: %v", err)
+	}
+	if _, err := f.WriteAt([]byte{0xAB, 0xCD}, storage.PageSize+14); err != nil {
+		t.Fatalf("failed to corrupt page: %v", err)
+	}
+	f.Close()
+
+	if exitCode := run([]string{"oba", "recover", "-data-dir", dir, "-verify"}); exitCode != 1 {
+		t.Errorf("expected exit code 1 for a damaged database, got %d", exitCode)
+	}
+}
+
+func TestRun_Migrate(t *testing.T) {
+	dir := t.TempDir()
+	db, err := engine.Open(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close database: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"help", []string{"oba", "migrate", "-h"}, 0},
+		{"dry run", []string{"oba", "migrate", "-data-dir", dir, "-dry-run"}, 0},
+		{"apply", []string{"oba", "migrate", "-data-dir", dir}, 0},
+		{"extra argument", []string{"oba", "migrate", "-data-dir", dir, "extra"}, 1},
+		{"missing database", []string{"oba", "migrate", "-data-dir", t.TempDir(), "-dry-run"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if exitCode := run(tt.args); exitCode != tt.want {
+				t.Errorf("expected exit code %d, got %d", tt.want, exitCode)
+			}
+		})
+	}
+}
+
+func TestRun_StorageAnalyze(t *testing.T) {
+	dir := t.TempDir()
+	db, err := engine.Open(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close database: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"help", []string{"oba", "storage", "-h"}, 0},
+		{"analyze help", []string{"oba", "storage", "analyze", "-h"}, 0},
+		{"analyze", []string{"oba", "storage", "analyze", "-data-dir", dir}, 0},
+		{"unknown subcommand", []string{"oba", "storage", "bogus"}, 1},
+		{"extra argument", []string{"oba", "storage", "analyze", "-data-dir", dir, "extra"}, 1},
+		{"missing database", []string{"oba", "storage", "analyze", "-data-dir", t.TempDir()}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if exitCode := run(tt.args); exitCode != tt.want {
+				t.Errorf("expected exit code %d, got %d", tt.want, exitCode)
+			}
+		})
+	}
+}
+
+func TestRun_IndexRebuild(t *testing.T) {
+	dir := t.TempDir()
+	db, err := engine.Open(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close database: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"help", []string{"oba", "index", "-h"}, 0},
+		{"rebuild help", []string{"oba", "index", "rebuild", "-h"}, 0},
+		{"rebuild", []string{"oba", "index", "rebuild", "-attr", "uid", "-data-dir", dir}, 0},
+		{"missing attr", []string{"oba", "index", "rebuild", "-data-dir", dir}, 1},
+		{"unknown subcommand", []string{"oba", "index", "bogus"}, 1},
+		{"extra argument", []string{"oba", "index", "rebuild", "-attr", "uid", "-data-dir", dir, "extra"}, 1},
+		{"missing database", []string{"oba", "index", "rebuild", "-attr", "uid", "-data-dir", t.TempDir()}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if exitCode := run(tt.args); exitCode != tt.want {
+				t.Errorf("expected exit code %d, got %d", tt.want, exitCode)
+			}
+		})
+	}
+}
+
 func TestPrintUsage(t *testing.T) {
 	var buf bytes.Buffer
 	printUsage(&buf)
@@ -580,3 +795,43 @@ func TestGetBuildDate(t *testing.T) {
 		t.Error("expected non-empty build date")
 	}
 }
+
+func TestRun_ACLTest(t *testing.T) {
+	aclFile := t.TempDir() + "/acl.yaml"
+	content := `defaultPolicy: "deny"
+rules:
+  - target: "*"
+    subject: "*"
+    rights: ["read"]
+    attributes: ["userPassword"]
+    deny: true
+  - target: "ou=users,dc=example,dc=com"
+    subject: "authenticated"
+    rights: ["read", "search"]
+`
+	if err := os.WriteFile(aclFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"allowed", []string{"-attr", "mail"}, 0},
+		{"denied by rule", []string{"-attr", "userPassword"}, 1},
+		{"denied by default", []string{"-op", "write"}, 1},
+		{"invalid operation", []string{"-op", "all"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"oba", "acl", "test", "-file", aclFile,
+				"-bind-dn", "uid=alice,ou=users,dc=example,dc=com",
+				"-target", "uid=bob,ou=users,dc=example,dc=com"}, tt.args...)
+			if exitCode := run(args); exitCode != tt.want {
+				t.Errorf("expected exit code %d, got %d", tt.want, exitCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Package main provides the migrate command for the oba LDAP server.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+// migrateCmd handles the migrate command.
+func migrateCmd(args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	configFile := fs.String("config", "", "Path to configuration file")
+	dataDir := fs.String("data-dir", "", "Data directory path (overrides config)")
+	dryRun := fs.Bool("dry-run", false, "Report the pending migrations without applying them")
+	help := fs.Bool("h", false, "Show help message")
+	helpLong := fs.Bool("help", false, "Show help message")
+
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	if *help || *helpLong {
+		printMigrateUsage(os.Stdout)
+		return 0
+	}
+
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Error: unexpected argument: %s\n", fs.Arg(0))
+		return 1
+	}
+
+	cfg := config.DefaultConfig()
+	if *configFile != "" {
+		var err error
+		cfg, err = config.LoadConfig(*configFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+			return 1
+		}
+	}
+	if *dataDir != "" {
+		cfg.Storage.DataDir = *dataDir
+	}
+
+	opts := storage.DefaultEngineOptions().
+		WithPageSize(cfg.Storage.PageSize).
+		WithCreateIfNotExists(false)
+	if cfg.Security.Encryption.Enabled && cfg.Security.Encryption.KeyFile != "" {
+		opts = opts.WithEncryptionKeyFile(cfg.Security.Encryption.KeyFile)
+	}
+
+	if *dryRun {
+		plan, err := engine.PlanMigrations(cfg.Storage.DataDir, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to plan migrations: %v\n", err)
+			return 1
+		}
+		if len(plan) == 0 {
+			fmt.Println("Database format is up to date")
+			return 0
+		}
+
+		fmt.Printf("%d pending migrations:\n", len(plan))
+		for _, p := range plan {
+			cost := "unknown size"
+			if p.Bytes >= 0 {
+				cost = fmt.Sprintf("~%d bytes", p.Bytes)
+			}
+			fmt.Printf("  %-16s %-8s %s", p.Migration.ID(), p.Migration.Mode, cost)
+			if p.Interrupted {
+				fmt.Print(", interrupted")
+			}
+			fmt.Println()
+			if p.Migration.Description != "" {
+				fmt.Printf("      %s\n", p.Migration.Description)
+			}
+		}
+		return 0
+	}
+
+	applied, err := engine.Migrate(cfg.Storage.DataDir, opts)
+	for _, m := range applied {
+		fmt.Printf("Applied %s (%s)\n", m.ID(), m.Mode)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: migration failed: %v\n", err)
+		return 1
+	}
+	if len(applied) == 0 {
+		fmt.Println("Database format is up to date")
+	}
+	return 0
+}
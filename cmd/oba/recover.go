@@ -0,0 +1,169 @@
+// Package main provides the recover command for the oba LDAP server.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+// recoverCmd handles the recover command.
+func recoverCmd(args []string) int {
+	fs := flag.NewFlagSet("recover", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	configFile := fs.String("config", "", "Path to configuration file")
+	dataDir := fs.String("data-dir", "", "Data directory path (overrides config)")
+	verify := fs.Bool("verify", false, "Check the integrity of the data and index files")
+	dumpWAL := fs.Bool("dump-wal", false, "Print each WAL record as JSON")
+	forceCheckpoint := fs.Bool("force-checkpoint", false, "Replay the WAL and write a clean checkpoint")
+	pruneFrom := fs.String("prune-pages", "", "Free a damaged page range (takes <from> <to>)")
+	rebuildIndex := fs.String("rebuild-index", "", "Drop and recreate the index for an attribute")
+	help := fs.Bool("h", false, "Show help message")
+	helpLong := fs.Bool("help", false, "Show help message")
+
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	// -prune-pages takes two values; the second one stops flag parsing, so
+	// consume it and parse the flags that follow.
+	var pruneTo string
+	if *pruneFrom != "" && fs.NArg() > 0 {
+		pruneTo = fs.Arg(0)
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return 1
+		}
+	}
+
+	if *help || *helpLong {
+		printRecoverUsage(os.Stdout)
+		return 0
+	}
+
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Error: unexpected argument: %s\n", fs.Arg(0))
+		return 1
+	}
+
+	var from, to uint64
+	if *pruneFrom != "" {
+		if pruneTo == "" {
+			fmt.Fprintln(os.Stderr, "Error: -prune-pages requires <from> <to>")
+			return 1
+		}
+		var err error
+		if from, err = strconv.ParseUint(*pruneFrom, 10, 64); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid page ID: %s\n", *pruneFrom)
+			return 1
+		}
+		if to, err = strconv.ParseUint(pruneTo, 10, 64); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid page ID: %s\n", pruneTo)
+			return 1
+		}
+	}
+
+	if !*verify && !*dumpWAL && !*forceCheckpoint && *pruneFrom == "" && *rebuildIndex == "" {
+		fmt.Fprintln(os.Stderr, "Error: no action selected (use -verify, -dump-wal, -force-checkpoint, -prune-pages or -rebuild-index)")
+		return 1
+	}
+
+	cfg := config.DefaultConfig()
+	if *configFile != "" {
+		var err error
+		cfg, err = config.LoadConfig(*configFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+			return 1
+		}
+	}
+	if *dataDir != "" {
+		cfg.Storage.DataDir = *dataDir
+	}
+
+	opts := storage.DefaultEngineOptions().
+		WithPageSize(cfg.Storage.PageSize).
+		WithCreateIfNotExists(false)
+	if cfg.Security.Encryption.Enabled && cfg.Security.Encryption.KeyFile != "" {
+		opts = opts.WithEncryptionKeyFile(cfg.Security.Encryption.KeyFile)
+	}
+
+	tool, err := engine.NewRecoveryTool(cfg.Storage.DataDir, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	// Verify and dump first so the output shows the state before any repair.
+	exitCode := 0
+	if *verify {
+		report, err := tool.Verify()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: verification failed: %v\n", err)
+			return 1
+		}
+		printVerificationReport(report)
+		if !report.OK() {
+			exitCode = 1
+		}
+	}
+
+	if *dumpWAL {
+		count, err := tool.DumpWAL(os.Stdout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to dump WAL: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Dumped %d WAL records\n", count)
+	}
+
+	if *forceCheckpoint {
+		if err := tool.ForceCheckpoint(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: checkpoint failed: %v\n", err)
+			return 1
+		}
+		fmt.Println("WAL replayed and checkpoint written")
+	}
+
+	if *pruneFrom != "" {
+		pruned, err := tool.PrunePages(storage.PageID(from), storage.PageID(to))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to prune pages %d-%d: %v\n", from, to, err)
+			return 1
+		}
+		fmt.Printf("Pruned %d pages in range %d-%d\n", pruned, from, to)
+	}
+
+	if *rebuildIndex != "" {
+		indexed, err := tool.RebuildIndex(*rebuildIndex)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to rebuild index %s: %v\n", *rebuildIndex, err)
+			return 1
+		}
+		fmt.Printf("Rebuilt index %s from %d entries\n", *rebuildIndex, indexed)
+	}
+
+	return exitCode
+}
+
+// printVerificationReport prints the findings of recover -verify.
+func printVerificationReport(report *storage.VerificationReport) {
+	fmt.Printf("Checked %d pages (%d free)\n", report.PagesChecked, report.FreePages)
+	for _, warning := range report.Warnings {
+		fmt.Printf("Warning: %s\n", warning)
+	}
+	if report.OK() {
+		fmt.Println("No problems found")
+		return
+	}
+
+	fmt.Printf("Found %d problem(s):\n", len(report.Errors))
+	for _, e := range report.Errors {
+		fmt.Printf("  %s\n", e)
+	}
+}
@@ -0,0 +1,169 @@
+// Package main provides the schema command for the oba LDAP server.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/schema"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+// schemaCmd handles the schema command.
+func schemaCmd(args []string) int {
+	if len(args) == 0 {
+		printSchemaUsage(os.Stdout)
+		return 0
+	}
+
+	// Check for help flags
+	if args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		printSchemaUsage(os.Stdout)
+		return 0
+	}
+
+	switch args[0] {
+	case "migrate":
+		return schemaMigrateCmd(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown schema subcommand: %s\n", args[0])
+		fmt.Fprintln(os.Stderr, "Run 'oba schema help' for usage.")
+		return 1
+	}
+}
+
+// defaultValues collects repeated -default attr=value flags.
+type defaultValues []string
+
+func (d *defaultValues) String() string {
+	return strings.Join(*d, ",")
+}
+
+func (d *defaultValues) Set(value string) error {
+	if !strings.Contains(value, "=") {
+		return fmt.Errorf("expected attr=value, got %q", value)
+	}
+	*d = append(*d, value)
+	return nil
+}
+
+// schemaMigrateCmd handles the schema migrate subcommand.
+func schemaMigrateCmd(args []string) int {
+	fs := flag.NewFlagSet("schema migrate", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	configFile := fs.String("config", "", "Path to configuration file")
+	dataDir := fs.String("data-dir", "", "Data directory path (overrides config)")
+	fromVersion := fs.Int("from-version", 0, "Schema version the data was written with")
+	toVersion := fs.Int("to-version", schema.CurrentVersion, "Schema version to migrate to")
+	fromSchema := fs.String("from-schema", "", "LDIF schema file to migrate from (overrides -from-version)")
+	toSchema := fs.String("to-schema", "", "LDIF schema file to migrate to (overrides -to-version)")
+	dryRun := fs.Bool("dry-run", false, "Print the migration plan without applying it")
+	var defaults defaultValues
+	fs.Var(&defaults, "default", "Value for a new MUST attribute, as attr=value (repeatable)")
+	help := fs.Bool("h", false, "Show help message")
+	helpLong := fs.Bool("help", false, "Show help message")
+
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	if *help || *helpLong {
+		printSchemaMigrateUsage(os.Stdout)
+		return 0
+	}
+
+	if *fromVersion == 0 && *fromSchema == "" {
+		fmt.Fprintln(os.Stderr, "Error: -from-version or -from-schema is required")
+		return 1
+	}
+
+	oldSchema, err := loadMigrationSchema(*fromSchema, *fromVersion)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to load source schema: %v\n", err)
+		return 1
+	}
+	newSchema, err := loadMigrationSchema(*toSchema, *toVersion)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to load target schema: %v\n", err)
+		return 1
+	}
+
+	plan, err := schema.NewMigrationPlan(oldSchema, newSchema)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	for _, d := range defaults {
+		parts := strings.SplitN(d, "=", 2)
+		plan.SetDefault(parts[0], parts[1])
+	}
+
+	if len(plan.Steps) == 0 {
+		fmt.Println("Schemas are identical, nothing to migrate")
+		return 0
+	}
+	fmt.Printf("Migration plan (%d steps):\n", len(plan.Steps))
+	for i, step := range plan.Steps {
+		fmt.Printf("  %d. %s\n", i+1, step)
+	}
+	if *dryRun {
+		return 0
+	}
+
+	cfg := config.DefaultConfig()
+	if *configFile != "" {
+		cfg, err = config.LoadConfig(*configFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+			return 1
+		}
+	}
+	if *dataDir != "" {
+		cfg.Storage.DataDir = *dataDir
+	}
+
+	opts := storage.DefaultEngineOptions().
+		WithPageSize(cfg.Storage.PageSize).
+		WithCreateIfNotExists(false)
+	if cfg.Security.Encryption.Enabled && cfg.Security.Encryption.KeyFile != "" {
+		opts = opts.WithEncryptionKeyFile(cfg.Security.Encryption.KeyFile)
+	}
+
+	db, err := engine.Open(cfg.Storage.DataDir, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to open database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	stats, err := plan.Apply(db)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: migration failed: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Scanned %d entries, updated %d\n", stats.ScannedEntries, stats.UpdatedEntries)
+	if len(stats.Violations) == 0 {
+		return 0
+	}
+
+	fmt.Printf("Found %d entries that could not be migrated:\n", len(stats.Violations))
+	for _, v := range stats.Violations {
+		fmt.Printf("  %s\n      %s\n", v.DN, v.Reason)
+	}
+	return 1
+}
+
+// loadMigrationSchema loads a schema from an LDIF file if path is set, and
+// from the shipped schema versions otherwise.
+func loadMigrationSchema(path string, version int) (*schema.Schema, error) {
+	if path != "" {
+		return schema.LoadSchema(path)
+	}
+	return schema.LoadVersion(version)
+}
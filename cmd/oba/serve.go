@@ -4,14 +4,17 @@ package main
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"flag"
 	"fmt"
 	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -21,12 +24,15 @@ import (
 	"github.com/KilimcininKorOglu/oba/internal/filter"
 	"github.com/KilimcininKorOglu/oba/internal/ldap"
 	"github.com/KilimcininKorOglu/oba/internal/logging"
+	"github.com/KilimcininKorOglu/oba/internal/metrics"
 	"github.com/KilimcininKorOglu/oba/internal/password"
 	"github.com/KilimcininKorOglu/oba/internal/raft"
 	"github.com/KilimcininKorOglu/oba/internal/rest"
 	"github.com/KilimcininKorOglu/oba/internal/server"
 	"github.com/KilimcininKorOglu/oba/internal/storage"
 	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+	"github.com/KilimcininKorOglu/oba/internal/systemd"
+	"github.com/KilimcininKorOglu/oba/internal/trace"
 )
 
 // Server errors.
@@ -36,6 +42,10 @@ var (
 	ErrListenerFailed       = errors.New("failed to create listener")
 )
 
+// jobCatalogFileName is the file in the data directory that holds the
+// admin job history.
+const jobCatalogFileName = "jobs.json"
+
 // raftLoggerAdapter adapts logging.Logger to raft.Logger interface.
 type raftLoggerAdapter struct {
 	logger logging.Logger
@@ -65,18 +75,31 @@ type LDAPServer struct {
 	logger                  logging.Logger
 	handler                 *server.Handler
 	backend                 *backend.ObaBackend
+	auditLog                *backend.AuditLogPlugin
+	tracerProvider          trace.TracerProvider
+	traceExporter           *trace.OTLPExporter
 	engine                  *engine.ObaDB
 	clusterBackend          *raft.ClusterBackend
 	listener                net.Listener
 	tlsListener             net.Listener
 	tlsConfig               *tls.Config
+	tlsCert                 *atomic.Pointer[tls.Certificate]
 	tlsCertFile             string
 	tlsKeyFile              string
 	persistentSearchHandler *server.PersistentSearchHandler
+	clientUpdateHandler     *server.ClientUpdateHandler
 	restServer              *rest.Server
+	metrics                 *server.Metrics
+	metricsServer           *server.MetricsServer
 	aclManager              *acl.Manager
 	aclWatcher              *acl.FileWatcher
+	bindThrottle            *server.BindThrottle
+	bindThrottleFile        string
+	wireDump                *server.WireDump
+	events                  server.EventHook
+	snmpEmitter             *logging.SNMPTrapEmitter
 	configWatcher           *config.ConfigWatcher
+	configTree              *configTree
 	pidFile                 string
 	running                 bool
 	mu                      sync.Mutex
@@ -84,6 +107,11 @@ type LDAPServer struct {
 	ctx                     context.Context
 	cancel                  context.CancelFunc
 
+	// connections tracks the open LDAP connections, limiting them to
+	// maxConnections and draining them at shutdown
+	connections *server.ConnectionTracker
+	idleTimeout time.Duration
+
 	// Hot-reloadable settings
 	maxConnections int
 	readTimeout    time.Duration
@@ -95,9 +123,10 @@ type LDAPServer struct {
 func NewServer(cfg *config.Config) (*LDAPServer, error) {
 	// Create logger
 	logger := logging.New(logging.Config{
-		Level:  cfg.Logging.Level,
-		Format: cfg.Logging.Format,
-		Output: cfg.Logging.Output,
+		Level:           cfg.Logging.Level,
+		Format:          cfg.Logging.Format,
+		Output:          cfg.Logging.Output,
+		ComponentLevels: cfg.Logging.ComponentLevels,
 	})
 
 	// Create log store if enabled (before creating sysLogger)
@@ -125,7 +154,7 @@ func NewServer(cfg *config.Config) (*LDAPServer, error) {
 	}
 
 	// System logger for non-audit operational logs (created AFTER store is set)
-	sysLogger := logger.WithSource("system")
+	sysLogger := logger.WithComponent("system")
 
 	// Log store status
 	if logger.GetStore() != nil {
@@ -155,17 +184,61 @@ func NewServer(cfg *config.Config) (*LDAPServer, error) {
 
 	// Create backend
 	be := backend.NewBackend(db, cfg)
+	be.SetLogger(sysLogger)
+	if cfg.Directory.UIDNumber.Enabled && cfg.Directory.BaseDN != "" {
+		counterDN := backend.UIDNumberCounterRDN + "," + cfg.Directory.BaseDN
+		be.RegisterPreAddHook(backend.UIDNumberHook(counterDN, cfg.Directory.UIDNumber.Start))
+		sysLogger.Info("uidNumber assignment enabled", "counter", counterDN, "start", cfg.Directory.UIDNumber.Start)
+	}
+	// Create tracer provider if telemetry is enabled. Without one, tracing
+	// is a no-op.
+	var tracerProvider trace.TracerProvider
+	var traceExporter *trace.OTLPExporter
+	if cfg.Telemetry.Enabled {
+		otlpCfg := trace.DefaultOTLPConfig(cfg.Telemetry.Endpoint)
+		otlpCfg.ServiceName = cfg.Telemetry.ServiceName
+		traceExporter, err = trace.NewOTLPExporter(otlpCfg)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+		}
+		tracerProvider = trace.NewProvider(traceExporter).
+			WithSampler(trace.ParentBased(trace.TraceIDRatio(cfg.Telemetry.SampleRatio)))
+		be.SetTracerProvider(tracerProvider)
+		sysLogger.Info("tracing enabled", "endpoint", cfg.Telemetry.Endpoint, "sample_ratio", cfg.Telemetry.SampleRatio)
+	}
 
-	// Create handler with backend integration
-	handler := server.NewHandler()
-	setupHandlers(handler, be, logger)
+	var auditLog *backend.AuditLogPlugin
+	if cfg.Directory.AuditLogPath != "" {
+		auditLog, err = backend.NewAuditLogPlugin(cfg.Directory.AuditLogPath)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+		be.RegisterPostCommitHook(auditLog.PostCommit)
+		sysLogger.Info("audit log enabled", "path", cfg.Directory.AuditLogPath)
+	}
 
-	// Create TLS config if certificates are provided
+	// Create TLS config if certificates are provided. The certificate is
+	// served per handshake from tlsCert, so ReloadTLSCert takes effect for
+	// new connections without restarting the listener.
 	var tlsConfig *tls.Config
+	tlsCert := new(atomic.Pointer[tls.Certificate])
 	if cfg.Server.TLSCert != "" && cfg.Server.TLSKey != "" {
-		tlsCfg := server.NewTLSConfig().WithCertFile(cfg.Server.TLSCert, cfg.Server.TLSKey)
-		var err error
-		tlsConfig, err = server.LoadTLSConfig(tlsCfg)
+		cert, err := loadTLSCert(cfg.Server.TLSCert, cfg.Server.TLSKey, cfg.Server.TLSKeyPassphrase)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to load TLS config: %w", err)
+		}
+		tlsCert.Store(cert)
+
+		tlsCfg := server.NewTLSConfig().WithGetCertificate(func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return tlsCert.Load(), nil
+		})
+		tlsCfg, err = applyTLSPolicy(tlsCfg, cfg.Security.TLS)
+		if err == nil {
+			tlsConfig, err = server.LoadTLSConfig(tlsCfg)
+		}
 		if err != nil {
 			db.Close()
 			return nil, fmt.Errorf("failed to load TLS config: %w", err)
@@ -176,6 +249,16 @@ func NewServer(cfg *config.Config) (*LDAPServer, error) {
 
 	// Create persistent search handler
 	psHandler := server.NewPersistentSearchHandler(be)
+	psc := cfg.Server.PersistentSearch
+	psHandler.SetLimits(server.PersistentSearchLimits{
+		QueueSize:        psc.QueueSize,
+		OverflowPolicy:   server.OverflowPolicy(psc.OverflowPolicy),
+		MaxPerConnection: psc.MaxPerConnection,
+		MaxPerBindDN:     psc.MaxPerBindDN,
+	})
+
+	// Create client update handler
+	cuHandler := server.NewClientUpdateHandler(be)
 
 	// Create ACL manager and watcher
 	var aclManager *acl.Manager
@@ -207,8 +290,12 @@ func NewServer(cfg *config.Config) (*LDAPServer, error) {
 		}
 	} else if len(cfg.ACL.Rules) > 0 {
 		// Load ACL from embedded config (no hot reload)
-		embeddedConfig := convertACLConfig(&cfg.ACL)
-		var err error
+		embeddedConfig, err := convertACLConfig(&cfg.ACL)
+		if err != nil {
+			db.Close()
+			cancel()
+			return nil, fmt.Errorf("invalid ACL config: %w", err)
+		}
 		aclManager, err = acl.NewManager(&acl.ManagerConfig{
 			EmbeddedConfig: embeddedConfig,
 			Logger:         sysLogger,
@@ -221,22 +308,111 @@ func NewServer(cfg *config.Config) (*LDAPServer, error) {
 		sysLogger.Info("ACL loaded from config", "rules", len(cfg.ACL.Rules))
 	}
 
+	// Resolve group: subjects against the directory, including dynamic groups
+	if aclManager != nil {
+		aclManager.SetGroupResolver(be)
+	}
+
+	// Expose runtime configuration under cn=config
+	tree := &configTree{
+		rootDN:     cfg.Directory.RootDN,
+		static:     cfg,
+		aclManager: aclManager,
+		indexes:    db.ListIndexes,
+	}
+
+	// Create handler with backend integration
+	handler := server.NewHandler()
+	setupHandlers(handler, be, aclManager, tree, logger)
+
+	// Cap the limits of searches by the profile of their bind DN
+	handler.SetSearchLimits(server.NewSearchLimits(searchLimitProfiles(cfg.Server.Limits), be))
+
+	// Throttle failed binds per source IP if enabled
+	var bindThrottle *server.BindThrottle
+	if cfg.Security.BindThrottle.Enabled {
+		bindThrottle, err = newBindThrottle(&cfg.Security.BindThrottle)
+		if err != nil {
+			db.Close()
+			cancel()
+			return nil, fmt.Errorf("failed to create bind throttle: %w", err)
+		}
+		handler.SetBindThrottle(bindThrottle)
+		sysLogger.Info("bind throttling enabled",
+			"max_failures", cfg.Security.BindThrottle.MaxFailures,
+			"window", cfg.Security.BindThrottle.Window.String())
+	}
+
+	// Prepare the wire dump if a directory is configured, so admins can
+	// enable it at runtime
+	var wireDump *server.WireDump
+	if wd := cfg.Logging.WireDump; wd.Directory != "" {
+		wireDump, err = server.NewWireDump(&server.WireDumpConfig{
+			Directory:        wd.Directory,
+			MaxBytes:         wd.MaxBytes,
+			ConnectionFilter: wd.ConnectionFilter,
+			Duration:         wd.Duration,
+			RawBER:           wd.RawBER,
+			IncludePasswords: wd.IncludePasswords,
+		})
+		if err == nil && wd.Enabled {
+			err = wireDump.Enable(wd.ConnectionFilter, wd.Duration, wd.RawBER)
+		}
+		if err != nil {
+			db.Close()
+			cancel()
+			return nil, fmt.Errorf("failed to create wire dump: %w", err)
+		}
+		if wd.Enabled {
+			sysLogger.Warn("LDAP wire dump enabled",
+				"directory", wd.Directory,
+				"duration", wd.Duration.String(),
+				"include_passwords", wd.IncludePasswords)
+		}
+	}
+
+	// Limit how much of the server one client can hold if configured
+	if fc := cfg.Server.Fairness; fc.MaxBufferedEntries > 0 || fc.MaxExpensiveSearches > 0 || fc.MaxOperationsPerBindDN > 0 {
+		handler.SetFairness(server.NewFairness(server.FairnessConfig{
+			MaxBufferedEntries:       fc.MaxBufferedEntries,
+			MaxExpensiveSearches:     fc.MaxExpensiveSearches,
+			ExpensiveSearchThreshold: fc.ExpensiveSearchThreshold,
+			MaxOperationsPerBindDN:   fc.MaxOperationsPerBindDN,
+		}, searchEstimator(be, tree)))
+		sysLogger.Info("connection fairness limits enabled",
+			"max_buffered_entries", fc.MaxBufferedEntries,
+			"max_expensive_searches", fc.MaxExpensiveSearches,
+			"expensive_search_threshold", fc.ExpensiveSearchThreshold,
+			"max_operations_per_bind_dn", fc.MaxOperationsPerBindDN)
+	}
+
 	// Create REST server if enabled
 	var restServer *rest.Server
 	if cfg.REST.Enabled {
 		restCfg := &rest.ServerConfig{
-			Address:      cfg.REST.Address,
-			TLSAddress:   cfg.REST.TLSAddress,
-			TLSCert:      cfg.Server.TLSCert,
-			TLSKey:       cfg.Server.TLSKey,
-			JWTSecret:    cfg.REST.JWTSecret,
-			TokenTTL:     cfg.REST.TokenTTL,
-			RateLimit:    cfg.REST.RateLimit,
-			CORSOrigins:  cfg.REST.CORSOrigins,
-			AdminDNs:     []string{cfg.Directory.RootDN},
-			ReadTimeout:  30 * time.Second,
-			WriteTimeout: 30 * time.Second,
-			IdleTimeout:  120 * time.Second,
+			Address:             cfg.REST.Address,
+			TLSAddress:          cfg.REST.TLSAddress,
+			TLSCert:             cfg.Server.TLSCert,
+			TLSKey:              cfg.Server.TLSKey,
+			TLSKeyPassphrase:    cfg.Server.TLSKeyPassphrase,
+			JWTSecret:           cfg.REST.JWTSecret,
+			TokenTTL:            cfg.REST.TokenTTL,
+			CursorTTL:           cfg.REST.CursorTTL,
+			RateLimit:           cfg.REST.RateLimit,
+			CORSOrigins:         cfg.REST.CORSOrigins,
+			CORSAllowedMethods:  cfg.REST.CORSAllowedMethods,
+			CORSAllowedHeaders:  cfg.REST.CORSAllowedHeaders,
+			CORSMaxAge:          cfg.REST.CORSMaxAge,
+			ExternalJWTIssuer:   cfg.REST.ExternalJWTIssuer,
+			ExternalJWTAudience: cfg.REST.ExternalJWTAudience,
+			JWKSUri:             cfg.REST.JWKSUri,
+			ClaimTemplate:       cfg.REST.ClaimTemplate,
+			TrustedProxies:      cfg.Server.TrustedProxies,
+			AdminDNs:            []string{cfg.Directory.RootDN},
+			ReadTimeout:         30 * time.Second,
+			WriteTimeout:        30 * time.Second,
+			IdleTimeout:         120 * time.Second,
+			TracerProvider:      tracerProvider,
 		}
 		restServer = rest.NewServer(restCfg, be, logger)
 
@@ -248,6 +424,24 @@ func NewServer(cfg *config.Config) (*LDAPServer, error) {
 		// Set logger for log endpoints
 		restServer.SetLogger(logger)
 
+		// Expose bind throttle state to admins
+		if bindThrottle != nil {
+			restServer.SetBindThrottle(bindThrottle)
+		}
+		if wireDump != nil {
+			restServer.SetWireDump(wireDump)
+		}
+
+		// Keep the admin job history next to the data
+		catalogFile := filepath.Join(cfg.Storage.DataDir, jobCatalogFileName)
+		jobManager, err := server.NewJobManager(catalogFile)
+		if err != nil {
+			sysLogger.Warn("failed to load job history, starting with none", "file", catalogFile, "error", err)
+			jobManager, _ = server.NewJobManager("")
+		}
+		jobManager.SetLogger(logger.WithComponent("jobs"))
+		restServer.SetJobManager(jobManager)
+
 		sysLogger.Info("REST API enabled", "address", cfg.REST.Address)
 	}
 
@@ -279,20 +473,28 @@ func NewServer(cfg *config.Config) (*LDAPServer, error) {
 		// Set logger for Raft debugging - use a separate logger without LogStore
 		// to avoid deadlock (Raft logging -> LogStore.Write -> Propose -> waits for runLeader)
 		raftLogger := logging.New(logging.Config{
-			Level:  cfg.Logging.Level,
-			Format: cfg.Logging.Format,
-			Output: "stdout",
+			Level:           cfg.Logging.Level,
+			Format:          cfg.Logging.Format,
+			Output:          "stdout",
+			ComponentLevels: cfg.Logging.ComponentLevels,
 		})
-		clusterBackend.SetLogger(&raftLoggerAdapter{logger: raftLogger.WithSource("raft")})
+		clusterBackend.SetLogger(&raftLoggerAdapter{logger: raftLogger.WithComponent("raft")})
 
 		// Set cluster backend on REST server
 		if restServer != nil {
 			restServer.SetClusterBackend(clusterBackend)
 		}
+		tree.clusterBackend = clusterBackend
 
 		// Set cluster writer on backend for cluster-aware writes
 		be.SetClusterWriter(clusterBackend)
 
+		// Route searches according to the cluster read preference
+		be.SetClusterReader(clusterBackend)
+
+		// Serialize index builds and schema changes across the cluster
+		be.SetLocker(clusterBackend.Lock(), fmt.Sprintf("node%d", cfg.Cluster.NodeID))
+
 		// Set cluster writer on log store for log replication
 		// Note: Raft's own logs (source="raft") are excluded to prevent infinite loop
 		if logStore := logger.GetStore(); logStore != nil {
@@ -307,20 +509,75 @@ func NewServer(cfg *config.Config) (*LDAPServer, error) {
 		sysLogger.Info("cluster backend created", "peers", len(cfg.Cluster.Peers))
 	}
 
+	// Create standalone Prometheus endpoint if configured
+	var ldapMetrics *server.Metrics
+	var metricsServer *server.MetricsServer
+	if cfg.Monitoring.PrometheusAddr != "" {
+		registry := metrics.NewRegistry()
+		ldapMetrics = server.NewMetrics()
+		if err := ldapMetrics.Register(registry); err != nil {
+			db.Close()
+			cancel()
+			return nil, fmt.Errorf("failed to register metrics: %w", err)
+		}
+		if err := registry.Register(engine.NewStatsCollector(db)); err != nil {
+			db.Close()
+			cancel()
+			return nil, fmt.Errorf("failed to register metrics: %w", err)
+		}
+		if err := registry.Register(server.NewPersistentSearchCollector(psHandler)); err != nil {
+			db.Close()
+			cancel()
+			return nil, fmt.Errorf("failed to register metrics: %w", err)
+		}
+		metricsServer = server.NewMetricsServer(cfg.Monitoring.PrometheusAddr, registry)
+		sysLogger.Info("Prometheus metrics enabled", "address", cfg.Monitoring.PrometheusAddr)
+	}
+
+	// Send server events as SNMP traps if configured
+	var events server.EventHook
+	var snmpEmitter *logging.SNMPTrapEmitter
+	if snmp := cfg.Monitoring.SNMPTrap; snmp.Enabled {
+		version, err := logging.ParseSnmpVersion(snmp.Version)
+		if err != nil {
+			db.Close()
+			cancel()
+			return nil, err
+		}
+		snmpEmitter = logging.NewSNMPTrapEmitter(snmp.Target, snmp.Community, version)
+		events = snmpEventHook(snmpEmitter, logger.WithComponent("system"))
+		sysLogger.Info("SNMP traps enabled", "target", snmpEmitter.Target(), "version", version.String())
+	}
+
 	return &LDAPServer{
 		config:                  cfg,
 		logger:                  logger,
 		handler:                 handler,
 		backend:                 be,
+		auditLog:                auditLog,
+		tracerProvider:          tracerProvider,
+		traceExporter:           traceExporter,
 		engine:                  db,
 		clusterBackend:          clusterBackend,
 		tlsConfig:               tlsConfig,
+		tlsCert:                 tlsCert,
 		tlsCertFile:             cfg.Server.TLSCert,
 		tlsKeyFile:              cfg.Server.TLSKey,
 		persistentSearchHandler: psHandler,
+		clientUpdateHandler:     cuHandler,
 		restServer:              restServer,
+		metrics:                 ldapMetrics,
+		metricsServer:           metricsServer,
 		aclManager:              aclManager,
 		aclWatcher:              aclWatcher,
+		bindThrottle:            bindThrottle,
+		bindThrottleFile:        cfg.Security.BindThrottle.StateFile,
+		wireDump:                wireDump,
+		events:                  events,
+		snmpEmitter:             snmpEmitter,
+		configTree:              tree,
+		connections:             server.NewConnectionTracker(cfg.Server.MaxConnections),
+		idleTimeout:             cfg.Server.IdleTimeout,
 		maxConnections:          cfg.Server.MaxConnections,
 		readTimeout:             cfg.Server.ReadTimeout,
 		writeTimeout:            cfg.Server.WriteTimeout,
@@ -329,26 +586,84 @@ func NewServer(cfg *config.Config) (*LDAPServer, error) {
 	}, nil
 }
 
+// newBindThrottle creates a bind throttle from configuration and restores
+// persisted state if a state file is configured.
+func newBindThrottle(cfg *config.BindThrottleConfig) (*server.BindThrottle, error) {
+	t, err := server.NewBindThrottle(&server.BindThrottleConfig{
+		MaxFailures: cfg.MaxFailures,
+		Window:      cfg.Window,
+		BaseDelay:   cfg.BaseDelay,
+		MaxDelay:    cfg.MaxDelay,
+		Allowlist:   cfg.Allowlist,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.StateFile != "" {
+		if err := t.Load(cfg.StateFile); err != nil {
+			return nil, fmt.Errorf("failed to load state: %w", err)
+		}
+	}
+	return t, nil
+}
+
+// searchEstimator sizes searches from the backend's candidate sets. Searches
+// of the cn=config subtree are never expensive.
+func searchEstimator(be backend.Backend, tree *configTree) server.SearchEstimator {
+	return func(req *ldap.SearchRequest) int {
+		if tree != nil && tree.contains(req.BaseObject) {
+			return 0
+		}
+		var f *filter.Filter
+		if req.Filter != nil {
+			f = convertSearchFilter(req.Filter)
+		}
+		return be.EstimateSearch(context.Background(), req.BaseObject, int(req.Scope), f)
+	}
+}
+
 // setupHandlers configures the LDAP operation handlers with backend integration.
-func setupHandlers(h *server.Handler, be backend.Backend, logger logging.Logger) {
+// If aclManager is not nil, it masks unreadable attributes in search results
+// and is consulted for compares and subtree deletes. Operations on the cn=config subtree
+// are served by tree.
+func setupHandlers(h *server.Handler, be backend.Backend, aclManager *acl.Manager, tree *configTree, logger logging.Logger) {
+	// Attribute-level read access is enforced on all search results
+	if aclManager != nil {
+		h.SetAttributeACL(aclManager)
+	}
+
+	// Collective attributes are merged into search results after masking
+	h.SetCollectiveAttributes(be)
+
+	// Search time limits are capped like those of the search handler
+	h.SetMaxTimeLimit(server.NewSearchConfig().MaxTimeLimit)
+
+	// Extended operations
+	extended := server.NewExtendedDispatcher()
+	extended.Register(server.NewCancelHandler())
+	h.SetExtendedDispatcher(extended)
+
 	// Bind handler
 	h.SetBindHandler(func(conn *server.Connection, req *ldap.BindRequest) *server.OperationResult {
 		if req.IsAnonymous() {
 			return &server.OperationResult{ResultCode: ldap.ResultSuccess}
 		}
 
+		ctx := conn.Context()
+
 		// Check if account is locked
-		if be.IsAccountLocked(req.Name) {
+		if be.IsAccountLocked(ctx, req.Name) {
 			return &server.OperationResult{
 				ResultCode:        ldap.ResultInvalidCredentials,
 				DiagnosticMessage: "account is locked due to too many failed attempts",
 			}
 		}
 
-		err := be.Bind(req.Name, string(req.SimplePassword))
+		err := be.Bind(ctx, req.Name, string(req.SimplePassword))
 		if err != nil {
 			// Record failed attempt
-			be.RecordAuthFailure(req.Name)
+			be.RecordAuthFailure(ctx, req.Name)
 			return &server.OperationResult{
 				ResultCode:        ldap.ResultInvalidCredentials,
 				DiagnosticMessage: "invalid credentials",
@@ -356,20 +671,42 @@ func setupHandlers(h *server.Handler, be backend.Backend, logger logging.Logger)
 		}
 
 		// Record successful authentication
-		be.RecordAuthSuccess(req.Name)
+		be.RecordAuthSuccess(ctx, req.Name)
 		return &server.OperationResult{ResultCode: ldap.ResultSuccess}
 	})
 
 	// Search handler
 	h.SetSearchHandler(func(conn *server.Connection, req *ldap.SearchRequest) *server.SearchResult {
+		if tree != nil && tree.contains(req.BaseObject) {
+			return tree.search(conn.BindDN(), req)
+		}
+
 		// Convert LDAP filter to backend filter
 		var f *filter.Filter
 		if req.Filter != nil {
 			f = convertSearchFilter(req.Filter)
 		}
 
-		entries, err := be.Search(req.BaseObject, int(req.Scope), f)
-		if err != nil {
+		// An Assertion control is checked against the base entry
+		ctx, result := assertionContext(requestContext(conn), req.Controls)
+		if result == nil {
+			result = assertEntry(ctx, be, req.BaseObject)
+		}
+		if result != nil {
+			return &server.SearchResult{OperationResult: *result}
+		}
+
+		search := be.Search
+		if server.FindShowDeletedControl(req.Controls) != nil {
+			search = be.SearchWithDeleted
+		}
+
+		// An expired time limit returns the entries found so far
+		resultCode := ldap.ResultSuccess
+		entries, err := search(ctx, req.BaseObject, int(req.Scope), f)
+		if errors.Is(err, context.DeadlineExceeded) {
+			resultCode = ldap.ResultTimeLimitExceeded
+		} else if err != nil {
 			return &server.SearchResult{
 				OperationResult: server.OperationResult{
 					ResultCode:        ldap.ResultOperationsError,
@@ -377,45 +714,41 @@ func setupHandlers(h *server.Handler, be backend.Backend, logger logging.Logger)
 				},
 			}
 		}
+		entries = be.ExpandDynamicGroups(ctx, entries)
+		entries = be.VirtualAttributes(ctx, entries, req.Attributes)
 
-		// Convert backend entries to server entries
+		// Convert backend entries to server entries, selecting attributes
+		// by any of their schema names
+		attrs := server.ExpandAttributeAliases(req.Attributes, be.Schema())
 		serverEntries := make([]*server.SearchEntry, len(entries))
 		for i, entry := range entries {
-			serverEntries[i] = &server.SearchEntry{
-				DN:         entry.DN,
-				Attributes: convertAttributes(entry),
-			}
+			serverEntries[i] = convertSearchEntry(entry, attrs, req.TypesOnly)
 		}
 
 		return &server.SearchResult{
-			OperationResult: server.OperationResult{ResultCode: ldap.ResultSuccess},
+			OperationResult: server.OperationResult{ResultCode: resultCode},
 			Entries:         serverEntries,
 		}
 	})
 
 	// Add handler
 	h.SetAddHandler(func(conn *server.Connection, req *ldap.AddRequest) *server.OperationResult {
+		if tree != nil && tree.contains(req.Entry) {
+			return unwillingToPerform("entries cannot be added under cn=config")
+		}
+
 		entry := backend.NewEntry(req.Entry)
 		for _, attr := range req.Attributes {
-			values := make([]string, len(attr.Values))
-			for i, v := range attr.Values {
-				values[i] = string(v)
-			}
-			entry.SetAttribute(attr.Type, values...)
+			entry.SetByteValues(attr.Type, attr.Values)
 		}
 
-		err := be.AddWithBindDN(entry, conn.BindDN())
-		if err != nil {
-			if err == backend.ErrEntryExists {
-				return &server.OperationResult{
-					ResultCode:        ldap.ResultEntryAlreadyExists,
-					DiagnosticMessage: "entry already exists",
-				}
-			}
-			return &server.OperationResult{
-				ResultCode:        ldap.ResultOperationsError,
-				DiagnosticMessage: err.Error(),
-			}
+		ctx, result := assertionContext(requestContext(conn), req.Controls)
+		if result != nil {
+			return result
+		}
+
+		if err := be.Add(ctx, entry); err != nil {
+			return operationFailure(err)
 		}
 
 		return &server.OperationResult{ResultCode: ldap.ResultSuccess}
@@ -423,8 +756,25 @@ func setupHandlers(h *server.Handler, be backend.Backend, logger logging.Logger)
 
 	// Delete handler
 	h.SetDeleteHandler(func(conn *server.Connection, req *ldap.DeleteRequest) *server.OperationResult {
+		if tree != nil && tree.contains(req.DN) {
+			return unwillingToPerform("entries under cn=config cannot be deleted")
+		}
+
+		ctx, result := assertionContext(requestContext(conn), req.Controls)
+		if result != nil {
+			return result
+		}
+
+		// Tree Delete control: delete the entry and all of its descendants
+		if server.FindTreeDeleteControl(req.Controls) != nil {
+			if result := assertEntry(ctx, be, req.DN); result != nil {
+				return result
+			}
+			return deleteSubtree(ctx, conn, be, aclManager, req.DN)
+		}
+
 		// Check for children
-		hasChildren, err := be.HasChildren(req.DN)
+		hasChildren, err := be.HasChildren(ctx, req.DN)
 		if err != nil {
 			return &server.OperationResult{
 				ResultCode:        ldap.ResultOperationsError,
@@ -438,18 +788,8 @@ func setupHandlers(h *server.Handler, be backend.Backend, logger logging.Logger)
 			}
 		}
 
-		err = be.Delete(req.DN)
-		if err != nil {
-			if err == backend.ErrEntryNotFound {
-				return &server.OperationResult{
-					ResultCode:        ldap.ResultNoSuchObject,
-					DiagnosticMessage: "entry not found",
-				}
-			}
-			return &server.OperationResult{
-				ResultCode:        ldap.ResultOperationsError,
-				DiagnosticMessage: err.Error(),
-			}
+		if err := be.Delete(ctx, req.DN); err != nil {
+			return operationFailure(err)
 		}
 
 		return &server.OperationResult{ResultCode: ldap.ResultSuccess}
@@ -457,6 +797,10 @@ func setupHandlers(h *server.Handler, be backend.Backend, logger logging.Logger)
 
 	// Modify handler
 	h.SetModifyHandler(func(conn *server.Connection, req *ldap.ModifyRequest) *server.OperationResult {
+		if tree != nil && tree.contains(req.Object) {
+			return tree.modify(conn.BindDN(), conn.Logger(), req)
+		}
+
 		changes := make([]backend.Modification, len(req.Changes))
 		for i, change := range req.Changes {
 			values := make([]string, len(change.Attribute.Values))
@@ -470,22 +814,185 @@ func setupHandlers(h *server.Handler, be backend.Backend, logger logging.Logger)
 			}
 		}
 
-		err := be.ModifyWithBindDN(req.Object, changes, conn.BindDN())
-		if err != nil {
-			if err == backend.ErrEntryNotFound {
-				return &server.OperationResult{
-					ResultCode:        ldap.ResultNoSuchObject,
-					DiagnosticMessage: "entry not found",
-				}
-			}
+		ctx, result := assertionContext(requestContext(conn), req.Controls)
+		if result != nil {
+			return result
+		}
+
+		if err := be.Modify(ctx, req.Object, changes); err != nil {
+			return operationFailure(err)
+		}
+
+		return &server.OperationResult{ResultCode: ldap.ResultSuccess}
+	})
+
+	// ModifyDN handler
+	h.SetModifyDNHandler(func(conn *server.Connection, req *ldap.ModifyDNRequest) *server.OperationResult {
+		if err := req.Validate(); err != nil {
 			return &server.OperationResult{
-				ResultCode:        ldap.ResultOperationsError,
+				ResultCode:        ldap.ResultProtocolError,
 				DiagnosticMessage: err.Error(),
 			}
 		}
+		if tree != nil && (tree.contains(req.Entry) || tree.contains(req.NewSuperior)) {
+			return unwillingToPerform("entries under cn=config cannot be renamed")
+		}
+
+		ctx, result := assertionContext(requestContext(conn), req.Controls)
+		if result != nil {
+			return result
+		}
+
+		err := be.ModifyDN(ctx, &backend.ModifyDNRequest{
+			DN:           req.Entry,
+			NewRDN:       req.NewRDN,
+			DeleteOldRDN: req.DeleteOldRDN,
+			NewSuperior:  req.NewSuperior,
+		})
+		if err != nil {
+			return operationFailure(err)
+		}
 
 		return &server.OperationResult{ResultCode: ldap.ResultSuccess}
 	})
+
+	// Compare handler
+	h.SetCompareHandler(func(conn *server.Connection, req *ldap.CompareRequest) *server.OperationResult {
+		if err := req.Validate(); err != nil {
+			return &server.OperationResult{
+				ResultCode:        ldap.ResultProtocolError,
+				DiagnosticMessage: err.Error(),
+			}
+		}
+
+		if aclManager != nil && !aclManager.CheckAccess(conn.AccessContext(req.DN, acl.Compare).WithAttributes(req.Attribute)) {
+			return &server.OperationResult{
+				ResultCode:        ldap.ResultInsufficientAccessRights,
+				DiagnosticMessage: "insufficient access rights",
+			}
+		}
+
+		ctx, result := assertionContext(requestContext(conn), req.Controls)
+		if result == nil {
+			result = assertEntry(ctx, be, req.DN)
+		}
+		if result != nil {
+			return result
+		}
+
+		match, err := be.CompareWithIndex(ctx, req.DN, req.Attribute, req.Value)
+		if err != nil {
+			return operationFailure(err)
+		}
+
+		if match {
+			return &server.OperationResult{ResultCode: ldap.ResultCompareTrue}
+		}
+		return &server.OperationResult{ResultCode: ldap.ResultCompareFalse}
+	})
+}
+
+// requestContext returns the context of the operation being handled on
+// conn, carrying the bind DN, request ID and client address for the
+// backend.
+func requestContext(conn *server.Connection) context.Context {
+	return backend.ContextWithOperation(conn.Context(), backend.OperationInfo{
+		BindDN:     conn.BindDN(),
+		RequestID:  conn.RequestID(),
+		ClientAddr: server.ClientIP(conn.RemoteAddr()),
+	})
+}
+
+// assertionContext returns ctx carrying the filter of an Assertion control
+// in controls, if there is one. A malformed control gives a protocolError
+// result.
+func assertionContext(ctx context.Context, controls []ldap.Control) (context.Context, *server.OperationResult) {
+	f, err := server.FindAssertionControl(controls)
+	if err != nil {
+		return nil, &server.OperationResult{
+			ResultCode:        ldap.ResultProtocolError,
+			DiagnosticMessage: "invalid assertion control",
+		}
+	}
+	if f == nil {
+		return ctx, nil
+	}
+	return backend.ContextWithAssertion(ctx, &backend.Assertion{Filter: convertSearchFilter(f)}), nil
+}
+
+// assertEntry checks the assertion carried by ctx against the entry dn,
+// for operations that do not write it. It returns nil if the assertion
+// holds or there is none.
+func assertEntry(ctx context.Context, be backend.Backend, dn string) *server.OperationResult {
+	if err := be.Assert(ctx, dn); err != nil {
+		return operationFailure(err)
+	}
+	return nil
+}
+
+// operationFailure returns the result for a backend operation that failed
+// with err. Hook rejections and backend errors carry their own result
+// code; anything else is an operationsError.
+func operationFailure(err error) *server.OperationResult {
+	if result := hookRejection(err); result != nil {
+		return result
+	}
+	var ldapErr *backend.LDAPError
+	if !errors.As(err, &ldapErr) {
+		return &server.OperationResult{
+			ResultCode:        ldap.ResultOperationsError,
+			DiagnosticMessage: err.Error(),
+		}
+	}
+	// A wrapped error keeps the detail added around the backend error
+	message := ldapErr.Message
+	if err != ldapErr {
+		message = err.Error()
+	}
+	return &server.OperationResult{
+		ResultCode:        ldapErr.ResultCode,
+		MatchedDN:         ldapErr.MatchedDN,
+		DiagnosticMessage: message,
+	}
+}
+
+// hookRejection returns the result for an operation rejected by a backend
+// hook, or nil if err is not a hook rejection.
+func hookRejection(err error) *server.OperationResult {
+	var hookErr *backend.HookError
+	if !errors.As(err, &hookErr) {
+		return nil
+	}
+	return &server.OperationResult{
+		ResultCode:        hookErr.ResultCode,
+		DiagnosticMessage: hookErr.Message,
+	}
+}
+
+// deleteSubtree handles a Delete request carrying the Tree Delete control.
+// The bind DN needs delete rights on every entry of the subtree.
+func deleteSubtree(ctx context.Context, conn *server.Connection, be backend.Backend, aclManager *acl.Manager, dn string) *server.OperationResult {
+	bindDN := conn.BindDN()
+
+	var allow func(string) bool
+	if aclManager != nil {
+		allow = func(entryDN string) bool {
+			return aclManager.CheckAccess(conn.AccessContext(entryDN, acl.Delete))
+		}
+	}
+
+	deleted, err := be.DeleteSubtree(ctx, dn, allow)
+	if len(deleted) > 0 {
+		conn.Logger().Info("subtree deleted",
+			"dn", dn,
+			"bind_dn", bindDN,
+			"entries", len(deleted))
+	}
+	if err != nil {
+		return operationFailure(err)
+	}
+
+	return &server.OperationResult{ResultCode: ldap.ResultSuccess}
 }
 
 // convertSearchFilter converts an LDAP search filter to a backend filter.
@@ -523,19 +1030,25 @@ func convertSearchFilter(sf *ldap.SearchFilter) *filter.Filter {
 // convertAttributes converts backend entry attributes to LDAP attributes.
 func convertAttributes(entry *backend.Entry) []ldap.Attribute {
 	attrs := make([]ldap.Attribute, 0, len(entry.Attributes))
-	for name, values := range entry.Attributes {
-		byteValues := make([][]byte, len(values))
-		for i, v := range values {
-			byteValues[i] = []byte(v)
-		}
+	for name := range entry.Attributes {
 		attrs = append(attrs, ldap.Attribute{
 			Type:   name,
-			Values: byteValues,
+			Values: entry.ByteValues(name),
 		})
 	}
 	return attrs
 }
 
+// convertSearchEntry converts a backend entry to a search result entry
+// holding the attributes selected by the request.
+func convertSearchEntry(entry *backend.Entry, attrs []string, typesOnly bool) *server.SearchEntry {
+	storageEntry := storage.NewEntry(entry.DN)
+	for name := range entry.Attributes {
+		storageEntry.SetAttribute(name, entry.ByteValues(name))
+	}
+	return server.BuildSearchEntry(storageEntry, attrs, typesOnly)
+}
+
 // Start starts the LDAP server.
 func (s *LDAPServer) Start() error {
 	s.mu.Lock()
@@ -551,21 +1064,33 @@ func (s *LDAPServer) Start() error {
 		if err := s.clusterBackend.Start(); err != nil {
 			return fmt.Errorf("failed to start cluster backend: %w", err)
 		}
-		s.logger.WithSource("system").Info("cluster backend started",
+		s.logger.WithComponent("system").Info("cluster backend started",
 			"nodeID", s.config.Cluster.NodeID,
 			"raftAddr", s.config.Cluster.RaftAddr)
 	}
 
+	// Sockets passed by systemd socket activation are used instead of
+	// binding the configured addresses
+	activated, err := systemd.ActivatedListeners()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrListenerFailed, err)
+	}
+	defer activated.Close()
+	if activated.Len() > 0 {
+		s.logger.WithComponent("system").Info("socket activated", "sockets", activated.Len())
+	}
+
 	// Start plain LDAP listener
 	if s.config.Server.Address != "" {
-		listener, err := net.Listen("tcp", s.config.Server.Address)
+		listener, err := s.listen(activated, "ldap", s.config.Server.Address)
 		if err != nil {
 			return fmt.Errorf("%w: %v", ErrListenerFailed, err)
 		}
 		s.mu.Lock()
 		s.listener = listener
 		s.mu.Unlock()
-		s.logger.WithSource("system").Info("LDAP server listening", "address", s.config.Server.Address)
+		s.logger.WithComponent("system").Info("LDAP server listening", "address", listener.Addr().String(),
+			"proxy_protocol", s.config.Server.ProxyProtocol)
 
 		s.wg.Add(1)
 		go s.acceptConnections(listener, false)
@@ -573,7 +1098,7 @@ func (s *LDAPServer) Start() error {
 
 	// Start TLS listener if configured
 	if s.config.Server.TLSAddress != "" && s.tlsConfig != nil {
-		listener, err := tls.Listen("tcp", s.config.Server.TLSAddress, s.tlsConfig)
+		listener, err := s.listen(activated, "ldaps", s.config.Server.TLSAddress)
 		if err != nil {
 			// Close plain listener if TLS fails
 			s.mu.Lock()
@@ -583,10 +1108,12 @@ func (s *LDAPServer) Start() error {
 			s.mu.Unlock()
 			return fmt.Errorf("%w: %v", ErrListenerFailed, err)
 		}
+		listener = tls.NewListener(listener, s.tlsConfig)
 		s.mu.Lock()
 		s.tlsListener = listener
 		s.mu.Unlock()
-		s.logger.WithSource("system").Info("LDAPS server listening", "address", s.config.Server.TLSAddress)
+		s.logger.WithComponent("system").Info("LDAPS server listening", "address", listener.Addr().String(),
+			"proxy_protocol", s.config.Server.ProxyProtocol)
 
 		s.wg.Add(1)
 		go s.acceptConnections(listener, true)
@@ -594,6 +1121,11 @@ func (s *LDAPServer) Start() error {
 
 	// Start REST server if enabled
 	if s.restServer != nil {
+		var restTLS net.Listener
+		if s.config.REST.TLSAddress != "" && s.config.Server.TLSCert != "" && s.config.Server.TLSKey != "" {
+			restTLS = activated.Take("rest-tls", s.config.REST.TLSAddress)
+		}
+		s.restServer.SetListeners(activated.Take("rest", s.config.REST.Address), restTLS)
 		if err := s.restServer.Start(); err != nil {
 			// Close LDAP listeners if REST fails
 			s.mu.Lock()
@@ -608,11 +1140,52 @@ func (s *LDAPServer) Start() error {
 		}
 	}
 
+	// Start metrics server if enabled
+	if s.metricsServer != nil {
+		if err := s.metricsServer.Start(); err != nil {
+			s.mu.Lock()
+			if s.listener != nil {
+				s.listener.Close()
+			}
+			if s.tlsListener != nil {
+				s.tlsListener.Close()
+			}
+			s.mu.Unlock()
+			if s.restServer != nil {
+				s.restServer.Stop(context.Background())
+			}
+			return fmt.Errorf("failed to start metrics server: %w", err)
+		}
+		s.logger.WithComponent("system").Info("metrics server listening", "address", s.config.Monitoring.PrometheusAddr)
+	}
+
 	// Start ACL file watcher if configured
 	if s.aclWatcher != nil {
 		s.aclWatcher.Start()
 	}
 
+	if s.events != nil {
+		go s.monitorEvents()
+		s.emitEvent(server.Event{Type: server.EventServerStart, Message: "server started"})
+	}
+
+	if n := activated.Len(); n > 0 {
+		s.logger.WithComponent("system").Warn("activated sockets match no configured listener, closing them",
+			"sockets", n)
+		activated.Close()
+	}
+
+	// Tell systemd that startup is complete: the storage engine was opened
+	// by NewLDAPServer and all listeners are up
+	if err := systemd.Notify(systemd.Ready, systemd.Status("serving")); err != nil {
+		s.logger.WithComponent("system").Warn("failed to notify systemd", "error", err)
+	}
+	go func() {
+		if err := systemd.RunWatchdog(s.ctx); err != nil {
+			s.logger.WithComponent("system").Warn("systemd watchdog stopped", "error", err)
+		}
+	}()
+
 	// Wait for all connections to finish
 	s.wg.Wait()
 	return nil
@@ -627,10 +1200,15 @@ func (s *LDAPServer) Stop(ctx context.Context) error {
 	}
 	s.running = false
 
+	if err := systemd.Notify(systemd.Stopping, systemd.Status("stopping")); err != nil {
+		s.logger.WithComponent("system").Warn("failed to notify systemd", "error", err)
+	}
+
 	// Copy references while holding lock
 	listener := s.listener
 	tlsListener := s.tlsListener
 	restServer := s.restServer
+	metricsServer := s.metricsServer
 	aclWatcher := s.aclWatcher
 	clusterBackend := s.clusterBackend
 	s.mu.Unlock()
@@ -638,10 +1216,15 @@ func (s *LDAPServer) Stop(ctx context.Context) error {
 	// Cancel the server context
 	s.cancel()
 
+	s.emitEvent(server.Event{Type: server.EventServerStop, Message: "server stopping"})
+	if s.snmpEmitter != nil {
+		s.snmpEmitter.Close()
+	}
+
 	// Stop cluster backend
 	if clusterBackend != nil {
 		clusterBackend.Stop()
-		s.logger.WithSource("system").Info("cluster backend stopped")
+		s.logger.WithComponent("system").Info("cluster backend stopped")
 	}
 
 	// Stop ACL file watcher
@@ -654,6 +1237,23 @@ func (s *LDAPServer) Stop(ctx context.Context) error {
 		restServer.Stop(ctx)
 	}
 
+	// Stop metrics server
+	if metricsServer != nil {
+		metricsServer.Stop(ctx)
+	}
+
+	// Persist bind throttle state
+	if s.bindThrottle != nil && s.bindThrottleFile != "" {
+		if err := s.bindThrottle.Save(s.bindThrottleFile); err != nil {
+			s.logger.WithComponent("system").Warn("failed to save bind throttle state", "error", err)
+		}
+	}
+
+	// Close the wire dump files
+	if s.wireDump != nil {
+		s.wireDump.Disable()
+	}
+
 	// Close listeners
 	if listener != nil {
 		listener.Close()
@@ -662,6 +1262,10 @@ func (s *LDAPServer) Stop(ctx context.Context) error {
 		tlsListener.Close()
 	}
 
+	// Ask the open connections to close once their current operation is
+	// done, telling their clients with a Notice of Disconnection
+	s.connections.Drain()
+
 	// Wait for connections to finish with timeout
 	done := make(chan struct{})
 	go func() {
@@ -679,7 +1283,14 @@ func (s *LDAPServer) Stop(ctx context.Context) error {
 		if s.engine != nil {
 			s.engine.Close()
 		}
-		s.logger.WithSource("system").Info("server stopped gracefully")
+		if s.auditLog != nil {
+			s.auditLog.Close()
+		}
+		// Send the remaining spans
+		if s.traceExporter != nil {
+			s.traceExporter.Shutdown(ctx)
+		}
+		s.logger.WithComponent("system").Info("server stopped gracefully")
 		return nil
 	case <-ctx.Done():
 		// Close log store first to flush pending writes
@@ -690,11 +1301,44 @@ func (s *LDAPServer) Stop(ctx context.Context) error {
 		if s.engine != nil {
 			s.engine.Close()
 		}
-		s.logger.WithSource("system").Warn("server shutdown timed out")
+		if s.auditLog != nil {
+			s.auditLog.Close()
+		}
+		// Send the remaining spans
+		if s.traceExporter != nil {
+			s.traceExporter.Shutdown(ctx)
+		}
+		s.logger.WithComponent("system").Warn("server shutdown timed out")
 		return ctx.Err()
 	}
 }
 
+// listen opens a TCP listener on address, or takes the activated socket
+// named name or listening on its port. If PROXY protocol is enabled, the
+// listener reads PROXY headers from the trusted proxies.
+func (s *LDAPServer) listen(activated *systemd.Listeners, name, address string) (net.Listener, error) {
+	listener := activated.Take(name, address)
+	if listener == nil {
+		var err error
+		listener, err = net.Listen("tcp", address)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !s.config.Server.ProxyProtocol {
+		return listener, nil
+	}
+
+	proxyCfg := server.DefaultProxyProtocolConfig()
+	proxyCfg.TrustedProxies = s.config.Server.TrustedProxies
+	proxyListener, err := server.NewProxyListener(listener, proxyCfg)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return proxyListener, nil
+}
+
 // acceptConnections accepts incoming connections on the listener.
 func (s *LDAPServer) acceptConnections(listener net.Listener, isTLS bool) {
 	defer s.wg.Done()
@@ -728,14 +1372,21 @@ func (s *LDAPServer) handleConnection(conn net.Conn, isTLS bool) {
 
 	// Create server struct for connection
 	srv := &server.Server{
-		Handler: s.handler,
-		Logger:  s.logger,
+		Handler:        s.handler,
+		Logger:         s.logger,
+		Metrics:        s.metrics,
+		TracerProvider: s.tracerProvider,
+		WireDump:       s.wireDump,
+		Events:         s.events,
+		Connections:    s.connections,
+		IdleTimeout:    s.idleTimeout,
 	}
 
 	// Create and handle connection
 	c := server.NewConnection(conn, srv)
 	c.SetTLS(isTLS)
 	c.SetPersistentSearchHandler(s.persistentSearchHandler)
+	c.SetClientUpdateHandler(s.clientUpdateHandler)
 	c.Handle()
 }
 
@@ -846,20 +1497,21 @@ func serveCmd(args []string) int {
 	if *configFile != "" {
 		srv.configManager = config.NewConfigManager(cfg, *configFile)
 		srv.configManager.SetOnUpdate(srv.handleConfigReload)
+		srv.configTree.manager = srv.configManager
 		if srv.restServer != nil {
 			srv.restServer.SetConfigManager(srv.configManager)
 		}
 		// Set config applier on cluster backend for config replication
 		if srv.clusterBackend != nil {
 			srv.clusterBackend.SetConfigApplier(srv.configManager)
-			srv.logger.WithSource("system").Info("config manager cluster replication enabled")
+			srv.logger.WithComponent("system").Info("config manager cluster replication enabled")
 		}
 	}
 
 	// Set ACL applier on cluster backend for ACL replication
 	if srv.clusterBackend != nil && srv.aclManager != nil {
 		srv.clusterBackend.SetACLApplier(srv.aclManager)
-		srv.logger.WithSource("system").Info("ACL manager cluster replication enabled")
+		srv.logger.WithComponent("system").Info("ACL manager cluster replication enabled")
 	}
 
 	// Write PID file
@@ -876,13 +1528,16 @@ func serveCmd(args []string) int {
 		configWatcher, err := config.NewConfigWatcher(&config.WatcherConfig{
 			FilePath: *configFile,
 			OnChange: srv.handleConfigReload,
+			OnError: func(err error) {
+				srv.logger.WithComponent("system").Error("config reload failed", "error", err)
+			},
 		})
 		if err != nil {
-			srv.logger.WithSource("system").Warn("failed to create config watcher", "error", err)
+			srv.logger.WithComponent("system").Warn("failed to create config watcher", "error", err)
 		} else {
 			srv.configWatcher = configWatcher
 			configWatcher.Start()
-			srv.logger.WithSource("system").Info("config file watcher started", "file", *configFile)
+			srv.logger.WithComponent("system").Info("config file watcher started", "file", *configFile)
 			defer configWatcher.Stop()
 		}
 	}
@@ -930,7 +1585,7 @@ func serveCmd(args []string) int {
 
 // handleSIGHUP handles the SIGHUP signal for ACL reload.
 func (s *LDAPServer) handleSIGHUP() {
-	sysLogger := s.logger.WithSource("system")
+	sysLogger := s.logger.WithComponent("system")
 	sysLogger.Info("received SIGHUP, reloading ACL configuration")
 
 	if s.aclManager == nil {
@@ -971,7 +1626,7 @@ func (s *LDAPServer) writePIDFile() error {
 	}
 
 	s.pidFile = pidFile
-	s.logger.WithSource("system").Info("PID file written", "file", pidFile, "pid", pid)
+	s.logger.WithComponent("system").Info("PID file written", "file", pidFile, "pid", pid)
 	return nil
 }
 
@@ -979,25 +1634,32 @@ func (s *LDAPServer) writePIDFile() error {
 func (s *LDAPServer) removePIDFile() {
 	if s.pidFile != "" {
 		os.Remove(s.pidFile)
-		s.logger.WithSource("system").Debug("PID file removed", "file", s.pidFile)
+		s.logger.WithComponent("system").Debug("PID file removed", "file", s.pidFile)
 	}
 }
 
 // convertACLConfig converts config.ACLConfig to acl.Config.
-func convertACLConfig(cfg *config.ACLConfig) *acl.Config {
+func convertACLConfig(cfg *config.ACLConfig) (*acl.Config, error) {
 	aclConfig := acl.NewConfig()
 	aclConfig.SetDefaultPolicy(cfg.DefaultPolicy)
 
-	for _, rule := range cfg.Rules {
+	for i, rule := range cfg.Rules {
 		rights, _ := acl.ParseRights(rule.Rights)
 		aclRule := acl.NewACL(rule.Target, rule.Subject, rights)
 		if len(rule.Attributes) > 0 {
 			aclRule.WithAttributes(rule.Attributes...)
 		}
+		if tr := rule.TimeRange; tr != nil {
+			timeRange, err := acl.ParseTimeRange(tr.Start, tr.End, tr.Weekdays, tr.Timezone)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: %w", i, err)
+			}
+			aclRule.TimeRange = timeRange
+		}
 		aclConfig.AddRule(aclRule)
 	}
 
-	return aclConfig
+	return aclConfig, nil
 }
 
 // SetMaxConnections updates the maximum connections limit at runtime.
@@ -1005,6 +1667,7 @@ func (s *LDAPServer) SetMaxConnections(max int) {
 	s.settingsMu.Lock()
 	defer s.settingsMu.Unlock()
 	s.maxConnections = max
+	s.connections.SetMax(max)
 }
 
 // GetMaxConnections returns the current maximum connections limit.
@@ -1042,26 +1705,69 @@ func (s *LDAPServer) GetWriteTimeout() time.Duration {
 	return s.writeTimeout
 }
 
-// ReloadTLSCert reloads TLS certificate and key from files.
-func (s *LDAPServer) ReloadTLSCert(certFile, keyFile string) error {
-	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+// ReloadTLSCert reloads TLS certificate and key from files, decrypting the
+// key with passphrase if it is encrypted. New TLS connections use the new
+// certificate immediately, while established connections keep the one they
+// were negotiated with.
+func (s *LDAPServer) ReloadTLSCert(certFile, keyFile, passphrase string) error {
+	cert, err := loadTLSCert(certFile, keyFile, passphrase)
 	if err != nil {
 		return fmt.Errorf("failed to load TLS certificate: %w", err)
 	}
 
 	s.settingsMu.Lock()
-	defer s.settingsMu.Unlock()
-
-	// Update TLS config with new certificate
-	if s.tlsConfig != nil {
-		s.tlsConfig.Certificates = []tls.Certificate{cert}
-	}
+	s.tlsCert.Store(cert)
 	s.tlsCertFile = certFile
 	s.tlsKeyFile = keyFile
+	s.settingsMu.Unlock()
+
+	s.logger.WithComponent("system").Info("TLS certificate reloaded",
+		"event", "certReloaded",
+		"cert", certFile,
+		"subject", cert.Leaf.Subject.String(),
+		"notAfter", cert.Leaf.NotAfter.UTC().Format(time.RFC3339))
 
 	return nil
 }
 
+// applyTLSPolicy sets the minimum version and cipher suites configured in
+// security.tls on tlsCfg.
+func applyTLSPolicy(tlsCfg *server.TLSConfig, policy config.TLSConfig) (*server.TLSConfig, error) {
+	minVersion, err := server.ParseTLSVersion(policy.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	if minVersion != 0 {
+		tlsCfg = tlsCfg.WithMinVersion(minVersion)
+	}
+
+	if len(policy.AllowedCipherSuites) > 0 {
+		suites, err := server.ParseCipherSuites(policy.AllowedCipherSuites)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg = tlsCfg.WithCipherSuites(suites)
+	}
+	return tlsCfg, nil
+}
+
+// loadTLSCert loads a certificate and key from files and parses the leaf
+// certificate. An encrypted key is decrypted with passphrase.
+func loadTLSCert(certFile, keyFile, passphrase string) (*tls.Certificate, error) {
+	cert, err := server.LoadCertificateWithPassphrase(certFile, keyFile, []byte(passphrase))
+	if err != nil {
+		return nil, err
+	}
+	if cert.Leaf == nil {
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return nil, err
+		}
+		cert.Leaf = leaf
+	}
+	return &cert, nil
+}
+
 // handleConfigReload handles config file changes and applies hot-reloadable settings.
 func (s *LDAPServer) handleConfigReload(oldCfg, newCfg *config.Config) {
 	s.logger.Info("config file changed, applying hot-reloadable settings")
@@ -1090,13 +1796,18 @@ func (s *LDAPServer) handleConfigReload(oldCfg, newCfg *config.Config) {
 		s.logger.Info("write timeout changed", "old", oldCfg.Server.WriteTimeout, "new", newCfg.Server.WriteTimeout)
 	}
 
+	// Search limit profiles
+	if !searchLimitsEqual(oldCfg.Server.Limits, newCfg.Server.Limits) {
+		s.handler.SearchLimits().SetProfiles(searchLimitProfiles(newCfg.Server.Limits))
+		s.logger.Info("search limits changed", "profiles", len(newCfg.Server.Limits))
+	}
+
 	// TLS certificate reload
-	if oldCfg.Server.TLSCert != newCfg.Server.TLSCert || oldCfg.Server.TLSKey != newCfg.Server.TLSKey {
+	if oldCfg.Server.TLSCert != newCfg.Server.TLSCert || oldCfg.Server.TLSKey != newCfg.Server.TLSKey ||
+		oldCfg.Server.TLSKeyPassphrase != newCfg.Server.TLSKeyPassphrase {
 		if newCfg.Server.TLSCert != "" && newCfg.Server.TLSKey != "" {
-			if err := s.ReloadTLSCert(newCfg.Server.TLSCert, newCfg.Server.TLSKey); err != nil {
+			if err := s.ReloadTLSCert(newCfg.Server.TLSCert, newCfg.Server.TLSKey, newCfg.Server.TLSKeyPassphrase); err != nil {
 				s.logger.Error("failed to reload TLS certificate", "error", err)
-			} else {
-				s.logger.Info("TLS certificate reloaded")
 			}
 		}
 	}
@@ -1156,7 +1867,8 @@ func (s *LDAPServer) passwordPolicyChanged(oldCfg, newCfg *config.Config) bool {
 		old.RequireDigit != new.RequireDigit ||
 		old.RequireSpecial != new.RequireSpecial ||
 		old.MaxAge != new.MaxAge ||
-		old.HistoryCount != new.HistoryCount
+		old.HistoryCount != new.HistoryCount ||
+		old.GraceLogins != new.GraceLogins
 }
 
 // convertPasswordPolicy converts config password policy to password.Policy.
@@ -1170,7 +1882,30 @@ func (s *LDAPServer) convertPasswordPolicy(cfg *config.PasswordPolicyConfig) *pa
 		RequireSpecial:   cfg.RequireSpecial,
 		MaxAge:           cfg.MaxAge,
 		HistoryCount:     cfg.HistoryCount,
+		GraceLogins:      cfg.GraceLogins,
+	}
+}
+
+// searchLimitsEqual compares two lists of search limit profiles.
+func searchLimitsEqual(a, b []config.SearchLimitConfig) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// searchLimitProfiles converts the configured search limit profiles.
+func searchLimitProfiles(limits []config.SearchLimitConfig) []server.SearchLimitProfile {
+	profiles := make([]server.SearchLimitProfile, len(limits))
+	for i, limit := range limits {
+		profiles[i] = server.SearchLimitProfile(limit)
 	}
+	return profiles
 }
 
 // stringSliceEqual compares two string slices for equality.
@@ -1,20 +1,33 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
 	"math/big"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/KilimcininKorOglu/oba/internal/backend"
+	"github.com/KilimcininKorOglu/oba/internal/backup"
 	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/server"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
 )
 
 func TestServeCmd_Help(t *testing.T) {
@@ -174,6 +187,104 @@ func TestNewServer_WithTLS(t *testing.T) {
 	}
 }
 
+func TestLDAPServer_ReloadTLSCert(t *testing.T) {
+	tlsPort := findAvailablePort(t)
+	tmpDir := t.TempDir()
+
+	writeCert := func(name string) (string, string) {
+		certPEM, keyPEM := generateValidTestCert(t)
+		certPath := filepath.Join(tmpDir, name+".pem")
+		keyPath := filepath.Join(tmpDir, name+"-key.pem")
+		if err := os.WriteFile(certPath, certPEM, 0644); err != nil {
+			t.Fatalf("failed to write cert: %v", err)
+		}
+		if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+			t.Fatalf("failed to write key: %v", err)
+		}
+		return certPath, keyPath
+	}
+	oldCert, oldKey := writeCert("old")
+	newCert, newKey := writeCert("new")
+
+	cfg := config.DefaultConfig()
+	cfg.Server.Address = ""
+	cfg.Server.TLSAddress = tlsPort
+	cfg.Server.TLSCert = oldCert
+	cfg.Server.TLSKey = oldKey
+	cfg.Storage.DataDir = tmpDir
+
+	srv, err := NewServer(cfg)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	go srv.Start()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Stop(ctx)
+	}()
+
+	dial := func() *tls.Conn {
+		t.Helper()
+		var conn *tls.Conn
+		var err error
+		for i := 0; i < 50; i++ {
+			conn, err = tls.Dial("tcp", tlsPort, &tls.Config{InsecureSkipVerify: true})
+			if err == nil {
+				return conn
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatalf("TLS handshake failed: %v", err)
+		return nil
+	}
+
+	first := dial()
+	defer first.Close()
+	firstLeaf := first.ConnectionState().PeerCertificates[0]
+
+	if err := srv.ReloadTLSCert(newCert, newKey, ""); err != nil {
+		t.Fatalf("ReloadTLSCert() error = %v", err)
+	}
+
+	second := dial()
+	defer second.Close()
+	secondLeaf := second.ConnectionState().PeerCertificates[0]
+
+	if firstLeaf.Equal(secondLeaf) {
+		t.Error("expected a new handshake to use the reloaded certificate")
+	}
+	if want := srv.tlsCert.Load().Leaf; !secondLeaf.Equal(want) {
+		t.Error("expected the new handshake to present the reloaded leaf certificate")
+	}
+
+	// The connection established before the reload stays usable: an
+	// anonymous simple bind still gets a response
+	bind := []byte{0x30, 0x0c, 0x02, 0x01, 0x01, 0x60, 0x07, 0x02, 0x01, 0x03, 0x04, 0x00, 0x80, 0x00}
+	if _, err := first.Write(bind); err != nil {
+		t.Fatalf("failed to write on existing connection: %v", err)
+	}
+	first.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 64)
+	n, err := first.Read(buf)
+	if err != nil {
+		t.Fatalf("expected existing connection to stay open, got %v", err)
+	}
+	if _, err := ldap.ParseLDAPMessage(buf[:n]); err != nil {
+		t.Errorf("failed to parse bind response: %v", err)
+	}
+
+	// A failed reload keeps serving the current certificate
+	if err := srv.ReloadTLSCert(filepath.Join(tmpDir, "missing.pem"), newKey, ""); err == nil {
+		t.Error("expected error for missing certificate file")
+	}
+	third := dial()
+	defer third.Close()
+	if !third.ConnectionState().PeerCertificates[0].Equal(secondLeaf) {
+		t.Error("expected failed reload to keep the current certificate")
+	}
+}
+
 func TestNewServer_InvalidTLS(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -238,6 +349,254 @@ func TestLDAPServer_StartStop(t *testing.T) {
 	}
 }
 
+func TestLDAPServer_PrometheusMetrics(t *testing.T) {
+	plainPort := findAvailablePort(t)
+	metricsPort := findAvailablePort(t)
+
+	cfg := config.DefaultConfig()
+	cfg.Server.Address = plainPort
+	cfg.Server.TLSAddress = ""
+	cfg.Storage.DataDir = t.TempDir()
+	cfg.REST.Enabled = false
+	cfg.Monitoring.PrometheusAddr = metricsPort
+
+	srv, err := NewServer(cfg)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Start()
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := net.Dial("tcp", plainPort)
+	if err != nil {
+		t.Fatalf("failed to connect to server: %v", err)
+	}
+
+	// Anonymous bind, wait for the response, then unbind
+	bind := []byte{0x30, 0x0c, 0x02, 0x01, 0x01, 0x60, 0x07, 0x02, 0x01, 0x03, 0x04, 0x00, 0x80, 0x00}
+	if _, err := conn.Write(bind); err != nil {
+		t.Fatalf("failed to send bind: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Read(make([]byte, 256)); err != nil {
+		t.Fatalf("failed to read bind response: %v", err)
+	}
+	conn.Write([]byte{0x30, 0x05, 0x02, 0x01, 0x02, 0x42, 0x00})
+	conn.Close()
+
+	want := `oba_ldap_operations_total{operation="bind",result="success"} 1`
+	var body string
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		resp, err := http.Get("http://" + metricsPort + "/metrics")
+		if err != nil {
+			t.Fatalf("failed to scrape metrics: %v", err)
+		}
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		body = string(data)
+		if strings.Contains(body, want) && strings.Contains(body, "oba_ldap_active_connections 0") {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if !strings.Contains(body, want) || !strings.Contains(body, "oba_ldap_connections_total 1") {
+		t.Errorf("metrics missing bind and connection counts:\n%s", body)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Stop(ctx); err != nil {
+		t.Errorf("failed to stop server: %v", err)
+	}
+	<-errCh
+
+	if _, err := http.Get("http://" + metricsPort + "/metrics"); err == nil {
+		t.Error("metrics server still serving after Stop")
+	}
+}
+
+func TestLDAPServer_BinaryAttributeRoundTrip(t *testing.T) {
+	certPEM, _ := generateValidTestCert(t)
+	block, _ := pem.Decode(certPEM)
+	der := block.Bytes
+
+	cfg := config.DefaultConfig()
+	cfg.Server.TLSAddress = ""
+	cfg.Storage.DataDir = t.TempDir()
+	cfg.REST.Enabled = false
+	cfg.Directory.BaseDN = "dc=example,dc=com"
+
+	srv, err := NewServer(cfg)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	conn := server.NewConnection(nil, &server.Server{Handler: srv.handler})
+
+	dn := "uid=alice,ou=users,dc=example,dc=com"
+	result := srv.handler.HandleAdd(conn, &ldap.AddRequest{Entry: dn, Attributes: []ldap.Attribute{
+		{Type: "objectClass", Values: [][]byte{[]byte("inetOrgPerson")}},
+		{Type: "cn", Values: [][]byte{[]byte("alice")}},
+		{Type: "sn", Values: [][]byte{[]byte("Alice")}},
+		{Type: "uid", Values: [][]byte{[]byte("alice")}},
+		{Type: "userCertificate;binary", Values: [][]byte{der}},
+	}})
+	if result.ResultCode != ldap.ResultSuccess {
+		t.Fatalf("add failed: %s %s", result.ResultCode, result.DiagnosticMessage)
+	}
+
+	// The transfer option is echoed only when requested
+	for requested, wantType := range map[string]string{
+		"userCertificate;binary": "usercertificate;binary",
+		"userCertificate":        "usercertificate",
+	} {
+		result := srv.handler.HandleSearch(conn, &ldap.SearchRequest{
+			BaseObject: dn,
+			Scope:      ldap.ScopeBaseObject,
+			Attributes: []string{requested},
+		})
+		if result.ResultCode != ldap.ResultSuccess || len(result.Entries) != 1 {
+			t.Fatalf("search %s: %s, %d entries", requested, result.ResultCode, len(result.Entries))
+		}
+		attrs := result.Entries[0].Attributes
+		if len(attrs) != 1 || attrs[0].Type != wantType {
+			t.Fatalf("search %s returned %v, want only %s", requested, attrs, wantType)
+		}
+		if len(attrs[0].Values) != 1 || !bytes.Equal(attrs[0].Values[0], der) {
+			t.Errorf("search %s: certificate not returned byte-identical", requested)
+		}
+	}
+	srv.engine.Close()
+
+	checkCertificate := func(t *testing.T, dataDir string) {
+		t.Helper()
+		db, err := engine.Open(dataDir, storage.DefaultEngineOptions())
+		if err != nil {
+			t.Fatalf("failed to open database: %v", err)
+		}
+		defer db.Close()
+
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatalf("failed to begin transaction: %v", err)
+		}
+		defer db.Rollback(tx)
+		entry, err := db.Get(tx, dn)
+		if err != nil {
+			t.Fatalf("failed to get entry: %v", err)
+		}
+		values := entry.GetAttribute("usercertificate")
+		if len(values) != 1 || !bytes.Equal(values[0], der) {
+			t.Errorf("certificate not stored byte-identical")
+		}
+	}
+
+	t.Run("backup and restore", func(t *testing.T) {
+		backupPath := filepath.Join(t.TempDir(), "backup.oba")
+		_, err := backup.NewBackupManager(nil).Backup(&backup.BackupOptions{
+			OutputPath: backupPath,
+			DataDir:    cfg.Storage.DataDir,
+			Format:     backup.FormatNative,
+		})
+		if err != nil {
+			t.Fatalf("backup failed: %v", err)
+		}
+
+		restoreDir := t.TempDir()
+		_, err = backup.NewRestoreManager(restoreDir).Restore(&backup.RestoreOptions{
+			InputPath: backupPath,
+			DataDir:   restoreDir,
+			Format:    backup.FormatNative,
+		})
+		if err != nil {
+			t.Fatalf("restore failed: %v", err)
+		}
+		checkCertificate(t, restoreDir)
+	})
+
+	t.Run("ldif export and import", func(t *testing.T) {
+		src, err := engine.Open(cfg.Storage.DataDir, storage.DefaultEngineOptions())
+		if err != nil {
+			t.Fatalf("failed to open database: %v", err)
+		}
+		var buf bytes.Buffer
+		err = backup.NewLDIFExporter(src).Export(&buf, cfg.Directory.BaseDN)
+		src.Close()
+		if err != nil {
+			t.Fatalf("export failed: %v", err)
+		}
+		if !strings.Contains(buf.String(), "usercertificate:: ") {
+			t.Errorf("certificate not base64 encoded in LDIF:\n%s", buf.String())
+		}
+
+		importDir := t.TempDir()
+		dst, err := engine.Open(importDir, storage.DefaultEngineOptions())
+		if err != nil {
+			t.Fatalf("failed to open database: %v", err)
+		}
+		err = backup.NewLDIFImporter(dst).Import(&buf)
+		dst.Close()
+		if err != nil {
+			t.Fatalf("import failed: %v", err)
+		}
+		checkCertificate(t, importDir)
+	})
+}
+
+// TestLDAPServer_Compare tests the compare handler result codes.
+func TestLDAPServer_Compare(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Server.TLSAddress = ""
+	cfg.Storage.DataDir = t.TempDir()
+	cfg.REST.Enabled = false
+	cfg.Directory.BaseDN = "dc=example,dc=com"
+
+	srv, err := NewServer(cfg)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer srv.engine.Close()
+	conn := server.NewConnection(nil, &server.Server{Handler: srv.handler})
+
+	dn := "uid=alice,ou=users,dc=example,dc=com"
+	result := srv.handler.HandleAdd(conn, &ldap.AddRequest{Entry: dn, Attributes: []ldap.Attribute{
+		{Type: "objectClass", Values: [][]byte{[]byte("inetOrgPerson")}},
+		{Type: "cn", Values: [][]byte{[]byte("Alice")}},
+		{Type: "sn", Values: [][]byte{[]byte("Smith")}},
+		{Type: "uid", Values: [][]byte{[]byte("alice")}},
+	}})
+	if result.ResultCode != ldap.ResultSuccess {
+		t.Fatalf("add failed: %s %s", result.ResultCode, result.DiagnosticMessage)
+	}
+
+	tests := []struct {
+		dn    string
+		attr  string
+		value string
+		want  ldap.ResultCode
+	}{
+		{dn, "uid", "alice", ldap.ResultCompareTrue},
+		{dn, "cn", "alice", ldap.ResultCompareTrue},
+		{dn, "uid", "bob", ldap.ResultCompareFalse},
+		{dn, "mail", "alice@example.com", ldap.ResultNoSuchAttribute},
+		{"uid=bob,ou=users,dc=example,dc=com", "uid", "bob", ldap.ResultNoSuchObject},
+	}
+	for _, tt := range tests {
+		result := srv.handler.HandleCompare(conn, &ldap.CompareRequest{
+			DN:        tt.dn,
+			Attribute: tt.attr,
+			Value:     []byte(tt.value),
+		})
+		if result.ResultCode != tt.want {
+			t.Errorf("compare %s %s=%s: got %s, want %s", tt.dn, tt.attr, tt.value, result.ResultCode, tt.want)
+		}
+	}
+}
+
 func TestLDAPServer_DoubleStart(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -414,6 +773,44 @@ func TestApplyEnvOverrides_Directory(t *testing.T) {
 	}
 }
 
+func TestHookRejection(t *testing.T) {
+	if result := hookRejection(backend.ErrEntryExists); result != nil {
+		t.Errorf("expected nil for a non-hook error, got %+v", result)
+	}
+
+	err := fmt.Errorf("add failed: %w", backend.NewHookError(ldap.ResultConstraintViolation, "no printers"))
+	result := hookRejection(err)
+	if result == nil || result.ResultCode != ldap.ResultConstraintViolation || result.DiagnosticMessage != "no printers" {
+		t.Errorf("hookRejection() = %+v, want constraintViolation with the hook message", result)
+	}
+}
+
+func TestOperationFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code ldap.ResultCode
+		diag string
+	}{
+		{"backend error", backend.ErrEntryNotFound, ldap.ResultNoSuchObject, "entry not found"},
+		{"wrapped backend error", fmt.Errorf("%w: cn is required", backend.ErrObjectClassViolation),
+			ldap.ResultObjectClassViolation, "backend: object class violation: cn is required"},
+		{"conflict", backend.ErrConflict, ldap.ResultBusy, backend.ErrConflict.Error()},
+		{"hook rejection", backend.NewHookError(ldap.ResultConstraintViolation, "no printers"),
+			ldap.ResultConstraintViolation, "no printers"},
+		{"other error", errors.New("disk full"), ldap.ResultOperationsError, "disk full"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := operationFailure(tt.err)
+			if result.ResultCode != tt.code || result.DiagnosticMessage != tt.diag {
+				t.Errorf("operationFailure() = %v %q, want %v %q",
+					result.ResultCode, result.DiagnosticMessage, tt.code, tt.diag)
+			}
+		})
+	}
+}
+
 func TestIsClosedError(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,112 @@
+// Package main provides the storage command for the oba LDAP server.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+// storageCmd handles the storage command.
+func storageCmd(args []string) int {
+	if len(args) == 0 {
+		printStorageUsage(os.Stdout)
+		return 0
+	}
+
+	// Check for help flags
+	if args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		printStorageUsage(os.Stdout)
+		return 0
+	}
+
+	switch args[0] {
+	case "analyze":
+		return storageAnalyzeCmd(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown storage subcommand: %s\n", args[0])
+		fmt.Fprintln(os.Stderr, "Run 'oba storage help' for usage.")
+		return 1
+	}
+}
+
+// storageAnalyzeCmd handles the storage analyze subcommand.
+func storageAnalyzeCmd(args []string) int {
+	fs := flag.NewFlagSet("storage analyze", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	configFile := fs.String("config", "", "Path to configuration file")
+	dataDir := fs.String("data-dir", "", "Data directory path (overrides config)")
+	help := fs.Bool("h", false, "Show help message")
+	helpLong := fs.Bool("help", false, "Show help message")
+
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	if *help || *helpLong {
+		printStorageAnalyzeUsage(os.Stdout)
+		return 0
+	}
+
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Error: unexpected argument: %s\n", fs.Arg(0))
+		return 1
+	}
+
+	cfg := config.DefaultConfig()
+	if *configFile != "" {
+		var err error
+		cfg, err = config.LoadConfig(*configFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+			return 1
+		}
+	}
+	if *dataDir != "" {
+		cfg.Storage.DataDir = *dataDir
+	}
+
+	for i, name := range []string{engine.DataFileName, engine.IndexFileName} {
+		path := filepath.Join(cfg.Storage.DataDir, name)
+		pm, err := storage.OpenPageManager(path, storage.Options{
+			PageSize: cfg.Storage.PageSize,
+			ReadOnly: true,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to open %s: %v\n", path, err)
+			return 1
+		}
+		report := pm.UsageReport()
+		pm.Close()
+
+		if i > 0 {
+			fmt.Println()
+		}
+		printUsageReport(os.Stdout, name, report)
+	}
+	return 0
+}
+
+// printUsageReport prints the page breakdown of one file.
+func printUsageReport(w io.Writer, name string, report *storage.PageUsageReport) {
+	fmt.Fprintf(w, "%s: %d pages of %d bytes\n", name, report.TotalPages, report.PageSize)
+	fmt.Fprintf(w, "  %-10s %10s %14s %10s\n", "TYPE", "PAGES", "BYTES", "OCCUPANCY")
+
+	for pageType := storage.PageTypeFree; pageType <= storage.PageTypeWAL; pageType++ {
+		stats, ok := report.ByType[pageType]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(w, "  %-10s %10d %14d %9.1f%%\n", pageType, stats.Count, stats.TotalBytes, stats.AverageOccupancy*100)
+	}
+	if report.Unreadable > 0 {
+		fmt.Fprintf(w, "  %d pages could not be read; run 'oba recover -verify'\n", report.Unreadable)
+	}
+}
@@ -215,14 +215,10 @@ func convertFilter(f *ldap.SearchFilter) *filter.Filter {
 	return filter.NewPresentFilter("objectClass")
 }
 
-func convertAttributes(attrs map[string][]string) []ldap.Attribute {
+func convertAttributes(attrs map[string][][]byte) []ldap.Attribute {
 	var result []ldap.Attribute
 	for name, values := range attrs {
-		byteValues := make([][]byte, len(values))
-		for i, v := range values {
-			byteValues[i] = []byte(v)
-		}
-		result = append(result, ldap.Attribute{Type: name, Values: byteValues})
+		result = append(result, ldap.Attribute{Type: name, Values: values})
 	}
 	return result
 }
@@ -54,8 +54,36 @@
 //   - "authenticated": Any authenticated user
 //   - "self": The entry being accessed (for self-modification)
 //   - "*": Everyone (anonymous and authenticated)
+//   - "group:<DN>": Members of the group, as decided by the GroupResolver
+//     set with Manager.SetGroupResolver
 //   - DN: Specific user DN
 //
+// # Connection Constraints
+//
+// Rules may also restrict the client connection. A rule whose constraints
+// are not satisfied does not apply, and evaluation moves on to the next rule:
+//
+//	// Allow writes only from the admin network over TLS
+//	rule := acl.NewACL("ou=users,dc=example,dc=com", "authenticated", acl.Write).
+//	    WithSourceCIDRs("10.0.0.0/8").
+//	    WithRequireTLS(true).
+//	    WithAuthMethod(acl.AuthMethodSimple)
+//
+// The server fills AccessContext.Connection with the client address, TLS
+// state, security strength factor and bind method.
+//
+// # Time Ranges
+//
+// A rule with a TimeRange only applies during a daily window of clock time,
+// checked against the Evaluator's clock (time.Now unless set with
+// Evaluator.SetClock):
+//
+//	// Allow writes on weekdays during office hours
+//	rule := acl.NewACL("ou=users,dc=example,dc=com", "authenticated", acl.Write)
+//	rule.TimeRange, err = acl.ParseTimeRange("09:00", "17:00", []string{"mon-fri"}, "Europe/Istanbul")
+//
+// A window whose end is before its start runs past midnight.
+//
 // # ACL Configuration
 //
 // Configure ACL with default policy and rules:
@@ -88,6 +116,6 @@
 // Rules are evaluated in order; first match wins:
 //
 //  1. Check each rule in order
-//  2. If rule matches target, subject, and operation, apply allow/deny
+//  2. If rule matches target, subject, connection, and operation, apply allow/deny
 //  3. If no rule matches, apply default policy
 package acl
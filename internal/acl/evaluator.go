@@ -2,6 +2,8 @@
 // for the Oba LDAP server.
 package acl
 
+import "time"
+
 // Entry represents an LDAP entry for attribute filtering.
 // This is a simplified interface to avoid circular dependencies.
 type Entry struct {
@@ -57,6 +59,7 @@ func (e *Entry) Clone() *Entry {
 type Evaluator struct {
 	config  *Config
 	matcher *Matcher
+	clock   ClockFunc
 }
 
 // NewEvaluator creates a new ACL evaluator with the given configuration.
@@ -68,9 +71,19 @@ func NewEvaluator(config *Config) *Evaluator {
 	return &Evaluator{
 		config:  config,
 		matcher: NewMatcher(),
+		clock:   time.Now,
 	}
 }
 
+// SetClock sets the clock that rule time ranges are checked against.
+// A nil clock restores time.Now.
+func (e *Evaluator) SetClock(clock ClockFunc) {
+	if clock == nil {
+		clock = time.Now
+	}
+	e.clock = clock
+}
+
 // CheckAccess determines if the operation is allowed based on ACL rules.
 // Uses first-match-wins semantics: the first matching rule determines access.
 // If no rules match, the default policy is applied.
@@ -79,6 +92,7 @@ func (e *Evaluator) CheckAccess(ctx *AccessContext) bool {
 		return e.config.IsDefaultAllow()
 	}
 
+	now := e.clock()
 	for _, rule := range e.config.Rules {
 		// Check if the rule matches the target DN
 		if !e.matcher.MatchesTarget(rule, ctx.TargetDN) {
@@ -90,6 +104,16 @@ func (e *Evaluator) CheckAccess(ctx *AccessContext) bool {
 			continue
 		}
 
+		// Check if the client connection satisfies the rule's constraints
+		if !e.matcher.MatchesConnection(rule, ctx.Connection) {
+			continue
+		}
+
+		// Check if the rule is in effect at this time
+		if !rule.ActiveAt(now) {
+			continue
+		}
+
 		// Check if the rule applies to the requested operation
 		if !rule.Rights.Has(ctx.Operation) {
 			continue
@@ -114,6 +138,7 @@ func (e *Evaluator) CheckAttributeAccess(ctx *AccessContext, attr string) bool {
 		return e.config.IsDefaultAllow()
 	}
 
+	now := e.clock()
 	for _, rule := range e.config.Rules {
 		// Check if the rule matches the target DN
 		if !e.matcher.MatchesTarget(rule, ctx.TargetDN) {
@@ -125,6 +150,16 @@ func (e *Evaluator) CheckAttributeAccess(ctx *AccessContext, attr string) bool {
 			continue
 		}
 
+		// Check if the client connection satisfies the rule's constraints
+		if !e.matcher.MatchesConnection(rule, ctx.Connection) {
+			continue
+		}
+
+		// Check if the rule is in effect at this time
+		if !rule.ActiveAt(now) {
+			continue
+		}
+
 		// Check if the rule applies to this attribute
 		if !rule.AppliesToAttribute(attr) {
 			continue
@@ -164,9 +199,10 @@ func (e *Evaluator) FilterAttributes(ctx *AccessContext, entry *Entry) *Entry {
 
 	// Create a read context for attribute filtering
 	readCtx := &AccessContext{
-		BindDN:    ctx.BindDN,
-		TargetDN:  entry.DN,
-		Operation: Read,
+		BindDN:     ctx.BindDN,
+		TargetDN:   entry.DN,
+		Operation:  Read,
+		Connection: ctx.Connection,
 	}
 
 	filtered := NewEntry(entry.DN)
@@ -194,9 +230,10 @@ func (e *Evaluator) FilterAttributeList(ctx *AccessContext, attrs []string) []st
 	}
 
 	readCtx := &AccessContext{
-		BindDN:    ctx.BindDN,
-		TargetDN:  ctx.TargetDN,
-		Operation: Read,
+		BindDN:     ctx.BindDN,
+		TargetDN:   ctx.TargetDN,
+		Operation:  Read,
+		Connection: ctx.Connection,
 	}
 
 	filtered := make([]string, 0, len(attrs))
@@ -209,6 +246,11 @@ func (e *Evaluator) FilterAttributeList(ctx *AccessContext, attrs []string) []st
 	return filtered
 }
 
+// SetGroupResolver sets the resolver used for group subjects.
+func (e *Evaluator) SetGroupResolver(groups GroupResolver) {
+	e.matcher.Groups = groups
+}
+
 // GetConfig returns the evaluator's configuration.
 func (e *Evaluator) GetConfig() *Config {
 	return e.config
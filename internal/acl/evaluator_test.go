@@ -700,3 +700,75 @@ func TestGetSetConfig(t *testing.T) {
 		}
 	})
 }
+
+func TestCheckAccess_ConnectionConstraints(t *testing.T) {
+	config := NewConfig()
+	config.SetDefaultPolicy("deny")
+
+	// Writes only from the admin network over TLS with a simple bind
+	config.AddRule(NewACL("ou=users,dc=example,dc=com", "authenticated", Write).
+		WithSourceCIDRs("10.0.0.0/8", "192.168.1.10").
+		WithRequireTLS(true).
+		WithMinSSF(128).
+		WithAuthMethod(AuthMethodSimple))
+	// Everyone else falls through to read-only access
+	config.AddRule(NewACL("ou=users,dc=example,dc=com", "authenticated", Read))
+
+	e := NewEvaluator(config)
+	bindDN := "uid=admin,dc=example,dc=com"
+	target := "uid=alice,ou=users,dc=example,dc=com"
+	admin := ConnectionInfo{RemoteIP: "10.1.2.3", TLS: true, SSF: 256, AuthMethod: AuthMethodSimple}
+
+	tests := []struct {
+		name     string
+		modify   func(*ConnectionInfo)
+		expected bool
+	}{
+		{"all constraints met", func(*ConnectionInfo) {}, true},
+		{"single host", func(c *ConnectionInfo) { c.RemoteIP = "192.168.1.10" }, true},
+		{"outside network", func(c *ConnectionInfo) { c.RemoteIP = "203.0.113.7" }, false},
+		{"unknown address", func(c *ConnectionInfo) { c.RemoteIP = "" }, false},
+		{"cleartext", func(c *ConnectionInfo) { c.TLS, c.SSF = false, 0 }, false},
+		{"weak cipher", func(c *ConnectionInfo) { c.SSF = 112 }, false},
+		{"other auth method", func(c *ConnectionInfo) { c.AuthMethod = AuthMethodSASLExternal }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := admin
+			tt.modify(&info)
+			ctx := NewAccessContext(bindDN, target, Write).WithConnection(info)
+			if result := e.CheckAccess(ctx); result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+
+			// The constrained rule falls through to the read rule
+			readCtx := NewAccessContext(bindDN, target, Read).WithConnection(info)
+			if !e.CheckAccess(readCtx) {
+				t.Error("expected read allowed by the unconstrained rule")
+			}
+		})
+	}
+}
+
+func TestFilterAttributes_ConnectionConstraints(t *testing.T) {
+	config := NewConfig()
+	config.SetDefaultPolicy("deny")
+	config.AddRule(NewACL("*", "*", Read).WithAttributes("userPassword").WithRequireTLS(true))
+	config.AddRule(NewACL("*", "*", Read).WithAttributes("cn"))
+
+	e := NewEvaluator(config)
+	entry := NewEntry("uid=alice,dc=example,dc=com")
+	entry.SetAttribute("cn", "Alice")
+	entry.SetAttribute("userPassword", "secret")
+
+	ctx := NewAccessContext("", entry.DN, Read)
+	if filtered := e.FilterAttributes(ctx, entry); filtered.HasAttribute("userPassword") {
+		t.Error("expected userPassword hidden on cleartext connection")
+	}
+
+	ctx.WithConnection(ConnectionInfo{TLS: true, SSF: 256})
+	if filtered := e.FilterAttributes(ctx, entry); !filtered.HasAttribute("userPassword") {
+		t.Error("expected userPassword visible over TLS")
+	}
+}
@@ -0,0 +1,271 @@
+package acl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Reasons reported for a rule in an Explanation.
+const (
+	// ReasonTargetMismatch means the rule target does not cover the target DN.
+	ReasonTargetMismatch = "target mismatch"
+
+	// ReasonSubjectMismatch means the rule subject does not cover the bind DN.
+	ReasonSubjectMismatch = "subject mismatch"
+
+	// ReasonConnectionMismatch means the client connection does not satisfy
+	// the rule's source, TLS, security strength or auth method constraints.
+	ReasonConnectionMismatch = "connection mismatch"
+
+	// ReasonOutsideTimeRange means the check was made outside the rule's
+	// time range.
+	ReasonOutsideTimeRange = "outside time range"
+
+	// ReasonAttributeNotCovered means the rule does not list the attribute.
+	ReasonAttributeNotCovered = "attribute not covered"
+
+	// ReasonRightNotGranted means the rule does not include the operation.
+	ReasonRightNotGranted = "operation not covered"
+
+	// ReasonAllowHit means the rule matched and grants access.
+	ReasonAllowHit = "allow hit"
+
+	// ReasonDenyHit means the rule matched and denies access.
+	ReasonDenyHit = "deny hit"
+)
+
+// DecisionDefaultPolicy is the Explanation.DecisionRule value when no rule
+// matched and the default policy decided.
+const DecisionDefaultPolicy = -1
+
+// RuleExplanation describes how a single rule was evaluated.
+type RuleExplanation struct {
+	// Index is the position of the rule in the configuration.
+	Index int
+
+	// Rule is the evaluated rule.
+	Rule *ACL
+
+	// Matched is true if the rule decided the request.
+	Matched bool
+
+	// Reason is why the rule did or did not match.
+	Reason string
+}
+
+// Explanation records how an access check was decided.
+type Explanation struct {
+	// Rules are the rules considered, in evaluation order. Rules after the
+	// deciding rule are never considered and are not listed.
+	Rules []RuleExplanation
+
+	// Allowed is the final decision.
+	Allowed bool
+
+	// DecisionRule is the index of the deciding rule, or
+	// DecisionDefaultPolicy if no rule matched.
+	DecisionRule int
+
+	// DefaultPolicy is the default policy at the time of the check.
+	DefaultPolicy string
+}
+
+// Source returns a human-readable description of what decided the request.
+func (x *Explanation) Source() string {
+	if x.DecisionRule == DecisionDefaultPolicy {
+		return "default policy (" + x.DefaultPolicy + ")"
+	}
+	return fmt.Sprintf("rule %d", x.DecisionRule)
+}
+
+// EvaluateExplain performs the same check as CheckAccess and records why each
+// rule did or did not match. If ctx.Attributes is set, the first attribute is
+// checked as in CheckAttributeAccess.
+func (e *Evaluator) EvaluateExplain(ctx *AccessContext) *Explanation {
+	x := &Explanation{
+		DecisionRule:  DecisionDefaultPolicy,
+		DefaultPolicy: e.config.DefaultPolicy,
+		Allowed:       e.config.IsDefaultAllow(),
+	}
+	if ctx == nil {
+		return x
+	}
+
+	attr := ""
+	if len(ctx.Attributes) > 0 {
+		attr = ctx.Attributes[0]
+	}
+
+	now := e.clock()
+	for i, rule := range e.config.Rules {
+		re := RuleExplanation{Index: i, Rule: rule}
+
+		switch {
+		case !e.matcher.MatchesTarget(rule, ctx.TargetDN):
+			re.Reason = ReasonTargetMismatch
+		case !e.matcher.MatchesSubject(rule, ctx.BindDN, ctx.TargetDN):
+			re.Reason = ReasonSubjectMismatch
+		case !e.matcher.MatchesConnection(rule, ctx.Connection):
+			re.Reason = ReasonConnectionMismatch
+		case !rule.ActiveAt(now):
+			re.Reason = ReasonOutsideTimeRange
+		case attr != "" && !rule.AppliesToAttribute(attr):
+			re.Reason = ReasonAttributeNotCovered
+		case !rule.Rights.Has(ctx.Operation):
+			re.Reason = ReasonRightNotGranted
+		default:
+			re.Matched = true
+			if rule.Deny {
+				re.Reason = ReasonDenyHit
+			} else {
+				re.Reason = ReasonAllowHit
+			}
+		}
+
+		x.Rules = append(x.Rules, re)
+		if re.Matched {
+			x.DecisionRule = i
+			x.Allowed = !rule.Deny
+			break
+		}
+	}
+
+	return x
+}
+
+// FindShadowedRules returns a warning for every rule that can never match
+// because an earlier rule covers all of its targets, subjects, rights and
+// attributes.
+func FindShadowedRules(config *Config) []string {
+	if config == nil {
+		return nil
+	}
+
+	var warnings []string
+	for j, later := range config.Rules {
+		if later == nil {
+			continue
+		}
+		for i, earlier := range config.Rules[:j] {
+			if earlier != nil && ruleCovers(earlier, later) {
+				warnings = append(warnings, fmt.Sprintf(
+					"rule %d is unreachable: shadowed by rule %d", j, i))
+				break
+			}
+		}
+	}
+	return warnings
+}
+
+// ruleCovers reports whether every request matched by b is matched by a.
+func ruleCovers(a, b *ACL) bool {
+	return targetCovers(a, b) &&
+		subjectCovers(a.Subject, b.Subject) &&
+		b.Rights&^a.Rights == 0 &&
+		attributesCover(a, b) &&
+		connectionCovers(a, b) &&
+		(a.TimeRange == nil || a.TimeRange.Equal(b.TimeRange))
+}
+
+// targetCovers reports whether a's target and scope include every DN
+// included by b's.
+func targetCovers(a, b *ACL) bool {
+	at := strings.ToLower(a.Target)
+	bt := strings.ToLower(b.Target)
+
+	if at == "*" {
+		return true
+	}
+	if bt == "*" {
+		return false
+	}
+	if at == bt && a.Scope == b.Scope {
+		return true
+	}
+
+	m := NewMatcher()
+	switch a.Scope {
+	case ScopeSubtree:
+		return m.isSubtreeMatch(at, bt)
+	case ScopeOne:
+		return b.Scope == ScopeBase && m.isImmediateChild(at, bt)
+	default:
+		return false
+	}
+}
+
+// subjectCovers reports whether subject a matches every bind DN matched by b.
+func subjectCovers(a, b string) bool {
+	a = strings.ToLower(a)
+	b = strings.ToLower(b)
+
+	switch {
+	case a == "*" || a == b:
+		return true
+	case a == "authenticated":
+		return b != "anonymous" && b != "*"
+	default:
+		return false
+	}
+}
+
+// connectionCovers reports whether a's connection constraints are satisfied
+// by every connection satisfying b's.
+func connectionCovers(a, b *ACL) bool {
+	if a.RequireTLS && !b.RequireTLS {
+		return false
+	}
+	if a.MinSSF > b.MinSSF {
+		return false
+	}
+	if a.AuthMethod != "" && !strings.EqualFold(a.AuthMethod, b.AuthMethod) {
+		return false
+	}
+	if len(a.SourceCIDRs) == 0 {
+		return true
+	}
+	if len(b.SourceCIDRs) == 0 {
+		return false
+	}
+
+	for _, bs := range b.SourceCIDRs {
+		bn, err := ParseSourceNetwork(bs)
+		if err != nil {
+			return false
+		}
+		bOnes, _ := bn.Mask.Size()
+
+		covered := false
+		for _, as := range a.SourceCIDRs {
+			an, err := ParseSourceNetwork(as)
+			if err != nil {
+				continue
+			}
+			aOnes, _ := an.Mask.Size()
+			if len(an.IP) == len(bn.IP) && aOnes <= bOnes && an.Contains(bn.IP) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// attributesCover reports whether a applies to every attribute b applies to.
+func attributesCover(a, b *ACL) bool {
+	if a.AppliesToAttribute("*") {
+		return true
+	}
+	if len(b.Attributes) == 0 {
+		return false
+	}
+	for _, attr := range b.Attributes {
+		if !a.AppliesToAttribute(attr) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,122 @@
+package acl
+
+import (
+	"testing"
+)
+
+func explainTestConfig() *Config {
+	config := NewConfig()
+	config.AddRule(NewACL("*", "cn=admin,dc=example,dc=com", All))
+	config.AddRule(NewACL("ou=groups,dc=example,dc=com", "authenticated", Read))
+	config.AddRule(NewACL("*", "*", Read).WithAttributes("userPassword").WithDeny(true))
+	config.AddRule(NewACL("ou=users,dc=example,dc=com", "authenticated", Read|Search))
+	return config
+}
+
+func TestEvaluateExplain(t *testing.T) {
+	e := NewEvaluator(explainTestConfig())
+
+	t.Run("deny hit on attribute", func(t *testing.T) {
+		ctx := NewAccessContext("uid=alice,ou=users,dc=example,dc=com", "uid=bob,ou=users,dc=example,dc=com", Read).
+			WithAttributes("userPassword")
+		x := e.EvaluateExplain(ctx)
+
+		if x.Allowed || x.DecisionRule != 2 || x.Source() != "rule 2" {
+			t.Fatalf("got allowed=%v rule=%d, want denied by rule 2", x.Allowed, x.DecisionRule)
+		}
+		want := []string{ReasonSubjectMismatch, ReasonTargetMismatch, ReasonDenyHit}
+		if len(x.Rules) != len(want) {
+			t.Fatalf("got %d rules considered, want %d", len(x.Rules), len(want))
+		}
+		for i, reason := range want {
+			if x.Rules[i].Reason != reason || x.Rules[i].Matched != (i == 2) {
+				t.Errorf("rule %d: got %q matched=%v, want %q", i, x.Rules[i].Reason, x.Rules[i].Matched, reason)
+			}
+		}
+		if x.Allowed != e.CheckAttributeAccess(ctx, "userPassword") {
+			t.Error("explanation disagrees with CheckAttributeAccess")
+		}
+	})
+
+	t.Run("attribute not covered", func(t *testing.T) {
+		ctx := NewAccessContext("uid=alice,ou=users,dc=example,dc=com", "uid=bob,ou=users,dc=example,dc=com", Read).
+			WithAttributes("mail")
+		x := e.EvaluateExplain(ctx)
+
+		if !x.Allowed || x.DecisionRule != 3 {
+			t.Fatalf("got allowed=%v rule=%d, want allowed by rule 3", x.Allowed, x.DecisionRule)
+		}
+		if x.Rules[2].Reason != ReasonAttributeNotCovered {
+			t.Errorf("rule 2 reason = %q, want %q", x.Rules[2].Reason, ReasonAttributeNotCovered)
+		}
+	})
+
+	t.Run("default policy", func(t *testing.T) {
+		ctx := NewAccessContext("uid=alice,ou=users,dc=example,dc=com", "uid=bob,ou=users,dc=example,dc=com", Write)
+		x := e.EvaluateExplain(ctx)
+
+		if x.Allowed || x.DecisionRule != DecisionDefaultPolicy {
+			t.Fatalf("got allowed=%v rule=%d, want default deny", x.Allowed, x.DecisionRule)
+		}
+		if x.Source() != "default policy (deny)" {
+			t.Errorf("Source() = %q", x.Source())
+		}
+		if len(x.Rules) != 4 || x.Rules[3].Reason != ReasonRightNotGranted {
+			t.Errorf("rules = %+v, want all 4 with last not granting write", x.Rules)
+		}
+	})
+}
+
+func TestFindShadowedRules(t *testing.T) {
+	if warnings := FindShadowedRules(explainTestConfig()); len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+
+	config := explainTestConfig()
+	// Covered by rule 3: same subtree, narrower rights.
+	config.AddRule(NewACL("uid=bob,ou=users,dc=example,dc=com", "uid=alice,ou=users,dc=example,dc=com", Read).WithScope(ScopeBase))
+	// Not covered: rule 3 does not grant write.
+	config.AddRule(NewACL("ou=users,dc=example,dc=com", "authenticated", Write))
+	// Covered by rule 2 for the same attribute.
+	config.AddRule(NewACL("ou=users,dc=example,dc=com", "anonymous", Read).WithAttributes("userPassword"))
+
+	warnings := FindShadowedRules(config)
+	want := []string{
+		"rule 4 is unreachable: shadowed by rule 3",
+		"rule 6 is unreachable: shadowed by rule 2",
+	}
+	if len(warnings) != len(want) {
+		t.Fatalf("got warnings %v, want %v", warnings, want)
+	}
+	for i := range want {
+		if warnings[i] != want[i] {
+			t.Errorf("warning %d = %q, want %q", i, warnings[i], want[i])
+		}
+	}
+}
+
+func TestFindShadowedRules_ConnectionConstraints(t *testing.T) {
+	config := NewConfig()
+	config.AddRule(NewACL("*", "authenticated", Write).WithSourceCIDRs("10.0.0.0/8").WithRequireTLS(true))
+	// Not covered: no TLS requirement
+	config.AddRule(NewACL("*", "authenticated", Write).WithSourceCIDRs("10.1.0.0/16"))
+	// Covered: narrower network, TLS required
+	config.AddRule(NewACL("*", "authenticated", Write).WithSourceCIDRs("10.1.0.0/16").WithRequireTLS(true).WithMinSSF(128))
+	// Not covered: any source
+	config.AddRule(NewACL("*", "authenticated", Write).WithRequireTLS(true))
+
+	warnings := FindShadowedRules(config)
+	if len(warnings) != 1 || warnings[0] != "rule 2 is unreachable: shadowed by rule 0" {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+}
+
+func TestEvaluateExplain_ConnectionMismatch(t *testing.T) {
+	config := NewConfig()
+	config.AddRule(NewACL("*", "*", Read).WithRequireTLS(true))
+
+	x := NewEvaluator(config).EvaluateExplain(NewAccessContext("", "dc=example,dc=com", Read))
+	if x.Allowed || len(x.Rules) != 1 || x.Rules[0].Reason != ReasonConnectionMismatch {
+		t.Errorf("unexpected explanation: %+v", x)
+	}
+}
@@ -20,6 +20,10 @@ var (
 	ErrMissingTarget  = errors.New("acl: missing target")
 	ErrMissingSubject = errors.New("acl: missing subject")
 	ErrMissingRights  = errors.New("acl: missing rights")
+
+	ErrInvalidSourceCIDR = errors.New("acl: invalid source CIDR")
+	ErrInvalidSSF        = errors.New("acl: invalid minimum SSF")
+	ErrInvalidAuthMethod = errors.New("acl: invalid auth method")
 )
 
 // FileConfig represents the ACL file structure.
@@ -37,6 +41,22 @@ type FileRuleConfig struct {
 	Rights     []string `yaml:"rights"`
 	Attributes []string `yaml:"attributes"`
 	Deny       bool     `yaml:"deny"`
+
+	SourceCIDRs []string `yaml:"sourceCIDRs"`
+	RequireTLS  bool     `yaml:"requireTLS"`
+	// MinSSF is kept as text so that convertRule can report invalid values.
+	MinSSF     string `yaml:"minSSF"`
+	AuthMethod string `yaml:"authMethod"`
+
+	TimeRange *FileTimeRangeConfig `yaml:"timeRange"`
+}
+
+// FileTimeRangeConfig represents the time range of a rule in the ACL file.
+type FileTimeRangeConfig struct {
+	Start    string   `yaml:"start"`
+	End      string   `yaml:"end"`
+	Weekdays []string `yaml:"weekdays"`
+	Timezone string   `yaml:"timezone"`
 }
 
 // LoadFromFile loads ACL configuration from a YAML file.
@@ -98,7 +118,11 @@ func parseACLYAMLToFileConfig(data []byte) (*FileConfig, error) {
 	var inRules bool
 	var inAttributes bool
 	var inRights bool
+	var inSourceCIDRs bool
+	var inTimeRange bool
+	var inWeekdays bool
 	var ruleIndent int
+	var timeRangeIndent int
 
 	for _, line := range lines {
 		// Skip empty lines and comments
@@ -114,6 +138,8 @@ func parseACLYAMLToFileConfig(data []byte) (*FileConfig, error) {
 			inRules = false
 			inAttributes = false
 			inRights = false
+			inSourceCIDRs = false
+			inTimeRange = false
 
 			if strings.HasPrefix(trimmed, "version:") {
 				val := strings.TrimSpace(strings.TrimPrefix(trimmed, "version:"))
@@ -148,9 +174,11 @@ func parseACLYAMLToFileConfig(data []byte) (*FileConfig, error) {
 					currentRule = &FileRuleConfig{}
 					inAttributes = false
 					inRights = false
+					inSourceCIDRs = false
+					inTimeRange = false
 					ruleIndent = indent
 
-					parseRuleKeyValue(currentRule, rest, &inAttributes, &inRights)
+					parseRuleKeyValue(currentRule, rest, &inAttributes, &inRights, &inSourceCIDRs)
 					continue
 				}
 
@@ -161,13 +189,30 @@ func parseACLYAMLToFileConfig(data []byte) (*FileConfig, error) {
 				currentRule = &FileRuleConfig{}
 				inAttributes = false
 				inRights = false
+				inSourceCIDRs = false
+				inTimeRange = false
 				ruleIndent = indent
 				continue
 			}
 
 			// Rule properties or list items
 			if currentRule != nil {
-				// List items for attributes or rights (deeper indent)
+				// Keys and weekdays of a timeRange block (deeper indent)
+				if inTimeRange && indent > timeRangeIndent {
+					if strings.HasPrefix(trimmed, "- ") {
+						if inWeekdays {
+							val := strings.TrimSpace(strings.TrimPrefix(trimmed, "- "))
+							val = strings.Trim(val, "\"'")
+							currentRule.TimeRange.Weekdays = append(currentRule.TimeRange.Weekdays, val)
+						}
+					} else {
+						inWeekdays = parseTimeRangeKeyValue(currentRule.TimeRange, trimmed)
+					}
+					continue
+				}
+				inTimeRange = false
+
+				// List items for attributes, rights or source CIDRs (deeper indent)
 				if strings.HasPrefix(trimmed, "- ") && indent > ruleIndent {
 					val := strings.TrimSpace(strings.TrimPrefix(trimmed, "- "))
 					val = strings.Trim(val, "\"'")
@@ -175,13 +220,25 @@ func parseACLYAMLToFileConfig(data []byte) (*FileConfig, error) {
 						currentRule.Attributes = append(currentRule.Attributes, val)
 					} else if inRights {
 						currentRule.Rights = append(currentRule.Rights, val)
+					} else if inSourceCIDRs {
+						currentRule.SourceCIDRs = append(currentRule.SourceCIDRs, val)
 					}
 					continue
 				}
 
 				// Key-value pairs for rule properties
+				if trimmed == "timeRange:" {
+					currentRule.TimeRange = &FileTimeRangeConfig{}
+					inAttributes = false
+					inRights = false
+					inSourceCIDRs = false
+					inTimeRange = true
+					inWeekdays = false
+					timeRangeIndent = indent
+					continue
+				}
 				if strings.Contains(trimmed, ":") && !strings.HasPrefix(trimmed, "- ") {
-					parseRuleKeyValue(currentRule, trimmed, &inAttributes, &inRights)
+					parseRuleKeyValue(currentRule, trimmed, &inAttributes, &inRights, &inSourceCIDRs)
 				}
 			}
 		}
@@ -196,9 +253,10 @@ func parseACLYAMLToFileConfig(data []byte) (*FileConfig, error) {
 }
 
 // parseRuleKeyValue parses a key: value pair for a rule.
-func parseRuleKeyValue(rule *FileRuleConfig, line string, inAttributes, inRights *bool) {
+func parseRuleKeyValue(rule *FileRuleConfig, line string, inAttributes, inRights, inSourceCIDRs *bool) {
 	*inAttributes = false
 	*inRights = false
+	*inSourceCIDRs = false
 
 	parts := strings.SplitN(line, ":", 2)
 	if len(parts) != 2 {
@@ -218,35 +276,72 @@ func parseRuleKeyValue(rule *FileRuleConfig, line string, inAttributes, inRights
 		rule.Scope = val
 	case "deny":
 		rule.Deny = val == "true" || val == "yes"
+	case "requireTLS":
+		rule.RequireTLS = val == "true" || val == "yes"
+	case "minSSF":
+		rule.MinSSF = val
+	case "authMethod":
+		rule.AuthMethod = val
 	case "rights":
-		*inRights = true
 		// Check for inline array: [read, write]
-		if strings.HasPrefix(val, "[") && strings.HasSuffix(val, "]") {
-			inner := val[1 : len(val)-1]
-			for _, r := range strings.Split(inner, ",") {
-				r = strings.TrimSpace(r)
-				r = strings.Trim(r, "\"'")
-				if r != "" {
-					rule.Rights = append(rule.Rights, r)
-				}
-			}
-			*inRights = false
-		}
+		items, inline := parseInlineList(val)
+		rule.Rights = append(rule.Rights, items...)
+		*inRights = !inline
 	case "attributes":
-		*inAttributes = true
-		// Check for inline array
-		if strings.HasPrefix(val, "[") && strings.HasSuffix(val, "]") {
-			inner := val[1 : len(val)-1]
-			for _, a := range strings.Split(inner, ",") {
-				a = strings.TrimSpace(a)
-				a = strings.Trim(a, "\"'")
-				if a != "" {
-					rule.Attributes = append(rule.Attributes, a)
-				}
-			}
-			*inAttributes = false
+		items, inline := parseInlineList(val)
+		rule.Attributes = append(rule.Attributes, items...)
+		*inAttributes = !inline
+	case "sourceCIDRs":
+		items, inline := parseInlineList(val)
+		rule.SourceCIDRs = append(rule.SourceCIDRs, items...)
+		*inSourceCIDRs = !inline
+	}
+}
+
+// parseTimeRangeKeyValue parses a key: value pair of a timeRange block. It
+// returns true if weekday list items may follow.
+func parseTimeRangeKeyValue(tr *FileTimeRangeConfig, line string) bool {
+	parts := strings.SplitN(line, ":", 2)
+	if len(parts) != 2 {
+		return false
+	}
+
+	key := strings.TrimSpace(parts[0])
+	val := strings.TrimSpace(parts[1])
+	val = strings.Trim(val, "\"'")
+
+	switch key {
+	case "start":
+		tr.Start = val
+	case "end":
+		tr.End = val
+	case "timezone":
+		tr.Timezone = val
+	case "weekdays":
+		items, inline := parseInlineList(val)
+		tr.Weekdays = append(tr.Weekdays, items...)
+		return !inline
+	}
+	return false
+}
+
+// parseInlineList parses an inline array such as [a, b]. It returns false
+// if val is not an inline array, in which case list items may follow.
+func parseInlineList(val string) ([]string, bool) {
+	if !strings.HasPrefix(val, "[") || !strings.HasSuffix(val, "]") {
+		return nil, false
+	}
+
+	var items []string
+	inner := val[1 : len(val)-1]
+	for _, item := range strings.Split(inner, ",") {
+		item = strings.TrimSpace(item)
+		item = strings.Trim(item, "\"'")
+		if item != "" {
+			items = append(items, item)
 		}
 	}
+	return items, true
 }
 
 // convertFileConfig converts FileConfig to acl.Config.
@@ -315,6 +410,42 @@ func convertRule(r *FileRuleConfig, index int) (*ACL, error) {
 	// Deny
 	acl.WithDeny(r.Deny)
 
+	// Connection constraints
+	for _, cidr := range r.SourceCIDRs {
+		if _, err := ParseSourceNetwork(cidr); err != nil {
+			return nil, fmt.Errorf("rule %d: %w: %s", index, ErrInvalidSourceCIDR, cidr)
+		}
+	}
+	if len(r.SourceCIDRs) > 0 {
+		acl.WithSourceCIDRs(r.SourceCIDRs...)
+	}
+
+	if r.MinSSF != "" {
+		ssf, err := strconv.Atoi(r.MinSSF)
+		if err != nil || ssf < 0 {
+			return nil, fmt.Errorf("rule %d: %w: %s", index, ErrInvalidSSF, r.MinSSF)
+		}
+		acl.WithMinSSF(ssf)
+	}
+
+	if r.AuthMethod != "" {
+		method, err := ParseAuthMethod(r.AuthMethod)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", index, err)
+		}
+		acl.WithAuthMethod(method)
+	}
+
+	acl.WithRequireTLS(r.RequireTLS)
+
+	if r.TimeRange != nil {
+		tr, err := ParseTimeRange(r.TimeRange.Start, r.TimeRange.End, r.TimeRange.Weekdays, r.TimeRange.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", index, err)
+		}
+		acl.TimeRange = tr
+	}
+
 	return acl, nil
 }
 
@@ -359,3 +490,15 @@ func ParseScope(s string) (Scope, error) {
 		return 0, fmt.Errorf("%w: %s", ErrInvalidScope, s)
 	}
 }
+
+// ParseAuthMethod converts a string auth method to its canonical form.
+func ParseAuthMethod(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case AuthMethodSimple:
+		return AuthMethodSimple, nil
+	case AuthMethodSASLExternal, "external":
+		return AuthMethodSASLExternal, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidAuthMethod, s)
+	}
+}
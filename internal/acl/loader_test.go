@@ -1,6 +1,7 @@
 package acl
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -396,3 +397,118 @@ func createTempFile(t *testing.T, content string) string {
 	}
 	return tmpFile
 }
+
+func TestParseACLYAML_ConnectionConstraints(t *testing.T) {
+	yaml := `
+version: 1
+defaultPolicy: deny
+rules:
+  - target: "ou=users,dc=example,dc=com"
+    subject: authenticated
+    rights: [write]
+    sourceCIDRs:
+      - 10.0.0.0/8
+      - "192.168.1.10"
+    requireTLS: true
+    minSSF: 128
+    authMethod: SASL-External
+  - target: "*"
+    subject: "*"
+    rights: [read]
+    sourceCIDRs: [2001:db8::/32]
+`
+
+	config, err := ParseACLYAML([]byte(yaml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(config.Rules) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(config.Rules))
+	}
+
+	rule := config.Rules[0]
+	if len(rule.SourceCIDRs) != 2 || rule.SourceCIDRs[0] != "10.0.0.0/8" || rule.SourceCIDRs[1] != "192.168.1.10" {
+		t.Errorf("unexpected sourceCIDRs: %v", rule.SourceCIDRs)
+	}
+	if !rule.RequireTLS {
+		t.Error("expected requireTLS")
+	}
+	if rule.MinSSF != 128 {
+		t.Errorf("expected minSSF 128, got %d", rule.MinSSF)
+	}
+	if rule.AuthMethod != AuthMethodSASLExternal {
+		t.Errorf("expected authMethod %q, got %q", AuthMethodSASLExternal, rule.AuthMethod)
+	}
+	if !rule.Rights.Has(Write) {
+		t.Errorf("expected write right, got %v", rule.Rights)
+	}
+
+	if got := config.Rules[1].SourceCIDRs; len(got) != 1 || got[0] != "2001:db8::/32" {
+		t.Errorf("unexpected inline sourceCIDRs: %v", got)
+	}
+}
+
+func TestParseACLYAML_TimeRange(t *testing.T) {
+	yaml := `
+rules:
+  - target: "*"
+    subject: authenticated
+    timeRange:
+      start: "09:00"
+      end: "17:00"
+      weekdays:
+        - mon-fri
+      timezone: UTC
+    rights: [read]
+  - target: "*"
+    subject: "*"
+    rights: [read]
+    timeRange:
+      start: "22:00"
+      end: "06:00"
+      weekdays: [sat, sunday]
+`
+
+	config, err := ParseACLYAML([]byte(yaml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(config.Rules) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(config.Rules))
+	}
+
+	tr := config.Rules[0].TimeRange
+	if tr == nil || tr.String() != "mon,tue,wed,thu,fri 09:00-17:00 UTC" {
+		t.Errorf("unexpected time range: %v", tr)
+	}
+	if !config.Rules[0].Rights.Has(Read) {
+		t.Errorf("expected read right after timeRange block, got %v", config.Rules[0].Rights)
+	}
+	if tr := config.Rules[1].TimeRange; tr == nil || tr.String() != "sat,sun 22:00-06:00" {
+		t.Errorf("unexpected time range: %v", tr)
+	}
+}
+
+func TestParseACLYAML_InvalidConnectionConstraints(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		err  error
+	}{
+		{"invalid CIDR", "sourceCIDRs: [10.0.0.0/33]", ErrInvalidSourceCIDR},
+		{"invalid SSF", "minSSF: high", ErrInvalidSSF},
+		{"negative SSF", "minSSF: -1", ErrInvalidSSF},
+		{"invalid auth method", "authMethod: kerberos", ErrInvalidAuthMethod},
+		{"invalid time range", "timeRange:\n      start: \"9am\"\n      end: \"17:00\"", ErrInvalidTimeRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yaml := "rules:\n  - target: \"*\"\n    subject: \"*\"\n    rights: [read]\n    " + tt.line + "\n"
+			_, err := ParseACLYAML([]byte(yaml))
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -29,6 +29,9 @@ type Manager struct {
 
 	// Version for Raft sync
 	version uint64
+
+	// Resolver for group subjects, kept across reloads
+	groups GroupResolver
 }
 
 // ManagerConfig holds configuration for ACLManager.
@@ -82,7 +85,8 @@ func NewManager(cfg *ManagerConfig) (*Manager, error) {
 		m.logInfo("ACL using default config", "defaultPolicy", m.config.DefaultPolicy)
 	}
 
-	m.evaluator = NewEvaluator(m.config)
+	m.evaluator = m.newEvaluator(m.config)
+	m.Validate()
 
 	return m, nil
 }
@@ -121,7 +125,7 @@ func (m *Manager) Reload() error {
 	m.mu.Lock()
 	oldRuleCount := len(m.config.Rules)
 	m.config = newConfig
-	m.evaluator = NewEvaluator(newConfig)
+	m.evaluator = m.newEvaluator(newConfig)
 	m.lastReload = time.Now()
 	m.lastError = nil
 	m.mu.Unlock()
@@ -133,10 +137,41 @@ func (m *Manager) Reload() error {
 		"newRules", len(newConfig.Rules),
 		"defaultPolicy", newConfig.DefaultPolicy,
 	)
+	m.Validate()
 
 	return nil
 }
 
+// Validate checks the current rules for rules that can never match because
+// an earlier, broader rule shadows them. Each finding is logged as a warning
+// and returned.
+func (m *Manager) Validate() []string {
+	m.mu.RLock()
+	warnings := FindShadowedRules(m.config)
+	m.mu.RUnlock()
+
+	for _, w := range warnings {
+		m.logWarn("ACL rule warning", "warning", w)
+	}
+	return warnings
+}
+
+// SetGroupResolver sets the resolver used for group subjects. It applies to
+// the current rules and to rules loaded later.
+func (m *Manager) SetGroupResolver(groups GroupResolver) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.groups = groups
+	m.evaluator.SetGroupResolver(groups)
+}
+
+// newEvaluator creates an evaluator for config using the group resolver.
+func (m *Manager) newEvaluator(config *Config) *Evaluator {
+	e := NewEvaluator(config)
+	e.SetGroupResolver(m.groups)
+	return e
+}
+
 // GetEvaluator returns the current ACL evaluator.
 // Thread-safe for concurrent access.
 func (m *Manager) GetEvaluator() *Evaluator {
@@ -161,6 +196,13 @@ func (m *Manager) CheckAccess(ctx *AccessContext) bool {
 	return m.evaluator.CheckAccess(ctx)
 }
 
+// Explain evaluates the request and reports why it was allowed or denied.
+func (m *Manager) Explain(ctx *AccessContext) *Explanation {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.evaluator.EvaluateExplain(ctx)
+}
+
 // CheckAttributeAccess checks if access to a specific attribute is allowed.
 func (m *Manager) CheckAttributeAccess(ctx *AccessContext, attr string) bool {
 	m.mu.RLock()
@@ -271,7 +313,14 @@ func (m *Manager) logInfo(msg string, keysAndValues ...interface{}) {
 // logInfoFromRaft logs Raft-applied events without feeding them back into log replication.
 func (m *Manager) logInfoFromRaft(msg string, keysAndValues ...interface{}) {
 	if m.logger != nil {
-		m.logger.WithSource("raft").Info(msg, keysAndValues...)
+		m.logger.WithComponent("raft").Info(msg, keysAndValues...)
+	}
+}
+
+// logWarn logs a warning message if logger is available.
+func (m *Manager) logWarn(msg string, keysAndValues ...interface{}) {
+	if m.logger != nil {
+		m.logger.Warn(msg, keysAndValues...)
 	}
 }
 
@@ -307,7 +356,7 @@ func (m *Manager) AddRule(rule *ACL, index int) error {
 		m.config.Rules = append(m.config.Rules[:index], append([]*ACL{rule}, m.config.Rules[index:]...)...)
 	}
 
-	m.evaluator = NewEvaluator(m.config)
+	m.evaluator = m.newEvaluator(m.config)
 	m.logInfo("ACL rule added", "index", index, "target", rule.Target, "subject", rule.Subject)
 
 	return nil
@@ -336,7 +385,7 @@ func (m *Manager) UpdateRule(index int, rule *ACL) error {
 	}
 
 	m.config.Rules[index] = rule
-	m.evaluator = NewEvaluator(m.config)
+	m.evaluator = m.newEvaluator(m.config)
 	m.logInfo("ACL rule updated", "index", index, "target", rule.Target, "subject", rule.Subject)
 
 	return nil
@@ -352,7 +401,7 @@ func (m *Manager) DeleteRule(index int) error {
 	}
 
 	m.config.Rules = append(m.config.Rules[:index], m.config.Rules[index+1:]...)
-	m.evaluator = NewEvaluator(m.config)
+	m.evaluator = m.newEvaluator(m.config)
 	m.logInfo("ACL rule deleted", "index", index)
 
 	return nil
@@ -369,7 +418,7 @@ func (m *Manager) SetDefaultPolicy(policy string) error {
 	defer m.mu.Unlock()
 
 	m.config.DefaultPolicy = policy
-	m.evaluator = NewEvaluator(m.config)
+	m.evaluator = m.newEvaluator(m.config)
 	m.logInfo("ACL default policy changed", "policy", policy)
 
 	return nil
@@ -440,6 +489,32 @@ func (m *Manager) configToYAML() string {
 		if rule.Deny {
 			sb.WriteString("    deny: true\n")
 		}
+		if len(rule.SourceCIDRs) > 0 {
+			sb.WriteString("    sourceCIDRs:\n")
+			for _, c := range rule.SourceCIDRs {
+				sb.WriteString(fmt.Sprintf("      - %q\n", c))
+			}
+		}
+		if rule.RequireTLS {
+			sb.WriteString("    requireTLS: true\n")
+		}
+		if rule.MinSSF > 0 {
+			sb.WriteString(fmt.Sprintf("    minSSF: %d\n", rule.MinSSF))
+		}
+		if rule.AuthMethod != "" {
+			sb.WriteString(fmt.Sprintf("    authMethod: %s\n", rule.AuthMethod))
+		}
+		if tr := rule.TimeRange; tr != nil {
+			sb.WriteString("    timeRange:\n")
+			sb.WriteString(fmt.Sprintf("      start: %q\n", tr.StartString()))
+			sb.WriteString(fmt.Sprintf("      end: %q\n", tr.EndString()))
+			if len(tr.Weekdays) > 0 {
+				sb.WriteString(fmt.Sprintf("      weekdays: [%s]\n", strings.Join(tr.WeekdayNames(), ", ")))
+			}
+			if name := tr.LocationName(); name != "" {
+				sb.WriteString(fmt.Sprintf("      timezone: %s\n", name))
+			}
+		}
 	}
 
 	return sb.String()
@@ -524,7 +599,7 @@ func (m *Manager) ApplyFullConfigFromRaft(rules []raft.ACLRuleData, defaultPolic
 
 	m.config.Rules = aclRules
 	m.config.DefaultPolicy = defaultPolicy
-	m.evaluator = NewEvaluator(m.config)
+	m.evaluator = m.newEvaluator(m.config)
 	m.lastReload = time.Now()
 	atomic.AddUint64(&m.reloadCount, 1)
 	atomic.AddUint64(&m.version, 1)
@@ -558,7 +633,7 @@ func (m *Manager) AddRuleFromRaft(ruleData *raft.ACLRuleData, index int) error {
 			append([]*ACL{rule}, m.config.Rules[index:]...)...)
 	}
 
-	m.evaluator = NewEvaluator(m.config)
+	m.evaluator = m.newEvaluator(m.config)
 	atomic.AddUint64(&m.version, 1)
 
 	m.logInfoFromRaft("ACL rule added from Raft", "index", index, "target", rule.Target)
@@ -585,7 +660,7 @@ func (m *Manager) UpdateRuleFromRaft(ruleData *raft.ACLRuleData, index int) erro
 	}
 
 	m.config.Rules[index] = rule
-	m.evaluator = NewEvaluator(m.config)
+	m.evaluator = m.newEvaluator(m.config)
 	atomic.AddUint64(&m.version, 1)
 
 	m.logInfoFromRaft("ACL rule updated from Raft", "index", index, "target", rule.Target)
@@ -603,7 +678,7 @@ func (m *Manager) DeleteRuleFromRaft(index int) error {
 	}
 
 	m.config.Rules = append(m.config.Rules[:index], m.config.Rules[index+1:]...)
-	m.evaluator = NewEvaluator(m.config)
+	m.evaluator = m.newEvaluator(m.config)
 	atomic.AddUint64(&m.version, 1)
 
 	m.logInfoFromRaft("ACL rule deleted from Raft", "index", index)
@@ -622,7 +697,7 @@ func (m *Manager) SetDefaultPolicyFromRaft(policy string) error {
 	defer m.mu.Unlock()
 
 	m.config.DefaultPolicy = policy
-	m.evaluator = NewEvaluator(m.config)
+	m.evaluator = m.newEvaluator(m.config)
 	atomic.AddUint64(&m.version, 1)
 
 	m.logInfoFromRaft("ACL default policy set from Raft", "policy", policy)
@@ -677,7 +752,7 @@ func (m *Manager) RestoreACLSnapshot(data []byte) error {
 
 	m.config.Rules = rules
 	m.config.DefaultPolicy = snapshot.DefaultPolicy
-	m.evaluator = NewEvaluator(m.config)
+	m.evaluator = m.newEvaluator(m.config)
 	atomic.StoreUint64(&m.version, snapshot.Version)
 
 	m.logInfoFromRaft("ACL restored from Raft snapshot",
@@ -695,6 +770,11 @@ func ruleDataToACL(data *raft.ACLRuleData) (*ACL, error) {
 		Subject:    data.Subject,
 		Attributes: data.Attributes,
 		Deny:       data.Deny,
+
+		SourceCIDRs: data.SourceCIDRs,
+		RequireTLS:  data.RequireTLS,
+		MinSSF:      data.MinSSF,
+		AuthMethod:  data.AuthMethod,
 	}
 
 	// Parse scope
@@ -731,11 +811,30 @@ func ruleDataToACL(data *raft.ACLRuleData) (*ACL, error) {
 		}
 	}
 
+	// Parse time range
+	if tr := data.TimeRange; tr != nil {
+		var err error
+		rule.TimeRange, err = ParseTimeRange(tr.Start, tr.End, tr.Weekdays, tr.Timezone)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return rule, nil
 }
 
 // aclToRuleData converts ACL to raft.ACLRuleData.
 func aclToRuleData(rule *ACL) raft.ACLRuleData {
+	var timeRange *raft.ACLTimeRangeData
+	if tr := rule.TimeRange; tr != nil {
+		timeRange = &raft.ACLTimeRangeData{
+			Start:    tr.StartString(),
+			End:      tr.EndString(),
+			Weekdays: tr.WeekdayNames(),
+			Timezone: tr.LocationName(),
+		}
+	}
+
 	return raft.ACLRuleData{
 		Target:     rule.Target,
 		Subject:    rule.Subject,
@@ -743,6 +842,13 @@ func aclToRuleData(rule *ACL) raft.ACLRuleData {
 		Rights:     rightsToStrings(rule.Rights),
 		Attributes: rule.Attributes,
 		Deny:       rule.Deny,
+
+		SourceCIDRs: rule.SourceCIDRs,
+		RequireTLS:  rule.RequireTLS,
+		MinSSF:      rule.MinSSF,
+		AuthMethod:  rule.AuthMethod,
+
+		TimeRange: timeRange,
 	}
 }
 
@@ -3,11 +3,27 @@
 package acl
 
 import (
+	"net"
 	"strings"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
 )
 
+// GroupSubjectPrefix introduces a group subject, "group:<group DN>", which
+// matches the members of the group.
+const GroupSubjectPrefix = "group:"
+
+// GroupResolver decides group membership for group subjects.
+type GroupResolver interface {
+	// IsMember reports whether memberDN is a member of the group groupDN.
+	IsMember(groupDN, memberDN string) bool
+}
+
 // Matcher provides DN and subject matching functionality for ACL evaluation.
-type Matcher struct{}
+type Matcher struct {
+	// Groups resolves group subjects. Group subjects match nobody when nil.
+	Groups GroupResolver
+}
 
 // NewMatcher creates a new Matcher instance.
 func NewMatcher() *Matcher {
@@ -17,8 +33,8 @@ func NewMatcher() *Matcher {
 // MatchesTarget checks if the target DN matches the ACL rule's target pattern.
 func (m *Matcher) MatchesTarget(rule *ACL, targetDN string) bool {
 	// Normalize DNs for comparison (case-insensitive)
-	ruleTarget := strings.ToLower(rule.Target)
-	target := strings.ToLower(targetDN)
+	ruleTarget := m.NormalizeDN(rule.Target)
+	target := m.NormalizeDN(targetDN)
 
 	// Wildcard matches everything
 	if ruleTarget == "*" {
@@ -46,7 +62,7 @@ func (m *Matcher) MatchesTarget(rule *ACL, targetDN string) bool {
 // MatchesSubject checks if the bind DN matches the ACL rule's subject.
 func (m *Matcher) MatchesSubject(rule *ACL, bindDN, targetDN string) bool {
 	subject := strings.ToLower(rule.Subject)
-	bind := strings.ToLower(bindDN)
+	bind := m.NormalizeDN(bindDN)
 
 	switch subject {
 	case "anonymous":
@@ -59,18 +75,74 @@ func (m *Matcher) MatchesSubject(rule *ACL, bindDN, targetDN string) bool {
 
 	case "self":
 		// Matches when the bind DN equals the target DN
-		return bindDN != "" && strings.EqualFold(bindDN, targetDN)
+		return bindDN != "" && m.NormalizeDN(bindDN) == m.NormalizeDN(targetDN)
 
 	case "*":
 		// Matches everyone (anonymous and authenticated)
 		return true
 
 	default:
+		if strings.HasPrefix(subject, GroupSubjectPrefix) {
+			// Matches the members of the group
+			groupDN := strings.TrimSpace(rule.Subject[len(GroupSubjectPrefix):])
+			return bindDN != "" && m.Groups != nil && m.Groups.IsMember(groupDN, bindDN)
+		}
+
 		// Exact DN match
-		return bind == subject
+		return bind == m.NormalizeDN(rule.Subject)
 	}
 }
 
+// MatchesConnection checks if the client connection satisfies the ACL rule's
+// connection constraints. Rules without constraints match every connection.
+func (m *Matcher) MatchesConnection(rule *ACL, conn ConnectionInfo) bool {
+	if rule.RequireTLS && !conn.TLS {
+		return false
+	}
+
+	if conn.SSF < rule.MinSSF {
+		return false
+	}
+
+	if rule.AuthMethod != "" && !strings.EqualFold(rule.AuthMethod, conn.AuthMethod) {
+		return false
+	}
+
+	if len(rule.SourceCIDRs) == 0 {
+		return true
+	}
+
+	ip := net.ParseIP(conn.RemoteIP)
+	if ip == nil {
+		return false
+	}
+	for _, source := range rule.SourceCIDRs {
+		network, err := ParseSourceNetwork(source)
+		if err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseSourceNetwork parses a source constraint given as a CIDR or as a
+// single IP address, which is treated as a host network.
+func ParseSourceNetwork(s string) (*net.IPNet, error) {
+	if ip := net.ParseIP(s); ip != nil {
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, err
+	}
+	return network, nil
+}
+
 // isImmediateChild checks if target is an immediate child of parent.
 // For example: "uid=alice,ou=users,dc=example,dc=com" is an immediate child of "ou=users,dc=example,dc=com"
 func (m *Matcher) isImmediateChild(parent, target string) bool {
@@ -96,9 +168,9 @@ func (m *Matcher) isImmediateChild(parent, target string) bool {
 		return false
 	}
 
-	// Prefix should be a single RDN (no commas)
+	// Prefix should be a single RDN
 	// This means target is an immediate child
-	return !strings.Contains(prefix, ",")
+	return len(m.ParseDN(prefix)) == 1
 }
 
 // isSubtreeMatch checks if target is equal to or a descendant of base.
@@ -129,19 +201,13 @@ func (m *Matcher) ParseDN(dn string) []string {
 		return nil
 	}
 
-	// Simple split by comma - doesn't handle escaped commas
-	// For a production implementation, proper DN parsing would be needed
-	parts := strings.Split(dn, ",")
-	result := make([]string, 0, len(parts))
-
-	for _, part := range parts {
-		trimmed := strings.TrimSpace(part)
-		if trimmed != "" {
-			result = append(result, trimmed)
-		}
+	// Escaped and quoted commas do not separate RDNs
+	rdns, err := ldap.SplitDN(dn)
+	if err != nil {
+		return nil
 	}
 
-	return result
+	return rdns
 }
 
 // GetParentDN returns the parent DN of the given DN.
@@ -151,29 +217,20 @@ func (m *Matcher) GetParentDN(dn string) string {
 		return ""
 	}
 
-	idx := strings.Index(dn, ",")
-	if idx == -1 {
+	rdns := m.ParseDN(dn)
+	if len(rdns) <= 1 {
 		return "" // Root DN has no parent
 	}
 
-	return dn[idx+1:]
+	return strings.Join(rdns[1:], ",")
 }
 
-// NormalizeDN normalizes a DN for comparison by converting to lowercase
-// and trimming whitespace around components.
+// NormalizeDN normalizes a DN for comparison by converting it to the
+// canonical form the directory keys entries by: lowercased, without
+// whitespace around components and with the components of multi-valued
+// RDNs in a fixed order.
 func (m *Matcher) NormalizeDN(dn string) string {
-	if dn == "" {
-		return ""
-	}
-
-	parts := m.ParseDN(dn)
-	normalized := make([]string, len(parts))
-
-	for i, part := range parts {
-		normalized[i] = strings.ToLower(strings.TrimSpace(part))
-	}
-
-	return strings.Join(normalized, ",")
+	return ldap.CanonicalDN(dn)
 }
 
 // MatchesPattern checks if a DN matches a pattern with wildcards.
@@ -206,7 +263,7 @@ func (m *Matcher) MatchesPattern(pattern, dn string) bool {
 			continue
 		}
 
-		if patternPart != dnParts[i] {
+		if ldap.CanonicalDN(patternPart) != ldap.CanonicalDN(dnParts[i]) {
 			return false
 		}
 	}
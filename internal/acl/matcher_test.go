@@ -1,6 +1,7 @@
 package acl
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -96,6 +97,47 @@ func TestMatcherMatchesSubject(t *testing.T) {
 	}
 }
 
+// staticGroups is a GroupResolver backed by a map of group DN to members.
+type staticGroups map[string][]string
+
+func (g staticGroups) IsMember(groupDN, memberDN string) bool {
+	for _, m := range g[strings.ToLower(groupDN)] {
+		if strings.EqualFold(m, memberDN) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestMatcherMatchesGroupSubject(t *testing.T) {
+	subject := "group:cn=admins,ou=groups,dc=example,dc=com"
+	rule := &ACL{Subject: subject}
+
+	m := NewMatcher()
+	if m.MatchesSubject(rule, "uid=alice,dc=example,dc=com", "") {
+		t.Error("expected group subject not to match without a resolver")
+	}
+
+	m.Groups = staticGroups{"cn=admins,ou=groups,dc=example,dc=com": {"uid=alice,dc=example,dc=com"}}
+	tests := []struct {
+		subject  string
+		bindDN   string
+		expected bool
+	}{
+		{subject, "uid=alice,dc=example,dc=com", true},
+		{"GROUP: CN=Admins,ou=groups,dc=example,dc=com", "UID=Alice,dc=example,dc=com", true},
+		{subject, "uid=bob,dc=example,dc=com", false},
+		{subject, "", false},
+		{"group:cn=other,ou=groups,dc=example,dc=com", "uid=alice,dc=example,dc=com", false},
+	}
+	for _, tt := range tests {
+		rule := &ACL{Subject: tt.subject}
+		if got := m.MatchesSubject(rule, tt.bindDN, ""); got != tt.expected {
+			t.Errorf("MatchesSubject(%q, %q) = %v, want %v", tt.subject, tt.bindDN, got, tt.expected)
+		}
+	}
+}
+
 func TestMatcherIsImmediateChild(t *testing.T) {
 	m := NewMatcher()
 
@@ -0,0 +1,279 @@
+package acl
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidTimeRange is returned for a time range that cannot be parsed.
+var ErrInvalidTimeRange = errors.New("acl: invalid time range")
+
+// ClockFunc returns the current time. The Evaluator checks rule time ranges
+// against it.
+type ClockFunc func() time.Time
+
+// day is the length of the clock day time ranges are measured in.
+const day = 24 * time.Hour
+
+// TimeRange restricts a rule to a daily window of clock time.
+type TimeRange struct {
+	// Start and End are the clock times the window opens and closes, as
+	// offsets from midnight. A window whose End is not after its Start
+	// closes on the next day; equal times cover the whole day.
+	Start time.Duration
+	End   time.Duration
+
+	// Weekdays are the days the window opens on. Empty means every day.
+	Weekdays []time.Weekday
+
+	// Location is the time zone of the clock times. Nil means local time.
+	Location *time.Location
+}
+
+// WithTimeRange restricts the rule to the clock times from start to end on
+// the given weekdays, and returns the ACL for chaining. Only the clock time
+// of start and end is used, in the location of start. Empty weekdays means
+// every day.
+func (a *ACL) WithTimeRange(start, end time.Time, weekdays []time.Weekday) *ACL {
+	loc := start.Location()
+	end = end.In(loc)
+	a.TimeRange = &TimeRange{
+		Start:    clockOf(start),
+		End:      clockOf(end),
+		Weekdays: weekdays,
+		Location: loc,
+	}
+	return a
+}
+
+// ActiveAt reports whether the rule applies at t: it has no time range or
+// t falls within it.
+func (a *ACL) ActiveAt(t time.Time) bool {
+	return a.TimeRange == nil || a.TimeRange.Contains(t)
+}
+
+// Contains reports whether t falls within the window.
+func (r *TimeRange) Contains(t time.Time) bool {
+	if r.Location != nil {
+		t = t.In(r.Location)
+	} else {
+		t = t.Local()
+	}
+	clock := clockOf(t)
+
+	switch {
+	case r.Start == r.End:
+		return r.onWeekday(t.Weekday())
+	case r.Start < r.End:
+		return clock >= r.Start && clock < r.End && r.onWeekday(t.Weekday())
+	case clock >= r.Start:
+		return r.onWeekday(t.Weekday())
+	}
+	// The morning part of a window opened the day before
+	if clock < r.End {
+		return r.onWeekday((t.Weekday() + 6) % 7)
+	}
+	return false
+}
+
+// onWeekday reports whether the window opens on d.
+func (r *TimeRange) onWeekday(d time.Weekday) bool {
+	return len(r.Weekdays) == 0 || containsWeekday(r.Weekdays, d)
+}
+
+// containsWeekday reports whether days contains d.
+func containsWeekday(days []time.Weekday, d time.Weekday) bool {
+	for _, w := range days {
+		if w == d {
+			return true
+		}
+	}
+	return false
+}
+
+// Equal reports whether r and o describe the same window.
+func (r *TimeRange) Equal(o *TimeRange) bool {
+	if r == nil || o == nil {
+		return r == o
+	}
+	if r.Start != o.Start || r.End != o.End || r.LocationName() != o.LocationName() {
+		return false
+	}
+	if len(r.Weekdays) != len(o.Weekdays) {
+		return false
+	}
+	for _, d := range r.Weekdays {
+		if !containsWeekday(o.Weekdays, d) {
+			return false
+		}
+	}
+	return true
+}
+
+// StartString returns the opening clock time as HH:MM, or HH:MM:SS if it
+// has seconds.
+func (r *TimeRange) StartString() string {
+	return formatClock(r.Start)
+}
+
+// EndString returns the closing clock time as HH:MM, or HH:MM:SS if it has
+// seconds.
+func (r *TimeRange) EndString() string {
+	return formatClock(r.End)
+}
+
+// WeekdayNames returns the short names of the weekdays, such as "mon".
+func (r *TimeRange) WeekdayNames() []string {
+	names := make([]string, len(r.Weekdays))
+	for i, d := range r.Weekdays {
+		names[i] = strings.ToLower(d.String()[:3])
+	}
+	return names
+}
+
+// LocationName returns the name of the time zone, or "" for local time.
+func (r *TimeRange) LocationName() string {
+	if r.Location == nil || r.Location == time.Local {
+		return ""
+	}
+	return r.Location.String()
+}
+
+// String returns a description such as "mon,tue 09:00-17:00 UTC".
+func (r *TimeRange) String() string {
+	s := r.StartString() + "-" + r.EndString()
+	if len(r.Weekdays) > 0 {
+		s = strings.Join(r.WeekdayNames(), ",") + " " + s
+	}
+	if name := r.LocationName(); name != "" {
+		s += " " + name
+	}
+	return s
+}
+
+// ParseTimeRange parses a time range from its configuration form: clock
+// times as HH:MM or HH:MM:SS, weekday names such as "mon" or "monday" or
+// ranges of them such as "mon-fri", and an IANA time zone name, empty for
+// local time.
+func ParseTimeRange(start, end string, weekdays []string, timezone string) (*TimeRange, error) {
+	r := &TimeRange{}
+
+	var err error
+	if r.Start, err = parseClock(start); err != nil {
+		return nil, err
+	}
+	if r.End, err = parseClock(end); err != nil {
+		return nil, err
+	}
+
+	for _, w := range weekdays {
+		days, err := parseWeekdays(w)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range days {
+			if !containsWeekday(r.Weekdays, d) {
+				r.Weekdays = append(r.Weekdays, d)
+			}
+		}
+	}
+
+	if timezone != "" {
+		if r.Location, err = time.LoadLocation(timezone); err != nil {
+			return nil, fmt.Errorf("%w: time zone %s", ErrInvalidTimeRange, timezone)
+		}
+	}
+
+	return r, nil
+}
+
+// validate checks that the clock times are within a day.
+func (r *TimeRange) validate() error {
+	if r.Start < 0 || r.Start >= day || r.End < 0 || r.End >= day {
+		return fmt.Errorf("%w: clock times must be within a day", ErrInvalidTimeRange)
+	}
+	for _, d := range r.Weekdays {
+		if d < time.Sunday || d > time.Saturday {
+			return fmt.Errorf("%w: weekday %d", ErrInvalidTimeRange, d)
+		}
+	}
+	return nil
+}
+
+// clockOf returns the clock time of t as an offset from midnight.
+func clockOf(t time.Time) time.Duration {
+	h, m, s := t.Clock()
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(s)*time.Second
+}
+
+// parseClock parses HH:MM or HH:MM:SS.
+func parseClock(s string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 2 && len(parts) != 3 {
+		return 0, fmt.Errorf("%w: clock time %q must be HH:MM or HH:MM:SS", ErrInvalidTimeRange, s)
+	}
+
+	units := []time.Duration{time.Hour, time.Minute, time.Second}
+	limits := []int{23, 59, 59}
+	var clock time.Duration
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || len(part) != 2 || n > limits[i] {
+			return 0, fmt.Errorf("%w: clock time %q must be HH:MM or HH:MM:SS", ErrInvalidTimeRange, s)
+		}
+		clock += time.Duration(n) * units[i]
+	}
+	return clock, nil
+}
+
+// formatClock formats an offset from midnight as HH:MM or HH:MM:SS.
+func formatClock(d time.Duration) string {
+	h, m, s := int(d/time.Hour), int(d%time.Hour/time.Minute), int(d%time.Minute/time.Second)
+	if s != 0 {
+		return fmt.Sprintf("%02d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d", h, m)
+}
+
+// parseWeekdays parses a weekday name, or a range of them such as "mon-fri"
+// that may wrap around the end of the week.
+func parseWeekdays(s string) ([]time.Weekday, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if from, to, ok := strings.Cut(s, "-"); ok {
+		first, err := parseWeekday(from)
+		if err != nil {
+			return nil, err
+		}
+		last, err := parseWeekday(to)
+		if err != nil {
+			return nil, err
+		}
+		days := []time.Weekday{first}
+		for d := first; d != last; {
+			d = (d + 1) % 7
+			days = append(days, d)
+		}
+		return days, nil
+	}
+
+	d, err := parseWeekday(s)
+	if err != nil {
+		return nil, err
+	}
+	return []time.Weekday{d}, nil
+}
+
+// parseWeekday parses a short or full English weekday name.
+func parseWeekday(s string) (time.Weekday, error) {
+	s = strings.TrimSpace(s)
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: weekday %q", ErrInvalidTimeRange, s)
+}
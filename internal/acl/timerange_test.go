@@ -0,0 +1,133 @@
+package acl
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// officeHours returns a Monday to Friday 09:00-17:00 UTC range.
+func officeHours(t *testing.T) *TimeRange {
+	t.Helper()
+
+	tr, err := ParseTimeRange("09:00", "17:00", []string{"mon-fri"}, "UTC")
+	if err != nil {
+		t.Fatalf("ParseTimeRange() error = %v", err)
+	}
+	return tr
+}
+
+func TestEvaluatorTimeRange(t *testing.T) {
+	config := NewConfig()
+	config.SetDefaultPolicy("deny")
+	rule := NewACL("*", "authenticated", Read)
+	rule.TimeRange = officeHours(t)
+	config.AddRule(rule)
+
+	evaluator := NewEvaluator(config)
+	ctx := NewAccessContext("uid=alice,ou=users,dc=example,dc=com", "dc=example,dc=com", Read)
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{"Tuesday 10am", time.Date(2024, time.June, 4, 10, 0, 0, 0, time.UTC), true},
+		{"Saturday 8pm", time.Date(2024, time.June, 8, 20, 0, 0, 0, time.UTC), false},
+		{"Tuesday 5pm", time.Date(2024, time.June, 4, 17, 0, 0, 0, time.UTC), false},
+		{"Tuesday 10am in Istanbul", time.Date(2024, time.June, 4, 10, 0, 0, 0, time.FixedZone("TRT", 3*3600)), false},
+		{"Tuesday 1pm in Istanbul", time.Date(2024, time.June, 4, 13, 0, 0, 0, time.FixedZone("TRT", 3*3600)), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluator.SetClock(func() time.Time { return tt.now })
+			if got := evaluator.CheckAccess(ctx); got != tt.want {
+				t.Errorf("CheckAccess() = %v, want %v", got, tt.want)
+			}
+			x := evaluator.EvaluateExplain(ctx)
+			if x.Allowed != tt.want {
+				t.Errorf("EvaluateExplain().Allowed = %v, want %v", x.Allowed, tt.want)
+			}
+			if !tt.want && x.Rules[0].Reason != ReasonOutsideTimeRange {
+				t.Errorf("Reason = %q, want %q", x.Rules[0].Reason, ReasonOutsideTimeRange)
+			}
+		})
+	}
+}
+
+func TestWithTimeRange(t *testing.T) {
+	loc := time.FixedZone("TRT", 3*3600)
+	rule := NewACL("*", "*", Read).WithTimeRange(
+		time.Date(0, 1, 1, 22, 0, 0, 0, loc),
+		time.Date(0, 1, 1, 6, 30, 0, 0, loc),
+		[]time.Weekday{time.Friday},
+	)
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want bool
+	}{
+		{"Friday night", time.Date(2024, time.June, 7, 23, 0, 0, 0, loc), true},
+		{"Saturday morning", time.Date(2024, time.June, 8, 6, 0, 0, 0, loc), true},
+		{"Saturday after close", time.Date(2024, time.June, 8, 6, 30, 0, 0, loc), false},
+		{"Friday morning", time.Date(2024, time.June, 7, 5, 0, 0, 0, loc), false},
+		{"Saturday night", time.Date(2024, time.June, 8, 23, 0, 0, 0, loc), false},
+	}
+
+	for _, tt := range tests {
+		if got := rule.ActiveAt(tt.t); got != tt.want {
+			t.Errorf("%s: ActiveAt() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if got := rule.TimeRange.String(); got != "fri 22:00-06:30 TRT" {
+		t.Errorf("String() = %q", got)
+	}
+	if !NewACL("*", "*", Read).ActiveAt(time.Now()) {
+		t.Error("rule without a time range should always be active")
+	}
+}
+
+func TestParseTimeRange(t *testing.T) {
+	tr, err := ParseTimeRange("08:15:30", "08:15:30", []string{"Friday-Monday", "sun"}, "")
+	if err != nil {
+		t.Fatalf("ParseTimeRange() error = %v", err)
+	}
+	want := []time.Weekday{time.Friday, time.Saturday, time.Sunday, time.Monday}
+	if len(tr.Weekdays) != len(want) {
+		t.Fatalf("Weekdays = %v, want %v", tr.Weekdays, want)
+	}
+	for i, d := range want {
+		if tr.Weekdays[i] != d {
+			t.Fatalf("Weekdays = %v, want %v", tr.Weekdays, want)
+		}
+	}
+	if tr.StartString() != "08:15:30" {
+		t.Errorf("StartString() = %q", tr.StartString())
+	}
+	// Equal times cover the whole day
+	if !tr.Contains(time.Date(2024, time.June, 3, 3, 0, 0, 0, time.Local)) {
+		t.Error("whole-day range does not contain Monday 03:00")
+	}
+
+	invalid := []struct {
+		start, end string
+		weekdays   []string
+		timezone   string
+	}{
+		{"9:00", "17:00", nil, ""},
+		{"09:00", "24:00", nil, ""},
+		{"09:00", "17:60", nil, ""},
+		{"09:00", "17:00", []string{"someday"}, ""},
+		{"09:00", "17:00", []string{"mon-"}, ""},
+		{"09:00", "17:00", nil, "Mars/Olympus"},
+	}
+	for _, tt := range invalid {
+		if _, err := ParseTimeRange(tt.start, tt.end, tt.weekdays, tt.timezone); !errors.Is(err, ErrInvalidTimeRange) {
+			t.Errorf("ParseTimeRange(%q, %q, %v, %q) error = %v, want ErrInvalidTimeRange",
+				tt.start, tt.end, tt.weekdays, tt.timezone, err)
+		}
+	}
+}
@@ -2,6 +2,8 @@
 // for the Oba LDAP server.
 package acl
 
+import "strings"
+
 // Right represents an LDAP access control right.
 // Rights are bit flags that can be combined using bitwise OR.
 type Right int
@@ -106,8 +108,37 @@ type ACL struct {
 
 	// Deny indicates this is a deny rule (true) or allow rule (false).
 	Deny bool
+
+	// SourceCIDRs restricts the rule to clients connecting from these
+	// networks. Entries may be CIDRs or single IP addresses.
+	// Empty slice means any source.
+	SourceCIDRs []string
+
+	// RequireTLS restricts the rule to connections protected by TLS.
+	RequireTLS bool
+
+	// MinSSF is the minimum security strength factor of the connection.
+	// Zero means no minimum.
+	MinSSF int
+
+	// AuthMethod restricts the rule to clients that bound with this
+	// method ("simple" or "sasl-external"). Empty string means any method.
+	AuthMethod string
+
+	// TimeRange restricts the rule to a daily window of clock time.
+	// Nil means at any time.
+	TimeRange *TimeRange
 }
 
+// Authentication methods accepted in ACL.AuthMethod.
+const (
+	// AuthMethodSimple is a simple (DN and password) bind
+	AuthMethodSimple = "simple"
+
+	// AuthMethodSASLExternal is a SASL EXTERNAL bind using the TLS client certificate
+	AuthMethodSASLExternal = "sasl-external"
+)
+
 // NewACL creates a new ACL rule with the given parameters.
 func NewACL(target, subject string, rights Right) *ACL {
 	return &ACL{
@@ -138,14 +169,45 @@ func (a *ACL) WithDeny(deny bool) *ACL {
 	return a
 }
 
+// WithSourceCIDRs sets the source networks and returns the ACL for chaining.
+func (a *ACL) WithSourceCIDRs(cidrs ...string) *ACL {
+	a.SourceCIDRs = cidrs
+	return a
+}
+
+// WithRequireTLS sets the TLS requirement and returns the ACL for chaining.
+func (a *ACL) WithRequireTLS(require bool) *ACL {
+	a.RequireTLS = require
+	return a
+}
+
+// WithMinSSF sets the minimum security strength factor and returns the ACL for chaining.
+func (a *ACL) WithMinSSF(ssf int) *ACL {
+	a.MinSSF = ssf
+	return a
+}
+
+// WithAuthMethod sets the required authentication method and returns the ACL for chaining.
+func (a *ACL) WithAuthMethod(method string) *ACL {
+	a.AuthMethod = method
+	return a
+}
+
+// HasConnectionConstraints returns true if the rule restricts the source
+// address, TLS state, security strength or authentication method.
+func (a *ACL) HasConnectionConstraints() bool {
+	return len(a.SourceCIDRs) > 0 || a.RequireTLS || a.MinSSF > 0 || a.AuthMethod != ""
+}
+
 // AppliesToAttribute checks if this ACL applies to the given attribute.
 // Returns true if Attributes is empty (applies to all) or if the attribute is in the list.
+// Attribute names are compared case-insensitively.
 func (a *ACL) AppliesToAttribute(attr string) bool {
 	if len(a.Attributes) == 0 {
 		return true
 	}
 	for _, allowed := range a.Attributes {
-		if allowed == attr || allowed == "*" {
+		if strings.EqualFold(allowed, attr) || allowed == "*" {
 			return true
 		}
 	}
@@ -202,6 +264,27 @@ type AccessContext struct {
 	// Attributes is the list of attributes being accessed.
 	// Used for read/write operations to check attribute-level permissions.
 	Attributes []string
+
+	// Connection describes the client connection of the request.
+	// Rules with connection constraints never match a zero ConnectionInfo.
+	Connection ConnectionInfo
+}
+
+// ConnectionInfo describes the client connection an operation arrives on.
+type ConnectionInfo struct {
+	// RemoteIP is the client IP address. Empty if unknown.
+	RemoteIP string
+
+	// TLS is true if the connection is protected by TLS.
+	TLS bool
+
+	// SSF is the security strength factor of the connection, roughly the
+	// key size in bits of the cipher protecting it. Zero for cleartext.
+	SSF int
+
+	// AuthMethod is how the client bound ("simple", "sasl-external").
+	// Empty string for anonymous connections.
+	AuthMethod string
 }
 
 // NewAccessContext creates a new access context.
@@ -220,6 +303,12 @@ func (c *AccessContext) WithAttributes(attrs ...string) *AccessContext {
 	return c
 }
 
+// WithConnection sets the connection info and returns the context for chaining.
+func (c *AccessContext) WithConnection(info ConnectionInfo) *AccessContext {
+	c.Connection = info
+	return c
+}
+
 // IsAnonymous returns true if the request is from an unauthenticated user.
 func (c *AccessContext) IsAnonymous() bool {
 	return c.BindDN == ""
@@ -129,6 +129,7 @@ func TestACLAppliesToAttribute(t *testing.T) {
 		{"specific matches", []string{"cn", "mail"}, "cn", true},
 		{"specific matches second", []string{"cn", "mail"}, "mail", true},
 		{"specific no match", []string{"cn", "mail"}, "userPassword", false},
+		{"case insensitive", []string{"userPassword"}, "userpassword", true},
 		{"wildcard matches all", []string{"*"}, "anything", true},
 	}
 
@@ -49,6 +49,29 @@ func ValidateConfig(config *Config) []error {
 		if rule.Scope < ScopeBase || rule.Scope > ScopeSubtree {
 			errs = append(errs, fmt.Errorf("rule %d: invalid scope %d", i, rule.Scope))
 		}
+
+		// Validate connection constraints
+		for _, cidr := range rule.SourceCIDRs {
+			if _, err := ParseSourceNetwork(cidr); err != nil {
+				errs = append(errs, fmt.Errorf("rule %d: invalid source CIDR %s", i, cidr))
+			}
+		}
+
+		if rule.MinSSF < 0 {
+			errs = append(errs, fmt.Errorf("rule %d: minSSF must be non-negative", i))
+		}
+
+		if rule.AuthMethod != "" {
+			if _, err := ParseAuthMethod(rule.AuthMethod); err != nil {
+				errs = append(errs, fmt.Errorf("rule %d: invalid auth method %s", i, rule.AuthMethod))
+			}
+		}
+
+		if rule.TimeRange != nil {
+			if err := rule.TimeRange.validate(); err != nil {
+				errs = append(errs, fmt.Errorf("rule %d: %w", i, err))
+			}
+		}
 	}
 
 	return errs
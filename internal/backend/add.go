@@ -4,6 +4,7 @@
 package backend
 
 import (
+	"context"
 	"strings"
 
 	"github.com/KilimcininKorOglu/oba/internal/storage"
@@ -23,7 +24,8 @@ var ErrObjectClassRequired = ErrInvalidEntry
 // 3. Checks if the parent entry exists (returns ErrNoParent)
 // 4. Validates that objectClass attribute is present
 // 5. Validates entry against schema if available
-// 6. Inserts the entry into storage within a transaction
+// 6. Enforces DIT structure rules if configured
+// 7. Inserts the entry into storage within a transaction
 func (b *ObaBackend) AddEntry(entry *storage.Entry) error {
 	if entry == nil || entry.DN == "" {
 		return ErrInvalidEntry
@@ -38,7 +40,6 @@ func (b *ObaBackend) AddEntry(entry *storage.Entry) error {
 
 	// Convert to backend entry for schema validation
 	backendEntry := convertFromStorageEntry(entry)
-	backendEntry.DN = normalizedDN
 
 	// Enforce OU placement rules for user/group object classes.
 	if err := validateEntryPlacement(backendEntry); err != nil {
@@ -52,12 +53,19 @@ func (b *ObaBackend) AddEntry(entry *storage.Entry) error {
 		}
 	}
 
-	// Start a transaction
-	txn, err := b.engine.Begin()
+	// Start a serializable transaction, so that the parent cannot be
+	// deleted before the entry commits
+	txn, err := b.beginSerializable(context.Background())
 	if err != nil {
 		return wrapStorageError(err)
 	}
 
+	// Enforce DIT structure rules if configured
+	if err := b.validateStructure(txn, backendEntry); err != nil {
+		b.engine.Rollback(txn)
+		return err
+	}
+
 	// Check if entry already exists
 	_, err = b.engine.Get(txn, normalizedDN)
 	if err == nil {
@@ -75,14 +83,15 @@ func (b *ObaBackend) AddEntry(entry *storage.Entry) error {
 	if parentDN != "" {
 		_, err = b.engine.Get(txn, parentDN)
 		if err != nil {
+			err := b.noParent(txn, parentDN)
 			b.engine.Rollback(txn)
-			return ErrNoParent
+			return err
 		}
 	}
 
-	// Create storage entry with normalized DN
+	// The stored entry keeps the DN as given; the engine keys it by the
+	// normalized form
 	storageEntry := entry.Clone()
-	storageEntry.DN = normalizedDN
 
 	// Put the entry
 	if err := b.engine.Put(txn, storageEntry); err != nil {
@@ -99,7 +108,8 @@ func (b *ObaBackend) AddEntry(entry *storage.Entry) error {
 }
 
 // GetEntry retrieves an entry by its DN.
-// Returns nil if the entry does not exist.
+// Returns nil if the entry does not exist. The entry is a copy, so the
+// caller may change it without affecting the stored entry.
 func (b *ObaBackend) GetEntry(dn string) (*storage.Entry, error) {
 	if dn == "" {
 		return nil, ErrInvalidDN
@@ -118,7 +128,7 @@ func (b *ObaBackend) GetEntry(dn string) (*storage.Entry, error) {
 		return nil, nil // Entry not found, return nil without error
 	}
 
-	return storageEntry, nil
+	return storageEntry.Clone(), nil
 }
 
 // hasStorageObjectClass checks if the storage entry has an objectClass attribute with at least one value.
@@ -136,3 +146,19 @@ func hasStorageObjectClass(entry *storage.Entry) bool {
 
 	return false
 }
+
+// noParent returns ErrNoParent for a missing parentDN, with the closest
+// superior of parentDN that exists in txn as the matched DN.
+func (b *ObaBackend) noParent(txn interface{}, parentDN string) error {
+	dn := parentDN
+	for {
+		var err error
+		dn, err = radix.GetParentDN(dn)
+		if err != nil || dn == "" {
+			return ErrNoParent
+		}
+		if _, err := b.engine.Get(txn, dn); err == nil {
+			return withMatchedDN(ErrNoParent, dn)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package backend
+
+import (
+	"context"
+	"errors"
+
+	"github.com/KilimcininKorOglu/oba/internal/filter"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/storage/mvcc"
+)
+
+// ErrAssertionFailed is returned when the assertion of an operation does
+// not hold for its target entry.
+var ErrAssertionFailed = newError(ldap.ResultAssertionFailed, "assertion failed")
+
+// Assertion is a precondition on the target entry of an operation. Writes
+// check it against the entry they read in their own transaction, so a
+// change committed since the client read the entry makes them fail with
+// ErrAssertionFailed instead of overwriting it.
+type Assertion struct {
+	// EntryCSN, if not empty, must equal the entryCSN of the entry. "*"
+	// matches any existing entry.
+	EntryCSN string
+	// Filter, if not nil, must match the entry (RFC 4528).
+	Filter *filter.Filter
+}
+
+type assertionKey struct{}
+
+// ContextWithAssertion returns a copy of ctx that carries a.
+func ContextWithAssertion(ctx context.Context, a *Assertion) context.Context {
+	return context.WithValue(ctx, assertionKey{}, a)
+}
+
+// AssertionFromContext returns the assertion carried by ctx, or nil.
+func AssertionFromContext(ctx context.Context) *Assertion {
+	if ctx == nil {
+		return nil
+	}
+	a, _ := ctx.Value(assertionKey{}).(*Assertion)
+	return a
+}
+
+// EntryVersion returns the version of an entry, its entryCSN. Entries
+// written before entryCSN was maintained have no version.
+func EntryVersion(entry *Entry) string {
+	return entry.GetFirstAttribute(AttrEntryCSN)
+}
+
+// Assert checks the assertion carried by ctx against the entry dn, for
+// operations that do not write it such as search and compare. It returns
+// nil if ctx carries no assertion.
+func (b *ObaBackend) Assert(ctx context.Context, dn string) error {
+	if AssertionFromContext(ctx) == nil {
+		return nil
+	}
+	entry, err := b.getEntry(ctx, normalizeDN(dn))
+	if err != nil {
+		return err
+	}
+	return b.checkAssertion(ctx, entry)
+}
+
+// checkAssertion checks the assertion carried by ctx against entry.
+func (b *ObaBackend) checkAssertion(ctx context.Context, entry *Entry) error {
+	a := AssertionFromContext(ctx)
+	if a == nil {
+		return nil
+	}
+
+	if a.EntryCSN != "" && a.EntryCSN != "*" && a.EntryCSN != EntryVersion(entry) {
+		return ErrAssertionFailed
+	}
+	if a.Filter != nil && !filter.NewEvaluator(b.schema).Evaluate(a.Filter, convertToFilterEntry(entry)) {
+		return ErrAssertionFailed
+	}
+	return nil
+}
+
+// recheckAssertion checks the assertion carried by ctx against the latest
+// committed version of dn. Standalone writers call it after their write
+// has claimed dn, when no other transaction can commit a change to it, so
+// that the check and the commit are atomic: a change committed since the
+// writer's snapshot was taken is seen here.
+func (b *ObaBackend) recheckAssertion(ctx context.Context, dn string) error {
+	if AssertionFromContext(ctx) == nil {
+		return nil
+	}
+	entry, err := b.getEntry(ctx, dn)
+	if err != nil {
+		return err
+	}
+	return b.checkAssertion(ctx, entry)
+}
+
+// assertedWriteError returns the error of a failed standalone write. A
+// write conflict means another transaction is changing the entry, so the
+// assertion of ctx cannot be known to hold and ErrAssertionFailed is
+// returned instead.
+func assertedWriteError(ctx context.Context, err error) error {
+	if AssertionFromContext(ctx) != nil && errors.Is(err, mvcc.ErrVersionConflict) {
+		return ErrAssertionFailed
+	}
+	return wrapStorageError(err)
+}
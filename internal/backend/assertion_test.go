@@ -0,0 +1,177 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/filter"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+// TestEntryCSN tests that writes set a new entryCSN on the entry.
+func TestEntryCSN(t *testing.T) {
+	backend := NewBackend(newMockStorageEngine(), nil)
+
+	entry := NewEntry("cn=printer,dc=example,dc=com")
+	entry.SetAttribute("objectclass", "device")
+	entry.SetAttribute("cn", "printer")
+	if err := backend.Add(context.Background(), entry); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	added := getVersion(t, backend, "cn=printer,dc=example,dc=com")
+	if added == "" {
+		t.Fatal("expected entryCSN after add")
+	}
+
+	changes := []Modification{{Type: ModReplace, Attribute: "description", Values: []string{"first floor"}}}
+	if err := backend.Modify(context.Background(), "cn=printer,dc=example,dc=com", changes); err != nil {
+		t.Fatalf("Modify() error = %v", err)
+	}
+	modified := getVersion(t, backend, "cn=printer,dc=example,dc=com")
+	if modified == added {
+		t.Errorf("expected entryCSN to change on modify, still %q", modified)
+	}
+	if modified < added {
+		t.Errorf("expected entryCSN to increase, got %q after %q", modified, added)
+	}
+}
+
+// TestAssertion tests that writes fail with ErrAssertionFailed when their
+// assertion does not hold.
+func TestAssertion(t *testing.T) {
+	backend := newAssertionBackend(t)
+	dn := "cn=printer,dc=example,dc=com"
+	version := getVersion(t, backend, dn)
+
+	modify := func(a *Assertion, value string) error {
+		ctx := ContextWithAssertion(context.Background(), a)
+		return backend.Modify(ctx, dn, []Modification{
+			{Type: ModReplace, Attribute: "description", Values: []string{value}},
+		})
+	}
+
+	if err := modify(&Assertion{EntryCSN: version}, "first"); err != nil {
+		t.Fatalf("Modify() with current version error = %v", err)
+	}
+	if err := modify(&Assertion{EntryCSN: version}, "second"); !errors.Is(err, ErrAssertionFailed) {
+		t.Errorf("Modify() with stale version error = %v, want ErrAssertionFailed", err)
+	}
+	if err := modify(&Assertion{EntryCSN: "*"}, "third"); err != nil {
+		t.Errorf("Modify() with * error = %v", err)
+	}
+
+	match := &Assertion{Filter: filter.NewEqualityFilter("description", []byte("third"))}
+	if err := backend.Assert(ContextWithAssertion(context.Background(), match), dn); err != nil {
+		t.Errorf("Assert() with matching filter error = %v", err)
+	}
+	mismatch := &Assertion{Filter: filter.NewEqualityFilter("description", []byte("first"))}
+	if err := modify(mismatch, "fourth"); !errors.Is(err, ErrAssertionFailed) {
+		t.Errorf("Modify() with mismatching filter error = %v, want ErrAssertionFailed", err)
+	}
+
+	stale := ContextWithAssertion(context.Background(), &Assertion{EntryCSN: version})
+	if err := backend.Delete(stale, dn); !errors.Is(err, ErrAssertionFailed) {
+		t.Errorf("Delete() with stale version error = %v, want ErrAssertionFailed", err)
+	}
+	current := ContextWithAssertion(context.Background(), &Assertion{EntryCSN: getVersion(t, backend, dn)})
+	if err := backend.Delete(current, dn); err != nil {
+		t.Errorf("Delete() with current version error = %v", err)
+	}
+}
+
+// TestAssertionModifyDN tests that a rename fails with ErrAssertionFailed
+// when its assertion does not hold.
+func TestAssertionModifyDN(t *testing.T) {
+	backend := newAssertionBackend(t)
+	req := &ModifyDNRequest{DN: "cn=printer,dc=example,dc=com", NewRDN: "cn=plotter"}
+
+	mismatch := &Assertion{Filter: filter.NewEqualityFilter("cn", []byte("scanner"))}
+	if err := backend.ModifyDN(ContextWithAssertion(context.Background(), mismatch), req); !errors.Is(err, ErrAssertionFailed) {
+		t.Fatalf("ModifyDN() with mismatching filter error = %v, want ErrAssertionFailed", err)
+	}
+	match := &Assertion{Filter: filter.NewEqualityFilter("cn", []byte("printer"))}
+	if err := backend.ModifyDN(ContextWithAssertion(context.Background(), match), req); err != nil {
+		t.Fatalf("ModifyDN() with matching filter error = %v", err)
+	}
+	getVersion(t, backend, "cn=plotter,dc=example,dc=com")
+}
+
+// TestAssertionRace tests that of two modifies asserting the same entry
+// version, exactly one succeeds however they interleave.
+func TestAssertionRace(t *testing.T) {
+	backend := newAssertionBackend(t)
+	dn := "cn=printer,dc=example,dc=com"
+
+	for round := 0; round < 50; round++ {
+		version := getVersion(t, backend, dn)
+		ctx := ContextWithAssertion(context.Background(), &Assertion{EntryCSN: version})
+
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		errs := make([]error, 2)
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				<-start
+				errs[i] = backend.Modify(ctx, dn, []Modification{
+					{Type: ModReplace, Attribute: "description", Values: []string{fmt.Sprintf("%d/%d", round, i)}},
+				})
+			}(i)
+		}
+		close(start)
+		wg.Wait()
+
+		succeeded := 0
+		for _, err := range errs {
+			switch {
+			case err == nil:
+				succeeded++
+			case !errors.Is(err, ErrAssertionFailed):
+				t.Fatalf("round %d: Modify() error = %v, want nil or ErrAssertionFailed", round, err)
+			}
+		}
+		if succeeded != 1 {
+			t.Fatalf("round %d: %d modifies succeeded, want 1 (errors %v)", round, succeeded, errs)
+		}
+	}
+}
+
+// newAssertionBackend returns a backend on a real engine, whose
+// transactions are isolated, holding cn=printer,dc=example,dc=com.
+func newAssertionBackend(t *testing.T) *ObaBackend {
+	t.Helper()
+	db, err := engine.Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	backend := NewBackend(db, config.DefaultConfig())
+
+	base := NewEntry("dc=example,dc=com")
+	base.SetAttribute("objectclass", "domain")
+	base.SetAttribute("dc", "example")
+	printer := NewEntry("cn=printer,dc=example,dc=com")
+	printer.SetAttribute("objectclass", "device")
+	printer.SetAttribute("cn", "printer")
+	for _, e := range []*Entry{base, printer} {
+		if err := backend.Add(context.Background(), e); err != nil {
+			t.Fatalf("Add(%s) error = %v", e.DN, err)
+		}
+	}
+	return backend
+}
+
+func getVersion(t *testing.T, backend *ObaBackend, dn string) string {
+	t.Helper()
+	entry, err := backend.getEntry(context.Background(), normalizeDN(dn))
+	if err != nil {
+		t.Fatalf("getEntry(%q) error = %v", dn, err)
+	}
+	return EntryVersion(entry)
+}
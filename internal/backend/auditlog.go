@@ -0,0 +1,129 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"sort"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/logging"
+)
+
+// AuditLogPlugin records every successful add, modify, delete and modify DN
+// in an append-only LDIF file, like OpenLDAP's auditlog overlay. Each
+// record starts with the time of the change:
+//
+//	time: 20260218103000Z
+//	dn: uid=alice,ou=users,dc=example,dc=com
+//	changetype: modify
+//	replace: mail
+//	mail: alice@example.com
+//	-
+//
+// The file is rotated daily.
+type AuditLogPlugin struct {
+	file *logging.RotatingFile
+	now  func() time.Time
+}
+
+// NewAuditLogPlugin opens the audit log at path for appending.
+func NewAuditLogPlugin(path string) (*AuditLogPlugin, error) {
+	file, err := logging.NewRotatingFile(logging.DefaultRotateConfig(path))
+	if err != nil {
+		return nil, err
+	}
+	return &AuditLogPlugin{file: file, now: time.Now}, nil
+}
+
+// PostCommit writes the record of op. It is a PostCommitHook.
+func (p *AuditLogPlugin) PostCommit(_ context.Context, op *WriteOp) error {
+	_, err := p.file.Write(p.record(op))
+	return err
+}
+
+// Close closes the audit log.
+func (p *AuditLogPlugin) Close() error {
+	return p.file.Close()
+}
+
+// record formats op as an LDIF change record.
+func (p *AuditLogPlugin) record(op *WriteOp) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("time: " + FormatTimestamp(p.now()) + "\n")
+	writeLDIFLine(&buf, "dn", op.DN)
+
+	switch op.Type {
+	case OpAdd:
+		buf.WriteString("changetype: add\n")
+		if op.Entry != nil {
+			names := make([]string, 0, len(op.Entry.Attributes))
+			for name := range op.Entry.Attributes {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				for _, value := range op.Entry.Attributes[name] {
+					writeLDIFLine(&buf, name, string(value))
+				}
+			}
+		}
+	case OpModify:
+		buf.WriteString("changetype: modify\n")
+		for _, change := range op.Changes {
+			writeLDIFLine(&buf, change.Type.String(), change.Attribute)
+			for _, value := range change.Values {
+				writeLDIFLine(&buf, change.Attribute, value)
+			}
+			buf.WriteString("-\n")
+		}
+	case OpDelete:
+		buf.WriteString("changetype: delete\n")
+	case OpModifyDN:
+		buf.WriteString("changetype: moddn\n")
+		if op.Rename != nil {
+			writeLDIFLine(&buf, "newrdn", op.Rename.NewRDN)
+			if op.Rename.DeleteOldRDN {
+				buf.WriteString("deleteoldrdn: 1\n")
+			} else {
+				buf.WriteString("deleteoldrdn: 0\n")
+			}
+			if op.Rename.NewSuperior != "" {
+				writeLDIFLine(&buf, "newsuperior", op.Rename.NewSuperior)
+			}
+		}
+	}
+
+	buf.WriteString("\n")
+	return buf.Bytes()
+}
+
+// writeLDIFLine writes an "attr: value" line, base64 encoding values that
+// are not safe in plain LDIF (RFC 2849).
+func writeLDIFLine(buf *bytes.Buffer, attr, value string) {
+	if ldifSafe(value) {
+		buf.WriteString(attr + ": " + value + "\n")
+		return
+	}
+	buf.WriteString(attr + ":: " + base64.StdEncoding.EncodeToString([]byte(value)) + "\n")
+}
+
+// ldifSafe reports whether value can be written as a plain LDIF value.
+func ldifSafe(value string) bool {
+	if value == "" {
+		return true
+	}
+	switch value[0] {
+	case ' ', ':', '<':
+		return false
+	}
+	if value[len(value)-1] == ' ' {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		if c := value[i]; c < 0x20 || c > 0x7E {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,119 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/backup"
+)
+
+func TestAuditLogPlugin(t *testing.T) {
+	be := openCollectiveBackend(t)
+
+	path := filepath.Join(t.TempDir(), "audit.ldif")
+	audit, err := NewAuditLogPlugin(path)
+	if err != nil {
+		t.Fatalf("NewAuditLogPlugin() error = %v", err)
+	}
+	defer audit.Close()
+	audit.now = func() time.Time { return time.Date(2026, 2, 18, 10, 30, 0, 0, time.UTC) }
+	be.RegisterPostCommitHook(audit.PostCommit)
+
+	dn := "uid=hans,ou=users,ou=berlin,dc=example,dc=com"
+	mods := []Modification{
+		{Type: ModReplace, Attribute: "description", Values: []string{"Zürich office"}},
+		{Type: ModAdd, Attribute: "mail", Values: []string{"hans@example.com"}},
+	}
+	if err := be.Modify(context.Background(), dn, mods); err != nil {
+		t.Fatalf("Modify() error = %v", err)
+	}
+	if err := be.ModifyDN(context.Background(), &ModifyDNRequest{DN: dn, NewRDN: "uid=hans2", DeleteOldRDN: true}); err != nil {
+		t.Fatalf("ModifyDN() error = %v", err)
+	}
+	if err := be.Delete(context.Background(), "uid=hans2,ou=users,ou=berlin,dc=example,dc=com"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	// A failed operation is not recorded
+	if err := be.Delete(context.Background(), "uid=nobody,dc=example,dc=com"); err == nil {
+		t.Fatal("expected Delete() of a missing entry to fail")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "time: 20260218103000Z\ndn: "+dn+"\nchangetype: modify\n") {
+		t.Errorf("unexpected audit record:\n%s", data)
+	}
+
+	records, err := backup.NewLDIFImporter(nil).Parse(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("audit log is not valid LDIF: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3", len(records))
+	}
+
+	modify := records[0]
+	if got := string(modify.Attributes["description"][0]); got != "Zürich office" {
+		t.Errorf("description = %q, want %q", got, "Zürich office")
+	}
+	if got := string(modify.Attributes["mail"][0]); got != "hans@example.com" {
+		t.Errorf("mail = %q, want %q", got, "hans@example.com")
+	}
+
+	wantTypes := []string{"modify", "moddn", "delete"}
+	for i, record := range records {
+		if got := string(record.Attributes["changetype"][0]); got != wantTypes[i] {
+			t.Errorf("record %d changetype = %q, want %q", i, got, wantTypes[i])
+		}
+	}
+	if got := string(records[1].Attributes["newrdn"][0]); got != "uid=hans2" {
+		t.Errorf("newrdn = %q, want %q", got, "uid=hans2")
+	}
+	if records[2].DN != "uid=hans2,ou=users,ou=berlin,dc=example,dc=com" {
+		t.Errorf("delete DN = %q", records[2].DN)
+	}
+}
+
+func TestAuditLogBinaryValues(t *testing.T) {
+	be := openCollectiveBackend(t)
+
+	path := filepath.Join(t.TempDir(), "audit.ldif")
+	audit, err := NewAuditLogPlugin(path)
+	if err != nil {
+		t.Fatalf("NewAuditLogPlugin() error = %v", err)
+	}
+	defer audit.Close()
+	be.RegisterPostCommitHook(audit.PostCommit)
+
+	der := testCertificateDER(t)
+	entry := NewEntry("uid=alice,ou=users,ou=berlin,dc=example,dc=com")
+	entry.SetAttribute("objectClass", "inetOrgPerson")
+	entry.SetAttribute("sn", "Alice")
+	entry.SetByteValues("userCertificate", [][]byte{der})
+	if err := be.Add(context.Background(), entry); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	if !strings.Contains(string(data), "\nusercertificate:: ") {
+		t.Errorf("expected base64 certificate value in audit log:\n%s", data)
+	}
+
+	records, err := backup.NewLDIFImporter(nil).Parse(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("audit log is not valid LDIF: %v", err)
+	}
+	if len(records) != 1 || !bytes.Equal(records[0].Attributes["usercertificate"][0], der) {
+		t.Error("certificate does not round-trip through the audit log")
+	}
+}
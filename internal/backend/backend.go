@@ -4,6 +4,7 @@
 package backend
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -12,38 +13,50 @@ import (
 
 	"github.com/KilimcininKorOglu/oba/internal/config"
 	"github.com/KilimcininKorOglu/oba/internal/filter"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/logging"
 	"github.com/KilimcininKorOglu/oba/internal/password"
 	"github.com/KilimcininKorOglu/oba/internal/schema"
 	"github.com/KilimcininKorOglu/oba/internal/server"
 	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/mvcc"
 	"github.com/KilimcininKorOglu/oba/internal/storage/radix"
 	"github.com/KilimcininKorOglu/oba/internal/storage/stream"
+	"github.com/KilimcininKorOglu/oba/internal/storage/tx"
+	"github.com/KilimcininKorOglu/oba/internal/trace"
 )
 
 // Backend errors.
 var (
 	// ErrInvalidCredentials is returned when authentication fails.
-	ErrInvalidCredentials = errors.New("backend: invalid credentials")
+	ErrInvalidCredentials = newError(ldap.ResultInvalidCredentials, "invalid credentials")
 	// ErrEntryNotFound is returned when an entry is not found.
-	ErrEntryNotFound = errors.New("backend: entry not found")
+	ErrEntryNotFound = newError(ldap.ResultNoSuchObject, "entry not found")
 	// ErrEntryExists is returned when an entry already exists.
-	ErrEntryExists = errors.New("backend: entry already exists")
+	ErrEntryExists = newError(ldap.ResultEntryAlreadyExists, "entry already exists")
 	// ErrInvalidDN is returned when a DN is invalid.
-	ErrInvalidDN = errors.New("backend: invalid DN")
+	ErrInvalidDN = newError(ldap.ResultInvalidDNSyntax, "invalid DN")
 	// ErrInvalidEntry is returned when an entry is invalid.
-	ErrInvalidEntry = errors.New("backend: invalid entry")
+	ErrInvalidEntry = newError(ldap.ResultObjectClassViolation, "invalid entry")
 	// ErrNoPassword is returned when an entry has no password attribute.
-	ErrNoPassword = errors.New("backend: no password attribute")
+	ErrNoPassword = newError(ldap.ResultInvalidCredentials, "no password attribute")
 	// ErrStorageError is returned when a storage operation fails.
-	ErrStorageError = errors.New("backend: storage error")
+	ErrStorageError = newError(ldap.ResultOperationsError, "storage error")
 	// ErrNotAllowedOnNonLeaf is returned when trying to delete an entry with children.
-	ErrNotAllowedOnNonLeaf = errors.New("backend: operation not allowed on non-leaf entry")
+	ErrNotAllowedOnNonLeaf = newError(ldap.ResultNotAllowedOnNonLeaf, "operation not allowed on non-leaf entry")
 	// ErrAccountDisabled is returned when trying to bind with a disabled account.
-	ErrAccountDisabled = errors.New("backend: account is disabled")
+	ErrAccountDisabled = newError(ldap.ResultInvalidCredentials, "account is disabled")
 	// ErrAccountLocked is returned when trying to bind with a locked account.
-	ErrAccountLocked = errors.New("backend: account is locked due to too many failed attempts")
+	ErrAccountLocked = newError(ldap.ResultInvalidCredentials, "account is locked due to too many failed attempts")
 	// ErrInvalidPlacement is returned when an entry is not under the correct organizational unit.
-	ErrInvalidPlacement = errors.New("backend: invalid entry placement")
+	ErrInvalidPlacement = newError(ldap.ResultNamingViolation, "invalid entry placement")
+	// ErrInsufficientAccess is returned when a subtree delete is denied for one of its entries.
+	ErrInsufficientAccess = newError(ldap.ResultInsufficientAccessRights, "insufficient access rights")
+	// ErrNoSuchAttribute is returned when a compared attribute is not present in the entry.
+	ErrNoSuchAttribute = newError(ldap.ResultNoSuchAttribute, "no such attribute")
+	// ErrConflict is returned when a write conflicts with a concurrent transaction.
+	// It wraps ErrBusy, since the operation succeeds when retried.
+	ErrConflict = fmt.Errorf("%w: conflicting concurrent update", ErrBusy)
 )
 
 // PasswordAttribute is the standard LDAP attribute name for user passwords.
@@ -54,71 +67,152 @@ const AccountDisabledAttribute = "obadisabled"
 
 // Backend defines the interface for LDAP backend operations.
 // It wraps the storage engine and provides LDAP-specific functionality.
+//
+// Every operation takes the context of the client request. The context
+// carries the OperationInfo of the client (see ContextWithOperation), and
+// ending it stops the operation: reads stop at the next entry fetched and
+// return the error of the context.
 type Backend interface {
 	// Bind authenticates a user with the given DN and password.
 	// Returns nil if authentication succeeds, or an error otherwise.
-	Bind(dn, password string) error
+	Bind(ctx context.Context, dn, password string) error
 
 	// Search searches for entries matching the given criteria.
 	// baseDN is the base distinguished name for the search.
 	// scope is the search scope (base, one-level, or subtree).
 	// f is the search filter.
-	// Returns matching entries or an error.
-	Search(baseDN string, scope int, f *filter.Filter) ([]*Entry, error)
+	// Returns matching entries or an error. If ctx ends during the
+	// search, it returns the entries found so far together with the
+	// error of ctx. The search is traced below the span carried by ctx.
+	Search(ctx context.Context, baseDN string, scope int, f *filter.Filter) ([]*Entry, error)
 
-	// Add adds a new entry to the directory.
-	// Returns an error if the entry already exists or is invalid.
-	Add(entry *Entry) error
+	// SearchWithDeleted is like Search but also returns entries that were
+	// moved to the recycle bin.
+	SearchWithDeleted(ctx context.Context, baseDN string, scope int, f *filter.Filter) ([]*Entry, error)
+
+	// EstimateSearch returns the number of entries a search would examine,
+	// without running it.
+	EstimateSearch(ctx context.Context, baseDN string, scope int, f *filter.Filter) int
+
+	// Assert checks the assertion carried by ctx (see ContextWithAssertion)
+	// against the entry dn, for operations that do not write it.
+	Assert(ctx context.Context, dn string) error
+
+	// CompareWithIndex reports whether the entry holds the value for the
+	// attribute, answering from an equality index when it can.
+	CompareWithIndex(ctx context.Context, dn, attr string, assertionValue []byte) (bool, error)
+
+	// ExpandDynamicGroups materializes the members of dynamic groups in
+	// search results, if dynamic group expansion is enabled.
+	ExpandDynamicGroups(ctx context.Context, entries []*Entry) []*Entry
+
+	// VirtualAttributes sets the computed attributes, such as entryDN and
+	// hasSubordinates, on search results that request them.
+	VirtualAttributes(ctx context.Context, entries []*Entry, attrs []string) []*Entry
 
-	// AddWithBindDN adds a new entry to the directory with operational attributes.
-	// The bindDN is used to set creatorsName and modifiersName.
+	// Schema returns the schema entries are validated against, or nil.
+	Schema() *schema.Schema
+
+	// CollectiveAttributes returns the collective attributes that apply to
+	// each of the entries named by dns.
+	CollectiveAttributes(ctx context.Context, dns []string) []map[string][][]byte
+
+	// Add adds a new entry to the directory.
+	// The bind DN carried by ctx is used to set creatorsName and
+	// modifiersName.
 	// Returns an error if the entry already exists or is invalid.
-	AddWithBindDN(entry *Entry, bindDN string) error
+	Add(ctx context.Context, entry *Entry) error
 
 	// Delete removes an entry from the directory.
 	// Returns an error if the entry does not exist.
-	Delete(dn string) error
+	Delete(ctx context.Context, dn string) error
+
+	// DeleteSubtree removes an entry and all of its descendants.
+	// If allow is not nil, it must accept every entry of the subtree.
+	// Returns the deleted DNs.
+	DeleteSubtree(ctx context.Context, dn string, allow func(dn string) bool) ([]string, error)
 
 	// HasChildren returns true if the entry has child entries.
-	HasChildren(dn string) (bool, error)
+	HasChildren(ctx context.Context, dn string) (bool, error)
 
 	// Modify modifies an existing entry.
+	// The bind DN carried by ctx is used to set modifiersName.
 	// Returns an error if the entry does not exist or the modifications are invalid.
-	Modify(dn string, changes []Modification) error
+	Modify(ctx context.Context, dn string, changes []Modification) error
 
-	// ModifyWithBindDN modifies an existing entry with operational attributes.
-	// The bindDN is used to set modifiersName.
-	// Returns an error if the entry does not exist or the modifications are invalid.
-	ModifyWithBindDN(dn string, changes []Modification, bindDN string) error
+	// ModifyDN renames or moves an entry.
+	ModifyDN(ctx context.Context, req *ModifyDNRequest) error
 
 	// IsAccountLocked checks if an account is locked due to too many failed attempts.
-	IsAccountLocked(dn string) bool
+	IsAccountLocked(ctx context.Context, dn string) bool
 
 	// RecordAuthFailure records a failed authentication attempt.
-	RecordAuthFailure(dn string)
+	RecordAuthFailure(ctx context.Context, dn string)
 
 	// RecordAuthSuccess records a successful authentication and clears failure history.
-	RecordAuthSuccess(dn string)
+	RecordAuthSuccess(ctx context.Context, dn string)
 }
 
 // ObaBackend implements the Backend interface using the ObaDB storage engine.
 type ObaBackend struct {
 	engine       storage.StorageEngine
 	schema       *schema.Schema
-	rootDN       string
-	rootPW       string
-	changeStream *stream.Broker
+	schemaStrict bool
+	// objectClassCascade drops the attributes of object classes a modify
+	// removes instead of rejecting it
+	objectClassCascade bool
+	rootDN             string
+	rootPW             string
+	changeStream       *stream.Broker
 
 	// Cluster mode support
 	clusterWriter ClusterWriter
+	clusterReader ClusterReader
 
 	// Security settings (hot-reloadable)
 	rateLimitEnabled  bool
 	rateLimitAttempts int
 	rateLimitDuration time.Duration
 	passwordPolicy    *password.Policy
+	passwordStatus    *password.HistoryManager
 	accountLockouts   map[string]*password.AccountLockout
 	securityMu        sync.RWMutex
+
+	// Subtree rename settings
+	maxRenameSubtree     int
+	referentialIntegrity bool
+
+	// Recycle bin (nil = deletes erase entries)
+	recycleBin *recycleBin
+
+	// DIT structure rules keyed by lowercase object class (nil = disabled)
+	structureRules map[string]*StructureRule
+	structureMu    sync.RWMutex
+
+	// Write operation hooks
+	hooks   hooks
+	logger  logging.Logger
+	hooksMu sync.RWMutex
+
+	// Children counted at most for numSubordinates (0 = exact)
+	maxNumSubordinates int
+
+	// Virtual attributes registered in addition to the built-in ones,
+	// guarded by hooksMu
+	virtualAttrs []VirtualAttribute
+
+	// Dynamic group expansion settings
+	dynGroupsEnabled   bool
+	dynGroupMaxMembers int
+	dynGroupMu         sync.RWMutex
+
+	// Cluster-wide lock serializing index builds and schema changes
+	locker   Locker
+	lockNode string
+	lockSeq  uint64
+
+	// Tracer of search spans (nil = not traced)
+	tracer trace.Tracer
 }
 
 // ClusterWriter interface for cluster-aware write operations.
@@ -130,17 +224,38 @@ type ClusterWriter interface {
 	IsLeader() bool
 }
 
+// ClusterReader interface for cluster-aware read operations.
+// When set, searches are routed through this interface so that the cluster
+// read preference decides whether they are served locally or by the leader.
+type ClusterReader interface {
+	SearchByDN(baseDN string, scope storage.Scope) storage.Iterator
+	SearchByFilter(baseDN string, f storage.FilterMatcher) storage.Iterator
+}
+
 // NewBackend creates a new ObaBackend with the given storage engine and configuration.
 func NewBackend(engine storage.StorageEngine, cfg *config.Config) *ObaBackend {
 	b := &ObaBackend{
 		engine:          engine,
 		changeStream:    stream.NewBroker(),
+		passwordStatus:  password.NewHistoryManager(0),
 		accountLockouts: make(map[string]*password.AccountLockout),
 	}
 
 	if cfg != nil {
 		b.rootDN = normalizeDN(cfg.Directory.RootDN)
 		b.rootPW = cfg.Directory.RootPassword
+		b.maxRenameSubtree = cfg.Directory.MaxRenameSubtree
+		b.referentialIntegrity = cfg.Directory.ReferentialIntegrity
+		b.maxNumSubordinates = cfg.Directory.MaxNumSubordinates
+		b.dynGroupsEnabled = cfg.Directory.DynamicGroups.Enabled
+		b.dynGroupMaxMembers = cfg.Directory.DynamicGroups.MaxMembers
+		if rb := cfg.Directory.RecycleBin; rb.Enabled {
+			b.EnableRecycleBin(RecycleBinConfig{
+				BaseDN:        cfg.Directory.BaseDN,
+				Retention:     rb.Retention,
+				PurgeInterval: rb.PurgeInterval,
+			})
+		}
 
 		// Initialize security settings
 		b.rateLimitEnabled = cfg.Security.RateLimit.Enabled
@@ -157,13 +272,29 @@ func NewBackend(engine storage.StorageEngine, cfg *config.Config) *ObaBackend {
 				RequireSpecial:   cfg.Security.PasswordPolicy.RequireSpecial,
 				MaxAge:           cfg.Security.PasswordPolicy.MaxAge,
 				HistoryCount:     cfg.Security.PasswordPolicy.HistoryCount,
+				GraceLogins:      cfg.Security.PasswordPolicy.GraceLogins,
 			}
+			b.passwordStatus.SetGraceLogins(cfg.Security.PasswordPolicy.GraceLogins)
 		}
 
 		// Bootstrap directory structure if baseDN is configured
 		if cfg.Directory.BaseDN != "" {
 			b.bootstrapDirectory(cfg.Directory.BaseDN)
 		}
+
+		// Enable DIT structure rules after bootstrap so existing layouts are not rejected
+		if cfg.Schema.StructureRules.Enabled {
+			b.SetStructureRules(StructureRulesFromConfig(cfg.Schema.StructureRules.Rules))
+		}
+
+		// Validate entries against the configured built-in schema sets
+		if len(cfg.Schema.Builtin) > 0 {
+			if s, err := schema.LoadBuiltinSchema(cfg.Schema.Builtin...); err == nil {
+				b.SetSchema(s)
+				b.SetSchemaStrict(cfg.Schema.Strict)
+				b.SetObjectClassCascade(cfg.Schema.ObjectClassCascade)
+			}
+		}
 	}
 
 	return b
@@ -175,7 +306,7 @@ func (b *ObaBackend) bootstrapDirectory(baseDN string) {
 	normalizedBaseDN := normalizeDN(baseDN)
 
 	// Check if base entry exists
-	_, err := b.getEntry(normalizedBaseDN)
+	_, err := b.getEntry(context.Background(), normalizedBaseDN)
 	if err == nil {
 		// Base entry exists, directory already bootstrapped
 		return
@@ -192,29 +323,32 @@ func (b *ObaBackend) bootstrapDirectory(baseDN string) {
 		baseEntry.SetAttribute("o", dc)
 	}
 
-	_ = b.Add(baseEntry)
+	ctx := context.Background()
+	_ = b.Add(ctx, baseEntry)
 
 	// Create ou=users
 	usersOU := NewEntry("ou=users," + normalizedBaseDN)
 	usersOU.SetAttribute("objectClass", "organizationalUnit", "top")
 	usersOU.SetAttribute("ou", "users")
-	_ = b.Add(usersOU)
+	_ = b.Add(ctx, usersOU)
 
 	// Create ou=groups
 	groupsOU := NewEntry("ou=groups," + normalizedBaseDN)
 	groupsOU.SetAttribute("objectClass", "organizationalUnit", "top")
 	groupsOU.SetAttribute("ou", "groups")
-	_ = b.Add(groupsOU)
+	_ = b.Add(ctx, groupsOU)
 }
 
 // extractDCFromDN extracts the first dc component from a DN.
 // e.g., "dc=example,dc=com" -> "example"
 func extractDCFromDN(dn string) string {
-	parts := strings.Split(strings.ToLower(dn), ",")
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		if strings.HasPrefix(part, "dc=") {
-			return part[3:]
+	rdns, err := ldap.SplitDN(strings.ToLower(dn))
+	if err != nil {
+		return ""
+	}
+	for _, rdn := range rdns {
+		if attrType, attrValue := parseRDNComponent(rdn); attrType == "dc" {
+			return attrValue
 		}
 	}
 	return ""
@@ -225,6 +359,30 @@ func (b *ObaBackend) SetSchema(s *schema.Schema) {
 	b.schema = s
 }
 
+// Schema returns the schema entries are validated against, or nil.
+func (b *ObaBackend) Schema() *schema.Schema {
+	return b.schema
+}
+
+// SetSchemaStrict controls whether entries using an objectClass that is not
+// defined in the schema are rejected. When false, unknown object classes are
+// accepted and the attributes of such entries are not checked against MUST/MAY.
+func (b *ObaBackend) SetSchemaStrict(strict bool) {
+	b.schemaStrict = strict
+}
+
+// SetMaxRenameSubtree sets the largest subtree a ModifyDN may move.
+// Zero means no limit.
+func (b *ObaBackend) SetMaxRenameSubtree(limit int) {
+	b.maxRenameSubtree = limit
+}
+
+// SetReferentialIntegrity enables or disables rewriting references to
+// renamed entries.
+func (b *ObaBackend) SetReferentialIntegrity(enabled bool) {
+	b.referentialIntegrity = enabled
+}
+
 // SetClusterWriter sets the cluster writer for cluster-aware write operations.
 // When set, all write operations (Add, Delete, Modify, ModifyDN) are routed
 // through the cluster writer for Raft consensus replication.
@@ -232,10 +390,16 @@ func (b *ObaBackend) SetClusterWriter(cw ClusterWriter) {
 	b.clusterWriter = cw
 }
 
+// SetClusterReader sets the cluster reader for cluster-aware searches.
+func (b *ObaBackend) SetClusterReader(cr ClusterReader) {
+	b.clusterReader = cr
+}
+
 // Bind authenticates a user with the given DN and password.
 // It first checks for root DN (admin) bind, then looks up the entry
-// in storage and verifies the password hash.
-func (b *ObaBackend) Bind(dn, password string) error {
+// in storage and verifies the password hash. A password expired under
+// the password policy is accepted only while grace logins remain.
+func (b *ObaBackend) Bind(ctx context.Context, dn, password string) error {
 	if dn == "" {
 		// Anonymous bind - always succeeds
 		return nil
@@ -249,7 +413,7 @@ func (b *ObaBackend) Bind(dn, password string) error {
 	}
 
 	// Look up entry in storage
-	entry, err := b.getEntry(normalizedDN)
+	entry, err := b.getEntry(ctx, normalizedDN)
 	if err != nil {
 		if err == ErrEntryNotFound {
 			return ErrInvalidCredentials
@@ -263,7 +427,11 @@ func (b *ObaBackend) Bind(dn, password string) error {
 	}
 
 	// Verify password
-	return b.verifyEntryPassword(entry, password)
+	if err := b.verifyEntryPassword(entry, password); err != nil {
+		return err
+	}
+
+	return b.checkPasswordExpiry(entry)
 }
 
 // verifyRootPassword verifies the password against the root password.
@@ -289,7 +457,7 @@ func (b *ObaBackend) verifyEntryPassword(entry *Entry, password string) error {
 
 	// Try each stored password (there may be multiple)
 	for _, storedPassword := range passwords {
-		err := server.VerifyPassword(password, storedPassword)
+		err := server.VerifyPassword(password, string(storedPassword))
 		if err == nil {
 			return nil
 		}
@@ -304,16 +472,44 @@ func (b *ObaBackend) isAccountDisabled(entry *Entry) bool {
 	if len(disabled) == 0 {
 		return false
 	}
-	val := strings.ToLower(disabled[0])
+	val := strings.ToLower(string(disabled[0]))
 	return val == "true" || val == "1" || val == "yes"
 }
 
 // Search searches for entries matching the given criteria.
-func (b *ObaBackend) Search(baseDN string, scope int, f *filter.Filter) ([]*Entry, error) {
+// Deleted entries in the recycle bin are only returned when baseDN is
+// inside the recycle bin.
+//
+// The search records its spans below the span in ctx: a backend.search
+// span with an engine.index_lookup child when an index narrows the
+// candidates and an engine.entry_fetch child for reading and filtering
+// the entries. Filter evaluation is summarized in attributes of the fetch
+// span rather than traced per entry.
+func (b *ObaBackend) Search(ctx context.Context, baseDN string, scope int, f *filter.Filter) ([]*Entry, error) {
+	return b.search(ctx, baseDN, scope, f, false)
+}
+
+// SearchWithDeleted is like Search but also returns deleted entries from the
+// recycle bin, as requested by the Show Deleted control.
+func (b *ObaBackend) SearchWithDeleted(ctx context.Context, baseDN string, scope int, f *filter.Filter) ([]*Entry, error) {
+	return b.search(ctx, baseDN, scope, f, true)
+}
+
+// search runs a search traced below ctx, skipping recycle bin entries
+// unless showDeleted is set or the search is based inside the bin. It
+// stops when ctx ends and returns the entries found so far with the error
+// of ctx.
+func (b *ObaBackend) search(ctx context.Context, baseDN string, scope int, f *filter.Filter, showDeleted bool) ([]*Entry, error) {
 	normalizedBaseDN := normalizeDN(baseDN)
+	hideDeleted := !showDeleted && !b.inRecycleBin(normalizedBaseDN)
 
-	// Start a read transaction
-	txn, err := b.engine.Begin()
+	ctx, span := b.startSpan(ctx, "backend.search",
+		trace.String(AttrBaseDN, normalizedBaseDN),
+		trace.Int(AttrScope, scope))
+	defer span.End()
+
+	// Start a read transaction, whose reads stop when ctx ends
+	txn, err := b.begin(ctx)
 	if err != nil {
 		return nil, wrapStorageError(err)
 	}
@@ -325,59 +521,160 @@ func (b *ObaBackend) Search(baseDN string, scope int, f *filter.Filter) ([]*Entr
 	// Create filter evaluator
 	evaluator := filter.NewEvaluator(b.schema)
 
+	// Filters may only use virtual attributes that are computed for
+	// every candidate
+	virtual, err := b.virtualFilterAttributes(f)
+	if err != nil {
+		return nil, err
+	}
+
+	// An entryDN equality names the only entry that can match, which is
+	// read instead of searched for
+	lookup := false
+	if dn, ok := entryDNAssertion(f); ok {
+		target := normalizeDN(dn)
+		if !inURLScope(target, normalizedBaseDN, ldap.SearchScope(scope)) {
+			span.SetAttributes(trace.Int(AttrEntriesReturned, 0))
+			return nil, nil
+		}
+		normalizedBaseDN, storageScope, lookup = target, storage.ScopeBase, true
+	}
+
 	var iter storage.Iterator
+	var matcher *filterMatcherWrapper
 	if f != nil {
 		// Create a filter matcher wrapper
-		matcher := &filterMatcherWrapper{filter: f, evaluator: evaluator}
+		matcher = b.newFilterMatcher(ctx, f, evaluator)
+		matcher.setVirtual(virtual, &VirtualReader{ctx: ctx, b: b, txn: txn})
+	}
+	switch {
+	case f != nil && !lookup && b.clusterReader != nil:
+		iter = b.clusterReader.SearchByFilter(normalizedBaseDN, matcher)
+	case f != nil && !lookup:
 		iter = b.engine.SearchByFilter(txn, normalizedBaseDN, matcher)
-	} else {
+	case b.clusterReader != nil:
+		iter = b.clusterReader.SearchByDN(normalizedBaseDN, storageScope)
+	default:
 		iter = b.engine.SearchByDN(txn, normalizedBaseDN, storageScope)
 	}
 	defer iter.Close()
 
+	_, fetch := b.startSpan(ctx, "engine.entry_fetch")
+	fetched := 0
+	defer func() {
+		fetch.SetAttributes(fetchAttributes(fetched, matcher)...)
+		fetch.End()
+	}()
+
 	var results []*Entry
 	for iter.Next() {
+		if err := ctx.Err(); err != nil {
+			span.SetAttributes(trace.Int(AttrEntriesReturned, len(results)))
+			return results, err
+		}
+
 		storageEntry := iter.Entry()
 		if storageEntry == nil {
 			continue
 		}
+		fetched++
+		if lookup && !matcher.Match(storageEntry) {
+			continue
+		}
+		if hideDeleted && b.inRecycleBin(normalizeDN(storageEntry.DN)) {
+			continue
+		}
 
 		// Convert storage entry to backend entry
 		entry := convertFromStorageEntry(storageEntry)
+		b.dropVirtualAttributes(entry)
 		results = append(results, entry)
 	}
 
 	if err := iter.Error(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+			span.SetAttributes(trace.Int(AttrEntriesReturned, len(results)))
+			return results, ctxErr
+		}
 		return nil, wrapStorageError(err)
 	}
 
+	span.SetAttributes(trace.Int(AttrEntriesReturned, len(results)))
 	return results, nil
 }
 
-// Add adds a new entry to the directory.
-// This is a convenience method that calls AddWithBindDN with an empty bindDN.
-func (b *ObaBackend) Add(entry *Entry) error {
-	return b.AddWithBindDN(entry, "")
-}
-
 // AddWithBindDN adds a new entry to the directory with operational attributes.
 // The bindDN is used to set creatorsName and modifiersName.
+//
+// Deprecated: Use Add with a context carrying the bind DN (see
+// ContextWithOperation).
 func (b *ObaBackend) AddWithBindDN(entry *Entry, bindDN string) error {
+	return b.Add(ContextWithOperation(context.Background(), OperationInfo{BindDN: bindDN}), entry)
+}
+
+// Add adds a new entry to the directory on behalf of the client in ctx.
+// The bind DN of the client is used to set creatorsName and
+// modifiersName, and the pre-add and post-commit hooks see the client.
+func (b *ObaBackend) Add(ctx context.Context, entry *Entry) error {
 	if entry == nil || entry.DN == "" {
 		return ErrInvalidEntry
 	}
 
-	normalizedDN := normalizeDN(entry.DN)
-	entry.DN = normalizedDN
+	entry.DN = strings.TrimSpace(entry.DN)
+	prettyDN := entry.DN
+	normalizedDN := normalizeDN(prettyDN)
+
+	release, err := b.lockSchemaEntry(normalizedDN)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// In standalone mode the write transaction starts before the hooks,
+	// so that their writes commit together with the entry. It is
+	// serializable, as the existence and uniqueness checks must hold
+	// until the entry commits.
+	var txn interface{}
+	if b.clusterWriter == nil {
+		var err error
+		if txn, err = b.beginSerializable(ctx); err != nil {
+			return wrapStorageError(err)
+		}
+		defer func() {
+			if txn != nil {
+				b.engine.Rollback(txn)
+			}
+		}()
+	}
+
+	op := b.newWriteOp(ctx, OpAdd, normalizedDN, txn)
+	op.Entry = entry
+	if err := b.runPreHooks(ctx, op); err != nil {
+		return err
+	}
+	entry.DN = prettyDN
 
 	// Set operational attributes for add operation
-	SetOperationalAttrs(entry, OpAdd, bindDN)
+	SetOperationalAttrs(entry, OpAdd, op.BindDN)
+	if entry.HasAttribute(PasswordAttribute) && !entry.HasAttribute(AttrPwdChangedTime) {
+		b.stampPasswordChange(entry)
+	}
 
 	// Enforce OU placement rules for user/group object classes.
 	if err := validateEntryPlacement(entry); err != nil {
 		return err
 	}
 
+	// Collective attributes are only held by their subentries
+	if err := validateCollectiveAttributes(entry); err != nil {
+		return err
+	}
+
+	// Virtual attributes are computed at search time
+	if err := b.validateVirtualAttributes(entry); err != nil {
+		return err
+	}
+
 	// Validate entry against schema if available
 	if b.schema != nil {
 		if err := b.validateEntry(entry); err != nil {
@@ -385,6 +682,24 @@ func (b *ObaBackend) AddWithBindDN(entry *Entry, bindDN string) error {
 		}
 	}
 
+	// An assertion on an add is checked against the new entry
+	if err := b.checkAssertion(ctx, entry); err != nil {
+		return err
+	}
+
+	// Enforce DIT structure rules if configured
+	if b.StructureRulesEnabled() {
+		readTxn, err := b.begin(ctx)
+		if err != nil {
+			return wrapStorageError(err)
+		}
+		err = b.validateStructure(readTxn, entry)
+		b.engine.Rollback(readTxn)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Convert to storage entry
 	storageEntry := convertToStorageEntry(entry)
 
@@ -392,10 +707,10 @@ func (b *ObaBackend) AddWithBindDN(entry *Entry, bindDN string) error {
 	if b.clusterWriter != nil {
 		var txn interface{}
 		var err error
-		requiresParent := dnUnderOU(normalizedDN, "users") || dnUnderOU(normalizedDN, "groups")
+		requiresParent := isUnderOU(normalizedDN, "users") || isUnderOU(normalizedDN, "groups")
 		if requiresParent {
 			// In cluster mode, reject orphan writes under managed OUs early.
-			txn, err = b.engine.Begin()
+			txn, err = b.begin(ctx)
 			if err != nil {
 				return wrapStorageError(err)
 			}
@@ -406,15 +721,16 @@ func (b *ObaBackend) AddWithBindDN(entry *Entry, bindDN string) error {
 			}
 			if parentDN != "" {
 				if _, err := b.engine.Get(txn, parentDN); err != nil {
+					err := b.noParent(txn, parentDN)
 					b.engine.Rollback(txn)
-					return ErrNoParent
+					return err
 				}
 			}
 			b.engine.Rollback(txn)
 		}
 
 		// Check if entry already exists (read is local)
-		txn, err = b.engine.Begin()
+		txn, err = b.begin(ctx)
 		if err != nil {
 			return wrapStorageError(err)
 		}
@@ -431,102 +747,123 @@ func (b *ObaBackend) AddWithBindDN(entry *Entry, bindDN string) error {
 
 		// Emit change event after successful commit
 		b.emitChange(stream.OpInsert, normalizedDN, storageEntry)
+		b.runPostCommitHooks(ctx, op)
 		return nil
 	}
 
-	// Standalone mode: direct write
-	txn, err := b.engine.Begin()
-	if err != nil {
-		return wrapStorageError(err)
-	}
-
-	// Check if entry already exists
-	_, err = b.engine.Get(txn, normalizedDN)
-	if err == nil {
-		b.engine.Rollback(txn)
+	// Standalone mode: check if entry already exists
+	if _, err := b.engine.Get(txn, normalizedDN); err == nil {
 		return ErrEntryExists
 	}
 
 	// Put the entry
 	if err := b.engine.Put(txn, storageEntry); err != nil {
-		b.engine.Rollback(txn)
 		return wrapStorageError(err)
 	}
 
 	// Commit the transaction
-	if err := b.engine.Commit(txn); err != nil {
+	err = b.engine.Commit(txn)
+	txn = nil
+	if err != nil {
 		return wrapStorageError(err)
 	}
 
 	// Emit change event after successful commit
 	b.emitChange(stream.OpInsert, normalizedDN, storageEntry)
+	b.runPostCommitHooks(ctx, op)
 
 	return nil
 }
 
-// Delete removes an entry from the directory.
-func (b *ObaBackend) Delete(dn string) error {
+// Delete removes an entry from the directory on behalf of the client in
+// ctx, which the pre-delete and post-commit hooks see.
+func (b *ObaBackend) Delete(ctx context.Context, dn string) error {
 	if dn == "" {
 		return ErrInvalidDN
 	}
 
 	normalizedDN := normalizeDN(dn)
 
-	// Check if entry exists and has children (read is local)
-	txn, err := b.engine.Begin()
+	release, err := b.lockSchemaEntry(normalizedDN)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Check if entry exists and has children. In standalone mode this is
+	// the write transaction, which the hooks share. It is serializable, so
+	// that a child added concurrently makes the delete fail.
+	txn, err := b.beginSerializable(ctx)
 	if err != nil {
 		return wrapStorageError(err)
 	}
-	_, err = b.engine.Get(txn, normalizedDN)
+	defer func() {
+		if txn != nil {
+			b.engine.Rollback(txn)
+		}
+	}()
+	storageEntry, err := b.engine.Get(txn, normalizedDN)
 	if err != nil {
-		b.engine.Rollback(txn)
 		return ErrEntryNotFound
 	}
+	if err := b.checkAssertion(ctx, convertFromStorageEntry(storageEntry)); err != nil {
+		return err
+	}
 	hasChildren, err := b.engine.HasChildren(txn, normalizedDN)
 	if err != nil {
-		b.engine.Rollback(txn)
 		return wrapStorageError(err)
 	}
 	if hasChildren {
-		b.engine.Rollback(txn)
 		return ErrNotAllowedOnNonLeaf
 	}
-	b.engine.Rollback(txn)
 
 	// If cluster writer is set, route through Raft consensus
 	if b.clusterWriter != nil {
-		if err := b.clusterWriter.Delete(normalizedDN); err != nil {
+		// Reads are local, writes are not part of a transaction
+		b.engine.Rollback(txn)
+		txn = nil
+
+		op := b.newWriteOp(ctx, OpDelete, normalizedDN, nil)
+		if err := b.runPreHooks(ctx, op); err != nil {
+			return err
+		}
+		if err := b.removeClusterEntry(normalizedDN); err != nil {
 			return wrapStorageError(err)
 		}
 		b.emitChange(stream.OpDelete, normalizedDN, nil)
+		b.runPostCommitHooks(ctx, op)
 		return nil
 	}
 
-	// Standalone mode: direct delete
-	txn, err = b.engine.Begin()
-	if err != nil {
-		return wrapStorageError(err)
+	op := b.newWriteOp(ctx, OpDelete, normalizedDN, txn)
+	if err := b.runPreHooks(ctx, op); err != nil {
+		return err
 	}
 
-	// Delete the entry
-	if err := b.engine.Delete(txn, normalizedDN); err != nil {
-		b.engine.Rollback(txn)
-		return wrapStorageError(err)
+	// Standalone mode: delete the entry, or move it to the recycle bin
+	if err := b.removeEntry(txn, normalizedDN); err != nil {
+		return assertedWriteError(ctx, err)
+	}
+	if err := b.recheckAssertion(ctx, normalizedDN); err != nil {
+		return err
 	}
 
 	// Commit the transaction
-	if err := b.engine.Commit(txn); err != nil {
+	err = b.engine.Commit(txn)
+	txn = nil
+	if err != nil {
 		return wrapStorageError(err)
 	}
 
 	// Emit change event after successful commit
 	b.emitChange(stream.OpDelete, normalizedDN, nil)
+	b.runPostCommitHooks(ctx, op)
 
 	return nil
 }
 
 // HasChildren returns true if the entry has child entries.
-func (b *ObaBackend) HasChildren(dn string) (bool, error) {
+func (b *ObaBackend) HasChildren(ctx context.Context, dn string) (bool, error) {
 	if dn == "" {
 		return false, ErrInvalidDN
 	}
@@ -534,7 +871,7 @@ func (b *ObaBackend) HasChildren(dn string) (bool, error) {
 	normalizedDN := normalizeDN(dn)
 
 	// Start a read transaction
-	txn, err := b.engine.Begin()
+	txn, err := b.begin(ctx)
 	if err != nil {
 		return false, wrapStorageError(err)
 	}
@@ -544,15 +881,19 @@ func (b *ObaBackend) HasChildren(dn string) (bool, error) {
 	return b.engine.HasChildren(txn, normalizedDN)
 }
 
-// Modify modifies an existing entry.
-// This is a convenience method that calls ModifyWithBindDN with an empty bindDN.
-func (b *ObaBackend) Modify(dn string, changes []Modification) error {
-	return b.ModifyWithBindDN(dn, changes, "")
-}
-
 // ModifyWithBindDN modifies an existing entry with operational attributes.
 // The bindDN is used to set modifiersName.
+//
+// Deprecated: Use Modify with a context carrying the bind DN (see
+// ContextWithOperation).
 func (b *ObaBackend) ModifyWithBindDN(dn string, changes []Modification, bindDN string) error {
+	return b.Modify(ContextWithOperation(context.Background(), OperationInfo{BindDN: bindDN}), dn, changes)
+}
+
+// Modify modifies an existing entry on behalf of the client in ctx. The
+// bind DN of the client is used to set modifiersName, and the pre-modify
+// and post-commit hooks see the client.
+func (b *ObaBackend) Modify(ctx context.Context, dn string, changes []Modification) error {
 	if dn == "" {
 		return ErrInvalidDN
 	}
@@ -563,26 +904,55 @@ func (b *ObaBackend) ModifyWithBindDN(dn string, changes []Modification, bindDN
 
 	normalizedDN := normalizeDN(dn)
 
-	// Start a read transaction to get existing entry
-	txn, err := b.engine.Begin()
+	release, err := b.lockSchemaEntry(normalizedDN)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Get the existing entry. In standalone mode this is the write
+	// transaction, which the hooks share.
+	txn, err := b.begin(ctx)
 	if err != nil {
 		return wrapStorageError(err)
 	}
+	defer func() {
+		if txn != nil {
+			b.engine.Rollback(txn)
+		}
+	}()
 
-	// Get the existing entry
 	storageEntry, err := b.engine.Get(txn, normalizedDN)
 	if err != nil {
-		b.engine.Rollback(txn)
 		return ErrEntryNotFound
 	}
-	b.engine.Rollback(txn)
+	if err := b.checkAssertion(ctx, convertFromStorageEntry(storageEntry)); err != nil {
+		return err
+	}
+	if b.clusterWriter != nil {
+		// Reads are local, writes are not part of a transaction
+		b.engine.Rollback(txn)
+		txn = nil
+	}
+
+	op := b.newWriteOp(ctx, OpModify, normalizedDN, txn)
+	op.Entry = convertFromStorageEntry(storageEntry)
+	op.Changes = changes
+	if err := b.runPreHooks(ctx, op); err != nil {
+		return err
+	}
+
+	if err := b.validateVirtualChanges(op.Changes); err != nil {
+		return err
+	}
 
 	// Convert to backend entry for modification
 	entry := convertFromStorageEntry(storageEntry)
+	b.dropVirtualAttributes(entry)
 
 	// Apply modifications
-	for _, mod := range changes {
-		attrName := strings.ToLower(mod.Attribute)
+	for _, mod := range op.Changes {
+		attrName := ldap.AttributeKey(mod.Attribute)
 
 		switch mod.Type {
 		case ModAdd:
@@ -608,19 +978,44 @@ func (b *ObaBackend) ModifyWithBindDN(dn string, changes []Modification, bindDN
 			} else {
 				entry.SetAttribute(attrName, mod.Values...)
 			}
+
+		case ModIncrement:
+			delta, err := incrementDelta(mod.Values)
+			if err != nil {
+				return err
+			}
+			if err := b.applyIncrement(entry, attrName, delta); err != nil {
+				return err
+			}
 		}
 	}
 
 	// Set operational attributes for modify operation
-	SetOperationalAttrs(entry, OpModify, bindDN)
+	SetOperationalAttrs(entry, OpModify, op.BindDN)
+	if changesPassword(op.Changes) {
+		b.stampPasswordChange(entry)
+	}
 
 	// Enforce OU placement rules for user/group object classes.
 	if err := validateEntryPlacement(entry); err != nil {
 		return err
 	}
 
+	// Collective attributes are only held by their subentries
+	if err := validateCollectiveAttributes(entry); err != nil {
+		return err
+	}
+
+	// Virtual attributes are computed at search time
+	if err := b.validateVirtualAttributes(entry); err != nil {
+		return err
+	}
+
 	// Validate modified entry against schema if available
 	if b.schema != nil {
+		if err := b.validateObjectClassChange(convertFromStorageEntry(storageEntry), entry); err != nil {
+			return err
+		}
 		if err := b.validateEntry(entry); err != nil {
 			return err
 		}
@@ -628,6 +1023,7 @@ func (b *ObaBackend) ModifyWithBindDN(dn string, changes []Modification, bindDN
 
 	// Convert back to storage entry
 	modifiedStorageEntry := convertToStorageEntry(entry)
+	op.Entry = entry
 
 	// If cluster writer is set, route through Raft consensus
 	if b.clusterWriter != nil {
@@ -635,35 +1031,35 @@ func (b *ObaBackend) ModifyWithBindDN(dn string, changes []Modification, bindDN
 			return wrapStorageError(err)
 		}
 		b.emitChange(stream.OpUpdate, normalizedDN, modifiedStorageEntry)
+		b.runPostCommitHooks(ctx, op)
 		return nil
 	}
 
-	// Standalone mode: direct write
-	txn, err = b.engine.Begin()
-	if err != nil {
-		return wrapStorageError(err)
-	}
-
-	// Put the modified entry
+	// Standalone mode: put the modified entry
 	if err := b.engine.Put(txn, modifiedStorageEntry); err != nil {
-		b.engine.Rollback(txn)
-		return wrapStorageError(err)
+		return assertedWriteError(ctx, err)
+	}
+	if err := b.recheckAssertion(ctx, normalizedDN); err != nil {
+		return err
 	}
 
 	// Commit the transaction
-	if err := b.engine.Commit(txn); err != nil {
+	err = b.engine.Commit(txn)
+	txn = nil
+	if err != nil {
 		return wrapStorageError(err)
 	}
 
 	// Emit change event after successful commit
 	b.emitChange(stream.OpUpdate, normalizedDN, modifiedStorageEntry)
+	b.runPostCommitHooks(ctx, op)
 
 	return nil
 }
 
-// getEntry retrieves an entry by DN.
-func (b *ObaBackend) getEntry(dn string) (*Entry, error) {
-	txn, err := b.engine.Begin()
+// getEntry retrieves an entry by DN on behalf of ctx.
+func (b *ObaBackend) getEntry(ctx context.Context, dn string) (*Entry, error) {
+	txn, err := b.begin(ctx)
 	if err != nil {
 		return nil, wrapStorageError(err)
 	}
@@ -690,15 +1086,22 @@ func (b *ObaBackend) validateEntry(entry *Entry) error {
 	}
 
 	for name, values := range entry.Attributes {
-		byteValues := make([][]byte, len(values))
-		for i, v := range values {
-			byteValues[i] = []byte(v)
-		}
-		schemaEntry.Attributes[name] = byteValues
+		schemaEntry.Attributes[name] = values
 	}
 
 	validator := schema.NewValidator(b.schema)
-	return validator.ValidateEntry(schemaEntry)
+	validator.SetStrict(b.schemaStrict)
+	err := validator.ValidateEntry(schemaEntry)
+
+	// Report object class problems as objectClassViolation
+	var ve *schema.ValidationError
+	if errors.As(err, &ve) {
+		switch ve.Code {
+		case schema.ErrObjectClassViolation, schema.ErrMissingRequiredAttribute, schema.ErrUndefinedAttributeType:
+			return fmt.Errorf("%w: %s", ErrObjectClassViolation, ve.Error())
+		}
+	}
+	return err
 }
 
 // convertToStorageEntry converts a backend Entry to a storage Entry.
@@ -706,11 +1109,7 @@ func convertToStorageEntry(entry *Entry) *storage.Entry {
 	storageEntry := storage.NewEntry(entry.DN)
 
 	for name, values := range entry.Attributes {
-		byteValues := make([][]byte, len(values))
-		for i, v := range values {
-			byteValues[i] = []byte(v)
-		}
-		storageEntry.SetAttribute(name, byteValues)
+		storageEntry.SetAttribute(name, cloneValues(values))
 	}
 
 	return storageEntry
@@ -742,6 +1141,7 @@ var standardAttrNames = map[string]string{
 	"uniquemember":             "uniqueMember",
 	"memberof":                 "memberOf",
 	"memberuid":                "memberUid",
+	"memberurl":                "memberURL",
 	"gidnumber":                "gidNumber",
 	"uidnumber":                "uidNumber",
 	"homedirectory":            "homeDirectory",
@@ -760,10 +1160,17 @@ var standardAttrNames = map[string]string{
 	"creatorsname":             "creatorsName",
 	"modifiersname":            "modifiersName",
 	"entryuuid":                "entryUUID",
+	"entrycsn":                 "entryCSN",
 	"entrydn":                  "entryDN",
 	"obadisabled":              "obaDisabled",
 	"obalocktime":              "obaLockTime",
 	"obafailedattempts":        "obaFailedAttempts",
+	"obaoriginaldn":            "obaOriginalDN",
+	"obadeletetimestamp":       "obaDeleteTimestamp",
+	"collectiveexclusions":     "collectiveExclusions",
+	"obapartialmembers":        "obaPartialMembers",
+	"pwdchangedtime":           "pwdChangedTime",
+	"pwdgraceusetime":          "pwdGraceUseTime",
 }
 
 // normalizeAttrName returns the standard LDAP attribute name
@@ -780,12 +1187,10 @@ func convertFromStorageEntry(storageEntry *storage.Entry) *Entry {
 	entry := NewEntry(storageEntry.DN)
 
 	for name, values := range storageEntry.Attributes {
-		stringValues := make([]string, len(values))
-		for i, v := range values {
-			stringValues[i] = string(v)
-		}
-		// Use lowercase attribute name for internal storage
-		entry.Attributes[strings.ToLower(name)] = stringValues
+		// Use the attribute key for internal storage, merging names that
+		// only differ in case or the binary transfer option
+		key := ldap.AttributeKey(name)
+		entry.Attributes[key] = append(entry.Attributes[key], cloneValues(values)...)
 	}
 
 	return entry
@@ -796,11 +1201,7 @@ func convertToFilterEntry(entry *Entry) *filter.Entry {
 	filterEntry := filter.NewEntry(entry.DN)
 
 	for name, values := range entry.Attributes {
-		byteValues := make([][]byte, len(values))
-		for i, v := range values {
-			byteValues[i] = []byte(v)
-		}
-		filterEntry.SetAttribute(name, byteValues...)
+		filterEntry.SetAttribute(name, values...)
 	}
 
 	return filterEntry
@@ -810,6 +1211,22 @@ func convertToFilterEntry(entry *Entry) *filter.Entry {
 type filterMatcherWrapper struct {
 	filter    *filter.Filter
 	evaluator *filter.Evaluator
+
+	// Virtual attributes the filter uses, computed for each entry
+	virtual []VirtualAttribute
+	reader  *VirtualReader
+
+	// Tracing of the search
+	ctx         context.Context
+	tracer      trace.Tracer
+	timed       bool
+	evaluations int
+	evalTime    time.Duration
+}
+
+// IndexTerms implements storage.IndexTermProvider.
+func (w *filterMatcherWrapper) IndexTerms() []storage.IndexTerm {
+	return filter.SplitIndexTerms(w.filter)
 }
 
 // Match implements storage.FilterMatcher.
@@ -818,18 +1235,35 @@ func (w *filterMatcherWrapper) Match(entry *storage.Entry) bool {
 		return true
 	}
 
+	w.evaluations++
+	if w.timed {
+		start := time.Now()
+		defer func() { w.evalTime += time.Since(start) }()
+	}
+
 	// Convert storage entry to filter entry
 	filterEntry := filter.NewEntry(entry.DN)
 	for name, values := range entry.Attributes {
 		filterEntry.SetAttribute(name, values...)
 	}
+	if len(w.virtual) > 0 {
+		computed := convertFromStorageEntry(entry)
+		for _, attr := range w.virtual {
+			name := strings.ToLower(attr.Name)
+			delete(filterEntry.Attributes, name)
+			if values := attr.Compute(w.reader, computed); len(values) > 0 {
+				filterEntry.SetStringAttribute(name, values...)
+			}
+		}
+	}
 
 	return w.evaluator.Evaluate(w.filter, filterEntry)
 }
 
-// normalizeDN normalizes a DN for consistent storage and lookup.
+// normalizeDN normalizes a DN for consistent storage and lookup. Entries
+// keep the DN they were added with; this form is only used as a key.
 func normalizeDN(dn string) string {
-	return strings.TrimSpace(strings.ToLower(dn))
+	return ldap.CanonicalDN(dn)
 }
 
 // wrapStorageError wraps a storage error with a backend error.
@@ -837,15 +1271,57 @@ func wrapStorageError(err error) error {
 	if err == nil {
 		return nil
 	}
+	if errors.Is(err, mvcc.ErrVersionConflict) || errors.Is(err, tx.ErrSerializationFailure) {
+		return fmt.Errorf("%w: %w", ErrConflict, err)
+	}
 	return fmt.Errorf("backend: %w", err)
 }
 
+// optionsEngine is implemented by storage engines that start
+// transactions with options, such as serializable transactions and
+// transactions bounded by a context.
+type optionsEngine interface {
+	BeginWithOptions(opts tx.TxOptions) (interface{}, error)
+}
+
+// begin starts a transaction for an operation on behalf of ctx. The reads
+// of the transaction fail with the error of ctx once it ends. Engines
+// without transaction options start a plain one.
+func (b *ObaBackend) begin(ctx context.Context) (interface{}, error) {
+	return b.beginWithOptions(tx.TxOptions{Context: ctx})
+}
+
+// beginSerializable starts a serializable transaction for an operation
+// that checks the directory before it writes, so that the check still
+// holds when the write commits. If a concurrent transaction invalidated
+// the check, the commit fails with ErrConflict. Engines without
+// serializable transactions start a plain one.
+func (b *ObaBackend) beginSerializable(ctx context.Context) (interface{}, error) {
+	return b.beginWithOptions(tx.TxOptions{Serializable: true, Context: ctx})
+}
+
+// beginWithOptions starts a transaction configured by opts, or a plain one
+// if the engine does not support options.
+func (b *ObaBackend) beginWithOptions(opts tx.TxOptions) (interface{}, error) {
+	if engine, ok := b.engine.(optionsEngine); ok {
+		return engine.BeginWithOptions(opts)
+	}
+	return b.engine.Begin()
+}
+
 // Watch creates a new change stream subscription with the given filter.
 // Returns a Subscriber that receives matching events on its Channel.
 func (b *ObaBackend) Watch(filter stream.WatchFilter) *stream.Subscriber {
 	return b.changeStream.Subscribe(filter)
 }
 
+// WatchWithBuffer creates a subscription that buffers up to bufferSize
+// events. Events published while the buffer is full are dropped and
+// counted by the Subscriber.
+func (b *ObaBackend) WatchWithBuffer(filter stream.WatchFilter, bufferSize int) *stream.Subscriber {
+	return b.changeStream.SubscribeWithBuffer(filter, bufferSize)
+}
+
 // WatchWithResume creates a subscription and replays events from the given token.
 // Returns stream.ErrTokenTooOld if the token is older than the oldest event in the replay buffer.
 func (b *ObaBackend) WatchWithResume(filter stream.WatchFilter, resumeToken uint64) (*stream.Subscriber, error) {
@@ -869,13 +1345,14 @@ func (b *ObaBackend) emitChange(op stream.OperationType, dn string, entry *stora
 	}
 	b.changeStream.Publish(stream.ChangeEvent{
 		Operation: op,
-		DN:        dn,
+		DN:        normalizeDN(dn),
 		Entry:     entry,
 	})
 }
 
 // Close closes the backend and releases resources.
 func (b *ObaBackend) Close() {
+	b.DisableRecycleBin()
 	if b.changeStream != nil {
 		b.changeStream.Close()
 	}
@@ -884,6 +1361,10 @@ func (b *ObaBackend) Close() {
 // SearchByDN searches for entries by DN with the given scope.
 // Returns an iterator over matching entries.
 func (b *ObaBackend) SearchByDN(baseDN string, scope storage.Scope) storage.Iterator {
+	if b.clusterReader != nil {
+		return b.clusterReader.SearchByDN(baseDN, scope)
+	}
+
 	txn, err := b.engine.Begin()
 	if err != nil {
 		return &errorIterator{err: wrapStorageError(err)}
@@ -963,6 +1444,12 @@ func (b *ObaBackend) SetPasswordPolicy(policy *password.Policy) {
 	b.securityMu.Lock()
 	defer b.securityMu.Unlock()
 	b.passwordPolicy = policy
+
+	graceLogins := 0
+	if policy != nil {
+		graceLogins = policy.GraceLogins
+	}
+	b.passwordStatus.SetGraceLogins(graceLogins)
 }
 
 // GetPasswordPolicy returns the current password policy.
@@ -987,7 +1474,7 @@ func (b *ObaBackend) GetAccountLockout(dn string) *password.AccountLockout {
 }
 
 // IsAccountLocked checks if an account is locked.
-func (b *ObaBackend) IsAccountLocked(dn string) bool {
+func (b *ObaBackend) IsAccountLocked(ctx context.Context, dn string) bool {
 	if !b.rateLimitEnabled {
 		return false
 	}
@@ -996,7 +1483,7 @@ func (b *ObaBackend) IsAccountLocked(dn string) bool {
 }
 
 // RecordAuthFailure records a failed authentication attempt.
-func (b *ObaBackend) RecordAuthFailure(dn string) {
+func (b *ObaBackend) RecordAuthFailure(ctx context.Context, dn string) {
 	if !b.rateLimitEnabled {
 		return
 	}
@@ -1005,7 +1492,7 @@ func (b *ObaBackend) RecordAuthFailure(dn string) {
 }
 
 // RecordAuthSuccess records a successful authentication.
-func (b *ObaBackend) RecordAuthSuccess(dn string) {
+func (b *ObaBackend) RecordAuthSuccess(ctx context.Context, dn string) {
 	if !b.rateLimitEnabled {
 		return
 	}
@@ -1029,6 +1516,11 @@ func (b *ObaBackend) Stats() *storage.EngineStats {
 	return b.engine.Stats()
 }
 
+// Compact reclaims the space of old entry versions and freed pages.
+func (b *ObaBackend) Compact() error {
+	return wrapStorageError(b.engine.Compact())
+}
+
 // GetLockedAccountCount returns the number of currently locked accounts.
 func (b *ObaBackend) GetLockedAccountCount() int {
 	b.securityMu.RLock()
@@ -1045,7 +1537,7 @@ func (b *ObaBackend) GetLockedAccountCount() int {
 
 // GetDisabledAccountCount returns the number of disabled accounts.
 func (b *ObaBackend) GetDisabledAccountCount() int {
-	entries, err := b.Search("", 2, nil) // subtree search from root
+	entries, err := b.Search(context.Background(), "", 2, nil) // subtree search from root
 	if err != nil {
 		return 0
 	}
@@ -1053,7 +1545,7 @@ func (b *ObaBackend) GetDisabledAccountCount() int {
 	count := 0
 	for _, entry := range entries {
 		if values := entry.GetAttribute(AccountDisabledAttribute); len(values) > 0 {
-			if strings.EqualFold(values[0], "true") {
+			if strings.EqualFold(string(values[0]), "true") {
 				count++
 			}
 		}
@@ -2,8 +2,20 @@
 package backend
 
 import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/KilimcininKorOglu/oba/internal/config"
 	"github.com/KilimcininKorOglu/oba/internal/filter"
@@ -57,6 +69,21 @@ func (m *mockStorageEngine) Delete(tx interface{}, dn string) error {
 	return nil
 }
 
+func (m *mockStorageEngine) CountChildren(tx interface{}, dn string, limit int) (int, error) {
+	count := 0
+	for entryDN := range m.entries {
+		rdn, parent, found := strings.Cut(entryDN, ",")
+		if !found || rdn == "" || parent != dn {
+			continue
+		}
+		count++
+		if count == limit {
+			break
+		}
+	}
+	return count, nil
+}
+
 func (m *mockStorageEngine) HasChildren(tx interface{}, dn string) (bool, error) {
 	// Check if any entry has this DN as a parent
 	for entryDN := range m.entries {
@@ -210,7 +237,7 @@ func TestBindAnonymous(t *testing.T) {
 	engine := newMockStorageEngine()
 	backend := NewBackend(engine, nil)
 
-	err := backend.Bind("", "")
+	err := backend.Bind(context.Background(), "", "")
 	if err != nil {
 		t.Errorf("expected anonymous bind to succeed, got error: %v", err)
 	}
@@ -240,7 +267,7 @@ func TestBindRootDN(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := backend.Bind(tt.dn, tt.password)
+			err := backend.Bind(context.Background(), tt.dn, tt.password)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Bind() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -275,7 +302,7 @@ func TestBindUserEntry(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := backend.Bind(tt.dn, tt.password)
+			err := backend.Bind(context.Background(), tt.dn, tt.password)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Bind() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -294,7 +321,7 @@ func TestBindNoPassword(t *testing.T) {
 	userEntry.SetStringAttribute("uid", "alice")
 	engine.entries["uid=alice,ou=users,dc=example,dc=com"] = userEntry
 
-	err := backend.Bind("uid=alice,ou=users,dc=example,dc=com", "anypassword")
+	err := backend.Bind(context.Background(), "uid=alice,ou=users,dc=example,dc=com", "anypassword")
 	if err != ErrNoPassword {
 		t.Errorf("expected ErrNoPassword, got %v", err)
 	}
@@ -310,7 +337,7 @@ func TestAdd(t *testing.T) {
 	entry.SetAttribute("uid", "alice")
 	entry.SetAttribute("cn", "Alice Smith")
 
-	err := backend.Add(entry)
+	err := backend.Add(context.Background(), entry)
 	if err != nil {
 		t.Fatalf("Add() error = %v", err)
 	}
@@ -331,13 +358,13 @@ func TestAddDuplicate(t *testing.T) {
 	entry.SetAttribute("objectclass", "person")
 	entry.SetAttribute("uid", "alice")
 
-	err := backend.Add(entry)
+	err := backend.Add(context.Background(), entry)
 	if err != nil {
 		t.Fatalf("first Add() error = %v", err)
 	}
 
 	// Try to add duplicate
-	err = backend.Add(entry)
+	err = backend.Add(context.Background(), entry)
 	if err != ErrEntryExists {
 		t.Errorf("expected ErrEntryExists, got %v", err)
 	}
@@ -359,7 +386,7 @@ func TestAddInvalidEntry(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := backend.Add(tt.entry)
+			err := backend.Add(context.Background(), tt.entry)
 			if err != tt.wantErr {
 				t.Errorf("Add() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -376,7 +403,7 @@ func TestAddRejectsUserOutsideUsersOU(t *testing.T) {
 	entry.SetAttribute("uid", "alice")
 	entry.SetAttribute("cn", "Alice")
 
-	err := backend.Add(entry)
+	err := backend.Add(context.Background(), entry)
 	if !errors.Is(err, ErrInvalidPlacement) {
 		t.Fatalf("expected ErrInvalidPlacement, got %v", err)
 	}
@@ -388,11 +415,11 @@ func TestAddWithBindDNRejectsUserOutsideUsersOUWithMixedCaseObjectClassKey(t *te
 
 	entry := &Entry{
 		DN: "uid=wrong,ou=groups,dc=example,dc=com",
-		Attributes: map[string][]string{
-			"objectClass": {"inetOrgPerson", "person"},
-			"uid":         {"wrong"},
-			"cn":          {"Wrong"},
-			"sn":          {"Wrong"},
+		Attributes: map[string][][]byte{
+			"objectClass": {[]byte("inetOrgPerson"), []byte("person")},
+			"uid":         {[]byte("wrong")},
+			"cn":          {[]byte("Wrong")},
+			"sn":          {[]byte("Wrong")},
 		},
 	}
 
@@ -412,7 +439,7 @@ func TestDelete(t *testing.T) {
 	entry.SetStringAttribute("objectclass", "person")
 	engine.entries["uid=alice,ou=users,dc=example,dc=com"] = entry
 
-	err := backend.Delete("uid=alice,ou=users,dc=example,dc=com")
+	err := backend.Delete(context.Background(), "uid=alice,ou=users,dc=example,dc=com")
 	if err != nil {
 		t.Fatalf("Delete() error = %v", err)
 	}
@@ -428,7 +455,7 @@ func TestDeleteNonExistent(t *testing.T) {
 	engine := newMockStorageEngine()
 	backend := NewBackend(engine, nil)
 
-	err := backend.Delete("uid=nonexistent,dc=example,dc=com")
+	err := backend.Delete(context.Background(), "uid=nonexistent,dc=example,dc=com")
 	if err != ErrEntryNotFound {
 		t.Errorf("expected ErrEntryNotFound, got %v", err)
 	}
@@ -439,7 +466,7 @@ func TestDeleteInvalidDN(t *testing.T) {
 	engine := newMockStorageEngine()
 	backend := NewBackend(engine, nil)
 
-	err := backend.Delete("")
+	err := backend.Delete(context.Background(), "")
 	if err != ErrInvalidDN {
 		t.Errorf("expected ErrInvalidDN, got %v", err)
 	}
@@ -461,7 +488,7 @@ func TestHasChildren(t *testing.T) {
 	engine.entries["uid=alice,ou=users,dc=example,dc=com"] = child
 
 	// Parent should have children
-	hasChildren, err := backend.HasChildren("ou=users,dc=example,dc=com")
+	hasChildren, err := backend.HasChildren(context.Background(), "ou=users,dc=example,dc=com")
 	if err != nil {
 		t.Fatalf("HasChildren() error = %v", err)
 	}
@@ -470,7 +497,7 @@ func TestHasChildren(t *testing.T) {
 	}
 
 	// Child should not have children
-	hasChildren, err = backend.HasChildren("uid=alice,ou=users,dc=example,dc=com")
+	hasChildren, err = backend.HasChildren(context.Background(), "uid=alice,ou=users,dc=example,dc=com")
 	if err != nil {
 		t.Fatalf("HasChildren() error = %v", err)
 	}
@@ -484,7 +511,7 @@ func TestHasChildrenInvalidDN(t *testing.T) {
 	engine := newMockStorageEngine()
 	backend := NewBackend(engine, nil)
 
-	_, err := backend.HasChildren("")
+	_, err := backend.HasChildren(context.Background(), "")
 	if err != ErrInvalidDN {
 		t.Errorf("expected ErrInvalidDN, got %v", err)
 	}
@@ -579,7 +606,7 @@ func TestModify(t *testing.T) {
 		{Type: ModDelete, Attribute: "mail", Values: nil},
 	}
 
-	err := backend.Modify("uid=alice,ou=users,dc=example,dc=com", changes)
+	err := backend.Modify(context.Background(), "uid=alice,ou=users,dc=example,dc=com", changes)
 	if err != nil {
 		t.Fatalf("Modify() error = %v", err)
 	}
@@ -609,6 +636,38 @@ func TestModify(t *testing.T) {
 	}
 }
 
+// TestModifyLanguageTags tests that modifications target the exact option set.
+func TestModifyLanguageTags(t *testing.T) {
+	engine := newMockStorageEngine()
+	backend := NewBackend(engine, nil)
+
+	entry := storage.NewEntry("uid=alice,ou=users,dc=example,dc=com")
+	entry.SetStringAttribute("objectclass", "person")
+	entry.SetStringAttribute("description", "Plain")
+	entry.SetStringAttribute("description;lang-de", "Hallo")
+	entry.SetStringAttribute("description;lang-en", "Hello")
+	engine.entries["uid=alice,ou=users,dc=example,dc=com"] = entry
+
+	changes := []Modification{
+		{Type: ModReplace, Attribute: "Description;LANG-DE", Values: []string{"Guten Tag"}},
+		{Type: ModDelete, Attribute: "description", Values: nil},
+	}
+	if err := backend.Modify(context.Background(), "uid=alice,ou=users,dc=example,dc=com", changes); err != nil {
+		t.Fatalf("Modify() error = %v", err)
+	}
+
+	modified := engine.entries["uid=alice,ou=users,dc=example,dc=com"]
+	if values := modified.GetAttribute("description"); len(values) != 0 {
+		t.Errorf("expected untagged description to be deleted, got %v", values)
+	}
+	if de := modified.GetAttribute("description;lang-de"); len(de) != 1 || string(de[0]) != "Guten Tag" {
+		t.Errorf("expected description;lang-de to be 'Guten Tag', got %v", de)
+	}
+	if en := modified.GetAttribute("description;lang-en"); len(en) != 1 || string(en[0]) != "Hello" {
+		t.Errorf("expected description;lang-en to be unchanged, got %v", en)
+	}
+}
+
 // TestModifyNonExistent tests modifying a non-existent entry.
 func TestModifyNonExistent(t *testing.T) {
 	engine := newMockStorageEngine()
@@ -618,7 +677,7 @@ func TestModifyNonExistent(t *testing.T) {
 		{Type: ModReplace, Attribute: "cn", Values: []string{"Test"}},
 	}
 
-	err := backend.Modify("uid=nonexistent,dc=example,dc=com", changes)
+	err := backend.Modify(context.Background(), "uid=nonexistent,dc=example,dc=com", changes)
 	if err != ErrEntryNotFound {
 		t.Errorf("expected ErrEntryNotFound, got %v", err)
 	}
@@ -629,12 +688,12 @@ func TestModifyEmptyChanges(t *testing.T) {
 	engine := newMockStorageEngine()
 	backend := NewBackend(engine, nil)
 
-	err := backend.Modify("uid=alice,dc=example,dc=com", nil)
+	err := backend.Modify(context.Background(), "uid=alice,dc=example,dc=com", nil)
 	if err != nil {
 		t.Errorf("expected no error for empty changes, got %v", err)
 	}
 
-	err = backend.Modify("uid=alice,dc=example,dc=com", []Modification{})
+	err = backend.Modify(context.Background(), "uid=alice,dc=example,dc=com", []Modification{})
 	if err != nil {
 		t.Errorf("expected no error for empty changes, got %v", err)
 	}
@@ -649,7 +708,7 @@ func TestModifyInvalidDN(t *testing.T) {
 		{Type: ModReplace, Attribute: "cn", Values: []string{"Test"}},
 	}
 
-	err := backend.Modify("", changes)
+	err := backend.Modify(context.Background(), "", changes)
 	if err != ErrInvalidDN {
 		t.Errorf("expected ErrInvalidDN, got %v", err)
 	}
@@ -674,7 +733,7 @@ func TestSearch(t *testing.T) {
 	engine.entries["uid=bob,ou=users,dc=example,dc=com"] = entry2
 
 	// Search without filter
-	results, err := backend.Search("dc=example,dc=com", int(storage.ScopeSubtree), nil)
+	results, err := backend.Search(context.Background(), "dc=example,dc=com", int(storage.ScopeSubtree), nil)
 	if err != nil {
 		t.Fatalf("Search() error = %v", err)
 	}
@@ -704,7 +763,7 @@ func TestSearchWithFilter(t *testing.T) {
 
 	// Search with equality filter
 	f := filter.NewEqualityFilter("uid", []byte("alice"))
-	results, err := backend.Search("dc=example,dc=com", int(storage.ScopeSubtree), f)
+	results, err := backend.Search(context.Background(), "dc=example,dc=com", int(storage.ScopeSubtree), f)
 	if err != nil {
 		t.Fatalf("Search() error = %v", err)
 	}
@@ -718,6 +777,109 @@ func TestSearchWithFilter(t *testing.T) {
 	}
 }
 
+// slowSearchEngine is a mock engine whose searches stall after the first
+// few entries.
+type slowSearchEngine struct {
+	*mockStorageEngine
+	fast  int
+	delay time.Duration
+}
+
+func (m *slowSearchEngine) SearchByDN(tx interface{}, baseDN string, scope storage.Scope) storage.Iterator {
+	iter := m.mockStorageEngine.SearchByDN(tx, baseDN, scope).(*mockIterator)
+	return &slowIterator{mockIterator: iter, fast: m.fast, delay: m.delay}
+}
+
+// slowIterator sleeps for delay before each entry after the first fast.
+type slowIterator struct {
+	*mockIterator
+	fast  int
+	delay time.Duration
+}
+
+func (it *slowIterator) Next() bool {
+	if it.index+1 >= it.fast {
+		time.Sleep(it.delay)
+	}
+	return it.mockIterator.Next()
+}
+
+// TestSearchTimeLimit tests that a search stopped by its deadline
+// returns the entries found so far.
+func TestSearchTimeLimit(t *testing.T) {
+	engine := &slowSearchEngine{mockStorageEngine: newMockStorageEngine(), fast: 3, delay: 20 * time.Millisecond}
+	backend := NewBackend(engine, nil)
+
+	for i := 0; i < 10; i++ {
+		dn := fmt.Sprintf("uid=user%d,ou=users,dc=example,dc=com", i)
+		entry := storage.NewEntry(dn)
+		entry.SetStringAttribute("objectclass", "person")
+		engine.entries[dn] = entry
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	results, err := backend.Search(ctx, "dc=example,dc=com", int(storage.ScopeSubtree), nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Search() error = %v, want deadline exceeded", err)
+	}
+	if len(results) == 0 || len(results) >= 10 {
+		t.Errorf("Search() returned %d entries, want a partial result", len(results))
+	}
+
+	// Without a deadline the search returns every entry
+	results, err = backend.Search(context.Background(), "dc=example,dc=com", int(storage.ScopeSubtree), nil)
+	if err != nil || len(results) != 10 {
+		t.Errorf("Search() = %d entries, %v, want 10", len(results), err)
+	}
+}
+
+// countdownContext is a context that is canceled once Err has been called
+// n times, so a test can cancel a scan at a fixed point.
+type countdownContext struct {
+	context.Context
+	mu sync.Mutex
+	n  int
+}
+
+func (c *countdownContext) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.n <= 0 {
+		return context.Canceled
+	}
+	c.n--
+	return nil
+}
+
+// TestSearchCanceledStopsSubtreeScan tests that canceling a search stops
+// the scan of a large subtree in the storage engine, also when no entry
+// matches the filter.
+func TestSearchCanceledStopsSubtreeScan(t *testing.T) {
+	be := newSubtreeTestBackend(t, 300)
+
+	filters := []struct {
+		name string
+		f    *filter.Filter
+	}{
+		{"no filter", nil},
+		{"unindexed filter", filter.NewEqualityFilter("description", []byte("none"))},
+	}
+
+	for _, tt := range filters {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := &countdownContext{Context: context.Background(), n: 20}
+			results, err := be.Search(ctx, "dc=example,dc=com", int(storage.ScopeSubtree), tt.f)
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("Search() error = %v, want %v", err, context.Canceled)
+			}
+			if len(results) > 20 {
+				t.Errorf("Search() returned %d entries after cancellation, want at most 20", len(results))
+			}
+		})
+	}
+}
+
 // TestSearchBaseScope tests searching with base scope.
 func TestSearchBaseScope(t *testing.T) {
 	engine := newMockStorageEngine()
@@ -730,7 +892,7 @@ func TestSearchBaseScope(t *testing.T) {
 	engine.entries["uid=alice,ou=users,dc=example,dc=com"] = entry
 
 	// Search with base scope
-	results, err := backend.Search("uid=alice,ou=users,dc=example,dc=com", int(storage.ScopeBase), nil)
+	results, err := backend.Search(context.Background(), "uid=alice,ou=users,dc=example,dc=com", int(storage.ScopeBase), nil)
 	if err != nil {
 		t.Fatalf("Search() error = %v", err)
 	}
@@ -766,12 +928,12 @@ func TestEntrySetAttribute(t *testing.T) {
 	entry.SetAttribute("cn", "Alice Smith")
 	entry.SetAttribute("mail", "alice@example.com", "alice.smith@example.com")
 
-	cn := entry.GetAttribute("cn")
+	cn := entry.GetStringAttribute("cn")
 	if len(cn) != 1 || cn[0] != "Alice Smith" {
 		t.Errorf("expected cn to be ['Alice Smith'], got %v", cn)
 	}
 
-	mail := entry.GetAttribute("mail")
+	mail := entry.GetStringAttribute("mail")
 	if len(mail) != 2 {
 		t.Errorf("expected mail to have 2 values, got %d", len(mail))
 	}
@@ -783,7 +945,7 @@ func TestEntryGetAttribute(t *testing.T) {
 	entry.SetAttribute("cn", "Alice Smith")
 
 	// Test case-insensitive lookup
-	cn := entry.GetAttribute("CN")
+	cn := entry.GetStringAttribute("CN")
 	if len(cn) != 1 || cn[0] != "Alice Smith" {
 		t.Errorf("expected cn to be ['Alice Smith'], got %v", cn)
 	}
@@ -795,6 +957,84 @@ func TestEntryGetAttribute(t *testing.T) {
 	}
 }
 
+// TestEntryBinaryAttribute tests that binary values and the binary
+// transfer option are handled by the entry accessors.
+func TestEntryBinaryAttribute(t *testing.T) {
+	entry := NewEntry("uid=alice,dc=example,dc=com")
+	value := []byte{0x30, 0x82, 0x00, 0xff, 0xfe, 0x80}
+
+	entry.SetByteValues("userCertificate;binary", [][]byte{value})
+	value[0] = 0 // the entry keeps its own copy
+
+	values := entry.ByteValues("userCertificate")
+	if len(values) != 1 || !bytes.Equal(values[0], []byte{0x30, 0x82, 0x00, 0xff, 0xfe, 0x80}) {
+		t.Errorf("expected binary value to round-trip, got %v", values)
+	}
+	if !entry.HasAttribute("USERCERTIFICATE;BINARY") {
+		t.Error("expected lookup with the binary option to find the attribute")
+	}
+	if _, ok := entry.Attributes["usercertificate"]; !ok {
+		t.Errorf("expected attribute stored as usercertificate, got %v", entry.AttributeNames())
+	}
+
+	entry.SetAttribute("description;lang-EN", "hello")
+	if _, ok := entry.Attributes["description;lang-en"]; !ok {
+		t.Errorf("expected tagged attribute stored as description;lang-en, got %v", entry.AttributeNames())
+	}
+}
+
+// testCertificateDER returns a self-signed DER-encoded X.509 certificate.
+func testCertificateDER(t *testing.T) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "alice"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	return der
+}
+
+// TestCertificateRoundTrip tests that a DER certificate is stored and read
+// back byte for byte.
+func TestCertificateRoundTrip(t *testing.T) {
+	be := openCollectiveBackend(t)
+	der := testCertificateDER(t)
+
+	dn := "uid=alice,ou=users,ou=berlin,dc=example,dc=com"
+	entry := NewEntry(dn)
+	entry.SetAttribute("objectClass", "inetOrgPerson")
+	entry.SetAttribute("sn", "Alice")
+	entry.SetByteValues("userCertificate;binary", [][]byte{der})
+	if err := be.Add(context.Background(), entry); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	entries, err := be.Search(context.Background(), dn, 0, nil)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Search() = %d entries, error = %v", len(entries), err)
+	}
+	values := entries[0].GetAttribute("userCertificate")
+	if len(values) != 1 || !bytes.Equal(values[0], der) {
+		t.Fatalf("certificate changed in storage: got %d values", len(values))
+	}
+	if _, err := x509.ParseCertificate(values[0]); err != nil {
+		t.Errorf("stored certificate does not parse: %v", err)
+	}
+	if got := entries[0].GetStringAttribute("userCertificate"); len(got) != 1 || got[0] != string(der) {
+		t.Error("GetStringAttribute() does not hold the certificate bytes")
+	}
+}
+
 // TestEntryGetFirstAttribute tests getting the first attribute value.
 func TestEntryGetFirstAttribute(t *testing.T) {
 	entry := NewEntry("uid=alice,dc=example,dc=com")
@@ -837,7 +1077,7 @@ func TestEntryAddAttributeValue(t *testing.T) {
 	entry.AddAttributeValue("mail", "alice@example.com")
 	entry.AddAttributeValue("mail", "alice.smith@example.com")
 
-	mail := entry.GetAttribute("mail")
+	mail := entry.GetStringAttribute("mail")
 	if len(mail) != 2 {
 		t.Errorf("expected mail to have 2 values, got %d", len(mail))
 	}
@@ -867,7 +1107,7 @@ func TestEntryDeleteAttributeValue(t *testing.T) {
 
 	entry.DeleteAttributeValue("mail", "alice@example.com")
 
-	mail := entry.GetAttribute("mail")
+	mail := entry.GetStringAttribute("mail")
 	if len(mail) != 1 || mail[0] != "alice.smith@example.com" {
 		t.Errorf("expected mail to be ['alice.smith@example.com'], got %v", mail)
 	}
@@ -900,6 +1140,78 @@ func TestEntryClone(t *testing.T) {
 	if entry.GetFirstAttribute("cn") != "Alice Smith" {
 		t.Error("expected original entry to be unchanged after modifying clone")
 	}
+
+	// Writing to the bytes of a cloned value leaves the original unchanged
+	clone.Attributes["mail"][0][0] = 'X'
+	if entry.GetFirstAttribute("mail") != "alice@example.com" {
+		t.Error("expected original values to be unchanged after writing to clone values")
+	}
+}
+
+// TestEntryCloneAttributes tests the shallow copy of the attribute map.
+func TestEntryCloneAttributes(t *testing.T) {
+	entry := NewEntry("uid=alice,dc=example,dc=com")
+	entry.SetAttribute("cn", "Alice Smith")
+	entry.SetAttribute("mail", "alice@example.com")
+
+	attrs := entry.CloneAttributes()
+	attrs["cn"] = [][]byte{[]byte("Modified")}
+	delete(attrs, "mail")
+	attrs["sn"] = [][]byte{[]byte("Smith")}
+
+	if entry.GetFirstAttribute("cn") != "Alice Smith" {
+		t.Error("expected original cn to be unchanged after replacing it in the copy")
+	}
+	if !entry.HasAttribute("mail") {
+		t.Error("expected original mail to survive deleting it from the copy")
+	}
+	if entry.HasAttribute("sn") {
+		t.Error("expected attribute added to the copy to be missing from the original")
+	}
+
+	var empty *Entry
+	if empty.CloneAttributes() != nil {
+		t.Error("expected nil attributes for a nil entry")
+	}
+}
+
+// sharingStorageEngine is a mockStorageEngine whose Get returns the stored
+// entry itself, like an engine serving entries from a cache.
+type sharingStorageEngine struct {
+	*mockStorageEngine
+}
+
+func (m *sharingStorageEngine) Get(tx interface{}, dn string) (*storage.Entry, error) {
+	entry, ok := m.entries[dn]
+	if !ok {
+		return nil, errors.New("entry not found")
+	}
+	return entry, nil
+}
+
+// TestGetEntryReturnsCopy tests that changing an entry returned by GetEntry
+// does not change the entry in the engine.
+func TestGetEntryReturnsCopy(t *testing.T) {
+	engine := &sharingStorageEngine{mockStorageEngine: newMockStorageEngine()}
+	backend := NewBackend(engine, nil)
+
+	stored := storage.NewEntry("uid=alice,dc=example,dc=com")
+	stored.SetStringAttribute("cn", "Alice Smith")
+	engine.entries[stored.DN] = stored
+
+	got, err := backend.GetEntry(stored.DN)
+	if err != nil || got == nil {
+		t.Fatalf("GetEntry() = %v, %v", got, err)
+	}
+	got.Attributes["cn"][0][0] = 'X'
+	got.SetStringAttribute("mail", "alice@example.com")
+
+	if v := string(stored.Attributes["cn"][0]); v != "Alice Smith" {
+		t.Errorf("stored cn = %q after changing the returned entry, want %q", v, "Alice Smith")
+	}
+	if _, ok := stored.Attributes["mail"]; ok {
+		t.Error("attribute added to the returned entry appeared in the stored entry")
+	}
 }
 
 // TestEntryCloneNil tests cloning a nil entry.
@@ -975,12 +1287,12 @@ func TestMultiValuedAttributes(t *testing.T) {
 	entry.SetAttribute("objectclass", "top", "person", "inetOrgPerson")
 	entry.SetAttribute("mail", "alice@example.com", "alice.smith@example.com", "a.smith@example.com")
 
-	objectClass := entry.GetAttribute("objectclass")
+	objectClass := entry.GetStringAttribute("objectclass")
 	if len(objectClass) != 3 {
 		t.Errorf("expected objectClass to have 3 values, got %d", len(objectClass))
 	}
 
-	mail := entry.GetAttribute("mail")
+	mail := entry.GetStringAttribute("mail")
 	if len(mail) != 3 {
 		t.Errorf("expected mail to have 3 values, got %d", len(mail))
 	}
@@ -1020,7 +1332,7 @@ func TestModifyDN_BasicRename(t *testing.T) {
 		DeleteOldRDN: true,
 	}
 
-	err := backend.ModifyDN(req)
+	err := backend.ModifyDN(context.Background(), req)
 	if err != nil {
 		t.Fatalf("ModifyDN failed: %v", err)
 	}
@@ -1069,7 +1381,7 @@ func TestModifyDN_MoveToNewParent(t *testing.T) {
 		NewSuperior:  "ou=people,dc=example,dc=com",
 	}
 
-	err := backend.ModifyDN(req)
+	err := backend.ModifyDN(context.Background(), req)
 	if err != nil {
 		t.Fatalf("ModifyDN failed: %v", err)
 	}
@@ -1102,7 +1414,7 @@ func TestModifyDN_EntryNotFound(t *testing.T) {
 		DeleteOldRDN: true,
 	}
 
-	err := backend.ModifyDN(req)
+	err := backend.ModifyDN(context.Background(), req)
 	if err != ErrEntryNotFound {
 		t.Errorf("expected ErrEntryNotFound, got %v", err)
 	}
@@ -1134,7 +1446,7 @@ func TestModifyDN_NewDNAlreadyExists(t *testing.T) {
 		DeleteOldRDN: true,
 	}
 
-	err := backend.ModifyDN(req)
+	err := backend.ModifyDN(context.Background(), req)
 	if err != ErrEntryExists {
 		t.Errorf("expected ErrEntryExists, got %v", err)
 	}
@@ -1157,7 +1469,7 @@ func TestModifyDN_NewSuperiorNotFound(t *testing.T) {
 		NewSuperior:  "ou=nonexistent,dc=example,dc=com",
 	}
 
-	err := backend.ModifyDN(req)
+	err := backend.ModifyDN(context.Background(), req)
 	if err != ErrNewSuperiorNotFound {
 		t.Errorf("expected ErrNewSuperiorNotFound, got %v", err)
 	}
@@ -1180,7 +1492,7 @@ func TestModifyRejectsGroupClassOutsideGroupsOU(t *testing.T) {
 		},
 	}
 
-	err := backend.Modify("uid=alice,ou=users,dc=example,dc=com", changes)
+	err := backend.Modify(context.Background(), "uid=alice,ou=users,dc=example,dc=com", changes)
 	if !errors.Is(err, ErrInvalidPlacement) {
 		t.Fatalf("expected ErrInvalidPlacement, got %v", err)
 	}
@@ -1210,7 +1522,7 @@ func TestModifyDNRejectsMoveUserToGroupsOU(t *testing.T) {
 		NewSuperior:  "ou=groups,dc=example,dc=com",
 	}
 
-	err := backend.ModifyDN(req)
+	err := backend.ModifyDN(context.Background(), req)
 	if !errors.Is(err, ErrInvalidPlacement) {
 		t.Fatalf("expected ErrInvalidPlacement, got %v", err)
 	}
@@ -1253,7 +1565,7 @@ func TestModifyDN_InvalidRequest(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := backend.ModifyDN(tt.req)
+			err := backend.ModifyDN(context.Background(), tt.req)
 			if err != tt.wantErr {
 				t.Errorf("ModifyDN() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -1283,7 +1595,7 @@ func TestModifyDN_DeleteOldRDN(t *testing.T) {
 		DeleteOldRDN: true,
 	}
 
-	err := backend.ModifyDN(req)
+	err := backend.ModifyDN(context.Background(), req)
 	if err != nil {
 		t.Fatalf("ModifyDN failed: %v", err)
 	}
@@ -1330,7 +1642,7 @@ func TestModifyDN_KeepOldRDN(t *testing.T) {
 		DeleteOldRDN: false,
 	}
 
-	err := backend.ModifyDN(req)
+	err := backend.ModifyDN(context.Background(), req)
 	if err != nil {
 		t.Fatalf("ModifyDN failed: %v", err)
 	}
@@ -1360,3 +1672,56 @@ func TestModifyDN_KeepOldRDN(t *testing.T) {
 		t.Error("new uid attribute value should be present")
 	}
 }
+
+// newBuiltinSchemaBackend creates a backend that validates entries against the
+// default built-in schema sets.
+func newBuiltinSchemaBackend(strict bool) *ObaBackend {
+	cfg := &config.Config{
+		Schema: config.SchemaConfig{
+			Builtin: []string{"core", "cosine", "inetorgperson"},
+			Strict:  strict,
+		},
+	}
+	return NewBackend(newMockStorageEngine(), cfg)
+}
+
+func TestBuiltinSchemaValidation(t *testing.T) {
+	backend := newBuiltinSchemaBackend(false)
+	if backend.schema == nil {
+		t.Fatal("expected built-in schema to be loaded")
+	}
+
+	if err := backend.Add(context.Background(), newPersonEntry("uid=alice,ou=users,dc=example,dc=com", "alice")); err != nil {
+		t.Fatalf("Add(inetOrgPerson) error = %v", err)
+	}
+
+	// Missing MUST attribute sn
+	entry := NewEntry("uid=bob,ou=users,dc=example,dc=com")
+	entry.SetAttribute("objectclass", "inetOrgPerson", "top")
+	entry.SetAttribute("uid", "bob")
+	entry.SetAttribute("cn", "bob")
+	if err := backend.Add(context.Background(), entry); !errors.Is(err, ErrObjectClassViolation) {
+		t.Fatalf("expected ErrObjectClassViolation, got %v", err)
+	}
+}
+
+func TestBuiltinSchemaUnknownObjectClass(t *testing.T) {
+	newEntry := func() *Entry {
+		entry := newPersonEntry("uid=carol,ou=users,dc=example,dc=com", "carol")
+		entry.SetAttribute("objectclass", "inetOrgPerson", "customPerson", "top")
+		entry.SetAttribute("customAttr", "value")
+		return entry
+	}
+
+	if err := newBuiltinSchemaBackend(false).Add(context.Background(), newEntry()); err != nil {
+		t.Fatalf("non-strict Add() error = %v", err)
+	}
+
+	err := newBuiltinSchemaBackend(true).Add(context.Background(), newEntry())
+	if !errors.Is(err, ErrObjectClassViolation) {
+		t.Fatalf("expected ErrObjectClassViolation in strict mode, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "customPerson") {
+		t.Errorf("expected diagnostic to name the objectClass, got %q", err.Error())
+	}
+}
@@ -0,0 +1,278 @@
+package backend
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/filter"
+	"github.com/KilimcininKorOglu/oba/internal/server"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+// benchLarge adds the 1M entry directory to the search benchmarks and runs
+// BenchmarkCompareWithIndex. Loading them takes minutes and a few GB of
+// memory:
+// go test ./internal/backend -run '^$' -bench Search -bench.large
+var benchLarge = flag.Bool("bench.large", false, "include the large directories in the benchmarks")
+
+// Benchmark directories hold teams of benchmarkTeamSize accounts under
+// ou=people, each with the password benchmarkPassword. They are kept out of
+// ou=users, where every write scans the directory for a duplicate uid, so
+// that large directories load in linear time.
+const (
+	benchmarkSuffix   = "dc=example,dc=com"
+	benchmarkTeamSize = 100
+	benchmarkPassword = "secret"
+)
+
+// benchmarkUserDN returns the DN of the i-th user of a benchmark directory.
+func benchmarkUserDN(i int) string {
+	return fmt.Sprintf("uid=user%d,ou=team%d,ou=people,%s", i, i/benchmarkTeamSize, benchmarkSuffix)
+}
+
+// benchmarkTeamDN returns the DN of the i-th team of a benchmark directory.
+func benchmarkTeamDN(i int) string {
+	return fmt.Sprintf("ou=team%d,ou=people,%s", i, benchmarkSuffix)
+}
+
+// benchmarkSizes returns the directory sizes of the search benchmarks.
+func benchmarkSizes() []int {
+	sizes := []int{10000, 100000}
+	if *benchLarge {
+		sizes = append(sizes, 1000000)
+	}
+	return sizes
+}
+
+// openBenchmarkBackend opens a backend on a real engine holding a generated
+// directory of n accounts. Without indexed, the default indexes are dropped
+// before loading.
+func openBenchmarkBackend(b *testing.B, n int, indexed bool) *ObaBackend {
+	b.Helper()
+
+	db, err := engine.Open(b.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		b.Fatalf("failed to open database: %v", err)
+	}
+	b.Cleanup(func() { db.Close() })
+
+	if !indexed {
+		for _, attr := range db.ListIndexes() {
+			if err := db.DropIndex(attr); err != nil {
+				b.Fatalf("DropIndex(%s) error = %v", attr, err)
+			}
+		}
+	}
+
+	hash, err := server.HashPassword(benchmarkPassword, server.SchemeSSHA256)
+	if err != nil {
+		b.Fatalf("HashPassword() error = %v", err)
+	}
+
+	var entries []*storage.Entry
+	suffix := storage.NewEntry(benchmarkSuffix)
+	suffix.SetStringAttribute("objectclass", "top", "organization", "dcObject")
+	suffix.SetStringAttribute("dc", "example")
+	entries = append(entries, suffix)
+	for _, ou := range []string{"people", "users"} {
+		parent := storage.NewEntry("ou=" + ou + "," + benchmarkSuffix)
+		parent.SetStringAttribute("objectclass", "top", "organizationalUnit")
+		parent.SetStringAttribute("ou", ou)
+		entries = append(entries, parent)
+	}
+	for i := 0; i < n; i++ {
+		if i%benchmarkTeamSize == 0 {
+			team := storage.NewEntry(benchmarkTeamDN(i / benchmarkTeamSize))
+			team.SetStringAttribute("objectclass", "top", "organizationalUnit")
+			team.SetStringAttribute("ou", fmt.Sprintf("team%d", i/benchmarkTeamSize))
+			entries = append(entries, team)
+		}
+		user := storage.NewEntry(benchmarkUserDN(i))
+		user.SetStringAttribute("objectclass", "top", "account", "simpleSecurityObject", "extensibleObject")
+		user.SetStringAttribute("uid", fmt.Sprintf("user%d", i))
+		user.SetStringAttribute("cn", fmt.Sprintf("User %d", i))
+		user.SetStringAttribute("sn", fmt.Sprintf("%d", i))
+		user.SetStringAttribute("mail", fmt.Sprintf("user%d@example.com", i))
+		user.SetStringAttribute("employeenumber", fmt.Sprintf("%d", i))
+		user.SetStringAttribute("userpassword", hash)
+		entries = append(entries, user)
+
+		// Commit in batches to bound the size of a transaction
+		if len(entries) >= 10000 {
+			putBenchmarkEntries(b, db, entries)
+			entries = entries[:0]
+		}
+	}
+	if len(entries) > 0 {
+		putBenchmarkEntries(b, db, entries)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Directory.BaseDN = benchmarkSuffix
+	be := NewBackend(db, cfg)
+	b.Cleanup(be.Close)
+	return be
+}
+
+// putBenchmarkEntries writes entries in a single transaction.
+func putBenchmarkEntries(b *testing.B, db *engine.ObaDB, entries []*storage.Entry) {
+	txn, err := db.Begin()
+	if err != nil {
+		b.Fatalf("Begin() error = %v", err)
+	}
+	for _, entry := range entries {
+		if err := db.Put(txn, entry); err != nil {
+			b.Fatalf("Put(%s) error = %v", entry.DN, err)
+		}
+	}
+	if err := db.Commit(txn); err != nil {
+		b.Fatalf("Commit() error = %v", err)
+	}
+}
+
+// BenchmarkBind benchmarks simple binds of accounts with a salted SHA-256
+// password.
+func BenchmarkBind(b *testing.B) {
+	be := openBenchmarkBackend(b, 10000, true)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := be.Bind(context.Background(), benchmarkUserDN(i%10000), benchmarkPassword); err != nil {
+			b.Fatalf("Bind() error = %v", err)
+		}
+	}
+}
+
+// BenchmarkSearchScope benchmarks searches of each scope by directory size:
+// a base search of an account, a one level search of a team of 100
+// accounts and a subtree search of the whole directory for one account by
+// its indexed uid.
+func BenchmarkSearchScope(b *testing.B) {
+	for _, n := range benchmarkSizes() {
+		be := openBenchmarkBackend(b, n, true)
+		teams := n / benchmarkTeamSize
+
+		b.Run(fmt.Sprintf("entries=%d/base", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				results, err := be.Search(context.Background(), benchmarkUserDN(i%n), int(storage.ScopeBase), nil)
+				if err != nil || len(results) != 1 {
+					b.Fatalf("Search() = %d entries, %v", len(results), err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("entries=%d/onelevel", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				results, err := be.Search(context.Background(), benchmarkTeamDN(i%teams), int(storage.ScopeOneLevel), nil)
+				if err != nil || len(results) != benchmarkTeamSize {
+					b.Fatalf("Search() = %d entries, %v", len(results), err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("entries=%d/subtree", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				f := filter.NewEqualityFilter("uid", []byte(fmt.Sprintf("user%d", i%n)))
+				results, err := be.Search(context.Background(), benchmarkSuffix, int(storage.ScopeSubtree), f)
+				if err != nil || len(results) != 1 {
+					b.Fatalf("Search() = %d entries, %v", len(results), err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkSearchFilter benchmarks a subtree search for one account of a 10k
+// entry directory, by an indexed and by an unindexed attribute.
+func BenchmarkSearchFilter(b *testing.B) {
+	const n = 10000
+	be := openBenchmarkBackend(b, n, true)
+
+	for _, bm := range []struct {
+		name string
+		attr string
+	}{
+		{"indexed", "uid"},
+		{"unindexed", "employeeNumber"},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				value := fmt.Sprintf("%d", i%n)
+				if bm.attr == "uid" {
+					value = "user" + value
+				}
+				f := filter.NewEqualityFilter(bm.attr, []byte(value))
+				results, err := be.Search(context.Background(), benchmarkSuffix, int(storage.ScopeSubtree), f)
+				if err != nil || len(results) != 1 {
+					b.Fatalf("Search() = %d entries, %v", len(results), err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkAdd benchmarks adding users under ou=users of a 10k entry
+// directory, with and without the default indexes.
+func BenchmarkAdd(b *testing.B) {
+	for _, indexed := range []bool{true, false} {
+		name := "indexed"
+		if !indexed {
+			name = "unindexed"
+		}
+		b.Run(name, func(b *testing.B) {
+			be := openBenchmarkBackend(b, 10000, indexed)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				entry := NewEntry(fmt.Sprintf("uid=new%d,ou=users,%s", i, benchmarkSuffix))
+				entry.SetAttribute("objectClass", "top", "person", "organizationalPerson", "inetOrgPerson")
+				entry.SetAttribute("uid", fmt.Sprintf("new%d", i))
+				entry.SetAttribute("cn", fmt.Sprintf("New %d", i))
+				entry.SetAttribute("sn", fmt.Sprintf("%d", i))
+				entry.SetAttribute("mail", fmt.Sprintf("new%d@example.com", i))
+				if err := be.Add(context.Background(), entry); err != nil {
+					b.Fatalf("Add() error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkModify benchmarks replacing the mail attribute, indexed by
+// default, of accounts with and without the default indexes.
+func BenchmarkModify(b *testing.B) {
+	const n = 10000
+	for _, indexed := range []bool{true, false} {
+		name := "indexed"
+		if !indexed {
+			name = "unindexed"
+		}
+		b.Run(name, func(b *testing.B) {
+			be := openBenchmarkBackend(b, n, indexed)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				changes := []Modification{
+					{Type: ModReplace, Attribute: "mail", Values: []string{fmt.Sprintf("user%d.%d@example.com", i%n, i)}},
+				}
+				if err := be.Modify(context.Background(), benchmarkUserDN(i%n), changes); err != nil {
+					b.Fatalf("Modify() error = %v", err)
+				}
+			}
+		})
+	}
+}
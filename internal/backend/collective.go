@@ -0,0 +1,220 @@
+// Package backend provides the LDAP backend interface that wraps the storage engine
+// and provides LDAP-specific operations including authentication, entry validation,
+// and coordination with the storage layer.
+package backend
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/KilimcininKorOglu/oba/internal/filter"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/storage/radix"
+)
+
+// Collective attribute names (RFC 3671).
+const (
+	// CollectiveOption is the attribute option that marks a collective
+	// attribute, as in "l;collective".
+	CollectiveOption = "collective"
+	// CollectiveExclusionsAttribute lists the collective attributes that do
+	// not apply to an entry.
+	CollectiveExclusionsAttribute = "collectiveExclusions"
+	// ExcludeAllCollectiveAttributes is the collectiveExclusions value that
+	// excludes every collective attribute.
+	ExcludeAllCollectiveAttributes = "excludeAllCollectiveAttributes"
+)
+
+// collectiveSubentryClass is the object class of subentries holding
+// collective attributes.
+const collectiveSubentryClass = "collectiveAttributeSubentry"
+
+// CollectiveAttributes returns, for each DN in dns, the collective
+// attributes that apply to the entry, keyed by attribute description such
+// as "l;collective". Entries without collective attributes get a nil map.
+//
+// A collectiveAttributeSubentry applies to every entry below its parent,
+// including the parent itself. The values of several subentries are
+// combined. Subentries get no collective attributes, and entries leave out
+// the attributes named in their collectiveExclusions. The stored entries
+// are not changed.
+func (b *ObaBackend) CollectiveAttributes(ctx context.Context, dns []string) []map[string][][]byte {
+	result := make([]map[string][][]byte, len(dns))
+
+	normalized := make([]string, len(dns))
+	for i, dn := range dns {
+		normalized[i] = normalizeDN(dn)
+	}
+	subentries := b.collectiveSubentries(ctx, normalized)
+	if len(subentries) == 0 {
+		return result
+	}
+
+	isSubentry := make(map[string]bool, len(subentries))
+	for _, sub := range subentries {
+		isSubentry[normalizeDN(sub.DN)] = true
+	}
+
+	for i, normalizedDN := range normalized {
+		if isSubentry[normalizedDN] {
+			continue
+		}
+
+		var attrs map[string][][]byte
+		for _, sub := range subentries {
+			if !inCollectiveScope(normalizedDN, normalizeDN(sub.DN)) {
+				continue
+			}
+			for name := range sub.Attributes {
+				if !isCollective(name) {
+					continue
+				}
+				if attrs == nil {
+					attrs = make(map[string][][]byte)
+				}
+				attrs[name] = appendUniqueValues(attrs[name], sub.ByteValues(name))
+			}
+		}
+		if attrs == nil {
+			continue
+		}
+
+		if entry, err := b.getEntry(ctx, normalizedDN); err == nil {
+			excludeCollective(attrs, entry.GetStringAttribute(CollectiveExclusionsAttribute))
+		}
+		if len(attrs) > 0 {
+			result[i] = attrs
+		}
+	}
+	return result
+}
+
+// collectiveSubentries returns the collectiveAttributeSubentry entries in
+// the naming contexts of the normalized dns. The naming context of a DN is
+// its topmost existing ancestor.
+func (b *ObaBackend) collectiveSubentries(ctx context.Context, dns []string) []*Entry {
+	exists := make(map[string]bool)
+	roots := make(map[string]bool)
+	for _, dn := range dns {
+		components, err := radix.ParseDNForward(dn)
+		if err != nil {
+			continue
+		}
+		for i := len(components) - 1; i >= 0; i-- {
+			ancestor := radix.JoinDN(components[i:])
+			found, ok := exists[ancestor]
+			if !ok {
+				_, err := b.getEntry(ctx, ancestor)
+				found = err == nil
+				exists[ancestor] = found
+			}
+			if found {
+				roots[ancestor] = true
+				break
+			}
+		}
+	}
+
+	f := &filter.Filter{
+		Type:      filter.FilterEquality,
+		Attribute: "objectClass",
+		Value:     []byte(collectiveSubentryClass),
+	}
+	var subentries []*Entry
+	for root := range roots {
+		entries, err := b.Search(ctx, root, int(ldap.ScopeWholeSubtree), f)
+		if err != nil {
+			continue
+		}
+		subentries = append(subentries, entries...)
+	}
+	return subentries
+}
+
+// validateCollectiveAttributes rejects collective attributes on entries
+// other than collectiveAttributeSubentry entries. Collective values are
+// managed only through their subentry.
+func validateCollectiveAttributes(entry *Entry) error {
+	if hasAnyObjectClass(entry, collectiveSubentryClass) {
+		return nil
+	}
+
+	names := make([]string, 0)
+	for name := range entry.Attributes {
+		if isCollective(name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	return fmt.Errorf("%w: collective attribute %s is only allowed in a %s",
+		ErrObjectClassViolation, names[0], collectiveSubentryClass)
+}
+
+// isCollective reports whether the attribute description name carries the
+// collective option.
+func isCollective(name string) bool {
+	desc, err := ldap.ParseAttributeDescription(name)
+	if err != nil {
+		return false
+	}
+	for _, opt := range desc.Options {
+		if strings.EqualFold(opt, CollectiveOption) {
+			return true
+		}
+	}
+	return false
+}
+
+// inCollectiveScope reports whether the normalized dn is within the scope of
+// the subentry subDN: its parent and everything below it.
+func inCollectiveScope(dn, subDN string) bool {
+	root, err := radix.GetParentDN(subDN)
+	if err != nil {
+		return false
+	}
+	if root == "" || dn == root {
+		return true
+	}
+	ok, _ := radix.IsDescendantOf(dn, root)
+	return ok
+}
+
+// excludeCollective removes the attributes named in exclusions from attrs.
+// Exclusions name the attribute type, so "l" removes "l;collective".
+func excludeCollective(attrs map[string][][]byte, exclusions []string) {
+	for _, excluded := range exclusions {
+		if strings.EqualFold(excluded, ExcludeAllCollectiveAttributes) {
+			clear(attrs)
+			return
+		}
+		excludedName, _, _ := strings.Cut(excluded, ";")
+		for name := range attrs {
+			attrName, _, _ := strings.Cut(name, ";")
+			if strings.EqualFold(attrName, excludedName) {
+				delete(attrs, name)
+			}
+		}
+	}
+}
+
+// appendUniqueValues appends the values not already in dst.
+func appendUniqueValues(dst, values [][]byte) [][]byte {
+	for _, v := range values {
+		found := false
+		for _, existing := range dst {
+			if string(existing) == string(v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			dst = append(dst, v)
+		}
+	}
+	return dst
+}
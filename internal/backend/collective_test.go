@@ -0,0 +1,151 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+// openCollectiveBackend opens a backend with a collective attribute
+// subentry stamping c=DE and l=Berlin on ou=berlin.
+func openCollectiveBackend(t *testing.T) *ObaBackend {
+	t.Helper()
+
+	db, err := engine.Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	cfg := config.DefaultConfig()
+	cfg.Directory.BaseDN = "dc=example,dc=com"
+	be := NewBackend(db, cfg)
+
+	entries := []*Entry{
+		NewEntry("ou=berlin,dc=example,dc=com"),
+		NewEntry("ou=users,ou=berlin,dc=example,dc=com"),
+		NewEntry("uid=hans,ou=users,ou=berlin,dc=example,dc=com"),
+		NewEntry("uid=anna,ou=users,ou=berlin,dc=example,dc=com"),
+		NewEntry("cn=location,ou=berlin,dc=example,dc=com"),
+	}
+	entries[0].SetAttribute("objectClass", "organizationalUnit")
+	entries[1].SetAttribute("objectClass", "organizationalUnit")
+	for _, user := range entries[2:4] {
+		user.SetAttribute("objectClass", "inetOrgPerson")
+		user.SetAttribute("sn", "User")
+	}
+	entries[3].SetAttribute(CollectiveExclusionsAttribute, "l")
+	entries[4].SetAttribute("objectClass", "subentry", "collectiveAttributeSubentry")
+	entries[4].SetAttribute("c;collective", "DE")
+	entries[4].SetAttribute("l;collective", "Berlin")
+
+	for _, entry := range entries {
+		if err := be.Add(context.Background(), entry); err != nil {
+			t.Fatalf("Add(%s) error = %v", entry.DN, err)
+		}
+	}
+	return be
+}
+
+func TestCollectiveAttributes(t *testing.T) {
+	be := openCollectiveBackend(t)
+
+	got := be.CollectiveAttributes(context.Background(), []string{
+		"UID=hans,ou=users,ou=berlin,dc=example,dc=com",
+		"uid=anna,ou=users,ou=berlin,dc=example,dc=com",
+		"ou=berlin,dc=example,dc=com",
+		"cn=location,ou=berlin,dc=example,dc=com",
+		"ou=users,dc=example,dc=com",
+	})
+
+	all := map[string][][]byte{
+		"c;collective": {[]byte("DE")},
+		"l;collective": {[]byte("Berlin")},
+	}
+	want := []map[string][][]byte{
+		all,
+		{"c;collective": {[]byte("DE")}},
+		all,
+		nil,
+		nil,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CollectiveAttributes() = %v, want %v", got, want)
+	}
+
+	// The stored entries are not changed
+	hans, err := be.getEntry(context.Background(), "uid=hans,ou=users,ou=berlin,dc=example,dc=com")
+	if err != nil {
+		t.Fatalf("getEntry() error = %v", err)
+	}
+	if hans.HasAttribute("c;collective") || hans.HasAttribute("c") {
+		t.Error("expected collective values not to be stored")
+	}
+
+	// Modifying an entry does not pick up collective values
+	mods := []Modification{{Type: ModReplace, Attribute: "description", Values: []string{"x"}}}
+	if err := be.Modify(context.Background(), "uid=hans,ou=users,ou=berlin,dc=example,dc=com", mods); err != nil {
+		t.Fatalf("Modify() error = %v", err)
+	}
+	hans, _ = be.getEntry(context.Background(), "uid=hans,ou=users,ou=berlin,dc=example,dc=com")
+	if hans.HasAttribute("c;collective") {
+		t.Error("expected modify not to store collective values")
+	}
+}
+
+func TestCollectiveAttributesExcludeAll(t *testing.T) {
+	be := openCollectiveBackend(t)
+
+	mods := []Modification{{Type: ModAdd, Attribute: CollectiveExclusionsAttribute, Values: []string{ExcludeAllCollectiveAttributes}}}
+	if err := be.Modify(context.Background(), "uid=hans,ou=users,ou=berlin,dc=example,dc=com", mods); err != nil {
+		t.Fatalf("Modify() error = %v", err)
+	}
+	if got := be.CollectiveAttributes(context.Background(), []string{"uid=hans,ou=users,ou=berlin,dc=example,dc=com"}); got[0] != nil {
+		t.Errorf("CollectiveAttributes() = %v, want none", got)
+	}
+}
+
+func TestCollectiveAttributesNotWritable(t *testing.T) {
+	be := openCollectiveBackend(t)
+
+	ou := NewEntry("ou=paris,dc=example,dc=com")
+	ou.SetAttribute("objectClass", "organizationalUnit")
+	ou.SetAttribute("l;collective", "Paris")
+	if err := be.Add(context.Background(), ou); !errors.Is(err, ErrObjectClassViolation) {
+		t.Errorf("Add() error = %v, want ErrObjectClassViolation", err)
+	}
+
+	mods := []Modification{{Type: ModAdd, Attribute: "C;Collective", Values: []string{"FR"}}}
+	if err := be.Modify(context.Background(), "ou=berlin,dc=example,dc=com", mods); !errors.Is(err, ErrObjectClassViolation) {
+		t.Errorf("Modify() error = %v, want ErrObjectClassViolation", err)
+	}
+
+	// The subentry itself remains writable
+	mods = []Modification{{Type: ModReplace, Attribute: "l;collective", Values: []string{"Berlin-Mitte"}}}
+	if err := be.Modify(context.Background(), "cn=location,ou=berlin,dc=example,dc=com", mods); err != nil {
+		t.Errorf("Modify() of the subentry error = %v", err)
+	}
+}
+
+func TestCollectiveAttributeSubentrySchema(t *testing.T) {
+	be := newBuiltinSchemaBackend(true)
+
+	sub := NewEntry("cn=location,ou=berlin,dc=example,dc=com")
+	sub.SetAttribute("objectClass", "top", "ldapSubEntry", "collectiveAttributeSubentry")
+	sub.SetAttribute("cn", "location")
+	sub.SetAttribute("l;collective", "Berlin")
+	if err := be.Add(context.Background(), sub); err != nil {
+		t.Errorf("Add(collectiveAttributeSubentry) error = %v", err)
+	}
+
+	person := newPersonEntry("uid=hans,ou=users,dc=example,dc=com", "hans")
+	person.SetAttribute(CollectiveExclusionsAttribute, ExcludeAllCollectiveAttributes)
+	if err := be.Add(context.Background(), person); err != nil {
+		t.Errorf("Add() with collectiveExclusions error = %v", err)
+	}
+}
@@ -2,3 +2,78 @@
 // and provides LDAP-specific operations including authentication, entry validation,
 // and coordination with the storage layer.
 package backend
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// CompareWithIndex reports whether the entry dn holds assertionValue for
+// attr, as an LDAP compare operation.
+//
+// If the attribute has an equality index and the stored value equals the
+// assertion exactly, the answer comes from the index without reading the
+// entry. Otherwise the entry is read and its values are compared, exactly
+// first and then ignoring case, so the result does not depend on whether
+// an index exists. An attribute description such as "cn" also matches
+// tagged values such as "cn;lang-de".
+//
+// Returns ErrEntryNotFound if the entry does not exist and
+// ErrNoSuchAttribute if it has no value for attr.
+func (b *ObaBackend) CompareWithIndex(ctx context.Context, dn, attr string, assertionValue []byte) (bool, error) {
+	if dn == "" {
+		return false, ErrInvalidDN
+	}
+	dn = normalizeDN(dn)
+
+	if reader, ok := b.engine.(storage.EqualityIndexReader); ok && strings.IndexByte(attr, ';') < 0 {
+		found, err := reader.HasIndexedValue(nil, dn, attr, assertionValue)
+		if err != nil {
+			return false, wrapStorageError(err)
+		}
+		if found {
+			return true, nil
+		}
+	}
+
+	entry, err := b.getEntry(ctx, dn)
+	if err != nil {
+		return false, err
+	}
+
+	values := compareValues(entry, attr)
+	if len(values) == 0 {
+		return false, ErrNoSuchAttribute
+	}
+	for _, v := range values {
+		if v == string(assertionValue) || bytes.EqualFold([]byte(v), assertionValue) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// compareValues returns the values of every attribute of entry matched by
+// the attribute description attr.
+func compareValues(entry *Entry, attr string) []string {
+	requested, err := ldap.ParseAttributeDescription(attr)
+	if err != nil {
+		return nil
+	}
+
+	var result []string
+	for name, values := range entry.Attributes {
+		stored, err := ldap.ParseAttributeDescription(name)
+		if err != nil || !ldap.MatchAttributeDescription(requested, stored) {
+			continue
+		}
+		for _, v := range values {
+			result = append(result, string(v))
+		}
+	}
+	return result
+}
@@ -0,0 +1,140 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+// openCompareBackend opens a backend on a real engine with n people under
+// ou=users, so that the default uid and mail indexes are used.
+func openCompareBackend(tb testing.TB, n int) *ObaBackend {
+	tb.Helper()
+
+	db, err := engine.Open(tb.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		tb.Fatalf("failed to open database: %v", err)
+	}
+	tb.Cleanup(func() { db.Close() })
+
+	txn, err := db.Begin()
+	if err != nil {
+		tb.Fatalf("Begin() error = %v", err)
+	}
+	for i := 0; i < n; i++ {
+		entry := storage.NewEntry(fmt.Sprintf("uid=user%d,ou=users,dc=example,dc=com", i))
+		entry.SetStringAttribute("objectclass", "person")
+		entry.SetStringAttribute("uid", fmt.Sprintf("user%d", i))
+		entry.SetStringAttribute("mail", fmt.Sprintf("User%d@Example.com", i))
+		entry.SetStringAttribute("description", "Staff")
+		if err := db.Put(txn, entry); err != nil {
+			tb.Fatalf("Put() error = %v", err)
+		}
+	}
+	if err := db.Commit(txn); err != nil {
+		tb.Fatalf("Commit() error = %v", err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Directory.BaseDN = "dc=example,dc=com"
+	return NewBackend(db, cfg)
+}
+
+// TestCompareWithIndex tests compare results with and without an index.
+func TestCompareWithIndex(t *testing.T) {
+	be := openCompareBackend(t, 5)
+	dn := "uid=user2,ou=users,dc=example,dc=com"
+
+	tests := []struct {
+		name    string
+		dn      string
+		attr    string
+		value   string
+		want    bool
+		wantErr error
+	}{
+		{"indexed value", dn, "uid", "user2", true, nil},
+		{"indexed value in other case", dn, "mail", "user2@example.com", true, nil},
+		{"indexed absent value", dn, "uid", "user3", false, nil},
+		{"unindexed value", dn, "description", "staff", true, nil},
+		{"unindexed absent value", dn, "description", "Contractor", false, nil},
+		{"no such attribute", dn, "telephoneNumber", "1234", false, ErrNoSuchAttribute},
+		{"no such entry", "uid=nobody,ou=users,dc=example,dc=com", "uid", "nobody", false, ErrEntryNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := be.CompareWithIndex(context.Background(), tt.dn, tt.attr, []byte(tt.value))
+			if err != tt.wantErr {
+				t.Fatalf("CompareWithIndex() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CompareWithIndex() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCompareWithIndexTaggedValues tests that an attribute without options
+// also compares against its language tagged values.
+func TestCompareWithIndexTaggedValues(t *testing.T) {
+	be := openCompareBackend(t, 0)
+
+	entry := NewEntry("cn=daniel,dc=example,dc=com")
+	entry.SetAttribute("cn", "Daniel")
+	entry.SetAttribute("cn;lang-de", "Daniela")
+	txn, _ := be.engine.Begin()
+	if err := be.engine.Put(txn, convertToStorageEntry(entry)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := be.engine.Commit(txn); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	for _, tt := range []struct {
+		attr  string
+		value string
+		want  bool
+	}{
+		{"cn", "Daniela", true},
+		{"cn;lang-de", "daniela", true},
+		{"cn;lang-de", "Daniel", false},
+	} {
+		got, err := be.CompareWithIndex(context.Background(), entry.DN, tt.attr, []byte(tt.value))
+		if err != nil || got != tt.want {
+			t.Errorf("CompareWithIndex(%s, %s) = %v, %v, want %v", tt.attr, tt.value, got, err, tt.want)
+		}
+	}
+}
+
+// BenchmarkCompareWithIndex benchmarks compare latency for a present and
+// an absent value of the indexed uid attribute over 500k entries. Present
+// values are answered from the index, absent values read the entry.
+func BenchmarkCompareWithIndex(b *testing.B) {
+	be := openCompareBackend(b, 500000)
+	dn := "uid=user421337,ou=users,dc=example,dc=com"
+
+	for _, bm := range []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{"present", "user421337", true},
+		{"absent", "user421338", false},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			value := []byte(bm.value)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				got, err := be.CompareWithIndex(context.Background(), dn, "uid", value)
+				if err != nil || got != bm.want {
+					b.Fatalf("CompareWithIndex() = %v, %v", got, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+// Package backend provides the LDAP backend interface that wraps the storage engine
+// and provides LDAP-specific operations including authentication, entry validation,
+// and coordination with the storage layer.
+package backend
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// csnClock hands out change sequence numbers that increase even when the
+// wall clock does not.
+var csnClock struct {
+	mu    sync.Mutex
+	last  time.Time
+	count int
+}
+
+// GenerateCSN generates a change sequence number in the format used by
+// OpenLDAP: YYYYmmddHHMMSS.uuuuuuZ#count#sid#mod, e.g.
+// "20260218103000.123456Z#000000#000#000000". The count separates CSNs
+// generated within the same microsecond. CSNs of one server sort in the
+// order they were generated.
+func GenerateCSN() string {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	csnClock.mu.Lock()
+	if now.After(csnClock.last) {
+		csnClock.last = now
+		csnClock.count = 0
+	} else {
+		csnClock.count++
+	}
+	t, count := csnClock.last, csnClock.count
+	csnClock.mu.Unlock()
+
+	return fmt.Sprintf("%s#%06x#000#000000", t.Format("20060102150405.000000Z"), count)
+}
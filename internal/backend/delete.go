@@ -3,6 +3,14 @@
 // and coordination with the storage layer.
 package backend
 
+import (
+	"context"
+	"sort"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/stream"
+)
+
 // DeleteEntry removes an entry from the directory with proper validation.
 // This method checks for children before deletion and returns appropriate errors.
 // Returns ErrEntryNotFound if the entry does not exist.
@@ -14,6 +22,12 @@ func (b *ObaBackend) DeleteEntry(dn string) error {
 
 	normalizedDN := normalizeDN(dn)
 
+	release, err := b.lockSchemaEntry(normalizedDN)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	// Start a transaction
 	txn, err := b.engine.Begin()
 	if err != nil {
@@ -52,3 +66,126 @@ func (b *ObaBackend) DeleteEntry(dn string) error {
 
 	return nil
 }
+
+// subtreeDeleteBatchSize is the maximum number of entries DeleteSubtree
+// removes in a single transaction.
+var subtreeDeleteBatchSize = 1000
+
+// DeleteSubtree removes an entry and all of its descendants, as requested by
+// the Tree Delete control. If allow is not nil it is called for every entry
+// in the subtree before anything is deleted, and ErrInsufficientAccess is
+// returned if it rejects any of them. Returns the deleted DNs, deepest first.
+//
+// Subtrees of up to 1000 entries are deleted in one transaction. Larger
+// subtrees are deleted in batches of 1000 entries, one transaction each.
+// Entries are always deleted before their parents, so if a later batch fails
+// the directory holds what is left of the subtree with no orphaned entries,
+// and the delete can be retried.
+//
+// With the recycle bin enabled, every entry of the subtree becomes its own
+// tombstone and can be restored once its parent has been restored.
+func (b *ObaBackend) DeleteSubtree(ctx context.Context, dn string, allow func(dn string) bool) ([]string, error) {
+	if dn == "" {
+		return nil, ErrInvalidDN
+	}
+
+	normalizedDN := normalizeDN(dn)
+
+	release, err := b.lockSchemaEntry(normalizedDN)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	txn, err := b.begin(ctx)
+	if err != nil {
+		return nil, wrapStorageError(err)
+	}
+
+	if _, err := b.engine.Get(txn, normalizedDN); err != nil {
+		b.engine.Rollback(txn)
+		return nil, ErrEntryNotFound
+	}
+
+	dns, err := b.subtreeDNs(txn, normalizedDN)
+	if err != nil {
+		b.engine.Rollback(txn)
+		return nil, err
+	}
+
+	if allow != nil {
+		for _, entryDN := range dns {
+			if !allow(entryDN) {
+				b.engine.Rollback(txn)
+				return nil, ErrInsufficientAccess
+			}
+		}
+	}
+
+	// Cluster mode: each delete is replicated on its own, deepest first.
+	if b.clusterWriter != nil {
+		b.engine.Rollback(txn)
+		for i, entryDN := range dns {
+			if err := b.removeClusterEntry(entryDN); err != nil {
+				return dns[:i], wrapStorageError(err)
+			}
+			b.emitChange(stream.OpDelete, entryDN, nil)
+		}
+		return dns, nil
+	}
+
+	deleted := 0
+	for deleted < len(dns) {
+		if txn == nil {
+			if txn, err = b.begin(ctx); err != nil {
+				return dns[:deleted], wrapStorageError(err)
+			}
+		}
+
+		end := deleted + subtreeDeleteBatchSize
+		if end > len(dns) {
+			end = len(dns)
+		}
+		for _, entryDN := range dns[deleted:end] {
+			if err := b.removeEntry(txn, entryDN); err != nil {
+				b.engine.Rollback(txn)
+				return dns[:deleted], wrapStorageError(err)
+			}
+		}
+		if err := b.engine.Commit(txn); err != nil {
+			return dns[:deleted], wrapStorageError(err)
+		}
+		txn = nil
+
+		for _, entryDN := range dns[deleted:end] {
+			b.emitChange(stream.OpDelete, entryDN, nil)
+		}
+		deleted = end
+	}
+
+	return dns, nil
+}
+
+// subtreeDNs returns the normalized DNs of dn and all of its descendants,
+// ordered so that every entry comes before its parent.
+func (b *ObaBackend) subtreeDNs(txn interface{}, dn string) ([]string, error) {
+	iter := b.engine.SearchByDN(txn, dn, storage.ScopeSubtree)
+	defer iter.Close()
+
+	var dns []string
+	for iter.Next() {
+		if entry := iter.Entry(); entry != nil {
+			dns = append(dns, normalizeDN(entry.DN))
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return nil, wrapStorageError(err)
+	}
+
+	// A child DN is its parent DN with an RDN prepended, so it is always
+	// longer. Sorting by length, longest first, puts children before parents.
+	sort.SliceStable(dns, func(i, j int) bool {
+		return len(dns[i]) > len(dns[j])
+	})
+	return dns, nil
+}
@@ -0,0 +1,125 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/filter"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+// newSubtreeTestBackend creates a backend on a real engine holding
+// ou=sales with an ou=users child of the given size, plus a sibling ou=hr.
+func newSubtreeTestBackend(t *testing.T, users int) *ObaBackend {
+	t.Helper()
+
+	db, err := engine.Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	be := NewBackend(db, config.DefaultConfig())
+
+	add := func(dn string, attrs map[string][]string) {
+		entry := NewEntry(dn)
+		for name, values := range attrs {
+			entry.SetAttribute(name, values...)
+		}
+		if err := be.Add(context.Background(), entry); err != nil {
+			t.Fatalf("failed to add %s: %v", dn, err)
+		}
+	}
+
+	add("dc=example,dc=com", map[string][]string{"objectClass": {"top", "domain"}, "dc": {"example"}})
+	add("ou=sales,dc=example,dc=com", map[string][]string{"objectClass": {"top", "organizationalUnit"}, "ou": {"sales"}})
+	add("ou=hr,dc=example,dc=com", map[string][]string{"objectClass": {"top", "organizationalUnit"}, "ou": {"hr"}})
+	add("ou=users,ou=sales,dc=example,dc=com", map[string][]string{"objectClass": {"top", "organizationalUnit"}, "ou": {"users"}})
+	for i := 0; i < users; i++ {
+		uid := fmt.Sprintf("user%04d", i)
+		add("uid="+uid+",ou=users,ou=sales,dc=example,dc=com", map[string][]string{
+			"objectClass": {"top", "person"},
+			"uid":         {uid},
+			"cn":          {uid},
+			"sn":          {uid},
+		})
+	}
+
+	return be
+}
+
+func TestDeleteSubtree(t *testing.T) {
+	defer func(size int) { subtreeDeleteBatchSize = size }(subtreeDeleteBatchSize)
+	subtreeDeleteBatchSize = 50
+
+	const users = 201
+	be := newSubtreeTestBackend(t, users)
+
+	deleted, err := be.DeleteSubtree(context.Background(), "ou=Sales,dc=example,dc=com", nil)
+	if err != nil {
+		t.Fatalf("DeleteSubtree() error = %v", err)
+	}
+	if len(deleted) != users+2 {
+		t.Errorf("deleted %d entries, want %d", len(deleted), users+2)
+	}
+
+	// Children are always deleted before their parents.
+	position := make(map[string]int, len(deleted))
+	for i, dn := range deleted {
+		position[dn] = i
+	}
+	for i, dn := range deleted {
+		parent := dn[strings.Index(dn, ",")+1:]
+		if j, ok := position[parent]; ok && j < i {
+			t.Fatalf("%s deleted before its child %s", parent, dn)
+		}
+	}
+
+	entries, err := be.Search(context.Background(), "dc=example,dc=com", 2, nil)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("%d entries left, want 2 (base and ou=hr)", len(entries))
+	}
+
+	// The uid index no longer knows about the deleted users.
+	entries, err = be.Search(context.Background(), "dc=example,dc=com", 2, filter.NewEqualityFilter("uid", []byte("user0042")))
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("found %d deleted users by uid", len(entries))
+	}
+
+	if _, err := be.DeleteSubtree(context.Background(), "ou=sales,dc=example,dc=com", nil); err != ErrEntryNotFound {
+		t.Errorf("second DeleteSubtree() error = %v, want ErrEntryNotFound", err)
+	}
+}
+
+func TestDeleteSubtreeDenied(t *testing.T) {
+	be := newSubtreeTestBackend(t, 10)
+
+	allow := func(dn string) bool {
+		return dn != "uid=user0007,ou=users,ou=sales,dc=example,dc=com"
+	}
+	deleted, err := be.DeleteSubtree(context.Background(), "ou=sales,dc=example,dc=com", allow)
+	if err != ErrInsufficientAccess {
+		t.Fatalf("DeleteSubtree() error = %v, want ErrInsufficientAccess", err)
+	}
+	if len(deleted) != 0 {
+		t.Errorf("deleted %d entries, want none", len(deleted))
+	}
+
+	entries, err := be.Search(context.Background(), "ou=sales,dc=example,dc=com", 2, nil)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(entries) != 12 {
+		t.Errorf("%d entries left in subtree, want 12", len(entries))
+	}
+}
@@ -18,13 +18,27 @@
 // The Backend interface defines the core operations:
 //
 //	type Backend interface {
-//	    Bind(dn, password string) error
-//	    Search(baseDN string, scope int, f *filter.Filter) ([]*Entry, error)
-//	    Add(entry *Entry) error
-//	    Delete(dn string) error
-//	    Modify(dn string, changes []Modification) error
+//	    Bind(ctx context.Context, dn, password string) error
+//	    Search(ctx context.Context, baseDN string, scope int, f *filter.Filter) ([]*Entry, error)
+//	    Add(ctx context.Context, entry *Entry) error
+//	    Delete(ctx context.Context, dn string) error
+//	    Modify(ctx context.Context, dn string, changes []Modification) error
 //	}
 //
+// Every operation takes the context of the request first. The client the
+// operation runs for (bind DN, request ID and client address) travels in
+// the context as an OperationInfo:
+//
+//	ctx = backend.ContextWithOperation(ctx, backend.OperationInfo{
+//	    BindDN:     "cn=admin,dc=example,dc=com",
+//	    ClientAddr: "192.0.2.10",
+//	})
+//
+// Canceling the context interrupts the operation: the storage engine
+// checks it before every entry it reads, so a long subtree scan stops
+// promptly, and Search returns the entries found so far with the
+// context's error.
+//
 // # Creating a Backend
 //
 // Create a new backend with a storage engine and configuration:
@@ -53,7 +67,7 @@
 //	entry.SetAttribute("uid", "alice")
 //	entry.SetAttribute("userPassword", "{SSHA}...")
 //
-//	if err := backend.Add(entry); err != nil {
+//	if err := backend.Add(ctx, entry); err != nil {
 //	    // handle error
 //	}
 //
@@ -67,10 +81,44 @@
 //	    {Type: backend.ModDelete, Attribute: "description", Values: nil}, // delete entire attribute
 //	}
 //
-//	if err := backend.Modify("uid=alice,ou=users,dc=example,dc=com", changes); err != nil {
+//	if err := backend.Modify(ctx, "uid=alice,ou=users,dc=example,dc=com", changes); err != nil {
 //	    // handle error
 //	}
 //
+// # Hooks
+//
+// Pre-operation hooks run before an add, modify or delete is applied and
+// may change the entry or modifications, or reject the operation:
+//
+//	backend.RegisterPreAddHook(func(ctx context.Context, op *backend.WriteOp) error {
+//	    if op.Entry.HasAttribute("pager") {
+//	        return backend.NewHookError(ldap.ResultConstraintViolation, "pager is not allowed")
+//	    }
+//	    return nil
+//	})
+//
+// In standalone mode they share the write transaction of the operation,
+// so entries written with WriteOp.Put commit or roll back with it.
+// Post-commit hooks run after the operation has been committed; their
+// errors and panics are logged. UIDNumberHook is a built-in pre-add hook.
+//
+// # Virtual Attributes
+//
+// Virtual attributes are computed when a search asks for them rather than
+// stored: entryDN, hasSubordinates, numSubordinates and entryVersion are
+// built in, and more can be registered:
+//
+//	backend.RegisterVirtualAttribute(backend.VirtualAttribute{
+//	    Name: "childCount",
+//	    Compute: func(r *backend.VirtualReader, entry *backend.Entry) []string {
+//	        n, _ := r.CountChildren(entry.DN, 0)
+//	        return []string{strconv.Itoa(n)}
+//	    },
+//	})
+//
+// Searches can only filter on virtual attributes marked Filterable, and
+// adds or modifies that supply one are rejected.
+//
 // # Error Handling
 //
 // The package defines specific errors for common failure conditions:
@@ -80,4 +128,16 @@
 //   - ErrEntryExists: Entry already exists (on Add)
 //   - ErrInvalidDN: Malformed distinguished name
 //   - ErrInvalidEntry: Entry validation failed
+//
+// Each of them is an LDAPError carrying the LDAP result code it is
+// returned to clients with, so a handler finds the code of any backend
+// error, wrapped or not, with errors.As:
+//
+//	var ldapErr *backend.LDAPError
+//	if errors.As(err, &ldapErr) {
+//	    code := ldapErr.ResultCode
+//	}
+//
+// An Add whose parent is missing reports the closest existing superior
+// as the error's MatchedDN.
 package backend
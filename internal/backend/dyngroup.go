@@ -0,0 +1,231 @@
+// Package backend provides the LDAP backend interface that wraps the storage engine
+// and provides LDAP-specific operations including authentication, entry validation,
+// and coordination with the storage layer.
+package backend
+
+import (
+	"context"
+	"sort"
+
+	"github.com/KilimcininKorOglu/oba/internal/filter"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/storage/radix"
+)
+
+// Dynamic group attribute names.
+const (
+	// MemberURLAttribute holds the LDAP URLs selecting the members of a
+	// groupOfURLs entry.
+	MemberURLAttribute = "memberURL"
+	// PartialMembersAttribute is set to TRUE on an expanded dynamic group
+	// whose member values were cut at the configured limit.
+	PartialMembersAttribute = "obaPartialMembers"
+)
+
+// dynamicGroupClass is the object class of dynamic groups.
+const dynamicGroupClass = "groupOfURLs"
+
+// SetDynamicGroups enables or disables dynamic group expansion. maxMembers
+// is the largest number of member values materialized for one group; zero
+// or less means no limit.
+func (b *ObaBackend) SetDynamicGroups(enabled bool, maxMembers int) {
+	b.dynGroupMu.Lock()
+	defer b.dynGroupMu.Unlock()
+	b.dynGroupsEnabled = enabled
+	b.dynGroupMaxMembers = maxMembers
+}
+
+// dynamicGroupSettings returns the dynamic group settings.
+func (b *ObaBackend) dynamicGroupSettings() (bool, int) {
+	b.dynGroupMu.RLock()
+	defer b.dynGroupMu.RUnlock()
+	return b.dynGroupsEnabled, b.dynGroupMaxMembers
+}
+
+// ExpandDynamicGroups returns entries with the members of every groupOfURLs
+// entry materialized as member values. Expanded groups are copies; other
+// entries are returned as given. When expansion is disabled entries is
+// returned unchanged.
+//
+// Static member values are kept. If the group has more members than the
+// configured limit, the first members in DN order are returned and the
+// copy gets PartialMembersAttribute set to TRUE. memberURL values that
+// cannot be parsed, name another server or carry an invalid filter are
+// skipped.
+func (b *ObaBackend) ExpandDynamicGroups(ctx context.Context, entries []*Entry) []*Entry {
+	enabled, maxMembers := b.dynamicGroupSettings()
+	if !enabled {
+		return entries
+	}
+
+	result := make([]*Entry, len(entries))
+	for i, entry := range entries {
+		result[i] = entry
+		if !isDynamicGroup(entry) {
+			continue
+		}
+
+		members, partial := b.groupMembers(ctx, entry, maxMembers)
+		expanded := entry.Clone()
+		expanded.SetAttribute("member", members...)
+		if partial {
+			expanded.SetAttribute(PartialMembersAttribute, "TRUE")
+		}
+		result[i] = expanded
+	}
+	return result
+}
+
+// GroupMembers returns the member DNs of the group groupDN in DN order.
+// Static member and uniqueMember values are always included; the members
+// selected by memberURL are added when dynamic groups are enabled. partial
+// is true when the list was cut at the configured limit.
+//
+// Returns ErrEntryNotFound if the group does not exist.
+func (b *ObaBackend) GroupMembers(groupDN string) (members []string, partial bool, err error) {
+	ctx := context.Background()
+	group, err := b.getEntry(ctx, normalizeDN(groupDN))
+	if err != nil {
+		return nil, false, err
+	}
+
+	enabled, maxMembers := b.dynamicGroupSettings()
+	if !enabled || !isDynamicGroup(group) {
+		members = staticMembers(group)
+		sort.Strings(members)
+		return members, false, nil
+	}
+
+	members, partial = b.groupMembers(ctx, group, maxMembers)
+	return members, partial, nil
+}
+
+// IsMember reports whether memberDN is a member of the group groupDN,
+// either through a static member or uniqueMember value or, when dynamic
+// groups are enabled, through one of the group's memberURL values. The
+// member limit does not apply. It implements acl.GroupResolver.
+func (b *ObaBackend) IsMember(groupDN, memberDN string) bool {
+	if groupDN == "" || memberDN == "" {
+		return false
+	}
+
+	ctx := context.Background()
+	group, err := b.getEntry(ctx, normalizeDN(groupDN))
+	if err != nil {
+		return false
+	}
+
+	normalizedMember := normalizeDN(memberDN)
+	for _, dn := range staticMembers(group) {
+		if normalizeDN(dn) == normalizedMember {
+			return true
+		}
+	}
+
+	if enabled, _ := b.dynamicGroupSettings(); !enabled || !isDynamicGroup(group) {
+		return false
+	}
+
+	member, err := b.getEntry(ctx, normalizedMember)
+	if err != nil {
+		return false
+	}
+	evaluator := filter.NewEvaluator(b.schema)
+	filterEntry := convertToFilterEntry(member)
+	for _, raw := range group.GetStringAttribute(MemberURLAttribute) {
+		u, f, ok := parseMemberURL(raw)
+		if !ok || !inURLScope(normalizedMember, u.DN, u.Scope) {
+			continue
+		}
+		if evaluator.Evaluate(f, filterEntry) {
+			return true
+		}
+	}
+	return false
+}
+
+// groupMembers returns the static and dynamic members of group in DN
+// order, capped at maxMembers when it is positive.
+func (b *ObaBackend) groupMembers(ctx context.Context, group *Entry, maxMembers int) ([]string, bool) {
+	seen := make(map[string]struct{})
+	var members []string
+	add := func(dn string) {
+		key := normalizeDN(dn)
+		if _, ok := seen[key]; ok {
+			return
+		}
+		seen[key] = struct{}{}
+		members = append(members, dn)
+	}
+
+	for _, dn := range staticMembers(group) {
+		add(dn)
+	}
+	for _, raw := range group.GetStringAttribute(MemberURLAttribute) {
+		u, f, ok := parseMemberURL(raw)
+		if !ok {
+			continue
+		}
+		entries, err := b.Search(ctx, u.DN, int(u.Scope), f)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			// Filtered searches do not apply the scope themselves
+			if inURLScope(normalizeDN(entry.DN), u.DN, u.Scope) {
+				add(entry.DN)
+			}
+		}
+	}
+
+	sort.Strings(members)
+	if maxMembers > 0 && len(members) > maxMembers {
+		return members[:maxMembers], true
+	}
+	return members, false
+}
+
+// isDynamicGroup reports whether entry is a groupOfURLs entry.
+func isDynamicGroup(entry *Entry) bool {
+	return hasAnyObjectClass(entry, dynamicGroupClass)
+}
+
+// staticMembers returns the member and uniqueMember values of group.
+func staticMembers(group *Entry) []string {
+	return append(group.GetStringAttribute("member"), group.GetStringAttribute("uniqueMember")...)
+}
+
+// parseMemberURL parses a memberURL value and its filter. URLs naming
+// another server are not supported.
+func parseMemberURL(raw string) (*ldap.URL, *filter.Filter, bool) {
+	u, err := ldap.ParseURL(raw)
+	if err != nil || u.Host != "" || u.Scheme != "ldap" {
+		return nil, nil, false
+	}
+	f, err := filter.Parse(u.Filter)
+	if err != nil {
+		return nil, nil, false
+	}
+	return u, f, true
+}
+
+// inURLScope reports whether the normalized dn is within the search scope
+// of baseDN.
+func inURLScope(dn, baseDN string, scope ldap.SearchScope) bool {
+	base := normalizeDN(baseDN)
+	if dn == base {
+		return scope != ldap.ScopeSingleLevel
+	}
+	if base == "" {
+		return scope == ldap.ScopeWholeSubtree
+	}
+
+	var ok bool
+	switch scope {
+	case ldap.ScopeSingleLevel:
+		ok, _ = radix.IsDirectChildOf(dn, base)
+	case ldap.ScopeWholeSubtree:
+		ok, _ = radix.IsDescendantOf(dn, base)
+	}
+	return ok
+}
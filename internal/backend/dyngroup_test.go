@@ -0,0 +1,167 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+// openDynGroupBackend opens a backend with five users in departments 42
+// and 7, a static group and a dynamic group selecting department 42.
+func openDynGroupBackend(t *testing.T) *ObaBackend {
+	t.Helper()
+
+	db, err := engine.Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	cfg := config.DefaultConfig()
+	cfg.Directory.BaseDN = "dc=example,dc=com"
+	cfg.Directory.DynamicGroups.Enabled = true
+	be := NewBackend(db, cfg)
+
+	for i := 0; i < 5; i++ {
+		user := NewEntry(fmt.Sprintf("uid=user%d,ou=users,dc=example,dc=com", i))
+		user.SetAttribute("objectClass", "inetOrgPerson")
+		user.SetAttribute("uid", fmt.Sprintf("user%d", i))
+		user.SetAttribute("cn", fmt.Sprintf("User %d", i))
+		user.SetAttribute("sn", "User")
+		dept := "42"
+		if i%2 == 1 {
+			dept = "7"
+		}
+		user.SetAttribute("departmentNumber", dept)
+		if err := be.Add(context.Background(), user); err != nil {
+			t.Fatalf("Add(%s) error = %v", user.DN, err)
+		}
+	}
+
+	static := NewEntry("cn=static,ou=groups,dc=example,dc=com")
+	static.SetAttribute("objectClass", "groupOfNames")
+	static.SetAttribute("cn", "static")
+	static.SetAttribute("member", "uid=user1,ou=users,dc=example,dc=com")
+	if err := be.Add(context.Background(), static); err != nil {
+		t.Fatalf("Add(%s) error = %v", static.DN, err)
+	}
+
+	dynamic := NewEntry("cn=dept42,ou=groups,dc=example,dc=com")
+	dynamic.SetAttribute("objectClass", "groupOfURLs")
+	dynamic.SetAttribute("cn", "dept42")
+	dynamic.SetAttribute("memberURL",
+		"ldap:///ou=users,dc=example,dc=com??sub?(departmentNumber=42)",
+		"ldap://other.example.com/ou=users,dc=example,dc=com??sub",
+		"not a url")
+	if err := be.Add(context.Background(), dynamic); err != nil {
+		t.Fatalf("Add(%s) error = %v", dynamic.DN, err)
+	}
+
+	return be
+}
+
+func TestExpandDynamicGroups(t *testing.T) {
+	be := openDynGroupBackend(t)
+
+	entries, err := be.Search(context.Background(), "ou=groups,dc=example,dc=com", 2, nil)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	expanded := be.ExpandDynamicGroups(context.Background(), entries)
+
+	want := []string{
+		"uid=user0,ou=users,dc=example,dc=com",
+		"uid=user2,ou=users,dc=example,dc=com",
+		"uid=user4,ou=users,dc=example,dc=com",
+	}
+	for i, entry := range expanded {
+		switch normalizeDN(entry.DN) {
+		case "cn=dept42,ou=groups,dc=example,dc=com":
+			if got := entry.GetStringAttribute("member"); !reflect.DeepEqual(got, want) {
+				t.Errorf("member = %v, want %v", got, want)
+			}
+			if entry.HasAttribute(PartialMembersAttribute) {
+				t.Error("expected complete member list")
+			}
+			if entries[i].HasAttribute("member") {
+				t.Error("expected the search result not to be modified")
+			}
+		case "cn=static,ou=groups,dc=example,dc=com":
+			if entry != entries[i] {
+				t.Error("expected static group to be returned as is")
+			}
+		}
+	}
+
+	// The member values are not stored
+	stored, err := be.getEntry(context.Background(), "cn=dept42,ou=groups,dc=example,dc=com")
+	if err != nil {
+		t.Fatalf("getEntry() error = %v", err)
+	}
+	if stored.HasAttribute("member") {
+		t.Error("expected member values not to be stored")
+	}
+}
+
+func TestExpandDynamicGroupsLimit(t *testing.T) {
+	be := openDynGroupBackend(t)
+	be.SetDynamicGroups(true, 2)
+
+	members, partial, err := be.GroupMembers("cn=dept42,ou=groups,dc=example,dc=com")
+	if err != nil {
+		t.Fatalf("GroupMembers() error = %v", err)
+	}
+	if len(members) != 2 || !partial {
+		t.Errorf("GroupMembers() = %v, %v, want 2 members and partial", members, partial)
+	}
+
+	entries, _ := be.Search(context.Background(), "cn=dept42,ou=groups,dc=example,dc=com", 0, nil)
+	expanded := be.ExpandDynamicGroups(context.Background(), entries)
+	if got := expanded[0].GetFirstAttribute(PartialMembersAttribute); got != "TRUE" {
+		t.Errorf("%s = %q, want TRUE", PartialMembersAttribute, got)
+	}
+}
+
+func TestExpandDynamicGroupsDisabled(t *testing.T) {
+	be := openDynGroupBackend(t)
+	be.SetDynamicGroups(false, 0)
+
+	entries, _ := be.Search(context.Background(), "cn=dept42,ou=groups,dc=example,dc=com", 0, nil)
+	if expanded := be.ExpandDynamicGroups(context.Background(), entries); expanded[0].HasAttribute("member") {
+		t.Error("expected no expansion when disabled")
+	}
+	if be.IsMember("cn=dept42,ou=groups,dc=example,dc=com", "uid=user0,ou=users,dc=example,dc=com") {
+		t.Error("expected no dynamic membership when disabled")
+	}
+	if !be.IsMember("cn=static,ou=groups,dc=example,dc=com", "uid=user1,ou=users,dc=example,dc=com") {
+		t.Error("expected static membership when disabled")
+	}
+}
+
+func TestIsMember(t *testing.T) {
+	be := openDynGroupBackend(t)
+	be.SetDynamicGroups(true, 1)
+
+	tests := []struct {
+		group  string
+		member string
+		want   bool
+	}{
+		{"cn=static,ou=groups,dc=example,dc=com", "uid=user1,ou=users,dc=example,dc=com", true},
+		{"cn=static,ou=groups,dc=example,dc=com", "uid=user0,ou=users,dc=example,dc=com", false},
+		{"cn=dept42,ou=groups,dc=example,dc=com", "UID=user4,ou=users,dc=example,dc=com", true},
+		{"cn=dept42,ou=groups,dc=example,dc=com", "uid=user3,ou=users,dc=example,dc=com", false},
+		{"cn=dept42,ou=groups,dc=example,dc=com", "uid=nobody,ou=users,dc=example,dc=com", false},
+		{"cn=missing,ou=groups,dc=example,dc=com", "uid=user0,ou=users,dc=example,dc=com", false},
+	}
+	for _, tt := range tests {
+		if got := be.IsMember(tt.group, tt.member); got != tt.want {
+			t.Errorf("IsMember(%s, %s) = %v, want %v", tt.group, tt.member, got, tt.want)
+		}
+	}
+}
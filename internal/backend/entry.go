@@ -4,36 +4,54 @@
 package backend
 
 import (
-	"strings"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
 )
 
 // Entry represents an LDAP entry with multi-valued attributes.
-// This is the backend's representation of an entry, using string values
-// for easier manipulation in LDAP operations.
+// This is the backend's representation of an entry. Values are byte
+// slices, so binary values such as certificates and photos are kept
+// exactly; GetStringAttribute and GetFirstAttribute give access to them as
+// strings.
 type Entry struct {
 	// DN is the distinguished name of the entry.
 	DN string
 
 	// Attributes contains the entry's attribute values.
-	// Key is the attribute name, value is a slice of string values.
-	Attributes map[string][]string
+	// Key is the attribute name, value is a slice of values.
+	// Names are stored in the form returned by ldap.AttributeKey: lowercase,
+	// with tagging options such as ";lang-en" but without ";binary".
+	Attributes map[string][][]byte
 }
 
 // NewEntry creates a new Entry with the given DN.
 func NewEntry(dn string) *Entry {
 	return &Entry{
 		DN:         dn,
-		Attributes: make(map[string][]string),
+		Attributes: make(map[string][][]byte),
 	}
 }
 
 // GetAttribute returns the values for the given attribute name.
 // Returns nil if the attribute does not exist.
-func (e *Entry) GetAttribute(name string) []string {
+func (e *Entry) GetAttribute(name string) [][]byte {
 	if e.Attributes == nil {
 		return nil
 	}
-	return e.Attributes[strings.ToLower(name)]
+	return e.Attributes[ldap.AttributeKey(name)]
+}
+
+// GetStringAttribute returns the values for the given attribute name as
+// strings. Returns nil if the attribute does not exist.
+func (e *Entry) GetStringAttribute(name string) []string {
+	values := e.GetAttribute(name)
+	if values == nil {
+		return nil
+	}
+	stringValues := make([]string, len(values))
+	for i, v := range values {
+		stringValues[i] = string(v)
+	}
+	return stringValues
 }
 
 // GetFirstAttribute returns the first value for the given attribute name.
@@ -43,7 +61,7 @@ func (e *Entry) GetFirstAttribute(name string) string {
 	if len(values) == 0 {
 		return ""
 	}
-	return values[0]
+	return string(values[0])
 }
 
 // HasAttribute returns true if the entry has the given attribute.
@@ -51,54 +69,76 @@ func (e *Entry) HasAttribute(name string) bool {
 	if e.Attributes == nil {
 		return false
 	}
-	values, ok := e.Attributes[strings.ToLower(name)]
+	values, ok := e.Attributes[ldap.AttributeKey(name)]
 	return ok && len(values) > 0
 }
 
-// SetAttribute sets the values for the given attribute name.
-// The attribute name is normalized to lowercase.
+// SetAttribute sets the string values for the given attribute name.
+// The attribute name is normalized with ldap.AttributeKey.
 func (e *Entry) SetAttribute(name string, values ...string) {
+	byteValues := make([][]byte, len(values))
+	for i, v := range values {
+		byteValues[i] = []byte(v)
+	}
+	e.setValues(name, byteValues)
+}
+
+// ByteValues returns a copy of the values of the given attribute.
+// Returns nil if the attribute does not exist.
+func (e *Entry) ByteValues(name string) [][]byte {
+	return cloneValues(e.GetAttribute(name))
+}
+
+// SetByteValues sets the values for the given attribute name.
+// The values are copied, so the caller may reuse its buffers.
+// The attribute name is normalized with ldap.AttributeKey.
+func (e *Entry) SetByteValues(name string, values [][]byte) {
+	e.setValues(name, cloneValues(values))
+}
+
+// setValues stores values under the normalized name.
+func (e *Entry) setValues(name string, values [][]byte) {
 	if e.Attributes == nil {
-		e.Attributes = make(map[string][]string)
+		e.Attributes = make(map[string][][]byte)
 	}
-	e.Attributes[strings.ToLower(name)] = values
+	e.Attributes[ldap.AttributeKey(name)] = values
 }
 
 // AddAttributeValue adds a value to the given attribute.
-// The attribute name is normalized to lowercase.
+// The attribute name is normalized with ldap.AttributeKey.
 func (e *Entry) AddAttributeValue(name string, value string) {
 	if e.Attributes == nil {
-		e.Attributes = make(map[string][]string)
+		e.Attributes = make(map[string][][]byte)
 	}
-	name = strings.ToLower(name)
-	e.Attributes[name] = append(e.Attributes[name], value)
+	name = ldap.AttributeKey(name)
+	e.Attributes[name] = append(e.Attributes[name], []byte(value))
 }
 
 // DeleteAttribute removes an attribute from the entry.
-// The attribute name is normalized to lowercase.
+// The attribute name is normalized with ldap.AttributeKey.
 func (e *Entry) DeleteAttribute(name string) {
 	if e.Attributes == nil {
 		return
 	}
-	delete(e.Attributes, strings.ToLower(name))
+	delete(e.Attributes, ldap.AttributeKey(name))
 }
 
 // DeleteAttributeValue removes a specific value from an attribute.
 // If the attribute has no more values after removal, the attribute is deleted.
-// The attribute name is normalized to lowercase.
+// The attribute name is normalized with ldap.AttributeKey.
 func (e *Entry) DeleteAttributeValue(name string, value string) {
 	if e.Attributes == nil {
 		return
 	}
-	name = strings.ToLower(name)
+	name = ldap.AttributeKey(name)
 	values := e.Attributes[name]
 	if len(values) == 0 {
 		return
 	}
 
-	newValues := make([]string, 0, len(values))
+	newValues := make([][]byte, 0, len(values))
 	for _, v := range values {
-		if v != value {
+		if string(v) != value {
 			newValues = append(newValues, v)
 		}
 	}
@@ -110,7 +150,9 @@ func (e *Entry) DeleteAttributeValue(name string, value string) {
 	}
 }
 
-// Clone creates a deep copy of the entry.
+// Clone creates a deep copy of the entry. The clone has its own attribute
+// map and value slices, so changing it in any way, including writing to
+// the bytes of a value, leaves e unchanged.
 func (e *Entry) Clone() *Entry {
 	if e == nil {
 		return nil
@@ -118,15 +160,40 @@ func (e *Entry) Clone() *Entry {
 
 	clone := &Entry{
 		DN:         e.DN,
-		Attributes: make(map[string][]string, len(e.Attributes)),
+		Attributes: make(map[string][][]byte, len(e.Attributes)),
 	}
 
 	for k, v := range e.Attributes {
-		values := make([]string, len(v))
-		copy(values, v)
-		clone.Attributes[k] = values
+		clone.Attributes[k] = cloneValues(v)
+	}
+
+	return clone
+}
+
+// CloneAttributes returns a new attribute map holding the value slices of
+// e. Adding, deleting or replacing attributes in the copy leaves e
+// unchanged, but the values themselves are shared, so a caller that
+// changes values in place needs Clone.
+func (e *Entry) CloneAttributes() map[string][][]byte {
+	if e == nil || e.Attributes == nil {
+		return nil
+	}
+	attrs := make(map[string][][]byte, len(e.Attributes))
+	for name, values := range e.Attributes {
+		attrs[name] = values
 	}
+	return attrs
+}
 
+// cloneValues returns a deep copy of values, or nil if values is nil.
+func cloneValues(values [][]byte) [][]byte {
+	if values == nil {
+		return nil
+	}
+	clone := make([][]byte, len(values))
+	for i, v := range values {
+		clone[i] = append([]byte(nil), v...)
+	}
 	return clone
 }
 
@@ -165,6 +232,9 @@ const (
 	ModDelete
 	// ModReplace replaces all values of an attribute.
 	ModReplace
+	// ModIncrement adds its single value, an integer, to the values of an
+	// integer attribute (RFC 4525).
+	ModIncrement
 )
 
 // String returns the string representation of the modification type.
@@ -176,6 +246,8 @@ func (m ModificationType) String() string {
 		return "delete"
 	case ModReplace:
 		return "replace"
+	case ModIncrement:
+		return "increment"
 	default:
 		return "unknown"
 	}
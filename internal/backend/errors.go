@@ -0,0 +1,60 @@
+package backend
+
+import (
+	"errors"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+)
+
+// LDAPError is a backend error carrying the LDAP result code it is
+// returned to clients with. The backend's sentinel errors are LDAPErrors,
+// so callers find the result code of any of them, wrapped or not, with
+// errors.As.
+type LDAPError struct {
+	// ResultCode is the LDAP result code returned to the client.
+	ResultCode ldap.ResultCode
+	// Message is the diagnostic message returned to the client.
+	Message string
+	// MatchedDN is the closest existing superior of a missing entry.
+	MatchedDN string
+}
+
+// newError creates a sentinel LDAPError.
+func newError(code ldap.ResultCode, message string) error {
+	return &LDAPError{ResultCode: code, Message: message}
+}
+
+// Error implements the error interface.
+func (e *LDAPError) Error() string {
+	return "backend: " + e.Message
+}
+
+// LDAPResult returns the LDAP result the error is returned to clients
+// with.
+func (e *LDAPError) LDAPResult() ldap.LDAPResult {
+	return ldap.LDAPResult{
+		ResultCode:        e.ResultCode,
+		MatchedDN:         e.MatchedDN,
+		DiagnosticMessage: e.Message,
+	}
+}
+
+// Is reports whether target is an LDAPError with the same result code and
+// message, so that a copy carrying a matched DN still matches its
+// sentinel.
+func (e *LDAPError) Is(target error) bool {
+	t, ok := target.(*LDAPError)
+	return ok && t.ResultCode == e.ResultCode && t.Message == e.Message
+}
+
+// withMatchedDN returns a copy of the LDAPError err reporting matchedDN as
+// the closest existing superior. Other errors are returned unchanged.
+func withMatchedDN(err error, matchedDN string) error {
+	var ldapErr *LDAPError
+	if matchedDN == "" || !errors.As(err, &ldapErr) || ldapErr != err {
+		return err
+	}
+	located := *ldapErr
+	located.MatchedDN = matchedDN
+	return &located
+}
@@ -0,0 +1,117 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+func TestLDAPErrorResultCodes(t *testing.T) {
+	be := newSubtreeTestBackend(t, 1)
+	const user = "uid=user0000,ou=users,ou=sales,dc=example,dc=com"
+
+	tests := []struct {
+		name    string
+		op      func() error
+		want    ldap.ResultCode
+		matched string
+	}{
+		{"add existing", func() error {
+			entry := NewEntry("ou=sales,dc=example,dc=com")
+			entry.SetAttribute("objectClass", "organizationalUnit")
+			entry.SetAttribute("ou", "sales")
+			return be.Add(context.Background(), entry)
+		}, ldap.ResultEntryAlreadyExists, ""},
+		{"add without parent", func() error {
+			entry := storage.NewEntry("cn=printer,ou=devices,ou=sales,dc=example,dc=com")
+			entry.SetStringAttribute("objectclass", "device")
+			entry.SetStringAttribute("cn", "printer")
+			return be.AddEntry(entry)
+		}, ldap.ResultNoSuchObject, "ou=sales,dc=example,dc=com"},
+		{"delete missing", func() error {
+			return be.Delete(context.Background(), "ou=missing,dc=example,dc=com")
+		}, ldap.ResultNoSuchObject, ""},
+		{"delete non-leaf", func() error {
+			return be.Delete(context.Background(), "ou=users,ou=sales,dc=example,dc=com")
+		}, ldap.ResultNotAllowedOnNonLeaf, ""},
+		{"modify missing", func() error {
+			return be.Modify(context.Background(), "ou=missing,dc=example,dc=com", []Modification{
+				{Type: ModReplace, Attribute: "description", Values: []string{"x"}},
+			})
+		}, ldap.ResultNoSuchObject, ""},
+		{"modify operational attribute", func() error {
+			return be.Modify(context.Background(), user, []Modification{
+				{Type: ModReplace, Attribute: "numSubordinates", Values: []string{"5"}},
+			})
+		}, ldap.ResultConstraintViolation, ""},
+		{"rename missing", func() error {
+			return be.ModifyDN(context.Background(), &ModifyDNRequest{DN: "ou=missing,dc=example,dc=com", NewRDN: "ou=found"})
+		}, ldap.ResultNoSuchObject, ""},
+		{"rename onto existing", func() error {
+			return be.ModifyDN(context.Background(), &ModifyDNRequest{DN: "ou=hr,dc=example,dc=com", NewRDN: "ou=sales"})
+		}, ldap.ResultEntryAlreadyExists, ""},
+		{"rename under missing superior", func() error {
+			return be.ModifyDN(context.Background(), &ModifyDNRequest{
+				DN:          user,
+				NewRDN:      "uid=user0000",
+				NewSuperior: "ou=missing,dc=example,dc=com",
+			})
+		}, ldap.ResultNoSuchObject, ""},
+		{"compare missing entry", func() error {
+			_, err := be.CompareWithIndex(context.Background(), "ou=missing,dc=example,dc=com", "ou", []byte("missing"))
+			return err
+		}, ldap.ResultNoSuchObject, ""},
+		{"compare missing attribute", func() error {
+			_, err := be.CompareWithIndex(context.Background(), user, "mail", []byte("user0000@example.com"))
+			return err
+		}, ldap.ResultNoSuchAttribute, ""},
+		{"bind without password", func() error {
+			return be.Bind(context.Background(), user, "secret")
+		}, ldap.ResultInvalidCredentials, ""},
+		{"bind missing entry", func() error {
+			return be.Bind(context.Background(), "uid=missing,dc=example,dc=com", "secret")
+		}, ldap.ResultInvalidCredentials, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.op()
+			var ldapErr *LDAPError
+			if !errors.As(err, &ldapErr) {
+				t.Fatalf("error = %v, want an LDAPError", err)
+			}
+			if ldapErr.ResultCode != tt.want {
+				t.Errorf("ResultCode = %v, want %v (error %v)", ldapErr.ResultCode, tt.want, err)
+			}
+			if ldapErr.MatchedDN != tt.matched {
+				t.Errorf("MatchedDN = %q, want %q", ldapErr.MatchedDN, tt.matched)
+			}
+		})
+	}
+}
+
+func TestLDAPErrorMatchesSentinel(t *testing.T) {
+	located := withMatchedDN(ErrEntryNotFound, "dc=example,dc=com")
+	if !errors.Is(located, ErrEntryNotFound) {
+		t.Error("error with a matched DN does not match ErrEntryNotFound")
+	}
+	if errors.Is(located, ErrDeletedEntryNotFound) {
+		t.Error("error with a matched DN matches ErrDeletedEntryNotFound")
+	}
+	if located.Error() != ErrEntryNotFound.Error() {
+		t.Errorf("Error() = %q, want %q", located.Error(), ErrEntryNotFound.Error())
+	}
+
+	wrapped := fmt.Errorf("%w: cn is required", ErrObjectClassViolation)
+	var ldapErr *LDAPError
+	if !errors.As(wrapped, &ldapErr) || ldapErr.ResultCode != ldap.ResultObjectClassViolation {
+		t.Errorf("errors.As(%v) = %v, want objectClassViolation", wrapped, ldapErr)
+	}
+	if !errors.As(ErrConflict, &ldapErr) || ldapErr.ResultCode != ldap.ResultBusy {
+		t.Errorf("errors.As(ErrConflict) = %v, want busy", ldapErr)
+	}
+}
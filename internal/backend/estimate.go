@@ -0,0 +1,29 @@
+package backend
+
+import (
+	"context"
+
+	"github.com/KilimcininKorOglu/oba/internal/filter"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// EstimateSearch returns the number of entries a search would examine,
+// from the index candidate set when indexes narrow it, without reading any
+// entry. A base scope search examines one entry. It returns 0 when the
+// storage engine cannot estimate.
+func (b *ObaBackend) EstimateSearch(ctx context.Context, baseDN string, scope int, f *filter.Filter) int {
+	if storage.Scope(scope) == storage.ScopeBase {
+		return 1
+	}
+
+	estimator, ok := b.engine.(storage.SearchEstimator)
+	if !ok {
+		return 0
+	}
+
+	var matcher storage.FilterMatcher
+	if f != nil {
+		matcher = b.newFilterMatcher(ctx, f, filter.NewEvaluator(b.schema))
+	}
+	return estimator.EstimateSearch(normalizeDN(baseDN), matcher)
+}
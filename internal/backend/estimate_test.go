@@ -0,0 +1,34 @@
+package backend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/filter"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+func TestEstimateSearch(t *testing.T) {
+	be := openCollectiveBackend(t)
+	people := &filter.Filter{Type: filter.FilterEquality, Attribute: "objectClass", Value: []byte("inetOrgPerson")}
+
+	if got := be.EstimateSearch(context.Background(), "ou=berlin,dc=example,dc=com", 0, nil); got != 1 {
+		t.Errorf("base scope estimate = %d, want 1", got)
+	}
+
+	// Without index narrowing every entry of the subtree is examined
+	if got := be.EstimateSearch(context.Background(), "ou=berlin,dc=example,dc=com", 2, people); got != 5 {
+		t.Errorf("unindexed estimate = %d, want 5", got)
+	}
+
+	be.engine.(*engine.ObaDB).SetSearchConfig(engine.SearchConfig{IndexSplitEnabled: true})
+	if got := be.EstimateSearch(context.Background(), "dc=example,dc=com", 2, people); got != 2 {
+		t.Errorf("indexed estimate = %d, want 2", got)
+	}
+	if got := be.EstimateSearch(context.Background(), "cn=location,ou=berlin,dc=example,dc=com", 2, people); got != 0 {
+		t.Errorf("indexed estimate outside the base = %d, want 0", got)
+	}
+	if got := be.EstimateSearch(context.Background(), "ou=users,ou=berlin,dc=example,dc=com", 1, nil); got != 3 {
+		t.Errorf("unfiltered estimate = %d, want 3", got)
+	}
+}
@@ -0,0 +1,262 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/logging"
+)
+
+// HookError is returned by a pre-operation hook to reject an operation
+// with a specific LDAP result code.
+type HookError struct {
+	// ResultCode is the LDAP result code returned to the client.
+	ResultCode ldap.ResultCode
+	// Message is the diagnostic message returned to the client.
+	Message string
+	// Err is the underlying error, if any.
+	Err error
+}
+
+// NewHookError creates a HookError with the given result code and message.
+func NewHookError(code ldap.ResultCode, message string) *HookError {
+	return &HookError{ResultCode: code, Message: message}
+}
+
+// Error implements the error interface.
+func (e *HookError) Error() string {
+	return "backend: rejected by hook: " + e.Message
+}
+
+// Unwrap returns the underlying error.
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+// OperationInfo identifies the client behind a backend operation. It is
+// carried by the context passed to the Backend methods.
+type OperationInfo struct {
+	// BindDN is the DN the client is bound as. Empty for anonymous.
+	BindDN string
+	// RequestID is the request ID used in the logs. Empty if unknown.
+	RequestID string
+	// ClientAddr is the address of the client. Empty if unknown.
+	ClientAddr string
+}
+
+type operationInfoKey struct{}
+
+// ContextWithOperation returns a copy of ctx that carries info.
+func ContextWithOperation(ctx context.Context, info OperationInfo) context.Context {
+	return context.WithValue(ctx, operationInfoKey{}, info)
+}
+
+// OperationFromContext returns the operation info carried by ctx.
+func OperationFromContext(ctx context.Context) OperationInfo {
+	if ctx == nil {
+		return OperationInfo{}
+	}
+	info, _ := ctx.Value(operationInfoKey{}).(OperationInfo)
+	return info
+}
+
+// WriteOp describes a write operation passed to hooks.
+type WriteOp struct {
+	// Type is OpAdd, OpModify, OpDelete or OpModifyDN.
+	Type OperationType
+	// DN is the normalized DN of the target entry. For a modify DN it is
+	// the DN before the rename.
+	DN string
+	// Entry is the entry being added. Pre-add hooks may change its
+	// attributes, but not its DN. For a modify it is the entry before
+	// the changes in pre-modify hooks and after them in post-commit
+	// hooks. For a modify DN it is the renamed entry. It is nil for a
+	// delete.
+	Entry *Entry
+	// Changes are the modifications of a modify. Pre-modify hooks may
+	// change, add or remove modifications.
+	Changes []Modification
+	// Rename is the request of a modify DN.
+	Rename *ModifyDNRequest
+	// BindDN is the DN of the client performing the operation.
+	BindDN string
+	// RequestID is the request ID of the operation, if known.
+	RequestID string
+	// ClientAddr is the address of the client, if known.
+	ClientAddr string
+
+	backend *ObaBackend
+	txn     interface{}
+}
+
+// newWriteOp creates the WriteOp of an operation on dn. txn is the write
+// transaction of the operation, or nil in cluster mode.
+func (b *ObaBackend) newWriteOp(ctx context.Context, opType OperationType, dn string, txn interface{}) *WriteOp {
+	info := OperationFromContext(ctx)
+	return &WriteOp{
+		Type:       opType,
+		DN:         dn,
+		BindDN:     info.BindDN,
+		RequestID:  info.RequestID,
+		ClientAddr: info.ClientAddr,
+		backend:    b,
+		txn:        txn,
+	}
+}
+
+// Get reads an entry within the transaction of the operation.
+func (op *WriteOp) Get(dn string) (*Entry, error) {
+	b := op.backend
+	txn := op.txn
+	if txn == nil {
+		var err error
+		if txn, err = b.engine.Begin(); err != nil {
+			return nil, wrapStorageError(err)
+		}
+		defer b.engine.Rollback(txn)
+	}
+
+	storageEntry, err := b.engine.Get(txn, normalizeDN(dn))
+	if err != nil {
+		return nil, ErrEntryNotFound
+	}
+	return convertFromStorageEntry(storageEntry), nil
+}
+
+// Put writes an entry within the transaction of the operation, so that it
+// is committed or rolled back together with the operation. The entry is
+// not validated. In cluster mode there is no shared transaction and the
+// entry is replicated immediately.
+func (op *WriteOp) Put(entry *Entry) error {
+	b := op.backend
+	storageEntry := convertToStorageEntry(entry)
+
+	if op.txn == nil {
+		if err := b.clusterWriter.Put(storageEntry); err != nil {
+			return wrapStorageError(err)
+		}
+		return nil
+	}
+	if err := b.engine.Put(op.txn, storageEntry); err != nil {
+		return wrapStorageError(err)
+	}
+	return nil
+}
+
+// PreHook runs before a write operation is applied. It may change the
+// operation, or reject it by returning an error. A *HookError selects the
+// LDAP result code, other errors are returned as unwillingToPerform.
+type PreHook func(ctx context.Context, op *WriteOp) error
+
+// PostCommitHook runs after a write operation has been committed. Errors
+// and panics are logged and do not affect the operation.
+type PostCommitHook func(ctx context.Context, op *WriteOp) error
+
+// hooks holds the registered hooks of a backend.
+type hooks struct {
+	preAdd     []PreHook
+	preModify  []PreHook
+	preDelete  []PreHook
+	postCommit []PostCommitHook
+}
+
+// RegisterPreAddHook registers a hook that runs before each add.
+// Hooks run in registration order.
+func (b *ObaBackend) RegisterPreAddHook(hook PreHook) {
+	b.hooksMu.Lock()
+	defer b.hooksMu.Unlock()
+	b.hooks.preAdd = append(b.hooks.preAdd, hook)
+}
+
+// RegisterPreModifyHook registers a hook that runs before each modify.
+// Hooks run in registration order.
+func (b *ObaBackend) RegisterPreModifyHook(hook PreHook) {
+	b.hooksMu.Lock()
+	defer b.hooksMu.Unlock()
+	b.hooks.preModify = append(b.hooks.preModify, hook)
+}
+
+// RegisterPreDeleteHook registers a hook that runs before each delete.
+// Hooks run in registration order.
+func (b *ObaBackend) RegisterPreDeleteHook(hook PreHook) {
+	b.hooksMu.Lock()
+	defer b.hooksMu.Unlock()
+	b.hooks.preDelete = append(b.hooks.preDelete, hook)
+}
+
+// RegisterPostCommitHook registers a hook that runs after each add,
+// modify, delete or modify DN is committed. Hooks run in registration order.
+func (b *ObaBackend) RegisterPostCommitHook(hook PostCommitHook) {
+	b.hooksMu.Lock()
+	defer b.hooksMu.Unlock()
+	b.hooks.postCommit = append(b.hooks.postCommit, hook)
+}
+
+// SetLogger sets the logger used to report post-commit hook failures.
+func (b *ObaBackend) SetLogger(logger logging.Logger) {
+	b.hooksMu.Lock()
+	defer b.hooksMu.Unlock()
+	b.logger = logger
+}
+
+// runPreHooks runs the pre-operation hooks for op and stops at the first
+// error.
+func (b *ObaBackend) runPreHooks(ctx context.Context, op *WriteOp) error {
+	b.hooksMu.RLock()
+	var pre []PreHook
+	switch op.Type {
+	case OpAdd:
+		pre = b.hooks.preAdd
+	case OpModify:
+		pre = b.hooks.preModify
+	case OpDelete:
+		pre = b.hooks.preDelete
+	}
+	b.hooksMu.RUnlock()
+
+	for _, hook := range pre {
+		if err := hook(ctx, op); err != nil {
+			if _, ok := err.(*HookError); ok {
+				return err
+			}
+			return &HookError{
+				ResultCode: ldap.ResultUnwillingToPerform,
+				Message:    err.Error(),
+				Err:        err,
+			}
+		}
+	}
+	return nil
+}
+
+// runPostCommitHooks runs the post-commit hooks for op, recovering and
+// logging errors and panics.
+func (b *ObaBackend) runPostCommitHooks(ctx context.Context, op *WriteOp) {
+	b.hooksMu.RLock()
+	post := b.hooks.postCommit
+	logger := b.logger
+	b.hooksMu.RUnlock()
+
+	for i, hook := range post {
+		if err := callPostCommitHook(ctx, hook, op); err != nil && logger != nil {
+			logger.Error("post-commit hook failed",
+				"hook", i,
+				"operation", string(op.Type),
+				"dn", op.DN,
+				"request_id", op.RequestID,
+				"error", err.Error())
+		}
+	}
+}
+
+// callPostCommitHook calls hook, turning a panic into an error.
+func callPostCommitHook(ctx context.Context, hook PostCommitHook, op *WriteOp) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return hook(ctx, op)
+}
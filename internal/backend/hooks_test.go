@@ -0,0 +1,233 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+// TestPreAddHooks tests that pre-add hooks run in order, see the request
+// and may change the entry.
+func TestPreAddHooks(t *testing.T) {
+	engine := newMockStorageEngine()
+	backend := NewBackend(engine, nil)
+
+	var calls []string
+	backend.RegisterPreAddHook(func(ctx context.Context, op *WriteOp) error {
+		calls = append(calls, "first")
+		if op.Type != OpAdd || op.BindDN != "cn=admin,dc=example,dc=com" || op.RequestID != "req-1" {
+			t.Errorf("unexpected op %+v", op)
+		}
+		op.Entry.SetAttribute("description", "from hook")
+		return nil
+	})
+	backend.RegisterPreAddHook(func(ctx context.Context, op *WriteOp) error {
+		calls = append(calls, "second")
+		if op.Entry.GetFirstAttribute("description") != "from hook" {
+			t.Error("expected second hook to see the first hook's change")
+		}
+		return nil
+	})
+
+	ctx := ContextWithOperation(context.Background(), OperationInfo{
+		BindDN:    "cn=admin,dc=example,dc=com",
+		RequestID: "req-1",
+	})
+	entry := NewEntry("cn=printer,dc=example,dc=com")
+	entry.SetAttribute("objectclass", "device")
+	entry.SetAttribute("cn", "printer")
+	if err := backend.Add(ctx, entry); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	if len(calls) != 2 || calls[0] != "first" || calls[1] != "second" {
+		t.Errorf("expected hooks in registration order, got %v", calls)
+	}
+	stored := engine.entries["cn=printer,dc=example,dc=com"]
+	if stored == nil || string(stored.GetAttribute("description")[0]) != "from hook" {
+		t.Fatal("expected entry to be stored with the hook's change")
+	}
+	if string(stored.GetAttribute("creatorsname")[0]) != "cn=admin,dc=example,dc=com" {
+		t.Errorf("expected creatorsName from the request, got %q", stored.GetAttribute("creatorsname"))
+	}
+}
+
+// TestPreHookVeto tests that a pre-operation hook can reject an operation.
+func TestPreHookVeto(t *testing.T) {
+	engine := newMockStorageEngine()
+	backend := NewBackend(engine, nil)
+
+	backend.RegisterPreAddHook(func(ctx context.Context, op *WriteOp) error {
+		return NewHookError(ldap.ResultConstraintViolation, "printers are not allowed")
+	})
+	var laterCalled bool
+	backend.RegisterPreAddHook(func(ctx context.Context, op *WriteOp) error {
+		laterCalled = true
+		return nil
+	})
+
+	entry := NewEntry("cn=printer,dc=example,dc=com")
+	entry.SetAttribute("cn", "printer")
+	err := backend.Add(context.Background(), entry)
+
+	var hookErr *HookError
+	if !errors.As(err, &hookErr) || hookErr.ResultCode != ldap.ResultConstraintViolation {
+		t.Fatalf("expected constraintViolation hook error, got %v", err)
+	}
+	if laterCalled {
+		t.Error("expected hooks after a veto not to run")
+	}
+	if _, ok := engine.entries["cn=printer,dc=example,dc=com"]; ok {
+		t.Error("expected rejected entry not to be stored")
+	}
+
+	// Plain errors are returned as unwillingToPerform
+	sentinel := errors.New("read only")
+	backend.RegisterPreDeleteHook(func(ctx context.Context, op *WriteOp) error {
+		return sentinel
+	})
+	engine.entries["cn=kept,dc=example,dc=com"] = storage.NewEntry("cn=kept,dc=example,dc=com")
+	err = backend.Delete(context.Background(), "cn=kept,dc=example,dc=com")
+	if !errors.As(err, &hookErr) || hookErr.ResultCode != ldap.ResultUnwillingToPerform || !errors.Is(err, sentinel) {
+		t.Fatalf("expected unwillingToPerform hook error wrapping the hook's error, got %v", err)
+	}
+	if _, ok := engine.entries["cn=kept,dc=example,dc=com"]; !ok {
+		t.Error("expected entry to survive a rejected delete")
+	}
+}
+
+// TestPreModifyHook tests that a pre-modify hook can change the modifications.
+func TestPreModifyHook(t *testing.T) {
+	engine := newMockStorageEngine()
+	backend := NewBackend(engine, nil)
+
+	entry := storage.NewEntry("cn=printer,dc=example,dc=com")
+	entry.SetStringAttribute("cn", "printer")
+	engine.entries[entry.DN] = entry
+
+	backend.RegisterPreModifyHook(func(ctx context.Context, op *WriteOp) error {
+		if op.Entry.GetFirstAttribute("cn") != "printer" {
+			t.Errorf("expected the current entry, got %v", op.Entry.Attributes)
+		}
+		op.Changes = append(op.Changes, Modification{
+			Type:      ModReplace,
+			Attribute: "l",
+			Values:    []string{"Floor 2"},
+		})
+		return nil
+	})
+
+	changes := []Modification{{Type: ModReplace, Attribute: "description", Values: []string{"Color"}}}
+	if err := backend.Modify(context.Background(), entry.DN, changes); err != nil {
+		t.Fatalf("Modify() error = %v", err)
+	}
+
+	modified := engine.entries[entry.DN]
+	if string(modified.GetAttribute("description")[0]) != "Color" || string(modified.GetAttribute("l")[0]) != "Floor 2" {
+		t.Errorf("expected both modifications applied, got %v", modified.Attributes)
+	}
+}
+
+// TestPostCommitHooks tests that post-commit hooks run after the write and
+// that a failing hook does not affect the operation or later hooks.
+func TestPostCommitHooks(t *testing.T) {
+	engine := newMockStorageEngine()
+	backend := NewBackend(engine, nil)
+
+	backend.RegisterPostCommitHook(func(ctx context.Context, op *WriteOp) error {
+		panic("boom")
+	})
+	backend.RegisterPostCommitHook(func(ctx context.Context, op *WriteOp) error {
+		return errors.New("sync failed")
+	})
+	var ops []OperationType
+	backend.RegisterPostCommitHook(func(ctx context.Context, op *WriteOp) error {
+		if _, ok := engine.entries[op.DN]; ok != (op.Type != OpDelete) {
+			t.Errorf("post-commit hook for %s ran before the write", op.Type)
+		}
+		ops = append(ops, op.Type)
+		return nil
+	})
+
+	entry := NewEntry("cn=printer,dc=example,dc=com")
+	entry.SetAttribute("cn", "printer")
+	if err := backend.Add(context.Background(), entry); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := backend.Modify(context.Background(), entry.DN, []Modification{{Type: ModAdd, Attribute: "description", Values: []string{"x"}}}); err != nil {
+		t.Fatalf("Modify() error = %v", err)
+	}
+	if err := backend.Delete(context.Background(), entry.DN); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if len(ops) != 3 || ops[0] != OpAdd || ops[1] != OpModify || ops[2] != OpDelete {
+		t.Errorf("expected post-commit hooks for add, modify and delete, got %v", ops)
+	}
+}
+
+// TestUIDNumberHook tests uidNumber assignment from the cn=uidNext counter.
+func TestUIDNumberHook(t *testing.T) {
+	db, err := engine.Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	cfg := config.DefaultConfig()
+	cfg.Directory.BaseDN = "dc=example,dc=com"
+	be := NewBackend(db, cfg)
+	be.RegisterPreAddHook(UIDNumberHook(UIDNumberCounterRDN+",dc=example,dc=com", 5000))
+
+	add := func(uid, uidNumber string) (*Entry, error) {
+		entry := NewEntry("uid=" + uid + ",ou=users,dc=example,dc=com")
+		entry.SetAttribute("objectClass", "top", "account", "posixAccount")
+		entry.SetAttribute("uid", uid)
+		entry.SetAttribute("cn", uid)
+		entry.SetAttribute("gidNumber", "100")
+		entry.SetAttribute("homeDirectory", "/home/"+uid)
+		if uidNumber != "" {
+			entry.SetAttribute("uidNumber", uidNumber)
+		}
+		return entry, be.Add(context.Background(), entry)
+	}
+
+	alice, err := add("alice", "")
+	if err != nil {
+		t.Fatalf("failed to add alice: %v", err)
+	}
+	if got := alice.GetFirstAttribute("uidNumber"); got != "5000" {
+		t.Errorf("expected uidNumber 5000, got %q", got)
+	}
+
+	// Explicit numbers are kept and do not advance the counter
+	if carol, err := add("carol", "42"); err != nil || carol.GetFirstAttribute("uidNumber") != "42" {
+		t.Fatalf("expected explicit uidNumber to be kept, got %v", err)
+	}
+
+	// A failed add rolls back the counter update
+	if _, err := add("alice", ""); err != ErrEntryExists {
+		t.Fatalf("expected ErrEntryExists, got %v", err)
+	}
+
+	bob, err := add("bob", "")
+	if err != nil {
+		t.Fatalf("failed to add bob: %v", err)
+	}
+	if got := bob.GetFirstAttribute("uidNumber"); got != "5001" {
+		t.Errorf("expected uidNumber 5001, got %q", got)
+	}
+
+	counter, err := be.getEntry(context.Background(), "cn=uidnext,dc=example,dc=com")
+	if err != nil {
+		t.Fatalf("expected counter entry: %v", err)
+	}
+	if got := counter.GetFirstAttribute("uidNumber"); got != "5002" {
+		t.Errorf("expected counter at 5002, got %q", got)
+	}
+}
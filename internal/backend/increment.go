@@ -0,0 +1,78 @@
+package backend
+
+import (
+	"context"
+	"math"
+	"strconv"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/schema"
+)
+
+// Errors for increment modifications.
+var (
+	// ErrNotInteger is returned when an increment targets an attribute that
+	// does not have Integer syntax or does not hold integers.
+	ErrNotInteger = newError(ldap.ResultConstraintViolation, "attribute does not have integer syntax")
+	// ErrIntegerOverflow is returned when an increment overflows a value.
+	ErrIntegerOverflow = newError(ldap.ResultConstraintViolation, "integer overflow")
+	// ErrInvalidIncrement is returned when an increment does not have
+	// exactly one integer value.
+	ErrInvalidIncrement = newError(ldap.ResultConstraintViolation, "increment requires exactly one integer value")
+)
+
+// ModifyIncrement adds delta to the values of the integer attribute attr
+// of the entry dn on behalf of the client in ctx. The values are read
+// and written in one transaction, so concurrent increments are not lost;
+// an increment that conflicts with a concurrent write fails with
+// ErrConflict.
+func (b *ObaBackend) ModifyIncrement(ctx context.Context, dn, attr string, delta int64) error {
+	return b.Modify(ctx, dn, []Modification{
+		{Type: ModIncrement, Attribute: attr, Values: []string{strconv.FormatInt(delta, 10)}},
+	})
+}
+
+// incrementDelta returns the number added by an increment modification
+// with values.
+func incrementDelta(values []string) (int64, error) {
+	if len(values) != 1 {
+		return 0, ErrInvalidIncrement
+	}
+	delta, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil {
+		return 0, ErrInvalidIncrement
+	}
+	return delta, nil
+}
+
+// applyIncrement adds delta to every value of attr in entry.
+//
+// Returns ErrNoSuchAttribute if entry does not have attr, ErrNotInteger if
+// attr does not have Integer syntax in the schema or a value is not an
+// integer, and ErrIntegerOverflow if a result does not fit in 64 bits.
+func (b *ObaBackend) applyIncrement(entry *Entry, attr string, delta int64) error {
+	if b.schema != nil {
+		if at := b.schema.GetAttributeType(attr); at != nil && at.Syntax != schema.SyntaxInteger {
+			return ErrNotInteger
+		}
+	}
+
+	values := entry.GetStringAttribute(attr)
+	if len(values) == 0 {
+		return ErrNoSuchAttribute
+	}
+
+	incremented := make([]string, len(values))
+	for i, value := range values {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return ErrNotInteger
+		}
+		if (delta > 0 && n > math.MaxInt64-delta) || (delta < 0 && n < math.MinInt64-delta) {
+			return ErrIntegerOverflow
+		}
+		incremented[i] = strconv.FormatInt(n+delta, 10)
+	}
+	entry.SetAttribute(attr, incremented...)
+	return nil
+}
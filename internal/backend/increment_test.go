@@ -0,0 +1,172 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/schema"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+const counterDN = "cn=counter,dc=example,dc=com"
+
+// openIncrementBackend opens a backend with the default schema holding
+// dc=example,dc=com and a counter entry whose uidNumber is value.
+func openIncrementBackend(t *testing.T, value string) *ObaBackend {
+	t.Helper()
+
+	db, err := engine.Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	be := NewBackend(db, config.DefaultConfig())
+
+	base := NewEntry("dc=example,dc=com")
+	base.SetAttribute("objectClass", "top", "domain")
+	base.SetAttribute("dc", "example")
+	if err := be.Add(context.Background(), base); err != nil {
+		t.Fatalf("Add(%s) error = %v", base.DN, err)
+	}
+
+	counter := NewEntry(counterDN)
+	counter.SetAttribute("objectClass", "top", "device", "extensibleObject")
+	counter.SetAttribute("cn", "counter")
+	counter.SetAttribute("uidNumber", value)
+	if err := be.Add(context.Background(), counter); err != nil {
+		t.Fatalf("Add(%s) error = %v", counterDN, err)
+	}
+
+	be.SetSchema(schema.LoadDefaultSchema())
+	return be
+}
+
+// counterValue returns the uidNumber of the counter entry.
+func counterValue(t *testing.T, be *ObaBackend) string {
+	t.Helper()
+	entry, err := be.getEntry(context.Background(), counterDN)
+	if err != nil {
+		t.Fatalf("getEntry() error = %v", err)
+	}
+	return entry.GetFirstAttribute("uidNumber")
+}
+
+func TestModifyIncrement(t *testing.T) {
+	be := openIncrementBackend(t, "10")
+
+	if err := be.ModifyIncrement(context.Background(), counterDN, "uidNumber", 5); err != nil {
+		t.Fatalf("ModifyIncrement() error = %v", err)
+	}
+	if got := counterValue(t, be); got != "15" {
+		t.Errorf("uidNumber = %s, want 15", got)
+	}
+
+	if err := be.Modify(context.Background(), counterDN, []Modification{
+		{Type: ModIncrement, Attribute: "uidNumber", Values: []string{"-20"}},
+	}); err != nil {
+		t.Fatalf("Modify() error = %v", err)
+	}
+	if got := counterValue(t, be); got != "-5" {
+		t.Errorf("uidNumber = %s, want -5", got)
+	}
+}
+
+func TestModifyIncrementConcurrent(t *testing.T) {
+	be := openIncrementBackend(t, "0")
+
+	const goroutines = 2
+	const increments = 50
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make(chan error, goroutines)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			for n := 0; n < increments; {
+				err := be.ModifyIncrement(context.Background(), counterDN, "uidNumber", 1)
+				switch {
+				case err == nil:
+					n++
+				case errors.Is(err, ErrConflict):
+					// The other increment committed first; retry
+				default:
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("ModifyIncrement() error = %v", err)
+	}
+	if got, want := counterValue(t, be), strconv.Itoa(goroutines*increments); got != want {
+		t.Errorf("uidNumber = %s, want %s", got, want)
+	}
+}
+
+func TestModifyIncrementOverflow(t *testing.T) {
+	be := openIncrementBackend(t, strconv.FormatInt(math.MaxInt64-1, 10))
+
+	if err := be.ModifyIncrement(context.Background(), counterDN, "uidNumber", 1); err != nil {
+		t.Fatalf("ModifyIncrement() to MaxInt64 error = %v", err)
+	}
+	if err := be.ModifyIncrement(context.Background(), counterDN, "uidNumber", 1); !errors.Is(err, ErrIntegerOverflow) {
+		t.Fatalf("ModifyIncrement() past MaxInt64 error = %v, want ErrIntegerOverflow", err)
+	}
+	if got := counterValue(t, be); got != strconv.FormatInt(math.MaxInt64, 10) {
+		t.Errorf("uidNumber = %s after overflow, want it unchanged", got)
+	}
+
+	if err := be.ModifyIncrement(context.Background(), counterDN, "uidNumber", math.MinInt64); err != nil {
+		t.Fatalf("ModifyIncrement() by MinInt64 error = %v", err)
+	}
+	if err := be.ModifyIncrement(context.Background(), counterDN, "uidNumber", math.MinInt64); !errors.Is(err, ErrIntegerOverflow) {
+		t.Fatalf("ModifyIncrement() past MinInt64 error = %v, want ErrIntegerOverflow", err)
+	}
+}
+
+func TestModifyIncrementErrors(t *testing.T) {
+	be := openIncrementBackend(t, "1")
+
+	tests := []struct {
+		name   string
+		attr   string
+		values []string
+		want   error
+	}{
+		{"non-integer syntax", "cn", []string{"1"}, ErrNotInteger},
+		{"missing attribute", "gidNumber", []string{"1"}, ErrNoSuchAttribute},
+		{"no value", "uidNumber", nil, ErrInvalidIncrement},
+		{"several values", "uidNumber", []string{"1", "2"}, ErrInvalidIncrement},
+		{"non-integer value", "uidNumber", []string{"one"}, ErrInvalidIncrement},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := be.Modify(context.Background(), counterDN, []Modification{
+				{Type: ModIncrement, Attribute: tt.attr, Values: tt.values},
+			})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Modify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if got := counterValue(t, be); got != "1" {
+		t.Errorf("uidNumber = %s after failed increments, want 1", got)
+	}
+}
@@ -4,8 +4,7 @@
 package backend
 
 import (
-	"strings"
-
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
 	"github.com/KilimcininKorOglu/oba/internal/server"
 )
 
@@ -42,7 +41,7 @@ func (b *ObaBackend) ModifyEntry(dn string, changes []server.Modification) error
 
 	// Apply modifications
 	for _, mod := range changes {
-		attrName := strings.ToLower(mod.Attribute)
+		attrName := ldap.AttributeKey(mod.Attribute)
 
 		switch mod.Type {
 		case server.ModifyAdd:
@@ -69,6 +68,20 @@ func (b *ObaBackend) ModifyEntry(dn string, changes []server.Modification) error
 			} else {
 				entry.SetAttribute(attrName, mod.Values...)
 			}
+
+		case server.ModifyIncrement:
+			delta, err := incrementDelta(mod.Values)
+			if err == nil {
+				err = b.applyIncrement(entry, attrName, delta)
+			}
+			if err != nil {
+				b.engine.Rollback(txn)
+				return err
+			}
+		}
+
+		if attrName == PasswordAttribute {
+			b.stampPasswordChange(entry)
 		}
 	}
 
@@ -4,9 +4,12 @@
 package backend
 
 import (
-	"errors"
+	"context"
+	"fmt"
+	"sort"
 	"strings"
 
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
 	"github.com/KilimcininKorOglu/oba/internal/storage"
 	"github.com/KilimcininKorOglu/oba/internal/storage/radix"
 )
@@ -14,11 +17,21 @@ import (
 // ModifyDN errors.
 var (
 	// ErrNewSuperiorNotFound is returned when the new superior DN does not exist.
-	ErrNewSuperiorNotFound = errors.New("backend: new superior not found")
+	ErrNewSuperiorNotFound = newError(ldap.ResultNoSuchObject, "new superior not found")
 	// ErrAffectsMultipleDSAs is returned when the operation would affect multiple DSAs.
-	ErrAffectsMultipleDSAs = errors.New("backend: operation affects multiple DSAs")
+	ErrAffectsMultipleDSAs = newError(ldap.ResultAffectsMultipleDSAs, "operation affects multiple DSAs")
+	// ErrSubtreeTooLarge is returned when a subtree rename exceeds the configured size limit.
+	ErrSubtreeTooLarge = newError(ldap.ResultAdminLimitExceeded, "subtree too large to rename")
 )
 
+// operationalDNAttributes are the DN-valued attributes maintained by the
+// server that are rewritten on every entry of a renamed subtree.
+var operationalDNAttributes = []string{"entrydn", "creatorsname", "modifiersname", "memberof"}
+
+// referenceAttributes are the DN-valued attributes rewritten anywhere in the
+// directory when referential integrity is enabled.
+var referenceAttributes = []string{"member", "uniquemember", "memberof", "owner", "manager", "secretary", "seealso"}
+
 // ModifyDNRequest represents a request to rename or move an entry.
 type ModifyDNRequest struct {
 	// DN is the distinguished name of the entry to rename/move.
@@ -40,7 +53,14 @@ type ModifyDNRequest struct {
 // 5. Updates the entry DN in storage
 // 6. If DeleteOldRDN is true, removes the old RDN attribute values
 // 7. Updates any children entries if moving a subtree
-func (b *ObaBackend) ModifyDN(req *ModifyDNRequest) error {
+// 8. Updates references to the moved entries if referential integrity is enabled
+//
+// An entry with children is moved together with its whole subtree in one
+// transaction. Subtrees larger than the configured limit are rejected with
+// ErrSubtreeTooLarge.
+//
+// The post-commit hooks see the client in ctx.
+func (b *ObaBackend) ModifyDN(ctx context.Context, req *ModifyDNRequest) error {
 	if req == nil {
 		return ErrInvalidEntry
 	}
@@ -56,8 +76,14 @@ func (b *ObaBackend) ModifyDN(req *ModifyDNRequest) error {
 	normalizedDN := normalizeDN(req.DN)
 	normalizedNewRDN := normalizeDN(req.NewRDN)
 
+	release, err := b.lockSchemaEntry(normalizedDN, normalizeDN(req.NewSuperior))
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	// Start a read transaction to validate
-	txn, err := b.engine.Begin()
+	txn, err := b.begin(ctx)
 	if err != nil {
 		return wrapStorageError(err)
 	}
@@ -68,6 +94,10 @@ func (b *ObaBackend) ModifyDN(req *ModifyDNRequest) error {
 		b.engine.Rollback(txn)
 		return ErrEntryNotFound
 	}
+	if err := b.checkAssertion(ctx, convertFromStorageEntry(storageEntry)); err != nil {
+		b.engine.Rollback(txn)
+		return err
+	}
 
 	// Calculate the new DN
 	newDN, err := b.calculateNewDN(normalizedDN, normalizedNewRDN, req.NewSuperior)
@@ -75,6 +105,7 @@ func (b *ObaBackend) ModifyDN(req *ModifyDNRequest) error {
 		b.engine.Rollback(txn)
 		return err
 	}
+	prettyNewDN := newDN
 
 	// Check if new DN already exists (unless it's the same as the old DN)
 	if !strings.EqualFold(normalizedDN, newDN) {
@@ -88,11 +119,17 @@ func (b *ObaBackend) ModifyDN(req *ModifyDNRequest) error {
 	// If NewSuperior is specified, verify it exists
 	if req.NewSuperior != "" {
 		normalizedNewSuperior := normalizeDN(req.NewSuperior)
-		_, err = b.engine.Get(txn, normalizedNewSuperior)
+		superior, err := b.engine.Get(txn, normalizedNewSuperior)
 		if err != nil {
 			b.engine.Rollback(txn)
 			return ErrNewSuperiorNotFound
 		}
+		prettyNewDN = strings.TrimSpace(req.NewRDN) + "," + superior.DN
+	} else {
+		prettyNewDN = strings.TrimSpace(req.NewRDN)
+		if parent := prettyParentDN(storageEntry.DN); parent != "" {
+			prettyNewDN += "," + parent
+		}
 	}
 
 	// Check if entry has children (for subtree move) - not supported in cluster mode yet
@@ -107,51 +144,99 @@ func (b *ObaBackend) ModifyDN(req *ModifyDNRequest) error {
 
 	// Handle old RDN attribute deletion if requested
 	if req.DeleteOldRDN {
-		oldRDN, err := radix.GetRDN(normalizedDN)
-		if err == nil {
-			b.removeRDNAttribute(entry, oldRDN)
+		if rdns, err := ldap.SplitDN(storageEntry.DN); err == nil && len(rdns) > 0 {
+			b.removeRDNAttribute(entry, rdns[0])
 		}
 	}
 
 	// Add new RDN attribute values
-	b.addRDNAttribute(entry, normalizedNewRDN)
+	b.addRDNAttribute(entry, req.NewRDN)
 
-	// Update the entry's DN
-	entry.DN = newDN
+	// Update the entry's DN, keeping the case the client used
+	entry.DN = prettyNewDN
 
 	// Enforce OU placement rules for user/group object classes.
 	if err := validateEntryPlacement(entry); err != nil {
+		b.engine.Rollback(txn)
+		return err
+	}
+
+	// Enforce DIT structure rules against the new parent and RDN.
+	if err := b.validateStructure(txn, entry); err != nil {
+		b.engine.Rollback(txn)
 		return err
 	}
 
 	// Convert back to storage entry
 	modifiedStorageEntry := convertToStorageEntry(entry)
+	rewriteDNValues(modifiedStorageEntry, b.renamedAttributes(), normalizedDN, newDN)
+	dropStoredEntryDN(modifiedStorageEntry)
 
 	// Close read transaction before cluster write
 	b.engine.Rollback(txn)
 
+	op := b.newWriteOp(ctx, OpModifyDN, normalizedDN, nil)
+	op.Entry = entry
+	op.Rename = req
+
 	// If cluster writer is set, route through Raft consensus (atomic operation)
 	if b.clusterWriter != nil {
 		// Note: subtree moves not supported in cluster mode yet
 		if hasChildren {
-			return errors.New("subtree moves not supported in cluster mode")
+			return &LDAPError{
+				ResultCode: ldap.ResultUnwillingToPerform,
+				Message:    "subtree moves not supported in cluster mode",
+			}
 		}
 		if err := b.clusterWriter.ModifyDN(normalizedDN, modifiedStorageEntry); err != nil {
 			return wrapStorageError(err)
 		}
+		b.runPostCommitHooks(ctx, op)
+		// References are updated after the rename, not atomically with it.
+		if b.referentialIntegrity {
+			return b.updateClusterReferences(normalizedDN, newDN)
+		}
 		return nil
 	}
 
 	// Standalone mode: direct write with transaction
-	txn, err = b.engine.Begin()
+	txn, err = b.begin(ctx)
 	if err != nil {
 		return wrapStorageError(err)
 	}
 
+	// Read everything that moves or changes before writing, so the reads
+	// are not affected by the entries being rewritten.
+	var descendants []*storage.Entry
+	if hasChildren {
+		descendants, err = b.collectDescendants(txn, normalizedDN)
+		if err != nil {
+			b.engine.Rollback(txn)
+			return err
+		}
+		if b.maxRenameSubtree > 0 && len(descendants)+1 > b.maxRenameSubtree {
+			b.engine.Rollback(txn)
+			return fmt.Errorf("%w: %d entries, limit is %d", ErrSubtreeTooLarge, len(descendants)+1, b.maxRenameSubtree)
+		}
+	}
+
+	var referrers []*storage.Entry
+	if b.referentialIntegrity {
+		referrers, err = b.collectReferrers(txn, normalizedDN, newDN)
+		if err != nil {
+			b.engine.Rollback(txn)
+			return err
+		}
+	}
+
 	// Delete the old entry
 	if err := b.engine.Delete(txn, normalizedDN); err != nil {
 		b.engine.Rollback(txn)
-		return wrapStorageError(err)
+		return assertedWriteError(ctx, err)
+	}
+	if err := b.recheckAssertion(ctx, normalizedDN); err != nil {
+		b.engine.Rollback(txn)
+		return err
 	}
 
 	// Put with new DN
@@ -161,10 +246,15 @@ func (b *ObaBackend) ModifyDN(req *ModifyDNRequest) error {
 	}
 
 	// If entry has children, update their DNs as well
-	if hasChildren {
-		if err := b.updateChildrenDNs(txn, normalizedDN, newDN); err != nil {
+	if err := b.moveDescendants(txn, descendants, normalizedDN, newDN, prettyNewDN); err != nil {
+		b.engine.Rollback(txn)
+		return err
+	}
+
+	for _, referrer := range referrers {
+		if err := b.engine.Put(txn, referrer); err != nil {
 			b.engine.Rollback(txn)
-			return err
+			return wrapStorageError(err)
 		}
 	}
 
@@ -173,6 +263,7 @@ func (b *ObaBackend) ModifyDN(req *ModifyDNRequest) error {
 		return wrapStorageError(err)
 	}
 
+	b.runPostCommitHooks(ctx, op)
 	return nil
 }
 
@@ -215,35 +306,37 @@ func (b *ObaBackend) hasChildren(txn interface{}, dn string) (bool, error) {
 	return false, iter.Error()
 }
 
-// updateChildrenDNs updates the DNs of all children when moving a subtree.
-func (b *ObaBackend) updateChildrenDNs(txn interface{}, oldParentDN, newParentDN string) error {
-	// Get all descendants using subtree scope
-	iter := b.engine.SearchByDN(txn, oldParentDN, storage.ScopeSubtree)
+// collectDescendants returns copies of all entries below dn, parents first.
+func (b *ObaBackend) collectDescendants(txn interface{}, dn string) ([]*storage.Entry, error) {
+	iter := b.engine.SearchByDN(txn, dn, storage.ScopeSubtree)
 	defer iter.Close()
 
-	var children []*storage.Entry
+	var descendants []*storage.Entry
 	for iter.Next() {
 		entry := iter.Entry()
-		if entry == nil {
-			continue
-		}
-		// Skip the parent entry itself (already handled)
-		if strings.EqualFold(entry.DN, oldParentDN) {
+		if entry == nil || normalizeDN(entry.DN) == dn {
 			continue
 		}
-		children = append(children, entry.Clone())
+		descendants = append(descendants, entry.Clone())
 	}
-
 	if err := iter.Error(); err != nil {
-		return wrapStorageError(err)
+		return nil, wrapStorageError(err)
 	}
 
-	// Update each child's DN
-	for _, child := range children {
-		oldChildDN := child.DN
+	sort.SliceStable(descendants, func(i, j int) bool {
+		return len(descendants[i].DN) < len(descendants[j].DN)
+	})
+	return descendants, nil
+}
 
-		// Calculate new child DN by replacing the old parent prefix with new parent
-		newChildDN := b.replaceParentDN(oldChildDN, oldParentDN, newParentDN)
+// moveDescendants moves the given entries from below oldParentDN to below
+// newParentDN, rewriting their DN-valued attributes along the way. The
+// moved entries keep the case of their own RDNs and take that of
+// prettyParentDN for the rest.
+func (b *ObaBackend) moveDescendants(txn interface{}, descendants []*storage.Entry, oldParentDN, newParentDN, prettyParentDN string) error {
+	attrs := b.renamedAttributes()
+	for _, child := range descendants {
+		oldChildDN := child.DN
 
 		// Delete old entry
 		if err := b.engine.Delete(txn, oldChildDN); err != nil {
@@ -251,7 +344,9 @@ func (b *ObaBackend) updateChildrenDNs(txn interface{}, oldParentDN, newParentDN
 		}
 
 		// Update DN and put new entry
-		child.DN = newChildDN
+		child.DN = b.replaceParentDN(oldChildDN, oldParentDN, prettyParentDN)
+		rewriteDNValues(child, attrs, oldParentDN, newParentDN)
+		dropStoredEntryDN(child)
 		if err := b.engine.Put(txn, child); err != nil {
 			return wrapStorageError(err)
 		}
@@ -260,23 +355,128 @@ func (b *ObaBackend) updateChildrenDNs(txn interface{}, oldParentDN, newParentDN
 	return nil
 }
 
+// collectReferrers returns copies of the entries outside the renamed subtree
+// whose reference attributes point into it, with those values rewritten.
+func (b *ObaBackend) collectReferrers(txn interface{}, oldDN, newDN string) ([]*storage.Entry, error) {
+	iter := b.engine.SearchByDN(txn, "", storage.ScopeSubtree)
+	defer iter.Close()
+
+	var referrers []*storage.Entry
+	for iter.Next() {
+		entry := iter.Entry()
+		if entry == nil {
+			continue
+		}
+		if _, inSubtree := renameDN(entry.DN, oldDN, newDN); inSubtree {
+			continue
+		}
+		if !referencesSubtree(entry, oldDN) {
+			continue
+		}
+		referrer := entry.Clone()
+		rewriteDNValues(referrer, referenceAttributes, oldDN, newDN)
+		referrers = append(referrers, referrer)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, wrapStorageError(err)
+	}
+	return referrers, nil
+}
+
+// updateClusterReferences rewrites references to a renamed entry through the
+// cluster writer.
+func (b *ObaBackend) updateClusterReferences(oldDN, newDN string) error {
+	txn, err := b.engine.Begin()
+	if err != nil {
+		return wrapStorageError(err)
+	}
+	referrers, err := b.collectReferrers(txn, oldDN, newDN)
+	b.engine.Rollback(txn)
+	if err != nil {
+		return err
+	}
+
+	for _, referrer := range referrers {
+		if err := b.clusterWriter.Put(referrer); err != nil {
+			return wrapStorageError(err)
+		}
+	}
+	return nil
+}
+
+// renamedAttributes returns the attributes rewritten on the entries of a
+// renamed subtree.
+func (b *ObaBackend) renamedAttributes() []string {
+	if !b.referentialIntegrity {
+		return operationalDNAttributes
+	}
+	return append(append([]string(nil), operationalDNAttributes...), referenceAttributes...)
+}
+
+// referencesSubtree reports whether any reference attribute of entry points
+// to dn or below it.
+func referencesSubtree(entry *storage.Entry, dn string) bool {
+	for _, attr := range referenceAttributes {
+		for _, v := range entry.GetAttribute(attr) {
+			if _, ok := renameDN(string(v), dn, dn); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rewriteDNValues moves the values of the given attributes that point to
+// oldDN or below it to newDN. It reports whether any value changed.
+func rewriteDNValues(entry *storage.Entry, attrs []string, oldDN, newDN string) bool {
+	changed := false
+	for _, attr := range attrs {
+		for i, v := range entry.GetAttribute(attr) {
+			if renamed, ok := renameDN(string(v), oldDN, newDN); ok {
+				entry.Attributes[attr][i] = []byte(renamed)
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// dropStoredEntryDN removes the entryDN stored by entries written before
+// it was computed, which the rename would leave stale.
+func dropStoredEntryDN(entry *storage.Entry) {
+	delete(entry.Attributes, strings.ToLower(AttrEntryDN))
+}
+
+// renameDN returns dn moved from below oldDN to below newDN. It reports
+// false if dn is neither oldDN nor one of its descendants.
+func renameDN(dn, oldDN, newDN string) (string, bool) {
+	normalized := normalizeDN(dn)
+	if normalized == oldDN {
+		return newDN, true
+	}
+	if strings.HasSuffix(normalized, ","+oldDN) {
+		return normalized[:len(normalized)-len(oldDN)] + newDN, true
+	}
+	return "", false
+}
+
 // replaceParentDN replaces the parent portion of a child DN with a new parent DN.
+// The RDNs of the child below the old parent keep their original form.
 func (b *ObaBackend) replaceParentDN(childDN, oldParentDN, newParentDN string) string {
-	// Get the relative part of the child DN (the part before the old parent)
-	childLower := strings.ToLower(childDN)
-	oldParentLower := strings.ToLower(oldParentDN)
-
-	// Find where the old parent starts in the child DN
-	idx := strings.Index(childLower, oldParentLower)
-	if idx == -1 {
+	childRDNs, err := ldap.SplitDN(childDN)
+	if err != nil {
+		return childDN
+	}
+	parentRDNs, err := ldap.SplitDN(oldParentDN)
+	if err != nil || len(parentRDNs) > len(childRDNs) {
+		return childDN // Should not happen, but return unchanged
+	}
+	if normalizeDN(strings.Join(childRDNs[len(childRDNs)-len(parentRDNs):], ",")) != normalizeDN(oldParentDN) {
 		return childDN // Should not happen, but return unchanged
 	}
 
 	// Get the relative part (everything before the old parent)
-	relativePart := childDN[:idx]
-
-	// Remove trailing comma if present
-	relativePart = strings.TrimSuffix(relativePart, ",")
+	relativePart := strings.Join(childRDNs[:len(childRDNs)-len(parentRDNs)], ",")
 
 	// Construct new DN
 	if relativePart == "" {
@@ -286,6 +486,16 @@ func (b *ObaBackend) replaceParentDN(childDN, oldParentDN, newParentDN string) s
 	return relativePart + "," + newParentDN
 }
 
+// prettyParentDN returns the parent of dn in its original form, or an empty
+// string for a root entry.
+func prettyParentDN(dn string) string {
+	rdns, err := ldap.SplitDN(dn)
+	if err != nil || len(rdns) < 2 {
+		return ""
+	}
+	return strings.Join(rdns[1:], ",")
+}
+
 // removeRDNAttribute removes the attribute values from the old RDN.
 func (b *ObaBackend) removeRDNAttribute(entry *Entry, rdn string) {
 	// Parse the RDN to get attribute type and value
@@ -307,7 +517,7 @@ func (b *ObaBackend) addRDNAttribute(entry *Entry, rdn string) {
 	}
 
 	// Check if the value already exists
-	existingValues := entry.GetAttribute(attrType)
+	existingValues := entry.GetStringAttribute(attrType)
 	for _, v := range existingValues {
 		if strings.EqualFold(v, attrValue) {
 			return // Value already exists
@@ -318,16 +528,14 @@ func (b *ObaBackend) addRDNAttribute(entry *Entry, rdn string) {
 	entry.AddAttributeValue(attrType, attrValue)
 }
 
-// parseRDNComponent parses an RDN component into attribute type and value.
+// parseRDNComponent parses an RDN component into attribute type and unescaped value.
+// For multi-valued RDNs only the first attribute is returned.
 // Example: "uid=alice" -> ("uid", "alice")
 func parseRDNComponent(rdn string) (string, string) {
-	idx := strings.Index(rdn, "=")
-	if idx == -1 {
+	avas, err := ldap.ParseRDN(rdn)
+	if err != nil || len(avas) == 0 {
 		return "", ""
 	}
 
-	attrType := strings.TrimSpace(rdn[:idx])
-	attrValue := strings.TrimSpace(rdn[idx+1:])
-
-	return strings.ToLower(attrType), attrValue
+	return strings.ToLower(avas[0].Type), avas[0].Value
 }
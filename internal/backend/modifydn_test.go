@@ -0,0 +1,228 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// addTestGroup adds ou=groups and a group whose member points into the
+// ou=sales subtree of newSubtreeTestBackend.
+func addTestGroup(t *testing.T, be *ObaBackend) {
+	t.Helper()
+
+	ou := NewEntry("ou=groups,dc=example,dc=com")
+	ou.SetAttribute("objectClass", "top", "organizationalUnit")
+	ou.SetAttribute("ou", "groups")
+	if err := be.Add(context.Background(), ou); err != nil {
+		t.Fatalf("failed to add ou=groups: %v", err)
+	}
+
+	group := NewEntry("cn=team,ou=groups,dc=example,dc=com")
+	group.SetAttribute("objectClass", "top", "groupOfNames")
+	group.SetAttribute("cn", "team")
+	group.SetAttribute("member",
+		"uid=user0003,ou=users,ou=sales,dc=example,dc=com",
+		"uid=admin,ou=users,ou=hr,dc=example,dc=com")
+	if err := be.Add(context.Background(), group); err != nil {
+		t.Fatalf("failed to add group: %v", err)
+	}
+}
+
+func TestModifyDNSubtree(t *testing.T) {
+	be := newSubtreeTestBackend(t, 20)
+	be.SetReferentialIntegrity(true)
+	addTestGroup(t, be)
+
+	err := be.ModifyDN(context.Background(), &ModifyDNRequest{
+		DN:           "ou=sales,dc=example,dc=com",
+		NewRDN:       "ou=marketing",
+		DeleteOldRDN: true,
+	})
+	if err != nil {
+		t.Fatalf("ModifyDN() error = %v", err)
+	}
+
+	entries, err := be.Search(context.Background(), "ou=sales,dc=example,dc=com", 2, nil)
+	if err == nil && len(entries) != 0 {
+		t.Errorf("%d entries left under the old DN", len(entries))
+	}
+
+	entries, err = be.Search(context.Background(), "ou=marketing,dc=example,dc=com", 2, nil)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(entries) != 22 {
+		t.Errorf("%d entries under the new DN, want 22", len(entries))
+	}
+
+	user, err := be.GetEntry("uid=user0003,ou=users,ou=marketing,dc=example,dc=com")
+	if err != nil {
+		t.Fatalf("moved user not found: %v", err)
+	}
+	derived := be.VirtualAttributes(context.Background(), []*Entry{convertFromStorageEntry(user)}, []string{AttrEntryDN})[0]
+	if got := derived.GetAttribute("entrydn"); len(got) != 1 || string(got[0]) != user.DN {
+		t.Errorf("entryDN = %v, want %s", got, user.DN)
+	}
+
+	ou, err := be.GetEntry("ou=marketing,dc=example,dc=com")
+	if err != nil {
+		t.Fatalf("renamed entry not found: %v", err)
+	}
+	if got := ou.GetAttribute("ou"); len(got) != 1 || string(got[0]) != "marketing" {
+		t.Errorf("ou = %v, want [marketing]", got)
+	}
+
+	group, err := be.GetEntry("cn=team,ou=groups,dc=example,dc=com")
+	if err != nil {
+		t.Fatalf("group not found: %v", err)
+	}
+	members := group.GetAttribute("member")
+	if len(members) != 2 ||
+		string(members[0]) != "uid=user0003,ou=users,ou=marketing,dc=example,dc=com" ||
+		string(members[1]) != "uid=admin,ou=users,ou=hr,dc=example,dc=com" {
+		t.Errorf("member = %v", members)
+	}
+}
+
+func TestModifyDNSubtreeWithoutReferentialIntegrity(t *testing.T) {
+	be := newSubtreeTestBackend(t, 5)
+	addTestGroup(t, be)
+
+	err := be.ModifyDN(context.Background(), &ModifyDNRequest{
+		DN:          "ou=users,ou=sales,dc=example,dc=com",
+		NewRDN:      "ou=users",
+		NewSuperior: "ou=hr,dc=example,dc=com",
+	})
+	if err != nil {
+		t.Fatalf("ModifyDN() error = %v", err)
+	}
+
+	if _, err := be.GetEntry("uid=user0004,ou=users,ou=hr,dc=example,dc=com"); err != nil {
+		t.Errorf("moved user not found: %v", err)
+	}
+
+	group, err := be.GetEntry("cn=team,ou=groups,dc=example,dc=com")
+	if err != nil {
+		t.Fatalf("group not found: %v", err)
+	}
+	if members := group.GetAttribute("member"); string(members[0]) != "uid=user0003,ou=users,ou=sales,dc=example,dc=com" {
+		t.Errorf("member rewritten without referential integrity: %v", members)
+	}
+}
+
+func TestModifyDNSubtreeTooLarge(t *testing.T) {
+	be := newSubtreeTestBackend(t, 10)
+	be.SetMaxRenameSubtree(5)
+
+	err := be.ModifyDN(context.Background(), &ModifyDNRequest{
+		DN:     "ou=sales,dc=example,dc=com",
+		NewRDN: "ou=marketing",
+	})
+	if !errors.Is(err, ErrSubtreeTooLarge) {
+		t.Fatalf("ModifyDN() error = %v, want ErrSubtreeTooLarge", err)
+	}
+
+	entries, err := be.Search(context.Background(), "ou=sales,dc=example,dc=com", 2, nil)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(entries) != 12 {
+		t.Errorf("%d entries left in subtree, want 12", len(entries))
+	}
+
+	// A leaf is still renamed under the same limit.
+	err = be.ModifyDN(context.Background(), &ModifyDNRequest{
+		DN:     "ou=hr,dc=example,dc=com",
+		NewRDN: "ou=people",
+	})
+	if err != nil {
+		t.Errorf("leaf ModifyDN() error = %v", err)
+	}
+}
+
+func TestModifyDNPrettyDN(t *testing.T) {
+	be := newSubtreeTestBackend(t, 2)
+
+	entry := NewEntry("OU=Sales Team,DC=Example,DC=Com")
+	entry.SetAttribute("objectClass", "top", "organizationalUnit")
+	entry.SetAttribute("ou", "Sales Team")
+	if err := be.Add(context.Background(), entry); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	child := NewEntry("CN=Printer,OU=Sales Team,DC=Example,DC=Com")
+	child.SetAttribute("objectClass", "top", "device")
+	child.SetAttribute("cn", "Printer")
+	if err := be.Add(context.Background(), child); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	entries, err := be.Search(context.Background(), "ou=sales team,dc=example,dc=com", 0, nil)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Search() = %v, %v", entries, err)
+	}
+	if entries[0].DN != "OU=Sales Team,DC=Example,DC=Com" {
+		t.Errorf("Search() DN = %q, want the DN as added", entries[0].DN)
+	}
+
+	err = be.ModifyDN(context.Background(), &ModifyDNRequest{
+		DN:           "ou=sales team,dc=example,dc=com",
+		NewRDN:       "OU=Field Sales",
+		DeleteOldRDN: true,
+	})
+	if err != nil {
+		t.Fatalf("ModifyDN() error = %v", err)
+	}
+
+	renamed, err := be.GetEntry("ou=field sales,dc=example,dc=com")
+	if err != nil {
+		t.Fatalf("renamed entry not found: %v", err)
+	}
+	if renamed.DN != "OU=Field Sales,DC=Example,DC=Com" {
+		t.Errorf("renamed DN = %q", renamed.DN)
+	}
+	if got := renamed.GetAttribute("ou"); len(got) != 1 || string(got[0]) != "Field Sales" {
+		t.Errorf("ou = %v, want [Field Sales]", got)
+	}
+
+	moved, err := be.GetEntry("cn=printer,ou=field sales,dc=example,dc=com")
+	if err != nil {
+		t.Fatalf("moved entry not found: %v", err)
+	}
+	if want := "CN=Printer,OU=Field Sales,DC=Example,DC=Com"; moved.DN != want {
+		t.Errorf("moved DN = %q, want %q", moved.DN, want)
+	}
+	derived := be.VirtualAttributes(context.Background(), []*Entry{convertFromStorageEntry(moved)}, []string{AttrEntryDN})[0]
+	if got := derived.GetAttribute(AttrEntryDN); len(got) != 1 || string(got[0]) != moved.DN {
+		t.Errorf("entryDN = %q, want %q", got, moved.DN)
+	}
+}
+
+func TestMultiValuedRDN(t *testing.T) {
+	be := newSubtreeTestBackend(t, 0)
+
+	entry := NewEntry("uid=a+cn=b,ou=users,ou=sales,dc=example,dc=com")
+	entry.SetAttribute("objectClass", "top", "person", "inetOrgPerson")
+	entry.SetAttribute("uid", "a")
+	entry.SetAttribute("cn", "b")
+	entry.SetAttribute("sn", "b")
+	if err := be.Add(context.Background(), entry); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	got, err := be.GetEntry("cn=b+uid=a,ou=users,ou=sales,dc=example,dc=com")
+	if err != nil {
+		t.Fatalf("GetEntry() error = %v", err)
+	}
+	if got.DN != entry.DN {
+		t.Errorf("GetEntry() DN = %q, want %q", got.DN, entry.DN)
+	}
+
+	dup := NewEntry("CN=B+UID=A,ou=users,ou=sales,dc=example,dc=com")
+	dup.SetAttribute("objectClass", "top", "person")
+	dup.SetAttribute("cn", "B")
+	dup.SetAttribute("sn", "B")
+	if err := be.Add(context.Background(), dup); !errors.Is(err, ErrEntryExists) {
+		t.Errorf("Add() of reordered RDN error = %v, want ErrEntryExists", err)
+	}
+}
@@ -0,0 +1,141 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/schema"
+)
+
+// ErrObjectClassModsProhibited is returned when a modify changes the
+// structural object class of an entry.
+var ErrObjectClassModsProhibited = newError(ldap.ResultObjectClassModsProhibited, "object class modification prohibited")
+
+// SetObjectClassCascade controls what happens when a modify removes an
+// object class that defines attributes the entry still holds. When true the
+// attributes no remaining class allows are removed with it; when false the
+// modify fails with objectClassViolation.
+func (b *ObaBackend) SetObjectClassCascade(enabled bool) {
+	b.objectClassCascade = enabled
+}
+
+// validateObjectClassChange checks the objectClass values of entry against
+// those of old, the entry before the modify. The structural object class
+// may not change; auxiliary classes may be added and removed as long as the
+// result passes schema validation. With cascading enabled the attributes
+// only the removed classes allowed are dropped from entry.
+func (b *ObaBackend) validateObjectClassChange(old, entry *Entry) error {
+	if b.schema == nil {
+		return nil
+	}
+
+	oldClasses := old.GetStringAttribute("objectclass")
+	newClasses := entry.GetStringAttribute("objectclass")
+
+	oldStructural, _ := structuralClass(b.schema, oldClasses)
+	if oldStructural != "" {
+		newStructural, ok := structuralClass(b.schema, newClasses)
+		if !ok || !strings.EqualFold(newStructural, oldStructural) {
+			return fmt.Errorf("%w: structural object class %s cannot be changed", ErrObjectClassModsProhibited, oldStructural)
+		}
+	}
+
+	if b.objectClassCascade {
+		b.dropRemovedClassAttributes(entry, removedClasses(oldClasses, newClasses), newClasses)
+	}
+	return nil
+}
+
+// structuralClass returns the structural object class of an entry with
+// the given objectClass values: the most specific of its structural
+// classes, which all others are superiors of. It returns "" if none of the
+// classes is a structural class of s, and false if the structural classes
+// do not form one superclass chain.
+func structuralClass(s *schema.Schema, classes []string) (string, bool) {
+	var structural []*schema.ObjectClass
+	for _, name := range classes {
+		if oc := s.GetObjectClass(name); oc != nil && oc.IsStructural() {
+			structural = append(structural, oc)
+		}
+	}
+
+	for _, oc := range structural {
+		chain := make(map[string]bool)
+		for _, name := range s.ObjectClassChain(oc.Name) {
+			chain[strings.ToLower(name)] = true
+		}
+		covers := true
+		for _, other := range structural {
+			if !chain[strings.ToLower(other.Name)] {
+				covers = false
+				break
+			}
+		}
+		if covers {
+			return oc.Name, true
+		}
+	}
+	return "", len(structural) == 0
+}
+
+// removedClasses returns the values of old that are not in current.
+func removedClasses(old, current []string) []string {
+	kept := make(map[string]bool, len(current))
+	for _, name := range current {
+		kept[strings.ToLower(name)] = true
+	}
+	var removed []string
+	for _, name := range old {
+		if !kept[strings.ToLower(name)] {
+			removed = append(removed, name)
+		}
+	}
+	return removed
+}
+
+// dropRemovedClassAttributes removes the attributes of entry that one of
+// removed allows and none of remaining does. Nothing is dropped if a
+// remaining class is unknown or extensibleObject, since the attributes
+// those allow cannot be listed.
+func (b *ObaBackend) dropRemovedClassAttributes(entry *Entry, removed, remaining []string) {
+	if len(removed) == 0 {
+		return
+	}
+
+	allowed := make(map[string]bool)
+	for _, name := range remaining {
+		oc := b.schema.GetObjectClass(name)
+		if oc == nil || strings.EqualFold(oc.Name, "extensibleObject") {
+			return
+		}
+		addClassAttributes(b.schema, name, allowed)
+	}
+
+	defined := make(map[string]bool)
+	for _, name := range removed {
+		addClassAttributes(b.schema, name, defined)
+	}
+
+	for attr := range entry.Attributes {
+		base := strings.ToLower(schema.AttributeTypeName(attr))
+		if base == "objectclass" || !defined[base] || allowed[base] {
+			continue
+		}
+		if at := b.schema.GetAttributeType(base); at != nil && at.IsOperational() {
+			continue
+		}
+		delete(entry.Attributes, attr)
+	}
+}
+
+// addClassAttributes adds the MUST and MAY attributes of the object class
+// name, including inherited ones, to attrs in lower case.
+func addClassAttributes(s *schema.Schema, name string, attrs map[string]bool) {
+	for _, attr := range s.GetAllMustAttributes(name) {
+		attrs[strings.ToLower(attr)] = true
+	}
+	for _, attr := range s.GetAllMayAttributes(name) {
+		attrs[strings.ToLower(attr)] = true
+	}
+}
@@ -0,0 +1,150 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/schema"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+const hostDN = "cn=host,ou=users,dc=example,dc=com"
+
+// openObjectClassBackend opens a backend with the default schema holding
+// dc=example,dc=com, ou=users and a device entry that is also a POSIX account.
+func openObjectClassBackend(t *testing.T) *ObaBackend {
+	t.Helper()
+
+	db, err := engine.Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	be := NewBackend(db, config.DefaultConfig())
+	be.SetSchema(schema.LoadDefaultSchema())
+
+	base := NewEntry("dc=example,dc=com")
+	base.SetAttribute("objectClass", "top", "domain")
+	base.SetAttribute("dc", "example")
+	users := NewEntry("ou=users,dc=example,dc=com")
+	users.SetAttribute("objectClass", "top", "organizationalUnit")
+	users.SetAttribute("ou", "users")
+	host := NewEntry(hostDN)
+	host.SetAttribute("objectClass", "top", "device", "posixAccount")
+	host.SetAttribute("cn", "host")
+	host.SetAttribute("uid", "host")
+	host.SetAttribute("uidNumber", "1000")
+	host.SetAttribute("gidNumber", "1000")
+	host.SetAttribute("homeDirectory", "/home/host")
+	host.SetAttribute("loginShell", "/bin/sh")
+	for _, entry := range []*Entry{base, users, host} {
+		if err := be.Add(context.Background(), entry); err != nil {
+			t.Fatalf("Add(%s) error = %v", entry.DN, err)
+		}
+	}
+	return be
+}
+
+func TestModifyStructuralObjectClass(t *testing.T) {
+	be := openObjectClassBackend(t)
+
+	tests := []struct {
+		name string
+		mod  Modification
+	}{
+		{"replace", Modification{Type: ModReplace, Attribute: "objectClass", Values: []string{"top", "applicationProcess", "posixAccount"}}},
+		{"add unrelated", Modification{Type: ModAdd, Attribute: "objectClass", Values: []string{"applicationProcess"}}},
+		{"remove", Modification{Type: ModDelete, Attribute: "objectClass", Values: []string{"device"}}},
+	}
+	for _, tt := range tests {
+		err := be.Modify(context.Background(), hostDN, []Modification{tt.mod})
+		if !errors.Is(err, ErrObjectClassModsProhibited) {
+			t.Errorf("%s: Modify() error = %v, want objectClassModsProhibited", tt.name, err)
+		}
+	}
+
+	// Superior classes of the structural class do not change it
+	if err := be.Modify(context.Background(), hostDN, []Modification{
+		{Type: ModDelete, Attribute: "objectClass", Values: []string{"top"}},
+	}); err != nil {
+		t.Errorf("Modify() removing top error = %v", err)
+	}
+}
+
+func TestModifyAuxiliaryObjectClass(t *testing.T) {
+	be := openObjectClassBackend(t)
+	ctx := context.Background()
+
+	// shadowAccount needs uid, which the entry has
+	if err := be.Modify(ctx, hostDN, []Modification{
+		{Type: ModAdd, Attribute: "objectClass", Values: []string{"shadowAccount"}},
+		{Type: ModAdd, Attribute: "shadowMax", Values: []string{"90"}},
+	}); err != nil {
+		t.Fatalf("Modify() adding shadowAccount error = %v", err)
+	}
+
+	// ipHost needs ipHostNumber
+	err := be.Modify(ctx, hostDN, []Modification{
+		{Type: ModAdd, Attribute: "objectClass", Values: []string{"ipHost"}},
+	})
+	if !errors.Is(err, ErrObjectClassViolation) {
+		t.Errorf("Modify() adding ipHost error = %v, want objectClassViolation", err)
+	}
+	if err := be.Modify(ctx, hostDN, []Modification{
+		{Type: ModAdd, Attribute: "objectClass", Values: []string{"ipHost"}},
+		{Type: ModAdd, Attribute: "ipHostNumber", Values: []string{"192.0.2.1"}},
+	}); err != nil {
+		t.Errorf("Modify() adding ipHost with ipHostNumber error = %v", err)
+	}
+
+	// The entry still holds shadowMax
+	err = be.Modify(ctx, hostDN, []Modification{
+		{Type: ModDelete, Attribute: "objectClass", Values: []string{"shadowAccount"}},
+	})
+	if !errors.Is(err, ErrObjectClassViolation) {
+		t.Errorf("Modify() removing shadowAccount error = %v, want objectClassViolation", err)
+	}
+	if err := be.Modify(ctx, hostDN, []Modification{
+		{Type: ModDelete, Attribute: "objectClass", Values: []string{"shadowAccount"}},
+		{Type: ModDelete, Attribute: "shadowMax"},
+	}); err != nil {
+		t.Errorf("Modify() removing shadowAccount and shadowMax error = %v", err)
+	}
+}
+
+func TestModifyObjectClassCascade(t *testing.T) {
+	be := openObjectClassBackend(t)
+	be.SetObjectClassCascade(true)
+
+	if err := be.Modify(context.Background(), hostDN, []Modification{
+		{Type: ModDelete, Attribute: "objectClass", Values: []string{"posixAccount"}},
+	}); err != nil {
+		t.Fatalf("Modify() error = %v", err)
+	}
+
+	entry, err := be.getEntry(context.Background(), hostDN)
+	if err != nil {
+		t.Fatalf("getEntry() error = %v", err)
+	}
+	for _, attr := range []string{"uid", "uidnumber", "gidnumber", "homedirectory", "loginshell"} {
+		if entry.HasAttribute(attr) {
+			t.Errorf("%s was not removed with posixAccount", attr)
+		}
+	}
+	// device allows cn as well
+	if entry.GetFirstAttribute("cn") != "host" {
+		t.Error("cn was removed with posixAccount")
+	}
+
+	// Changing the structural class is still prohibited
+	err = be.Modify(context.Background(), hostDN, []Modification{
+		{Type: ModReplace, Attribute: "objectClass", Values: []string{"top", "applicationProcess"}},
+	})
+	if !errors.Is(err, ErrObjectClassModsProhibited) {
+		t.Errorf("Modify() error = %v, want objectClassModsProhibited", err)
+	}
+}
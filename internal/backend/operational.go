@@ -18,10 +18,14 @@ const (
 	AttrCreatorsName = "creatorsName"
 	// AttrModifiersName is the DN of the last modifier.
 	AttrModifiersName = "modifiersName"
-	// AttrEntryDN is the DN of the entry itself.
+	// AttrEntryDN is the DN of the entry itself. It is a virtual
+	// attribute, computed when read.
 	AttrEntryDN = "entryDN"
 	// AttrEntryUUID is the unique identifier of the entry (RFC 4530).
 	AttrEntryUUID = "entryUUID"
+	// AttrEntryCSN is the change sequence number of the last change to the
+	// entry. It changes on every add and modify and serves as its version.
+	AttrEntryCSN = "entryCSN"
 	// AttrSubschemaSubentry is the DN of the applicable schema.
 	AttrSubschemaSubentry = "subschemaSubentry"
 	// AttrHasSubordinates indicates whether the entry has children.
@@ -57,12 +61,16 @@ const (
 	OpAdd OperationType = "add"
 	// OpModify represents a modify operation.
 	OpModify OperationType = "modify"
+	// OpDelete represents a delete operation.
+	OpDelete OperationType = "delete"
+	// OpModifyDN represents a modify DN operation.
+	OpModifyDN OperationType = "moddn"
 )
 
 // SetOperationalAttrs sets operational attributes on an entry based on the operation type.
 // For add operations, it sets createTimestamp, creatorsName, and entryUUID.
-// For both add and modify operations, it sets modifyTimestamp and modifiersName.
-// The entryDN is always set to the entry's DN.
+// For both add and modify operations, it sets modifyTimestamp, modifiersName
+// and entryCSN. The entryDN is not stored; it is a virtual attribute.
 func SetOperationalAttrs(entry *Entry, op OperationType, bindDN string) {
 	if entry == nil {
 		return
@@ -82,10 +90,8 @@ func SetOperationalAttrs(entry *Entry, op OperationType, bindDN string) {
 		// Set modification attributes
 		entry.SetAttribute(AttrModifyTimestamp, FormatTimestamp(now))
 		entry.SetAttribute(AttrModifiersName, bindDN)
+		entry.SetAttribute(AttrEntryCSN, GenerateCSN())
 	}
-
-	// Always set entryDN
-	entry.SetAttribute(AttrEntryDN, entry.DN)
 }
 
 // SetSubordinateAttrs sets the hasSubordinates and numSubordinates attributes on an entry.
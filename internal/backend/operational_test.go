@@ -180,10 +180,9 @@ func TestSetOperationalAttrsAdd(t *testing.T) {
 		t.Error("entryUUID should be set")
 	}
 
-	// Check entryDN is set
-	entryDN := entry.GetFirstAttribute(AttrEntryDN)
-	if entryDN != entry.DN {
-		t.Errorf("entryDN = %s, expected %s", entryDN, entry.DN)
+	// entryDN is virtual
+	if entry.HasAttribute(AttrEntryDN) {
+		t.Error("entryDN should not be set")
 	}
 }
 
@@ -407,10 +406,9 @@ func TestAddWithBindDNSetsOperationalAttrs(t *testing.T) {
 		t.Error("entryUUID should be set")
 	}
 
-	// Check entryDN is set
-	entryDN := storedEntry.GetAttribute("entrydn")
-	if len(entryDN) == 0 || string(entryDN[0]) != "uid=test,ou=users,dc=example,dc=com" {
-		t.Errorf("entryDN = %v, expected uid=test,ou=users,dc=example,dc=com", entryDN)
+	// entryDN is virtual and not stored
+	if storedEntry.HasAttribute("entrydn") {
+		t.Errorf("entryDN = %v, should not be stored", storedEntry.GetAttribute("entrydn"))
 	}
 }
 
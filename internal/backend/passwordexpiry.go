@@ -0,0 +1,99 @@
+package backend
+
+import (
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/password"
+	"github.com/KilimcininKorOglu/oba/internal/storage/stream"
+)
+
+// Password policy operational attributes (draft-behera-ldap-password-policy).
+const (
+	// AttrPwdChangedTime is the time the password of an entry was last changed.
+	AttrPwdChangedTime = "pwdChangedTime"
+	// AttrPwdGraceUseTime holds the time of each grace login since the
+	// password of an entry expired.
+	AttrPwdGraceUseTime = "pwdGraceUseTime"
+)
+
+// checkPasswordExpiry is called after the password of entry was verified.
+// If the password expired under the password policy, the bind uses a grace
+// login, recorded as a pwdGraceUseTime value of the entry, and fails with
+// ErrInvalidCredentials once all grace logins have been used.
+func (b *ObaBackend) checkPasswordExpiry(entry *Entry) error {
+	policy := b.GetPasswordPolicy()
+	if policy == nil || !policy.Enabled || policy.MaxAge <= 0 {
+		return nil
+	}
+
+	changed := ParseTimestamp(entry.GetFirstAttribute(AttrPwdChangedTime))
+	if changed.IsZero() || !policy.IsExpired(changed) {
+		return nil
+	}
+
+	// The grace logins used before a restart are kept on the entry
+	if _, ok := b.passwordStatus.GetStatus(entry.DN); !ok {
+		b.passwordStatus.SetStatus(entry.DN, password.Status{
+			GraceLoginsUsed: len(entry.GetAttribute(AttrPwdGraceUseTime)),
+		})
+	}
+	if _, err := b.passwordStatus.IncrGraceLogins(entry.DN); err != nil {
+		return ErrInvalidCredentials
+	}
+
+	entry.AddAttributeValue(AttrPwdGraceUseTime, FormatTimestamp(time.Now()))
+	return b.putEntry(entry)
+}
+
+// stampPasswordChange records a password change of entry when the password
+// policy is enabled: it sets pwdChangedTime and clears the grace logins of
+// the previous password.
+func (b *ObaBackend) stampPasswordChange(entry *Entry) {
+	if policy := b.GetPasswordPolicy(); policy == nil || !policy.Enabled {
+		return
+	}
+
+	entry.SetAttribute(AttrPwdChangedTime, FormatTimestamp(time.Now()))
+	entry.DeleteAttribute(AttrPwdGraceUseTime)
+	b.passwordStatus.ResetGraceLogins(entry.DN)
+}
+
+// changesPassword reports whether changes modify the userPassword attribute.
+func changesPassword(changes []Modification) bool {
+	for _, mod := range changes {
+		if ldap.AttributeKey(mod.Attribute) == PasswordAttribute {
+			return true
+		}
+	}
+	return false
+}
+
+// putEntry stores entry outside of a write operation, for updates of
+// operational attributes that bypass validation and hooks.
+func (b *ObaBackend) putEntry(entry *Entry) error {
+	storageEntry := convertToStorageEntry(entry)
+
+	if b.clusterWriter != nil {
+		if err := b.clusterWriter.Put(storageEntry); err != nil {
+			return wrapStorageError(err)
+		}
+		b.emitChange(stream.OpUpdate, entry.DN, storageEntry)
+		return nil
+	}
+
+	txn, err := b.engine.Begin()
+	if err != nil {
+		return wrapStorageError(err)
+	}
+	if err := b.engine.Put(txn, storageEntry); err != nil {
+		b.engine.Rollback(txn)
+		return wrapStorageError(err)
+	}
+	if err := b.engine.Commit(txn); err != nil {
+		return wrapStorageError(err)
+	}
+
+	b.emitChange(stream.OpUpdate, entry.DN, storageEntry)
+	return nil
+}
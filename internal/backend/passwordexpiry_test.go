@@ -0,0 +1,116 @@
+package backend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/password"
+	"github.com/KilimcininKorOglu/oba/internal/server"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// newExpiredPasswordBackend creates a backend whose policy allows
+// graceLogins binds with an expired password, and a user whose password
+// expired a day ago.
+func newExpiredPasswordBackend(t *testing.T, graceLogins int) (*ObaBackend, *mockStorageEngine, string) {
+	t.Helper()
+
+	engine := newMockStorageEngine()
+	b := NewBackend(engine, nil)
+	b.SetPasswordPolicy(&password.Policy{
+		Enabled:     true,
+		MaxAge:      24 * time.Hour,
+		GraceLogins: graceLogins,
+	})
+
+	dn := "uid=alice,ou=users,dc=example,dc=com"
+	hashedPassword, _ := server.HashPassword("secret", server.SchemeSHA256)
+	entry := storage.NewEntry(dn)
+	entry.SetStringAttribute("objectclass", "person", "inetOrgPerson")
+	entry.SetStringAttribute("uid", "alice")
+	entry.SetStringAttribute("userpassword", hashedPassword)
+	entry.SetStringAttribute("pwdchangedtime", FormatTimestamp(time.Now().Add(-48*time.Hour)))
+	engine.entries[dn] = entry
+
+	return b, engine, dn
+}
+
+func TestBindExpiredPasswordGraceLogins(t *testing.T) {
+	b, engine, dn := newExpiredPasswordBackend(t, 2)
+
+	for i := 1; i <= 2; i++ {
+		if err := b.Bind(context.Background(), dn, "secret"); err != nil {
+			t.Fatalf("grace login %d failed: %v", i, err)
+		}
+		if got := len(engine.entries[dn].GetAttribute("pwdgraceusetime")); got != i {
+			t.Errorf("after grace login %d: %d pwdGraceUseTime values, want %d", i, got, i)
+		}
+	}
+
+	if err := b.Bind(context.Background(), dn, "secret"); err != ErrInvalidCredentials {
+		t.Fatalf("bind after grace logins exhausted: err = %v, want ErrInvalidCredentials", err)
+	}
+	if got := len(engine.entries[dn].GetAttribute("pwdgraceusetime")); got != 2 {
+		t.Errorf("exhausted bind recorded a grace login: %d values", got)
+	}
+
+	// A wrong password is rejected before grace logins are considered
+	if err := b.Bind(context.Background(), dn, "wrong"); err != ErrInvalidCredentials {
+		t.Errorf("wrong password: err = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestBindExpiredPasswordGraceLoginsFromEntry(t *testing.T) {
+	b, engine, dn := newExpiredPasswordBackend(t, 2)
+	engine.entries[dn].SetStringAttribute("pwdgraceusetime", FormatTimestamp(time.Now()))
+
+	if err := b.Bind(context.Background(), dn, "secret"); err != nil {
+		t.Fatalf("remaining grace login failed: %v", err)
+	}
+	if err := b.Bind(context.Background(), dn, "secret"); err != ErrInvalidCredentials {
+		t.Fatalf("err = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestBindExpiredPasswordNoGraceLogins(t *testing.T) {
+	b, _, dn := newExpiredPasswordBackend(t, 0)
+
+	if err := b.Bind(context.Background(), dn, "secret"); err != ErrInvalidCredentials {
+		t.Errorf("err = %v, want ErrInvalidCredentials", err)
+	}
+
+	// Without a maximum age passwords do not expire
+	b.SetPasswordPolicy(&password.Policy{Enabled: true})
+	if err := b.Bind(context.Background(), dn, "secret"); err != nil {
+		t.Errorf("bind without maxAge failed: %v", err)
+	}
+}
+
+func TestPasswordChangeResetsGraceLogins(t *testing.T) {
+	b, engine, dn := newExpiredPasswordBackend(t, 1)
+
+	if err := b.Bind(context.Background(), dn, "secret"); err != nil {
+		t.Fatalf("grace login failed: %v", err)
+	}
+	if err := b.Bind(context.Background(), dn, "secret"); err != ErrInvalidCredentials {
+		t.Fatalf("err = %v, want ErrInvalidCredentials", err)
+	}
+
+	hashedPassword, _ := server.HashPassword("newsecret", server.SchemeSHA256)
+	if err := b.Modify(context.Background(), dn, []Modification{*NewModification(ModReplace, "userPassword", hashedPassword)}); err != nil {
+		t.Fatalf("Modify failed: %v", err)
+	}
+
+	stored := engine.entries[dn]
+	if len(stored.GetAttribute("pwdgraceusetime")) != 0 {
+		t.Error("pwdGraceUseTime should be cleared by a password change")
+	}
+	changed := ParseTimestamp(string(stored.GetAttribute("pwdchangedtime")[0]))
+	if time.Since(changed) > time.Minute {
+		t.Errorf("pwdChangedTime = %v, want now", changed)
+	}
+	if err := b.Bind(context.Background(), dn, "newsecret"); err != nil {
+		t.Errorf("bind with new password failed: %v", err)
+	}
+}
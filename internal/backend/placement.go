@@ -47,10 +47,10 @@ func validateEntryPlacement(entry *Entry) error {
 		}
 	}
 
-	if hasUserClass && !dnUnderOU(dn, "users") {
+	if hasUserClass && !isUnderOU(dn, "users") {
 		return fmt.Errorf("%w: user entries must be under ou=users", ErrInvalidPlacement)
 	}
-	if hasGroupClass && !dnUnderOU(dn, "groups") {
+	if hasGroupClass && !isUnderOU(dn, "groups") {
 		return fmt.Errorf("%w: group entries must be under ou=groups", ErrInvalidPlacement)
 	}
 
@@ -63,19 +63,12 @@ func getObjectClasses(entry *Entry) []string {
 	}
 	for name, values := range entry.Attributes {
 		if strings.EqualFold(name, "objectclass") {
-			return values
+			classes := make([]string, len(values))
+			for i, v := range values {
+				classes[i] = string(v)
+			}
+			return classes
 		}
 	}
 	return nil
 }
-
-func dnUnderOU(dn, ouName string) bool {
-	needle := "ou=" + strings.ToLower(strings.TrimSpace(ouName))
-	if dn == needle {
-		return true
-	}
-	if strings.Contains(dn, ","+needle+",") {
-		return true
-	}
-	return strings.HasSuffix(dn, ","+needle)
-}
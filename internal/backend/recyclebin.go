@@ -0,0 +1,402 @@
+// Package backend provides the LDAP backend interface that wraps the storage engine
+// and provides LDAP-specific operations including authentication, entry validation,
+// and coordination with the storage layer.
+package backend
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/radix"
+	"github.com/KilimcininKorOglu/oba/internal/storage/stream"
+)
+
+// Recycle bin errors.
+var (
+	// ErrRecycleBinDisabled is returned when the recycle bin is used while it is disabled.
+	ErrRecycleBinDisabled = newError(ldap.ResultUnwillingToPerform, "recycle bin is disabled")
+	// ErrDeletedEntryNotFound is returned when no deleted entry has the given ID.
+	ErrDeletedEntryNotFound = newError(ldap.ResultNoSuchObject, "deleted entry not found")
+	// ErrRestoreNoParent is returned when the parent of a deleted entry no longer exists.
+	ErrRestoreNoParent = newError(ldap.ResultNoSuchObject, "parent of deleted entry does not exist")
+)
+
+// Recycle bin names.
+const (
+	// RecycleBinRDN is the RDN of the recycle bin container below the base DN.
+	RecycleBinRDN = "cn=deleted objects"
+	// AttrOriginalDN holds the DN a deleted entry had before it was deleted.
+	AttrOriginalDN = "obaOriginalDN"
+	// AttrDeleteTimestamp is the time an entry was deleted.
+	AttrDeleteTimestamp = "obaDeleteTimestamp"
+)
+
+// RecycleBinConfig configures the recycle bin.
+type RecycleBinConfig struct {
+	// BaseDN is the entry the recycle bin container is created under.
+	// An empty BaseDN puts the container at the root.
+	BaseDN string
+	// Retention is how long deleted entries are kept before being purged.
+	Retention time.Duration
+	// PurgeInterval is how often expired entries are purged.
+	// Zero disables the background purge.
+	PurgeInterval time.Duration
+}
+
+// recycleBin is the state of an enabled recycle bin.
+type recycleBin struct {
+	dn        string
+	retention time.Duration
+	stopCh    chan struct{}
+	doneCh    chan struct{}
+}
+
+// EnableRecycleBin makes deletes move entries to the recycle bin instead of
+// erasing them. Deleted entries are kept as tombstones named by their
+// entryUUID below cn=deleted objects, with their original DN and deletion
+// time, and are hidden from searches that are not based inside the bin.
+// Tombstones older than the retention period are purged in the background.
+func (b *ObaBackend) EnableRecycleBin(cfg RecycleBinConfig) {
+	b.DisableRecycleBin()
+
+	rb := &recycleBin{
+		dn:        RecycleBinRDN,
+		retention: cfg.Retention,
+	}
+	if base := normalizeDN(cfg.BaseDN); base != "" {
+		rb.dn = RecycleBinRDN + "," + base
+	}
+
+	if cfg.PurgeInterval > 0 && cfg.Retention > 0 {
+		rb.stopCh = make(chan struct{})
+		rb.doneCh = make(chan struct{})
+		go b.purgeLoop(rb, cfg.PurgeInterval)
+	}
+
+	b.recycleBin = rb
+}
+
+// DisableRecycleBin makes deletes erase entries again. Existing tombstones
+// are kept.
+func (b *ObaBackend) DisableRecycleBin() {
+	rb := b.recycleBin
+	if rb == nil {
+		return
+	}
+	b.recycleBin = nil
+
+	if rb.stopCh != nil {
+		close(rb.stopCh)
+		<-rb.doneCh
+	}
+}
+
+// RecycleBinDN returns the DN of the recycle bin container, or an empty
+// string if the recycle bin is disabled.
+func (b *ObaBackend) RecycleBinDN() string {
+	if b.recycleBin == nil {
+		return ""
+	}
+	return b.recycleBin.dn
+}
+
+// inRecycleBin reports whether dn is the recycle bin container or a
+// tombstone inside it.
+func (b *ObaBackend) inRecycleBin(dn string) bool {
+	rb := b.recycleBin
+	if rb == nil {
+		return false
+	}
+	return dn == rb.dn || strings.HasSuffix(dn, ","+rb.dn)
+}
+
+// recycles reports whether deleting dn moves it to the recycle bin.
+func (b *ObaBackend) recycles(dn string) bool {
+	return b.recycleBin != nil && !b.inRecycleBin(dn)
+}
+
+// tombstoneDN returns the DN of the tombstone with the given ID.
+func (b *ObaBackend) tombstoneDN(id string) string {
+	return "entryuuid=" + strings.ToLower(id) + "," + b.recycleBin.dn
+}
+
+// newTombstone returns the tombstone for entry deleted at now.
+func (b *ObaBackend) newTombstone(entry *storage.Entry, now time.Time) *storage.Entry {
+	tombstone := entry.Clone()
+
+	id := ""
+	if values := entry.GetAttribute("entryuuid"); len(values) > 0 {
+		id = string(values[0])
+	}
+	if id == "" {
+		id = GenerateUUID()
+		tombstone.SetStringAttribute("entryuuid", id)
+	}
+
+	tombstone.DN = b.tombstoneDN(id)
+	tombstone.SetStringAttribute(strings.ToLower(AttrOriginalDN), entry.DN)
+	tombstone.SetStringAttribute(strings.ToLower(AttrDeleteTimestamp), FormatTimestamp(now))
+	return tombstone
+}
+
+// newRecycleBinContainer returns the recycle bin container entry.
+func (b *ObaBackend) newRecycleBinContainer() *storage.Entry {
+	container := storage.NewEntry(b.recycleBin.dn)
+	container.SetStringAttribute("objectclass", "top", "container")
+	container.SetStringAttribute("cn", "deleted objects")
+	return container
+}
+
+// removeEntry deletes dn within txn, moving it to the recycle bin if the
+// recycle bin is enabled.
+func (b *ObaBackend) removeEntry(txn interface{}, dn string) error {
+	if !b.recycles(dn) {
+		return b.engine.Delete(txn, dn)
+	}
+
+	entry, err := b.engine.Get(txn, dn)
+	if err != nil {
+		return err
+	}
+	if _, err := b.engine.Get(txn, b.recycleBin.dn); err != nil {
+		if err := b.engine.Put(txn, b.newRecycleBinContainer()); err != nil {
+			return err
+		}
+	}
+	if err := b.engine.Delete(txn, dn); err != nil {
+		return err
+	}
+	return b.engine.Put(txn, b.newTombstone(entry, time.Now()))
+}
+
+// removeClusterEntry deletes dn through the cluster writer, moving it to the
+// recycle bin if the recycle bin is enabled.
+func (b *ObaBackend) removeClusterEntry(dn string) error {
+	if !b.recycles(dn) {
+		return b.clusterWriter.Delete(dn)
+	}
+
+	txn, err := b.engine.Begin()
+	if err != nil {
+		return err
+	}
+	entry, err := b.engine.Get(txn, dn)
+	if err != nil {
+		b.engine.Rollback(txn)
+		return err
+	}
+	_, binErr := b.engine.Get(txn, b.recycleBin.dn)
+	b.engine.Rollback(txn)
+
+	if binErr != nil {
+		if err := b.clusterWriter.Put(b.newRecycleBinContainer()); err != nil {
+			return err
+		}
+	}
+	return b.clusterWriter.ModifyDN(dn, b.newTombstone(entry, time.Now()))
+}
+
+// ListDeleted returns the tombstones in the recycle bin.
+func (b *ObaBackend) ListDeleted() ([]*Entry, error) {
+	if b.recycleBin == nil {
+		return nil, ErrRecycleBinDisabled
+	}
+	return b.Search(context.Background(), b.recycleBin.dn, int(storage.ScopeOneLevel), nil)
+}
+
+// Restore moves the deleted entry with the given ID back to its original DN
+// and returns that DN. The entry is validated as if it were added again: the
+// original parent must exist, the original DN must be free, and the entry
+// must satisfy the placement rules, the schema and the DIT structure rules.
+func (b *ObaBackend) Restore(id string) (string, error) {
+	if b.recycleBin == nil {
+		return "", ErrRecycleBinDisabled
+	}
+	if id == "" || strings.ContainsAny(id, ",=") {
+		return "", ErrDeletedEntryNotFound
+	}
+	tombstoneDN := b.tombstoneDN(id)
+
+	txn, err := b.engine.Begin()
+	if err != nil {
+		return "", wrapStorageError(err)
+	}
+
+	tombstone, err := b.engine.Get(txn, tombstoneDN)
+	if err != nil {
+		b.engine.Rollback(txn)
+		return "", ErrDeletedEntryNotFound
+	}
+
+	entry := convertFromStorageEntry(tombstone)
+	prettyDN := strings.TrimSpace(entry.GetFirstAttribute(AttrOriginalDN))
+	originalDN := normalizeDN(prettyDN)
+	if originalDN == "" {
+		b.engine.Rollback(txn)
+		return "", ErrInvalidEntry
+	}
+	entry.DeleteAttribute(AttrOriginalDN)
+	entry.DeleteAttribute(AttrDeleteTimestamp)
+	entry.DN = prettyDN
+	b.dropVirtualAttributes(entry)
+
+	if _, err := b.engine.Get(txn, originalDN); err == nil {
+		b.engine.Rollback(txn)
+		return "", ErrEntryExists
+	}
+	parentDN, err := radix.GetParentDN(originalDN)
+	if err != nil {
+		b.engine.Rollback(txn)
+		return "", ErrInvalidDN
+	}
+	if parentDN != "" {
+		if _, err := b.engine.Get(txn, parentDN); err != nil {
+			b.engine.Rollback(txn)
+			return "", ErrRestoreNoParent
+		}
+	}
+
+	if err := validateEntryPlacement(entry); err != nil {
+		b.engine.Rollback(txn)
+		return "", err
+	}
+	if b.schema != nil {
+		if err := b.validateEntry(entry); err != nil {
+			b.engine.Rollback(txn)
+			return "", err
+		}
+	}
+	if err := b.validateStructure(txn, entry); err != nil {
+		b.engine.Rollback(txn)
+		return "", err
+	}
+	b.engine.Rollback(txn)
+
+	storageEntry := convertToStorageEntry(entry)
+
+	if b.clusterWriter != nil {
+		if err := b.clusterWriter.ModifyDN(tombstoneDN, storageEntry); err != nil {
+			return "", wrapStorageError(err)
+		}
+		b.emitChange(stream.OpInsert, originalDN, storageEntry)
+		return originalDN, nil
+	}
+
+	txn, err = b.engine.Begin()
+	if err != nil {
+		return "", wrapStorageError(err)
+	}
+	if err := b.engine.Delete(txn, tombstoneDN); err != nil {
+		b.engine.Rollback(txn)
+		return "", wrapStorageError(err)
+	}
+	if err := b.engine.Put(txn, storageEntry); err != nil {
+		b.engine.Rollback(txn)
+		return "", wrapStorageError(err)
+	}
+	if err := b.engine.Commit(txn); err != nil {
+		return "", wrapStorageError(err)
+	}
+
+	b.emitChange(stream.OpInsert, originalDN, storageEntry)
+	return originalDN, nil
+}
+
+// PurgeDeleted permanently removes the tombstones deleted before the given
+// time and returns how many were removed.
+func (b *ObaBackend) PurgeDeleted(before time.Time) (int, error) {
+	rb := b.recycleBin
+	if rb == nil {
+		return 0, ErrRecycleBinDisabled
+	}
+	return b.purgeTombstones(rb.dn, before)
+}
+
+// purgeTombstones permanently removes the tombstones below binDN deleted
+// before the given time.
+func (b *ObaBackend) purgeTombstones(binDN string, before time.Time) (int, error) {
+	txn, err := b.engine.Begin()
+	if err != nil {
+		return 0, wrapStorageError(err)
+	}
+
+	var expired []string
+	iter := b.engine.SearchByDN(txn, binDN, storage.ScopeOneLevel)
+	for iter.Next() {
+		tombstone := iter.Entry()
+		if tombstone == nil {
+			continue
+		}
+		values := tombstone.GetAttribute(strings.ToLower(AttrDeleteTimestamp))
+		if len(values) == 0 {
+			continue
+		}
+		if deleted := ParseTimestamp(string(values[0])); !deleted.IsZero() && deleted.Before(before) {
+			expired = append(expired, normalizeDN(tombstone.DN))
+		}
+	}
+	iterErr := iter.Error()
+	iter.Close()
+	b.engine.Rollback(txn)
+	if iterErr != nil {
+		return 0, wrapStorageError(iterErr)
+	}
+
+	if b.clusterWriter != nil {
+		for i, dn := range expired {
+			if err := b.clusterWriter.Delete(dn); err != nil {
+				return i, wrapStorageError(err)
+			}
+		}
+		return len(expired), nil
+	}
+
+	purged := 0
+	for purged < len(expired) {
+		end := purged + subtreeDeleteBatchSize
+		if end > len(expired) {
+			end = len(expired)
+		}
+
+		txn, err := b.engine.Begin()
+		if err != nil {
+			return purged, wrapStorageError(err)
+		}
+		for _, dn := range expired[purged:end] {
+			if err := b.engine.Delete(txn, dn); err != nil {
+				b.engine.Rollback(txn)
+				return purged, wrapStorageError(err)
+			}
+		}
+		if err := b.engine.Commit(txn); err != nil {
+			return purged, wrapStorageError(err)
+		}
+		purged = end
+	}
+
+	return purged, nil
+}
+
+// purgeLoop periodically purges expired tombstones until rb is disabled.
+func (b *ObaBackend) purgeLoop(rb *recycleBin, interval time.Duration) {
+	defer close(rb.doneCh)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-rb.stopCh:
+			return
+		case <-ticker.C:
+			// Only the leader purges; followers apply its deletes.
+			if b.clusterWriter != nil && !b.clusterWriter.IsLeader() {
+				continue
+			}
+			_, _ = b.purgeTombstones(rb.dn, time.Now().Add(-rb.retention))
+		}
+	}
+}
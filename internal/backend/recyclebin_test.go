@@ -0,0 +1,187 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/filter"
+)
+
+// newRecycleBinTestBackend returns a subtree test backend with the recycle
+// bin enabled and no background purge.
+func newRecycleBinTestBackend(t *testing.T, users int) *ObaBackend {
+	t.Helper()
+
+	be := newSubtreeTestBackend(t, users)
+	be.EnableRecycleBin(RecycleBinConfig{BaseDN: "dc=example,dc=com", Retention: time.Hour})
+	t.Cleanup(be.DisableRecycleBin)
+	return be
+}
+
+// tombstoneID returns the ID of the only tombstone with the given original DN.
+func tombstoneID(t *testing.T, be *ObaBackend, originalDN string) string {
+	t.Helper()
+
+	deleted, err := be.ListDeleted()
+	if err != nil {
+		t.Fatalf("ListDeleted() error = %v", err)
+	}
+	for _, entry := range deleted {
+		if entry.GetFirstAttribute(AttrOriginalDN) == originalDN {
+			return entry.GetFirstAttribute(AttrEntryUUID)
+		}
+	}
+	t.Fatalf("no tombstone for %s", originalDN)
+	return ""
+}
+
+func TestRecycleBinDeleteAndRestore(t *testing.T) {
+	be := newRecycleBinTestBackend(t, 3)
+	const dn = "uid=user0001,ou=users,ou=sales,dc=example,dc=com"
+
+	if err := be.Delete(context.Background(), dn); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	// The entry is hidden from normal searches, including indexed ones.
+	if entries, _ := be.Search(context.Background(), dn, 0, nil); len(entries) != 0 {
+		t.Error("deleted entry still found by base search")
+	}
+	uidFilter := filter.NewEqualityFilter("uid", []byte("user0001"))
+	if entries, _ := be.Search(context.Background(), "dc=example,dc=com", 2, uidFilter); len(entries) != 0 {
+		t.Errorf("deleted entry still found by uid: %v", entries[0].DN)
+	}
+
+	// It is visible with Show Deleted and when searching the bin.
+	entries, err := be.SearchWithDeleted(context.Background(), "dc=example,dc=com", 2, uidFilter)
+	if err != nil {
+		t.Fatalf("SearchWithDeleted() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].GetFirstAttribute(AttrOriginalDN) != dn {
+		t.Fatalf("SearchWithDeleted() = %v, want the tombstone", entries)
+	}
+	if entries[0].GetFirstAttribute(AttrDeleteTimestamp) == "" {
+		t.Error("tombstone has no deletion timestamp")
+	}
+	if entries, _ := be.Search(context.Background(), be.RecycleBinDN(), 1, nil); len(entries) != 1 {
+		t.Errorf("bin search returned %d entries, want 1", len(entries))
+	}
+
+	id := tombstoneID(t, be, dn)
+	restored, err := be.Restore(id)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if restored != dn {
+		t.Errorf("Restore() = %s, want %s", restored, dn)
+	}
+
+	entry, err := be.GetEntry(dn)
+	if err != nil {
+		t.Fatalf("restored entry not found: %v", err)
+	}
+	if entry.HasAttribute("obaoriginaldn") || entry.HasAttribute("obadeletetimestamp") {
+		t.Error("restored entry kept tombstone attributes")
+	}
+	if got := entry.GetAttribute("entryuuid"); len(got) != 1 || string(got[0]) != id {
+		t.Errorf("entryUUID = %q, want %s", got, id)
+	}
+
+	if _, err := be.Restore(id); !errors.Is(err, ErrDeletedEntryNotFound) {
+		t.Errorf("second Restore() error = %v, want ErrDeletedEntryNotFound", err)
+	}
+}
+
+func TestRecycleBinRestoreChecks(t *testing.T) {
+	be := newRecycleBinTestBackend(t, 2)
+	const dn = "uid=user0000,ou=users,ou=sales,dc=example,dc=com"
+
+	if err := be.Delete(context.Background(), dn); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	id := tombstoneID(t, be, dn)
+
+	// The uid is free again while the old entry is in the bin.
+	user := NewEntry(dn)
+	user.SetAttribute("objectClass", "top", "person")
+	user.SetAttribute("uid", "user0000")
+	user.SetAttribute("cn", "user0000")
+	user.SetAttribute("sn", "user0000")
+	if err := be.Add(context.Background(), user); err != nil {
+		t.Fatalf("re-adding deleted user: %v", err)
+	}
+	if _, err := be.Restore(id); !errors.Is(err, ErrEntryExists) {
+		t.Errorf("Restore() over a live entry error = %v, want ErrEntryExists", err)
+	}
+
+	// Deleting the whole subtree tombstones every entry; a child cannot be
+	// restored before its parent.
+	if _, err := be.DeleteSubtree(context.Background(), "ou=users,ou=sales,dc=example,dc=com", nil); err != nil {
+		t.Fatalf("DeleteSubtree() error = %v", err)
+	}
+	childID := tombstoneID(t, be, "uid=user0001,ou=users,ou=sales,dc=example,dc=com")
+	if _, err := be.Restore(childID); !errors.Is(err, ErrRestoreNoParent) {
+		t.Fatalf("Restore() of orphan error = %v, want ErrRestoreNoParent", err)
+	}
+	if _, err := be.Restore(tombstoneID(t, be, "ou=users,ou=sales,dc=example,dc=com")); err != nil {
+		t.Fatalf("Restore() of parent error = %v", err)
+	}
+	if _, err := be.Restore(childID); err != nil {
+		t.Errorf("Restore() of child error = %v", err)
+	}
+}
+
+func TestRecycleBinPurge(t *testing.T) {
+	be := newRecycleBinTestBackend(t, 5)
+
+	if _, err := be.DeleteSubtree(context.Background(), "ou=users,ou=sales,dc=example,dc=com", nil); err != nil {
+		t.Fatalf("DeleteSubtree() error = %v", err)
+	}
+
+	purged, err := be.PurgeDeleted(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeDeleted() error = %v", err)
+	}
+	if purged != 0 {
+		t.Errorf("purged %d fresh tombstones", purged)
+	}
+
+	purged, err = be.PurgeDeleted(time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeDeleted() error = %v", err)
+	}
+	if purged != 6 {
+		t.Errorf("purged %d tombstones, want 6", purged)
+	}
+	if deleted, _ := be.ListDeleted(); len(deleted) != 0 {
+		t.Errorf("%d tombstones left after purge", len(deleted))
+	}
+
+	// Deleting inside the bin erases for good.
+	if err := be.Delete(context.Background(), "ou=hr,dc=example,dc=com"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	id := tombstoneID(t, be, "ou=hr,dc=example,dc=com")
+	if err := be.Delete(context.Background(), be.tombstoneDN(id)); err != nil {
+		t.Fatalf("Delete() of tombstone error = %v", err)
+	}
+	if deleted, _ := be.ListDeleted(); len(deleted) != 0 {
+		t.Errorf("%d tombstones left after deleting from the bin", len(deleted))
+	}
+}
+
+func TestRecycleBinDisabled(t *testing.T) {
+	be := newSubtreeTestBackend(t, 1)
+
+	if _, err := be.Restore("x"); !errors.Is(err, ErrRecycleBinDisabled) {
+		t.Errorf("Restore() error = %v, want ErrRecycleBinDisabled", err)
+	}
+	if err := be.Delete(context.Background(), "ou=hr,dc=example,dc=com"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if entries, _ := be.SearchWithDeleted(context.Background(), "dc=example,dc=com", 2, nil); len(entries) != 4 {
+		t.Errorf("found %d entries, want 4 (deletes erase without a bin)", len(entries))
+	}
+}
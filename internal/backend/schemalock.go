@@ -0,0 +1,127 @@
+// Package backend provides the LDAP backend interface that wraps the storage engine
+// and provides LDAP-specific operations including authentication, entry validation,
+// and coordination with the storage layer.
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/schema"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/index"
+)
+
+// SchemaLockResource is the name of the lock that serializes index builds
+// and changes to the subschema subtree.
+const SchemaLockResource = "schema"
+
+// schemaLockTTL bounds how long a crashed holder keeps the schema lock.
+const schemaLockTTL = 30 * time.Second
+
+// ErrBusy is returned when an operation cannot take a lock held by another
+// operation.
+var ErrBusy = newError(ldap.ResultBusy, "resource is busy")
+
+// Locker provides mutual exclusion across the cluster. It is implemented by
+// raft.DistributedLock.
+type Locker interface {
+	// Acquire takes the lock on resource for holder. The lock expires
+	// after ttl.
+	Acquire(resource, holder string, ttl time.Duration) error
+
+	// Release releases the lock on resource held by holder.
+	Release(resource, holder string) error
+}
+
+// SetLocker sets the locker used to serialize index builds and schema
+// changes across the cluster. node identifies this server in lock holder
+// names. Without a locker these operations are not serialized.
+func (b *ObaBackend) SetLocker(locker Locker, node string) {
+	b.locker = locker
+	b.lockNode = node
+}
+
+// CreateIndex creates an index for attribute while holding the schema lock,
+// so that no other node changes the schema or builds an index at the same
+// time.
+//
+// Returns ErrBusy if the schema lock is held by another operation.
+func (b *ObaBackend) CreateIndex(attribute string, indexType storage.IndexType) error {
+	if attribute == "" {
+		return ErrInvalidEntry
+	}
+
+	release, err := b.lockSchema()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return wrapStorageError(b.engine.CreateIndex(attribute, indexType))
+}
+
+// ErrIndexRebuildUnsupported is returned by RebuildIndex when the storage
+// engine cannot rebuild an index while online.
+var ErrIndexRebuildUnsupported = newError(ldap.ResultUnwillingToPerform, "index rebuild is not supported by the storage engine")
+
+// RebuildIndex rebuilds the index for attribute from the entries while the
+// server keeps running, reporting its progress to progress, which may be
+// nil. The index is local to this server, so the schema lock is not taken.
+//
+// Returns ErrBusy if the index is already being rebuilt.
+func (b *ObaBackend) RebuildIndex(ctx context.Context, attribute string, progress func(storage.IndexRebuildProgress)) (storage.IndexRebuildProgress, error) {
+	if attribute == "" {
+		return storage.IndexRebuildProgress{}, ErrInvalidEntry
+	}
+
+	rebuilder, ok := b.engine.(storage.IndexRebuilder)
+	if !ok {
+		return storage.IndexRebuildProgress{}, ErrIndexRebuildUnsupported
+	}
+
+	p, err := rebuilder.RebuildIndex(ctx, attribute, progress)
+	if errors.Is(err, index.ErrRebuildInProgress) {
+		return p, fmt.Errorf("%w: %w", ErrBusy, err)
+	}
+	return p, wrapStorageError(err)
+}
+
+// lockSchemaEntry takes the schema lock if one of the normalized dns is in
+// the subschema subtree. The returned function releases the lock.
+func (b *ObaBackend) lockSchemaEntry(dns ...string) (func(), error) {
+	for _, dn := range dns {
+		if isSchemaDN(dn) {
+			return b.lockSchema()
+		}
+	}
+	return func() {}, nil
+}
+
+// lockSchema takes the schema lock under a holder name unique to this
+// operation. The returned function releases the lock.
+func (b *ObaBackend) lockSchema() (func(), error) {
+	if b.locker == nil {
+		return func() {}, nil
+	}
+
+	holder := fmt.Sprintf("%s/%d", b.lockNode, atomic.AddUint64(&b.lockSeq, 1))
+	if err := b.locker.Acquire(SchemaLockResource, holder, schemaLockTTL); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBusy, err)
+	}
+	return func() {
+		// A lock that cannot be released expires after schemaLockTTL
+		b.locker.Release(SchemaLockResource, holder)
+	}, nil
+}
+
+// isSchemaDN reports whether the normalized dn is the subschema subentry
+// or below it.
+func isSchemaDN(dn string) bool {
+	return dn == schema.SubschemaDN || strings.HasSuffix(dn, ","+schema.SubschemaDN)
+}
@@ -0,0 +1,95 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+var errLockHeld = errors.New("lock held")
+
+// recordingLocker is a Locker that records lock calls and rejects every
+// acquire while busy is set.
+type recordingLocker struct {
+	busy     bool
+	acquired []string
+	released []string
+}
+
+func (l *recordingLocker) Acquire(resource, holder string, ttl time.Duration) error {
+	if l.busy {
+		return errLockHeld
+	}
+	l.acquired = append(l.acquired, resource+"="+holder)
+	return nil
+}
+
+func (l *recordingLocker) Release(resource, holder string) error {
+	l.released = append(l.released, resource+"="+holder)
+	return nil
+}
+
+func openLockedBackend(t *testing.T) (*ObaBackend, *recordingLocker) {
+	t.Helper()
+
+	db, err := engine.Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	cfg := config.DefaultConfig()
+	cfg.Directory.BaseDN = "dc=example,dc=com"
+	be := NewBackend(db, cfg)
+
+	locker := &recordingLocker{}
+	be.SetLocker(locker, "node1")
+	return be, locker
+}
+
+func TestCreateIndexTakesSchemaLock(t *testing.T) {
+	be, locker := openLockedBackend(t)
+
+	if err := be.CreateIndex("departmentNumber", storage.IndexEquality); err != nil {
+		t.Fatalf("CreateIndex() error = %v", err)
+	}
+	if len(locker.acquired) != 1 || len(locker.released) != 1 || locker.acquired[0] != locker.released[0] {
+		t.Errorf("acquired %v, released %v, want one matching schema lock", locker.acquired, locker.released)
+	}
+
+	locker.busy = true
+	if err := be.CreateIndex("employeeNumber", storage.IndexEquality); !errors.Is(err, ErrBusy) {
+		t.Errorf("CreateIndex() error = %v, want ErrBusy", err)
+	}
+}
+
+func TestSchemaEntryWritesTakeSchemaLock(t *testing.T) {
+	be, locker := openLockedBackend(t)
+	locker.busy = true
+
+	schemaEntry := NewEntry("cn=custom,cn=schema")
+	schemaEntry.SetAttribute("objectClass", "subentry")
+	if err := be.Add(context.Background(), schemaEntry); !errors.Is(err, ErrBusy) {
+		t.Errorf("Add() error = %v, want ErrBusy", err)
+	}
+	mods := []Modification{{Type: ModReplace, Attribute: "description", Values: []string{"x"}}}
+	if err := be.Modify(context.Background(), "CN=Schema", mods); !errors.Is(err, ErrBusy) {
+		t.Errorf("Modify() error = %v, want ErrBusy", err)
+	}
+	if err := be.Delete(context.Background(), "cn=custom,cn=schema"); !errors.Is(err, ErrBusy) {
+		t.Errorf("Delete() error = %v, want ErrBusy", err)
+	}
+
+	// Entries outside the subschema subtree do not take the lock
+	if err := be.Modify(context.Background(), "dc=example,dc=com", mods); err != nil {
+		t.Errorf("Modify() outside cn=schema error = %v", err)
+	}
+	if len(locker.acquired) != 0 {
+		t.Errorf("acquired %v, want none", locker.acquired)
+	}
+}
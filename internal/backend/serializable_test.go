@@ -0,0 +1,166 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+// TestConcurrentAddsUnderDeletedParent tests that adds under a parent that
+// is deleted concurrently never leave orphans: either the parent survives
+// or none of the adds commit.
+func TestConcurrentAddsUnderDeletedParent(t *testing.T) {
+	be := newSubtreeTestBackend(t, 0)
+
+	const rounds = 20
+	const adders = 8
+	for round := 0; round < rounds; round++ {
+		parentDN := fmt.Sprintf("ou=team%d,dc=example,dc=com", round)
+		parent := storage.NewEntry(parentDN)
+		parent.SetStringAttribute("objectclass", "top", "organizationalUnit")
+		parent.SetStringAttribute("ou", fmt.Sprintf("team%d", round))
+		if err := be.AddEntry(parent); err != nil {
+			t.Fatalf("AddEntry(%s) error = %v", parentDN, err)
+		}
+
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		errs := make(chan error, adders+1)
+		for i := 0; i < adders; i++ {
+			child := storage.NewEntry(fmt.Sprintf("cn=member%d,%s", i, parentDN))
+			child.SetStringAttribute("objectclass", "top", "device")
+			child.SetStringAttribute("cn", fmt.Sprintf("member%d", i))
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				errs <- be.AddEntry(child)
+			}()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs <- be.Delete(context.Background(), parentDN)
+		}()
+		close(start)
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			switch {
+			case err == nil, errors.Is(err, ErrConflict), errors.Is(err, ErrNoParent),
+				errors.Is(err, ErrNotAllowedOnNonLeaf):
+			default:
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+
+		if _, err := be.engine.Get(nil, parentDN); err == nil {
+			continue
+		}
+		if n := countEntries(be.engine.SearchByDN(nil, parentDN, storage.ScopeOneLevel)); n != 0 {
+			t.Fatalf("round %d: %d entries left below the deleted parent", round, n)
+		}
+	}
+}
+
+// TestDeleteParentDuringAdds tests that deleting a parent fails when
+// children are added after the delete checked for them.
+func TestDeleteParentDuringAdds(t *testing.T) {
+	be := newSubtreeTestBackend(t, 0)
+	parentDN := "ou=users,ou=sales,dc=example,dc=com"
+
+	// The delete waits after its checks until the adds have committed
+	checked := make(chan struct{})
+	added := make(chan struct{})
+	be.RegisterPreDeleteHook(func(ctx context.Context, op *WriteOp) error {
+		close(checked)
+		<-added
+		return nil
+	})
+
+	result := make(chan error, 1)
+	go func() { result <- be.Delete(context.Background(), parentDN) }()
+	<-checked
+	for i := 0; i < 3; i++ {
+		child := storage.NewEntry(fmt.Sprintf("cn=member%d,%s", i, parentDN))
+		child.SetStringAttribute("objectclass", "top", "device")
+		child.SetStringAttribute("cn", fmt.Sprintf("member%d", i))
+		if err := be.AddEntry(child); err != nil {
+			t.Fatalf("AddEntry() error = %v", err)
+		}
+	}
+	close(added)
+
+	if err := <-result; !errors.Is(err, ErrConflict) {
+		t.Fatalf("Delete() error = %v, want ErrConflict", err)
+	}
+	if _, err := be.engine.Get(nil, parentDN); err != nil {
+		t.Errorf("parent should survive the failed delete: %v", err)
+	}
+}
+
+// TestConcurrentAddsWithSameUID tests that of concurrent adds of users
+// with the same uid exactly one commits, although all of them checked the
+// uid before any of them committed.
+func TestConcurrentAddsWithSameUID(t *testing.T) {
+	be := newSubtreeTestBackend(t, 0)
+
+	const adders = 8
+	var begun sync.WaitGroup
+	begun.Add(adders)
+	be.RegisterPreAddHook(func(ctx context.Context, op *WriteOp) error {
+		begun.Done()
+		begun.Wait()
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make(chan error, adders)
+	for i := 0; i < adders; i++ {
+		entry := NewEntry(fmt.Sprintf("cn=twin%d,ou=users,ou=sales,dc=example,dc=com", i))
+		entry.SetAttribute("objectClass", "top", "person")
+		entry.SetAttribute("uid", "twin")
+		entry.SetAttribute("cn", fmt.Sprintf("twin%d", i))
+		entry.SetAttribute("sn", "twin")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs <- be.Add(context.Background(), entry)
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	added := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			added++
+		case errors.Is(err, ErrConflict), errors.Is(err, engine.ErrUIDNotUnique):
+		default:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if added != 1 {
+		t.Errorf("%d adds committed, want exactly 1", added)
+	}
+}
+
+func countEntries(iter storage.Iterator) int {
+	defer iter.Close()
+	n := 0
+	for iter.Next() {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,256 @@
+package backend
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/radix"
+)
+
+// DIT structure errors.
+var (
+	// ErrNamingViolation is returned when an entry's RDN violates a structure rule.
+	ErrNamingViolation = newError(ldap.ResultNamingViolation, "naming violation")
+	// ErrObjectClassViolation is returned when an entry is placed under a parent
+	// that its structure rule does not allow.
+	ErrObjectClassViolation = newError(ldap.ResultObjectClassViolation, "object class violation")
+)
+
+// StructureRule restricts where entries of a structural object class may be
+// placed in the DIT and which attributes may be used to name them.
+type StructureRule struct {
+	// ObjectClass is the structural object class the rule applies to.
+	ObjectClass string
+	// Parents lists the object classes an allowed parent entry must have one of.
+	// An empty list allows any parent.
+	Parents []string
+	// NamingAttributes lists the attributes allowed as the RDN attribute.
+	// An empty list allows any RDN attribute.
+	NamingAttributes []string
+}
+
+// StructureViolation describes an existing entry that violates a structure rule.
+type StructureViolation struct {
+	DN     string `json:"dn"`
+	Rule   string `json:"rule"`
+	Reason string `json:"reason"`
+}
+
+// StructureCheckReport summarizes a DIT structure check run.
+type StructureCheckReport struct {
+	ScannedEntries int                   `json:"scannedEntries"`
+	Violations     []*StructureViolation `json:"violations"`
+}
+
+// StructureRulesFromConfig converts configured structure rules to backend rules.
+func StructureRulesFromConfig(cfg []config.StructureRuleConfig) []StructureRule {
+	rules := make([]StructureRule, 0, len(cfg))
+	for _, r := range cfg {
+		rules = append(rules, StructureRule{
+			ObjectClass:      r.ObjectClass,
+			Parents:          r.Parents,
+			NamingAttributes: r.NamingAttributes,
+		})
+	}
+	return rules
+}
+
+// SetStructureRules enables DIT structure enforcement with the given rules.
+// Passing no rules disables enforcement.
+func (b *ObaBackend) SetStructureRules(rules []StructureRule) {
+	b.structureMu.Lock()
+	defer b.structureMu.Unlock()
+
+	if len(rules) == 0 {
+		b.structureRules = nil
+		return
+	}
+
+	b.structureRules = make(map[string]*StructureRule, len(rules))
+	for i := range rules {
+		rule := rules[i]
+		b.structureRules[strings.ToLower(strings.TrimSpace(rule.ObjectClass))] = &rule
+	}
+}
+
+// StructureRulesEnabled returns true if DIT structure rules are enforced.
+func (b *ObaBackend) StructureRulesEnabled() bool {
+	b.structureMu.RLock()
+	defer b.structureMu.RUnlock()
+	return len(b.structureRules) > 0
+}
+
+// validateStructure checks an entry against the configured structure rules.
+// The parent entry is read within the given transaction.
+func (b *ObaBackend) validateStructure(txn interface{}, entry *Entry) error {
+	rule := b.structureRuleFor(entry)
+	if rule == nil {
+		return nil
+	}
+
+	var parent *Entry
+	if len(rule.Parents) > 0 {
+		parentDN, err := radix.GetParentDN(normalizeDN(entry.DN))
+		if err != nil {
+			return ErrInvalidDN
+		}
+		if parentDN != "" {
+			storageParent, err := b.engine.Get(txn, parentDN)
+			if err != nil {
+				return b.noParent(txn, parentDN)
+			}
+			parent = convertFromStorageEntry(storageParent)
+		}
+	}
+
+	return b.checkStructureRule(rule, entry, parent)
+}
+
+// structureRuleFor returns the structure rule matching one of the entry's object classes.
+func (b *ObaBackend) structureRuleFor(entry *Entry) *StructureRule {
+	b.structureMu.RLock()
+	defer b.structureMu.RUnlock()
+
+	if len(b.structureRules) == 0 {
+		return nil
+	}
+
+	for _, oc := range getObjectClasses(entry) {
+		if rule, ok := b.structureRules[strings.ToLower(strings.TrimSpace(oc))]; ok {
+			return rule
+		}
+	}
+	return nil
+}
+
+// checkStructureRule checks the entry's naming attribute and parent against a rule.
+// A nil parent means the entry is a root entry.
+func (b *ObaBackend) checkStructureRule(rule *StructureRule, entry *Entry, parent *Entry) error {
+	rdnAttr, rdnValue := parseDNFirstRDN(entry.DN)
+	if rdnAttr == "" {
+		return ErrInvalidDN
+	}
+
+	if len(rule.NamingAttributes) > 0 && !containsFold(rule.NamingAttributes, rdnAttr) {
+		return fmt.Errorf("%w: structure rule for %s requires RDN attribute to be one of %s",
+			ErrNamingViolation, rule.ObjectClass, strings.Join(rule.NamingAttributes, ", "))
+	}
+
+	if !entryHasValueFold(entry, rdnAttr, rdnValue) {
+		return fmt.Errorf("%w: structure rule for %s requires RDN value %s=%s to be present in the entry",
+			ErrNamingViolation, rule.ObjectClass, rdnAttr, rdnValue)
+	}
+
+	if len(rule.Parents) == 0 {
+		return nil
+	}
+	if parent == nil || !b.hasAllowedParentClass(parent, rule.Parents) {
+		return fmt.Errorf("%w: structure rule for %s requires parent to be one of %s",
+			ErrObjectClassViolation, rule.ObjectClass, strings.Join(rule.Parents, ", "))
+	}
+
+	return nil
+}
+
+// hasAllowedParentClass returns true if the parent has one of the allowed object
+// classes, either directly or through schema inheritance.
+func (b *ObaBackend) hasAllowedParentClass(parent *Entry, allowed []string) bool {
+	for _, oc := range getObjectClasses(parent) {
+		name := strings.TrimSpace(oc)
+		for name != "" {
+			if containsFold(allowed, name) {
+				return true
+			}
+			if b.schema == nil {
+				break
+			}
+			def := b.schema.GetObjectClass(name)
+			if def == nil {
+				break
+			}
+			name = def.Superior
+		}
+	}
+	return false
+}
+
+// CheckStructureRules scans all entries and reports those that violate the
+// configured structure rules. No entries are modified.
+func (b *ObaBackend) CheckStructureRules() (*StructureCheckReport, error) {
+	report := &StructureCheckReport{
+		Violations: make([]*StructureViolation, 0),
+	}
+
+	txn, err := b.engine.Begin()
+	if err != nil {
+		return nil, wrapStorageError(err)
+	}
+	defer b.engine.Rollback(txn)
+
+	iter := b.engine.SearchByDN(txn, "", storage.ScopeSubtree)
+	defer iter.Close()
+
+	entries := make(map[string]*Entry)
+	for iter.Next() {
+		storageEntry := iter.Entry()
+		if storageEntry == nil {
+			continue
+		}
+		entry := convertFromStorageEntry(storageEntry)
+		entries[normalizeDN(entry.DN)] = entry
+	}
+	if err := iter.Error(); err != nil {
+		return nil, wrapStorageError(err)
+	}
+
+	report.ScannedEntries = len(entries)
+	for dn, entry := range entries {
+		rule := b.structureRuleFor(entry)
+		if rule == nil {
+			continue
+		}
+
+		var parent *Entry
+		if parentDN, err := radix.GetParentDN(dn); err == nil && parentDN != "" {
+			parent = entries[parentDN]
+		}
+
+		if err := b.checkStructureRule(rule, entry, parent); err != nil {
+			report.Violations = append(report.Violations, &StructureViolation{
+				DN:     dn,
+				Rule:   rule.ObjectClass,
+				Reason: err.Error(),
+			})
+		}
+	}
+
+	sort.Slice(report.Violations, func(i, j int) bool {
+		return report.Violations[i].DN < report.Violations[j].DN
+	})
+
+	return report, nil
+}
+
+// containsFold returns true if values contains s, ignoring case.
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return true
+		}
+	}
+	return false
+}
+
+// entryHasValueFold returns true if the entry has the given attribute value, ignoring case.
+func entryHasValueFold(entry *Entry, attr, value string) bool {
+	for _, v := range entry.GetStringAttribute(attr) {
+		if strings.EqualFold(strings.TrimSpace(v), value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/config"
+)
+
+// newStructureTestBackend creates a backend with a small tree and person/ou structure rules.
+func newStructureTestBackend(t *testing.T) (*ObaBackend, *mockStorageEngine) {
+	t.Helper()
+
+	engine := newMockStorageEngine()
+	backend := NewBackend(engine, nil)
+
+	base := NewEntry("dc=example,dc=com")
+	base.SetAttribute("objectclass", "organization", "dcObject", "top")
+	base.SetAttribute("dc", "example")
+	base.SetAttribute("o", "example")
+	if err := backend.Add(context.Background(), base); err != nil {
+		t.Fatalf("Add(base) error = %v", err)
+	}
+
+	users := NewEntry("ou=users,dc=example,dc=com")
+	users.SetAttribute("objectclass", "organizationalUnit", "top")
+	users.SetAttribute("ou", "users")
+	if err := backend.Add(context.Background(), users); err != nil {
+		t.Fatalf("Add(users) error = %v", err)
+	}
+
+	backend.SetStructureRules([]StructureRule{
+		{ObjectClass: "inetOrgPerson", Parents: []string{"organizationalUnit"}, NamingAttributes: []string{"uid", "cn"}},
+		{ObjectClass: "organizationalUnit", Parents: []string{"organization", "organizationalUnit"}, NamingAttributes: []string{"ou"}},
+	})
+
+	return backend, engine
+}
+
+func newPersonEntry(dn, uid string) *Entry {
+	entry := NewEntry(dn)
+	entry.SetAttribute("objectclass", "inetOrgPerson", "person", "top")
+	entry.SetAttribute("uid", uid)
+	entry.SetAttribute("cn", uid)
+	entry.SetAttribute("sn", uid)
+	return entry
+}
+
+func TestStructureRulesAllowValidEntry(t *testing.T) {
+	backend, _ := newStructureTestBackend(t)
+
+	if err := backend.Add(context.Background(), newPersonEntry("uid=alice,ou=users,dc=example,dc=com", "alice")); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+}
+
+func TestStructureRulesRejectWrongParent(t *testing.T) {
+	backend, _ := newStructureTestBackend(t)
+
+	people := NewEntry("ou=people,uid=alice,ou=users,dc=example,dc=com")
+	people.SetAttribute("objectclass", "organizationalUnit")
+	people.SetAttribute("ou", "people")
+
+	if err := backend.Add(context.Background(), newPersonEntry("uid=alice,ou=users,dc=example,dc=com", "alice")); err != nil {
+		t.Fatalf("Add(alice) error = %v", err)
+	}
+
+	err := backend.Add(context.Background(), people)
+	if !errors.Is(err, ErrObjectClassViolation) {
+		t.Fatalf("expected ErrObjectClassViolation, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "organizationalUnit") {
+		t.Errorf("expected diagnostic to name the rule, got %q", err.Error())
+	}
+}
+
+func TestStructureRulesRejectNamingAttribute(t *testing.T) {
+	backend, _ := newStructureTestBackend(t)
+
+	entry := newPersonEntry("mail=alice@example.com,ou=users,dc=example,dc=com", "alice")
+	entry.SetAttribute("mail", "alice@example.com")
+
+	err := backend.Add(context.Background(), entry)
+	if !errors.Is(err, ErrNamingViolation) {
+		t.Fatalf("expected ErrNamingViolation, got %v", err)
+	}
+}
+
+func TestStructureRulesRejectMissingRDNValue(t *testing.T) {
+	backend, _ := newStructureTestBackend(t)
+
+	err := backend.Add(context.Background(), newPersonEntry("uid=alice,ou=users,dc=example,dc=com", "bob"))
+	if !errors.Is(err, ErrNamingViolation) {
+		t.Fatalf("expected ErrNamingViolation, got %v", err)
+	}
+}
+
+func TestStructureRulesModifyDN(t *testing.T) {
+	backend, _ := newStructureTestBackend(t)
+
+	if err := backend.Add(context.Background(), newPersonEntry("uid=alice,ou=users,dc=example,dc=com", "alice")); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	org := NewEntry("o=partner,ou=users,dc=example,dc=com")
+	org.SetAttribute("objectclass", "organization")
+	org.SetAttribute("o", "partner")
+	if err := backend.Add(context.Background(), org); err != nil {
+		t.Fatalf("Add(org) error = %v", err)
+	}
+
+	err := backend.ModifyDN(context.Background(), &ModifyDNRequest{
+		DN:          "uid=alice,ou=users,dc=example,dc=com",
+		NewRDN:      "uid=alice",
+		NewSuperior: "o=partner,ou=users,dc=example,dc=com",
+	})
+	if !errors.Is(err, ErrObjectClassViolation) {
+		t.Fatalf("expected ErrObjectClassViolation, got %v", err)
+	}
+
+	err = backend.ModifyDN(context.Background(), &ModifyDNRequest{
+		DN:     "uid=alice,ou=users,dc=example,dc=com",
+		NewRDN: "mail=alice",
+	})
+	if !errors.Is(err, ErrNamingViolation) {
+		t.Fatalf("expected ErrNamingViolation, got %v", err)
+	}
+}
+
+func TestStructureRulesDisabledByDefault(t *testing.T) {
+	engine := newMockStorageEngine()
+	backend := NewBackend(engine, nil)
+
+	if backend.StructureRulesEnabled() {
+		t.Fatal("expected structure rules to be disabled by default")
+	}
+
+	entry := newPersonEntry("cn=alice,ou=users,dc=example,dc=com", "alice")
+	if err := backend.Add(context.Background(), entry); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+}
+
+func TestCheckStructureRulesReportsExistingViolations(t *testing.T) {
+	backend, _ := newStructureTestBackend(t)
+	backend.SetStructureRules(nil)
+
+	if err := backend.Add(context.Background(), newPersonEntry("uid=alice,ou=users,dc=example,dc=com", "alice")); err != nil {
+		t.Fatalf("Add(alice) error = %v", err)
+	}
+	if err := backend.Add(context.Background(), newPersonEntry("uid=bob,ou=users,dc=example,dc=com", "robert")); err != nil {
+		t.Fatalf("Add(bob) error = %v", err)
+	}
+
+	backend.SetStructureRules(StructureRulesFromConfig([]config.StructureRuleConfig{
+		{ObjectClass: "inetOrgPerson", Parents: []string{"organizationalUnit"}, NamingAttributes: []string{"uid"}},
+	}))
+
+	report, err := backend.CheckStructureRules()
+	if err != nil {
+		t.Fatalf("CheckStructureRules() error = %v", err)
+	}
+	if report.ScannedEntries != 4 {
+		t.Errorf("ScannedEntries = %d, want 4", report.ScannedEntries)
+	}
+	if len(report.Violations) != 1 {
+		t.Fatalf("expected 1 violation, got %d", len(report.Violations))
+	}
+	if report.Violations[0].DN != "uid=bob,ou=users,dc=example,dc=com" {
+		t.Errorf("violation DN = %q", report.Violations[0].DN)
+	}
+}
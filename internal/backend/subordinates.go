@@ -0,0 +1,42 @@
+// Package backend provides the LDAP backend interface that wraps the storage engine
+// and provides LDAP-specific operations including authentication, entry validation,
+// and coordination with the storage layer.
+package backend
+
+import (
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+)
+
+// ErrNoUserModification is returned when an add or modify supplies a value
+// for an operational attribute that is derived by the server.
+var ErrNoUserModification = newError(ldap.ResultConstraintViolation, "attribute is not user modifiable")
+
+// SetMaxNumSubordinates sets the number of children at which counting for
+// numSubordinates stops. Zero counts all children.
+func (b *ObaBackend) SetMaxNumSubordinates(limit int) {
+	b.maxNumSubordinates = limit
+}
+
+// computeHasSubordinates returns whether the entry has children in the
+// DN tree. It only needs to find the first child.
+func computeHasSubordinates(r *VirtualReader, entry *Entry) []string {
+	count, err := r.CountChildren(entry.DN, 1)
+	if err != nil {
+		return nil
+	}
+	if count > 0 {
+		return []string{"TRUE"}
+	}
+	return []string{"FALSE"}
+}
+
+// computeNumSubordinates returns the number of children of the entry in
+// the DN tree. It is cut at the configured maximum, so it reports "at
+// least" that many children for larger containers.
+func computeNumSubordinates(r *VirtualReader, entry *Entry) []string {
+	count, err := r.CountChildren(entry.DN, r.b.maxNumSubordinates)
+	if err != nil {
+		return nil
+	}
+	return []string{formatInt(count)}
+}
@@ -0,0 +1,172 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+// openSubordinatesBackend opens a backend holding dc=example,dc=com with
+// ou=users and ou=groups below it and three users in ou=users.
+func openSubordinatesBackend(t *testing.T) *ObaBackend {
+	t.Helper()
+
+	db, err := engine.Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	cfg := config.DefaultConfig()
+	cfg.Directory.BaseDN = "dc=example,dc=com"
+	be := NewBackend(db, cfg)
+
+	for i := 0; i < 3; i++ {
+		user := NewEntry(fmt.Sprintf("uid=user%d,ou=users,dc=example,dc=com", i))
+		user.SetAttribute("objectClass", "inetOrgPerson")
+		user.SetAttribute("uid", fmt.Sprintf("user%d", i))
+		user.SetAttribute("cn", fmt.Sprintf("User %d", i))
+		user.SetAttribute("sn", "User")
+		if err := be.Add(context.Background(), user); err != nil {
+			t.Fatalf("Add(%s) error = %v", user.DN, err)
+		}
+	}
+
+	return be
+}
+
+// subordinateValues returns the hasSubordinates and numSubordinates values
+// of the search result with the given DN.
+func subordinateValues(t *testing.T, entries []*Entry, dn string) (string, string) {
+	t.Helper()
+	for _, entry := range entries {
+		if normalizeDN(entry.DN) == normalizeDN(dn) {
+			return entry.GetFirstAttribute(AttrHasSubordinates), entry.GetFirstAttribute(AttrNumSubordinates)
+		}
+	}
+	t.Fatalf("no search result for %s", dn)
+	return "", ""
+}
+
+func TestSubordinateAttributes(t *testing.T) {
+	be := openSubordinatesBackend(t)
+
+	entries, err := be.Search(context.Background(), "dc=example,dc=com", 2, nil)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		attrs   []string
+		dn      string
+		wantHas string
+		wantNum string
+	}{
+		{"not requested", nil, "ou=users,dc=example,dc=com", "", ""},
+		{"user attributes", []string{"*"}, "ou=users,dc=example,dc=com", "", ""},
+		{"all operational", []string{"+"}, "ou=users,dc=example,dc=com", "TRUE", "3"},
+		{"by name", []string{"hasSubordinates"}, "ou=users,dc=example,dc=com", "TRUE", ""},
+		{"case insensitive", []string{"NUMSUBORDINATES"}, "ou=users,dc=example,dc=com", "", "3"},
+		{"base", []string{"+"}, "dc=example,dc=com", "TRUE", "2"},
+		{"leaf", []string{"hasSubordinates", "numSubordinates"}, "uid=user0,ou=users,dc=example,dc=com", "FALSE", "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			has, num := subordinateValues(t, be.VirtualAttributes(context.Background(), entries, tt.attrs), tt.dn)
+			if has != tt.wantHas || num != tt.wantNum {
+				t.Errorf("hasSubordinates, numSubordinates = %q, %q, want %q, %q",
+					has, num, tt.wantHas, tt.wantNum)
+			}
+		})
+	}
+
+	// The search results themselves are left untouched
+	if has, num := subordinateValues(t, entries, "ou=users,dc=example,dc=com"); has != "" || num != "" {
+		t.Errorf("search result was modified: %q, %q", has, num)
+	}
+}
+
+func TestSubordinateAttributesLimit(t *testing.T) {
+	be := openSubordinatesBackend(t)
+	be.SetMaxNumSubordinates(2)
+
+	entries, err := be.Search(context.Background(), "ou=users,dc=example,dc=com", 0, nil)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	has, num := subordinateValues(t, be.VirtualAttributes(context.Background(), entries, []string{"+"}), "ou=users,dc=example,dc=com")
+	if has != "TRUE" || num != "2" {
+		t.Errorf("hasSubordinates, numSubordinates = %q, %q, want TRUE, 2", has, num)
+	}
+}
+
+func TestSubordinateAttributesFollowTree(t *testing.T) {
+	be := openSubordinatesBackend(t)
+
+	count := func(dn string) string {
+		entries, err := be.Search(context.Background(), dn, 0, nil)
+		if err != nil {
+			t.Fatalf("Search() error = %v", err)
+		}
+		_, num := subordinateValues(t, be.VirtualAttributes(context.Background(), entries, []string{"numSubordinates"}), dn)
+		return num
+	}
+
+	if err := be.Delete(context.Background(), "uid=user0,ou=users,dc=example,dc=com"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got := count("ou=users,dc=example,dc=com"); got != "2" {
+		t.Errorf("numSubordinates after delete = %s, want 2", got)
+	}
+
+	staff := NewEntry("ou=staff,ou=users,dc=example,dc=com")
+	staff.SetAttribute("objectClass", "organizationalUnit")
+	staff.SetAttribute("ou", "staff")
+	if err := be.Add(context.Background(), staff); err != nil {
+		t.Fatalf("Add(%s) error = %v", staff.DN, err)
+	}
+	err := be.ModifyDN(context.Background(), &ModifyDNRequest{
+		DN:           "uid=user1,ou=users,dc=example,dc=com",
+		NewRDN:       "uid=user1",
+		DeleteOldRDN: true,
+		NewSuperior:  staff.DN,
+	})
+	if err != nil {
+		t.Fatalf("ModifyDN() error = %v", err)
+	}
+	if got := count("ou=users,dc=example,dc=com"); got != "2" {
+		t.Errorf("numSubordinates of ou=users after move = %s, want 2", got)
+	}
+	if got := count(staff.DN); got != "1" {
+		t.Errorf("numSubordinates of ou=staff after move = %s, want 1", got)
+	}
+}
+
+func TestSubordinateAttributesNotStorable(t *testing.T) {
+	be := openSubordinatesBackend(t)
+
+	entry := NewEntry("uid=user9,ou=users,dc=example,dc=com")
+	entry.SetAttribute("objectClass", "inetOrgPerson")
+	entry.SetAttribute("uid", "user9")
+	entry.SetAttribute("cn", "User 9")
+	entry.SetAttribute("sn", "User")
+	entry.SetAttribute(AttrHasSubordinates, "TRUE")
+	if err := be.Add(context.Background(), entry); !errors.Is(err, ErrNoUserModification) {
+		t.Errorf("Add() error = %v, want ErrNoUserModification", err)
+	}
+
+	for _, mod := range []ModificationType{ModAdd, ModReplace} {
+		changes := []Modification{*NewModification(mod, AttrNumSubordinates, "5")}
+		err := be.Modify(context.Background(), "ou=users,dc=example,dc=com", changes)
+		if !errors.Is(err, ErrNoUserModification) {
+			t.Errorf("Modify(%s) error = %v, want ErrNoUserModification", mod, err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package backend
+
+import (
+	"context"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/filter"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/trace"
+)
+
+// TracerName is the instrumentation scope of the backend's spans.
+const TracerName = "github.com/KilimcininKorOglu/oba/internal/backend"
+
+// Span attribute keys
+const (
+	// AttrBaseDN is the normalized base DN of a search
+	AttrBaseDN = "ldap.base_dn"
+	// AttrScope is the scope of a search
+	AttrScope = "ldap.scope"
+	// AttrEntriesReturned is the number of entries a search returned
+	AttrEntriesReturned = "ldap.entries_returned"
+	// AttrEntriesFetched is the number of entries the engine returned
+	AttrEntriesFetched = "engine.entries_fetched"
+	// AttrFilterEvaluations is the number of entries the filter was
+	// evaluated on
+	AttrFilterEvaluations = "filter.evaluations"
+	// AttrFilterEvalTime is the total time spent evaluating the filter,
+	// in microseconds
+	AttrFilterEvalTime = "filter.eval_time_us"
+)
+
+// SetTracerProvider sets the provider of the tracer that records search
+// spans. Without one, searches are not traced.
+func (b *ObaBackend) SetTracerProvider(tp trace.TracerProvider) {
+	b.tracer = tp.Tracer(TracerName)
+}
+
+// startSpan starts a span below the span in ctx. It returns a no-op span
+// when no tracer is set.
+func (b *ObaBackend) startSpan(ctx context.Context, name string, attrs ...trace.Attribute) (context.Context, trace.Span) {
+	if b.tracer == nil {
+		return ctx, trace.SpanFromContext(nil)
+	}
+	return b.tracer.Start(ctx, name, attrs...)
+}
+
+// StartSpan implements storage.SpanStarter.
+func (w *filterMatcherWrapper) StartSpan(name string, attrs ...trace.Attribute) trace.Span {
+	if w.tracer == nil {
+		return trace.SpanFromContext(nil)
+	}
+	_, span := w.tracer.Start(w.ctx, name, attrs...)
+	return span
+}
+
+// newFilterMatcher creates the matcher of a search traced below ctx.
+// Evaluation is only timed when the search span is sampled.
+func (b *ObaBackend) newFilterMatcher(ctx context.Context, f *filter.Filter, evaluator *filter.Evaluator) *filterMatcherWrapper {
+	return &filterMatcherWrapper{
+		filter:    f,
+		evaluator: evaluator,
+		ctx:       ctx,
+		tracer:    b.tracer,
+		timed:     trace.SpanFromContext(ctx).SpanContext().IsSampled(),
+	}
+}
+
+// setVirtual makes the matcher compute the virtual attributes attrs of
+// each entry, reading with r.
+func (w *filterMatcherWrapper) setVirtual(attrs []VirtualAttribute, r *VirtualReader) {
+	w.virtual = attrs
+	w.reader = r
+}
+
+// fetchAttributes returns the attributes of the entry fetch span of a
+// search that fetched entries with matcher, which may be nil.
+func fetchAttributes(fetched int, matcher *filterMatcherWrapper) []trace.Attribute {
+	attrs := []trace.Attribute{trace.Int(AttrEntriesFetched, fetched)}
+	if matcher != nil {
+		attrs = append(attrs,
+			trace.Int(AttrFilterEvaluations, matcher.evaluations),
+			trace.Int(AttrFilterEvalTime, int(matcher.evalTime/time.Microsecond)))
+	}
+	return attrs
+}
+
+var _ storage.SpanStarter = (*filterMatcherWrapper)(nil)
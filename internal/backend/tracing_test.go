@@ -0,0 +1,82 @@
+package backend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/filter"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+	"github.com/KilimcininKorOglu/oba/internal/trace"
+)
+
+func TestSearchContextSpans(t *testing.T) {
+	be := openCollectiveBackend(t)
+	be.engine.(*engine.ObaDB).SetSearchConfig(engine.SearchConfig{IndexSplitEnabled: true})
+
+	exporter := trace.NewInMemoryExporter()
+	tp := trace.NewProvider(exporter)
+	be.SetTracerProvider(tp)
+
+	ctx, root := tp.Tracer("test").Start(context.Background(), "ldap.search")
+	f := &filter.Filter{Type: filter.FilterEquality, Attribute: "objectClass", Value: []byte("inetOrgPerson")}
+	entries, err := be.Search(ctx, "dc=example,dc=com", 2, f)
+	root.End()
+	if err != nil {
+		t.Fatalf("SearchContext() error = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+
+	spans := make(map[string]trace.SpanData)
+	for _, span := range exporter.Spans() {
+		spans[span.Name] = span
+	}
+
+	search, ok := spans["backend.search"]
+	if !ok || search.Parent != root.SpanContext() {
+		t.Fatalf("backend.search span missing or not below the operation span: %+v", spans)
+	}
+	if n, _ := search.Attribute(AttrEntriesReturned); n != "2" {
+		t.Errorf("%s = %q, want 2", AttrEntriesReturned, n)
+	}
+	if dn, _ := search.Attribute(AttrBaseDN); dn != "dc=example,dc=com" {
+		t.Errorf("%s = %q", AttrBaseDN, dn)
+	}
+
+	for _, name := range []string{"engine.index_lookup", "engine.entry_fetch"} {
+		span, ok := spans[name]
+		if !ok {
+			t.Errorf("%s span missing", name)
+			continue
+		}
+		if span.Parent != search.SpanContext {
+			t.Errorf("%s span is not a child of backend.search", name)
+		}
+	}
+	if used, _ := spans["engine.index_lookup"].Attribute("index.used"); used != "true" {
+		t.Errorf("index.used = %q, want true", used)
+	}
+	fetch := spans["engine.entry_fetch"]
+	if n, _ := fetch.Attribute(AttrFilterEvaluations); n != "2" {
+		t.Errorf("%s = %q, want 2 (the index candidates)", AttrFilterEvaluations, n)
+	}
+	if _, ok := fetch.Attribute(AttrFilterEvalTime); !ok {
+		t.Errorf("%s missing", AttrFilterEvalTime)
+	}
+}
+
+func TestSearchWithoutTracer(t *testing.T) {
+	be := openCollectiveBackend(t)
+
+	exporter := trace.NewInMemoryExporter()
+	ctx, root := trace.NewProvider(exporter).Tracer("test").Start(context.Background(), "ldap.search")
+	if _, err := be.Search(ctx, "dc=example,dc=com", 2, nil); err != nil {
+		t.Fatalf("SearchContext() error = %v", err)
+	}
+	root.End()
+
+	if n := len(exporter.Spans()); n != 1 {
+		t.Errorf("exported %d spans, want only the operation span", n)
+	}
+}
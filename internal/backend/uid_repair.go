@@ -1,10 +1,12 @@
 package backend
 
 import (
+	"context"
 	"errors"
 	"sort"
 	"strings"
 
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
 	"github.com/KilimcininKorOglu/oba/internal/storage"
 )
 
@@ -45,8 +47,10 @@ type uidRecord struct {
 // 1. Entries outside ou=users do not keep uid (attribute removed).
 // 2. For users where RDN is uid=... but uid attribute mismatches, uid is replaced with RDN value.
 // 3. Remaining duplicates are resolved deterministically by keeping one canonical entry and deleting others.
-func (b *ObaBackend) RepairUIDUniqueness(dryRun bool, bindDN string) (*UIDRepairReport, error) {
-	entries, err := b.Search("", int(storage.ScopeSubtree), nil)
+//
+// Changes are made on behalf of the client in ctx.
+func (b *ObaBackend) RepairUIDUniqueness(ctx context.Context, dryRun bool) (*UIDRepairReport, error) {
+	entries, err := b.Search(ctx, "", int(storage.ScopeSubtree), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -100,9 +104,9 @@ func (b *ObaBackend) RepairUIDUniqueness(dryRun bool, bindDN string) (*UIDRepair
 			report.Actions = append(report.Actions, action)
 			continue
 		}
-		err := b.ModifyWithBindDN(rec.dn, []Modification{
+		err := b.Modify(ctx, rec.dn, []Modification{
 			{Type: ModDelete, Attribute: "uid"},
-		}, bindDN)
+		})
 		if err != nil {
 			action.Error = err.Error()
 			report.Failed++
@@ -144,7 +148,7 @@ func (b *ObaBackend) RepairUIDUniqueness(dryRun bool, bindDN string) (*UIDRepair
 				report.Actions = append(report.Actions, action)
 				continue
 			}
-			err := b.Delete(rec.dn)
+			err := b.Delete(ctx, rec.dn)
 			if err != nil && !errors.Is(err, ErrEntryNotFound) {
 				action.Error = err.Error()
 				report.Failed++
@@ -166,9 +170,9 @@ func (b *ObaBackend) RepairUIDUniqueness(dryRun bool, bindDN string) (*UIDRepair
 			report.Actions = append(report.Actions, action)
 			continue
 		}
-		err := b.ModifyWithBindDN(rec.dn, []Modification{
+		err := b.Modify(ctx, rec.dn, []Modification{
 			{Type: ModReplace, Attribute: "uid", Values: []string{targetUID}},
-		}, bindDN)
+		})
 		if err != nil {
 			action.Error = err.Error()
 			report.Failed++
@@ -217,7 +221,7 @@ func (b *ObaBackend) RepairUIDUniqueness(dryRun bool, bindDN string) (*UIDRepair
 				report.Actions = append(report.Actions, action)
 				continue
 			}
-			err := b.Delete(rec.dn)
+			err := b.Delete(ctx, rec.dn)
 			if err != nil && !errors.Is(err, ErrEntryNotFound) {
 				action.Error = err.Error()
 				report.Failed++
@@ -233,22 +237,22 @@ func (b *ObaBackend) RepairUIDUniqueness(dryRun bool, bindDN string) (*UIDRepair
 }
 
 func parseDNFirstRDN(dn string) (string, string) {
-	parts := strings.SplitN(strings.TrimSpace(dn), ",", 2)
-	if len(parts) == 0 {
-		return "", ""
-	}
-	kv := strings.SplitN(strings.TrimSpace(parts[0]), "=", 2)
-	if len(kv) != 2 {
+	rdns, err := ldap.SplitDN(dn)
+	if err != nil || len(rdns) == 0 {
 		return "", ""
 	}
-	return strings.ToLower(strings.TrimSpace(kv[0])), strings.ToLower(strings.TrimSpace(kv[1]))
+	attrType, attrValue := parseRDNComponent(rdns[0])
+	return attrType, strings.ToLower(attrValue)
 }
 
 func isUnderOU(dn, ou string) bool {
-	parts := strings.Split(strings.ToLower(dn), ",")
-	target := "ou=" + strings.ToLower(strings.TrimSpace(ou))
-	for _, p := range parts {
-		if strings.TrimSpace(p) == target {
+	rdns, err := ldap.SplitDN(dn)
+	if err != nil {
+		return false
+	}
+	for _, rdn := range rdns {
+		attrType, attrValue := parseRDNComponent(rdn)
+		if attrType == "ou" && strings.EqualFold(attrValue, strings.TrimSpace(ou)) {
 			return true
 		}
 	}
@@ -259,7 +263,7 @@ func hasAnyObjectClass(entry *Entry, names ...string) bool {
 	if entry == nil {
 		return false
 	}
-	values := entry.GetAttribute("objectClass")
+	values := entry.GetStringAttribute("objectClass")
 	if len(values) == 0 {
 		return false
 	}
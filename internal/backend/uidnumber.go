@@ -0,0 +1,65 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+)
+
+// DefaultUIDNumberStart is the first uidNumber assigned when the counter
+// entry does not exist yet.
+const DefaultUIDNumberStart = 10000
+
+// UIDNumberCounterRDN is the RDN of the uidNumber counter entry, which is
+// kept directly below the base DN.
+const UIDNumberCounterRDN = "cn=uidNext"
+
+// UIDNumberHook returns a pre-add hook that assigns the next free uidNumber
+// to posixAccount entries added without one. The next number is kept in
+// the uidNumber attribute of the counter entry counterDN, which is created
+// with start on first use. The counter is updated in the transaction of
+// the add, so a failed add does not use up a number and concurrent adds
+// conflict instead of sharing one.
+func UIDNumberHook(counterDN string, start int) PreHook {
+	counterDN = normalizeDN(counterDN)
+
+	return func(ctx context.Context, op *WriteOp) error {
+		if op.Entry.HasAttribute("uidNumber") || !hasObjectClass(op.Entry, "posixAccount") {
+			return nil
+		}
+
+		next := start
+		counter, err := op.Get(counterDN)
+		switch {
+		case err == ErrEntryNotFound:
+			counter = NewEntry(counterDN)
+			counter.SetAttribute("objectClass", "top", "extensibleObject")
+			counter.SetAttribute("cn", "uidNext")
+		case err != nil:
+			return err
+		default:
+			next, err = strconv.Atoi(counter.GetFirstAttribute("uidNumber"))
+			if err != nil {
+				return NewHookError(ldap.ResultOperationsError,
+					fmt.Sprintf("invalid uidNumber in %s", counterDN))
+			}
+		}
+
+		op.Entry.SetAttribute("uidNumber", strconv.Itoa(next))
+		counter.SetAttribute("uidNumber", strconv.Itoa(next+1))
+		return op.Put(counter)
+	}
+}
+
+// hasObjectClass reports whether entry has the object class, ignoring case.
+func hasObjectClass(entry *Entry, class string) bool {
+	for _, oc := range entry.GetStringAttribute("objectClass") {
+		if strings.EqualFold(oc, class) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,312 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/KilimcininKorOglu/oba/internal/filter"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+)
+
+// AttrEntryVersion is the version of an entry, computed from its entryCSN.
+const AttrEntryVersion = "entryVersion"
+
+// ErrVirtualAttributeFilter is returned for a search filter on a virtual
+// attribute that cannot be filtered on.
+var ErrVirtualAttributeFilter = newError(ldap.ResultUnwillingToPerform, "cannot filter on a virtual attribute")
+
+// VirtualAttribute is an attribute whose values are computed when an entry
+// is read rather than stored. Adds and modifies that supply it are
+// rejected with ErrNoUserModification.
+type VirtualAttribute struct {
+	// Name is the name of the attribute.
+	Name string
+
+	// Compute returns the values of the attribute for entry, or nil if
+	// the entry has none. r reads the directory in the transaction of the
+	// read.
+	Compute func(r *VirtualReader, entry *Entry) []string
+
+	// Filterable allows search filters on the attribute. Its values are
+	// then computed for every candidate entry of such a search, so only
+	// cheap attributes should set it. Searches filtering on other virtual
+	// attributes fail with ErrVirtualAttributeFilter.
+	Filterable bool
+}
+
+// VirtualReader gives virtual attributes read access to the directory.
+// Its transaction is started on first use.
+type VirtualReader struct {
+	ctx context.Context
+	b   *ObaBackend
+	txn interface{}
+	own bool
+}
+
+// Context returns the context of the read.
+func (r *VirtualReader) Context() context.Context {
+	return r.ctx
+}
+
+// Get returns the entry dn.
+func (r *VirtualReader) Get(dn string) (*Entry, error) {
+	txn, err := r.begin()
+	if err != nil {
+		return nil, err
+	}
+	entry, err := r.b.engine.Get(txn, normalizeDN(dn))
+	if err != nil {
+		return nil, ErrEntryNotFound
+	}
+	return convertFromStorageEntry(entry), nil
+}
+
+// CountChildren returns the number of children of dn, counting at most
+// limit of them. Zero counts all children.
+func (r *VirtualReader) CountChildren(dn string, limit int) (int, error) {
+	txn, err := r.begin()
+	if err != nil {
+		return 0, err
+	}
+	return r.b.engine.CountChildren(txn, normalizeDN(dn), limit)
+}
+
+// begin returns the transaction of the reader.
+func (r *VirtualReader) begin() (interface{}, error) {
+	if r.txn == nil {
+		txn, err := r.b.begin(r.ctx)
+		if err != nil {
+			return nil, wrapStorageError(err)
+		}
+		r.txn = txn
+		r.own = true
+	}
+	return r.txn, nil
+}
+
+// close rolls back the transaction if the reader started it.
+func (r *VirtualReader) close() {
+	if r.own {
+		r.b.engine.Rollback(r.txn)
+		r.txn, r.own = nil, false
+	}
+}
+
+// builtinVirtualAttributes are computed by every backend.
+var builtinVirtualAttributes = []VirtualAttribute{
+	{Name: AttrEntryDN, Compute: computeEntryDN, Filterable: true},
+	{Name: AttrHasSubordinates, Compute: computeHasSubordinates},
+	{Name: AttrNumSubordinates, Compute: computeNumSubordinates},
+	{Name: AttrEntryVersion, Compute: computeEntryVersion},
+}
+
+// RegisterVirtualAttribute adds a virtual attribute to the backend. An
+// attribute registered under the name of an earlier one, built-in or not,
+// replaces it. Clients request registered attributes by name or with "+",
+// so they should be operational attributes of the schema.
+func (b *ObaBackend) RegisterVirtualAttribute(attr VirtualAttribute) {
+	if attr.Name == "" || attr.Compute == nil {
+		return
+	}
+
+	b.hooksMu.Lock()
+	defer b.hooksMu.Unlock()
+	for i, registered := range b.virtualAttrs {
+		if strings.EqualFold(registered.Name, attr.Name) {
+			b.virtualAttrs[i] = attr
+			return
+		}
+	}
+	b.virtualAttrs = append(b.virtualAttrs, attr)
+}
+
+// virtualAttributes returns the virtual attributes of the backend.
+func (b *ObaBackend) virtualAttributes() []VirtualAttribute {
+	b.hooksMu.RLock()
+	registered := b.virtualAttrs
+	b.hooksMu.RUnlock()
+
+	attrs := make([]VirtualAttribute, 0, len(builtinVirtualAttributes)+len(registered))
+	for _, attr := range builtinVirtualAttributes {
+		if findVirtualAttribute(registered, attr.Name) == nil {
+			attrs = append(attrs, attr)
+		}
+	}
+	return append(attrs, registered...)
+}
+
+// virtualAttribute returns the virtual attribute name, or nil if name is
+// stored. Attribute options are ignored.
+func (b *ObaBackend) virtualAttribute(name string) *VirtualAttribute {
+	return findVirtualAttribute(b.virtualAttributes(), name)
+}
+
+// findVirtualAttribute returns the attribute of attrs named name, or nil.
+func findVirtualAttribute(attrs []VirtualAttribute, name string) *VirtualAttribute {
+	base, _, _ := strings.Cut(strings.TrimSpace(name), ";")
+	for i := range attrs {
+		if strings.EqualFold(attrs[i].Name, base) {
+			return &attrs[i]
+		}
+	}
+	return nil
+}
+
+// VirtualAttributes returns entries with the virtual attributes set that
+// attrs, the attribute list of a search request, asks for by name or
+// with "+". Entries that get values are copies; the others are returned
+// as given.
+func (b *ObaBackend) VirtualAttributes(ctx context.Context, entries []*Entry, attrs []string) []*Entry {
+	var wanted []VirtualAttribute
+	for _, attr := range b.virtualAttributes() {
+		if requestsAttribute(attrs, attr.Name) {
+			wanted = append(wanted, attr)
+		}
+	}
+	if len(entries) == 0 || len(wanted) == 0 {
+		return entries
+	}
+
+	r := &VirtualReader{ctx: ctx, b: b}
+	defer r.close()
+
+	result := make([]*Entry, len(entries))
+	for i, entry := range entries {
+		derived := entry.Clone()
+		for _, attr := range wanted {
+			derived.DeleteAttribute(attr.Name)
+			if values := attr.Compute(r, entry); len(values) > 0 {
+				derived.SetAttribute(attr.Name, values...)
+			}
+		}
+		result[i] = derived
+	}
+	return result
+}
+
+// requestsAttribute reports whether the search attribute list attrs asks
+// for the operational attribute name, by name or with "+".
+func requestsAttribute(attrs []string, name string) bool {
+	for _, attr := range attrs {
+		attr = strings.TrimSpace(attr)
+		if attr == "+" {
+			return true
+		}
+		base, _, _ := strings.Cut(attr, ";")
+		if strings.EqualFold(base, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateVirtualAttributes rejects entries carrying virtual attributes.
+// Their values are computed, so clients cannot store them.
+func (b *ObaBackend) validateVirtualAttributes(entry *Entry) error {
+	for name := range entry.Attributes {
+		if attr := b.virtualAttribute(name); attr != nil {
+			return fmt.Errorf("%w: %s", ErrNoUserModification, attr.Name)
+		}
+	}
+	return nil
+}
+
+// validateVirtualChanges rejects modifications of virtual attributes.
+func (b *ObaBackend) validateVirtualChanges(changes []Modification) error {
+	for _, mod := range changes {
+		if attr := b.virtualAttribute(mod.Attribute); attr != nil {
+			return fmt.Errorf("%w: %s", ErrNoUserModification, attr.Name)
+		}
+	}
+	return nil
+}
+
+// dropVirtualAttributes removes stored values of virtual attributes from
+// an entry. Entries written before entryDN was computed still store it.
+func (b *ObaBackend) dropVirtualAttributes(entry *Entry) {
+	for _, attr := range b.virtualAttributes() {
+		entry.DeleteAttribute(attr.Name)
+	}
+}
+
+// virtualFilterAttributes returns the virtual attributes f filters on. It
+// fails with ErrVirtualAttributeFilter if one of them is not filterable.
+func (b *ObaBackend) virtualFilterAttributes(f *filter.Filter) ([]VirtualAttribute, error) {
+	attrs := b.virtualAttributes()
+	var used []VirtualAttribute
+	var walk func(f *filter.Filter) error
+	walk = func(f *filter.Filter) error {
+		if f == nil {
+			return nil
+		}
+		for _, child := range f.Children {
+			if err := walk(child); err != nil {
+				return err
+			}
+		}
+		if err := walk(f.Child); err != nil {
+			return err
+		}
+
+		name := f.Attribute
+		if f.Substring != nil {
+			name = f.Substring.Attribute
+		}
+		if f.Extensible != nil {
+			name = f.Extensible.Attribute
+		}
+		attr := findVirtualAttribute(attrs, name)
+		if attr == nil {
+			return nil
+		}
+		if !attr.Filterable {
+			return fmt.Errorf("%w: %s", ErrVirtualAttributeFilter, attr.Name)
+		}
+		if findVirtualAttribute(used, attr.Name) == nil {
+			used = append(used, *attr)
+		}
+		return nil
+	}
+	if err := walk(f); err != nil {
+		return nil, err
+	}
+	return used, nil
+}
+
+// entryDNAssertion returns the DN a filter requires with an equality
+// match on entryDN, at its top or in a top level AND, so that the search
+// can read that entry instead of scanning. It returns false otherwise.
+func entryDNAssertion(f *filter.Filter) (string, bool) {
+	if f == nil {
+		return "", false
+	}
+	switch f.Type {
+	case filter.FilterEquality:
+		base, _, _ := strings.Cut(f.Attribute, ";")
+		if strings.EqualFold(base, AttrEntryDN) {
+			return string(f.Value), true
+		}
+	case filter.FilterAnd:
+		for _, child := range f.Children {
+			if dn, ok := entryDNAssertion(child); ok {
+				return dn, true
+			}
+		}
+	}
+	return "", false
+}
+
+// computeEntryDN returns the DN of the entry.
+func computeEntryDN(_ *VirtualReader, entry *Entry) []string {
+	return []string{entry.DN}
+}
+
+// computeEntryVersion returns the entryCSN of the entry. Entries written
+// before entryCSN was maintained have no version.
+func computeEntryVersion(_ *VirtualReader, entry *Entry) []string {
+	if version := EntryVersion(entry); version != "" {
+		return []string{version}
+	}
+	return nil
+}
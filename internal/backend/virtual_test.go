@@ -0,0 +1,217 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/filter"
+)
+
+// searchDNs returns the sorted DNs of a search with the filter string.
+func searchDNs(t *testing.T, be *ObaBackend, baseDN string, scope int, filterStr string) []string {
+	t.Helper()
+
+	f, err := filter.Parse(filterStr)
+	if err != nil {
+		t.Fatalf("Parse(%s) error = %v", filterStr, err)
+	}
+	entries, err := be.Search(context.Background(), baseDN, scope, f)
+	if err != nil {
+		t.Fatalf("Search(%s) error = %v", filterStr, err)
+	}
+	dns := make([]string, len(entries))
+	for i, entry := range entries {
+		dns[i] = normalizeDN(entry.DN)
+	}
+	sort.Strings(dns)
+	return dns
+}
+
+func TestVirtualAttributesComputed(t *testing.T) {
+	be := openSubordinatesBackend(t)
+
+	entries, err := be.Search(context.Background(), "uid=user0,ou=users,dc=example,dc=com", 0, nil)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Search() = %d entries, error = %v", len(entries), err)
+	}
+	user := entries[0]
+	if user.HasAttribute(AttrEntryDN) {
+		t.Errorf("entryDN stored: %v", user.GetAttribute(AttrEntryDN))
+	}
+
+	derived := be.VirtualAttributes(context.Background(), entries, []string{"+"})[0]
+	if got := derived.GetFirstAttribute(AttrEntryDN); got != user.DN {
+		t.Errorf("entryDN = %q, want %q", got, user.DN)
+	}
+	if got, want := derived.GetFirstAttribute(AttrEntryVersion), user.GetFirstAttribute(AttrEntryCSN); got == "" || got != want {
+		t.Errorf("entryVersion = %q, want the entryCSN %q", got, want)
+	}
+
+	derived = be.VirtualAttributes(context.Background(), entries, []string{"cn"})[0]
+	if derived.HasAttribute(AttrEntryDN) || derived.HasAttribute(AttrEntryVersion) {
+		t.Error("virtual attributes computed although not requested")
+	}
+}
+
+func TestEntryDNFilter(t *testing.T) {
+	be := openSubordinatesBackend(t)
+
+	const user1 = "uid=user1,ou=users,dc=example,dc=com"
+	tests := []struct {
+		name   string
+		baseDN string
+		scope  int
+		filter string
+		want   []string
+	}{
+		{"equality", "dc=example,dc=com", 2, "(entryDN=" + user1 + ")", []string{user1}},
+		{"case insensitive", "dc=example,dc=com", 2, "(entryDN=UID=User1,OU=Users,DC=Example,DC=Com)", []string{user1}},
+		{"in and", "dc=example,dc=com", 2, "(&(objectClass=inetOrgPerson)(entryDN=" + user1 + "))", []string{user1}},
+		{"and not matching", "dc=example,dc=com", 2, "(&(uid=user2)(entryDN=" + user1 + "))", []string{}},
+		{"outside base", "ou=groups,dc=example,dc=com", 2, "(entryDN=" + user1 + ")", []string{}},
+		{"below one level", "dc=example,dc=com", 1, "(entryDN=" + user1 + ")", []string{}},
+		{"base scope", user1, 0, "(entryDN=" + user1 + ")", []string{user1}},
+		{"missing entry", "dc=example,dc=com", 2, "(entryDN=uid=nobody,dc=example,dc=com)", []string{}},
+		{"in or", "ou=users,dc=example,dc=com", 2, "(|(entryDN=" + user1 + ")(entryDN=uid=user2,ou=users,dc=example,dc=com))",
+			[]string{user1, "uid=user2,ou=users,dc=example,dc=com"}},
+		{"presence", "ou=users,dc=example,dc=com", 2, "(entryDN=*)",
+			[]string{"ou=users,dc=example,dc=com", "uid=user0,ou=users,dc=example,dc=com", user1, "uid=user2,ou=users,dc=example,dc=com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := searchDNs(t, be, tt.baseDN, tt.scope, tt.filter)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("Search() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVirtualAttributeFilterRejected(t *testing.T) {
+	be := openSubordinatesBackend(t)
+
+	for _, filterStr := range []string{
+		"(hasSubordinates=TRUE)",
+		"(&(objectClass=*)(!(numSubordinates>=1)))",
+		"(entryVersion=*)",
+	} {
+		f, _ := filter.Parse(filterStr)
+		_, err := be.Search(context.Background(), "dc=example,dc=com", 2, f)
+		if !errors.Is(err, ErrVirtualAttributeFilter) {
+			t.Errorf("Search(%s) error = %v, want ErrVirtualAttributeFilter", filterStr, err)
+		}
+	}
+}
+
+func TestVirtualAttributesNotStorable(t *testing.T) {
+	be := openSubordinatesBackend(t)
+
+	entry := NewEntry("uid=user9,ou=users,dc=example,dc=com")
+	entry.SetAttribute("objectClass", "inetOrgPerson")
+	entry.SetAttribute("uid", "user9")
+	entry.SetAttribute("cn", "User 9")
+	entry.SetAttribute("sn", "User")
+	entry.SetAttribute(AttrEntryDN, entry.DN)
+	if err := be.Add(context.Background(), entry); !errors.Is(err, ErrNoUserModification) {
+		t.Errorf("Add() error = %v, want ErrNoUserModification", err)
+	}
+
+	for _, mod := range []ModificationType{ModAdd, ModDelete, ModReplace} {
+		changes := []Modification{*NewModification(mod, AttrEntryDN, "uid=other,dc=example,dc=com")}
+		err := be.Modify(context.Background(), "uid=user0,ou=users,dc=example,dc=com", changes)
+		if !errors.Is(err, ErrNoUserModification) {
+			t.Errorf("Modify(%s) error = %v, want ErrNoUserModification", mod, err)
+		}
+	}
+}
+
+// TestStoredEntryDNDropped tests that the entryDN stored by entries
+// written before it was computed is not returned and is removed on write.
+func TestStoredEntryDNDropped(t *testing.T) {
+	be := openSubordinatesBackend(t)
+
+	const dn = "uid=user0,ou=users,dc=example,dc=com"
+	stored, err := be.GetEntry(dn)
+	if err != nil {
+		t.Fatalf("GetEntry() error = %v", err)
+	}
+	stored.SetStringAttribute(AttrEntryDN, "uid=stale,dc=example,dc=com")
+	txn, _ := be.engine.Begin()
+	if err := be.engine.Put(txn, stored); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := be.engine.Commit(txn); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	entries, err := be.Search(context.Background(), dn, 0, nil)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Search() = %d entries, error = %v", len(entries), err)
+	}
+	if entries[0].HasAttribute(AttrEntryDN) {
+		t.Errorf("search returned the stored entryDN %v", entries[0].GetAttribute(AttrEntryDN))
+	}
+	if got := searchDNs(t, be, "dc=example,dc=com", 2, "(entryDN=uid=stale,dc=example,dc=com)"); len(got) != 0 {
+		t.Errorf("filter matched the stored entryDN: %v", got)
+	}
+
+	changes := []Modification{*NewModification(ModReplace, "description", "updated")}
+	if err := be.Modify(context.Background(), dn, changes); err != nil {
+		t.Fatalf("Modify() error = %v", err)
+	}
+	stored, _ = be.GetEntry(dn)
+	if stored.HasAttribute(AttrEntryDN) {
+		t.Errorf("entryDN still stored after modify: %q", stored.GetAttribute(AttrEntryDN))
+	}
+}
+
+func TestRegisterVirtualAttribute(t *testing.T) {
+	be := openSubordinatesBackend(t)
+
+	// The RDN value of the parent, filterable
+	be.RegisterVirtualAttribute(VirtualAttribute{
+		Name: "parentName",
+		Compute: func(r *VirtualReader, entry *Entry) []string {
+			parent, err := r.Get(entry.DN[strings.Index(entry.DN, ",")+1:])
+			if err != nil {
+				return nil
+			}
+			_, value, _ := strings.Cut(strings.SplitN(parent.DN, ",", 2)[0], "=")
+			return []string{value}
+		},
+		Filterable: true,
+	})
+
+	entries, err := be.Search(context.Background(), "dc=example,dc=com", 2, nil)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	for _, entry := range be.VirtualAttributes(context.Background(), entries, []string{"parentName"}) {
+		if strings.HasPrefix(entry.DN, "uid=") && entry.GetFirstAttribute("parentName") != "users" {
+			t.Errorf("parentName of %s = %q, want users", entry.DN, entry.GetFirstAttribute("parentName"))
+		}
+	}
+
+	got := searchDNs(t, be, "dc=example,dc=com", 2, "(parentName=users)")
+	if len(got) != 3 {
+		t.Errorf("Search(parentName=users) = %v, want the three users", got)
+	}
+
+	changes := []Modification{*NewModification(ModReplace, "parentName", "groups")}
+	if err := be.Modify(context.Background(), "uid=user0,ou=users,dc=example,dc=com", changes); !errors.Is(err, ErrNoUserModification) {
+		t.Errorf("Modify() error = %v, want ErrNoUserModification", err)
+	}
+
+	// A registration replaces the built-in attribute of the same name
+	be.RegisterVirtualAttribute(VirtualAttribute{
+		Name:    "HASSUBORDINATES",
+		Compute: func(*VirtualReader, *Entry) []string { return []string{"UNKNOWN"} },
+	})
+	derived := be.VirtualAttributes(context.Background(), entries[:1], []string{"hasSubordinates"})[0]
+	if got := derived.GetFirstAttribute(AttrHasSubordinates); got != "UNKNOWN" {
+		t.Errorf("hasSubordinates = %q, want the registered value", got)
+	}
+}
@@ -2,6 +2,7 @@
 package backup
 
 import (
+	"crypto/ecdsa"
 	"encoding/binary"
 	"errors"
 	"hash/crc32"
@@ -17,8 +18,10 @@ const (
 	BackupMagicByte2 = 'A'
 	BackupMagicByte3 = 'B'
 
-	// BackupVersion is the current backup format version.
-	BackupVersion uint32 = 1
+	// BackupVersion is the current backup format version. Version 2
+	// backups hold pages in storage.PageFormatV1, version 1 backups in
+	// storage.PageFormatV0.
+	BackupVersion uint32 = 2
 
 	// BackupHeaderSize is the size of the backup header in bytes.
 	BackupHeaderSize = 64
@@ -42,6 +45,7 @@ var (
 	ErrBackupCorrupted   = errors.New("backup file is corrupted")
 	ErrImportFailed      = errors.New("import failed")
 	ErrExportFailed      = errors.New("export failed")
+	ErrSignNotSupported  = errors.New("only native backups can be signed")
 )
 
 // BackupFormat represents the backup file format.
@@ -74,6 +78,10 @@ type BackupOptions struct {
 
 	// BaseDN is the base DN for LDIF export (optional, defaults to root).
 	BaseDN string
+
+	// SignKey is the P-256 ECDSA key the backup header is signed with
+	// (optional). Only native backups can be signed.
+	SignKey *ecdsa.PrivateKey
 }
 
 // Validate validates the backup options.
@@ -90,6 +98,10 @@ func (o *BackupOptions) Validate() error {
 		return ErrUnsupportedFormat
 	}
 
+	if o.SignKey != nil && o.Format != FormatNative {
+		return ErrSignNotSupported
+	}
+
 	return nil
 }
 
@@ -107,6 +119,11 @@ type RestoreOptions struct {
 	// DataDir is the target directory for restored data.
 	// Used by RestoreManager for specifying the restore destination.
 	DataDir string
+
+	// VerifyKey is the P-256 ECDSA public key the backup signature is
+	// checked against (optional). When set, unsigned backups and backups
+	// with an invalid signature are rejected.
+	VerifyKey *ecdsa.PublicKey
 }
 
 // Validate validates the restore options.
@@ -137,6 +154,9 @@ func (o *RestoreOptions) Validate() error {
 //   - Bytes 32-39: EntryCount (uint64)
 //   - Bytes 40-43: Checksum (uint32, CRC32 of all page data)
 //   - Bytes 44-63: Reserved
+//
+// Signed headers, those with BackupFlagSigned set, are followed by the
+// SignatureSize bytes of their signature.
 type BackupHeader struct {
 	Magic      [4]byte
 	Version    uint32
@@ -147,6 +167,7 @@ type BackupHeader struct {
 	EntryCount uint64
 	Checksum   uint32
 	Reserved   [20]byte
+	Signature  [SignatureSize]byte
 }
 
 // Backup flags.
@@ -157,6 +178,8 @@ const (
 	BackupFlagIncremental
 	// BackupFlagMultiFile indicates the backup contains multiple files.
 	BackupFlagMultiFile
+	// BackupFlagSigned indicates the header is followed by a signature.
+	BackupFlagSigned
 )
 
 // Storage file names.
@@ -221,14 +244,53 @@ func (h *BackupHeader) SetMultiFile(multiFile bool) {
 	}
 }
 
+// IsSigned returns true if the header is followed by a signature.
+func (h *BackupHeader) IsSigned() bool {
+	return h.Flags&BackupFlagSigned != 0
+}
+
+// SetSigned sets the signed flag.
+func (h *BackupHeader) SetSigned(signed bool) {
+	if signed {
+		h.Flags |= BackupFlagSigned
+	} else {
+		h.Flags &^= BackupFlagSigned
+	}
+}
+
+// Size returns the size of the serialized header, including the signature
+// of a signed header.
+func (h *BackupHeader) Size() int {
+	if h.IsSigned() {
+		return BackupHeaderSize + SignatureSize
+	}
+	return BackupHeaderSize
+}
+
 // Serialize writes the backup header to a byte slice.
 func (h *BackupHeader) Serialize() ([]byte, error) {
-	buf := make([]byte, BackupHeaderSize)
+	buf := make([]byte, h.Size())
 	return buf, h.SerializeTo(buf)
 }
 
 // SerializeTo writes the backup header to an existing byte slice.
 func (h *BackupHeader) SerializeTo(buf []byte) error {
+	if len(buf) < h.Size() {
+		return ErrInvalidBackup
+	}
+
+	if err := h.serializeFields(buf); err != nil {
+		return err
+	}
+	if h.IsSigned() {
+		copy(buf[BackupHeaderSize:BackupHeaderSize+SignatureSize], h.Signature[:])
+	}
+	return nil
+}
+
+// serializeFields writes the fixed-size part of the header, without the
+// signature, to buf.
+func (h *BackupHeader) serializeFields(buf []byte) error {
 	if len(buf) < BackupHeaderSize {
 		return ErrInvalidBackup
 	}
@@ -267,7 +329,8 @@ func (h *BackupHeader) SerializeTo(buf []byte) error {
 	return nil
 }
 
-// Deserialize reads the backup header from a byte slice.
+// Deserialize reads the backup header from a byte slice. The signature of
+// a signed header is read if buf holds it.
 func (h *BackupHeader) Deserialize(buf []byte) error {
 	if len(buf) < BackupHeaderSize {
 		return ErrInvalidBackup
@@ -300,6 +363,12 @@ func (h *BackupHeader) Deserialize(buf []byte) error {
 	// Read reserved
 	copy(h.Reserved[:], buf[44:64])
 
+	// Read signature
+	h.Signature = [SignatureSize]byte{}
+	if h.IsSigned() && len(buf) >= BackupHeaderSize+SignatureSize {
+		copy(h.Signature[:], buf[BackupHeaderSize:BackupHeaderSize+SignatureSize])
+	}
+
 	return nil
 }
 
@@ -0,0 +1,243 @@
+package backup
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// Attributes read by the delta exporter. Stored attribute names are lower
+// case.
+const (
+	attrCreateTimestamp = "createtimestamp"
+	attrModifyTimestamp = "modifytimestamp"
+	// attrOriginalDN and attrDeleteTimestamp are set on the tombstones of
+	// the recycle bin.
+	attrOriginalDN      = "obaoriginaldn"
+	attrDeleteTimestamp = "obadeletetimestamp"
+
+	// recycleBinType and recycleBinValue make up the RDN of the recycle
+	// bin container, cn=Deleted Objects.
+	recycleBinType  = "cn"
+	recycleBinValue = "deleted objects"
+
+	// generalizedTimeLayout is the layout of the server's timestamps.
+	generalizedTimeLayout = "20060102150405Z"
+)
+
+// LDIFExportStats contains statistics about an LDIF export.
+type LDIFExportStats struct {
+	// Scanned is the number of entries read.
+	Scanned uint64
+
+	// Added, Modified and Deleted count the records written by change type.
+	Added    uint64
+	Modified uint64
+	Deleted  uint64
+
+	// Duration is the time taken to complete the export.
+	Duration time.Duration
+}
+
+// DeltaLDIFOptions configures a DeltaLDIFExporter.
+type DeltaLDIFOptions struct {
+	// BaseDN is the root of the exported subtree. Empty exports everything.
+	BaseDN string
+
+	// Since is the start of the exported period. Entries created or
+	// modified at or after Since are exported. Timestamps have a
+	// resolution of one second, so Since is rounded down to the second.
+	Since time.Time
+}
+
+// DeltaLDIFExporter exports the changes made since a point in time as LDIF
+// change records (RFC 2849 section 5), for incremental exports of large
+// directories:
+//
+//   - entries created since then become "changetype: add" records;
+//   - entries modified since then become "changetype: modify" records that
+//     replace every attribute of the entry;
+//   - tombstones in the recycle bin deleted since then become
+//     "changetype: delete" records of their original DN.
+//
+// Deletes are only exported while the recycle bin keeps the tombstones.
+// Entries are streamed from a single read transaction.
+type DeltaLDIFExporter struct {
+	engine storage.StorageEngine
+	opts   DeltaLDIFOptions
+}
+
+// NewDeltaLDIFExporter creates a new DeltaLDIFExporter with the given
+// storage engine and options.
+func NewDeltaLDIFExporter(engine storage.StorageEngine, opts DeltaLDIFOptions) *DeltaLDIFExporter {
+	return &DeltaLDIFExporter{
+		engine: engine,
+		opts:   opts,
+	}
+}
+
+// Export writes the change records to w.
+func (e *DeltaLDIFExporter) Export(w io.Writer) (*LDIFExportStats, error) {
+	if e.engine == nil {
+		return nil, ErrNilEngine
+	}
+
+	if w == nil {
+		return nil, ErrExportFailed
+	}
+
+	start := time.Now()
+	since := e.opts.Since.UTC().Truncate(time.Second)
+
+	tx, err := e.engine.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTransactionFail, err)
+	}
+	defer e.engine.Rollback(tx)
+
+	iter := e.engine.SearchByDN(tx, e.opts.BaseDN, storage.ScopeSubtree)
+	defer iter.Close()
+
+	stats := &LDIFExportStats{}
+	for iter.Next() {
+		entry := iter.Entry()
+		if entry == nil {
+			continue
+		}
+		stats.Scanned++
+
+		if err := e.exportEntry(w, entry, since, stats); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrExportFailed, err)
+		}
+	}
+
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrExportFailed, err)
+	}
+
+	stats.Duration = time.Since(start)
+	return stats, nil
+}
+
+// exportEntry writes the change record of entry, if it changed at or after
+// since.
+func (e *DeltaLDIFExporter) exportEntry(w io.Writer, entry *storage.Entry, since time.Time, stats *LDIFExportStats) error {
+	if originalDN := firstValue(entry, attrOriginalDN); originalDN != "" {
+		if !changedSince(entry, attrDeleteTimestamp, since) {
+			return nil
+		}
+		stats.Deleted++
+		return writeDeleteRecord(w, originalDN)
+	}
+	if isRecycleBin(entry.DN) {
+		return nil
+	}
+
+	switch {
+	case changedSince(entry, attrCreateTimestamp, since):
+		stats.Added++
+		return writeAddRecord(w, entry)
+	case changedSince(entry, attrModifyTimestamp, since):
+		stats.Modified++
+		return writeModifyRecord(w, entry)
+	}
+	return nil
+}
+
+// changedSince reports whether the timestamp attr of entry is at or after
+// since. Entries without the timestamp are not exported.
+func changedSince(entry *storage.Entry, attr string, since time.Time) bool {
+	t, err := time.Parse(generalizedTimeLayout, firstValue(entry, attr))
+	return err == nil && !t.Before(since)
+}
+
+// firstValue returns the first value of attr in entry, or "".
+func firstValue(entry *storage.Entry, attr string) string {
+	if values := entry.Attributes[attr]; len(values) > 0 {
+		return string(values[0])
+	}
+	return ""
+}
+
+// isRecycleBin reports whether dn is the recycle bin container.
+func isRecycleBin(dn string) bool {
+	rdns, err := ldap.SplitDN(dn)
+	if err != nil || len(rdns) == 0 {
+		return false
+	}
+	avas, err := ldap.ParseRDN(rdns[0])
+	if err != nil || len(avas) != 1 {
+		return false
+	}
+	return strings.EqualFold(avas[0].Type, recycleBinType) && strings.EqualFold(avas[0].Value, recycleBinValue)
+}
+
+// writeAddRecord writes entry as a "changetype: add" record.
+func writeAddRecord(w io.Writer, entry *storage.Entry) error {
+	if err := writeValue(w, "dn", []byte(entry.DN)); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(w, "changetype: add"); err != nil {
+		return err
+	}
+	for _, attr := range getSortedAttributeNames(entry.Attributes) {
+		for _, value := range entry.Attributes[attr] {
+			if err := writeValue(w, attr, value); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// writeModifyRecord writes entry as a "changetype: modify" record that
+// replaces each of its attributes.
+func writeModifyRecord(w io.Writer, entry *storage.Entry) error {
+	if err := writeValue(w, "dn", []byte(entry.DN)); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(w, "changetype: modify"); err != nil {
+		return err
+	}
+	for _, attr := range getSortedAttributeNames(entry.Attributes) {
+		if _, err := fmt.Fprintf(w, "replace: %s\n", attr); err != nil {
+			return err
+		}
+		for _, value := range entry.Attributes[attr] {
+			if err := writeValue(w, attr, value); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w, "-"); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// writeDeleteRecord writes a "changetype: delete" record for dn.
+func writeDeleteRecord(w io.Writer, dn string) error {
+	if err := writeValue(w, "dn", []byte(dn)); err != nil {
+		return err
+	}
+	_, err := fmt.Fprint(w, "changetype: delete\n\n")
+	return err
+}
+
+// writeValue writes one "name: value" line, base64-encoding the value if
+// needed.
+func writeValue(w io.Writer, name string, value []byte) error {
+	if needsBase64Encoding(value) {
+		_, err := fmt.Fprintf(w, "%s:: %s\n", name, base64.StdEncoding.EncodeToString(value))
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%s: %s\n", name, value)
+	return err
+}
@@ -0,0 +1,183 @@
+package backup
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+// putEntries stores entries in db in one transaction.
+func putEntries(t *testing.T, db *engine.ObaDB, entries ...*storage.Entry) {
+	t.Helper()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	for _, entry := range entries {
+		if err := db.Put(tx, entry); err != nil {
+			db.Rollback(tx)
+			t.Fatalf("Failed to put entry %s: %v", entry.DN, err)
+		}
+	}
+	if err := db.Commit(tx); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+}
+
+// stampedEntry returns a test entry created and modified at the given times.
+func stampedEntry(dn string, created, modified time.Time) *storage.Entry {
+	entry := createTestEntry(dn, "person", dn)
+	entry.SetStringAttribute(attrCreateTimestamp, created.UTC().Format(generalizedTimeLayout))
+	entry.SetStringAttribute(attrModifyTimestamp, modified.UTC().Format(generalizedTimeLayout))
+	return entry
+}
+
+// recordDNs returns the DN and change type of every record in ldif.
+func recordDNs(ldif string) map[string]string {
+	records := make(map[string]string)
+	for _, record := range strings.Split(strings.TrimSpace(ldif), "\n\n") {
+		lines := strings.Split(record, "\n")
+		if len(lines) < 2 {
+			continue
+		}
+		records[strings.TrimPrefix(lines[0], "dn: ")] = strings.TrimPrefix(lines[1], "changetype: ")
+	}
+	return records
+}
+
+// TestDeltaLDIFExporterModified tests that after modifying 10 of 100
+// entries, the delta export contains exactly those 10.
+func TestDeltaLDIFExporterModified(t *testing.T) {
+	db, err := engine.Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	since := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	modified := time.Date(2026, 2, 2, 12, 0, 0, 0, time.UTC)
+
+	base := stampedEntry("dc=example,dc=com", created, created)
+	entries := []*storage.Entry{base}
+	for i := 0; i < 100; i++ {
+		entries = append(entries, stampedEntry(fmt.Sprintf("uid=user%d,dc=example,dc=com", i), created, created))
+	}
+	putEntries(t, db, entries...)
+
+	var changed []*storage.Entry
+	for i := 0; i < 100; i += 10 {
+		entry := stampedEntry(fmt.Sprintf("uid=user%d,dc=example,dc=com", i), created, modified)
+		entry.SetStringAttribute("description", "changed")
+		changed = append(changed, entry)
+	}
+	putEntries(t, db, changed...)
+
+	var buf bytes.Buffer
+	stats, err := NewDeltaLDIFExporter(db, DeltaLDIFOptions{BaseDN: "dc=example,dc=com", Since: since}).Export(&buf)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	if stats.Scanned != 101 || stats.Modified != 10 || stats.Added != 0 || stats.Deleted != 0 {
+		t.Errorf("stats = %+v, want 101 scanned and 10 modified", stats)
+	}
+	records := recordDNs(buf.String())
+	if len(records) != 10 {
+		t.Fatalf("got %d records, want 10:\n%s", len(records), buf.String())
+	}
+	for _, entry := range changed {
+		if records[entry.DN] != "modify" {
+			t.Errorf("record of %s = %q, want modify", entry.DN, records[entry.DN])
+		}
+	}
+	if !strings.Contains(buf.String(), "replace: description\ndescription: changed\n-\n") {
+		t.Errorf("expected a replace of description:\n%s", buf.String())
+	}
+}
+
+// TestDeltaLDIFExporterChangeTypes tests that new entries become add
+// records and recycle bin tombstones delete records.
+func TestDeltaLDIFExporterChangeTypes(t *testing.T) {
+	db, err := engine.Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	old := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	since := time.Date(2026, 2, 1, 10, 30, 15, 500, time.UTC)
+	recent := time.Date(2026, 2, 1, 10, 30, 15, 0, time.UTC)
+
+	bin := stampedEntry("cn=deleted objects,dc=example,dc=com", recent, recent)
+	oldTombstone := stampedEntry("entryUUID=1,cn=deleted objects,dc=example,dc=com", old, old)
+	oldTombstone.SetStringAttribute(attrOriginalDN, "uid=old,dc=example,dc=com")
+	oldTombstone.SetStringAttribute(attrDeleteTimestamp, old.Format(generalizedTimeLayout))
+	tombstone := stampedEntry("entryUUID=2,cn=deleted objects,dc=example,dc=com", old, old)
+	tombstone.SetStringAttribute(attrOriginalDN, "uid=gone,dc=example,dc=com")
+	tombstone.SetStringAttribute(attrDeleteTimestamp, recent.Format(generalizedTimeLayout))
+
+	putEntries(t, db,
+		stampedEntry("dc=example,dc=com", old, old),
+		stampedEntry("uid=new,dc=example,dc=com", recent, recent),
+		bin, oldTombstone, tombstone,
+	)
+
+	var buf bytes.Buffer
+	stats, err := NewDeltaLDIFExporter(db, DeltaLDIFOptions{Since: since}).Export(&buf)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	if stats.Added != 1 || stats.Modified != 0 || stats.Deleted != 1 {
+		t.Errorf("stats = %+v, want 1 added and 1 deleted", stats)
+	}
+	records := recordDNs(buf.String())
+	want := map[string]string{
+		"uid=new,dc=example,dc=com":  "add",
+		"uid=gone,dc=example,dc=com": "delete",
+	}
+	if len(records) != len(want) {
+		t.Fatalf("records = %v, want %v", records, want)
+	}
+	for dn, changeType := range want {
+		if records[dn] != changeType {
+			t.Errorf("record of %s = %q, want %q", dn, records[dn], changeType)
+		}
+	}
+}
+
+// TestDeltaLDIFExporterNilEngine tests the delta exporter with a nil engine.
+func TestIsRecycleBin(t *testing.T) {
+	tests := []struct {
+		dn   string
+		want bool
+	}{
+		{"cn=Deleted Objects,dc=example,dc=com", true},
+		{"CN = deleted objects , dc=example,dc=com", true},
+		{`cn=deleted\20objects,dc=example,dc=com`, true},
+		{"cn=Deleted Objects", true},
+		{`cn=deleted objects\,x,dc=example,dc=com`, false},
+		{"cn=deleted objects+uid=x,dc=example,dc=com", false},
+		{"uid=deleted objects,dc=example,dc=com", false},
+		{"uid=alice,cn=Deleted Objects,dc=example,dc=com", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isRecycleBin(tt.dn); got != tt.want {
+			t.Errorf("isRecycleBin(%q) = %v, want %v", tt.dn, got, tt.want)
+		}
+	}
+}
+
+func TestDeltaLDIFExporterNilEngine(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := NewDeltaLDIFExporter(nil, DeltaLDIFOptions{}).Export(&buf); err != ErrNilEngine {
+		t.Errorf("Export() error = %v, want %v", err, ErrNilEngine)
+	}
+}
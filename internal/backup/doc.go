@@ -47,6 +47,15 @@
 //
 //	stats, err := backup.Full(engine, opts)
 //
+// Export only the changes made since the last export, as LDIF change
+// records (add, modify and delete):
+//
+//	exporter := backup.NewDeltaLDIFExporter(engine, backup.DeltaLDIFOptions{
+//	    BaseDN: "dc=example,dc=com",
+//	    Since:  lastExport,
+//	})
+//	stats, err := exporter.Export(w)
+//
 // # Restoring Backups
 //
 // Restore from a backup:
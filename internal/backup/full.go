@@ -84,6 +84,7 @@ func (bm *BackupManager) fullBackup(opts *BackupOptions) (*BackupStats, error) {
 	header.TotalPages = totalPages
 	header.SetCompressed(opts.Compress)
 	header.SetIncremental(opts.Incremental)
+	header.SetSigned(opts.SignKey != nil)
 
 	// Write placeholder header (will update checksum at end)
 	headerBuf, err := header.Serialize()
@@ -141,6 +142,11 @@ func (bm *BackupManager) fullBackup(opts *BackupOptions) (*BackupStats, error) {
 	// Update header with checksum
 	header.Checksum = checksumWriter.Checksum()
 	header.EntryCount = stats.EntryCount
+	if opts.SignKey != nil {
+		if err := signHeader(header, opts.SignKey); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBackupFailed, err)
+		}
+	}
 
 	// Seek back to beginning and rewrite header
 	if _, err := out.Seek(0, io.SeekStart); err != nil {
@@ -266,18 +272,19 @@ func (bm *BackupManager) fullRestore(opts *RestoreOptions) (*BackupStats, error)
 	defer in.Close()
 
 	// Read and validate header
-	headerBuf := make([]byte, BackupHeaderSize)
-	if _, err := io.ReadFull(in, headerBuf); err != nil {
+	header, err := readBackupHeader(in)
+	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrRestoreFailed, err)
 	}
 
-	header := &BackupHeader{}
-	if err := header.Deserialize(headerBuf); err != nil {
+	if err := header.Validate(); err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrRestoreFailed, err)
 	}
 
-	if err := header.Validate(); err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrRestoreFailed, err)
+	if opts.VerifyKey != nil {
+		if err := verifyHeaderSignature(header, opts.VerifyKey); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrRestoreFailed, err)
+		}
 	}
 
 	// Setup reader (with optional decompression)
@@ -313,7 +320,7 @@ func (bm *BackupManager) fullRestore(opts *RestoreOptions) (*BackupStats, error)
 		}
 
 		// Seek back to start of data
-		if _, err := in.Seek(BackupHeaderSize, io.SeekStart); err != nil {
+		if _, err := in.Seek(int64(header.Size()), io.SeekStart); err != nil {
 			return nil, fmt.Errorf("%w: %v", ErrRestoreFailed, err)
 		}
 
@@ -435,13 +442,8 @@ func (bm *BackupManager) VerifyBackup(path string) error {
 	defer in.Close()
 
 	// Read header
-	headerBuf := make([]byte, BackupHeaderSize)
-	if _, err := io.ReadFull(in, headerBuf); err != nil {
-		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
-	}
-
-	header := &BackupHeader{}
-	if err := header.Deserialize(headerBuf); err != nil {
+	header, err := readBackupHeader(in)
+	if err != nil {
 		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
 	}
 
@@ -490,13 +492,8 @@ func (bm *BackupManager) GetBackupInfo(path string) (*BackupHeader, error) {
 	defer in.Close()
 
 	// Read header
-	headerBuf := make([]byte, BackupHeaderSize)
-	if _, err := io.ReadFull(in, headerBuf); err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
-	}
-
-	header := &BackupHeader{}
-	if err := header.Deserialize(headerBuf); err != nil {
+	header, err := readBackupHeader(in)
+	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
 	}
 
@@ -524,6 +521,7 @@ func (bm *BackupManager) directoryBackup(opts *BackupOptions) (*BackupStats, err
 	header := NewBackupHeader()
 	header.SetCompressed(opts.Compress)
 	header.SetMultiFile(true)
+	header.SetSigned(opts.SignKey != nil)
 
 	// Write placeholder header
 	headerBuf, err := header.Serialize()
@@ -614,6 +612,11 @@ func (bm *BackupManager) directoryBackup(opts *BackupOptions) (*BackupStats, err
 	// Update header with checksum
 	header.Checksum = checksumWriter.Checksum()
 	header.TotalPages = uint64(fileCount)
+	if opts.SignKey != nil {
+		if err := signHeader(header, opts.SignKey); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBackupFailed, err)
+		}
+	}
 
 	// Seek back and write final header
 	if _, err := out.Seek(0, io.SeekStart); err != nil {
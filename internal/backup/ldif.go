@@ -155,6 +155,11 @@ func needsBase64Encoding(value []byte) bool {
 		return true
 	}
 
+	// A trailing space would be lost by readers that trim lines
+	if value[len(value)-1] == ' ' {
+		return true
+	}
+
 	// Check all characters
 	for _, b := range value {
 		// NUL character
@@ -291,6 +296,11 @@ func (i *LDIFImporter) Parse(r io.Reader) ([]*storage.Entry, error) {
 			continue
 		}
 
+		// Skip the separators between the changes of a modify record
+		if line == "-" {
+			continue
+		}
+
 		// Empty line marks end of entry
 		if line == "" {
 			if entry != nil {
@@ -4,6 +4,7 @@ package backup
 import (
 	"bytes"
 	"encoding/base64"
+	"fmt"
 	"strings"
 	"testing"
 
@@ -68,6 +69,11 @@ func TestNeedsBase64Encoding(t *testing.T) {
 			value:    []byte{0x00, 0x01, 0x02, 0xFF},
 			expected: true,
 		},
+		{
+			name:     "ends with space",
+			value:    []byte("hello "),
+			expected: true,
+		},
 		{
 			name:     "all printable ASCII",
 			value:    []byte("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"),
@@ -240,6 +246,33 @@ cn: Test User
 	}
 }
 
+// TestParseLDIFModifyRecord tests parsing a modify change record with
+// separators.
+func TestParseLDIFModifyRecord(t *testing.T) {
+	ldif := `dn: uid=test,dc=example,dc=com
+changetype: modify
+replace: cn
+cn: Test User
+-
+add: mail
+mail: test@example.com
+-
+
+`
+
+	entries, err := ParseLDIF(strings.NewReader(ldif))
+	if err != nil {
+		t.Fatalf("ParseLDIF failed: %v", err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	if got := string(entries[0].Attributes["mail"][0]); got != "test@example.com" {
+		t.Errorf("mail = %q, want %q", got, "test@example.com")
+	}
+}
+
 // TestParseLDIFNoTrailingNewline tests parsing LDIF without trailing newline.
 func TestParseLDIFNoTrailingNewline(t *testing.T) {
 	ldif := `dn: uid=test,dc=example,dc=com
@@ -745,6 +778,34 @@ func TestParseLDIFOnlyComments(t *testing.T) {
 	}
 }
 
+// BenchmarkLDIFImport benchmarks importing account entries from LDIF, one
+// transaction per entry. The time per operation is the time per entry.
+func BenchmarkLDIFImport(b *testing.B) {
+	var ldif bytes.Buffer
+	for i := 0; i < b.N; i++ {
+		fmt.Fprintf(&ldif, "dn: uid=user%d,ou=people,dc=example,dc=com\n", i)
+		ldif.WriteString("objectClass: top\nobjectClass: account\nobjectClass: extensibleObject\n")
+		fmt.Fprintf(&ldif, "uid: user%d\ncn: User %d\nmail: user%d@example.com\n\n", i, i, i)
+	}
+
+	db, err := engine.Open(b.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		b.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	b.SetBytes(int64(ldif.Len() / b.N))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	if err := NewLDIFImporter(db).Import(&ldif); err != nil {
+		b.Fatalf("Import failed: %v", err)
+	}
+
+	b.StopTimer()
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "entries/s")
+}
+
 // Helper function to create test entries.
 func createTestEntry(dn, objectClass, cn string) *storage.Entry {
 	entry := storage.NewEntry(dn)
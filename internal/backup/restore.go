@@ -115,18 +115,19 @@ func (rm *RestoreManager) restoreFull(in *os.File, opts *RestoreOptions, dataDir
 	}
 
 	// Read and validate header
-	headerBuf := make([]byte, BackupHeaderSize)
-	if _, err := io.ReadFull(in, headerBuf); err != nil {
+	header, err := readBackupHeader(in)
+	if err != nil {
 		return nil, fmt.Errorf("%w: failed to read header: %v", ErrRestoreFailed, err)
 	}
 
-	header := &BackupHeader{}
-	if err := header.Deserialize(headerBuf); err != nil {
+	if err := header.Validate(); err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrRestoreFailed, err)
 	}
 
-	if err := header.Validate(); err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrRestoreFailed, err)
+	if opts.VerifyKey != nil {
+		if err := verifyHeaderSignature(header, opts.VerifyKey); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrRestoreFailed, err)
+		}
 	}
 
 	// Check if this is a multi-file backup
@@ -149,7 +150,7 @@ func (rm *RestoreManager) restoreFull(in *os.File, opts *RestoreOptions, dataDir
 		}
 
 		// Seek back to start of data
-		if _, err := in.Seek(BackupHeaderSize, io.SeekStart); err != nil {
+		if _, err := in.Seek(int64(header.Size()), io.SeekStart); err != nil {
 			return nil, fmt.Errorf("%w: %v", ErrRestoreFailed, err)
 		}
 
@@ -178,6 +179,9 @@ func (rm *RestoreManager) restoreFull(in *os.File, opts *RestoreOptions, dataDir
 	fileHeader := storage.NewFileHeader()
 	fileHeader.PageSize = header.PageSize
 	fileHeader.TotalPages = header.TotalPages + 1 // +1 for header page
+	if err := fileHeader.SetComponentVersion(storage.PageFormatComponent, pageFormat(header.Version)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRestoreFailed, err)
+	}
 
 	headerBytes, err := fileHeader.Serialize()
 	if err != nil {
@@ -287,6 +291,12 @@ func (rm *RestoreManager) restoreIncremental(in *os.File, opts *RestoreOptions,
 	}
 	defer out.Close()
 
+	// The pages are written as they are, so the database must be in the
+	// page format of the backup
+	if err := checkPageFormat(out, header.Version); err != nil {
+		return nil, err
+	}
+
 	// Restore pages
 	pageSize := int(header.PageSize)
 	pageIDSize := 8
@@ -334,7 +344,7 @@ func (rm *RestoreManager) restoreIncremental(in *os.File, opts *RestoreOptions,
 // verifyFullBackupChecksum verifies the checksum of a full backup.
 func (rm *RestoreManager) verifyFullBackupChecksum(in *os.File, header *BackupHeader) error {
 	// Seek to start of data
-	if _, err := in.Seek(BackupHeaderSize, io.SeekStart); err != nil {
+	if _, err := in.Seek(int64(header.Size()), io.SeekStart); err != nil {
 		return fmt.Errorf("%w: %v", ErrRestoreFailed, err)
 	}
 
@@ -438,10 +448,13 @@ func (rm *RestoreManager) RestoreChain(backups []string, opts *RestoreOptions) (
 	}
 
 	// First backup must be a full backup
+	// Only full backups are signed, so the signature of the first backup
+	// is the only one checked
 	firstOpts := &RestoreOptions{
 		InputPath: backups[0],
 		Verify:    opts.Verify,
 		DataDir:   dataDir,
+		VerifyKey: opts.VerifyKey,
 	}
 
 	// Verify first backup is a full backup
@@ -546,13 +559,8 @@ func (rm *RestoreManager) VerifyBackup(path string) error {
 // verifyFullBackup verifies a full backup file.
 func (rm *RestoreManager) verifyFullBackup(in *os.File) error {
 	// Read header
-	headerBuf := make([]byte, BackupHeaderSize)
-	if _, err := io.ReadFull(in, headerBuf); err != nil {
-		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
-	}
-
-	header := &BackupHeader{}
-	if err := header.Deserialize(headerBuf); err != nil {
+	header, err := readBackupHeader(in)
+	if err != nil {
 		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
 	}
 
@@ -598,13 +606,8 @@ func (rm *RestoreManager) GetBackupInfo(path string) (interface{}, error) {
 
 	switch backupType {
 	case "full":
-		headerBuf := make([]byte, BackupHeaderSize)
-		if _, err := io.ReadFull(in, headerBuf); err != nil {
-			return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
-		}
-
-		header := &BackupHeader{}
-		if err := header.Deserialize(headerBuf); err != nil {
+		header, err := readBackupHeader(in)
+		if err != nil {
 			return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
 		}
 
@@ -914,3 +917,34 @@ func (rm *RestoreManager) restoreMultiFile(in *os.File, opts *RestoreOptions, da
 	stats.Duration = time.Since(startTime)
 	return stats, nil
 }
+
+// pageFormat returns the format of the pages in a backup of the given
+// version.
+func pageFormat(version uint32) uint32 {
+	if version < 2 {
+		return storage.PageFormatV0
+	}
+	return storage.PageFormatV1
+}
+
+// checkPageFormat returns an error if the data file f is not in the page
+// format of a backup of the given version.
+func checkPageFormat(f *os.File, version uint32) error {
+	buf := make([]byte, storage.FileHeaderSize)
+	if _, err := f.ReadAt(buf, 0); err != nil {
+		return fmt.Errorf("%w: failed to read data file header: %v", ErrRestoreFailed, err)
+	}
+	fileHeader := &storage.FileHeader{}
+	if err := fileHeader.DeserializeAndValidate(buf); err != nil {
+		return fmt.Errorf("%w: %v", ErrRestoreFailed, err)
+	}
+	versions, err := fileHeader.ComponentVersions()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRestoreFailed, err)
+	}
+	if format := versions[storage.PageFormatComponent]; format != pageFormat(version) {
+		return fmt.Errorf("%w: database pages are in format %d, backup pages in format %d; restore a full backup first",
+			ErrRestoreFailed, format, pageFormat(version))
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// BackupInfo describes a native backup file found by ScanBackupDir.
+type BackupInfo struct {
+	// Path is the path of the backup file.
+	Path string
+
+	// Size is the size of the file in bytes.
+	Size int64
+
+	// Header is the header of the backup.
+	Header *BackupHeader
+}
+
+// Created returns the time the backup was taken.
+func (i *BackupInfo) Created() time.Time {
+	return time.Unix(i.Header.Timestamp, 0)
+}
+
+// Type returns "incr" for incremental backups and "full" for others.
+func (i *BackupInfo) Type() string {
+	if i.Header.IsIncremental() {
+		return "incr"
+	}
+	return "full"
+}
+
+// IsExpired reports whether the backup is older than retentionDays. Backups
+// never expire if retentionDays is not positive.
+func (i *BackupInfo) IsExpired(retentionDays int) bool {
+	if retentionDays <= 0 {
+		return false
+	}
+	return time.Since(i.Created()) > time.Duration(retentionDays)*24*time.Hour
+}
+
+// ScanBackupDir returns the native backups in dir, oldest first. Only the
+// header of each file is read. Files without a backup header, such as LDIF
+// backups, are skipped. Subdirectories are not scanned.
+func ScanBackupDir(dir string) ([]*BackupInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []*BackupInfo
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		info, err := readBackupInfo(filepath.Join(dir, entry.Name()))
+		if errors.Is(err, ErrInvalidBackup) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+
+	sort.SliceStable(infos, func(a, b int) bool {
+		return infos[a].Header.Timestamp < infos[b].Header.Timestamp
+	})
+	return infos, nil
+}
+
+// readBackupInfo reads the header of the backup at path. It returns
+// ErrInvalidBackup if the file is not a native backup.
+func readBackupInfo(path string) (*BackupInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := readBackupHeader(f)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, ErrInvalidBackup
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := header.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+
+	return &BackupInfo{Path: path, Size: stat.Size(), Header: header}, nil
+}
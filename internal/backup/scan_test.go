@@ -0,0 +1,119 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScanBackupDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "data")
+	backupDir := filepath.Join(tmpDir, "backups")
+	for _, dir := range []string{dataDir, backupDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dataDir, "data.oba"), []byte("backup data"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	backups := []struct {
+		name     string
+		compress bool
+		signed   bool
+	}{
+		{"a.oba", false, false},
+		{"b.oba", true, false},
+		{"c.bak", false, true},
+	}
+	for _, b := range backups {
+		opts := &BackupOptions{
+			OutputPath: filepath.Join(backupDir, b.name),
+			DataDir:    dataDir,
+			Compress:   b.compress,
+		}
+		if b.signed {
+			opts.SignKey = newTestSigningKey(t)
+		}
+		if _, err := NewBackupManager(nil).Backup(opts); err != nil {
+			t.Fatalf("Backup(%s) error = %v", b.name, err)
+		}
+	}
+
+	// Files that are not native backups are skipped
+	if err := os.WriteFile(filepath.Join(backupDir, "export.ldif"), []byte("dn: dc=example,dc=com\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(backupDir, "empty.oba"), nil, 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(backupDir, "old"), 0755); err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+
+	infos, err := ScanBackupDir(backupDir)
+	if err != nil {
+		t.Fatalf("ScanBackupDir() error = %v", err)
+	}
+	if len(infos) != len(backups) {
+		t.Fatalf("ScanBackupDir() found %d backups, want %d", len(infos), len(backups))
+	}
+
+	for i, b := range backups {
+		info := infos[i]
+		if got := filepath.Base(info.Path); got != b.name {
+			t.Errorf("infos[%d] = %s, want %s", i, got, b.name)
+		}
+		if info.Header.IsCompressed() != b.compress {
+			t.Errorf("%s: IsCompressed() = %v, want %v", b.name, info.Header.IsCompressed(), b.compress)
+		}
+		if info.Header.IsSigned() != b.signed {
+			t.Errorf("%s: IsSigned() = %v, want %v", b.name, info.Header.IsSigned(), b.signed)
+		}
+		if info.Type() != "full" {
+			t.Errorf("%s: Type() = %s, want full", b.name, info.Type())
+		}
+		stat, err := os.Stat(info.Path)
+		if err != nil {
+			t.Fatalf("Stat() error = %v", err)
+		}
+		if info.Size != stat.Size() {
+			t.Errorf("%s: Size = %d, want %d", b.name, info.Size, stat.Size())
+		}
+		if time.Since(info.Created()) > time.Minute {
+			t.Errorf("%s: Created() = %v, want about now", b.name, info.Created())
+		}
+	}
+
+	if _, err := ScanBackupDir(filepath.Join(tmpDir, "missing")); err == nil {
+		t.Error("ScanBackupDir() of a missing directory should fail")
+	}
+}
+
+func TestBackupInfoIsExpired(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name          string
+		created       time.Time
+		retentionDays int
+		want          bool
+	}{
+		{"recent", now.Add(-time.Hour), 7, false},
+		{"old", now.Add(-8 * 24 * time.Hour), 7, true},
+		{"no retention", now.Add(-365 * 24 * time.Hour), 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := NewBackupHeader()
+			header.Timestamp = tt.created.Unix()
+			info := &BackupInfo{Header: header}
+			if got := info.IsExpired(tt.retentionDays); got != tt.want {
+				t.Errorf("IsExpired(%d) = %v, want %v", tt.retentionDays, got, tt.want)
+			}
+		})
+	}
+}
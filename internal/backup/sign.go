@@ -0,0 +1,205 @@
+// Package backup provides backup and restore functionality for ObaDB.
+package backup
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+)
+
+// SignatureSize is the size of a backup signature in bytes: the r and s
+// values of a P-256 ECDSA signature, 32 bytes each, big-endian.
+const SignatureSize = 64
+
+// Signature errors.
+var (
+	ErrBackupNotSigned   = errors.New("backup is not signed")
+	ErrInvalidSignature  = errors.New("backup signature is invalid")
+	ErrUnsupportedKey    = errors.New("signing key must be a P-256 ECDSA key")
+	ErrSigningKeyInvalid = errors.New("invalid signing key file")
+)
+
+// Signer signs backup headers with an ECDSA P-256 private key.
+//
+// The signature covers the serialized header, which holds the checksum of
+// the backup data, so replacing either the header or the data of a signed
+// backup is detected when it is verified.
+type Signer struct {
+	key *ecdsa.PrivateKey
+}
+
+// NewSigner creates a new Signer with the given private key.
+func NewSigner(privateKey *ecdsa.PrivateKey) *Signer {
+	return &Signer{key: privateKey}
+}
+
+// Sign returns the signature of header. The signature field of the header
+// is not part of the signed data.
+func (s *Signer) Sign(header *BackupHeader) ([]byte, error) {
+	if s.key == nil || s.key.Curve != elliptic.P256() {
+		return nil, ErrUnsupportedKey
+	}
+
+	digest, err := headerDigest(header)
+	if err != nil {
+		return nil, err
+	}
+
+	r, sv, err := ecdsa.Sign(rand.Reader, s.key, digest)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBackupFailed, err)
+	}
+
+	sig := make([]byte, SignatureSize)
+	r.FillBytes(sig[:SignatureSize/2])
+	sv.FillBytes(sig[SignatureSize/2:])
+	return sig, nil
+}
+
+// Verifier verifies the signatures of backup headers.
+type Verifier struct{}
+
+// Verify checks that sig is a valid signature of header made with the
+// private key of publicKey.
+func (v *Verifier) Verify(header *BackupHeader, sig []byte, publicKey *ecdsa.PublicKey) error {
+	if publicKey == nil || publicKey.Curve != elliptic.P256() {
+		return ErrUnsupportedKey
+	}
+	if len(sig) != SignatureSize {
+		return ErrInvalidSignature
+	}
+
+	digest, err := headerDigest(header)
+	if err != nil {
+		return err
+	}
+
+	r := new(big.Int).SetBytes(sig[:SignatureSize/2])
+	s := new(big.Int).SetBytes(sig[SignatureSize/2:])
+	if !ecdsa.Verify(publicKey, digest, r, s) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// signHeader signs header with key and stores the signature in it. The
+// header must already have BackupFlagSigned set.
+func signHeader(header *BackupHeader, key *ecdsa.PrivateKey) error {
+	sig, err := NewSigner(key).Sign(header)
+	if err != nil {
+		return err
+	}
+	copy(header.Signature[:], sig)
+	return nil
+}
+
+// verifyHeaderSignature checks the signature stored in header against
+// publicKey.
+func verifyHeaderSignature(header *BackupHeader, publicKey *ecdsa.PublicKey) error {
+	if !header.IsSigned() {
+		return ErrBackupNotSigned
+	}
+	return (&Verifier{}).Verify(header, header.Signature[:], publicKey)
+}
+
+// headerDigest returns the SHA-256 digest of the serialized header without
+// its signature.
+func headerDigest(header *BackupHeader) ([]byte, error) {
+	buf := make([]byte, BackupHeaderSize)
+	if err := header.serializeFields(buf); err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(buf)
+	return digest[:], nil
+}
+
+// LoadSigningKey reads a PEM encoded P-256 ECDSA private key, in SEC 1 or
+// PKCS #8 form, from path.
+func LoadSigningKey(path string) (*ecdsa.PrivateKey, error) {
+	block, err := readPEMBlock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSigningKeyInvalid, err)
+	}
+
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok || ecKey.Curve != elliptic.P256() {
+		return nil, ErrUnsupportedKey
+	}
+	return ecKey, nil
+}
+
+// LoadVerifyKey reads a PEM encoded P-256 ECDSA public key in PKIX form
+// from path.
+func LoadVerifyKey(path string) (*ecdsa.PublicKey, error) {
+	block, err := readPEMBlock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSigningKeyInvalid, err)
+	}
+
+	ecKey, ok := key.(*ecdsa.PublicKey)
+	if !ok || ecKey.Curve != elliptic.P256() {
+		return nil, ErrUnsupportedKey
+	}
+	return ecKey, nil
+}
+
+// readPEMBlock reads the first PEM block of the file at path.
+func readPEMBlock(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSigningKeyInvalid, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM data in %s", ErrSigningKeyInvalid, path)
+	}
+	return block, nil
+}
+
+// readBackupHeader reads a backup header, and its signature if the header
+// is signed, from r.
+func readBackupHeader(r io.Reader) (*BackupHeader, error) {
+	buf := make([]byte, BackupHeaderSize+SignatureSize)
+	if _, err := io.ReadFull(r, buf[:BackupHeaderSize]); err != nil {
+		return nil, err
+	}
+
+	header := &BackupHeader{}
+	if err := header.Deserialize(buf[:BackupHeaderSize]); err != nil {
+		return nil, err
+	}
+
+	if header.IsSigned() {
+		if _, err := io.ReadFull(r, buf[BackupHeaderSize:]); err != nil {
+			return nil, err
+		}
+		if err := header.Deserialize(buf); err != nil {
+			return nil, err
+		}
+	}
+	return header, nil
+}
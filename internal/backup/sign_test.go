@@ -0,0 +1,214 @@
+// Package backup provides backup and restore functionality for ObaDB.
+package backup
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestSigningKey generates a P-256 key for signing test backups.
+func newTestSigningKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	return key
+}
+
+// createDirBackup backs up a data directory holding one storage file and
+// returns the path of the backup. The backup is signed if key is not nil.
+func createDirBackup(t *testing.T, key *ecdsa.PrivateKey) string {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "data")
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dataDir, "data.oba"), []byte("signed backup data"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	backupPath := filepath.Join(tmpDir, "backup.bak")
+	_, err := NewBackupManager(nil).Backup(&BackupOptions{
+		OutputPath: backupPath,
+		DataDir:    dataDir,
+		SignKey:    key,
+	})
+	if err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	return backupPath
+}
+
+func TestSignVerifyHeader(t *testing.T) {
+	key := newTestSigningKey(t)
+
+	header := NewBackupHeader()
+	header.PageSize = 4096
+	header.TotalPages = 10
+	header.Checksum = 0xDEADBEEF
+	header.SetSigned(true)
+
+	sig, err := NewSigner(key).Sign(header)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if len(sig) != SignatureSize {
+		t.Fatalf("Sign() len = %d, want %d", len(sig), SignatureSize)
+	}
+
+	verifier := &Verifier{}
+	if err := verifier.Verify(header, sig, &key.PublicKey); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	t.Run("modified header", func(t *testing.T) {
+		buf, err := header.Serialize()
+		if err != nil {
+			t.Fatalf("Serialize() error = %v", err)
+		}
+		buf[41] ^= 0x01 // one byte of the checksum
+
+		tampered := &BackupHeader{}
+		if err := tampered.Deserialize(buf); err != nil {
+			t.Fatalf("Deserialize() error = %v", err)
+		}
+		if err := verifier.Verify(tampered, sig, &key.PublicKey); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("Verify() error = %v, want ErrInvalidSignature", err)
+		}
+	})
+
+	t.Run("other key", func(t *testing.T) {
+		other := newTestSigningKey(t)
+		if err := verifier.Verify(header, sig, &other.PublicKey); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("Verify() error = %v, want ErrInvalidSignature", err)
+		}
+	})
+
+	t.Run("unsupported curve", func(t *testing.T) {
+		p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateKey() error = %v", err)
+		}
+		if _, err := NewSigner(p384).Sign(header); !errors.Is(err, ErrUnsupportedKey) {
+			t.Errorf("Sign() error = %v, want ErrUnsupportedKey", err)
+		}
+	})
+}
+
+func TestSignedBackupRestore(t *testing.T) {
+	key := newTestSigningKey(t)
+	backupPath := createDirBackup(t, key)
+
+	info, err := NewRestoreManager("").GetBackupInfo(backupPath)
+	if err != nil {
+		t.Fatalf("GetBackupInfo() error = %v", err)
+	}
+	if header := info.(*BackupHeader); !header.IsSigned() {
+		t.Error("backup header is not signed")
+	}
+
+	t.Run("valid signature", func(t *testing.T) {
+		restoreDir := filepath.Join(t.TempDir(), "restored")
+		_, err := NewRestoreManager(restoreDir).Restore(&RestoreOptions{
+			InputPath: backupPath,
+			Verify:    true,
+			VerifyKey: &key.PublicKey,
+		})
+		if err != nil {
+			t.Fatalf("Restore() error = %v", err)
+		}
+
+		data, err := os.ReadFile(filepath.Join(restoreDir, "data.oba"))
+		if err != nil || string(data) != "signed backup data" {
+			t.Errorf("restored data = %q, %v", data, err)
+		}
+	})
+
+	t.Run("tampered header", func(t *testing.T) {
+		data, err := os.ReadFile(backupPath)
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+		data[8] ^= 0x01 // one byte of the timestamp
+		tamperedPath := filepath.Join(t.TempDir(), "tampered.bak")
+		if err := os.WriteFile(tamperedPath, data, 0644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+
+		_, err = NewRestoreManager(t.TempDir()).Restore(&RestoreOptions{
+			InputPath: tamperedPath,
+			VerifyKey: &key.PublicKey,
+		})
+		if !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("Restore() error = %v, want ErrInvalidSignature", err)
+		}
+	})
+
+	t.Run("unsigned backup", func(t *testing.T) {
+		unsignedPath := createDirBackup(t, nil)
+		_, err := NewRestoreManager(t.TempDir()).Restore(&RestoreOptions{
+			InputPath: unsignedPath,
+			VerifyKey: &key.PublicKey,
+		})
+		if !errors.Is(err, ErrBackupNotSigned) {
+			t.Errorf("Restore() error = %v, want ErrBackupNotSigned", err)
+		}
+	})
+
+	t.Run("ldif backup", func(t *testing.T) {
+		opts := &BackupOptions{OutputPath: "out.ldif", Format: FormatLDIF, SignKey: key}
+		if err := opts.Validate(); !errors.Is(err, ErrSignNotSupported) {
+			t.Errorf("Validate() error = %v, want ErrSignNotSupported", err)
+		}
+	})
+}
+
+func TestLoadSigningKeys(t *testing.T) {
+	key := newTestSigningKey(t)
+	dir := t.TempDir()
+
+	privDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey() error = %v", err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey() error = %v", err)
+	}
+
+	privPath := filepath.Join(dir, "backup.key")
+	pubPath := filepath.Join(dir, "backup.pub")
+	if err := os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: privDER}), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	signKey, err := LoadSigningKey(privPath)
+	if err != nil {
+		t.Fatalf("LoadSigningKey() error = %v", err)
+	}
+	verifyKey, err := LoadVerifyKey(pubPath)
+	if err != nil {
+		t.Fatalf("LoadVerifyKey() error = %v", err)
+	}
+	if !signKey.PublicKey.Equal(verifyKey) {
+		t.Error("loaded keys do not match")
+	}
+
+	if _, err := LoadVerifyKey(privPath); !errors.Is(err, ErrSigningKeyInvalid) {
+		t.Errorf("LoadVerifyKey(private key) error = %v, want ErrSigningKeyInvalid", err)
+	}
+}
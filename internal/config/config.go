@@ -8,15 +8,22 @@ import (
 
 // Config holds the complete server configuration.
 type Config struct {
-	Server    ServerConfig    `yaml:"server"`
-	Directory DirectoryConfig `yaml:"directory"`
-	Storage   StorageConfig   `yaml:"storage"`
-	Logging   LogConfig       `yaml:"logging"`
-	Security  SecurityConfig  `yaml:"security"`
-	ACL       ACLConfig       `yaml:"acl"`
-	ACLFile   string          `yaml:"aclFile"`
-	REST      RESTConfig      `yaml:"rest"`
-	Cluster   ClusterConfig   `yaml:"cluster"`
+	Server     ServerConfig     `yaml:"server"`
+	Directory  DirectoryConfig  `yaml:"directory"`
+	Storage    StorageConfig    `yaml:"storage"`
+	Logging    LogConfig        `yaml:"logging"`
+	Security   SecurityConfig   `yaml:"security"`
+	ACL        ACLConfig        `yaml:"acl"`
+	ACLFile    string           `yaml:"aclFile"`
+	REST       RESTConfig       `yaml:"rest"`
+	Cluster    ClusterConfig    `yaml:"cluster"`
+	Schema     SchemaConfig     `yaml:"schema"`
+	Monitoring MonitoringConfig `yaml:"monitoring"`
+	Telemetry  TelemetryConfig  `yaml:"telemetry"`
+
+	// secrets records the secret references resolved by the loader, by
+	// key
+	secrets map[string]secretRef
 }
 
 // ResolvePaths resolves relative paths in the configuration to absolute paths.
@@ -57,10 +64,12 @@ func (c *Config) ResolvePaths() error {
 
 	// Resolve encryption key file path
 	if c.Security.Encryption.KeyFile != "" {
-		c.Security.Encryption.KeyFile, err = filepath.Abs(c.Security.Encryption.KeyFile)
+		keyFile := c.Security.Encryption.KeyFile
+		c.Security.Encryption.KeyFile, err = filepath.Abs(keyFile)
 		if err != nil {
 			return err
 		}
+		c.updateSecret("security.encryption.keyFile", keyFile, c.Security.Encryption.KeyFile)
 	}
 
 	// Resolve ACL file path
@@ -92,6 +101,78 @@ type ServerConfig struct {
 	ReadTimeout    time.Duration `yaml:"readTimeout"`
 	WriteTimeout   time.Duration `yaml:"writeTimeout"`
 	PIDFile        string        `yaml:"pidFile"`
+	// TLSKeyPassphrase decrypts TLSKey when it is encrypted. It may be a
+	// secret reference.
+	TLSKeyPassphrase string `yaml:"tlsKeyPassphrase"`
+	// IdleTimeout closes LDAP connections with no request outstanding
+	// after this long without one. Zero keeps them open.
+	IdleTimeout time.Duration `yaml:"idleTimeout"`
+	// ProxyProtocol accepts PROXY protocol v1/v2 headers on the LDAP
+	// listeners from TrustedProxies.
+	ProxyProtocol bool `yaml:"proxyProtocol"`
+	// TrustedProxies lists the CIDRs of load balancers whose PROXY headers
+	// and REST X-Forwarded-For/X-Real-IP headers are honored.
+	TrustedProxies []string `yaml:"trustedProxies"`
+	// Fairness limits how much of the server one client can hold.
+	Fairness FairnessConfig `yaml:"fairness"`
+	// PersistentSearch bounds the notification queues and the number of
+	// persistent searches.
+	PersistentSearch PersistentSearchConfig `yaml:"persistentSearch"`
+	// Limits are the search limit profiles. A search gets the profile
+	// whose subject matches its bind DN most specifically.
+	Limits []SearchLimitConfig `yaml:"limits"`
+}
+
+// LimitDefault marks a search limit that is not set by a profile, so the
+// server default applies. A limit of 0 ("unlimited") removes the cap.
+const LimitDefault = -1
+
+// SearchLimitConfig caps the size and time limits of the searches of the
+// clients Subject matches.
+type SearchLimitConfig struct {
+	// Subject is "anonymous", "authenticated", "*", "group:<DN>" or a
+	// bind DN.
+	Subject string `yaml:"subject"`
+	// SizeLimit is the maximum number of entries a search returns.
+	SizeLimit int `yaml:"sizeLimit"`
+	// TimeLimit is the maximum duration of a search in seconds.
+	TimeLimit int `yaml:"timeLimit"`
+}
+
+// FairnessConfig holds limits that keep one client from monopolizing the
+// server. A zero limit is disabled. Operations over a limit fail with busy.
+type FairnessConfig struct {
+	// MaxBufferedEntries is the number of search result entries a
+	// connection may hold for writing.
+	MaxBufferedEntries int `yaml:"maxBufferedEntries"`
+	// MaxExpensiveSearches is the number of expensive searches that may
+	// run at once.
+	MaxExpensiveSearches int `yaml:"maxExpensiveSearches"`
+	// ExpensiveSearchThreshold is the estimated number of examined entries
+	// above which a search is expensive.
+	ExpensiveSearchThreshold int `yaml:"expensiveSearchThreshold"`
+	// MaxOperationsPerBindDN is the number of operations a bound DN may
+	// have in progress across all of its connections.
+	MaxOperationsPerBindDN int `yaml:"maxOperationsPerBindDN"`
+}
+
+// PersistentSearchConfig holds the resource limits of persistent searches.
+// A zero count limit is disabled. Searches over a limit fail with
+// adminLimitExceeded.
+type PersistentSearchConfig struct {
+	// QueueSize is the number of change notifications queued for each
+	// persistent search. Zero uses the change stream default of 256.
+	QueueSize int `yaml:"queueSize"`
+	// OverflowPolicy is "drop" to drop changes that do not fit in a full
+	// queue and mark the next notification, or "disconnect" to close the
+	// connection with a Notice of Disconnection.
+	OverflowPolicy string `yaml:"overflowPolicy"`
+	// MaxPerConnection is the number of persistent searches a connection
+	// may run at once.
+	MaxPerConnection int `yaml:"maxPerConnection"`
+	// MaxPerBindDN is the number of persistent searches a bound DN may run
+	// at once across all of its connections.
+	MaxPerBindDN int `yaml:"maxPerBindDN"`
 }
 
 // DirectoryConfig holds directory-related configuration.
@@ -99,6 +180,49 @@ type DirectoryConfig struct {
 	BaseDN       string `yaml:"baseDN"`
 	RootDN       string `yaml:"rootDN"`
 	RootPassword string `yaml:"rootPassword"`
+	// MaxRenameSubtree is the largest subtree a ModifyDN may move in one
+	// transaction. Zero means no limit.
+	MaxRenameSubtree int `yaml:"maxRenameSubtree"`
+	// ReferentialIntegrity rewrites member, uniqueMember and similar DN
+	// values that point into a renamed subtree.
+	ReferentialIntegrity bool `yaml:"referentialIntegrity"`
+	// MaxNumSubordinates caps the children counted for numSubordinates.
+	// Zero counts them exactly.
+	MaxNumSubordinates int `yaml:"maxNumSubordinates"`
+	// RecycleBin keeps deleted entries so they can be restored.
+	RecycleBin RecycleBinConfig `yaml:"recycleBin"`
+	// UIDNumber assigns uidNumber to new posixAccount entries.
+	UIDNumber UIDNumberConfig `yaml:"uidNumber"`
+	// DynamicGroups expands the memberURL of groupOfURLs entries.
+	DynamicGroups DynamicGroupsConfig `yaml:"dynamicGroups"`
+	// AuditLogPath is the LDIF file that records every successful write.
+	// Empty disables the audit log.
+	AuditLogPath string `yaml:"auditLogPath"`
+}
+
+// DynamicGroupsConfig holds dynamic group expansion configuration.
+type DynamicGroupsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MaxMembers is the largest number of member values materialized for
+	// one group. Larger groups are returned partially.
+	MaxMembers int `yaml:"maxMembers"`
+}
+
+// UIDNumberConfig holds uidNumber auto-assignment configuration.
+type UIDNumberConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Start is the first uidNumber assigned when the cn=uidNext counter
+	// entry does not exist yet.
+	Start int `yaml:"start"`
+}
+
+// RecycleBinConfig holds recycle bin configuration.
+type RecycleBinConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Retention is how long deleted entries are kept before being purged.
+	Retention time.Duration `yaml:"retention"`
+	// PurgeInterval is how often expired entries are purged.
+	PurgeInterval time.Duration `yaml:"purgeInterval"`
 }
 
 // StorageConfig holds storage engine configuration.
@@ -113,10 +237,12 @@ type StorageConfig struct {
 
 // LogConfig holds logging configuration.
 type LogConfig struct {
-	Level  string         `yaml:"level"`
-	Format string         `yaml:"format"`
-	Output string         `yaml:"output"`
-	Store  LogStoreConfig `yaml:"store"`
+	Level           string            `yaml:"level"`
+	Format          string            `yaml:"format"`
+	Output          string            `yaml:"output"`
+	ComponentLevels map[string]string `yaml:"componentLevels"` // Level overrides per component
+	Store           LogStoreConfig    `yaml:"store"`
+	WireDump        WireDumpConfig    `yaml:"wireDump"`
 }
 
 // LogStoreConfig holds log storage configuration.
@@ -130,11 +256,24 @@ type LogStoreConfig struct {
 	RetainDays int           `yaml:"retainDays"` // Days to retain archives (0 = forever)
 }
 
+// WireDumpConfig holds LDAP wire dump configuration.
+type WireDumpConfig struct {
+	Enabled          bool          `yaml:"enabled"`
+	Directory        string        `yaml:"directory"`
+	MaxBytes         int64         `yaml:"maxBytes"`         // Max size of each connection file
+	ConnectionFilter []string      `yaml:"connectionFilter"` // Client IPs or CIDRs to dump (empty = all)
+	Duration         time.Duration `yaml:"duration"`         // Disable after this long (0 = never)
+	RawBER           bool          `yaml:"rawBER"`           // Add the hex encoded BER of each message
+	IncludePasswords bool          `yaml:"includePasswords"` // Do not redact bind credentials and passwords
+}
+
 // SecurityConfig holds security-related configuration.
 type SecurityConfig struct {
 	PasswordPolicy PasswordPolicyConfig `yaml:"passwordPolicy"`
 	RateLimit      RateLimitConfig      `yaml:"rateLimit"`
+	BindThrottle   BindThrottleConfig   `yaml:"bindThrottle"`
 	Encryption     EncryptionConfig     `yaml:"encryption"`
+	TLS            TLSConfig            `yaml:"tls"`
 }
 
 // EncryptionConfig holds encryption at rest configuration.
@@ -143,6 +282,12 @@ type EncryptionConfig struct {
 	KeyFile string `yaml:"keyFile"`
 }
 
+// TLSConfig holds the protocol settings of the LDAPS and StartTLS listeners.
+type TLSConfig struct {
+	MinVersion          string   `yaml:"minVersion"`          // "tls12" or "tls13"; empty means TLS 1.2
+	AllowedCipherSuites []string `yaml:"allowedCipherSuites"` // secure TLS 1.2 suite names; empty means secure defaults
+}
+
 // PasswordPolicyConfig holds password policy configuration.
 type PasswordPolicyConfig struct {
 	Enabled          bool          `yaml:"enabled"`
@@ -153,6 +298,7 @@ type PasswordPolicyConfig struct {
 	RequireSpecial   bool          `yaml:"requireSpecial"`
 	MaxAge           time.Duration `yaml:"maxAge"`
 	HistoryCount     int           `yaml:"historyCount"`
+	GraceLogins      int           `yaml:"graceLogins"`
 }
 
 // RateLimitConfig holds rate limiting configuration.
@@ -162,6 +308,17 @@ type RateLimitConfig struct {
 	LockoutDuration time.Duration `yaml:"lockoutDuration"`
 }
 
+// BindThrottleConfig holds per-source-IP bind throttling configuration.
+type BindThrottleConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	MaxFailures int           `yaml:"maxFailures"` // Failures within Window before delays start
+	Window      time.Duration `yaml:"window"`      // Sliding window for counting failures
+	BaseDelay   time.Duration `yaml:"baseDelay"`   // First delay, doubled per further failure
+	MaxDelay    time.Duration `yaml:"maxDelay"`    // Upper bound for the delay
+	Allowlist   []string      `yaml:"allowlist"`   // CIDRs that are never throttled
+	StateFile   string        `yaml:"stateFile"`   // Optional file to persist state across restarts
+}
+
 // ACLConfig holds access control list configuration.
 type ACLConfig struct {
 	DefaultPolicy string          `yaml:"defaultPolicy"`
@@ -174,6 +331,17 @@ type ACLRuleConfig struct {
 	Subject    string   `yaml:"subject"`
 	Rights     []string `yaml:"rights"`
 	Attributes []string `yaml:"attributes"`
+
+	// TimeRange restricts the rule to a daily window. Nil means always.
+	TimeRange *TimeRangeConfig `yaml:"timeRange"`
+}
+
+// TimeRangeConfig holds the daily window of an ACL rule.
+type TimeRangeConfig struct {
+	Start    string   `yaml:"start"`    // Opening clock time, HH:MM or HH:MM:SS
+	End      string   `yaml:"end"`      // Closing clock time, HH:MM or HH:MM:SS
+	Weekdays []string `yaml:"weekdays"` // Weekday names or ranges such as mon-fri (empty = every day)
+	Timezone string   `yaml:"timezone"` // IANA time zone name (empty = local time)
 }
 
 // RESTConfig holds REST API configuration.
@@ -183,8 +351,25 @@ type RESTConfig struct {
 	TLSAddress  string        `yaml:"tlsAddress"`
 	JWTSecret   string        `yaml:"jwtSecret"`
 	TokenTTL    time.Duration `yaml:"tokenTTL"`
+	CursorTTL   time.Duration `yaml:"cursorTTL"`
 	RateLimit   int           `yaml:"rateLimit"`
 	CORSOrigins []string      `yaml:"corsOrigins"`
+	// CORSAllowedMethods and CORSAllowedHeaders list the methods and
+	// request headers allowed for cross-origin requests; empty lists use
+	// the REST server defaults.
+	CORSAllowedMethods []string `yaml:"corsAllowedMethods"`
+	CORSAllowedHeaders []string `yaml:"corsAllowedHeaders"`
+	// CORSMaxAge is how long browsers may cache a preflight response. A
+	// negative value disables caching.
+	CORSMaxAge time.Duration `yaml:"corsMaxAge"`
+	// ExternalJWTIssuer, ExternalJWTAudience, JWKSUri and ClaimTemplate
+	// accept JWTs of an external identity provider issued for the oba
+	// REST API, verified with the keys at JWKSUri and mapped to a bind DN
+	// by the ClaimTemplate Go template.
+	ExternalJWTIssuer   string `yaml:"externalJWTIssuer"`
+	ExternalJWTAudience string `yaml:"externalJWTAudience"`
+	JWKSUri             string `yaml:"jwksUri"`
+	ClaimTemplate       string `yaml:"claimTemplate"`
 }
 
 // ClusterConfig holds Raft cluster configuration.
@@ -197,6 +382,11 @@ type ClusterConfig struct {
 	HeartbeatTimeout time.Duration `yaml:"heartbeatTimeout"`
 	SnapshotInterval uint64        `yaml:"snapshotInterval"`
 	DataDir          string        `yaml:"dataDir"`
+	// ReadPreference selects which node serves reads: leader, follower or any
+	// (default). With follower, nodes lagging more than MaxStalenessLog
+	// entries behind the leader forward reads to it.
+	ReadPreference  string `yaml:"readPreference"`
+	MaxStalenessLog uint64 `yaml:"maxStalenessLog"`
 }
 
 // PeerConfig holds peer node configuration.
@@ -204,3 +394,67 @@ type PeerConfig struct {
 	ID   uint64 `yaml:"id"`
 	Addr string `yaml:"addr"`
 }
+
+// SchemaConfig holds schema and DIT structure configuration.
+type SchemaConfig struct {
+	// Builtin lists the built-in schema sets to load (core, cosine,
+	// inetorgperson, nis, dyngroup). Entries are validated against the
+	// schema only when at least one set is configured.
+	Builtin []string `yaml:"builtin"`
+	// Strict rejects entries that use an objectClass not defined in the schema.
+	Strict bool `yaml:"strict"`
+	// ObjectClassCascade removes the attributes of an object class that a
+	// modify removes, instead of rejecting the modify while the entry still
+	// holds them.
+	ObjectClassCascade bool                 `yaml:"objectClassCascade"`
+	StructureRules     StructureRulesConfig `yaml:"structureRules"`
+}
+
+// MonitoringConfig holds metrics exposition configuration.
+type MonitoringConfig struct {
+	// PrometheusAddr is the address of a standalone HTTP server that serves
+	// Prometheus metrics at /metrics. Empty disables it.
+	PrometheusAddr string `yaml:"prometheusAddr"`
+	// SNMPTrap sends SNMP traps for server events.
+	SNMPTrap SNMPConfig `yaml:"snmpTrap"`
+}
+
+// SNMPConfig holds SNMP trap configuration.
+type SNMPConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Target is the host:port of the trap receiver. The port defaults to 162.
+	Target    string `yaml:"target"`
+	Community string `yaml:"community"`
+	// Version is the SNMP version of the traps. Only "2c" is supported.
+	Version string `yaml:"version"`
+	// ReplicationLagThreshold is the number of committed log entries a
+	// cluster node may have left to apply before obaReplicationLag is sent.
+	ReplicationLagThreshold uint64 `yaml:"replicationLagThreshold"`
+	// StorageFullPercent is the used share of the data volume at which
+	// obaStorageFull is sent.
+	StorageFullPercent int `yaml:"storageFullPercent"`
+}
+
+// TelemetryConfig holds OpenTelemetry tracing configuration.
+type TelemetryConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Endpoint is the OTLP/HTTP collector URL, e.g. http://localhost:4318.
+	Endpoint    string `yaml:"endpoint"`
+	ServiceName string `yaml:"serviceName"`
+	// SampleRatio is the fraction of new traces that are recorded. Requests
+	// that carry a parent trace follow its sampling decision.
+	SampleRatio float64 `yaml:"sampleRatio"`
+}
+
+// StructureRulesConfig holds DIT structure rule enforcement configuration.
+type StructureRulesConfig struct {
+	Enabled bool                  `yaml:"enabled"`
+	Rules   []StructureRuleConfig `yaml:"rules"`
+}
+
+// StructureRuleConfig holds a single DIT structure rule.
+type StructureRuleConfig struct {
+	ObjectClass      string   `yaml:"objectClass"`
+	Parents          []string `yaml:"parents"`
+	NamingAttributes []string `yaml:"namingAttributes"`
+}
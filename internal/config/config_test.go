@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -81,6 +82,7 @@ server:
   maxConnections: 5000
   readTimeout: 60s
   writeTimeout: 45s
+  idleTimeout: 15m
 `
 		config, err := ParseConfig([]byte(yaml))
 		if err != nil {
@@ -101,6 +103,9 @@ server:
 		if config.Server.WriteTimeout != 45*time.Second {
 			t.Errorf("expected write timeout 45s, got %v", config.Server.WriteTimeout)
 		}
+		if config.Server.IdleTimeout != 15*time.Minute {
+			t.Errorf("expected idle timeout 15m, got %v", config.Server.IdleTimeout)
+		}
 	})
 
 	t.Run("parse directory config", func(t *testing.T) {
@@ -109,6 +114,19 @@ directory:
   baseDN: "dc=example,dc=com"
   rootDN: "cn=admin,dc=example,dc=com"
   rootPassword: "secret"
+  maxRenameSubtree: 500
+  referentialIntegrity: true
+  maxNumSubordinates: 100
+  recycleBin:
+    enabled: true
+    retention: 48h
+  uidNumber:
+    enabled: true
+    start: 20000
+  dynamicGroups:
+    enabled: true
+    maxMembers: 50
+  auditLogPath: "/var/log/oba/audit.ldif"
 `
 		config, err := ParseConfig([]byte(yaml))
 		if err != nil {
@@ -123,6 +141,30 @@ directory:
 		if config.Directory.RootPassword != "secret" {
 			t.Errorf("expected rootPassword 'secret', got %q", config.Directory.RootPassword)
 		}
+		if config.Directory.MaxRenameSubtree != 500 {
+			t.Errorf("expected maxRenameSubtree 500, got %d", config.Directory.MaxRenameSubtree)
+		}
+		if !config.Directory.ReferentialIntegrity {
+			t.Error("expected referentialIntegrity to be enabled")
+		}
+		if config.Directory.MaxNumSubordinates != 100 {
+			t.Errorf("expected maxNumSubordinates 100, got %d", config.Directory.MaxNumSubordinates)
+		}
+		if !config.Directory.RecycleBin.Enabled || config.Directory.RecycleBin.Retention != 48*time.Hour {
+			t.Errorf("unexpected recycleBin config %+v", config.Directory.RecycleBin)
+		}
+		if config.Directory.RecycleBin.PurgeInterval != time.Hour {
+			t.Errorf("expected default purgeInterval 1h, got %v", config.Directory.RecycleBin.PurgeInterval)
+		}
+		if un := config.Directory.UIDNumber; !un.Enabled || un.Start != 20000 {
+			t.Errorf("unexpected uidNumber config %+v", un)
+		}
+		if dg := config.Directory.DynamicGroups; !dg.Enabled || dg.MaxMembers != 50 {
+			t.Errorf("unexpected dynamicGroups config %+v", dg)
+		}
+		if config.Directory.AuditLogPath != "/var/log/oba/audit.ldif" {
+			t.Errorf("expected auditLogPath '/var/log/oba/audit.ldif', got %q", config.Directory.AuditLogPath)
+		}
 	})
 
 	t.Run("parse storage config", func(t *testing.T) {
@@ -161,6 +203,9 @@ logging:
   level: "debug"
   format: "text"
   output: "/var/log/oba.log"
+  componentLevels:
+    raft: debug
+    storage: warn
 `
 		config, err := ParseConfig([]byte(yaml))
 		if err != nil {
@@ -175,6 +220,15 @@ logging:
 		if config.Logging.Output != "/var/log/oba.log" {
 			t.Errorf("expected output '/var/log/oba.log', got %q", config.Logging.Output)
 		}
+		levels := config.Logging.ComponentLevels
+		if len(levels) != 2 || levels["raft"] != "debug" || levels["storage"] != "warn" {
+			t.Errorf("expected componentLevels raft=debug storage=warn, got %v", levels)
+		}
+
+		config.Logging.ComponentLevels["raft"] = "verbose"
+		if errs := validateLogConfig(&config.Logging); len(errs) != 1 {
+			t.Errorf("expected one validation error for an invalid component level, got %v", errs)
+		}
 	})
 
 	t.Run("parse security config", func(t *testing.T) {
@@ -189,10 +243,21 @@ security:
     requireSpecial: true
     maxAge: 90d
     historyCount: 5
+    graceLogins: 3
   rateLimit:
     enabled: true
     maxAttempts: 3
     lockoutDuration: 30m
+  bindThrottle:
+    enabled: true
+    maxFailures: 20
+    window: 10m
+    baseDelay: 500ms
+    maxDelay: 1m
+    allowlist:
+      - 10.0.0.0/8
+      - 192.168.1.0/24
+    stateFile: /var/lib/oba/throttle.json
 `
 		config, err := ParseConfig([]byte(yaml))
 		if err != nil {
@@ -213,6 +278,9 @@ security:
 		if config.Security.PasswordPolicy.HistoryCount != 5 {
 			t.Errorf("expected historyCount 5, got %d", config.Security.PasswordPolicy.HistoryCount)
 		}
+		if config.Security.PasswordPolicy.GraceLogins != 3 {
+			t.Errorf("expected graceLogins 3, got %d", config.Security.PasswordPolicy.GraceLogins)
+		}
 		if !config.Security.RateLimit.Enabled {
 			t.Error("expected rate limit enabled")
 		}
@@ -222,6 +290,19 @@ security:
 		if config.Security.RateLimit.LockoutDuration != 30*time.Minute {
 			t.Errorf("expected lockoutDuration 30m, got %v", config.Security.RateLimit.LockoutDuration)
 		}
+		bt := config.Security.BindThrottle
+		if !bt.Enabled || bt.MaxFailures != 20 || bt.Window != 10*time.Minute {
+			t.Errorf("unexpected bind throttle config: %+v", bt)
+		}
+		if bt.BaseDelay != 500*time.Millisecond || bt.MaxDelay != time.Minute {
+			t.Errorf("unexpected bind throttle delays: %v, %v", bt.BaseDelay, bt.MaxDelay)
+		}
+		if len(bt.Allowlist) != 2 || bt.Allowlist[1] != "192.168.1.0/24" {
+			t.Errorf("unexpected bind throttle allowlist: %v", bt.Allowlist)
+		}
+		if bt.StateFile != "/var/lib/oba/throttle.json" {
+			t.Errorf("unexpected bind throttle stateFile: %q", bt.StateFile)
+		}
 	})
 
 	t.Run("parse acl config", func(t *testing.T) {
@@ -238,6 +319,38 @@ acl:
 		}
 	})
 
+	t.Run("parse acl time range", func(t *testing.T) {
+		yaml := `
+acl:
+  rules:
+    - target: "*"
+      subject: "authenticated"
+      rights: [read]
+      timeRange:
+        start: "09:00"
+        end: "17:00"
+        weekdays: [mon-fri]
+        timezone: "Europe/Istanbul"
+`
+		config, err := ParseConfig([]byte(yaml))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(config.ACL.Rules) != 1 {
+			t.Fatalf("expected 1 rule, got %d", len(config.ACL.Rules))
+		}
+		tr := config.ACL.Rules[0].TimeRange
+		if tr == nil {
+			t.Fatal("expected timeRange")
+		}
+		if tr.Start != "09:00" || tr.End != "17:00" || tr.Timezone != "Europe/Istanbul" {
+			t.Errorf("unexpected timeRange: %+v", tr)
+		}
+		if len(tr.Weekdays) != 1 || tr.Weekdays[0] != "mon-fri" {
+			t.Errorf("unexpected weekdays: %v", tr.Weekdays)
+		}
+	})
+
 	t.Run("parse quoted values", func(t *testing.T) {
 		yaml := `
 directory:
@@ -577,6 +690,472 @@ acl:
 	}
 }
 
+func TestSchemaStructureRules(t *testing.T) {
+	yaml := `
+schema:
+  structureRules:
+    enabled: true
+    rules:
+      - objectClass: "inetOrgPerson"
+        parents: ["organizationalUnit"]
+        namingAttributes:
+          - uid
+          - cn
+      - objectClass: "organizationalUnit"
+        parents: ["organization", "organizationalUnit"]
+`
+	config, err := ParseConfig([]byte(yaml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sr := config.Schema.StructureRules
+	if !sr.Enabled {
+		t.Error("schema.structureRules.enabled: expected true")
+	}
+	if len(sr.Rules) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(sr.Rules))
+	}
+	if sr.Rules[0].ObjectClass != "inetOrgPerson" {
+		t.Errorf("rules[0].objectClass: expected 'inetOrgPerson', got %q", sr.Rules[0].ObjectClass)
+	}
+	if len(sr.Rules[0].Parents) != 1 || sr.Rules[0].Parents[0] != "organizationalUnit" {
+		t.Errorf("rules[0].parents: got %v", sr.Rules[0].Parents)
+	}
+	if len(sr.Rules[0].NamingAttributes) != 2 || sr.Rules[0].NamingAttributes[1] != "cn" {
+		t.Errorf("rules[0].namingAttributes: got %v", sr.Rules[0].NamingAttributes)
+	}
+	if len(sr.Rules[1].Parents) != 2 {
+		t.Errorf("rules[1].parents: got %v", sr.Rules[1].Parents)
+	}
+
+	config.Schema.StructureRules.Rules = append(config.Schema.StructureRules.Rules, StructureRuleConfig{ObjectClass: "InetOrgPerson"})
+	if errs := validateSchemaConfig(&config.Schema); len(errs) != 1 {
+		t.Errorf("expected 1 duplicate rule error, got %v", errs)
+	}
+}
+
+func TestSchemaBuiltin(t *testing.T) {
+	yaml := `
+schema:
+  builtin: [core, cosine, inetorgperson]
+  strict: true
+  objectClassCascade: true
+`
+	config, err := ParseConfig([]byte(yaml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(config.Schema.Builtin) != 3 || config.Schema.Builtin[2] != "inetorgperson" {
+		t.Errorf("schema.builtin: got %v", config.Schema.Builtin)
+	}
+	if !config.Schema.Strict {
+		t.Error("schema.strict: expected true")
+	}
+	if !config.Schema.ObjectClassCascade {
+		t.Error("schema.objectClassCascade: expected true")
+	}
+	if errs := validateSchemaConfig(&config.Schema); len(errs) != 0 {
+		t.Errorf("unexpected validation errors: %v", errs)
+	}
+
+	config.Schema.Builtin = []string{"core", "bogus"}
+	if errs := validateSchemaConfig(&config.Schema); len(errs) != 1 {
+		t.Errorf("expected 1 unknown set error, got %v", errs)
+	}
+
+	config.Schema.Builtin = nil
+	if errs := validateSchemaConfig(&config.Schema); len(errs) != 1 {
+		t.Errorf("expected strict without builtin to be rejected, got %v", errs)
+	}
+}
+
+func TestClusterReadPreference(t *testing.T) {
+	yaml := `
+cluster:
+  enabled: true
+  readPreference: follower
+  maxStalenessLog: 50
+`
+	config, err := ParseConfig([]byte(yaml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.Cluster.ReadPreference != "follower" {
+		t.Errorf("cluster.readPreference: got %q", config.Cluster.ReadPreference)
+	}
+	if config.Cluster.MaxStalenessLog != 50 {
+		t.Errorf("cluster.maxStalenessLog: got %d", config.Cluster.MaxStalenessLog)
+	}
+	if errs := validateClusterConfig(&config.Cluster); len(errs) != 0 {
+		t.Errorf("unexpected validation errors: %v", errs)
+	}
+
+	config.Cluster.ReadPreference = "nearest"
+	if errs := validateClusterConfig(&config.Cluster); len(errs) != 1 {
+		t.Errorf("expected invalid read preference error, got %v", errs)
+	}
+}
+
+func TestMonitoringConfig(t *testing.T) {
+	yaml := `
+monitoring:
+  prometheusAddr: "127.0.0.1:9090"
+`
+	config, err := ParseConfig([]byte(yaml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.Monitoring.PrometheusAddr != "127.0.0.1:9090" {
+		t.Errorf("monitoring.prometheusAddr: got %q", config.Monitoring.PrometheusAddr)
+	}
+
+	config.Monitoring.PrometheusAddr = "no-port"
+	errs := ValidateConfig(config)
+	found := false
+	for _, err := range errs {
+		if ve, ok := err.(ValidationError); ok && ve.Field == "monitoring.prometheusAddr" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected monitoring.prometheusAddr validation error, got %v", errs)
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	config, err := ParseConfig([]byte(`
+security:
+  tls:
+    minVersion: tls13
+    allowedCipherSuites:
+      - TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256
+      - TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tlsCfg := config.Security.TLS
+	if tlsCfg.MinVersion != "tls13" || len(tlsCfg.AllowedCipherSuites) != 2 {
+		t.Errorf("security.tls = %+v", tlsCfg)
+	}
+	if errs := validateTLSConfig(&tlsCfg); len(errs) != 0 {
+		t.Errorf("unexpected validation errors: %v", errs)
+	}
+
+	tlsCfg.MinVersion = "tls10"
+	tlsCfg.AllowedCipherSuites = append(tlsCfg.AllowedCipherSuites, "TLS_BOGUS_SUITE")
+	errs := validateTLSConfig(&tlsCfg)
+	if len(errs) != 2 {
+		t.Fatalf("expected minVersion and cipher suite errors, got %v", errs)
+	}
+	if ve, ok := errs[1].(ValidationError); !ok || ve.Field != "security.tls.allowedCipherSuites" {
+		t.Errorf("unexpected cipher suite error: %v", errs[1])
+	}
+
+	for _, name := range []string{"TLS_RSA_WITH_RC4_128_SHA", "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256", "TLS_AES_128_GCM_SHA256"} {
+		tlsCfg = TLSConfig{AllowedCipherSuites: []string{name}}
+		if errs := validateTLSConfig(&tlsCfg); len(errs) != 1 {
+			t.Errorf("%s: expected a cipher suite error, got %v", name, errs)
+		}
+	}
+}
+
+func TestSNMPTrapConfig(t *testing.T) {
+	config, err := ParseConfig([]byte(`
+monitoring:
+  snmpTrap:
+    enabled: true
+    target: "nms.example.com:162"
+    community: "monitor"
+    storageFullPercent: 95
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	snmp := config.Monitoring.SNMPTrap
+	if !snmp.Enabled || snmp.Target != "nms.example.com:162" || snmp.Community != "monitor" {
+		t.Errorf("monitoring.snmpTrap = %+v", snmp)
+	}
+	if snmp.Version != "2c" || snmp.ReplicationLagThreshold != 1000 || snmp.StorageFullPercent != 95 {
+		t.Errorf("monitoring.snmpTrap defaults = %+v", snmp)
+	}
+	if errs := validateSNMPConfig(&snmp); len(errs) != 0 {
+		t.Errorf("unexpected validation errors: %v", errs)
+	}
+
+	snmp.Target = ""
+	snmp.Version = "3"
+	snmp.StorageFullPercent = 120
+	if errs := validateSNMPConfig(&snmp); len(errs) != 3 {
+		t.Errorf("expected target, version and percent errors, got %v", errs)
+	}
+}
+
+func TestRESTCORSConfig(t *testing.T) {
+	config, err := ParseConfig([]byte(`
+rest:
+  corsOrigins:
+    - "https://app.example.com"
+  corsAllowedMethods: [GET, POST]
+  corsAllowedHeaders:
+    - Content-Type
+    - Authorization
+    - If-Match
+  corsMaxAge: 10m
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := strings.Join(config.REST.CORSAllowedMethods, ","); got != "GET,POST" {
+		t.Errorf("rest.corsAllowedMethods: got %q", got)
+	}
+	if got := strings.Join(config.REST.CORSAllowedHeaders, ","); got != "Content-Type,Authorization,If-Match" {
+		t.Errorf("rest.corsAllowedHeaders: got %q", got)
+	}
+	if config.REST.CORSMaxAge != 10*time.Minute {
+		t.Errorf("rest.corsMaxAge: got %v", config.REST.CORSMaxAge)
+	}
+
+	if def := DefaultConfig(); def.REST.CORSMaxAge != 24*time.Hour {
+		t.Errorf("default rest.corsMaxAge: got %v", def.REST.CORSMaxAge)
+	}
+}
+
+func TestFairnessConfig(t *testing.T) {
+	if got := DefaultConfig().Server.Fairness; got != (FairnessConfig{ExpensiveSearchThreshold: 10000}) {
+		t.Errorf("unexpected fairness defaults: %+v", got)
+	}
+
+	yaml := `
+server:
+  fairness:
+    maxBufferedEntries: 50000
+    maxExpensiveSearches: 4
+    expensiveSearchThreshold: 20000
+    maxOperationsPerBindDN: 16
+`
+	config, err := ParseConfig([]byte(yaml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := FairnessConfig{
+		MaxBufferedEntries:       50000,
+		MaxExpensiveSearches:     4,
+		ExpensiveSearchThreshold: 20000,
+		MaxOperationsPerBindDN:   16,
+	}
+	if config.Server.Fairness != want {
+		t.Errorf("server.fairness: got %+v, want %+v", config.Server.Fairness, want)
+	}
+
+	config.Server.Fairness.MaxOperationsPerBindDN = -1
+	if errs := validateServerConfig(&config.Server); len(errs) != 1 {
+		t.Errorf("expected one validation error, got %v", errs)
+	}
+
+	if _, err := ParseConfig([]byte("server:\n  fairness:\n    maxBufferedEntries: many\n")); err == nil {
+		t.Error("expected error for invalid maxBufferedEntries")
+	}
+}
+
+func TestPersistentSearchConfig(t *testing.T) {
+	if got := DefaultConfig().Server.PersistentSearch; got != (PersistentSearchConfig{QueueSize: 256, OverflowPolicy: "drop"}) {
+		t.Errorf("unexpected persistent search defaults: %+v", got)
+	}
+
+	yaml := `
+server:
+  persistentSearch:
+    queueSize: 1024
+    overflowPolicy: disconnect
+    maxPerConnection: 4
+    maxPerBindDN: 16
+`
+	config, err := ParseConfig([]byte(yaml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := PersistentSearchConfig{
+		QueueSize:        1024,
+		OverflowPolicy:   "disconnect",
+		MaxPerConnection: 4,
+		MaxPerBindDN:     16,
+	}
+	if config.Server.PersistentSearch != want {
+		t.Errorf("server.persistentSearch: got %+v, want %+v", config.Server.PersistentSearch, want)
+	}
+	if errs := validateServerConfig(&config.Server); len(errs) != 0 {
+		t.Errorf("unexpected validation errors: %v", errs)
+	}
+
+	// The limits survive saving the config
+	saved, err := ParseConfig([]byte(NewConfigManager(config, "").configToYAML()))
+	if err != nil {
+		t.Fatalf("failed to parse saved config: %v", err)
+	}
+	if saved.Server.PersistentSearch != want {
+		t.Errorf("saved server.persistentSearch: got %+v, want %+v", saved.Server.PersistentSearch, want)
+	}
+
+	config.Server.PersistentSearch.OverflowPolicy = "block"
+	config.Server.PersistentSearch.MaxPerBindDN = -1
+	if errs := validateServerConfig(&config.Server); len(errs) != 2 {
+		t.Errorf("expected two validation errors, got %v", errs)
+	}
+
+	if _, err := ParseConfig([]byte("server:\n  persistentSearch:\n    queueSize: large\n")); err == nil {
+		t.Error("expected error for invalid queueSize")
+	}
+}
+
+func TestSearchLimitsConfig(t *testing.T) {
+	yaml := `
+server:
+  limits:
+    - subject: "cn=sync,ou=services,dc=example,dc=com"
+      sizeLimit: unlimited
+      timeLimit: 600
+    - subject: authenticated
+      sizeLimit: 500
+`
+	config, err := ParseConfig([]byte(yaml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []SearchLimitConfig{
+		{Subject: "cn=sync,ou=services,dc=example,dc=com", SizeLimit: 0, TimeLimit: 600},
+		{Subject: "authenticated", SizeLimit: 500, TimeLimit: LimitDefault},
+	}
+	if len(config.Server.Limits) != len(want) {
+		t.Fatalf("server.limits: got %+v, want %+v", config.Server.Limits, want)
+	}
+	for i := range want {
+		if config.Server.Limits[i] != want[i] {
+			t.Errorf("server.limits[%d]: got %+v, want %+v", i, config.Server.Limits[i], want[i])
+		}
+	}
+	if errs := validateServerConfig(&config.Server); len(errs) != 0 {
+		t.Errorf("unexpected validation errors: %v", errs)
+	}
+
+	// The profiles survive saving the config
+	saved, err := ParseConfig([]byte(NewConfigManager(config, "").configToYAML()))
+	if err != nil {
+		t.Fatalf("failed to parse saved config: %v", err)
+	}
+	for i := range want {
+		if saved.Server.Limits[i] != want[i] {
+			t.Errorf("saved server.limits[%d]: got %+v, want %+v", i, saved.Server.Limits[i], want[i])
+		}
+	}
+
+	config.Server.Limits = append(config.Server.Limits, SearchLimitConfig{SizeLimit: -2, TimeLimit: LimitDefault})
+	if errs := validateServerConfig(&config.Server); len(errs) != 2 {
+		t.Errorf("expected two validation errors, got %v", errs)
+	}
+
+	if _, err := ParseConfig([]byte("server:\n  limits:\n    - subject: anonymous\n      sizeLimit: many\n")); err == nil {
+		t.Error("expected error for invalid sizeLimit")
+	}
+}
+
+func TestWireDumpConfig(t *testing.T) {
+	config, err := ParseConfig([]byte(`
+logging:
+  wireDump:
+    enabled: true
+    directory: /var/log/oba/wiredump
+    maxBytes: 1048576
+    connectionFilter:
+      - 10.0.0.0/8
+      - 192.168.1.20
+    duration: 30m
+    rawBER: true
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wd := config.Logging.WireDump
+	if !wd.Enabled || wd.Directory != "/var/log/oba/wiredump" || wd.MaxBytes != 1048576 {
+		t.Errorf("logging.wireDump: got %+v", wd)
+	}
+	if got := strings.Join(wd.ConnectionFilter, ","); got != "10.0.0.0/8,192.168.1.20" {
+		t.Errorf("logging.wireDump.connectionFilter: got %q", got)
+	}
+	if wd.Duration != 30*time.Minute || !wd.RawBER || wd.IncludePasswords {
+		t.Errorf("logging.wireDump: got %+v", wd)
+	}
+	if errs := validateWireDumpConfig(&wd); len(errs) != 0 {
+		t.Errorf("unexpected validation errors: %v", errs)
+	}
+
+	wd.Directory = ""
+	wd.ConnectionFilter = []string{"bogus"}
+	if errs := validateWireDumpConfig(&wd); len(errs) != 2 {
+		t.Errorf("expected two validation errors, got %v", errs)
+	}
+
+	if def := DefaultConfig().Logging.WireDump; def.Enabled || def.Duration != time.Hour {
+		t.Errorf("unexpected wire dump defaults: %+v", def)
+	}
+}
+
+func TestTelemetryConfig(t *testing.T) {
+	config := DefaultConfig()
+	if config.Telemetry.Enabled || config.Telemetry.ServiceName != "oba" || config.Telemetry.SampleRatio != 1.0 {
+		t.Errorf("unexpected telemetry defaults: %+v", config.Telemetry)
+	}
+
+	yaml := `
+telemetry:
+  enabled: true
+  endpoint: "http://collector:4318"
+  serviceName: "oba-east"
+  sampleRatio: 0.25
+`
+	config, err := ParseConfig([]byte(yaml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !config.Telemetry.Enabled {
+		t.Error("telemetry.enabled: expected true")
+	}
+	if config.Telemetry.Endpoint != "http://collector:4318" {
+		t.Errorf("telemetry.endpoint: got %q", config.Telemetry.Endpoint)
+	}
+	if config.Telemetry.ServiceName != "oba-east" {
+		t.Errorf("telemetry.serviceName: got %q", config.Telemetry.ServiceName)
+	}
+	if config.Telemetry.SampleRatio != 0.25 {
+		t.Errorf("telemetry.sampleRatio: got %v", config.Telemetry.SampleRatio)
+	}
+	if errs := validateTelemetryConfig(&config.Telemetry); len(errs) != 0 {
+		t.Errorf("unexpected validation errors: %v", errs)
+	}
+
+	config.Telemetry.Endpoint = ""
+	config.Telemetry.SampleRatio = 1.5
+	if errs := validateTelemetryConfig(&config.Telemetry); len(errs) != 2 {
+		t.Errorf("expected endpoint and sample ratio errors, got %v", errs)
+	}
+
+	if _, err := ParseConfig([]byte("telemetry:\n  sampleRatio: half\n")); err == nil {
+		t.Error("expected error for invalid sample ratio")
+	}
+}
+
 func TestInvalidYAML(t *testing.T) {
 	t.Run("missing colon", func(t *testing.T) {
 		yaml := `
@@ -720,3 +1299,223 @@ func TestSubstituteEnvVars(t *testing.T) {
 		}
 	})
 }
+
+func TestSecretReferences(t *testing.T) {
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("TEST_OBA_ROOT_PASSWORD", "envsecret")
+
+		config, err := ParseConfig([]byte(`
+directory:
+  rootPassword: "${env:TEST_OBA_ROOT_PASSWORD}"
+`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if config.Directory.RootPassword != "envsecret" {
+			t.Errorf("expected rootPassword 'envsecret', got %q", config.Directory.RootPassword)
+		}
+		if ref := config.SecretReference("directory.rootPassword"); ref != "${env:TEST_OBA_ROOT_PASSWORD}" {
+			t.Errorf("expected reference to be recorded, got %q", ref)
+		}
+	})
+
+	t.Run("file trims trailing newline", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "jwt-secret")
+		if err := os.WriteFile(path, []byte("filesecret\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		config, err := ParseConfig([]byte(`
+rest:
+  jwtSecret: "${file:` + path + `}"
+`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if config.REST.JWTSecret != "filesecret" {
+			t.Errorf("expected jwtSecret 'filesecret', got %q", config.REST.JWTSecret)
+		}
+	})
+
+	t.Run("exec", func(t *testing.T) {
+		if _, err := os.Stat("/bin/echo"); err != nil {
+			t.Skip("/bin/echo not available")
+		}
+
+		config, err := ParseConfig([]byte(`
+server:
+  tlsKeyPassphrase: "${exec:/bin/echo execsecret}"
+`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if config.Server.TLSKeyPassphrase != "execsecret" {
+			t.Errorf("expected tlsKeyPassphrase 'execsecret', got %q", config.Server.TLSKeyPassphrase)
+		}
+	})
+
+	t.Run("exec timeout", func(t *testing.T) {
+		if _, err := os.Stat("/bin/sleep"); err != nil {
+			t.Skip("/bin/sleep not available")
+		}
+		defer func(timeout time.Duration) { secretExecTimeout = timeout }(secretExecTimeout)
+		secretExecTimeout = 50 * time.Millisecond
+
+		_, err := ParseConfig([]byte(`
+directory:
+  rootPassword: "${exec:/bin/sleep 5}"
+`))
+		if err == nil || !strings.Contains(err.Error(), "timed out") {
+			t.Errorf("expected timeout error, got %v", err)
+		}
+	})
+
+	t.Run("failures name the key", func(t *testing.T) {
+		os.Unsetenv("TEST_OBA_MISSING_SECRET")
+
+		_, err := ParseConfig([]byte(`
+directory:
+  rootPassword: "${env:TEST_OBA_MISSING_SECRET}"
+rest:
+  jwtSecret: "${file:/nonexistent/jwt-secret}"
+`))
+		if err == nil {
+			t.Fatal("expected error for unresolvable secrets")
+		}
+		for _, want := range []string{"directory.rootPassword", "TEST_OBA_MISSING_SECRET", "rest.jwtSecret"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected error to mention %q, got %v", want, err)
+			}
+		}
+	})
+
+	t.Run("only secret fields are resolved", func(t *testing.T) {
+		config, err := ParseConfig([]byte(`
+server:
+  address: "${env:TEST_OBA_ADDRESS}"
+`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if config.Server.Address != "${env:TEST_OBA_ADDRESS}" {
+			t.Errorf("expected address to be kept, got %q", config.Server.Address)
+		}
+	})
+
+	t.Run("with secret references", func(t *testing.T) {
+		t.Setenv("TEST_OBA_ROOT_PASSWORD", "envsecret")
+		t.Setenv("TEST_OBA_JWT_SECRET", "jwtsecret")
+
+		config, err := ParseConfig([]byte(`
+directory:
+  rootPassword: "${env:TEST_OBA_ROOT_PASSWORD}"
+rest:
+  jwtSecret: "${env:TEST_OBA_JWT_SECRET}"
+`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		config.REST.JWTSecret = "changed"
+
+		masked := config.WithSecretReferences()
+		if masked.Directory.RootPassword != "${env:TEST_OBA_ROOT_PASSWORD}" {
+			t.Errorf("expected rootPassword reference, got %q", masked.Directory.RootPassword)
+		}
+		if masked.REST.JWTSecret != "changed" {
+			t.Errorf("expected changed jwtSecret to be kept, got %q", masked.REST.JWTSecret)
+		}
+		if config.Directory.RootPassword != "envsecret" {
+			t.Errorf("expected original config to keep the secret, got %q", config.Directory.RootPassword)
+		}
+	})
+
+	t.Run("key file path resolution", func(t *testing.T) {
+		t.Setenv("TEST_OBA_KEY_FILE", "keys/oba.key")
+
+		config, err := ParseConfig([]byte(`
+security:
+  encryption:
+    keyFile: "${env:TEST_OBA_KEY_FILE}"
+`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := config.ResolvePaths(); err != nil {
+			t.Fatalf("ResolvePaths() error = %v", err)
+		}
+		if !filepath.IsAbs(config.Security.Encryption.KeyFile) {
+			t.Errorf("expected absolute key file, got %q", config.Security.Encryption.KeyFile)
+		}
+		if ref := config.SecretReference("security.encryption.keyFile"); ref != "${env:TEST_OBA_KEY_FILE}" {
+			t.Errorf("expected reference to survive path resolution, got %q", ref)
+		}
+	})
+}
+
+func TestSubstituteEnvVarsKeepsSecretReferences(t *testing.T) {
+	input := []byte("key: ${env:TEST_VAR} ${file:/run/secret} ${exec:fetch secret}")
+	if result := substituteEnvVars(input); string(result) != string(input) {
+		t.Errorf("expected %q, got %q", string(input), string(result))
+	}
+}
+
+func TestExternalJWTConfig(t *testing.T) {
+	config, err := ParseConfig([]byte(`
+rest:
+  externalJWTIssuer: "https://idp.example.com"
+  externalJWTAudience: "oba-rest"
+  jwksUri: "https://idp.example.com/.well-known/jwks.json"
+  claimTemplate: "uid={{.sub}},ou=users,dc=example,dc=com"
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.REST.ExternalJWTIssuer != "https://idp.example.com" {
+		t.Errorf("rest.externalJWTIssuer: got %q", config.REST.ExternalJWTIssuer)
+	}
+	if config.REST.ExternalJWTAudience != "oba-rest" {
+		t.Errorf("rest.externalJWTAudience: got %q", config.REST.ExternalJWTAudience)
+	}
+	if config.REST.JWKSUri != "https://idp.example.com/.well-known/jwks.json" {
+		t.Errorf("rest.jwksUri: got %q", config.REST.JWKSUri)
+	}
+	if config.REST.ClaimTemplate != "uid={{.sub}},ou=users,dc=example,dc=com" {
+		t.Errorf("rest.claimTemplate: got %q", config.REST.ClaimTemplate)
+	}
+	for _, err := range ValidateConfig(config) {
+		if ve, ok := err.(ValidationError); ok && strings.HasPrefix(ve.Field, "rest.") {
+			t.Errorf("unexpected validation error: %v", err)
+		}
+	}
+
+	restErrors := func() map[string]bool {
+		found := map[string]bool{}
+		for _, err := range ValidateConfig(config) {
+			if ve, ok := err.(ValidationError); ok && strings.HasPrefix(ve.Field, "rest.") {
+				found[ve.Field] = true
+			}
+		}
+		return found
+	}
+
+	// Plain http is only accepted for a loopback host
+	config.REST.JWKSUri = "http://127.0.0.1:8080/jwks"
+	if found := restErrors(); len(found) != 0 {
+		t.Errorf("unexpected validation errors for a loopback http JWKS URI: %v", found)
+	}
+	for _, uri := range []string{"http://idp.example.com/jwks", "ftp://idp.example.com/jwks"} {
+		config.REST.JWKSUri = uri
+		if !restErrors()["rest.jwksUri"] {
+			t.Errorf("expected rest.jwksUri validation error for %s", uri)
+		}
+	}
+
+	config.REST.ExternalJWTAudience = ""
+	config.REST.ClaimTemplate = "uid={{.sub"
+	found := restErrors()
+	for _, field := range []string{"rest.externalJWTAudience", "rest.jwksUri", "rest.claimTemplate"} {
+		if !found[field] {
+			t.Errorf("expected %s validation error", field)
+		}
+	}
+}
@@ -14,11 +14,34 @@ func DefaultConfig() *Config {
 			MaxConnections: 10000,
 			ReadTimeout:    30 * time.Second,
 			WriteTimeout:   30 * time.Second,
+			Fairness: FairnessConfig{
+				ExpensiveSearchThreshold: 10000,
+			},
+			PersistentSearch: PersistentSearchConfig{
+				QueueSize:      256,
+				OverflowPolicy: "drop",
+			},
 		},
 		Directory: DirectoryConfig{
 			BaseDN:       "",
 			RootDN:       "",
 			RootPassword: "",
+
+			MaxRenameSubtree:     10000,
+			ReferentialIntegrity: false,
+			RecycleBin: RecycleBinConfig{
+				Enabled:       false,
+				Retention:     30 * 24 * time.Hour,
+				PurgeInterval: time.Hour,
+			},
+			UIDNumber: UIDNumberConfig{
+				Enabled: false,
+				Start:   10000,
+			},
+			DynamicGroups: DynamicGroupsConfig{
+				Enabled:    false,
+				MaxMembers: 1000,
+			},
 		},
 		Storage: StorageConfig{
 			DataDir:            "/var/lib/oba",
@@ -32,6 +55,10 @@ func DefaultConfig() *Config {
 			Level:  "info",
 			Format: "json",
 			Output: "stdout",
+			WireDump: WireDumpConfig{
+				MaxBytes: 10 * 1024 * 1024,
+				Duration: time.Hour,
+			},
 		},
 		Security: SecurityConfig{
 			PasswordPolicy: PasswordPolicyConfig{
@@ -49,6 +76,13 @@ func DefaultConfig() *Config {
 				MaxAttempts:     5,
 				LockoutDuration: 15 * time.Minute,
 			},
+			BindThrottle: BindThrottleConfig{
+				Enabled:     false,
+				MaxFailures: 10,
+				Window:      5 * time.Minute,
+				BaseDelay:   time.Second,
+				MaxDelay:    30 * time.Second,
+			},
 		},
 		ACL: ACLConfig{
 			DefaultPolicy: "deny",
@@ -60,8 +94,23 @@ func DefaultConfig() *Config {
 			TLSAddress:  "",
 			JWTSecret:   "",
 			TokenTTL:    24 * time.Hour,
+			CursorTTL:   5 * time.Minute,
 			RateLimit:   100,
 			CORSOrigins: []string{"*"},
+			CORSMaxAge:  24 * time.Hour,
+		},
+		Monitoring: MonitoringConfig{
+			SNMPTrap: SNMPConfig{
+				Community:               "public",
+				Version:                 "2c",
+				ReplicationLagThreshold: 1000,
+				StorageFullPercent:      90,
+			},
+		},
+		Telemetry: TelemetryConfig{
+			Enabled:     false,
+			ServiceName: "oba",
+			SampleRatio: 1.0,
 		},
 	}
 }
@@ -45,6 +45,22 @@
 //	OBA_DIRECTORY_ROOTPASSWORD=secret
 //	OBA_LOGGING_LEVEL=debug
 //
+// # Secret References
+//
+// The secret values server.tlsKeyPassphrase, directory.rootPassword,
+// security.encryption.keyFile and rest.jwtSecret may refer to where the
+// secret is kept instead of containing it:
+//
+//	rootPassword: "${env:OBA_ROOT_PASSWORD}"
+//	rootPassword: "${file:/run/secrets/root-password}"
+//	rootPassword: "${exec:/usr/bin/fetch-secret oba-root}"
+//
+// References are resolved whenever the file is loaded, including hot
+// reloads. A reference that cannot be resolved fails the load with an
+// error naming the key. Config.WithSecretReferences restores the
+// references, so that shown or saved configurations do not contain the
+// resolved secrets.
+//
 // # Example Configuration
 //
 // A typical configuration file:
@@ -55,23 +55,77 @@ type ConfigJSON struct {
 	Security  SecurityConfigJSON  `json:"security"`
 	REST      RESTConfigJSON      `json:"rest"`
 	Storage   StorageConfigJSON   `json:"storage"`
+	Telemetry TelemetryConfigJSON `json:"telemetry"`
 }
 
 // DirectoryConfigJSON represents directory config in JSON.
 type DirectoryConfigJSON struct {
-	BaseDN string `json:"baseDN"`
-	RootDN string `json:"rootDN"`
+	BaseDN               string                  `json:"baseDN"`
+	RootDN               string                  `json:"rootDN"`
+	MaxRenameSubtree     int                     `json:"maxRenameSubtree"`
+	ReferentialIntegrity bool                    `json:"referentialIntegrity"`
+	MaxNumSubordinates   int                     `json:"maxNumSubordinates"`
+	RecycleBin           RecycleBinConfigJSON    `json:"recycleBin"`
+	UIDNumber            UIDNumberConfigJSON     `json:"uidNumber"`
+	DynamicGroups        DynamicGroupsConfigJSON `json:"dynamicGroups"`
+	AuditLogPath         string                  `json:"auditLogPath,omitempty"`
+}
+
+// DynamicGroupsConfigJSON represents dynamic group expansion config in JSON.
+type DynamicGroupsConfigJSON struct {
+	Enabled    bool `json:"enabled"`
+	MaxMembers int  `json:"maxMembers"`
+}
+
+// UIDNumberConfigJSON represents uidNumber auto-assignment config in JSON.
+type UIDNumberConfigJSON struct {
+	Enabled bool `json:"enabled"`
+	Start   int  `json:"start"`
+}
+
+// RecycleBinConfigJSON represents recycle bin config in JSON.
+type RecycleBinConfigJSON struct {
+	Enabled       bool   `json:"enabled"`
+	Retention     string `json:"retention"`
+	PurgeInterval string `json:"purgeInterval"`
 }
 
 // ServerConfigJSON represents server config in JSON.
 type ServerConfigJSON struct {
-	Address        string `json:"address"`
-	TLSAddress     string `json:"tlsAddress,omitempty"`
-	MaxConnections int    `json:"maxConnections"`
-	ReadTimeout    string `json:"readTimeout"`
-	WriteTimeout   string `json:"writeTimeout"`
-	TLSCert        string `json:"tlsCert,omitempty"`
-	TLSKey         string `json:"tlsKey,omitempty"`
+	Address        string             `json:"address"`
+	TLSAddress     string             `json:"tlsAddress,omitempty"`
+	MaxConnections int                `json:"maxConnections"`
+	ReadTimeout    string             `json:"readTimeout"`
+	WriteTimeout   string             `json:"writeTimeout"`
+	TLSCert        string             `json:"tlsCert,omitempty"`
+	TLSKey         string             `json:"tlsKey,omitempty"`
+	Fairness       FairnessConfigJSON `json:"fairness"`
+	Limits         []SearchLimitJSON  `json:"limits,omitempty"`
+
+	PersistentSearch PersistentSearchConfigJSON `json:"persistentSearch"`
+}
+
+// SearchLimitJSON represents a search limit profile in JSON.
+type SearchLimitJSON struct {
+	Subject   string `json:"subject"`
+	SizeLimit int    `json:"sizeLimit"`
+	TimeLimit int    `json:"timeLimit"`
+}
+
+// FairnessConfigJSON represents connection fairness config in JSON.
+type FairnessConfigJSON struct {
+	MaxBufferedEntries       int `json:"maxBufferedEntries"`
+	MaxExpensiveSearches     int `json:"maxExpensiveSearches"`
+	ExpensiveSearchThreshold int `json:"expensiveSearchThreshold"`
+	MaxOperationsPerBindDN   int `json:"maxOperationsPerBindDN"`
+}
+
+// PersistentSearchConfigJSON represents persistent search limits in JSON.
+type PersistentSearchConfigJSON struct {
+	QueueSize        int    `json:"queueSize"`
+	OverflowPolicy   string `json:"overflowPolicy"`
+	MaxPerConnection int    `json:"maxPerConnection"`
+	MaxPerBindDN     int    `json:"maxPerBindDN"`
 }
 
 // LogConfigJSON represents logging config in JSON.
@@ -84,8 +138,10 @@ type LogConfigJSON struct {
 // SecurityConfigJSON represents security config in JSON.
 type SecurityConfigJSON struct {
 	RateLimit      RateLimitConfigJSON      `json:"rateLimit"`
+	BindThrottle   BindThrottleConfigJSON   `json:"bindThrottle"`
 	PasswordPolicy PasswordPolicyConfigJSON `json:"passwordPolicy"`
 	Encryption     EncryptionConfigJSON     `json:"encryption"`
+	TLS            TLSConfigJSON            `json:"tls"`
 }
 
 // RateLimitConfigJSON represents rate limit config in JSON.
@@ -95,6 +151,17 @@ type RateLimitConfigJSON struct {
 	LockoutDuration string `json:"lockoutDuration"`
 }
 
+// BindThrottleConfigJSON represents bind throttle config in JSON.
+type BindThrottleConfigJSON struct {
+	Enabled     bool     `json:"enabled"`
+	MaxFailures int      `json:"maxFailures"`
+	Window      string   `json:"window"`
+	BaseDelay   string   `json:"baseDelay"`
+	MaxDelay    string   `json:"maxDelay"`
+	Allowlist   []string `json:"allowlist"`
+	StateFile   string   `json:"stateFile"`
+}
+
 // PasswordPolicyConfigJSON represents password policy config in JSON.
 type PasswordPolicyConfigJSON struct {
 	Enabled          bool   `json:"enabled"`
@@ -105,6 +172,7 @@ type PasswordPolicyConfigJSON struct {
 	RequireSpecial   bool   `json:"requireSpecial"`
 	MaxAge           string `json:"maxAge"`
 	HistoryCount     int    `json:"historyCount"`
+	GraceLogins      int    `json:"graceLogins"`
 }
 
 // EncryptionConfigJSON represents encryption config in JSON.
@@ -113,6 +181,12 @@ type EncryptionConfigJSON struct {
 	KeyFile string `json:"keyFile,omitempty"`
 }
 
+// TLSConfigJSON represents TLS protocol config in JSON.
+type TLSConfigJSON struct {
+	MinVersion          string   `json:"minVersion,omitempty"`
+	AllowedCipherSuites []string `json:"allowedCipherSuites,omitempty"`
+}
+
 // RESTConfigJSON represents REST config in JSON.
 type RESTConfigJSON struct {
 	Enabled     bool     `json:"enabled"`
@@ -131,6 +205,14 @@ type StorageConfigJSON struct {
 	CheckpointInterval string `json:"checkpointInterval"`
 }
 
+// TelemetryConfigJSON represents telemetry config in JSON.
+type TelemetryConfigJSON struct {
+	Enabled     bool    `json:"enabled"`
+	Endpoint    string  `json:"endpoint,omitempty"`
+	ServiceName string  `json:"serviceName"`
+	SampleRatio float64 `json:"sampleRatio"`
+}
+
 // ToJSON returns config as JSON-serializable struct with sensitive data masked.
 func (m *ConfigManager) ToJSON() *ConfigJSON {
 	m.mu.RLock()
@@ -145,10 +227,31 @@ func (m *ConfigManager) ToJSON() *ConfigJSON {
 			WriteTimeout:   m.config.Server.WriteTimeout.String(),
 			TLSCert:        m.config.Server.TLSCert,
 			TLSKey:         maskPath(m.config.Server.TLSKey),
+			Fairness:       FairnessConfigJSON(m.config.Server.Fairness),
+			Limits:         m.searchLimitsJSON(),
+
+			PersistentSearch: PersistentSearchConfigJSON(m.config.Server.PersistentSearch),
 		},
 		Directory: DirectoryConfigJSON{
-			BaseDN: m.config.Directory.BaseDN,
-			RootDN: m.config.Directory.RootDN,
+			BaseDN:               m.config.Directory.BaseDN,
+			RootDN:               m.config.Directory.RootDN,
+			MaxRenameSubtree:     m.config.Directory.MaxRenameSubtree,
+			ReferentialIntegrity: m.config.Directory.ReferentialIntegrity,
+			MaxNumSubordinates:   m.config.Directory.MaxNumSubordinates,
+			RecycleBin: RecycleBinConfigJSON{
+				Enabled:       m.config.Directory.RecycleBin.Enabled,
+				Retention:     m.config.Directory.RecycleBin.Retention.String(),
+				PurgeInterval: m.config.Directory.RecycleBin.PurgeInterval.String(),
+			},
+			UIDNumber: UIDNumberConfigJSON{
+				Enabled: m.config.Directory.UIDNumber.Enabled,
+				Start:   m.config.Directory.UIDNumber.Start,
+			},
+			DynamicGroups: DynamicGroupsConfigJSON{
+				Enabled:    m.config.Directory.DynamicGroups.Enabled,
+				MaxMembers: m.config.Directory.DynamicGroups.MaxMembers,
+			},
+			AuditLogPath: m.config.Directory.AuditLogPath,
 		},
 		Logging: LogConfigJSON{
 			Level:  m.config.Logging.Level,
@@ -161,6 +264,11 @@ func (m *ConfigManager) ToJSON() *ConfigJSON {
 				MaxAttempts:     m.config.Security.RateLimit.MaxAttempts,
 				LockoutDuration: m.config.Security.RateLimit.LockoutDuration.String(),
 			},
+			BindThrottle: m.bindThrottleJSON(),
+			TLS: TLSConfigJSON{
+				MinVersion:          m.config.Security.TLS.MinVersion,
+				AllowedCipherSuites: m.config.Security.TLS.AllowedCipherSuites,
+			},
 			PasswordPolicy: PasswordPolicyConfigJSON{
 				Enabled:          m.config.Security.PasswordPolicy.Enabled,
 				MinLength:        m.config.Security.PasswordPolicy.MinLength,
@@ -170,6 +278,7 @@ func (m *ConfigManager) ToJSON() *ConfigJSON {
 				RequireSpecial:   m.config.Security.PasswordPolicy.RequireSpecial,
 				MaxAge:           m.config.Security.PasswordPolicy.MaxAge.String(),
 				HistoryCount:     m.config.Security.PasswordPolicy.HistoryCount,
+				GraceLogins:      m.config.Security.PasswordPolicy.GraceLogins,
 			},
 			Encryption: EncryptionConfigJSON{
 				Enabled: m.config.Security.Encryption.Enabled,
@@ -190,6 +299,7 @@ func (m *ConfigManager) ToJSON() *ConfigJSON {
 			BufferPoolSize:     m.config.Storage.BufferPoolSize,
 			CheckpointInterval: m.config.Storage.CheckpointInterval.String(),
 		},
+		Telemetry: m.telemetryJSON(),
 	}
 }
 
@@ -208,6 +318,10 @@ func (m *ConfigManager) GetSection(section string) (interface{}, error) {
 			WriteTimeout:   m.config.Server.WriteTimeout.String(),
 			TLSCert:        m.config.Server.TLSCert,
 			TLSKey:         maskPath(m.config.Server.TLSKey),
+			Fairness:       FairnessConfigJSON(m.config.Server.Fairness),
+			Limits:         m.searchLimitsJSON(),
+
+			PersistentSearch: PersistentSearchConfigJSON(m.config.Server.PersistentSearch),
 		}, nil
 	case "logging":
 		return LogConfigJSON{
@@ -222,6 +336,11 @@ func (m *ConfigManager) GetSection(section string) (interface{}, error) {
 				MaxAttempts:     m.config.Security.RateLimit.MaxAttempts,
 				LockoutDuration: m.config.Security.RateLimit.LockoutDuration.String(),
 			},
+			BindThrottle: m.bindThrottleJSON(),
+			TLS: TLSConfigJSON{
+				MinVersion:          m.config.Security.TLS.MinVersion,
+				AllowedCipherSuites: m.config.Security.TLS.AllowedCipherSuites,
+			},
 			PasswordPolicy: PasswordPolicyConfigJSON{
 				Enabled:          m.config.Security.PasswordPolicy.Enabled,
 				MinLength:        m.config.Security.PasswordPolicy.MinLength,
@@ -231,6 +350,7 @@ func (m *ConfigManager) GetSection(section string) (interface{}, error) {
 				RequireSpecial:   m.config.Security.PasswordPolicy.RequireSpecial,
 				MaxAge:           m.config.Security.PasswordPolicy.MaxAge.String(),
 				HistoryCount:     m.config.Security.PasswordPolicy.HistoryCount,
+				GraceLogins:      m.config.Security.PasswordPolicy.GraceLogins,
 			},
 			Encryption: EncryptionConfigJSON{
 				Enabled: m.config.Security.Encryption.Enabled,
@@ -253,6 +373,8 @@ func (m *ConfigManager) GetSection(section string) (interface{}, error) {
 			BufferPoolSize:     m.config.Storage.BufferPoolSize,
 			CheckpointInterval: m.config.Storage.CheckpointInterval.String(),
 		}, nil
+	case "telemetry":
+		return m.telemetryJSON(), nil
 	default:
 		return nil, fmt.Errorf("unknown section: %s", section)
 	}
@@ -333,6 +455,9 @@ func (m *ConfigManager) UpdateSection(section string, data map[string]interface{
 		if v, ok := data["historyCount"].(float64); ok {
 			newConfig.Security.PasswordPolicy.HistoryCount = int(v)
 		}
+		if v, ok := data["graceLogins"].(float64); ok {
+			newConfig.Security.PasswordPolicy.GraceLogins = int(v)
+		}
 	case "rest":
 		if v, ok := data["rateLimit"].(float64); ok {
 			newConfig.REST.RateLimit = int(v)
@@ -417,76 +542,282 @@ func (m *ConfigManager) SaveToFile() error {
 
 // configToYAML converts config to YAML string.
 func (m *ConfigManager) configToYAML() string {
+	// Secrets are written as the references they were resolved from
+	cfg := m.config.WithSecretReferences()
+
 	var sb strings.Builder
 
 	sb.WriteString("server:\n")
-	sb.WriteString(fmt.Sprintf("  address: %q\n", m.config.Server.Address))
-	if m.config.Server.TLSAddress != "" {
-		sb.WriteString(fmt.Sprintf("  tlsAddress: %q\n", m.config.Server.TLSAddress))
+	sb.WriteString(fmt.Sprintf("  address: %q\n", cfg.Server.Address))
+	if cfg.Server.TLSAddress != "" {
+		sb.WriteString(fmt.Sprintf("  tlsAddress: %q\n", cfg.Server.TLSAddress))
+	}
+	sb.WriteString(fmt.Sprintf("  maxConnections: %d\n", cfg.Server.MaxConnections))
+	sb.WriteString(fmt.Sprintf("  readTimeout: %s\n", cfg.Server.ReadTimeout))
+	sb.WriteString(fmt.Sprintf("  writeTimeout: %s\n", cfg.Server.WriteTimeout))
+	if cfg.Server.TLSKeyPassphrase != "" {
+		sb.WriteString(fmt.Sprintf("  tlsKeyPassphrase: %q\n", cfg.Server.TLSKeyPassphrase))
+	}
+	if cfg.Server.IdleTimeout > 0 {
+		sb.WriteString(fmt.Sprintf("  idleTimeout: %s\n", cfg.Server.IdleTimeout))
+	}
+	if cfg.Server.PIDFile != "" {
+		sb.WriteString(fmt.Sprintf("  pidFile: %q\n", cfg.Server.PIDFile))
+	}
+	if cfg.Server.ProxyProtocol {
+		sb.WriteString("  proxyProtocol: true\n")
+	}
+	if len(cfg.Server.TrustedProxies) > 0 {
+		sb.WriteString(fmt.Sprintf("  trustedProxies: %s\n", formatInlineArray(cfg.Server.TrustedProxies)))
 	}
-	sb.WriteString(fmt.Sprintf("  maxConnections: %d\n", m.config.Server.MaxConnections))
-	sb.WriteString(fmt.Sprintf("  readTimeout: %s\n", m.config.Server.ReadTimeout))
-	sb.WriteString(fmt.Sprintf("  writeTimeout: %s\n", m.config.Server.WriteTimeout))
-	if m.config.Server.PIDFile != "" {
-		sb.WriteString(fmt.Sprintf("  pidFile: %q\n", m.config.Server.PIDFile))
+	if f := cfg.Server.Fairness; f.MaxBufferedEntries > 0 || f.MaxExpensiveSearches > 0 || f.MaxOperationsPerBindDN > 0 {
+		sb.WriteString("  fairness:\n")
+		sb.WriteString(fmt.Sprintf("    maxBufferedEntries: %d\n", f.MaxBufferedEntries))
+		sb.WriteString(fmt.Sprintf("    maxExpensiveSearches: %d\n", f.MaxExpensiveSearches))
+		sb.WriteString(fmt.Sprintf("    expensiveSearchThreshold: %d\n", f.ExpensiveSearchThreshold))
+		sb.WriteString(fmt.Sprintf("    maxOperationsPerBindDN: %d\n", f.MaxOperationsPerBindDN))
+	}
+	ps := cfg.Server.PersistentSearch
+	sb.WriteString("  persistentSearch:\n")
+	sb.WriteString(fmt.Sprintf("    queueSize: %d\n", ps.QueueSize))
+	if ps.OverflowPolicy != "" {
+		sb.WriteString(fmt.Sprintf("    overflowPolicy: %s\n", ps.OverflowPolicy))
+	}
+	if ps.MaxPerConnection > 0 {
+		sb.WriteString(fmt.Sprintf("    maxPerConnection: %d\n", ps.MaxPerConnection))
+	}
+	if ps.MaxPerBindDN > 0 {
+		sb.WriteString(fmt.Sprintf("    maxPerBindDN: %d\n", ps.MaxPerBindDN))
+	}
+	if len(cfg.Server.Limits) > 0 {
+		sb.WriteString("  limits:\n")
+		for _, limit := range cfg.Server.Limits {
+			sb.WriteString(fmt.Sprintf("    - subject: %q\n", limit.Subject))
+			if limit.SizeLimit != LimitDefault {
+				sb.WriteString(fmt.Sprintf("      sizeLimit: %s\n", formatSearchLimit(limit.SizeLimit)))
+			}
+			if limit.TimeLimit != LimitDefault {
+				sb.WriteString(fmt.Sprintf("      timeLimit: %s\n", formatSearchLimit(limit.TimeLimit)))
+			}
+		}
 	}
 
 	sb.WriteString("\ndirectory:\n")
-	sb.WriteString(fmt.Sprintf("  baseDN: %q\n", m.config.Directory.BaseDN))
-	sb.WriteString(fmt.Sprintf("  rootDN: %q\n", m.config.Directory.RootDN))
-	sb.WriteString(fmt.Sprintf("  rootPassword: %q\n", m.config.Directory.RootPassword))
+	sb.WriteString(fmt.Sprintf("  baseDN: %q\n", cfg.Directory.BaseDN))
+	sb.WriteString(fmt.Sprintf("  rootDN: %q\n", cfg.Directory.RootDN))
+	sb.WriteString(fmt.Sprintf("  rootPassword: %q\n", cfg.Directory.RootPassword))
+	sb.WriteString(fmt.Sprintf("  maxRenameSubtree: %d\n", cfg.Directory.MaxRenameSubtree))
+	if cfg.Directory.ReferentialIntegrity {
+		sb.WriteString("  referentialIntegrity: true\n")
+	}
+	if cfg.Directory.MaxNumSubordinates > 0 {
+		sb.WriteString(fmt.Sprintf("  maxNumSubordinates: %d\n", cfg.Directory.MaxNumSubordinates))
+	}
+	if rb := cfg.Directory.RecycleBin; rb.Enabled {
+		sb.WriteString("  recycleBin:\n")
+		sb.WriteString("    enabled: true\n")
+		sb.WriteString(fmt.Sprintf("    retention: %s\n", rb.Retention))
+		sb.WriteString(fmt.Sprintf("    purgeInterval: %s\n", rb.PurgeInterval))
+	}
+	if un := cfg.Directory.UIDNumber; un.Enabled {
+		sb.WriteString("  uidNumber:\n")
+		sb.WriteString("    enabled: true\n")
+		sb.WriteString(fmt.Sprintf("    start: %d\n", un.Start))
+	}
+	if dg := cfg.Directory.DynamicGroups; dg.Enabled {
+		sb.WriteString("  dynamicGroups:\n")
+		sb.WriteString("    enabled: true\n")
+		sb.WriteString(fmt.Sprintf("    maxMembers: %d\n", dg.MaxMembers))
+	}
+	if cfg.Directory.AuditLogPath != "" {
+		sb.WriteString(fmt.Sprintf("  auditLogPath: %q\n", cfg.Directory.AuditLogPath))
+	}
 
 	sb.WriteString("\nstorage:\n")
-	sb.WriteString(fmt.Sprintf("  dataDir: %q\n", m.config.Storage.DataDir))
-	sb.WriteString(fmt.Sprintf("  pageSize: %d\n", m.config.Storage.PageSize))
-	sb.WriteString(fmt.Sprintf("  bufferPoolSize: %q\n", m.config.Storage.BufferPoolSize))
-	sb.WriteString(fmt.Sprintf("  checkpointInterval: %s\n", m.config.Storage.CheckpointInterval))
+	sb.WriteString(fmt.Sprintf("  dataDir: %q\n", cfg.Storage.DataDir))
+	sb.WriteString(fmt.Sprintf("  pageSize: %d\n", cfg.Storage.PageSize))
+	sb.WriteString(fmt.Sprintf("  bufferPoolSize: %q\n", cfg.Storage.BufferPoolSize))
+	sb.WriteString(fmt.Sprintf("  checkpointInterval: %s\n", cfg.Storage.CheckpointInterval))
 
 	sb.WriteString("\nsecurity:\n")
 	sb.WriteString("  encryption:\n")
-	sb.WriteString(fmt.Sprintf("    enabled: %t\n", m.config.Security.Encryption.Enabled))
-	if m.config.Security.Encryption.KeyFile != "" {
-		sb.WriteString(fmt.Sprintf("    keyFile: %q\n", m.config.Security.Encryption.KeyFile))
+	sb.WriteString(fmt.Sprintf("    enabled: %t\n", cfg.Security.Encryption.Enabled))
+	if cfg.Security.Encryption.KeyFile != "" {
+		sb.WriteString(fmt.Sprintf("    keyFile: %q\n", cfg.Security.Encryption.KeyFile))
 	}
 	sb.WriteString("  rateLimit:\n")
-	sb.WriteString(fmt.Sprintf("    enabled: %t\n", m.config.Security.RateLimit.Enabled))
-	sb.WriteString(fmt.Sprintf("    maxAttempts: %d\n", m.config.Security.RateLimit.MaxAttempts))
-	sb.WriteString(fmt.Sprintf("    lockoutDuration: %s\n", m.config.Security.RateLimit.LockoutDuration))
+	sb.WriteString(fmt.Sprintf("    enabled: %t\n", cfg.Security.RateLimit.Enabled))
+	sb.WriteString(fmt.Sprintf("    maxAttempts: %d\n", cfg.Security.RateLimit.MaxAttempts))
+	sb.WriteString(fmt.Sprintf("    lockoutDuration: %s\n", cfg.Security.RateLimit.LockoutDuration))
+	sb.WriteString("  bindThrottle:\n")
+	sb.WriteString(fmt.Sprintf("    enabled: %t\n", cfg.Security.BindThrottle.Enabled))
+	sb.WriteString(fmt.Sprintf("    maxFailures: %d\n", cfg.Security.BindThrottle.MaxFailures))
+	sb.WriteString(fmt.Sprintf("    window: %s\n", cfg.Security.BindThrottle.Window))
+	sb.WriteString(fmt.Sprintf("    baseDelay: %s\n", cfg.Security.BindThrottle.BaseDelay))
+	sb.WriteString(fmt.Sprintf("    maxDelay: %s\n", cfg.Security.BindThrottle.MaxDelay))
+	if len(cfg.Security.BindThrottle.Allowlist) > 0 {
+		sb.WriteString(fmt.Sprintf("    allowlist: %s\n", formatInlineArray(cfg.Security.BindThrottle.Allowlist)))
+	}
+	if cfg.Security.BindThrottle.StateFile != "" {
+		sb.WriteString(fmt.Sprintf("    stateFile: %q\n", cfg.Security.BindThrottle.StateFile))
+	}
+	if cfg.Security.TLS.MinVersion != "" || len(cfg.Security.TLS.AllowedCipherSuites) > 0 {
+		sb.WriteString("  tls:\n")
+		if cfg.Security.TLS.MinVersion != "" {
+			sb.WriteString(fmt.Sprintf("    minVersion: %q\n", cfg.Security.TLS.MinVersion))
+		}
+		if len(cfg.Security.TLS.AllowedCipherSuites) > 0 {
+			sb.WriteString(fmt.Sprintf("    allowedCipherSuites: %s\n", formatInlineArray(cfg.Security.TLS.AllowedCipherSuites)))
+		}
+	}
 	sb.WriteString("  passwordPolicy:\n")
-	sb.WriteString(fmt.Sprintf("    enabled: %t\n", m.config.Security.PasswordPolicy.Enabled))
-	sb.WriteString(fmt.Sprintf("    minLength: %d\n", m.config.Security.PasswordPolicy.MinLength))
+	sb.WriteString(fmt.Sprintf("    enabled: %t\n", cfg.Security.PasswordPolicy.Enabled))
+	sb.WriteString(fmt.Sprintf("    minLength: %d\n", cfg.Security.PasswordPolicy.MinLength))
 
 	sb.WriteString("\nlogging:\n")
-	sb.WriteString(fmt.Sprintf("  level: %q\n", m.config.Logging.Level))
-	sb.WriteString(fmt.Sprintf("  format: %q\n", m.config.Logging.Format))
-	sb.WriteString(fmt.Sprintf("  output: %q\n", m.config.Logging.Output))
+	sb.WriteString(fmt.Sprintf("  level: %q\n", cfg.Logging.Level))
+	sb.WriteString(fmt.Sprintf("  format: %q\n", cfg.Logging.Format))
+	sb.WriteString(fmt.Sprintf("  output: %q\n", cfg.Logging.Output))
 
-	if m.config.ACLFile != "" {
-		sb.WriteString(fmt.Sprintf("\naclFile: %q\n", m.config.ACLFile))
+	if cfg.ACLFile != "" {
+		sb.WriteString(fmt.Sprintf("\naclFile: %q\n", cfg.ACLFile))
 	}
 
 	sb.WriteString("\nrest:\n")
-	sb.WriteString(fmt.Sprintf("  enabled: %t\n", m.config.REST.Enabled))
-	sb.WriteString(fmt.Sprintf("  address: %q\n", m.config.REST.Address))
-	sb.WriteString(fmt.Sprintf("  jwtSecret: %q\n", m.config.REST.JWTSecret))
-	sb.WriteString(fmt.Sprintf("  tokenTTL: %s\n", m.config.REST.TokenTTL))
-	sb.WriteString(fmt.Sprintf("  rateLimit: %d\n", m.config.REST.RateLimit))
+	sb.WriteString(fmt.Sprintf("  enabled: %t\n", cfg.REST.Enabled))
+	sb.WriteString(fmt.Sprintf("  address: %q\n", cfg.REST.Address))
+	sb.WriteString(fmt.Sprintf("  jwtSecret: %q\n", cfg.REST.JWTSecret))
+	sb.WriteString(fmt.Sprintf("  tokenTTL: %s\n", cfg.REST.TokenTTL))
+	sb.WriteString(fmt.Sprintf("  cursorTTL: %s\n", cfg.REST.CursorTTL))
+	sb.WriteString(fmt.Sprintf("  rateLimit: %d\n", cfg.REST.RateLimit))
 	sb.WriteString("  corsOrigins:\n")
-	for _, origin := range m.config.REST.CORSOrigins {
+	for _, origin := range cfg.REST.CORSOrigins {
 		sb.WriteString(fmt.Sprintf("    - %q\n", origin))
 	}
+	if cfg.REST.JWKSUri != "" {
+		sb.WriteString(fmt.Sprintf("  externalJWTIssuer: %q\n", cfg.REST.ExternalJWTIssuer))
+		sb.WriteString(fmt.Sprintf("  externalJWTAudience: %q\n", cfg.REST.ExternalJWTAudience))
+		sb.WriteString(fmt.Sprintf("  jwksUri: %q\n", cfg.REST.JWKSUri))
+		sb.WriteString(fmt.Sprintf("  claimTemplate: %q\n", cfg.REST.ClaimTemplate))
+	}
+
+	sc := cfg.Schema
+	if len(sc.Builtin) > 0 || sc.Strict || sc.ObjectClassCascade || sc.StructureRules.Enabled || len(sc.StructureRules.Rules) > 0 {
+		sb.WriteString("\nschema:\n")
+	}
+	if len(sc.Builtin) > 0 {
+		sb.WriteString(fmt.Sprintf("  builtin: %s\n", formatInlineArray(sc.Builtin)))
+	}
+	if sc.Strict {
+		sb.WriteString("  strict: true\n")
+	}
+	if sc.ObjectClassCascade {
+		sb.WriteString("  objectClassCascade: true\n")
+	}
+	if sr := sc.StructureRules; sr.Enabled || len(sr.Rules) > 0 {
+		sb.WriteString("  structureRules:\n")
+		sb.WriteString(fmt.Sprintf("    enabled: %t\n", sr.Enabled))
+		sb.WriteString("    rules:\n")
+		for _, rule := range sr.Rules {
+			sb.WriteString(fmt.Sprintf("      - objectClass: %q\n", rule.ObjectClass))
+			if len(rule.Parents) > 0 {
+				sb.WriteString(fmt.Sprintf("        parents: %s\n", formatInlineArray(rule.Parents)))
+			}
+			if len(rule.NamingAttributes) > 0 {
+				sb.WriteString(fmt.Sprintf("        namingAttributes: %s\n", formatInlineArray(rule.NamingAttributes)))
+			}
+		}
+	}
+
+	if cfg.Monitoring.PrometheusAddr != "" || cfg.Monitoring.SNMPTrap.Enabled {
+		sb.WriteString("\nmonitoring:\n")
+		if cfg.Monitoring.PrometheusAddr != "" {
+			sb.WriteString(fmt.Sprintf("  prometheusAddr: %q\n", cfg.Monitoring.PrometheusAddr))
+		}
+		if snmp := cfg.Monitoring.SNMPTrap; snmp.Enabled {
+			sb.WriteString("  snmpTrap:\n")
+			sb.WriteString("    enabled: true\n")
+			sb.WriteString(fmt.Sprintf("    target: %q\n", snmp.Target))
+			sb.WriteString(fmt.Sprintf("    community: %q\n", snmp.Community))
+			sb.WriteString(fmt.Sprintf("    version: %q\n", snmp.Version))
+			sb.WriteString(fmt.Sprintf("    replicationLagThreshold: %d\n", snmp.ReplicationLagThreshold))
+			sb.WriteString(fmt.Sprintf("    storageFullPercent: %d\n", snmp.StorageFullPercent))
+		}
+	}
+
+	if cfg.Telemetry.Enabled {
+		sb.WriteString("\ntelemetry:\n")
+		sb.WriteString("  enabled: true\n")
+		sb.WriteString(fmt.Sprintf("  endpoint: %q\n", cfg.Telemetry.Endpoint))
+		sb.WriteString(fmt.Sprintf("  serviceName: %q\n", cfg.Telemetry.ServiceName))
+		sb.WriteString(fmt.Sprintf("  sampleRatio: %s\n", strconv.FormatFloat(cfg.Telemetry.SampleRatio, 'g', -1, 64)))
+	}
 
 	return sb.String()
 }
 
+// formatInlineArray formats a string slice as an inline YAML array.
+func formatInlineArray(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// formatSearchLimit formats a search limit, writing 0 as unlimited.
+func formatSearchLimit(limit int) string {
+	if limit == 0 {
+		return "unlimited"
+	}
+	return strconv.Itoa(limit)
+}
+
 // copyConfig creates a deep copy of config.
 func copyConfig(c *Config) *Config {
 	newConfig := *c
 	newConfig.REST.CORSOrigins = make([]string, len(c.REST.CORSOrigins))
 	copy(newConfig.REST.CORSOrigins, c.REST.CORSOrigins)
+	newConfig.Server.TrustedProxies = append([]string(nil), c.Server.TrustedProxies...)
+	newConfig.Server.Limits = append([]SearchLimitConfig(nil), c.Server.Limits...)
+	newConfig.Security.BindThrottle.Allowlist = append([]string(nil), c.Security.BindThrottle.Allowlist...)
+	newConfig.Security.TLS.AllowedCipherSuites = append([]string(nil), c.Security.TLS.AllowedCipherSuites...)
 	return &newConfig
 }
 
+// searchLimitsJSON returns the search limit profiles in JSON form.
+func (m *ConfigManager) searchLimitsJSON() []SearchLimitJSON {
+	var limits []SearchLimitJSON
+	for _, limit := range m.config.Server.Limits {
+		limits = append(limits, SearchLimitJSON(limit))
+	}
+	return limits
+}
+
+// bindThrottleJSON returns the bind throttle settings in JSON form.
+func (m *ConfigManager) bindThrottleJSON() BindThrottleConfigJSON {
+	bt := m.config.Security.BindThrottle
+	return BindThrottleConfigJSON{
+		Enabled:     bt.Enabled,
+		MaxFailures: bt.MaxFailures,
+		Window:      bt.Window.String(),
+		BaseDelay:   bt.BaseDelay.String(),
+		MaxDelay:    bt.MaxDelay.String(),
+		Allowlist:   bt.Allowlist,
+		StateFile:   bt.StateFile,
+	}
+}
+
+// telemetryJSON returns the telemetry section in JSON form.
+func (m *ConfigManager) telemetryJSON() TelemetryConfigJSON {
+	return TelemetryConfigJSON{
+		Enabled:     m.config.Telemetry.Enabled,
+		Endpoint:    m.config.Telemetry.Endpoint,
+		ServiceName: m.config.Telemetry.ServiceName,
+		SampleRatio: m.config.Telemetry.SampleRatio,
+	}
+}
+
 // maskPath masks sensitive file paths (shows path but indicates it's sensitive).
 func maskPath(path string) string {
 	if path == "" {
@@ -589,6 +920,11 @@ func (m *ConfigManager) ApplyFromRaft(section string, data map[string]string) er
 				newConfig.Security.PasswordPolicy.HistoryCount = i
 			}
 		}
+		if v, ok := data["graceLogins"]; ok {
+			if i, err := strconv.Atoi(v); err == nil {
+				newConfig.Security.PasswordPolicy.GraceLogins = i
+			}
+		}
 	case "rest":
 		if v, ok := data["rateLimit"]; ok {
 			if i, err := strconv.Atoi(v); err == nil {
@@ -654,6 +990,7 @@ func (m *ConfigManager) GetConfigSnapshot() ([]byte, error) {
 	snapshot.Data["security.passwordpolicy.requireSpecial"] = strconv.FormatBool(m.config.Security.PasswordPolicy.RequireSpecial)
 	snapshot.Data["security.passwordpolicy.maxAge"] = m.config.Security.PasswordPolicy.MaxAge.String()
 	snapshot.Data["security.passwordpolicy.historyCount"] = strconv.Itoa(m.config.Security.PasswordPolicy.HistoryCount)
+	snapshot.Data["security.passwordpolicy.graceLogins"] = strconv.Itoa(m.config.Security.PasswordPolicy.GraceLogins)
 	snapshot.Data["rest.rateLimit"] = strconv.Itoa(m.config.REST.RateLimit)
 	snapshot.Data["rest.tokenTTL"] = m.config.REST.TokenTTL.String()
 	snapshot.Data["rest.corsOrigins"] = strings.Join(m.config.REST.CORSOrigins, ",")
@@ -736,6 +1073,11 @@ func (m *ConfigManager) RestoreConfigSnapshot(data []byte) error {
 			m.config.Security.PasswordPolicy.HistoryCount = i
 		}
 	}
+	if v, ok := snapshot.Data["security.passwordpolicy.graceLogins"]; ok {
+		if i, err := strconv.Atoi(v); err == nil {
+			m.config.Security.PasswordPolicy.GraceLogins = i
+		}
+	}
 	if v, ok := snapshot.Data["rest.rateLimit"]; ok {
 		if i, err := strconv.Atoi(v); err == nil {
 			m.config.REST.RateLimit = i
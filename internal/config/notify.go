@@ -0,0 +1,30 @@
+package config
+
+import "errors"
+
+// errNotifyUnsupported is returned by newFileNotifier on platforms without
+// file system events.
+var errNotifyUnsupported = errors.New("file system events are not supported")
+
+// fileNotifier reports file system events that may have changed a file.
+type fileNotifier interface {
+	// Events receives a value after events for the file. Events that
+	// arrive before the previous one is received are merged with it.
+	Events() <-chan struct{}
+	// Close stops watching.
+	Close() error
+}
+
+// configMapDataDir is the entry that Kubernetes atomically swaps, by
+// renaming a symlink, when it updates a mounted ConfigMap. The config
+// file is a symlink through it, so events for the file name itself do not
+// arrive.
+const configMapDataDir = "..data"
+
+// notifyPending sends a value on ch unless one is already waiting.
+func notifyPending(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
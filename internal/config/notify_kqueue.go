@@ -0,0 +1,142 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// kqueuePollTimeout bounds a wait for events, so that Close is noticed.
+const kqueuePollTimeout = 200 * time.Millisecond
+
+// Vnode events that may change a watched file or directory.
+const (
+	kqueueDirFlags  = syscall.NOTE_WRITE
+	kqueueFileFlags = syscall.NOTE_WRITE | syscall.NOTE_EXTEND | syscall.NOTE_ATTRIB |
+		syscall.NOTE_DELETE | syscall.NOTE_RENAME
+)
+
+// kqueueNotifier watches a file and its directory with kqueue. Directory
+// events do not name the entry that changed, so every event is reported;
+// the watcher checks whether the file changed. The file is opened again
+// after each event, since it may have been replaced.
+type kqueueNotifier struct {
+	path    string
+	kq      int
+	dirFd   int
+	fileFd  int
+	events  chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// newFileNotifier watches path and its directory with kqueue.
+func newFileNotifier(path string) (fileNotifier, error) {
+	kq, err := syscall.Kqueue()
+	if err != nil {
+		return nil, os.NewSyscallError("kqueue", err)
+	}
+	syscall.CloseOnExec(kq)
+
+	dirFd, err := syscall.Open(filepath.Dir(path), syscall.O_RDONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		syscall.Close(kq)
+		return nil, &os.PathError{Op: "open", Path: filepath.Dir(path), Err: err}
+	}
+
+	n := &kqueueNotifier{
+		path:    path,
+		kq:      kq,
+		dirFd:   dirFd,
+		fileFd:  -1,
+		events:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if err := n.register(dirFd, kqueueDirFlags); err != nil {
+		syscall.Close(dirFd)
+		syscall.Close(kq)
+		return nil, err
+	}
+	n.openFile()
+
+	go n.waitLoop()
+	return n, nil
+}
+
+// Events returns the channel receiving events for the file.
+func (n *kqueueNotifier) Events() <-chan struct{} {
+	return n.events
+}
+
+// Close stops watching.
+func (n *kqueueNotifier) Close() error {
+	close(n.done)
+	<-n.stopped
+
+	if n.fileFd >= 0 {
+		syscall.Close(n.fileFd)
+	}
+	syscall.Close(n.dirFd)
+	return syscall.Close(n.kq)
+}
+
+// register adds a vnode filter for fd.
+func (n *kqueueNotifier) register(fd int, fflags uint32) error {
+	var change syscall.Kevent_t
+	syscall.SetKevent(&change, fd, syscall.EVFILT_VNODE, syscall.EV_ADD|syscall.EV_ENABLE|syscall.EV_CLEAR)
+	change.Fflags = fflags
+	if _, err := syscall.Kevent(n.kq, []syscall.Kevent_t{change}, nil, nil); err != nil {
+		return os.NewSyscallError("kevent", err)
+	}
+	return nil
+}
+
+// openFile watches the file now at the path, if there is one. Closing the
+// previous descriptor removes its filter.
+func (n *kqueueNotifier) openFile() {
+	if n.fileFd >= 0 {
+		syscall.Close(n.fileFd)
+		n.fileFd = -1
+	}
+
+	fd, err := syscall.Open(n.path, syscall.O_RDONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return
+	}
+	if err := n.register(fd, kqueueFileFlags); err != nil {
+		syscall.Close(fd)
+		return
+	}
+	n.fileFd = fd
+}
+
+// waitLoop waits for kqueue events until the notifier is closed.
+func (n *kqueueNotifier) waitLoop() {
+	defer close(n.stopped)
+
+	events := make([]syscall.Kevent_t, 8)
+	timeout := syscall.NsecToTimespec(int64(kqueuePollTimeout))
+	for {
+		select {
+		case <-n.done:
+			return
+		default:
+		}
+
+		count, err := syscall.Kevent(n.kq, nil, events, &timeout)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return
+		}
+		if count > 0 {
+			n.openFile()
+			notifyPending(n.events)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// inotifyMask selects the directory events that may change a file in it:
+// writes, creation, and renames into place.
+const inotifyMask = syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE | syscall.IN_ATTRIB |
+	syscall.IN_CREATE | syscall.IN_MOVED_TO
+
+// inotifyNotifier watches the directory of a file with inotify and reports
+// the events for the file name.
+type inotifyNotifier struct {
+	file    *os.File
+	name    string
+	events  chan struct{}
+	stopped chan struct{}
+}
+
+// newFileNotifier watches the directory of path with inotify.
+func newFileNotifier(path string) (fileNotifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	if _, err := syscall.InotifyAddWatch(fd, filepath.Dir(path), inotifyMask); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("inotify_add_watch", err)
+	}
+
+	// The descriptor is non-blocking, so reads go through the runtime
+	// poller and Close interrupts a pending read
+	n := &inotifyNotifier{
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		name:    filepath.Base(path),
+		events:  make(chan struct{}, 1),
+		stopped: make(chan struct{}),
+	}
+	go n.readLoop()
+	return n, nil
+}
+
+// Events returns the channel receiving events for the file.
+func (n *inotifyNotifier) Events() <-chan struct{} {
+	return n.events
+}
+
+// Close stops watching.
+func (n *inotifyNotifier) Close() error {
+	err := n.file.Close()
+	<-n.stopped
+	return err
+}
+
+// readLoop reads inotify events until the notifier is closed.
+func (n *inotifyNotifier) readLoop() {
+	defer close(n.stopped)
+
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		count, err := n.file.Read(buf)
+		if err != nil {
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= count; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			nameEnd := nameStart + int(event.Len)
+			if nameEnd > count {
+				break
+			}
+			name := string(trimNUL(buf[nameStart:nameEnd]))
+			offset = nameEnd
+
+			// An overflow may have dropped events for the file
+			if event.Mask&syscall.IN_Q_OVERFLOW != 0 || name == n.name || name == configMapDataDir {
+				notifyPending(n.events)
+			}
+		}
+	}
+}
+
+// trimNUL strips the NUL padding of an inotify event name.
+func trimNUL(b []byte) []byte {
+	for i, c := range b {
+		if c == 0 {
+			return b[:i]
+		}
+	}
+	return b
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package config
+
+// newFileNotifier reports that the platform has no file system events, so
+// the watcher polls.
+func newFileNotifier(path string) (fileNotifier, error) {
+	return nil, errNotifyUnsupported
+}
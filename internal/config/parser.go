@@ -52,10 +52,16 @@ func ParseConfig(data []byte) (*Config, error) {
 		return nil, err
 	}
 
+	// Resolve the secret references of secret values
+	if errs := resolveSecrets(config); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
 	return config, nil
 }
 
 // substituteEnvVars replaces ${VAR} and ${VAR:-default} patterns with environment variable values.
+// Secret references (${env:...}, ${file:...} and ${exec:...}) are left for resolveSecrets.
 func substituteEnvVars(data []byte) []byte {
 	// Pattern matches ${VAR} or ${VAR:-default}
 	re := regexp.MustCompile(`\$\{([^}]+)\}`)
@@ -63,6 +69,9 @@ func substituteEnvVars(data []byte) []byte {
 	return re.ReplaceAllFunc(data, func(match []byte) []byte {
 		// Extract content between ${ and }
 		content := string(match[2 : len(match)-1])
+		if isSecretReference(content) {
+			return match
+		}
 
 		// Check for default value syntax: VAR:-default
 		if idx := strings.Index(content, ":-"); idx != -1 {
@@ -308,6 +317,18 @@ func applyConfig(root *yamlNode, config *Config) error {
 			if err := applyClusterConfig(node, &config.Cluster); err != nil {
 				return err
 			}
+		case "schema":
+			if err := applySchemaConfig(node, &config.Schema); err != nil {
+				return err
+			}
+		case "monitoring":
+			if err := applyMonitoringConfig(node, &config.Monitoring); err != nil {
+				return err
+			}
+		case "telemetry":
+			if err := applyTelemetryConfig(node, &config.Telemetry); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -357,10 +378,147 @@ func applyServerConfig(node *yamlNode, config *ServerConfig) error {
 				}
 				config.WriteTimeout = dur
 			}
+		case "idleTimeout":
+			if child.value != "" {
+				dur, err := parseDuration(child.value)
+				if err != nil {
+					return err
+				}
+				config.IdleTimeout = dur
+			}
 		case "pidFile":
 			if child.value != "" {
 				config.PIDFile = child.value
 			}
+		case "tlsKeyPassphrase":
+			config.TLSKeyPassphrase = child.value
+		case "proxyProtocol":
+			config.ProxyProtocol = parseBool(child.value)
+		case "trustedProxies":
+			if inlineArr := parseInlineArray(child.value); inlineArr != nil {
+				config.TrustedProxies = inlineArr
+			} else if len(child.listItems) > 0 {
+				config.TrustedProxies = child.listItems
+			}
+		case "fairness":
+			if err := applyFairnessConfig(child, &config.Fairness); err != nil {
+				return err
+			}
+		case "persistentSearch":
+			if err := applyPersistentSearchConfig(child, &config.PersistentSearch); err != nil {
+				return err
+			}
+		case "limits":
+			limits, err := parseSearchLimits(child)
+			if err != nil {
+				return err
+			}
+			config.Limits = limits
+		}
+	}
+	return nil
+}
+
+// parseSearchLimits parses search limit profiles from a YAML node.
+func parseSearchLimits(node *yamlNode) ([]SearchLimitConfig, error) {
+	var limits []SearchLimitConfig
+
+	for _, child := range node.children {
+		limit := SearchLimitConfig{SizeLimit: LimitDefault, TimeLimit: LimitDefault}
+
+		for _, limitChild := range child.children {
+			var target *int
+			switch limitChild.key {
+			case "subject":
+				limit.Subject = limitChild.value
+				continue
+			case "sizeLimit":
+				target = &limit.SizeLimit
+			case "timeLimit":
+				target = &limit.TimeLimit
+			default:
+				continue
+			}
+			val, err := parseSearchLimit(limitChild.value)
+			if err != nil {
+				return nil, err
+			}
+			*target = val
+		}
+
+		limits = append(limits, limit)
+	}
+
+	return limits, nil
+}
+
+// parseSearchLimit parses a size or time limit, where "unlimited" is 0.
+func parseSearchLimit(s string) (int, error) {
+	if s == "" {
+		return LimitDefault, nil
+	}
+	if strings.EqualFold(s, "unlimited") {
+		return 0, nil
+	}
+	val, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, ErrInvalidNumber
+	}
+	return val, nil
+}
+
+// applyFairnessConfig applies connection fairness configuration.
+func applyFairnessConfig(node *yamlNode, config *FairnessConfig) error {
+	for _, child := range node.children {
+		var target *int
+		switch child.key {
+		case "maxBufferedEntries":
+			target = &config.MaxBufferedEntries
+		case "maxExpensiveSearches":
+			target = &config.MaxExpensiveSearches
+		case "expensiveSearchThreshold":
+			target = &config.ExpensiveSearchThreshold
+		case "maxOperationsPerBindDN":
+			target = &config.MaxOperationsPerBindDN
+		default:
+			continue
+		}
+		if child.value != "" {
+			val, err := strconv.Atoi(child.value)
+			if err != nil {
+				return ErrInvalidNumber
+			}
+			*target = val
+		}
+	}
+	return nil
+}
+
+// applyPersistentSearchConfig applies persistent search configuration.
+func applyPersistentSearchConfig(node *yamlNode, config *PersistentSearchConfig) error {
+	for _, child := range node.children {
+		var target *int
+		switch child.key {
+		case "overflowPolicy":
+			if child.value != "" {
+				config.OverflowPolicy = child.value
+			}
+			continue
+		case "queueSize":
+			target = &config.QueueSize
+		case "maxPerConnection":
+			target = &config.MaxPerConnection
+		case "maxPerBindDN":
+			target = &config.MaxPerBindDN
+		default:
+			continue
+		}
+		if child.value != "" {
+			val, err := strconv.Atoi(child.value)
+			if err != nil {
+				return ErrInvalidNumber
+			}
+			*target = val
 		}
 	}
 	return nil
@@ -382,6 +540,103 @@ func applyDirectoryConfig(node *yamlNode, config *DirectoryConfig) error {
 			if child.value != "" {
 				config.RootPassword = child.value
 			}
+		case "maxRenameSubtree":
+			if child.value != "" {
+				val, err := strconv.Atoi(child.value)
+				if err != nil {
+					return ErrInvalidNumber
+				}
+				config.MaxRenameSubtree = val
+			}
+		case "referentialIntegrity":
+			config.ReferentialIntegrity = parseBool(child.value)
+		case "maxNumSubordinates":
+			if child.value != "" {
+				val, err := strconv.Atoi(child.value)
+				if err != nil {
+					return ErrInvalidNumber
+				}
+				config.MaxNumSubordinates = val
+			}
+		case "recycleBin":
+			if err := applyRecycleBinConfig(child, &config.RecycleBin); err != nil {
+				return err
+			}
+		case "uidNumber":
+			if err := applyUIDNumberConfig(child, &config.UIDNumber); err != nil {
+				return err
+			}
+		case "dynamicGroups":
+			if err := applyDynamicGroupsConfig(child, &config.DynamicGroups); err != nil {
+				return err
+			}
+		case "auditLogPath":
+			config.AuditLogPath = child.value
+		}
+	}
+	return nil
+}
+
+// applyDynamicGroupsConfig applies dynamic group expansion configuration.
+func applyDynamicGroupsConfig(node *yamlNode, config *DynamicGroupsConfig) error {
+	for _, child := range node.children {
+		switch child.key {
+		case "enabled":
+			config.Enabled = parseBool(child.value)
+		case "maxMembers":
+			if child.value != "" {
+				val, err := strconv.Atoi(child.value)
+				if err != nil {
+					return ErrInvalidNumber
+				}
+				config.MaxMembers = val
+			}
+		}
+	}
+	return nil
+}
+
+// applyUIDNumberConfig applies uidNumber auto-assignment configuration.
+func applyUIDNumberConfig(node *yamlNode, config *UIDNumberConfig) error {
+	for _, child := range node.children {
+		switch child.key {
+		case "enabled":
+			config.Enabled = parseBool(child.value)
+		case "start":
+			if child.value != "" {
+				val, err := strconv.Atoi(child.value)
+				if err != nil {
+					return ErrInvalidNumber
+				}
+				config.Start = val
+			}
+		}
+	}
+	return nil
+}
+
+// applyRecycleBinConfig applies recycle bin configuration.
+func applyRecycleBinConfig(node *yamlNode, config *RecycleBinConfig) error {
+	for _, child := range node.children {
+		switch child.key {
+		case "enabled":
+			config.Enabled = parseBool(child.value)
+		case "retention":
+			if child.value != "" {
+				dur, err := parseDuration(child.value)
+				if err != nil {
+					return err
+				}
+				config.Retention = dur
+			}
+		case "purgeInterval":
+			if child.value != "" {
+				dur, err := parseDuration(child.value)
+				if err != nil {
+					return err
+				}
+				config.PurgeInterval = dur
+			}
 		}
 	}
 	return nil
@@ -440,10 +695,19 @@ func applyLogConfig(node *yamlNode, config *LogConfig) error {
 			if child.value != "" {
 				config.Output = child.value
 			}
+		case "componentLevels":
+			config.ComponentLevels = make(map[string]string, len(child.children))
+			for _, component := range child.children {
+				config.ComponentLevels[component.key] = component.value
+			}
 		case "store":
 			if err := applyLogStoreConfig(child, &config.Store); err != nil {
 				return err
 			}
+		case "wireDump":
+			if err := applyWireDumpConfig(child, &config.WireDump); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -494,6 +758,45 @@ func applyLogStoreConfig(node *yamlNode, config *LogStoreConfig) error {
 	return nil
 }
 
+// applyWireDumpConfig applies wire dump configuration.
+func applyWireDumpConfig(node *yamlNode, config *WireDumpConfig) error {
+	for _, child := range node.children {
+		switch child.key {
+		case "enabled":
+			config.Enabled = parseBool(child.value)
+		case "directory":
+			config.Directory = child.value
+		case "maxBytes":
+			if child.value != "" {
+				n, err := strconv.ParseInt(child.value, 10, 64)
+				if err != nil {
+					return err
+				}
+				config.MaxBytes = n
+			}
+		case "connectionFilter":
+			if inlineArr := parseInlineArray(child.value); inlineArr != nil {
+				config.ConnectionFilter = inlineArr
+			} else if len(child.listItems) > 0 {
+				config.ConnectionFilter = child.listItems
+			}
+		case "duration":
+			if child.value != "" {
+				dur, err := parseDuration(child.value)
+				if err != nil {
+					return err
+				}
+				config.Duration = dur
+			}
+		case "rawBER":
+			config.RawBER = parseBool(child.value)
+		case "includePasswords":
+			config.IncludePasswords = parseBool(child.value)
+		}
+	}
+	return nil
+}
+
 // applySecurityConfig applies security configuration.
 func applySecurityConfig(node *yamlNode, config *SecurityConfig) error {
 	for _, child := range node.children {
@@ -506,10 +809,18 @@ func applySecurityConfig(node *yamlNode, config *SecurityConfig) error {
 			if err := applyRateLimitConfig(child, &config.RateLimit); err != nil {
 				return err
 			}
+		case "bindThrottle":
+			if err := applyBindThrottleConfig(child, &config.BindThrottle); err != nil {
+				return err
+			}
 		case "encryption":
 			if err := applyEncryptionConfig(child, &config.Encryption); err != nil {
 				return err
 			}
+		case "tls":
+			if err := applyTLSConfig(child, &config.TLS); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -553,6 +864,14 @@ func applyPasswordPolicyConfig(node *yamlNode, config *PasswordPolicyConfig) err
 				}
 				config.HistoryCount = val
 			}
+		case "graceLogins":
+			if child.value != "" {
+				val, err := strconv.Atoi(child.value)
+				if err != nil {
+					return ErrInvalidNumber
+				}
+				config.GraceLogins = val
+			}
 		}
 	}
 	return nil
@@ -585,6 +904,57 @@ func applyRateLimitConfig(node *yamlNode, config *RateLimitConfig) error {
 	return nil
 }
 
+// applyBindThrottleConfig applies bind throttle configuration.
+func applyBindThrottleConfig(node *yamlNode, config *BindThrottleConfig) error {
+	for _, child := range node.children {
+		switch child.key {
+		case "enabled":
+			config.Enabled = parseBool(child.value)
+		case "maxFailures":
+			if child.value != "" {
+				val, err := strconv.Atoi(child.value)
+				if err != nil {
+					return ErrInvalidNumber
+				}
+				config.MaxFailures = val
+			}
+		case "window":
+			if child.value != "" {
+				dur, err := parseDuration(child.value)
+				if err != nil {
+					return err
+				}
+				config.Window = dur
+			}
+		case "baseDelay":
+			if child.value != "" {
+				dur, err := parseDuration(child.value)
+				if err != nil {
+					return err
+				}
+				config.BaseDelay = dur
+			}
+		case "maxDelay":
+			if child.value != "" {
+				dur, err := parseDuration(child.value)
+				if err != nil {
+					return err
+				}
+				config.MaxDelay = dur
+			}
+		case "allowlist":
+			if inlineArr := parseInlineArray(child.value); inlineArr != nil {
+				config.Allowlist = inlineArr
+			} else if len(child.listItems) > 0 {
+				config.Allowlist = child.listItems
+			}
+		case "stateFile":
+			config.StateFile = child.value
+		}
+	}
+	return nil
+}
+
 // applyEncryptionConfig applies encryption configuration.
 func applyEncryptionConfig(node *yamlNode, config *EncryptionConfig) error {
 	for _, child := range node.children {
@@ -600,6 +970,23 @@ func applyEncryptionConfig(node *yamlNode, config *EncryptionConfig) error {
 	return nil
 }
 
+// applyTLSConfig applies TLS protocol configuration.
+func applyTLSConfig(node *yamlNode, config *TLSConfig) error {
+	for _, child := range node.children {
+		switch child.key {
+		case "minVersion":
+			config.MinVersion = child.value
+		case "allowedCipherSuites":
+			if inlineArr := parseInlineArray(child.value); inlineArr != nil {
+				config.AllowedCipherSuites = inlineArr
+			} else if len(child.listItems) > 0 {
+				config.AllowedCipherSuites = child.listItems
+			}
+		}
+	}
+	return nil
+}
+
 // applyACLConfig applies ACL configuration.
 func applyACLConfig(node *yamlNode, config *ACLConfig) error {
 	for _, child := range node.children {
@@ -646,6 +1033,8 @@ func parseACLRules(node *yamlNode) ([]ACLRuleConfig, error) {
 				} else if len(ruleChild.listItems) > 0 {
 					rule.Attributes = ruleChild.listItems
 				}
+			case "timeRange":
+				rule.TimeRange = parseTimeRange(ruleChild)
 			}
 		}
 
@@ -655,6 +1044,29 @@ func parseACLRules(node *yamlNode) ([]ACLRuleConfig, error) {
 	return rules, nil
 }
 
+// parseTimeRange parses the timeRange block of an ACL rule.
+func parseTimeRange(node *yamlNode) *TimeRangeConfig {
+	tr := &TimeRangeConfig{}
+	for _, child := range node.children {
+		switch child.key {
+		case "start":
+			tr.Start = child.value
+		case "end":
+			tr.End = child.value
+		case "weekdays":
+			// Try inline array first, then list items
+			if inlineArr := parseInlineArray(child.value); inlineArr != nil {
+				tr.Weekdays = inlineArr
+			} else if len(child.listItems) > 0 {
+				tr.Weekdays = child.listItems
+			}
+		case "timezone":
+			tr.Timezone = child.value
+		}
+	}
+	return tr
+}
+
 // applyRESTConfig applies REST API configuration.
 func applyRESTConfig(node *yamlNode, config *RESTConfig) error {
 	for _, child := range node.children {
@@ -673,6 +1085,14 @@ func applyRESTConfig(node *yamlNode, config *RESTConfig) error {
 			if child.value != "" {
 				config.JWTSecret = child.value
 			}
+		case "externalJWTIssuer":
+			config.ExternalJWTIssuer = child.value
+		case "externalJWTAudience":
+			config.ExternalJWTAudience = child.value
+		case "jwksUri":
+			config.JWKSUri = child.value
+		case "claimTemplate":
+			config.ClaimTemplate = child.value
 		case "tokenTTL":
 			if child.value != "" {
 				dur, err := parseDuration(child.value)
@@ -681,6 +1101,14 @@ func applyRESTConfig(node *yamlNode, config *RESTConfig) error {
 				}
 				config.TokenTTL = dur
 			}
+		case "cursorTTL":
+			if child.value != "" {
+				dur, err := parseDuration(child.value)
+				if err != nil {
+					return err
+				}
+				config.CursorTTL = dur
+			}
 		case "rateLimit":
 			if child.value != "" {
 				val, err := strconv.Atoi(child.value)
@@ -695,6 +1123,26 @@ func applyRESTConfig(node *yamlNode, config *RESTConfig) error {
 			} else if len(child.listItems) > 0 {
 				config.CORSOrigins = child.listItems
 			}
+		case "corsAllowedMethods":
+			if inlineArr := parseInlineArray(child.value); inlineArr != nil {
+				config.CORSAllowedMethods = inlineArr
+			} else if len(child.listItems) > 0 {
+				config.CORSAllowedMethods = child.listItems
+			}
+		case "corsAllowedHeaders":
+			if inlineArr := parseInlineArray(child.value); inlineArr != nil {
+				config.CORSAllowedHeaders = inlineArr
+			} else if len(child.listItems) > 0 {
+				config.CORSAllowedHeaders = child.listItems
+			}
+		case "corsMaxAge":
+			if child.value != "" {
+				dur, err := parseDuration(child.value)
+				if err != nil {
+					return err
+				}
+				config.CORSMaxAge = dur
+			}
 		}
 	}
 	return nil
@@ -783,6 +1231,16 @@ func applyClusterConfig(node *yamlNode, config *ClusterConfig) error {
 			if child.value != "" {
 				config.DataDir = child.value
 			}
+		case "readPreference":
+			config.ReadPreference = child.value
+		case "maxStalenessLog":
+			if child.value != "" {
+				val, err := strconv.ParseUint(child.value, 10, 64)
+				if err != nil {
+					return ErrInvalidNumber
+				}
+				config.MaxStalenessLog = val
+			}
 		}
 	}
 	return nil
@@ -811,3 +1269,148 @@ func parseClusterPeers(node *yamlNode) ([]PeerConfig, error) {
 	}
 	return peers, nil
 }
+
+// applySchemaConfig applies schema configuration.
+func applySchemaConfig(node *yamlNode, config *SchemaConfig) error {
+	for _, child := range node.children {
+		switch child.key {
+		case "builtin":
+			if inlineArr := parseInlineArray(child.value); inlineArr != nil {
+				config.Builtin = inlineArr
+			} else if len(child.listItems) > 0 {
+				config.Builtin = child.listItems
+			}
+		case "strict":
+			config.Strict = parseBool(child.value)
+		case "objectClassCascade":
+			config.ObjectClassCascade = parseBool(child.value)
+		case "structureRules":
+			if err := applyStructureRulesConfig(child, &config.StructureRules); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applyMonitoringConfig applies monitoring configuration.
+func applyMonitoringConfig(node *yamlNode, config *MonitoringConfig) error {
+	for _, child := range node.children {
+		switch child.key {
+		case "prometheusAddr":
+			config.PrometheusAddr = child.value
+		case "snmpTrap":
+			if err := applySNMPConfig(child, &config.SNMPTrap); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applySNMPConfig applies SNMP trap configuration.
+func applySNMPConfig(node *yamlNode, config *SNMPConfig) error {
+	for _, child := range node.children {
+		switch child.key {
+		case "enabled":
+			config.Enabled = parseBool(child.value)
+		case "target":
+			config.Target = child.value
+		case "community":
+			config.Community = child.value
+		case "version":
+			if child.value != "" {
+				config.Version = child.value
+			}
+		case "replicationLagThreshold":
+			if child.value != "" {
+				val, err := strconv.ParseUint(child.value, 10, 64)
+				if err != nil {
+					return ErrInvalidNumber
+				}
+				config.ReplicationLagThreshold = val
+			}
+		case "storageFullPercent":
+			if child.value != "" {
+				val, err := strconv.Atoi(child.value)
+				if err != nil {
+					return ErrInvalidNumber
+				}
+				config.StorageFullPercent = val
+			}
+		}
+	}
+	return nil
+}
+
+// applyTelemetryConfig applies telemetry configuration.
+func applyTelemetryConfig(node *yamlNode, config *TelemetryConfig) error {
+	for _, child := range node.children {
+		switch child.key {
+		case "enabled":
+			config.Enabled = parseBool(child.value)
+		case "endpoint":
+			config.Endpoint = child.value
+		case "serviceName":
+			if child.value != "" {
+				config.ServiceName = child.value
+			}
+		case "sampleRatio":
+			if child.value != "" {
+				val, err := strconv.ParseFloat(child.value, 64)
+				if err != nil {
+					return ErrInvalidNumber
+				}
+				config.SampleRatio = val
+			}
+		}
+	}
+	return nil
+}
+
+// applyStructureRulesConfig applies DIT structure rule configuration.
+func applyStructureRulesConfig(node *yamlNode, config *StructureRulesConfig) error {
+	for _, child := range node.children {
+		switch child.key {
+		case "enabled":
+			config.Enabled = parseBool(child.value)
+		case "rules":
+			config.Rules = parseStructureRules(child)
+		}
+	}
+	return nil
+}
+
+// parseStructureRules parses DIT structure rules from a YAML node.
+func parseStructureRules(node *yamlNode) []StructureRuleConfig {
+	var rules []StructureRuleConfig
+
+	for _, child := range node.children {
+		rule := StructureRuleConfig{}
+
+		for _, ruleChild := range child.children {
+			switch ruleChild.key {
+			case "objectClass":
+				rule.ObjectClass = ruleChild.value
+			case "parents":
+				if inlineArr := parseInlineArray(ruleChild.value); inlineArr != nil {
+					rule.Parents = inlineArr
+				} else if len(ruleChild.listItems) > 0 {
+					rule.Parents = ruleChild.listItems
+				}
+			case "namingAttributes":
+				if inlineArr := parseInlineArray(ruleChild.value); inlineArr != nil {
+					rule.NamingAttributes = inlineArr
+				} else if len(ruleChild.listItems) > 0 {
+					rule.NamingAttributes = ruleChild.listItems
+				}
+			}
+		}
+
+		if rule.ObjectClass != "" {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules
+}
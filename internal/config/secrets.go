@@ -0,0 +1,178 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// secretExecTimeout bounds the run time of ${exec:...} secret commands.
+var secretExecTimeout = 10 * time.Second
+
+// Secret reference prefixes. A secret value of the form ${env:NAME},
+// ${file:PATH} or ${exec:COMMAND} is replaced by what it refers to.
+const (
+	secretEnvPrefix  = "env:"
+	secretFilePrefix = "file:"
+	secretExecPrefix = "exec:"
+)
+
+// secretField is a configuration value that may be a secret reference.
+type secretField struct {
+	key   string
+	value func(c *Config) *string
+}
+
+// secretFields are the values resolved by resolveSecrets.
+var secretFields = []secretField{
+	{"server.tlsKeyPassphrase", func(c *Config) *string { return &c.Server.TLSKeyPassphrase }},
+	{"directory.rootPassword", func(c *Config) *string { return &c.Directory.RootPassword }},
+	{"security.encryption.keyFile", func(c *Config) *string { return &c.Security.Encryption.KeyFile }},
+	{"rest.jwtSecret", func(c *Config) *string { return &c.REST.JWTSecret }},
+}
+
+// secretRef records the reference a secret value was resolved from.
+type secretRef struct {
+	reference string
+	value     string
+}
+
+// isSecretReference reports whether the content of a ${...} expression is a
+// secret reference.
+func isSecretReference(content string) bool {
+	return strings.HasPrefix(content, secretEnvPrefix) ||
+		strings.HasPrefix(content, secretFilePrefix) ||
+		strings.HasPrefix(content, secretExecPrefix)
+}
+
+// resolveSecrets replaces the secret references in the secret values of c
+// with what they refer to:
+//
+//   - ${env:NAME} is the environment variable NAME
+//   - ${file:PATH} is the content of the file PATH
+//   - ${exec:COMMAND ARGS...} is the standard output of COMMAND, run
+//     without a shell and killed after secretExecTimeout
+//
+// Trailing newlines are removed from files and command output. Other
+// values are kept. Every value that cannot be resolved is reported as a
+// ValidationError naming its key.
+func resolveSecrets(c *Config) []error {
+	var errs []error
+	for _, field := range secretFields {
+		value := field.value(c)
+		if !strings.HasPrefix(*value, "${") || !strings.HasSuffix(*value, "}") {
+			continue
+		}
+		content := (*value)[2 : len(*value)-1]
+		if !isSecretReference(content) {
+			continue
+		}
+
+		resolved, err := resolveSecret(content)
+		if err != nil {
+			errs = append(errs, ValidationError{
+				Field:   field.key,
+				Message: fmt.Sprintf("cannot resolve %s: %v", *value, err),
+			})
+			continue
+		}
+		if c.secrets == nil {
+			c.secrets = make(map[string]secretRef)
+		}
+		c.secrets[field.key] = secretRef{reference: *value, value: resolved}
+		*value = resolved
+	}
+	return errs
+}
+
+// resolveSecret returns the value of a secret reference, without ${ }.
+func resolveSecret(content string) (string, error) {
+	switch {
+	case strings.HasPrefix(content, secretEnvPrefix):
+		name := strings.TrimPrefix(content, secretEnvPrefix)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+
+	case strings.HasPrefix(content, secretFilePrefix):
+		data, err := os.ReadFile(strings.TrimPrefix(content, secretFilePrefix))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+
+	default:
+		args := strings.Fields(strings.TrimPrefix(content, secretExecPrefix))
+		if len(args) == 0 {
+			return "", errors.New("no command")
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), secretExecTimeout)
+		defer cancel()
+
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			if ctx.Err() != nil {
+				return "", fmt.Errorf("timed out after %s", secretExecTimeout)
+			}
+			if msg, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n"); msg != "" {
+				return "", fmt.Errorf("%w: %s", err, msg)
+			}
+			return "", err
+		}
+		return strings.TrimRight(stdout.String(), "\r\n"), nil
+	}
+}
+
+// SecretReference returns the secret reference the value of key (such as
+// "directory.rootPassword") was resolved from, or "" if the value was not
+// a reference or has changed since.
+func (c *Config) SecretReference(key string) string {
+	ref, ok := c.secrets[key]
+	if !ok {
+		return ""
+	}
+	for _, field := range secretFields {
+		if field.key == key && *field.value(c) == ref.value {
+			return ref.reference
+		}
+	}
+	return ""
+}
+
+// WithSecretReferences returns a copy of c in which the values resolved
+// from secret references are replaced by the references, so that the
+// configuration can be shown or saved without the secrets.
+func (c *Config) WithSecretReferences() *Config {
+	masked := copyConfig(c)
+	for _, field := range secretFields {
+		if ref := c.SecretReference(field.key); ref != "" {
+			*field.value(masked) = ref
+		}
+	}
+	return masked
+}
+
+// updateSecret records that the secret value of key was rewritten from
+// old to value, such as a key file path made absolute.
+func (c *Config) updateSecret(key, old, value string) {
+	if ref, ok := c.secrets[key]; ok && ref.value == old {
+		ref.value = value
+		secrets := make(map[string]secretRef, len(c.secrets))
+		for k, v := range c.secrets {
+			secrets[k] = v
+		}
+		secrets[key] = ref
+		c.secrets = secrets
+	}
+}
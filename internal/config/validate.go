@@ -2,11 +2,17 @@
 package config
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"text/template"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/schema"
 )
 
 // ValidationError represents a configuration validation error.
@@ -43,6 +49,30 @@ func ValidateConfig(config *Config) []error {
 	// Validate ACL configuration
 	errs = append(errs, validateACLConfig(&config.ACL)...)
 
+	// Validate schema configuration
+	errs = append(errs, validateSchemaConfig(&config.Schema)...)
+
+	// Validate cluster configuration
+	errs = append(errs, validateClusterConfig(&config.Cluster)...)
+
+	// Validate REST configuration
+	errs = append(errs, validateRESTConfig(&config.REST)...)
+
+	// Validate monitoring configuration
+	if config.Monitoring.PrometheusAddr != "" {
+		if err := validateAddress(config.Monitoring.PrometheusAddr); err != nil {
+			errs = append(errs, ValidationError{
+				Field:   "monitoring.prometheusAddr",
+				Message: err.Error(),
+			})
+		}
+	}
+
+	errs = append(errs, validateSNMPConfig(&config.Monitoring.SNMPTrap)...)
+
+	// Validate telemetry configuration
+	errs = append(errs, validateTelemetryConfig(&config.Telemetry)...)
+
 	return errs
 }
 
@@ -109,6 +139,95 @@ func validateServerConfig(config *ServerConfig) []error {
 		})
 	}
 
+	if config.IdleTimeout < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "server.idleTimeout",
+			Message: "must be non-negative",
+		})
+	}
+
+	// Validate trusted proxies
+	for _, cidr := range config.TrustedProxies {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			errs = append(errs, ValidationError{
+				Field:   "server.trustedProxies",
+				Message: fmt.Sprintf("invalid CIDR %q", cidr),
+			})
+		}
+	}
+
+	if config.ProxyProtocol && len(config.TrustedProxies) == 0 {
+		errs = append(errs, ValidationError{
+			Field:   "server.trustedProxies",
+			Message: "at least one trusted proxy is required when proxyProtocol is enabled",
+		})
+	}
+
+	// Validate fairness limits
+	for _, limit := range []struct {
+		field string
+		value int
+	}{
+		{"server.fairness.maxBufferedEntries", config.Fairness.MaxBufferedEntries},
+		{"server.fairness.maxExpensiveSearches", config.Fairness.MaxExpensiveSearches},
+		{"server.fairness.expensiveSearchThreshold", config.Fairness.ExpensiveSearchThreshold},
+		{"server.fairness.maxOperationsPerBindDN", config.Fairness.MaxOperationsPerBindDN},
+	} {
+		if limit.value < 0 {
+			errs = append(errs, ValidationError{
+				Field:   limit.field,
+				Message: "must be non-negative",
+			})
+		}
+	}
+
+	// Validate persistent search limits
+	switch config.PersistentSearch.OverflowPolicy {
+	case "", "drop", "disconnect":
+	default:
+		errs = append(errs, ValidationError{
+			Field:   "server.persistentSearch.overflowPolicy",
+			Message: "must be drop or disconnect",
+		})
+	}
+	for _, limit := range []struct {
+		field string
+		value int
+	}{
+		{"server.persistentSearch.queueSize", config.PersistentSearch.QueueSize},
+		{"server.persistentSearch.maxPerConnection", config.PersistentSearch.MaxPerConnection},
+		{"server.persistentSearch.maxPerBindDN", config.PersistentSearch.MaxPerBindDN},
+	} {
+		if limit.value < 0 {
+			errs = append(errs, ValidationError{
+				Field:   limit.field,
+				Message: "must be non-negative",
+			})
+		}
+	}
+
+	// Validate search limit profiles
+	for i, limit := range config.Limits {
+		if strings.TrimSpace(limit.Subject) == "" {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("server.limits[%d].subject", i),
+				Message: "subject is required",
+			})
+		}
+		if limit.SizeLimit < LimitDefault {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("server.limits[%d].sizeLimit", i),
+				Message: "must be non-negative or unlimited",
+			})
+		}
+		if limit.TimeLimit < LimitDefault {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("server.limits[%d].timeLimit", i),
+				Message: "must be non-negative or unlimited",
+			})
+		}
+	}
+
 	return errs
 }
 
@@ -136,6 +255,49 @@ func validateDirectoryConfig(config *DirectoryConfig) []error {
 		}
 	}
 
+	if config.MaxRenameSubtree < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "directory.maxRenameSubtree",
+			Message: "must not be negative",
+		})
+	}
+
+	if config.MaxNumSubordinates < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "directory.maxNumSubordinates",
+			Message: "must not be negative",
+		})
+	}
+
+	if rb := config.RecycleBin; rb.Enabled {
+		if rb.Retention <= 0 {
+			errs = append(errs, ValidationError{
+				Field:   "directory.recycleBin.retention",
+				Message: "must be positive when the recycle bin is enabled",
+			})
+		}
+		if rb.PurgeInterval <= 0 {
+			errs = append(errs, ValidationError{
+				Field:   "directory.recycleBin.purgeInterval",
+				Message: "must be positive when the recycle bin is enabled",
+			})
+		}
+	}
+
+	if un := config.UIDNumber; un.Enabled && un.Start <= 0 {
+		errs = append(errs, ValidationError{
+			Field:   "directory.uidNumber.start",
+			Message: "must be positive when uidNumber assignment is enabled",
+		})
+	}
+
+	if dg := config.DynamicGroups; dg.Enabled && dg.MaxMembers <= 0 {
+		errs = append(errs, ValidationError{
+			Field:   "directory.dynamicGroups.maxMembers",
+			Message: "must be positive when dynamic groups are enabled",
+		})
+	}
+
 	return errs
 }
 
@@ -201,6 +363,14 @@ func validateLogConfig(config *LogConfig) []error {
 			Message: "must be debug, info, warn, or error",
 		})
 	}
+	for component, level := range config.ComponentLevels {
+		if !validLevels[strings.ToLower(level)] {
+			errs = append(errs, ValidationError{
+				Field:   "logging.componentLevels." + component,
+				Message: "must be debug, info, warn, or error",
+			})
+		}
+	}
 
 	// Validate log format
 	validFormats := map[string]bool{"text": true, "json": true}
@@ -228,6 +398,48 @@ func validateLogConfig(config *LogConfig) []error {
 		}
 	}
 
+	errs = append(errs, validateWireDumpConfig(&config.WireDump)...)
+
+	return errs
+}
+
+// validateWireDumpConfig validates wire dump configuration.
+func validateWireDumpConfig(config *WireDumpConfig) []error {
+	var errs []error
+
+	for _, source := range config.ConnectionFilter {
+		if net.ParseIP(source) != nil {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(source); err != nil {
+			errs = append(errs, ValidationError{
+				Field:   "logging.wireDump.connectionFilter",
+				Message: fmt.Sprintf("invalid IP or CIDR %q", source),
+			})
+		}
+	}
+
+	if config.MaxBytes < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "logging.wireDump.maxBytes",
+			Message: "must not be negative",
+		})
+	}
+
+	if config.Duration < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "logging.wireDump.duration",
+			Message: "must not be negative",
+		})
+	}
+
+	if config.Enabled && config.Directory == "" {
+		errs = append(errs, ValidationError{
+			Field:   "logging.wireDump.directory",
+			Message: "is required when the wire dump is enabled",
+		})
+	}
+
 	return errs
 }
 
@@ -241,6 +453,102 @@ func validateSecurityConfig(config *SecurityConfig) []error {
 	// Validate rate limit
 	errs = append(errs, validateRateLimitConfig(&config.RateLimit)...)
 
+	// Validate bind throttle
+	errs = append(errs, validateBindThrottleConfig(&config.BindThrottle)...)
+
+	// Validate TLS protocol settings
+	errs = append(errs, validateTLSConfig(&config.TLS)...)
+
+	return errs
+}
+
+// validateTLSConfig validates the TLS protocol settings.
+func validateTLSConfig(config *TLSConfig) []error {
+	var errs []error
+
+	switch strings.ToLower(config.MinVersion) {
+	case "", "tls12", "tls13":
+	default:
+		errs = append(errs, ValidationError{
+			Field:   "security.tls.minVersion",
+			Message: fmt.Sprintf("invalid TLS version %q (must be tls12 or tls13)", config.MinVersion),
+		})
+	}
+
+	// Only secure TLS 1.2 suites can be configured
+	suites := make(map[string]string)
+	for _, suite := range tls.CipherSuites() {
+		suites[suite.Name] = "TLS 1.3 cipher suite %q is not configurable (list TLS 1.2 suites only)"
+		for _, v := range suite.SupportedVersions {
+			if v == tls.VersionTLS12 {
+				suites[suite.Name] = ""
+			}
+		}
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		suites[suite.Name] = "insecure cipher suite %q is not allowed"
+	}
+	for _, name := range config.AllowedCipherSuites {
+		msg, known := suites[strings.ToUpper(name)]
+		if !known {
+			msg = "unknown cipher suite %q"
+		}
+		if msg != "" {
+			errs = append(errs, ValidationError{
+				Field:   "security.tls.allowedCipherSuites",
+				Message: fmt.Sprintf(msg, name),
+			})
+		}
+	}
+
+	return errs
+}
+
+// validateBindThrottleConfig validates bind throttle configuration.
+func validateBindThrottleConfig(config *BindThrottleConfig) []error {
+	var errs []error
+
+	for _, cidr := range config.Allowlist {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			errs = append(errs, ValidationError{
+				Field:   "security.bindThrottle.allowlist",
+				Message: fmt.Sprintf("invalid CIDR %q", cidr),
+			})
+		}
+	}
+
+	if !config.Enabled {
+		return errs
+	}
+
+	if config.MaxFailures < 1 {
+		errs = append(errs, ValidationError{
+			Field:   "security.bindThrottle.maxFailures",
+			Message: "must be at least 1 when bind throttling is enabled",
+		})
+	}
+
+	if config.Window <= 0 {
+		errs = append(errs, ValidationError{
+			Field:   "security.bindThrottle.window",
+			Message: "must be positive when bind throttling is enabled",
+		})
+	}
+
+	if config.BaseDelay <= 0 {
+		errs = append(errs, ValidationError{
+			Field:   "security.bindThrottle.baseDelay",
+			Message: "must be positive when bind throttling is enabled",
+		})
+	}
+
+	if config.MaxDelay < config.BaseDelay {
+		errs = append(errs, ValidationError{
+			Field:   "security.bindThrottle.maxDelay",
+			Message: "must not be less than baseDelay",
+		})
+	}
+
 	return errs
 }
 
@@ -263,6 +571,13 @@ func validatePasswordPolicyConfig(config *PasswordPolicyConfig) []error {
 			})
 		}
 
+		if config.GraceLogins < 0 {
+			errs = append(errs, ValidationError{
+				Field:   "security.passwordPolicy.graceLogins",
+				Message: "must be non-negative",
+			})
+		}
+
 		if config.MaxAge < 0 {
 			errs = append(errs, ValidationError{
 				Field:   "security.passwordPolicy.maxAge",
@@ -346,6 +661,185 @@ func validateACLConfig(config *ACLConfig) []error {
 				})
 			}
 		}
+
+		// The clock times and weekdays are parsed when the rules are loaded
+		if rule.TimeRange != nil {
+			if rule.TimeRange.Start == "" {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("acl.rules[%d].timeRange.start", i),
+					Message: "start is required",
+				})
+			}
+			if rule.TimeRange.End == "" {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("acl.rules[%d].timeRange.end", i),
+					Message: "end is required",
+				})
+			}
+		}
+	}
+
+	return errs
+}
+
+// validateSchemaConfig validates schema configuration.
+func validateSchemaConfig(config *SchemaConfig) []error {
+	var errs []error
+
+	for i, name := range config.Builtin {
+		if !schema.IsBuiltinSchema(name) {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("schema.builtin[%d]", i),
+				Message: fmt.Sprintf("unknown schema set %s (available: %s)", name, strings.Join(schema.BuiltinSchemaNames(), ", ")),
+			})
+		}
+	}
+
+	if config.Strict && len(config.Builtin) == 0 {
+		errs = append(errs, ValidationError{
+			Field:   "schema.strict",
+			Message: "strict mode requires at least one schema.builtin set",
+		})
+	}
+
+	seen := make(map[string]bool)
+	for i, rule := range config.StructureRules.Rules {
+		if rule.ObjectClass == "" {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("schema.structureRules.rules[%d].objectClass", i),
+				Message: "objectClass is required",
+			})
+			continue
+		}
+
+		key := strings.ToLower(rule.ObjectClass)
+		if seen[key] {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("schema.structureRules.rules[%d].objectClass", i),
+				Message: fmt.Sprintf("duplicate rule for objectClass %s", rule.ObjectClass),
+			})
+		}
+		seen[key] = true
+	}
+
+	return errs
+}
+
+// validateClusterConfig validates cluster configuration.
+func validateClusterConfig(config *ClusterConfig) []error {
+	var errs []error
+
+	switch strings.ToLower(config.ReadPreference) {
+	case "", "leader", "follower", "any":
+	default:
+		errs = append(errs, ValidationError{
+			Field:   "cluster.readPreference",
+			Message: fmt.Sprintf("invalid read preference %s (must be leader, follower or any)", config.ReadPreference),
+		})
+	}
+
+	return errs
+}
+
+// validateRESTConfig validates REST API configuration.
+func validateRESTConfig(config *RESTConfig) []error {
+	var errs []error
+
+	if config.ExternalJWTIssuer == "" && config.ExternalJWTAudience == "" && config.JWKSUri == "" && config.ClaimTemplate == "" {
+		return nil
+	}
+
+	if config.ExternalJWTIssuer == "" {
+		errs = append(errs, ValidationError{
+			Field:   "rest.externalJWTIssuer",
+			Message: "issuer is required to accept external JWTs",
+		})
+	}
+
+	if config.ExternalJWTAudience == "" {
+		errs = append(errs, ValidationError{
+			Field:   "rest.externalJWTAudience",
+			Message: "audience is required to accept external JWTs",
+		})
+	}
+
+	// Keys fetched over plain http could be replaced in transit, letting
+	// anyone on the path sign tokens for any DN
+	if u, err := url.Parse(config.JWKSUri); err != nil || u.Host == "" || !(u.Scheme == "https" || (u.Scheme == "http" && isLoopbackHost(u.Hostname()))) {
+		errs = append(errs, ValidationError{
+			Field:   "rest.jwksUri",
+			Message: fmt.Sprintf("invalid JWKS URI %s (must be an https URL, or http to a loopback host)", config.JWKSUri),
+		})
+	}
+
+	if config.ClaimTemplate == "" {
+		errs = append(errs, ValidationError{
+			Field:   "rest.claimTemplate",
+			Message: "claim template is required to accept external JWTs",
+		})
+	} else if _, err := template.New("claims").Parse(config.ClaimTemplate); err != nil {
+		errs = append(errs, ValidationError{
+			Field:   "rest.claimTemplate",
+			Message: err.Error(),
+		})
+	}
+
+	return errs
+}
+
+// validateSNMPConfig validates SNMP trap configuration.
+func validateSNMPConfig(config *SNMPConfig) []error {
+	var errs []error
+
+	if config.Enabled && config.Target == "" {
+		errs = append(errs, ValidationError{
+			Field:   "monitoring.snmpTrap.target",
+			Message: "target is required when SNMP traps are enabled",
+		})
+	}
+
+	switch strings.ToLower(config.Version) {
+	case "", "2c", "v2c":
+	default:
+		errs = append(errs, ValidationError{
+			Field:   "monitoring.snmpTrap.version",
+			Message: fmt.Sprintf("unsupported SNMP version %s (must be 2c)", config.Version),
+		})
+	}
+
+	if config.StorageFullPercent < 0 || config.StorageFullPercent > 100 {
+		errs = append(errs, ValidationError{
+			Field:   "monitoring.snmpTrap.storageFullPercent",
+			Message: "storage full percent must be between 0 and 100",
+		})
+	}
+
+	return errs
+}
+
+// validateTelemetryConfig validates telemetry configuration.
+func validateTelemetryConfig(config *TelemetryConfig) []error {
+	var errs []error
+
+	if config.Enabled {
+		if config.Endpoint == "" {
+			errs = append(errs, ValidationError{
+				Field:   "telemetry.endpoint",
+				Message: "endpoint is required when telemetry is enabled",
+			})
+		} else if u, err := url.Parse(config.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, ValidationError{
+				Field:   "telemetry.endpoint",
+				Message: fmt.Sprintf("invalid endpoint %s (must be an http or https URL)", config.Endpoint),
+			})
+		}
+	}
+
+	if config.SampleRatio < 0 || config.SampleRatio > 1 {
+		errs = append(errs, ValidationError{
+			Field:   "telemetry.sampleRatio",
+			Message: "sample ratio must be between 0 and 1",
+		})
 	}
 
 	return errs
@@ -373,21 +867,29 @@ func validateAddress(addr string) error {
 	return nil
 }
 
+// isLoopbackHost reports whether host is localhost or a loopback address.
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
 // validateDN validates a distinguished name format.
 func validateDN(dn string) error {
 	if dn == "" {
 		return nil
 	}
 
-	// Basic DN validation: should contain at least one RDN
-	parts := strings.Split(dn, ",")
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		if part == "" {
-			continue
-		}
-		if !strings.Contains(part, "=") {
-			return fmt.Errorf("invalid RDN format: %s", part)
+	// Each RDN must be a valid type=value pair
+	rdns, err := ldap.SplitDN(dn)
+	if err != nil {
+		return fmt.Errorf("invalid DN format: %s", dn)
+	}
+	for _, rdn := range rdns {
+		if _, err := ldap.ParseRDN(rdn); err != nil {
+			return fmt.Errorf("invalid RDN format: %s", rdn)
 		}
 	}
 
@@ -1,20 +1,26 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"sync"
 	"time"
 )
 
 // ConfigWatcher watches a config file for changes and triggers reload.
+// It waits for file system events (inotify on Linux, kqueue on macOS and
+// the BSDs) on the directory of the file, so that a file replaced by a
+// rename, as Kubernetes updates mounted ConfigMaps, is noticed. Elsewhere,
+// or if UsePolling is set, it checks the file every poll interval.
 type ConfigWatcher struct {
 	filePath     string
 	pollInterval time.Duration
 	debounce     time.Duration
-	lastModTime  time.Time
-	lastSize     int64
+	usePolling   bool
+	lastInfo     os.FileInfo
 	lastConfig   *Config
 	onChange     func(oldCfg, newCfg *Config)
+	onError      func(err error)
 	stopCh       chan struct{}
 	stoppedCh    chan struct{}
 	mu           sync.Mutex
@@ -25,8 +31,15 @@ type ConfigWatcher struct {
 type WatcherConfig struct {
 	FilePath     string
 	PollInterval time.Duration // Default: 100ms
-	Debounce     time.Duration // Default: 200ms
-	OnChange     func(oldCfg, newCfg *Config)
+	Debounce     time.Duration // Default: 100ms
+	// UsePolling checks the file every PollInterval instead of waiting
+	// for file system events. The watcher also polls on platforms without
+	// inotify or kqueue.
+	UsePolling bool
+	OnChange   func(oldCfg, newCfg *Config)
+	// OnError, if set, is called when a changed config file cannot be
+	// loaded or fails validation. The previous config stays in effect.
+	OnError func(err error)
 }
 
 // NewConfigWatcher creates a new config file watcher.
@@ -45,7 +58,7 @@ func NewConfigWatcher(cfg *WatcherConfig) (*ConfigWatcher, error) {
 
 	debounce := cfg.Debounce
 	if debounce == 0 {
-		debounce = 200 * time.Millisecond
+		debounce = 100 * time.Millisecond
 	}
 
 	// Get initial file stats
@@ -64,10 +77,11 @@ func NewConfigWatcher(cfg *WatcherConfig) (*ConfigWatcher, error) {
 		filePath:     cfg.FilePath,
 		pollInterval: pollInterval,
 		debounce:     debounce,
-		lastModTime:  info.ModTime(),
-		lastSize:     info.Size(),
+		usePolling:   cfg.UsePolling,
+		lastInfo:     info,
 		lastConfig:   initialConfig,
 		onChange:     cfg.OnChange,
+		onError:      cfg.OnError,
 		stopCh:       make(chan struct{}),
 		stoppedCh:    make(chan struct{}),
 	}, nil
@@ -100,17 +114,37 @@ func (w *ConfigWatcher) Stop() {
 	<-w.stoppedCh
 }
 
-// watchLoop is the main polling loop.
+// watchLoop waits for changes of the config file and reloads it once they
+// stop for the debounce delay. With file system events, the file is
+// checked after the delay, so events for other files in the directory do
+// not cause a reload. When polling, it is checked on every tick.
 func (w *ConfigWatcher) watchLoop() {
 	defer close(w.stoppedCh)
 
-	ticker := time.NewTicker(w.pollInterval)
-	defer ticker.Stop()
+	var eventCh <-chan struct{}
+	var tickCh <-chan time.Time
+	if notifier, err := w.newNotifier(); err == nil {
+		defer notifier.Close()
+		eventCh = notifier.Events()
+	} else {
+		ticker := time.NewTicker(w.pollInterval)
+		defer ticker.Stop()
+		tickCh = ticker.C
+	}
 
 	var pendingReload bool
 	var debounceTimer *time.Timer
 	var debounceCh <-chan time.Time
 
+	schedule := func() {
+		pendingReload = true
+		if debounceTimer != nil {
+			debounceTimer.Stop()
+		}
+		debounceTimer = time.NewTimer(w.debounce)
+		debounceCh = debounceTimer.C
+	}
+
 	for {
 		select {
 		case <-w.stopCh:
@@ -119,24 +153,26 @@ func (w *ConfigWatcher) watchLoop() {
 			}
 			return
 
-		case <-ticker.C:
+		case <-eventCh:
+			schedule()
+
+		case <-tickCh:
 			changed, err := w.checkFileChanged()
 			if err != nil {
 				continue
 			}
 
 			if changed {
-				pendingReload = true
-				if debounceTimer != nil {
-					debounceTimer.Stop()
-				}
-				debounceTimer = time.NewTimer(w.debounce)
-				debounceCh = debounceTimer.C
+				schedule()
 			}
 
 		case <-debounceCh:
 			if pendingReload {
-				w.triggerReload()
+				if eventCh == nil {
+					w.triggerReload()
+				} else if changed, err := w.checkFileChanged(); err == nil && changed {
+					w.triggerReload()
+				}
 				pendingReload = false
 			}
 			debounceTimer = nil
@@ -145,35 +181,44 @@ func (w *ConfigWatcher) watchLoop() {
 	}
 }
 
-// checkFileChanged checks if the config file has been modified.
+// newNotifier returns the source of file system events for the config
+// file, or an error if the watcher polls.
+func (w *ConfigWatcher) newNotifier() (fileNotifier, error) {
+	if w.usePolling {
+		return nil, errNotifyUnsupported
+	}
+	return newFileNotifier(w.filePath)
+}
+
+// checkFileChanged checks if the config file has been modified or
+// replaced.
 func (w *ConfigWatcher) checkFileChanged() (bool, error) {
 	info, err := os.Stat(w.filePath)
 	if err != nil {
 		return false, err
 	}
 
-	modTime := info.ModTime()
-	size := info.Size()
-
-	if modTime != w.lastModTime || size != w.lastSize {
-		w.lastModTime = modTime
-		w.lastSize = size
-		return true, nil
+	last := w.lastInfo
+	if os.SameFile(info, last) && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+		return false, nil
 	}
 
-	return false, nil
+	w.lastInfo = info
+	return true, nil
 }
 
 // triggerReload loads the new config and calls onChange.
 func (w *ConfigWatcher) triggerReload() {
 	newConfig, err := LoadConfig(w.filePath)
 	if err != nil {
+		w.reportError(err)
 		return
 	}
 
 	// Validate new config
 	errs := ValidateConfig(newConfig)
 	if len(errs) > 0 {
+		w.reportError(errors.Join(errs...))
 		return
 	}
 
@@ -183,6 +228,13 @@ func (w *ConfigWatcher) triggerReload() {
 	w.onChange(oldConfig, newConfig)
 }
 
+// reportError passes a reload failure to the OnError callback, if any.
+func (w *ConfigWatcher) reportError(err error) {
+	if w.onError != nil {
+		w.onError(err)
+	}
+}
+
 // IsRunning returns true if the watcher is running.
 func (w *ConfigWatcher) IsRunning() bool {
 	w.mu.Lock()
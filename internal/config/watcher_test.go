@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// watcherTestConfig returns a config file setting the log level.
+func watcherTestConfig(level string) []byte {
+	return []byte(fmt.Sprintf("directory:\n  baseDN: \"dc=example,dc=com\"\nlogging:\n  level: %s\n", level))
+}
+
+// writeFileAtomic writes data to a temporary file in the directory of path
+// and renames it over path.
+func writeFileAtomic(t *testing.T, path string, data []byte) {
+	t.Helper()
+
+	tmp := filepath.Join(filepath.Dir(path), ".config.yaml.tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+}
+
+// startTestWatcher starts a watcher of path that counts its reloads.
+func startTestWatcher(t *testing.T, path string, usePolling bool) (*ConfigWatcher, *atomic.Int32) {
+	t.Helper()
+
+	var reloads atomic.Int32
+	w, err := NewConfigWatcher(&WatcherConfig{
+		FilePath:     path,
+		PollInterval: 10 * time.Millisecond,
+		UsePolling:   usePolling,
+		OnChange:     func(oldCfg, newCfg *Config) { reloads.Add(1) },
+		OnError:      func(err error) { t.Errorf("reload failed: %v", err) },
+	})
+	if err != nil {
+		t.Fatalf("NewConfigWatcher() error = %v", err)
+	}
+	w.Start()
+	t.Cleanup(w.Stop)
+
+	// Let the watcher set up its notifier
+	time.Sleep(50 * time.Millisecond)
+	return w, &reloads
+}
+
+// waitReloads waits past the debounce delay and checks the reload count.
+func waitReloads(t *testing.T, reloads *atomic.Int32, want int32) {
+	t.Helper()
+
+	time.Sleep(400 * time.Millisecond)
+	if got := reloads.Load(); got != want {
+		t.Errorf("config reloaded %d times, want %d", got, want)
+	}
+}
+
+func TestConfigWatcherRename(t *testing.T) {
+	for _, usePolling := range []bool{false, true} {
+		t.Run(fmt.Sprintf("polling=%v", usePolling), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, watcherTestConfig("info"), 0644); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+
+			w, reloads := startTestWatcher(t, path, usePolling)
+
+			writeFileAtomic(t, path, watcherTestConfig("debug"))
+			waitReloads(t, reloads, 1)
+			if level := w.GetCurrentConfig().Logging.Level; level != "debug" {
+				t.Errorf("Logging.Level = %q, want debug", level)
+			}
+
+			// Other files in the directory do not reload the config
+			other := filepath.Join(filepath.Dir(path), "other.yaml")
+			if err := os.WriteFile(other, watcherTestConfig("warn"), 0644); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+			waitReloads(t, reloads, 1)
+		})
+	}
+}
+
+// TestConfigWatcherConfigMap tests an update of a config file mounted from
+// a Kubernetes ConfigMap, where the file is a symlink through the ..data
+// symlink, which is replaced by a rename.
+func TestConfigWatcherConfigMap(t *testing.T) {
+	dir := t.TempDir()
+
+	// writeVersion writes a ConfigMap version directory and points a
+	// symlink named link at it
+	writeVersion := func(version, link, level string) {
+		if err := os.Mkdir(filepath.Join(dir, version), 0755); err != nil {
+			t.Fatalf("Mkdir() error = %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, version, "config.yaml"), watcherTestConfig(level), 0644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		if err := os.Symlink(version, filepath.Join(dir, link)); err != nil {
+			t.Fatalf("Symlink() error = %v", err)
+		}
+	}
+
+	writeVersion("..2026_01", "..data", "info")
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.Symlink(filepath.Join("..data", "config.yaml"), path); err != nil {
+		t.Fatalf("Symlink() error = %v", err)
+	}
+
+	w, reloads := startTestWatcher(t, path, false)
+
+	writeVersion("..2026_02", "..data_tmp", "debug")
+	if err := os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+
+	waitReloads(t, reloads, 1)
+	if level := w.GetCurrentConfig().Logging.Level; level != "debug" {
+		t.Errorf("Logging.Level = %q, want debug", level)
+	}
+}
@@ -0,0 +1,121 @@
+package filter
+
+import (
+	"github.com/KilimcininKorOglu/oba/internal/schema"
+	"github.com/KilimcininKorOglu/oba/internal/storage/index"
+)
+
+// Complexity of the assertions, relative to an equality match.
+const (
+	complexityEquality   = 1
+	complexityPresent    = 1
+	complexityOrdering   = 2
+	complexitySubstring  = 5
+	complexityExtensible = 5
+	complexityApprox     = 10
+)
+
+// Complexity estimates the cost of evaluating the filter against an
+// entry: 1 for equality and presence, 2 for ordering, 5 for substring and
+// extensible matches and 10 for approximate matches. An AND costs the sum
+// of its children, an OR twice its most complex child and a NOT one more
+// than its child.
+func (f *Filter) Complexity() int {
+	return complexity(f, assertionComplexity)
+}
+
+// WithSchemaComplexity returns a complexity function that, unlike
+// Complexity, counts assertions answered by an index of im as free. The
+// schema resolves attribute aliases to the indexed name and tells whether
+// substring assertions can use a substring index; a nil schema assumes
+// caseIgnoreSubstringsMatch.
+func WithSchemaComplexity(s *schema.Schema, im *index.IndexManager) func(*Filter) int {
+	return func(f *Filter) int {
+		return complexity(f, func(f *Filter) int {
+			if im != nil && indexedAssertion(s, im, f) {
+				return 0
+			}
+			return assertionComplexity(f)
+		})
+	}
+}
+
+// complexity computes the complexity of f, with the complexity of its
+// assertions given by assertion.
+func complexity(f *Filter, assertion func(*Filter) int) int {
+	if f == nil {
+		return 0
+	}
+
+	switch f.Type {
+	case FilterAnd:
+		total := 0
+		for _, child := range f.Children {
+			total += complexity(child, assertion)
+		}
+		return total
+	case FilterOr:
+		most := 0
+		for _, child := range f.Children {
+			if c := complexity(child, assertion); c > most {
+				most = c
+			}
+		}
+		return most * 2
+	case FilterNot:
+		return complexity(f.Child, assertion) + 1
+	default:
+		return assertion(f)
+	}
+}
+
+// assertionComplexity returns the complexity of the assertion f.
+func assertionComplexity(f *Filter) int {
+	switch f.Type {
+	case FilterEquality:
+		return complexityEquality
+	case FilterPresent:
+		return complexityPresent
+	case FilterGreaterOrEqual, FilterLessOrEqual:
+		return complexityOrdering
+	case FilterSubstring:
+		return complexitySubstring
+	case FilterExtensibleMatch:
+		return complexityExtensible
+	case FilterApproxMatch:
+		return complexityApprox
+	default:
+		return complexityEquality
+	}
+}
+
+// indexedAssertion reports whether the assertion f can be answered by an
+// index of im, as Optimizer.Optimize would use it.
+func indexedAssertion(s *schema.Schema, im *index.IndexManager, f *Filter) bool {
+	attr := f.Attribute
+	if f.Type == FilterSubstring && f.Substring != nil {
+		attr = f.Substring.Attribute
+	}
+
+	idx, ok := im.GetIndex(normalizeAttr(attr))
+	if !ok && s != nil {
+		if at := s.GetAttributeType(attr); at != nil {
+			attr = at.Name
+			idx, ok = im.GetIndex(normalizeAttr(attr))
+		}
+	}
+	if !ok {
+		return false
+	}
+
+	switch f.Type {
+	case FilterEquality, FilterGreaterOrEqual, FilterLessOrEqual:
+		return idx.Type == index.IndexEquality
+	case FilterPresent:
+		return idx.Type == index.IndexPresence
+	case FilterSubstring:
+		return idx.Type == index.IndexSubstring && substringRuleFor(s, normalizeAttr(attr)).indexable()
+	default:
+		return false
+	}
+}
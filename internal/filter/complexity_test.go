@@ -0,0 +1,89 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/schema"
+	"github.com/KilimcininKorOglu/oba/internal/storage/index"
+)
+
+// TestFilterComplexity tests the complexity of each filter type.
+func TestFilterComplexity(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   int
+	}{
+		{"(uid=alice)", 1},
+		{"(mail=*)", 1},
+		{"(uidNumber>=1000)", 2},
+		{"(cn=*admin*)", 5},
+		{"(cn:caseExactMatch:=Admin)", 5},
+		{"(cn~=alise)", 10},
+		{"(&(uid=alice)(cn=*admin*))", 6},
+		{"(|(uid=alice)(cn=*admin*))", 10},
+		{"(!(cn~=alise))", 11},
+		{"(&(objectClass=person)(|(uid=a)(!(mail=*))))", 5},
+	}
+
+	for _, tt := range tests {
+		f, err := Parse(tt.filter)
+		if err != nil {
+			t.Fatalf("Parse(%s) error = %v", tt.filter, err)
+		}
+		if got := f.Complexity(); got != tt.want {
+			t.Errorf("%s: Complexity() = %d, want %d", tt.filter, got, tt.want)
+		}
+	}
+}
+
+// TestWithSchemaComplexity tests that indexed assertions count as free.
+func TestWithSchemaComplexity(t *testing.T) {
+	pm, _, cleanup := testSetup(t)
+	defer cleanup()
+
+	im, err := index.NewIndexManager(pm)
+	if err != nil {
+		t.Fatalf("failed to create index manager: %v", err)
+	}
+	defer im.Close()
+	for _, attr := range []string{"description", "telephonenumber"} {
+		if err := im.CreateIndex(attr, index.IndexSubstring); err != nil {
+			t.Fatalf("failed to create substring index: %v", err)
+		}
+	}
+
+	complexity := WithSchemaComplexity(schema.LoadDefaultSchema(), im)
+	tests := []struct {
+		filter string
+		want   int
+	}{
+		// "uid" has a default equality index, found through its alias too
+		{"(uid=alice)", 0},
+		{"(userid=alice)", 0},
+		{"(uid>=a)", 0},
+		// An equality index does not answer presence
+		{"(mail=*)", 1},
+		{"(description=*admin*)", 0},
+		// telephoneNumberSubstringsMatch drops characters the index keeps
+		{"(telephoneNumber=*555*)", 5},
+		{"(uid~=alise)", 10},
+		{"(&(uid=alice)(mail=*))", 1},
+		{"(!(uid=alice))", 1},
+	}
+
+	for _, tt := range tests {
+		f, err := Parse(tt.filter)
+		if err != nil {
+			t.Fatalf("Parse(%s) error = %v", tt.filter, err)
+		}
+		if got := complexity(f); got != tt.want {
+			t.Errorf("%s: complexity = %d, want %d", tt.filter, got, tt.want)
+		}
+	}
+
+	// Without indexes it is Complexity
+	f, _ := Parse("(&(uid=alice)(cn=*admin*))")
+	if got := WithSchemaComplexity(nil, nil)(f); got != f.Complexity() {
+		t.Errorf("complexity without indexes = %d, want %d", got, f.Complexity())
+	}
+}
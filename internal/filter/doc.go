@@ -15,6 +15,7 @@
 //   - Less-or-Equal (<=): Comparison filter
 //   - Present (=*): Attribute existence check
 //   - Approximate (~=): Fuzzy matching
+//   - Extensible (:=): Matching rule and DN attribute matching
 //
 // # Filter Construction
 //
@@ -77,6 +78,12 @@
 //	    // Entry matches filter
 //	}
 //
+// With WithObjectClassExpansion, objectClass equality filters also match
+// subclasses, so (objectClass=person) matches an entry that only stores
+// inetOrgPerson:
+//
+//	evaluator := filter.NewEvaluator(schema).WithObjectClassExpansion(schema)
+//
 // # Filter Optimization
 //
 // The Optimizer can reorder and simplify filters for better performance:
@@ -89,4 +96,10 @@
 //   - Reordering AND/OR children by selectivity
 //   - Eliminating redundant filters
 //   - Simplifying nested structures
+//
+// Filter.Complexity estimates the cost of evaluating a filter against an
+// entry, and WithSchemaComplexity lowers it for assertions answered by an
+// index. ReorderAnd uses them to evaluate the cheapest AND children first:
+//
+//	reordered := optimizer.ReorderAnd(f) // (&(mail=*)(uid=alice)) -> (&(uid=alice)(mail=*))
 package filter
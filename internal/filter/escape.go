@@ -0,0 +1,102 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EscapeValue escapes s for use as an assertion value in a filter string
+// (RFC 4515 Section 3). '*', '(', ')', '\' and NUL are written as \2a,
+// \28, \29, \5c and \00, so a value built from user input matches only
+// itself and cannot change the structure of the filter.
+func EscapeValue(s string) string {
+	if !strings.ContainsAny(s, "*()\\\x00") {
+		return s
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(s) + 8)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '*', '(', ')', '\\', 0:
+			fmt.Fprintf(&sb, "\\%02x", c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// String returns the filter in the string representation of RFC 4515,
+// with its values escaped. Parsing the result gives an equal filter.
+func (f *Filter) String() string {
+	var sb strings.Builder
+	f.writeTo(&sb)
+	return sb.String()
+}
+
+// writeTo writes the string representation of f to sb.
+func (f *Filter) writeTo(sb *strings.Builder) {
+	if f == nil {
+		return
+	}
+
+	sb.WriteByte('(')
+	switch f.Type {
+	case FilterAnd, FilterOr:
+		if f.Type == FilterAnd {
+			sb.WriteByte('&')
+		} else {
+			sb.WriteByte('|')
+		}
+		for _, child := range f.Children {
+			child.writeTo(sb)
+		}
+	case FilterNot:
+		sb.WriteByte('!')
+		f.Child.writeTo(sb)
+	case FilterEquality:
+		writeItem(sb, f.Attribute, "=", f.Value)
+	case FilterGreaterOrEqual:
+		writeItem(sb, f.Attribute, ">=", f.Value)
+	case FilterLessOrEqual:
+		writeItem(sb, f.Attribute, "<=", f.Value)
+	case FilterApproxMatch:
+		writeItem(sb, f.Attribute, "~=", f.Value)
+	case FilterPresent:
+		sb.WriteString(f.Attribute)
+		sb.WriteString("=*")
+	case FilterSubstring:
+		if sf := f.Substring; sf != nil {
+			sb.WriteString(sf.Attribute)
+			sb.WriteByte('=')
+			sb.WriteString(EscapeValue(string(sf.Initial)))
+			sb.WriteByte('*')
+			for _, part := range sf.Any {
+				sb.WriteString(EscapeValue(string(part)))
+				sb.WriteByte('*')
+			}
+			sb.WriteString(EscapeValue(string(sf.Final)))
+		}
+	case FilterExtensibleMatch:
+		if em := f.Extensible; em != nil {
+			sb.WriteString(em.Attribute)
+			if em.DNAttributes {
+				sb.WriteString(":dn")
+			}
+			if em.MatchingRule != "" {
+				sb.WriteByte(':')
+				sb.WriteString(em.MatchingRule)
+			}
+			writeItem(sb, "", ":=", em.Value)
+		}
+	}
+	sb.WriteByte(')')
+}
+
+// writeItem writes an attribute value assertion.
+func writeItem(sb *strings.Builder, attr, op string, value []byte) {
+	sb.WriteString(attr)
+	sb.WriteString(op)
+	sb.WriteString(EscapeValue(string(value)))
+}
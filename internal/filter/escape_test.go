@@ -0,0 +1,117 @@
+package filter
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEscapeValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"alice", "alice"},
+		{"a*b", `a\2ab`},
+		{"(admin)", `\28admin\29`},
+		{`C:\temp`, `C:\5ctemp`},
+		{"a\x00b", `a\00b`},
+		{"*)(uid=*", `\2a\29\28uid=\2a`},
+	}
+	for _, tt := range tests {
+		if got := EscapeValue(tt.value); got != tt.want {
+			t.Errorf("EscapeValue(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+
+	// An escaped value parses back to an equality match on itself
+	for _, tt := range tests {
+		f, err := Parse("(cn=" + EscapeValue(tt.value) + ")")
+		if err != nil {
+			t.Fatalf("Parse(escaped %q) error = %v", tt.value, err)
+		}
+		if f.Type != FilterEquality || string(f.Value) != tt.value {
+			t.Errorf("Parse(escaped %q) = %s %q, want an equality match", tt.value, f.Type, f.Value)
+		}
+	}
+}
+
+func TestParseEscapes(t *testing.T) {
+	f, err := Parse(`(cn=Jo\2a\28n\29)`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if f.Type != FilterEquality || string(f.Value) != "Jo*(n)" {
+		t.Errorf("Parse() = %s %q, want equality Jo*(n)", f.Type, f.Value)
+	}
+
+	f, err = Parse(`(cn=a\2a*b\5c*c)`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := &SubstringFilter{Attribute: "cn", Initial: []byte("a*"), Any: [][]byte{[]byte(`b\`)}, Final: []byte("c")}
+	if f.Type != FilterSubstring || !reflect.DeepEqual(f.Substring, want) {
+		t.Errorf("Parse() substring = %+v, want %+v", f.Substring, want)
+	}
+}
+
+func TestParseRejectsMalformed(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   error
+	}{
+		{"(cn=a)(b)", ErrInvalidValue},
+		{"(cn=a(b)", ErrInvalidValue},
+		{"(&(cn=a)(sn=b(c))", ErrUnbalancedParens},
+		{"(!(cn=a)(cn=b))", ErrInvalidValue},
+		{"(cn=a\x00b)", ErrInvalidValue},
+		{`(cn=a\2)`, ErrInvalidValue},
+		{`(cn=a\zz)`, ErrInvalidValue},
+		{"(cn>=a)(b)", ErrInvalidValue},
+		{"(c n=a)", ErrInvalidAttribute},
+		{"(cn*=a)", ErrInvalidAttribute},
+		{"(cn\x00=a)", ErrInvalidAttribute},
+		{"(1cn=a)", ErrInvalidAttribute},
+		{"(cn:bad rule:=a)", ErrInvalidFilter},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.filter); !errors.Is(err, tt.want) {
+			t.Errorf("Parse(%q) error = %v, want %v", tt.filter, err, tt.want)
+		}
+	}
+}
+
+func TestFilterString(t *testing.T) {
+	tests := []string{
+		"(cn=alice)",
+		"(mail=*)",
+		`(cn=Jo\2an\28\29)`,
+		"(cn=ab*c*d)",
+		"(cn=*x*)",
+		`(cn=a\5c*)`,
+		"(uidNumber>=1000)",
+		"(uidNumber<=1000)",
+		"(cn~=alise)",
+		"(cn:dn:caseExactMatch:=Admin)",
+		"(:caseIgnoreMatch:=x)",
+		"(&(objectClass=person)(|(uid=a)(!(mail=*))))",
+		"(cn;lang-fr=Jean)",
+	}
+	for _, filterStr := range tests {
+		f, err := Parse(filterStr)
+		if err != nil {
+			t.Fatalf("Parse(%s) error = %v", filterStr, err)
+		}
+		if got := f.String(); got != filterStr {
+			t.Errorf("String() = %s, want %s", got, filterStr)
+		}
+	}
+
+	f := NewEqualityFilter("cn", []byte("*)(objectClass=*"))
+	if got, want := f.String(), `(cn=\2a\29\28objectClass=\2a)`; got != want {
+		t.Errorf("String() = %s, want %s", got, want)
+	}
+	if _, err := Parse(f.String()); err != nil {
+		t.Errorf("Parse(String()) error = %v", err)
+	}
+}
@@ -1,12 +1,19 @@
 package filter
 
 import (
+	"strings"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
 	"github.com/KilimcininKorOglu/oba/internal/schema"
 )
 
 // Evaluator evaluates LDAP search filters against entries.
 type Evaluator struct {
 	schema *schema.Schema
+
+	// objectClasses resolves the superiors of object classes when
+	// objectClass equality filters match subclasses.
+	objectClasses *schema.Schema
 }
 
 // NewEvaluator creates a new filter evaluator with the given schema.
@@ -18,6 +25,15 @@ func NewEvaluator(s *schema.Schema) *Evaluator {
 	}
 }
 
+// WithObjectClassExpansion makes objectClass equality filters match the
+// subclasses of the asserted class, as resolved by s: (objectClass=person)
+// matches an entry that only stores inetOrgPerson, and (objectClass=top)
+// matches every entry with a known class. It returns the evaluator.
+func (e *Evaluator) WithObjectClassExpansion(s *schema.Schema) *Evaluator {
+	e.objectClasses = s
+	return e
+}
+
 // Evaluate tests whether an entry matches a filter.
 // Returns true if the entry matches the filter, false otherwise.
 func (e *Evaluator) Evaluate(filter *Filter, entry *Entry) bool {
@@ -44,6 +60,8 @@ func (e *Evaluator) Evaluate(filter *Filter, entry *Entry) bool {
 		return e.evaluateLessOrEqual(filter.Attribute, filter.Value, entry)
 	case FilterApproxMatch:
 		return e.evaluateApproxMatch(filter.Attribute, filter.Value, entry)
+	case FilterExtensibleMatch:
+		return e.evaluateExtensibleMatch(filter.Extensible, entry)
 	default:
 		return false
 	}
@@ -103,6 +121,28 @@ func (e *Evaluator) evaluateEquality(attr string, value []byte, entry *Entry) bo
 			return true
 		}
 	}
+
+	if e.objectClasses != nil && normalizeAttributeName(attr) == "objectclass" {
+		return e.hasSuperiorClass(values, value)
+	}
+	return false
+}
+
+// hasSuperiorClass reports whether one of the object classes in values
+// inherits from the class named by value.
+func (e *Evaluator) hasSuperiorClass(values [][]byte, value []byte) bool {
+	name := string(value)
+	if oc := e.objectClasses.GetObjectClass(name); oc != nil {
+		name = oc.Name
+	}
+
+	for _, v := range values {
+		for _, sup := range e.objectClasses.ObjectClassChain(string(v)) {
+			if strings.EqualFold(sup, name) {
+				return true
+			}
+		}
+	}
 	return false
 }
 
@@ -117,8 +157,10 @@ func (e *Evaluator) evaluateSubstring(sf *SubstringFilter, entry *Entry) bool {
 		return false
 	}
 
+	// The assertion is normalized once for all values
+	matcher := newSubstringMatcher(substringRuleFor(e.schema, sf.Attribute), sf.Initial, sf.Any, sf.Final)
 	for _, v := range values {
-		if matchSubstring(v, sf.Initial, sf.Any, sf.Final) {
+		if matcher.match(v) {
 			return true
 		}
 	}
@@ -176,23 +218,132 @@ func (e *Evaluator) evaluateApproxMatch(attr string, value []byte, entry *Entry)
 	return false
 }
 
+// evaluateExtensibleMatch tests an entry against an extensible match filter.
+// Without a matching rule the attribute's equality matching is used; without
+// an attribute every attribute of the entry is tested. Unknown matching rules
+// never match.
+func (e *Evaluator) evaluateExtensibleMatch(em *ExtensibleMatchFilter, entry *Entry) bool {
+	if em == nil {
+		return false
+	}
+
+	match := matchEquality
+	if em.MatchingRule != "" {
+		var ok bool
+		if match, ok = lookupMatchingRule(em.MatchingRule); !ok {
+			return false
+		}
+	}
+
+	if em.Attribute != "" {
+		if matchAnyValue(e.getAttributeValues(em.Attribute, entry), em.Value, match) {
+			return true
+		}
+	} else {
+		for _, values := range entry.Attributes {
+			if matchAnyValue(values, em.Value, match) {
+				return true
+			}
+		}
+	}
+
+	if em.DNAttributes {
+		return matchDNAttributes(entry.DN, em.Attribute, em.Value, match)
+	}
+	return false
+}
+
 // getAttributeValues retrieves attribute values from an entry.
-// Performs case-insensitive attribute name lookup.
+// Performs case-insensitive attribute name lookup. With a schema, any name
+// of the attribute type matches, so "commonName" yields the values stored
+// under "cn". Attribute options are treated as subtypes (RFC 4512 Section
+// 2.5): "description" also yields the values of "description;lang-de",
+// while "description;lang-de" yields only the values of variants carrying
+// that option.
 func (e *Evaluator) getAttributeValues(attr string, entry *Entry) [][]byte {
-	// First try exact match
-	if values, ok := entry.Attributes[attr]; ok {
+	if strings.IndexByte(attr, ';') >= 0 || hasAttributeOptions(entry) {
+		return e.attributeValuesWithOptions(attr, entry)
+	}
+
+	if values, ok := lookupAttribute(attr, entry); ok {
 		return values
 	}
 
-	// Try case-insensitive match
+	// Try the other names of the attribute type
+	if e.schema != nil {
+		if at := e.schema.ResolveAlias(attr); at != nil {
+			for _, name := range at.Names {
+				if values, ok := lookupAttribute(name, entry); ok {
+					return values
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// lookupAttribute returns the values of the attribute attr of the entry,
+// matching the name exactly first and then without regard to case.
+func lookupAttribute(attr string, entry *Entry) ([][]byte, bool) {
+	if values, ok := entry.Attributes[attr]; ok {
+		return values, true
+	}
+
 	attrLower := normalizeAttributeName(attr)
 	for name, values := range entry.Attributes {
 		if normalizeAttributeName(name) == attrLower {
-			return values
+			return values, true
 		}
 	}
 
-	return nil
+	return nil, false
+}
+
+// hasAttributeOptions reports whether any attribute of the entry carries
+// options.
+func hasAttributeOptions(entry *Entry) bool {
+	for name := range entry.Attributes {
+		if strings.IndexByte(name, ';') >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// attributeValuesWithOptions collects the values of every attribute of the
+// entry matched by the attribute description attr.
+func (e *Evaluator) attributeValuesWithOptions(attr string, entry *Entry) [][]byte {
+	requested, err := ldap.ParseAttributeDescription(attr)
+	if err != nil {
+		return nil
+	}
+	requestedType := e.resolveAttributeType(requested.Name)
+
+	var result [][]byte
+	for name, values := range entry.Attributes {
+		stored, err := ldap.ParseAttributeDescription(name)
+		if err != nil {
+			continue
+		}
+		if requestedType != nil && e.resolveAttributeType(stored.Name) == requestedType {
+			stored.Name = requested.Name
+		}
+		if !ldap.MatchAttributeDescription(requested, stored) {
+			continue
+		}
+		result = append(result, values...)
+	}
+	return result
+}
+
+// resolveAttributeType returns the schema attribute type named name, or
+// nil without a schema.
+func (e *Evaluator) resolveAttributeType(name string) *schema.AttributeType {
+	if e.schema == nil {
+		return nil
+	}
+	return e.schema.ResolveAlias(name)
 }
 
 // GetSchema returns the evaluator's schema.
@@ -184,6 +184,86 @@ func TestEvaluateSubstring(t *testing.T) {
 	}
 }
 
+func TestEvaluateSubstringMatchingRules(t *testing.T) {
+	s := schema.LoadDefaultSchema()
+	serial := schema.NewAttributeType("1.3.6.1.4.1.99999.1", "serialCode")
+	serial.SetMatchingRules("caseExactMatch", "", "caseExactSubstringsMatch")
+	s.AddAttributeType(serial)
+
+	e := NewEvaluator(s)
+	entry := createTestEntry("uid=alice,dc=example,dc=com", map[string][]string{
+		"cn":              {"Alice Smith"},
+		"telephoneNumber": {"+1 555-123-4567"},
+		"serialCode":      {"AbC-123"},
+	})
+
+	tests := []struct {
+		name     string
+		attr     string
+		initial  string
+		any      []string
+		final    string
+		expected bool
+	}{
+		{"inherited caseIgnore", "cn", "alice", nil, "", true},
+		{"caseExact match", "serialCode", "AbC", nil, "", true},
+		{"caseExact case differs", "serialCode", "abc", nil, "", false},
+		{"telephoneNumber without separators", "telephoneNumber", "", []string{"5551234"}, "", true},
+		{"telephoneNumber other separators", "telephoneNumber", "", nil, "123 4567", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var anyBytes [][]byte
+			for _, a := range tt.any {
+				anyBytes = append(anyBytes, []byte(a))
+			}
+			filter := NewSubstringFilter(&SubstringFilter{
+				Attribute: tt.attr,
+				Initial:   []byte(tt.initial),
+				Any:       anyBytes,
+				Final:     []byte(tt.final),
+			})
+			if result := e.Evaluate(filter, entry); result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestEvaluateAttributeOptions(t *testing.T) {
+	e := NewEvaluator(schema.LoadDefaultSchema())
+	entry := createTestEntry("uid=alice,dc=example,dc=com", map[string][]string{
+		"description":         {"Plain"},
+		"description;lang-de": {"Beschreibung"},
+		"description;lang-en": {"Description"},
+	})
+
+	tests := []struct {
+		name     string
+		filter   *Filter
+		expected bool
+	}{
+		{"base matches untagged value", NewEqualityFilter("description", []byte("plain")), true},
+		{"base matches tagged value", NewEqualityFilter("description", []byte("beschreibung")), true},
+		{"tag matches its own value", NewEqualityFilter("description;lang-de", []byte("Beschreibung")), true},
+		{"tag ignores other tags", NewEqualityFilter("description;lang-de", []byte("Description")), false},
+		{"tag ignores untagged value", NewEqualityFilter("Description;Lang-DE", []byte("Plain")), false},
+		{"tag uses base matching rule", NewEqualityFilter("description;lang-en", []byte("DESCRIPTION")), true},
+		{"present with tag", NewPresentFilter("description;lang-en"), true},
+		{"present with missing tag", NewPresentFilter("description;lang-fr"), false},
+		{"substring on base", NewSubstringFilter(&SubstringFilter{Attribute: "description", Initial: []byte("besch")}), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := e.Evaluate(tt.filter, entry); result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
 func TestEvaluateSubstringNilFilter(t *testing.T) {
 	e := NewEvaluator(nil)
 	entry := createTestEntry("uid=test,dc=example,dc=com", map[string][]string{
@@ -304,6 +384,89 @@ func TestEvaluateApproxMatch(t *testing.T) {
 	}
 }
 
+func TestEvaluateExtensibleMatch(t *testing.T) {
+	e := NewEvaluator(nil)
+	entry := createTestEntry("cn=Alice Smith,ou=Engineering,dc=example,dc=com", map[string][]string{
+		"cn":      {"Alice  Smith", "alice"},
+		"manager": {"uid=Bob, ou=People, dc=example, dc=com"},
+	})
+
+	tests := []struct {
+		name     string
+		filter   string
+		expected bool
+	}{
+		{"caseIgnoreMatch by name", "(cn:caseIgnoreMatch:=ALICE)", true},
+		{"caseIgnoreMatch by OID", "(cn:2.5.13.2:=alice smith)", true},
+		{"caseIgnoreMatch no match", "(cn:caseIgnoreMatch:=bob)", false},
+		{"default equality rule", "(cn:=Alice)", true},
+		{"distinguishedNameMatch", "(manager:distinguishedNameMatch:=uid=bob,ou=people,dc=example,dc=com)", true},
+		{"distinguishedNameMatch no match", "(manager:2.5.13.1:=uid=carol,ou=people,dc=example,dc=com)", false},
+		{"rule without attribute", "(:caseIgnoreMatch:=ALICE)", true},
+		{"unknown rule", "(:dn:1.2.3.4:=engineering)", false},
+		{"attribute only in DN", "(ou:=Engineering)", false},
+		{"dnAttributes with attribute", "(ou:dn:=engineering)", true},
+		{"dnAttributes with rule", "(:dn:caseIgnoreMatch:=EXAMPLE)", true},
+		{"dnAttributes wrong attribute", "(ou:dn:=example)", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := Parse(tt.filter)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.filter, err)
+			}
+			result := e.Evaluate(filter, entry)
+			if result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+
+	t.Run("nil components", func(t *testing.T) {
+		if e.Evaluate(&Filter{Type: FilterExtensibleMatch}, entry) {
+			t.Error("expected false for nil extensible match")
+		}
+	})
+}
+
+func TestParseExtensibleMatch(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   ExtensibleMatchFilter
+	}{
+		{"(cn:caseIgnoreMatch:=alice)", ExtensibleMatchFilter{MatchingRule: "caseIgnoreMatch", Attribute: "cn", Value: []byte("alice")}},
+		{"(cn:=alice)", ExtensibleMatchFilter{Attribute: "cn", Value: []byte("alice")}},
+		{"(cn:dn:=alice)", ExtensibleMatchFilter{Attribute: "cn", Value: []byte("alice"), DNAttributes: true}},
+		{"(cn:DN:2.5.13.2:=alice)", ExtensibleMatchFilter{MatchingRule: "2.5.13.2", Attribute: "cn", Value: []byte("alice"), DNAttributes: true}},
+		{"(:dn:1.2.3.4:=value)", ExtensibleMatchFilter{MatchingRule: "1.2.3.4", Value: []byte("value"), DNAttributes: true}},
+		{"(:1.2.3.4:=a=b)", ExtensibleMatchFilter{MatchingRule: "1.2.3.4", Value: []byte("a=b")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			f, err := Parse(tt.filter)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if f.Type != FilterExtensibleMatch || f.Extensible == nil {
+				t.Fatalf("Parse() type = %v, want %v", f.Type, FilterExtensibleMatch)
+			}
+			got := *f.Extensible
+			if got.MatchingRule != tt.want.MatchingRule || got.Attribute != tt.want.Attribute ||
+				string(got.Value) != string(tt.want.Value) || got.DNAttributes != tt.want.DNAttributes {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	for _, invalid := range []string{"(:=alice)", "(:dn:=alice)", "(cn:dn:rule:extra:=x)", "(cn::=x)"} {
+		if _, err := Parse(invalid); err == nil {
+			t.Errorf("Parse(%q) expected error", invalid)
+		}
+	}
+}
+
 func TestEvaluateAnd(t *testing.T) {
 	e := NewEvaluator(nil)
 	entry := createTestEntry("uid=alice,dc=example,dc=com", map[string][]string{
@@ -706,6 +869,108 @@ func TestEvaluatorSchemaAccessors(t *testing.T) {
 	})
 }
 
+func TestEvaluateObjectClassExpansion(t *testing.T) {
+	s := schema.LoadDefaultSchema()
+	entries := []*Entry{
+		createTestEntry("uid=alice,ou=users,dc=example,dc=com", map[string][]string{
+			"objectClass": {"inetOrgPerson"},
+		}),
+		createTestEntry("ou=users,dc=example,dc=com", map[string][]string{
+			"objectClass": {"organizationalUnit"},
+		}),
+		createTestEntry("cn=admins,dc=example,dc=com", map[string][]string{
+			"objectClass": {"groupOfNames"},
+		}),
+		createTestEntry("dc=example,dc=com", map[string][]string{
+			"objectClass": {"domain", "dcObject"},
+		}),
+	}
+
+	count := func(e *Evaluator, f *Filter) int {
+		n := 0
+		for _, entry := range entries {
+			if e.Evaluate(f, entry) {
+				n++
+			}
+		}
+		return n
+	}
+
+	e := NewEvaluator(s).WithObjectClassExpansion(s)
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"top", 4},
+		{"TOP", 4},
+		{"2.5.6.0", 4},
+		{"person", 1},
+		{"organizationalPerson", 1},
+		{"inetOrgPerson", 1},
+		{"organizationalUnit", 1},
+		{"dcObject", 1},
+		{"device", 0},
+	}
+	for _, tt := range tests {
+		if got := count(e, NewEqualityFilter("objectClass", []byte(tt.value))); got != tt.want {
+			t.Errorf("(objectClass=%s) matched %d entries, want %d", tt.value, got, tt.want)
+		}
+	}
+
+	t.Run("without expansion", func(t *testing.T) {
+		if got := count(NewEvaluator(s), NewEqualityFilter("objectClass", []byte("top"))); got != 0 {
+			t.Errorf("(objectClass=top) matched %d entries, want 0", got)
+		}
+	})
+
+	t.Run("other attributes", func(t *testing.T) {
+		entry := createTestEntry("cn=test,dc=example,dc=com", map[string][]string{
+			"description": {"inetOrgPerson"},
+		})
+		if e.Evaluate(NewEqualityFilter("description", []byte("person")), entry) {
+			t.Error("expected expansion to apply only to objectClass")
+		}
+	})
+}
+
+func TestEvaluateAttributeAliases(t *testing.T) {
+	e := NewEvaluator(schema.LoadDefaultSchema())
+	byCN := createTestEntry("cn=alice,dc=example,dc=com", map[string][]string{
+		"cn": {"alice"},
+	})
+	byCommonName := createTestEntry("cn=bob,dc=example,dc=com", map[string][]string{
+		"commonName":         {"bob"},
+		"commonName;lang-de": {"robert"},
+	})
+
+	tests := []struct {
+		name   string
+		filter *Filter
+		entry  *Entry
+		want   bool
+	}{
+		{"commonName matches cn", NewEqualityFilter("commonName", []byte("alice")), byCN, true},
+		{"cn matches commonName", NewEqualityFilter("cn", []byte("bob")), byCommonName, true},
+		{"present by alias", NewPresentFilter("commonName"), byCN, true},
+		{"alias with options", NewEqualityFilter("cn;lang-de", []byte("robert")), byCommonName, true},
+		{"options as subtypes", NewEqualityFilter("CN", []byte("robert")), byCommonName, true},
+		{"other attribute", NewPresentFilter("sn"), byCN, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := e.Evaluate(tt.filter, tt.entry); got != tt.want {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("without schema", func(t *testing.T) {
+		if NewEvaluator(nil).Evaluate(NewEqualityFilter("commonName", []byte("alice")), byCN) {
+			t.Error("expected aliases to require a schema")
+		}
+	})
+}
+
 func TestUnknownFilterType(t *testing.T) {
 	e := NewEvaluator(nil)
 	entry := createTestEntry("uid=test,dc=example,dc=com", map[string][]string{
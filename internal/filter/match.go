@@ -3,8 +3,35 @@ package filter
 import (
 	"bytes"
 	"strings"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+)
+
+// Matching rule OIDs supported by extensible match filters (RFC 4517).
+const (
+	OIDCaseIgnoreMatch        = "2.5.13.2"
+	OIDDistinguishedNameMatch = "2.5.13.1"
 )
 
+// matchFunc compares an attribute value with an assertion value.
+type matchFunc func(value, assertion []byte) bool
+
+// matchingRules maps lowercased matching rule names and OIDs to their
+// implementations.
+var matchingRules = map[string]matchFunc{
+	"caseignorematch":         matchCaseIgnore,
+	OIDCaseIgnoreMatch:        matchCaseIgnore,
+	"distinguishednamematch":  matchDistinguishedName,
+	OIDDistinguishedNameMatch: matchDistinguishedName,
+}
+
+// lookupMatchingRule returns the implementation of a matching rule given by
+// name or OID.
+func lookupMatchingRule(rule string) (matchFunc, bool) {
+	match, ok := matchingRules[strings.ToLower(rule)]
+	return match, ok
+}
+
 // matchEquality performs case-insensitive equality matching between two byte slices.
 // This is the default matching behavior for string attributes in LDAP.
 func matchEquality(a, b []byte) bool {
@@ -16,44 +43,11 @@ func matchEqualityExact(a, b []byte) bool {
 	return bytes.Equal(a, b)
 }
 
-// matchSubstring checks if a value matches a substring filter pattern.
-// The pattern consists of optional initial, any (middle), and final components.
+// matchSubstring checks if a value matches a substring filter pattern under
+// caseIgnoreSubstringsMatch. The pattern consists of optional initial, any
+// (middle), and final components.
 func matchSubstring(value []byte, initial []byte, any [][]byte, final []byte) bool {
-	// Convert to lowercase for case-insensitive matching
-	valueLower := bytes.ToLower(value)
-	pos := 0
-
-	// Check initial substring
-	if len(initial) > 0 {
-		initialLower := bytes.ToLower(initial)
-		if !bytes.HasPrefix(valueLower, initialLower) {
-			return false
-		}
-		pos = len(initial)
-	}
-
-	// Check middle substrings (any)
-	for _, substr := range any {
-		if len(substr) == 0 {
-			continue
-		}
-		substrLower := bytes.ToLower(substr)
-		idx := bytes.Index(valueLower[pos:], substrLower)
-		if idx < 0 {
-			return false
-		}
-		pos += idx + len(substr)
-	}
-
-	// Check final substring
-	if len(final) > 0 {
-		finalLower := bytes.ToLower(final)
-		if !bytes.HasSuffix(valueLower[pos:], finalLower) {
-			return false
-		}
-	}
-
-	return true
+	return newSubstringMatcher(caseIgnoreSubstrings, initial, any, final).match(value)
 }
 
 // matchGreaterOrEqual performs case-insensitive greater-or-equal comparison.
@@ -110,3 +104,60 @@ func normalizeForApprox(value []byte) []byte {
 func normalizeAttributeName(name string) string {
 	return strings.ToLower(name)
 }
+
+// matchCaseIgnore implements caseIgnoreMatch: values are compared without
+// regard to case, ignoring leading, trailing and repeated whitespace.
+func matchCaseIgnore(value, assertion []byte) bool {
+	return bytes.Equal(normalizeForApprox(value), normalizeForApprox(assertion))
+}
+
+// matchDistinguishedName implements distinguishedNameMatch: both values are
+// parsed as DNs and compared in normalized form. Values that are not valid
+// DNs never match.
+func matchDistinguishedName(value, assertion []byte) bool {
+	a, err := ldap.NormalizeDN(string(value))
+	if err != nil {
+		return false
+	}
+	b, err := ldap.NormalizeDN(string(assertion))
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(a, b)
+}
+
+// matchAnyValue reports whether any of values matches the assertion.
+func matchAnyValue(values [][]byte, assertion []byte, match matchFunc) bool {
+	for _, v := range values {
+		if match(v, assertion) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchDNAttributes tests the attribute values that make up the RDNs of dn.
+// If attr is empty, every RDN attribute is tested.
+func matchDNAttributes(dn, attr string, assertion []byte, match matchFunc) bool {
+	rdns, err := ldap.SplitDN(dn)
+	if err != nil {
+		return false
+	}
+
+	attrLower := normalizeAttributeName(attr)
+	for _, rdn := range rdns {
+		avas, err := ldap.ParseRDN(rdn)
+		if err != nil {
+			return false
+		}
+		for _, ava := range avas {
+			if attr != "" && normalizeAttributeName(ava.Type) != attrLower {
+				continue
+			}
+			if match([]byte(ava.Value), assertion) {
+				return true
+			}
+		}
+	}
+	return false
+}
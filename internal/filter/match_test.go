@@ -1,6 +1,8 @@
 package filter
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -77,6 +79,13 @@ func TestMatchSubstring(t *testing.T) {
 		{"empty any", []byte("hello"), nil, [][]byte{[]byte("")}, nil, true},
 		{"multiple any", []byte("a-b-c-d"), nil, [][]byte{[]byte("-"), []byte("-"), []byte("-")}, nil, true},
 		{"multiple any partial match", []byte("a-b-c"), nil, [][]byte{[]byte("-"), []byte("-"), []byte("-")}, nil, false},
+		{"initial and final overlap", []byte("abc"), []byte("ab"), nil, []byte("bc"), false},
+		{"any inside initial", []byte("abcdef"), []byte("abc"), [][]byte{[]byte("bc")}, nil, false},
+		{"any inside final", []byte("abcdef"), nil, [][]byte{[]byte("de")}, []byte("def"), false},
+		{"initial shrinks when folded", []byte("İSTANBUL"), []byte("İS"), [][]byte{[]byte("ta")}, nil, true},
+		{"unicode case insensitive", []byte("Grüße aus Köln"), nil, [][]byte{[]byte("GRÜSSE"), []byte("KÖLN")}, nil, false},
+		{"unicode any", []byte("Grüße aus Köln"), nil, [][]byte{[]byte("GRÜ"), []byte("KÖLN")}, nil, true},
+		{"non-ascii pattern ascii value", []byte("kelvin"), nil, [][]byte{[]byte("é")}, nil, false},
 	}
 
 	for _, tt := range tests {
@@ -89,6 +98,75 @@ func TestMatchSubstring(t *testing.T) {
 	}
 }
 
+func TestSubstringMatcherRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     string
+		value    string
+		initial  string
+		any      []string
+		final    string
+		expected bool
+	}{
+		{"caseExact match", "caseExactSubstringsMatch", "Hello World", "Hello", nil, "World", true},
+		{"caseExact case differs", "caseExactSubstringsMatch", "Hello World", "hello", nil, "", false},
+		{"caseExactIA5 by OID", "1.3.6.1.4.1.4203.1.2.1", "Hello World", "", []string{"o W"}, "", true},
+		{"caseIgnore by OID", "2.5.13.4", "Hello World", "HELLO", nil, "", true},
+		{"numericString ignores spaces", "numericStringSubstringsMatch", "123 456 789", "1234", []string{"67"}, "", true},
+		{"numericString pattern spaces", "numericStringSubstringsMatch", "123456789", "1 2 3", nil, "8 9", true},
+		{"telephoneNumber ignores separators", "telephoneNumberSubstringsMatch", "+1 555-123-4567", "+1555", nil, "4567", true},
+		{"telephoneNumber any across separators", "telephoneNumberSubstringsMatch", "+1 555-123-4567", "", []string{"5551234"}, "", true},
+		{"telephoneNumber wrong digits", "telephoneNumberSubstringsMatch", "+1 555-123-4567", "", []string{"999"}, "", false},
+		{"unknown rule falls back to caseIgnore", "", "Hello World", "HELLO", nil, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, ok := substringRules[strings.ToLower(tt.rule)]
+			if !ok {
+				rule = caseIgnoreSubstrings
+			}
+
+			var anyBytes [][]byte
+			for _, a := range tt.any {
+				anyBytes = append(anyBytes, []byte(a))
+			}
+
+			m := newSubstringMatcher(rule, []byte(tt.initial), anyBytes, []byte(tt.final))
+			if result := m.match([]byte(tt.value)); result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+			// The matcher reuses its buffer across values
+			if result := m.match([]byte(tt.value)); result != tt.expected {
+				t.Errorf("second match: expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestIndexFoldASCII(t *testing.T) {
+	tests := []struct {
+		value     string
+		component string
+		expected  int
+	}{
+		{"Hello World", "world", 6},
+		{"Hello World", "o", 4},
+		{"lLlL-LAZY", "lazy", 5},
+		{"abc", "abcd", -1},
+		{"abc", "", 0},
+		{"xyzXYZ", "yzx", 1},
+		{"1-2-3", "-3", 3},
+		{"Hello", "xyz", -1},
+	}
+
+	for _, tt := range tests {
+		if got := indexFoldASCII([]byte(tt.value), []byte(tt.component)); got != tt.expected {
+			t.Errorf("indexFoldASCII(%q, %q) = %d, want %d", tt.value, tt.component, got, tt.expected)
+		}
+	}
+}
+
 func TestMatchGreaterOrEqual(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -218,3 +296,49 @@ func TestNormalizeAttributeName(t *testing.T) {
 		})
 	}
 }
+
+// longDescription returns a multi-KB mixed-case description value.
+func longDescription(size int) []byte {
+	const sentence = "The Quick Brown Fox jumps over the Lazy Dog near the River Bank. "
+	value := make([]byte, 0, size+len(sentence))
+	for len(value) < size {
+		value = append(value, sentence...)
+	}
+	return value[:size]
+}
+
+func BenchmarkEvaluateSubstringLongValues(b *testing.B) {
+	patterns := []struct {
+		name string
+		sf   *SubstringFilter
+	}{
+		{"any", &SubstringFilter{Attribute: "description", Any: [][]byte{[]byte("LAZY CAT")}}},
+		{"initial-any-final", &SubstringFilter{
+			Attribute: "description",
+			Initial:   []byte("the quick"),
+			Any:       [][]byte{[]byte("fox"), []byte("river")},
+			Final:     []byte("BANK. "),
+		}},
+	}
+
+	for _, size := range []int{1024, 8192} {
+		entry := NewEntry("cn=doc,dc=example,dc=com")
+		var values [][]byte
+		for i := 0; i < 4; i++ {
+			values = append(values, longDescription(size+i))
+		}
+		entry.SetAttribute("description", values...)
+
+		for _, p := range patterns {
+			b.Run(fmt.Sprintf("%s/%dB", p.name, size), func(b *testing.B) {
+				e := NewEvaluator(nil)
+				f := NewSubstringFilter(p.sf)
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					e.Evaluate(f, entry)
+				}
+			})
+		}
+	}
+}
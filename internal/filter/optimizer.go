@@ -3,8 +3,10 @@
 package filter
 
 import (
+	"sort"
 	"strings"
 
+	"github.com/KilimcininKorOglu/oba/internal/schema"
 	"github.com/KilimcininKorOglu/oba/internal/storage/index"
 )
 
@@ -13,6 +15,7 @@ import (
 // available indexes and estimating execution costs.
 type Optimizer struct {
 	indexManager *index.IndexManager
+	schema       *schema.Schema
 }
 
 // NewOptimizer creates a new Optimizer with the given IndexManager.
@@ -23,6 +26,12 @@ func NewOptimizer(im *index.IndexManager) *Optimizer {
 	}
 }
 
+// SetSchema sets the schema used to look up matching rules. Without a
+// schema, substring assertions are assumed to use caseIgnoreSubstringsMatch.
+func (o *Optimizer) SetSchema(s *schema.Schema) {
+	o.schema = s
+}
+
 // Optimize analyzes a filter and returns an optimized query plan.
 // It considers available indexes and selects the most efficient execution strategy.
 func (o *Optimizer) Optimize(filter *Filter) *QueryPlan {
@@ -55,6 +64,57 @@ func (o *Optimizer) Optimize(filter *Filter) *QueryPlan {
 	}
 }
 
+// ReorderAnd returns filter with the children of its AND filters sorted by
+// ascending complexity, so that evaluation rejects entries with the
+// cheapest assertions first. Assertions answered by an index count as
+// free (see WithSchemaComplexity). Children of equal complexity keep their
+// order. filter itself is not modified.
+func (o *Optimizer) ReorderAnd(filter *Filter) *Filter {
+	return reorderAnd(filter, WithSchemaComplexity(o.schema, o.indexManager))
+}
+
+// reorderAnd sorts the AND children of f, at every level, by complexity.
+func reorderAnd(f *Filter, complexity func(*Filter) int) *Filter {
+	if f == nil {
+		return nil
+	}
+
+	switch f.Type {
+	case FilterAnd, FilterOr:
+		children := make([]*Filter, len(f.Children))
+		costs := make([]int, len(f.Children))
+		for i, child := range f.Children {
+			children[i] = reorderAnd(child, complexity)
+			costs[i] = complexity(children[i])
+		}
+		if f.Type == FilterAnd {
+			sort.Stable(byComplexity{children, costs})
+		}
+		reordered := *f
+		reordered.Children = children
+		return &reordered
+	case FilterNot:
+		reordered := *f
+		reordered.Child = reorderAnd(f.Child, complexity)
+		return &reordered
+	default:
+		return f
+	}
+}
+
+// byComplexity sorts filters by their precomputed complexity.
+type byComplexity struct {
+	filters []*Filter
+	costs   []int
+}
+
+func (b byComplexity) Len() int           { return len(b.filters) }
+func (b byComplexity) Less(i, j int) bool { return b.costs[i] < b.costs[j] }
+func (b byComplexity) Swap(i, j int) {
+	b.filters[i], b.filters[j] = b.filters[j], b.filters[i]
+	b.costs[i], b.costs[j] = b.costs[j], b.costs[i]
+}
+
 // optimizeEquality optimizes an equality filter (attr=value).
 // Uses an equality index if available.
 func (o *Optimizer) optimizeEquality(filter *Filter) *QueryPlan {
@@ -121,6 +181,12 @@ func (o *Optimizer) optimizeSubstring(filter *Filter) *QueryPlan {
 		return NewFullScanPlan(filter)
 	}
 
+	// Index keys are only case folded, which does not hold for rules that
+	// drop insignificant characters
+	if !substringRuleFor(o.schema, attr).indexable() {
+		return NewFullScanPlan(filter)
+	}
+
 	// Extract the best searchable component from the substring filter
 	lookup := o.extractSubstringLookup(filter.Substring)
 	if lookup == nil {
@@ -140,23 +206,24 @@ func (o *Optimizer) optimizeSubstring(filter *Filter) *QueryPlan {
 }
 
 // extractSubstringLookup extracts the best lookup key from a substring filter.
-// Returns the longest component that can be used for index lookup.
+// Returns the first component that can be used for index lookup, folded like
+// the substring index keys.
 func (o *Optimizer) extractSubstringLookup(sf *SubstringFilter) []byte {
 	// Prefer initial (prefix) as it's most selective
 	if len(sf.Initial) >= 3 {
-		return sf.Initial
+		return index.FoldSubstring(sf.Initial)
 	}
 
 	// Try any middle components
 	for _, any := range sf.Any {
 		if len(any) >= 3 {
-			return any
+			return index.FoldSubstring(any)
 		}
 	}
 
 	// Try final (suffix)
 	if len(sf.Final) >= 3 {
-		return sf.Final
+		return index.FoldSubstring(sf.Final)
 	}
 
 	return nil
@@ -330,6 +397,8 @@ func (o *Optimizer) estimateCost(filter *Filter) int {
 		return CostPostFilter
 	case FilterApproxMatch:
 		return CostPostFilter * 3 // Approximate matching is very expensive
+	case FilterExtensibleMatch:
+		return CostPostFilter * 2 // Matching rules may normalize every value
 	default:
 		return CostPostFilter
 	}
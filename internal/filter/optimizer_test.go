@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/KilimcininKorOglu/oba/internal/schema"
 	"github.com/KilimcininKorOglu/oba/internal/storage"
 	"github.com/KilimcininKorOglu/oba/internal/storage/index"
 )
@@ -257,6 +258,48 @@ func TestOptimizeSubstringWithIndex(t *testing.T) {
 	}
 }
 
+// TestOptimizeSubstringMatchingRules tests that lookup keys are case folded
+// like the index keys and that rules the index cannot serve fall back to a
+// full scan.
+func TestOptimizeSubstringMatchingRules(t *testing.T) {
+	pm, _, cleanup := testSetup(t)
+	defer cleanup()
+
+	im, err := index.NewIndexManager(pm)
+	if err != nil {
+		t.Fatalf("failed to create index manager: %v", err)
+	}
+	defer im.Close()
+
+	for _, attr := range []string{"description", "telephonenumber"} {
+		if err := im.CreateIndex(attr, index.IndexSubstring); err != nil {
+			t.Fatalf("failed to create substring index: %v", err)
+		}
+	}
+
+	opt := NewOptimizer(im)
+	opt.SetSchema(schema.LoadDefaultSchema())
+
+	plan := opt.Optimize(NewSubstringFilter(&SubstringFilter{
+		Attribute: "description",
+		Initial:   []byte("ADMIN"),
+	}))
+	if !plan.UseIndex {
+		t.Fatal("expected index usage for caseIgnoreSubstringsMatch")
+	}
+	if string(plan.IndexLookup) != "admin" {
+		t.Errorf("expected folded lookup key %q, got %q", "admin", plan.IndexLookup)
+	}
+
+	plan = opt.Optimize(NewSubstringFilter(&SubstringFilter{
+		Attribute: "telephoneNumber",
+		Initial:   []byte("+1 555"),
+	}))
+	if plan.UseIndex {
+		t.Error("expected full scan for telephoneNumberSubstringsMatch")
+	}
+}
+
 // TestOptimizeSubstringShortPattern tests substring with pattern too short.
 func TestOptimizeSubstringShortPattern(t *testing.T) {
 	pm, _, cleanup := testSetup(t)
@@ -886,3 +929,97 @@ func TestOptimizeSubstringWithFinalComponent(t *testing.T) {
 		t.Errorf("expected IndexLookup 'admin', got '%s'", plan.IndexLookup)
 	}
 }
+
+func TestSplitIndexTerms(t *testing.T) {
+	filter := NewAndFilter(
+		NewEqualityFilter("UID", []byte("alice")),
+		NewPresentFilter("mail"),
+		NewSubstringFilter(&SubstringFilter{Attribute: "cn", Initial: []byte("Al")}),
+		NewNotFilter(NewEqualityFilter("sn", []byte("Smith"))),
+	)
+
+	terms := SplitIndexTerms(filter)
+	if len(terms) != 2 {
+		t.Fatalf("expected 2 terms, got %d", len(terms))
+	}
+	if terms[0].Attribute != "uid" || string(terms[0].Value) != "alice" {
+		t.Errorf("unexpected equality term: %+v", terms[0])
+	}
+	if terms[1].Attribute != "mail" || terms[1].Value != nil {
+		t.Errorf("unexpected presence term: %+v", terms[1])
+	}
+
+	if terms := SplitIndexTerms(NewEqualityFilter("cn", []byte("x"))); len(terms) != 1 {
+		t.Errorf("expected a single term for an equality filter, got %d", len(terms))
+	}
+	if terms := SplitIndexTerms(NewOrFilter(NewEqualityFilter("cn", []byte("x")))); len(terms) != 0 {
+		t.Errorf("expected no terms for an OR filter, got %d", len(terms))
+	}
+}
+
+// andAttributes returns the attributes of the children of an AND filter.
+func andAttributes(f *Filter) []string {
+	attrs := make([]string, len(f.Children))
+	for i, child := range f.Children {
+		attrs[i] = child.Attribute
+		if child.Substring != nil {
+			attrs[i] = child.Substring.Attribute
+		}
+	}
+	return attrs
+}
+
+// TestReorderAnd tests that AND children are sorted by complexity.
+func TestReorderAnd(t *testing.T) {
+	pm, _, cleanup := testSetup(t)
+	defer cleanup()
+
+	im, err := index.NewIndexManager(pm)
+	if err != nil {
+		t.Fatalf("failed to create index manager: %v", err)
+	}
+	defer im.Close()
+
+	opt := NewOptimizer(im)
+	opt.SetSchema(schema.LoadDefaultSchema())
+
+	// Both assertions cost 1, but uid is indexed
+	f, err := Parse("(&(mail=*)(uid=alice))")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	reordered := opt.ReorderAnd(f)
+	if got := andAttributes(reordered); got[0] != "uid" || got[1] != "mail" {
+		t.Errorf("reordered = %v, want [uid mail]", got)
+	}
+	if got := andAttributes(f); got[0] != "mail" {
+		t.Errorf("original filter modified: %v", got)
+	}
+
+	// Nested ANDs are reordered; OR children keep their order
+	f, _ = Parse("(|(&(description~=x)(description=*y*)(l=z))(cn=a))")
+	reordered = opt.ReorderAnd(f)
+	if got := andAttributes(reordered.Children[0]); got[0] != "l" || got[1] != "description" || reordered.Children[0].Children[2].Type != FilterApproxMatch {
+		t.Errorf("nested AND = %v, want l, substring, approximate", got)
+	}
+	if reordered.Children[1].Attribute != "cn" {
+		t.Error("OR children should keep their order")
+	}
+}
+
+// TestReorderAndWithoutIndexes tests reordering by Complexity alone.
+func TestReorderAndWithoutIndexes(t *testing.T) {
+	f, _ := Parse("(&(cn~=alise)(mail=*)(cn=*admin*)(uid=alice))")
+	reordered := NewOptimizer(nil).ReorderAnd(f)
+
+	want := []string{"mail", "uid", "cn", "cn"}
+	got := andAttributes(reordered)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("reordered = %v, want %v", got, want)
+		}
+	}
+	if reordered.Children[3].Type != FilterApproxMatch {
+		t.Error("approximate match should come last")
+	}
+}
@@ -3,6 +3,8 @@ package filter
 import (
 	"errors"
 	"strings"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
 )
 
 // Parser errors
@@ -12,6 +14,8 @@ var (
 	ErrUnbalancedParens = errors.New("unbalanced parentheses")
 	ErrMissingAttribute = errors.New("missing attribute name")
 	ErrMissingValue     = errors.New("missing filter value")
+	ErrInvalidAttribute = errors.New("invalid filter attribute")
+	ErrInvalidValue     = errors.New("invalid filter value")
 )
 
 // Parse parses an LDAP filter string into a Filter structure.
@@ -22,9 +26,15 @@ var (
 //   - (attr>=value)    - greater or equal
 //   - (attr<=value)    - less or equal
 //   - (attr~=value)    - approximate match
+//   - (attr:dn:rule:=value) - extensible match (":dn" and ":rule" optional)
 //   - (&(f1)(f2)...)   - AND
 //   - (|(f1)(f2)...)   - OR
 //   - (!(filter))      - NOT
+//
+// Values use the escaping of RFC 4515: '*', '(', ')', '\' and NUL are
+// written as \2a, \28, \29, \5c and \00, and any byte may be written as
+// a backslash and two hex digits. Unescaped parentheses or NULs in a value
+// and attribute descriptions that are not valid are rejected.
 func Parse(filterStr string) (*Filter, error) {
 	filterStr = strings.TrimSpace(filterStr)
 	if filterStr == "" {
@@ -142,32 +152,31 @@ func parseFilterList(s string) ([]*Filter, error) {
 }
 
 func parseSimpleFilter(s string) (*Filter, error) {
-	// Check for different operators
-	if idx := strings.Index(s, ">="); idx > 0 {
-		attr := strings.TrimSpace(s[:idx])
-		value := s[idx+2:]
-		if attr == "" {
-			return nil, ErrMissingAttribute
-		}
-		return NewGreaterOrEqualFilter(attr, []byte(value)), nil
+	// Extensible match: the ":=" operator comes before any other '='
+	if idx := strings.Index(s, ":="); idx >= 0 && !strings.ContainsAny(s[:idx], "=<>~") {
+		return parseExtensibleFilter(s[:idx], s[idx+2:])
 	}
 
-	if idx := strings.Index(s, "<="); idx > 0 {
-		attr := strings.TrimSpace(s[:idx])
-		value := s[idx+2:]
-		if attr == "" {
-			return nil, ErrMissingAttribute
-		}
-		return NewLessOrEqualFilter(attr, []byte(value)), nil
-	}
-
-	if idx := strings.Index(s, "~="); idx > 0 {
-		attr := strings.TrimSpace(s[:idx])
-		value := s[idx+2:]
-		if attr == "" {
-			return nil, ErrMissingAttribute
+	// Check for different operators
+	for _, op := range []struct {
+		token string
+		new   func(string, []byte) *Filter
+	}{
+		{">=", NewGreaterOrEqualFilter},
+		{"<=", NewLessOrEqualFilter},
+		{"~=", NewApproxMatchFilter},
+	} {
+		if idx := strings.Index(s, op.token); idx > 0 {
+			attr, err := parseAttribute(s[:idx])
+			if err != nil {
+				return nil, err
+			}
+			value, err := unescapeValue(s[idx+2:])
+			if err != nil {
+				return nil, err
+			}
+			return op.new(attr, value), nil
 		}
-		return NewApproxMatchFilter(attr, []byte(value)), nil
 	}
 
 	// Equality or substring or presence
@@ -176,58 +185,165 @@ func parseSimpleFilter(s string) (*Filter, error) {
 		return nil, ErrInvalidFilter
 	}
 
-	attr := strings.TrimSpace(s[:idx])
-	value := s[idx+1:]
-
-	if attr == "" {
-		return nil, ErrMissingAttribute
+	attr, err := parseAttribute(s[:idx])
+	if err != nil {
+		return nil, err
 	}
+	value := s[idx+1:]
 
 	// Presence filter: (attr=*)
 	if value == "*" {
 		return NewPresentFilter(attr), nil
 	}
 
-	// Check for substring filter
+	// Check for substring filter. An escaped '*' (\2a) is a literal.
 	if strings.Contains(value, "*") {
 		return parseSubstringFilter(attr, value)
 	}
 
 	// Simple equality
-	return NewEqualityFilter(attr, []byte(value)), nil
+	v, err := unescapeValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return NewEqualityFilter(attr, v), nil
+}
+
+// parseExtensibleFilter parses the attr[:dn][:rule] part of an extensible
+// match filter. Either the attribute or the matching rule must be present.
+func parseExtensibleFilter(desc, value string) (*Filter, error) {
+	parts := strings.Split(strings.TrimSpace(desc), ":")
+
+	v, err := unescapeValue(value)
+	if err != nil {
+		return nil, err
+	}
+	em := &ExtensibleMatchFilter{
+		Attribute: parts[0],
+		Value:     v,
+	}
+	if em.Attribute != "" {
+		if _, err := parseAttribute(em.Attribute); err != nil {
+			return nil, err
+		}
+	}
+
+	rest := parts[1:]
+	if len(rest) > 0 && strings.EqualFold(rest[0], "dn") {
+		em.DNAttributes = true
+		rest = rest[1:]
+	}
+	switch len(rest) {
+	case 0:
+	case 1:
+		desc, err := ldap.ParseAttributeDescription(rest[0])
+		if err != nil || len(desc.Options) > 0 || desc.Name == "*" {
+			return nil, ErrInvalidFilter
+		}
+		em.MatchingRule = rest[0]
+	default:
+		return nil, ErrInvalidFilter
+	}
+
+	if em.Attribute == "" && em.MatchingRule == "" {
+		return nil, ErrMissingAttribute
+	}
+
+	return NewExtensibleMatchFilter(em), nil
 }
 
 func parseSubstringFilter(attr, value string) (*Filter, error) {
+	// Split on the unescaped '*' before unescaping the parts
 	parts := strings.Split(value, "*")
+	values := make([][]byte, len(parts))
+	for i, part := range parts {
+		v, err := unescapeValue(part)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
 
 	sf := &SubstringFilter{
 		Attribute: attr,
 	}
-
-	for i, part := range parts {
-		if part == "" {
+	for i, v := range values {
+		if len(v) == 0 {
 			continue
 		}
 
-		if i == 0 {
-			// First non-empty part is initial
-			sf.Initial = []byte(part)
-		} else if i == len(parts)-1 {
-			// Last non-empty part is final
-			sf.Final = []byte(part)
-		} else {
+		switch i {
+		case 0:
+			// A value not starting with '*' has an initial part
+			sf.Initial = v
+		case len(values) - 1:
+			// A value not ending with '*' has a final part
+			sf.Final = v
+		default:
 			// Middle parts are "any"
-			sf.Any = append(sf.Any, []byte(part))
+			sf.Any = append(sf.Any, v)
 		}
 	}
 
-	// Handle edge cases
-	if !strings.HasPrefix(value, "*") && len(parts) > 0 && parts[0] != "" {
-		sf.Initial = []byte(parts[0])
+	return NewSubstringFilter(sf), nil
+}
+
+// parseAttribute returns the attribute description of a filter item. It
+// must be an attribute type, a name or numeric OID, with options.
+func parseAttribute(s string) (string, error) {
+	attr := strings.TrimSpace(s)
+	if attr == "" {
+		return "", ErrMissingAttribute
+	}
+	if strings.Contains(attr, "*") {
+		return "", ErrInvalidAttribute
+	}
+	if _, err := ldap.ParseAttributeDescription(attr); err != nil {
+		return "", ErrInvalidAttribute
 	}
-	if !strings.HasSuffix(value, "*") && len(parts) > 0 && parts[len(parts)-1] != "" {
-		sf.Final = []byte(parts[len(parts)-1])
+	return attr, nil
+}
+
+// unescapeValue decodes the RFC 4515 escapes of an assertion value.
+// Unescaped parentheses and NULs end a value, so their presence means the
+// filter is malformed.
+func unescapeValue(s string) ([]byte, error) {
+	if strings.ContainsAny(s, "()\x00") {
+		return nil, ErrInvalidValue
+	}
+	if !strings.Contains(s, `\`) {
+		return []byte(s), nil
 	}
 
-	return NewSubstringFilter(sf), nil
+	value := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			value = append(value, s[i])
+			continue
+		}
+		if i+2 >= len(s) {
+			return nil, ErrInvalidValue
+		}
+		hi, ok1 := unhex(s[i+1])
+		lo, ok2 := unhex(s[i+2])
+		if !ok1 || !ok2 {
+			return nil, ErrInvalidValue
+		}
+		value = append(value, hi<<4|lo)
+		i += 2
+	}
+	return value, nil
+}
+
+// unhex returns the value of the hex digit c.
+func unhex(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
 }
@@ -3,6 +3,7 @@
 package filter
 
 import (
+	"github.com/KilimcininKorOglu/oba/internal/storage"
 	"github.com/KilimcininKorOglu/oba/internal/storage/index"
 )
 
@@ -128,3 +129,30 @@ func (p *QueryPlan) String() string {
 	}
 	return result
 }
+
+// SplitIndexTerms returns the equality and presence assertions that every
+// entry matching the filter must satisfy: the filter itself, or the direct
+// children of an AND filter. Other children are left for the post-filter.
+func SplitIndexTerms(filter *Filter) []storage.IndexTerm {
+	if filter == nil {
+		return nil
+	}
+
+	children := []*Filter{filter}
+	if filter.Type == FilterAnd {
+		children = filter.Children
+	}
+
+	var terms []storage.IndexTerm
+	for _, child := range children {
+		switch child.Type {
+		case FilterEquality:
+			if len(child.Value) > 0 {
+				terms = append(terms, storage.IndexTerm{Attribute: normalizeAttr(child.Attribute), Value: child.Value})
+			}
+		case FilterPresent:
+			terms = append(terms, storage.IndexTerm{Attribute: normalizeAttr(child.Attribute)})
+		}
+	}
+	return terms
+}
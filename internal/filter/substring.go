@@ -0,0 +1,264 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+
+	"github.com/KilimcininKorOglu/oba/internal/schema"
+	"github.com/KilimcininKorOglu/oba/internal/storage/index"
+)
+
+// substringRule describes how a substring matching rule prepares values and
+// assertion components before they are compared.
+type substringRule struct {
+	// fold compares values without regard to case.
+	fold bool
+
+	// insignificant lists bytes that are removed before comparison, such as
+	// the spaces of a numeric string.
+	insignificant string
+}
+
+// caseIgnoreSubstrings is caseIgnoreSubstringsMatch, the rule used for
+// attributes without a known substring matching rule.
+var caseIgnoreSubstrings = substringRule{fold: true}
+
+// substringRules maps lowercased substring matching rule names and OIDs to
+// their behavior (RFC 4517).
+var substringRules = map[string]substringRule{
+	"caseignoresubstringsmatch":      caseIgnoreSubstrings,
+	"2.5.13.4":                       caseIgnoreSubstrings,
+	"caseexactsubstringsmatch":       {},
+	"2.5.13.7":                       {},
+	"numericstringsubstringsmatch":   {insignificant: " "},
+	"2.5.13.10":                      {insignificant: " "},
+	"caseignorelistsubstringsmatch":  caseIgnoreSubstrings,
+	"2.5.13.12":                      caseIgnoreSubstrings,
+	"telephonenumbersubstringsmatch": {fold: true, insignificant: " -"},
+	"2.5.13.21":                      {fold: true, insignificant: " -"},
+	"caseignoreia5substringsmatch":   caseIgnoreSubstrings,
+	"1.3.6.1.4.1.1466.109.114.3":     caseIgnoreSubstrings,
+	"caseexactia5substringsmatch":    {},
+	"1.3.6.1.4.1.4203.1.2.1":         {},
+}
+
+// substringRuleFor returns the substring matching rule of attr in s. Without
+// a schema, or if the attribute has no known rule, caseIgnoreSubstringsMatch
+// is used.
+func substringRuleFor(s *schema.Schema, attr string) substringRule {
+	if s != nil {
+		if rule, ok := substringRules[strings.ToLower(s.GetEffectiveSubstringMatch(attr))]; ok {
+			return rule
+		}
+	}
+	return caseIgnoreSubstrings
+}
+
+// indexable reports whether substring index keys, which are only case
+// folded, can serve assertions under this rule.
+func (r substringRule) indexable() bool {
+	return r.insignificant == ""
+}
+
+// appendNormalized appends value to dst prepared for comparison.
+func (r substringRule) appendNormalized(dst, value []byte) []byte {
+	start := len(dst)
+	if r.fold {
+		dst = index.AppendFoldSubstring(dst, value)
+	} else {
+		dst = append(dst, value...)
+	}
+
+	if r.insignificant == "" {
+		return dst
+	}
+
+	kept := dst[:start]
+	for _, c := range dst[start:] {
+		if strings.IndexByte(r.insignificant, c) < 0 {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// substringMatcher is a substring assertion prepared for one matching rule.
+// It is not safe for concurrent use.
+type substringMatcher struct {
+	rule    substringRule
+	initial []byte
+	any     [][]byte
+	final   []byte
+
+	// buf holds the normalized form of the value being matched.
+	buf []byte
+}
+
+// newSubstringMatcher normalizes the components of a substring assertion
+// for rule. Empty any components are dropped.
+func newSubstringMatcher(rule substringRule, initial []byte, any [][]byte, final []byte) *substringMatcher {
+	m := &substringMatcher{
+		rule:    rule,
+		initial: rule.appendNormalized(nil, initial),
+		final:   rule.appendNormalized(nil, final),
+	}
+	for _, component := range any {
+		if normalized := rule.appendNormalized(nil, component); len(normalized) > 0 {
+			m.any = append(m.any, normalized)
+		}
+	}
+	return m
+}
+
+// match reports whether value matches the assertion. ASCII values under a
+// rule that only folds case are compared in place; other values are
+// normalized into a reused buffer first.
+func (m *substringMatcher) match(value []byte) bool {
+	if m.rule.insignificant == "" && (!m.rule.fold || isASCII(value)) {
+		return scanSubstring(value, m.initial, m.any, m.final, m.rule.fold)
+	}
+
+	m.buf = m.rule.appendNormalized(m.buf[:0], value)
+	return scanSubstring(m.buf, m.initial, m.any, m.final, false)
+}
+
+// scanSubstring matches value against normalized components in a single
+// left-to-right pass. The initial and final components are anchored, so they
+// are checked first and cut off; each any component is then taken at its
+// leftmost occurrence in what remains. The leftmost occurrence leaves the
+// most room for the components after it, so no backtracking is needed.
+//
+// If foldASCII is set, value is ASCII and is compared as if lowercased.
+func scanSubstring(value, initial []byte, any [][]byte, final []byte, foldASCII bool) bool {
+	if len(value) < len(initial)+len(final) {
+		return false
+	}
+	if !equalBytes(value[:len(initial)], initial, foldASCII) ||
+		!equalBytes(value[len(value)-len(final):], final, foldASCII) {
+		return false
+	}
+
+	rest := value[len(initial) : len(value)-len(final)]
+	for _, component := range any {
+		i := indexBytes(rest, component, foldASCII)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(component):]
+	}
+	return true
+}
+
+// equalBytes compares a value slice with a normalized component.
+func equalBytes(value, component []byte, foldASCII bool) bool {
+	if !foldASCII {
+		return bytes.Equal(value, component)
+	}
+	if len(value) != len(component) {
+		return false
+	}
+	for i, c := range value {
+		if lowerASCII(c) != component[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// indexBytes returns the index of the first occurrence of a normalized
+// component in value, or -1.
+func indexBytes(value, component []byte, foldASCII bool) int {
+	if !foldASCII {
+		return bytes.Index(value, component)
+	}
+	return indexFoldASCII(value, component)
+}
+
+// indexFoldASCII finds a lowercased component in an ASCII value of any case.
+// Candidate positions are located by searching for both cases of the first
+// byte with bytes.IndexByte, so the value is scanned once.
+func indexFoldASCII(value, component []byte) int {
+	n := len(component)
+	if n == 0 {
+		return 0
+	}
+	if n > len(value) {
+		return -1
+	}
+
+	// Occurrences of the first byte must leave room for the rest
+	limit := len(value) - n + 1
+	lower := component[0]
+	upper := upperASCII(lower)
+
+	nextLower := nextByte(value, 0, limit, lower)
+	nextUpper := -1
+	if upper != lower {
+		nextUpper = nextByte(value, 0, limit, upper)
+	}
+
+	for {
+		i := nextLower
+		if i < 0 || (nextUpper >= 0 && nextUpper < i) {
+			i = nextUpper
+		}
+		if i < 0 {
+			return -1
+		}
+
+		if equalBytes(value[i+1:i+n], component[1:], true) {
+			return i
+		}
+
+		if i == nextLower {
+			nextLower = nextByte(value, i+1, limit, lower)
+		} else {
+			nextUpper = nextByte(value, i+1, limit, upper)
+		}
+	}
+}
+
+// nextByte returns the index of the first c in value[from:limit], or -1.
+func nextByte(value []byte, from, limit int, c byte) int {
+	if from >= limit {
+		return -1
+	}
+	i := bytes.IndexByte(value[from:limit], c)
+	if i < 0 {
+		return -1
+	}
+	return from + i
+}
+
+// isASCII reports whether value contains only ASCII bytes.
+func isASCII(value []byte) bool {
+	for len(value) >= 8 {
+		if binary.LittleEndian.Uint64(value)&0x8080808080808080 != 0 {
+			return false
+		}
+		value = value[8:]
+	}
+	for _, c := range value {
+		if c >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// lowerASCII lowercases an ASCII letter.
+func lowerASCII(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+// upperASCII uppercases an ASCII letter.
+func upperASCII(c byte) byte {
+	if 'a' <= c && c <= 'z' {
+		return c - ('a' - 'A')
+	}
+	return c
+}
@@ -58,12 +58,13 @@ func (ft FilterType) String() string {
 
 // Filter represents an LDAP search filter.
 type Filter struct {
-	Type      FilterType
-	Attribute string
-	Value     []byte
-	Children  []*Filter        // For AND/OR filters
-	Child     *Filter          // For NOT filter
-	Substring *SubstringFilter // For substring filters
+	Type       FilterType
+	Attribute  string
+	Value      []byte
+	Children   []*Filter              // For AND/OR filters
+	Child      *Filter                // For NOT filter
+	Substring  *SubstringFilter       // For substring filters
+	Extensible *ExtensibleMatchFilter // For extensible match filters
 }
 
 // SubstringFilter represents the components of a substring filter.
@@ -74,6 +75,15 @@ type SubstringFilter struct {
 	Final     []byte   // Final substring (after last *)
 }
 
+// ExtensibleMatchFilter represents the components of an extensible match
+// filter (RFC 4511 Section 4.5.1.7.7).
+type ExtensibleMatchFilter struct {
+	MatchingRule string // Matching rule name or OID (optional)
+	Attribute    string // Attribute type (optional if MatchingRule is set)
+	Value        []byte // Assertion value
+	DNAttributes bool   // Also match the attributes of the entry's DN
+}
+
 // NewAndFilter creates a new AND filter with the given children.
 func NewAndFilter(children ...*Filter) *Filter {
 	return &Filter{
@@ -151,6 +161,16 @@ func NewApproxMatchFilter(attribute string, value []byte) *Filter {
 	}
 }
 
+// NewExtensibleMatchFilter creates a new extensible match filter.
+func NewExtensibleMatchFilter(em *ExtensibleMatchFilter) *Filter {
+	return &Filter{
+		Type:       FilterExtensibleMatch,
+		Attribute:  em.Attribute,
+		Value:      em.Value,
+		Extensible: em,
+	}
+}
+
 // Entry represents an LDAP entry for filter evaluation.
 // This is a simplified interface to avoid circular dependencies.
 type Entry struct {
@@ -36,6 +36,9 @@ type AddRequest struct {
 	Entry string
 	// Attributes contains the attributes for the new entry
 	Attributes []Attribute
+	// Controls are the controls sent with the request message. They are
+	// not part of the AddRequest encoding and are set by the server.
+	Controls []Control
 }
 
 // Errors for AddRequest parsing
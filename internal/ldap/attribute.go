@@ -0,0 +1,214 @@
+// Package ldap implements LDAP protocol message parsing and encoding
+// as specified in RFC 4511.
+package ldap
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidAttributeDescription is returned when an attribute description
+// is malformed.
+var ErrInvalidAttributeDescription = errors.New("ldap: invalid attribute description")
+
+// AttributeDescription is an attribute type with its options, as in
+// "cn;lang-fr" or "userCertificate;binary" (RFC 4512 Section 2.5).
+type AttributeDescription struct {
+	// Name is the attribute type: a name, a numeric OID, or "*" for all
+	// user attributes.
+	Name string
+	// Options are the attribute options in the order written. An option
+	// ending in '-' or '*' is a range that matches any option with that
+	// prefix.
+	Options []string
+}
+
+// ParseAttributeDescription parses an attribute description per RFC 4512
+// Section 2.5:
+//
+//	attributedescription = attributetype options
+//	options = *( SEMI option )
+//	option = 1*keychar
+//
+// As an extension for attribute selection, the type may be "*" and an
+// option may end in "*", so that "*;lang-*" selects all language tagged
+// user attributes.
+func ParseAttributeDescription(s string) (AttributeDescription, error) {
+	parts := strings.Split(strings.TrimSpace(s), ";")
+
+	name := parts[0]
+	if name != "*" && !isKeystring(name) && !isNumericOID(name) {
+		return AttributeDescription{}, ErrInvalidAttributeDescription
+	}
+
+	desc := AttributeDescription{Name: name}
+	for _, opt := range parts[1:] {
+		if !isOption(opt) {
+			return AttributeDescription{}, ErrInvalidAttributeDescription
+		}
+		desc.Options = append(desc.Options, opt)
+	}
+	return desc, nil
+}
+
+// String returns the attribute description in its textual form.
+func (d AttributeDescription) String() string {
+	if len(d.Options) == 0 {
+		return d.Name
+	}
+	return d.Name + ";" + strings.Join(d.Options, ";")
+}
+
+// HasBinaryOption reports whether the description carries the "binary"
+// transfer option (RFC 4522).
+func (d AttributeDescription) HasBinaryOption() bool {
+	for _, opt := range d.Options {
+		if strings.EqualFold(opt, "binary") {
+			return true
+		}
+	}
+	return false
+}
+
+// Key returns the name under which values of the description are stored:
+// the lowercased type followed by its lowercased tagging options. Options
+// are unordered (RFC 4512 Section 2.5), so they are sorted and duplicates
+// are dropped, making "cn;x-a;lang-de" and "cn;lang-de;x-a" the same
+// attribute. The transfer option "binary" only selects how values are
+// carried on the wire, so it is not part of the key and
+// "userCertificate;binary" is stored as "usercertificate".
+func (d AttributeDescription) Key() string {
+	options := make([]string, 0, len(d.Options))
+	for _, opt := range d.Options {
+		if strings.EqualFold(opt, "binary") {
+			continue
+		}
+		options = append(options, strings.ToLower(opt))
+	}
+	sort.Strings(options)
+
+	var b strings.Builder
+	b.WriteString(strings.ToLower(d.Name))
+	for i, opt := range options {
+		if i > 0 && opt == options[i-1] {
+			continue
+		}
+		b.WriteByte(';')
+		b.WriteString(opt)
+	}
+	return b.String()
+}
+
+// AttributeKey returns the storage key of the attribute description s, as
+// described for AttributeDescription.Key. Names that do not parse as an
+// attribute description are only lowercased.
+func AttributeKey(s string) string {
+	if strings.IndexByte(s, ';') < 0 {
+		return strings.ToLower(s)
+	}
+	desc, err := ParseAttributeDescription(s)
+	if err != nil {
+		return strings.ToLower(s)
+	}
+	return desc.Key()
+}
+
+// MatchAttributeDescription reports whether a stored attribute satisfies a
+// requested attribute description. The types must be equal, unless the
+// request is for "*". Every tagging option of the request must be present
+// on the stored attribute, while options the request does not mention are
+// ignored, so "cn" matches "cn;lang-fr" but "cn;lang-fr" does not match
+// "cn". The transfer option "binary" only selects the encoding and is not
+// used for matching.
+func MatchAttributeDescription(requested, stored AttributeDescription) bool {
+	if requested.Name != "*" && !strings.EqualFold(requested.Name, stored.Name) {
+		return false
+	}
+
+	for _, want := range requested.Options {
+		if strings.EqualFold(want, "binary") {
+			continue
+		}
+		if !hasMatchingOption(stored.Options, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasMatchingOption reports whether any option in options matches want.
+// A want ending in '-' or '*' matches by prefix, so "lang-*" and "lang-"
+// both match "lang-fr" and "lang-en-us".
+func hasMatchingOption(options []string, want string) bool {
+	want = strings.ToLower(want)
+	prefix := ""
+	switch {
+	case strings.HasSuffix(want, "*"):
+		prefix = strings.TrimSuffix(want, "*")
+	case strings.HasSuffix(want, "-"):
+		prefix = want
+	}
+
+	for _, opt := range options {
+		opt = strings.ToLower(opt)
+		if opt == want || (prefix != "" && strings.HasPrefix(opt, prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isKeystring reports whether s is a descr: ALPHA followed by letters,
+// digits and hyphens.
+func isKeystring(s string) bool {
+	if s == "" || !isAlpha(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isKeychar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isNumericOID reports whether s is a dotted sequence of numbers.
+func isNumericOID(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, part := range strings.Split(s, ".") {
+		if part == "" || (len(part) > 1 && part[0] == '0') {
+			return false
+		}
+		for i := 0; i < len(part); i++ {
+			if part[i] < '0' || part[i] > '9' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isOption reports whether s is a valid option, allowing a trailing '*'.
+func isOption(s string) bool {
+	s = strings.TrimSuffix(s, "*")
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isKeychar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isKeychar(c byte) bool {
+	return isAlpha(c) || (c >= '0' && c <= '9') || c == '-'
+}
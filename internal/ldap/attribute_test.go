@@ -0,0 +1,123 @@
+package ldap
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseAttributeDescription(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    AttributeDescription
+		wantErr bool
+	}{
+		{in: "cn", want: AttributeDescription{Name: "cn"}},
+		{in: "cn;lang-fr", want: AttributeDescription{Name: "cn", Options: []string{"lang-fr"}}},
+		{in: "userCertificate;binary", want: AttributeDescription{Name: "userCertificate", Options: []string{"binary"}}},
+		{in: "2.5.4.3;lang-en-us;x-foo", want: AttributeDescription{Name: "2.5.4.3", Options: []string{"lang-en-us", "x-foo"}}},
+		{in: "*;lang-*", want: AttributeDescription{Name: "*", Options: []string{"lang-*"}}},
+		{in: "", wantErr: true},
+		{in: "cn;", wantErr: true},
+		{in: "1cn", wantErr: true},
+		{in: "2.5..4", wantErr: true},
+		{in: "cn;lang_fr", wantErr: true},
+		{in: ";lang-fr", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAttributeDescription(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidAttributeDescription) {
+				t.Errorf("ParseAttributeDescription(%q) error = %v, want ErrInvalidAttributeDescription", tt.in, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseAttributeDescription(%q) error = %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseAttributeDescription(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+		if got.String() != tt.in {
+			t.Errorf("String() = %q, want %q", got.String(), tt.in)
+		}
+	}
+}
+
+func TestMatchAttributeDescription(t *testing.T) {
+	tests := []struct {
+		requested string
+		stored    string
+		want      bool
+	}{
+		{"cn", "cn", true},
+		{"CN", "cn;lang-fr", true},
+		{"cn;lang-fr", "cn;lang-fr", true},
+		{"cn;LANG-FR", "cn;lang-fr;x-foo", true},
+		{"cn;lang-fr", "cn", false},
+		{"cn;lang-fr", "cn;lang-de", false},
+		{"cn;lang-fr", "sn;lang-fr", false},
+		{"userCertificate;binary", "usercertificate", true},
+		{"userCertificate;binary", "usercertificate;binary", true},
+		{"*", "description;x-unknown", true},
+		{"*;lang-*", "cn;lang-fr", true},
+		{"*;lang-*", "sn;lang-en-us", true},
+		{"*;lang-*", "cn", false},
+		{"cn;lang-", "cn;lang-fr", true},
+	}
+
+	for _, tt := range tests {
+		requested, err := ParseAttributeDescription(tt.requested)
+		if err != nil {
+			t.Fatalf("ParseAttributeDescription(%q) error = %v", tt.requested, err)
+		}
+		stored, err := ParseAttributeDescription(tt.stored)
+		if err != nil {
+			t.Fatalf("ParseAttributeDescription(%q) error = %v", tt.stored, err)
+		}
+		if got := MatchAttributeDescription(requested, stored); got != tt.want {
+			t.Errorf("MatchAttributeDescription(%q, %q) = %v, want %v", tt.requested, tt.stored, got, tt.want)
+		}
+	}
+}
+
+func TestAttributeKey(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"cn", "cn"},
+		{"userCertificate;binary", "usercertificate"},
+		{"userCertificate;BINARY", "usercertificate"},
+		{"description;lang-EN;binary", "description;lang-en"},
+		{"CN;Lang-Fr", "cn;lang-fr"},
+		{"cn;x-a;lang-de", "cn;lang-de;x-a"},
+		{"cn;lang-de;LANG-DE", "cn;lang-de"},
+		{"bad;", "bad;"},
+	}
+
+	for _, tt := range tests {
+		if got := AttributeKey(tt.in); got != tt.want {
+			t.Errorf("AttributeKey(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestHasBinaryOption(t *testing.T) {
+	for in, want := range map[string]bool{
+		"userCertificate;binary":       true,
+		"userCertificate;Binary":       true,
+		"userCertificate":              false,
+		"description;lang-en;x-binary": false,
+	} {
+		desc, err := ParseAttributeDescription(in)
+		if err != nil {
+			t.Fatalf("ParseAttributeDescription(%q) error = %v", in, err)
+		}
+		if got := desc.HasBinaryOption(); got != want {
+			t.Errorf("%q.HasBinaryOption() = %v, want %v", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package ldap
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loadCorpusMessages returns the client corpus messages by name.
+func loadCorpusMessages(b *testing.B) (names []string, messages map[string][]byte) {
+	files, err := filepath.Glob(filepath.Join("testdata", "clients", "*.hex"))
+	if err != nil || len(files) == 0 {
+		b.Fatalf("no client corpus files found: %v", err)
+	}
+
+	messages = make(map[string][]byte)
+	for _, file := range files {
+		data, err := readHexFile(file)
+		if err != nil {
+			b.Fatalf("failed to read %s: %v", file, err)
+		}
+		name := strings.TrimSuffix(filepath.Base(file), ".hex")
+		names = append(names, name)
+		messages[name] = data
+	}
+	return names, messages
+}
+
+// parseRequestOperation parses the operation of a request message.
+func parseRequestOperation(msg *LDAPMessage) (interface{ Encode() ([]byte, error) }, error) {
+	data := msg.Operation.Data
+	switch msg.Operation.Tag {
+	case ApplicationBindRequest:
+		return ParseBindRequest(data)
+	case ApplicationUnbindRequest:
+		return ParseUnbindRequest(data)
+	case ApplicationSearchRequest:
+		return ParseSearchRequest(data)
+	case ApplicationModifyRequest:
+		return ParseModifyRequest(data)
+	case ApplicationAddRequest:
+		return ParseAddRequest(data)
+	case ApplicationDelRequest:
+		return ParseDeleteRequest(data)
+	case ApplicationModifyDNRequest:
+		return ParseModifyDNRequest(data)
+	case ApplicationCompareRequest:
+		return ParseCompareRequest(data)
+	case ApplicationAbandonRequest:
+		return ParseAbandonRequest(data)
+	default:
+		return nil, fmt.Errorf("no request parser for APPLICATION %d", msg.Operation.Tag)
+	}
+}
+
+// BenchmarkDecodeRequest benchmarks parsing the requests of the client
+// corpus, envelope and operation.
+func BenchmarkDecodeRequest(b *testing.B) {
+	names, messages := loadCorpusMessages(b)
+
+	for _, name := range names {
+		data := messages[name]
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				msg, err := ParseLDAPMessage(data)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := parseRequestOperation(msg); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkEncodeRequest benchmarks encoding the requests of the client
+// corpus, envelope and operation.
+func BenchmarkEncodeRequest(b *testing.B) {
+	names, messages := loadCorpusMessages(b)
+
+	for _, name := range names {
+		msg, err := ParseLDAPMessage(messages[name])
+		if err != nil {
+			b.Fatal(err)
+		}
+		req, err := parseRequestOperation(msg)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				opData, err := req.Encode()
+				if err != nil {
+					b.Fatal(err)
+				}
+				out := &LDAPMessage{
+					MessageID: msg.MessageID,
+					Operation: &RawOperation{Tag: msg.Operation.Tag, Data: opData},
+					Controls:  msg.Controls,
+				}
+				if _, err := out.Encode(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkEncodeSearchResultEntry benchmarks encoding a typical person
+// entry returned by a search.
+func BenchmarkEncodeSearchResultEntry(b *testing.B) {
+	entry := &SearchResultEntry{
+		ObjectName: "uid=jdoe,ou=users,dc=example,dc=com",
+		Attributes: []PartialAttribute{
+			{Type: "objectClass", Values: [][]byte{[]byte("top"), []byte("person"), []byte("organizationalPerson"), []byte("inetOrgPerson")}},
+			{Type: "uid", Values: [][]byte{[]byte("jdoe")}},
+			{Type: "cn", Values: [][]byte{[]byte("John Doe")}},
+			{Type: "sn", Values: [][]byte{[]byte("Doe")}},
+			{Type: "givenName", Values: [][]byte{[]byte("John")}},
+			{Type: "mail", Values: [][]byte{[]byte("jdoe@example.com")}},
+			{Type: "telephoneNumber", Values: [][]byte{[]byte("+1 555 0100")}},
+		},
+	}
+	for i := 0; i < 5; i++ {
+		entry.Attributes = append(entry.Attributes, PartialAttribute{
+			Type:   "memberOf",
+			Values: [][]byte{[]byte(fmt.Sprintf("cn=group%d,ou=groups,dc=example,dc=com", i))},
+		})
+	}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := entry.Encode(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -28,6 +28,9 @@ type CompareRequest struct {
 	Attribute string
 	// Value is the assertion value to compare against
 	Value []byte
+	// Controls are the controls sent with the request message. They are
+	// not part of the CompareRequest encoding and are set by the server.
+	Controls []Control
 }
 
 // Errors for CompareRequest parsing
@@ -0,0 +1,839 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// The strict decoder below is written from RFC 4511 and X.690 alone and
+// shares no code with the ber package. Clients decode our responses with
+// their own BER libraries; checking the encoder against a second decoder
+// catches bugs that our parser would accept because it shares the
+// encoder's assumptions. It is stricter than most clients: it requires
+// definite, minimal lengths (as DER does), minimal integers, booleans of
+// 0x00 or 0xFF, and DEFAULT values to be omitted.
+
+// BER identifier classes.
+const (
+	strictUniversal   = 0x00
+	strictApplication = 0x40
+	strictContext     = 0x80
+)
+
+// Universal tag numbers used by LDAP.
+const (
+	strictTagBoolean     = 1
+	strictTagInteger     = 2
+	strictTagOctetString = 4
+	strictTagEnumerated  = 10
+	strictTagSequence    = 16
+	strictTagSet         = 17
+)
+
+// strictElement is an element read by a strictReader.
+type strictElement struct {
+	class       byte
+	constructed bool
+	tag         int
+	content     []byte
+}
+
+// strictReader reads the elements of data one after another.
+type strictReader struct {
+	data []byte
+	off  int
+}
+
+// more reports whether elements remain.
+func (r *strictReader) more() bool {
+	return r.off < len(r.data)
+}
+
+// next reads the next element.
+func (r *strictReader) next() (strictElement, error) {
+	if len(r.data)-r.off < 2 {
+		return strictElement{}, fmt.Errorf("offset %d: truncated element", r.off)
+	}
+
+	id := r.data[r.off]
+	if id&0x1f == 0x1f {
+		return strictElement{}, fmt.Errorf("offset %d: high tag number form", r.off)
+	}
+	el := strictElement{class: id & 0xc0, constructed: id&0x20 != 0, tag: int(id & 0x1f)}
+
+	first := r.data[r.off+1]
+	r.off += 2
+	length := int(first)
+	if first&0x80 != 0 {
+		n := int(first & 0x7f)
+		switch {
+		case n == 0:
+			return el, fmt.Errorf("offset %d: indefinite length", r.off)
+		case n > 4:
+			return el, fmt.Errorf("offset %d: %d length octets", r.off, n)
+		case len(r.data)-r.off < n:
+			return el, fmt.Errorf("offset %d: truncated length", r.off)
+		case r.data[r.off] == 0:
+			return el, fmt.Errorf("offset %d: length with a leading zero octet", r.off)
+		}
+		length = 0
+		for _, b := range r.data[r.off : r.off+n] {
+			length = length<<8 | int(b)
+		}
+		r.off += n
+		if length < 0x80 {
+			return el, fmt.Errorf("offset %d: long form for length %d", r.off, length)
+		}
+	}
+
+	if length > len(r.data)-r.off {
+		return el, fmt.Errorf("offset %d: length %d exceeds the %d octets left", r.off, length, len(r.data)-r.off)
+	}
+	el.content = r.data[r.off : r.off+length]
+	r.off += length
+	return el, nil
+}
+
+// peek returns the next element without consuming it.
+func (r *strictReader) peek() (strictElement, error) {
+	off := r.off
+	el, err := r.next()
+	r.off = off
+	return el, err
+}
+
+// expect reads the next element, which must have the given identifier, and
+// returns its content.
+func (r *strictReader) expect(class byte, constructed bool, tag int, what string) ([]byte, error) {
+	el, err := r.next()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", what, err)
+	}
+	if el.class != class || el.constructed != constructed || el.tag != tag {
+		return nil, fmt.Errorf("%s: got identifier class 0x%02x constructed %t tag %d, want class 0x%02x constructed %t tag %d",
+			what, el.class, el.constructed, el.tag, class, constructed, tag)
+	}
+	return el.content, nil
+}
+
+// nextIs reports whether the next element has the given identifier.
+func (r *strictReader) nextIs(class byte, tag int) bool {
+	el, err := r.peek()
+	return err == nil && el.class == class && el.tag == tag
+}
+
+// end fails if elements remain.
+func (r *strictReader) end(what string) error {
+	if r.more() {
+		return fmt.Errorf("%s: %d trailing octets", what, len(r.data)-r.off)
+	}
+	return nil
+}
+
+func (r *strictReader) octetString(what string) ([]byte, error) {
+	return r.expect(strictUniversal, false, strictTagOctetString, what)
+}
+
+// integer reads an INTEGER, or an ENUMERATED if tag says so, and checks it
+// is within [min, max].
+func (r *strictReader) integer(tag int, min, max int64, what string) (int64, error) {
+	content, err := r.expect(strictUniversal, false, tag, what)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strictInteger(content)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", what, err)
+	}
+	if v < min || v > max {
+		return 0, fmt.Errorf("%s: %d is outside [%d, %d]", what, v, min, max)
+	}
+	return v, nil
+}
+
+func (r *strictReader) boolean(what string) (bool, error) {
+	content, err := r.expect(strictUniversal, false, strictTagBoolean, what)
+	if err != nil {
+		return false, err
+	}
+	return strictBoolean(content, what)
+}
+
+// strictInteger decodes the minimal two's complement content of an
+// INTEGER.
+func strictInteger(content []byte) (int64, error) {
+	if len(content) == 0 || len(content) > 8 {
+		return 0, fmt.Errorf("integer of %d octets", len(content))
+	}
+	if len(content) > 1 {
+		if (content[0] == 0x00 && content[1]&0x80 == 0) || (content[0] == 0xff && content[1]&0x80 != 0) {
+			return 0, fmt.Errorf("integer % x is not minimal", content)
+		}
+	}
+
+	v := int64(int8(content[0]))
+	for _, b := range content[1:] {
+		v = v<<8 | int64(b)
+	}
+	return v, nil
+}
+
+func strictBoolean(content []byte, what string) (bool, error) {
+	if len(content) != 1 || (content[0] != 0x00 && content[0] != 0xff) {
+		return false, fmt.Errorf("%s: boolean % x is not 00 or ff", what, content)
+	}
+	return content[0] == 0xff, nil
+}
+
+// strictPrimitiveOps are the protocol operations that are not constructed.
+var strictPrimitiveOps = map[int]bool{
+	ApplicationUnbindRequest:  true,
+	ApplicationDelRequest:     true,
+	ApplicationAbandonRequest: true,
+}
+
+// validateMessage checks that data is exactly one LDAPMessage whose
+// protocol operation and controls follow RFC 4511.
+func validateMessage(data []byte) error {
+	r := &strictReader{data: data}
+	envelope, err := r.expect(strictUniversal, true, strictTagSequence, "LDAPMessage")
+	if err != nil {
+		return err
+	}
+	if err := r.end("LDAPMessage"); err != nil {
+		return err
+	}
+
+	m := &strictReader{data: envelope}
+	if _, err := m.integer(strictTagInteger, 0, math.MaxInt32, "messageID"); err != nil {
+		return err
+	}
+
+	op, err := m.next()
+	if err != nil {
+		return fmt.Errorf("protocolOp: %w", err)
+	}
+	if op.class != strictApplication {
+		return fmt.Errorf("protocolOp: class 0x%02x is not APPLICATION", op.class)
+	}
+	if op.constructed == strictPrimitiveOps[op.tag] {
+		return fmt.Errorf("protocolOp: APPLICATION %d has the wrong constructed bit", op.tag)
+	}
+	if err := validateOperation(op.tag, op.content); err != nil {
+		return fmt.Errorf("APPLICATION %d: %w", op.tag, err)
+	}
+
+	if m.more() {
+		controls, err := m.expect(strictContext, true, 0, "controls")
+		if err != nil {
+			return err
+		}
+		if err := validateControls(controls); err != nil {
+			return err
+		}
+	}
+	return m.end("LDAPMessage")
+}
+
+// validateControls checks the content of the implicitly tagged [0]
+// Controls: the Control sequences themselves.
+func validateControls(content []byte) error {
+	r := &strictReader{data: content}
+	for r.more() {
+		control, err := r.expect(strictUniversal, true, strictTagSequence, "Control")
+		if err != nil {
+			return err
+		}
+
+		c := &strictReader{data: control}
+		oid, err := c.octetString("controlType")
+		if err != nil {
+			return err
+		}
+		if !isNumericOID(string(oid)) {
+			return fmt.Errorf("controlType %q is not a numeric OID", oid)
+		}
+		if c.nextIs(strictUniversal, strictTagBoolean) {
+			critical, err := c.boolean("criticality")
+			if err != nil {
+				return err
+			}
+			if !critical {
+				return errors.New("criticality: DEFAULT FALSE is encoded")
+			}
+		}
+		if c.more() {
+			if _, err := c.octetString("controlValue"); err != nil {
+				return err
+			}
+		}
+		if err := c.end("Control"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateOperation checks the content of a protocol operation.
+func validateOperation(tag int, content []byte) error {
+	r := &strictReader{data: content}
+
+	switch tag {
+	case ApplicationBindRequest:
+		if _, err := r.integer(strictTagInteger, 1, 127, "version"); err != nil {
+			return err
+		}
+		if _, err := r.octetString("name"); err != nil {
+			return err
+		}
+		auth, err := r.next()
+		if err != nil {
+			return fmt.Errorf("authentication: %w", err)
+		}
+		switch {
+		case auth.class == strictContext && auth.tag == AuthSimple && !auth.constructed:
+		case auth.class == strictContext && auth.tag == AuthSASL && auth.constructed:
+			s := &strictReader{data: auth.content}
+			if _, err := s.octetString("mechanism"); err != nil {
+				return err
+			}
+			if s.more() {
+				if _, err := s.octetString("credentials"); err != nil {
+					return err
+				}
+			}
+			if err := s.end("SaslCredentials"); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("authentication: unexpected tag [%d]", auth.tag)
+		}
+
+	case ApplicationUnbindRequest:
+		if len(content) != 0 {
+			return errors.New("UnbindRequest is not NULL")
+		}
+
+	case ApplicationSearchRequest:
+		if _, err := r.octetString("baseObject"); err != nil {
+			return err
+		}
+		if _, err := r.integer(strictTagEnumerated, 0, 2, "scope"); err != nil {
+			return err
+		}
+		if _, err := r.integer(strictTagEnumerated, 0, 3, "derefAliases"); err != nil {
+			return err
+		}
+		if _, err := r.integer(strictTagInteger, 0, math.MaxInt32, "sizeLimit"); err != nil {
+			return err
+		}
+		if _, err := r.integer(strictTagInteger, 0, math.MaxInt32, "timeLimit"); err != nil {
+			return err
+		}
+		if _, err := r.boolean("typesOnly"); err != nil {
+			return err
+		}
+		if err := validateFilter(r); err != nil {
+			return err
+		}
+		attrs, err := r.expect(strictUniversal, true, strictTagSequence, "attributes")
+		if err != nil {
+			return err
+		}
+		if err := validateOctetStrings(attrs, "attribute selector"); err != nil {
+			return err
+		}
+
+	case ApplicationModifyRequest:
+		if _, err := r.octetString("object"); err != nil {
+			return err
+		}
+		changes, err := r.expect(strictUniversal, true, strictTagSequence, "changes")
+		if err != nil {
+			return err
+		}
+		c := &strictReader{data: changes}
+		for c.more() {
+			change, err := c.expect(strictUniversal, true, strictTagSequence, "change")
+			if err != nil {
+				return err
+			}
+			ch := &strictReader{data: change}
+			if _, err := ch.integer(strictTagEnumerated, 0, 2, "operation"); err != nil {
+				return err
+			}
+			if _, err := readStrictAttribute(ch, 0); err != nil {
+				return err
+			}
+			if err := ch.end("change"); err != nil {
+				return err
+			}
+		}
+
+	case ApplicationAddRequest:
+		if _, err := r.octetString("entry"); err != nil {
+			return err
+		}
+		attrs, err := r.expect(strictUniversal, true, strictTagSequence, "attributes")
+		if err != nil {
+			return err
+		}
+		a := &strictReader{data: attrs}
+		for a.more() {
+			if _, err := readStrictAttribute(a, 1); err != nil {
+				return err
+			}
+		}
+
+	case ApplicationDelRequest:
+		// LDAPDN, the content is the DN itself
+		return nil
+
+	case ApplicationModifyDNRequest:
+		if _, err := r.octetString("entry"); err != nil {
+			return err
+		}
+		if _, err := r.octetString("newrdn"); err != nil {
+			return err
+		}
+		if _, err := r.boolean("deleteoldrdn"); err != nil {
+			return err
+		}
+		if r.more() {
+			if _, err := r.expect(strictContext, false, 0, "newSuperior"); err != nil {
+				return err
+			}
+		}
+
+	case ApplicationCompareRequest:
+		if _, err := r.octetString("entry"); err != nil {
+			return err
+		}
+		ava, err := r.expect(strictUniversal, true, strictTagSequence, "ava")
+		if err != nil {
+			return err
+		}
+		if err := validateAssertion(ava); err != nil {
+			return err
+		}
+
+	case ApplicationAbandonRequest:
+		v, err := strictInteger(content)
+		if err != nil {
+			return fmt.Errorf("messageID: %w", err)
+		}
+		if v < 0 || v > math.MaxInt32 {
+			return fmt.Errorf("messageID %d is out of range", v)
+		}
+		return nil
+
+	default:
+		_, err := decodeResponse(tag, content)
+		return err
+	}
+
+	return r.end("operation")
+}
+
+// validateOctetStrings checks that content is a series of OCTET STRINGs.
+func validateOctetStrings(content []byte, what string) error {
+	r := &strictReader{data: content}
+	for r.more() {
+		if _, err := r.octetString(what); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateAssertion checks the content of an AttributeValueAssertion.
+func validateAssertion(content []byte) error {
+	r := &strictReader{data: content}
+	if _, err := r.octetString("attributeDesc"); err != nil {
+		return err
+	}
+	if _, err := r.octetString("assertionValue"); err != nil {
+		return err
+	}
+	return r.end("AttributeValueAssertion")
+}
+
+// validateFilter reads and checks one Filter.
+func validateFilter(r *strictReader) error {
+	el, err := r.next()
+	if err != nil {
+		return fmt.Errorf("filter: %w", err)
+	}
+	if el.class != strictContext {
+		return fmt.Errorf("filter: class 0x%02x is not context-specific", el.class)
+	}
+	if el.constructed != (el.tag != FilterTagPresent) {
+		return fmt.Errorf("filter [%d]: wrong constructed bit", el.tag)
+	}
+
+	f := &strictReader{data: el.content}
+	switch el.tag {
+	case FilterTagAnd, FilterTagOr:
+		for f.more() {
+			if err := validateFilter(f); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case FilterTagNot:
+		if err := validateFilter(f); err != nil {
+			return err
+		}
+		return f.end("not")
+
+	case FilterTagEquality, FilterTagGreaterOrEqual, FilterTagLessOrEqual, FilterTagApproxMatch:
+		return validateAssertion(el.content)
+
+	case FilterTagSubstrings:
+		if _, err := f.octetString("substrings type"); err != nil {
+			return err
+		}
+		subs, err := f.expect(strictUniversal, true, strictTagSequence, "substrings")
+		if err != nil {
+			return err
+		}
+		if len(subs) == 0 {
+			return errors.New("substrings: no components")
+		}
+		s := &strictReader{data: subs}
+		for i := 0; s.more(); i++ {
+			sub, err := s.next()
+			if err != nil {
+				return fmt.Errorf("substring: %w", err)
+			}
+			if sub.class != strictContext || sub.constructed || sub.tag > SubstringFinal {
+				return fmt.Errorf("substring: unexpected identifier tag %d", sub.tag)
+			}
+			if sub.tag == SubstringInitial && i != 0 {
+				return errors.New("substring: initial is not first")
+			}
+			if sub.tag == SubstringFinal && s.more() {
+				return errors.New("substring: final is not last")
+			}
+		}
+		return f.end("substrings")
+
+	case FilterTagPresent:
+		return nil
+
+	case FilterTagExtensibleMatch:
+		last := 0
+		hasRuleOrType, hasValue := false, false
+		for f.more() {
+			part, err := f.next()
+			if err != nil {
+				return fmt.Errorf("extensible match: %w", err)
+			}
+			if part.class != strictContext || part.constructed || part.tag <= last || part.tag > ExtMatchDNAttributes {
+				return fmt.Errorf("extensible match: unexpected component [%d]", part.tag)
+			}
+			last = part.tag
+			switch part.tag {
+			case ExtMatchMatchingRule, ExtMatchType:
+				hasRuleOrType = true
+			case ExtMatchMatchValue:
+				hasValue = true
+			case ExtMatchDNAttributes:
+				dn, err := strictBoolean(part.content, "dnAttributes")
+				if err != nil {
+					return err
+				}
+				if !dn {
+					return errors.New("dnAttributes: DEFAULT FALSE is encoded")
+				}
+			}
+		}
+		if !hasValue || !hasRuleOrType {
+			return errors.New("extensible match: missing matchValue, or matchingRule and type")
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("filter: unknown choice [%d]", el.tag)
+	}
+}
+
+// readStrictAttribute reads an Attribute or PartialAttribute with at least
+// minValues values.
+func readStrictAttribute(r *strictReader, minValues int) (PartialAttribute, error) {
+	var attr PartialAttribute
+
+	content, err := r.expect(strictUniversal, true, strictTagSequence, "attribute")
+	if err != nil {
+		return attr, err
+	}
+	a := &strictReader{data: content}
+	typ, err := a.octetString("attribute type")
+	if err != nil {
+		return attr, err
+	}
+	attr.Type = string(typ)
+
+	vals, err := a.expect(strictUniversal, true, strictTagSet, "vals")
+	if err != nil {
+		return attr, err
+	}
+	v := &strictReader{data: vals}
+	for v.more() {
+		value, err := v.octetString("value")
+		if err != nil {
+			return attr, err
+		}
+		attr.Values = append(attr.Values, value)
+	}
+	if len(attr.Values) < minValues {
+		return attr, fmt.Errorf("attribute %q has %d values, want at least %d", attr.Type, len(attr.Values), minValues)
+	}
+	return attr, a.end("attribute")
+}
+
+// readStrictResult reads the components of an LDAPResult.
+func readStrictResult(r *strictReader) (LDAPResult, error) {
+	var result LDAPResult
+
+	code, err := r.integer(strictTagEnumerated, 0, math.MaxInt32, "resultCode")
+	if err != nil {
+		return result, err
+	}
+	result.ResultCode = ResultCode(code)
+
+	matched, err := r.octetString("matchedDN")
+	if err != nil {
+		return result, err
+	}
+	result.MatchedDN = string(matched)
+
+	message, err := r.octetString("diagnosticMessage")
+	if err != nil {
+		return result, err
+	}
+	result.DiagnosticMessage = string(message)
+
+	if r.nextIs(strictContext, ContextTagReferral) {
+		referral, err := r.expect(strictContext, true, ContextTagReferral, "referral")
+		if err != nil {
+			return result, err
+		}
+		uris := &strictReader{data: referral}
+		for uris.more() {
+			uri, err := uris.octetString("referral URI")
+			if err != nil {
+				return result, err
+			}
+			result.Referral = append(result.Referral, string(uri))
+		}
+		if len(result.Referral) == 0 {
+			return result, errors.New("referral: no URIs")
+		}
+	}
+	return result, nil
+}
+
+// encodableResponse is a response type of this package.
+type encodableResponse interface {
+	Encode() ([]byte, error)
+}
+
+// decodeResponse decodes the content of a response operation into the
+// response type of this package.
+func decodeResponse(tag int, content []byte) (encodableResponse, error) {
+	r := &strictReader{data: content}
+
+	var resp encodableResponse
+	switch tag {
+	case ApplicationBindResponse:
+		result, err := readStrictResult(r)
+		if err != nil {
+			return nil, err
+		}
+		bind := &BindResponse{LDAPResult: result}
+		if r.more() {
+			creds, err := r.expect(strictContext, false, ContextTagServerSASLCreds, "serverSaslCreds")
+			if err != nil {
+				return nil, err
+			}
+			bind.ServerSASLCreds = creds
+		}
+		resp = bind
+
+	case ApplicationSearchResultEntry:
+		name, err := r.octetString("objectName")
+		if err != nil {
+			return nil, err
+		}
+		entry := &SearchResultEntry{ObjectName: string(name)}
+		attrs, err := r.expect(strictUniversal, true, strictTagSequence, "attributes")
+		if err != nil {
+			return nil, err
+		}
+		a := &strictReader{data: attrs}
+		for a.more() {
+			attr, err := readStrictAttribute(a, 0)
+			if err != nil {
+				return nil, err
+			}
+			entry.Attributes = append(entry.Attributes, attr)
+		}
+		resp = entry
+
+	case ApplicationSearchResultDone, ApplicationModifyResponse, ApplicationAddResponse,
+		ApplicationDelResponse, ApplicationModifyDNResponse, ApplicationCompareResponse:
+		result, err := readStrictResult(r)
+		if err != nil {
+			return nil, err
+		}
+		switch tag {
+		case ApplicationSearchResultDone:
+			resp = &SearchResultDone{LDAPResult: result}
+		case ApplicationModifyResponse:
+			resp = &ModifyResponse{LDAPResult: result}
+		case ApplicationAddResponse:
+			resp = &AddResponse{LDAPResult: result}
+		case ApplicationDelResponse:
+			resp = &DeleteResponse{LDAPResult: result}
+		case ApplicationModifyDNResponse:
+			resp = &ModifyDNResponse{LDAPResult: result}
+		default:
+			resp = &CompareResponse{LDAPResult: result}
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown operation APPLICATION %d", tag)
+	}
+
+	return resp, r.end("operation")
+}
+
+// decodeResponseElement decodes a response as encoded by its Encode method,
+// with the APPLICATION tag.
+func decodeResponseElement(data []byte) (int, encodableResponse, error) {
+	r := &strictReader{data: data}
+	el, err := r.next()
+	if err != nil {
+		return 0, nil, err
+	}
+	if el.class != strictApplication || !el.constructed {
+		return 0, nil, fmt.Errorf("response is not a constructed APPLICATION element")
+	}
+	if err := r.end("response"); err != nil {
+		return 0, nil, err
+	}
+	resp, err := decodeResponse(el.tag, el.content)
+	return el.tag, resp, err
+}
+
+func TestStrictDecoder_RejectsNonCanonicalEncodings(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"indefinite length", []byte{0x30, 0x80, 0x02, 0x01, 0x01, 0x42, 0x00, 0x00, 0x00}},
+		{"long form for short length", []byte{0x30, 0x81, 0x05, 0x02, 0x01, 0x01, 0x42, 0x00}},
+		{"length with leading zero", []byte{0x30, 0x82, 0x00, 0x05, 0x02, 0x01, 0x01, 0x42, 0x00}},
+		{"non-minimal integer", []byte{0x30, 0x06, 0x02, 0x02, 0x00, 0x01, 0x42, 0x00}},
+		{"negative message ID", []byte{0x30, 0x05, 0x02, 0x01, 0xff, 0x42, 0x00}},
+		{"trailing data", []byte{0x30, 0x05, 0x02, 0x01, 0x01, 0x42, 0x00, 0x00}},
+		{"constructed unbind", []byte{0x30, 0x05, 0x02, 0x01, 0x01, 0x62, 0x00}},
+		{"boolean 01", []byte{
+			0x30, 0x12, 0x02, 0x01, 0x01, 0x6c, 0x0d,
+			0x04, 0x04, 'c', '=', 'a', 'b', 0x04, 0x02, 'c', '=', 0x01, 0x01, 0x01,
+		}},
+		{"controls wrapped in a SEQUENCE", []byte{
+			0x30, 0x13, 0x02, 0x01, 0x01, 0x42, 0x00,
+			0xa0, 0x0c, 0x30, 0x0a, 0x30, 0x08, 0x04, 0x06, '1', '.', '2', '.', '3', '4',
+		}},
+		{"criticality FALSE", []byte{
+			0x30, 0x14, 0x02, 0x01, 0x01, 0x42, 0x00,
+			0xa0, 0x0d, 0x30, 0x0b, 0x04, 0x06, '1', '.', '2', '.', '3', '4', 0x01, 0x01, 0x00,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateMessage(tt.data); err == nil {
+				t.Errorf("validateMessage(% x) accepted a non-canonical encoding", tt.data)
+			}
+		})
+	}
+
+	valid := []byte{
+		0x30, 0x11, 0x02, 0x01, 0x01, 0x42, 0x00,
+		0xa0, 0x0a, 0x30, 0x08, 0x04, 0x06, '1', '.', '2', '.', '3', '4',
+	}
+	if err := validateMessage(valid); err != nil {
+		t.Errorf("validateMessage() rejected a canonical unbind: %v", err)
+	}
+}
+
+// TestResponses_IndependentDecoder encodes every response type, wraps it
+// in a message as the server does and checks the result with the strict
+// decoder.
+func TestResponses_IndependentDecoder(t *testing.T) {
+	referral := LDAPResult{
+		ResultCode:        ResultReferral,
+		MatchedDN:         "dc=example,dc=com",
+		DiagnosticMessage: "referral",
+		Referral:          []string{"ldap://a.example.com/dc=example,dc=com", "ldap://b.example.com/"},
+	}
+
+	tests := []struct {
+		name string
+		resp encodableResponse
+	}{
+		{"BindResponse", &BindResponse{LDAPResult: NewSuccessResult()}},
+		{"BindResponse with SASL", &BindResponse{LDAPResult: LDAPResult{ResultCode: ResultSASLBindInProgress}, ServerSASLCreds: []byte{0x60, 0x00}}},
+		{"SearchResultEntry", &SearchResultEntry{
+			ObjectName: "cn=Zoë,dc=example,dc=com",
+			Attributes: []PartialAttribute{
+				{Type: "cn", Values: [][]byte{[]byte("Zoë")}},
+				{Type: "jpegPhoto", Values: [][]byte{{0xff, 0xd8, 0x00}, {}}},
+				{Type: "typesOnly"},
+			},
+		}},
+		{"SearchResultEntry without attributes", &SearchResultEntry{ObjectName: ""}},
+		{"SearchResultDone", &SearchResultDone{LDAPResult: NewErrorResultWithDN(ResultNoSuchObject, "dc=example,dc=com", "no such object")}},
+		{"SearchResultDone with referral", &SearchResultDone{LDAPResult: referral}},
+		{"ModifyResponse", &ModifyResponse{LDAPResult: NewSuccessResult()}},
+		{"AddResponse", &AddResponse{LDAPResult: NewErrorResult(ResultEntryAlreadyExists, "")}},
+		{"DeleteResponse", &DeleteResponse{LDAPResult: referral}},
+		{"ModifyDNResponse", &ModifyDNResponse{LDAPResult: NewSuccessResult()}},
+		{"CompareResponse", &CompareResponse{LDAPResult: LDAPResult{ResultCode: ResultCompareTrue}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := tt.resp.Encode()
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			tag, _, err := decodeResponseElement(encoded)
+			if err != nil {
+				t.Fatalf("independent decoder rejects the response: %v\n%x", err, encoded)
+			}
+
+			msg := &LDAPMessage{
+				MessageID: MaxMessageID,
+				Operation: &RawOperation{Tag: tag, Data: extractOperationData(encoded)},
+				Controls: []Control{
+					{OID: "1.2.840.113556.1.4.319", Value: []byte{0x30, 0x05, 0x02, 0x01, 0x00, 0x04, 0x00}},
+					{OID: "2.16.840.1.113730.3.4.2", Criticality: true},
+				},
+			}
+			wrapped, err := msg.Encode()
+			if err != nil {
+				t.Fatalf("LDAPMessage.Encode() error = %v", err)
+			}
+			if err := validateMessage(wrapped); err != nil {
+				t.Errorf("independent decoder rejects the message: %v\n%x", err, wrapped)
+			}
+		})
+	}
+}
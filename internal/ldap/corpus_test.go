@@ -0,0 +1,298 @@
+package ldap
+
+import (
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// updateGolden rewrites the golden files of the client corpus from the
+// current parser: go test ./internal/ldap -run TestClientCorpus -update
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of the client corpus")
+
+// readHexFile reads a corpus file: hex bytes separated by white space, with
+// lines starting with # as comments.
+func readHexFile(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var digits strings.Builder
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		digits.WriteString(strings.Join(strings.Fields(line), ""))
+	}
+	return hex.DecodeString(digits.String())
+}
+
+// describeMessage parses the operation of a request message and returns a
+// stable text form of the result, one field per line.
+func describeMessage(msg *LDAPMessage) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "messageID: %d\n", msg.MessageID)
+	fmt.Fprintf(&b, "operation: %s\n", msg.OperationType())
+
+	data := msg.Operation.Data
+	switch msg.Operation.Tag {
+	case ApplicationBindRequest:
+		req, err := ParseBindRequest(data)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "version: %d\n", req.Version)
+		fmt.Fprintf(&b, "name: %q\n", req.Name)
+		fmt.Fprintf(&b, "authMethod: %s\n", req.AuthMethod)
+		if req.AuthMethod == AuthMethodSimple {
+			fmt.Fprintf(&b, "password: %q\n", req.SimplePassword)
+		} else {
+			fmt.Fprintf(&b, "mechanism: %q\n", req.SASLCredentials.Mechanism)
+			fmt.Fprintf(&b, "credentials: %q\n", req.SASLCredentials.Credentials)
+		}
+
+	case ApplicationUnbindRequest:
+		if _, err := ParseUnbindRequest(data); err != nil {
+			return "", err
+		}
+
+	case ApplicationSearchRequest:
+		req, err := ParseSearchRequest(data)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "baseObject: %q\n", req.BaseObject)
+		fmt.Fprintf(&b, "scope: %s\n", req.Scope)
+		fmt.Fprintf(&b, "derefAliases: %s\n", req.DerefAliases)
+		fmt.Fprintf(&b, "sizeLimit: %d\n", req.SizeLimit)
+		fmt.Fprintf(&b, "timeLimit: %d\n", req.TimeLimit)
+		fmt.Fprintf(&b, "typesOnly: %t\n", req.TypesOnly)
+		fmt.Fprintf(&b, "filter: %s\n", describeFilter(req.Filter))
+		fmt.Fprintf(&b, "attributes: %q\n", req.Attributes)
+
+	case ApplicationModifyRequest:
+		req, err := ParseModifyRequest(data)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "object: %q\n", req.Object)
+		for _, change := range req.Changes {
+			fmt.Fprintf(&b, "change: %s %q %q\n", change.Operation, change.Attribute.Type, change.Attribute.Values)
+		}
+
+	case ApplicationAddRequest:
+		req, err := ParseAddRequest(data)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "entry: %q\n", req.Entry)
+		for _, attr := range req.Attributes {
+			fmt.Fprintf(&b, "attribute: %q %q\n", attr.Type, attr.Values)
+		}
+
+	case ApplicationDelRequest:
+		req, err := ParseDeleteRequest(data)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "dn: %q\n", req.DN)
+
+	case ApplicationModifyDNRequest:
+		req, err := ParseModifyDNRequest(data)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "entry: %q\n", req.Entry)
+		fmt.Fprintf(&b, "newRDN: %q\n", req.NewRDN)
+		fmt.Fprintf(&b, "deleteOldRDN: %t\n", req.DeleteOldRDN)
+		fmt.Fprintf(&b, "newSuperior: %q\n", req.NewSuperior)
+
+	case ApplicationCompareRequest:
+		req, err := ParseCompareRequest(data)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "dn: %q\n", req.DN)
+		fmt.Fprintf(&b, "attribute: %q\n", req.Attribute)
+		fmt.Fprintf(&b, "value: %q\n", req.Value)
+
+	case ApplicationAbandonRequest:
+		req, err := ParseAbandonRequest(data)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "abandonID: %d\n", req.MessageID)
+
+	default:
+		return "", fmt.Errorf("no request parser for APPLICATION %d", msg.Operation.Tag)
+	}
+
+	for _, ctrl := range msg.Controls {
+		fmt.Fprintf(&b, "control: %s critical=%t value=%x\n", ctrl.OID, ctrl.Criticality, ctrl.Value)
+	}
+
+	return b.String(), nil
+}
+
+// describeFilter returns a text form of filter close to RFC 4515 with the
+// assertion values quoted.
+func describeFilter(filter *SearchFilter) string {
+	if filter == nil {
+		return "<nil>"
+	}
+
+	switch filter.Type {
+	case FilterTagAnd, FilterTagOr:
+		op := "&"
+		if filter.Type == FilterTagOr {
+			op = "|"
+		}
+		var b strings.Builder
+		b.WriteString("(" + op)
+		for _, child := range filter.Children {
+			b.WriteString(describeFilter(child))
+		}
+		return b.String() + ")"
+	case FilterTagNot:
+		return "(!" + describeFilter(filter.Child) + ")"
+	case FilterTagEquality:
+		return fmt.Sprintf("(%s=%q)", filter.Attribute, filter.Value)
+	case FilterTagGreaterOrEqual:
+		return fmt.Sprintf("(%s>=%q)", filter.Attribute, filter.Value)
+	case FilterTagLessOrEqual:
+		return fmt.Sprintf("(%s<=%q)", filter.Attribute, filter.Value)
+	case FilterTagApproxMatch:
+		return fmt.Sprintf("(%s~=%q)", filter.Attribute, filter.Value)
+	case FilterTagPresent:
+		return fmt.Sprintf("(%s=*)", filter.Attribute)
+	case FilterTagSubstrings:
+		sub := filter.Substrings
+		return fmt.Sprintf("(%s=initial:%q any:%q final:%q)", filter.Attribute, sub.Initial, sub.Any, sub.Final)
+	case FilterTagExtensibleMatch:
+		ext := filter.ExtensibleMatch
+		return fmt.Sprintf("(%s:dn=%t:%s:=%q)", ext.Type, ext.DNAttributes, ext.MatchingRule, ext.MatchValue)
+	default:
+		return fmt.Sprintf("(unknown filter %d)", filter.Type)
+	}
+}
+
+// reencodeRequest parses the operation of msg and encodes it again, with
+// the message envelope.
+func reencodeRequest(msg *LDAPMessage) ([]byte, error) {
+	var req interface{ Encode() ([]byte, error) }
+	var err error
+
+	data := msg.Operation.Data
+	switch msg.Operation.Tag {
+	case ApplicationBindRequest:
+		req, err = ParseBindRequest(data)
+	case ApplicationUnbindRequest:
+		req, err = ParseUnbindRequest(data)
+	case ApplicationSearchRequest:
+		req, err = ParseSearchRequest(data)
+	case ApplicationModifyRequest:
+		req, err = ParseModifyRequest(data)
+	case ApplicationAddRequest:
+		req, err = ParseAddRequest(data)
+	case ApplicationDelRequest:
+		req, err = ParseDeleteRequest(data)
+	case ApplicationModifyDNRequest:
+		req, err = ParseModifyDNRequest(data)
+	case ApplicationCompareRequest:
+		req, err = ParseCompareRequest(data)
+	case ApplicationAbandonRequest:
+		req, err = ParseAbandonRequest(data)
+	default:
+		return nil, fmt.Errorf("no request parser for APPLICATION %d", msg.Operation.Tag)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	opData, err := req.Encode()
+	if err != nil {
+		return nil, err
+	}
+	reencoded := &LDAPMessage{
+		MessageID: msg.MessageID,
+		Operation: &RawOperation{Tag: msg.Operation.Tag, Data: opData},
+		Controls:  msg.Controls,
+	}
+	return reencoded.Encode()
+}
+
+// TestClientCorpus parses requests as sent by real clients and compares the
+// result with the golden file next to each. The requests are then encoded
+// by this package, which must give the same parse result and bytes that the
+// independent decoder accepts.
+func TestClientCorpus(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "clients", "*.hex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no client corpus files found")
+	}
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".hex")
+		t.Run(name, func(t *testing.T) {
+			data, err := readHexFile(file)
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", file, err)
+			}
+
+			msg, err := ParseLDAPMessage(data)
+			if err != nil {
+				t.Fatalf("ParseLDAPMessage() error = %v", err)
+			}
+			got, err := describeMessage(msg)
+			if err != nil {
+				t.Fatalf("failed to parse operation: %v", err)
+			}
+
+			golden := strings.TrimSuffix(file, ".hex") + ".golden"
+			if *updateGolden {
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read golden file: %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("parse result differs from %s\ngot:\n%s\nwant:\n%s", golden, got, want)
+			}
+
+			reencoded, err := reencodeRequest(msg)
+			if err != nil {
+				t.Fatalf("failed to encode the parsed request: %v", err)
+			}
+			if err := validateMessage(reencoded); err != nil {
+				t.Errorf("independent decoder rejects the encoded request: %v\n%x", err, reencoded)
+			}
+
+			remsg, err := ParseLDAPMessage(reencoded)
+			if err != nil {
+				t.Fatalf("failed to parse the encoded request: %v", err)
+			}
+			again, err := describeMessage(remsg)
+			if err != nil {
+				t.Fatalf("failed to parse the encoded operation: %v", err)
+			}
+			if again != got {
+				t.Errorf("encoded request parses differently\ngot:\n%s\nwant:\n%s", again, got)
+			}
+			if twice, err := reencodeRequest(remsg); err != nil || !bytes.Equal(twice, reencoded) {
+				t.Errorf("encoding is not stable: %x, then %x (%v)", reencoded, twice, err)
+			}
+		})
+	}
+}
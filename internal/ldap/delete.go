@@ -14,6 +14,9 @@ import (
 type DeleteRequest struct {
 	// DN is the distinguished name of the entry to delete
 	DN string
+	// Controls are the controls sent with the request message. They are
+	// not part of the DelRequest encoding and are set by the server.
+	Controls []Control
 }
 
 // Errors for DeleteRequest parsing
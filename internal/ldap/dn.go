@@ -0,0 +1,376 @@
+// Package ldap implements LDAP protocol message parsing and encoding
+// as specified in RFC 4511.
+package ldap
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Errors for DN parsing
+var (
+	// ErrInvalidDN is returned when a DN string is malformed
+	ErrInvalidDN = errors.New("ldap: invalid DN")
+	// ErrInvalidDNEscape is returned when a DN value contains a malformed escape sequence
+	ErrInvalidDNEscape = errors.New("ldap: invalid DN escape sequence")
+)
+
+// AttributeTypeAndValue is a single type=value pair of an RDN.
+type AttributeTypeAndValue struct {
+	// Type is the attribute type as written in the DN
+	Type string
+	// Value is the unescaped attribute value. Hex-encoded values (#...)
+	// are returned as written.
+	Value string
+}
+
+// hexDigits is used to encode escaped bytes.
+const hexDigits = "0123456789ABCDEF"
+
+// EscapeDNValue escapes an attribute value for use in a DN string per
+// RFC 4514 Section 2.4. The characters , + " \ < > ; are escaped with a
+// backslash, as are a leading '#' and leading or trailing spaces. NUL and
+// other control characters are escaped as \XX hex pairs.
+func EscapeDNValue(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	b.Grow(len(s) + 8)
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ',' || c == '+' || c == '"' || c == '\\' || c == '<' || c == '>' || c == ';':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == '#' && i == 0:
+			b.WriteString("\\#")
+		case c == ' ' && (i == 0 || i == len(s)-1):
+			b.WriteString("\\ ")
+		case c < 0x20 || c == 0x7F:
+			b.WriteByte('\\')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&0x0F])
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+// UnescapeDNValue reverses EscapeDNValue. It accepts both backslash-escaped
+// special characters and \XX hex pairs, so multi-byte UTF-8 characters may
+// be written as a sequence of hex pairs.
+func UnescapeDNValue(s string) (string, error) {
+	if strings.IndexByte(s, '\\') == -1 {
+		return s, nil
+	}
+
+	buf := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' {
+			buf = append(buf, c)
+			continue
+		}
+
+		if i+1 >= len(s) {
+			return "", ErrInvalidDNEscape
+		}
+		next := s[i+1]
+
+		if isHexDigit(next) {
+			if i+2 >= len(s) || !isHexDigit(s[i+2]) {
+				return "", ErrInvalidDNEscape
+			}
+			buf = append(buf, hexValue(next)<<4|hexValue(s[i+2]))
+			i += 2
+			continue
+		}
+
+		if !isDNEscapable(next) {
+			return "", ErrInvalidDNEscape
+		}
+		buf = append(buf, next)
+		i++
+	}
+
+	return string(buf), nil
+}
+
+// SplitDN splits a DN into its RDN strings, leaf first. Escaped and quoted
+// separators are not split on and the RDNs keep their original escaping.
+//
+// Example:
+//
+//	`cn=Smith\, John,ou=users,dc=example,dc=com` -> [`cn=Smith\, John`, "ou=users", "dc=example", "dc=com"]
+func SplitDN(dn string) ([]string, error) {
+	dn = strings.TrimSpace(dn)
+	if dn == "" {
+		return nil, nil
+	}
+
+	parts, err := splitUnescaped(dn, ",;")
+	if err != nil {
+		return nil, err
+	}
+
+	rdns := make([]string, 0, len(parts))
+	for _, part := range parts {
+		rdn := trimDNComponent(part)
+		if rdn == "" {
+			return nil, ErrInvalidDN
+		}
+		rdns = append(rdns, rdn)
+	}
+
+	return rdns, nil
+}
+
+// ParseRDN parses an RDN into its attribute type and value pairs.
+// Multi-valued RDNs (joined with '+') return more than one pair.
+func ParseRDN(rdn string) ([]AttributeTypeAndValue, error) {
+	avas, err := parseRDN(rdn)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]AttributeTypeAndValue, len(avas))
+	for i, ava := range avas {
+		result[i] = ava.AttributeTypeAndValue
+	}
+	return result, nil
+}
+
+// NormalizeDN parses a DN and re-serializes it in a canonical form:
+// attribute types are lowercased, the components of multi-valued RDNs are
+// sorted, insignificant whitespace is removed and values are re-escaped.
+// Attribute values keep their case.
+func NormalizeDN(dn string) (string, error) {
+	return normalizeDN(dn, false)
+}
+
+// CanonicalDN returns the form of dn used to key and compare entries. It is
+// NormalizeDN with the attribute values lowercased as well, so two DNs that
+// differ only in case, spacing, escaping or the order of the components of
+// a multi-valued RDN have the same canonical form:
+//
+//	CanonicalDN("UID=Alice+CN=Bob, DC=Com") -> "cn=bob+uid=alice,dc=com"
+//
+// A DN that cannot be parsed is lowercased and trimmed instead.
+func CanonicalDN(dn string) string {
+	canonical, err := normalizeDN(dn, true)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(dn))
+	}
+	return canonical
+}
+
+// normalizeDN implements NormalizeDN and CanonicalDN. Case is folded before
+// the components of multi-valued RDNs are sorted so their order does not
+// depend on the case of the input.
+func normalizeDN(dn string, foldValues bool) (string, error) {
+	rdns, err := SplitDN(dn)
+	if err != nil {
+		return "", err
+	}
+
+	normalized := make([]string, len(rdns))
+	for i, rdn := range rdns {
+		avas, err := parseRDN(rdn)
+		if err != nil {
+			return "", err
+		}
+
+		parts := make([]string, len(avas))
+		for j, ava := range avas {
+			value := ava.Value
+			if ava.hex {
+				value = strings.ToLower(value)
+			} else {
+				if foldValues {
+					value = strings.ToLower(value)
+				}
+				value = EscapeDNValue(value)
+			}
+			parts[j] = strings.ToLower(ava.Type) + "=" + value
+		}
+		sort.Strings(parts)
+		normalized[i] = strings.Join(parts, "+")
+	}
+
+	return strings.Join(normalized, ","), nil
+}
+
+// parsedAVA is an attribute type and value with its encoding form.
+type parsedAVA struct {
+	AttributeTypeAndValue
+	// hex is true if the value was written as a #-prefixed BER hex string
+	hex bool
+}
+
+// parseRDN parses an RDN into attribute type and value pairs.
+func parseRDN(rdn string) ([]parsedAVA, error) {
+	rdn = trimDNComponent(rdn)
+	if rdn == "" {
+		return nil, ErrInvalidDN
+	}
+
+	parts, err := splitUnescaped(rdn, "+")
+	if err != nil {
+		return nil, err
+	}
+
+	avas := make([]parsedAVA, 0, len(parts))
+	for _, part := range parts {
+		ava, err := parseAVA(part)
+		if err != nil {
+			return nil, err
+		}
+		avas = append(avas, ava)
+	}
+
+	return avas, nil
+}
+
+// parseAVA parses a single type=value pair.
+func parseAVA(s string) (parsedAVA, error) {
+	eqIdx := strings.IndexByte(s, '=')
+	if eqIdx == -1 {
+		return parsedAVA{}, ErrInvalidDN
+	}
+
+	attrType := strings.TrimSpace(s[:eqIdx])
+	if attrType == "" || strings.ContainsAny(attrType, "\\\" ") {
+		return parsedAVA{}, ErrInvalidDN
+	}
+
+	raw := trimDNComponent(s[eqIdx+1:])
+	ava := parsedAVA{AttributeTypeAndValue: AttributeTypeAndValue{Type: attrType}}
+
+	switch {
+	case strings.HasPrefix(raw, "#"):
+		for i := 1; i < len(raw); i++ {
+			if !isHexDigit(raw[i]) {
+				return parsedAVA{}, ErrInvalidDN
+			}
+		}
+		ava.Value = raw
+		ava.hex = true
+
+	case strings.HasPrefix(raw, "\""):
+		if closingQuote(raw) != len(raw)-1 {
+			return parsedAVA{}, ErrInvalidDN
+		}
+		value, err := UnescapeDNValue(raw[1 : len(raw)-1])
+		if err != nil {
+			return parsedAVA{}, err
+		}
+		ava.Value = value
+
+	default:
+		value, err := UnescapeDNValue(raw)
+		if err != nil {
+			return parsedAVA{}, err
+		}
+		ava.Value = value
+	}
+
+	return ava, nil
+}
+
+// splitUnescaped splits s on any of the separator bytes that are neither
+// escaped with a backslash nor inside a quoted value.
+func splitUnescaped(s, seps string) ([]string, error) {
+	var parts []string
+	start := 0
+	quoted := false
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			if i+1 >= len(s) {
+				return nil, ErrInvalidDNEscape
+			}
+			i++
+		case c == '"':
+			quoted = !quoted
+		case !quoted && strings.IndexByte(seps, c) != -1:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	if quoted {
+		return nil, ErrInvalidDN
+	}
+
+	return append(parts, s[start:]), nil
+}
+
+// closingQuote returns the index of the unescaped quote that closes the
+// quoted value starting at s[0], or -1 if there is none.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// trimDNComponent removes insignificant leading and trailing spaces while
+// keeping a trailing space that is escaped with a backslash.
+func trimDNComponent(s string) string {
+	s = strings.TrimLeft(s, " ")
+
+	end := len(s)
+	for end > 0 && s[end-1] == ' ' {
+		// Count the backslashes before the space; an odd count escapes it.
+		backslashes := 0
+		for j := end - 2; j >= 0 && s[j] == '\\'; j-- {
+			backslashes++
+		}
+		if backslashes%2 == 1 {
+			break
+		}
+		end--
+	}
+
+	return s[:end]
+}
+
+// isHexDigit reports whether c is a hexadecimal digit.
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// hexValue returns the value of a hexadecimal digit.
+func hexValue(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0'
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+// isDNEscapable reports whether c may follow a backslash in a DN value.
+func isDNEscapable(c byte) bool {
+	switch c {
+	case ',', '+', '"', '\\', '<', '>', ';', '#', '=', ' ':
+		return true
+	default:
+		return false
+	}
+}
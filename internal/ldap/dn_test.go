@@ -0,0 +1,212 @@
+package ldap
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEscapeDNValue(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"alice", "alice"},
+		{"Smith, John", `Smith\, John`},
+		{`a+b"c\d<e>f;g`, `a\+b\"c\\d\<e\>f\;g`},
+		{"#hash", `\#hash`},
+		{"mid#hash", "mid#hash"},
+		{" padded ", `\ padded\ `},
+		{"in ner", "in ner"},
+		{"nul\x00byte", `nul\00byte`},
+		{"tab\t", `tab\09`},
+		{"dél", "dél"},
+		{"a=b", "a=b"},
+	}
+
+	for _, tt := range tests {
+		if got := EscapeDNValue(tt.in); got != tt.want {
+			t.Errorf("EscapeDNValue(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestUnescapeDNValue(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "alice", want: "alice"},
+		{in: `Smith\, John`, want: "Smith, John"},
+		{in: `\#hash`, want: "#hash"},
+		{in: `\ padded\ `, want: " padded "},
+		{in: `d\C3\A9l`, want: "dél"},
+		{in: `a\=b`, want: "a=b"},
+		{in: `nul\00byte`, want: "nul\x00byte"},
+		{in: `trailing\`, wantErr: true},
+		{in: `bad\4`, wantErr: true},
+		{in: `bad\zz`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := UnescapeDNValue(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidDNEscape) {
+				t.Errorf("UnescapeDNValue(%q) error = %v, want ErrInvalidDNEscape", tt.in, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("UnescapeDNValue(%q) error = %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("UnescapeDNValue(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSplitDN(t *testing.T) {
+	got, err := SplitDN(`cn=Smith\, John , ou="a,b";dc=example,dc=com`)
+	if err != nil {
+		t.Fatalf("SplitDN() error = %v", err)
+	}
+	want := []string{`cn=Smith\, John`, `ou="a,b"`, "dc=example", "dc=com"}
+	if len(got) != len(want) {
+		t.Fatalf("SplitDN() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("SplitDN()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	if rdns, err := SplitDN(""); err != nil || rdns != nil {
+		t.Errorf("SplitDN(\"\") = %v, %v", rdns, err)
+	}
+	for _, dn := range []string{"cn=a,,dc=com", `cn=a\`, `cn="open`} {
+		if _, err := SplitDN(dn); err == nil {
+			t.Errorf("SplitDN(%q) expected error", dn)
+		}
+	}
+}
+
+func TestParseRDN(t *testing.T) {
+	avas, err := ParseRDN(`cn=Smith\, John+uid=jsmith`)
+	if err != nil {
+		t.Fatalf("ParseRDN() error = %v", err)
+	}
+	if len(avas) != 2 {
+		t.Fatalf("expected 2 pairs, got %d", len(avas))
+	}
+	if avas[0].Type != "cn" || avas[0].Value != "Smith, John" {
+		t.Errorf("avas[0] = %+v", avas[0])
+	}
+	if avas[1].Type != "uid" || avas[1].Value != "jsmith" {
+		t.Errorf("avas[1] = %+v", avas[1])
+	}
+
+	quoted, err := ParseRDN(`ou="a,b+c"`)
+	if err != nil || len(quoted) != 1 || quoted[0].Value != "a,b+c" {
+		t.Errorf("ParseRDN(quoted) = %+v, %v", quoted, err)
+	}
+
+	for _, rdn := range []string{"", "noequals", "=value", `c n=x`} {
+		if _, err := ParseRDN(rdn); err == nil {
+			t.Errorf("ParseRDN(%q) expected error", rdn)
+		}
+	}
+}
+
+func TestNormalizeDN(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "", want: ""},
+		{in: "UID=Alice, OU=Users, DC=Example, DC=Com", want: "uid=Alice,ou=Users,dc=Example,dc=Com"},
+		{in: "uid=alice+CN=Alice Smith,dc=com", want: "cn=Alice Smith+uid=alice,dc=com"},
+		{in: `cn=Smith\2C John,dc=com`, want: `cn=Smith\, John,dc=com`},
+		{in: `cn="Smith, John",dc=com`, want: `cn=Smith\, John,dc=com`},
+		{in: `cn=\ lead\ ,dc=com`, want: `cn=\ lead\ ,dc=com`},
+		{in: "cn=#04024869,dc=com", want: "cn=#04024869,dc=com"},
+		{in: "cn=#0A0b,dc=com", want: "cn=#0a0b,dc=com"},
+		{in: "cn=a;dc=com", want: "cn=a,dc=com"},
+		{in: "cn", wantErr: true},
+		{in: `cn=bad\q`, wantErr: true},
+		{in: "cn=#zz", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeDN(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("NormalizeDN(%q) expected error, got %q", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("NormalizeDN(%q) error = %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NormalizeDN(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalDN(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "", want: ""},
+		{in: "UID=Alice, OU=Users, DC=Example, DC=Com", want: "uid=alice,ou=users,dc=example,dc=com"},
+		{in: "uid=a+cn=b,dc=com", want: "cn=b+uid=a,dc=com"},
+		{in: "cn=b+uid=a,dc=com", want: "cn=b+uid=a,dc=com"},
+		{in: "cn=B+cn=a,dc=com", want: "cn=a+cn=b,dc=com"},
+		{in: `cn="Smith, John",dc=com`, want: `cn=smith\, john,dc=com`},
+		{in: "cn=#0A0b,dc=com", want: "cn=#0a0b,dc=com"},
+		{in: ` CN=Bad\q `, want: `cn=bad\q`},
+	}
+
+	for _, tt := range tests {
+		if got := CanonicalDN(tt.in); got != tt.want {
+			t.Errorf("CanonicalDN(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func FuzzEscapeDNValue(f *testing.F) {
+	for _, seed := range []string{"", "alice", "Smith, John", "#x", " a ", "\x00", "dél", `\`, `"q"`, "a+b=c;d"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		escaped := EscapeDNValue(s)
+
+		unescaped, err := UnescapeDNValue(escaped)
+		if err != nil {
+			t.Fatalf("UnescapeDNValue(%q) error = %v", escaped, err)
+		}
+		if unescaped != s {
+			t.Fatalf("round trip of %q gave %q", s, unescaped)
+		}
+
+		dn := "CN = " + escaped + " ,dc=example"
+		normalized, err := NormalizeDN(dn)
+		if err != nil {
+			t.Fatalf("NormalizeDN(%q) error = %v", dn, err)
+		}
+		if want := "cn=" + escaped + ",dc=example"; normalized != want {
+			t.Fatalf("NormalizeDN(%q) = %q, want %q", dn, normalized, want)
+		}
+
+		again, err := NormalizeDN(normalized)
+		if err != nil || again != normalized {
+			t.Fatalf("NormalizeDN not idempotent: %q -> %q (%v)", normalized, again, err)
+		}
+	})
+}
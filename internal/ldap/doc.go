@@ -69,9 +69,20 @@
 //	    },
 //	}
 //
+// # Distinguished Names
+//
+// DN attribute values are escaped per RFC 4514 when building DNs, and DNs
+// are split with escape-aware helpers rather than plain string operations:
+//
+//	dn := "cn=" + ldap.EscapeDNValue("Smith, John") + ",dc=example,dc=com"
+//	rdns, err := ldap.SplitDN(dn)        // [`cn=Smith\, John`, "dc=example", "dc=com"]
+//	norm, err := ldap.NormalizeDN(dn)    // lowercased types, sorted multi-valued RDNs
+//	key := ldap.CanonicalDN(dn)          // as NormalizeDN, with values lowercased too
+//
 // # References
 //
 //   - RFC 4511: LDAP Protocol
 //   - RFC 4512: LDAP Directory Information Models
 //   - RFC 4513: LDAP Authentication Methods
+//   - RFC 4514: LDAP String Representation of Distinguished Names
 package ldap
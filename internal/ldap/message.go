@@ -126,8 +126,8 @@ func parseControls(decoder *ber.BERDecoder) ([]Control, error) {
 	}
 
 	// Check what comes next - it could be:
-	// 1. A SEQUENCE OF Control (standard)
-	// 2. Direct Control SEQUENCE(s) (some clients)
+	// 1. Direct Control SEQUENCE(s) (standard, [0] is an implicit tag)
+	// 2. A SEQUENCE OF Control wrapper (older versions of this package)
 	class, _, tagNum, peekErr := decoder.PeekTag()
 	if peekErr != nil {
 		return nil, peekErr
@@ -309,23 +309,18 @@ func isConstructedOperation(tag int) bool {
 }
 
 // encodeControls encodes the Controls field.
+// RFC 4511 uses implicit tags, so the [0] tag replaces the SEQUENCE OF tag
+// and holds the Control sequences directly.
 func encodeControls(encoder *ber.BEREncoder, controls []Control) error {
 	// Write context tag [0] for controls
 	ctxPos := encoder.WriteContextTag(ContextTagControls, true)
 
-	// Write SEQUENCE OF Control
-	seqPos := encoder.BeginSequence()
-
 	for _, ctrl := range controls {
 		if err := encodeControl(encoder, ctrl); err != nil {
 			return err
 		}
 	}
 
-	if err := encoder.EndSequence(seqPos); err != nil {
-		return err
-	}
-
 	return encoder.EndContextTag(ctxPos)
 }
 
@@ -18,6 +18,9 @@ const (
 	ModifyOperationDelete ModifyOperation = 1
 	// ModifyOperationReplace replaces all values of an attribute
 	ModifyOperationReplace ModifyOperation = 2
+	// ModifyOperationIncrement adds a number to the values of an integer
+	// attribute (RFC 4525)
+	ModifyOperationIncrement ModifyOperation = 3
 )
 
 // String returns the string representation of the modify operation
@@ -29,6 +32,8 @@ func (m ModifyOperation) String() string {
 		return "Delete"
 	case ModifyOperationReplace:
 		return "Replace"
+	case ModifyOperationIncrement:
+		return "Increment"
 	default:
 		return "Unknown"
 	}
@@ -37,7 +42,7 @@ func (m ModifyOperation) String() string {
 // Modification represents a single modification in a ModifyRequest
 // Change ::= SEQUENCE {
 //
-//	operation       ENUMERATED { add(0), delete(1), replace(2) },
+//	operation       ENUMERATED { add(0), delete(1), replace(2), increment(3) },
 //	modification    PartialAttribute
 //
 // }
@@ -53,7 +58,7 @@ type Modification struct {
 //
 //	object          LDAPDN,
 //	changes         SEQUENCE OF change SEQUENCE {
-//	                    operation       ENUMERATED { add(0), delete(1), replace(2) },
+//	                    operation       ENUMERATED { add(0), delete(1), replace(2), increment(3) },
 //	                    modification    PartialAttribute
 //	                }
 //
@@ -63,6 +68,9 @@ type ModifyRequest struct {
 	Object string
 	// Changes contains the list of modifications to apply
 	Changes []Modification
+	// Controls are the controls sent with the request message. They are
+	// not part of the ModifyRequest encoding and are set by the server.
+	Controls []Control
 }
 
 // Errors for ModifyRequest parsing
@@ -116,7 +124,7 @@ func ParseModifyRequest(data []byte) (*ModifyRequest, error) {
 // parseModification parses a single modification from the decoder
 // Change ::= SEQUENCE {
 //
-//	operation       ENUMERATED { add(0), delete(1), replace(2) },
+//	operation       ENUMERATED { add(0), delete(1), replace(2), increment(3) },
 //	modification    PartialAttribute
 //
 // }
@@ -135,7 +143,7 @@ func parseModification(decoder *ber.BERDecoder) (Modification, error) {
 		return mod, NewParseError(decoder.Offset(), "failed to read operation", err)
 	}
 
-	if operation < 0 || operation > 2 {
+	if operation < 0 || operation > int64(ModifyOperationIncrement) {
 		return mod, ErrInvalidModifyOperation
 	}
 	mod.Operation = ModifyOperation(operation)
@@ -246,7 +254,7 @@ func (r *ModifyRequest) Validate() error {
 		return ErrEmptyModifications
 	}
 	for _, change := range r.Changes {
-		if change.Operation < 0 || change.Operation > 2 {
+		if change.Operation < 0 || change.Operation > ModifyOperationIncrement {
 			return ErrInvalidModifyOperation
 		}
 	}
@@ -26,6 +26,9 @@ type ModifyDNRequest struct {
 	DeleteOldRDN bool
 	// NewSuperior is the optional new parent DN (for moving entries)
 	NewSuperior string
+	// Controls are the controls sent with the request message. They are
+	// not part of the ModifyDNRequest encoding and are set by the server.
+	Controls []Control
 }
 
 // Errors for ModifyDNRequest parsing
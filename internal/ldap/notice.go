@@ -0,0 +1,50 @@
+package ldap
+
+import (
+	"github.com/KilimcininKorOglu/oba/internal/ber"
+)
+
+// NoticeOfDisconnectionOID is the responseName of the Notice of
+// Disconnection, per RFC 4511 Section 4.4.1.
+const NoticeOfDisconnectionOID = "1.3.6.1.4.1.1466.20036"
+
+// NewNoticeOfDisconnection creates a Notice of Disconnection: an
+// unsolicited ExtendedResponse with message ID 0 that a server sends
+// before it closes a connection for a reason other than an unbind.
+//
+// ExtendedResponse ::= [APPLICATION 24] SEQUENCE {
+//
+//	COMPONENTS OF LDAPResult,
+//	responseName     [10] LDAPOID OPTIONAL,
+//	responseValue    [11] OCTET STRING OPTIONAL
+//
+// }
+func NewNoticeOfDisconnection(resultCode ResultCode, message string) (*LDAPMessage, error) {
+	encoder := ber.NewBEREncoder(64)
+
+	result := NewErrorResult(resultCode, message)
+	if err := result.Encode(encoder); err != nil {
+		return nil, err
+	}
+	if err := encoder.WriteTaggedValue(10, false, []byte(NoticeOfDisconnectionOID)); err != nil {
+		return nil, err
+	}
+
+	return &LDAPMessage{
+		MessageID: 0,
+		Operation: &RawOperation{
+			Tag:  ApplicationExtendedResponse,
+			Data: encoder.Bytes(),
+		},
+	}, nil
+}
+
+// EncodeNoticeOfDisconnection encodes a Notice of Disconnection with
+// resultCode and message.
+func EncodeNoticeOfDisconnection(resultCode ResultCode, message string) ([]byte, error) {
+	msg, err := NewNoticeOfDisconnection(resultCode, message)
+	if err != nil {
+		return nil, err
+	}
+	return msg.Encode()
+}
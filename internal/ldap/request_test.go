@@ -2,6 +2,7 @@ package ldap
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 
 	"github.com/KilimcininKorOglu/oba/internal/ber"
@@ -386,6 +387,66 @@ func TestParseSearchRequest_SubstringFilter(t *testing.T) {
 	}
 }
 
+func TestParseSearchRequest_ExtensibleMatchFilter(t *testing.T) {
+	encodeRequest := func(components func(*ber.BEREncoder)) []byte {
+		encoder := ber.NewBEREncoder(256)
+		encoder.WriteOctetString([]byte("dc=example,dc=com"))
+		encoder.WriteEnumerated(2)
+		encoder.WriteEnumerated(0)
+		encoder.WriteInteger(0)
+		encoder.WriteInteger(0)
+		encoder.WriteBoolean(false)
+
+		extEncoder := ber.NewBEREncoder(128)
+		components(extEncoder)
+		encoder.WriteTaggedValue(FilterTagExtensibleMatch, true, extEncoder.Bytes())
+
+		attrSeqPos := encoder.BeginSequence()
+		encoder.EndSequence(attrSeqPos)
+		return encoder.Bytes()
+	}
+
+	// filter = extensibleMatch [9] { matchingRule="1.2.3.4", type="ou", matchValue="people", dnAttributes=TRUE }
+	data := encodeRequest(func(e *ber.BEREncoder) {
+		e.WriteTaggedValue(ExtMatchMatchingRule, false, []byte("1.2.3.4"))
+		e.WriteTaggedValue(ExtMatchType, false, []byte("ou"))
+		e.WriteTaggedValue(ExtMatchMatchValue, false, []byte("people"))
+		e.WriteTaggedValue(ExtMatchDNAttributes, false, []byte{0xFF})
+	})
+
+	req, err := ParseSearchRequest(data)
+	if err != nil {
+		t.Fatalf("ParseSearchRequest failed: %v", err)
+	}
+
+	if req.Filter.Type != FilterTagExtensibleMatch {
+		t.Fatalf("Filter.Type = %d, want %d", req.Filter.Type, FilterTagExtensibleMatch)
+	}
+	ext := req.Filter.ExtensibleMatch
+	if ext == nil {
+		t.Fatal("Filter.ExtensibleMatch is nil")
+	}
+	if ext.MatchingRule != "1.2.3.4" || ext.Type != "ou" || !bytes.Equal(ext.MatchValue, []byte("people")) || !ext.DNAttributes {
+		t.Errorf("ExtensibleMatch = %+v", ext)
+	}
+
+	// matchValue is required
+	data = encodeRequest(func(e *ber.BEREncoder) {
+		e.WriteTaggedValue(ExtMatchType, false, []byte("ou"))
+	})
+	if _, err := ParseSearchRequest(data); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("missing matchValue: got %v, want ErrInvalidFilter", err)
+	}
+
+	// Either matchingRule or type is required
+	data = encodeRequest(func(e *ber.BEREncoder) {
+		e.WriteTaggedValue(ExtMatchMatchValue, false, []byte("people"))
+	})
+	if _, err := ParseSearchRequest(data); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("missing rule and type: got %v, want ErrInvalidFilter", err)
+	}
+}
+
 func TestParseSearchRequest_InvalidScope(t *testing.T) {
 	encoder := ber.NewBEREncoder(256)
 
@@ -407,6 +468,79 @@ func TestParseSearchRequest_InvalidScope(t *testing.T) {
 	}
 }
 
+func TestSearchRequest_Encode(t *testing.T) {
+	req := &SearchRequest{
+		BaseObject: "ou=users,dc=example,dc=com",
+		Scope:      ScopeWholeSubtree,
+		SizeLimit:  10,
+		TimeLimit:  5,
+		Filter: &SearchFilter{
+			Type: FilterTagAnd,
+			Children: []*SearchFilter{
+				{Type: FilterTagEquality, Attribute: "objectClass", Value: []byte("person")},
+				{Type: FilterTagNot, Child: &SearchFilter{Type: FilterTagPresent, Attribute: "mail"}},
+				{
+					Type:      FilterTagSubstrings,
+					Attribute: "cn",
+					Substrings: &SubstringComponents{
+						Initial: []byte("a"),
+						Any:     [][]byte{[]byte("l")},
+						Final:   []byte("e"),
+					},
+				},
+			},
+		},
+		Attributes: []string{"cn", "uid"},
+	}
+
+	data, err := req.Encode()
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	parsed, err := ParseSearchRequest(data)
+	if err != nil {
+		t.Fatalf("ParseSearchRequest failed: %v", err)
+	}
+
+	if parsed.BaseObject != req.BaseObject {
+		t.Errorf("BaseObject = %q, want %q", parsed.BaseObject, req.BaseObject)
+	}
+	if parsed.Scope != req.Scope || parsed.SizeLimit != 10 || parsed.TimeLimit != 5 {
+		t.Errorf("unexpected scope/limits: %v %d %d", parsed.Scope, parsed.SizeLimit, parsed.TimeLimit)
+	}
+	if parsed.Filter == nil || parsed.Filter.Type != FilterTagAnd || len(parsed.Filter.Children) != 3 {
+		t.Fatalf("unexpected filter: %+v", parsed.Filter)
+	}
+	if parsed.Filter.Children[1].Child == nil || parsed.Filter.Children[1].Child.Attribute != "mail" {
+		t.Errorf("NOT filter not preserved")
+	}
+	sub := parsed.Filter.Children[2].Substrings
+	if sub == nil || string(sub.Initial) != "a" || len(sub.Any) != 1 || string(sub.Final) != "e" {
+		t.Errorf("substring filter not preserved: %+v", sub)
+	}
+	if len(parsed.Attributes) != 2 || parsed.Attributes[1] != "uid" {
+		t.Errorf("Attributes = %v", parsed.Attributes)
+	}
+}
+
+func TestSearchRequest_EncodeDefaultFilter(t *testing.T) {
+	req := &SearchRequest{BaseObject: "dc=example,dc=com", Scope: ScopeBaseObject}
+
+	data, err := req.Encode()
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	parsed, err := ParseSearchRequest(data)
+	if err != nil {
+		t.Fatalf("ParseSearchRequest failed: %v", err)
+	}
+	if parsed.Filter == nil || parsed.Filter.Type != FilterTagPresent || parsed.Filter.Attribute != "objectClass" {
+		t.Errorf("expected (objectClass=*) filter, got %+v", parsed.Filter)
+	}
+}
+
 // ============================================================================
 // AddRequest Tests
 // ============================================================================
@@ -730,6 +864,30 @@ func TestParseModifyRequest_Basic(t *testing.T) {
 	}
 }
 
+func TestParseModifyRequest_Increment(t *testing.T) {
+	req := &ModifyRequest{Object: "cn=counter,dc=example,dc=com"}
+	req.AddStringModification(ModifyOperationIncrement, "uidNumber", "5")
+
+	data, err := req.Encode()
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	parsed, err := ParseModifyRequest(data)
+	if err != nil {
+		t.Fatalf("ParseModifyRequest failed: %v", err)
+	}
+	if len(parsed.Changes) != 1 || parsed.Changes[0].Operation != ModifyOperationIncrement {
+		t.Fatalf("Changes = %+v, want one increment", parsed.Changes)
+	}
+	if parsed.Changes[0].Operation.String() != "Increment" {
+		t.Errorf("String() = %q, want Increment", parsed.Changes[0].Operation.String())
+	}
+	if err := parsed.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
 func TestModifyRequest_Encode(t *testing.T) {
 	req := &ModifyRequest{
 		Object: "uid=bob,ou=users,dc=example,dc=com",
@@ -51,6 +51,10 @@ func TestResultCode_String(t *testing.T) {
 		{ResultObjectClassModsProhibited, "objectClassModsProhibited"},
 		{ResultAffectsMultipleDSAs, "affectsMultipleDSAs"},
 		{ResultOther, "other"},
+		{ResultCanceled, "canceled"},
+		{ResultNoSuchOperation, "noSuchOperation"},
+		{ResultTooLate, "tooLate"},
+		{ResultCannotCancel, "cannotCancel"},
 		{ResultCode(999), "unknown"},
 	}
 
@@ -786,6 +790,10 @@ func TestResultCodeValues(t *testing.T) {
 		{ResultObjectClassModsProhibited, 69},
 		{ResultAffectsMultipleDSAs, 71},
 		{ResultOther, 80},
+		{ResultCanceled, 118},
+		{ResultNoSuchOperation, 119},
+		{ResultTooLate, 120},
+		{ResultCannotCancel, 121},
 	}
 
 	for _, tt := range tests {
@@ -825,3 +833,40 @@ func TestSearchResultEntry_LargeEntry(t *testing.T) {
 		t.Errorf("First byte = 0x%02x, want 0x64", encoded[0])
 	}
 }
+
+func TestEncodeNoticeOfDisconnection(t *testing.T) {
+	data, err := EncodeNoticeOfDisconnection(ResultUnavailable, "server is shutting down")
+	if err != nil {
+		t.Fatalf("EncodeNoticeOfDisconnection() error = %v", err)
+	}
+
+	msg, err := ParseLDAPMessage(data)
+	if err != nil {
+		t.Fatalf("ParseLDAPMessage() error = %v", err)
+	}
+	if msg.MessageID != 0 {
+		t.Errorf("MessageID = %d, want 0", msg.MessageID)
+	}
+	if msg.Operation.Tag != ApplicationExtendedResponse {
+		t.Fatalf("Tag = %d, want ExtendedResponse", msg.Operation.Tag)
+	}
+
+	decoder := ber.NewBERDecoder(msg.Operation.Data)
+	code, err := decoder.ReadEnumerated()
+	if err != nil || ResultCode(code) != ResultUnavailable {
+		t.Errorf("resultCode = %d, %v, want unavailable", code, err)
+	}
+	if matched, err := decoder.ReadOctetString(); err != nil || len(matched) != 0 {
+		t.Errorf("matchedDN = %q, %v, want empty", matched, err)
+	}
+	if diag, err := decoder.ReadOctetString(); err != nil || string(diag) != "server is shutting down" {
+		t.Errorf("diagnosticMessage = %q, %v", diag, err)
+	}
+	tag, _, name, err := decoder.ReadTaggedValue()
+	if err != nil || tag != 10 || string(name) != NoticeOfDisconnectionOID {
+		t.Errorf("responseName = [%d] %q, %v, want [10] %s", tag, name, err, NoticeOfDisconnectionOID)
+	}
+	if decoder.Remaining() != 0 {
+		t.Errorf("%d bytes left after responseName, want no responseValue", decoder.Remaining())
+	}
+}
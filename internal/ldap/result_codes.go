@@ -52,6 +52,11 @@ package ldap
 //	-- 72-79 unused --
 //	other                        (80),
 //	...
+//	canceled                     (118), -- RFC 3909
+//	noSuchOperation              (119), -- RFC 3909
+//	tooLate                      (120), -- RFC 3909
+//	cannotCancel                 (121), -- RFC 3909
+//	assertionFailed              (122), -- RFC 4528
 //
 // }
 type ResultCode int
@@ -207,6 +212,26 @@ const (
 
 	// ResultOther indicates an error not covered by other result codes.
 	ResultOther ResultCode = 80
+
+	// ResultCanceled indicates the operation was canceled by a Cancel
+	// extended operation (RFC 3909).
+	ResultCanceled ResultCode = 118
+
+	// ResultNoSuchOperation indicates the operation to cancel is unknown
+	// or already complete (RFC 3909).
+	ResultNoSuchOperation ResultCode = 119
+
+	// ResultTooLate indicates the operation to cancel had already sent
+	// all of its responses (RFC 3909).
+	ResultTooLate ResultCode = 120
+
+	// ResultCannotCancel indicates the operation to cancel cannot be
+	// canceled (RFC 3909).
+	ResultCannotCancel ResultCode = 121
+
+	// ResultAssertionFailed indicates the assertion of an Assertion
+	// control was not true for the target entry (RFC 4528).
+	ResultAssertionFailed ResultCode = 122
 )
 
 // String returns the string representation of the result code.
@@ -290,6 +315,16 @@ func (r ResultCode) String() string {
 		return "affectsMultipleDSAs"
 	case ResultOther:
 		return "other"
+	case ResultCanceled:
+		return "canceled"
+	case ResultNoSuchOperation:
+		return "noSuchOperation"
+	case ResultTooLate:
+		return "tooLate"
+	case ResultCannotCancel:
+		return "cannotCancel"
+	case ResultAssertionFailed:
+		return "assertionFailed"
 	default:
 		return "unknown"
 	}
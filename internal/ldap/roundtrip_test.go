@@ -0,0 +1,465 @@
+package ldap
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// roundTripIterations is the number of random values checked for each type.
+const roundTripIterations = 300
+
+// roundTripSeed seeds the generators, so that failures are reproducible.
+const roundTripSeed = 4511
+
+// randomValues generates field values biased towards the edge cases of the
+// encoding: empty and zero-length values, high Unicode, lengths at the
+// boundaries of the length forms and the largest integers LDAP allows.
+type randomValues struct {
+	rng *rand.Rand
+}
+
+func newRandomValues(t *testing.T) *randomValues {
+	t.Logf("seed %d", roundTripSeed)
+	return &randomValues{rng: rand.New(rand.NewSource(roundTripSeed))}
+}
+
+// lengths are the lengths around the boundaries of the BER length forms.
+var lengths = []int{0, 1, 127, 128, 255, 256, 65535, 65536}
+
+var unicodeSamples = []string{
+	"Zoë Ñandú", "日本語", "Ελληνικά", "😀🎉", "\U0001D4D0\U0001D4D1", "\U0010FFFF", "a\x00b",
+}
+
+func (g *randomValues) oneOf(n int) int {
+	return g.rng.Intn(n)
+}
+
+func (g *randomValues) bool() bool {
+	return g.rng.Intn(2) == 0
+}
+
+// str returns a random string, possibly empty.
+func (g *randomValues) str() string {
+	switch g.oneOf(6) {
+	case 0:
+		return ""
+	case 1:
+		return unicodeSamples[g.oneOf(len(unicodeSamples))]
+	case 2:
+		// A length near a length form boundary, mostly the small ones
+		n := lengths[g.oneOf(6)]
+		return strings.Repeat("x", n)
+	default:
+		b := make([]byte, 1+g.oneOf(20))
+		for i := range b {
+			b[i] = byte('a' + g.oneOf(26))
+		}
+		return string(b)
+	}
+}
+
+// nonEmptyStr returns a random string that is not empty.
+func (g *randomValues) nonEmptyStr() string {
+	if s := g.str(); s != "" {
+		return s
+	}
+	return "cn"
+}
+
+// dn returns a random DN, possibly empty.
+func (g *randomValues) dn() string {
+	if g.oneOf(5) == 0 {
+		return ""
+	}
+	return "cn=" + g.nonEmptyStr() + ",ou=" + unicodeSamples[g.oneOf(len(unicodeSamples))] + ",dc=example,dc=com"
+}
+
+// value returns a random zero-length or binary value. Zero-length values
+// are returned as empty, not nil, as the parser returns them.
+func (g *randomValues) value() []byte {
+	n := 0
+	switch g.oneOf(5) {
+	case 0:
+	case 1:
+		n = lengths[g.oneOf(len(lengths))]
+	default:
+		n = 1 + g.oneOf(32)
+	}
+	b := make([]byte, n)
+	g.rng.Read(b)
+	return b
+}
+
+// values returns between min and min+3 random values, or nil for none.
+func (g *randomValues) values(min int) [][]byte {
+	var values [][]byte
+	for i := min + g.oneOf(4); i > 0; i-- {
+		values = append(values, g.value())
+	}
+	return values
+}
+
+// limit returns a random INTEGER (0 .. maxInt).
+func (g *randomValues) limit() int {
+	limits := []int{0, 1, 127, 128, 255, 256, 32767, 32768, math.MaxInt32}
+	return limits[g.oneOf(len(limits))]
+}
+
+// messageID returns a random MessageID, including the largest.
+func (g *randomValues) messageID() int {
+	ids := []int{MinMessageID, 1, 127, 128, 65535, MaxMessageID}
+	return ids[g.oneOf(len(ids))]
+}
+
+// oid returns a random numeric OID.
+func (g *randomValues) oid() string {
+	oids := []string{"1.2.840.113556.1.4.319", "2.16.840.1.113730.3.4.2", "1.3.6.1.4.1.4203.1.10.1", "0.0"}
+	return oids[g.oneOf(len(oids))]
+}
+
+// controls returns up to three random controls, or nil.
+func (g *randomValues) controls() []Control {
+	var controls []Control
+	for i := g.oneOf(4); i > 0; i-- {
+		ctrl := Control{OID: g.oid(), Criticality: g.bool()}
+		// An empty controlValue is omitted and parses as absent
+		if value := g.value(); len(value) > 0 {
+			ctrl.Value = value
+		}
+		controls = append(controls, ctrl)
+	}
+	return controls
+}
+
+func (g *randomValues) attributes(minValues int) []Attribute {
+	var attrs []Attribute
+	for i := g.oneOf(4); i > 0; i-- {
+		attrs = append(attrs, Attribute{Type: g.nonEmptyStr(), Values: g.values(minValues)})
+	}
+	return attrs
+}
+
+// filter returns a random filter of at most depth levels.
+func (g *randomValues) filter(depth int) *SearchFilter {
+	kinds := []int{FilterTagEquality, FilterTagSubstrings, FilterTagGreaterOrEqual, FilterTagLessOrEqual,
+		FilterTagPresent, FilterTagApproxMatch, FilterTagExtensibleMatch}
+	if depth > 0 {
+		kinds = append(kinds, FilterTagAnd, FilterTagOr, FilterTagNot)
+	}
+
+	filter := &SearchFilter{Type: kinds[g.oneOf(len(kinds))]}
+	switch filter.Type {
+	case FilterTagAnd, FilterTagOr:
+		for i := 1 + g.oneOf(3); i > 0; i-- {
+			filter.Children = append(filter.Children, g.filter(depth-1))
+		}
+	case FilterTagNot:
+		filter.Child = g.filter(depth - 1)
+	case FilterTagPresent:
+		filter.Attribute = g.nonEmptyStr()
+	case FilterTagSubstrings:
+		filter.Attribute = g.nonEmptyStr()
+		sub := &SubstringComponents{}
+		if g.bool() {
+			sub.Initial = g.value()
+		}
+		sub.Any = g.values(0)
+		if g.bool() || (sub.Initial == nil && sub.Any == nil) {
+			sub.Final = g.value()
+		}
+		filter.Substrings = sub
+	case FilterTagExtensibleMatch:
+		ext := &ExtensibleMatchComponents{MatchValue: g.value(), DNAttributes: g.bool()}
+		if g.bool() {
+			ext.MatchingRule = g.oid()
+		}
+		if ext.MatchingRule == "" || g.bool() {
+			ext.Type = g.nonEmptyStr()
+		}
+		filter.ExtensibleMatch = ext
+	default:
+		filter.Attribute = g.nonEmptyStr()
+		filter.Value = g.value()
+	}
+	return filter
+}
+
+// requestCase generates random requests of one type.
+type requestCase struct {
+	name     string
+	tag      int
+	generate func(g *randomValues) interface{ Encode() ([]byte, error) }
+	parse    func(data []byte) (interface{ Encode() ([]byte, error) }, error)
+}
+
+var requestCases = []requestCase{
+	{
+		name: "BindRequest",
+		tag:  ApplicationBindRequest,
+		generate: func(g *randomValues) interface{ Encode() ([]byte, error) } {
+			req := &BindRequest{Version: 1 + g.oneOf(127), Name: g.dn()}
+			if g.bool() {
+				req.AuthMethod = AuthMethodSimple
+				req.SimplePassword = g.value()
+			} else {
+				req.AuthMethod = AuthMethodSASL
+				req.SASLCredentials = &SASLCredentials{Mechanism: g.str()}
+				// Empty credentials are omitted and parse as absent
+				if creds := g.value(); len(creds) > 0 {
+					req.SASLCredentials.Credentials = creds
+				}
+			}
+			return req
+		},
+		parse: func(data []byte) (interface{ Encode() ([]byte, error) }, error) { return ParseBindRequest(data) },
+	},
+	{
+		name: "UnbindRequest",
+		tag:  ApplicationUnbindRequest,
+		generate: func(g *randomValues) interface{ Encode() ([]byte, error) } {
+			return &UnbindRequest{}
+		},
+		parse: func(data []byte) (interface{ Encode() ([]byte, error) }, error) { return ParseUnbindRequest(data) },
+	},
+	{
+		name: "SearchRequest",
+		tag:  ApplicationSearchRequest,
+		generate: func(g *randomValues) interface{ Encode() ([]byte, error) } {
+			req := &SearchRequest{
+				BaseObject:   g.dn(),
+				Scope:        SearchScope(g.oneOf(3)),
+				DerefAliases: DerefAliases(g.oneOf(4)),
+				SizeLimit:    g.limit(),
+				TimeLimit:    g.limit(),
+				TypesOnly:    g.bool(),
+				Filter:       g.filter(3),
+			}
+			for i := g.oneOf(4); i > 0; i-- {
+				req.Attributes = append(req.Attributes, g.str())
+			}
+			return req
+		},
+		parse: func(data []byte) (interface{ Encode() ([]byte, error) }, error) { return ParseSearchRequest(data) },
+	},
+	{
+		name: "ModifyRequest",
+		tag:  ApplicationModifyRequest,
+		generate: func(g *randomValues) interface{ Encode() ([]byte, error) } {
+			req := &ModifyRequest{Object: g.dn()}
+			for i := g.oneOf(4); i > 0; i-- {
+				req.Changes = append(req.Changes, Modification{
+					Operation: ModifyOperation(g.oneOf(3)),
+					Attribute: Attribute{Type: g.nonEmptyStr(), Values: g.values(0)},
+				})
+			}
+			return req
+		},
+		parse: func(data []byte) (interface{ Encode() ([]byte, error) }, error) { return ParseModifyRequest(data) },
+	},
+	{
+		name: "AddRequest",
+		tag:  ApplicationAddRequest,
+		generate: func(g *randomValues) interface{ Encode() ([]byte, error) } {
+			return &AddRequest{Entry: g.dn(), Attributes: g.attributes(1)}
+		},
+		parse: func(data []byte) (interface{ Encode() ([]byte, error) }, error) { return ParseAddRequest(data) },
+	},
+	{
+		name: "DeleteRequest",
+		tag:  ApplicationDelRequest,
+		generate: func(g *randomValues) interface{ Encode() ([]byte, error) } {
+			return &DeleteRequest{DN: g.dn()}
+		},
+		parse: func(data []byte) (interface{ Encode() ([]byte, error) }, error) { return ParseDeleteRequest(data) },
+	},
+	{
+		name: "ModifyDNRequest",
+		tag:  ApplicationModifyDNRequest,
+		generate: func(g *randomValues) interface{ Encode() ([]byte, error) } {
+			req := &ModifyDNRequest{Entry: g.dn(), NewRDN: g.str(), DeleteOldRDN: g.bool()}
+			if g.bool() {
+				req.NewSuperior = g.dn()
+			}
+			return req
+		},
+		parse: func(data []byte) (interface{ Encode() ([]byte, error) }, error) { return ParseModifyDNRequest(data) },
+	},
+	{
+		name: "CompareRequest",
+		tag:  ApplicationCompareRequest,
+		generate: func(g *randomValues) interface{ Encode() ([]byte, error) } {
+			return &CompareRequest{DN: g.dn(), Attribute: g.str(), Value: g.value()}
+		},
+		parse: func(data []byte) (interface{ Encode() ([]byte, error) }, error) { return ParseCompareRequest(data) },
+	},
+	{
+		name: "AbandonRequest",
+		tag:  ApplicationAbandonRequest,
+		generate: func(g *randomValues) interface{ Encode() ([]byte, error) } {
+			return &AbandonRequest{MessageID: g.messageID()}
+		},
+		parse: func(data []byte) (interface{ Encode() ([]byte, error) }, error) { return ParseAbandonRequest(data) },
+	},
+}
+
+// TestRequests_RoundTrip checks for random requests of every type that
+// parsing the encoding gives back the request, that encoding it again gives
+// the same bytes, and that the message is accepted by the strict decoder.
+func TestRequests_RoundTrip(t *testing.T) {
+	for _, tc := range requestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := newRandomValues(t)
+
+			for i := 0; i < roundTripIterations; i++ {
+				req := tc.generate(g)
+				encoded, err := req.Encode()
+				if err != nil {
+					t.Fatalf("iteration %d: Encode() error = %v\n%+v", i, err, req)
+				}
+
+				parsed, err := tc.parse(encoded)
+				if err != nil {
+					t.Fatalf("iteration %d: parse error = %v\n%x", i, err, encoded)
+				}
+				if !reflect.DeepEqual(parsed, req) {
+					t.Fatalf("iteration %d: parse result differs\ngot:  %+v\nwant: %+v", i, parsed, req)
+				}
+				reencoded, err := parsed.Encode()
+				if err != nil {
+					t.Fatalf("iteration %d: second Encode() error = %v", i, err)
+				}
+				if !bytes.Equal(reencoded, encoded) {
+					t.Fatalf("iteration %d: encoding is not stable\nfirst:  %x\nsecond: %x", i, encoded, reencoded)
+				}
+
+				msg := &LDAPMessage{
+					MessageID: g.messageID(),
+					Operation: &RawOperation{Tag: tc.tag, Data: encoded},
+					Controls:  g.controls(),
+				}
+				wrapped, err := msg.Encode()
+				if err != nil {
+					t.Fatalf("iteration %d: LDAPMessage.Encode() error = %v", i, err)
+				}
+				if err := validateMessage(wrapped); err != nil {
+					t.Fatalf("iteration %d: independent decoder rejects the message: %v\n%x", i, err, wrapped)
+				}
+
+				parsedMsg, err := ParseLDAPMessage(wrapped)
+				if err != nil {
+					t.Fatalf("iteration %d: ParseLDAPMessage() error = %v", i, err)
+				}
+				if !reflect.DeepEqual(parsedMsg, msg) {
+					t.Fatalf("iteration %d: message differs\ngot:  %+v\nwant: %+v", i, parsedMsg, msg)
+				}
+			}
+		})
+	}
+}
+
+// responseCase generates random responses of one type.
+type responseCase struct {
+	name     string
+	generate func(g *randomValues) encodableResponse
+}
+
+// result returns a random LDAPResult.
+func (g *randomValues) result() LDAPResult {
+	codes := []ResultCode{ResultSuccess, ResultNoSuchObject, ResultReferral, ResultOther, ResultCode(math.MaxInt32)}
+	result := LDAPResult{
+		ResultCode:        codes[g.oneOf(len(codes))],
+		MatchedDN:         g.dn(),
+		DiagnosticMessage: g.str(),
+	}
+	for i := g.oneOf(3); i > 0; i-- {
+		result.Referral = append(result.Referral, "ldap://"+g.nonEmptyStr()+"/"+g.dn())
+	}
+	return result
+}
+
+var responseCases = []responseCase{
+	{"BindResponse", func(g *randomValues) encodableResponse {
+		resp := &BindResponse{LDAPResult: g.result()}
+		// Empty credentials are omitted and decode as absent
+		if creds := g.value(); len(creds) > 0 {
+			resp.ServerSASLCreds = creds
+		}
+		return resp
+	}},
+	{"SearchResultEntry", func(g *randomValues) encodableResponse {
+		entry := &SearchResultEntry{ObjectName: g.dn()}
+		for _, attr := range g.attributes(0) {
+			entry.Attributes = append(entry.Attributes, PartialAttribute(attr))
+		}
+		return entry
+	}},
+	{"SearchResultDone", func(g *randomValues) encodableResponse {
+		return &SearchResultDone{LDAPResult: g.result()}
+	}},
+	{"ModifyResponse", func(g *randomValues) encodableResponse {
+		return &ModifyResponse{LDAPResult: g.result()}
+	}},
+	{"AddResponse", func(g *randomValues) encodableResponse {
+		return &AddResponse{LDAPResult: g.result()}
+	}},
+	{"DeleteResponse", func(g *randomValues) encodableResponse {
+		return &DeleteResponse{LDAPResult: g.result()}
+	}},
+	{"ModifyDNResponse", func(g *randomValues) encodableResponse {
+		return &ModifyDNResponse{LDAPResult: g.result()}
+	}},
+	{"CompareResponse", func(g *randomValues) encodableResponse {
+		return &CompareResponse{LDAPResult: g.result()}
+	}},
+}
+
+// TestResponses_RoundTrip checks for random responses of every type that
+// the strict decoder decodes the encoding back to the response, and that
+// encoding the decoded response gives the same bytes.
+func TestResponses_RoundTrip(t *testing.T) {
+	for _, tc := range responseCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := newRandomValues(t)
+
+			for i := 0; i < roundTripIterations; i++ {
+				resp := tc.generate(g)
+				encoded, err := resp.Encode()
+				if err != nil {
+					t.Fatalf("iteration %d: Encode() error = %v\n%+v", i, err, resp)
+				}
+
+				tag, decoded, err := decodeResponseElement(encoded)
+				if err != nil {
+					t.Fatalf("iteration %d: independent decoder rejects the response: %v\n%x", i, err, encoded)
+				}
+				if !reflect.DeepEqual(decoded, resp) {
+					t.Fatalf("iteration %d: decoded response differs\ngot:  %+v\nwant: %+v", i, decoded, resp)
+				}
+				reencoded, err := decoded.Encode()
+				if err != nil {
+					t.Fatalf("iteration %d: second Encode() error = %v", i, err)
+				}
+				if !bytes.Equal(reencoded, encoded) {
+					t.Fatalf("iteration %d: encoding is not stable\nfirst:  %x\nsecond: %x", i, encoded, reencoded)
+				}
+
+				msg := &LDAPMessage{
+					MessageID: g.messageID(),
+					Operation: &RawOperation{Tag: tag, Data: extractOperationData(encoded)},
+					Controls:  g.controls(),
+				}
+				wrapped, err := msg.Encode()
+				if err != nil {
+					t.Fatalf("iteration %d: LDAPMessage.Encode() error = %v", i, err)
+				}
+				if err := validateMessage(wrapped); err != nil {
+					t.Fatalf("iteration %d: independent decoder rejects the message: %v\n%x", i, err, wrapped)
+				}
+			}
+		})
+	}
+}
@@ -156,6 +156,9 @@ type SearchRequest struct {
 	Filter *SearchFilter
 	// Attributes is the list of attributes to return (empty = all user attributes)
 	Attributes []string
+	// Controls are the controls sent with the request message. They are
+	// not part of the SearchRequest encoding and are set by the server.
+	Controls []Control
 }
 
 // Errors for SearchRequest parsing
@@ -255,6 +258,165 @@ func ParseSearchRequest(data []byte) (*SearchRequest, error) {
 	return req, nil
 }
 
+// Encode encodes the SearchRequest to BER format (without the APPLICATION tag).
+// A nil filter is encoded as (objectClass=*).
+func (r *SearchRequest) Encode() ([]byte, error) {
+	encoder := ber.NewBEREncoder(256)
+
+	// Write baseObject (LDAPDN - OCTET STRING)
+	if err := encoder.WriteOctetString([]byte(r.BaseObject)); err != nil {
+		return nil, err
+	}
+
+	// Write scope and derefAliases (ENUMERATED)
+	if err := encoder.WriteEnumerated(int64(r.Scope)); err != nil {
+		return nil, err
+	}
+	if err := encoder.WriteEnumerated(int64(r.DerefAliases)); err != nil {
+		return nil, err
+	}
+
+	// Write sizeLimit and timeLimit (INTEGER)
+	if err := encoder.WriteInteger(int64(r.SizeLimit)); err != nil {
+		return nil, err
+	}
+	if err := encoder.WriteInteger(int64(r.TimeLimit)); err != nil {
+		return nil, err
+	}
+
+	// Write typesOnly (BOOLEAN)
+	if err := encoder.WriteBoolean(r.TypesOnly); err != nil {
+		return nil, err
+	}
+
+	// Write filter
+	filter := r.Filter
+	if filter == nil {
+		filter = &SearchFilter{Type: FilterTagPresent, Attribute: "objectClass"}
+	}
+	if err := encodeSearchFilter(encoder, filter); err != nil {
+		return nil, err
+	}
+
+	// Write attributes (SEQUENCE OF AttributeDescription)
+	attrPos := encoder.BeginSequence()
+	for _, attr := range r.Attributes {
+		if err := encoder.WriteOctetString([]byte(attr)); err != nil {
+			return nil, err
+		}
+	}
+	if err := encoder.EndSequence(attrPos); err != nil {
+		return nil, err
+	}
+
+	return encoder.Bytes(), nil
+}
+
+// encodeSearchFilter encodes a search filter with its context-specific tag.
+func encodeSearchFilter(encoder *ber.BEREncoder, filter *SearchFilter) error {
+	if filter == nil {
+		return ErrInvalidFilter
+	}
+
+	switch filter.Type {
+	case FilterTagAnd, FilterTagOr:
+		pos := encoder.WriteContextTag(filter.Type, true)
+		for _, child := range filter.Children {
+			if err := encodeSearchFilter(encoder, child); err != nil {
+				return err
+			}
+		}
+		return encoder.EndContextTag(pos)
+
+	case FilterTagNot:
+		pos := encoder.WriteContextTag(FilterTagNot, true)
+		if err := encodeSearchFilter(encoder, filter.Child); err != nil {
+			return err
+		}
+		return encoder.EndContextTag(pos)
+
+	case FilterTagEquality, FilterTagGreaterOrEqual, FilterTagLessOrEqual, FilterTagApproxMatch:
+		pos := encoder.WriteContextTag(filter.Type, true)
+		if err := encoder.WriteOctetString([]byte(filter.Attribute)); err != nil {
+			return err
+		}
+		if err := encoder.WriteOctetString(filter.Value); err != nil {
+			return err
+		}
+		return encoder.EndContextTag(pos)
+
+	case FilterTagSubstrings:
+		if filter.Substrings == nil {
+			return ErrInvalidSubstringFilter
+		}
+		pos := encoder.WriteContextTag(FilterTagSubstrings, true)
+		if err := encoder.WriteOctetString([]byte(filter.Attribute)); err != nil {
+			return err
+		}
+		subPos := encoder.BeginSequence()
+		if filter.Substrings.Initial != nil {
+			if err := encoder.WriteTaggedValue(SubstringInitial, false, filter.Substrings.Initial); err != nil {
+				return err
+			}
+		}
+		for _, value := range filter.Substrings.Any {
+			if err := encoder.WriteTaggedValue(SubstringAny, false, value); err != nil {
+				return err
+			}
+		}
+		if filter.Substrings.Final != nil {
+			if err := encoder.WriteTaggedValue(SubstringFinal, false, filter.Substrings.Final); err != nil {
+				return err
+			}
+		}
+		if err := encoder.EndSequence(subPos); err != nil {
+			return err
+		}
+		return encoder.EndContextTag(pos)
+
+	case FilterTagPresent:
+		return encoder.WriteTaggedValue(FilterTagPresent, false, []byte(filter.Attribute))
+
+	case FilterTagExtensibleMatch:
+		if filter.ExtensibleMatch == nil {
+			return ErrInvalidFilter
+		}
+		ext := filter.ExtensibleMatch
+		pos := encoder.WriteContextTag(FilterTagExtensibleMatch, true)
+		if ext.MatchingRule != "" {
+			if err := encoder.WriteTaggedValue(ExtMatchMatchingRule, false, []byte(ext.MatchingRule)); err != nil {
+				return err
+			}
+		}
+		if ext.Type != "" {
+			if err := encoder.WriteTaggedValue(ExtMatchType, false, []byte(ext.Type)); err != nil {
+				return err
+			}
+		}
+		if err := encoder.WriteTaggedValue(ExtMatchMatchValue, false, ext.MatchValue); err != nil {
+			return err
+		}
+		if ext.DNAttributes {
+			if err := encoder.WriteTaggedValue(ExtMatchDNAttributes, false, []byte{0xFF}); err != nil {
+				return err
+			}
+		}
+		return encoder.EndContextTag(pos)
+
+	default:
+		return ErrInvalidFilter
+	}
+}
+
+// ParseFilter parses a BER-encoded Filter outside of a SearchRequest, such
+// as the value of an Assertion control (RFC 4528).
+func ParseFilter(data []byte) (*SearchFilter, error) {
+	if len(data) == 0 {
+		return nil, ErrInvalidFilter
+	}
+	return parseSearchFilter(ber.NewBERDecoder(data))
+}
+
 // parseSearchFilter parses a search filter from the decoder
 func parseSearchFilter(decoder *ber.BERDecoder) (*SearchFilter, error) {
 	// Read the filter using ReadTaggedValue which handles context-specific tags
@@ -400,9 +562,11 @@ const (
 	ExtMatchDNAttributes = 4 // [4] dnAttributes
 )
 
-// parseExtensibleMatch parses an extensible match filter
+// parseExtensibleMatch parses an extensible match filter.
+// RFC 4511 requires the matchValue and at least one of matchingRule or type.
 func parseExtensibleMatch(decoder *ber.BERDecoder) (*ExtensibleMatchComponents, error) {
 	components := &ExtensibleMatchComponents{}
+	hasValue := false
 
 	for decoder.Remaining() > 0 {
 		tagNum, _, value, err := decoder.ReadTaggedValue()
@@ -417,13 +581,23 @@ func parseExtensibleMatch(decoder *ber.BERDecoder) (*ExtensibleMatchComponents,
 			components.Type = string(value)
 		case ExtMatchMatchValue:
 			components.MatchValue = value
+			hasValue = true
 		case ExtMatchDNAttributes:
 			// Boolean encoded as single byte
 			if len(value) > 0 && value[0] != 0 {
 				components.DNAttributes = true
 			}
+		default:
+			return nil, NewParseError(decoder.Offset(), "unknown extensible match component tag", ErrInvalidFilter)
 		}
 	}
 
+	if !hasValue {
+		return nil, NewParseError(decoder.Offset(), "extensible match filter missing matchValue", ErrInvalidFilter)
+	}
+	if components.MatchingRule == "" && components.Type == "" {
+		return nil, NewParseError(decoder.Offset(), "extensible match filter requires matchingRule or type", ErrInvalidFilter)
+	}
+
 	return components, nil
 }
@@ -0,0 +1,232 @@
+// Package ldap implements LDAP protocol message parsing and encoding
+// as specified in RFC 4511.
+package ldap
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// ErrInvalidURL is returned when an LDAP URL is malformed.
+var ErrInvalidURL = errors.New("ldap: invalid LDAP URL")
+
+// DefaultURLFilter is the filter of an LDAP URL that does not specify one.
+const DefaultURLFilter = "(objectClass=*)"
+
+// URL is a parsed LDAP URL as defined in RFC 4516:
+//
+//	ldap://host:port/dn?attributes?scope?filter?extensions
+type URL struct {
+	// Scheme is "ldap", "ldaps" or "ldapi", in lowercase
+	Scheme string
+	// Host is the host and optional port. Empty means the local server.
+	Host string
+	// DN is the percent-decoded base DN
+	DN string
+	// Attributes lists the requested attributes. Empty means all.
+	Attributes []string
+	// Scope is the search scope. Defaults to ScopeBaseObject.
+	Scope SearchScope
+	// Filter is the search filter. Defaults to DefaultURLFilter.
+	Filter string
+	// Extensions holds the URL extensions in order
+	Extensions []URLExtension
+}
+
+// URLExtension is a single extension of an LDAP URL.
+type URLExtension struct {
+	// Type is the extension type, an OID or a descriptor
+	Type string
+	// Value is the percent-decoded extension value. Empty if not given.
+	Value string
+	// Critical is true when the extension was marked with '!'
+	Critical bool
+}
+
+// ParseURL parses an LDAP URL per RFC 4516. Each field is percent-decoded.
+// Missing fields take the RFC defaults: base scope and the filter
+// (objectClass=*).
+func ParseURL(s string) (*URL, error) {
+	sep := strings.Index(s, "://")
+	if sep < 0 {
+		return nil, ErrInvalidURL
+	}
+
+	u := &URL{
+		Scheme: strings.ToLower(s[:sep]),
+		Scope:  ScopeBaseObject,
+		Filter: DefaultURLFilter,
+	}
+	switch u.Scheme {
+	case "ldap", "ldaps", "ldapi":
+	default:
+		return nil, ErrInvalidURL
+	}
+
+	rest := s[sep+3:]
+	slash := strings.IndexByte(rest, '/')
+	if slash < 0 {
+		if strings.IndexByte(rest, '?') >= 0 {
+			return nil, ErrInvalidURL
+		}
+		host, err := url.PathUnescape(rest)
+		if err != nil {
+			return nil, ErrInvalidURL
+		}
+		u.Host = host
+		return u, nil
+	}
+
+	host, err := url.PathUnescape(rest[:slash])
+	if err != nil {
+		return nil, ErrInvalidURL
+	}
+	u.Host = host
+
+	parts := strings.Split(rest[slash+1:], "?")
+	if len(parts) > 5 {
+		return nil, ErrInvalidURL
+	}
+
+	if u.DN, err = url.PathUnescape(parts[0]); err != nil {
+		return nil, ErrInvalidURL
+	}
+
+	if len(parts) > 1 && parts[1] != "" {
+		for _, attr := range strings.Split(parts[1], ",") {
+			decoded, err := url.PathUnescape(attr)
+			if err != nil || decoded == "" {
+				return nil, ErrInvalidURL
+			}
+			u.Attributes = append(u.Attributes, decoded)
+		}
+	}
+
+	if len(parts) > 2 && parts[2] != "" {
+		switch strings.ToLower(parts[2]) {
+		case "base":
+			u.Scope = ScopeBaseObject
+		case "one":
+			u.Scope = ScopeSingleLevel
+		case "sub":
+			u.Scope = ScopeWholeSubtree
+		default:
+			return nil, ErrInvalidURL
+		}
+	}
+
+	if len(parts) > 3 && parts[3] != "" {
+		if u.Filter, err = url.PathUnescape(parts[3]); err != nil {
+			return nil, ErrInvalidURL
+		}
+	}
+
+	if len(parts) > 4 && parts[4] != "" {
+		for _, ext := range strings.Split(parts[4], ",") {
+			e, err := parseURLExtension(ext)
+			if err != nil {
+				return nil, err
+			}
+			u.Extensions = append(u.Extensions, e)
+		}
+	}
+
+	return u, nil
+}
+
+// parseURLExtension parses a single [!]type[=value] extension.
+func parseURLExtension(s string) (URLExtension, error) {
+	var e URLExtension
+	if strings.HasPrefix(s, "!") {
+		e.Critical = true
+		s = s[1:]
+	}
+
+	typ, value, hasValue := strings.Cut(s, "=")
+	if typ == "" {
+		return e, ErrInvalidURL
+	}
+	e.Type = typ
+	if hasValue {
+		decoded, err := url.PathUnescape(value)
+		if err != nil {
+			return e, ErrInvalidURL
+		}
+		e.Value = decoded
+	}
+	return e, nil
+}
+
+// String returns the URL in RFC 4516 form. Characters that would change
+// the structure of the URL are percent-encoded.
+func (u *URL) String() string {
+	var b strings.Builder
+	b.WriteString(u.Scheme)
+	b.WriteString("://")
+	b.WriteString(u.Host)
+	b.WriteByte('/')
+	b.WriteString(escapeURLField(u.DN, false))
+
+	attrs := make([]string, len(u.Attributes))
+	for i, attr := range u.Attributes {
+		attrs[i] = escapeURLField(attr, true)
+	}
+	exts := make([]string, len(u.Extensions))
+	for i, e := range u.Extensions {
+		ext := escapeURLField(e.Type, true)
+		if e.Critical {
+			ext = "!" + ext
+		}
+		if e.Value != "" {
+			ext += "=" + escapeURLField(e.Value, true)
+		}
+		exts[i] = ext
+	}
+
+	fields := []string{
+		strings.Join(attrs, ","),
+		strings.ToLower(u.Scope.urlString()),
+		escapeURLField(u.Filter, false),
+		strings.Join(exts, ","),
+	}
+	// Trailing empty fields are omitted
+	last := len(fields)
+	for last > 0 && fields[last-1] == "" {
+		last--
+	}
+	for _, f := range fields[:last] {
+		b.WriteByte('?')
+		b.WriteString(f)
+	}
+	return b.String()
+}
+
+// urlString returns the scope keyword used in LDAP URLs.
+func (s SearchScope) urlString() string {
+	switch s {
+	case ScopeSingleLevel:
+		return "one"
+	case ScopeWholeSubtree:
+		return "sub"
+	default:
+		return ""
+	}
+}
+
+// escapeURLField percent-encodes '%', '?' and characters outside printable
+// ASCII. Commas are also encoded when comma is true.
+func escapeURLField(s string, comma bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '%' || c == '?' || c <= 0x20 || c >= 0x7F || (comma && c == ',') {
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&0x0F])
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,113 @@
+package ldap
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want URL
+	}{
+		{
+			in:   "ldap://",
+			want: URL{Scheme: "ldap", Scope: ScopeBaseObject, Filter: DefaultURLFilter},
+		},
+		{
+			in:   "LDAPS://ldap.example.com:636",
+			want: URL{Scheme: "ldaps", Host: "ldap.example.com:636", Scope: ScopeBaseObject, Filter: DefaultURLFilter},
+		},
+		{
+			in:   "ldap:///dc=example,dc=com",
+			want: URL{Scheme: "ldap", DN: "dc=example,dc=com", Scope: ScopeBaseObject, Filter: DefaultURLFilter},
+		},
+		{
+			in: "ldap:///ou=users,dc=example,dc=com??sub?(departmentNumber=42)",
+			want: URL{
+				Scheme: "ldap",
+				DN:     "ou=users,dc=example,dc=com",
+				Scope:  ScopeWholeSubtree,
+				Filter: "(departmentNumber=42)",
+			},
+		},
+		{
+			in: "ldap://host/o=University%20of%20Michigan,c=US?cn,mail?ONE",
+			want: URL{
+				Scheme:     "ldap",
+				Host:       "host",
+				DN:         "o=University of Michigan,c=US",
+				Attributes: []string{"cn", "mail"},
+				Scope:      ScopeSingleLevel,
+				Filter:     DefaultURLFilter,
+			},
+		},
+		{
+			in: "ldap:///o=An%20Example%5C2C%20Inc.,c=US???(cn=a%3Fb)?!e-bindname=cn=Manager%2cdc=example,x-ext",
+			want: URL{
+				Scheme: "ldap",
+				DN:     `o=An Example\2C Inc.,c=US`,
+				Scope:  ScopeBaseObject,
+				Filter: "(cn=a?b)",
+				Extensions: []URLExtension{
+					{Type: "e-bindname", Value: "cn=Manager,dc=example", Critical: true},
+					{Type: "x-ext"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseURL(tt.in)
+		if err != nil {
+			t.Errorf("ParseURL(%q) error = %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("ParseURL(%q) = %+v, want %+v", tt.in, *got, tt.want)
+		}
+	}
+}
+
+func TestParseURLInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"dc=example,dc=com",
+		"http://host/dc=example",
+		"ldap://host?cn",
+		"ldap:///dc=example??subtree",
+		"ldap:///dc=example?cn,,mail",
+		"ldap:///dc=example%2",
+		"ldap:///dc=example????!",
+		"ldap:///dc=example?cn?sub?(cn=*)?x?y",
+	}
+
+	for _, in := range tests {
+		if _, err := ParseURL(in); !errors.Is(err, ErrInvalidURL) {
+			t.Errorf("ParseURL(%q) error = %v, want ErrInvalidURL", in, err)
+		}
+	}
+}
+
+func TestURLString(t *testing.T) {
+	tests := []string{
+		"ldap:///ou=users,dc=example,dc=com??sub?(departmentNumber=42)",
+		"ldap://host/o=University%20of%20Michigan,c=US?cn,mail?one?(objectClass=*)",
+		"ldap:///dc=example???(cn=a%3Fb)?!x-ext=a%2Cb",
+	}
+
+	for _, in := range tests {
+		u, err := ParseURL(in)
+		if err != nil {
+			t.Fatalf("ParseURL(%q) error = %v", in, err)
+		}
+		if got := u.String(); got != in {
+			t.Errorf("String() = %q, want %q", got, in)
+		}
+		again, err := ParseURL(u.String())
+		if err != nil || !reflect.DeepEqual(again, u) {
+			t.Errorf("ParseURL(String()) = %+v, %v, want %+v", again, err, u)
+		}
+	}
+}
@@ -7,6 +7,7 @@
 //   - Multiple log levels (debug, info, warn, error)
 //   - Text and JSON output formats
 //   - Request ID tracking for distributed tracing
+//   - Component tagging with per-component log levels
 //   - Field-based contextual logging
 //
 // # Creating a Logger
@@ -83,6 +84,21 @@
 //	connLogger.Info("bind request received")
 //	connLogger.Info("bind successful")
 //
+// # Components
+//
+// Loggers for parts of the server are tagged with a component, which is
+// added to every entry as the "component" field:
+//
+//	raftLogger := logger.WithComponent("raft")
+//
+// Config.ComponentLevels sets the level of a component, overriding
+// Config.Level:
+//
+//	logger := logging.New(logging.Config{
+//	    Level:           "info",
+//	    ComponentLevels: map[string]string{"raft": "debug", "storage": "warn"},
+//	})
+//
 // # Output Formats
 //
 // Text format (human-readable):
@@ -100,4 +116,14 @@
 //	logging.Config{Output: "stdout"}           // Standard output
 //	logging.Config{Output: "stderr"}           // Standard error
 //	logging.Config{Output: "/var/log/oba.log"} // File path
+//
+// # SNMP Traps
+//
+// SNMPTrapEmitter sends the notifications of the OBA MIB to a network
+// management station as SNMPv2c traps:
+//
+//	emitter := logging.NewSNMPTrapEmitter("nms.example.com:162", "public", logging.SNMPv2c)
+//	defer emitter.Close()
+//
+//	emitter.Emit(logging.BindFailureTrap(bindDN, clientIP, "invalidCredentials"))
 package logging
@@ -90,8 +90,9 @@ type Logger interface {
 	Error(msg string, keysAndValues ...interface{})
 	// WithRequestID returns a new logger with the given request ID.
 	WithRequestID(requestID string) Logger
-	// WithSource returns a new logger with the given source.
-	WithSource(source string) Logger
+	// WithComponent returns a new logger that tags its entries with the
+	// given component and logs at the component's configured level.
+	WithComponent(component string) Logger
 	// WithUser returns a new logger with the given user DN.
 	WithUser(user string) Logger
 	// WithFields returns a new logger with the given fields.
@@ -122,9 +123,13 @@ type logger struct {
 	fields    map[string]interface{}
 	mu        sync.Mutex
 	requestID string
-	source    string
+	component string
 	user      string
 	store     *LogStore
+
+	// componentLevels maps component names to their level overrides.
+	// It is shared by all loggers derived from the same New call.
+	componentLevels map[string]Level
 }
 
 // Config holds the logger configuration.
@@ -132,6 +137,10 @@ type Config struct {
 	Level  string
 	Format string
 	Output string
+
+	// ComponentLevels overrides Level for the loggers returned by
+	// WithComponent, keyed by component name (e.g. "raft": "debug").
+	ComponentLevels map[string]string
 }
 
 // New creates a new Logger with the given configuration.
@@ -152,11 +161,17 @@ func New(cfg Config) Logger {
 		}
 	}
 
+	componentLevels := make(map[string]Level, len(cfg.ComponentLevels))
+	for component, level := range cfg.ComponentLevels {
+		componentLevels[component] = ParseLevel(level)
+	}
+
 	return &logger{
-		level:  ParseLevel(cfg.Level),
-		format: ParseFormat(cfg.Format),
-		output: output,
-		fields: make(map[string]interface{}),
+		level:           ParseLevel(cfg.Level),
+		format:          ParseFormat(cfg.Format),
+		output:          output,
+		fields:          make(map[string]interface{}),
+		componentLevels: componentLevels,
 	}
 }
 
@@ -202,10 +217,14 @@ func (l *logger) WithRequestID(requestID string) Logger {
 	return newLogger
 }
 
-// WithSource returns a new logger with the given source.
-func (l *logger) WithSource(source string) Logger {
+// WithComponent returns a new logger with the given component. Its level
+// is the component's level from Config.ComponentLevels, if there is one.
+func (l *logger) WithComponent(component string) Logger {
 	newLogger := l.clone()
-	newLogger.source = source
+	newLogger.component = component
+	if level, ok := l.componentLevels[component]; ok {
+		newLogger.level = level
+	}
 	return newLogger
 }
 
@@ -344,14 +363,15 @@ func (l *logger) clone() *logger {
 		newFields[k] = v
 	}
 	return &logger{
-		level:     l.level,
-		format:    l.format,
-		output:    l.output,
-		fields:    newFields,
-		requestID: l.requestID,
-		source:    l.source,
-		user:      l.user,
-		store:     l.store,
+		level:           l.level,
+		format:          l.format,
+		output:          l.output,
+		fields:          newFields,
+		requestID:       l.requestID,
+		component:       l.component,
+		user:            l.user,
+		store:           l.store,
+		componentLevels: l.componentLevels,
 	}
 }
 
@@ -375,6 +395,10 @@ func (l *logger) log(level Level, msg string, keysAndValues ...interface{}) {
 		entry["request_id"] = l.requestID
 	}
 
+	if l.component != "" {
+		entry["component"] = l.component
+	}
+
 	// Add base fields
 	for k, v := range l.fields {
 		entry[k] = v
@@ -391,11 +415,11 @@ func (l *logger) log(level Level, msg string, keysAndValues ...interface{}) {
 	if l.store != nil {
 		fields := make(map[string]interface{})
 		for k, v := range entry {
-			if k != "ts" && k != "level" && k != "msg" && k != "request_id" && k != "source" && k != "user" {
+			if k != "ts" && k != "level" && k != "msg" && k != "request_id" && k != "component" && k != "user" {
 				fields[k] = v
 			}
 		}
-		l.store.Write(level.String(), msg, l.source, l.user, l.requestID, fields)
+		l.store.Write(level.String(), msg, l.component, l.user, l.requestID, fields)
 	}
 
 	// Format and write
@@ -446,7 +470,7 @@ func (n *nopLogger) Info(_ string, _ ...interface{})    {}
 func (n *nopLogger) Warn(_ string, _ ...interface{})    {}
 func (n *nopLogger) Error(_ string, _ ...interface{})   {}
 func (n *nopLogger) WithRequestID(_ string) Logger      { return n }
-func (n *nopLogger) WithSource(_ string) Logger         { return n }
+func (n *nopLogger) WithComponent(_ string) Logger      { return n }
 func (n *nopLogger) WithUser(_ string) Logger           { return n }
 func (n *nopLogger) WithFields(_ ...interface{}) Logger { return n }
 func (n *nopLogger) SetLevel(_ Level)                   {}
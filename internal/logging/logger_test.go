@@ -208,6 +208,49 @@ func TestLoggerWithFields(t *testing.T) {
 	}
 }
 
+func TestLoggerWithComponent(t *testing.T) {
+	var buf bytes.Buffer
+	root := New(Config{
+		Level:  "info",
+		Format: "json",
+		ComponentLevels: map[string]string{
+			"raft":    "debug",
+			"storage": "warn",
+		},
+	})
+	root.SetOutput(&buf)
+
+	raft := root.WithComponent("raft")
+	storage := root.WithComponent("storage")
+	ldap := root.WithComponent("ldap")
+
+	raft.Debug("raft debug")
+	raft.Info("raft info")
+	storage.Info("storage info")
+	storage.Warn("storage warn")
+	ldap.Debug("ldap debug")
+	ldap.Info("ldap info")
+	root.Debug("root debug")
+
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Failed to parse JSON output %q: %v", line, err)
+		}
+		msg := entry["msg"].(string)
+		if component := strings.Fields(msg)[0]; entry["component"] != component {
+			t.Errorf("%q has component %v, want %s", msg, entry["component"], component)
+		}
+		got = append(got, msg)
+	}
+
+	want := []string{"raft debug", "raft info", "storage warn", "ldap info"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("logged %v, want %v", got, want)
+	}
+}
+
 func TestLoggerCloneIsolation(t *testing.T) {
 	var buf bytes.Buffer
 	l := &logger{
@@ -0,0 +1,136 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RotateConfig holds configuration for a file rotated at a fixed interval.
+type RotateConfig struct {
+	Path     string        // Path of the active file
+	Interval time.Duration // Rotation interval (e.g., 24h)
+	Mode     os.FileMode   // Permissions of new files
+}
+
+// DefaultRotateConfig returns a configuration that rotates path daily.
+func DefaultRotateConfig(path string) RotateConfig {
+	return RotateConfig{
+		Path:     path,
+		Interval: 24 * time.Hour,
+		Mode:     0600,
+	}
+}
+
+// RotatingFile is an append-only file that is rotated when the current
+// interval ends. The rotated file is renamed to the active path followed by
+// the start of its interval in UTC, e.g. audit.ldif.20260218.
+type RotatingFile struct {
+	config RotateConfig
+	mu     sync.Mutex
+	file   *os.File
+	period time.Time
+	now    func() time.Time
+}
+
+// NewRotatingFile opens the active file of config for appending, creating
+// it and its directory if needed. An active file left over from an earlier
+// interval is rotated first.
+func NewRotatingFile(config RotateConfig) (*RotatingFile, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("rotating file path required")
+	}
+	if config.Interval <= 0 {
+		config.Interval = 24 * time.Hour
+	}
+	if config.Mode == 0 {
+		config.Mode = 0600
+	}
+
+	if err := os.MkdirAll(filepath.Dir(config.Path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	f := &RotatingFile{config: config, now: time.Now}
+	if info, err := os.Stat(config.Path); err == nil {
+		f.period = f.periodOf(info.ModTime())
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write appends p to the active file, rotating it first if its interval
+// has ended. A single Write is never split across files.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if err := f.open(); err != nil {
+		return 0, err
+	}
+	return f.file.Write(p)
+}
+
+// Close closes the active file.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// open makes sure the active file belongs to the current interval, rotating
+// the previous one if needed.
+func (f *RotatingFile) open() error {
+	current := f.periodOf(f.now())
+	if f.file != nil && f.period.Equal(current) {
+		return nil
+	}
+
+	if f.file != nil {
+		if err := f.file.Close(); err != nil {
+			return fmt.Errorf("failed to close log file: %w", err)
+		}
+		f.file = nil
+	}
+	if !f.period.IsZero() && !f.period.Equal(current) {
+		if err := os.Rename(f.config.Path, f.rotatedPath(f.period)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate log file: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(f.config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, f.config.Mode)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	f.file = file
+	f.period = current
+	return nil
+}
+
+// periodOf returns the start of the interval containing t.
+func (f *RotatingFile) periodOf(t time.Time) time.Time {
+	return t.UTC().Truncate(f.config.Interval)
+}
+
+// rotatedPath returns the name of the file for the interval starting at
+// period. Intervals shorter than a day include the time.
+func (f *RotatingFile) rotatedPath(period time.Time) string {
+	layout := "20060102"
+	if f.config.Interval < 24*time.Hour {
+		layout = "20060102T150405"
+	}
+	return f.config.Path + "." + period.Format(layout)
+}
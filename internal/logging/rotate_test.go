@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.ldif")
+	f, err := NewRotatingFile(DefaultRotateConfig(path))
+	if err != nil {
+		t.Fatalf("NewRotatingFile() error = %v", err)
+	}
+	defer f.Close()
+
+	now := time.Date(2026, 2, 18, 23, 59, 0, 0, time.UTC)
+	f.now = func() time.Time { return now }
+	f.period = f.periodOf(now)
+
+	if _, err := f.Write([]byte("first\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := f.Write([]byte("second\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	rotated, err := os.ReadFile(path + ".20260218")
+	if err != nil {
+		t.Fatalf("rotated file: %v", err)
+	}
+	if string(rotated) != "first\n" {
+		t.Errorf("rotated file = %q, want %q", rotated, "first\n")
+	}
+	active, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("active file: %v", err)
+	}
+	if string(active) != "second\n" {
+		t.Errorf("active file = %q, want %q", active, "second\n")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestRotatingFileClosed(t *testing.T) {
+	f, err := NewRotatingFile(DefaultRotateConfig(filepath.Join(t.TempDir(), "audit.ldif")))
+	if err != nil {
+		t.Fatalf("NewRotatingFile() error = %v", err)
+	}
+	f.Close()
+	if _, err := f.Write([]byte("x\n")); err == nil {
+		t.Error("expected Write() on a closed file to fail")
+	}
+}
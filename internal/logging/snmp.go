@@ -0,0 +1,425 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/ber"
+)
+
+// SnmpVersion is the SNMP version of the messages sent by an
+// SNMPTrapEmitter. Its value is the version field of the message.
+type SnmpVersion int
+
+// SNMP versions.
+const (
+	// SNMPv2c sends SNMPv2-Trap-PDUs (RFC 3416) in community based
+	// messages (RFC 1901).
+	SNMPv2c SnmpVersion = 1
+)
+
+// String returns the configuration name of the version.
+func (v SnmpVersion) String() string {
+	switch v {
+	case SNMPv2c:
+		return "2c"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseSnmpVersion parses an SNMP version name. Only "2c" is supported;
+// an empty name selects it.
+func ParseSnmpVersion(s string) (SnmpVersion, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "2c", "v2c":
+		return SNMPv2c, nil
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrSNMPVersion, s)
+	}
+}
+
+// SNMP trap errors.
+var (
+	// ErrSNMPVersion is returned for SNMP versions other than v2c.
+	ErrSNMPVersion = errors.New("logging: unsupported SNMP version")
+	// ErrSNMPValue is returned for variable binding values that cannot be
+	// encoded.
+	ErrSNMPValue = errors.New("logging: unsupported SNMP value type")
+	// ErrSNMPOID is returned for malformed object identifiers.
+	ErrSNMPOID = errors.New("logging: invalid SNMP object identifier")
+)
+
+// DefaultSNMPTrapPort is the UDP port traps are sent to when the target
+// does not name one.
+const DefaultSNMPTrapPort = "162"
+
+// ObaMIB is the root of the OBA MIB, under a private enterprise number.
+// The MIB module is in docs/OBA-MIB.txt. Notifications are under
+// ObaMIB.0 and the objects they carry under ObaMIB.1.
+const ObaMIB = "1.3.6.1.4.1.61423.1"
+
+// TrapType is the notification OID of a trap in the OBA MIB.
+type TrapType string
+
+// Trap types of the OBA MIB.
+const (
+	// TrapServerStart (obaServerStart) is sent when the server starts
+	// accepting connections.
+	TrapServerStart TrapType = ObaMIB + ".0.1"
+	// TrapServerStop (obaServerStop) is sent when the server shuts down.
+	TrapServerStop TrapType = ObaMIB + ".0.2"
+	// TrapBindFailure (obaBindFailure) is sent when a bind fails.
+	TrapBindFailure TrapType = ObaMIB + ".0.3"
+	// TrapReplicationLag (obaReplicationLag) is sent when a cluster node
+	// falls behind the commit index.
+	TrapReplicationLag TrapType = ObaMIB + ".0.4"
+	// TrapStorageFull (obaStorageFull) is sent when the volume of the data
+	// directory is nearly full.
+	TrapStorageFull TrapType = ObaMIB + ".0.5"
+)
+
+// String returns the MIB name of the trap type.
+func (t TrapType) String() string {
+	switch t {
+	case TrapServerStart:
+		return "obaServerStart"
+	case TrapServerStop:
+		return "obaServerStop"
+	case TrapBindFailure:
+		return "obaBindFailure"
+	case TrapReplicationLag:
+		return "obaReplicationLag"
+	case TrapStorageFull:
+		return "obaStorageFull"
+	default:
+		return string(t)
+	}
+}
+
+// Objects of the OBA MIB carried by the traps.
+const (
+	// OIDEventDescription (obaEventDescription) is a DisplayString
+	// describing the event.
+	OIDEventDescription = ObaMIB + ".1.1"
+	// OIDBindDN (obaBindDN) is the DN of a failed bind.
+	OIDBindDN = ObaMIB + ".1.2"
+	// OIDClientAddress (obaClientAddress) is the address of the client.
+	OIDClientAddress = ObaMIB + ".1.3"
+	// OIDReplicationLagEntries (obaReplicationLagEntries) is the number of
+	// committed log entries not yet applied, as a Gauge32.
+	OIDReplicationLagEntries = ObaMIB + ".1.4"
+	// OIDStorageUsedPercent (obaStorageUsedPercent) is the used share of
+	// the data volume, as an Integer32.
+	OIDStorageUsedPercent = ObaMIB + ".1.5"
+)
+
+// Standard objects sent first in every SNMPv2 trap (RFC 3416 section 4.2.6).
+const (
+	oidSysUpTime   = "1.3.6.1.2.1.1.3.0"
+	oidSnmpTrapOID = "1.3.6.1.6.3.1.1.4.1.0"
+)
+
+// VarBind is a variable binding of a trap. Value is a string or []byte
+// (OCTET STRING), an int or int64 (INTEGER) or a uint32 (Gauge32).
+type VarBind struct {
+	OID   string
+	Value interface{}
+}
+
+// Trap is a notification of the OBA MIB.
+type Trap struct {
+	Type     TrapType
+	Bindings []VarBind
+}
+
+// ServerStartTrap returns an obaServerStart trap.
+func ServerStartTrap(description string) Trap {
+	return Trap{Type: TrapServerStart, Bindings: []VarBind{{OIDEventDescription, description}}}
+}
+
+// ServerStopTrap returns an obaServerStop trap.
+func ServerStopTrap(description string) Trap {
+	return Trap{Type: TrapServerStop, Bindings: []VarBind{{OIDEventDescription, description}}}
+}
+
+// BindFailureTrap returns an obaBindFailure trap for a failed bind of
+// bindDN from clientAddr.
+func BindFailureTrap(bindDN, clientAddr, description string) Trap {
+	return Trap{Type: TrapBindFailure, Bindings: []VarBind{
+		{OIDBindDN, bindDN},
+		{OIDClientAddress, clientAddr},
+		{OIDEventDescription, description},
+	}}
+}
+
+// ReplicationLagTrap returns an obaReplicationLag trap for a node that is
+// entries behind the commit index.
+func ReplicationLagTrap(entries uint64) Trap {
+	if entries > 1<<32-1 {
+		entries = 1<<32 - 1
+	}
+	return Trap{Type: TrapReplicationLag, Bindings: []VarBind{
+		{OIDReplicationLagEntries, uint32(entries)},
+		{OIDEventDescription, fmt.Sprintf("replication is %d entries behind", entries)},
+	}}
+}
+
+// StorageFullTrap returns an obaStorageFull trap for a data volume that is
+// usedPercent full.
+func StorageFullTrap(usedPercent int) Trap {
+	return Trap{Type: TrapStorageFull, Bindings: []VarBind{
+		{OIDStorageUsedPercent, usedPercent},
+		{OIDEventDescription, fmt.Sprintf("data volume is %d%% full", usedPercent)},
+	}}
+}
+
+// SNMPTrapEmitter sends traps over UDP to a network management station.
+// It is safe for concurrent use.
+type SNMPTrapEmitter struct {
+	target    string
+	community string
+	version   SnmpVersion
+	start     time.Time
+	requestID atomic.Int32
+
+	mu     sync.Mutex
+	conn   net.Conn
+	closed bool
+}
+
+// NewSNMPTrapEmitter creates an emitter that sends traps to target
+// (host or host:port, port 162 by default) with the given community.
+// The socket is opened by the first Emit.
+func NewSNMPTrapEmitter(target string, community string, version SnmpVersion) *SNMPTrapEmitter {
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		target = net.JoinHostPort(target, DefaultSNMPTrapPort)
+	}
+	return &SNMPTrapEmitter{
+		target:    target,
+		community: community,
+		version:   version,
+		start:     time.Now(),
+	}
+}
+
+// Target returns the address traps are sent to.
+func (e *SNMPTrapEmitter) Target() string {
+	return e.target
+}
+
+// Emit sends trap. sysUpTime is the time since the emitter was created.
+func (e *SNMPTrapEmitter) Emit(trap Trap) error {
+	if e.version != SNMPv2c {
+		return ErrSNMPVersion
+	}
+
+	msg, err := e.encode(trap)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return net.ErrClosed
+	}
+	if e.conn == nil {
+		conn, err := net.Dial("udp", e.target)
+		if err != nil {
+			return fmt.Errorf("logging: SNMP trap target %s: %w", e.target, err)
+		}
+		e.conn = conn
+	}
+
+	if _, err := e.conn.Write(msg); err != nil {
+		// Redial with the next trap, the target may have changed address
+		e.conn.Close()
+		e.conn = nil
+		return fmt.Errorf("logging: sending SNMP trap: %w", err)
+	}
+	return nil
+}
+
+// Close closes the socket of the emitter. Later traps are not sent.
+func (e *SNMPTrapEmitter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.closed = true
+	if e.conn == nil {
+		return nil
+	}
+	err := e.conn.Close()
+	e.conn = nil
+	return err
+}
+
+// encode encodes trap as an SNMPv2c message:
+//
+//	Message ::= SEQUENCE { version INTEGER, community OCTET STRING, data PDU }
+//	SNMPv2-Trap-PDU ::= [7] IMPLICIT SEQUENCE {
+//	    request-id INTEGER, error-status INTEGER, error-index INTEGER,
+//	    variable-bindings SEQUENCE OF SEQUENCE { name OID, value ANY } }
+func (e *SNMPTrapEmitter) encode(trap Trap) ([]byte, error) {
+	uptime := uint32(time.Since(e.start) / (10 * time.Millisecond))
+	bindings := append([]VarBind{
+		{oidSysUpTime, timeTicks(uptime)},
+		{oidSnmpTrapOID, objectID(trap.Type)},
+	}, trap.Bindings...)
+
+	enc := ber.NewBEREncoder(256)
+	msgPos := enc.BeginSequence()
+	enc.WriteInteger(int64(e.version))
+	enc.WriteOctetString([]byte(e.community))
+
+	pduPos := enc.WriteContextTag(7, true)
+	enc.WriteInteger(int64(e.requestID.Add(1)))
+	enc.WriteInteger(0) // error-status
+	enc.WriteInteger(0) // error-index
+
+	listPos := enc.BeginSequence()
+	for _, vb := range bindings {
+		vbPos := enc.BeginSequence()
+		if err := writeOID(enc, vb.OID); err != nil {
+			return nil, err
+		}
+		if err := writeSNMPValue(enc, vb.Value); err != nil {
+			return nil, fmt.Errorf("%w: %s", err, vb.OID)
+		}
+		enc.EndSequence(vbPos)
+	}
+	enc.EndSequence(listPos)
+	enc.EndContextTag(pduPos)
+	enc.EndSequence(msgPos)
+
+	return enc.Bytes(), nil
+}
+
+// timeTicks and objectID select the SMI type of a value written by
+// writeSNMPValue.
+type (
+	timeTicks uint32
+	objectID  string
+)
+
+// SMI application types (RFC 2578 section 7.1).
+const (
+	tagGauge32   = 2
+	tagTimeTicks = 3
+)
+
+// writeSNMPValue writes v with its SMI type.
+func writeSNMPValue(enc *ber.BEREncoder, v interface{}) error {
+	switch v := v.(type) {
+	case string:
+		return enc.WriteOctetString([]byte(v))
+	case []byte:
+		return enc.WriteOctetString(v)
+	case int:
+		return enc.WriteInteger(int64(v))
+	case int64:
+		return enc.WriteInteger(v)
+	case uint32:
+		return writeUnsigned(enc, tagGauge32, v)
+	case timeTicks:
+		return writeUnsigned(enc, tagTimeTicks, uint32(v))
+	case objectID:
+		return writeOID(enc, string(v))
+	default:
+		return ErrSNMPValue
+	}
+}
+
+// writeUnsigned writes an unsigned 32-bit application type. The value is
+// encoded like an INTEGER, so a set high bit needs a leading zero octet.
+func writeUnsigned(enc *ber.BEREncoder, tag int, v uint32) error {
+	content := []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+	for len(content) > 1 && content[0] == 0 {
+		content = content[1:]
+	}
+	if content[0]&0x80 != 0 {
+		content = append([]byte{0}, content...)
+	}
+
+	if err := enc.WriteTag(ber.ClassApplication, ber.TypePrimitive, tag); err != nil {
+		return err
+	}
+	if err := enc.WriteLength(len(content)); err != nil {
+		return err
+	}
+	enc.WriteRaw(content)
+	return nil
+}
+
+// writeOID writes a dotted object identifier.
+func writeOID(enc *ber.BEREncoder, oid string) error {
+	content, err := encodeOID(oid)
+	if err != nil {
+		return err
+	}
+	if err := enc.WriteTag(ber.ClassUniversal, ber.TypePrimitive, ber.TagOID); err != nil {
+		return err
+	}
+	if err := enc.WriteLength(len(content)); err != nil {
+		return err
+	}
+	enc.WriteRaw(content)
+	return nil
+}
+
+// encodeOID returns the content octets of a dotted object identifier
+// (X.690 section 8.19).
+func encodeOID(oid string) ([]byte, error) {
+	parts := strings.Split(oid, ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("%w: %q", ErrSNMPOID, oid)
+	}
+	arcs := make([]uint64, len(parts))
+	for i, part := range parts {
+		arc, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrSNMPOID, oid)
+		}
+		arcs[i] = arc
+	}
+	if arcs[0] > 2 || (arcs[0] < 2 && arcs[1] > 39) {
+		return nil, fmt.Errorf("%w: %q", ErrSNMPOID, oid)
+	}
+
+	// The first two arcs share one subidentifier
+	content := appendBase128(nil, arcs[0]*40+arcs[1])
+	for _, arc := range arcs[2:] {
+		content = appendBase128(content, arc)
+	}
+	return content, nil
+}
+
+// appendBase128 appends v in base 128, most significant group first, with
+// the high bit set on all but the last octet.
+func appendBase128(buf []byte, v uint64) []byte {
+	var groups [10]byte
+	n := 0
+	for {
+		groups[n] = byte(v & 0x7F)
+		n++
+		v >>= 7
+		if v == 0 {
+			break
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		b := groups[i]
+		if i > 0 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+	}
+	return buf
+}
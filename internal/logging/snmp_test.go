@@ -0,0 +1,280 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/ber"
+)
+
+// decodedTrap is an SNMPv2c trap message read back from the wire.
+type decodedTrap struct {
+	version   int64
+	community string
+	requestID int64
+	bindings  []decodedBinding
+}
+
+// decodedBinding is a variable binding with its BER class and tag.
+type decodedBinding struct {
+	oid   string
+	class int
+	tag   int
+	value []byte
+}
+
+// receiveTrap reads one datagram from conn and decodes it.
+func receiveTrap(t *testing.T, conn net.PacketConn) *decodedTrap {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 65535)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("no trap received: %v", err)
+	}
+	trap, err := decodeTrap(buf[:n])
+	if err != nil {
+		t.Fatalf("failed to decode trap: %v", err)
+	}
+	return trap
+}
+
+func decodeTrap(data []byte) (*decodedTrap, error) {
+	msg, err := ber.NewBERDecoder(data).ReadSequenceContents()
+	if err != nil {
+		return nil, err
+	}
+	trap := &decodedTrap{}
+	if trap.version, err = msg.ReadInteger(); err != nil {
+		return nil, err
+	}
+	community, err := msg.ReadOctetString()
+	if err != nil {
+		return nil, err
+	}
+	trap.community = string(community)
+
+	pdu, err := msg.ReadContextTagContents(7)
+	if err != nil {
+		return nil, err
+	}
+	if trap.requestID, err = pdu.ReadInteger(); err != nil {
+		return nil, err
+	}
+	for _, field := range []string{"error-status", "error-index"} {
+		if v, err := pdu.ReadInteger(); err != nil || v != 0 {
+			return nil, fmt.Errorf("%s = %d, %v", field, v, err)
+		}
+	}
+
+	list, err := pdu.ReadSequenceContents()
+	if err != nil {
+		return nil, err
+	}
+	for list.Remaining() > 0 {
+		vb, err := list.ReadSequenceContents()
+		if err != nil {
+			return nil, err
+		}
+		_, _, name, err := readTLV(vb)
+		if err != nil {
+			return nil, err
+		}
+		class, tag, value, err := readTLV(vb)
+		if err != nil {
+			return nil, err
+		}
+		trap.bindings = append(trap.bindings, decodedBinding{decodeOID(name), class, tag, value})
+	}
+	return trap, nil
+}
+
+// readTLV reads one element and returns its class, tag number and content.
+func readTLV(d *ber.BERDecoder) (class, tag int, value []byte, err error) {
+	raw, err := d.ReadRawValue()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	inner := ber.NewBERDecoder(raw)
+	class, _, tag, err = inner.ReadTag()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	if _, err := inner.ReadLength(); err != nil {
+		return 0, 0, nil, err
+	}
+	return class, tag, raw[inner.Offset():], nil
+}
+
+func decodeOID(content []byte) string {
+	var arcs []string
+	var v uint64
+	for _, b := range content {
+		v = v<<7 | uint64(b&0x7F)
+		if b&0x80 != 0 {
+			continue
+		}
+		if len(arcs) == 0 {
+			// The first subidentifier holds two arcs
+			first := min(v/40, 2)
+			arcs = append(arcs, fmt.Sprint(first), fmt.Sprint(v-first*40))
+		} else {
+			arcs = append(arcs, fmt.Sprint(v))
+		}
+		v = 0
+	}
+	return strings.Join(arcs, ".")
+}
+
+func decodeUnsigned(content []byte) uint64 {
+	var v uint64
+	for _, b := range content {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
+
+func listenTrapReceiver(t *testing.T) net.PacketConn {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestSNMPTrapEmitter(t *testing.T) {
+	receiver := listenTrapReceiver(t)
+	emitter := NewSNMPTrapEmitter(receiver.LocalAddr().String(), "monitor", SNMPv2c)
+	defer emitter.Close()
+
+	trap := BindFailureTrap("uid=alice,dc=example,dc=com", "192.0.2.10", "invalidCredentials")
+	if err := emitter.Emit(trap); err != nil {
+		t.Fatalf("Emit() error = %v", err)
+	}
+	got := receiveTrap(t, receiver)
+
+	if got.version != 1 {
+		t.Errorf("version = %d, want 1 (v2c)", got.version)
+	}
+	if got.community != "monitor" {
+		t.Errorf("community = %q, want monitor", got.community)
+	}
+	if len(got.bindings) != 5 {
+		t.Fatalf("got %d variable bindings, want 5: %+v", len(got.bindings), got.bindings)
+	}
+
+	uptime := got.bindings[0]
+	if uptime.oid != "1.3.6.1.2.1.1.3.0" || uptime.class != ber.ClassApplication || uptime.tag != 3 {
+		t.Errorf("first binding = %+v, want sysUpTime.0 TimeTicks", uptime)
+	}
+
+	trapOID := got.bindings[1]
+	if trapOID.oid != "1.3.6.1.6.3.1.1.4.1.0" || trapOID.tag != ber.TagOID {
+		t.Errorf("second binding = %+v, want snmpTrapOID.0 OID", trapOID)
+	}
+	if oid := decodeOID(trapOID.value); oid != string(TrapBindFailure) {
+		t.Errorf("snmpTrapOID = %s, want obaBindFailure %s", oid, TrapBindFailure)
+	}
+
+	want := []struct {
+		oid, value string
+	}{
+		{OIDBindDN, "uid=alice,dc=example,dc=com"},
+		{OIDClientAddress, "192.0.2.10"},
+		{OIDEventDescription, "invalidCredentials"},
+	}
+	for i, w := range want {
+		vb := got.bindings[i+2]
+		if vb.oid != w.oid || vb.tag != ber.TagOctetString || string(vb.value) != w.value {
+			t.Errorf("binding %d = %s %q, want %s %q", i+2, vb.oid, vb.value, w.oid, w.value)
+		}
+	}
+
+	// Request IDs increase
+	if err := emitter.Emit(ServerStartTrap("server started")); err != nil {
+		t.Fatalf("Emit() error = %v", err)
+	}
+	if next := receiveTrap(t, receiver); next.requestID != got.requestID+1 {
+		t.Errorf("request-id = %d, want %d", next.requestID, got.requestID+1)
+	}
+}
+
+func TestSNMPTrapTypes(t *testing.T) {
+	receiver := listenTrapReceiver(t)
+	emitter := NewSNMPTrapEmitter(receiver.LocalAddr().String(), "public", SNMPv2c)
+	defer emitter.Close()
+
+	tests := []struct {
+		trap  Trap
+		name  string
+		oid   string
+		class int
+		tag   int
+		value uint64
+	}{
+		{ServerStartTrap("started"), "obaServerStart", OIDEventDescription, ber.ClassUniversal, ber.TagOctetString, 0},
+		{ServerStopTrap("stopping"), "obaServerStop", OIDEventDescription, ber.ClassUniversal, ber.TagOctetString, 0},
+		{ReplicationLagTrap(3000000000), "obaReplicationLag", OIDReplicationLagEntries, ber.ClassApplication, 2, 3000000000},
+		{StorageFullTrap(93), "obaStorageFull", OIDStorageUsedPercent, ber.ClassUniversal, ber.TagInteger, 93},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.trap.Type.String() != tt.name {
+				t.Errorf("String() = %s, want %s", tt.trap.Type, tt.name)
+			}
+			if err := emitter.Emit(tt.trap); err != nil {
+				t.Fatalf("Emit() error = %v", err)
+			}
+			got := receiveTrap(t, receiver)
+			if oid := decodeOID(got.bindings[1].value); oid != string(tt.trap.Type) {
+				t.Errorf("snmpTrapOID = %s, want %s", oid, tt.trap.Type)
+			}
+			vb := got.bindings[2]
+			if vb.oid != tt.oid || vb.class != tt.class || vb.tag != tt.tag {
+				t.Errorf("binding = %s class %#x tag %d, want %s class %#x tag %d", vb.oid, vb.class, vb.tag, tt.oid, tt.class, tt.tag)
+			}
+			if tt.value != 0 && decodeUnsigned(vb.value) != tt.value {
+				t.Errorf("value = %d, want %d", decodeUnsigned(vb.value), tt.value)
+			}
+		})
+	}
+}
+
+func TestSNMPTrapEmitterErrors(t *testing.T) {
+	if _, err := ParseSnmpVersion("3"); !errors.Is(err, ErrSNMPVersion) {
+		t.Errorf("ParseSnmpVersion(3) error = %v, want ErrSNMPVersion", err)
+	}
+	if v, err := ParseSnmpVersion(""); err != nil || v != SNMPv2c {
+		t.Errorf("ParseSnmpVersion(\"\") = %v, %v, want 2c", v, err)
+	}
+
+	receiver := listenTrapReceiver(t)
+	if err := NewSNMPTrapEmitter(receiver.LocalAddr().String(), "public", SnmpVersion(3)).Emit(ServerStartTrap("")); !errors.Is(err, ErrSNMPVersion) {
+		t.Errorf("Emit() with v3 error = %v, want ErrSNMPVersion", err)
+	}
+
+	emitter := NewSNMPTrapEmitter(receiver.LocalAddr().String(), "public", SNMPv2c)
+	bad := Trap{Type: TrapServerStart, Bindings: []VarBind{{OIDEventDescription, 1.5}}}
+	if err := emitter.Emit(bad); !errors.Is(err, ErrSNMPValue) {
+		t.Errorf("Emit() with float value error = %v, want ErrSNMPValue", err)
+	}
+	bad = Trap{Type: "1.3.x", Bindings: nil}
+	if err := emitter.Emit(bad); !errors.Is(err, ErrSNMPOID) {
+		t.Errorf("Emit() with bad OID error = %v, want ErrSNMPOID", err)
+	}
+
+	emitter.Close()
+	if err := emitter.Emit(ServerStopTrap("")); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Emit() after Close error = %v, want net.ErrClosed", err)
+	}
+
+	if target := NewSNMPTrapEmitter("nms.example.com", "public", SNMPv2c).Target(); target != "nms.example.com:162" {
+		t.Errorf("Target() = %s, want the default port", target)
+	}
+}
@@ -0,0 +1,195 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// LatencyBuckets are histogram bucket upper bounds in seconds for request
+// latencies, from 1ms to 1s.
+var LatencyBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+// Histogram counts observations in buckets of increasing upper bounds and
+// keeps their count and sum.
+type Histogram struct {
+	name    string
+	help    string
+	buckets []float64
+
+	// counts holds the observations of each bucket, not cumulated, and
+	// of the +Inf bucket last
+	counts  []atomic.Uint64
+	count   atomic.Uint64
+	sumBits atomic.Uint64
+}
+
+// NewHistogram creates a histogram with the given bucket upper bounds,
+// which must be sorted in increasing order.
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	h := &Histogram{name: name, help: help}
+	h.init(buckets)
+	return h
+}
+
+// init sets up the buckets of the histogram.
+func (h *Histogram) init(buckets []float64) {
+	h.buckets = buckets
+	h.counts = make([]atomic.Uint64, len(buckets)+1)
+}
+
+// Observe records the value v.
+func (h *Histogram) Observe(v float64) {
+	h.counts[sort.SearchFloat64s(h.buckets, v)].Add(1)
+	for {
+		old := h.sumBits.Load()
+		sum := math.Float64bits(math.Float64frombits(old) + v)
+		if h.sumBits.CompareAndSwap(old, sum) {
+			break
+		}
+	}
+	h.count.Add(1)
+}
+
+// Count returns the number of observations.
+func (h *Histogram) Count() uint64 {
+	return h.count.Load()
+}
+
+// Sum returns the sum of the observed values.
+func (h *Histogram) Sum() float64 {
+	return math.Float64frombits(h.sumBits.Load())
+}
+
+// Name returns the metric name.
+func (h *Histogram) Name() string {
+	return h.name
+}
+
+// WriteText writes the histogram in the text exposition format.
+func (h *Histogram) WriteText(w io.Writer) error {
+	writeHeader(w, h.name, h.help, "histogram")
+	return h.writeSamples(w, "")
+}
+
+// writeSamples writes the bucket, sum and count samples with the
+// formatted labels in front of the le label.
+func (h *Histogram) writeSamples(w io.Writer, labels string) error {
+	sep := ""
+	if labels != "" {
+		sep = ","
+	}
+
+	var cumulative uint64
+	for i := range h.counts {
+		cumulative += h.counts[i].Load()
+		le := "+Inf"
+		if i < len(h.buckets) {
+			le = formatFloat(h.buckets[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{%s%sle=%q} %d\n", h.name, labels, sep, le, cumulative)
+	}
+
+	suffix := ""
+	if labels != "" {
+		suffix = "{" + labels + "}"
+	}
+	fmt.Fprintf(w, "%s_sum%s %s\n", h.name, suffix, formatFloat(h.Sum()))
+	_, err := fmt.Fprintf(w, "%s_count%s %d\n", h.name, suffix, h.Count())
+	return err
+}
+
+// HistogramVec is a family of histograms partitioned by label values.
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu         sync.RWMutex
+	histograms map[string]*labeledHistogram
+}
+
+// labeledHistogram is a histogram of a HistogramVec with its label values.
+type labeledHistogram struct {
+	values    []string
+	histogram Histogram
+}
+
+// NewHistogramVec creates a histogram family with the given buckets and
+// label names.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{
+		name:       name,
+		help:       help,
+		labels:     labels,
+		buckets:    buckets,
+		histograms: make(map[string]*labeledHistogram),
+	}
+}
+
+// WithLabelValues returns the histogram for the label values, creating it
+// on first use. It panics if the number of values does not match the
+// labels.
+func (v *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+
+	key := strings.Join(values, "\xff")
+
+	v.mu.RLock()
+	lh, ok := v.histograms[key]
+	v.mu.RUnlock()
+	if ok {
+		return &lh.histogram
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if lh, ok = v.histograms[key]; !ok {
+		lh = &labeledHistogram{values: append([]string(nil), values...)}
+		lh.histogram.name = v.name
+		lh.histogram.init(v.buckets)
+		v.histograms[key] = lh
+	}
+	return &lh.histogram
+}
+
+// Name returns the metric name.
+func (v *HistogramVec) Name() string {
+	return v.name
+}
+
+// WriteText writes every histogram of the family, ordered by label values.
+func (v *HistogramVec) WriteText(w io.Writer) error {
+	v.mu.RLock()
+	keys := make([]string, 0, len(v.histograms))
+	for key := range v.histograms {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	histograms := make([]*labeledHistogram, 0, len(keys))
+	for _, key := range keys {
+		histograms = append(histograms, v.histograms[key])
+	}
+	v.mu.RUnlock()
+
+	writeHeader(w, v.name, v.help, "histogram")
+	for _, lh := range histograms {
+		if err := lh.histogram.writeSamples(w, formatLabels(v.labels, lh.values)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatFloat formats a sample value or bucket bound.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
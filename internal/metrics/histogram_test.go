@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHistogramWriteText(t *testing.T) {
+	r := NewRegistry()
+
+	latency := NewHistogram("test_latency_seconds", "Latency.", []float64{0.1, 1})
+	sizes := NewHistogramVec("test_size_bytes", "Sizes by kind.", []float64{10}, "kind")
+	r.MustRegister(latency, sizes)
+
+	latency.Observe(0.05)
+	latency.Observe(0.1)
+	latency.Observe(0.5)
+	latency.Observe(2)
+	sizes.WithLabelValues("b").Observe(20)
+	sizes.WithLabelValues("a").Observe(5)
+
+	var sb strings.Builder
+	if err := r.WriteText(&sb); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+
+	want := `# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{le="0.1"} 2
+test_latency_seconds_bucket{le="1"} 3
+test_latency_seconds_bucket{le="+Inf"} 4
+test_latency_seconds_sum 2.65
+test_latency_seconds_count 4
+# HELP test_size_bytes Sizes by kind.
+# TYPE test_size_bytes histogram
+test_size_bytes_bucket{kind="a",le="10"} 1
+test_size_bytes_bucket{kind="a",le="+Inf"} 1
+test_size_bytes_sum{kind="a"} 5
+test_size_bytes_count{kind="a"} 1
+test_size_bytes_bucket{kind="b",le="10"} 0
+test_size_bytes_bucket{kind="b",le="+Inf"} 1
+test_size_bytes_sum{kind="b"} 20
+test_size_bytes_count{kind="b"} 1
+`
+	if sb.String() != want {
+		t.Errorf("WriteText output:\n%s\nwant:\n%s", sb.String(), want)
+	}
+
+	if got := latency.Count(); got != 4 {
+		t.Errorf("Count() = %d, want 4", got)
+	}
+	if got := sizes.WithLabelValues("a").Sum(); got != 5 {
+		t.Errorf("Sum() = %v, want 5", got)
+	}
+}
+
+func TestHistogramConcurrentObserve(t *testing.T) {
+	h := NewHistogram("test_seconds", "", LatencyBuckets)
+
+	done := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		go func() {
+			for j := 0; j < 1000; j++ {
+				h.Observe(0.002)
+			}
+			done <- struct{}{}
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		<-done
+	}
+
+	if got := h.Count(); got != 8000 {
+		t.Errorf("Count() = %d, want 8000", got)
+	}
+	if got := h.Sum(); got < 15.99 || got > 16.01 {
+		t.Errorf("Sum() = %v, want 16", got)
+	}
+}
@@ -0,0 +1,340 @@
+// Package metrics provides counters, gauges and histograms that are exposed in the
+// Prometheus text exposition format. It uses only the standard library, so
+// the server can be scraped by Prometheus without a client library.
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Registry errors
+var (
+	// ErrDuplicateMetric is returned when a metric name is registered twice
+	ErrDuplicateMetric = errors.New("metrics: duplicate metric name")
+	// ErrInvalidMetricName is returned when a metric or label name is not valid
+	ErrInvalidMetricName = errors.New("metrics: invalid metric name")
+)
+
+// ContentType is the content type of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Collector is a metric family that can be registered with a Registry.
+type Collector interface {
+	// Name returns the name of the metric family.
+	Name() string
+	// WriteText writes the HELP and TYPE lines and the samples of the
+	// family in the text exposition format.
+	WriteText(w io.Writer) error
+}
+
+// Registerer registers collectors.
+type Registerer interface {
+	Register(c Collector) error
+}
+
+// Registry holds registered collectors and writes them out in name order.
+// It is safe for concurrent use.
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]Collector
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		collectors: make(map[string]Collector),
+	}
+}
+
+// Register adds c to the registry.
+func (r *Registry) Register(c Collector) error {
+	name := c.Name()
+	if !validName(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidMetricName, name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.collectors[name]; exists {
+		return fmt.Errorf("%w: %s", ErrDuplicateMetric, name)
+	}
+	r.collectors[name] = c
+	return nil
+}
+
+// MustRegister registers the collectors and panics on the first error.
+func (r *Registry) MustRegister(cs ...Collector) {
+	for _, c := range cs {
+		if err := r.Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Unregister removes the collector registered under name.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	delete(r.collectors, name)
+	r.mu.Unlock()
+}
+
+// WriteText writes every registered collector to w.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	collectors := make([]Collector, 0, len(names))
+	sort.Strings(names)
+	for _, name := range names {
+		collectors = append(collectors, r.collectors[name])
+	}
+	r.mu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		if err := c.WriteText(bw); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ServeHTTP writes the registry in the text exposition format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	if req.Method == http.MethodHead {
+		return
+	}
+	r.WriteText(w)
+}
+
+// Counter is a value that only increases.
+type Counter struct {
+	name  string
+	help  string
+	value atomic.Uint64
+}
+
+// NewCounter creates a counter.
+func NewCounter(name, help string) *Counter {
+	return &Counter{name: name, help: help}
+}
+
+// Inc increments the counter by one.
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Add increments the counter by n.
+func (c *Counter) Add(n uint64) {
+	c.value.Add(n)
+}
+
+// Value returns the current count.
+func (c *Counter) Value() uint64 {
+	return c.value.Load()
+}
+
+// Name returns the metric name.
+func (c *Counter) Name() string {
+	return c.name
+}
+
+// WriteText writes the counter in the text exposition format.
+func (c *Counter) WriteText(w io.Writer) error {
+	writeHeader(w, c.name, c.help, "counter")
+	_, err := fmt.Fprintf(w, "%s %d\n", c.name, c.Value())
+	return err
+}
+
+// Gauge is a value that can go up and down.
+type Gauge struct {
+	name  string
+	help  string
+	value atomic.Int64
+}
+
+// NewGauge creates a gauge.
+func NewGauge(name, help string) *Gauge {
+	return &Gauge{name: name, help: help}
+}
+
+// Inc increments the gauge by one.
+func (g *Gauge) Inc() {
+	g.value.Add(1)
+}
+
+// Dec decrements the gauge by one.
+func (g *Gauge) Dec() {
+	g.value.Add(-1)
+}
+
+// Set sets the gauge to v.
+func (g *Gauge) Set(v int64) {
+	g.value.Store(v)
+}
+
+// Value returns the current value.
+func (g *Gauge) Value() int64 {
+	return g.value.Load()
+}
+
+// Name returns the metric name.
+func (g *Gauge) Name() string {
+	return g.name
+}
+
+// WriteText writes the gauge in the text exposition format.
+func (g *Gauge) WriteText(w io.Writer) error {
+	writeHeader(w, g.name, g.help, "gauge")
+	_, err := fmt.Fprintf(w, "%s %d\n", g.name, g.Value())
+	return err
+}
+
+// CounterVec is a family of counters partitioned by label values.
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu       sync.RWMutex
+	counters map[string]*labeledCounter
+}
+
+// labeledCounter is a counter of a CounterVec with its label values.
+type labeledCounter struct {
+	values  []string
+	counter Counter
+}
+
+// NewCounterVec creates a counter family with the given label names.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{
+		name:     name,
+		help:     help,
+		labels:   labels,
+		counters: make(map[string]*labeledCounter),
+	}
+}
+
+// WithLabelValues returns the counter for the label values, creating it on
+// first use. It panics if the number of values does not match the labels.
+func (v *CounterVec) WithLabelValues(values ...string) *Counter {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+
+	key := strings.Join(values, "\xff")
+
+	v.mu.RLock()
+	lc, ok := v.counters[key]
+	v.mu.RUnlock()
+	if ok {
+		return &lc.counter
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if lc, ok = v.counters[key]; !ok {
+		lc = &labeledCounter{values: append([]string(nil), values...)}
+		v.counters[key] = lc
+	}
+	return &lc.counter
+}
+
+// Name returns the metric name.
+func (v *CounterVec) Name() string {
+	return v.name
+}
+
+// WriteText writes every counter of the family, ordered by label values.
+func (v *CounterVec) WriteText(w io.Writer) error {
+	v.mu.RLock()
+	keys := make([]string, 0, len(v.counters))
+	for key := range v.counters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	counters := make([]*labeledCounter, 0, len(keys))
+	for _, key := range keys {
+		counters = append(counters, v.counters[key])
+	}
+	v.mu.RUnlock()
+
+	writeHeader(w, v.name, v.help, "counter")
+	for _, lc := range counters {
+		if _, err := fmt.Fprintf(w, "%s{%s} %d\n", v.name, formatLabels(v.labels, lc.values), lc.counter.Value()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric family.
+func writeHeader(w io.Writer, name, help, typ string) {
+	if help != "" {
+		fmt.Fprintf(w, "# HELP %s %s\n", name, escapeHelp(help))
+	}
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+}
+
+// formatLabels formats label pairs as name="value",...
+func formatLabels(names, values []string) string {
+	var sb strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(name)
+		sb.WriteByte('=')
+		sb.WriteByte('"')
+		sb.WriteString(labelEscaper.Replace(values[i]))
+		sb.WriteByte('"')
+	}
+	return sb.String()
+}
+
+// Escapers for HELP text and label values
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// escapeHelp escapes backslashes and newlines in HELP text.
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+// validName reports whether name is a valid metric name.
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_' || c == ':':
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWriteText(t *testing.T) {
+	r := NewRegistry()
+
+	requests := NewCounter("test_requests_total", "Total requests.")
+	active := NewGauge("test_active", "Active things.\nSecond line with \\.")
+	errs := NewCounterVec("test_errors_total", "Errors by kind.", "kind", "code")
+	r.MustRegister(requests, active, errs)
+
+	requests.Add(3)
+	requests.Inc()
+	active.Inc()
+	active.Inc()
+	active.Dec()
+	errs.WithLabelValues("timeout", "1").Inc()
+	errs.WithLabelValues("quote\"d", "2").Add(2)
+	errs.WithLabelValues("timeout", "1").Inc()
+
+	var sb strings.Builder
+	if err := r.WriteText(&sb); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+
+	want := `# HELP test_active Active things.\nSecond line with \\.
+# TYPE test_active gauge
+test_active 1
+# HELP test_errors_total Errors by kind.
+# TYPE test_errors_total counter
+test_errors_total{kind="quote\"d",code="2"} 2
+test_errors_total{kind="timeout",code="1"} 2
+# HELP test_requests_total Total requests.
+# TYPE test_requests_total counter
+test_requests_total 4
+`
+	if sb.String() != want {
+		t.Errorf("WriteText output:\n%s\nwant:\n%s", sb.String(), want)
+	}
+}
+
+func TestRegistryRegisterErrors(t *testing.T) {
+	r := NewRegistry()
+
+	if err := r.Register(NewCounter("dup_total", "")); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := r.Register(NewGauge("dup_total", "")); !errors.Is(err, ErrDuplicateMetric) {
+		t.Errorf("Register duplicate = %v, want %v", err, ErrDuplicateMetric)
+	}
+
+	for _, name := range []string{"", "1abc", "has-dash", "has space"} {
+		if err := r.Register(NewCounter(name, "")); !errors.Is(err, ErrInvalidMetricName) {
+			t.Errorf("Register(%q) = %v, want %v", name, err, ErrInvalidMetricName)
+		}
+	}
+
+	r.Unregister("dup_total")
+	if err := r.Register(NewGauge("dup_total", "")); err != nil {
+		t.Errorf("Register after Unregister failed: %v", err)
+	}
+}
+
+func TestCounterVecLabelCountMismatch(t *testing.T) {
+	v := NewCounterVec("test_total", "", "a", "b")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for wrong number of label values")
+		}
+	}()
+	v.WithLabelValues("only-one")
+}
+
+func TestRegistryServeHTTP(t *testing.T) {
+	r := NewRegistry()
+	c := NewCounter("served_total", "")
+	r.MustRegister(c)
+	c.Inc()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status = %d, want 200", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("Content-Type = %q, want %q", ct, ContentType)
+	}
+	if !strings.Contains(rec.Body.String(), "served_total 1\n") {
+		t.Errorf("Body = %q, want served_total sample", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/metrics", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want 405", rec.Code)
+	}
+}
@@ -4,9 +4,14 @@ package password
 
 import (
 	"crypto/subtle"
+	"errors"
 	"sync"
 )
 
+// ErrGraceLoginsExhausted is returned by IncrGraceLogins when a user has
+// used all grace logins of an expired password.
+var ErrGraceLoginsExhausted = errors.New("password: grace logins exhausted")
+
 // History tracks previous password hashes to prevent reuse.
 // It maintains a fixed-size list of hashed passwords and provides
 // constant-time comparison to check if a password was used before.
@@ -169,11 +174,19 @@ func (h *History) Clone() *History {
 	return clone
 }
 
+// Status is the per-user password state that is not part of the history.
+type Status struct {
+	// GraceLoginsUsed is the number of binds since the password expired
+	GraceLoginsUsed int
+}
+
 // HistoryManager manages password histories for multiple users.
 type HistoryManager struct {
 	mu              sync.RWMutex
 	histories       map[string]*History // DN -> History
+	statuses        map[string]*Status  // DN -> Status
 	defaultMaxCount int
+	graceLogins     int
 }
 
 // NewHistoryManager creates a new history manager with the specified
@@ -184,6 +197,7 @@ func NewHistoryManager(defaultMaxCount int) *HistoryManager {
 	}
 	return &HistoryManager{
 		histories:       make(map[string]*History),
+		statuses:        make(map[string]*Status),
 		defaultMaxCount: defaultMaxCount,
 	}
 }
@@ -304,3 +318,77 @@ func (m *HistoryManager) DefaultMaxCount() int {
 
 	return m.defaultMaxCount
 }
+
+// SetGraceLogins sets the number of grace logins allowed after a password
+// expired.
+func (m *HistoryManager) SetGraceLogins(count int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if count < 0 {
+		count = 0
+	}
+	m.graceLogins = count
+}
+
+// GraceLogins returns the number of grace logins allowed after a password
+// expired.
+func (m *HistoryManager) GraceLogins() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.graceLogins
+}
+
+// GetStatus returns the password status of a user DN and whether one is
+// tracked.
+func (m *HistoryManager) GetStatus(dn string) (Status, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status, exists := m.statuses[normalizeDN(dn)]
+	if !exists {
+		return Status{}, false
+	}
+	return *status, true
+}
+
+// SetStatus sets the password status of a user DN, e.g. when loading it
+// from storage.
+func (m *HistoryManager) SetStatus(dn string, status Status) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.statuses[normalizeDN(dn)] = &status
+}
+
+// IncrGraceLogins uses a grace login of a user DN and returns the number
+// remaining. It returns ErrGraceLoginsExhausted without using one when
+// all grace logins have been used.
+func (m *HistoryManager) IncrGraceLogins(dn string) (remaining int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	normalizedDN := normalizeDN(dn)
+	status, exists := m.statuses[normalizedDN]
+	if !exists {
+		status = &Status{}
+		m.statuses[normalizedDN] = status
+	}
+
+	if status.GraceLoginsUsed >= m.graceLogins {
+		return 0, ErrGraceLoginsExhausted
+	}
+	status.GraceLoginsUsed++
+
+	return m.graceLogins - status.GraceLoginsUsed, nil
+}
+
+// ResetGraceLogins clears the used grace logins of a user DN, typically
+// after the password was changed.
+func (m *HistoryManager) ResetGraceLogins(dn string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.statuses, normalizeDN(dn))
+}
@@ -455,3 +455,56 @@ func TestHistoryManagerConcurrency(t *testing.T) {
 		<-done
 	}
 }
+
+func TestHistoryManagerIncrGraceLogins(t *testing.T) {
+	m := NewHistoryManager(5)
+	m.SetGraceLogins(2)
+	dn := "uid=alice,dc=example,dc=com"
+
+	remaining, err := m.IncrGraceLogins(dn)
+	if err != nil || remaining != 1 {
+		t.Fatalf("first grace login: remaining %d, err %v; want 1, nil", remaining, err)
+	}
+	remaining, err = m.IncrGraceLogins("UID=Alice,DC=Example,DC=Com")
+	if err != nil || remaining != 0 {
+		t.Fatalf("second grace login: remaining %d, err %v; want 0, nil", remaining, err)
+	}
+	if _, err := m.IncrGraceLogins(dn); err != ErrGraceLoginsExhausted {
+		t.Fatalf("third grace login: err %v, want ErrGraceLoginsExhausted", err)
+	}
+
+	status, ok := m.GetStatus(dn)
+	if !ok || status.GraceLoginsUsed != 2 {
+		t.Errorf("status = %+v, %v; want 2 grace logins used", status, ok)
+	}
+
+	m.ResetGraceLogins(dn)
+	if _, ok := m.GetStatus(dn); ok {
+		t.Error("expected no status after reset")
+	}
+	if _, err := m.IncrGraceLogins(dn); err != nil {
+		t.Errorf("grace login after reset: %v", err)
+	}
+}
+
+func TestHistoryManagerSetStatus(t *testing.T) {
+	m := NewHistoryManager(0)
+	m.SetGraceLogins(3)
+	dn := "uid=bob,dc=example,dc=com"
+
+	m.SetStatus(dn, Status{GraceLoginsUsed: 2})
+	remaining, err := m.IncrGraceLogins(dn)
+	if err != nil || remaining != 0 {
+		t.Fatalf("grace login: remaining %d, err %v; want 0, nil", remaining, err)
+	}
+	if _, err := m.IncrGraceLogins(dn); err != ErrGraceLoginsExhausted {
+		t.Errorf("err = %v, want ErrGraceLoginsExhausted", err)
+	}
+
+	t.Run("no grace logins", func(t *testing.T) {
+		m := NewHistoryManager(0)
+		if _, err := m.IncrGraceLogins(dn); err != ErrGraceLoginsExhausted {
+			t.Errorf("err = %v, want ErrGraceLoginsExhausted", err)
+		}
+	})
+}
@@ -3,8 +3,9 @@
 package password
 
 import (
-	"strings"
 	"sync"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
 )
 
 // Manager handles password policy management with support for
@@ -149,7 +150,7 @@ func (m *Manager) ClearUserPolicies() {
 // normalizeDN normalizes a DN for consistent map lookups.
 // It converts to lowercase and trims whitespace.
 func normalizeDN(dn string) string {
-	// Normalize by converting to lowercase and trimming spaces
+	// Normalize to the canonical form the directory keys entries by
 	// This ensures consistent lookups regardless of case variations
-	return strings.ToLower(strings.TrimSpace(dn))
+	return ldap.CanonicalDN(dn)
 }
@@ -34,6 +34,9 @@ type Policy struct {
 	// MaxAge is the maximum password age before expiration (0 = never expires)
 	MaxAge time.Duration
 
+	// GraceLogins is the number of binds allowed after the password expired
+	GraceLogins int
+
 	// MinAge is the minimum time before a password can be changed
 	MinAge time.Duration
 
@@ -296,6 +299,10 @@ func (p *Policy) Merge(override *Policy) *Policy {
 		result.MinAge = override.MinAge
 	}
 
+	if override.GraceLogins > 0 {
+		result.GraceLogins = override.GraceLogins
+	}
+
 	if override.HistoryCount > 0 {
 		result.HistoryCount = override.HistoryCount
 	}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/KilimcininKorOglu/oba/internal/config"
 	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
 )
 
 // ClusterBackend wraps a storage engine with Raft consensus for cluster mode.
@@ -19,11 +20,15 @@ type ClusterBackend struct {
 	logEngine    storage.StorageEngine
 	stateMachine *ObaDBStateMachine
 	node         *Node
+	lock         *DistributedLock
 	config       *config.ClusterConfig
 	transport    Transport
 	snapStore    *SnapshotStore
 	logger       Logger
 
+	// Read routing
+	readPreference ReadPreference
+
 	// Config and ACL appliers for replication
 	configApplier ConfigApplier
 	aclApplier    ACLApplier
@@ -69,8 +74,14 @@ func NewClusterBackend(cfg *ClusterBackendConfig) (*ClusterBackend, error) {
 		return nil, err
 	}
 
+	readPreference, err := ParseReadPreference(cc.ReadPreference)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, cc.ReadPreference)
+	}
+
 	// Create state machine
 	stateMachine := NewObaDBStateMachine(cfg.Engine)
+	stateMachine.SetMaxStalenessLog(cc.MaxStalenessLog)
 
 	// Create Raft node config
 	peers := make([]*Peer, len(cc.Peers))
@@ -102,21 +113,29 @@ func NewClusterBackend(cfg *ClusterBackendConfig) (*ClusterBackend, error) {
 	if err != nil {
 		return nil, err
 	}
+	stateMachine.SetReadRouter(node)
 
 	cb := &ClusterBackend{
 		engine:         cfg.Engine,
 		stateMachine:   stateMachine,
 		node:           node,
+		lock:           NewDistributedLock(node),
 		config:         cc,
 		transport:      transport,
 		snapStore:      snapStore,
 		logger:         &defaultLogger{},
 		onLeaderChange: cfg.OnLeaderChange,
+		readPreference: readPreference,
 	}
 
 	return cb, nil
 }
 
+// Lock returns the distributed lock of the cluster.
+func (cb *ClusterBackend) Lock() *DistributedLock {
+	return cb.lock
+}
+
 // SetLogger sets the logger for the cluster backend.
 func (cb *ClusterBackend) SetLogger(logger Logger) {
 	cb.mu.Lock()
@@ -226,6 +245,17 @@ func (cb *ClusterBackend) Put(entry *storage.Entry) error {
 		return err
 	}
 
+	// The leader picks the entryUUID of a new entry so that every node
+	// stores the same one; an existing entry keeps its own when applied
+	if !entry.HasAttribute(engine.EntryUUIDAttribute) {
+		uuid, err := engine.NewEntryUUID()
+		if err != nil {
+			return err
+		}
+		entry = entry.Clone()
+		entry.SetStringAttribute(engine.EntryUUIDAttribute, uuid)
+	}
+
 	cmd := CreatePutCommand(entry)
 	return cb.node.Propose(cmd)
 }
@@ -274,27 +304,33 @@ func (cb *ClusterBackend) DeleteLog(dn string) error {
 	return cb.node.Propose(cmd)
 }
 
-// Get retrieves an entry from local storage.
-// Reads are served locally for performance.
+// Get retrieves an entry. The configured read preference decides whether
+// it is served locally or by the leader.
 func (cb *ClusterBackend) Get(dn string) (*storage.Entry, error) {
-	tx, err := cb.engine.Begin()
-	if err != nil {
-		return nil, err
-	}
-	defer cb.engine.Rollback(tx)
-
-	return cb.engine.Get(tx, dn)
+	return cb.stateMachine.Read([]byte(dn), cb.readPreference)
 }
 
-// Search searches entries from local storage.
-// Reads are served locally for performance.
+// Search searches entries under baseDN.
+// The configured read preference decides whether it is served locally or by the leader.
 func (cb *ClusterBackend) Search(baseDN string, scope storage.Scope) storage.Iterator {
-	tx, err := cb.engine.Begin()
-	if err != nil {
-		return &emptyIterator{err: err}
-	}
-	// Note: Transaction will be held until iterator is closed
-	return cb.engine.SearchByDN(tx, baseDN, scope)
+	return cb.SearchByDN(baseDN, scope)
+}
+
+// SearchByDN searches entries under baseDN in the given scope, routed
+// according to the configured read preference.
+func (cb *ClusterBackend) SearchByDN(baseDN string, scope storage.Scope) storage.Iterator {
+	return cb.stateMachine.SearchByDN(baseDN, scope, cb.readPreference)
+}
+
+// SearchByFilter searches entries under baseDN matching f, routed according
+// to the configured read preference.
+func (cb *ClusterBackend) SearchByFilter(baseDN string, f storage.FilterMatcher) storage.Iterator {
+	return cb.stateMachine.SearchByFilter(baseDN, f, cb.readPreference)
+}
+
+// ReadPreference returns the read preference used for Get and searches.
+func (cb *ClusterBackend) ReadPreference() ReadPreference {
+	return cb.readPreference
 }
 
 // ClusterStatus returns the current cluster status.
@@ -348,6 +384,30 @@ func (i *emptyIterator) Entry() *storage.Entry { return nil }
 func (i *emptyIterator) Error() error          { return i.err }
 func (i *emptyIterator) Close()                {}
 
+// sliceIterator iterates over the buffered results of a forwarded read.
+type sliceIterator struct {
+	entries []*storage.Entry
+	index   int
+}
+
+func (i *sliceIterator) Next() bool {
+	if i.index < len(i.entries) {
+		i.index++
+		return true
+	}
+	return false
+}
+
+func (i *sliceIterator) Entry() *storage.Entry {
+	if i.index > 0 && i.index <= len(i.entries) {
+		return i.entries[i.index-1]
+	}
+	return nil
+}
+
+func (i *sliceIterator) Error() error { return nil }
+func (i *sliceIterator) Close()       {}
+
 // ProposeConfigChange proposes a config change through Raft consensus.
 // Only the leader can accept config changes.
 func (cb *ClusterBackend) ProposeConfigChange(section string, data map[string]string, version uint64) error {
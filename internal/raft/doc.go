@@ -54,6 +54,28 @@
 //   - Committed entries are never lost
 //   - All nodes see the same order of committed entries
 //
+// # Read Preference
+//
+// Reads through ObaDBStateMachine.Read and ClusterBackend searches are routed
+// by ReadPreference:
+//   - PreferLeader: followers forward reads to the leader over RPCRead
+//   - PreferFollower: followers read locally while within MaxStalenessLog
+//     entries of the leader's commit index, otherwise forward to the leader
+//   - AnyNode: every node reads locally
+//
+// # Distributed Locks
+//
+// DistributedLock provides cluster-wide mutual exclusion. Acquire and Release
+// append CmdLock and CmdUnlock entries, forwarded to the leader over RPCLock
+// when proposed on a follower. ObaDBStateMachine keeps the lock table and
+// judges expiry against the time the leader stamped on each entry:
+//
+//	lock := raft.NewDistributedLock(node)
+//	if err := lock.Acquire("schema", "node1/42", 30*time.Second); err != nil {
+//	    return err // ErrLockHeld if another holder owns the lock
+//	}
+//	defer lock.Release("schema", "node1/42")
+//
 // # Failure Handling
 //
 // The cluster can tolerate (N-1)/2 failures for N nodes:
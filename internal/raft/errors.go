@@ -34,6 +34,25 @@ var (
 	// ErrTimeout is returned when an operation times out.
 	ErrTimeout = errors.New("raft: operation timeout")
 
+	// ErrReadFailed is returned when a read forwarded to the leader fails.
+	ErrReadFailed = errors.New("raft: read failed")
+
+	// ErrInvalidReadPreference is returned for an unknown read preference name.
+	ErrInvalidReadPreference = errors.New("raft: invalid read preference")
+
 	// ErrInvalidConfig is returned when configuration is invalid.
 	ErrInvalidConfig = errors.New("raft: invalid configuration")
+
+	// ErrLockHeld is returned when a lock is held by another holder.
+	ErrLockHeld = errors.New("raft: lock held by another holder")
+
+	// ErrLockNotHeld is returned when releasing a lock held by another holder.
+	ErrLockNotHeld = errors.New("raft: lock not held")
+
+	// ErrInvalidLock is returned for a lock request without a resource or
+	// holder, or with a non-positive TTL.
+	ErrInvalidLock = errors.New("raft: invalid lock request")
+
+	// ErrLockFailed is returned when a lock request forwarded to the leader fails.
+	ErrLockFailed = errors.New("raft: lock request failed")
 )
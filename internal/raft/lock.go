@@ -0,0 +1,209 @@
+package raft
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// LockEntry is the state of a distributed lock.
+type LockEntry struct {
+	Holder  string    // Identifies the lock holder
+	Expires time.Time // The lock is free after this time
+}
+
+// DistributedLock provides cluster-wide mutual exclusion through the Raft log.
+// Lock and unlock commands are appended to the log and applied by every
+// node's ObaDBStateMachine, so all nodes agree on the holder of a lock.
+//
+// Commands proposed on a follower are forwarded to the leader. The leader
+// stamps each command with its time, and expiry is judged against that
+// time when the command is applied, so replicas never depend on their own
+// clocks.
+type DistributedLock struct {
+	node *Node
+}
+
+// NewDistributedLock creates a distributed lock on top of node.
+func NewDistributedLock(node *Node) *DistributedLock {
+	return &DistributedLock{node: node}
+}
+
+// Acquire takes the lock on resource for holder and waits until the lock
+// command is committed. The lock expires ttl after the leader accepted the
+// command unless it is acquired again by the same holder, which renews it.
+//
+// Returns ErrLockHeld if another holder owns an unexpired lock on resource.
+func (l *DistributedLock) Acquire(resource, holder string, ttl time.Duration) error {
+	if resource == "" || holder == "" || ttl <= 0 {
+		return ErrInvalidLock
+	}
+
+	cmd, err := CreateLockCommand(resource, holder, ttl)
+	if err != nil {
+		return err
+	}
+	return l.propose(cmd)
+}
+
+// Release releases the lock on resource held by holder and waits until the
+// unlock command is committed. Releasing a lock that is not held is a no-op.
+//
+// Returns ErrLockNotHeld if resource is locked by another holder.
+func (l *DistributedLock) Release(resource, holder string) error {
+	if resource == "" || holder == "" {
+		return ErrInvalidLock
+	}
+
+	cmd, err := CreateUnlockCommand(resource, holder)
+	if err != nil {
+		return err
+	}
+	return l.propose(cmd)
+}
+
+// propose proposes cmd on the leader, forwarding it when this node is a
+// follower.
+func (l *DistributedLock) propose(cmd *Command) error {
+	if l.node.IsLeader() {
+		return l.node.proposeLock(cmd)
+	}
+	return l.node.ForwardLock(cmd)
+}
+
+// proposeLock stamps a lock command with the leader's time and proposes it.
+func (n *Node) proposeLock(cmd *Command) error {
+	lockCmd, err := DeserializeLockCommand(cmd.LockData)
+	if err != nil {
+		return err
+	}
+	lockCmd.Time = time.Now().UnixNano()
+
+	stamped := *cmd
+	if stamped.LockData, err = SerializeLockCommand(lockCmd); err != nil {
+		return err
+	}
+	return n.Propose(&stamped)
+}
+
+// handleLock serves a lock command forwarded by a follower. Only the leader
+// answers.
+func (n *Node) handleLock(data []byte) []byte {
+	cmd, err := DeserializeCommand(data)
+	if err != nil {
+		return (&LockReply{Status: LockStatusError, Error: err.Error()}).Serialize()
+	}
+	if cmd.Type != CmdLock && cmd.Type != CmdUnlock {
+		return (&LockReply{Status: LockStatusError, Error: "not a lock command"}).Serialize()
+	}
+
+	if !n.IsLeader() {
+		return (&LockReply{Status: LockStatusNotLeader}).Serialize()
+	}
+
+	err = n.proposeLock(cmd)
+	switch {
+	case err == nil:
+		return (&LockReply{Status: LockStatusOK}).Serialize()
+	case errors.Is(err, ErrLockHeld):
+		return (&LockReply{Status: LockStatusHeld}).Serialize()
+	case errors.Is(err, ErrLockNotHeld):
+		return (&LockReply{Status: LockStatusNotHeld}).Serialize()
+	case errors.Is(err, ErrNotLeader):
+		return (&LockReply{Status: LockStatusNotLeader}).Serialize()
+	default:
+		return (&LockReply{Status: LockStatusError, Error: err.Error()}).Serialize()
+	}
+}
+
+// ForwardLock proposes a lock or unlock command on the current leader.
+func (n *Node) ForwardLock(cmd *Command) error {
+	leaderID := n.LeaderID()
+	if leaderID == 0 {
+		return ErrLeaderUnknown
+	}
+	if leaderID == n.id {
+		return ErrNotLeader
+	}
+
+	data, err := cmd.Serialize()
+	if err != nil {
+		return err
+	}
+	resp, err := n.transport.Send(leaderID, RPCLock, data)
+	if err != nil {
+		return err
+	}
+	reply, err := DeserializeLockReply(resp)
+	if err != nil {
+		return err
+	}
+
+	switch reply.Status {
+	case LockStatusOK:
+		return nil
+	case LockStatusHeld:
+		return ErrLockHeld
+	case LockStatusNotHeld:
+		return ErrLockNotHeld
+	case LockStatusNotLeader:
+		return ErrNotLeader
+	default:
+		return fmt.Errorf("%w: %s", ErrLockFailed, reply.Error)
+	}
+}
+
+// applyLockCommand applies lock and unlock commands. Expired locks are
+// dropped whenever a lock command is applied.
+func (sm *ObaDBStateMachine) applyLockCommand(cmd *Command) error {
+	lockCmd, err := DeserializeLockCommand(cmd.LockData)
+	if err != nil {
+		return fmt.Errorf("failed to deserialize lock command: %w", err)
+	}
+	now := time.Unix(0, lockCmd.Time)
+
+	if sm.locks == nil {
+		sm.locks = make(map[string]LockEntry)
+	}
+	for resource, lock := range sm.locks {
+		if !now.Before(lock.Expires) {
+			delete(sm.locks, resource)
+		}
+	}
+
+	current, held := sm.locks[lockCmd.Resource]
+	switch cmd.Type {
+	case CmdLock:
+		if held && current.Holder != lockCmd.Holder {
+			return NewApplyResultError(ApplyResultRejectConflict, ErrLockHeld)
+		}
+		sm.locks[lockCmd.Resource] = LockEntry{
+			Holder:  lockCmd.Holder,
+			Expires: now.Add(lockCmd.TTL),
+		}
+
+	case CmdUnlock:
+		if !held {
+			return NewApplyResultError(ApplyResultIdempotent, nil)
+		}
+		if current.Holder != lockCmd.Holder {
+			return NewApplyResultError(ApplyResultRejectConflict, ErrLockNotHeld)
+		}
+		delete(sm.locks, lockCmd.Resource)
+	}
+
+	return nil
+}
+
+// Locks returns a copy of the lock table. Locks that expired since the
+// last lock command was applied may still be listed.
+func (sm *ObaDBStateMachine) Locks() map[string]LockEntry {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	locks := make(map[string]LockEntry, len(sm.locks))
+	for resource, lock := range sm.locks {
+		locks[resource] = lock
+	}
+	return locks
+}
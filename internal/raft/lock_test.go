@@ -0,0 +1,253 @@
+package raft
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newLockTestCluster starts a 3-node cluster backed by ObaDBStateMachine and
+// waits until every node knows the leader.
+func newLockTestCluster(t *testing.T) (*TestCluster, []*ObaDBStateMachine) {
+	t.Helper()
+
+	var sms []*ObaDBStateMachine
+	cluster := newTestClusterWith(3, func() StateMachine {
+		sm := NewObaDBStateMachine(NewMockStorageEngine())
+		sms = append(sms, sm)
+		return sm
+	})
+	cluster.Start()
+	t.Cleanup(cluster.Stop)
+
+	if cluster.WaitForLeader(2*time.Second) == nil {
+		t.Fatal("No leader elected")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for _, node := range cluster.nodes {
+		for node.LeaderID() == 0 {
+			if time.Now().After(deadline) {
+				t.Fatalf("node %d does not know the leader", node.ID())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	return cluster, sms
+}
+
+// acquireWithRetry retries Acquire while the cluster changes leaders.
+func acquireWithRetry(l *DistributedLock, resource, holder string, ttl time.Duration) error {
+	for i := 0; ; i++ {
+		err := l.Acquire(resource, holder, ttl)
+		if (errors.Is(err, ErrNotLeader) || errors.Is(err, ErrLeaderUnknown)) && i < 50 {
+			time.Sleep(20 * time.Millisecond)
+			continue
+		}
+		return err
+	}
+}
+
+func TestDistributedLockMutualExclusion(t *testing.T) {
+	cluster, sms := newLockTestCluster(t)
+
+	locks := make([]*DistributedLock, len(cluster.nodes))
+	for i, node := range cluster.nodes {
+		locks[i] = NewDistributedLock(node)
+	}
+
+	for round := 0; round < 5; round++ {
+		errs := make([]error, len(locks))
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		for i, l := range locks {
+			wg.Add(1)
+			go func(i int, l *DistributedLock) {
+				defer wg.Done()
+				<-start
+				errs[i] = acquireWithRetry(l, "schema", fmt.Sprintf("node%d", i+1), time.Minute)
+			}(i, l)
+		}
+		close(start)
+		wg.Wait()
+
+		winner := -1
+		for i, err := range errs {
+			switch {
+			case err == nil:
+				if winner >= 0 {
+					t.Fatalf("round %d: node%d and node%d both acquired the lock", round, winner+1, i+1)
+				}
+				winner = i
+			case !errors.Is(err, ErrLockHeld):
+				t.Fatalf("round %d: node%d Acquire() error = %v, want ErrLockHeld", round, i+1, err)
+			}
+		}
+		if winner < 0 {
+			t.Fatalf("round %d: no node acquired the lock", round)
+		}
+
+		// Every replica agrees on the holder
+		holder := fmt.Sprintf("node%d", winner+1)
+		deadline := time.Now().Add(2 * time.Second)
+		for _, sm := range sms {
+			for sm.Locks()["schema"].Holder != holder {
+				if time.Now().After(deadline) {
+					t.Fatalf("round %d: replica holder = %q, want %q", round, sm.Locks()["schema"].Holder, holder)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+
+		if err := locks[winner].Release("schema", holder); err != nil {
+			t.Fatalf("round %d: Release() error = %v", round, err)
+		}
+	}
+}
+
+func TestDistributedLockRelease(t *testing.T) {
+	cluster, _ := newLockTestCluster(t)
+	l := NewDistributedLock(cluster.nodes[0])
+
+	if err := acquireWithRetry(l, "index", "a", time.Minute); err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	// Acquiring again renews the lock
+	if err := l.Acquire("index", "a", time.Minute); err != nil {
+		t.Fatalf("Acquire() renewal error = %v", err)
+	}
+	if err := l.Release("index", "b"); !errors.Is(err, ErrLockNotHeld) {
+		t.Fatalf("Release() by another holder error = %v, want ErrLockNotHeld", err)
+	}
+	if err := l.Release("index", "a"); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if err := l.Release("index", "a"); err != nil {
+		t.Fatalf("Release() of a free lock error = %v", err)
+	}
+	if err := l.Acquire("index", "b", time.Minute); err != nil {
+		t.Fatalf("Acquire() after release error = %v", err)
+	}
+}
+
+func TestDistributedLockExpiry(t *testing.T) {
+	cluster, _ := newLockTestCluster(t)
+	l := NewDistributedLock(cluster.nodes[1])
+
+	if err := acquireWithRetry(l, "schema", "a", 50*time.Millisecond); err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	if err := l.Acquire("schema", "b", time.Minute); !errors.Is(err, ErrLockHeld) {
+		t.Fatalf("Acquire() error = %v, want ErrLockHeld", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if err := l.Acquire("schema", "b", time.Minute); err != nil {
+		t.Fatalf("Acquire() after expiry error = %v", err)
+	}
+}
+
+func TestDistributedLockInvalid(t *testing.T) {
+	l := NewDistributedLock(nil)
+	if err := l.Acquire("", "a", time.Second); err != ErrInvalidLock {
+		t.Errorf("Acquire() without resource error = %v, want ErrInvalidLock", err)
+	}
+	if err := l.Acquire("schema", "a", 0); err != ErrInvalidLock {
+		t.Errorf("Acquire() without TTL error = %v, want ErrInvalidLock", err)
+	}
+	if err := l.Release("schema", ""); err != ErrInvalidLock {
+		t.Errorf("Release() without holder error = %v, want ErrInvalidLock", err)
+	}
+}
+
+// lockCommandAt builds a lock command stamped with the given time.
+func lockCommandAt(t *testing.T, cmdType uint8, resource, holder string, ttl time.Duration, at time.Time) *Command {
+	t.Helper()
+	data, err := SerializeLockCommand(&LockCommand{
+		Resource: resource,
+		Holder:   holder,
+		TTL:      ttl,
+		Time:     at.UnixNano(),
+	})
+	if err != nil {
+		t.Fatalf("SerializeLockCommand() error = %v", err)
+	}
+	return &Command{Type: cmdType, LockData: data}
+}
+
+func TestObaDBStateMachineApplyLock(t *testing.T) {
+	sm := NewObaDBStateMachine(NewMockStorageEngine())
+	t0 := time.Unix(1000, 0)
+
+	steps := []struct {
+		name string
+		cmd  *Command
+		want ApplyResult
+	}{
+		{"acquire", lockCommandAt(t, CmdLock, "schema", "a", time.Second, t0), ApplyResultApplied},
+		{"conflict", lockCommandAt(t, CmdLock, "schema", "b", time.Second, t0.Add(500*time.Millisecond)), ApplyResultRejectConflict},
+		{"renew", lockCommandAt(t, CmdLock, "schema", "a", time.Second, t0.Add(900*time.Millisecond)), ApplyResultApplied},
+		{"still held", lockCommandAt(t, CmdLock, "schema", "b", time.Second, t0.Add(1500*time.Millisecond)), ApplyResultRejectConflict},
+		{"expired", lockCommandAt(t, CmdLock, "schema", "b", time.Second, t0.Add(1900*time.Millisecond)), ApplyResultApplied},
+		{"release other", lockCommandAt(t, CmdUnlock, "schema", "a", 0, t0.Add(2*time.Second)), ApplyResultRejectConflict},
+		{"release", lockCommandAt(t, CmdUnlock, "schema", "b", 0, t0.Add(2*time.Second)), ApplyResultApplied},
+		{"release free", lockCommandAt(t, CmdUnlock, "schema", "b", 0, t0.Add(2*time.Second)), ApplyResultIdempotent},
+	}
+	for _, step := range steps {
+		if got := ApplyResultFromError(sm.Apply(step.cmd)); got != step.want {
+			t.Fatalf("%s: result = %v, want %v", step.name, got, step.want)
+		}
+	}
+	if len(sm.Locks()) != 0 {
+		t.Errorf("Locks() = %v, want none", sm.Locks())
+	}
+}
+
+func TestObaDBStateMachineSnapshotLocks(t *testing.T) {
+	sm1 := NewObaDBStateMachine(NewMockStorageEngine())
+	expires := time.Unix(2000, 0)
+	if err := sm1.Apply(lockCommandAt(t, CmdLock, "schema", "a", time.Second, expires.Add(-time.Second))); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	data, err := sm1.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	sm2 := NewObaDBStateMachine(NewMockStorageEngine())
+	if err := sm2.Restore(data); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	lock, ok := sm2.Locks()["schema"]
+	if !ok || lock.Holder != "a" || !lock.Expires.Equal(expires) {
+		t.Errorf("restored lock = %+v, %v, want holder a expiring at %v", lock, ok, expires)
+	}
+}
+
+func TestLockCommandSerialization(t *testing.T) {
+	cmd, err := CreateLockCommand("schema", "node1", 5*time.Second)
+	if err != nil {
+		t.Fatalf("CreateLockCommand() error = %v", err)
+	}
+	data, err := cmd.Serialize()
+	if err != nil {
+		t.Fatalf("Serialize() error = %v", err)
+	}
+	decoded, err := DeserializeCommand(data)
+	if err != nil {
+		t.Fatalf("DeserializeCommand() error = %v", err)
+	}
+	if decoded.Type != CmdLock {
+		t.Errorf("Type = %d, want CmdLock", decoded.Type)
+	}
+
+	lockCmd, err := DeserializeLockCommand(decoded.LockData)
+	if err != nil {
+		t.Fatalf("DeserializeLockCommand() error = %v", err)
+	}
+	if lockCmd.Resource != "schema" || lockCmd.Holder != "node1" || lockCmd.TTL != 5*time.Second {
+		t.Errorf("LockCommand = %+v", lockCmd)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 )
 
 // Log entry types.
@@ -69,6 +70,8 @@ const (
 	CmdACLUpdateRule              // Update single ACL rule
 	CmdACLDeleteRule              // Delete single ACL rule
 	CmdACLSetDefault              // Set default ACL policy
+	CmdLock                       // Acquire or renew a distributed lock
+	CmdUnlock                     // Release a distributed lock
 )
 
 // Database IDs for multi-database support.
@@ -169,6 +172,21 @@ type ACLRuleData struct {
 	Rights     []string // Rights: "read", "write", "add", "delete", "search", "compare", "all"
 	Attributes []string // Attribute filter (empty = all)
 	Deny       bool     // Deny rule flag
+
+	SourceCIDRs []string // Client networks (empty = any)
+	RequireTLS  bool     // Require a TLS-protected connection
+	MinSSF      int      // Minimum security strength factor (0 = none)
+	AuthMethod  string   // Required bind method (empty = any)
+
+	TimeRange *ACLTimeRangeData // Daily window the rule applies in (nil = always)
+}
+
+// ACLTimeRangeData represents the time range of an ACL rule for serialization.
+type ACLTimeRangeData struct {
+	Start    string   // Opening clock time, HH:MM or HH:MM:SS
+	End      string   // Closing clock time, HH:MM or HH:MM:SS
+	Weekdays []string // Weekday names (empty = every day)
+	Timezone string   // IANA time zone name (empty = local time)
 }
 
 // ACLCommand represents an ACL update command for Raft replication.
@@ -222,6 +240,31 @@ func SerializeACLCommand(cmd *ACLCommand) ([]byte, error) {
 		}
 	}
 
+	// Connection constraints follow all rules, so that commands written
+	// before they existed still decode
+	for i := range cmd.Rules {
+		if err := serializeACLConstraints(&buf, &cmd.Rules[i]); err != nil {
+			return nil, err
+		}
+	}
+	if hasRule {
+		if err := serializeACLConstraints(&buf, cmd.Rule); err != nil {
+			return nil, err
+		}
+	}
+
+	// Time ranges follow the constraints for the same reason
+	for i := range cmd.Rules {
+		if err := serializeACLTimeRange(&buf, cmd.Rules[i].TimeRange); err != nil {
+			return nil, err
+		}
+	}
+	if hasRule {
+		if err := serializeACLTimeRange(&buf, cmd.Rule.TimeRange); err != nil {
+			return nil, err
+		}
+	}
+
 	return buf.Bytes(), nil
 }
 
@@ -281,6 +324,34 @@ func DeserializeACLCommand(data []byte) (*ACLCommand, error) {
 		}
 	}
 
+	// Connection constraints, absent in older commands
+	if buf.Len() > 0 {
+		for i := range cmd.Rules {
+			if err := deserializeACLConstraints(buf, &cmd.Rules[i]); err != nil {
+				return nil, err
+			}
+		}
+		if hasRule {
+			if err := deserializeACLConstraints(buf, cmd.Rule); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Time ranges, absent in older commands
+	if buf.Len() > 0 {
+		for i := range cmd.Rules {
+			if cmd.Rules[i].TimeRange, err = deserializeACLTimeRange(buf); err != nil {
+				return nil, err
+			}
+		}
+		if hasRule {
+			if cmd.Rule.TimeRange, err = deserializeACLTimeRange(buf); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	return cmd, nil
 }
 
@@ -384,9 +455,219 @@ func deserializeACLRule(buf *bytes.Reader) (*ACLRuleData, error) {
 	return rule, nil
 }
 
+func serializeACLConstraints(buf *bytes.Buffer, rule *ACLRuleData) error {
+	// Source CIDRs count and values
+	if err := binary.Write(buf, binary.LittleEndian, uint16(len(rule.SourceCIDRs))); err != nil {
+		return err
+	}
+	for _, c := range rule.SourceCIDRs {
+		if err := writeString(buf, c); err != nil {
+			return err
+		}
+	}
+
+	// RequireTLS flag
+	if err := binary.Write(buf, binary.LittleEndian, rule.RequireTLS); err != nil {
+		return err
+	}
+
+	// MinSSF
+	if err := binary.Write(buf, binary.LittleEndian, int32(rule.MinSSF)); err != nil {
+		return err
+	}
+
+	// AuthMethod
+	return writeString(buf, rule.AuthMethod)
+}
+
+func deserializeACLConstraints(buf *bytes.Reader, rule *ACLRuleData) error {
+	// Source CIDRs
+	var cidrCount uint16
+	if err := binary.Read(buf, binary.LittleEndian, &cidrCount); err != nil {
+		return ErrLogCorrupted
+	}
+	if cidrCount > 0 {
+		rule.SourceCIDRs = make([]string, cidrCount)
+	}
+	for i := uint16(0); i < cidrCount; i++ {
+		c, err := readString(buf)
+		if err != nil {
+			return ErrLogCorrupted
+		}
+		rule.SourceCIDRs[i] = c
+	}
+
+	// RequireTLS flag
+	if err := binary.Read(buf, binary.LittleEndian, &rule.RequireTLS); err != nil {
+		return ErrLogCorrupted
+	}
+
+	// MinSSF
+	var minSSF int32
+	if err := binary.Read(buf, binary.LittleEndian, &minSSF); err != nil {
+		return ErrLogCorrupted
+	}
+	rule.MinSSF = int(minSSF)
+
+	// AuthMethod
+	var err error
+	rule.AuthMethod, err = readString(buf)
+	if err != nil {
+		return ErrLogCorrupted
+	}
+
+	return nil
+}
+
+func serializeACLTimeRange(buf *bytes.Buffer, tr *ACLTimeRangeData) error {
+	// Presence flag
+	if err := binary.Write(buf, binary.LittleEndian, tr != nil); err != nil {
+		return err
+	}
+	if tr == nil {
+		return nil
+	}
+
+	// Start and End
+	if err := writeString(buf, tr.Start); err != nil {
+		return err
+	}
+	if err := writeString(buf, tr.End); err != nil {
+		return err
+	}
+
+	// Weekdays count and values
+	if err := binary.Write(buf, binary.LittleEndian, uint16(len(tr.Weekdays))); err != nil {
+		return err
+	}
+	for _, d := range tr.Weekdays {
+		if err := writeString(buf, d); err != nil {
+			return err
+		}
+	}
+
+	// Timezone
+	return writeString(buf, tr.Timezone)
+}
+
+func deserializeACLTimeRange(buf *bytes.Reader) (*ACLTimeRangeData, error) {
+	// Presence flag
+	var hasTimeRange bool
+	if err := binary.Read(buf, binary.LittleEndian, &hasTimeRange); err != nil {
+		return nil, ErrLogCorrupted
+	}
+	if !hasTimeRange {
+		return nil, nil
+	}
+
+	tr := &ACLTimeRangeData{}
+	var err error
+
+	// Start and End
+	if tr.Start, err = readString(buf); err != nil {
+		return nil, ErrLogCorrupted
+	}
+	if tr.End, err = readString(buf); err != nil {
+		return nil, ErrLogCorrupted
+	}
+
+	// Weekdays
+	var dayCount uint16
+	if err := binary.Read(buf, binary.LittleEndian, &dayCount); err != nil {
+		return nil, ErrLogCorrupted
+	}
+	if dayCount > 0 {
+		tr.Weekdays = make([]string, dayCount)
+	}
+	for i := uint16(0); i < dayCount; i++ {
+		if tr.Weekdays[i], err = readString(buf); err != nil {
+			return nil, ErrLogCorrupted
+		}
+	}
+
+	// Timezone
+	if tr.Timezone, err = readString(buf); err != nil {
+		return nil, ErrLogCorrupted
+	}
+
+	return tr, nil
+}
+
+// LockCommand represents a distributed lock command for Raft replication.
+type LockCommand struct {
+	Resource string        // Name of the locked resource
+	Holder   string        // Identifies the lock holder
+	TTL      time.Duration // Lock lifetime from Time (for CmdLock)
+	Time     int64         // Proposal time in Unix nanoseconds, set by the leader
+}
+
+// SerializeLockCommand encodes a LockCommand to bytes.
+func SerializeLockCommand(cmd *LockCommand) ([]byte, error) {
+	var buf bytes.Buffer
+
+	// Resource
+	if err := writeString(&buf, cmd.Resource); err != nil {
+		return nil, err
+	}
+
+	// Holder
+	if err := writeString(&buf, cmd.Holder); err != nil {
+		return nil, err
+	}
+
+	// TTL
+	if err := binary.Write(&buf, binary.LittleEndian, int64(cmd.TTL)); err != nil {
+		return nil, err
+	}
+
+	// Time
+	if err := binary.Write(&buf, binary.LittleEndian, cmd.Time); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// DeserializeLockCommand decodes a LockCommand from bytes.
+func DeserializeLockCommand(data []byte) (*LockCommand, error) {
+	if len(data) < 2 {
+		return nil, ErrLogCorrupted
+	}
+
+	buf := bytes.NewReader(data)
+	cmd := &LockCommand{}
+
+	// Resource
+	var err error
+	cmd.Resource, err = readString(buf)
+	if err != nil {
+		return nil, ErrLogCorrupted
+	}
+
+	// Holder
+	cmd.Holder, err = readString(buf)
+	if err != nil {
+		return nil, ErrLogCorrupted
+	}
+
+	// TTL
+	var ttl int64
+	if err := binary.Read(buf, binary.LittleEndian, &ttl); err != nil {
+		return nil, ErrLogCorrupted
+	}
+	cmd.TTL = time.Duration(ttl)
+
+	// Time
+	if err := binary.Read(buf, binary.LittleEndian, &cmd.Time); err != nil {
+		return nil, ErrLogCorrupted
+	}
+
+	return cmd, nil
+}
+
 // Command represents an LDAP operation or config/ACL change to be replicated.
 type Command struct {
-	Type       uint8  // CmdPut, CmdDelete, CmdModifyDN, CmdConfigUpdate, CmdACL*
+	Type       uint8  // CmdPut, CmdDelete, CmdModifyDN, CmdConfigUpdate, CmdACL*, CmdLock, CmdUnlock
 	DatabaseID uint8  // Target database (DBMain, DBLog)
 	DN         string // Target DN
 	OldDN      string // Previous DN (for CmdModifyDN)
@@ -394,6 +675,7 @@ type Command struct {
 	EntryData  []byte // Serialized entry data (for CmdPut)
 	ConfigData []byte // Serialized ConfigCommand (for CmdConfigUpdate)
 	ACLData    []byte // Serialized ACLCommand (for CmdACL*)
+	LockData   []byte // Serialized LockCommand (for CmdLock, CmdUnlock)
 }
 
 // Serialize encodes the command to bytes.
@@ -436,6 +718,11 @@ func (c *Command) Serialize() ([]byte, error) {
 		return nil, err
 	}
 
+	// LockData
+	if err := writeBytes(&buf, c.LockData); err != nil {
+		return nil, err
+	}
+
 	return buf.Bytes(), nil
 }
 
@@ -499,6 +786,13 @@ func DeserializeCommand(data []byte) (*Command, error) {
 		cmd.ACLData = nil
 	}
 
+	// LockData (may not exist in old format)
+	cmd.LockData, err = readBytes(buf)
+	if err != nil {
+		// Old format without LockData, ignore
+		cmd.LockData = nil
+	}
+
 	return cmd, nil
 }
 
@@ -944,3 +1238,38 @@ func CreateACLSetDefaultCommand(defaultPolicy string, version uint64) (*Command,
 		ACLData: aclData,
 	}, nil
 }
+
+// CreateLockCommand creates a Raft command acquiring or renewing the lock on
+// resource for holder. The proposal time is set by the leader.
+func CreateLockCommand(resource, holder string, ttl time.Duration) (*Command, error) {
+	lockData, err := SerializeLockCommand(&LockCommand{
+		Resource: resource,
+		Holder:   holder,
+		TTL:      ttl,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Command{
+		Type:     CmdLock,
+		LockData: lockData,
+	}, nil
+}
+
+// CreateUnlockCommand creates a Raft command releasing the lock on resource
+// held by holder.
+func CreateUnlockCommand(resource, holder string) (*Command, error) {
+	lockData, err := SerializeLockCommand(&LockCommand{
+		Resource: resource,
+		Holder:   holder,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Command{
+		Type:     CmdUnlock,
+		LockData: lockData,
+	}, nil
+}
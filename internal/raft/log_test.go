@@ -3,6 +3,7 @@ package raft
 import (
 	"bytes"
 	"os"
+	"reflect"
 	"testing"
 )
 
@@ -398,6 +399,91 @@ func TestACLCommandWithSingleRule(t *testing.T) {
 	}
 }
 
+func TestACLCommandConnectionConstraints(t *testing.T) {
+	cmd := &ACLCommand{
+		DefaultPolicy: "deny",
+		Rules: []ACLRuleData{
+			{Target: "*", Subject: "*", Scope: "subtree", Rights: []string{"read"}, Attributes: []string{}},
+			{Target: "*", Subject: "authenticated", Scope: "subtree", Rights: []string{"write"}, Attributes: []string{},
+				SourceCIDRs: []string{"10.0.0.0/8"}, RequireTLS: true, MinSSF: 128, AuthMethod: "simple"},
+		},
+		Rule: &ACLRuleData{Target: "*", Subject: "*", Scope: "base", Rights: []string{"read"}, Attributes: []string{},
+			AuthMethod: "sasl-external"},
+	}
+
+	data, err := SerializeACLCommand(cmd)
+	if err != nil {
+		t.Fatalf("SerializeACLCommand failed: %v", err)
+	}
+	restored, err := DeserializeACLCommand(data)
+	if err != nil {
+		t.Fatalf("DeserializeACLCommand failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(restored.Rules, cmd.Rules) {
+		t.Errorf("Rules mismatch: got %+v, want %+v", restored.Rules, cmd.Rules)
+	}
+	if !reflect.DeepEqual(restored.Rule, cmd.Rule) {
+		t.Errorf("Rule mismatch: got %+v, want %+v", restored.Rule, cmd.Rule)
+	}
+}
+
+func TestACLCommandTimeRange(t *testing.T) {
+	cmd := &ACLCommand{
+		DefaultPolicy: "deny",
+		Rules: []ACLRuleData{
+			{Target: "*", Subject: "*", Scope: "subtree", Rights: []string{"read"}, Attributes: []string{},
+				TimeRange: &ACLTimeRangeData{Start: "09:00", End: "17:00", Weekdays: []string{"mon", "fri"}, Timezone: "Europe/Istanbul"}},
+			{Target: "*", Subject: "*", Scope: "subtree", Rights: []string{"write"}, Attributes: []string{}},
+		},
+		Rule: &ACLRuleData{Target: "*", Subject: "*", Scope: "base", Rights: []string{"read"}, Attributes: []string{},
+			TimeRange: &ACLTimeRangeData{Start: "22:00", End: "06:00"}},
+	}
+
+	data, err := SerializeACLCommand(cmd)
+	if err != nil {
+		t.Fatalf("SerializeACLCommand failed: %v", err)
+	}
+	restored, err := DeserializeACLCommand(data)
+	if err != nil {
+		t.Fatalf("DeserializeACLCommand failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(restored.Rules, cmd.Rules) {
+		t.Errorf("Rules mismatch: got %+v, want %+v", restored.Rules, cmd.Rules)
+	}
+	if !reflect.DeepEqual(restored.Rule, cmd.Rule) {
+		t.Errorf("Rule mismatch: got %+v, want %+v", restored.Rule, cmd.Rule)
+	}
+}
+
+func TestACLCommandWithoutConnectionConstraints(t *testing.T) {
+	cmd := &ACLCommand{
+		DefaultPolicy: "allow",
+		Rules:         []ACLRuleData{{Target: "*", Subject: "*", Scope: "subtree", Rights: []string{"read"}, Attributes: []string{}}},
+	}
+
+	data, err := SerializeACLCommand(cmd)
+	if err != nil {
+		t.Fatalf("SerializeACLCommand failed: %v", err)
+	}
+
+	// Commands written before connection constraints end after the rules.
+	// An empty constraint block is a CIDR count, the TLS flag, MinSSF and
+	// an empty auth method string, and is followed by the time range flag.
+	const emptyConstraints = 2 + 1 + 4 + 2
+	const emptyTimeRange = 1
+	legacy := data[:len(data)-emptyConstraints-emptyTimeRange]
+
+	restored, err := DeserializeACLCommand(legacy)
+	if err != nil {
+		t.Fatalf("DeserializeACLCommand failed: %v", err)
+	}
+	if !reflect.DeepEqual(restored.Rules, cmd.Rules) {
+		t.Errorf("Rules mismatch: got %+v, want %+v", restored.Rules, cmd.Rules)
+	}
+}
+
 func TestCreateConfigUpdateCommand(t *testing.T) {
 	data := map[string]string{
 		"level":  "info",
@@ -1,10 +1,15 @@
 package raft
 
 import (
+	"errors"
+	"fmt"
 	"math/rand"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
 )
 
 // Logger interface for Raft logging
@@ -160,6 +165,15 @@ func (n *Node) LastApplied() uint64 {
 	return n.state.LastApplied()
 }
 
+// LeaderCommitIndex returns the leader's commit index as last reported to
+// this node. On the leader it is the local commit index.
+func (n *Node) LeaderCommitIndex() uint64 {
+	if n.state.IsLeader() {
+		return n.state.CommitIndex()
+	}
+	return n.state.LeaderCommit()
+}
+
 // Start starts the Raft node.
 func (n *Node) Start() error {
 	if !atomic.CompareAndSwapInt32(&n.running, 0, 1) {
@@ -418,6 +432,10 @@ func (n *Node) handleRPC(msgType uint8, data []byte) []byte {
 		return n.handleAppendEntries(data)
 	case RPCInstallSnapshot:
 		return n.handleInstallSnapshot(data)
+	case RPCRead:
+		return n.handleRead(data)
+	case RPCLock:
+		return n.handleLock(data)
 	default:
 		return nil
 	}
@@ -523,6 +541,8 @@ func (n *Node) handleAppendEntries(data []byte) []byte {
 		}
 	}
 
+	n.state.SetLeaderCommit(args.LeaderCommit)
+
 	// Update commit index
 	if args.LeaderCommit > n.state.CommitIndex() {
 		newCommit := args.LeaderCommit
@@ -578,6 +598,77 @@ func (n *Node) handleInstallSnapshot(data []byte) []byte {
 	return reply.Serialize()
 }
 
+// handleRead serves a read forwarded by a follower. Only the leader answers.
+func (n *Node) handleRead(data []byte) []byte {
+	args, err := DeserializeReadArgs(data)
+	if err != nil {
+		return (&ReadReply{Status: ReadStatusError, Error: err.Error()}).Serialize()
+	}
+
+	if !n.IsLeader() {
+		return (&ReadReply{Status: ReadStatusNotLeader}).Serialize()
+	}
+
+	reader, ok := n.stateMachine.(localReader)
+	if !ok {
+		return (&ReadReply{Status: ReadStatusError, Error: "state machine does not support reads"}).Serialize()
+	}
+
+	entries, err := reader.readLocal(args)
+	if err != nil {
+		if errors.Is(err, engine.ErrEntryNotFound) {
+			return (&ReadReply{Status: ReadStatusNotFound}).Serialize()
+		}
+		return (&ReadReply{Status: ReadStatusError, Error: err.Error()}).Serialize()
+	}
+
+	reply := &ReadReply{Status: ReadStatusOK, Entries: make([][]byte, 0, len(entries))}
+	for _, entry := range entries {
+		reply.Entries = append(reply.Entries, serializeEntry(entry))
+	}
+	return reply.Serialize()
+}
+
+// ForwardRead performs a read on the current leader.
+func (n *Node) ForwardRead(args *ReadArgs) ([]*storage.Entry, error) {
+	leaderID := n.LeaderID()
+	if leaderID == 0 {
+		return nil, ErrLeaderUnknown
+	}
+	if leaderID == n.id {
+		return nil, ErrNotLeader
+	}
+
+	resp, err := n.transport.Send(leaderID, RPCRead, args.Serialize())
+	if err != nil {
+		return nil, err
+	}
+	reply, err := DeserializeReadReply(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	switch reply.Status {
+	case ReadStatusOK:
+	case ReadStatusNotFound:
+		return nil, engine.ErrEntryNotFound
+	case ReadStatusNotLeader:
+		return nil, ErrNotLeader
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrReadFailed, reply.Error)
+	}
+
+	entries := make([]*storage.Entry, 0, len(reply.Entries))
+	for _, data := range reply.Entries {
+		entry, err := deserializeEntry(data)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
 func (n *Node) sendRequestVote(peerID uint64, args *RequestVoteArgs) (*RequestVoteReply, error) {
 	data := args.Serialize()
 	resp, err := n.transport.Send(peerID, RPCRequestVote, data)
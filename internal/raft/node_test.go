@@ -60,6 +60,12 @@ type TestCluster struct {
 }
 
 func NewTestCluster(size int) *TestCluster {
+	return newTestClusterWith(size, func() StateMachine { return NewMockStateMachine() })
+}
+
+// newTestClusterWith creates a test cluster whose nodes use the state
+// machines returned by newSM.
+func newTestClusterWith(size int, newSM func() StateMachine) *TestCluster {
 	network := NewInMemoryNetwork()
 	nodes := make([]*Node, size)
 
@@ -83,9 +89,7 @@ func NewTestCluster(size int) *TestCluster {
 		}
 
 		transport := network.NewTransport(uint64(i+1), cfg.Addr)
-		sm := NewMockStateMachine()
-
-		node, err := NewNode(cfg, sm, transport)
+		node, err := NewNode(cfg, newSM(), transport)
 		if err != nil {
 			panic(err)
 		}
@@ -0,0 +1,247 @@
+package raft
+
+import (
+	"strings"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// ReadPreference controls which node serves a read in cluster mode.
+type ReadPreference uint8
+
+// Read preferences.
+const (
+	// PreferLeader serves reads from the leader. Followers forward reads to it.
+	PreferLeader ReadPreference = iota
+	// PreferFollower serves reads locally as long as the local node has applied
+	// the log to within MaxStalenessLog entries of the leader's commit index.
+	// Lagging followers forward reads to the leader.
+	PreferFollower
+	// AnyNode always serves reads locally, regardless of replication lag.
+	AnyNode
+)
+
+// DefaultMaxStalenessLog is the number of log entries a follower may lag behind
+// the leader's commit index and still serve PreferFollower reads.
+const DefaultMaxStalenessLog uint64 = 100
+
+// String returns the configuration name of the read preference.
+func (p ReadPreference) String() string {
+	switch p {
+	case PreferLeader:
+		return "leader"
+	case PreferFollower:
+		return "follower"
+	case AnyNode:
+		return "any"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseReadPreference parses a read preference name (leader, follower, any).
+// An empty name selects AnyNode, which matches the behaviour of local reads.
+func ParseReadPreference(s string) (ReadPreference, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "any":
+		return AnyNode, nil
+	case "leader":
+		return PreferLeader, nil
+	case "follower":
+		return PreferFollower, nil
+	default:
+		return AnyNode, ErrInvalidReadPreference
+	}
+}
+
+// ReadRouter provides the replication state needed to route reads and a way
+// to forward reads to the leader. Node implements it.
+type ReadRouter interface {
+	IsLeader() bool
+	LastApplied() uint64
+	LeaderCommitIndex() uint64
+	ForwardRead(args *ReadArgs) ([]*storage.Entry, error)
+}
+
+// localReader is implemented by state machines that can serve forwarded reads.
+type localReader interface {
+	readLocal(args *ReadArgs) ([]*storage.Entry, error)
+}
+
+// SetReadRouter sets the router used to forward reads to the leader.
+// Without a router every read is served locally.
+func (sm *ObaDBStateMachine) SetReadRouter(router ReadRouter) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.router = router
+}
+
+// SetMaxStalenessLog sets how many log entries this node may lag behind the
+// leader and still serve PreferFollower reads. Zero selects DefaultMaxStalenessLog.
+func (sm *ObaDBStateMachine) SetMaxStalenessLog(n uint64) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if n == 0 {
+		n = DefaultMaxStalenessLog
+	}
+	sm.maxStaleness = n
+}
+
+// Read returns the entry with the given DN, routed according to pref.
+func (sm *ObaDBStateMachine) Read(key []byte, pref ReadPreference) (*storage.Entry, error) {
+	entries, err := sm.routeRead(&ReadArgs{Op: ReadOpGet, DN: string(key)}, pref)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	return entries[0], nil
+}
+
+// SearchByDN returns an iterator over the entries under baseDN in the
+// given scope, routed according to pref. Local reads stream from the
+// engine; only reads forwarded to the leader are buffered.
+func (sm *ObaDBStateMachine) SearchByDN(baseDN string, scope storage.Scope, pref ReadPreference) storage.Iterator {
+	if sm.canReadLocally(pref) {
+		return sm.searchLocal(func(txn interface{}) storage.Iterator {
+			return sm.mainEngine.SearchByDN(txn, baseDN, scope)
+		})
+	}
+
+	entries, err := sm.router.ForwardRead(&ReadArgs{Op: ReadOpSearch, Scope: uint8(scope), DN: baseDN})
+	if err != nil {
+		return &emptyIterator{err: err}
+	}
+	return &sliceIterator{entries: entries}
+}
+
+// SearchByFilter returns an iterator over the entries under baseDN that
+// match f, routed according to pref. Local reads stream from the engine's
+// filter search; reads forwarded to the leader fetch the subtree and apply
+// f on this node.
+func (sm *ObaDBStateMachine) SearchByFilter(baseDN string, f storage.FilterMatcher, pref ReadPreference) storage.Iterator {
+	if sm.canReadLocally(pref) {
+		return sm.searchLocal(func(txn interface{}) storage.Iterator {
+			return sm.mainEngine.SearchByFilter(txn, baseDN, f)
+		})
+	}
+
+	entries, err := sm.router.ForwardRead(&ReadArgs{Op: ReadOpSearch, Scope: uint8(storage.ScopeSubtree), DN: baseDN})
+	if err != nil {
+		return &emptyIterator{err: err}
+	}
+
+	matched := entries[:0]
+	for _, entry := range entries {
+		if f == nil || f.Match(entry) {
+			matched = append(matched, entry)
+		}
+	}
+	return &sliceIterator{entries: matched}
+}
+
+// searchLocal runs a search on the main engine in a read transaction that
+// lasts until the returned iterator is closed.
+func (sm *ObaDBStateMachine) searchLocal(search func(txn interface{}) storage.Iterator) storage.Iterator {
+	txn, err := sm.mainEngine.Begin()
+	if err != nil {
+		return &emptyIterator{err: err}
+	}
+	return &txnIterator{Iterator: search(txn), engine: sm.mainEngine, txn: txn}
+}
+
+// txnIterator is an engine iterator that rolls back its read transaction
+// when closed.
+type txnIterator struct {
+	storage.Iterator
+	engine storage.StorageEngine
+	txn    interface{}
+}
+
+// Close closes the iterator and ends its transaction.
+func (i *txnIterator) Close() {
+	i.Iterator.Close()
+	i.engine.Rollback(i.txn)
+}
+
+// routeRead serves args locally or forwards it to the leader.
+func (sm *ObaDBStateMachine) routeRead(args *ReadArgs, pref ReadPreference) ([]*storage.Entry, error) {
+	if sm.canReadLocally(pref) {
+		return sm.readLocal(args)
+	}
+	return sm.router.ForwardRead(args)
+}
+
+// canReadLocally reports whether a read with the given preference may be
+// served by this node.
+func (sm *ObaDBStateMachine) canReadLocally(pref ReadPreference) bool {
+	sm.mu.Lock()
+	router := sm.router
+	maxStaleness := sm.maxStaleness
+	sm.mu.Unlock()
+
+	if router == nil || router.IsLeader() {
+		return true
+	}
+
+	switch pref {
+	case AnyNode:
+		return true
+	case PreferFollower:
+		if maxStaleness == 0 {
+			maxStaleness = DefaultMaxStalenessLog
+		}
+		return router.LastApplied()+maxStaleness >= router.LeaderCommitIndex()
+	default:
+		return false
+	}
+}
+
+// readLocal serves a read from the main engine.
+func (sm *ObaDBStateMachine) readLocal(args *ReadArgs) ([]*storage.Entry, error) {
+	if args.Op == ReadOpGet {
+		txn, err := sm.mainEngine.Begin()
+		if err != nil {
+			return nil, err
+		}
+		defer sm.mainEngine.Rollback(txn)
+
+		entry, err := sm.mainEngine.Get(txn, args.DN)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			return nil, nil
+		}
+		return []*storage.Entry{entry}, nil
+	}
+
+	return sm.collect(func(txn interface{}) storage.Iterator {
+		return sm.mainEngine.SearchByDN(txn, args.DN, storage.Scope(args.Scope))
+	})
+}
+
+// collect runs a search in a read transaction and returns all results, for
+// reads forwarded by followers that are sent back in one response.
+func (sm *ObaDBStateMachine) collect(search func(txn interface{}) storage.Iterator) ([]*storage.Entry, error) {
+	txn, err := sm.mainEngine.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer sm.mainEngine.Rollback(txn)
+
+	iter := search(txn)
+	defer iter.Close()
+
+	var entries []*storage.Entry
+	for iter.Next() {
+		if entry := iter.Entry(); entry != nil {
+			entries = append(entries, entry.Clone())
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,254 @@
+package raft
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// fakeReadRouter is a ReadRouter with fixed replication state.
+type fakeReadRouter struct {
+	leader       bool
+	lastApplied  uint64
+	leaderCommit uint64
+	leaderEngine *MockStorageEngine
+	forwarded    int
+}
+
+func (r *fakeReadRouter) IsLeader() bool            { return r.leader }
+func (r *fakeReadRouter) LastApplied() uint64       { return r.lastApplied }
+func (r *fakeReadRouter) LeaderCommitIndex() uint64 { return r.leaderCommit }
+
+func (r *fakeReadRouter) ForwardRead(args *ReadArgs) ([]*storage.Entry, error) {
+	r.forwarded++
+	return NewObaDBStateMachine(r.leaderEngine).readLocal(args)
+}
+
+func newReadTestEntry(dn, source string) *storage.Entry {
+	entry := storage.NewEntry(dn)
+	entry.SetStringAttribute("description", source)
+	return entry
+}
+
+func TestParseReadPreference(t *testing.T) {
+	tests := []struct {
+		in   string
+		want ReadPreference
+	}{
+		{"", AnyNode},
+		{"any", AnyNode},
+		{"leader", PreferLeader},
+		{"Follower", PreferFollower},
+	}
+	for _, tt := range tests {
+		got, err := ParseReadPreference(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseReadPreference(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+		if tt.in != "" && got.String() != tt.want.String() {
+			t.Errorf("String() = %q", got.String())
+		}
+	}
+
+	if _, err := ParseReadPreference("nearest"); !errors.Is(err, ErrInvalidReadPreference) {
+		t.Errorf("expected ErrInvalidReadPreference, got %v", err)
+	}
+}
+
+func TestReadPreferenceRouting(t *testing.T) {
+	const dn = "uid=alice,dc=example,dc=com"
+
+	leaderEngine := NewMockStorageEngine()
+	leaderEngine.Put(nil, newReadTestEntry(dn, "leader"))
+
+	followerEngine := NewMockStorageEngine()
+	followerEngine.Put(nil, newReadTestEntry(dn, "follower"))
+
+	tests := []struct {
+		name         string
+		pref         ReadPreference
+		leader       bool
+		lastApplied  uint64
+		leaderCommit uint64
+		wantSource   string
+	}{
+		{name: "up-to-date follower serves read", pref: PreferFollower, lastApplied: 120, leaderCommit: 125, wantSource: "follower"},
+		{name: "lagging follower falls back to leader", pref: PreferFollower, lastApplied: 10, leaderCommit: 500, wantSource: "leader"},
+		{name: "prefer leader forwards", pref: PreferLeader, lastApplied: 125, leaderCommit: 125, wantSource: "leader"},
+		{name: "any node reads locally", pref: AnyNode, lastApplied: 10, leaderCommit: 500, wantSource: "follower"},
+		{name: "leader reads locally", pref: PreferLeader, leader: true, wantSource: "follower"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := &fakeReadRouter{
+				leader:       tt.leader,
+				lastApplied:  tt.lastApplied,
+				leaderCommit: tt.leaderCommit,
+				leaderEngine: leaderEngine,
+			}
+			sm := NewObaDBStateMachine(followerEngine)
+			sm.SetReadRouter(router)
+			sm.SetMaxStalenessLog(100)
+
+			entry, err := sm.Read([]byte(dn), tt.pref)
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if got := entry.GetAttribute("description"); len(got) != 1 || string(got[0]) != tt.wantSource {
+				t.Errorf("read served by %q, want %q", got, tt.wantSource)
+			}
+
+			iter := sm.SearchByDN("dc=example,dc=com", storage.ScopeSubtree, tt.pref)
+			if _, local := iter.(*txnIterator); local != (tt.wantSource == "follower") {
+				t.Errorf("SearchByDN() iterator = %T, want an engine iterator only for local reads", iter)
+			}
+			entries, err := drainIterator(iter)
+			if err != nil {
+				t.Fatalf("SearchByDN() error = %v", err)
+			}
+			if len(entries) != 1 || string(entries[0].GetAttribute("description")[0]) != tt.wantSource {
+				t.Errorf("search served by wrong node: %v", entries)
+			}
+
+			wantForwarded := 0
+			if tt.wantSource == "leader" {
+				wantForwarded = 2
+			}
+			if router.forwarded != wantForwarded {
+				t.Errorf("forwarded = %d, want %d", router.forwarded, wantForwarded)
+			}
+		})
+	}
+}
+
+func TestSearchByFilterForwardedAppliesFilter(t *testing.T) {
+	leaderEngine := NewMockStorageEngine()
+	leaderEngine.Put(nil, newReadTestEntry("uid=alice,dc=example,dc=com", "match"))
+	leaderEngine.Put(nil, newReadTestEntry("uid=bob,dc=example,dc=com", "other"))
+
+	sm := NewObaDBStateMachine(NewMockStorageEngine())
+	sm.SetReadRouter(&fakeReadRouter{lastApplied: 1, leaderCommit: 1000, leaderEngine: leaderEngine})
+
+	entries, err := drainIterator(sm.SearchByFilter("dc=example,dc=com", descriptionMatcher("match"), PreferFollower))
+	if err != nil {
+		t.Fatalf("SearchByFilter() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].DN != "uid=alice,dc=example,dc=com" {
+		t.Errorf("unexpected entries: %v", entries)
+	}
+}
+
+// drainIterator returns the entries of iter and closes it.
+func drainIterator(iter storage.Iterator) ([]*storage.Entry, error) {
+	defer iter.Close()
+
+	var entries []*storage.Entry
+	for iter.Next() {
+		entries = append(entries, iter.Entry())
+	}
+	return entries, iter.Error()
+}
+
+// descriptionMatcher matches entries by their description value.
+type descriptionMatcher string
+
+func (m descriptionMatcher) Match(entry *storage.Entry) bool {
+	values := entry.GetAttribute("description")
+	return len(values) == 1 && string(values[0]) == string(m)
+}
+
+func TestForwardReadToLeader(t *testing.T) {
+	network := NewInMemoryNetwork()
+	peers := []*Peer{
+		{ID: 1, Addr: "node1:4445"},
+		{ID: 2, Addr: "node2:4445"},
+		{ID: 3, Addr: "node3:4445"},
+	}
+
+	nodes := make([]*Node, len(peers))
+	engines := make([]*MockStorageEngine, len(peers))
+	for i, p := range peers {
+		engines[i] = NewMockStorageEngine()
+		sm := NewObaDBStateMachine(engines[i])
+		node, err := NewNode(&NodeConfig{
+			ID:               p.ID,
+			Addr:             p.Addr,
+			Peers:            peers,
+			ElectionTimeout:  50 * time.Millisecond,
+			HeartbeatTimeout: 20 * time.Millisecond,
+		}, sm, network.NewTransport(p.ID, p.Addr))
+		if err != nil {
+			t.Fatalf("NewNode failed: %v", err)
+		}
+		sm.SetReadRouter(node)
+		nodes[i] = node
+		node.Start()
+		defer node.Stop()
+	}
+
+	var leader, follower *Node
+	deadline := time.Now().Add(2 * time.Second)
+	for leader == nil && time.Now().Before(deadline) {
+		for _, n := range nodes {
+			if n.IsLeader() {
+				leader = n
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if leader == nil {
+		t.Fatal("no leader elected")
+	}
+	for _, n := range nodes {
+		if n != leader && n.LeaderID() == leader.ID() {
+			follower = n
+			break
+		}
+	}
+	if follower == nil {
+		t.Fatal("no follower knows the leader")
+	}
+
+	// Only the leader holds the entry, so a forwarded read must reach it.
+	const dn = "uid=alice,dc=example,dc=com"
+	engines[leader.ID()-1].Put(nil, newReadTestEntry(dn, "leader"))
+
+	entries, err := follower.ForwardRead(&ReadArgs{Op: ReadOpGet, DN: dn})
+	if err != nil {
+		t.Fatalf("ForwardRead() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].DN != dn {
+		t.Fatalf("unexpected entries: %v", entries)
+	}
+
+	if _, err := leader.ForwardRead(&ReadArgs{Op: ReadOpGet, DN: dn}); !errors.Is(err, ErrNotLeader) {
+		t.Errorf("expected ErrNotLeader from leader, got %v", err)
+	}
+}
+
+func TestReadArgsSerialize(t *testing.T) {
+	args := &ReadArgs{Op: ReadOpSearch, Scope: uint8(storage.ScopeOneLevel), DN: "dc=example,dc=com"}
+	got, err := DeserializeReadArgs(args.Serialize())
+	if err != nil {
+		t.Fatalf("DeserializeReadArgs() error = %v", err)
+	}
+	if *got != *args {
+		t.Errorf("got %+v, want %+v", got, args)
+	}
+
+	reply := &ReadReply{Status: ReadStatusError, Error: "boom", Entries: [][]byte{{1, 2}, {3}}}
+	gotReply, err := DeserializeReadReply(reply.Serialize())
+	if err != nil {
+		t.Fatalf("DeserializeReadReply() error = %v", err)
+	}
+	if gotReply.Status != reply.Status || gotReply.Error != reply.Error || len(gotReply.Entries) != 2 {
+		t.Errorf("got %+v, want %+v", gotReply, reply)
+	}
+
+	if _, err := DeserializeReadReply([]byte{0, 0}); err == nil {
+		t.Error("expected error for truncated reply")
+	}
+}
@@ -14,6 +14,10 @@ const (
 	RPCAppendEntriesReply
 	RPCInstallSnapshot
 	RPCInstallSnapshotReply
+	RPCRead
+	RPCReadReply
+	RPCLock
+	RPCLockReply
 )
 
 // RequestVoteArgs is sent by candidates to gather votes.
@@ -251,3 +255,143 @@ func DeserializeInstallSnapshotReply(data []byte) (*InstallSnapshotReply, error)
 		Term: binary.LittleEndian.Uint64(data[0:8]),
 	}, nil
 }
+
+// Read operations forwarded to the leader.
+const (
+	ReadOpGet    uint8 = iota // Fetch a single entry by DN
+	ReadOpSearch              // Fetch entries under a base DN
+)
+
+// Read reply status codes.
+const (
+	ReadStatusOK        uint8 = iota // Entries contains the result
+	ReadStatusNotFound               // The requested entry does not exist
+	ReadStatusNotLeader              // The receiving node is not the leader
+	ReadStatusError                  // The read failed, see Error
+)
+
+// ReadArgs is sent by a follower to read from the leader.
+type ReadArgs struct {
+	Op    uint8  // ReadOpGet or ReadOpSearch
+	Scope uint8  // Search scope for ReadOpSearch
+	DN    string // Entry DN or search base DN
+}
+
+// Serialize encodes ReadArgs to bytes.
+func (r *ReadArgs) Serialize() []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(r.Op)
+	buf.WriteByte(r.Scope)
+	writeString(&buf, r.DN)
+	return buf.Bytes()
+}
+
+// DeserializeReadArgs decodes ReadArgs from bytes.
+func DeserializeReadArgs(data []byte) (*ReadArgs, error) {
+	if len(data) < 2 {
+		return nil, ErrLogCorrupted
+	}
+	dn, err := readString(bytes.NewReader(data[2:]))
+	if err != nil {
+		return nil, ErrLogCorrupted
+	}
+	return &ReadArgs{
+		Op:    data[0],
+		Scope: data[1],
+		DN:    dn,
+	}, nil
+}
+
+// ReadReply is the response to Read.
+type ReadReply struct {
+	Status  uint8    // One of the ReadStatus codes
+	Error   string   // Error message for ReadStatusError
+	Entries [][]byte // Serialized entries
+}
+
+// Serialize encodes ReadReply to bytes.
+func (r *ReadReply) Serialize() []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(r.Status)
+	writeString(&buf, r.Error)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(r.Entries)))
+	for _, entry := range r.Entries {
+		writeBytes(&buf, entry)
+	}
+	return buf.Bytes()
+}
+
+// DeserializeReadReply decodes ReadReply from bytes.
+func DeserializeReadReply(data []byte) (*ReadReply, error) {
+	if len(data) < 1 {
+		return nil, ErrLogCorrupted
+	}
+
+	reader := bytes.NewReader(data[1:])
+	msg, err := readString(reader)
+	if err != nil {
+		return nil, ErrLogCorrupted
+	}
+
+	var count uint32
+	if err := binary.Read(reader, binary.LittleEndian, &count); err != nil {
+		return nil, ErrLogCorrupted
+	}
+	if int64(count) > int64(reader.Len()) {
+		return nil, ErrLogCorrupted
+	}
+
+	reply := &ReadReply{
+		Status:  data[0],
+		Error:   msg,
+		Entries: make([][]byte, 0, count),
+	}
+	for i := uint32(0); i < count; i++ {
+		entry, err := readBytes(reader)
+		if err != nil {
+			return nil, ErrLogCorrupted
+		}
+		reply.Entries = append(reply.Entries, entry)
+	}
+
+	return reply, nil
+}
+
+// Lock reply status codes.
+const (
+	LockStatusOK        uint8 = iota // The command was committed and applied
+	LockStatusHeld                   // The lock is held by another holder
+	LockStatusNotHeld                // The lock to release is held by another holder
+	LockStatusNotLeader              // The receiving node is not the leader
+	LockStatusError                  // The command failed, see Error
+)
+
+// LockReply is the response to a lock command forwarded to the leader. The
+// request is a serialized Command of type CmdLock or CmdUnlock.
+type LockReply struct {
+	Status uint8  // One of the LockStatus codes
+	Error  string // Error message for LockStatusError
+}
+
+// Serialize encodes LockReply to bytes.
+func (r *LockReply) Serialize() []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(r.Status)
+	writeString(&buf, r.Error)
+	return buf.Bytes()
+}
+
+// DeserializeLockReply decodes LockReply from bytes.
+func DeserializeLockReply(data []byte) (*LockReply, error) {
+	if len(data) < 1 {
+		return nil, ErrLogCorrupted
+	}
+	msg, err := readString(bytes.NewReader(data[1:]))
+	if err != nil {
+		return nil, ErrLogCorrupted
+	}
+	return &LockReply{
+		Status: data[0],
+		Error:  msg,
+	}, nil
+}
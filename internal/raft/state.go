@@ -90,7 +90,8 @@ type NodeState struct {
 	matchIndex map[uint64]uint64 // peer ID -> highest replicated index
 
 	// Leader tracking
-	leaderID uint64
+	leaderID     uint64
+	leaderCommit uint64 // Last commit index reported by the leader
 
 	// Timing
 	lastHeartbeat time.Time
@@ -340,6 +341,20 @@ func (s *NodeState) SetLeaderID(id uint64) {
 	s.leaderID = id
 }
 
+// LeaderCommit returns the last commit index reported by the leader.
+func (s *NodeState) LeaderCommit() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.leaderCommit
+}
+
+// SetLeaderCommit records the commit index reported by the leader.
+func (s *NodeState) SetLeaderCommit(index uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.leaderCommit = index
+}
+
 // Log returns the Raft log.
 func (s *NodeState) Log() *RaftLog {
 	s.mu.RLock()
@@ -9,6 +9,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/KilimcininKorOglu/oba/internal/storage"
 	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
@@ -42,6 +43,9 @@ type ObaDBStateMachine struct {
 	logEngine     storage.StorageEngine
 	configApplier ConfigApplier
 	aclApplier    ACLApplier
+	router        ReadRouter
+	maxStaleness  uint64
+	locks         map[string]LockEntry // Distributed locks by resource
 	mu            sync.Mutex
 }
 
@@ -168,6 +172,8 @@ func (sm *ObaDBStateMachine) Apply(cmd *Command) error {
 		return sm.applyConfigCommand(cmd)
 	case CmdACLFullUpdate, CmdACLAddRule, CmdACLUpdateRule, CmdACLDeleteRule, CmdACLSetDefault:
 		return sm.applyACLCommand(cmd)
+	case CmdLock, CmdUnlock:
+		return sm.applyLockCommand(cmd)
 	default:
 		return fmt.Errorf("unknown command type: %d", cmd.Type)
 	}
@@ -251,6 +257,8 @@ func classifyPutApplyError(err error) error {
 	switch {
 	case errors.Is(err, engine.ErrUIDNotUnique):
 		return NewApplyResultError(ApplyResultRejectConflict, err)
+	case errors.Is(err, engine.ErrEntryUUIDNotUnique):
+		return NewApplyResultError(ApplyResultRejectConflict, err)
 	case errors.Is(err, engine.ErrEntryExists):
 		return NewApplyResultError(ApplyResultRejectConflict, err)
 	case errors.Is(err, tx.ErrWriteConflict):
@@ -356,7 +364,7 @@ func (sm *ObaDBStateMachine) Snapshot() ([]byte, error) {
 	var buf bytes.Buffer
 
 	// Snapshot version (for backward compatibility)
-	binary.Write(&buf, binary.LittleEndian, uint8(2)) // Version 2 includes config/ACL
+	binary.Write(&buf, binary.LittleEndian, uint8(3)) // Version 2 includes config/ACL, 3 locks
 
 	// Snapshot main database
 	mainData, err := sm.snapshotEngine(sm.mainEngine)
@@ -402,9 +410,63 @@ func (sm *ObaDBStateMachine) Snapshot() ([]byte, error) {
 		binary.Write(&buf, binary.LittleEndian, uint32(0))
 	}
 
+	// Snapshot distributed locks
+	lockData, err := sm.snapshotLocks()
+	if err != nil {
+		return nil, err
+	}
+	binary.Write(&buf, binary.LittleEndian, uint32(len(lockData)))
+	buf.Write(lockData)
+
 	return buf.Bytes(), nil
 }
 
+// snapshotLocks encodes the lock table.
+func (sm *ObaDBStateMachine) snapshotLocks() ([]byte, error) {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint32(len(sm.locks)))
+	for resource, lock := range sm.locks {
+		if err := writeString(&buf, resource); err != nil {
+			return nil, err
+		}
+		if err := writeString(&buf, lock.Holder); err != nil {
+			return nil, err
+		}
+		binary.Write(&buf, binary.LittleEndian, lock.Expires.UnixNano())
+	}
+	return buf.Bytes(), nil
+}
+
+// restoreLocks replaces the lock table with the one encoded in data.
+func (sm *ObaDBStateMachine) restoreLocks(data []byte) error {
+	reader := bytes.NewReader(data)
+
+	var count uint32
+	if err := binary.Read(reader, binary.LittleEndian, &count); err != nil {
+		return err
+	}
+
+	locks := make(map[string]LockEntry, count)
+	for i := uint32(0); i < count; i++ {
+		resource, err := readString(reader)
+		if err != nil {
+			return err
+		}
+		holder, err := readString(reader)
+		if err != nil {
+			return err
+		}
+		var expires int64
+		if err := binary.Read(reader, binary.LittleEndian, &expires); err != nil {
+			return err
+		}
+		locks[resource] = LockEntry{Holder: holder, Expires: time.Unix(0, expires)}
+	}
+
+	sm.locks = locks
+	return nil
+}
+
 // snapshotEngine creates a snapshot of a single engine.
 func (sm *ObaDBStateMachine) snapshotEngine(engine storage.StorageEngine) ([]byte, error) {
 	tx, err := engine.Begin()
@@ -523,6 +585,21 @@ func (sm *ObaDBStateMachine) Restore(data []byte) error {
 		}
 	}
 
+	// Version 3+ includes distributed locks
+	if version >= 3 {
+		var lockLen uint32
+		if err := binary.Read(reader, binary.LittleEndian, &lockLen); err != nil {
+			return nil // No lock data, ok
+		}
+		lockData := make([]byte, lockLen)
+		if _, err := io.ReadFull(reader, lockData); err != nil {
+			return err
+		}
+		if err := sm.restoreLocks(lockData); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -242,6 +242,10 @@ func (m *MockStorageEngine) HasChildren(tx interface{}, dn string) (bool, error)
 	return false, nil
 }
 
+func (m *MockStorageEngine) CountChildren(tx interface{}, dn string, limit int) (int, error) {
+	return 0, nil
+}
+
 func (m *MockStorageEngine) SearchByDN(tx interface{}, baseDN string, scope storage.Scope) storage.Iterator {
 	return &mockIterator{entries: m.getAllEntries()}
 }
@@ -376,7 +380,7 @@ func TestObaDBStateMachineSnapshot(t *testing.T) {
 		t.Error("Snapshot should not be empty")
 	}
 
-	// Verify snapshot format: [version:1][mainLen:4][mainData][logLen:4][logData][configLen:4][configData][aclLen:4][aclData]
+	// Verify snapshot format: [version:1][mainLen:4][mainData][logLen:4][logData][configLen:4][configData][aclLen:4][aclData][lockLen:4][lockData]
 	reader := bytes.NewReader(data)
 
 	// Read version
@@ -384,8 +388,8 @@ func TestObaDBStateMachineSnapshot(t *testing.T) {
 	if err := binary.Read(reader, binary.LittleEndian, &version); err != nil {
 		t.Fatalf("Failed to read version: %v", err)
 	}
-	if version != 2 {
-		t.Errorf("Expected version 2, got %d", version)
+	if version != 3 {
+		t.Errorf("Expected version 3, got %d", version)
 	}
 
 	// Read main database length
@@ -2,6 +2,7 @@ package rest
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -20,6 +21,21 @@ type ACLRuleJSON struct {
 	Rights     []string `json:"rights"`
 	Attributes []string `json:"attributes,omitempty"`
 	Deny       bool     `json:"deny"`
+
+	SourceCIDRs []string `json:"sourceCIDRs,omitempty"`
+	RequireTLS  bool     `json:"requireTLS,omitempty"`
+	MinSSF      int      `json:"minSSF,omitempty"`
+	AuthMethod  string   `json:"authMethod,omitempty"`
+
+	TimeRange *ACLTimeRangeJSON `json:"timeRange,omitempty"`
+}
+
+// ACLTimeRangeJSON represents the time range of an ACL rule in JSON format.
+type ACLTimeRangeJSON struct {
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Weekdays []string `json:"weekdays,omitempty"`
+	Timezone string   `json:"timezone,omitempty"`
 }
 
 // ACLConfigJSON represents ACL configuration in JSON format.
@@ -439,6 +455,96 @@ func (h *Handlers) HandleValidateACL(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// ACLTestRequest is the body of POST /api/v1/acl/test.
+type ACLTestRequest struct {
+	BindDN    string `json:"bindDN"`
+	Target    string `json:"target"`
+	Operation string `json:"operation"`
+	Attribute string `json:"attribute,omitempty"`
+
+	// Connection details checked by rules with connection constraints
+	RemoteIP   string `json:"remoteIP,omitempty"`
+	TLS        bool   `json:"tls,omitempty"`
+	SSF        int    `json:"ssf,omitempty"`
+	AuthMethod string `json:"authMethod,omitempty"`
+}
+
+// ACLTestRuleJSON describes how a single rule was evaluated.
+type ACLTestRuleJSON struct {
+	Index   int         `json:"index"`
+	Rule    ACLRuleJSON `json:"rule"`
+	Matched bool        `json:"matched"`
+	Reason  string      `json:"reason"`
+}
+
+// ACLTestResponse is the response of POST /api/v1/acl/test.
+type ACLTestResponse struct {
+	Allowed      bool              `json:"allowed"`
+	DecisionRule int               `json:"decisionRule"`
+	Source       string            `json:"source"`
+	Rules        []ACLTestRuleJSON `json:"rules"`
+	Warnings     []string          `json:"warnings,omitempty"`
+}
+
+// HandleTestACL handles POST /api/v1/acl/test
+func (h *Handlers) HandleTestACL(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&h.requestCount, 1)
+
+	if h.aclManager == nil {
+		writeError(w, http.StatusServiceUnavailable, "acl_not_configured", "ACL manager not configured")
+		return
+	}
+
+	var req ACLTestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "invalid JSON body")
+		return
+	}
+
+	if req.Target == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", "target is required")
+		return
+	}
+
+	if req.Operation == "" {
+		req.Operation = "read"
+	}
+	operation, err := acl.ParseRights([]string{req.Operation})
+	if err != nil || operation == acl.All {
+		writeError(w, http.StatusBadRequest, "invalid_operation", "invalid operation: "+req.Operation)
+		return
+	}
+
+	ctx := acl.NewAccessContext(req.BindDN, req.Target, operation).WithConnection(acl.ConnectionInfo{
+		RemoteIP:   req.RemoteIP,
+		TLS:        req.TLS,
+		SSF:        req.SSF,
+		AuthMethod: req.AuthMethod,
+	})
+	if req.Attribute != "" {
+		ctx.WithAttributes(req.Attribute)
+	}
+
+	x := h.aclManager.Explain(ctx)
+	response := ACLTestResponse{
+		Allowed:      x.Allowed,
+		DecisionRule: x.DecisionRule,
+		Source:       x.Source(),
+		Rules:        make([]ACLTestRuleJSON, len(x.Rules)),
+		Warnings:     acl.FindShadowedRules(h.aclManager.GetConfig()),
+	}
+	for i, re := range x.Rules {
+		response.Rules[i] = ACLTestRuleJSON{
+			Index:   re.Index,
+			Rule:    *aclRuleToJSON(re.Rule),
+			Matched: re.Matched,
+			Reason:  re.Reason,
+		}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
 // aclRulesToJSON converts ACL rules to JSON format.
 func aclRulesToJSON(rules []*acl.ACL) []ACLRuleJSON {
 	result := make([]ACLRuleJSON, len(rules))
@@ -450,6 +556,16 @@ func aclRulesToJSON(rules []*acl.ACL) []ACLRuleJSON {
 
 // aclRuleToJSON converts a single ACL rule to JSON format.
 func aclRuleToJSON(rule *acl.ACL) *ACLRuleJSON {
+	var timeRange *ACLTimeRangeJSON
+	if tr := rule.TimeRange; tr != nil {
+		timeRange = &ACLTimeRangeJSON{
+			Start:    tr.StartString(),
+			End:      tr.EndString(),
+			Weekdays: tr.WeekdayNames(),
+			Timezone: tr.LocationName(),
+		}
+	}
+
 	return &ACLRuleJSON{
 		Target:     rule.Target,
 		Subject:    rule.Subject,
@@ -457,6 +573,13 @@ func aclRuleToJSON(rule *acl.ACL) *ACLRuleJSON {
 		Rights:     rightsToStrings(rule.Rights),
 		Attributes: rule.Attributes,
 		Deny:       rule.Deny,
+
+		SourceCIDRs: rule.SourceCIDRs,
+		RequireTLS:  rule.RequireTLS,
+		MinSSF:      rule.MinSSF,
+		AuthMethod:  rule.AuthMethod,
+
+		TimeRange: timeRange,
 	}
 }
 
@@ -483,6 +606,37 @@ func jsonToACLRule(j *ACLRuleJSON) (*acl.ACL, error) {
 
 	rule.WithDeny(j.Deny)
 
+	for _, cidr := range j.SourceCIDRs {
+		if _, err := acl.ParseSourceNetwork(cidr); err != nil {
+			return nil, fmt.Errorf("%w: %s", acl.ErrInvalidSourceCIDR, cidr)
+		}
+	}
+	if len(j.SourceCIDRs) > 0 {
+		rule.WithSourceCIDRs(j.SourceCIDRs...)
+	}
+
+	if j.MinSSF < 0 {
+		return nil, fmt.Errorf("%w: %d", acl.ErrInvalidSSF, j.MinSSF)
+	}
+	rule.WithMinSSF(j.MinSSF)
+
+	if j.AuthMethod != "" {
+		method, err := acl.ParseAuthMethod(j.AuthMethod)
+		if err != nil {
+			return nil, err
+		}
+		rule.WithAuthMethod(method)
+	}
+
+	rule.WithRequireTLS(j.RequireTLS)
+
+	if tr := j.TimeRange; tr != nil {
+		rule.TimeRange, err = acl.ParseTimeRange(tr.Start, tr.End, tr.Weekdays, tr.Timezone)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return rule, nil
 }
 
@@ -524,6 +678,16 @@ func extractPathParam(path, prefix string) string {
 
 // aclRuleToRaftData converts ACLRuleJSON to raft.ACLRuleData for Raft replication.
 func aclRuleToRaftData(j *ACLRuleJSON) *raft.ACLRuleData {
+	var timeRange *raft.ACLTimeRangeData
+	if tr := j.TimeRange; tr != nil {
+		timeRange = &raft.ACLTimeRangeData{
+			Start:    tr.Start,
+			End:      tr.End,
+			Weekdays: tr.Weekdays,
+			Timezone: tr.Timezone,
+		}
+	}
+
 	return &raft.ACLRuleData{
 		Target:     j.Target,
 		Subject:    j.Subject,
@@ -531,5 +695,12 @@ func aclRuleToRaftData(j *ACLRuleJSON) *raft.ACLRuleData {
 		Rights:     j.Rights,
 		Attributes: j.Attributes,
 		Deny:       j.Deny,
+
+		SourceCIDRs: j.SourceCIDRs,
+		RequireTLS:  j.RequireTLS,
+		MinSSF:      j.MinSSF,
+		AuthMethod:  j.AuthMethod,
+
+		TimeRange: timeRange,
 	}
 }
@@ -1,6 +1,7 @@
 package rest
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
@@ -30,6 +31,7 @@ type Authenticator struct {
 	backend   *backend.ObaBackend
 	jwtSecret []byte
 	tokenTTL  time.Duration
+	external  *ExternalJWTVerifier
 	mu        sync.RWMutex
 }
 
@@ -56,21 +58,29 @@ func (a *Authenticator) GetTokenTTL() time.Duration {
 	return a.tokenTTL
 }
 
+// SetExternalVerifier makes ValidateToken also accept tokens of an
+// external identity provider. Nil accepts only tokens issued here.
+func (a *Authenticator) SetExternalVerifier(v *ExternalJWTVerifier) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.external = v
+}
+
 // Authenticate validates credentials and returns a JWT token.
-func (a *Authenticator) Authenticate(dn, password string) (string, error) {
+func (a *Authenticator) Authenticate(ctx context.Context, dn, password string) (string, error) {
 	// Check if account is locked
-	if a.backend.IsAccountLocked(dn) {
+	if a.backend.IsAccountLocked(ctx, dn) {
 		return "", backend.ErrAccountLocked
 	}
 
-	if err := a.backend.Bind(dn, password); err != nil {
+	if err := a.backend.Bind(ctx, dn, password); err != nil {
 		// Record failed attempt
-		a.backend.RecordAuthFailure(dn)
+		a.backend.RecordAuthFailure(ctx, dn)
 		return "", err
 	}
 
 	// Record successful authentication
-	a.backend.RecordAuthSuccess(dn)
+	a.backend.RecordAuthSuccess(ctx, dn)
 	return a.generateToken(dn)
 }
 
@@ -101,13 +111,31 @@ func (a *Authenticator) generateToken(dn string) (string, error) {
 	return message + "." + signatureB64, nil
 }
 
-// ValidateToken validates a JWT token and returns the claims.
+// ValidateToken validates a JWT token and returns the claims. Tokens
+// issued here are signed with HS256; tokens with another algorithm are
+// passed to the external verifier, if one is set.
 func (a *Authenticator) ValidateToken(token string) (*JWTClaims, error) {
 	parts := splitToken(token)
 	if len(parts) != 3 {
 		return nil, ErrInvalidToken
 	}
 
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeTokenPart(parts[0], &header); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if header.Alg != "HS256" {
+		a.mu.RLock()
+		external := a.external
+		a.mu.RUnlock()
+		if external == nil {
+			return nil, ErrInvalidToken
+		}
+		return external.Verify(token)
+	}
+
 	message := parts[0] + "." + parts[1]
 	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
 	if err != nil {
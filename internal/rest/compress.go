@@ -0,0 +1,180 @@
+package rest
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultMinCompressSize is the smallest response body compressed by
+// CompressMiddleware when no other size is configured.
+const DefaultMinCompressSize = 1024
+
+// gzipWriters reuses gzip writers across responses, since each one holds
+// several hundred kilobytes of compression state.
+var gzipWriters = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(io.Discard)
+	},
+}
+
+// CompressMiddleware gzips the responses of clients that send
+// Accept-Encoding: gzip. Bodies shorter than minSize bytes are sent
+// uncompressed, as the gzip framing would outweigh the savings. Zero uses
+// DefaultMinCompressSize; a negative value disables compression.
+//
+// A response that is flushed before minSize bytes are written, such as a
+// streamed search, is compressed from the first flush on.
+func CompressMiddleware(minSize int) Middleware {
+	if minSize == 0 {
+		minSize = DefaultMinCompressSize
+	}
+	return func(next http.Handler) http.Handler {
+		if minSize < 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			if !acceptsGzip(r) || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressResponseWriter{ResponseWriter: w, minSize: minSize, statusCode: http.StatusOK}
+			defer cw.close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// acceptsGzip reports whether the Accept-Encoding header of r allows gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		// gzip;q=0 refuses gzip
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(name, "q") {
+				q, err := strconv.ParseFloat(value, 64)
+				return err == nil && q > 0
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// compressResponseWriter buffers the start of a response until it knows
+// whether the body is large enough to compress.
+type compressResponseWriter struct {
+	http.ResponseWriter
+	minSize     int
+	statusCode  int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	gz          *gzip.Writer
+}
+
+func (w *compressResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.statusCode = code
+	// Responses without a body go out as they are
+	if code == http.StatusNoContent || code == http.StatusNotModified || code < http.StatusOK {
+		w.decided = true
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *compressResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.decided {
+		if w.gz != nil {
+			return w.gz.Write(p)
+		}
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= w.minSize {
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush commits to compression, so that streamed responses are compressed
+// as a whole, and passes the flush through.
+func (w *compressResponseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		if err := w.start(true); err != nil {
+			return
+		}
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// start sends the header and the buffered body, compressed if compress is
+// set and the handler did not encode the body itself.
+func (w *compressResponseWriter) start(compress bool) error {
+	w.decided = true
+	h := w.Header()
+	if compress && h.Get("Content-Encoding") == "" {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		w.gz = gzipWriters.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.statusCode)
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.gz != nil {
+		_, err := w.gz.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// close sends a body that stayed below the size limit uncompressed, or
+// finishes the gzip stream.
+func (w *compressResponseWriter) close() {
+	if !w.decided {
+		if !w.wroteHeader {
+			// The handler wrote nothing
+			return
+		}
+		w.start(false)
+		return
+	}
+	if w.gz != nil {
+		w.gz.Close()
+		w.gz.Reset(io.Discard)
+		gzipWriters.Put(w.gz)
+		w.gz = nil
+	}
+}
@@ -0,0 +1,165 @@
+package rest
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/backend"
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/logging"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+// TestSearchCompression tests that a search response larger than
+// MinCompressSize is gzipped for clients that accept it, and decompresses
+// to the uncompressed response.
+func TestSearchCompression(t *testing.T) {
+	db, err := engine.Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Directory.RootDN = "cn=admin,dc=example,dc=com"
+	cfg.Directory.RootPassword = "secret"
+	be := backend.NewBackend(db, cfg)
+
+	base := backend.NewEntry("dc=example,dc=com")
+	base.SetAttribute("objectclass", "domain")
+	base.SetAttribute("dc", "example")
+	if err := be.Add(context.Background(), base); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	for i := 0; i < 30; i++ {
+		e := backend.NewEntry(fmt.Sprintf("cn=printer%d,dc=example,dc=com", i))
+		e.SetAttribute("objectclass", "device")
+		e.SetAttribute("cn", fmt.Sprintf("printer%d", i))
+		e.SetAttribute("description", "network printer on the second floor")
+		if err := be.Add(context.Background(), e); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	srvCfg := DefaultServerConfig()
+	srvCfg.JWTSecret = "0123456789abcdef0123456789abcdef"
+	srvCfg.RateLimit = 0
+	s := NewServer(srvCfg, be, logging.NewNop())
+
+	search := func(acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/search?baseDN=dc=example,dc=com&filter=(objectClass=device)", nil)
+		req.SetBasicAuth(cfg.Directory.RootDN, cfg.Directory.RootPassword)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	plain := search("")
+	if plain.Code != http.StatusOK {
+		t.Fatalf("search status = %d, body %s", plain.Code, plain.Body)
+	}
+	if plain.Body.Len() <= DefaultMinCompressSize {
+		t.Fatalf("search returned %d bytes, want more than %d", plain.Body.Len(), DefaultMinCompressSize)
+	}
+	if enc := plain.Header().Get("Content-Encoding"); enc != "" {
+		t.Errorf("Content-Encoding without Accept-Encoding = %q", enc)
+	}
+
+	compressed := search("br, gzip;q=0.8")
+	if enc := compressed.Header().Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", enc)
+	}
+	if compressed.Body.Len() >= plain.Body.Len() {
+		t.Errorf("compressed body is %d bytes, uncompressed %d", compressed.Body.Len(), plain.Body.Len())
+	}
+	zr, err := gzip.NewReader(compressed.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("failed to decompress body: %v", err)
+	}
+	// The two searches may return the entries in a different order
+	if got, want := searchDNs(t, body), searchDNs(t, plain.Body.Bytes()); !reflect.DeepEqual(got, want) {
+		t.Errorf("decompressed entries = %v, want %v", got, want)
+	}
+
+	if enc := search("gzip;q=0").Header().Get("Content-Encoding"); enc != "" {
+		t.Errorf("Content-Encoding with gzip;q=0 = %q", enc)
+	}
+}
+
+// TestCompressMiddlewareMinSize tests that small bodies are sent
+// uncompressed and flushed bodies compressed.
+func TestCompressMiddlewareMinSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		wantGzip bool
+	}{
+		{
+			name: "small body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"ok":true}`))
+			},
+		},
+		{
+			name: "flushed body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"ok":true}`))
+				w.(http.Flusher).Flush()
+			},
+			wantGzip: true,
+		},
+		{
+			name: "no content",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rec := httptest.NewRecorder()
+			CompressMiddleware(0)(tt.handler).ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Encoding") == "gzip"; got != tt.wantGzip {
+				t.Errorf("gzip = %v, want %v", got, tt.wantGzip)
+			}
+			if vary := rec.Header().Get("Vary"); vary != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", vary)
+			}
+		})
+	}
+}
+
+// searchDNs decodes a search response and returns its entry DNs, sorted.
+func searchDNs(t *testing.T, body []byte) []string {
+	t.Helper()
+	var resp SearchResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("failed to decode search response: %v", err)
+	}
+	dns := make([]string, 0, len(resp.Entries))
+	for _, entry := range resp.Entries {
+		dns = append(dns, entry.DN)
+	}
+	sort.Strings(dns)
+	return dns
+}
@@ -0,0 +1,190 @@
+package rest
+
+import (
+	"container/list"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cursor errors.
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrCursorExpired = errors.New("cursor expired")
+)
+
+// Cursor is the search state carried between pages of a paginated search.
+type Cursor struct {
+	ID         string `json:"id"`
+	BaseDN     string `json:"baseDN"`
+	Scope      int    `json:"scope"`
+	Filter     string `json:"filter,omitempty"`
+	Attributes string `json:"attributes,omitempty"`
+	LastSeenDN string `json:"lastSeenDN"`
+	SizeLimit  int    `json:"sizeLimit"`
+	Offset     int    `json:"offset"`
+	ExpiresAt  int64  `json:"exp"`
+}
+
+// CursorStoreConfig holds cursor store configuration.
+type CursorStoreConfig struct {
+	// TTL is how long an issued cursor stays valid.
+	TTL time.Duration
+	// Capacity is the maximum number of live cursors. The least recently
+	// issued cursors are evicted first.
+	Capacity int
+	// Secret signs cursor tokens. A random secret is generated if empty.
+	Secret []byte
+}
+
+// DefaultCursorStoreConfig returns default cursor store configuration.
+func DefaultCursorStoreConfig() *CursorStoreConfig {
+	return &CursorStoreConfig{
+		TTL:      5 * time.Minute,
+		Capacity: 10000,
+	}
+}
+
+// CursorStore issues and resolves opaque, signed cursor tokens.
+// Live cursors are tracked in an LRU cache so that the number of outstanding
+// cursors is bounded and each token can be used only once.
+type CursorStore struct {
+	secret   []byte
+	ttl      time.Duration
+	capacity int
+
+	entries map[string]*list.Element
+	order   *list.List // front = most recently issued
+	mu      sync.Mutex
+}
+
+// NewCursorStore creates a new cursor store.
+func NewCursorStore(cfg *CursorStoreConfig) *CursorStore {
+	if cfg == nil {
+		cfg = DefaultCursorStoreConfig()
+	}
+
+	defaults := DefaultCursorStoreConfig()
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = defaults.TTL
+	}
+	capacity := cfg.Capacity
+	if capacity <= 0 {
+		capacity = defaults.Capacity
+	}
+
+	secret := cfg.Secret
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		rand.Read(secret)
+	}
+
+	return &CursorStore{
+		secret:   secret,
+		ttl:      ttl,
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// SetTTL updates the cursor TTL for cursors issued from now on.
+func (s *CursorStore) SetTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ttl = ttl
+}
+
+// Issue registers the cursor and returns its token.
+func (s *CursorStore) Issue(c *Cursor) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c.ID = hex.EncodeToString(id)
+	c.ExpiresAt = time.Now().Add(s.ttl).Unix()
+
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	s.entries[c.ID] = s.order.PushFront(c.ID)
+	for s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(string))
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	signature := base64.RawURLEncoding.EncodeToString(s.sign([]byte(encoded)))
+	return encoded + "." + signature, nil
+}
+
+// Resolve validates a token and returns its cursor. A resolved cursor is
+// removed from the store, so each token can be used once.
+func (s *CursorStore) Resolve(token string) (*Cursor, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, s.sign([]byte(encoded))) {
+		return nil, ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.entries[c.ID]
+	if !ok {
+		return nil, ErrCursorExpired
+	}
+	s.order.Remove(elem)
+	delete(s.entries, c.ID)
+
+	if time.Now().Unix() > c.ExpiresAt {
+		return nil, ErrCursorExpired
+	}
+
+	return &c, nil
+}
+
+// Len returns the number of live cursors.
+func (s *CursorStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+func (s *CursorStore) sign(message []byte) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write(message)
+	return h.Sum(nil)
+}
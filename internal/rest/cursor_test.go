@@ -0,0 +1,197 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/backend"
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+func newSearchTestHandlers(t *testing.T, users int) *Handlers {
+	t.Helper()
+
+	db, err := engine.Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	be := backend.NewBackend(db, config.DefaultConfig())
+
+	base := backend.NewEntry("dc=example,dc=com")
+	base.SetAttribute("objectClass", "top", "domain")
+	base.SetAttribute("dc", "example")
+	if err := be.Add(context.Background(), base); err != nil {
+		t.Fatalf("failed to add base entry: %v", err)
+	}
+
+	ou := backend.NewEntry("ou=users,dc=example,dc=com")
+	ou.SetAttribute("objectClass", "top", "organizationalUnit")
+	ou.SetAttribute("ou", "users")
+	if err := be.Add(context.Background(), ou); err != nil {
+		t.Fatalf("failed to add ou entry: %v", err)
+	}
+
+	for i := 0; i < users; i++ {
+		uid := fmt.Sprintf("user%03d", i)
+		entry := backend.NewEntry("uid=" + uid + ",ou=users,dc=example,dc=com")
+		entry.SetAttribute("objectClass", "top", "person")
+		entry.SetAttribute("uid", uid)
+		entry.SetAttribute("cn", uid)
+		entry.SetAttribute("sn", uid)
+		if err := be.Add(context.Background(), entry); err != nil {
+			t.Fatalf("failed to add %s: %v", uid, err)
+		}
+	}
+
+	return NewHandlers(be, nil)
+}
+
+func doSearch(t *testing.T, h *Handlers, query url.Values) (int, SearchResponse) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/search?"+query.Encode(), nil)
+	rec := httptest.NewRecorder()
+	h.HandleSearch(rec, req)
+
+	var resp SearchResponse
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+	}
+	return rec.Code, resp
+}
+
+func TestSearchCursorPagination(t *testing.T) {
+	const users, pageSize = 500, 50
+	h := newSearchTestHandlers(t, users)
+
+	query := url.Values{}
+	query.Set("baseDN", "dc=example,dc=com")
+	query.Set("scope", "sub")
+	query.Set("filter", "(objectClass=person)")
+	query.Set("limit", fmt.Sprint(pageSize))
+
+	seen := make(map[string]int)
+	pages := 0
+	for {
+		code, resp := doSearch(t, h, query)
+		if code != http.StatusOK {
+			t.Fatalf("page %d: status %d", pages, code)
+		}
+		pages++
+		if len(resp.Entries) > pageSize {
+			t.Fatalf("page %d: %d entries exceeds page size", pages, len(resp.Entries))
+		}
+		for _, e := range resp.Entries {
+			seen[e.DN]++
+		}
+		if resp.NextCursor == "" {
+			if resp.HasMore {
+				t.Fatal("hasMore without nextCursor")
+			}
+			break
+		}
+		if pages > users/pageSize {
+			t.Fatal("too many pages")
+		}
+		query = url.Values{}
+		query.Set("cursor", resp.NextCursor)
+	}
+
+	if pages != users/pageSize {
+		t.Errorf("pages = %d, want %d", pages, users/pageSize)
+	}
+	if len(seen) != users {
+		t.Errorf("saw %d distinct entries, want %d", len(seen), users)
+	}
+	for dn, n := range seen {
+		if n != 1 {
+			t.Errorf("%s returned %d times", dn, n)
+		}
+	}
+}
+
+func TestSearchCursorRejectsReuseAndTampering(t *testing.T) {
+	h := newSearchTestHandlers(t, 10)
+
+	query := url.Values{}
+	query.Set("baseDN", "dc=example,dc=com")
+	query.Set("limit", "3")
+	_, resp := doSearch(t, h, query)
+	if resp.NextCursor == "" {
+		t.Fatal("expected nextCursor")
+	}
+
+	next := url.Values{}
+	next.Set("cursor", resp.NextCursor)
+	if code, _ := doSearch(t, h, next); code != http.StatusOK {
+		t.Fatalf("first use: status %d", code)
+	}
+	if code, _ := doSearch(t, h, next); code != http.StatusBadRequest {
+		t.Errorf("reused cursor: status %d, want 400", code)
+	}
+
+	tampered := url.Values{}
+	tampered.Set("cursor", "x"+resp.NextCursor)
+	if code, _ := doSearch(t, h, tampered); code != http.StatusBadRequest {
+		t.Errorf("tampered cursor: status %d, want 400", code)
+	}
+}
+
+func TestCursorStore(t *testing.T) {
+	store := NewCursorStore(&CursorStoreConfig{TTL: time.Minute, Capacity: 2})
+
+	first, err := store.Issue(&Cursor{BaseDN: "dc=example,dc=com", LastSeenDN: "uid=a", SizeLimit: 10})
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	c, err := store.Resolve(first)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if c.BaseDN != "dc=example,dc=com" || c.LastSeenDN != "uid=a" || c.SizeLimit != 10 {
+		t.Errorf("cursor = %+v", c)
+	}
+
+	// The oldest cursor is evicted once capacity is exceeded.
+	oldest, _ := store.Issue(&Cursor{BaseDN: "a"})
+	store.Issue(&Cursor{BaseDN: "b"})
+	store.Issue(&Cursor{BaseDN: "c"})
+	if store.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", store.Len())
+	}
+	if _, err := store.Resolve(oldest); !errors.Is(err, ErrCursorExpired) {
+		t.Errorf("evicted cursor: got %v, want ErrCursorExpired", err)
+	}
+
+	other := NewCursorStore(nil)
+	token, _ := other.Issue(&Cursor{BaseDN: "a"})
+	if _, err := store.Resolve(token); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("foreign cursor: got %v, want ErrInvalidCursor", err)
+	}
+
+	expiring := NewCursorStore(&CursorStoreConfig{TTL: time.Minute})
+	token, _ = expiring.Issue(&Cursor{BaseDN: "a"})
+	expiring.mu.Lock()
+	expiring.ttl = -time.Hour
+	expiring.mu.Unlock()
+	stale, _ := expiring.Issue(&Cursor{BaseDN: "b"})
+	if _, err := expiring.Resolve(stale); !errors.Is(err, ErrCursorExpired) {
+		t.Errorf("expired cursor: got %v, want ErrCursorExpired", err)
+	}
+	if _, err := expiring.Resolve(token); err != nil {
+		t.Errorf("live cursor: %v", err)
+	}
+}
@@ -26,9 +26,10 @@
 //
 // Other:
 //
-//	POST /api/v1/bulk    - Bulk operations
-//	POST /api/v1/compare - Compare attribute value
-//	GET  /api/v1/health  - Health check
+//	POST /api/v1/bulk                 - Bulk operations
+//	POST /api/v1/compare              - Compare attribute value
+//	GET  /api/v1/groups/{dn}/members  - List or check group members
+//	GET  /api/v1/health               - Health check
 //
 // # Authentication
 //
@@ -43,6 +43,7 @@ var ldapToHTTPStatus = map[ldap.ResultCode]int{
 	ldap.ResultNotAllowedOnRDN:             http.StatusBadRequest,
 	ldap.ResultEntryAlreadyExists:          http.StatusConflict,
 	ldap.ResultObjectClassModsProhibited:   http.StatusBadRequest,
+	ldap.ResultAssertionFailed:             http.StatusPreconditionFailed,
 	ldap.ResultOther:                       http.StatusInternalServerError,
 }
 
@@ -57,24 +58,54 @@ func mapBackendError(err error) (int, string, string) {
 	if errors.Is(err, backend.ErrInvalidPlacement) {
 		return http.StatusBadRequest, "invalid_placement", "entry must be under the correct OU"
 	}
+	if errors.Is(err, backend.ErrNamingViolation) {
+		return http.StatusBadRequest, "naming_violation", err.Error()
+	}
+	if errors.Is(err, backend.ErrObjectClassViolation) {
+		return http.StatusBadRequest, "object_class_violation", err.Error()
+	}
+	if errors.Is(err, backend.ErrNoUserModification) {
+		return http.StatusBadRequest, "constraint_violation", err.Error()
+	}
+	if errors.Is(err, backend.ErrSubtreeTooLarge) {
+		return http.StatusRequestEntityTooLarge, "subtree_too_large", err.Error()
+	}
+	if errors.Is(err, backend.ErrBusy) {
+		return http.StatusServiceUnavailable, "busy", err.Error()
+	}
+	if errors.Is(err, backend.ErrAssertionFailed) {
+		return http.StatusPreconditionFailed, "precondition_failed", "entry has been modified"
+	}
+	var hookErr *backend.HookError
+	if errors.As(err, &hookErr) {
+		return mapLDAPResultCode(hookErr.ResultCode), "rejected_by_hook", hookErr.Message
+	}
 
-	switch err {
-	case backend.ErrInvalidCredentials:
+	switch {
+	case errors.Is(err, backend.ErrInvalidCredentials):
 		return http.StatusUnauthorized, "invalid_credentials", "invalid credentials"
-	case backend.ErrEntryNotFound:
+	case errors.Is(err, backend.ErrEntryNotFound):
 		return http.StatusNotFound, "not_found", "entry not found"
-	case backend.ErrEntryExists:
+	case errors.Is(err, backend.ErrEntryExists):
 		return http.StatusConflict, "entry_exists", "entry already exists"
-	case backend.ErrInvalidDN:
+	case errors.Is(err, backend.ErrInvalidDN):
 		return http.StatusBadRequest, "invalid_dn", "invalid DN"
-	case backend.ErrInvalidEntry:
+	case errors.Is(err, backend.ErrInvalidEntry):
 		return http.StatusBadRequest, "invalid_entry", "invalid entry"
-	case backend.ErrNoPassword:
+	case errors.Is(err, backend.ErrNoPassword):
 		return http.StatusBadRequest, "no_password", "no password attribute"
-	case backend.ErrStorageError:
+	case errors.Is(err, backend.ErrStorageError):
 		return http.StatusInternalServerError, "storage_error", "storage error"
-	case backend.ErrNotAllowedOnNonLeaf:
+	case errors.Is(err, backend.ErrNotAllowedOnNonLeaf):
 		return http.StatusConflict, "not_allowed_on_non_leaf", "operation not allowed on non-leaf entry"
+	case errors.Is(err, backend.ErrInsufficientAccess):
+		return http.StatusForbidden, "insufficient_access", "insufficient access rights"
+	case errors.Is(err, backend.ErrRecycleBinDisabled):
+		return http.StatusNotFound, "recycle_bin_disabled", "recycle bin is disabled"
+	case errors.Is(err, backend.ErrDeletedEntryNotFound):
+		return http.StatusNotFound, "not_found", "deleted entry not found"
+	case errors.Is(err, backend.ErrRestoreNoParent):
+		return http.StatusConflict, "no_parent", "parent of deleted entry does not exist"
 	default:
 		if strings.Contains(strings.ToLower(err.Error()), "uid attribute") &&
 			strings.Contains(strings.ToLower(err.Error()), "unique") {
@@ -94,20 +125,11 @@ func mapLDAPResultCode(code ldap.ResultCode) int {
 
 // ldapResultCodeFromError returns LDAP result code from backend error.
 func ldapResultCodeFromError(err error) int {
-	switch err {
-	case backend.ErrInvalidCredentials:
-		return int(ldap.ResultInvalidCredentials)
-	case backend.ErrEntryNotFound:
-		return int(ldap.ResultNoSuchObject)
-	case backend.ErrEntryExists:
-		return int(ldap.ResultEntryAlreadyExists)
-	case backend.ErrInvalidDN:
-		return int(ldap.ResultInvalidDNSyntax)
-	case backend.ErrNotAllowedOnNonLeaf:
-		return int(ldap.ResultNotAllowedOnNonLeaf)
-	default:
-		return int(ldap.ResultOther)
+	var ldapErr *backend.LDAPError
+	if errors.As(err, &ldapErr) {
+		return int(ldapErr.ResultCode)
 	}
+	return int(ldap.ResultOther)
 }
 
 func writeJSON(w http.ResponseWriter, status int, data interface{}) {
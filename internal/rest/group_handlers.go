@@ -0,0 +1,46 @@
+package rest
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// HandleGetGroupMembers handles GET /api/v1/groups/{dn}/members
+// Query params:
+// - member=<DN> only checks whether the given DN is a member
+//
+// Members selected by the memberURL of a dynamic group are included when
+// dynamic groups are enabled.
+func (h *Handlers) HandleGetGroupMembers(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&h.requestCount, 1)
+
+	dn, ok := pathDN(w, r)
+	if !ok {
+		return
+	}
+	member := r.URL.Query().Get("member")
+	if member != "" && !checkDN(w, "member", member) {
+		return
+	}
+
+	members, partial, err := h.backend.GroupMembers(dn)
+	if err != nil {
+		status, code, msg := mapBackendError(err)
+		writeError(w, status, code, msg)
+		return
+	}
+
+	if member != "" {
+		isMember := h.backend.IsMember(dn, member)
+		h.auditLog(r, "check group member", "dn", dn, "member", member, "isMember", isMember)
+		writeJSON(w, http.StatusOK, GroupMembershipResponse{DN: dn, Member: member, IsMember: isMember})
+		return
+	}
+
+	h.auditLog(r, "get group members", "dn", dn, "members", len(members), "partial", partial)
+	writeJSON(w, http.StatusOK, GroupMembersResponse{
+		DN:      dn,
+		Members: members,
+		Partial: partial,
+	})
+}
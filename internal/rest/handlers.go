@@ -20,6 +20,7 @@ import (
 	"github.com/KilimcininKorOglu/oba/internal/ldap"
 	"github.com/KilimcininKorOglu/oba/internal/logging"
 	"github.com/KilimcininKorOglu/oba/internal/raft"
+	"github.com/KilimcininKorOglu/oba/internal/server"
 	"github.com/KilimcininKorOglu/oba/internal/storage/radix"
 )
 
@@ -29,7 +30,11 @@ type Handlers struct {
 	auth          *Authenticator
 	aclManager    *acl.Manager
 	configManager *config.ConfigManager
+	bindThrottle  *server.BindThrottle
+	wireDump      *server.WireDump
 	logger        logging.Logger
+	cursors       *CursorStore
+	jobs          *server.JobManager
 	startTime     time.Time
 	requestCount  int64
 	activeConns   int64
@@ -54,15 +59,37 @@ func NewHandlers(be *backend.ObaBackend, auth *Authenticator) *Handlers {
 	return &Handlers{
 		backend:   be,
 		auth:      auth,
+		cursors:   NewCursorStore(nil),
+		jobs:      newMemoryJobManager(),
 		startTime: time.Now(),
 	}
 }
 
+// SetCursorStore sets the store used for search pagination cursors.
+func (h *Handlers) SetCursorStore(cs *CursorStore) {
+	h.cursors = cs
+}
+
 // SetACLManager sets the ACL manager for ACL-related endpoints.
 func (h *Handlers) SetACLManager(m *acl.Manager) {
 	h.aclManager = m
 }
 
+// SetBindThrottle sets the LDAP bind throttle for admin endpoints.
+func (h *Handlers) SetBindThrottle(t *server.BindThrottle) {
+	h.bindThrottle = t
+}
+
+// SetJobManager sets the manager that runs admin jobs.
+func (h *Handlers) SetJobManager(jm *server.JobManager) {
+	h.jobs = jm
+}
+
+// SetWireDump sets the LDAP wire dump for admin endpoints.
+func (h *Handlers) SetWireDump(wd *server.WireDump) {
+	h.wireDump = wd
+}
+
 // SetConfigManager sets the config manager for config-related endpoints.
 func (h *Handlers) SetConfigManager(m *config.ConfigManager) {
 	h.configManager = m
@@ -93,7 +120,7 @@ func (h *Handlers) auditLogWithUser(r *http.Request, user string, msg string, ke
 	if h.logger == nil {
 		return
 	}
-	logger := h.logger.WithSource("rest")
+	logger := h.logger.WithComponent("rest")
 	// Use explicit user if provided, otherwise get from context
 	if user == "" {
 		user = BindDN(r)
@@ -102,7 +129,7 @@ func (h *Handlers) auditLogWithUser(r *http.Request, user string, msg string, ke
 		logger = logger.WithUser(user)
 	}
 	// Add client IP
-	keyvals = append(keyvals, "remoteAddr", getClientIPFromRequest(r))
+	keyvals = append(keyvals, "remoteAddr", getClientIP(r))
 
 	// Audit logging must never block request handling.
 	// In cluster mode, log persistence may briefly wait for Raft replay.
@@ -110,18 +137,6 @@ func (h *Handlers) auditLogWithUser(r *http.Request, user string, msg string, ke
 	go logger.Info(msg, fields...)
 }
 
-// getClientIPFromRequest extracts client IP from request
-func getClientIPFromRequest(r *http.Request) string {
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		parts := strings.Split(xff, ",")
-		return strings.TrimSpace(parts[0])
-	}
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return xri
-	}
-	return r.RemoteAddr
-}
-
 // HandleBind handles POST /api/v1/auth/bind
 func (h *Handlers) HandleBind(w http.ResponseWriter, r *http.Request) {
 	atomic.AddInt64(&h.requestCount, 1)
@@ -132,8 +147,11 @@ func (h *Handlers) HandleBind(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid_request", "invalid JSON body")
 		return
 	}
+	if req.DN != "" && !checkDN(w, "DN", req.DN) {
+		return
+	}
 
-	token, err := h.auth.Authenticate(req.DN, req.Password)
+	token, err := h.auth.Authenticate(r.Context(), req.DN, req.Password)
 	if err != nil {
 		atomic.AddInt64(&h.failedLogins24h, 1)
 		if err == backend.ErrInvalidCredentials {
@@ -162,19 +180,12 @@ func (h *Handlers) HandleBind(w http.ResponseWriter, r *http.Request) {
 func (h *Handlers) HandleGetEntry(w http.ResponseWriter, r *http.Request) {
 	atomic.AddInt64(&h.requestCount, 1)
 
-	dn := Param(r, "dn")
-	if dn == "" {
-		writeError(w, http.StatusBadRequest, "missing_dn", "DN is required")
+	decodedDN, ok := pathDN(w, r)
+	if !ok {
 		return
 	}
 
-	decodedDN, err := url.PathUnescape(dn)
-	if err != nil {
-		writeError(w, http.StatusBadRequest, "invalid_dn", "invalid DN encoding")
-		return
-	}
-
-	entries, err := h.backend.Search(decodedDN, int(ldap.ScopeBaseObject), nil)
+	entries, err := h.backend.Search(r.Context(), decodedDN, int(ldap.ScopeBaseObject), nil)
 	if err != nil {
 		status, code, msg := mapBackendError(err)
 		writeError(w, status, code, msg)
@@ -185,8 +196,10 @@ func (h *Handlers) HandleGetEntry(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusNotFound, "not_found", "entry not found")
 		return
 	}
+	entries = h.backend.ExpandDynamicGroups(r.Context(), entries)
 
 	h.auditLog(r, "get entry", "dn", decodedDN)
+	setETag(w, entries[0])
 	writeJSON(w, http.StatusOK, convertEntry(entries[0]))
 }
 
@@ -196,11 +209,26 @@ func (h *Handlers) HandleSearch(w http.ResponseWriter, r *http.Request) {
 	atomic.AddInt64(&h.searchCount, 1)
 	query := r.URL.Query()
 
+	// A cursor replaces the query with the parameters of the original search.
+	var cursor *Cursor
+	if token := query.Get("cursor"); token != "" {
+		var err error
+		cursor, err = h.cursors.Resolve(token)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_cursor", err.Error())
+			return
+		}
+		query = cursor.query()
+	}
+
 	baseDN := query.Get("baseDN")
 	if baseDN == "" {
 		writeError(w, http.StatusBadRequest, "missing_base_dn", "baseDN is required")
 		return
 	}
+	if !checkDN(w, "baseDN", baseDN) {
+		return
+	}
 
 	scopeStr := query.Get("scope")
 	scope := ldap.ScopeWholeSubtree
@@ -216,14 +244,12 @@ func (h *Handlers) HandleSearch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse filter if provided
+	// Parse filter if provided. Only its re-serialized form is kept.
 	var searchFilter *filter.Filter
-	filterStr := query.Get("filter")
-	if filterStr != "" {
-		var err error
-		searchFilter, err = filter.Parse(filterStr)
-		if err != nil {
-			writeError(w, http.StatusBadRequest, "invalid_filter", "invalid filter syntax: "+err.Error())
+	var filterStr string
+	if raw := query.Get("filter"); raw != "" {
+		var ok bool
+		if searchFilter, filterStr, ok = parseSearchFilter(w, raw); !ok {
 			return
 		}
 	}
@@ -246,9 +272,9 @@ func (h *Handlers) HandleSearch(w http.ResponseWriter, r *http.Request) {
 
 	var requestedAttrs []string
 	if attrs := query.Get("attributes"); attrs != "" {
-		requestedAttrs = strings.Split(attrs, ",")
-		for i := range requestedAttrs {
-			requestedAttrs[i] = strings.TrimSpace(requestedAttrs[i])
+		var ok bool
+		if requestedAttrs, ok = parseAttributeList(w, attrs); !ok {
+			return
 		}
 	}
 
@@ -257,7 +283,7 @@ func (h *Handlers) HandleSearch(w http.ResponseWriter, r *http.Request) {
 		timeLimit, _ = strconv.Atoi(tl)
 	}
 
-	ctx := r.Context()
+	ctx := operationContext(r)
 	if timeLimit > 0 {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeLimit)*time.Second)
@@ -269,7 +295,7 @@ func (h *Handlers) HandleSearch(w http.ResponseWriter, r *http.Request) {
 	var searchErr error
 
 	go func() {
-		entries, searchErr = h.backend.Search(baseDN, int(scope), searchFilter)
+		entries, searchErr = h.backend.Search(ctx, baseDN, int(scope), searchFilter)
 		close(searchDone)
 	}()
 
@@ -290,7 +316,7 @@ func (h *Handlers) HandleSearch(w http.ResponseWriter, r *http.Request) {
 	// from subtree results so UI listing does not appear empty.
 	if scope == ldap.ScopeSingleLevel && searchFilter == nil && len(entries) == 0 {
 		subtreeFilter := filter.NewPresentFilter("objectClass")
-		subtreeEntries, err := h.backend.Search(baseDN, int(ldap.ScopeWholeSubtree), subtreeFilter)
+		subtreeEntries, err := h.backend.Search(r.Context(), baseDN, int(ldap.ScopeWholeSubtree), subtreeFilter)
 		if err == nil {
 			directChildren := make([]*backend.Entry, 0, len(subtreeEntries))
 			for _, entry := range subtreeEntries {
@@ -344,8 +370,16 @@ func (h *Handlers) HandleSearch(w http.ResponseWriter, r *http.Request) {
 
 	totalCount := len(entries)
 
+	// Paged results are returned in DN order so that cursors can resume
+	// after the last DN seen.
+	if limit > 0 || cursor != nil {
+		sortEntriesByDN(entries)
+	}
+
 	hasMore := false
-	if offset > 0 {
+	if cursor != nil {
+		entries = entriesAfterDN(entries, cursor.LastSeenDN)
+	} else if offset > 0 {
 		if offset >= len(entries) {
 			entries = nil
 		} else {
@@ -357,6 +391,23 @@ func (h *Handlers) HandleSearch(w http.ResponseWriter, r *http.Request) {
 		hasMore = true
 	}
 
+	nextCursor := ""
+	if hasMore {
+		token, err := h.cursors.Issue(&Cursor{
+			BaseDN:     baseDN,
+			Scope:      int(scope),
+			Filter:     filterStr,
+			Attributes: strings.Join(requestedAttrs, ","),
+			LastSeenDN: entries[len(entries)-1].DN,
+			SizeLimit:  limit,
+			Offset:     offset + len(entries),
+		})
+		if err == nil {
+			nextCursor = token
+		}
+	}
+
+	entries = h.backend.ExpandDynamicGroups(r.Context(), entries)
 	result := make([]*Entry, len(entries))
 	for i, e := range entries {
 		result[i] = convertEntryWithAttrs(e, requestedAttrs)
@@ -379,7 +430,47 @@ func (h *Handlers) HandleSearch(w http.ResponseWriter, r *http.Request) {
 		Offset:     offset,
 		Limit:      limit,
 		HasMore:    hasMore,
+		NextCursor: nextCursor,
+	})
+}
+
+// query rebuilds the search query parameters stored in the cursor.
+func (c *Cursor) query() url.Values {
+	query := url.Values{}
+	query.Set("baseDN", c.BaseDN)
+	switch ldap.SearchScope(c.Scope) {
+	case ldap.ScopeBaseObject:
+		query.Set("scope", "base")
+	case ldap.ScopeSingleLevel:
+		query.Set("scope", "one")
+	default:
+		query.Set("scope", "sub")
+	}
+	if c.Filter != "" {
+		query.Set("filter", c.Filter)
+	}
+	if c.Attributes != "" {
+		query.Set("attributes", c.Attributes)
+	}
+	query.Set("offset", strconv.Itoa(c.Offset))
+	query.Set("limit", strconv.Itoa(c.SizeLimit))
+	return query
+}
+
+// sortEntriesByDN sorts entries by case-insensitive DN.
+func sortEntriesByDN(entries []*backend.Entry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return strings.ToLower(entries[i].DN) < strings.ToLower(entries[j].DN)
+	})
+}
+
+// entriesAfterDN returns the entries of a DN-sorted slice that sort after lastDN.
+func entriesAfterDN(entries []*backend.Entry, lastDN string) []*backend.Entry {
+	last := strings.ToLower(lastDN)
+	idx := sort.Search(len(entries), func(i int) bool {
+		return strings.ToLower(entries[i].DN) > last
 	})
+	return entries[idx:]
 }
 
 // HandleAddEntry handles POST /api/v1/entries
@@ -397,14 +488,16 @@ func (h *Handlers) HandleAddEntry(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "missing_dn", "DN is required")
 		return
 	}
+	if !checkDN(w, "DN", req.DN) {
+		return
+	}
 
 	entry := &backend.Entry{
 		DN:         req.DN,
-		Attributes: req.Attributes,
+		Attributes: toByteAttributes(req.Attributes),
 	}
 
-	bindDN := BindDN(r)
-	err := h.backend.AddWithBindDN(entry, bindDN)
+	err := h.backend.Add(operationContext(r), entry)
 	if err != nil {
 		status, code, msg := mapBackendError(err)
 		writeError(w, status, code, msg)
@@ -421,15 +514,8 @@ func (h *Handlers) HandleModifyEntry(w http.ResponseWriter, r *http.Request) {
 	atomic.AddInt64(&h.requestCount, 1)
 	atomic.AddInt64(&h.modifyCount, 1)
 
-	dn := Param(r, "dn")
-	if dn == "" {
-		writeError(w, http.StatusBadRequest, "missing_dn", "DN is required")
-		return
-	}
-
-	decodedDN, err := url.PathUnescape(dn)
-	if err != nil {
-		writeError(w, http.StatusBadRequest, "invalid_dn", "invalid DN encoding")
+	decodedDN, ok := pathDN(w, r)
+	if !ok {
 		return
 	}
 
@@ -461,8 +547,7 @@ func (h *Handlers) HandleModifyEntry(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	bindDN := BindDN(r)
-	err = h.backend.ModifyWithBindDN(decodedDN, changes, bindDN)
+	err := h.backend.Modify(ifMatchContext(operationContext(r), r), decodedDN, changes)
 	if err != nil {
 		status, code, msg := mapBackendError(err)
 		writeError(w, status, code, msg)
@@ -471,8 +556,9 @@ func (h *Handlers) HandleModifyEntry(w http.ResponseWriter, r *http.Request) {
 
 	h.auditLog(r, "entry modified", "dn", decodedDN, "changes", len(changes))
 
-	entries, _ := h.backend.Search(decodedDN, int(ldap.ScopeBaseObject), nil)
+	entries, _ := h.backend.Search(r.Context(), decodedDN, int(ldap.ScopeBaseObject), nil)
 	if len(entries) > 0 {
+		setETag(w, entries[0])
 		writeJSON(w, http.StatusOK, convertEntry(entries[0]))
 	} else {
 		w.WriteHeader(http.StatusNoContent)
@@ -480,30 +566,67 @@ func (h *Handlers) HandleModifyEntry(w http.ResponseWriter, r *http.Request) {
 }
 
 // HandleDeleteEntry handles DELETE /api/v1/entries/{dn}
+// Query params:
+// - subtree=true|false (default false): also delete all descendants
 func (h *Handlers) HandleDeleteEntry(w http.ResponseWriter, r *http.Request) {
 	atomic.AddInt64(&h.requestCount, 1)
 	atomic.AddInt64(&h.deleteCount, 1)
 
-	dn := Param(r, "dn")
-	if dn == "" {
-		writeError(w, http.StatusBadRequest, "missing_dn", "DN is required")
+	decodedDN, ok := pathDN(w, r)
+	if !ok {
 		return
 	}
 
-	decodedDN, err := url.PathUnescape(dn)
+	if raw := r.URL.Query().Get("subtree"); raw != "" {
+		subtree, err := strconv.ParseBool(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_subtree", "subtree must be true or false")
+			return
+		}
+		if subtree {
+			// The precondition applies to the root of the subtree.
+			if err := h.backend.Assert(ifMatchContext(operationContext(r), r), decodedDN); err != nil {
+				status, code, msg := mapBackendError(err)
+				writeError(w, status, code, msg)
+				return
+			}
+			h.deleteSubtree(w, r, decodedDN)
+			return
+		}
+	}
+
+	err := h.backend.Delete(ifMatchContext(operationContext(r), r), decodedDN)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "invalid_dn", "invalid DN encoding")
+		status, code, msg := mapBackendError(err)
+		writeError(w, status, code, msg)
 		return
 	}
 
-	err = h.backend.Delete(decodedDN)
+	h.auditLog(r, "entry deleted", "dn", decodedDN)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteSubtree deletes an entry and all of its descendants. When an ACL
+// manager is set, the caller needs delete rights on every entry.
+func (h *Handlers) deleteSubtree(w http.ResponseWriter, r *http.Request, dn string) {
+	var allow func(string) bool
+	if h.aclManager != nil {
+		bindDN := BindDN(r)
+		allow = func(entryDN string) bool {
+			return h.aclManager.CanDelete(bindDN, entryDN)
+		}
+	}
+
+	deleted, err := h.backend.DeleteSubtree(operationContext(r), dn, allow)
+	if len(deleted) > 0 {
+		h.auditLog(r, "subtree deleted", "dn", dn, "entries", len(deleted))
+	}
 	if err != nil {
 		status, code, msg := mapBackendError(err)
 		writeError(w, status, code, msg)
 		return
 	}
 
-	h.auditLog(r, "entry deleted", "dn", decodedDN)
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -511,24 +634,16 @@ func (h *Handlers) HandleDeleteEntry(w http.ResponseWriter, r *http.Request) {
 func (h *Handlers) HandleDisableEntry(w http.ResponseWriter, r *http.Request) {
 	atomic.AddInt64(&h.requestCount, 1)
 
-	dn := Param(r, "dn")
-	if dn == "" {
-		writeError(w, http.StatusBadRequest, "missing_dn", "DN is required")
-		return
-	}
-
-	decodedDN, err := url.PathUnescape(dn)
-	if err != nil {
-		writeError(w, http.StatusBadRequest, "invalid_dn", "invalid DN encoding")
+	decodedDN, ok := pathDN(w, r)
+	if !ok {
 		return
 	}
 
-	bindDN := BindDN(r)
 	changes := []backend.Modification{
 		{Type: backend.ModReplace, Attribute: "obaDisabled", Values: []string{"TRUE"}},
 	}
 
-	err = h.backend.ModifyWithBindDN(decodedDN, changes, bindDN)
+	err := h.backend.Modify(operationContext(r), decodedDN, changes)
 	if err != nil {
 		status, code, msg := mapBackendError(err)
 		writeError(w, status, code, msg)
@@ -543,24 +658,16 @@ func (h *Handlers) HandleDisableEntry(w http.ResponseWriter, r *http.Request) {
 func (h *Handlers) HandleEnableEntry(w http.ResponseWriter, r *http.Request) {
 	atomic.AddInt64(&h.requestCount, 1)
 
-	dn := Param(r, "dn")
-	if dn == "" {
-		writeError(w, http.StatusBadRequest, "missing_dn", "DN is required")
-		return
-	}
-
-	decodedDN, err := url.PathUnescape(dn)
-	if err != nil {
-		writeError(w, http.StatusBadRequest, "invalid_dn", "invalid DN encoding")
+	decodedDN, ok := pathDN(w, r)
+	if !ok {
 		return
 	}
 
-	bindDN := BindDN(r)
 	changes := []backend.Modification{
 		{Type: backend.ModDelete, Attribute: "obaDisabled", Values: nil},
 	}
 
-	err = h.backend.ModifyWithBindDN(decodedDN, changes, bindDN)
+	err := h.backend.Modify(operationContext(r), decodedDN, changes)
 	if err != nil {
 		// Ignore "no such attribute" error when enabling
 		if err != backend.ErrEntryNotFound {
@@ -578,15 +685,8 @@ func (h *Handlers) HandleEnableEntry(w http.ResponseWriter, r *http.Request) {
 func (h *Handlers) HandleUnlockEntry(w http.ResponseWriter, r *http.Request) {
 	atomic.AddInt64(&h.requestCount, 1)
 
-	dn := Param(r, "dn")
-	if dn == "" {
-		writeError(w, http.StatusBadRequest, "missing_dn", "DN is required")
-		return
-	}
-
-	decodedDN, err := url.PathUnescape(dn)
-	if err != nil {
-		writeError(w, http.StatusBadRequest, "invalid_dn", "invalid DN encoding")
+	decodedDN, ok := pathDN(w, r)
+	if !ok {
 		return
 	}
 
@@ -601,19 +701,12 @@ func (h *Handlers) HandleUnlockEntry(w http.ResponseWriter, r *http.Request) {
 func (h *Handlers) HandleGetLockStatus(w http.ResponseWriter, r *http.Request) {
 	atomic.AddInt64(&h.requestCount, 1)
 
-	dn := Param(r, "dn")
-	if dn == "" {
-		writeError(w, http.StatusBadRequest, "missing_dn", "DN is required")
+	decodedDN, ok := pathDN(w, r)
+	if !ok {
 		return
 	}
 
-	decodedDN, err := url.PathUnescape(dn)
-	if err != nil {
-		writeError(w, http.StatusBadRequest, "invalid_dn", "invalid DN encoding")
-		return
-	}
-
-	locked := h.backend.IsAccountLocked(decodedDN)
+	locked := h.backend.IsAccountLocked(r.Context(), decodedDN)
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{"dn": decodedDN, "locked": locked})
 }
@@ -622,15 +715,8 @@ func (h *Handlers) HandleGetLockStatus(w http.ResponseWriter, r *http.Request) {
 func (h *Handlers) HandleModifyDN(w http.ResponseWriter, r *http.Request) {
 	atomic.AddInt64(&h.requestCount, 1)
 
-	dn := Param(r, "dn")
-	if dn == "" {
-		writeError(w, http.StatusBadRequest, "missing_dn", "DN is required")
-		return
-	}
-
-	decodedDN, err := url.PathUnescape(dn)
-	if err != nil {
-		writeError(w, http.StatusBadRequest, "invalid_dn", "invalid DN encoding")
+	decodedDN, ok := pathDN(w, r)
+	if !ok {
 		return
 	}
 
@@ -644,6 +730,13 @@ func (h *Handlers) HandleModifyDN(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "missing_new_rdn", "newRDN is required")
 		return
 	}
+	if len(req.NewRDN) > maxDNLength || validateRDN(req.NewRDN) != nil {
+		writeError(w, http.StatusBadRequest, "invalid_dn", "newRDN: "+errInvalidRDN.Error())
+		return
+	}
+	if req.NewSuperior != "" && !checkDN(w, "newSuperior", req.NewSuperior) {
+		return
+	}
 
 	// Use backend's ModifyDN which handles cluster mode atomically
 	modifyReq := &backend.ModifyDNRequest{
@@ -653,7 +746,7 @@ func (h *Handlers) HandleModifyDN(w http.ResponseWriter, r *http.Request) {
 		NewSuperior:  req.NewSuperior,
 	}
 
-	if err := h.backend.ModifyDN(modifyReq); err != nil {
+	if err := h.backend.ModifyDN(ifMatchContext(operationContext(r), r), modifyReq); err != nil {
 		status, code, msg := mapBackendError(err)
 		writeError(w, status, code, msg)
 		return
@@ -665,7 +758,7 @@ func (h *Handlers) HandleModifyDN(w http.ResponseWriter, r *http.Request) {
 	h.auditLog(r, "entry moved", "oldDN", decodedDN, "newDN", newDN)
 
 	// Fetch the moved entry for response
-	entries, _ := h.backend.Search(newDN, int(ldap.ScopeBaseObject), nil)
+	entries, _ := h.backend.Search(r.Context(), newDN, int(ldap.ScopeBaseObject), nil)
 	if len(entries) > 0 {
 		w.Header().Set("Location", "/api/v1/entries/"+url.PathEscape(newDN))
 		writeJSON(w, http.StatusOK, convertEntry(entries[0]))
@@ -675,6 +768,56 @@ func (h *Handlers) HandleModifyDN(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// HandleListDeleted handles GET /api/v1/deleted
+func (h *Handlers) HandleListDeleted(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&h.requestCount, 1)
+
+	entries, err := h.backend.ListDeleted()
+	if err != nil {
+		status, code, msg := mapBackendError(err)
+		writeError(w, status, code, msg)
+		return
+	}
+
+	result := make([]*Entry, len(entries))
+	for i, entry := range entries {
+		result[i] = convertEntry(entry)
+	}
+	writeJSON(w, http.StatusOK, &SearchResponse{
+		Entries:    result,
+		TotalCount: len(result),
+		Limit:      len(result),
+	})
+}
+
+// HandleRestoreDeleted handles POST /api/v1/deleted/{id}/restore
+func (h *Handlers) HandleRestoreDeleted(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&h.requestCount, 1)
+
+	id := Param(r, "id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "missing_id", "deleted entry ID is required")
+		return
+	}
+
+	dn, err := h.backend.Restore(id)
+	if err != nil {
+		status, code, msg := mapBackendError(err)
+		writeError(w, status, code, msg)
+		return
+	}
+
+	h.auditLog(r, "entry restored", "dn", dn, "id", id)
+
+	w.Header().Set("Location", "/api/v1/entries/"+url.PathEscape(dn))
+	entries, _ := h.backend.Search(r.Context(), dn, int(ldap.ScopeBaseObject), nil)
+	if len(entries) > 0 {
+		writeJSON(w, http.StatusOK, convertEntry(entries[0]))
+	} else {
+		writeJSON(w, http.StatusOK, map[string]string{"dn": dn})
+	}
+}
+
 // HandleCompare handles POST /api/v1/compare
 func (h *Handlers) HandleCompare(w http.ResponseWriter, r *http.Request) {
 	atomic.AddInt64(&h.requestCount, 1)
@@ -685,15 +828,18 @@ func (h *Handlers) HandleCompare(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid_request", "invalid JSON body")
 		return
 	}
+	if !checkDN(w, "DN", req.DN) {
+		return
+	}
 
-	entries, err := h.backend.Search(req.DN, int(ldap.ScopeBaseObject), nil)
+	entries, err := h.backend.Search(r.Context(), req.DN, int(ldap.ScopeBaseObject), nil)
 	if err != nil || len(entries) == 0 {
 		writeError(w, http.StatusNotFound, "not_found", "entry not found")
 		return
 	}
 
 	entry := entries[0]
-	values := entry.GetAttribute(req.Attribute)
+	values := entry.GetStringAttribute(req.Attribute)
 	match := false
 	for _, v := range values {
 		if v == req.Value {
@@ -840,7 +986,7 @@ func (h *Handlers) HandleBulk(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	bindDN := BindDN(r)
+	ctx := operationContext(r)
 	results := make([]BulkOperationResult, len(req.Operations))
 	succeeded := 0
 	failed := 0
@@ -852,16 +998,18 @@ func (h *Handlers) HandleBulk(w http.ResponseWriter, r *http.Request) {
 			Operation: op.Operation,
 		}
 
-		var err error
-		switch op.Operation {
-		case "add":
+		err := validateDN(op.DN)
+		switch {
+		case err != nil:
+			err = fmt.Errorf("%w: %v", backend.ErrInvalidDN, err)
+		case op.Operation == "add":
 			entry := &backend.Entry{
 				DN:         op.DN,
-				Attributes: op.Attributes,
+				Attributes: toByteAttributes(op.Attributes),
 			}
-			err = h.backend.AddWithBindDN(entry, bindDN)
+			err = h.backend.Add(ctx, entry)
 
-		case "modify":
+		case op.Operation == "modify":
 			changes := make([]backend.Modification, len(op.Changes))
 			for j, c := range op.Changes {
 				var modType backend.ModificationType
@@ -879,10 +1027,10 @@ func (h *Handlers) HandleBulk(w http.ResponseWriter, r *http.Request) {
 					Values:    c.Values,
 				}
 			}
-			err = h.backend.ModifyWithBindDN(op.DN, changes, bindDN)
+			err = h.backend.Modify(ctx, op.DN, changes)
 
-		case "delete":
-			err = h.backend.Delete(op.DN)
+		case op.Operation == "delete":
+			err = h.backend.Delete(ctx, op.DN)
 
 		default:
 			err = fmt.Errorf("unknown operation: %s", op.Operation)
@@ -943,6 +1091,9 @@ func (h *Handlers) HandleStreamSearch(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "missing_base_dn", "baseDN is required")
 		return
 	}
+	if !checkDN(w, "baseDN", baseDN) {
+		return
+	}
 
 	scopeStr := query.Get("scope")
 	scope := ldap.ScopeWholeSubtree
@@ -953,14 +1104,12 @@ func (h *Handlers) HandleStreamSearch(w http.ResponseWriter, r *http.Request) {
 		scope = ldap.ScopeSingleLevel
 	}
 
-	// Parse filter if provided
+	// Parse filter if provided. Only its re-serialized form is kept.
 	var searchFilter *filter.Filter
-	filterStr := query.Get("filter")
-	if filterStr != "" {
-		var err error
-		searchFilter, err = filter.Parse(filterStr)
-		if err != nil {
-			writeError(w, http.StatusBadRequest, "invalid_filter", "invalid filter syntax: "+err.Error())
+	var filterStr string
+	if raw := query.Get("filter"); raw != "" {
+		var ok bool
+		if searchFilter, filterStr, ok = parseSearchFilter(w, raw); !ok {
 			return
 		}
 	}
@@ -974,11 +1123,12 @@ func (h *Handlers) HandleStreamSearch(w http.ResponseWriter, r *http.Request) {
 		flusher.Flush()
 	}
 
-	entries, err := h.backend.Search(baseDN, int(scope), searchFilter)
+	entries, err := h.backend.Search(operationContext(r), baseDN, int(scope), searchFilter)
 	if err != nil {
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
+	entries = h.backend.ExpandDynamicGroups(r.Context(), entries)
 
 	encoder := json.NewEncoder(w)
 	for _, e := range entries {
@@ -1010,8 +1160,40 @@ func (h *Handlers) HandleStreamSearch(w http.ResponseWriter, r *http.Request) {
 func convertEntry(e *backend.Entry) *Entry {
 	return &Entry{
 		DN:         e.DN,
-		Attributes: e.Attributes,
+		Attributes: toStringAttributes(e.Attributes),
+	}
+}
+
+// toByteAttributes converts JSON attribute values to backend values.
+func toByteAttributes(attrs map[string][]string) map[string][][]byte {
+	if attrs == nil {
+		return nil
+	}
+	result := make(map[string][][]byte, len(attrs))
+	for name, values := range attrs {
+		byteValues := make([][]byte, len(values))
+		for i, v := range values {
+			byteValues[i] = []byte(v)
+		}
+		result[name] = byteValues
 	}
+	return result
+}
+
+// toStringAttributes converts backend attribute values to JSON values.
+func toStringAttributes(attrs map[string][][]byte) map[string][]string {
+	if attrs == nil {
+		return nil
+	}
+	result := make(map[string][]string, len(attrs))
+	for name, values := range attrs {
+		stringValues := make([]string, len(values))
+		for i, v := range values {
+			stringValues[i] = string(v)
+		}
+		result[name] = stringValues
+	}
+	return result
 }
 
 func convertEntryWithAttrs(e *backend.Entry, attrs []string) *Entry {
@@ -1019,7 +1201,7 @@ func convertEntryWithAttrs(e *backend.Entry, attrs []string) *Entry {
 		return convertEntry(e)
 	}
 
-	filtered := make(map[string][]string)
+	filtered := make(map[string][][]byte)
 	for _, attr := range attrs {
 		attrLower := strings.ToLower(attr)
 		for k, v := range e.Attributes {
@@ -1032,7 +1214,7 @@ func convertEntryWithAttrs(e *backend.Entry, attrs []string) *Entry {
 
 	return &Entry{
 		DN:         e.DN,
-		Attributes: filtered,
+		Attributes: toStringAttributes(filtered),
 	}
 }
 
@@ -1040,21 +1222,13 @@ func calculateNewDN(oldDN, newRDN, newSuperior string) string {
 	if newSuperior != "" {
 		return newRDN + "," + newSuperior
 	}
-	parts := strings.SplitN(oldDN, ",", 2)
-	if len(parts) == 2 {
-		return newRDN + "," + parts[1]
+	rdns, err := ldap.SplitDN(oldDN)
+	if err == nil && len(rdns) > 1 {
+		return newRDN + "," + strings.Join(rdns[1:], ",")
 	}
 	return newRDN
 }
 
-func parseRDN(rdn string) (string, string) {
-	parts := strings.SplitN(rdn, "=", 2)
-	if len(parts) == 2 {
-		return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
-	}
-	return "", ""
-}
-
 func copyAttributes(attrs map[string][]string) map[string][]string {
 	result := make(map[string][]string)
 	for k, v := range attrs {
@@ -1187,3 +1361,35 @@ func (h *Handlers) HandleClusterLeader(w http.ResponseWriter, r *http.Request) {
 		"isLeader":   h.clusterBackend.IsLeader(),
 	})
 }
+
+// setETag sets the ETag header of a response to the version of entry.
+// Entries without a version get no ETag.
+func setETag(w http.ResponseWriter, entry *backend.Entry) {
+	if version := backend.EntryVersion(entry); version != "" {
+		w.Header().Set("ETag", `"`+version+`"`)
+	}
+}
+
+// operationContext returns the context of r carrying the bind DN and
+// client address for the backend.
+func operationContext(r *http.Request) context.Context {
+	return backend.ContextWithOperation(r.Context(), backend.OperationInfo{
+		BindDN:     BindDN(r),
+		ClientAddr: getClientIP(r),
+	})
+}
+
+// ifMatchContext returns ctx with an assertion on the entry version taken
+// from the If-Match header of r, or ctx unchanged if the header is absent.
+// The write then fails with 412 Precondition Failed if the entry changed
+// since the client read its ETag.
+func ifMatchContext(ctx context.Context, r *http.Request) context.Context {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch == "" {
+		return ctx
+	}
+	if ifMatch != "*" {
+		ifMatch = strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`)
+	}
+	return backend.ContextWithAssertion(ctx, &backend.Assertion{EntryCSN: ifMatch})
+}
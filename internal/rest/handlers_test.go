@@ -0,0 +1,88 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/backend"
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+// TestEntryIfMatch tests that GET returns the entry version as ETag and
+// that writes with a stale If-Match fail with 412.
+func TestEntryIfMatch(t *testing.T) {
+	db, err := engine.Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	be := backend.NewBackend(db, config.DefaultConfig())
+	for _, e := range []*backend.Entry{
+		newTestEntry("dc=example,dc=com", "domain", "dc", "example"),
+		newTestEntry("cn=printer,dc=example,dc=com", "device", "cn", "printer"),
+	} {
+		if err := be.Add(context.Background(), e); err != nil {
+			t.Fatalf("Add(%s) error = %v", e.DN, err)
+		}
+	}
+	h := NewHandlers(be, nil)
+	dn := "cn=printer,dc=example,dc=com"
+
+	do := func(handler http.HandlerFunc, method, ifMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/entries/"+url.PathEscape(dn), strings.NewReader(body))
+		req = req.WithContext(withParams(req.Context(), map[string]string{"dn": url.PathEscape(dn)}))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+	modify := func(ifMatch, value string) *httptest.ResponseRecorder {
+		body := `{"changes":[{"operation":"replace","attribute":"description","values":["` + value + `"]}]}`
+		return do(h.HandleModifyEntry, http.MethodPatch, ifMatch, body)
+	}
+
+	rec := do(h.HandleGetEntry, http.MethodGet, "", "")
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" {
+		t.Fatalf("GET status = %d, ETag = %q", rec.Code, etag)
+	}
+
+	rec = modify(etag, "first")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PATCH with current ETag status = %d, body %s", rec.Code, rec.Body)
+	}
+	newETag := rec.Header().Get("ETag")
+	if newETag == "" || newETag == etag {
+		t.Errorf("expected a new ETag after modify, got %q", newETag)
+	}
+
+	if rec = modify(etag, "second"); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("PATCH with stale ETag status = %d, want %d", rec.Code, http.StatusPreconditionFailed)
+	}
+	if rec = modify("W/"+newETag, "third"); rec.Code != http.StatusOK {
+		t.Errorf("PATCH with weak current ETag status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	if rec = do(h.HandleDeleteEntry, http.MethodDelete, newETag, ""); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("DELETE with stale ETag status = %d, want %d", rec.Code, http.StatusPreconditionFailed)
+	}
+	if rec = do(h.HandleDeleteEntry, http.MethodDelete, "*", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE with * status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+}
+
+func newTestEntry(dn, objectClass, rdnAttr, rdnValue string) *backend.Entry {
+	e := backend.NewEntry(dn)
+	e.SetAttribute("objectclass", objectClass)
+	e.SetAttribute(rdnAttr, rdnValue)
+	return e
+}
@@ -0,0 +1,142 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"github.com/KilimcininKorOglu/oba/internal/server"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// Exclusive job resources. Only one job at a time may hold each.
+const (
+	jobResourceCompaction  = "compaction"
+	jobResourceIndexPrefix = "index:"
+)
+
+// newMemoryJobManager creates a job manager that keeps no history on disk,
+// used until SetJobManager is called.
+func newMemoryJobManager() *server.JobManager {
+	jm, _ := server.NewJobManager("")
+	return jm
+}
+
+// startJob starts a job and writes it as the response, or writes the error
+// that kept it from starting.
+func (h *Handlers) startJob(w http.ResponseWriter, r *http.Request, spec server.JobSpec, fn server.JobFunc, msg string) {
+	job, err := h.jobs.Start(spec, fn)
+	if err != nil {
+		if errors.Is(err, server.ErrJobResourceBusy) {
+			writeError(w, http.StatusConflict, "job_busy", err.Error())
+			return
+		}
+		writeError(w, http.StatusServiceUnavailable, "unavailable", err.Error())
+		return
+	}
+
+	h.auditLog(r, msg, "type", string(job.Type), "target", job.Target, "job", job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// HandleRebuildIndex handles POST /api/v1/admin/indexes/{attr}/rebuild.
+// The index is rebuilt in the background while the server keeps serving
+// it; the response is the job to poll for progress.
+func (h *Handlers) HandleRebuildIndex(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&h.requestCount, 1)
+
+	attr, err := url.PathUnescape(Param(r, "attr"))
+	if err != nil || attr == "" {
+		writeError(w, http.StatusBadRequest, "invalid_attribute", "invalid attribute name")
+		return
+	}
+
+	spec := server.JobSpec{
+		Type:       server.JobIndexRebuild,
+		Target:     attr,
+		Resource:   jobResourceIndexPrefix + strings.ToLower(attr),
+		Cancelable: true,
+	}
+	h.startJob(w, r, spec, func(ctx context.Context, jr *server.JobReporter) error {
+		jr.Logf("rebuilding index for %s", attr)
+		p, err := h.backend.RebuildIndex(ctx, attr, func(p storage.IndexRebuildProgress) {
+			jr.SetProgress("entriesScanned", int64(p.EntriesScanned))
+			jr.SetProgress("entriesIndexed", int64(p.EntriesIndexed))
+			jr.SetProgress("keysWritten", int64(p.KeysWritten))
+			jr.SetProgress("elapsedMs", p.Elapsed.Milliseconds())
+		})
+		if err != nil {
+			return err
+		}
+		jr.Logf("indexed %d of %d entries", p.EntriesIndexed, p.EntriesScanned)
+		return nil
+	}, "index rebuild started")
+}
+
+// HandleCompact handles POST /api/v1/admin/compact. Compaction runs in the
+// background; the response is the job to poll for its result.
+func (h *Handlers) HandleCompact(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&h.requestCount, 1)
+
+	spec := server.JobSpec{
+		Type:     server.JobCompaction,
+		Resource: jobResourceCompaction,
+	}
+	h.startJob(w, r, spec, func(ctx context.Context, jr *server.JobReporter) error {
+		return h.backend.Compact()
+	}, "compaction started")
+}
+
+// HandleListJobs handles GET /api/v1/admin/jobs. If the job history could
+// not be saved, the response carries the error as persistenceError.
+func (h *Handlers) HandleListJobs(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&h.requestCount, 1)
+
+	jobs := h.jobs.List()
+	resp := map[string]interface{}{
+		"jobs":  jobs,
+		"count": len(jobs),
+	}
+	if err := h.jobs.SaveError(); err != nil {
+		resp["persistenceError"] = err.Error()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// HandleGetJob handles GET /api/v1/admin/jobs/{id}.
+func (h *Handlers) HandleGetJob(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&h.requestCount, 1)
+
+	job, ok := h.jobs.Get(Param(r, "id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "job not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// HandleCancelJob handles POST /api/v1/admin/jobs/{id}/cancel. The job is
+// asked to stop; it is marked canceled once it has.
+func (h *Handlers) HandleCancelJob(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&h.requestCount, 1)
+
+	id := Param(r, "id")
+	switch err := h.jobs.Cancel(id); {
+	case errors.Is(err, server.ErrJobNotFound):
+		writeError(w, http.StatusNotFound, "not_found", "job not found")
+		return
+	case errors.Is(err, server.ErrJobFinished), errors.Is(err, server.ErrJobNotCancelable):
+		writeError(w, http.StatusConflict, "job_not_cancelable", err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+
+	h.auditLog(r, "job canceled", "job", id)
+	job, _ := h.jobs.Get(id)
+	writeJSON(w, http.StatusAccepted, job)
+}
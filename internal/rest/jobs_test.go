@@ -0,0 +1,224 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/server"
+)
+
+// waitForJob polls GET /api/v1/admin/jobs/{id} until the job finishes.
+func waitForJob(t *testing.T, h *Handlers, id string) server.Job {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/jobs/"+id, nil)
+		req = req.WithContext(withParams(req.Context(), map[string]string{"id": id}))
+		rec := httptest.NewRecorder()
+		h.HandleGetJob(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("get job status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+
+		var job server.Job
+		if err := json.Unmarshal(rec.Body.Bytes(), &job); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		if job.State.Finished() {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s still running", id)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHandleRebuildIndex(t *testing.T) {
+	h := newSearchTestHandlers(t, 10)
+	defer h.jobs.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/indexes/uid/rebuild", nil)
+	req = req.WithContext(withParams(req.Context(), map[string]string{"attr": "uid"}))
+	rec := httptest.NewRecorder()
+	h.HandleRebuildIndex(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var started server.Job
+	if err := json.Unmarshal(rec.Body.Bytes(), &started); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if started.Type != server.JobIndexRebuild || started.Target != "uid" || started.ID == "" {
+		t.Fatalf("started job = %+v", started)
+	}
+
+	job := waitForJob(t, h, started.ID)
+	if job.State != server.JobSucceeded || job.FinishedAt == nil {
+		t.Fatalf("job = %+v, want succeeded", job)
+	}
+	// The base entry, ou=users and the users
+	if job.Progress["entriesScanned"] != 12 || job.Progress["entriesIndexed"] != 10 {
+		t.Errorf("progress = %+v, want 12 entries scanned and 10 indexed", job.Progress)
+	}
+}
+
+func TestHandleCompact(t *testing.T) {
+	h := newSearchTestHandlers(t, 1)
+	defer h.jobs.Close()
+
+	rec := httptest.NewRecorder()
+	h.HandleCompact(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/compact", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var started server.Job
+	if err := json.Unmarshal(rec.Body.Bytes(), &started); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+
+	if job := waitForJob(t, h, started.ID); job.State != server.JobSucceeded || job.Type != server.JobCompaction {
+		t.Errorf("job = %+v, want a succeeded compaction", job)
+	}
+}
+
+func TestHandleGetJobNotFound(t *testing.T) {
+	h := NewHandlers(nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/jobs/missing", nil)
+	req = req.WithContext(withParams(req.Context(), map[string]string{"id": "missing"}))
+	rec := httptest.NewRecorder()
+	h.HandleGetJob(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestHandleListJobs(t *testing.T) {
+	h := newSearchTestHandlers(t, 1)
+	defer h.jobs.Close()
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		h.HandleCompact(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/compact", nil))
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+		var started server.Job
+		if err := json.Unmarshal(rec.Body.Bytes(), &started); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		waitForJob(t, h, started.ID)
+	}
+
+	rec := httptest.NewRecorder()
+	h.HandleListJobs(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/jobs", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Jobs  []server.Job `json:"jobs"`
+		Count int          `json:"count"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if resp.Count != 2 || len(resp.Jobs) != 2 {
+		t.Errorf("listed %d jobs, want 2", len(resp.Jobs))
+	}
+	if strings.Contains(rec.Body.String(), "persistenceError") {
+		t.Errorf("unexpected persistenceError in %s", rec.Body.String())
+	}
+}
+
+func TestHandleListJobsPersistenceError(t *testing.T) {
+	jm, err := server.NewJobManager(filepath.Join(t.TempDir(), "missing", "jobs.json"))
+	if err != nil {
+		t.Fatalf("NewJobManager() error = %v", err)
+	}
+	h := NewHandlers(nil, nil)
+	h.SetJobManager(jm)
+	defer h.jobs.Close()
+
+	job, err := h.jobs.Start(server.JobSpec{Type: server.JobCompaction},
+		func(ctx context.Context, r *server.JobReporter) error { return nil })
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	waitForJob(t, h, job.ID)
+
+	rec := httptest.NewRecorder()
+	h.HandleListJobs(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/jobs", nil))
+	var resp struct {
+		PersistenceError string `json:"persistenceError"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if resp.PersistenceError == "" {
+		t.Errorf("persistenceError missing from %s", rec.Body.String())
+	}
+}
+
+func TestHandleStartJobResourceBusy(t *testing.T) {
+	h := NewHandlers(nil, nil)
+	defer h.jobs.Close()
+
+	release := make(chan struct{})
+	_, err := h.jobs.Start(server.JobSpec{Type: server.JobCompaction, Resource: jobResourceCompaction},
+		func(ctx context.Context, r *server.JobReporter) error {
+			<-release
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer close(release)
+
+	rec := httptest.NewRecorder()
+	h.HandleCompact(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/compact", nil))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+}
+
+func TestHandleCancelJob(t *testing.T) {
+	h := NewHandlers(nil, nil)
+	defer h.jobs.Close()
+
+	started, err := h.jobs.Start(server.JobSpec{Type: server.JobIndexRebuild, Target: "uid", Cancelable: true},
+		func(ctx context.Context, r *server.JobReporter) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	cancelJob := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/jobs/"+started.ID+"/cancel", nil)
+		req = req.WithContext(withParams(req.Context(), map[string]string{"id": started.ID}))
+		rec := httptest.NewRecorder()
+		h.HandleCancelJob(rec, req)
+		return rec.Code
+	}
+
+	if code := cancelJob(); code != http.StatusAccepted {
+		t.Fatalf("cancel status = %d, want %d", code, http.StatusAccepted)
+	}
+	if job := waitForJob(t, h, started.ID); job.State != server.JobCanceled {
+		t.Errorf("state = %s, want canceled", job.State)
+	}
+	if code := cancelJob(); code != http.StatusConflict {
+		t.Errorf("cancel of finished job status = %d, want %d", code, http.StatusConflict)
+	}
+}
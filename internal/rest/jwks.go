@@ -0,0 +1,414 @@
+package rest
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+)
+
+// External JWT errors.
+var (
+	ErrUnknownSigningKey   = errors.New("rest: unknown token signing key")
+	ErrUnsupportedTokenAlg = errors.New("rest: unsupported token algorithm")
+	ErrTokenIssuer         = errors.New("rest: token issuer not accepted")
+	ErrTokenAudience       = errors.New("rest: token audience not accepted")
+	ErrInsecureJWKSURI     = errors.New("rest: JWKS URI must use https")
+	ErrEmptyClaimDN        = errors.New("rest: claim template produced an empty DN")
+)
+
+// Defaults for fetching a JWKS.
+const (
+	// DefaultJWKSRefreshInterval is how long fetched keys are used before
+	// the JWKS is fetched again.
+	DefaultJWKSRefreshInterval = time.Hour
+
+	// jwksMinRefetch is the shortest time between fetches triggered by a
+	// token signed with an unknown key, so that such tokens cannot make
+	// the server hammer the identity provider.
+	jwksMinRefetch = time.Minute
+
+	// jwksFetchTimeout bounds a JWKS request.
+	jwksFetchTimeout = 10 * time.Second
+
+	// jwksMaxSize bounds the size of a JWKS response.
+	jwksMaxSize = 1 << 20
+)
+
+// JWTClaimMapper maps the claims of an externally issued JWT to a bind DN
+// by rendering a template such as "uid={{.sub}},ou=users,dc=example,dc=com".
+// String claims are escaped as DN attribute values before rendering, so a
+// claim cannot add RDNs to the DN.
+type JWTClaimMapper struct {
+	template *template.Template
+}
+
+// NewJWTClaimMapper parses the claim template. A template that refers to
+// a claim missing from a token fails for that token.
+func NewJWTClaimMapper(claimTemplate string) (*JWTClaimMapper, error) {
+	tmpl, err := template.New("claims").Option("missingkey=error").Parse(claimTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("rest: invalid claim template: %w", err)
+	}
+	return &JWTClaimMapper{template: tmpl}, nil
+}
+
+// MapClaims renders the template with the claims and returns the DN.
+func (m *JWTClaimMapper) MapClaims(claims map[string]interface{}) (string, error) {
+	data := make(map[string]interface{}, len(claims))
+	for name, value := range claims {
+		if s, ok := value.(string); ok {
+			value = ldap.EscapeDNValue(s)
+		}
+		data[name] = value
+	}
+
+	var b strings.Builder
+	if err := m.template.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("rest: mapping token claims: %w", err)
+	}
+
+	dn := strings.TrimSpace(b.String())
+	if dn == "" {
+		return "", ErrEmptyClaimDN
+	}
+	return dn, nil
+}
+
+// JWKSKeySet holds the public keys published at a JWKS endpoint. Keys are
+// fetched on first use, again after the refresh interval, and again when a
+// token names a key that is not in the set.
+type JWKSKeySet struct {
+	uri     string
+	client  *http.Client
+	refresh time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// NewJWKSKeySet creates a key set for the JWKS at uri. A nil client uses
+// one with a 10 second timeout.
+func NewJWKSKeySet(uri string, client *http.Client) *JWKSKeySet {
+	if client == nil {
+		client = &http.Client{Timeout: jwksFetchTimeout}
+	}
+	return &JWKSKeySet{
+		uri:     uri,
+		client:  client,
+		refresh: DefaultJWKSRefreshInterval,
+	}
+}
+
+// Key returns the public key with the given key ID. An empty kid matches
+// the only key of a set that has exactly one.
+func (ks *JWKSKeySet) Key(kid string) (crypto.PublicKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	age := time.Since(ks.fetchedAt)
+	key, found := ks.lookupLocked(kid)
+	if ks.keys == nil || age > ks.refresh || (!found && age > jwksMinRefetch) {
+		if err := ks.fetchLocked(); err != nil {
+			// Keep using the keys fetched before
+			if !found {
+				return nil, err
+			}
+			return key, nil
+		}
+		key, found = ks.lookupLocked(kid)
+	}
+
+	if !found {
+		return nil, ErrUnknownSigningKey
+	}
+	return key, nil
+}
+
+// lookupLocked finds a key. Must be called with the lock held.
+func (ks *JWKSKeySet) lookupLocked(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(ks.keys) == 1 {
+		for _, key := range ks.keys {
+			return key, true
+		}
+	}
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+// fetchLocked fetches the JWKS. Keys of unsupported types are skipped.
+// Must be called with the lock held.
+func (ks *JWKSKeySet) fetchLocked() error {
+	// Count failed fetches too, so that an unreachable endpoint is not
+	// retried for every request
+	ks.fetchedAt = time.Now()
+
+	resp, err := ks.client.Get(ks.uri)
+	if err != nil {
+		return fmt.Errorf("rest: fetching JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("rest: fetching JWKS: %s", resp.Status)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, resp.Body, jwksMaxSize))
+	if err := decoder.Decode(&set); err != nil {
+		return fmt.Errorf("rest: decoding JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	ks.keys = keys
+	return nil
+}
+
+// jsonWebKey is an RSA or elliptic curve public key of a JWKS (RFC 7517).
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey decodes the key.
+func (k *jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		exponent := int(new(big.Int).SetBytes(e).Int64())
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}, nil
+
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != 32 {
+			return nil, errors.New("invalid EC key")
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil || len(y) != 32 {
+			return nil, errors.New("invalid EC key")
+		}
+		// Reject points that are not on the curve
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// ExternalJWTVerifier accepts JWTs issued by an external identity
+// provider. Tokens must be signed with RS256 or ES256 by a key of the
+// provider's JWKS, carry its issuer, the audience of the oba REST API and
+// an expiry, and are mapped to a bind DN by a JWTClaimMapper.
+type ExternalJWTVerifier struct {
+	issuer   string
+	audience string
+	keys     *JWKSKeySet
+	mapper   *JWTClaimMapper
+}
+
+// NewExternalJWTVerifier creates a verifier for tokens of issuer issued
+// for audience, signed by keys published at jwksURI. The JWKS URI must use
+// https, or http to a loopback host.
+func NewExternalJWTVerifier(issuer, audience, jwksURI, claimTemplate string, client *http.Client) (*ExternalJWTVerifier, error) {
+	if issuer == "" || audience == "" || jwksURI == "" || claimTemplate == "" {
+		return nil, errors.New("rest: external JWTs need an issuer, an audience, a JWKS URI and a claim template")
+	}
+	if err := checkJWKSURI(jwksURI); err != nil {
+		return nil, err
+	}
+	mapper, err := NewJWTClaimMapper(claimTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return &ExternalJWTVerifier{
+		issuer:   issuer,
+		audience: audience,
+		keys:     NewJWKSKeySet(jwksURI, client),
+		mapper:   mapper,
+	}, nil
+}
+
+// checkJWKSURI rejects JWKS URIs whose keys could be replaced in transit:
+// anything but https, except http to a loopback host.
+func checkJWKSURI(jwksURI string) error {
+	u, err := url.Parse(jwksURI)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("rest: invalid JWKS URI %q", jwksURI)
+	}
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		host := u.Hostname()
+		if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+			return nil
+		}
+	}
+	return ErrInsecureJWKSURI
+}
+
+// hasAudience reports whether the aud claim, a string or an array of
+// strings, contains audience.
+func hasAudience(aud interface{}, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if s, ok := a.(string); ok && s == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Verify checks the token and returns its claims with the mapped DN.
+func (v *ExternalJWTVerifier) Verify(token string) (*JWTClaims, error) {
+	parts := splitToken(token)
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeTokenPart(parts[0], &header); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	key, err := v.keys.Key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeTokenPart(parts[1], &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if iss, _ := claims["iss"].(string); iss != v.issuer {
+		return nil, ErrTokenIssuer
+	}
+	if !hasAudience(claims["aud"], v.audience) {
+		return nil, ErrTokenAudience
+	}
+
+	now := time.Now().Unix()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	if now > int64(exp) {
+		return nil, ErrTokenExpired
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now < int64(nbf) {
+		return nil, ErrInvalidToken
+	}
+
+	dn, err := v.mapper.MapClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	iat, _ := claims["iat"].(float64)
+	return &JWTClaims{DN: dn, IssuedAt: int64(iat), ExpiresAt: int64(exp)}, nil
+}
+
+// verifySignature checks an RS256 or ES256 signature of message.
+func verifySignature(alg string, key crypto.PublicKey, message string, signature []byte) error {
+	digest := sha256.Sum256([]byte(message))
+
+	switch alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrInvalidToken
+		}
+		if err := rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature); err != nil {
+			return ErrInvalidToken
+		}
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return ErrInvalidToken
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecKey, digest[:], r, s) {
+			return ErrInvalidToken
+		}
+	default:
+		return ErrUnsupportedTokenAlg
+	}
+	return nil
+}
+
+// decodeTokenPart decodes a base64url encoded JSON part of a JWT.
+func decodeTokenPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,301 @@
+package rest
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/backend"
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/logging"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+const (
+	testIssuer   = "https://idp.example.com"
+	testAudience = "oba-rest"
+)
+
+// mockIdP is an identity provider publishing an RSA and an EC key.
+type mockIdP struct {
+	server  *httptest.Server
+	rsaKey  *rsa.PrivateKey
+	ecKey   *ecdsa.PrivateKey
+	fetches atomic.Int32
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	idp := &mockIdP{rsaKey: rsaKey, ecKey: ecKey}
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+			{"kty": "oct", "kid": "secret", "k": "c2VjcmV0"},
+		},
+	}
+	idp.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idp.fetches.Add(1)
+		json.NewEncoder(w).Encode(jwks)
+	}))
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// token issues a token signed with the key kid.
+func (idp *mockIdP) token(t *testing.T, kid string, claims map[string]interface{}) string {
+	t.Helper()
+
+	alg := "RS256"
+	if kid == "ec-1" {
+		alg = "ES256"
+	}
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	message := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(message))
+
+	var signature []byte
+	var err error
+	if alg == "RS256" {
+		signature, err = rsa.SignPKCS1v15(rand.Reader, idp.rsaKey, crypto.SHA256, digest[:])
+	} else {
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, idp.ecKey, digest[:])
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	if err != nil {
+		t.Fatalf("signing token: %v", err)
+	}
+	return message + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// claims returns valid claims for sub.
+func claims(sub string) map[string]interface{} {
+	now := time.Now().Unix()
+	return map[string]interface{}{
+		"iss": testIssuer,
+		"aud": testAudience,
+		"sub": sub,
+		"iat": now,
+		"exp": now + 300,
+	}
+}
+
+func TestJWTClaimMapper(t *testing.T) {
+	mapper, err := NewJWTClaimMapper("uid={{.sub}},ou=users,dc=example,dc=com")
+	if err != nil {
+		t.Fatalf("NewJWTClaimMapper() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		claims  map[string]interface{}
+		want    string
+		wantErr bool
+	}{
+		{"plain", map[string]interface{}{"sub": "alice@example.com"}, "uid=alice@example.com,ou=users,dc=example,dc=com", false},
+		{"escaped", map[string]interface{}{"sub": "alice,ou=admins"}, `uid=alice\,ou=admins,ou=users,dc=example,dc=com`, false},
+		{"missing claim", map[string]interface{}{"email": "alice@example.com"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mapper.MapClaims(tt.claims)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MapClaims() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("MapClaims() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewJWTClaimMapper("uid={{.sub"); err == nil {
+		t.Error("NewJWTClaimMapper() should reject an invalid template")
+	}
+	empty, _ := NewJWTClaimMapper("{{.sub}}")
+	if _, err := empty.MapClaims(map[string]interface{}{"sub": ""}); !errors.Is(err, ErrEmptyClaimDN) {
+		t.Errorf("MapClaims() of an empty DN: error = %v, want ErrEmptyClaimDN", err)
+	}
+}
+
+func TestExternalJWTVerifier(t *testing.T) {
+	idp := newMockIdP(t)
+	verifier, err := NewExternalJWTVerifier(testIssuer, testAudience, idp.server.URL, "uid={{.sub}},ou=users,dc=example,dc=com", nil)
+	if err != nil {
+		t.Fatalf("NewExternalJWTVerifier() error = %v", err)
+	}
+	const wantDN = "uid=alice,ou=users,dc=example,dc=com"
+
+	for _, kid := range []string{"rsa-1", "ec-1"} {
+		got, err := verifier.Verify(idp.token(t, kid, claims("alice")))
+		if err != nil {
+			t.Fatalf("Verify(%s token) error = %v", kid, err)
+		}
+		if got.DN != wantDN {
+			t.Errorf("Verify(%s token) DN = %q, want %q", kid, got.DN, wantDN)
+		}
+	}
+	if n := idp.fetches.Load(); n != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", n)
+	}
+
+	otherIssuer := claims("alice")
+	otherIssuer["iss"] = "https://evil.example.com"
+	otherAudience := claims("alice")
+	otherAudience["aud"] = []string{"other-app", "another-app"}
+	noAudience := claims("alice")
+	delete(noAudience, "aud")
+	expired := claims("alice")
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+	noExpiry := claims("alice")
+	delete(noExpiry, "exp")
+	tampered := idp.token(t, "rsa-1", claims("alice"))
+	tampered = tampered[:len(tampered)-4] + "AAAA"
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"other issuer", idp.token(t, "rsa-1", otherIssuer), ErrTokenIssuer},
+		{"other audience", idp.token(t, "rsa-1", otherAudience), ErrTokenAudience},
+		{"no audience", idp.token(t, "rsa-1", noAudience), ErrTokenAudience},
+		{"expired", idp.token(t, "rsa-1", expired), ErrTokenExpired},
+		{"no expiry", idp.token(t, "rsa-1", noExpiry), ErrInvalidToken},
+		{"tampered", tampered, ErrInvalidToken},
+		{"unknown key", idp.token(t, "rsa-2", claims("alice")), ErrUnknownSigningKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := verifier.Verify(tt.token); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// An audience array holding the API among other clients is accepted
+	audiences := claims("alice")
+	audiences["aud"] = []string{"other-app", testAudience}
+	if _, err := verifier.Verify(idp.token(t, "rsa-1", audiences)); err != nil {
+		t.Errorf("Verify() with an audience array error = %v", err)
+	}
+
+	// The unknown key refetched the JWKS once; a second one within a
+	// minute does not
+	verifier.Verify(idp.token(t, "rsa-3", claims("alice")))
+	if n := idp.fetches.Load(); n != 1 {
+		t.Errorf("JWKS fetched %d times, want 1 within the refetch interval", n)
+	}
+}
+
+// TestExternalJWTAuthentication tests that the REST API accepts a token of
+// the external issuer and passes the mapped DN to the backend.
+func TestExternalJWTAuthentication(t *testing.T) {
+	db, err := engine.Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	be := backend.NewBackend(db, config.DefaultConfig())
+	for _, e := range []*backend.Entry{
+		newTestEntry("dc=example,dc=com", "domain", "dc", "example"),
+		newTestEntry("cn=printer,dc=example,dc=com", "device", "cn", "printer"),
+	} {
+		if err := be.Add(context.Background(), e); err != nil {
+			t.Fatalf("Add(%s) error = %v", e.DN, err)
+		}
+	}
+
+	idp := newMockIdP(t)
+	cfg := DefaultServerConfig()
+	cfg.JWTSecret = "0123456789abcdef0123456789abcdef"
+	cfg.ExternalJWTIssuer = testIssuer
+	cfg.ExternalJWTAudience = testAudience
+	cfg.JWKSUri = idp.server.URL
+	cfg.ClaimTemplate = "uid={{.sub}},ou=users,dc=example,dc=com"
+	s := NewServer(cfg, be, logging.NewNop())
+
+	dn := "cn=printer,dc=example,dc=com"
+	modify := func(token string) *httptest.ResponseRecorder {
+		body := `{"changes":[{"operation":"replace","attribute":"description","values":["updated"]}]}`
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/entries/"+url.PathEscape(dn), strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := modify(idp.token(t, "ec-1", claims("alice@example.com"))); rec.Code != http.StatusOK {
+		t.Fatalf("PATCH with external token status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	entry, err := be.GetEntry(dn)
+	if err != nil {
+		t.Fatalf("GetEntry() error = %v", err)
+	}
+	want := "uid=alice@example.com,ou=users,dc=example,dc=com"
+	if got := entry.GetAttribute("modifiersname"); len(got) != 1 || string(got[0]) != want {
+		t.Errorf("modifiersName = %v, want %s", got, want)
+	}
+
+	// Tokens of another issuer are rejected
+	other := claims("alice@example.com")
+	other["iss"] = "https://evil.example.com"
+	if rec := modify(idp.token(t, "ec-1", other)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("PATCH with another issuer's token status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestNewExternalJWTVerifierJWKSURI(t *testing.T) {
+	const template = "uid={{.sub}},ou=users,dc=example,dc=com"
+	tests := []struct {
+		uri  string
+		want error
+	}{
+		{"https://idp.example.com/jwks", nil},
+		{"http://127.0.0.1:8080/jwks", nil},
+		{"http://localhost/jwks", nil},
+		{"http://idp.example.com/jwks", ErrInsecureJWKSURI},
+		{"ftp://idp.example.com/jwks", ErrInsecureJWKSURI},
+	}
+	for _, tt := range tests {
+		_, err := NewExternalJWTVerifier(testIssuer, testAudience, tt.uri, template, nil)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("NewExternalJWTVerifier(%s) error = %v, want %v", tt.uri, err, tt.want)
+		}
+	}
+
+	if _, err := NewExternalJWTVerifier(testIssuer, "", "https://idp.example.com/jwks", template, nil); err == nil {
+		t.Error("NewExternalJWTVerifier() without an audience succeeded")
+	}
+}
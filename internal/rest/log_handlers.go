@@ -363,7 +363,7 @@ func (h *Handlers) HandleInternalLog(w http.ResponseWriter, r *http.Request) {
 	if h.logger != nil {
 		logger := h.logger
 		if req.Source != "" {
-			logger = logger.WithSource(req.Source)
+			logger = logger.WithComponent(req.Source)
 		}
 		if req.User != "" {
 			logger = logger.WithUser(req.User)
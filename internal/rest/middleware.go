@@ -3,12 +3,15 @@ package rest
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/KilimcininKorOglu/oba/internal/logging"
+	"github.com/KilimcininKorOglu/oba/internal/trace"
 )
 
 type bindDNKey struct{}
@@ -39,7 +42,7 @@ func (w *loggingResponseWriter) SetUser(user string) {
 // LoggingMiddleware logs HTTP requests.
 // Note: Detailed audit logs are written by handlers. This middleware only logs errors.
 func LoggingMiddleware(logger logging.Logger) Middleware {
-	restLogger := logger.WithSource("rest")
+	restLogger := logger.WithComponent("rest")
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			wrapped := &loggingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
@@ -190,14 +193,74 @@ func (w *responseWriter) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
-// CORSMiddleware handles CORS headers.
+// CORS defaults, used when a CORSConfig field is left empty.
+const DefaultCORSMaxAge = 24 * time.Hour
+
+var (
+	// DefaultCORSAllowedMethods are the methods allowed for cross-origin
+	// requests. OPTIONS is always allowed.
+	DefaultCORSAllowedMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+	// DefaultCORSAllowedHeaders are the request headers allowed for
+	// cross-origin requests.
+	DefaultCORSAllowedHeaders = []string{"Content-Type", "Authorization"}
+)
+
+// CORSConfig configures the CORS middleware.
+type CORSConfig struct {
+	// AllowedOrigins lists the origins allowed to make requests; "*"
+	// allows any origin
+	AllowedOrigins []string
+	// AllowedMethods lists the allowed methods (default
+	// DefaultCORSAllowedMethods)
+	AllowedMethods []string
+	// AllowedHeaders lists the allowed request headers (default
+	// DefaultCORSAllowedHeaders)
+	AllowedHeaders []string
+	// MaxAge is how long browsers may cache a preflight response (default
+	// DefaultCORSMaxAge). A negative value omits Access-Control-Max-Age.
+	MaxAge time.Duration
+}
+
+// CORSMiddleware handles CORS headers with the default methods, headers and
+// max age.
 func CORSMiddleware(allowedOrigins []string) Middleware {
+	return CORSMiddlewareWithConfig(CORSConfig{AllowedOrigins: allowedOrigins})
+}
+
+// CORSMiddlewareWithConfig handles CORS headers as configured by cfg.
+func CORSMiddlewareWithConfig(cfg CORSConfig) Middleware {
+	methods := cfg.AllowedMethods
+	if len(methods) == 0 {
+		methods = DefaultCORSAllowedMethods
+	}
+	hasOptions := false
+	for _, m := range methods {
+		if strings.EqualFold(m, http.MethodOptions) {
+			hasOptions = true
+		}
+	}
+	if !hasOptions {
+		methods = append(methods[:len(methods):len(methods)], http.MethodOptions)
+	}
+	allowMethods := strings.Join(methods, ", ")
+
+	headers := cfg.AllowedHeaders
+	if len(headers) == 0 {
+		headers = DefaultCORSAllowedHeaders
+	}
+	allowHeaders := strings.Join(headers, ", ")
+
+	maxAge := cfg.MaxAge
+	if maxAge == 0 {
+		maxAge = DefaultCORSMaxAge
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			origin := r.Header.Get("Origin")
 
 			allowed := false
-			for _, o := range allowedOrigins {
+			for _, o := range cfg.AllowedOrigins {
 				if o == "*" || o == origin {
 					allowed = true
 					break
@@ -206,10 +269,13 @@ func CORSMiddleware(allowedOrigins []string) Middleware {
 
 			if allowed && origin != "" {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
-				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+				w.Header().Set("Access-Control-Allow-Methods", allowMethods)
+				w.Header().Set("Access-Control-Allow-Headers", allowHeaders)
 				w.Header().Set("Access-Control-Allow-Credentials", "true")
-				w.Header().Set("Access-Control-Max-Age", "86400")
+				w.Header().Set("Access-Control-Expose-Headers", "ETag")
+				if maxAge > 0 {
+					w.Header().Set("Access-Control-Max-Age", strconv.FormatInt(int64(maxAge/time.Second), 10))
+				}
 			}
 
 			if r.Method == http.MethodOptions {
@@ -299,21 +365,71 @@ func (b *tokenBucket) Allow() bool {
 	return false
 }
 
+// getClientIP returns the IP portion of r.RemoteAddr. RealIPMiddleware
+// has already replaced it with the forwarded client address if the
+// request came through a trusted proxy.
 func getClientIP(r *http.Request) string {
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		parts := strings.Split(xff, ",")
-		return strings.TrimSpace(parts[0])
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// RealIPMiddleware sets r.RemoteAddr to the client address from
+// X-Forwarded-For or X-Real-IP for requests whose peer is a trusted proxy.
+// For X-Forwarded-For, the rightmost address that is not itself a trusted
+// proxy is used, so clients cannot spoof it by prepending entries.
+func RealIPMiddleware(trusted []*net.IPNet) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isTrustedIP(getClientIP(r), trusted) {
+				if ip := forwardedClientIP(r, trusted); ip != "" {
+					r.RemoteAddr = ip
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
 	}
+}
 
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
+// forwardedClientIP returns the client IP from the forwarding headers of r,
+// or "" if there is none.
+func forwardedClientIP(r *http.Request, trusted []*net.IPNet) string {
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		client := ""
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+			client = hop
+			if !isTrustedIP(hop, trusted) {
+				break
+			}
+		}
+		return client
+	}
+
+	if xri := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(xri) != nil {
 		return xri
 	}
+	return ""
+}
 
-	ip := r.RemoteAddr
-	if idx := strings.LastIndex(ip, ":"); idx != -1 {
-		ip = ip[:idx]
+// isTrustedIP returns true if ip is within trusted.
+func isTrustedIP(ip string, trusted []*net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
 	}
-	return ip
+	for _, ipNet := range trusted {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
 }
 
 // ConnectionTrackingMiddleware tracks active connections.
@@ -361,7 +477,7 @@ func AuthMiddleware(auth *Authenticator, excludePaths []string) Middleware {
 					return
 				}
 
-				if err := auth.backend.Bind(dn, password); err != nil {
+				if err := auth.backend.Bind(r.Context(), dn, password); err != nil {
 					writeError(w, http.StatusUnauthorized, "unauthorized", "invalid credentials")
 					return
 				}
@@ -437,3 +553,49 @@ func AdminOnlyMiddleware(adminDNs []string, adminPaths []string, excludePaths []
 		})
 	}
 }
+
+// TracerName is the instrumentation scope of the REST API's spans.
+const TracerName = "github.com/KilimcininKorOglu/oba/internal/rest"
+
+// tracingResponseWriter records the status code for the request span.
+type tracingResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (w *tracingResponseWriter) WriteHeader(code int) {
+	w.statusCode = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush passes flushes of streamed responses through.
+func (w *tracingResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// TracingMiddleware starts a span for each request. A W3C traceparent
+// header makes the span part of the caller's trace. The span is carried in
+// the request context, so backend spans of the handler become its
+// children.
+func TracingMiddleware(tp trace.TracerProvider) Middleware {
+	tracer := tp.Tracer(TracerName)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if sc, err := trace.ParseTraceparent(r.Header.Get("traceparent")); err == nil {
+				ctx = trace.ContextWithRemoteSpanContext(ctx, sc)
+			}
+
+			ctx, span := tracer.Start(ctx, "http.request",
+				trace.String("http.method", r.Method),
+				trace.String("http.target", r.URL.Path))
+			defer span.End()
+
+			wrapped := &tracingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(wrapped, r.WithContext(ctx))
+			span.SetAttributes(trace.Int("http.status_code", wrapped.statusCode))
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package rest
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/backend"
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/logging"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+	"github.com/KilimcininKorOglu/oba/internal/trace"
+)
+
+func TestRealIPMiddleware(t *testing.T) {
+	_, lb, _ := net.ParseCIDR("10.0.0.0/8")
+	trusted := []*net.IPNet{lb}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{"direct client", "203.0.113.7:5000", nil, "203.0.113.7"},
+		{"untrusted forwarded", "203.0.113.7:5000", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "203.0.113.7"},
+		{"untrusted real ip", "203.0.113.7:5000", map[string]string{"X-Real-IP": "198.51.100.1"}, "203.0.113.7"},
+		{"trusted forwarded", "10.0.0.5:5000", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "198.51.100.1"},
+		{"trusted spoofed chain", "10.0.0.5:5000", map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.1"}, "198.51.100.1"},
+		{"trusted proxy chain", "10.0.0.5:5000", map[string]string{"X-Forwarded-For": "198.51.100.1, 10.0.0.9"}, "198.51.100.1"},
+		{"trusted real ip", "10.0.0.5:5000", map[string]string{"X-Real-IP": "2001:db8::1"}, "2001:db8::1"},
+		{"trusted invalid header", "10.0.0.5:5000", map[string]string{"X-Real-IP": "not-an-ip"}, "10.0.0.5"},
+		{"trusted no header", "10.0.0.5:5000", nil, "10.0.0.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := RealIPMiddleware(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = getClientIP(r)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/health", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("client IP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	exporter := trace.NewInMemoryExporter()
+	var handlerSpan trace.SpanContext
+	handler := TracingMiddleware(trace.NewProvider(exporter))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanFromContext(r.Context()).SpanContext()
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	remote, _ := trace.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/entries/dc=example,dc=com", nil)
+	req.Header.Set("traceparent", remote.Traceparent())
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.SpanContext.TraceID != remote.TraceID || span.Parent.SpanID != remote.SpanID {
+		t.Error("request span does not continue the caller's trace")
+	}
+	if handlerSpan != span.SpanContext {
+		t.Error("request context does not hold the request span")
+	}
+	if code, _ := span.Attribute("http.status_code"); code != "404" {
+		t.Errorf("http.status_code = %q, want 404", code)
+	}
+}
+
+// preflight sends a CORS preflight request from origin to handler.
+func preflight(handler http.Handler, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodOptions, "/api/v1/entries/dc=example,dc=com", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCORSMiddlewarePreflight(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("preflight request reached the handler")
+	})
+
+	tests := []struct {
+		name        string
+		cfg         CORSConfig
+		origin      string
+		wantOrigin  string
+		wantMethods string
+		wantHeaders string
+		wantMaxAge  string
+	}{
+		{
+			name:        "defaults",
+			cfg:         CORSConfig{AllowedOrigins: []string{"*"}},
+			origin:      "https://app.example.com",
+			wantOrigin:  "https://app.example.com",
+			wantMethods: "GET, POST, PUT, PATCH, DELETE, OPTIONS",
+			wantHeaders: "Content-Type, Authorization",
+			wantMaxAge:  "86400",
+		},
+		{
+			name: "configured",
+			cfg: CORSConfig{
+				AllowedOrigins: []string{"https://app.example.com"},
+				AllowedMethods: []string{"GET", "PUT"},
+				AllowedHeaders: []string{"Content-Type", "Authorization", "If-Match"},
+				MaxAge:         10 * time.Minute,
+			},
+			origin:      "https://app.example.com",
+			wantOrigin:  "https://app.example.com",
+			wantMethods: "GET, PUT, OPTIONS",
+			wantHeaders: "Content-Type, Authorization, If-Match",
+			wantMaxAge:  "600",
+		},
+		{
+			name:        "options listed",
+			cfg:         CORSConfig{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"OPTIONS", "GET"}},
+			origin:      "https://app.example.com",
+			wantOrigin:  "https://app.example.com",
+			wantMethods: "OPTIONS, GET",
+			wantHeaders: "Content-Type, Authorization",
+			wantMaxAge:  "86400",
+		},
+		{
+			name:        "caching disabled",
+			cfg:         CORSConfig{AllowedOrigins: []string{"*"}, MaxAge: -1},
+			origin:      "https://app.example.com",
+			wantOrigin:  "https://app.example.com",
+			wantMethods: "GET, POST, PUT, PATCH, DELETE, OPTIONS",
+			wantHeaders: "Content-Type, Authorization",
+		},
+		{
+			name:   "origin not allowed",
+			cfg:    CORSConfig{AllowedOrigins: []string{"https://app.example.com"}},
+			origin: "https://evil.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := preflight(CORSMiddlewareWithConfig(tt.cfg)(next), tt.origin)
+
+			if rec.Code != http.StatusNoContent {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusNoContent)
+			}
+			for header, want := range map[string]string{
+				"Access-Control-Allow-Origin":  tt.wantOrigin,
+				"Access-Control-Allow-Methods": tt.wantMethods,
+				"Access-Control-Allow-Headers": tt.wantHeaders,
+				"Access-Control-Max-Age":       tt.wantMaxAge,
+			} {
+				if got := rec.Header().Get(header); got != want {
+					t.Errorf("%s = %q, want %q", header, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestServerCORSPreflight(t *testing.T) {
+	db, err := engine.Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	cfg := DefaultServerConfig()
+	cfg.JWTSecret = "0123456789abcdef0123456789abcdef"
+	cfg.CORSOrigins = []string{"https://app.example.com"}
+	cfg.CORSAllowedMethods = []string{"GET", "PUT"}
+	cfg.CORSAllowedHeaders = []string{"Content-Type", "Authorization", "If-Match"}
+	cfg.CORSMaxAge = time.Hour
+	s := NewServer(cfg, backend.NewBackend(db, config.DefaultConfig()), logging.NewNop())
+
+	rec := preflight(s.router, "https://app.example.com")
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	for header, want := range map[string]string{
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Methods":     "GET, PUT, OPTIONS",
+		"Access-Control-Allow-Headers":     "Content-Type, Authorization, If-Match",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Max-Age":           "3600",
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+}
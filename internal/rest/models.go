@@ -42,6 +42,7 @@ type SearchResponse struct {
 	Offset     int      `json:"offset"`
 	Limit      int      `json:"limit"`
 	HasMore    bool     `json:"hasMore"`
+	NextCursor string   `json:"nextCursor,omitempty"`
 }
 
 // AddRequest represents an add request.
@@ -81,6 +82,21 @@ type CompareResponse struct {
 	Match bool `json:"match"`
 }
 
+// GroupMembersResponse represents the members of a group.
+type GroupMembersResponse struct {
+	DN      string   `json:"dn"`
+	Members []string `json:"members"`
+	// Partial is true when the member list was cut at the configured limit.
+	Partial bool `json:"partial"`
+}
+
+// GroupMembershipResponse represents a group membership check.
+type GroupMembershipResponse struct {
+	DN       string `json:"dn"`
+	Member   string `json:"member"`
+	IsMember bool   `json:"isMember"`
+}
+
 // ErrorResponse represents an error response.
 type ErrorResponse struct {
 	Error      string `json:"error"`
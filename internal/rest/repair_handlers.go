@@ -28,8 +28,7 @@ func (h *Handlers) HandleRepairUIDUniqueness(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	bindDN := BindDN(r)
-	report, err := h.backend.RepairUIDUniqueness(dryRun, bindDN)
+	report, err := h.backend.RepairUIDUniqueness(operationContext(r), dryRun)
 	if err != nil {
 		status, code, msg := mapBackendError(err)
 		writeError(w, status, code, msg)
@@ -90,18 +90,42 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		ctx := withParams(req.Context(), params)
 		req = req.WithContext(ctx)
 
-		var handler http.Handler = route.Handler
-		for i := len(r.middleware) - 1; i >= 0; i-- {
-			handler = r.middleware[i](handler)
-		}
+		r.wrap(route.Handler).ServeHTTP(w, req)
+		return
+	}
 
-		handler.ServeHTTP(w, req)
+	// Answer OPTIONS for paths routed with other methods, so that CORS
+	// preflight requests reach the middleware
+	if req.Method == http.MethodOptions && r.hasPath(req.URL.Path) {
+		r.wrap(http.HandlerFunc(noContent)).ServeHTTP(w, req)
 		return
 	}
 
 	r.notFound(w, req)
 }
 
+// wrap applies the middleware to handler, the first added outermost.
+func (r *Router) wrap(handler http.Handler) http.Handler {
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		handler = r.middleware[i](handler)
+	}
+	return handler
+}
+
+// hasPath reports whether any route matches path.
+func (r *Router) hasPath(path string) bool {
+	for _, route := range r.routes {
+		if _, ok := matchPattern(route.Pattern, path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func noContent(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
 type paramsKey struct{}
 
 func withParams(ctx context.Context, params map[string]string) context.Context {
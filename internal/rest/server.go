@@ -14,22 +14,55 @@ import (
 	"github.com/KilimcininKorOglu/oba/internal/config"
 	"github.com/KilimcininKorOglu/oba/internal/logging"
 	"github.com/KilimcininKorOglu/oba/internal/raft"
+	"github.com/KilimcininKorOglu/oba/internal/server"
+	"github.com/KilimcininKorOglu/oba/internal/trace"
 )
 
 // ServerConfig holds REST server configuration.
 type ServerConfig struct {
-	Address      string
-	TLSAddress   string
-	TLSCert      string
-	TLSKey       string
-	JWTSecret    string
-	TokenTTL     time.Duration
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	IdleTimeout  time.Duration
-	RateLimit    int
-	CORSOrigins  []string
-	AdminDNs     []string
+	Address    string
+	TLSAddress string
+	TLSCert    string
+	TLSKey     string
+	// TLSKeyPassphrase decrypts TLSKey when it is encrypted.
+	TLSKeyPassphrase string
+	JWTSecret        string
+	TokenTTL         time.Duration
+	CursorTTL        time.Duration
+	ReadTimeout      time.Duration
+	WriteTimeout     time.Duration
+	IdleTimeout      time.Duration
+	RateLimit        int
+	CORSOrigins      []string
+	AdminDNs         []string
+	// CORSAllowedMethods and CORSAllowedHeaders list the methods and
+	// request headers allowed for cross-origin requests. Empty lists use
+	// DefaultCORSAllowedMethods and DefaultCORSAllowedHeaders.
+	CORSAllowedMethods []string
+	CORSAllowedHeaders []string
+	// CORSMaxAge is how long browsers may cache a preflight response. Zero
+	// uses DefaultCORSMaxAge; a negative value disables caching.
+	CORSMaxAge time.Duration
+	// TrustedProxies lists the CIDRs whose X-Forwarded-For and X-Real-IP
+	// headers are honored. Headers from other sources are ignored.
+	TrustedProxies []string
+	// TracerProvider provides the tracer for request spans (nil disables
+	// tracing).
+	TracerProvider trace.TracerProvider
+	// MinCompressSize is the smallest search or export response body
+	// compressed for clients that accept gzip. Zero uses
+	// DefaultMinCompressSize; a negative value disables compression.
+	MinCompressSize int
+	// ExternalJWTIssuer, ExternalJWTAudience, JWKSUri and ClaimTemplate
+	// accept JWTs of an external identity provider: tokens whose iss claim
+	// is ExternalJWTIssuer and whose aud claim contains
+	// ExternalJWTAudience, signed by a key published at JWKSUri, bind as
+	// the DN rendered from ClaimTemplate, such as
+	// "uid={{.sub}},ou=users,dc=example,dc=com". All four are needed.
+	ExternalJWTIssuer   string
+	ExternalJWTAudience string
+	JWKSUri             string
+	ClaimTemplate       string
 }
 
 // DefaultServerConfig returns default configuration.
@@ -37,11 +70,15 @@ func DefaultServerConfig() *ServerConfig {
 	return &ServerConfig{
 		Address:      ":8080",
 		TokenTTL:     24 * time.Hour,
+		CursorTTL:    5 * time.Minute,
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  120 * time.Second,
 		RateLimit:    100,
 		CORSOrigins:  []string{"*"},
+		CORSMaxAge:   DefaultCORSMaxAge,
+
+		MinCompressSize: DefaultMinCompressSize,
 	}
 }
 
@@ -56,6 +93,11 @@ type Server struct {
 	server    *http.Server
 	tlsServer *http.Server
 
+	// listener and tlsListener, when set, are served instead of binding
+	// Address and TLSAddress
+	listener    net.Listener
+	tlsListener net.Listener
+
 	// Hot-reloadable settings
 	rateLimit   int32 // atomic
 	tokenTTL    int64 // atomic (nanoseconds)
@@ -67,6 +109,7 @@ type Server struct {
 func NewServer(cfg *ServerConfig, be *backend.ObaBackend, logger logging.Logger) *Server {
 	auth := NewAuthenticator(be, cfg.JWTSecret, cfg.TokenTTL)
 	handlers := NewHandlers(be, auth)
+	handlers.SetCursorStore(NewCursorStore(&CursorStoreConfig{TTL: cfg.CursorTTL}))
 
 	router := NewRouter()
 
@@ -82,6 +125,15 @@ func NewServer(cfg *ServerConfig, be *backend.ObaBackend, logger logging.Logger)
 		corsOrigins: cfg.CORSOrigins,
 	}
 
+	if cfg.JWKSUri != "" {
+		verifier, err := NewExternalJWTVerifier(cfg.ExternalJWTIssuer, cfg.ExternalJWTAudience, cfg.JWKSUri, cfg.ClaimTemplate, nil)
+		if err != nil {
+			logger.WithComponent("system").Warn("ignoring external JWT issuer", "error", err)
+		} else {
+			auth.SetExternalVerifier(verifier)
+		}
+	}
+
 	s.setupRoutes()
 	s.setupMiddleware()
 
@@ -89,6 +141,11 @@ func NewServer(cfg *ServerConfig, be *backend.ObaBackend, logger logging.Logger)
 }
 
 func (s *Server) setupRoutes() {
+	// Search results and exports can be large, so they are compressed
+	compress := func(handler http.HandlerFunc) http.HandlerFunc {
+		return CompressMiddleware(s.config.MinCompressSize)(handler).ServeHTTP
+	}
+
 	s.router.GET("/api/v1/health", s.handlers.HandleHealth)
 	s.router.GET("/api/v1/stats", s.handlers.HandleStats)
 	s.router.GET("/api/v1/activities", s.handlers.HandleActivities)
@@ -107,13 +164,18 @@ func (s *Server) setupRoutes() {
 	s.router.POST("/api/v1/entries/{dn}/unlock", s.handlers.HandleUnlockEntry)
 	s.router.GET("/api/v1/entries/{dn}/lock-status", s.handlers.HandleGetLockStatus)
 
-	s.router.GET("/api/v1/search", s.handlers.HandleSearch)
-	s.router.GET("/api/v1/search/stream", s.handlers.HandleStreamSearch)
+	s.router.GET("/api/v1/deleted", compress(s.handlers.HandleListDeleted))
+	s.router.POST("/api/v1/deleted/{id}/restore", s.handlers.HandleRestoreDeleted)
+
+	s.router.GET("/api/v1/search", compress(s.handlers.HandleSearch))
+	s.router.GET("/api/v1/search/stream", compress(s.handlers.HandleStreamSearch))
 
 	s.router.POST("/api/v1/bulk", s.handlers.HandleBulk)
 
 	s.router.POST("/api/v1/compare", s.handlers.HandleCompare)
 
+	s.router.GET("/api/v1/groups/{dn}/members", compress(s.handlers.HandleGetGroupMembers))
+
 	// ACL management endpoints
 	s.router.GET("/api/v1/acl", s.handlers.HandleGetACL)
 	s.router.GET("/api/v1/acl/rules", s.handlers.HandleGetACLRules)
@@ -125,6 +187,7 @@ func (s *Server) setupRoutes() {
 	s.router.POST("/api/v1/acl/reload", s.handlers.HandleReloadACL)
 	s.router.POST("/api/v1/acl/save", s.handlers.HandleSaveACL)
 	s.router.POST("/api/v1/acl/validate", s.handlers.HandleValidateACL)
+	s.router.POST("/api/v1/acl/test", s.handlers.HandleTestACL)
 
 	// Config management endpoints
 	s.router.GET("/api/v1/config", s.handlers.HandleGetConfig)
@@ -135,15 +198,26 @@ func (s *Server) setupRoutes() {
 	s.router.POST("/api/v1/config/validate", s.handlers.HandleValidateConfig)
 
 	// Log management endpoints
-	s.router.GET("/api/v1/logs", s.handlers.HandleGetLogs)
+	s.router.GET("/api/v1/logs", compress(s.handlers.HandleGetLogs))
 	s.router.GET("/api/v1/logs/stats", s.handlers.HandleGetLogStats)
 	s.router.DELETE("/api/v1/logs", s.handlers.HandleClearLogs)
-	s.router.GET("/api/v1/logs/export", s.handlers.HandleExportLogs)
+	s.router.GET("/api/v1/logs/export", compress(s.handlers.HandleExportLogs))
 	s.router.GET("/api/v1/logs/archives", s.handlers.HandleGetLogArchives)
 	s.router.GET("/api/v1/logs/archives/stats", s.handlers.HandleGetLogArchiveStats)
 	s.router.POST("/api/v1/logs/archive", s.handlers.HandleArchiveLogsNow)
 	s.router.POST("/api/v1/logs/archives/cleanup", s.handlers.HandleCleanupArchives)
 
+	// Admin endpoints
+	s.router.GET("/api/v1/admin/bind-throttle", s.handlers.HandleGetBindThrottle)
+	s.router.DELETE("/api/v1/admin/bind-throttle/{ip}", s.handlers.HandleResetBindThrottle)
+	s.router.GET("/api/v1/admin/wire-dump", s.handlers.HandleGetWireDump)
+	s.router.PUT("/api/v1/admin/wire-dump", s.handlers.HandleSetWireDump)
+	s.router.POST("/api/v1/admin/indexes/{attr}/rebuild", s.handlers.HandleRebuildIndex)
+	s.router.POST("/api/v1/admin/compact", s.handlers.HandleCompact)
+	s.router.GET("/api/v1/admin/jobs", s.handlers.HandleListJobs)
+	s.router.GET("/api/v1/admin/jobs/{id}", s.handlers.HandleGetJob)
+	s.router.POST("/api/v1/admin/jobs/{id}/cancel", s.handlers.HandleCancelJob)
+
 	// Internal endpoints (cluster node-to-node communication)
 	s.router.POST("/api/v1/internal/log", s.handlers.HandleInternalLog)
 
@@ -156,6 +230,21 @@ func (s *Server) setupRoutes() {
 }
 
 func (s *Server) setupMiddleware() {
+	// Tracing outermost, so the request span covers all other middleware
+	if s.config.TracerProvider != nil {
+		s.router.Use(TracingMiddleware(s.config.TracerProvider))
+	}
+
+	// Resolve the client address before anything logs or rate limits it
+	if len(s.config.TrustedProxies) > 0 {
+		trusted, err := server.TrustedNetworks(s.config.TrustedProxies)
+		if err != nil {
+			s.logger.WithComponent("system").Warn("ignoring trusted proxies", "error", err)
+		} else {
+			s.router.Use(RealIPMiddleware(trusted))
+		}
+	}
+
 	// Logging middleware first (outermost) to capture user info after auth
 	s.router.Use(LoggingMiddleware(s.logger))
 
@@ -163,7 +252,12 @@ func (s *Server) setupMiddleware() {
 	s.router.Use(ConnectionTrackingMiddleware(s.handlers))
 
 	if len(s.config.CORSOrigins) > 0 {
-		s.router.Use(CORSMiddleware(s.config.CORSOrigins))
+		s.router.Use(CORSMiddlewareWithConfig(CORSConfig{
+			AllowedOrigins: s.config.CORSOrigins,
+			AllowedMethods: s.config.CORSAllowedMethods,
+			AllowedHeaders: s.config.CORSAllowedHeaders,
+			MaxAge:         s.config.CORSMaxAge,
+		}))
 	}
 
 	if s.config.RateLimit > 0 {
@@ -183,14 +277,25 @@ func (s *Server) setupMiddleware() {
 	if len(s.config.AdminDNs) > 0 {
 		s.router.Use(AdminOnlyMiddleware(s.config.AdminDNs, []string{
 			"/api/v1/acl",
+			"/api/v1/admin",
 			"/api/v1/config",
 			"/api/v1/cluster/repair",
+			"/api/v1/deleted",
 		}, []string{
 			"/api/v1/config/public",
 		}))
 	}
 }
 
+// SetListeners makes Start serve on listener and tlsListener, such as
+// sockets passed by systemd, instead of binding Address and TLSAddress.
+// A nil listener keeps binding its address. tlsListener accepts plain TCP
+// connections; TLS is added by the server.
+func (s *Server) SetListeners(listener, tlsListener net.Listener) {
+	s.listener = listener
+	s.tlsListener = tlsListener
+}
+
 // Start starts the REST server.
 func (s *Server) Start() error {
 	s.server = &http.Server{
@@ -201,17 +306,22 @@ func (s *Server) Start() error {
 		IdleTimeout:  s.config.IdleTimeout,
 	}
 
-	listener, err := net.Listen("tcp", s.config.Address)
-	if err != nil {
-		return err
+	listener := s.listener
+	if listener == nil {
+		var err error
+		listener, err = net.Listen("tcp", s.config.Address)
+		if err != nil {
+			return err
+		}
 	}
 
-	s.logger.WithSource("system").Info("REST server started", "address", s.config.Address)
+	s.logger.WithComponent("system").Info("REST server started", "address", listener.Addr().String(),
+		"activated", s.listener != nil)
 
 	go s.server.Serve(listener)
 
 	if s.config.TLSAddress != "" && s.config.TLSCert != "" && s.config.TLSKey != "" {
-		cert, err := tls.LoadX509KeyPair(s.config.TLSCert, s.config.TLSKey)
+		cert, err := server.LoadCertificateWithPassphrase(s.config.TLSCert, s.config.TLSKey, []byte(s.config.TLSKeyPassphrase))
 		if err != nil {
 			return err
 		}
@@ -230,12 +340,18 @@ func (s *Server) Start() error {
 			IdleTimeout:  s.config.IdleTimeout,
 		}
 
-		tlsListener, err := tls.Listen("tcp", s.config.TLSAddress, tlsConfig)
-		if err != nil {
-			return err
+		var tlsListener net.Listener
+		if s.tlsListener != nil {
+			tlsListener = tls.NewListener(s.tlsListener, tlsConfig)
+		} else {
+			tlsListener, err = tls.Listen("tcp", s.config.TLSAddress, tlsConfig)
+			if err != nil {
+				return err
+			}
 		}
 
-		s.logger.WithSource("system").Info("REST TLS server started", "address", s.config.TLSAddress)
+		s.logger.WithComponent("system").Info("REST TLS server started", "address", tlsListener.Addr().String(),
+			"activated", s.tlsListener != nil)
 
 		go s.tlsServer.Serve(tlsListener)
 	}
@@ -245,6 +361,8 @@ func (s *Server) Start() error {
 
 // Stop gracefully stops the REST server.
 func (s *Server) Stop(ctx context.Context) error {
+	s.handlers.jobs.Close()
+
 	if s.server != nil {
 		if err := s.server.Shutdown(ctx); err != nil {
 			return err
@@ -257,7 +375,7 @@ func (s *Server) Stop(ctx context.Context) error {
 		}
 	}
 
-	s.logger.WithSource("system").Info("REST server stopped")
+	s.logger.WithComponent("system").Info("REST server stopped")
 	return nil
 }
 
@@ -310,6 +428,22 @@ func (s *Server) SetConfigManager(m *config.ConfigManager) {
 	s.handlers.SetConfigManager(m)
 }
 
+// SetJobManager sets the manager that runs admin jobs. The server closes
+// it when stopped.
+func (s *Server) SetJobManager(jm *server.JobManager) {
+	s.handlers.SetJobManager(jm)
+}
+
+// SetBindThrottle sets the LDAP bind throttle for admin endpoints.
+func (s *Server) SetBindThrottle(t *server.BindThrottle) {
+	s.handlers.SetBindThrottle(t)
+}
+
+// SetWireDump sets the LDAP wire dump for admin endpoints.
+func (s *Server) SetWireDump(wd *server.WireDump) {
+	s.handlers.SetWireDump(wd)
+}
+
 // SetClusterBackend sets the cluster backend for cluster-related endpoints.
 func (s *Server) SetClusterBackend(cb *raft.ClusterBackend) {
 	s.handlers.SetClusterBackend(cb)
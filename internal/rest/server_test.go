@@ -0,0 +1,47 @@
+package rest
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/backend"
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/logging"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+func TestServerSetListeners(t *testing.T) {
+	db, err := engine.Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	cfg := DefaultServerConfig()
+	cfg.JWTSecret = "0123456789abcdef0123456789abcdef"
+	// Binding this address fails, the listener set below is served
+	cfg.Address = "256.0.0.1:8080"
+	s := NewServer(cfg, backend.NewBackend(db, config.DefaultConfig()), logging.NewNop())
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	s.SetListeners(listener, nil)
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer s.Stop(context.Background())
+
+	resp, err := http.Get("http://" + listener.Addr().String() + "/api/v1/health")
+	if err != nil {
+		t.Fatalf("GET health: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
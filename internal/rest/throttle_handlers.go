@@ -0,0 +1,77 @@
+package rest
+
+import (
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+// BindThrottleEntryJSON represents the throttle state of one source IP.
+type BindThrottleEntryJSON struct {
+	IP          string    `json:"ip"`
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"lastFailure"`
+	Delay       string    `json:"delay"`
+	DelayMs     int64     `json:"delayMs"`
+}
+
+// BindThrottleResponse represents the bind throttle state.
+type BindThrottleResponse struct {
+	Entries []BindThrottleEntryJSON `json:"entries"`
+	Total   int                     `json:"total"`
+}
+
+// HandleGetBindThrottle handles GET /api/v1/admin/bind-throttle
+func (h *Handlers) HandleGetBindThrottle(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&h.requestCount, 1)
+
+	if h.bindThrottle == nil {
+		writeError(w, http.StatusServiceUnavailable, "bind_throttle_disabled", "bind throttling is not enabled")
+		return
+	}
+
+	status := h.bindThrottle.Status()
+	entries := make([]BindThrottleEntryJSON, len(status))
+	for i, s := range status {
+		entries[i] = BindThrottleEntryJSON{
+			IP:          s.IP,
+			Failures:    s.Failures,
+			LastFailure: s.LastFailure,
+			Delay:       s.Delay.String(),
+			DelayMs:     s.Delay.Milliseconds(),
+		}
+	}
+
+	writeJSON(w, http.StatusOK, BindThrottleResponse{
+		Entries: entries,
+		Total:   len(entries),
+	})
+}
+
+// HandleResetBindThrottle handles DELETE /api/v1/admin/bind-throttle/{ip}
+func (h *Handlers) HandleResetBindThrottle(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&h.requestCount, 1)
+
+	if h.bindThrottle == nil {
+		writeError(w, http.StatusServiceUnavailable, "bind_throttle_disabled", "bind throttling is not enabled")
+		return
+	}
+
+	ip, err := url.PathUnescape(extractPathParam(r.URL.Path, "/api/v1/admin/bind-throttle/"))
+	if err != nil || ip == "" {
+		writeError(w, http.StatusBadRequest, "invalid_ip", "invalid IP address")
+		return
+	}
+
+	if !h.bindThrottle.Reset(ip) {
+		writeError(w, http.StatusNotFound, "not_found", "IP address is not throttled")
+		return
+	}
+
+	h.auditLog(r, "bind throttle reset", "ip", ip)
+	writeJSON(w, http.StatusOK, map[string]string{
+		"message": "bind throttle reset",
+		"ip":      ip,
+	})
+}
@@ -0,0 +1,142 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/KilimcininKorOglu/oba/internal/filter"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+)
+
+// Limits on the DNs, filters and attribute lists clients send. They are
+// far above what a directory needs and bound the work one request can
+// cause.
+const (
+	maxDNLength     = 1024
+	maxFilterLength = 4096
+	maxAttributes   = 100
+)
+
+// Input validation errors.
+var (
+	errDNTooLong     = fmt.Errorf("DN exceeds %d bytes", maxDNLength)
+	errInvalidDN     = errors.New("malformed DN")
+	errInvalidRDN    = errors.New("malformed RDN")
+	errFilterTooLong = fmt.Errorf("filter exceeds %d bytes", maxFilterLength)
+)
+
+// validateDN checks a DN sent by a client. It must fit in maxDNLength and
+// be an RFC 4514 DN whose attribute types are names or numeric OIDs. The
+// handlers reject malformed DNs with 400 before calling the backend.
+func validateDN(dn string) error {
+	if len(dn) > maxDNLength {
+		return errDNTooLong
+	}
+	rdns, err := ldap.SplitDN(dn)
+	if err != nil || len(rdns) == 0 {
+		return errInvalidDN
+	}
+	for _, rdn := range rdns {
+		if validateRDN(rdn) != nil {
+			return errInvalidDN
+		}
+	}
+	return nil
+}
+
+// validateRDN checks a single RDN such as the new RDN of a rename.
+func validateRDN(rdn string) error {
+	// NUL has to be escaped as \00 (RFC 4514 Section 2.4)
+	if strings.IndexByte(rdn, 0) >= 0 {
+		return errInvalidRDN
+	}
+	avas, err := ldap.ParseRDN(rdn)
+	if err != nil {
+		return errInvalidRDN
+	}
+	for _, ava := range avas {
+		desc, err := ldap.ParseAttributeDescription(ava.Type)
+		if err != nil || desc.Name == "*" || len(desc.Options) > 0 {
+			return errInvalidRDN
+		}
+	}
+	return nil
+}
+
+// checkDN validates the DN of the request field name. If it is not valid
+// it writes a 400 response and returns false.
+func checkDN(w http.ResponseWriter, name, dn string) bool {
+	if err := validateDN(dn); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_dn", name+": "+err.Error())
+		return false
+	}
+	return true
+}
+
+// pathDN returns the DN of the {dn} path parameter. If it is missing,
+// badly encoded or malformed it writes a 400 response and returns false.
+func pathDN(w http.ResponseWriter, r *http.Request) (string, bool) {
+	dn := Param(r, "dn")
+	if dn == "" {
+		writeError(w, http.StatusBadRequest, "missing_dn", "DN is required")
+		return "", false
+	}
+
+	decodedDN, err := url.PathUnescape(dn)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_dn", "invalid DN encoding")
+		return "", false
+	}
+	if !checkDN(w, "DN", decodedDN) {
+		return "", false
+	}
+	return decodedDN, true
+}
+
+// parseSearchFilter parses the filter of a search request. It returns the
+// filter with its re-serialized string, which is what cursors and logs
+// keep, so the raw input is not passed on. If the filter is too long or
+// malformed it writes a 400 response and returns false.
+func parseSearchFilter(w http.ResponseWriter, s string) (*filter.Filter, string, bool) {
+	if len(s) > maxFilterLength {
+		writeError(w, http.StatusBadRequest, "invalid_filter", errFilterTooLong.Error())
+		return nil, "", false
+	}
+	f, err := filter.Parse(s)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_filter", "invalid filter syntax: "+err.Error())
+		return nil, "", false
+	}
+	return f, f.String(), true
+}
+
+// parseAttributeList splits the comma separated attribute list of a search
+// request. Entries must be attribute descriptions, "*" or "+". If there
+// are more than maxAttributes or one is malformed it writes a 400
+// response and returns false.
+func parseAttributeList(w http.ResponseWriter, s string) ([]string, bool) {
+	var attrs []string
+	for _, attr := range strings.Split(s, ",") {
+		if attr = strings.TrimSpace(attr); attr != "" {
+			attrs = append(attrs, attr)
+		}
+	}
+	if len(attrs) > maxAttributes {
+		writeError(w, http.StatusBadRequest, "invalid_attributes",
+			fmt.Sprintf("at most %d attributes may be requested", maxAttributes))
+		return nil, false
+	}
+	for _, attr := range attrs {
+		if attr == "+" {
+			continue
+		}
+		if _, err := ldap.ParseAttributeDescription(attr); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_attributes", "invalid attribute: "+attr)
+			return nil, false
+		}
+	}
+	return attrs, true
+}
@@ -0,0 +1,200 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/backend"
+	"github.com/KilimcininKorOglu/oba/internal/config"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+// newValidationHandlers returns handlers over a backend holding two
+// devices.
+func newValidationHandlers(t *testing.T) (*Handlers, *backend.ObaBackend) {
+	t.Helper()
+
+	db, err := engine.Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	be := backend.NewBackend(db, config.DefaultConfig())
+	for _, e := range []*backend.Entry{
+		newTestEntry("dc=example,dc=com", "domain", "dc", "example"),
+		newTestEntry("cn=printer,dc=example,dc=com", "device", "cn", "printer"),
+		newTestEntry("cn=scanner,dc=example,dc=com", "device", "cn", "scanner"),
+	} {
+		if err := be.Add(context.Background(), e); err != nil {
+			t.Fatalf("Add(%s) error = %v", e.DN, err)
+		}
+	}
+	return NewHandlers(be, nil), be
+}
+
+// errorCode returns the error code of a JSON error response.
+func errorCode(rec *httptest.ResponseRecorder) string {
+	var resp ErrorResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	return resp.Error
+}
+
+func TestValidateDN(t *testing.T) {
+	valid := []string{
+		"dc=example,dc=com",
+		`cn=Smith\, John,ou=users,dc=example,dc=com`,
+		"cn=a\\00b,dc=example,dc=com",
+		"uid=alice+cn=Alice,dc=example,dc=com",
+		"2.5.4.3=printer,dc=example,dc=com",
+		"cn=(printer),dc=example,dc=com",
+	}
+	for _, dn := range valid {
+		if err := validateDN(dn); err != nil {
+			t.Errorf("validateDN(%q) error = %v", dn, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"cn=a\x00b,dc=example,dc=com",
+		"cn=printer,,dc=example,dc=com",
+		"printer,dc=example,dc=com",
+		"(cn=*)=x,dc=example,dc=com",
+		"cn;binary=x,dc=example,dc=com",
+		`cn=a\zz,dc=example,dc=com`,
+		"cn=" + strings.Repeat("a", maxDNLength) + ",dc=example,dc=com",
+	}
+	for _, dn := range invalid {
+		if err := validateDN(dn); err == nil {
+			t.Errorf("validateDN(%q) accepted a malformed DN", dn)
+		}
+	}
+}
+
+// TestHostilePathDNs tests that malformed DNs in the path are rejected
+// with 400 and leave the directory unchanged.
+func TestHostilePathDNs(t *testing.T) {
+	h, be := newValidationHandlers(t)
+
+	handlers := map[string]http.HandlerFunc{
+		http.MethodGet:    h.HandleGetEntry,
+		http.MethodPatch:  h.HandleModifyEntry,
+		http.MethodDelete: h.HandleDeleteEntry,
+		"members":         h.HandleGetGroupMembers,
+	}
+	dns := []string{
+		"cn=printer\x00,dc=example,dc=com",
+		"cn=printer,,dc=example,dc=com",
+		"*)(objectClass=*",
+		"cn=" + strings.Repeat("x", 2*maxDNLength),
+	}
+	body := `{"changes":[{"operation":"replace","attribute":"description","values":["x"]}]}`
+	for name, handler := range handlers {
+		for _, dn := range dns {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			req = req.WithContext(withParams(req.Context(), map[string]string{"dn": url.PathEscape(dn)}))
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+			if rec.Code != http.StatusBadRequest || errorCode(rec) != "invalid_dn" {
+				t.Errorf("%s %q status = %d, body %s, want 400 invalid_dn", name, dn, rec.Code, rec.Body)
+			}
+		}
+	}
+
+	if _, err := be.GetEntry("cn=printer,dc=example,dc=com"); err != nil {
+		t.Errorf("GetEntry() after rejected requests error = %v", err)
+	}
+}
+
+// TestHostileSearchInput tests that search requests with malformed base
+// DNs, filters or attribute lists are rejected with 400.
+func TestHostileSearchInput(t *testing.T) {
+	h, _ := newValidationHandlers(t)
+
+	tests := []struct {
+		name  string
+		query url.Values
+		code  string
+	}{
+		{"base DN with NUL", url.Values{"baseDN": {"dc=example\x00,dc=com"}}, "invalid_dn"},
+		{"overlong base DN", url.Values{"baseDN": {strings.Repeat("dc=x,", maxDNLength/4) + "dc=com"}}, "invalid_dn"},
+		{"appended filter", url.Values{"filter": {"(cn=printer)(objectClass=*)"}}, "invalid_filter"},
+		{"unescaped parens", url.Values{"filter": {"(cn=*)(|(cn=*))"}}, "invalid_filter"},
+		{"unbalanced", url.Values{"filter": {"(&(cn=printer)(objectClass=*)"}}, "invalid_filter"},
+		{"embedded NUL", url.Values{"filter": {"(cn=printer\x00)"}}, "invalid_filter"},
+		{"bad escape", url.Values{"filter": {`(cn=printer\q)`}}, "invalid_filter"},
+		{"bad attribute", url.Values{"filter": {"(c)n=printer)"}}, "invalid_filter"},
+		{"overlong filter", url.Values{"filter": {"(cn=" + strings.Repeat("a", maxFilterLength) + ")"}}, "invalid_filter"},
+		{"too many attributes", url.Values{"attributes": {strings.Repeat("cn,", maxAttributes+1)}}, "invalid_attributes"},
+		{"bad attribute list", url.Values{"attributes": {"cn,(sn)"}}, "invalid_attributes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.query.Get("baseDN") == "" {
+				tt.query.Set("baseDN", "dc=example,dc=com")
+			}
+			handlers := []http.HandlerFunc{h.HandleSearch}
+			if tt.code != "invalid_attributes" {
+				// The stream search has no attribute list
+				handlers = append(handlers, h.HandleStreamSearch)
+			}
+			for _, handler := range handlers {
+				req := httptest.NewRequest(http.MethodGet, "/api/v1/search?"+tt.query.Encode(), nil)
+				rec := httptest.NewRecorder()
+				handler(rec, req)
+				if rec.Code != http.StatusBadRequest {
+					t.Fatalf("status = %d, body %s, want 400", rec.Code, rec.Body)
+				}
+				if code := errorCode(rec); code != tt.code {
+					t.Errorf("error = %s, want %s", code, tt.code)
+				}
+			}
+		})
+	}
+}
+
+// TestSearchFilterReserialized tests that an escaped filter matches
+// literally and that the cursor keeps the re-serialized filter rather
+// than the raw input.
+func TestSearchFilterReserialized(t *testing.T) {
+	h, _ := newValidationHandlers(t)
+
+	search := func(filterStr string, limit string) SearchResponse {
+		t.Helper()
+		query := url.Values{"baseDN": {"dc=example,dc=com"}, "filter": {filterStr}, "limit": {limit}}
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/search?"+query.Encode(), nil)
+		rec := httptest.NewRecorder()
+		h.HandleSearch(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("search %s status = %d, body %s", filterStr, rec.Code, rec.Body)
+		}
+		var resp SearchResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decoding response: %v", err)
+		}
+		return resp
+	}
+
+	if resp := search(`(cn=\2a)`, "0"); len(resp.Entries) != 0 {
+		t.Errorf("escaped * matched %d entries, want none", len(resp.Entries))
+	}
+
+	resp := search(" objectClass=device ", "1")
+	if !resp.HasMore || resp.NextCursor == "" {
+		t.Fatalf("search with limit 1 returned no cursor: %+v", resp)
+	}
+	cursor, err := h.cursors.Resolve(resp.NextCursor)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if cursor.Filter != "(objectClass=device)" {
+		t.Errorf("cursor filter = %q, want the re-serialized (objectClass=device)", cursor.Filter)
+	}
+}
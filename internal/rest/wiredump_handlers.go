@@ -0,0 +1,121 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/server"
+)
+
+// WireDumpResponse represents the LDAP wire dump state.
+type WireDumpResponse struct {
+	Enabled          bool       `json:"enabled"`
+	ExpiresAt        *time.Time `json:"expiresAt,omitempty"`
+	Connections      int        `json:"connections"`
+	Directory        string     `json:"directory"`
+	MaxBytes         int64      `json:"maxBytes"`
+	ConnectionFilter []string   `json:"connectionFilter"`
+	Duration         string     `json:"duration"`
+	RawBER           bool       `json:"rawBER"`
+	IncludePasswords bool       `json:"includePasswords"`
+	LastError        string     `json:"lastError,omitempty"`
+}
+
+// WireDumpRequest represents a request to change the wire dump state.
+// Fields left out keep their current value.
+type WireDumpRequest struct {
+	Enabled          bool      `json:"enabled"`
+	ConnectionFilter *[]string `json:"connectionFilter"`
+	Duration         *string   `json:"duration"`
+	RawBER           *bool     `json:"rawBER"`
+}
+
+// HandleGetWireDump handles GET /api/v1/admin/wire-dump
+func (h *Handlers) HandleGetWireDump(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&h.requestCount, 1)
+
+	if h.wireDump == nil {
+		writeError(w, http.StatusServiceUnavailable, "wire_dump_unavailable", "wire dump directory is not configured")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, wireDumpResponse(h.wireDump.Status()))
+}
+
+// HandleSetWireDump handles PUT /api/v1/admin/wire-dump
+func (h *Handlers) HandleSetWireDump(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&h.requestCount, 1)
+
+	if h.wireDump == nil {
+		writeError(w, http.StatusServiceUnavailable, "wire_dump_unavailable", "wire dump directory is not configured")
+		return
+	}
+
+	var req WireDumpRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "invalid JSON body")
+		return
+	}
+
+	if !req.Enabled {
+		h.wireDump.Disable()
+		h.auditLog(r, "wire dump disabled")
+		writeJSON(w, http.StatusOK, wireDumpResponse(h.wireDump.Status()))
+		return
+	}
+
+	current := h.wireDump.Status().Config
+	filter := current.ConnectionFilter
+	if req.ConnectionFilter != nil {
+		filter = *req.ConnectionFilter
+	}
+	duration := current.Duration
+	if req.Duration != nil {
+		d, err := time.ParseDuration(*req.Duration)
+		if err != nil || d < 0 {
+			writeError(w, http.StatusBadRequest, "invalid_duration", "duration must be a non-negative Go duration such as 30m")
+			return
+		}
+		duration = d
+	}
+	rawBER := current.RawBER
+	if req.RawBER != nil {
+		rawBER = *req.RawBER
+	}
+
+	if err := h.wireDump.Enable(filter, duration, rawBER); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	h.auditLog(r, "wire dump enabled",
+		"connection_filter", filter,
+		"duration", duration.String(),
+		"raw_ber", rawBER)
+	writeJSON(w, http.StatusOK, wireDumpResponse(h.wireDump.Status()))
+}
+
+// wireDumpResponse converts a wire dump status to its JSON form.
+func wireDumpResponse(status server.WireDumpStatus) WireDumpResponse {
+	resp := WireDumpResponse{
+		Enabled:          status.Enabled,
+		Connections:      status.Connections,
+		Directory:        status.Config.Directory,
+		MaxBytes:         status.Config.MaxBytes,
+		ConnectionFilter: status.Config.ConnectionFilter,
+		Duration:         status.Config.Duration.String(),
+		RawBER:           status.Config.RawBER,
+		IncludePasswords: status.Config.IncludePasswords,
+		LastError:        status.LastError,
+	}
+	if resp.ConnectionFilter == nil {
+		resp.ConnectionFilter = []string{}
+	}
+	if !status.ExpiresAt.IsZero() {
+		expiresAt := status.ExpiresAt
+		resp.ExpiresAt = &expiresAt
+	}
+	return resp
+}
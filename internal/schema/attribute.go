@@ -1,5 +1,7 @@
 package schema
 
+import "strings"
+
 // AttributeUsage defines how an attribute is used in the directory.
 // This determines whether the attribute is user-modifiable and its scope.
 type AttributeUsage int
@@ -46,20 +48,22 @@ func (u AttributeUsage) IsOperational() bool {
 // AttributeType represents an LDAP attribute type definition.
 // Attribute types define the syntax and constraints for attribute values.
 type AttributeType struct {
-	OID         string         // Object Identifier (e.g., "2.5.4.3")
-	Name        string         // Primary name (e.g., "cn")
-	Names       []string       // All names including aliases (e.g., ["cn", "commonName"])
-	Desc        string         // Human-readable description
-	Obsolete    bool           // Whether this attribute type is obsolete
-	Superior    string         // Parent attribute type name or OID
-	Equality    string         // Matching rule OID/name for equality matching
-	Ordering    string         // Matching rule OID/name for ordering matching
-	Substring   string         // Matching rule OID/name for substring matching
-	Syntax      string         // Syntax OID (e.g., "1.3.6.1.4.1.1466.115.121.1.15")
-	SingleValue bool           // If true, attribute can have only one value
-	Collective  bool           // If true, attribute is collective
-	NoUserMod   bool           // If true, attribute cannot be modified by users
-	Usage       AttributeUsage // How the attribute is used
+	OID          string              // Object Identifier (e.g., "2.5.4.3")
+	Name         string              // Primary name (e.g., "cn")
+	Names        []string            // All names including aliases (e.g., ["cn", "commonName"])
+	Desc         string              // Human-readable description
+	Obsolete     bool                // Whether this attribute type is obsolete
+	Superior     string              // Parent attribute type name or OID
+	Equality     string              // Matching rule OID/name for equality matching
+	Ordering     string              // Matching rule OID/name for ordering matching
+	Substring    string              // Matching rule OID/name for substring matching
+	Syntax       string              // Syntax OID (e.g., "1.3.6.1.4.1.1466.115.121.1.15")
+	SyntaxLength int                 // Suggested maximum value length from SYNTAX oid{len} (0 = none)
+	SingleValue  bool                // If true, attribute can have only one value
+	Collective   bool                // If true, attribute is collective
+	NoUserMod    bool                // If true, attribute cannot be modified by users
+	Usage        AttributeUsage      // How the attribute is used
+	Extensions   map[string][]string // X- extensions (e.g., X-ORIGIN)
 }
 
 // NewAttributeType creates a new AttributeType with the given OID and name.
@@ -73,6 +77,18 @@ func NewAttributeType(oid, name string) *AttributeType {
 	}
 }
 
+// Aliases returns the names of the attribute type other than its primary
+// name, as in "commonName" for cn.
+func (at *AttributeType) Aliases() []string {
+	var aliases []string
+	for _, name := range at.Names {
+		if !strings.EqualFold(name, at.Name) {
+			aliases = append(aliases, name)
+		}
+	}
+	return aliases
+}
+
 // IsUserAttribute returns true if this is a user-modifiable attribute.
 func (at *AttributeType) IsUserAttribute() bool {
 	return at.Usage == UserApplications && !at.NoUserMod
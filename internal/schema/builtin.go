@@ -0,0 +1,174 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Built-in schema set names.
+const (
+	// BuiltinCore is the core schema (RFC 4512, RFC 4519).
+	BuiltinCore = "core"
+	// BuiltinCosine is the COSINE schema (RFC 4524).
+	BuiltinCosine = "cosine"
+	// BuiltinInetOrgPerson is the inetOrgPerson schema (RFC 2798).
+	BuiltinInetOrgPerson = "inetorgperson"
+	// BuiltinNIS is the NIS schema (RFC 2307).
+	BuiltinNIS = "nis"
+	// BuiltinDynGroup is the dynamic group schema (groupOfURLs).
+	BuiltinDynGroup = "dyngroup"
+)
+
+// ErrUnknownBuiltinSchema is returned when a requested built-in schema set does not exist.
+var ErrUnknownBuiltinSchema = errors.New("unknown built-in schema set")
+
+// DefaultBuiltinSchemas is the list of schema sets loaded when none are configured.
+var DefaultBuiltinSchemas = []string{BuiltinCore, BuiltinCosine, BuiltinInetOrgPerson}
+
+// builtinSet is a named group of schema definitions.
+type builtinSet struct {
+	requires       []string
+	syntaxes       []string
+	matchingRules  []string
+	attributeTypes []string
+	objectClasses  []string
+}
+
+// builtinSetOrder lists the built-in schema sets in dependency order.
+var builtinSetOrder = []string{BuiltinCore, BuiltinCosine, BuiltinInetOrgPerson, BuiltinNIS, BuiltinDynGroup}
+
+// builtinSets maps set names to their definitions.
+var builtinSets = map[string]*builtinSet{
+	BuiltinCore: {
+		syntaxes:       coreSyntaxes,
+		matchingRules:  coreMatchingRules,
+		attributeTypes: coreAttributeTypes,
+		objectClasses:  coreObjectClasses,
+	},
+	BuiltinCosine: {
+		requires:       []string{BuiltinCore},
+		attributeTypes: cosineAttributeTypes,
+		objectClasses:  cosineObjectClasses,
+	},
+	BuiltinInetOrgPerson: {
+		requires:       []string{BuiltinCore, BuiltinCosine},
+		attributeTypes: inetOrgPersonAttributeTypes,
+		objectClasses:  inetOrgPersonObjectClasses,
+	},
+	BuiltinNIS: {
+		requires:       []string{BuiltinCore, BuiltinCosine},
+		syntaxes:       nisSyntaxes,
+		attributeTypes: nisAttributeTypes,
+		objectClasses:  nisObjectClasses,
+	},
+	BuiltinDynGroup: {
+		requires:       []string{BuiltinCore},
+		attributeTypes: dynGroupAttributeTypes,
+		objectClasses:  dynGroupObjectClasses,
+	},
+}
+
+// BuiltinSchemaNames returns the names of all built-in schema sets.
+func BuiltinSchemaNames() []string {
+	names := make([]string, len(builtinSetOrder))
+	copy(names, builtinSetOrder)
+	return names
+}
+
+// IsBuiltinSchema reports whether name is a built-in schema set. Names are case-insensitive.
+func IsBuiltinSchema(name string) bool {
+	_, ok := builtinSets[strings.ToLower(name)]
+	return ok
+}
+
+// LoadBuiltinSchema builds a schema from the named built-in schema sets.
+// Sets required by the requested ones are loaded as well, so
+// LoadBuiltinSchema("inetorgperson") also loads core and cosine.
+// With no names, DefaultBuiltinSchemas is loaded.
+func LoadBuiltinSchema(names ...string) (*Schema, error) {
+	if len(names) == 0 {
+		names = DefaultBuiltinSchemas
+	}
+
+	selected := make(map[string]bool)
+	var include func(name string) error
+	include = func(name string) error {
+		key := strings.ToLower(strings.TrimSpace(name))
+		set, ok := builtinSets[key]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownBuiltinSchema, name)
+		}
+		if selected[key] {
+			return nil
+		}
+		selected[key] = true
+		for _, dep := range set.requires {
+			if err := include(dep); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, name := range names {
+		if err := include(name); err != nil {
+			return nil, err
+		}
+	}
+
+	s := NewSchema()
+
+	// Load in order: syntaxes, matching rules, attribute types, object classes
+	// so that each set only refers to definitions loaded before it.
+	for _, name := range builtinSetOrder {
+		if !selected[name] {
+			continue
+		}
+		if err := loadBuiltinSet(s, builtinSets[name]); err != nil {
+			return nil, fmt.Errorf("schema set %s: %w", name, err)
+		}
+	}
+
+	if err := resolveObjectClassInheritance(s); err != nil {
+		return nil, err
+	}
+	if err := resolveAttributeTypeInheritance(s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// loadBuiltinSet parses the definitions of a built-in set into the schema.
+func loadBuiltinSet(s *Schema, set *builtinSet) error {
+	for _, def := range set.syntaxes {
+		syn, err := parseSyntaxDef(def)
+		if err != nil {
+			return err
+		}
+		s.AddSyntax(syn)
+	}
+	for _, def := range set.matchingRules {
+		mr, err := parseMatchingRule(def)
+		if err != nil {
+			return err
+		}
+		s.AddMatchingRule(mr)
+	}
+	for _, def := range set.attributeTypes {
+		at, err := ParseAttributeTypeDescription(def)
+		if err != nil {
+			return err
+		}
+		s.AddAttributeType(at)
+	}
+	for _, def := range set.objectClasses {
+		oc, err := ParseObjectClassDescription(def)
+		if err != nil {
+			return err
+		}
+		s.AddObjectClass(oc)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestBuiltinSchemaCompleteness checks that every attribute referenced by the
+// object classes of each built-in set resolves to a defined attribute type,
+// syntax and matching rules when the set is loaded on its own.
+func TestBuiltinSchemaCompleteness(t *testing.T) {
+	for _, name := range BuiltinSchemaNames() {
+		t.Run(name, func(t *testing.T) {
+			s, err := LoadBuiltinSchema(name)
+			if err != nil {
+				t.Fatalf("LoadBuiltinSchema(%q) error = %v", name, err)
+			}
+
+			for key, oc := range s.ObjectClasses {
+				if key != oc.OID {
+					continue
+				}
+				if oc.Superior != "" && s.GetObjectClass(oc.Superior) == nil {
+					t.Errorf("%s: superior %q not defined", oc.Name, oc.Superior)
+				}
+
+				attrs := append(append([]string{}, oc.Must...), oc.May...)
+				for _, attr := range attrs {
+					checkBuiltinAttribute(t, s, oc.Name, attr)
+				}
+			}
+
+			for key, at := range s.AttributeTypes {
+				if key != at.OID {
+					continue
+				}
+				if at.Superior != "" && s.GetAttributeType(at.Superior) == nil {
+					t.Errorf("%s: superior %q not defined", at.Name, at.Superior)
+				}
+				checkBuiltinAttribute(t, s, at.Name, at.Name)
+			}
+
+			for key, mr := range s.MatchingRules {
+				if key == mr.OID && s.GetSyntax(mr.Syntax) == nil {
+					t.Errorf("matching rule %s: syntax %q not defined", mr.Name, mr.Syntax)
+				}
+			}
+		})
+	}
+}
+
+// checkBuiltinAttribute reports attributes that do not resolve to a type,
+// syntax and matching rules in the schema.
+func checkBuiltinAttribute(t *testing.T, s *Schema, owner, attr string) {
+	t.Helper()
+
+	at := s.GetAttributeType(attr)
+	if at == nil {
+		t.Errorf("%s: attribute %q not defined", owner, attr)
+		return
+	}
+
+	syntax := s.GetEffectiveSyntax(attr)
+	if syntax == "" {
+		t.Errorf("%s: attribute %q has no syntax", owner, attr)
+	} else if s.GetSyntax(syntax) == nil {
+		t.Errorf("%s: attribute %q uses undefined syntax %q", owner, attr, syntax)
+	}
+
+	for _, rule := range []string{at.Equality, at.Ordering, at.Substring} {
+		if rule != "" && s.GetMatchingRule(rule) == nil {
+			t.Errorf("%s: attribute %q uses undefined matching rule %q", owner, attr, rule)
+		}
+	}
+}
+
+func TestLoadBuiltinSchemaDependencies(t *testing.T) {
+	s, err := LoadBuiltinSchema("InetOrgPerson")
+	if err != nil {
+		t.Fatalf("LoadBuiltinSchema() error = %v", err)
+	}
+
+	// inetorgperson pulls in core and cosine
+	for _, name := range []string{"top", "organizationalPerson", "domain", "inetOrgPerson"} {
+		if s.GetObjectClass(name) == nil {
+			t.Errorf("object class %q not loaded", name)
+		}
+	}
+	if s.GetObjectClass("posixAccount") != nil {
+		t.Error("posixAccount should not be loaded without the nis set")
+	}
+}
+
+func TestLoadBuiltinSchemaDefaults(t *testing.T) {
+	s, err := LoadBuiltinSchema()
+	if err != nil {
+		t.Fatalf("LoadBuiltinSchema() error = %v", err)
+	}
+	if s.GetObjectClass("inetOrgPerson") == nil {
+		t.Error("default sets should include inetOrgPerson")
+	}
+	if s.GetObjectClass("groupOfURLs") != nil {
+		t.Error("default sets should not include dyngroup")
+	}
+}
+
+func TestLoadBuiltinSchemaUnknown(t *testing.T) {
+	_, err := LoadBuiltinSchema("core", "bogus")
+	if !errors.Is(err, ErrUnknownBuiltinSchema) {
+		t.Fatalf("expected ErrUnknownBuiltinSchema, got %v", err)
+	}
+	if IsBuiltinSchema("bogus") || !IsBuiltinSchema("NIS") {
+		t.Error("IsBuiltinSchema returned unexpected result")
+	}
+}
+
+func TestBuiltinSchemaValidatesInetOrgPerson(t *testing.T) {
+	s, err := LoadBuiltinSchema(DefaultBuiltinSchemas...)
+	if err != nil {
+		t.Fatalf("LoadBuiltinSchema() error = %v", err)
+	}
+	v := NewValidator(s)
+
+	entry := NewEntry("uid=alice,ou=users,dc=example,dc=com")
+	entry.SetStringAttribute("objectclass", "inetOrgPerson", "organizationalPerson", "person", "top")
+	entry.SetStringAttribute("uid", "alice")
+	entry.SetStringAttribute("cn", "Alice")
+	entry.SetStringAttribute("sn", "Smith")
+	entry.SetStringAttribute("displayname", "Alice Smith")
+	entry.SetStringAttribute("employeeNumber", "42")
+	entry.SetStringAttribute("createTimestamp", "20240101000000Z")
+
+	if err := v.ValidateEntry(entry); err != nil {
+		t.Fatalf("ValidateEntry() error = %v", err)
+	}
+
+	entry.SetStringAttribute("uidNumber", "1000")
+	if err := v.ValidateEntry(entry); err == nil {
+		t.Fatal("expected uidNumber to be rejected without posixAccount")
+	}
+}
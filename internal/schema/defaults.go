@@ -1,15 +1,101 @@
 package schema
 
-// Default LDAP schema definitions including core object classes and attribute types.
-// These are based on RFC 4512, RFC 4519, and common LDAP implementations.
+// Built-in LDAP schema definitions, grouped into the standard schema sets
+// shipped with most directory servers. The definitions are based on
+// RFC 4512, RFC 4519, RFC 4524, RFC 2798, RFC 2307, RFC 3296 and the
+// OpenLDAP dyngroup schema.
 
-// defaultAttributeTypes contains the standard LDAP attribute type definitions.
-var defaultAttributeTypes = []string{
+// coreSyntaxes contains the standard LDAP syntax definitions (RFC 4517).
+var coreSyntaxes = []string{
+	`( 1.3.6.1.4.1.1466.115.121.1.3 DESC 'Attribute Type Description' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.4 DESC 'Audio' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.5 DESC 'Binary' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.6 DESC 'Bit String' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.7 DESC 'Boolean' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.8 DESC 'Certificate' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.11 DESC 'Country String' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.12 DESC 'DN' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.14 DESC 'Delivery Method' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.15 DESC 'Directory String' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.16 DESC 'DIT Content Rule Description' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.17 DESC 'DIT Structure Rule Description' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.21 DESC 'Enhanced Guide' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.22 DESC 'Facsimile Telephone Number' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.23 DESC 'Fax' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.24 DESC 'Generalized Time' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.25 DESC 'Guide' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.26 DESC 'IA5 String' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.27 DESC 'INTEGER' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.28 DESC 'JPEG' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.30 DESC 'Matching Rule Description' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.31 DESC 'Matching Rule Use Description' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.34 DESC 'Name And Optional UID' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.35 DESC 'Name Form Description' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.36 DESC 'Numeric String' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.37 DESC 'Object Class Description' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.38 DESC 'OID' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.39 DESC 'Other Mailbox' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.40 DESC 'Octet String' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.41 DESC 'Postal Address' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.43 DESC 'Presentation Address' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.44 DESC 'Printable String' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.50 DESC 'Telephone Number' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.51 DESC 'Teletex Terminal Identifier' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.52 DESC 'Telex Number' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.53 DESC 'UTC Time' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.54 DESC 'LDAP Syntax Description' )`,
+	`( 1.3.6.1.4.1.1466.115.121.1.58 DESC 'Substring Assertion' )`,
+	`( 1.3.6.1.1.15.1 DESC 'X.509 Certificate Exact Assertion' )`,
+	`( 1.3.6.1.1.16.1 DESC 'UUID' )`,
+}
+
+// coreMatchingRules contains the standard LDAP matching rule definitions (RFC 4517).
+var coreMatchingRules = []string{
+	`( 2.5.13.0 NAME 'objectIdentifierMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.38 )`,
+	`( 2.5.13.1 NAME 'distinguishedNameMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.12 )`,
+	`( 2.5.13.2 NAME 'caseIgnoreMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 2.5.13.3 NAME 'caseIgnoreOrderingMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 2.5.13.4 NAME 'caseIgnoreSubstringsMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.58 )`,
+	`( 2.5.13.5 NAME 'caseExactMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 2.5.13.6 NAME 'caseExactOrderingMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 2.5.13.7 NAME 'caseExactSubstringsMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.58 )`,
+	`( 2.5.13.8 NAME 'numericStringMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.36 )`,
+	`( 2.5.13.9 NAME 'numericStringOrderingMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.36 )`,
+	`( 2.5.13.10 NAME 'numericStringSubstringsMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.58 )`,
+	`( 2.5.13.11 NAME 'caseIgnoreListMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.41 )`,
+	`( 2.5.13.12 NAME 'caseIgnoreListSubstringsMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.58 )`,
+	`( 2.5.13.13 NAME 'booleanMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.7 )`,
+	`( 2.5.13.14 NAME 'integerMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.27 )`,
+	`( 2.5.13.15 NAME 'integerOrderingMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.27 )`,
+	`( 2.5.13.16 NAME 'bitStringMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.6 )`,
+	`( 2.5.13.17 NAME 'octetStringMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.40 )`,
+	`( 2.5.13.18 NAME 'octetStringOrderingMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.40 )`,
+	`( 2.5.13.20 NAME 'telephoneNumberMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.50 )`,
+	`( 2.5.13.21 NAME 'telephoneNumberSubstringsMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.58 )`,
+	`( 2.5.13.22 NAME 'presentationAddressMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.43 )`,
+	`( 2.5.13.23 NAME 'uniqueMemberMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.34 )`,
+	`( 2.5.13.27 NAME 'generalizedTimeMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.24 )`,
+	`( 2.5.13.28 NAME 'generalizedTimeOrderingMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.24 )`,
+	`( 2.5.13.29 NAME 'integerFirstComponentMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.27 )`,
+	`( 2.5.13.30 NAME 'objectIdentifierFirstComponentMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.38 )`,
+	`( 2.5.13.34 NAME 'certificateExactMatch' SYNTAX 1.3.6.1.1.15.1 )`,
+	`( 1.3.6.1.4.1.1466.109.114.1 NAME 'caseExactIA5Match' SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 )`,
+	`( 1.3.6.1.4.1.1466.109.114.2 NAME 'caseIgnoreIA5Match' SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 )`,
+	`( 1.3.6.1.4.1.1466.109.114.3 NAME 'caseIgnoreIA5SubstringsMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.58 )`,
+	`( 1.3.6.1.4.1.4203.1.2.1 NAME 'caseExactIA5SubstringsMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.58 )`,
+	`( 1.3.6.1.1.16.2 NAME 'UUIDMatch' SYNTAX 1.3.6.1.1.16.1 )`,
+	`( 1.3.6.1.1.16.3 NAME 'UUIDOrderingMatch' SYNTAX 1.3.6.1.1.16.1 )`,
+}
+
+// coreAttributeTypes contains the core attribute type definitions
+// (RFC 4512, RFC 4519, RFC 3296 and server operational attributes).
+var coreAttributeTypes = []string{
 	// Core attributes (RFC 4512)
 	`( 2.5.4.0 NAME 'objectClass' DESC 'Object class membership' EQUALITY objectIdentifierMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.38 )`,
 	`( 2.5.4.1 NAME ( 'aliasedObjectName' 'aliasedEntryName' ) DESC 'Aliased object name' EQUALITY distinguishedNameMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.12 SINGLE-VALUE )`,
 
 	// Naming attributes (RFC 4519)
+	`( 2.5.4.41 NAME 'name' DESC 'Name' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
 	`( 2.5.4.3 NAME ( 'cn' 'commonName' ) DESC 'Common name' SUP name )`,
 	`( 2.5.4.4 NAME ( 'sn' 'surname' ) DESC 'Surname' SUP name )`,
 	`( 2.5.4.5 NAME 'serialNumber' DESC 'Serial number' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.44 )`,
@@ -21,11 +107,8 @@ var defaultAttributeTypes = []string{
 	`( 2.5.4.11 NAME ( 'ou' 'organizationalUnitName' ) DESC 'Organizational unit name' SUP name )`,
 	`( 2.5.4.12 NAME 'title' DESC 'Title' SUP name )`,
 	`( 2.5.4.13 NAME 'description' DESC 'Description' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
-
-	// User attributes
-	`( 2.5.4.20 NAME 'telephoneNumber' DESC 'Telephone number' EQUALITY telephoneNumberMatch SUBSTR telephoneNumberSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.50 )`,
-	`( 2.5.4.35 NAME 'userPassword' DESC 'User password' EQUALITY octetStringMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.40 )`,
-	`( 2.5.4.41 NAME 'name' DESC 'Name' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 2.5.4.14 NAME 'searchGuide' DESC 'Search guide' SYNTAX 1.3.6.1.4.1.1466.115.121.1.25 )`,
+	`( 2.5.4.15 NAME 'businessCategory' DESC 'Business category' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
 	`( 2.5.4.42 NAME ( 'givenName' 'gn' ) DESC 'Given name' SUP name )`,
 	`( 2.5.4.43 NAME 'initials' DESC 'Initials' SUP name )`,
 	`( 2.5.4.44 NAME 'generationQualifier' DESC 'Generation qualifier' SUP name )`,
@@ -33,38 +116,89 @@ var defaultAttributeTypes = []string{
 	`( 2.5.4.46 NAME 'dnQualifier' DESC 'DN qualifier' EQUALITY caseIgnoreMatch ORDERING caseIgnoreOrderingMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.44 )`,
 	`( 2.5.4.49 NAME 'distinguishedName' DESC 'Distinguished name' EQUALITY distinguishedNameMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.12 )`,
 
-	// Domain component (RFC 4519)
-	`( 0.9.2342.19200300.100.1.25 NAME ( 'dc' 'domainComponent' ) DESC 'Domain component' EQUALITY caseIgnoreIA5Match SUBSTR caseIgnoreIA5SubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 SINGLE-VALUE )`,
+	// Postal and telecommunication attributes (RFC 4519)
+	`( 2.5.4.16 NAME 'postalAddress' DESC 'Postal address' EQUALITY caseIgnoreListMatch SUBSTR caseIgnoreListSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.41 )`,
+	`( 2.5.4.17 NAME 'postalCode' DESC 'Postal code' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 2.5.4.18 NAME 'postOfficeBox' DESC 'Post office box' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 2.5.4.19 NAME 'physicalDeliveryOfficeName' DESC 'Physical delivery office name' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 2.5.4.20 NAME 'telephoneNumber' DESC 'Telephone number' EQUALITY telephoneNumberMatch SUBSTR telephoneNumberSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.50 )`,
+	`( 2.5.4.21 NAME 'telexNumber' DESC 'Telex number' SYNTAX 1.3.6.1.4.1.1466.115.121.1.52 )`,
+	`( 2.5.4.22 NAME 'teletexTerminalIdentifier' DESC 'Teletex terminal identifier' SYNTAX 1.3.6.1.4.1.1466.115.121.1.51 )`,
+	`( 2.5.4.23 NAME 'facsimileTelephoneNumber' DESC 'Facsimile telephone number' SYNTAX 1.3.6.1.4.1.1466.115.121.1.22 )`,
+	`( 2.5.4.24 NAME 'x121Address' DESC 'X.121 address' EQUALITY numericStringMatch SUBSTR numericStringSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.36 )`,
+	`( 2.5.4.25 NAME 'internationaliSDNNumber' DESC 'International ISDN number' EQUALITY numericStringMatch SUBSTR numericStringSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.36 )`,
+	`( 2.5.4.26 NAME 'registeredAddress' DESC 'Registered postal address' SUP postalAddress SYNTAX 1.3.6.1.4.1.1466.115.121.1.41 )`,
+	`( 2.5.4.27 NAME 'destinationIndicator' DESC 'Destination indicator' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.44 )`,
+	`( 2.5.4.28 NAME 'preferredDeliveryMethod' DESC 'Preferred delivery method' SYNTAX 1.3.6.1.4.1.1466.115.121.1.14 SINGLE-VALUE )`,
 
-	// User ID (RFC 4519)
+	// Relationship attributes (RFC 4519)
+	`( 2.5.4.31 NAME 'member' DESC 'Member' SUP distinguishedName )`,
+	`( 2.5.4.32 NAME 'owner' DESC 'Owner' SUP distinguishedName )`,
+	`( 2.5.4.33 NAME 'roleOccupant' DESC 'Role occupant' SUP distinguishedName )`,
+	`( 2.5.4.34 NAME 'seeAlso' DESC 'See also' SUP distinguishedName )`,
+	`( 2.5.4.50 NAME 'uniqueMember' DESC 'Unique member' EQUALITY uniqueMemberMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.34 )`,
+
+	// Security attributes (RFC 4519, RFC 4523)
+	`( 2.5.4.35 NAME 'userPassword' DESC 'User password' EQUALITY octetStringMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.40 )`,
+	`( 2.5.4.36 NAME 'userCertificate' DESC 'X.509 user certificate' EQUALITY certificateExactMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.8 )`,
+
+	// Domain component and user ID (RFC 4519)
+	`( 0.9.2342.19200300.100.1.25 NAME ( 'dc' 'domainComponent' ) DESC 'Domain component' EQUALITY caseIgnoreIA5Match SUBSTR caseIgnoreIA5SubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 SINGLE-VALUE )`,
 	`( 0.9.2342.19200300.100.1.1 NAME ( 'uid' 'userid' ) DESC 'User ID' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
 
 	// Mail (RFC 4524)
 	`( 0.9.2342.19200300.100.1.3 NAME ( 'mail' 'rfc822Mailbox' ) DESC 'Email address' EQUALITY caseIgnoreIA5Match SUBSTR caseIgnoreIA5SubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 )`,
 
-	// Member attributes
-	`( 2.5.4.31 NAME 'member' DESC 'Member' SUP distinguishedName )`,
-	`( 2.5.4.50 NAME 'uniqueMember' DESC 'Unique member' EQUALITY uniqueMemberMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.34 )`,
-	`( 2.5.4.34 NAME 'seeAlso' DESC 'See also' SUP distinguishedName )`,
+	// Labeled URI (RFC 2079)
+	`( 1.3.6.1.4.1.250.1.57 NAME 'labeledURI' DESC 'Uniform Resource Identifier with optional label' EQUALITY caseExactMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+
+	// Named referrals (RFC 3296)
+	`( 2.16.840.1.113730.3.1.34 NAME 'ref' DESC 'Named reference' EQUALITY caseExactMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 USAGE distributedOperation )`,
+
+	// Subschema attributes (RFC 4512)
+	`( 2.5.21.1 NAME 'dITStructureRules' DESC 'DIT structure rules' EQUALITY integerFirstComponentMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.17 USAGE directoryOperation )`,
+	`( 2.5.21.2 NAME 'dITContentRules' DESC 'DIT content rules' EQUALITY objectIdentifierFirstComponentMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.16 USAGE directoryOperation )`,
+	`( 2.5.21.4 NAME 'matchingRules' DESC 'Matching rules' EQUALITY objectIdentifierFirstComponentMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.30 USAGE directoryOperation )`,
+	`( 2.5.21.5 NAME 'attributeTypes' DESC 'Attribute types' EQUALITY objectIdentifierFirstComponentMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.3 USAGE directoryOperation )`,
+	`( 2.5.21.6 NAME 'objectClasses' DESC 'Object classes' EQUALITY objectIdentifierFirstComponentMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.37 USAGE directoryOperation )`,
+	`( 2.5.21.7 NAME 'nameForms' DESC 'Name forms' EQUALITY objectIdentifierFirstComponentMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.35 USAGE directoryOperation )`,
+	`( 2.5.21.8 NAME 'matchingRuleUse' DESC 'Matching rule uses' EQUALITY objectIdentifierFirstComponentMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.31 USAGE directoryOperation )`,
+	`( 1.3.6.1.4.1.1466.101.120.16 NAME 'ldapSyntaxes' DESC 'LDAP syntaxes' EQUALITY objectIdentifierFirstComponentMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.54 USAGE directoryOperation )`,
 
 	// Operational attributes (RFC 4512)
 	`( 2.5.18.1 NAME 'createTimestamp' DESC 'Creation timestamp' EQUALITY generalizedTimeMatch ORDERING generalizedTimeOrderingMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.24 SINGLE-VALUE NO-USER-MODIFICATION USAGE directoryOperation )`,
 	`( 2.5.18.2 NAME 'modifyTimestamp' DESC 'Modification timestamp' EQUALITY generalizedTimeMatch ORDERING generalizedTimeOrderingMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.24 SINGLE-VALUE NO-USER-MODIFICATION USAGE directoryOperation )`,
 	`( 2.5.18.3 NAME 'creatorsName' DESC 'Creators name' EQUALITY distinguishedNameMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.12 SINGLE-VALUE NO-USER-MODIFICATION USAGE directoryOperation )`,
 	`( 2.5.18.4 NAME 'modifiersName' DESC 'Modifiers name' EQUALITY distinguishedNameMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.12 SINGLE-VALUE NO-USER-MODIFICATION USAGE directoryOperation )`,
+	`( 2.5.18.9 NAME 'hasSubordinates' DESC 'Has subordinates' EQUALITY booleanMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.7 SINGLE-VALUE NO-USER-MODIFICATION USAGE directoryOperation )`,
 	`( 2.5.18.10 NAME 'subschemaSubentry' DESC 'Subschema subentry' EQUALITY distinguishedNameMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.12 SINGLE-VALUE NO-USER-MODIFICATION USAGE directoryOperation )`,
+	`( 2.5.18.7 NAME 'collectiveExclusions' DESC 'Collective attributes excluded from the entry' EQUALITY objectIdentifierMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.38 USAGE directoryOperation )`,
 	`( 2.5.21.9 NAME 'structuralObjectClass' DESC 'Structural object class' EQUALITY objectIdentifierMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.38 SINGLE-VALUE NO-USER-MODIFICATION USAGE directoryOperation )`,
 	`( 1.3.6.1.1.20 NAME 'entryDN' DESC 'Entry DN' EQUALITY distinguishedNameMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.12 SINGLE-VALUE NO-USER-MODIFICATION USAGE directoryOperation )`,
+	`( 1.3.6.1.4.1.4203.666.1.7 NAME 'entryCSN' DESC 'Change sequence number of the entry' EQUALITY octetStringMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.40 SINGLE-VALUE NO-USER-MODIFICATION USAGE directoryOperation )`,
 	`( 1.3.6.1.1.16.4 NAME 'entryUUID' DESC 'Entry UUID' EQUALITY UUIDMatch ORDERING UUIDOrderingMatch SYNTAX 1.3.6.1.1.16.1 SINGLE-VALUE NO-USER-MODIFICATION USAGE directoryOperation )`,
-	`( 2.5.21.8 NAME 'hasSubordinates' DESC 'Has subordinates' EQUALITY booleanMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.7 SINGLE-VALUE NO-USER-MODIFICATION USAGE directoryOperation )`,
 	`( 2.16.840.1.113730.3.1.69 NAME 'numSubordinates' DESC 'Number of subordinates' EQUALITY integerMatch ORDERING integerOrderingMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.27 SINGLE-VALUE NO-USER-MODIFICATION USAGE directoryOperation )`,
+	`( 1.2.840.113556.1.2.102 NAME 'memberOf' DESC 'Groups the entry is a member of' EQUALITY distinguishedNameMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.12 NO-USER-MODIFICATION USAGE dSAOperation )`,
+
+	// Password policy state (draft-behera-ldap-password-policy)
+	`( 1.3.6.1.4.1.42.2.27.8.1.14 NAME 'pwdMustChange' DESC 'Password must be changed after reset' EQUALITY booleanMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.7 SINGLE-VALUE USAGE directoryOperation )`,
+	`( 1.3.6.1.4.1.42.2.27.8.1.16 NAME 'pwdChangedTime' DESC 'Time the password was last changed' EQUALITY generalizedTimeMatch ORDERING generalizedTimeOrderingMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.24 SINGLE-VALUE NO-USER-MODIFICATION USAGE directoryOperation )`,
+	`( 1.3.6.1.4.1.42.2.27.8.1.17 NAME 'pwdAccountLockedTime' DESC 'Time the account was locked' EQUALITY generalizedTimeMatch ORDERING generalizedTimeOrderingMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.24 SINGLE-VALUE USAGE directoryOperation )`,
+	`( 1.3.6.1.4.1.42.2.27.8.1.19 NAME 'pwdFailureTime' DESC 'Times of consecutive authentication failures' EQUALITY generalizedTimeMatch ORDERING generalizedTimeOrderingMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.24 NO-USER-MODIFICATION USAGE directoryOperation )`,
+	`( 1.3.6.1.4.1.42.2.27.8.1.20 NAME 'pwdHistory' DESC 'History of previously used passwords' EQUALITY octetStringMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.40 NO-USER-MODIFICATION USAGE directoryOperation )`,
+	`( 1.3.6.1.4.1.42.2.27.8.1.21 NAME 'pwdGraceUseTime' DESC 'Times of grace authentications' EQUALITY generalizedTimeMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.24 NO-USER-MODIFICATION USAGE directoryOperation )`,
+	`( 1.3.6.1.4.1.42.2.27.8.1.22 NAME 'pwdReset' DESC 'Password has been reset' EQUALITY booleanMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.7 SINGLE-VALUE USAGE directoryOperation )`,
+	`( 1.3.6.1.4.1.42.2.27.8.1.23 NAME 'pwdPolicySubentry' DESC 'Password policy applied to the entry' EQUALITY distinguishedNameMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.12 SINGLE-VALUE USAGE directoryOperation )`,
 }
 
-// defaultObjectClasses contains the standard LDAP object class definitions.
-var defaultObjectClasses = []string{
+// coreObjectClasses contains the core object class definitions
+// (RFC 4512, RFC 4519, RFC 3296).
+var coreObjectClasses = []string{
 	// Core object classes (RFC 4512)
 	`( 2.5.6.0 NAME 'top' DESC 'Top of the object class hierarchy' ABSTRACT MUST objectClass )`,
 	`( 2.5.6.1 NAME 'alias' DESC 'Alias object class' SUP top STRUCTURAL MUST aliasedObjectName )`,
+	`( 1.3.6.1.4.1.1466.101.120.111 NAME 'extensibleObject' DESC 'Entry may hold any user attribute' SUP top AUXILIARY )`,
+	`( 2.5.20.1 NAME 'subschema' DESC 'Subschema' AUXILIARY MAY ( dITStructureRules $ nameForms $ dITContentRules $ objectClasses $ attributeTypes $ matchingRules $ matchingRuleUse ) )`,
 
 	// RFC 4519 object classes
 	`( 2.5.6.2 NAME 'country' DESC 'Country' SUP top STRUCTURAL MUST c MAY ( searchGuide $ description ) )`,
@@ -73,145 +207,161 @@ var defaultObjectClasses = []string{
 	`( 2.5.6.5 NAME 'organizationalUnit' DESC 'Organizational unit' SUP top STRUCTURAL MUST ou MAY ( userPassword $ searchGuide $ seeAlso $ businessCategory $ x121Address $ registeredAddress $ destinationIndicator $ preferredDeliveryMethod $ telexNumber $ teletexTerminalIdentifier $ telephoneNumber $ internationaliSDNNumber $ facsimileTelephoneNumber $ street $ postOfficeBox $ postalCode $ postalAddress $ physicalDeliveryOfficeName $ st $ l $ description ) )`,
 	`( 2.5.6.6 NAME 'person' DESC 'Person' SUP top STRUCTURAL MUST ( sn $ cn ) MAY ( userPassword $ telephoneNumber $ seeAlso $ description ) )`,
 	`( 2.5.6.7 NAME 'organizationalPerson' DESC 'Organizational person' SUP person STRUCTURAL MAY ( title $ x121Address $ registeredAddress $ destinationIndicator $ preferredDeliveryMethod $ telexNumber $ teletexTerminalIdentifier $ telephoneNumber $ internationaliSDNNumber $ facsimileTelephoneNumber $ street $ postOfficeBox $ postalCode $ postalAddress $ physicalDeliveryOfficeName $ ou $ st $ l ) )`,
-	`( 2.5.6.8 NAME 'organizationalRole' DESC 'Organizational role' SUP top STRUCTURAL MUST cn MAY ( x121Address $ registeredAddress $ destinationIndicator $ preferredDeliveryMethod $ telexNumber $ teletexTerminalIdentifier $ telephoneNumber $ internationaliSDNNumber $ facsimileTelephoneNumber $ seeAlso $ roleOccupant $ preferredDeliveryMethod $ street $ postOfficeBox $ postalCode $ postalAddress $ physicalDeliveryOfficeName $ ou $ st $ l $ description ) )`,
+	`( 2.5.6.8 NAME 'organizationalRole' DESC 'Organizational role' SUP top STRUCTURAL MUST cn MAY ( x121Address $ registeredAddress $ destinationIndicator $ preferredDeliveryMethod $ telexNumber $ teletexTerminalIdentifier $ telephoneNumber $ internationaliSDNNumber $ facsimileTelephoneNumber $ seeAlso $ roleOccupant $ street $ postOfficeBox $ postalCode $ postalAddress $ physicalDeliveryOfficeName $ ou $ st $ l $ description ) )`,
 	`( 2.5.6.9 NAME 'groupOfNames' DESC 'Group of names' SUP top STRUCTURAL MUST ( member $ cn ) MAY ( businessCategory $ seeAlso $ owner $ ou $ o $ description ) )`,
+	`( 2.5.6.10 NAME 'residentialPerson' DESC 'Residential person' SUP person STRUCTURAL MUST l MAY ( businessCategory $ x121Address $ registeredAddress $ destinationIndicator $ preferredDeliveryMethod $ telexNumber $ teletexTerminalIdentifier $ telephoneNumber $ internationaliSDNNumber $ facsimileTelephoneNumber $ street $ postOfficeBox $ postalCode $ postalAddress $ physicalDeliveryOfficeName $ st $ l ) )`,
+	`( 2.5.6.11 NAME 'applicationProcess' DESC 'Application process' SUP top STRUCTURAL MUST cn MAY ( seeAlso $ ou $ l $ description ) )`,
+	`( 2.5.6.14 NAME 'device' DESC 'Device' SUP top STRUCTURAL MUST cn MAY ( serialNumber $ seeAlso $ owner $ ou $ o $ l $ description ) )`,
 	`( 2.5.6.17 NAME 'groupOfUniqueNames' DESC 'Group of unique names' SUP top STRUCTURAL MUST ( uniqueMember $ cn ) MAY ( businessCategory $ seeAlso $ owner $ ou $ o $ description ) )`,
+	`( 1.3.6.1.4.1.1466.344 NAME 'dcObject' DESC 'Domain component object' SUP top AUXILIARY MUST dc )`,
+	`( 1.3.6.1.1.3.1 NAME 'uidObject' DESC 'User ID object' SUP top AUXILIARY MUST uid )`,
 
-	// inetOrgPerson (RFC 2798)
-	`( 2.16.840.1.113730.3.2.2 NAME 'inetOrgPerson' DESC 'Internet organizational person' SUP organizationalPerson STRUCTURAL MAY ( audio $ businessCategory $ carLicense $ departmentNumber $ displayName $ employeeNumber $ employeeType $ givenName $ homePhone $ homePostalAddress $ initials $ jpegPhoto $ labeledURI $ mail $ manager $ mobile $ o $ pager $ photo $ roomNumber $ secretary $ uid $ userCertificate $ x500uniqueIdentifier $ preferredLanguage $ userSMIMECertificate $ userPKCS12 ) )`,
+	// Labeled URI object (RFC 2079)
+	`( 1.3.6.1.4.1.250.3.15 NAME 'labeledURIObject' DESC 'Object with a labeled URI' SUP top AUXILIARY MAY labeledURI )`,
 
-	// Domain component (RFC 4519)
-	`( 0.9.2342.19200300.100.4.13 NAME 'domain' DESC 'Domain' SUP top STRUCTURAL MUST dc MAY ( userPassword $ searchGuide $ seeAlso $ businessCategory $ x121Address $ registeredAddress $ destinationIndicator $ preferredDeliveryMethod $ telexNumber $ teletexTerminalIdentifier $ telephoneNumber $ internationaliSDNNumber $ facsimileTelephoneNumber $ street $ postOfficeBox $ postalCode $ postalAddress $ physicalDeliveryOfficeName $ st $ l $ description $ o $ associatedName ) )`,
-	`( 1.3.6.1.4.1.1466.344 NAME 'dcObject' DESC 'Domain component object' SUP top AUXILIARY MUST dc )`,
+	// Simple security object (RFC 4524)
+	`( 0.9.2342.19200300.100.4.19 NAME 'simpleSecurityObject' DESC 'Simple security object' SUP top AUXILIARY MUST userPassword )`,
 
-	// Subschema (RFC 4512)
-	`( 2.5.20.1 NAME 'subschema' DESC 'Subschema' AUXILIARY MAY ( dITStructureRules $ nameForms $ ditContentRules $ objectClasses $ attributeTypes $ matchingRules $ matchingRuleUse ) )`,
+	// Named referral (RFC 3296)
+	`( 2.16.840.1.113730.3.2.6 NAME 'referral' DESC 'Named subordinate reference' SUP top STRUCTURAL MUST ref )`,
 
-	// LDAP subentry (RFC 3672)
+	// LDAP subentry
 	`( 2.16.840.1.113719.2.142.6.1.1 NAME 'ldapSubEntry' DESC 'LDAP subentry' SUP top STRUCTURAL MAY cn )`,
 
-	// Extended person (common extension)
-	`( 1.3.6.1.4.1.5923.1.2.2 NAME 'eduPerson' DESC 'Educational person' AUXILIARY MAY ( eduPersonAffiliation $ eduPersonNickname $ eduPersonOrgDN $ eduPersonOrgUnitDN $ eduPersonPrimaryAffiliation $ eduPersonPrincipalName $ eduPersonEntitlement $ eduPersonPrimaryOrgUnitDN $ eduPersonScopedAffiliation ) )`,
+	// Collective attribute subentry (RFC 3671)
+	`( 2.5.17.2 NAME 'collectiveAttributeSubentry' DESC 'Collective attribute subentry' SUP top AUXILIARY MAY ( c $ l $ st $ street $ o $ ou $ postalAddress $ postalCode $ postOfficeBox $ telephoneNumber $ facsimileTelephoneNumber $ description ) )`,
+}
 
-	// Simple security object
-	`( 0.9.2342.19200300.100.4.19 NAME 'simpleSecurityObject' DESC 'Simple security object' SUP top AUXILIARY MUST userPassword )`,
+// cosineAttributeTypes contains the COSINE attribute type definitions (RFC 4524).
+var cosineAttributeTypes = []string{
+	`( 0.9.2342.19200300.100.1.2 NAME 'textEncodedORAddress' DESC 'Text encoded O/R address' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 0.9.2342.19200300.100.1.4 NAME 'info' DESC 'General information' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 0.9.2342.19200300.100.1.5 NAME ( 'drink' 'favouriteDrink' ) DESC 'Favorite drink' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 0.9.2342.19200300.100.1.6 NAME 'roomNumber' DESC 'Room number' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 0.9.2342.19200300.100.1.7 NAME 'photo' DESC 'Photo in G3 fax format' SYNTAX 1.3.6.1.4.1.1466.115.121.1.23 )`,
+	`( 0.9.2342.19200300.100.1.8 NAME 'userClass' DESC 'Category of user' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 0.9.2342.19200300.100.1.9 NAME 'host' DESC 'Host computer' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 0.9.2342.19200300.100.1.10 NAME 'manager' DESC 'Manager' EQUALITY distinguishedNameMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.12 )`,
+	`( 0.9.2342.19200300.100.1.11 NAME 'documentIdentifier' DESC 'Document identifier' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 0.9.2342.19200300.100.1.12 NAME 'documentTitle' DESC 'Document title' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 0.9.2342.19200300.100.1.13 NAME 'documentVersion' DESC 'Document version' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 0.9.2342.19200300.100.1.14 NAME 'documentAuthor' DESC 'Document author' EQUALITY distinguishedNameMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.12 )`,
+	`( 0.9.2342.19200300.100.1.15 NAME 'documentLocation' DESC 'Document location' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 0.9.2342.19200300.100.1.20 NAME ( 'homePhone' 'homeTelephoneNumber' ) DESC 'Home telephone number' EQUALITY telephoneNumberMatch SUBSTR telephoneNumberSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.50 )`,
+	`( 0.9.2342.19200300.100.1.21 NAME 'secretary' DESC 'Secretary' EQUALITY distinguishedNameMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.12 )`,
+	`( 0.9.2342.19200300.100.1.22 NAME 'otherMailbox' DESC 'Other mailbox' SYNTAX 1.3.6.1.4.1.1466.115.121.1.39 )`,
+	`( 0.9.2342.19200300.100.1.26 NAME 'aRecord' DESC 'DNS A record' EQUALITY caseIgnoreIA5Match SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 )`,
+	`( 0.9.2342.19200300.100.1.27 NAME 'mDRecord' DESC 'DNS MD record' EQUALITY caseIgnoreIA5Match SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 )`,
+	`( 0.9.2342.19200300.100.1.28 NAME 'mXRecord' DESC 'DNS MX record' EQUALITY caseIgnoreIA5Match SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 )`,
+	`( 0.9.2342.19200300.100.1.29 NAME 'nSRecord' DESC 'DNS NS record' EQUALITY caseIgnoreIA5Match SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 )`,
+	`( 0.9.2342.19200300.100.1.30 NAME 'sOARecord' DESC 'DNS SOA record' EQUALITY caseIgnoreIA5Match SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 )`,
+	`( 0.9.2342.19200300.100.1.31 NAME 'cNAMERecord' DESC 'DNS CNAME record' EQUALITY caseIgnoreIA5Match SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 )`,
+	`( 0.9.2342.19200300.100.1.37 NAME 'associatedDomain' DESC 'Associated DNS domain' EQUALITY caseIgnoreIA5Match SUBSTR caseIgnoreIA5SubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 )`,
+	`( 0.9.2342.19200300.100.1.38 NAME 'associatedName' DESC 'Associated name' EQUALITY distinguishedNameMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.12 )`,
+	`( 0.9.2342.19200300.100.1.39 NAME 'homePostalAddress' DESC 'Home postal address' EQUALITY caseIgnoreListMatch SUBSTR caseIgnoreListSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.41 )`,
+	`( 0.9.2342.19200300.100.1.40 NAME 'personalTitle' DESC 'Personal title' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 0.9.2342.19200300.100.1.41 NAME ( 'mobile' 'mobileTelephoneNumber' ) DESC 'Mobile telephone number' EQUALITY telephoneNumberMatch SUBSTR telephoneNumberSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.50 )`,
+	`( 0.9.2342.19200300.100.1.42 NAME ( 'pager' 'pagerTelephoneNumber' ) DESC 'Pager telephone number' EQUALITY telephoneNumberMatch SUBSTR telephoneNumberSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.50 )`,
+	`( 0.9.2342.19200300.100.1.43 NAME ( 'co' 'friendlyCountryName' ) DESC 'Friendly country name' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 0.9.2342.19200300.100.1.44 NAME 'uniqueIdentifier' DESC 'Unique identifier' EQUALITY caseIgnoreMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 0.9.2342.19200300.100.1.45 NAME 'organizationalStatus' DESC 'Organizational status' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 0.9.2342.19200300.100.1.48 NAME 'buildingName' DESC 'Building name' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 0.9.2342.19200300.100.1.55 NAME 'audio' DESC 'Audio' SYNTAX 1.3.6.1.4.1.1466.115.121.1.4 )`,
+	`( 0.9.2342.19200300.100.1.56 NAME 'documentPublisher' DESC 'Document publisher' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+}
 
-	// Account (RFC 4524)
+// cosineObjectClasses contains the COSINE object class definitions (RFC 4524).
+var cosineObjectClasses = []string{
 	`( 0.9.2342.19200300.100.4.5 NAME 'account' DESC 'Account' SUP top STRUCTURAL MUST uid MAY ( description $ seeAlso $ l $ o $ ou $ host ) )`,
+	`( 0.9.2342.19200300.100.4.6 NAME 'document' DESC 'Document' SUP top STRUCTURAL MUST documentIdentifier MAY ( cn $ description $ seeAlso $ l $ o $ ou $ documentTitle $ documentVersion $ documentAuthor $ documentLocation $ documentPublisher ) )`,
+	`( 0.9.2342.19200300.100.4.7 NAME 'room' DESC 'Room' SUP top STRUCTURAL MUST cn MAY ( roomNumber $ description $ seeAlso $ telephoneNumber ) )`,
+	`( 0.9.2342.19200300.100.4.9 NAME 'documentSeries' DESC 'Document series' SUP top STRUCTURAL MUST cn MAY ( description $ l $ o $ ou $ seeAlso $ telephoneNumber ) )`,
+	`( 0.9.2342.19200300.100.4.13 NAME 'domain' DESC 'Domain' SUP top STRUCTURAL MUST dc MAY ( userPassword $ searchGuide $ seeAlso $ businessCategory $ x121Address $ registeredAddress $ destinationIndicator $ preferredDeliveryMethod $ telexNumber $ teletexTerminalIdentifier $ telephoneNumber $ internationaliSDNNumber $ facsimileTelephoneNumber $ street $ postOfficeBox $ postalCode $ postalAddress $ physicalDeliveryOfficeName $ st $ l $ description $ o $ associatedName ) )`,
+	`( 0.9.2342.19200300.100.4.14 NAME 'RFC822localPart' DESC 'RFC 822 local part' SUP domain STRUCTURAL MAY ( cn $ description $ destinationIndicator $ facsimileTelephoneNumber $ internationaliSDNNumber $ physicalDeliveryOfficeName $ postalAddress $ postalCode $ postOfficeBox $ preferredDeliveryMethod $ registeredAddress $ seeAlso $ sn $ street $ telephoneNumber $ teletexTerminalIdentifier $ telexNumber $ x121Address ) )`,
+	`( 0.9.2342.19200300.100.4.15 NAME 'dNSDomain' DESC 'DNS domain' SUP domain STRUCTURAL MAY ( aRecord $ mDRecord $ mXRecord $ nSRecord $ sOARecord $ cNAMERecord ) )`,
+	`( 0.9.2342.19200300.100.4.17 NAME 'domainRelatedObject' DESC 'Object related to a DNS domain' SUP top AUXILIARY MUST associatedDomain )`,
+	`( 0.9.2342.19200300.100.4.18 NAME 'friendlyCountry' DESC 'Country with a friendly name' SUP country STRUCTURAL MUST co )`,
+}
 
-	// POSIX account and group (RFC 2307)
-	`( 1.3.6.1.1.1.2.0 NAME 'posixAccount' DESC 'POSIX account' SUP top AUXILIARY MUST ( cn $ uid $ uidNumber $ gidNumber $ homeDirectory ) MAY ( userPassword $ loginShell $ gecos $ description ) )`,
-	`( 1.3.6.1.1.1.2.2 NAME 'posixGroup' DESC 'POSIX group' SUP top STRUCTURAL MUST ( cn $ gidNumber ) MAY ( userPassword $ memberUid $ description ) )`,
+// inetOrgPersonAttributeTypes contains the inetOrgPerson attribute type
+// definitions (RFC 2798).
+var inetOrgPersonAttributeTypes = []string{
+	`( 2.16.840.1.113730.3.1.1 NAME 'carLicense' DESC 'Vehicle license or registration plate' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 2.16.840.1.113730.3.1.2 NAME 'departmentNumber' DESC 'Department number' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 2.16.840.1.113730.3.1.241 NAME 'displayName' DESC 'Preferred display name' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 SINGLE-VALUE )`,
+	`( 2.16.840.1.113730.3.1.3 NAME 'employeeNumber' DESC 'Employee number' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 SINGLE-VALUE )`,
+	`( 2.16.840.1.113730.3.1.4 NAME 'employeeType' DESC 'Type of employment' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
+	`( 0.9.2342.19200300.100.1.60 NAME 'jpegPhoto' DESC 'Photo in JPEG format' SYNTAX 1.3.6.1.4.1.1466.115.121.1.28 )`,
+	`( 2.16.840.1.113730.3.1.39 NAME 'preferredLanguage' DESC 'Preferred written or spoken language' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 SINGLE-VALUE )`,
+	`( 2.16.840.1.113730.3.1.40 NAME 'userSMIMECertificate' DESC 'PKCS#7 SignedData used for S/MIME' SYNTAX 1.3.6.1.4.1.1466.115.121.1.5 )`,
+	`( 2.16.840.1.113730.3.1.216 NAME 'userPKCS12' DESC 'PKCS#12 PFX PDU for exchange of personal identity information' SYNTAX 1.3.6.1.4.1.1466.115.121.1.5 )`,
 }
 
-// defaultMatchingRules contains the standard LDAP matching rule definitions.
-var defaultMatchingRules = []string{
-	`( 2.5.13.0 NAME 'objectIdentifierMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.38 )`,
-	`( 2.5.13.1 NAME 'distinguishedNameMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.12 )`,
-	`( 2.5.13.2 NAME 'caseIgnoreMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
-	`( 2.5.13.3 NAME 'caseIgnoreOrderingMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
-	`( 2.5.13.4 NAME 'caseIgnoreSubstringsMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.58 )`,
-	`( 2.5.13.5 NAME 'caseExactMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
-	`( 2.5.13.6 NAME 'caseExactOrderingMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )`,
-	`( 2.5.13.7 NAME 'caseExactSubstringsMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.58 )`,
-	`( 2.5.13.8 NAME 'numericStringMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.36 )`,
-	`( 2.5.13.10 NAME 'numericStringSubstringsMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.58 )`,
-	`( 2.5.13.11 NAME 'caseIgnoreListMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.41 )`,
-	`( 2.5.13.13 NAME 'booleanMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.7 )`,
-	`( 2.5.13.14 NAME 'integerMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.27 )`,
-	`( 2.5.13.15 NAME 'integerOrderingMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.27 )`,
-	`( 2.5.13.16 NAME 'bitStringMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.6 )`,
-	`( 2.5.13.17 NAME 'octetStringMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.40 )`,
-	`( 2.5.13.18 NAME 'octetStringOrderingMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.40 )`,
-	`( 2.5.13.20 NAME 'telephoneNumberMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.50 )`,
-	`( 2.5.13.21 NAME 'telephoneNumberSubstringsMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.58 )`,
-	`( 2.5.13.22 NAME 'presentationAddressMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.43 )`,
-	`( 2.5.13.23 NAME 'uniqueMemberMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.34 )`,
-	`( 2.5.13.27 NAME 'generalizedTimeMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.24 )`,
-	`( 2.5.13.28 NAME 'generalizedTimeOrderingMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.24 )`,
-	`( 2.5.13.29 NAME 'integerFirstComponentMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.27 )`,
-	`( 2.5.13.30 NAME 'objectIdentifierFirstComponentMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.38 )`,
-	`( 1.3.6.1.4.1.1466.109.114.1 NAME 'caseExactIA5Match' SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 )`,
-	`( 1.3.6.1.4.1.1466.109.114.2 NAME 'caseIgnoreIA5Match' SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 )`,
-	`( 1.3.6.1.4.1.1466.109.114.3 NAME 'caseIgnoreIA5SubstringsMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.58 )`,
-	`( 1.3.6.1.1.16.2 NAME 'UUIDMatch' SYNTAX 1.3.6.1.1.16.1 )`,
-	`( 1.3.6.1.1.16.3 NAME 'UUIDOrderingMatch' SYNTAX 1.3.6.1.1.16.1 )`,
+// inetOrgPersonObjectClasses contains the inetOrgPerson object class (RFC 2798).
+var inetOrgPersonObjectClasses = []string{
+	`( 2.16.840.1.113730.3.2.2 NAME 'inetOrgPerson' DESC 'Internet organizational person' SUP organizationalPerson STRUCTURAL MAY ( audio $ businessCategory $ carLicense $ departmentNumber $ displayName $ employeeNumber $ employeeType $ givenName $ homePhone $ homePostalAddress $ initials $ jpegPhoto $ labeledURI $ mail $ manager $ mobile $ o $ pager $ photo $ roomNumber $ secretary $ uid $ userCertificate $ x500uniqueIdentifier $ preferredLanguage $ userSMIMECertificate $ userPKCS12 ) )`,
 }
 
-// defaultSyntaxes contains the standard LDAP syntax definitions.
-var defaultSyntaxes = []string{
-	`( 1.3.6.1.4.1.1466.115.121.1.3 DESC 'Attribute Type Description' )`,
-	`( 1.3.6.1.4.1.1466.115.121.1.6 DESC 'Bit String' )`,
-	`( 1.3.6.1.4.1.1466.115.121.1.7 DESC 'Boolean' )`,
-	`( 1.3.6.1.4.1.1466.115.121.1.11 DESC 'Country String' )`,
-	`( 1.3.6.1.4.1.1466.115.121.1.12 DESC 'DN' )`,
-	`( 1.3.6.1.4.1.1466.115.121.1.14 DESC 'Delivery Method' )`,
-	`( 1.3.6.1.4.1.1466.115.121.1.15 DESC 'Directory String' )`,
-	`( 1.3.6.1.4.1.1466.115.121.1.22 DESC 'Facsimile Telephone Number' )`,
-	`( 1.3.6.1.4.1.1466.115.121.1.24 DESC 'Generalized Time' )`,
-	`( 1.3.6.1.4.1.1466.115.121.1.26 DESC 'IA5 String' )`,
-	`( 1.3.6.1.4.1.1466.115.121.1.27 DESC 'INTEGER' )`,
-	`( 1.3.6.1.4.1.1466.115.121.1.34 DESC 'Name And Optional UID' )`,
-	`( 1.3.6.1.4.1.1466.115.121.1.36 DESC 'Numeric String' )`,
-	`( 1.3.6.1.4.1.1466.115.121.1.37 DESC 'Object Class Description' )`,
-	`( 1.3.6.1.4.1.1466.115.121.1.38 DESC 'OID' )`,
-	`( 1.3.6.1.4.1.1466.115.121.1.39 DESC 'Other Mailbox' )`,
-	`( 1.3.6.1.4.1.1466.115.121.1.40 DESC 'Octet String' )`,
-	`( 1.3.6.1.4.1.1466.115.121.1.41 DESC 'Postal Address' )`,
-	`( 1.3.6.1.4.1.1466.115.121.1.43 DESC 'Presentation Address' )`,
-	`( 1.3.6.1.4.1.1466.115.121.1.44 DESC 'Printable String' )`,
-	`( 1.3.6.1.4.1.1466.115.121.1.50 DESC 'Telephone Number' )`,
-	`( 1.3.6.1.4.1.1466.115.121.1.51 DESC 'Teletex Terminal Identifier' )`,
-	`( 1.3.6.1.4.1.1466.115.121.1.52 DESC 'Telex Number' )`,
-	`( 1.3.6.1.4.1.1466.115.121.1.53 DESC 'UTC Time' )`,
-	`( 1.3.6.1.4.1.1466.115.121.1.58 DESC 'Substring Assertion' )`,
-	`( 1.3.6.1.1.16.1 DESC 'UUID' )`,
+// nisSyntaxes contains the syntaxes introduced by the NIS schema (RFC 2307).
+var nisSyntaxes = []string{
+	`( 1.3.6.1.1.1.0.0 DESC 'RFC 2307 NIS Netgroup Triple' )`,
+	`( 1.3.6.1.1.1.0.1 DESC 'RFC 2307 Boot Parameter' )`,
 }
 
-// loadDefaultAttributeTypes loads the default attribute types into the schema.
-func loadDefaultAttributeTypes(s *Schema) error {
-	for _, def := range defaultAttributeTypes {
-		at, err := parseAttributeType(def)
-		if err != nil {
-			return err
-		}
-		s.AddAttributeType(at)
-	}
-	return nil
+// nisAttributeTypes contains the NIS attribute type definitions (RFC 2307).
+var nisAttributeTypes = []string{
+	`( 1.3.6.1.1.1.1.0 NAME 'uidNumber' DESC 'Numeric user ID' EQUALITY integerMatch ORDERING integerOrderingMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.27 SINGLE-VALUE )`,
+	`( 1.3.6.1.1.1.1.1 NAME 'gidNumber' DESC 'Numeric group ID' EQUALITY integerMatch ORDERING integerOrderingMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.27 SINGLE-VALUE )`,
+	`( 1.3.6.1.1.1.1.2 NAME 'gecos' DESC 'GECOS field' EQUALITY caseIgnoreIA5Match SUBSTR caseIgnoreIA5SubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 SINGLE-VALUE )`,
+	`( 1.3.6.1.1.1.1.3 NAME 'homeDirectory' DESC 'Absolute path to the home directory' EQUALITY caseExactIA5Match SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 SINGLE-VALUE )`,
+	`( 1.3.6.1.1.1.1.4 NAME 'loginShell' DESC 'Path to the login shell' EQUALITY caseExactIA5Match SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 SINGLE-VALUE )`,
+	`( 1.3.6.1.1.1.1.5 NAME 'shadowLastChange' DESC 'Days since the epoch of the last password change' EQUALITY integerMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.27 SINGLE-VALUE )`,
+	`( 1.3.6.1.1.1.1.6 NAME 'shadowMin' DESC 'Minimum days between password changes' EQUALITY integerMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.27 SINGLE-VALUE )`,
+	`( 1.3.6.1.1.1.1.7 NAME 'shadowMax' DESC 'Maximum days a password is valid' EQUALITY integerMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.27 SINGLE-VALUE )`,
+	`( 1.3.6.1.1.1.1.8 NAME 'shadowWarning' DESC 'Days of warning before password expiry' EQUALITY integerMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.27 SINGLE-VALUE )`,
+	`( 1.3.6.1.1.1.1.9 NAME 'shadowInactive' DESC 'Days of inactivity allowed after expiry' EQUALITY integerMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.27 SINGLE-VALUE )`,
+	`( 1.3.6.1.1.1.1.10 NAME 'shadowExpire' DESC 'Days since the epoch when the account expires' EQUALITY integerMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.27 SINGLE-VALUE )`,
+	`( 1.3.6.1.1.1.1.11 NAME 'shadowFlag' DESC 'Reserved shadow flag' EQUALITY integerMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.27 SINGLE-VALUE )`,
+	`( 1.3.6.1.1.1.1.12 NAME 'memberUid' DESC 'Member user ID' EQUALITY caseExactIA5Match SUBSTR caseExactIA5SubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 )`,
+	`( 1.3.6.1.1.1.1.13 NAME 'memberNisNetgroup' DESC 'Member netgroup' EQUALITY caseExactIA5Match SUBSTR caseExactIA5SubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 )`,
+	`( 1.3.6.1.1.1.1.14 NAME 'nisNetgroupTriple' DESC 'Netgroup triple' SYNTAX 1.3.6.1.1.1.0.0 )`,
+	`( 1.3.6.1.1.1.1.15 NAME 'ipServicePort' DESC 'Service port number' EQUALITY integerMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.27 SINGLE-VALUE )`,
+	`( 1.3.6.1.1.1.1.16 NAME 'ipServiceProtocol' DESC 'Service protocol name' SUP name )`,
+	`( 1.3.6.1.1.1.1.17 NAME 'ipProtocolNumber' DESC 'IP protocol number' EQUALITY integerMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.27 SINGLE-VALUE )`,
+	`( 1.3.6.1.1.1.1.18 NAME 'oncRpcNumber' DESC 'ONC RPC number' EQUALITY integerMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.27 SINGLE-VALUE )`,
+	`( 1.3.6.1.1.1.1.19 NAME 'ipHostNumber' DESC 'IP address' EQUALITY caseIgnoreIA5Match SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 )`,
+	`( 1.3.6.1.1.1.1.20 NAME 'ipNetworkNumber' DESC 'IP network' EQUALITY caseIgnoreIA5Match SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 SINGLE-VALUE )`,
+	`( 1.3.6.1.1.1.1.21 NAME 'ipNetmaskNumber' DESC 'IP netmask' EQUALITY caseIgnoreIA5Match SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 SINGLE-VALUE )`,
+	`( 1.3.6.1.1.1.1.22 NAME 'macAddress' DESC 'MAC address' EQUALITY caseIgnoreIA5Match SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 )`,
+	`( 1.3.6.1.1.1.1.23 NAME 'bootParameter' DESC 'Boot parameter' SYNTAX 1.3.6.1.1.1.0.1 )`,
+	`( 1.3.6.1.1.1.1.24 NAME 'bootFile' DESC 'Boot image name' EQUALITY caseExactIA5Match SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 )`,
+	`( 1.3.6.1.1.1.1.26 NAME 'nisMapName' DESC 'NIS map name' SUP name )`,
+	`( 1.3.6.1.1.1.1.27 NAME 'nisMapEntry' DESC 'NIS map entry' EQUALITY caseExactIA5Match SUBSTR caseExactIA5SubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 SINGLE-VALUE )`,
 }
 
-// loadDefaultObjectClasses loads the default object classes into the schema.
-func loadDefaultObjectClasses(s *Schema) error {
-	for _, def := range defaultObjectClasses {
-		oc, err := parseObjectClass(def)
-		if err != nil {
-			return err
-		}
-		s.AddObjectClass(oc)
-	}
-	return nil
+// nisObjectClasses contains the NIS object class definitions (RFC 2307).
+var nisObjectClasses = []string{
+	`( 1.3.6.1.1.1.2.0 NAME 'posixAccount' DESC 'POSIX account' SUP top AUXILIARY MUST ( cn $ uid $ uidNumber $ gidNumber $ homeDirectory ) MAY ( userPassword $ loginShell $ gecos $ description ) )`,
+	`( 1.3.6.1.1.1.2.1 NAME 'shadowAccount' DESC 'Shadow password account' SUP top AUXILIARY MUST uid MAY ( userPassword $ shadowLastChange $ shadowMin $ shadowMax $ shadowWarning $ shadowInactive $ shadowExpire $ shadowFlag $ description ) )`,
+	`( 1.3.6.1.1.1.2.2 NAME 'posixGroup' DESC 'POSIX group' SUP top STRUCTURAL MUST ( cn $ gidNumber ) MAY ( userPassword $ memberUid $ description ) )`,
+	`( 1.3.6.1.1.1.2.3 NAME 'ipService' DESC 'Internet service' SUP top STRUCTURAL MUST ( cn $ ipServicePort $ ipServiceProtocol ) MAY description )`,
+	`( 1.3.6.1.1.1.2.4 NAME 'ipProtocol' DESC 'IP protocol' SUP top STRUCTURAL MUST ( cn $ ipProtocolNumber ) MAY description )`,
+	`( 1.3.6.1.1.1.2.5 NAME 'oncRpc' DESC 'ONC RPC binding' SUP top STRUCTURAL MUST ( cn $ oncRpcNumber ) MAY description )`,
+	`( 1.3.6.1.1.1.2.6 NAME 'ipHost' DESC 'Host with IP addresses' SUP top AUXILIARY MUST ( cn $ ipHostNumber ) MAY ( l $ description $ manager ) )`,
+	`( 1.3.6.1.1.1.2.7 NAME 'ipNetwork' DESC 'IP network' SUP top STRUCTURAL MUST ( cn $ ipNetworkNumber ) MAY ( ipNetmaskNumber $ l $ description $ manager ) )`,
+	`( 1.3.6.1.1.1.2.8 NAME 'nisNetgroup' DESC 'NIS netgroup' SUP top STRUCTURAL MUST cn MAY ( nisNetgroupTriple $ memberNisNetgroup $ description ) )`,
+	`( 1.3.6.1.1.1.2.9 NAME 'nisMap' DESC 'NIS map' SUP top STRUCTURAL MUST nisMapName MAY description )`,
+	`( 1.3.6.1.1.1.2.10 NAME 'nisObject' DESC 'Entry in a NIS map' SUP top STRUCTURAL MUST ( cn $ nisMapEntry $ nisMapName ) MAY description )`,
+	`( 1.3.6.1.1.1.2.11 NAME 'ieee802Device' DESC 'Device with a MAC address' SUP top AUXILIARY MAY macAddress )`,
+	`( 1.3.6.1.1.1.2.12 NAME 'bootableDevice' DESC 'Device with boot parameters' SUP top AUXILIARY MAY ( bootFile $ bootParameter ) )`,
 }
 
-// loadDefaultMatchingRules loads the default matching rules into the schema.
-func loadDefaultMatchingRules(s *Schema) error {
-	for _, def := range defaultMatchingRules {
-		mr, err := parseMatchingRule(def)
-		if err != nil {
-			return err
-		}
-		s.AddMatchingRule(mr)
-	}
-	return nil
+// dynGroupAttributeTypes contains the dynamic group attribute type definitions.
+var dynGroupAttributeTypes = []string{
+	`( 2.16.840.1.113730.3.1.198 NAME 'memberURL' DESC 'LDAP URL identifying group members' EQUALITY caseExactIA5Match SUBSTR caseExactIA5SubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 )`,
 }
 
-// loadDefaultSyntaxes loads the default syntaxes into the schema.
-func loadDefaultSyntaxes(s *Schema) error {
-	for _, def := range defaultSyntaxes {
-		syn, err := parseSyntaxDef(def)
-		if err != nil {
-			return err
-		}
-		s.AddSyntax(syn)
-	}
-	return nil
+// dynGroupObjectClasses contains the dynamic group object class definitions.
+var dynGroupObjectClasses = []string{
+	`( 2.16.840.1.113730.3.2.33 NAME 'groupOfURLs' DESC 'Group whose members are selected by LDAP URLs' SUP top STRUCTURAL MUST cn MAY ( memberURL $ businessCategory $ description $ o $ ou $ owner $ seeAlso ) )`,
 }
@@ -0,0 +1,262 @@
+package schema
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SubschemaDN is the DN of the subschema subentry that publishes the schema.
+const SubschemaDN = "cn=schema"
+
+// Subschema attribute names (RFC 4512 Section 4.2).
+const (
+	AttrLDAPSyntaxes   = "ldapSyntaxes"
+	AttrMatchingRules  = "matchingRules"
+	AttrAttributeTypes = "attributeTypes"
+	AttrObjectClasses  = "objectClasses"
+)
+
+// String returns the RFC 4512 ObjectClassDescription of the object class.
+// The result can be parsed back with ParseObjectClassDescription.
+func (oc *ObjectClass) String() string {
+	var b strings.Builder
+	b.WriteString("( ")
+	b.WriteString(oc.OID)
+	writeNames(&b, oc.Name, oc.Names)
+	writeQDString(&b, "DESC", oc.Desc)
+	if oc.Obsolete {
+		b.WriteString(" OBSOLETE")
+	}
+	writeOIDs(&b, "SUP", nonEmpty(oc.Superior))
+	b.WriteString(" ")
+	b.WriteString(oc.Kind.String())
+	writeOIDs(&b, "MUST", oc.Must)
+	writeOIDs(&b, "MAY", oc.May)
+	writeExtensions(&b, oc.Extensions)
+	b.WriteString(" )")
+	return b.String()
+}
+
+// String returns the RFC 4512 AttributeTypeDescription of the attribute type.
+// The result can be parsed back with ParseAttributeTypeDescription.
+func (at *AttributeType) String() string {
+	var b strings.Builder
+	b.WriteString("( ")
+	b.WriteString(at.OID)
+	writeNames(&b, at.Name, at.Names)
+	writeQDString(&b, "DESC", at.Desc)
+	if at.Obsolete {
+		b.WriteString(" OBSOLETE")
+	}
+	writeOIDs(&b, "SUP", nonEmpty(at.Superior))
+	writeOIDs(&b, "EQUALITY", nonEmpty(at.Equality))
+	writeOIDs(&b, "ORDERING", nonEmpty(at.Ordering))
+	writeOIDs(&b, "SUBSTR", nonEmpty(at.Substring))
+	if at.Syntax != "" {
+		b.WriteString(" SYNTAX ")
+		b.WriteString(at.Syntax)
+		if at.SyntaxLength > 0 {
+			b.WriteString("{")
+			b.WriteString(strconv.Itoa(at.SyntaxLength))
+			b.WriteString("}")
+		}
+	}
+	if at.SingleValue {
+		b.WriteString(" SINGLE-VALUE")
+	}
+	if at.Collective {
+		b.WriteString(" COLLECTIVE")
+	}
+	if at.NoUserMod {
+		b.WriteString(" NO-USER-MODIFICATION")
+	}
+	if at.Usage != UserApplications {
+		b.WriteString(" USAGE ")
+		b.WriteString(at.Usage.String())
+	}
+	writeExtensions(&b, at.Extensions)
+	b.WriteString(" )")
+	return b.String()
+}
+
+// String returns the RFC 4512 MatchingRuleDescription of the matching rule.
+func (mr *MatchingRule) String() string {
+	var b strings.Builder
+	b.WriteString("( ")
+	b.WriteString(mr.OID)
+	writeNames(&b, mr.Name, mr.Names)
+	writeQDString(&b, "DESC", mr.Description)
+	if mr.Obsolete {
+		b.WriteString(" OBSOLETE")
+	}
+	writeOIDs(&b, "SYNTAX", nonEmpty(mr.Syntax))
+	b.WriteString(" )")
+	return b.String()
+}
+
+// String returns the RFC 4512 SyntaxDescription of the syntax.
+func (syn *Syntax) String() string {
+	var b strings.Builder
+	b.WriteString("( ")
+	b.WriteString(syn.OID)
+	writeQDString(&b, "DESC", syn.Description)
+	b.WriteString(" )")
+	return b.String()
+}
+
+// SubschemaAttributes renders the schema as the values of the subschema
+// subentry attributes (ldapSyntaxes, matchingRules, attributeTypes and
+// objectClasses). Each definition appears once, ordered by OID.
+func (s *Schema) SubschemaAttributes() map[string][]string {
+	attrs := make(map[string][]string, 4)
+
+	syntaxes := make(map[string]string)
+	for _, syn := range s.Syntaxes {
+		syntaxes[syn.OID] = syn.String()
+	}
+	attrs[AttrLDAPSyntaxes] = sortedDescriptions(syntaxes)
+
+	rules := make(map[string]string)
+	for _, mr := range s.MatchingRules {
+		rules[mr.OID] = mr.String()
+	}
+	attrs[AttrMatchingRules] = sortedDescriptions(rules)
+
+	types := make(map[string]string)
+	for _, at := range s.AttributeTypes {
+		types[at.OID] = at.String()
+	}
+	attrs[AttrAttributeTypes] = sortedDescriptions(types)
+
+	classes := make(map[string]string)
+	for _, oc := range s.ObjectClasses {
+		classes[oc.OID] = oc.String()
+	}
+	attrs[AttrObjectClasses] = sortedDescriptions(classes)
+
+	return attrs
+}
+
+// WriteLDIF writes the schema as a cn=schema subentry in LDIF.
+// The output can be read back with LoadSchemaFromLDIF.
+func (s *Schema) WriteLDIF(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "dn: %s\nobjectClass: top\nobjectClass: ldapSubentry\nobjectClass: subschema\ncn: schema\n", SubschemaDN); err != nil {
+		return err
+	}
+
+	attrs := s.SubschemaAttributes()
+	for _, name := range []string{AttrLDAPSyntaxes, AttrMatchingRules, AttrAttributeTypes, AttrObjectClasses} {
+		for _, value := range attrs[name] {
+			if _, err := fmt.Fprintf(w, "%s: %s\n", name, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sortedDescriptions returns the descriptions ordered by their OID key.
+func sortedDescriptions(byOID map[string]string) []string {
+	oids := make([]string, 0, len(byOID))
+	for oid := range byOID {
+		oids = append(oids, oid)
+	}
+	sort.Strings(oids)
+
+	result := make([]string, len(oids))
+	for i, oid := range oids {
+		result[i] = byOID[oid]
+	}
+	return result
+}
+
+// writeNames writes the NAME field as a single qdescr or a qdescr list.
+func writeNames(b *strings.Builder, name string, names []string) {
+	if len(names) == 0 {
+		names = nonEmpty(name)
+	}
+	switch len(names) {
+	case 0:
+	case 1:
+		b.WriteString(" NAME '")
+		b.WriteString(names[0])
+		b.WriteString("'")
+	default:
+		b.WriteString(" NAME (")
+		for _, n := range names {
+			b.WriteString(" '")
+			b.WriteString(n)
+			b.WriteString("'")
+		}
+		b.WriteString(" )")
+	}
+}
+
+// writeQDString writes a keyword followed by a quoted, escaped string.
+func writeQDString(b *strings.Builder, keyword, value string) {
+	if value == "" {
+		return
+	}
+	b.WriteString(" ")
+	b.WriteString(keyword)
+	b.WriteString(" '")
+	b.WriteString(escapeQDString(value))
+	b.WriteString("'")
+}
+
+// writeOIDs writes a keyword followed by a single oid or a "$"-separated oid list.
+func writeOIDs(b *strings.Builder, keyword string, oids []string) {
+	switch len(oids) {
+	case 0:
+	case 1:
+		b.WriteString(" ")
+		b.WriteString(keyword)
+		b.WriteString(" ")
+		b.WriteString(oids[0])
+	default:
+		b.WriteString(" ")
+		b.WriteString(keyword)
+		b.WriteString(" ( ")
+		b.WriteString(strings.Join(oids, " $ "))
+		b.WriteString(" )")
+	}
+}
+
+// writeExtensions writes the X- extensions in name order.
+func writeExtensions(b *strings.Builder, ext map[string][]string) {
+	names := make([]string, 0, len(ext))
+	for name := range ext {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		values := ext[name]
+		b.WriteString(" ")
+		b.WriteString(name)
+		if len(values) == 1 {
+			b.WriteString(" '")
+			b.WriteString(escapeQDString(values[0]))
+			b.WriteString("'")
+			continue
+		}
+		b.WriteString(" (")
+		for _, v := range values {
+			b.WriteString(" '")
+			b.WriteString(escapeQDString(v))
+			b.WriteString("'")
+		}
+		b.WriteString(" )")
+	}
+}
+
+// nonEmpty returns s as a one-element slice, or nil if it is empty.
+func nonEmpty(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return []string{s}
+}
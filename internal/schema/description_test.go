@@ -0,0 +1,128 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestObjectClassDescriptionRoundTrip(t *testing.T) {
+	tests := []string{
+		"( 2.5.6.6 NAME 'person' SUP top STRUCTURAL MUST ( sn $ cn ) MAY userPassword )",
+		"( 2.5.6.0 NAME 'top' ABSTRACT MUST objectClass )",
+		"( 2.5.6.1 NAME ( 'alias' 'aliasObject' ) DESC 'RFC 4512: an alias' SUP top STRUCTURAL MUST aliasedObjectName )",
+		"( 1.3.6.1.4.1.1466.344 NAME 'dcObject' OBSOLETE SUP top AUXILIARY MUST dc X-ORIGIN 'RFC 4519' )",
+		"( 1.2.3.4 NAME 'quoted' DESC 'it\\27s a \\5C test' STRUCTURAL X-ORIGIN ( 'a' 'b' ) )",
+	}
+
+	for _, input := range tests {
+		oc, err := ParseObjectClassDescription(input)
+		if err != nil {
+			t.Fatalf("ParseObjectClassDescription(%q) error = %v", input, err)
+		}
+		if got := oc.String(); got != input {
+			t.Errorf("round trip:\n got  %s\n want %s", got, input)
+		}
+	}
+}
+
+func TestParseObjectClassDescriptionFields(t *testing.T) {
+	oc, err := ParseObjectClassDescription("( 1.2.3.4 NAME 'quoted' DESC 'it\\27s a \\5C test' SUP ( top $ other ) AUXILIARY X-ORIGIN ( 'a' 'b' ) )")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if oc.Desc != `it's a \ test` {
+		t.Errorf("Desc = %q", oc.Desc)
+	}
+	if oc.Superior != "top" {
+		t.Errorf("Superior = %q", oc.Superior)
+	}
+	if got := oc.Extensions["X-ORIGIN"]; !stringSliceEqual(got, []string{"a", "b"}) {
+		t.Errorf("X-ORIGIN = %v", got)
+	}
+}
+
+func TestAttributeTypeDescriptionRoundTrip(t *testing.T) {
+	tests := []string{
+		"( 2.5.4.3 NAME ( 'cn' 'commonName' ) SUP name )",
+		"( 2.5.4.41 NAME 'name' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15{32768} )",
+		"( 2.5.18.1 NAME 'createTimestamp' EQUALITY generalizedTimeMatch ORDERING generalizedTimeOrderingMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.24 SINGLE-VALUE NO-USER-MODIFICATION USAGE directoryOperation )",
+		"( 1.2.3.5 NAME 'legacy' DESC 'old attribute' OBSOLETE SYNTAX 1.3.6.1.4.1.1466.115.121.1.26{64} COLLECTIVE X-ORIGIN 'local' X-SCHEMA-FILE 'legacy.ldif' )",
+	}
+
+	for _, input := range tests {
+		at, err := ParseAttributeTypeDescription(input)
+		if err != nil {
+			t.Fatalf("ParseAttributeTypeDescription(%q) error = %v", input, err)
+		}
+		if got := at.String(); got != input {
+			t.Errorf("round trip:\n got  %s\n want %s", got, input)
+		}
+	}
+}
+
+func TestParseAttributeTypeDescriptionSyntaxLength(t *testing.T) {
+	at, err := ParseAttributeTypeDescription("( 2.5.4.3 NAME 'cn' SYNTAX '1.3.6.1.4.1.1466.115.121.1.15{64}' )")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if at.Syntax != SyntaxDirectoryString || at.SyntaxLength != 64 {
+		t.Errorf("Syntax = %q, SyntaxLength = %d", at.Syntax, at.SyntaxLength)
+	}
+
+	for _, input := range []string{
+		"( 2.5.4.3 NAME 'cn' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15{abc} )",
+		"( 2.5.4.3 NAME 'cn' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15{64 )",
+		"( 2.5.4.3 NAME 'cn' X-ORIGIN )",
+	} {
+		if _, err := ParseAttributeTypeDescription(input); err == nil {
+			t.Errorf("ParseAttributeTypeDescription(%q) expected error", input)
+		}
+	}
+}
+
+func TestSchemaWriteLDIFRoundTrip(t *testing.T) {
+	original, err := LoadBuiltinSchema(BuiltinCore, BuiltinCosine)
+	if err != nil {
+		t.Fatalf("LoadBuiltinSchema() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := original.WriteLDIF(&buf); err != nil {
+		t.Fatalf("WriteLDIF() error = %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "dn: cn=schema\n") {
+		t.Errorf("unexpected LDIF header: %q", buf.String()[:40])
+	}
+
+	loaded, err := LoadSchemaFromLDIF(&buf)
+	if err != nil {
+		t.Fatalf("LoadSchemaFromLDIF() error = %v", err)
+	}
+
+	want := original.SubschemaAttributes()
+	got := loaded.SubschemaAttributes()
+	for _, name := range []string{AttrLDAPSyntaxes, AttrMatchingRules, AttrAttributeTypes, AttrObjectClasses} {
+		if len(want[name]) == 0 {
+			t.Errorf("%s: no values rendered", name)
+		}
+		if !stringSliceEqual(got[name], want[name]) {
+			t.Errorf("%s: round trip changed %d values to %d", name, len(want[name]), len(got[name]))
+		}
+	}
+}
+
+func TestLoadSchemaFromLDIFBase64(t *testing.T) {
+	def := "( 1.2.3.6 NAME 'encoded' DESC 'base64 value' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )"
+	ldif := "dn: cn=schema\nattributeTypes:: " + base64.StdEncoding.EncodeToString([]byte(def)) + "\n"
+
+	s, err := LoadSchemaFromLDIF(strings.NewReader(ldif))
+	if err != nil {
+		t.Fatalf("LoadSchemaFromLDIF() error = %v", err)
+	}
+	at := s.GetAttributeType("encoded")
+	if at == nil || at.Desc != "base64 value" {
+		t.Errorf("GetAttributeType(encoded) = %+v", at)
+	}
+}
@@ -91,6 +91,40 @@
 //	// Load from LDIF file
 //	schema, err := schema.LoadFromLDIF("/path/to/schema.ldif")
 //
+// # Schema Descriptions
+//
+// Definitions use the RFC 4512 description format. ParseObjectClassDescription
+// and ParseAttributeTypeDescription parse them, and the String methods of
+// ObjectClass and AttributeType render them back:
+//
+//	oc, err := schema.ParseObjectClassDescription(
+//	    "( 2.5.6.6 NAME 'person' SUP top STRUCTURAL MUST ( sn $ cn ) )")
+//	fmt.Println(oc.String())
+//
+// Schema.WriteLDIF renders the whole schema as a cn=schema subentry that
+// LoadSchemaFromLDIF reads back.
+//
+// # Built-in Schema Sets
+//
+// The standard schema sets core, cosine, inetorgperson, nis and dyngroup are
+// compiled in and can be combined. Sets required by a selected set are
+// loaded as well:
+//
+//	s, err := schema.LoadBuiltinSchema("inetorgperson", "nis")
+//
+// By default the Validator rejects entries using undefined object classes.
+// Call SetStrict(false) to accept them instead.
+//
+// # Migrations
+//
+// Released schemas are numbered; LoadVersion returns one of them. A
+// MigrationPlan lists the steps between two schemas and applies them to the
+// stored entries:
+//
+//	plan, err := schema.NewMigrationPlan(oldSchema, newSchema)
+//	plan.SetDefault("employeeType", "staff")
+//	stats, err := plan.Apply(engine)
+//
 // # Standard Syntaxes
 //
 // Common LDAP syntaxes:
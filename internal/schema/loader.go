@@ -2,6 +2,7 @@ package schema
 
 import (
 	"bufio"
+	"encoding/base64"
 	"errors"
 	"io"
 	"os"
@@ -45,6 +46,7 @@ func LoadSchemaFromLDIF(r io.Reader) (*Schema, error) {
 
 	scanner := bufio.NewScanner(r)
 	var currentAttr string
+	var currentBase64 bool
 	var currentValue strings.Builder
 
 	processValue := func() error {
@@ -53,6 +55,13 @@ func LoadSchemaFromLDIF(r io.Reader) (*Schema, error) {
 		}
 
 		value := strings.TrimSpace(currentValue.String())
+		if currentBase64 {
+			decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(value, " ", ""))
+			if err != nil {
+				return ErrInvalidLDIF
+			}
+			value = strings.TrimSpace(string(decoded))
+		}
 		if value == "" {
 			return nil
 		}
@@ -61,13 +70,13 @@ func LoadSchemaFromLDIF(r io.Reader) (*Schema, error) {
 		switch strings.ToLower(currentAttr) {
 		case "attributetypes":
 			var at *AttributeType
-			at, err = parseAttributeType(value)
+			at, err = ParseAttributeTypeDescription(value)
 			if err == nil {
 				s.AddAttributeType(at)
 			}
 		case "objectclasses":
 			var oc *ObjectClass
-			oc, err = parseObjectClass(value)
+			oc, err = ParseObjectClassDescription(value)
 			if err == nil {
 				s.AddObjectClass(oc)
 			}
@@ -86,6 +95,7 @@ func LoadSchemaFromLDIF(r io.Reader) (*Schema, error) {
 		}
 
 		currentAttr = ""
+		currentBase64 = false
 		currentValue.Reset()
 		return err
 	}
@@ -124,9 +134,9 @@ func LoadSchemaFromLDIF(r io.Reader) (*Schema, error) {
 		attrValue := strings.TrimSpace(line[colonIdx+1:])
 
 		// Handle base64 encoded values (attribute:: value)
-		if strings.HasSuffix(attrName, ":") {
-			attrName = strings.TrimSuffix(attrName, ":")
-			// Base64 decoding would go here if needed
+		if strings.HasPrefix(attrValue, ":") {
+			attrValue = strings.TrimSpace(attrValue[1:])
+			currentBase64 = true
 		}
 
 		currentAttr = attrName
@@ -154,16 +164,15 @@ func LoadSchemaFromLDIF(r io.Reader) (*Schema, error) {
 }
 
 // LoadDefaultSchema loads the built-in default schema with standard
-// LDAP object classes and attribute types.
+// LDAP object classes and attribute types from every built-in schema set.
 func LoadDefaultSchema() *Schema {
 	s := NewSchema()
 
-	// Load in order: syntaxes, matching rules, attribute types, object classes
-	// This ensures dependencies are available when needed
-	_ = loadDefaultSyntaxes(s)
-	_ = loadDefaultMatchingRules(s)
-	_ = loadDefaultAttributeTypes(s)
-	_ = loadDefaultObjectClasses(s)
+	// Sets are listed in dependency order, so definitions referenced by a
+	// set are always available when it is loaded
+	for _, name := range builtinSetOrder {
+		_ = loadBuiltinSet(s, builtinSets[name])
+	}
 
 	// Resolve inheritance
 	_ = resolveObjectClassInheritance(s)
@@ -354,6 +363,28 @@ func (s *Schema) GetAllMayAttributes(ocName string) []string {
 	return result
 }
 
+// ObjectClassChain returns the name of an object class followed by the
+// names of its superior classes, nearest first, e.g. inetOrgPerson,
+// organizationalPerson, person, top. Returns nil if the class is not found.
+func (s *Schema) ObjectClassChain(name string) []string {
+	oc := s.GetObjectClass(name)
+	if oc == nil {
+		return nil
+	}
+
+	seen := make(map[*ObjectClass]bool)
+	var chain []string
+	for oc != nil && !seen[oc] {
+		seen[oc] = true
+		chain = append(chain, oc.Name)
+		if oc.Superior == "" {
+			break
+		}
+		oc = s.GetObjectClass(oc.Superior)
+	}
+	return chain
+}
+
 // GetEffectiveSyntax returns the effective syntax OID for an attribute type,
 // resolving inheritance if necessary.
 func (s *Schema) GetEffectiveSyntax(atName string) string {
@@ -397,3 +428,25 @@ func (s *Schema) GetEffectiveEqualityMatch(atName string) string {
 
 	return ""
 }
+
+// GetEffectiveSubstringMatch returns the effective substring matching rule
+// for an attribute type, resolving inheritance if necessary.
+func (s *Schema) GetEffectiveSubstringMatch(atName string) string {
+	at := s.GetAttributeType(atName)
+	if at == nil {
+		return ""
+	}
+
+	// Walk up the inheritance chain
+	for at != nil {
+		if at.Substring != "" {
+			return at.Substring
+		}
+		if at.Superior == "" {
+			break
+		}
+		at = s.GetAttributeType(at.Superior)
+	}
+
+	return ""
+}
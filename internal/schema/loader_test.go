@@ -70,7 +70,7 @@ func TestParseObjectClass(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			oc, err := parseObjectClass(tt.input)
+			oc, err := ParseObjectClassDescription(tt.input)
 			if tt.wantErr {
 				if err == nil {
 					t.Error("expected error, got nil")
@@ -168,7 +168,7 @@ func TestParseAttributeType(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			at, err := parseAttributeType(tt.input)
+			at, err := ParseAttributeTypeDescription(tt.input)
 			if tt.wantErr {
 				if err == nil {
 					t.Error("expected error, got nil")
@@ -237,6 +237,9 @@ objectClasses: ( 2.5.6.6 NAME 'person' SUP top STRUCTURAL MUST ( sn $ cn ) MAY d
 			t.Errorf("cn Superior = %q, want %q", at.Superior, "name")
 		}
 	}
+	if alias := s.ResolveAlias("commonName"); alias == nil || alias != at {
+		t.Errorf("ResolveAlias(commonName) = %v, want cn", alias)
+	}
 
 	// Check object classes
 	oc := s.GetObjectClass("top")
@@ -382,6 +385,12 @@ func TestAttributeTypeInheritance(t *testing.T) {
 	if effectiveEquality != "caseIgnoreMatch" {
 		t.Errorf("cn effective equality = %q, want %q", effectiveEquality, "caseIgnoreMatch")
 	}
+
+	// cn should inherit substring matching from name
+	effectiveSubstring := s.GetEffectiveSubstringMatch("cn")
+	if effectiveSubstring != "caseIgnoreSubstringsMatch" {
+		t.Errorf("cn effective substring = %q, want %q", effectiveSubstring, "caseIgnoreSubstringsMatch")
+	}
 }
 
 func TestGetAllMustAttributes(t *testing.T) {
@@ -404,6 +413,28 @@ func TestGetAllMustAttributes(t *testing.T) {
 	}
 }
 
+func TestObjectClassChain(t *testing.T) {
+	s := LoadDefaultSchema()
+
+	chain := s.ObjectClassChain("inetorgperson")
+	want := []string{"inetOrgPerson", "organizationalPerson", "person", "top"}
+	if len(chain) != len(want) {
+		t.Fatalf("ObjectClassChain(inetorgperson) = %v, want %v", chain, want)
+	}
+	for i := range want {
+		if chain[i] != want[i] {
+			t.Errorf("ObjectClassChain(inetorgperson)[%d] = %q, want %q", i, chain[i], want[i])
+		}
+	}
+
+	if chain := s.ObjectClassChain("top"); len(chain) != 1 || chain[0] != "top" {
+		t.Errorf("ObjectClassChain(top) = %v, want [top]", chain)
+	}
+	if chain := s.ObjectClassChain("unknownClass"); chain != nil {
+		t.Errorf("ObjectClassChain(unknownClass) = %v, want nil", chain)
+	}
+}
+
 func TestGetAllMayAttributes(t *testing.T) {
 	s := LoadDefaultSchema()
 
@@ -708,6 +739,9 @@ func TestNonexistentSchemaElements(t *testing.T) {
 	if s.GetEffectiveEqualityMatch("nonexistent") != "" {
 		t.Error("should return empty string for nonexistent attribute equality")
 	}
+	if s.GetEffectiveSubstringMatch("nonexistent") != "" {
+		t.Error("should return empty string for nonexistent attribute substring")
+	}
 }
 
 // Helper functions
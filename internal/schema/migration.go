@@ -0,0 +1,430 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// ErrNilSchema is returned when a migration plan is built from a nil schema.
+var ErrNilSchema = errors.New("schema is nil")
+
+// migrationBatchSize is the maximum number of entries written back in a
+// single transaction by MigrationPlan.Apply.
+var migrationBatchSize = 1000
+
+// MigrationStepType identifies the kind of change a MigrationStep makes.
+type MigrationStepType int
+
+// Migration step types, in the order they are applied. Renames come first so
+// later steps see entries under their new names.
+const (
+	// RenameAttributeType renames an attribute type that keeps its OID.
+	RenameAttributeType MigrationStepType = iota
+	// RenameObjectClass renames an object class that keeps its OID.
+	RenameObjectClass
+	// AddAttributeType adds a new attribute type.
+	AddAttributeType
+	// ChangeAttributeSyntax changes the syntax of an attribute type.
+	ChangeAttributeSyntax
+	// AddObjectClass adds a new object class.
+	AddObjectClass
+	// AddMustAttribute adds a required attribute to an existing object class.
+	AddMustAttribute
+	// RemoveObjectClass removes an object class.
+	RemoveObjectClass
+	// RemoveAttributeType removes an attribute type.
+	RemoveAttributeType
+)
+
+// String returns the string representation of the MigrationStepType.
+func (t MigrationStepType) String() string {
+	switch t {
+	case RenameAttributeType:
+		return "RenameAttributeType"
+	case RenameObjectClass:
+		return "RenameObjectClass"
+	case AddAttributeType:
+		return "AddAttributeType"
+	case ChangeAttributeSyntax:
+		return "ChangeAttributeSyntax"
+	case AddObjectClass:
+		return "AddObjectClass"
+	case AddMustAttribute:
+		return "AddMustAttribute"
+	case RemoveObjectClass:
+		return "RemoveObjectClass"
+	case RemoveAttributeType:
+		return "RemoveAttributeType"
+	default:
+		return "Unknown"
+	}
+}
+
+// MigrationStep is a single difference between two schemas.
+type MigrationStep struct {
+	Type MigrationStepType
+	// OID is the OID of the attribute type or object class changed by the step.
+	OID string
+	// Name is the name in the new schema. OldName is the name in the old
+	// schema; it differs from Name only for renames.
+	Name    string
+	OldName string
+	// Attribute is the attribute added by an AddMustAttribute step.
+	Attribute string
+	// OldSyntax and NewSyntax are the syntax OIDs of a ChangeAttributeSyntax step.
+	OldSyntax string
+	NewSyntax string
+}
+
+// String returns a human-readable description of the step.
+func (s MigrationStep) String() string {
+	switch s.Type {
+	case RenameAttributeType, RenameObjectClass:
+		return fmt.Sprintf("%s %s -> %s", s.Type, s.OldName, s.Name)
+	case ChangeAttributeSyntax:
+		return fmt.Sprintf("%s %s %s -> %s", s.Type, s.Name, s.OldSyntax, s.NewSyntax)
+	case AddMustAttribute:
+		return fmt.Sprintf("%s %s MUST %s", s.Type, s.Name, s.Attribute)
+	case RemoveAttributeType, RemoveObjectClass:
+		return fmt.Sprintf("%s %s", s.Type, s.OldName)
+	default:
+		return fmt.Sprintf("%s %s", s.Type, s.Name)
+	}
+}
+
+// MigrationViolation is an entry that could not be brought in line with the
+// new schema. The entry is left as it is.
+type MigrationViolation struct {
+	DN     string `json:"dn"`
+	Reason string `json:"reason"`
+}
+
+// MigrationStats summarizes a migration run.
+type MigrationStats struct {
+	ScannedEntries int                   `json:"scannedEntries"`
+	UpdatedEntries int                   `json:"updatedEntries"`
+	Violations     []*MigrationViolation `json:"violations"`
+}
+
+// MigrationPlan is the ordered list of steps that upgrades stored entries
+// from one schema to another.
+type MigrationPlan struct {
+	Steps []MigrationStep
+
+	from     *Schema
+	to       *Schema
+	defaults map[string][][]byte
+}
+
+// NewMigrationPlan compares two schemas and returns the steps needed to move
+// entries from old to new. Attribute types and object classes are matched by
+// OID, so a definition that keeps its OID but changes its name is a rename.
+func NewMigrationPlan(old, new *Schema) (*MigrationPlan, error) {
+	if old == nil || new == nil {
+		return nil, ErrNilSchema
+	}
+
+	plan := &MigrationPlan{
+		from:     old,
+		to:       new,
+		defaults: make(map[string][][]byte),
+	}
+	plan.diffAttributeTypes()
+	plan.diffObjectClasses()
+
+	sort.SliceStable(plan.Steps, func(i, j int) bool {
+		a, b := plan.Steps[i], plan.Steps[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.OldName < b.OldName
+	})
+
+	return plan, nil
+}
+
+// SetDefault sets the values given to entries that lack an attribute added
+// as MUST by an AddMustAttribute step. Without a default, such entries are
+// reported as violations.
+func (p *MigrationPlan) SetDefault(attribute string, values ...string) {
+	byteValues := make([][]byte, len(values))
+	for i, v := range values {
+		byteValues[i] = []byte(v)
+	}
+	p.defaults[strings.ToLower(attribute)] = byteValues
+}
+
+// diffAttributeTypes adds the steps for attribute type changes.
+func (p *MigrationPlan) diffAttributeTypes() {
+	oldTypes := uniqueAttributeTypes(p.from)
+	newTypes := uniqueAttributeTypes(p.to)
+
+	for key, oldAT := range oldTypes {
+		newAT, ok := newTypes[key]
+		if !ok {
+			p.Steps = append(p.Steps, MigrationStep{Type: RemoveAttributeType, OID: oldAT.OID, OldName: oldAT.Name})
+			continue
+		}
+		if !strings.EqualFold(oldAT.Name, newAT.Name) {
+			p.Steps = append(p.Steps, MigrationStep{Type: RenameAttributeType, OID: newAT.OID, Name: newAT.Name, OldName: oldAT.Name})
+		}
+		oldSyntax := p.from.GetEffectiveSyntax(oldAT.Name)
+		newSyntax := p.to.GetEffectiveSyntax(newAT.Name)
+		if oldSyntax != newSyntax {
+			p.Steps = append(p.Steps, MigrationStep{
+				Type:      ChangeAttributeSyntax,
+				OID:       newAT.OID,
+				Name:      newAT.Name,
+				OldName:   oldAT.Name,
+				OldSyntax: oldSyntax,
+				NewSyntax: newSyntax,
+			})
+		}
+	}
+
+	for key, newAT := range newTypes {
+		if _, ok := oldTypes[key]; !ok {
+			p.Steps = append(p.Steps, MigrationStep{Type: AddAttributeType, OID: newAT.OID, Name: newAT.Name})
+		}
+	}
+}
+
+// diffObjectClasses adds the steps for object class changes, including
+// attributes that became MUST.
+func (p *MigrationPlan) diffObjectClasses() {
+	oldClasses := uniqueObjectClasses(p.from)
+	newClasses := uniqueObjectClasses(p.to)
+
+	for key, oldOC := range oldClasses {
+		newOC, ok := newClasses[key]
+		if !ok {
+			p.Steps = append(p.Steps, MigrationStep{Type: RemoveObjectClass, OID: oldOC.OID, OldName: oldOC.Name})
+			continue
+		}
+		if !strings.EqualFold(oldOC.Name, newOC.Name) {
+			p.Steps = append(p.Steps, MigrationStep{Type: RenameObjectClass, OID: newOC.OID, Name: newOC.Name, OldName: oldOC.Name})
+		}
+
+		oldMust := make(map[string]bool, len(oldOC.Must))
+		for _, attr := range oldOC.Must {
+			oldMust[attributeKey(p.from, attr)] = true
+		}
+		for _, attr := range newOC.Must {
+			if !oldMust[attributeKey(p.to, attr)] {
+				p.Steps = append(p.Steps, MigrationStep{Type: AddMustAttribute, OID: newOC.OID, Name: newOC.Name, Attribute: attr})
+			}
+		}
+	}
+
+	for key, newOC := range newClasses {
+		if _, ok := oldClasses[key]; !ok {
+			p.Steps = append(p.Steps, MigrationStep{Type: AddObjectClass, OID: newOC.OID, Name: newOC.Name})
+		}
+	}
+}
+
+// Apply migrates the entries stored in engine to the new schema. All entries
+// are read from one snapshot and the changed ones are written back in
+// transactions of up to 1000 entries. Entries that cannot be migrated are
+// left unchanged and reported in the returned stats.
+func (p *MigrationPlan) Apply(engine storage.StorageEngine) (*MigrationStats, error) {
+	stats := &MigrationStats{
+		Violations: make([]*MigrationViolation, 0),
+	}
+
+	entries, err := readAllEntries(engine)
+	if err != nil {
+		return nil, err
+	}
+	stats.ScannedEntries = len(entries)
+
+	var updated []*storage.Entry
+	for _, entry := range entries {
+		changed := false
+		for _, step := range p.Steps {
+			stepChanged, violation := p.applyStep(step, entry)
+			changed = changed || stepChanged
+			if violation != "" {
+				stats.Violations = append(stats.Violations, &MigrationViolation{DN: entry.DN, Reason: violation})
+			}
+		}
+		if changed {
+			updated = append(updated, entry)
+		}
+	}
+
+	for start := 0; start < len(updated); start += migrationBatchSize {
+		end := start + migrationBatchSize
+		if end > len(updated) {
+			end = len(updated)
+		}
+
+		txn, err := engine.Begin()
+		if err != nil {
+			return stats, err
+		}
+		for _, entry := range updated[start:end] {
+			if err := engine.Put(txn, entry); err != nil {
+				engine.Rollback(txn)
+				return stats, fmt.Errorf("failed to migrate %s: %w", entry.DN, err)
+			}
+		}
+		if err := engine.Commit(txn); err != nil {
+			return stats, err
+		}
+		stats.UpdatedEntries = end
+	}
+
+	return stats, nil
+}
+
+// applyStep applies a step to an entry in place. It reports whether the
+// entry changed, and why the entry violates the new schema if it does.
+func (p *MigrationPlan) applyStep(step MigrationStep, entry *storage.Entry) (bool, string) {
+	switch step.Type {
+	case RenameAttributeType:
+		oldName := strings.ToLower(step.OldName)
+		values, ok := entry.Attributes[oldName]
+		if !ok {
+			return false, ""
+		}
+		delete(entry.Attributes, oldName)
+		for _, v := range values {
+			entry.AddAttributeValue(step.Name, v)
+		}
+		return true, ""
+
+	case RenameObjectClass:
+		changed := false
+		for i, v := range entry.GetAttribute("objectclass") {
+			if strings.EqualFold(string(v), step.OldName) {
+				entry.Attributes["objectclass"][i] = []byte(step.Name)
+				changed = true
+			}
+		}
+		return changed, ""
+
+	case ChangeAttributeSyntax:
+		syntax := p.to.GetSyntax(step.NewSyntax)
+		if syntax == nil || !syntax.HasValidator() {
+			return false, ""
+		}
+		for _, v := range entry.GetAttribute(step.Name) {
+			if !syntax.Validate(v) {
+				return false, fmt.Sprintf("value of %s does not conform to syntax %s", step.Name, step.NewSyntax)
+			}
+		}
+
+	case AddMustAttribute:
+		if entry.HasAttribute(step.Attribute) || !p.hasObjectClass(entry, step.Name) {
+			return false, ""
+		}
+		values, ok := p.defaults[strings.ToLower(step.Attribute)]
+		if !ok {
+			return false, fmt.Sprintf("missing attribute %s required by %s", step.Attribute, step.Name)
+		}
+		for _, v := range values {
+			entry.AddAttributeValue(step.Attribute, append([]byte(nil), v...))
+		}
+		return true, ""
+
+	case RemoveObjectClass:
+		for _, v := range entry.GetAttribute("objectclass") {
+			if strings.EqualFold(string(v), step.OldName) {
+				return false, fmt.Sprintf("object class %s was removed", step.OldName)
+			}
+		}
+
+	case RemoveAttributeType:
+		if entry.HasAttribute(step.OldName) {
+			return false, fmt.Sprintf("attribute type %s was removed", step.OldName)
+		}
+	}
+
+	return false, ""
+}
+
+// hasObjectClass reports whether any of the entry's object classes is name
+// or inherits from it in the new schema.
+func (p *MigrationPlan) hasObjectClass(entry *storage.Entry, name string) bool {
+	for _, v := range entry.GetAttribute("objectclass") {
+		oc := p.to.GetObjectClass(string(v))
+		for depth := 0; oc != nil && depth < 32; depth++ {
+			if strings.EqualFold(oc.Name, name) {
+				return true
+			}
+			if oc.Superior == "" {
+				break
+			}
+			oc = p.to.GetObjectClass(oc.Superior)
+		}
+	}
+	return false
+}
+
+// readAllEntries returns copies of all entries in the engine.
+func readAllEntries(engine storage.StorageEngine) ([]*storage.Entry, error) {
+	txn, err := engine.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer engine.Rollback(txn)
+
+	iter := engine.SearchByDN(txn, "", storage.ScopeSubtree)
+	defer iter.Close()
+
+	var entries []*storage.Entry
+	for iter.Next() {
+		if entry := iter.Entry(); entry != nil {
+			entries = append(entries, entry.Clone())
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// uniqueAttributeTypes returns the attribute types of s keyed by OID, or by
+// lowercase name for types without an OID.
+func uniqueAttributeTypes(s *Schema) map[string]*AttributeType {
+	types := make(map[string]*AttributeType)
+	for _, at := range s.AttributeTypes {
+		key := at.OID
+		if key == "" {
+			key = strings.ToLower(at.Name)
+		}
+		types[key] = at
+	}
+	return types
+}
+
+// uniqueObjectClasses returns the object classes of s keyed by OID, or by
+// lowercase name for classes without an OID.
+func uniqueObjectClasses(s *Schema) map[string]*ObjectClass {
+	classes := make(map[string]*ObjectClass)
+	for _, oc := range s.ObjectClasses {
+		key := oc.OID
+		if key == "" {
+			key = strings.ToLower(oc.Name)
+		}
+		classes[key] = oc
+	}
+	return classes
+}
+
+// attributeKey returns the key uniqueAttributeTypes uses for an attribute
+// name in s.
+func attributeKey(s *Schema, name string) string {
+	if at := s.GetAttributeType(name); at != nil && at.OID != "" {
+		return at.OID
+	}
+	return strings.ToLower(name)
+}
@@ -0,0 +1,269 @@
+package schema
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/engine"
+)
+
+const migrationV1 = `dn: cn=schema
+objectClass: top
+objectClass: subschema
+attributeTypes: ( 2.5.4.0 NAME 'objectClass' SYNTAX 1.3.6.1.4.1.1466.115.121.1.38 )
+attributeTypes: ( 2.5.4.3 NAME 'cn' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )
+attributeTypes: ( 2.5.4.4 NAME 'sn' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )
+attributeTypes: ( 2.5.4.11 NAME 'ou' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )
+attributeTypes: ( 2.5.4.12 NAME 'title' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )
+objectClasses: ( 2.5.6.0 NAME 'top' ABSTRACT MUST objectClass )
+objectClasses: ( 2.5.6.6 NAME 'person' SUP top STRUCTURAL MUST ( sn $ cn ) MAY title )
+objectClasses: ( 2.5.6.5 NAME 'organizationalUnit' SUP top STRUCTURAL MUST ou )
+`
+
+// migrationV2 renames title to jobTitle and makes the new employeeType
+// attribute a MUST of person.
+const migrationV2 = `dn: cn=schema
+objectClass: top
+objectClass: subschema
+attributeTypes: ( 2.5.4.0 NAME 'objectClass' SYNTAX 1.3.6.1.4.1.1466.115.121.1.38 )
+attributeTypes: ( 2.5.4.3 NAME 'cn' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )
+attributeTypes: ( 2.5.4.4 NAME 'sn' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )
+attributeTypes: ( 2.5.4.11 NAME 'ou' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )
+attributeTypes: ( 2.5.4.12 NAME 'jobTitle' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )
+attributeTypes: ( 2.16.840.1.113730.3.1.4 NAME 'employeeType' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )
+objectClasses: ( 2.5.6.0 NAME 'top' ABSTRACT MUST objectClass )
+objectClasses: ( 2.5.6.6 NAME 'person' SUP top STRUCTURAL MUST ( sn $ cn $ employeeType ) MAY jobTitle )
+objectClasses: ( 2.5.6.5 NAME 'organizationalUnit' SUP top STRUCTURAL MUST ou )
+`
+
+func loadMigrationSchemas(t *testing.T) (*Schema, *Schema) {
+	t.Helper()
+
+	v1, err := LoadSchemaFromLDIF(strings.NewReader(migrationV1))
+	if err != nil {
+		t.Fatalf("failed to load v1 schema: %v", err)
+	}
+	v2, err := LoadSchemaFromLDIF(strings.NewReader(migrationV2))
+	if err != nil {
+		t.Fatalf("failed to load v2 schema: %v", err)
+	}
+	return v1, v2
+}
+
+// newMigrationTestEngine opens an engine holding an ou and two people, one
+// of which has a title.
+func newMigrationTestEngine(t *testing.T) storage.StorageEngine {
+	t.Helper()
+
+	db, err := engine.Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	ou := storage.NewEntry("ou=users,dc=example,dc=com")
+	ou.SetStringAttribute("objectclass", "top", "organizationalUnit")
+	ou.SetStringAttribute("ou", "users")
+
+	alice := storage.NewEntry("uid=alice,ou=users,dc=example,dc=com")
+	alice.SetStringAttribute("objectclass", "top", "person")
+	alice.SetStringAttribute("cn", "Alice")
+	alice.SetStringAttribute("sn", "Smith")
+	alice.SetStringAttribute("title", "Engineer")
+
+	bob := storage.NewEntry("uid=bob,ou=users,dc=example,dc=com")
+	bob.SetStringAttribute("objectclass", "top", "person")
+	bob.SetStringAttribute("cn", "Bob")
+	bob.SetStringAttribute("sn", "Jones")
+
+	txn, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	for _, entry := range []*storage.Entry{ou, alice, bob} {
+		if err := db.Put(txn, entry); err != nil {
+			t.Fatalf("Put(%s) error = %v", entry.DN, err)
+		}
+	}
+	if err := db.Commit(txn); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	return db
+}
+
+func getMigratedEntry(t *testing.T, db storage.StorageEngine, dn string) *storage.Entry {
+	t.Helper()
+
+	txn, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	defer db.Rollback(txn)
+
+	entry, err := db.Get(txn, dn)
+	if err != nil {
+		t.Fatalf("Get(%s) error = %v", dn, err)
+	}
+	return entry
+}
+
+func TestNewMigrationPlan(t *testing.T) {
+	v1, v2 := loadMigrationSchemas(t)
+
+	plan, err := NewMigrationPlan(v1, v2)
+	if err != nil {
+		t.Fatalf("NewMigrationPlan() error = %v", err)
+	}
+
+	want := []string{
+		"RenameAttributeType title -> jobTitle",
+		"AddAttributeType employeeType",
+		"AddMustAttribute person MUST employeeType",
+	}
+	if len(plan.Steps) != len(want) {
+		t.Fatalf("got %d steps %v, want %d", len(plan.Steps), plan.Steps, len(want))
+	}
+	for i, step := range plan.Steps {
+		if step.String() != want[i] {
+			t.Errorf("step %d = %q, want %q", i, step.String(), want[i])
+		}
+	}
+
+	plan, err = NewMigrationPlan(v1, v1)
+	if err != nil {
+		t.Fatalf("NewMigrationPlan() error = %v", err)
+	}
+	if len(plan.Steps) != 0 {
+		t.Errorf("identical schemas produced steps %v", plan.Steps)
+	}
+
+	if _, err := NewMigrationPlan(nil, v2); !errors.Is(err, ErrNilSchema) {
+		t.Errorf("NewMigrationPlan(nil) error = %v, want ErrNilSchema", err)
+	}
+}
+
+func TestMigrationPlanReverse(t *testing.T) {
+	v1, v2 := loadMigrationSchemas(t)
+
+	plan, err := NewMigrationPlan(v2, v1)
+	if err != nil {
+		t.Fatalf("NewMigrationPlan() error = %v", err)
+	}
+
+	want := []string{
+		"RenameAttributeType jobTitle -> title",
+		"RemoveAttributeType employeeType",
+	}
+	if len(plan.Steps) != len(want) {
+		t.Fatalf("got %d steps %v, want %d", len(plan.Steps), plan.Steps, len(want))
+	}
+	for i, step := range plan.Steps {
+		if step.String() != want[i] {
+			t.Errorf("step %d = %q, want %q", i, step.String(), want[i])
+		}
+	}
+}
+
+func TestMigrationPlanApply(t *testing.T) {
+	v1, v2 := loadMigrationSchemas(t)
+	db := newMigrationTestEngine(t)
+
+	plan, err := NewMigrationPlan(v1, v2)
+	if err != nil {
+		t.Fatalf("NewMigrationPlan() error = %v", err)
+	}
+	plan.SetDefault("employeeType", "staff")
+
+	stats, err := plan.Apply(db)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if stats.ScannedEntries != 3 {
+		t.Errorf("ScannedEntries = %d, want 3", stats.ScannedEntries)
+	}
+	if stats.UpdatedEntries != 2 {
+		t.Errorf("UpdatedEntries = %d, want 2", stats.UpdatedEntries)
+	}
+	if len(stats.Violations) != 0 {
+		t.Errorf("Violations = %v, want none", stats.Violations)
+	}
+
+	alice := getMigratedEntry(t, db, "uid=alice,ou=users,dc=example,dc=com")
+	if alice.HasAttribute("title") {
+		t.Error("alice still has title after rename")
+	}
+	if got := alice.GetAttribute("jobtitle"); len(got) != 1 || string(got[0]) != "Engineer" {
+		t.Errorf("alice jobTitle = %q, want Engineer", got)
+	}
+	if got := alice.GetAttribute("employeetype"); len(got) != 1 || string(got[0]) != "staff" {
+		t.Errorf("alice employeeType = %q, want staff", got)
+	}
+
+	ou := getMigratedEntry(t, db, "ou=users,dc=example,dc=com")
+	if ou.HasAttribute("employeetype") {
+		t.Error("organizationalUnit was given employeeType")
+	}
+
+	// A second run finds nothing left to do.
+	stats, err = plan.Apply(db)
+	if err != nil {
+		t.Fatalf("second Apply() error = %v", err)
+	}
+	if stats.UpdatedEntries != 0 {
+		t.Errorf("second UpdatedEntries = %d, want 0", stats.UpdatedEntries)
+	}
+}
+
+func TestMigrationPlanApplyMissingDefault(t *testing.T) {
+	v1, v2 := loadMigrationSchemas(t)
+	db := newMigrationTestEngine(t)
+
+	plan, err := NewMigrationPlan(v1, v2)
+	if err != nil {
+		t.Fatalf("NewMigrationPlan() error = %v", err)
+	}
+
+	stats, err := plan.Apply(db)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if len(stats.Violations) != 2 {
+		t.Fatalf("got %d violations, want 2", len(stats.Violations))
+	}
+	for _, v := range stats.Violations {
+		if !strings.Contains(v.Reason, "employeeType") {
+			t.Errorf("violation for %s = %q, want missing employeeType", v.DN, v.Reason)
+		}
+	}
+
+	// The rename still applies to entries that violate the new schema.
+	alice := getMigratedEntry(t, db, "uid=alice,ou=users,dc=example,dc=com")
+	if !alice.HasAttribute("jobtitle") {
+		t.Error("alice jobTitle missing")
+	}
+	if alice.HasAttribute("employeetype") {
+		t.Error("alice was given employeeType without a default")
+	}
+}
+
+func TestLoadVersion(t *testing.T) {
+	s, err := LoadVersion(CurrentVersion)
+	if err != nil {
+		t.Fatalf("LoadVersion(%d) error = %v", CurrentVersion, err)
+	}
+	if s.GetObjectClass("inetOrgPerson") == nil {
+		t.Error("current schema has no inetOrgPerson")
+	}
+
+	if _, err := LoadVersion(0); !errors.Is(err, ErrUnknownSchemaVersion) {
+		t.Errorf("LoadVersion(0) error = %v, want ErrUnknownSchemaVersion", err)
+	}
+
+	versions := Versions()
+	if len(versions) == 0 || versions[len(versions)-1] != CurrentVersion {
+		t.Errorf("Versions() = %v, want to end with %d", versions, CurrentVersion)
+	}
+}
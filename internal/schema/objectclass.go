@@ -39,15 +39,16 @@ func (k ObjectClassKind) String() string {
 // Object classes define the set of attributes that entries of that class
 // must have (MUST) and may have (MAY).
 type ObjectClass struct {
-	OID      string          // Object Identifier (e.g., "2.5.6.6")
-	Name     string          // Primary name (e.g., "person")
-	Names    []string        // All names including aliases
-	Desc     string          // Human-readable description
-	Obsolete bool            // Whether this object class is obsolete
-	Superior string          // Parent object class name or OID
-	Kind     ObjectClassKind // Abstract, Structural, or Auxiliary
-	Must     []string        // Required attribute names
-	May      []string        // Optional attribute names
+	OID        string              // Object Identifier (e.g., "2.5.6.6")
+	Name       string              // Primary name (e.g., "person")
+	Names      []string            // All names including aliases
+	Desc       string              // Human-readable description
+	Obsolete   bool                // Whether this object class is obsolete
+	Superior   string              // Parent object class name or OID
+	Kind       ObjectClassKind     // Abstract, Structural, or Auxiliary
+	Must       []string            // Required attribute names
+	May        []string            // Optional attribute names
+	Extensions map[string][]string // X- extensions (e.g., X-ORIGIN)
 }
 
 // NewObjectClass creates a new ObjectClass with the given OID and name.
@@ -2,6 +2,7 @@ package schema
 
 import (
 	"errors"
+	"strconv"
 	"strings"
 )
 
@@ -14,26 +15,14 @@ var (
 	ErrUnterminatedParens   = errors.New("unterminated parentheses")
 )
 
-// parseObjectClass parses an LDAP object class definition string.
-// Format: ( OID NAME 'name' SUP superior KIND MUST (attr1 $ attr2) MAY (attr3) )
-func parseObjectClass(s string) (*ObjectClass, error) {
-	s = strings.TrimSpace(s)
-	if len(s) < 2 || s[0] != '(' || s[len(s)-1] != ')' {
-		return nil, ErrInvalidObjectClass
-	}
-
-	// Remove outer parentheses
-	s = strings.TrimSpace(s[1 : len(s)-1])
-
-	tokens, err := tokenize(s)
+// ParseObjectClassDescription parses an RFC 4512 ObjectClassDescription.
+// Format: ( OID NAME 'name' DESC 'text' SUP superior KIND MUST ( attr1 $ attr2 ) MAY attr3 X-ORIGIN 'text' )
+func ParseObjectClassDescription(s string) (*ObjectClass, error) {
+	tokens, err := tokenizeDescription(s, ErrInvalidObjectClass)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(tokens) == 0 {
-		return nil, ErrMissingOID
-	}
-
 	oc := &ObjectClass{
 		OID:  tokens[0],
 		Kind: ObjectClassStructural, // Default kind
@@ -60,7 +49,7 @@ func parseObjectClass(s string) (*ObjectClass, error) {
 			if i >= len(tokens) {
 				return nil, ErrInvalidObjectClass
 			}
-			oc.Desc = unquote(tokens[i])
+			oc.Desc = unquoteDescription(tokens[i])
 		case "OBSOLETE":
 			oc.Obsolete = true
 		case "SUP":
@@ -68,7 +57,9 @@ func parseObjectClass(s string) (*ObjectClass, error) {
 			if i >= len(tokens) {
 				return nil, ErrInvalidObjectClass
 			}
-			oc.Superior = unquote(tokens[i])
+			if sups := parseAttributeList(tokens[i]); len(sups) > 0 {
+				oc.Superior = sups[0]
+			}
 		case "ABSTRACT":
 			oc.Kind = ObjectClassAbstract
 		case "STRUCTURAL":
@@ -87,6 +78,14 @@ func parseObjectClass(s string) (*ObjectClass, error) {
 				return nil, ErrInvalidObjectClass
 			}
 			oc.May = parseAttributeList(tokens[i])
+		default:
+			if strings.HasPrefix(keyword, "X-") {
+				i++
+				if i >= len(tokens) {
+					return nil, ErrInvalidObjectClass
+				}
+				oc.Extensions = addExtension(oc.Extensions, tokens[i-1], tokens[i])
+			}
 		}
 		i++
 	}
@@ -94,26 +93,14 @@ func parseObjectClass(s string) (*ObjectClass, error) {
 	return oc, nil
 }
 
-// parseAttributeType parses an LDAP attribute type definition string.
-// Format: ( OID NAME 'name' SYNTAX syntaxOID SINGLE-VALUE ... )
-func parseAttributeType(s string) (*AttributeType, error) {
-	s = strings.TrimSpace(s)
-	if len(s) < 2 || s[0] != '(' || s[len(s)-1] != ')' {
-		return nil, ErrInvalidAttributeType
-	}
-
-	// Remove outer parentheses
-	s = strings.TrimSpace(s[1 : len(s)-1])
-
-	tokens, err := tokenize(s)
+// ParseAttributeTypeDescription parses an RFC 4512 AttributeTypeDescription.
+// Format: ( OID NAME 'name' SUP superior EQUALITY rule SYNTAX oid{len} SINGLE-VALUE USAGE usage X-ORIGIN 'text' )
+func ParseAttributeTypeDescription(s string) (*AttributeType, error) {
+	tokens, err := tokenizeDescription(s, ErrInvalidAttributeType)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(tokens) == 0 {
-		return nil, ErrMissingOID
-	}
-
 	at := &AttributeType{
 		OID:   tokens[0],
 		Usage: UserApplications, // Default usage
@@ -138,7 +125,7 @@ func parseAttributeType(s string) (*AttributeType, error) {
 			if i >= len(tokens) {
 				return nil, ErrInvalidAttributeType
 			}
-			at.Desc = unquote(tokens[i])
+			at.Desc = unquoteDescription(tokens[i])
 		case "OBSOLETE":
 			at.Obsolete = true
 		case "SUP":
@@ -172,6 +159,11 @@ func parseAttributeType(s string) (*AttributeType, error) {
 			}
 			// Syntax may include length constraint like "1.3.6.1.4.1.1466.115.121.1.15{256}"
 			at.Syntax = parseSyntaxOID(tokens[i])
+			length, err := parseSyntaxLength(tokens[i])
+			if err != nil {
+				return nil, err
+			}
+			at.SyntaxLength = length
 		case "SINGLE-VALUE":
 			at.SingleValue = true
 		case "COLLECTIVE":
@@ -184,6 +176,14 @@ func parseAttributeType(s string) (*AttributeType, error) {
 				return nil, ErrInvalidAttributeType
 			}
 			at.Usage = parseUsage(tokens[i])
+		default:
+			if strings.HasPrefix(keyword, "X-") {
+				i++
+				if i >= len(tokens) {
+					return nil, ErrInvalidAttributeType
+				}
+				at.Extensions = addExtension(at.Extensions, tokens[i-1], tokens[i])
+			}
 		}
 		i++
 	}
@@ -191,6 +191,42 @@ func parseAttributeType(s string) (*AttributeType, error) {
 	return at, nil
 }
 
+// tokenizeDescription strips the outer parentheses of a definition and
+// splits it into tokens. The first token is the OID.
+func tokenizeDescription(s string, errInvalid error) ([]string, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '(' || s[len(s)-1] != ')' {
+		return nil, errInvalid
+	}
+
+	// Remove outer parentheses
+	s = strings.TrimSpace(s[1 : len(s)-1])
+
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, ErrMissingOID
+	}
+
+	return tokens, nil
+}
+
+// addExtension records the values of an X- extension.
+func addExtension(ext map[string][]string, name, value string) map[string][]string {
+	if ext == nil {
+		ext = make(map[string][]string)
+	}
+	values := parseNames(value)
+	for i, v := range values {
+		values[i] = unescapeQDString(v)
+	}
+	ext[strings.ToUpper(name)] = values
+	return ext
+}
+
 // tokenize splits a schema definition into tokens, handling quoted strings and parentheses.
 func tokenize(s string) ([]string, error) {
 	var tokens []string
@@ -325,6 +361,49 @@ func parseSyntaxOID(s string) string {
 	return s
 }
 
+// parseSyntaxLength extracts the length bound from a syntax specification
+// such as "1.3.6.1.4.1.1466.115.121.1.15{256}". It returns 0 if there is none.
+func parseSyntaxLength(s string) (int, error) {
+	s = unquote(s)
+	idx := strings.Index(s, "{")
+	if idx == -1 {
+		return 0, nil
+	}
+	if !strings.HasSuffix(s, "}") {
+		return 0, ErrInvalidAttributeType
+	}
+	length, err := strconv.Atoi(s[idx+1 : len(s)-1])
+	if err != nil || length < 0 {
+		return 0, ErrInvalidAttributeType
+	}
+	return length, nil
+}
+
+// unquoteDescription removes the quotes of a qdstring and decodes its
+// \27 and \5C escapes.
+func unquoteDescription(s string) string {
+	return unescapeQDString(unquote(s))
+}
+
+// unescapeQDString decodes the \27 (quote) and \5C (backslash) escapes of an
+// RFC 4512 qdstring.
+func unescapeQDString(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	r := strings.NewReplacer("\\27", "'", "\\5C", "\\", "\\5c", "\\")
+	return r.Replace(s)
+}
+
+// escapeQDString encodes quotes and backslashes for use in an RFC 4512 qdstring.
+func escapeQDString(s string) string {
+	if !strings.ContainsAny(s, "'\\") {
+		return s
+	}
+	r := strings.NewReplacer("\\", "\\5C", "'", "\\27")
+	return r.Replace(s)
+}
+
 // parseUsage parses an attribute usage value.
 func parseUsage(s string) AttributeUsage {
 	switch strings.ToLower(unquote(s)) {
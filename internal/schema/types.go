@@ -2,6 +2,8 @@
 // attribute types, syntaxes, and matching rules.
 package schema
 
+import "strings"
+
 // Schema represents the complete LDAP schema containing all definitions
 // for object classes, attribute types, syntaxes, and matching rules.
 type Schema struct {
@@ -9,6 +11,10 @@ type Schema struct {
 	AttributeTypes map[string]*AttributeType
 	Syntaxes       map[string]*Syntax
 	MatchingRules  map[string]*MatchingRule
+
+	// attributeNames indexes the attribute types by the lowercased OID and
+	// every name, for ResolveAlias.
+	attributeNames map[string]*AttributeType
 }
 
 // NewSchema creates a new empty Schema with initialized maps.
@@ -18,6 +24,7 @@ func NewSchema() *Schema {
 		AttributeTypes: make(map[string]*AttributeType),
 		Syntaxes:       make(map[string]*Syntax),
 		MatchingRules:  make(map[string]*MatchingRule),
+		attributeNames: make(map[string]*AttributeType),
 	}
 }
 
@@ -42,7 +49,7 @@ func NewMatchingRule(oid, name string) *MatchingRule {
 }
 
 // GetObjectClass retrieves an object class by name or OID.
-// Returns nil if not found.
+// Names are matched case-insensitively. Returns nil if not found.
 func (s *Schema) GetObjectClass(nameOrOID string) *ObjectClass {
 	if oc, ok := s.ObjectClasses[nameOrOID]; ok {
 		return oc
@@ -50,7 +57,7 @@ func (s *Schema) GetObjectClass(nameOrOID string) *ObjectClass {
 	// Search by alias
 	for _, oc := range s.ObjectClasses {
 		for _, alias := range oc.Names {
-			if alias == nameOrOID {
+			if strings.EqualFold(alias, nameOrOID) {
 				return oc
 			}
 		}
@@ -59,15 +66,18 @@ func (s *Schema) GetObjectClass(nameOrOID string) *ObjectClass {
 }
 
 // GetAttributeType retrieves an attribute type by name or OID.
-// Returns nil if not found.
+// Names are matched case-insensitively. Attribute options are ignored, so
+// "description;lang-de" returns the description type. Returns nil if not
+// found.
 func (s *Schema) GetAttributeType(nameOrOID string) *AttributeType {
+	nameOrOID = AttributeTypeName(nameOrOID)
 	if at, ok := s.AttributeTypes[nameOrOID]; ok {
 		return at
 	}
 	// Search by alias
 	for _, at := range s.AttributeTypes {
 		for _, alias := range at.Names {
-			if alias == nameOrOID {
+			if strings.EqualFold(alias, nameOrOID) {
 				return at
 			}
 		}
@@ -75,6 +85,15 @@ func (s *Schema) GetAttributeType(nameOrOID string) *AttributeType {
 	return nil
 }
 
+// AttributeTypeName returns the attribute type of an attribute description
+// without its options, as in "description" for "description;lang-de".
+func AttributeTypeName(description string) string {
+	if i := strings.IndexByte(description, ';'); i >= 0 {
+		return description[:i]
+	}
+	return description
+}
+
 // GetSyntax retrieves a syntax by OID.
 // Returns nil if not found.
 func (s *Schema) GetSyntax(oid string) *Syntax {
@@ -82,7 +101,7 @@ func (s *Schema) GetSyntax(oid string) *Syntax {
 }
 
 // GetMatchingRule retrieves a matching rule by name or OID.
-// Returns nil if not found.
+// Names are matched case-insensitively. Returns nil if not found.
 func (s *Schema) GetMatchingRule(nameOrOID string) *MatchingRule {
 	if mr, ok := s.MatchingRules[nameOrOID]; ok {
 		return mr
@@ -90,7 +109,7 @@ func (s *Schema) GetMatchingRule(nameOrOID string) *MatchingRule {
 	// Search by alias
 	for _, mr := range s.MatchingRules {
 		for _, alias := range mr.Names {
-			if alias == nameOrOID {
+			if strings.EqualFold(alias, nameOrOID) {
 				return mr
 			}
 		}
@@ -118,6 +137,27 @@ func (s *Schema) AddAttributeType(at *AttributeType) {
 	if at.Name != "" {
 		s.AttributeTypes[at.Name] = at
 	}
+
+	if s.attributeNames == nil {
+		s.attributeNames = make(map[string]*AttributeType)
+	}
+	if at.OID != "" {
+		s.attributeNames[strings.ToLower(at.OID)] = at
+	}
+	if at.Name != "" {
+		s.attributeNames[strings.ToLower(at.Name)] = at
+	}
+	for _, name := range at.Names {
+		s.attributeNames[strings.ToLower(name)] = at
+	}
+}
+
+// ResolveAlias returns the attribute type that name denotes by any of its
+// names or its OID, ignoring case and attribute options: "commonName" and
+// "CN;lang-en" both resolve to cn. It returns nil if no attribute type
+// added to the schema has the name.
+func (s *Schema) ResolveAlias(name string) *AttributeType {
+	return s.attributeNames[strings.ToLower(AttributeTypeName(name))]
 }
 
 // AddSyntax adds a syntax to the schema by its OID.
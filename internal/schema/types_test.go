@@ -172,3 +172,25 @@ func TestSchemaEmptyOIDOrName(t *testing.T) {
 		t.Error("should not add syntax with empty OID")
 	}
 }
+
+func TestSchemaResolveAlias(t *testing.T) {
+	s := NewSchema()
+	at := &AttributeType{OID: "2.5.4.3", Name: "cn", Names: []string{"cn", "commonName"}}
+	s.AddAttributeType(at)
+
+	tests := []string{"cn", "commonName", "COMMONNAME", "cn;lang-en", "commonName;binary", "2.5.4.3"}
+	for _, name := range tests {
+		if got := s.ResolveAlias(name); got != at {
+			t.Errorf("ResolveAlias(%q) = %v, want cn", name, got)
+		}
+	}
+
+	if got := s.ResolveAlias("sn"); got != nil {
+		t.Errorf("ResolveAlias(sn) = %v, want nil", got)
+	}
+
+	aliases := at.Aliases()
+	if len(aliases) != 1 || aliases[0] != "commonName" {
+		t.Errorf("Aliases() = %v, want [commonName]", aliases)
+	}
+}
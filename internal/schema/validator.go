@@ -174,15 +174,31 @@ func NewStringModification(modType ModificationType, attr string, values ...stri
 // Validator validates LDAP entries against a schema.
 type Validator struct {
 	schema *Schema
+	strict bool
 }
 
 // NewValidator creates a new Validator with the given schema.
+// The validator starts in strict mode.
 func NewValidator(schema *Schema) *Validator {
 	return &Validator{
 		schema: schema,
+		strict: true,
 	}
 }
 
+// SetStrict controls how object classes that are not defined in the schema
+// are handled. In strict mode entries using them are rejected. Otherwise the
+// unknown classes are ignored and, since their attributes cannot be checked,
+// the entry may hold any attribute.
+func (v *Validator) SetStrict(strict bool) {
+	v.strict = strict
+}
+
+// IsStrict returns true if entries with undefined object classes are rejected.
+func (v *Validator) IsStrict() bool {
+	return v.strict
+}
+
 // ValidateEntry validates an entry against the schema.
 // It checks:
 // 1. Entry must have objectClass attribute
@@ -197,7 +213,7 @@ func (v *Validator) ValidateEntry(entry *Entry) error {
 	}
 
 	// 1. Get all object classes
-	classes := entry.GetAll("objectClass")
+	classes := v.getAttributeCaseInsensitive(entry, "objectclass")
 	if len(classes) == 0 {
 		return NewValidationError(ErrObjectClassViolation, "objectClass required")
 	}
@@ -206,11 +222,22 @@ func (v *Validator) ValidateEntry(entry *Entry) error {
 	must := make(map[string]bool)
 	may := make(map[string]bool)
 	hasStructural := false
+	// extensible is set when the entry may hold attributes outside MUST and MAY
+	extensible := false
+	unknownClass := false
 
 	for _, className := range classes {
 		oc := v.schema.GetObjectClass(className)
 		if oc == nil {
-			return NewValidationErrorWithAttr(ErrObjectClassViolation, "unknown objectClass", className)
+			if v.strict {
+				return NewValidationErrorWithAttr(ErrObjectClassViolation, "unknown objectClass", className)
+			}
+			unknownClass = true
+			extensible = true
+			continue
+		}
+		if strings.EqualFold(oc.Name, "extensibleObject") {
+			extensible = true
 		}
 
 		// 2. Check for at least one structural object class
@@ -229,8 +256,9 @@ func (v *Validator) ValidateEntry(entry *Entry) error {
 		}
 	}
 
-	// 2. At least one structural object class required
-	if !hasStructural {
+	// 2. At least one structural object class required. An unknown class
+	// accepted in non-strict mode may be the structural one.
+	if !hasStructural && !unknownClass {
 		return NewValidationError(ErrObjectClassViolation, "at least one structural objectClass required")
 	}
 
@@ -241,9 +269,10 @@ func (v *Validator) ValidateEntry(entry *Entry) error {
 		}
 	}
 
-	// 4. Check all attributes are allowed
+	// 4. Check all attributes are allowed. Variants with options, such as
+	// description;lang-de, are allowed when their base attribute is.
 	for attr := range entry.Attributes {
-		attrLower := strings.ToLower(attr)
+		attrLower := strings.ToLower(AttributeTypeName(attr))
 
 		// Skip objectClass - it's always allowed
 		if attrLower == "objectclass" {
@@ -251,7 +280,7 @@ func (v *Validator) ValidateEntry(entry *Entry) error {
 		}
 
 		// Check if attribute is allowed by MUST or MAY
-		if !must[attrLower] && !may[attrLower] {
+		if !must[attrLower] && !may[attrLower] && !extensible {
 			// Check if it's an operational attribute
 			if !v.isOperational(attr) {
 				return NewValidationErrorWithAttr(ErrUndefinedAttributeType, "attribute not allowed by objectClass", attr)
@@ -367,9 +396,10 @@ func (v *Validator) isOperational(attr string) bool {
 }
 
 // hasAttributeCaseInsensitive checks if the entry has an attribute (case-insensitive).
+// A variant of the attribute with options also counts.
 func (v *Validator) hasAttributeCaseInsensitive(entry *Entry, attrLower string) bool {
 	for attr := range entry.Attributes {
-		if strings.ToLower(attr) == attrLower {
+		if strings.ToLower(AttributeTypeName(attr)) == attrLower {
 			values := entry.Attributes[attr]
 			if len(values) > 0 {
 				return true
@@ -379,6 +409,19 @@ func (v *Validator) hasAttributeCaseInsensitive(entry *Entry, attrLower string)
 	return false
 }
 
+// getAttributeCaseInsensitive returns the string values of an attribute (case-insensitive).
+func (v *Validator) getAttributeCaseInsensitive(entry *Entry, attrLower string) []string {
+	var result []string
+	for attr, values := range entry.Attributes {
+		if strings.ToLower(attr) == attrLower {
+			for _, value := range values {
+				result = append(result, string(value))
+			}
+		}
+	}
+	return result
+}
+
 // validateAttributeSyntax validates attribute values against their syntax.
 func (v *Validator) validateAttributeSyntax(attr string, values [][]byte) error {
 	// Get the effective syntax for this attribute
@@ -160,6 +160,42 @@ func TestValidateEntry_UnknownObjectClass(t *testing.T) {
 	}
 }
 
+func TestValidateEntry_UnknownObjectClassNonStrict(t *testing.T) {
+	s := setupTestSchema()
+	v := NewValidator(s)
+	v.SetStrict(false)
+
+	entry := NewEntry("cn=test,dc=example,dc=com")
+	entry.SetStringAttribute("objectClass", "person", "unknownClass")
+	entry.SetStringAttribute("cn", "test")
+	entry.SetStringAttribute("sn", "test")
+	entry.SetStringAttribute("unknownAttr", "value")
+
+	if err := v.ValidateEntry(entry); err != nil {
+		t.Fatalf("expected unknown objectClass to be accepted, got %v", err)
+	}
+
+	// MUST attributes of known classes are still enforced
+	delete(entry.Attributes, "sn")
+	if err := v.ValidateEntry(entry); err == nil {
+		t.Fatal("expected error for missing sn")
+	}
+}
+
+func TestValidateEntry_CaseInsensitiveNames(t *testing.T) {
+	s := setupTestSchema()
+	v := NewValidator(s)
+
+	entry := NewEntry("cn=test,dc=example,dc=com")
+	entry.SetStringAttribute("objectclass", "PERSON", "top")
+	entry.SetStringAttribute("cn", "test")
+	entry.SetStringAttribute("sn", "test")
+
+	if err := v.ValidateEntry(entry); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 func TestValidateEntry_NoStructuralObjectClass(t *testing.T) {
 	s := setupTestSchema()
 	v := NewValidator(s)
@@ -284,6 +320,40 @@ func TestValidateEntry_ValidPerson(t *testing.T) {
 	}
 }
 
+func TestValidateEntry_AttributeOptions(t *testing.T) {
+	s := setupTestSchema()
+	v := NewValidator(s)
+
+	newEntry := func(attr string, value string) *Entry {
+		entry := NewEntry("cn=John Doe,dc=example,dc=com")
+		entry.SetStringAttribute("objectClass", "person")
+		entry.SetStringAttribute("cn", "John Doe")
+		entry.SetStringAttribute("sn", "Doe")
+		entry.SetStringAttribute(attr, value)
+		return entry
+	}
+
+	// A tagged variant is allowed wherever its base attribute is
+	if err := v.ValidateEntry(newEntry("description;lang-de", "Eine Person")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The base attribute's syntax applies to the variant
+	err := v.ValidateEntry(newEntry("description;lang-de", ""))
+	if ve, ok := err.(*ValidationError); !ok || ve.Code != ErrInvalidAttributeSyntax {
+		t.Errorf("expected ErrInvalidAttributeSyntax, got %v", err)
+	}
+
+	err = v.ValidateEntry(newEntry("unknownAttr;lang-de", "value"))
+	if ve, ok := err.(*ValidationError); !ok || ve.Code != ErrUndefinedAttributeType {
+		t.Errorf("expected ErrUndefinedAttributeType, got %v", err)
+	}
+
+	if at := s.GetAttributeType("Description;lang-de"); at == nil || at.Name != "description" {
+		t.Errorf("GetAttributeType with options = %v, want description", at)
+	}
+}
+
 func TestValidateEntry_ValidInetOrgPerson(t *testing.T) {
 	s := setupTestSchema()
 	v := NewValidator(s)
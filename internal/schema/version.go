@@ -0,0 +1,42 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// CurrentVersion is the version of the schema shipped with this release.
+const CurrentVersion = 1
+
+// ErrUnknownSchemaVersion is returned when a requested schema version does not exist.
+var ErrUnknownSchemaVersion = errors.New("unknown schema version")
+
+// versions maps each released schema version to a function that builds it.
+// A release that changes the shipped schema adds a new version here and
+// bumps CurrentVersion; earlier versions must stay loadable so databases can
+// be migrated from them.
+var versions = map[int]func() (*Schema, error){
+	1: func() (*Schema, error) {
+		return LoadBuiltinSchema(BuiltinCore, BuiltinCosine, BuiltinInetOrgPerson)
+	},
+}
+
+// LoadVersion returns the schema shipped with the given schema version.
+func LoadVersion(version int) (*Schema, error) {
+	build, ok := versions[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownSchemaVersion, version)
+	}
+	return build()
+}
+
+// Versions returns all known schema versions in ascending order.
+func Versions() []int {
+	list := make([]int, 0, len(versions))
+	for v := range versions {
+		list = append(list, v)
+	}
+	sort.Ints(list)
+	return list
+}
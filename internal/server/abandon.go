@@ -4,6 +4,21 @@ package server
 import (
 	"context"
 	"sync"
+	"sync/atomic"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+)
+
+// Pending operation states.
+const (
+	// opRunning is an operation that has not sent its final response.
+	opRunning int32 = iota
+	// opSent is an operation that is sending or has sent its final response.
+	opSent
+	// opCanceled is an operation ended by a Cancel extended operation.
+	opCanceled
+	// opAbandoned is an operation ended by an Abandon request.
+	opAbandoned
 )
 
 // PendingOperation represents an operation that can be abandoned.
@@ -15,6 +30,27 @@ type PendingOperation struct {
 	Cancel context.CancelFunc
 	// Done is closed when the operation completes
 	Done chan struct{}
+	// state is one of the pending operation states
+	state atomic.Int32
+}
+
+// FinalResult is called by an operation before it sends its final
+// response, with the result code it would send. It returns the result
+// code to send, which is canceled if a Cancel extended operation ended
+// the operation, and false if the operation was abandoned and must not
+// respond at all. Once FinalResult returned, the operation can no longer
+// be canceled.
+func (op *PendingOperation) FinalResult(code ldap.ResultCode) (ldap.ResultCode, bool) {
+	if op.state.CompareAndSwap(opRunning, opSent) {
+		return code, true
+	}
+	switch op.state.Load() {
+	case opCanceled:
+		return ldap.ResultCanceled, true
+	case opAbandoned:
+		return code, false
+	}
+	return code, true
 }
 
 // AbandonHandler manages pending operations and handles abandon requests.
@@ -47,7 +83,7 @@ func (h *AbandonHandler) Handle(conn *Connection, messageID int) {
 	op, exists := h.pendingOps[messageID]
 	h.mu.RUnlock()
 
-	if exists {
+	if exists && op.state.CompareAndSwap(opRunning, opAbandoned) {
 		// Cancel the operation
 		op.Cancel()
 		// Note: We don't unregister here - the operation itself should
@@ -56,6 +92,31 @@ func (h *AbandonHandler) Handle(conn *Connection, messageID int) {
 	// No response is sent for Abandon requests per RFC 4511
 }
 
+// CancelOperation ends the operation with the given message ID on behalf
+// of a Cancel extended operation (RFC 3909). It waits until the operation
+// has sent its canceled response and returns success. It returns
+// noSuchOperation if no such operation is pending or it is already being
+// abandoned or canceled, and tooLate if it has sent its final response.
+func (h *AbandonHandler) CancelOperation(messageID int) ldap.ResultCode {
+	h.mu.RLock()
+	op, exists := h.pendingOps[messageID]
+	h.mu.RUnlock()
+
+	if !exists {
+		return ldap.ResultNoSuchOperation
+	}
+	if !op.state.CompareAndSwap(opRunning, opCanceled) {
+		if op.state.Load() == opSent {
+			return ldap.ResultTooLate
+		}
+		return ldap.ResultNoSuchOperation
+	}
+
+	op.Cancel()
+	<-op.Done
+	return ldap.ResultSuccess
+}
+
 // Register registers a pending operation with the given message ID.
 // The cancel function will be called if an abandon request is received
 // for this message ID.
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"crypto/tls"
+	"strings"
+
+	"github.com/KilimcininKorOglu/oba/internal/acl"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+)
+
+// ConnectionInfo returns the connection details used by ACL rules with
+// source, TLS, security strength or auth method constraints.
+func (c *Connection) ConnectionInfo() acl.ConnectionInfo {
+	state := c.loadState()
+
+	info := acl.ConnectionInfo{
+		RemoteIP:   ClientIP(c.RemoteAddr()),
+		TLS:        state.isTLS,
+		AuthMethod: state.authMethod,
+	}
+
+	// The handshake may complete after SetTLS, so read the state now
+	if tlsConn, ok := c.conn.(*tls.Conn); ok {
+		state := tlsConn.ConnectionState()
+		if state.HandshakeComplete {
+			info.TLS = true
+			info.SSF = cipherSuiteSSF(state.CipherSuite)
+		}
+	}
+
+	return info
+}
+
+// AccessContext returns an ACL access context for an operation on
+// targetDN by the bound user of this connection.
+func (c *Connection) AccessContext(targetDN string, operation acl.Right) *acl.AccessContext {
+	return acl.NewAccessContext(c.BindDN(), targetDN, operation).WithConnection(c.ConnectionInfo())
+}
+
+// accessContext returns conn.AccessContext, or an anonymous context
+// without connection details if conn is nil.
+func accessContext(conn *Connection, targetDN string, operation acl.Right) *acl.AccessContext {
+	if conn == nil {
+		return acl.NewAccessContext("", targetDN, operation)
+	}
+	return conn.AccessContext(targetDN, operation)
+}
+
+// bindAuthMethod returns the ACL auth method of a successful bind, or an
+// empty string for anonymous binds.
+func bindAuthMethod(req *ldap.BindRequest) string {
+	if req.IsAnonymous() {
+		return ""
+	}
+	if req.AuthMethod == ldap.AuthMethodSASL {
+		if req.SASLCredentials != nil && strings.EqualFold(req.SASLCredentials.Mechanism, "EXTERNAL") {
+			return acl.AuthMethodSASLExternal
+		}
+		return ""
+	}
+	return acl.AuthMethodSimple
+}
+
+// cipherSuiteSSF returns the security strength factor of a TLS cipher
+// suite, which is the key size in bits of its bulk cipher.
+func cipherSuiteSSF(id uint16) int {
+	name := tls.CipherSuiteName(id)
+	switch {
+	case strings.Contains(name, "AES_256"), strings.Contains(name, "CHACHA20"):
+		return 256
+	case strings.Contains(name, "AES_128"):
+		return 128
+	case strings.Contains(name, "3DES"):
+		return 112
+	case strings.Contains(name, "RC4"):
+		return 64
+	default:
+		return 1
+	}
+}
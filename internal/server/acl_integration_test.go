@@ -2,6 +2,9 @@
 package server
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
 	"net"
 	"testing"
 
@@ -26,8 +29,10 @@ func (m *aclTestConn) LocalAddr() net.Addr {
 // createACLTestConnection creates a test connection with the given bind DN.
 func createACLTestConnection(bindDN string) *Connection {
 	conn := NewConnection(&aclTestConn{}, nil)
-	conn.bindDN = bindDN
-	conn.authenticated = bindDN != ""
+	conn.updateState(func(s *connState) {
+		s.bindDN = bindDN
+		s.authenticated = bindDN != ""
+	})
 	return conn
 }
 
@@ -265,6 +270,122 @@ func TestDeleteHandler_ACL_DeletePermission(t *testing.T) {
 	}
 }
 
+// TestDeleteHandler_ACL_ConnectionConstraints tests that handlers evaluate
+// rules against the client address, TLS state and bind method.
+func TestDeleteHandler_ACL_ConnectionConstraints(t *testing.T) {
+	adminDN := "cn=admin,dc=example,dc=com"
+
+	tests := []struct {
+		name         string
+		rule         *acl.ACL
+		authMethod   string
+		expectedCode ldap.ResultCode
+	}{
+		{
+			name:         "source network matches",
+			rule:         acl.NewACL("*", adminDN, acl.All).WithSourceCIDRs("127.0.0.0/8"),
+			authMethod:   acl.AuthMethodSimple,
+			expectedCode: ldap.ResultSuccess,
+		},
+		{
+			name:         "source network does not match",
+			rule:         acl.NewACL("*", adminDN, acl.All).WithSourceCIDRs("10.0.0.0/8"),
+			authMethod:   acl.AuthMethodSimple,
+			expectedCode: ldap.ResultInsufficientAccessRights,
+		},
+		{
+			name:         "TLS required on cleartext connection",
+			rule:         acl.NewACL("*", adminDN, acl.All).WithRequireTLS(true),
+			authMethod:   acl.AuthMethodSimple,
+			expectedCode: ldap.ResultInsufficientAccessRights,
+		},
+		{
+			name:         "auth method matches",
+			rule:         acl.NewACL("*", adminDN, acl.All).WithAuthMethod(acl.AuthMethodSimple),
+			authMethod:   acl.AuthMethodSimple,
+			expectedCode: ldap.ResultSuccess,
+		},
+		{
+			name:         "auth method does not match",
+			rule:         acl.NewACL("*", adminDN, acl.All).WithAuthMethod(acl.AuthMethodSASLExternal),
+			authMethod:   acl.AuthMethodSimple,
+			expectedCode: ldap.ResultInsufficientAccessRights,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aclConfig := acl.NewConfig()
+			aclConfig.SetDefaultPolicy("deny")
+			aclConfig.AddRule(tt.rule)
+
+			backend := newMockDeleteBackend()
+			entry := storage.NewEntry("uid=alice,ou=users,dc=example,dc=com")
+			entry.SetStringAttribute("objectclass", "person")
+			backend.addEntry(entry)
+
+			handler := NewDeleteHandler(&DeleteConfig{
+				Backend:      backend,
+				ACLEvaluator: acl.NewEvaluator(aclConfig),
+			})
+
+			conn := createACLTestConnection(adminDN)
+			conn.updateState(func(s *connState) {
+				s.authMethod = tt.authMethod
+			})
+			req := &ldap.DeleteRequest{
+				DN: "uid=alice,ou=users,dc=example,dc=com",
+			}
+
+			result := handler.Handle(conn, req)
+
+			if result.ResultCode != tt.expectedCode {
+				t.Errorf("Handle() ResultCode = %v, want %v", result.ResultCode, tt.expectedCode)
+			}
+		})
+	}
+}
+
+// TestBindAuthMethod tests the ACL auth method recorded for binds.
+func TestBindAuthMethod(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ldap.BindRequest
+		want string
+	}{
+		{"anonymous", &ldap.BindRequest{AuthMethod: ldap.AuthMethodSimple}, ""},
+		{"simple", &ldap.BindRequest{Name: "cn=admin", AuthMethod: ldap.AuthMethodSimple, SimplePassword: []byte("secret")}, acl.AuthMethodSimple},
+		{"sasl external", &ldap.BindRequest{AuthMethod: ldap.AuthMethodSASL, SASLCredentials: &ldap.SASLCredentials{Mechanism: "EXTERNAL"}}, acl.AuthMethodSASLExternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bindAuthMethod(tt.req); got != tt.want {
+				t.Errorf("bindAuthMethod() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCipherSuiteSSF tests the security strength factor of TLS cipher suites.
+func TestCipherSuiteSSF(t *testing.T) {
+	tests := []struct {
+		suite uint16
+		want  int
+	}{
+		{tls.TLS_AES_256_GCM_SHA384, 256},
+		{tls.TLS_CHACHA20_POLY1305_SHA256, 256},
+		{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, 128},
+		{tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA, 112},
+	}
+
+	for _, tt := range tests {
+		if got := cipherSuiteSSF(tt.suite); got != tt.want {
+			t.Errorf("cipherSuiteSSF(%s) = %d, want %d", tls.CipherSuiteName(tt.suite), got, tt.want)
+		}
+	}
+}
+
 // TestModifyHandler_ACL_WritePermission tests that modify checks write permission.
 func TestModifyHandler_ACL_WritePermission(t *testing.T) {
 	// Create ACL config
@@ -466,3 +587,141 @@ func TestModifyHandler_ACL_NilEvaluator(t *testing.T) {
 		t.Errorf("Handle() ResultCode = %v, want %v", result.ResultCode, ldap.ResultSuccess)
 	}
 }
+
+// TestAttributeACLFilter tests masking of individual attributes.
+func TestAttributeACLFilter(t *testing.T) {
+	aclConfig := acl.NewConfig()
+	aclConfig.SetDefaultPolicy("allow")
+	aclConfig.AddRule(acl.NewACL("*", "anonymous", acl.Read).WithAttributes("userPassword").WithDeny(true))
+	evaluator := acl.NewEvaluator(aclConfig)
+
+	entry := &SearchEntry{
+		DN: "uid=alice,ou=users,dc=example,dc=com",
+		Attributes: []ldap.Attribute{
+			{Type: "uid", Values: [][]byte{[]byte("alice")}},
+			{Type: "userpassword", Values: [][]byte{[]byte("secret")}},
+			{Type: "userPassword;binary", Values: [][]byte{[]byte("secret")}},
+		},
+	}
+	filter := NewAttributeACLFilter(entry, evaluator)
+
+	anonymous := filter.Filter(acl.AccessContext{TargetDN: entry.DN, Operation: acl.Read})
+	if len(anonymous.Attributes) != 1 || anonymous.Attributes[0].Type != "uid" {
+		t.Errorf("anonymous attributes = %v, want only uid", anonymous.Attributes)
+	}
+
+	bound := filter.Filter(acl.AccessContext{BindDN: "uid=bob,ou=users,dc=example,dc=com", TargetDN: entry.DN, Operation: acl.Read})
+	if len(bound.Attributes) != 3 {
+		t.Errorf("bound attributes = %v, want all 3", bound.Attributes)
+	}
+
+	if len(entry.Attributes) != 3 {
+		t.Error("Filter() modified the original entry")
+	}
+}
+
+// TestConnectionSearch_AttributeACL tests that search results are masked
+// before they are sent to the client.
+func TestConnectionSearch_AttributeACL(t *testing.T) {
+	aclConfig := acl.NewConfig()
+	aclConfig.SetDefaultPolicy("allow")
+	aclConfig.AddRule(acl.NewACL("*", "anonymous", acl.Read).WithAttributes("userPassword").WithDeny(true))
+
+	handler := NewHandler()
+	handler.SetSearchHandler(func(_ *Connection, req *ldap.SearchRequest) *SearchResult {
+		return &SearchResult{
+			OperationResult: OperationResult{ResultCode: ldap.ResultSuccess},
+			Entries: []*SearchEntry{{
+				DN: req.BaseObject,
+				Attributes: []ldap.Attribute{
+					{Type: "cn", Values: [][]byte{[]byte("Alice Smith")}},
+					{Type: "userPassword", Values: [][]byte{[]byte("{SSHA}hash")}},
+				},
+			}},
+		}
+	})
+	handler.SetAttributeACL(acl.NewEvaluator(aclConfig))
+
+	for _, bindDN := range []string{"", "cn=admin,dc=example,dc=com"} {
+		mock := newMockConn()
+		conn := NewConnection(mock, &Server{Handler: handler})
+		conn.updateState(func(s *connState) {
+			s.bindDN = bindDN
+		})
+
+		conn.handleSearch(createSearchRequest(1, "uid=alice,ou=users,dc=example,dc=com", ldap.ScopeBaseObject))
+
+		out := mock.writeBuf.Bytes()
+		if !bytes.Contains(out, []byte("Alice Smith")) {
+			t.Errorf("bindDN %q: cn missing from result", bindDN)
+		}
+		leaked := bytes.Contains(out, []byte("{SSHA}hash"))
+		if bindDN == "" && leaked {
+			t.Error("userPassword returned to anonymous bind")
+		}
+		if bindDN != "" && !leaked {
+			t.Error("userPassword withheld from bound user")
+		}
+	}
+}
+
+// collectiveSource is a CollectiveAttributeSource returning the same
+// collective attributes for every entry.
+type collectiveSource map[string][][]byte
+
+func (s collectiveSource) CollectiveAttributes(ctx context.Context, dns []string) []map[string][][]byte {
+	result := make([]map[string][][]byte, len(dns))
+	for i := range dns {
+		result[i] = s
+	}
+	return result
+}
+
+// TestConnectionSearch_CollectiveAttributes tests that collective attributes
+// are merged after attribute masking and can be turned off with a control.
+func TestConnectionSearch_CollectiveAttributes(t *testing.T) {
+	aclConfig := acl.NewConfig()
+	aclConfig.SetDefaultPolicy("allow")
+	aclConfig.AddRule(acl.NewACL("*", "anonymous", acl.Read).WithAttributes("telephoneNumber").WithDeny(true))
+
+	handler := NewHandler()
+	handler.SetSearchHandler(func(_ *Connection, req *ldap.SearchRequest) *SearchResult {
+		return &SearchResult{
+			OperationResult: OperationResult{ResultCode: ldap.ResultSuccess},
+			Entries: []*SearchEntry{{
+				DN:         req.BaseObject,
+				Attributes: []ldap.Attribute{{Type: "cn", Values: [][]byte{[]byte("Alice Smith")}}},
+			}},
+		}
+	})
+	handler.SetAttributeACL(acl.NewEvaluator(aclConfig))
+	handler.SetCollectiveAttributes(collectiveSource{
+		"l;collective":               {[]byte("Berlin")},
+		"telephoneNumber;collective": {[]byte("+49 30 1234")},
+	})
+
+	search := func(controls ...ldap.Control) []byte {
+		mock := newMockConn()
+		conn := NewConnection(mock, &Server{Handler: handler})
+		msg := createSearchRequest(1, "uid=alice,ou=users,dc=example,dc=com", ldap.ScopeBaseObject)
+		msg.Controls = controls
+		conn.handleSearch(msg)
+		return mock.writeBuf.Bytes()
+	}
+
+	out := search()
+	if !bytes.Contains(out, []byte("l;collective")) || !bytes.Contains(out, []byte("Berlin")) {
+		t.Error("collective attribute l missing from result")
+	}
+	if bytes.Contains(out, []byte("+49 30 1234")) {
+		t.Error("collective attribute denied by ACL returned to anonymous bind")
+	}
+
+	out = search(ldap.Control{OID: NoCollectiveAttributesOID})
+	if bytes.Contains(out, []byte("Berlin")) {
+		t.Error("collective attributes returned despite the control")
+	}
+	if !bytes.Contains(out, []byte("Alice Smith")) {
+		t.Error("cn missing from result")
+	}
+}
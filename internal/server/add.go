@@ -2,6 +2,7 @@
 package server
 
 import (
+	"errors"
 	"strings"
 
 	"github.com/KilimcininKorOglu/oba/internal/acl"
@@ -72,11 +73,7 @@ func (h *AddHandlerImpl) Handle(conn *Connection, req *ldap.AddRequest) *Operati
 
 	// Step 4: Check ACL add permission
 	if h.config.ACLEvaluator != nil {
-		bindDN := ""
-		if conn != nil {
-			bindDN = conn.BindDN()
-		}
-		if !h.config.ACLEvaluator.CanAdd(bindDN, dn) {
+		if !h.config.ACLEvaluator.CheckAccess(accessContext(conn, dn, acl.Add)) {
 			return &OperationResult{
 				ResultCode:        ldap.ResultInsufficientAccessRights,
 				DiagnosticMessage: "insufficient access rights",
@@ -136,7 +133,7 @@ func validateAddRequest(req *ldap.AddRequest) error {
 // normalizeDNForAdd normalizes a DN for consistent comparison.
 // It converts to lowercase and trims whitespace.
 func normalizeDNForAdd(dn string) string {
-	return strings.ToLower(strings.TrimSpace(dn))
+	return ldap.CanonicalDN(dn)
 }
 
 // hasObjectClassAttribute checks if the add request contains an objectClass attribute.
@@ -161,6 +158,13 @@ func convertAddRequestToEntry(req *ldap.AddRequest) *storage.Entry {
 	return entry
 }
 
+// resultError is implemented by backend errors that carry the LDAP result
+// they are returned to clients with, such as backend.LDAPError.
+type resultError interface {
+	error
+	LDAPResult() ldap.LDAPResult
+}
+
 // mapAddError maps backend errors to LDAP result codes.
 func mapAddError(err error, dn string) *OperationResult {
 	if err == nil {
@@ -169,8 +173,37 @@ func mapAddError(err error, dn string) *OperationResult {
 		}
 	}
 
+	var resErr resultError
+	if errors.As(err, &resErr) {
+		result := resErr.LDAPResult()
+		if err != resErr {
+			result.DiagnosticMessage = err.Error()
+		}
+		return &OperationResult{
+			ResultCode:        result.ResultCode,
+			MatchedDN:         result.MatchedDN,
+			DiagnosticMessage: result.DiagnosticMessage,
+		}
+	}
+
+	// Other backends are mapped by their error messages
 	errStr := err.Error()
 
+	// Check for DIT structure rule violations
+	if strings.Contains(errStr, "naming violation") {
+		return &OperationResult{
+			ResultCode:        ldap.ResultNamingViolation,
+			DiagnosticMessage: errStr,
+		}
+	}
+
+	if strings.Contains(errStr, "object class violation") {
+		return &OperationResult{
+			ResultCode:        ldap.ResultObjectClassViolation,
+			DiagnosticMessage: errStr,
+		}
+	}
+
 	// Check for specific error types
 	if strings.Contains(errStr, "already exists") {
 		return &OperationResult{
@@ -3,6 +3,7 @@ package server
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/KilimcininKorOglu/oba/internal/ldap"
@@ -631,6 +632,33 @@ func TestMapAddError(t *testing.T) {
 	}
 }
 
+// resultErr is a backend error carrying its LDAP result.
+type resultErr struct{ result ldap.LDAPResult }
+
+func (e *resultErr) Error() string               { return "backend: " + e.result.DiagnosticMessage }
+func (e *resultErr) LDAPResult() ldap.LDAPResult { return e.result }
+
+// TestMapAddErrorResult tests that mapAddError uses the result carried by
+// a backend error.
+func TestMapAddErrorResult(t *testing.T) {
+	err := fmt.Errorf("add failed: %w", &resultErr{ldap.LDAPResult{
+		ResultCode:        ldap.ResultNoSuchObject,
+		MatchedDN:         "dc=example,dc=com",
+		DiagnosticMessage: "entry not found",
+	}})
+
+	result := mapAddError(err, "uid=test,ou=users,dc=example,dc=com")
+	if result.ResultCode != ldap.ResultNoSuchObject {
+		t.Errorf("ResultCode = %v, want %v", result.ResultCode, ldap.ResultNoSuchObject)
+	}
+	if result.MatchedDN != "dc=example,dc=com" {
+		t.Errorf("MatchedDN = %q, want dc=example,dc=com", result.MatchedDN)
+	}
+	if result.DiagnosticMessage != err.Error() {
+		t.Errorf("DiagnosticMessage = %q, want %q", result.DiagnosticMessage, err.Error())
+	}
+}
+
 // TestAddHandlerImpl_Handle_RootEntry tests adding a root entry.
 func TestAddHandlerImpl_Handle_RootEntry(t *testing.T) {
 	mockBackend := newMockAddBackend()
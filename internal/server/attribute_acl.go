@@ -0,0 +1,51 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/KilimcininKorOglu/oba/internal/acl"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+)
+
+// AttributeAccessChecker decides whether an attribute may be accessed.
+// Both *acl.Evaluator and *acl.Manager implement it.
+type AttributeAccessChecker interface {
+	CheckAttributeAccess(ctx *acl.AccessContext, attr string) bool
+}
+
+// AttributeACLFilter masks the attributes of a search entry that the
+// requester may not read.
+type AttributeACLFilter struct {
+	entry   *SearchEntry
+	checker AttributeAccessChecker
+}
+
+// NewAttributeACLFilter creates an AttributeACLFilter for the given entry.
+func NewAttributeACLFilter(entry *SearchEntry, checker AttributeAccessChecker) *AttributeACLFilter {
+	return &AttributeACLFilter{
+		entry:   entry,
+		checker: checker,
+	}
+}
+
+// Filter returns a copy of the entry without the attributes ctx may not
+// access. Attribute options are ignored, so a rule for "cn" also covers
+// "cn;lang-fr". The original entry is not modified.
+func (f *AttributeACLFilter) Filter(ctx acl.AccessContext) *SearchEntry {
+	if f.entry == nil || f.checker == nil {
+		return f.entry
+	}
+
+	attrs := make([]ldap.Attribute, 0, len(f.entry.Attributes))
+	for _, attr := range f.entry.Attributes {
+		name, _, _ := strings.Cut(attr.Type, ";")
+		if f.checker.CheckAttributeAccess(&ctx, name) {
+			attrs = append(attrs, attr)
+		}
+	}
+
+	return &SearchEntry{
+		DN:         f.entry.DN,
+		Attributes: attrs,
+	}
+}
@@ -4,6 +4,8 @@ package server
 import (
 	"strings"
 
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/schema"
 	"github.com/KilimcininKorOglu/oba/internal/storage"
 )
 
@@ -105,13 +107,79 @@ func (s *AttributeSelector) selectAllUserAttributes(entry *storage.Entry) map[st
 // addAttribute adds an attribute to the result if it exists in the entry.
 // Uses case-insensitive matching.
 func (s *AttributeSelector) addAttribute(result map[string][][]byte, entry *storage.Entry, attrName string) {
-	// Case-insensitive attribute lookup
+	addMatchingAttributes(result, entry, attrName)
+}
+
+// addMatchingAttributes adds every attribute of the entry matched by the
+// requested attribute description, keeping the stored name and options in
+// the result. Requesting "cn" returns "cn;lang-fr" as well, and "*;lang-*"
+// returns all language tagged user attributes. Invalid descriptions match
+// nothing. If the request carries the binary transfer option, it is echoed
+// on the returned names, so "userCertificate;binary" is answered as
+// "usercertificate;binary".
+func addMatchingAttributes(result map[string][][]byte, entry *storage.Entry, attrName string) {
+	requested, err := ldap.ParseAttributeDescription(attrName)
+	if err != nil {
+		return
+	}
+
 	for name, values := range entry.Attributes {
-		if strings.EqualFold(name, attrName) {
-			result[name] = values
-			return
+		stored, err := ldap.ParseAttributeDescription(name)
+		if err != nil {
+			if strings.EqualFold(name, attrName) {
+				result[name] = values
+			}
+			continue
 		}
+		if requested.Name == "*" && IsOperationalAttribute(stored.Name) {
+			continue
+		}
+		if !ldap.MatchAttributeDescription(requested, stored) {
+			continue
+		}
+		if requested.HasBinaryOption() && !stored.HasBinaryOption() {
+			name += ";binary"
+		}
+		result[name] = values
+	}
+}
+
+// ExpandAttributeAliases adds the other names of each requested attribute
+// type known to the schema, so requesting "commonName" also selects values
+// stored under "cn". Attribute options are kept on the added names. The
+// special selectors and unknown attributes are passed through unchanged.
+func ExpandAttributeAliases(requestedAttrs []string, s *schema.Schema) []string {
+	if s == nil || len(requestedAttrs) == 0 {
+		return requestedAttrs
 	}
+
+	seen := make(map[string]bool, len(requestedAttrs))
+	result := make([]string, 0, len(requestedAttrs))
+	add := func(attr string) {
+		key := strings.ToLower(attr)
+		if !seen[key] {
+			seen[key] = true
+			result = append(result, attr)
+		}
+	}
+
+	for _, attr := range requestedAttrs {
+		add(attr)
+
+		name, options := attr, ""
+		if i := strings.IndexByte(attr, ';'); i >= 0 {
+			name, options = attr[:i], attr[i:]
+		}
+		at := s.ResolveAlias(name)
+		if at == nil {
+			continue
+		}
+		for _, alias := range at.Names {
+			add(alias + options)
+		}
+	}
+
+	return result
 }
 
 // SelectAttributes is a convenience function that selects attributes from an entry.
@@ -138,10 +206,13 @@ func IsOperationalAttribute(name string) bool {
 		// Entry metadata
 		"entrydn":               true,
 		"entryuuid":             true,
+		"entrycsn":              true,
+		"entryversion":          true,
 		"subschemasubentry":     true,
 		"hassubordinates":       true,
 		"numsubordinates":       true,
 		"structuralobjectclass": true,
+		"collectiveexclusions":  true,
 
 		// DSA-specific
 		"namingcontexts":          true,
@@ -156,7 +227,7 @@ func IsOperationalAttribute(name string) bool {
 		"pwdaccountlockedtime": true,
 		"pwdfailuretime":       true,
 		"pwdhistory":           true,
-		"pwdgraceuseTime":      true,
+		"pwdgraceusetime":      true,
 		"pwdreset":             true,
 		"pwdmustchange":        true,
 		"pwdpolicysubentry":    true,
@@ -2,8 +2,6 @@
 package server
 
 import (
-	"strings"
-
 	"github.com/KilimcininKorOglu/oba/internal/ldap"
 	"github.com/KilimcininKorOglu/oba/internal/storage"
 )
@@ -201,7 +199,7 @@ func (h *BindHandlerImpl) verifyEntryPassword(entry *storage.Entry, password str
 // normalizeDN normalizes a DN for consistent comparison.
 // It converts to lowercase and trims whitespace.
 func normalizeDN(dn string) string {
-	return strings.ToLower(strings.TrimSpace(dn))
+	return ldap.CanonicalDN(dn)
 }
 
 // CreateBindHandler creates a BindHandler function from a BindHandlerImpl.
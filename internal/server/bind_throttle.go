@@ -0,0 +1,312 @@
+// Package server provides the LDAP server implementation.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Bind throttle errors
+var (
+	// ErrInvalidAllowlistCIDR is returned when an allowlist entry is not a valid CIDR
+	ErrInvalidAllowlistCIDR = errors.New("server: invalid bind throttle allowlist CIDR")
+)
+
+// maxTrackedFailures bounds the failure timestamps kept per source IP.
+const maxTrackedFailures = 1024
+
+// BindThrottleConfig holds configuration for source-IP bind throttling.
+type BindThrottleConfig struct {
+	// MaxFailures is the number of failed binds within Window before delays start
+	MaxFailures int
+	// Window is the sliding window over which failures are counted
+	Window time.Duration
+	// BaseDelay is the delay after the first failure over MaxFailures
+	BaseDelay time.Duration
+	// MaxDelay caps the exponential backoff delay
+	MaxDelay time.Duration
+	// Allowlist contains CIDRs that are never throttled
+	Allowlist []string
+}
+
+// DefaultBindThrottleConfig returns a bind throttle configuration with sensible defaults.
+func DefaultBindThrottleConfig() *BindThrottleConfig {
+	return &BindThrottleConfig{
+		MaxFailures: 10,
+		Window:      5 * time.Minute,
+		BaseDelay:   time.Second,
+		MaxDelay:    30 * time.Second,
+	}
+}
+
+// BindThrottleStatus describes the throttle state of one source IP.
+type BindThrottleStatus struct {
+	// IP is the client source address
+	IP string
+	// Failures is the number of failed binds within the window
+	Failures int
+	// LastFailure is the time of the most recent failed bind
+	LastFailure time.Time
+	// Delay is the delay applied to the next failed bind
+	Delay time.Duration
+}
+
+// bindThrottleState is the persisted form of the throttle state.
+type bindThrottleState struct {
+	IP       string      `json:"ip"`
+	Failures []time.Time `json:"failures"`
+}
+
+// BindThrottle tracks failed binds per source IP and computes tarpit delays
+// for clients that exceed the configured threshold. It complements the
+// per-DN account lockout, which attackers avoid by rotating usernames.
+type BindThrottle struct {
+	config    *BindThrottleConfig
+	allowlist []*net.IPNet
+
+	mu        sync.Mutex
+	failures  map[string][]time.Time
+	lastSweep time.Time
+
+	// now returns the current time; replaced in tests
+	now func() time.Time
+}
+
+// NewBindThrottle creates a new BindThrottle with the given configuration.
+func NewBindThrottle(config *BindThrottleConfig) (*BindThrottle, error) {
+	if config == nil {
+		config = DefaultBindThrottleConfig()
+	}
+
+	allowlist := make([]*net.IPNet, 0, len(config.Allowlist))
+	for _, cidr := range config.Allowlist {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidAllowlistCIDR, cidr)
+		}
+		allowlist = append(allowlist, ipNet)
+	}
+
+	return &BindThrottle{
+		config:    config,
+		allowlist: allowlist,
+		failures:  make(map[string][]time.Time),
+		now:       time.Now,
+	}, nil
+}
+
+// IsAllowlisted returns true if ip is covered by the allowlist.
+func (t *BindThrottle) IsAllowlisted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, ipNet := range t.allowlist {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordFailure records a failed bind from ip and returns the delay to
+// apply before responding. Allowlisted addresses are never delayed.
+func (t *BindThrottle) RecordFailure(ip string) time.Duration {
+	if ip == "" || t.IsAllowlisted(ip) {
+		return 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.sweepLocked(now)
+
+	failures := append(t.pruneLocked(ip, now), now)
+	if len(failures) > maxTrackedFailures {
+		failures = failures[len(failures)-maxTrackedFailures:]
+	}
+	t.failures[ip] = failures
+
+	return t.delayFor(len(failures))
+}
+
+// Delay returns the delay that the next failed bind from ip would receive.
+func (t *BindThrottle) Delay(ip string) time.Duration {
+	if t.IsAllowlisted(ip) {
+		return 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.delayFor(len(t.pruneLocked(ip, t.now())) + 1)
+}
+
+// Status returns the state of every tracked source IP, most failures first.
+func (t *BindThrottle) Status() []BindThrottleStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	result := make([]BindThrottleStatus, 0, len(t.failures))
+	for ip := range t.failures {
+		failures := t.pruneLocked(ip, now)
+		if len(failures) == 0 {
+			continue
+		}
+		result = append(result, BindThrottleStatus{
+			IP:          ip,
+			Failures:    len(failures),
+			LastFailure: failures[len(failures)-1],
+			Delay:       t.delayFor(len(failures) + 1),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Failures != result[j].Failures {
+			return result[i].Failures > result[j].Failures
+		}
+		return result[i].IP < result[j].IP
+	})
+	return result
+}
+
+// Reset clears the failures recorded for ip.
+// Returns false if ip was not tracked.
+func (t *BindThrottle) Reset(ip string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.failures[ip]; !ok {
+		return false
+	}
+	delete(t.failures, ip)
+	return true
+}
+
+// Save writes the throttle state to path so it survives restarts.
+func (t *BindThrottle) Save(path string) error {
+	t.mu.Lock()
+	now := t.now()
+	states := make([]bindThrottleState, 0, len(t.failures))
+	for ip := range t.failures {
+		if failures := t.pruneLocked(ip, now); len(failures) > 0 {
+			states = append(states, bindThrottleState{IP: ip, Failures: failures})
+		}
+	}
+	t.mu.Unlock()
+
+	data, err := json.Marshal(states)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// Load restores throttle state written by Save. A missing file is not an error.
+func (t *BindThrottle) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var states []bindThrottleState
+	if err := json.Unmarshal(data, &states); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	for _, s := range states {
+		t.failures[s.IP] = s.Failures
+		if len(t.pruneLocked(s.IP, now)) == 0 {
+			delete(t.failures, s.IP)
+		}
+	}
+	return nil
+}
+
+// delayFor returns the delay for the given number of failures in the window.
+// The delay doubles for each failure over MaxFailures, up to MaxDelay.
+func (t *BindThrottle) delayFor(failures int) time.Duration {
+	over := failures - t.config.MaxFailures
+	if over <= 0 || t.config.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := t.config.BaseDelay
+	for i := 1; i < over; i++ {
+		delay *= 2
+		if t.config.MaxDelay > 0 && delay >= t.config.MaxDelay {
+			break
+		}
+	}
+	if t.config.MaxDelay > 0 && delay > t.config.MaxDelay {
+		delay = t.config.MaxDelay
+	}
+	return delay
+}
+
+// pruneLocked drops failures of ip that fell out of the window and returns
+// the remaining ones. Caller must hold t.mu.
+func (t *BindThrottle) pruneLocked(ip string, now time.Time) []time.Time {
+	failures := t.failures[ip]
+	cutoff := now.Add(-t.config.Window)
+
+	i := 0
+	for i < len(failures) && !failures[i].After(cutoff) {
+		i++
+	}
+	if i == 0 {
+		return failures
+	}
+
+	failures = failures[i:]
+	if len(failures) == 0 {
+		delete(t.failures, ip)
+		return nil
+	}
+	t.failures[ip] = failures
+	return failures
+}
+
+// sweepLocked removes expired source IPs at most once per window so that
+// the map does not grow without bound. Caller must hold t.mu.
+func (t *BindThrottle) sweepLocked(now time.Time) {
+	if now.Sub(t.lastSweep) < t.config.Window {
+		return
+	}
+	t.lastSweep = now
+	for ip := range t.failures {
+		t.pruneLocked(ip, now)
+	}
+}
+
+// ClientIP returns the IP portion of a connection's remote address.
+func ClientIP(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+)
+
+func newTestBindThrottle(t *testing.T, config *BindThrottleConfig) (*BindThrottle, *time.Time) {
+	t.Helper()
+	throttle, err := NewBindThrottle(config)
+	if err != nil {
+		t.Fatalf("NewBindThrottle() error = %v", err)
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	throttle.now = func() time.Time { return now }
+	return throttle, &now
+}
+
+func TestBindThrottleBackoff(t *testing.T) {
+	throttle, _ := newTestBindThrottle(t, &BindThrottleConfig{
+		MaxFailures: 2,
+		Window:      time.Minute,
+		BaseDelay:   time.Second,
+		MaxDelay:    5 * time.Second,
+	})
+
+	want := []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := throttle.RecordFailure("10.0.0.1"); got != w {
+			t.Errorf("failure %d: delay = %v, want %v", i+1, got, w)
+		}
+	}
+
+	if got := throttle.RecordFailure("10.0.0.2"); got != 0 {
+		t.Errorf("other IP delay = %v, want 0", got)
+	}
+}
+
+func TestBindThrottleSlidingWindow(t *testing.T) {
+	throttle, now := newTestBindThrottle(t, &BindThrottleConfig{
+		MaxFailures: 1,
+		Window:      time.Minute,
+		BaseDelay:   time.Second,
+		MaxDelay:    time.Minute,
+	})
+
+	throttle.RecordFailure("10.0.0.1")
+	*now = now.Add(40 * time.Second)
+	if got := throttle.RecordFailure("10.0.0.1"); got != time.Second {
+		t.Errorf("delay within window = %v, want 1s", got)
+	}
+
+	// The first failure leaves the window
+	*now = now.Add(30 * time.Second)
+	if got := throttle.Delay("10.0.0.1"); got != time.Second {
+		t.Errorf("Delay() = %v, want 1s", got)
+	}
+
+	*now = now.Add(time.Minute)
+	if got := throttle.Delay("10.0.0.1"); got != 0 {
+		t.Errorf("Delay() after window = %v, want 0", got)
+	}
+	if status := throttle.Status(); len(status) != 0 {
+		t.Errorf("Status() = %v, want empty", status)
+	}
+}
+
+func TestBindThrottleAllowlist(t *testing.T) {
+	throttle, _ := newTestBindThrottle(t, &BindThrottleConfig{
+		MaxFailures: 1,
+		Window:      time.Minute,
+		BaseDelay:   time.Second,
+		MaxDelay:    time.Minute,
+		Allowlist:   []string{"10.1.0.0/16", "fd00::/8"},
+	})
+
+	for _, ip := range []string{"10.1.2.3", "fd00::1"} {
+		for i := 0; i < 5; i++ {
+			if got := throttle.RecordFailure(ip); got != 0 {
+				t.Fatalf("RecordFailure(%s) = %v, want 0", ip, got)
+			}
+		}
+	}
+	if status := throttle.Status(); len(status) != 0 {
+		t.Errorf("Status() = %v, want empty", status)
+	}
+
+	_, err := NewBindThrottle(&BindThrottleConfig{Allowlist: []string{"10.1.0.0"}})
+	if !errors.Is(err, ErrInvalidAllowlistCIDR) {
+		t.Errorf("NewBindThrottle() error = %v, want ErrInvalidAllowlistCIDR", err)
+	}
+}
+
+func TestBindThrottleStatusAndReset(t *testing.T) {
+	throttle, _ := newTestBindThrottle(t, &BindThrottleConfig{
+		MaxFailures: 1,
+		Window:      time.Minute,
+		BaseDelay:   time.Second,
+		MaxDelay:    time.Minute,
+	})
+
+	throttle.RecordFailure("10.0.0.1")
+	throttle.RecordFailure("10.0.0.2")
+	throttle.RecordFailure("10.0.0.2")
+
+	status := throttle.Status()
+	if len(status) != 2 {
+		t.Fatalf("Status() returned %d entries, want 2", len(status))
+	}
+	if status[0].IP != "10.0.0.2" || status[0].Failures != 2 || status[0].Delay != 2*time.Second {
+		t.Errorf("Status()[0] = %+v", status[0])
+	}
+
+	if !throttle.Reset("10.0.0.2") {
+		t.Error("Reset() = false, want true")
+	}
+	if throttle.Reset("10.0.0.2") {
+		t.Error("second Reset() = true, want false")
+	}
+	if got := throttle.Delay("10.0.0.2"); got != 0 {
+		t.Errorf("Delay() after reset = %v, want 0", got)
+	}
+}
+
+func TestBindThrottleSaveLoad(t *testing.T) {
+	config := &BindThrottleConfig{
+		MaxFailures: 1,
+		Window:      time.Minute,
+		BaseDelay:   time.Second,
+		MaxDelay:    time.Minute,
+	}
+	path := filepath.Join(t.TempDir(), "throttle.json")
+
+	throttle, now := newTestBindThrottle(t, config)
+	if err := throttle.Load(path); err != nil {
+		t.Fatalf("Load() of missing file error = %v", err)
+	}
+	throttle.RecordFailure("10.0.0.1")
+	throttle.RecordFailure("10.0.0.1")
+	if err := throttle.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	restored, restoredNow := newTestBindThrottle(t, config)
+	*restoredNow = *now
+	if err := restored.Load(path); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := restored.Delay("10.0.0.1"); got != 2*time.Second {
+		t.Errorf("Delay() after Load() = %v, want 2s", got)
+	}
+}
+
+func TestConnectionBindTarpit(t *testing.T) {
+	throttle, err := NewBindThrottle(&BindThrottleConfig{
+		MaxFailures: 1,
+		Window:      time.Minute,
+		BaseDelay:   50 * time.Millisecond,
+		MaxDelay:    time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewBindThrottle() error = %v", err)
+	}
+
+	handler := NewHandler()
+	handler.SetBindThrottle(throttle)
+	handler.SetBindHandler(func(conn *Connection, req *ldap.BindRequest) *OperationResult {
+		return &OperationResult{ResultCode: ldap.ResultInvalidCredentials}
+	})
+
+	mockConn := newMockConn()
+	conn := NewConnection(mockConn, &Server{Handler: handler})
+
+	var data []byte
+	for i := 1; i <= 3; i++ {
+		data = append(data, createBindRequestMessage(i, 3, "cn=user,dc=example,dc=com", "wrong")...)
+	}
+	data = append(data, createUnbindRequestMessage(4)...)
+	mockConn.setReadData(data)
+
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		conn.Handle()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Handle did not complete")
+	}
+
+	// Second and third failures are delayed by 50ms and 100ms
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("binds completed in %v, want at least 150ms", elapsed)
+	}
+	if got := conn.BindFailures(); got != 3 {
+		t.Errorf("BindFailures() = %d, want 3", got)
+	}
+
+	status := throttle.Status()
+	if len(status) != 1 || status[0].IP != "192.168.1.100" || status[0].Failures != 3 {
+		t.Errorf("Status() = %+v", status)
+	}
+}
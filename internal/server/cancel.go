@@ -0,0 +1,81 @@
+// Package server provides the LDAP server implementation.
+package server
+
+import (
+	"github.com/KilimcininKorOglu/oba/internal/ber"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+)
+
+// CancelOID is the OID of the Cancel extended operation.
+// Per RFC 3909: "LDAP Cancel Operation"
+const CancelOID = "1.3.6.1.1.8"
+
+// CancelHandler implements the Cancel extended operation. Unlike Abandon,
+// Cancel is answered: the canceled operation ends with the canceled
+// result code, then the Cancel request gets its own response.
+//
+// Per RFC 3909, the request value is:
+//
+//	cancelRequestValue ::= SEQUENCE {
+//	    cancelID        MessageID
+//	}
+//
+// The response has no name and no value. Its result code is success if
+// the operation was canceled, noSuchOperation if no operation with the
+// message ID is pending, and tooLate if the operation had already sent
+// its final response.
+type CancelHandler struct{}
+
+// NewCancelHandler creates a new CancelHandler.
+func NewCancelHandler() *CancelHandler {
+	return &CancelHandler{}
+}
+
+// OID returns the object identifier for the Cancel extended operation.
+func (h *CancelHandler) OID() string {
+	return CancelOID
+}
+
+// Handle cancels the operation named by the request on the connection.
+func (h *CancelHandler) Handle(conn *Connection, req *ExtendedRequest) (*ExtendedResponse, error) {
+	cancelID, err := parseCancelRequestValue(req.Value)
+	if err != nil {
+		return &ExtendedResponse{
+			Result: OperationResult{
+				ResultCode:        ldap.ResultProtocolError,
+				DiagnosticMessage: "invalid cancel request",
+			},
+		}, nil
+	}
+
+	result := OperationResult{ResultCode: conn.operations.CancelOperation(cancelID)}
+	if result.ResultCode != ldap.ResultSuccess {
+		result.DiagnosticMessage = result.ResultCode.String()
+	}
+
+	conn.Logger().Debug("cancel request",
+		"cancel_id", cancelID,
+		"result_code", result.ResultCode.String())
+
+	return &ExtendedResponse{Result: result}, nil
+}
+
+// parseCancelRequestValue returns the cancelID of a Cancel request value.
+func parseCancelRequestValue(data []byte) (int, error) {
+	if len(data) == 0 {
+		return 0, ldap.NewParseError(0, "empty cancel request value", nil)
+	}
+
+	decoder := ber.NewBERDecoder(data)
+	if _, err := decoder.ExpectSequence(); err != nil {
+		return 0, err
+	}
+	cancelID, err := decoder.ReadInteger()
+	if err != nil {
+		return 0, err
+	}
+	if cancelID < 0 || cancelID > ldap.MaxMessageID {
+		return 0, ldap.NewParseError(decoder.Offset(), "cancelID out of range", nil)
+	}
+	return int(cancelID), nil
+}
@@ -0,0 +1,187 @@
+// Package server provides the LDAP server implementation.
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/ber"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// cancelRequest encodes a Cancel extended request for cancelID.
+func cancelRequest(t *testing.T, messageID, cancelID int) []byte {
+	t.Helper()
+
+	value := ber.NewBEREncoder(16)
+	seq := value.BeginSequence()
+	value.WriteInteger(int64(cancelID))
+	value.EndSequence(seq)
+
+	req := ber.NewBEREncoder(32)
+	req.WriteTaggedValue(0, false, []byte(CancelOID))
+	req.WriteTaggedValue(1, false, value.Bytes())
+
+	msg := &ldap.LDAPMessage{
+		MessageID: messageID,
+		Operation: &ldap.RawOperation{Tag: ldap.ApplicationExtendedRequest, Data: req.Bytes()},
+	}
+	data, err := msg.Encode()
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	return data
+}
+
+// readResultCode reads the next message and returns it with its result code.
+func readResultCode(t *testing.T, client net.Conn) (*ldap.LDAPMessage, ldap.ResultCode) {
+	t.Helper()
+
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	msg, err := readLDAPMessage(client)
+	if err != nil {
+		t.Fatalf("readLDAPMessage() error = %v", err)
+	}
+	code, err := ber.NewBERDecoder(msg.Operation.Data).ReadEnumerated()
+	if err != nil {
+		t.Fatalf("ReadEnumerated() error = %v", err)
+	}
+	return msg, ldap.ResultCode(code)
+}
+
+func TestCancelOperation(t *testing.T) {
+	h := NewAbandonHandler()
+
+	if code := h.CancelOperation(1); code != ldap.ResultNoSuchOperation {
+		t.Errorf("CancelOperation(unknown) = %s, want noSuchOperation", code)
+	}
+
+	// A running operation ends with canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	op := h.Register(1, cancel)
+	final := make(chan ldap.ResultCode, 1)
+	go func() {
+		<-ctx.Done()
+		code, _ := op.FinalResult(ldap.ResultSuccess)
+		final <- code
+		h.Unregister(1)
+	}()
+	if code := h.CancelOperation(1); code != ldap.ResultSuccess {
+		t.Errorf("CancelOperation(running) = %s, want success", code)
+	}
+	if code := <-final; code != ldap.ResultCanceled {
+		t.Errorf("canceled operation result = %s, want canceled", code)
+	}
+	if code := h.CancelOperation(1); code != ldap.ResultNoSuchOperation {
+		t.Errorf("CancelOperation(completed) = %s, want noSuchOperation", code)
+	}
+
+	// An operation sending its final response can no longer be canceled
+	op = h.Register(2, func() {})
+	if code, ok := op.FinalResult(ldap.ResultSuccess); code != ldap.ResultSuccess || !ok {
+		t.Errorf("FinalResult() = %s, %v, want success, true", code, ok)
+	}
+	if code := h.CancelOperation(2); code != ldap.ResultTooLate {
+		t.Errorf("CancelOperation(sent) = %s, want tooLate", code)
+	}
+
+	// An abandoned operation sends nothing and cannot be canceled
+	op = h.Register(3, func() {})
+	h.Handle(nil, 3)
+	if code := h.CancelOperation(3); code != ldap.ResultNoSuchOperation {
+		t.Errorf("CancelOperation(abandoned) = %s, want noSuchOperation", code)
+	}
+	if _, ok := op.FinalResult(ldap.ResultSuccess); ok {
+		t.Error("FinalResult() of abandoned operation = true, want false")
+	}
+}
+
+func TestParseCancelRequestValue(t *testing.T) {
+	value := ber.NewBEREncoder(16)
+	seq := value.BeginSequence()
+	value.WriteInteger(42)
+	value.EndSequence(seq)
+
+	id, err := parseCancelRequestValue(value.Bytes())
+	if err != nil || id != 42 {
+		t.Errorf("parseCancelRequestValue() = %d, %v, want 42", id, err)
+	}
+
+	negative := ber.NewBEREncoder(16)
+	seq = negative.BeginSequence()
+	negative.WriteInteger(-1)
+	negative.EndSequence(seq)
+
+	for name, data := range map[string][]byte{
+		"empty":    nil,
+		"no seq":   {0x02, 0x01, 0x2a},
+		"negative": negative.Bytes(),
+	} {
+		if _, err := parseCancelRequestValue(data); err == nil {
+			t.Errorf("%s: parseCancelRequestValue() error = nil", name)
+		}
+	}
+}
+
+func TestCancelClientUpdate(t *testing.T) {
+	backend := newMockClientUpdateBackend()
+	alice := storage.NewEntry("uid=alice,ou=users,dc=example,dc=com")
+	alice.SetStringAttribute("objectClass", "person")
+	backend.addEntry(alice)
+
+	extended := NewExtendedDispatcher()
+	extended.Register(NewCancelHandler())
+	handler := NewHandler()
+	handler.SetExtendedDispatcher(extended)
+
+	client, srv := net.Pipe()
+	conn := NewConnection(srv, &Server{Handler: handler})
+	conn.SetClientUpdateHandler(NewClientUpdateHandler(backend))
+	go conn.Handle()
+	t.Cleanup(func() { client.Close() })
+
+	if _, err := client.Write(clientUpdateSearch(t, 1, "dc=example,dc=com", nil)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	readUpdate(t, client)
+	if msg, _, _, _ := readUpdate(t, client); msg.OperationType() != ldap.ApplicationIntermediateResponse {
+		t.Fatalf("got %s, want sync done IntermediateResponse", msg.OperationType())
+	}
+
+	// The search ends with canceled, then the Cancel succeeds
+	if _, err := client.Write(cancelRequest(t, 2, 1)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	msg, code := readResultCode(t, client)
+	if msg.OperationType() != ldap.ApplicationSearchResultDone || msg.MessageID != 1 || code != ldap.ResultCanceled {
+		t.Fatalf("got %s for message %d with %s, want canceled SearchResultDone for 1", msg.OperationType(), msg.MessageID, code)
+	}
+	msg, code = readResultCode(t, client)
+	if msg.OperationType() != ldap.ApplicationExtendedResponse || msg.MessageID != 2 || code != ldap.ResultSuccess {
+		t.Fatalf("got %s for message %d with %s, want successful ExtendedResponse for 2", msg.OperationType(), msg.MessageID, code)
+	}
+
+	// The search is gone
+	if _, err := client.Write(cancelRequest(t, 3, 1)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if msg, code = readResultCode(t, client); msg.MessageID != 3 || code != ldap.ResultNoSuchOperation {
+		t.Fatalf("got %s for message %d, want noSuchOperation for 3", code, msg.MessageID)
+	}
+}
+
+func TestCancelSupportedExtension(t *testing.T) {
+	extended := NewExtendedDispatcher()
+	extended.Register(NewCancelHandler())
+
+	dse := NewRootDSEProvider(NewRootDSEConfig().WithExtendedDispatcher(extended)).GetRootDSE()
+	for _, oid := range dse.SupportedExtension {
+		if oid == CancelOID {
+			return
+		}
+	}
+	t.Errorf("supportedExtension = %v, want %s", dse.SupportedExtension, CancelOID)
+}
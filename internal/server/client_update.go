@@ -0,0 +1,488 @@
+// Package server provides the LDAP server implementation.
+package server
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"github.com/KilimcininKorOglu/oba/internal/acl"
+	"github.com/KilimcininKorOglu/oba/internal/ber"
+	"github.com/KilimcininKorOglu/oba/internal/filter"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/stream"
+)
+
+// ClientUpdateOID is the OID of the Client Update control
+// (draft-chiba-ldap-client-update). The same OID marks the entry update
+// control on returned entries and the sync done intermediate response.
+const ClientUpdateOID = "1.3.6.1.4.1.4203.666.3.2"
+
+// Entry update states sent in the entry update control.
+const (
+	UpdateStatePresent = 0
+	UpdateStateAdd     = 1
+	UpdateStateModify  = 2
+	UpdateStateDelete  = 3
+)
+
+// clientUpdateCookieSize is the size of an update cookie: the change
+// stream token, big-endian.
+const clientUpdateCookieSize = 8
+
+// ErrInvalidUpdateCookie is returned when an update cookie was not issued by this server.
+var ErrInvalidUpdateCookie = errors.New("server: invalid client update cookie")
+
+// ClientUpdateControl represents the Client Update request control.
+//
+//	ClientUpdateControl ::= SEQUENCE {
+//	    updateCookie OCTET STRING OPTIONAL
+//	}
+//
+// Without a cookie the client receives the full content followed by
+// changes. With a cookie from an earlier session it receives the changes
+// made since, or the full content again if they are no longer known.
+type ClientUpdateControl struct {
+	Cookie      []byte
+	Criticality bool
+}
+
+// ParseClientUpdateControl parses a Client Update control from an LDAP Control.
+func ParseClientUpdateControl(ctrl ldap.Control) (*ClientUpdateControl, error) {
+	if ctrl.OID != ClientUpdateOID {
+		return nil, nil
+	}
+
+	cuc := &ClientUpdateControl{Criticality: ctrl.Criticality}
+	if len(ctrl.Value) == 0 {
+		return cuc, nil
+	}
+
+	decoder := ber.NewBERDecoder(ctrl.Value)
+	if _, err := decoder.ExpectSequence(); err != nil {
+		return nil, err
+	}
+	if decoder.Remaining() > 0 {
+		cookie, err := decoder.ReadOctetString()
+		if err != nil {
+			return nil, err
+		}
+		if len(cookie) > 0 {
+			if len(cookie) != clientUpdateCookieSize {
+				return nil, ErrInvalidUpdateCookie
+			}
+			cuc.Cookie = cookie
+		}
+	}
+
+	return cuc, nil
+}
+
+// FindClientUpdateControl searches for a Client Update control in controls.
+func FindClientUpdateControl(controls []ldap.Control) (*ClientUpdateControl, error) {
+	for _, ctrl := range controls {
+		if ctrl.OID == ClientUpdateOID {
+			return ParseClientUpdateControl(ctrl)
+		}
+	}
+	return nil, nil
+}
+
+// EntryUpdateControl is attached to every entry sent in a client update
+// session.
+//
+//	EntryUpdate ::= SEQUENCE {
+//	    state        ENUMERATED { present(0), add(1), modify(2), delete(3) },
+//	    updateCookie OCTET STRING OPTIONAL
+//	}
+type EntryUpdateControl struct {
+	State  int
+	Cookie []byte
+}
+
+// Encode encodes the EntryUpdateControl to BER format.
+func (euc *EntryUpdateControl) Encode() ([]byte, error) {
+	encoder := ber.NewBEREncoder(32)
+
+	seqPos := encoder.BeginSequence()
+	if err := encoder.WriteEnumerated(int64(euc.State)); err != nil {
+		return nil, err
+	}
+	if len(euc.Cookie) > 0 {
+		if err := encoder.WriteOctetString(euc.Cookie); err != nil {
+			return nil, err
+		}
+	}
+	if err := encoder.EndSequence(seqPos); err != nil {
+		return nil, err
+	}
+
+	return encoder.Bytes(), nil
+}
+
+// ToLDAPControl converts EntryUpdateControl to an ldap.Control.
+func (euc *EntryUpdateControl) ToLDAPControl() (ldap.Control, error) {
+	value, err := euc.Encode()
+	if err != nil {
+		return ldap.Control{}, err
+	}
+
+	return ldap.Control{
+		OID:   ClientUpdateOID,
+		Value: value,
+	}, nil
+}
+
+// encodeUpdateCookie encodes a change stream token as an update cookie.
+func encodeUpdateCookie(token uint64) []byte {
+	cookie := make([]byte, clientUpdateCookieSize)
+	binary.BigEndian.PutUint64(cookie, token)
+	return cookie
+}
+
+// ClientUpdateBackend defines the interface for client update operations.
+type ClientUpdateBackend interface {
+	PersistentSearchBackend
+	// WatchWithResume creates a subscription that first replays the
+	// events after resumeToken.
+	WatchWithResume(filter stream.WatchFilter, resumeToken uint64) (*stream.Subscriber, error)
+	// ChangeStreamStats returns statistics about the change stream.
+	ChangeStreamStats() stream.BrokerStats
+}
+
+// PersistentConnection tracks the state of one client update session.
+type PersistentConnection struct {
+	conn       *Connection
+	messageID  int
+	req        *ldap.SearchRequest
+	filter     *filter.Filter
+	subscriber *stream.Subscriber
+	cancel     context.CancelFunc
+
+	mu sync.Mutex
+	// token is the change stream token of the last change sent
+	token uint64
+	// synced is set once the initial content has been sent
+	synced bool
+}
+
+// MessageID returns the message ID of the search that started the session.
+func (p *PersistentConnection) MessageID() int {
+	return p.messageID
+}
+
+// Cookie returns the update cookie of the last change sent to the client.
+func (p *PersistentConnection) Cookie() []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return encodeUpdateCookie(p.token)
+}
+
+// Synced reports whether the initial content has been sent.
+func (p *PersistentConnection) Synced() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.synced
+}
+
+// ClientUpdateHandler handles searches with the Client Update control.
+type ClientUpdateHandler struct {
+	backend   ClientUpdateBackend
+	evaluator *filter.Evaluator
+	mu        sync.Mutex
+	sessions  map[*Connection]map[int]*PersistentConnection
+}
+
+// NewClientUpdateHandler creates a new client update handler.
+func NewClientUpdateHandler(backend ClientUpdateBackend) *ClientUpdateHandler {
+	return &ClientUpdateHandler{
+		backend:   backend,
+		evaluator: filter.NewEvaluator(nil),
+		sessions:  make(map[*Connection]map[int]*PersistentConnection),
+	}
+}
+
+// Handle processes a client update search. It sends the content of the
+// search, marks the end of it with a sync done intermediate response, and
+// then pushes changes until the connection is closed or an error occurs.
+func (h *ClientUpdateHandler) Handle(
+	conn *Connection,
+	req *ldap.SearchRequest,
+	ctrl *ClientUpdateControl,
+	messageID int,
+) {
+	if h.backend == nil {
+		conn.WriteMessage(conn.createSearchDoneResponse(messageID, ldap.ResultUnwillingToPerform, "", "client update not configured"))
+		return
+	}
+
+	watchFilter := stream.WatchFilter{
+		BaseDN: req.BaseObject,
+		Scope:  int(req.Scope),
+	}
+
+	// Resume from the cookie if the changes since are still known, and
+	// send the full content otherwise
+	var sub *stream.Subscriber
+	var token uint64
+	resumed := false
+	if ctrl.Cookie != nil {
+		token = binary.BigEndian.Uint64(ctrl.Cookie)
+		var err error
+		sub, err = h.backend.WatchWithResume(watchFilter, token)
+		resumed = err == nil && sub != nil
+	}
+	if !resumed {
+		sub = h.backend.Watch(watchFilter)
+		token = h.backend.ChangeStreamStats().CurrentToken
+	}
+	if sub == nil {
+		conn.WriteMessage(conn.createSearchDoneResponse(messageID, ldap.ResultUnwillingToPerform, "", "failed to subscribe"))
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pc := &PersistentConnection{
+		conn:       conn,
+		messageID:  messageID,
+		req:        req,
+		filter:     convertSearchFilter(req.Filter),
+		subscriber: sub,
+		cancel:     cancel,
+		token:      token,
+	}
+
+	h.addSession(pc)
+	op := conn.operations.Register(messageID, cancel)
+	defer func() {
+		h.removeSession(pc)
+		h.backend.Unwatch(sub.ID)
+		cancel()
+		conn.operations.Unregister(messageID)
+	}()
+
+	if !resumed {
+		if err := h.sendContent(pc); err != nil {
+			return
+		}
+	}
+
+	pc.mu.Lock()
+	pc.synced = true
+	pc.mu.Unlock()
+	if err := h.sendSyncDone(pc); err != nil {
+		return
+	}
+
+	for {
+		select {
+		case event, ok := <-sub.Channel:
+			if !ok {
+				h.finish(pc, op)
+				return
+			}
+			if err := h.sendChange(pc, &event); err != nil {
+				return
+			}
+
+		case <-ctx.Done():
+			h.finish(pc, op)
+			return
+		}
+	}
+}
+
+// finish sends the SearchResultDone that ends the client update search of
+// pc: canceled if a Cancel request ended it, none if it was abandoned.
+func (h *ClientUpdateHandler) finish(pc *PersistentConnection, op *PendingOperation) {
+	code, ok := op.FinalResult(ldap.ResultSuccess)
+	if !ok {
+		return
+	}
+	pc.conn.WriteMessage(pc.conn.createSearchDoneResponse(pc.messageID, code, "", ""))
+}
+
+// addSession registers pc with its connection.
+func (h *ClientUpdateHandler) addSession(pc *PersistentConnection) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sessions := h.sessions[pc.conn]
+	if sessions == nil {
+		sessions = make(map[int]*PersistentConnection)
+		h.sessions[pc.conn] = sessions
+	}
+	sessions[pc.messageID] = pc
+}
+
+// removeSession unregisters pc.
+func (h *ClientUpdateHandler) removeSession(pc *PersistentConnection) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sessions := h.sessions[pc.conn]
+	delete(sessions, pc.messageID)
+	if len(sessions) == 0 {
+		delete(h.sessions, pc.conn)
+	}
+}
+
+// sendContent sends the entries that match the search with state present.
+func (h *ClientUpdateHandler) sendContent(pc *PersistentConnection) error {
+	iter := h.backend.SearchByDN(pc.req.BaseObject, storage.Scope(pc.req.Scope))
+	if iter == nil {
+		return nil
+	}
+	defer iter.Close()
+
+	count := 0
+	for iter.Next() {
+		entry := iter.Entry()
+		if entry == nil || !h.matches(pc, entry) {
+			continue
+		}
+		if pc.req.SizeLimit > 0 && count >= pc.req.SizeLimit {
+			break
+		}
+
+		if err := h.sendEntry(pc, entry, UpdateStatePresent, nil); err != nil {
+			return err
+		}
+		count++
+	}
+
+	return iter.Error()
+}
+
+// sendChange sends a change event as entries with their update state.
+func (h *ClientUpdateHandler) sendChange(pc *PersistentConnection, event *stream.ChangeEvent) error {
+	pc.mu.Lock()
+	pc.token = event.Token
+	pc.mu.Unlock()
+	cookie := encodeUpdateCookie(event.Token)
+
+	switch event.Operation {
+	case stream.OpDelete:
+		return h.sendEntry(pc, storage.NewEntry(event.DN), UpdateStateDelete, cookie)
+
+	case stream.OpModifyDN:
+		// The entry left its old name; send it under the new one if it
+		// still matches
+		if event.OldDN != "" {
+			if err := h.sendEntry(pc, storage.NewEntry(event.OldDN), UpdateStateDelete, cookie); err != nil {
+				return err
+			}
+		}
+		if event.Entry != nil && h.matches(pc, event.Entry) {
+			return h.sendEntry(pc, event.Entry, UpdateStateAdd, cookie)
+		}
+		return nil
+
+	case stream.OpInsert:
+		if event.Entry == nil || !h.matches(pc, event.Entry) {
+			return nil
+		}
+		return h.sendEntry(pc, event.Entry, UpdateStateAdd, cookie)
+
+	case stream.OpUpdate:
+		if event.Entry == nil {
+			return nil
+		}
+		// An entry that no longer matches the filter left the content
+		if !h.matches(pc, event.Entry) {
+			return h.sendEntry(pc, storage.NewEntry(event.DN), UpdateStateDelete, cookie)
+		}
+		return h.sendEntry(pc, event.Entry, UpdateStateModify, cookie)
+	}
+
+	return nil
+}
+
+// matches reports whether entry matches the search filter of pc.
+func (h *ClientUpdateHandler) matches(pc *PersistentConnection, entry *storage.Entry) bool {
+	if pc.filter == nil {
+		return true
+	}
+	return h.evaluator.Evaluate(pc.filter, convertToFilterEntry(entry))
+}
+
+// sendEntry sends entry with an entry update control. Deleted entries are
+// sent with their DN only.
+func (h *ClientUpdateHandler) sendEntry(pc *PersistentConnection, entry *storage.Entry, state int, cookie []byte) error {
+	conn := pc.conn
+
+	searchEntry := &SearchEntry{DN: entry.DN}
+	if state != UpdateStateDelete {
+		searchEntry = buildSearchEntry(entry, pc.req.Attributes, pc.req.TypesOnly)
+		if conn.handler != nil && conn.handler.attributeACL != nil {
+			ctx := *conn.AccessContext(entry.DN, acl.Read)
+			searchEntry = NewAttributeACLFilter(searchEntry, conn.handler.attributeACL).Filter(ctx)
+		}
+	}
+
+	msg := conn.createSearchEntryResponse(pc.messageID, searchEntry)
+	if msg == nil {
+		return ErrInvalidMessage
+	}
+	euc := &EntryUpdateControl{State: state, Cookie: cookie}
+	ctrl, err := euc.ToLDAPControl()
+	if err != nil {
+		return err
+	}
+	msg.Controls = append(msg.Controls, ctrl)
+
+	return conn.WriteMessage(msg)
+}
+
+// sendSyncDone sends the intermediate response that ends the initial
+// content. Its value is the update cookie to resume from.
+//
+//	IntermediateResponse ::= [APPLICATION 25] SEQUENCE {
+//	    responseName  [0] LDAPOID OPTIONAL,
+//	    responseValue [1] OCTET STRING OPTIONAL
+//	}
+func (h *ClientUpdateHandler) sendSyncDone(pc *PersistentConnection) error {
+	encoder := ber.NewBEREncoder(64)
+	if err := encoder.WriteTaggedValue(0, false, []byte(ClientUpdateOID)); err != nil {
+		return err
+	}
+	if err := encoder.WriteTaggedValue(1, false, pc.Cookie()); err != nil {
+		return err
+	}
+
+	return pc.conn.WriteMessage(&ldap.LDAPMessage{
+		MessageID: pc.messageID,
+		Operation: &ldap.RawOperation{
+			Tag:  ldap.ApplicationIntermediateResponse,
+			Data: encoder.Bytes(),
+		},
+	})
+}
+
+// CancelSession cancels the client update sessions of a connection.
+func (h *ClientUpdateHandler) CancelSession(conn *Connection) {
+	h.mu.Lock()
+	sessions := make([]*PersistentConnection, 0, len(h.sessions[conn]))
+	for _, pc := range h.sessions[conn] {
+		sessions = append(sessions, pc)
+	}
+	h.mu.Unlock()
+
+	for _, pc := range sessions {
+		pc.cancel()
+	}
+}
+
+// ActiveSessions returns the number of active client update sessions.
+func (h *ClientUpdateHandler) ActiveSessions() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	count := 0
+	for _, sessions := range h.sessions {
+		count += len(sessions)
+	}
+	return count
+}
@@ -0,0 +1,231 @@
+// Package server provides the LDAP server implementation.
+package server
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/ber"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/stream"
+)
+
+// mockClientUpdateBackend implements ClientUpdateBackend on top of the
+// search mock, publishing its changes to a change stream like the backend.
+type mockClientUpdateBackend struct {
+	*mockSearchBackend
+	broker *stream.Broker
+}
+
+func newMockClientUpdateBackend() *mockClientUpdateBackend {
+	return &mockClientUpdateBackend{
+		mockSearchBackend: newMockSearchBackend(),
+		broker:            stream.NewBroker(),
+	}
+}
+
+func (m *mockClientUpdateBackend) Watch(filter stream.WatchFilter) *stream.Subscriber {
+	return m.broker.Subscribe(filter)
+}
+
+func (m *mockClientUpdateBackend) WatchWithBuffer(filter stream.WatchFilter, bufferSize int) *stream.Subscriber {
+	return m.broker.SubscribeWithBuffer(filter, bufferSize)
+}
+
+func (m *mockClientUpdateBackend) WatchWithResume(filter stream.WatchFilter, token uint64) (*stream.Subscriber, error) {
+	return m.broker.SubscribeWithResume(filter, token)
+}
+
+func (m *mockClientUpdateBackend) Unwatch(id stream.SubscriberID) {
+	m.broker.Unsubscribe(id)
+}
+
+func (m *mockClientUpdateBackend) ChangeStreamStats() stream.BrokerStats {
+	return m.broker.Stats()
+}
+
+func (m *mockClientUpdateBackend) add(entry *storage.Entry) {
+	m.addEntry(entry)
+	m.broker.Publish(stream.ChangeEvent{Operation: stream.OpInsert, DN: entry.DN, Entry: entry})
+}
+
+func (m *mockClientUpdateBackend) delete(dn string) {
+	delete(m.entries, dn)
+	m.broker.Publish(stream.ChangeEvent{Operation: stream.OpDelete, DN: dn})
+}
+
+// clientUpdateSearch encodes a subtree search with the Client Update control.
+func clientUpdateSearch(t *testing.T, messageID int, baseDN string, cookie []byte) []byte {
+	t.Helper()
+
+	search := ber.NewBEREncoder(128)
+	search.WriteOctetString([]byte(baseDN))
+	search.WriteEnumerated(int64(ldap.ScopeWholeSubtree))
+	search.WriteEnumerated(0)
+	search.WriteInteger(0)
+	search.WriteInteger(0)
+	search.WriteBoolean(false)
+	search.WriteTaggedValue(7, false, []byte("objectClass"))
+	attrs := search.BeginSequence()
+	search.EndSequence(attrs)
+
+	value := ber.NewBEREncoder(32)
+	seq := value.BeginSequence()
+	if cookie != nil {
+		value.WriteOctetString(cookie)
+	}
+	value.EndSequence(seq)
+
+	msg := &ldap.LDAPMessage{
+		MessageID: messageID,
+		Operation: &ldap.RawOperation{Tag: ldap.ApplicationSearchRequest, Data: search.Bytes()},
+		Controls:  []ldap.Control{{OID: ClientUpdateOID, Value: value.Bytes()}},
+	}
+	data, err := msg.Encode()
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	return data
+}
+
+// startClientUpdateConnection serves a connection over a pipe and returns
+// the client end.
+func startClientUpdateConnection(t *testing.T, h *ClientUpdateHandler) net.Conn {
+	t.Helper()
+
+	client, srv := net.Pipe()
+	conn := NewConnection(srv, &Server{Handler: NewHandler()})
+	conn.SetClientUpdateHandler(h)
+	go conn.Handle()
+
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// readUpdate reads the next message and decodes its entry update control.
+func readUpdate(t *testing.T, client net.Conn) (msg *ldap.LDAPMessage, dn string, state int, cookie []byte) {
+	t.Helper()
+
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	msg, err := readLDAPMessage(client)
+	if err != nil {
+		t.Fatalf("readLDAPMessage() error = %v", err)
+	}
+	if msg.OperationType() != ldap.ApplicationSearchResultEntry {
+		return msg, "", -1, nil
+	}
+
+	dnBytes, err := ber.NewBERDecoder(msg.Operation.Data).ReadOctetString()
+	if err != nil {
+		t.Fatalf("ReadOctetString() error = %v", err)
+	}
+	if len(msg.Controls) != 1 || msg.Controls[0].OID != ClientUpdateOID {
+		t.Fatalf("entry %s has controls %+v, want entry update control", dnBytes, msg.Controls)
+	}
+
+	decoder := ber.NewBERDecoder(msg.Controls[0].Value)
+	if _, err := decoder.ExpectSequence(); err != nil {
+		t.Fatalf("ExpectSequence() error = %v", err)
+	}
+	st, err := decoder.ReadEnumerated()
+	if err != nil {
+		t.Fatalf("ReadEnumerated() error = %v", err)
+	}
+	if decoder.Remaining() > 0 {
+		if cookie, err = decoder.ReadOctetString(); err != nil {
+			t.Fatalf("ReadOctetString() error = %v", err)
+		}
+	}
+	return msg, string(dnBytes), int(st), cookie
+}
+
+func TestClientUpdateNotifications(t *testing.T) {
+	backend := newMockClientUpdateBackend()
+	alice := storage.NewEntry("uid=alice,ou=users,dc=example,dc=com")
+	alice.SetStringAttribute("objectClass", "person")
+	backend.addEntry(alice)
+
+	h := NewClientUpdateHandler(backend)
+	client := startClientUpdateConnection(t, h)
+	if _, err := client.Write(clientUpdateSearch(t, 1, "dc=example,dc=com", nil)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	// Initial content, then the end of it
+	if _, dn, state, _ := readUpdate(t, client); dn != alice.DN || state != UpdateStatePresent {
+		t.Fatalf("got %s state %d, want %s present", dn, state, alice.DN)
+	}
+	msg, _, _, _ := readUpdate(t, client)
+	if msg.OperationType() != ldap.ApplicationIntermediateResponse || msg.MessageID != 1 {
+		t.Fatalf("got %s for message %d, want sync done IntermediateResponse", msg.OperationType(), msg.MessageID)
+	}
+
+	bob := storage.NewEntry("uid=bob,ou=users,dc=example,dc=com")
+	bob.SetStringAttribute("objectClass", "person")
+	backend.add(bob)
+
+	_, dn, state, addCookie := readUpdate(t, client)
+	if dn != bob.DN || state != UpdateStateAdd {
+		t.Fatalf("got %s state %d, want %s add", dn, state, bob.DN)
+	}
+	if len(addCookie) != clientUpdateCookieSize {
+		t.Fatalf("add cookie = %x", addCookie)
+	}
+
+	backend.delete(bob.DN)
+	if _, dn, state, _ := readUpdate(t, client); dn != bob.DN || state != UpdateStateDelete {
+		t.Fatalf("got %s state %d, want %s delete", dn, state, bob.DN)
+	}
+
+	if h.ActiveSessions() != 1 {
+		t.Errorf("ActiveSessions() = %d, want 1", h.ActiveSessions())
+	}
+
+	// Closing the client ends the session
+	client.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for h.ActiveSessions() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("session was not cleaned up after disconnect")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := backend.broker.SubscriberCount(); n != 0 {
+		t.Errorf("SubscriberCount() = %d after disconnect, want 0", n)
+	}
+
+	// A client resuming from the add cookie only receives the delete
+	resumed := startClientUpdateConnection(t, h)
+	if _, err := resumed.Write(clientUpdateSearch(t, 2, "dc=example,dc=com", addCookie)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if msg, _, _, _ := readUpdate(t, resumed); msg.OperationType() != ldap.ApplicationIntermediateResponse {
+		t.Fatalf("got %s, want sync done without content", msg.OperationType())
+	}
+	if _, dn, state, _ := readUpdate(t, resumed); dn != bob.DN || state != UpdateStateDelete {
+		t.Fatalf("got %s state %d after resume, want %s delete", dn, state, bob.DN)
+	}
+}
+
+func TestParseClientUpdateControl(t *testing.T) {
+	ctrl, err := ParseClientUpdateControl(ldap.Control{OID: ClientUpdateOID, Criticality: true})
+	if err != nil || ctrl == nil || !ctrl.Criticality || ctrl.Cookie != nil {
+		t.Fatalf("ParseClientUpdateControl(no value) = %+v, %v", ctrl, err)
+	}
+
+	encoder := ber.NewBEREncoder(16)
+	seq := encoder.BeginSequence()
+	encoder.WriteOctetString([]byte("short"))
+	encoder.EndSequence(seq)
+	_, err = ParseClientUpdateControl(ldap.Control{OID: ClientUpdateOID, Value: encoder.Bytes()})
+	if !errors.Is(err, ErrInvalidUpdateCookie) {
+		t.Errorf("ParseClientUpdateControl(short cookie) error = %v, want ErrInvalidUpdateCookie", err)
+	}
+
+	if ctrl, err := ParseClientUpdateControl(ldap.Control{OID: PersistentSearchOID}); ctrl != nil || err != nil {
+		t.Errorf("ParseClientUpdateControl(other OID) = %+v, %v", ctrl, err)
+	}
+}
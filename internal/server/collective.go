@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/KilimcininKorOglu/oba/internal/acl"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// NoCollectiveAttributesOID is the OID of the No Collective Attributes
+// control. It has no value; its presence on a Search request leaves
+// collective attributes out of the results. The OID is in the UUID based
+// 2.25 arc, which needs no registration.
+const NoCollectiveAttributesOID = "2.25.113513556312362096689454852066948116640"
+
+// CollectiveAttributeSource supplies the collective attributes (RFC 3671)
+// merged into search results.
+type CollectiveAttributeSource interface {
+	// CollectiveAttributes returns, for each DN in dns, the collective
+	// attributes of the entry keyed by attribute description, such as
+	// "l;collective". Entries without collective attributes get a nil map.
+	CollectiveAttributes(ctx context.Context, dns []string) []map[string][][]byte
+}
+
+// FindNoCollectiveAttributesControl searches for a No Collective Attributes
+// control in a slice of controls. Returns nil if not found.
+func FindNoCollectiveAttributesControl(controls []ldap.Control) *ldap.Control {
+	for i := range controls {
+		if controls[i].OID == NoCollectiveAttributesOID {
+			return &controls[i]
+		}
+	}
+	return nil
+}
+
+// mergeCollectiveAttributes adds the collective attributes of each entry
+// selected by the request. It runs after attribute masking, so the merged
+// attributes are checked against the attribute ACL on their own. Merged
+// entries are copies.
+func (c *Connection) mergeCollectiveAttributes(req *ldap.SearchRequest, entries []*SearchEntry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	dns := make([]string, len(entries))
+	for i, entry := range entries {
+		dns[i] = entry.DN
+	}
+	sets := c.handler.collective.CollectiveAttributes(c.Context(), dns)
+
+	var ctx acl.AccessContext
+	if c.handler.attributeACL != nil {
+		ctx = *c.AccessContext("", acl.Read)
+	}
+
+	for i, attrs := range sets {
+		if i >= len(entries) || len(attrs) == 0 {
+			continue
+		}
+
+		collective := storage.NewEntry(entries[i].DN)
+		for name, values := range attrs {
+			collective.Attributes[name] = values
+		}
+		selected := SelectAttributes(collective, req.Attributes)
+		if len(selected) == 0 {
+			continue
+		}
+
+		names := make([]string, 0, len(selected))
+		for name := range selected {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		merged := &SearchEntry{
+			DN:         entries[i].DN,
+			Attributes: append([]ldap.Attribute(nil), entries[i].Attributes...),
+		}
+		ctx.TargetDN = entries[i].DN
+		for _, name := range names {
+			if c.handler.attributeACL != nil {
+				attrName, _, _ := strings.Cut(name, ";")
+				if !c.handler.attributeACL.CheckAttributeAccess(&ctx, attrName) {
+					continue
+				}
+			}
+			attr := ldap.Attribute{Type: name}
+			if !req.TypesOnly {
+				attr.Values = selected[name]
+			}
+			merged.Attributes = append(merged.Attributes, attr)
+		}
+		entries[i] = merged
+	}
+}
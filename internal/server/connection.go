@@ -2,17 +2,22 @@
 package server
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/KilimcininKorOglu/oba/internal/acl"
 	"github.com/KilimcininKorOglu/oba/internal/ber"
 	"github.com/KilimcininKorOglu/oba/internal/ldap"
 	"github.com/KilimcininKorOglu/oba/internal/logging"
+	"github.com/KilimcininKorOglu/oba/internal/trace"
 )
 
 // Connection errors
@@ -33,39 +38,92 @@ const MaxMessageSize = 16 * 1024 * 1024
 // Connection represents an individual client connection to the LDAP server.
 // It manages the connection state, reads LDAP messages from the network,
 // dispatches them to appropriate handlers, and sends responses back.
+//
+// See the package documentation for the locking rules.
 type Connection struct {
 	// conn is the underlying network connection
 	conn net.Conn
 	// server is the parent server instance
 	server *Server
-	// bindDN is the currently bound DN (empty for anonymous)
-	bindDN string
-	// authenticated indicates whether the connection is authenticated
-	authenticated bool
+	// state is the current identity and TLS state; see connState
+	state atomic.Pointer[connState]
+	// writeMu serializes the messages written to conn
+	writeMu sync.Mutex
+	// closed indicates whether the connection has been closed
+	closed atomic.Bool
+	// mu guards the bookkeeping fields below it. It is only held to
+	// read or update them, never while a handler runs or during I/O.
+	mu sync.Mutex
 	// messageID tracks the last processed message ID
 	messageID int
-	// mu protects concurrent access to connection state
-	mu sync.Mutex
-	// closed indicates whether the connection has been closed
-	closed bool
 	// handler is the operation handler for this connection
 	handler *Handler
-	// logger is the logger for this connection
-	logger logging.Logger
 	// requestID is the unique identifier for this connection
 	requestID string
 	// startTime is when the connection was established
 	startTime time.Time
+	// persistentSearchHandler handles persistent search requests
+	persistentSearchHandler *PersistentSearchHandler
+	// clientUpdateHandler handles client update searches
+	clientUpdateHandler *ClientUpdateHandler
+	// operations tracks the operations Abandon and Cancel can end
+	operations *AbandonHandler
+	// done is closed when the connection is closed
+	done chan struct{}
+	// bindFailures counts failed binds on this connection
+	bindFailures int
+	// tracing starts the spans of the connection (nil if tracing is off)
+	tracing *OtelMiddleware
+	// ctx holds the span of the operation being handled
+	ctx context.Context
+	// trace holds the spans of the message being handled
+	trace *requestTrace
+	// bufferedEntries counts search entries held for writing
+	bufferedEntries int
+	// draining is set when the server shuts down; the connection closes
+	// once its current operation is done
+	draining atomic.Bool
+	// protocolErrors counts the unsupported operations received. Only the
+	// message loop touches it.
+	protocolErrors int
+}
+
+// connState is the identity and TLS state of a connection. A published
+// connState is never modified: bind, StartTLS and SetLogger store a new
+// copy, so handlers read a consistent snapshot without locking.
+type connState struct {
+	// bindDN is the currently bound DN (empty for anonymous)
+	bindDN string
+	// authenticated indicates whether the connection is authenticated
+	authenticated bool
+	// authMethod is the ACL auth method of the current bind ("" if anonymous)
+	authMethod string
 	// isTLS indicates whether the connection is using TLS
 	isTLS bool
 	// tlsState holds the TLS connection state (nil if not TLS)
 	tlsState *tls.ConnectionState
 	// clientCert holds the client certificate if provided (nil if not provided)
 	clientCert *x509.Certificate
-	// persistentSearchHandler handles persistent search requests
-	persistentSearchHandler *PersistentSearchHandler
-	// done is closed when the connection is closed
-	done chan struct{}
+	// logger is the logger for this connection
+	logger logging.Logger
+}
+
+// loadState returns the current state snapshot of c.
+func (c *Connection) loadState() *connState {
+	return c.state.Load()
+}
+
+// updateState publishes a copy of the current state changed by fn. fn may
+// run more than once if the state is swapped concurrently.
+func (c *Connection) updateState(fn func(s *connState)) {
+	for {
+		old := c.state.Load()
+		next := *old
+		fn(&next)
+		if c.state.CompareAndSwap(old, &next) {
+			return
+		}
+	}
 }
 
 // Server represents the LDAP server (placeholder for now).
@@ -75,6 +133,22 @@ type Server struct {
 	Handler *Handler
 	// Logger is the server's logger
 	Logger logging.Logger
+	// Metrics records connection and operation metrics (nil disables them)
+	Metrics *Metrics
+	// TracerProvider provides the tracer for operation spans (nil
+	// disables tracing)
+	TracerProvider trace.TracerProvider
+	// WireDump records the messages of the connection (nil disables it)
+	WireDump *WireDump
+	// Events receives the events observed by the connections (nil
+	// disables them)
+	Events EventHook
+	// Connections tracks the open connections of the server, limiting
+	// their number and draining them at shutdown (nil disables both)
+	Connections *ConnectionTracker
+	// IdleTimeout closes connections that send no request for this long,
+	// unless they have operations outstanding (zero disables it)
+	IdleTimeout time.Duration
 }
 
 // NewConnection creates a new Connection for the given network connection.
@@ -84,23 +158,24 @@ func NewConnection(conn net.Conn, server *Server) *Connection {
 	// Get logger from server or create a nop logger
 	var logger logging.Logger
 	if server != nil && server.Logger != nil {
-		logger = server.Logger.WithSource("ldap").WithRequestID(requestID)
+		logger = server.Logger.WithComponent("ldap").WithRequestID(requestID)
 	} else {
 		logger = logging.NewNop()
 	}
 
 	c := &Connection{
-		conn:          conn,
-		server:        server,
-		bindDN:        "",
-		authenticated: false,
-		messageID:     0,
-		closed:        false,
-		logger:        logger,
-		requestID:     requestID,
-		startTime:     time.Now(),
-		isTLS:         false,
-		done:          make(chan struct{}),
+		conn:       conn,
+		server:     server,
+		messageID:  0,
+		requestID:  requestID,
+		startTime:  time.Now(),
+		done:       make(chan struct{}),
+		operations: NewAbandonHandler(),
+	}
+	c.state.Store(&connState{logger: logger})
+
+	if server != nil && server.TracerProvider != nil {
+		c.tracing = NewOtelMiddleware(server.TracerProvider)
 	}
 
 	// Create a handler for this connection
@@ -118,26 +193,54 @@ func NewConnection(conn net.Conn, server *Server) *Connection {
 // This method blocks until the connection is closed or an error occurs.
 func (c *Connection) Handle() {
 	// Log connection established (debug level - not audit relevant)
-	c.logger.Debug("connection established",
+	c.Logger().Debug("connection established",
 		"client", c.conn.RemoteAddr().String(),
-		"tls", c.isTLS)
+		"tls", c.IsTLS())
+
+	metrics := c.metrics()
+	if metrics != nil {
+		metrics.ConnectionsTotal.Inc()
+		metrics.ActiveConnections.Inc()
+		defer metrics.ActiveConnections.Dec()
+	}
+
+	connCtx := context.Background()
+	if c.tracing != nil {
+		var span trace.Span
+		connCtx, span = c.tracing.startConnection(connCtx, c)
+		defer span.End()
+		c.setContext(connCtx)
+	}
 
 	defer func() {
 		// Log connection closed (debug level - not audit relevant)
-		c.logger.Debug("connection closed",
+		c.Logger().Debug("connection closed",
 			"client", c.conn.RemoteAddr().String(),
 			"duration_ms", time.Since(c.startTime).Milliseconds())
 		c.Close()
 	}()
 
+	if tracker := c.tracker(); tracker != nil {
+		if notice := tracker.add(c); notice != nil {
+			c.disconnect(notice)
+			return
+		}
+		defer tracker.remove(c)
+	}
+
 	for {
 		// Check if connection is closed
-		c.mu.Lock()
-		if c.closed {
-			c.mu.Unlock()
+		if c.isClosed() {
+			return
+		}
+
+		// The idle deadline is armed before the drain check, so that a
+		// Drain racing with it still interrupts the read
+		c.armIdleTimeout()
+		if c.draining.Load() {
+			c.disconnect(noticeShutdown)
 			return
 		}
-		c.mu.Unlock()
 
 		// Read the next message
 		msg, err := c.ReadMessage()
@@ -146,16 +249,25 @@ func (c *Connection) Handle() {
 			if err == io.EOF || errors.Is(err, net.ErrClosed) || c.isClosed() {
 				return
 			}
+			if isTimeout(err) {
+				if c.draining.Load() {
+					c.disconnect(noticeShutdown)
+				} else {
+					c.disconnect(noticeIdleTimeout)
+				}
+				return
+			}
 			// Log error and continue or close based on severity
 			if errors.Is(err, ErrMessageTooLarge) || errors.Is(err, ErrInvalidMessage) {
 				// Protocol error - close connection
-				c.logger.Warn("protocol error",
+				c.Logger().Warn("protocol error",
 					"error", err.Error(),
 					"client", c.conn.RemoteAddr().String())
+				c.disconnect(noticeInvalidMessage)
 				return
 			}
 			// Network error - close connection
-			c.logger.Warn("network error",
+			c.Logger().Warn("network error",
 				"error", err.Error(),
 				"client", c.conn.RemoteAddr().String())
 			return
@@ -163,25 +275,130 @@ func (c *Connection) Handle() {
 
 		// Handle unbind request specially - it closes the connection
 		if msg.OperationType() == ldap.OperationType(ldap.ApplicationUnbindRequest) {
-			c.logger.Debug("unbind request received",
+			c.Logger().Debug("unbind request received",
 				"message_id", msg.MessageID)
+			if metrics != nil {
+				metrics.observeOperation(msg, nil)
+			}
 			return
 		}
 
+		if c.tracing != nil {
+			ctx, rt := c.tracing.startRequest(connCtx, msg)
+			c.mu.Lock()
+			c.ctx, c.trace = ctx, rt
+			c.mu.Unlock()
+		}
+
 		// Dispatch the message to the appropriate handler
+		start := time.Now()
 		response := c.dispatchMessage(msg)
+		if metrics != nil {
+			metrics.observeOperation(msg, response)
+		}
 
 		// Send response(s) if any
+		var writeErr error
 		if response != nil {
-			if err := c.WriteMessage(response); err != nil {
-				// Write error - close connection
-				c.logger.Warn("write error",
-					"error", err.Error(),
-					"client", c.conn.RemoteAddr().String())
-				return
-			}
+			writeErr = c.WriteMessage(response)
+		}
+		if metrics != nil {
+			metrics.observeDuration(msg, response, time.Since(start))
 		}
+		c.endTrace(connCtx, response)
+		if writeErr != nil {
+			// Write error - close connection
+			c.Logger().Warn("write error",
+				"error", writeErr.Error(),
+				"client", c.conn.RemoteAddr().String())
+			return
+		}
+
+		if c.protocolErrors >= maxProtocolErrors {
+			c.Logger().Warn("protocol error",
+				"error", "too many unsupported operations",
+				"client", c.conn.RemoteAddr().String())
+			c.disconnect(noticeProtocolErrors)
+			return
+		}
+	}
+}
+
+// tracker returns the connection tracker of the parent server, or nil.
+func (c *Connection) tracker() *ConnectionTracker {
+	if c.server == nil {
+		return nil
+	}
+	return c.server.Connections
+}
+
+// armIdleTimeout sets the read deadline for the next request. Connections
+// with operations outstanding, such as persistent searches, wait for
+// their results and are not idle.
+func (c *Connection) armIdleTimeout() {
+	if c.server == nil || c.server.IdleTimeout <= 0 {
+		return
+	}
+	if c.operations.PendingCount() > 0 {
+		c.conn.SetReadDeadline(time.Time{})
+		return
+	}
+	c.conn.SetReadDeadline(time.Now().Add(c.server.IdleTimeout))
+}
+
+// drain makes the connection close once its current operation is done,
+// interrupting a read waiting for the next request.
+func (c *Connection) drain() {
+	c.draining.Store(true)
+	c.conn.SetReadDeadline(time.Now())
+}
+
+// disconnect sends notice to the client before the connection is closed.
+func (c *Connection) disconnect(notice *disconnectNotice) {
+	c.Logger().Debug("notice of disconnection",
+		"client", c.conn.RemoteAddr().String(),
+		"result_code", notice.resultCode.String(),
+		"reason", notice.message)
+	if err := c.SendDisconnectNotice(notice.resultCode, notice.message); err != nil {
+		c.Logger().Debug("failed to send notice of disconnection",
+			"error", err.Error(),
+			"client", c.conn.RemoteAddr().String())
+	}
+}
+
+// SendDisconnectNotice sends a Notice of Disconnection (RFC 4511 Section
+// 4.4.1) telling the client that the server is about to close the
+// connection for the reason given by resultCode and message. The caller
+// closes the connection.
+func (c *Connection) SendDisconnectNotice(resultCode ldap.ResultCode, message string) error {
+	msg, err := ldap.NewNoticeOfDisconnection(resultCode, message)
+	if err != nil {
+		return err
 	}
+	c.conn.SetWriteDeadline(time.Now().Add(disconnectNoticeTimeout))
+	return c.WriteMessage(msg)
+}
+
+// isTimeout reports whether err is a network timeout.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// metrics returns the metrics of the parent server, or nil.
+func (c *Connection) metrics() *Metrics {
+	if c.server == nil {
+		return nil
+	}
+	return c.server.Metrics
+}
+
+// wireDump returns the wire dump of the parent server, or nil.
+func (c *Connection) wireDump() *WireDump {
+	if c.server == nil {
+		return nil
+	}
+	return c.server.WireDump
 }
 
 // dispatchMessage dispatches a message to the appropriate handler.
@@ -191,6 +408,21 @@ func (c *Connection) dispatchMessage(msg *ldap.LDAPMessage) *ldap.LDAPMessage {
 		return c.createErrorResponse(msg.MessageID, ldap.ResultUnwillingToPerform, "no handler configured")
 	}
 
+	// Limit concurrent operations of the bound DN. Binds change the
+	// identity and abandons free resources, so neither is limited.
+	switch msg.OperationType() {
+	case ldap.OperationType(ldap.ApplicationBindRequest), ldap.OperationType(ldap.ApplicationAbandonRequest):
+	default:
+		if c.handler.fairness != nil {
+			release, err := c.handler.fairness.acquireOperation(c.BindDN())
+			if err != nil {
+				c.rejectFairness(LimitBindDNOperations, err)
+				return c.createBusyResponse(msg, err.Error())
+			}
+			defer release()
+		}
+	}
+
 	// Dispatch based on operation type
 	switch msg.OperationType() {
 	case ldap.OperationType(ldap.ApplicationBindRequest):
@@ -207,10 +439,16 @@ func (c *Connection) dispatchMessage(msg *ldap.LDAPMessage) *ldap.LDAPMessage {
 		return c.handleModifyDN(msg)
 	case ldap.OperationType(ldap.ApplicationCompareRequest):
 		return c.handleCompare(msg)
+	case ldap.OperationType(ldap.ApplicationExtendedRequest):
+		return c.handleExtended(msg)
 	case ldap.OperationType(ldap.ApplicationAbandonRequest):
 		// Abandon requests don't get a response
+		if req, err := ldap.ParseAbandonRequest(msg.Operation.Data); err == nil {
+			c.operations.Handle(c, req.MessageID)
+		}
 		return nil
 	default:
+		c.protocolErrors++
 		return c.createErrorResponse(msg.MessageID, ldap.ResultProtocolError, "unsupported operation")
 	}
 }
@@ -221,13 +459,15 @@ func (c *Connection) handleBind(msg *ldap.LDAPMessage) *ldap.LDAPMessage {
 
 	req, err := ldap.ParseBindRequest(msg.Operation.Data)
 	if err != nil {
-		c.logger.Warn("bind request parse error",
+		c.Logger().Warn("bind request parse error",
 			"error", err.Error(),
 			"message_id", msg.MessageID)
 		return c.createBindResponse(msg.MessageID, ldap.ResultProtocolError, "", "invalid bind request")
 	}
 
-	c.logger.Debug("bind request",
+	c.startOperationSpan("bind", req.Name)
+
+	c.Logger().Debug("bind request",
 		"dn", req.Name,
 		"version", req.Version,
 		"auth_method", req.AuthMethod.String(),
@@ -238,50 +478,126 @@ func (c *Connection) handleBind(msg *ldap.LDAPMessage) *ldap.LDAPMessage {
 
 	// Update connection state on successful bind
 	if result.ResultCode == ldap.ResultSuccess {
-		c.mu.Lock()
-		c.bindDN = req.Name
-		c.authenticated = !req.IsAnonymous()
-		c.logger = c.logger.WithUser(req.Name)
-		c.mu.Unlock()
-
-		c.logger.Info("bind successful",
+		c.updateState(func(s *connState) {
+			s.bindDN = req.Name
+			s.authenticated = !req.IsAnonymous()
+			s.authMethod = bindAuthMethod(req)
+			s.logger = s.logger.WithUser(req.Name)
+		})
+
+		c.Logger().Info("bind successful",
 			"dn", req.Name,
 			"duration_ms", time.Since(start).Milliseconds())
 	} else {
-		c.logger.Warn("bind failed",
+		c.mu.Lock()
+		c.bindFailures++
+		failures := c.bindFailures
+		c.mu.Unlock()
+
+		c.Logger().Warn("bind failed",
 			"dn", req.Name,
 			"result_code", result.ResultCode.String(),
 			"error", result.DiagnosticMessage,
+			"connection_failures", failures,
 			"duration_ms", time.Since(start).Milliseconds())
+
+		c.emitEvent(Event{
+			Type:       EventBindFailure,
+			Message:    result.ResultCode.String(),
+			BindDN:     req.Name,
+			ClientAddr: ClientIP(c.RemoteAddr()),
+		})
+
+		if result.ResultCode == ldap.ResultInvalidCredentials {
+			c.tarpitBind()
+		}
 	}
 
 	return c.createBindResponse(msg.MessageID, result.ResultCode, result.MatchedDN, result.DiagnosticMessage)
 }
 
+// tarpitBind records a failed bind against the client IP and waits for the
+// throttle delay before the response is sent. The wait ends early if the
+// connection is closed.
+func (c *Connection) tarpitBind() {
+	if c.handler == nil || c.handler.bindThrottle == nil {
+		return
+	}
+
+	ip := ClientIP(c.RemoteAddr())
+	delay := c.handler.bindThrottle.RecordFailure(ip)
+	if delay <= 0 {
+		return
+	}
+
+	c.Logger().Warn("bind throttled",
+		"client_ip", ip,
+		"delay_ms", delay.Milliseconds())
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-c.done:
+	}
+}
+
 // handleSearch handles a search request.
 func (c *Connection) handleSearch(msg *ldap.LDAPMessage) *ldap.LDAPMessage {
 	start := time.Now()
 
 	req, err := ldap.ParseSearchRequest(msg.Operation.Data)
 	if err != nil {
-		c.logger.Warn("search request parse error",
+		c.Logger().Warn("search request parse error",
 			"error", err.Error(),
 			"message_id", msg.MessageID)
 		return c.createSearchDoneResponse(msg.MessageID, ldap.ResultProtocolError, "", "invalid search request")
 	}
+	req.Controls = msg.Controls
+
+	c.startOperationSpan("search", req.BaseObject)
+	c.setSpanAttributes(trace.String(AttrScope, req.Scope.String()))
 
-	c.logger.Debug("search request",
+	c.Logger().Debug("search request",
 		"base_dn", req.BaseObject,
 		"scope", req.Scope.String(),
 		"size_limit", req.SizeLimit,
 		"time_limit", req.TimeLimit,
 		"message_id", msg.MessageID)
 
+	// Check for Client Update Control
+	if len(msg.Controls) > 0 {
+		cuCtrl, err := FindClientUpdateControl(msg.Controls)
+		if err != nil {
+			c.Logger().Warn("client update control parse error",
+				"error", err.Error(),
+				"message_id", msg.MessageID)
+			return c.createSearchDoneResponse(msg.MessageID, ldap.ResultProtocolError, "", "invalid client update control")
+		}
+		if cuCtrl != nil {
+			// Handle client update (blocks until connection closes)
+			if c.clientUpdateHandler != nil {
+				c.Logger().Info("starting client update",
+					"base_dn", req.BaseObject,
+					"scope", req.Scope.String(),
+					"resume", cuCtrl.Cookie != nil,
+					"message_id", msg.MessageID)
+				go c.clientUpdateHandler.Handle(c, req, cuCtrl, msg.MessageID)
+				return nil // Response will be sent by the handler
+			}
+			// Client update not configured
+			if cuCtrl.Criticality {
+				return c.createSearchDoneResponse(msg.MessageID, ldap.ResultUnavailableCriticalExtension, "", "client update not supported")
+			}
+			// Non-critical, fall through to normal search
+		}
+	}
+
 	// Check for Persistent Search Control
 	if len(msg.Controls) > 0 {
 		psCtrl, err := FindPersistentSearchControl(msg.Controls)
 		if err != nil {
-			c.logger.Warn("persistent search control parse error",
+			c.Logger().Warn("persistent search control parse error",
 				"error", err.Error(),
 				"message_id", msg.MessageID)
 			return c.createSearchDoneResponse(msg.MessageID, ldap.ResultProtocolError, "", "invalid persistent search control")
@@ -289,7 +605,7 @@ func (c *Connection) handleSearch(msg *ldap.LDAPMessage) *ldap.LDAPMessage {
 		if psCtrl != nil {
 			// Handle persistent search (blocks until connection closes)
 			if c.persistentSearchHandler != nil {
-				c.logger.Info("starting persistent search",
+				c.Logger().Info("starting persistent search",
 					"base_dn", req.BaseObject,
 					"scope", req.Scope.String(),
 					"changes_only", psCtrl.ChangesOnly,
@@ -305,33 +621,107 @@ func (c *Connection) handleSearch(msg *ldap.LDAPMessage) *ldap.LDAPMessage {
 		}
 	}
 
+	// Searches that examine many entries need one of the few slots
+	if c.handler.fairness != nil {
+		release, err := c.handler.fairness.acquireSearch(req)
+		if err != nil {
+			c.rejectFairness(LimitExpensiveSearches, err)
+			return c.createSearchDoneResponse(msg.MessageID, ldap.ResultBusy, "", err.Error())
+		}
+		defer release()
+	}
+
+	// The handler sees the time limit as the deadline of the connection
+	// context, and returns the entries found so far when it expires
+	limit := c.handler.searchLimits(c.BindDN(), req)
+	if limit.timeLimit > 0 {
+		ctx, cancel := context.WithTimeout(c.Context(), limit.timeLimit)
+		defer cancel()
+		c.setContext(ctx)
+	}
+
 	// Call the handler
 	result := c.handler.HandleSearch(c, req)
 
+	// Return no more entries than the size limit
+	if limit.sizeLimit > 0 && len(result.Entries) > limit.sizeLimit {
+		result.Entries = result.Entries[:limit.sizeLimit]
+		if result.ResultCode == ldap.ResultSuccess {
+			result.ResultCode = ldap.ResultSizeLimitExceeded
+		}
+	}
+
+	// Mask attributes the bound user may not read
+	if c.handler.attributeACL != nil {
+		ctx := *c.AccessContext("", acl.Read)
+		for i, entry := range result.Entries {
+			ctx.TargetDN = entry.DN
+			result.Entries[i] = NewAttributeACLFilter(entry, c.handler.attributeACL).Filter(ctx)
+		}
+	}
+
+	// Merge collective attributes unless the client asked not to
+	if c.handler.collective != nil && FindNoCollectiveAttributesControl(req.Controls) == nil {
+		c.mergeCollectiveAttributes(req, result.Entries)
+	}
+
+	// Hold the entries in the connection's write budget until written
+	if c.handler.fairness != nil {
+		release, err := c.handler.fairness.reserveEntries(c, len(result.Entries))
+		if err != nil {
+			c.rejectFairness(LimitBufferedEntries, err)
+			return c.createSearchDoneResponse(msg.MessageID, ldap.ResultBusy, "", err.Error())
+		}
+		defer release()
+	}
+
 	// Send search result entries first
 	for _, entry := range result.Entries {
 		entryMsg := c.createSearchEntryResponse(msg.MessageID, entry)
 		if err := c.WriteMessage(entryMsg); err != nil {
-			c.logger.Warn("search entry write error",
+			c.Logger().Warn("search entry write error",
 				"error", err.Error(),
 				"base_dn", req.BaseObject)
 			return nil // Connection error, will be handled by caller
 		}
 	}
 
+	// Send continuation references after the entries
+	for _, ref := range result.References {
+		refMsg := c.createSearchReferenceResponse(msg.MessageID, ref)
+		if refMsg == nil {
+			continue
+		}
+		if err := c.WriteMessage(refMsg); err != nil {
+			c.Logger().Warn("search reference write error",
+				"error", err.Error(),
+				"base_dn", req.BaseObject)
+			return nil
+		}
+	}
+
+	c.setSpanAttributes(trace.Int(AttrEntriesReturned, len(result.Entries)))
+
 	// Log search completion
 	if result.ResultCode == ldap.ResultSuccess {
-		c.logger.Info("search completed",
+		c.Logger().Info("search completed",
 			"base_dn", req.BaseObject,
 			"scope", req.Scope.String(),
 			"results", len(result.Entries),
+			"references", len(result.References),
+			"size_limit", limit.sizeLimit,
+			"time_limit", int(limit.timeLimit/time.Second),
+			"limit_profile", limit.subject,
 			"duration_ms", time.Since(start).Milliseconds())
 	} else {
-		c.logger.Warn("search failed",
+		c.Logger().Warn("search failed",
 			"base_dn", req.BaseObject,
 			"scope", req.Scope.String(),
 			"result_code", result.ResultCode.String(),
 			"error", result.DiagnosticMessage,
+			"size_limit", limit.sizeLimit,
+			"time_limit", int(limit.timeLimit/time.Second),
+			"limit_profile", limit.subject,
 			"duration_ms", time.Since(start).Milliseconds())
 	}
 
@@ -345,13 +735,16 @@ func (c *Connection) handleAdd(msg *ldap.LDAPMessage) *ldap.LDAPMessage {
 
 	req, err := ldap.ParseAddRequest(msg.Operation.Data)
 	if err != nil {
-		c.logger.Warn("add request parse error",
+		c.Logger().Warn("add request parse error",
 			"error", err.Error(),
 			"message_id", msg.MessageID)
 		return c.createAddResponse(msg.MessageID, ldap.ResultProtocolError, "", "invalid add request")
 	}
+	req.Controls = msg.Controls
 
-	c.logger.Debug("add request",
+	c.startOperationSpan("add", req.Entry)
+
+	c.Logger().Debug("add request",
 		"entry", req.Entry,
 		"attributes_count", len(req.Attributes),
 		"message_id", msg.MessageID)
@@ -361,11 +754,11 @@ func (c *Connection) handleAdd(msg *ldap.LDAPMessage) *ldap.LDAPMessage {
 
 	// Log result
 	if result.ResultCode == ldap.ResultSuccess {
-		c.logger.Info("add successful",
+		c.Logger().Info("add successful",
 			"entry", req.Entry,
 			"duration_ms", time.Since(start).Milliseconds())
 	} else {
-		c.logger.Warn("add failed",
+		c.Logger().Warn("add failed",
 			"entry", req.Entry,
 			"result_code", result.ResultCode.String(),
 			"error", result.DiagnosticMessage,
@@ -381,13 +774,16 @@ func (c *Connection) handleDelete(msg *ldap.LDAPMessage) *ldap.LDAPMessage {
 
 	req, err := ldap.ParseDeleteRequest(msg.Operation.Data)
 	if err != nil {
-		c.logger.Warn("delete request parse error",
+		c.Logger().Warn("delete request parse error",
 			"error", err.Error(),
 			"message_id", msg.MessageID)
 		return c.createDeleteResponse(msg.MessageID, ldap.ResultProtocolError, "", "invalid delete request")
 	}
+	req.Controls = msg.Controls
+
+	c.startOperationSpan("delete", req.DN)
 
-	c.logger.Debug("delete request",
+	c.Logger().Debug("delete request",
 		"dn", req.DN,
 		"message_id", msg.MessageID)
 
@@ -396,11 +792,11 @@ func (c *Connection) handleDelete(msg *ldap.LDAPMessage) *ldap.LDAPMessage {
 
 	// Log result
 	if result.ResultCode == ldap.ResultSuccess {
-		c.logger.Info("delete successful",
+		c.Logger().Info("delete successful",
 			"dn", req.DN,
 			"duration_ms", time.Since(start).Milliseconds())
 	} else {
-		c.logger.Warn("delete failed",
+		c.Logger().Warn("delete failed",
 			"dn", req.DN,
 			"result_code", result.ResultCode.String(),
 			"error", result.DiagnosticMessage,
@@ -416,13 +812,16 @@ func (c *Connection) handleModify(msg *ldap.LDAPMessage) *ldap.LDAPMessage {
 
 	req, err := ldap.ParseModifyRequest(msg.Operation.Data)
 	if err != nil {
-		c.logger.Warn("modify request parse error",
+		c.Logger().Warn("modify request parse error",
 			"error", err.Error(),
 			"message_id", msg.MessageID)
 		return c.createModifyResponse(msg.MessageID, ldap.ResultProtocolError, "", "invalid modify request")
 	}
+	req.Controls = msg.Controls
 
-	c.logger.Debug("modify request",
+	c.startOperationSpan("modify", req.Object)
+
+	c.Logger().Debug("modify request",
 		"object", req.Object,
 		"changes_count", len(req.Changes),
 		"message_id", msg.MessageID)
@@ -432,12 +831,12 @@ func (c *Connection) handleModify(msg *ldap.LDAPMessage) *ldap.LDAPMessage {
 
 	// Log result
 	if result.ResultCode == ldap.ResultSuccess {
-		c.logger.Info("modify successful",
+		c.Logger().Info("modify successful",
 			"object", req.Object,
 			"changes_count", len(req.Changes),
 			"duration_ms", time.Since(start).Milliseconds())
 	} else {
-		c.logger.Warn("modify failed",
+		c.Logger().Warn("modify failed",
 			"object", req.Object,
 			"result_code", result.ResultCode.String(),
 			"error", result.DiagnosticMessage,
@@ -453,13 +852,16 @@ func (c *Connection) handleModifyDN(msg *ldap.LDAPMessage) *ldap.LDAPMessage {
 
 	req, err := ldap.ParseModifyDNRequest(msg.Operation.Data)
 	if err != nil {
-		c.logger.Warn("modifydn request parse error",
+		c.Logger().Warn("modifydn request parse error",
 			"error", err.Error(),
 			"message_id", msg.MessageID)
 		return c.createModifyDNResponse(msg.MessageID, ldap.ResultProtocolError, "", "invalid modifydn request")
 	}
+	req.Controls = msg.Controls
+
+	c.startOperationSpan("modifydn", req.Entry)
 
-	c.logger.Debug("modifydn request",
+	c.Logger().Debug("modifydn request",
 		"entry", req.Entry,
 		"new_rdn", req.NewRDN,
 		"delete_old_rdn", req.DeleteOldRDN,
@@ -471,12 +873,12 @@ func (c *Connection) handleModifyDN(msg *ldap.LDAPMessage) *ldap.LDAPMessage {
 
 	// Log result
 	if result.ResultCode == ldap.ResultSuccess {
-		c.logger.Info("modifydn successful",
+		c.Logger().Info("modifydn successful",
 			"entry", req.Entry,
 			"new_rdn", req.NewRDN,
 			"duration_ms", time.Since(start).Milliseconds())
 	} else {
-		c.logger.Warn("modifydn failed",
+		c.Logger().Warn("modifydn failed",
 			"entry", req.Entry,
 			"result_code", result.ResultCode.String(),
 			"error", result.DiagnosticMessage,
@@ -492,13 +894,16 @@ func (c *Connection) handleCompare(msg *ldap.LDAPMessage) *ldap.LDAPMessage {
 
 	req, err := ldap.ParseCompareRequest(msg.Operation.Data)
 	if err != nil {
-		c.logger.Warn("compare request parse error",
+		c.Logger().Warn("compare request parse error",
 			"error", err.Error(),
 			"message_id", msg.MessageID)
 		return c.createCompareResponse(msg.MessageID, ldap.ResultProtocolError, "", "invalid compare request")
 	}
+	req.Controls = msg.Controls
 
-	c.logger.Debug("compare request",
+	c.startOperationSpan("compare", req.DN)
+
+	c.Logger().Debug("compare request",
 		"dn", req.DN,
 		"attribute", req.Attribute,
 		"message_id", msg.MessageID)
@@ -508,17 +913,17 @@ func (c *Connection) handleCompare(msg *ldap.LDAPMessage) *ldap.LDAPMessage {
 
 	// Log result
 	if result.ResultCode == ldap.ResultCompareTrue {
-		c.logger.Info("compare result: true",
+		c.Logger().Info("compare result: true",
 			"dn", req.DN,
 			"attribute", req.Attribute,
 			"duration_ms", time.Since(start).Milliseconds())
 	} else if result.ResultCode == ldap.ResultCompareFalse {
-		c.logger.Info("compare result: false",
+		c.Logger().Info("compare result: false",
 			"dn", req.DN,
 			"attribute", req.Attribute,
 			"duration_ms", time.Since(start).Milliseconds())
 	} else {
-		c.logger.Warn("compare failed",
+		c.Logger().Warn("compare failed",
 			"dn", req.DN,
 			"attribute", req.Attribute,
 			"result_code", result.ResultCode.String(),
@@ -529,11 +934,86 @@ func (c *Connection) handleCompare(msg *ldap.LDAPMessage) *ldap.LDAPMessage {
 	return c.createCompareResponse(msg.MessageID, result.ResultCode, result.MatchedDN, result.DiagnosticMessage)
 }
 
+// handleExtended handles an extended request.
+func (c *Connection) handleExtended(msg *ldap.LDAPMessage) *ldap.LDAPMessage {
+	req, err := ParseExtendedRequest(msg.Operation.Data)
+	if err != nil {
+		c.Logger().Warn("extended request parse error",
+			"error", err.Error(),
+			"message_id", msg.MessageID)
+		return createExtendedResponse(msg.MessageID, &ExtendedResponse{
+			Result: OperationResult{
+				ResultCode:        ldap.ResultProtocolError,
+				DiagnosticMessage: "invalid extended request",
+			},
+		})
+	}
+
+	c.startOperationSpan("extended", "")
+
+	c.Logger().Debug("extended request",
+		"oid", req.OID,
+		"message_id", msg.MessageID)
+
+	dispatcher := c.handler.ExtendedDispatcher()
+	if dispatcher == nil {
+		return createExtendedResponse(msg.MessageID, &ExtendedResponse{
+			Result: OperationResult{
+				ResultCode:        ldap.ResultProtocolError,
+				DiagnosticMessage: "unsupported extended operation: " + req.OID,
+			},
+		})
+	}
+
+	resp, err := dispatcher.Handle(c, req)
+	if err != nil && resp == nil {
+		resp = &ExtendedResponse{
+			Result: OperationResult{
+				ResultCode:        ldap.ResultOperationsError,
+				DiagnosticMessage: err.Error(),
+			},
+		}
+	}
+	return createExtendedResponse(msg.MessageID, resp)
+}
+
 // ReadMessage reads the next LDAP message from the connection.
 func (c *Connection) ReadMessage() (*ldap.LDAPMessage, error) {
+	raw, err := readRawLDAPMessage(c.conn)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := ldap.ParseLDAPMessage(raw)
+	if wd := c.wireDump(); wd != nil {
+		wd.record(c, wireInbound, raw, msg, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidMessage, err)
+	}
+
+	// Update message ID tracking
+	c.mu.Lock()
+	c.messageID = msg.MessageID
+	c.mu.Unlock()
+
+	return msg, nil
+}
+
+// readLDAPMessage reads a single BER-encoded LDAP message from r.
+func readLDAPMessage(r io.Reader) (*ldap.LDAPMessage, error) {
+	raw, err := readRawLDAPMessage(r)
+	if err != nil {
+		return nil, err
+	}
+	return ldap.ParseLDAPMessage(raw)
+}
+
+// readRawLDAPMessage reads the encoding of a single LDAP message from r.
+func readRawLDAPMessage(r io.Reader) ([]byte, error) {
 	// Read the tag byte
 	tagBuf := make([]byte, 1)
-	if _, err := io.ReadFull(c.conn, tagBuf); err != nil {
+	if _, err := io.ReadFull(r, tagBuf); err != nil {
 		return nil, err
 	}
 
@@ -543,7 +1023,7 @@ func (c *Connection) ReadMessage() (*ldap.LDAPMessage, error) {
 	}
 
 	// Read the length
-	length, lengthBytes, err := c.readLength()
+	length, lengthBytes, err := readBERLength(r)
 	if err != nil {
 		return nil, err
 	}
@@ -555,7 +1035,7 @@ func (c *Connection) ReadMessage() (*ldap.LDAPMessage, error) {
 
 	// Read the message content
 	content := make([]byte, length)
-	if _, err := io.ReadFull(c.conn, content); err != nil {
+	if _, err := io.ReadFull(r, content); err != nil {
 		return nil, err
 	}
 
@@ -565,26 +1045,15 @@ func (c *Connection) ReadMessage() (*ldap.LDAPMessage, error) {
 	copy(fullMessage[1:], lengthBytes)
 	copy(fullMessage[1+len(lengthBytes):], content)
 
-	// Parse the LDAP message
-	msg, err := ldap.ParseLDAPMessage(fullMessage)
-	if err != nil {
-		return nil, err
-	}
-
-	// Update message ID tracking
-	c.mu.Lock()
-	c.messageID = msg.MessageID
-	c.mu.Unlock()
-
-	return msg, nil
+	return fullMessage, nil
 }
 
-// readLength reads a BER length from the connection.
+// readBERLength reads a BER length from r.
 // Returns the length value and the raw length bytes.
-func (c *Connection) readLength() (int, []byte, error) {
+func readBERLength(r io.Reader) (int, []byte, error) {
 	// Read the first length byte
 	firstByte := make([]byte, 1)
-	if _, err := io.ReadFull(c.conn, firstByte); err != nil {
+	if _, err := io.ReadFull(r, firstByte); err != nil {
 		return 0, nil, err
 	}
 
@@ -603,7 +1072,7 @@ func (c *Connection) readLength() (int, []byte, error) {
 
 	// Read the length bytes
 	lengthBytes := make([]byte, numBytes)
-	if _, err := io.ReadFull(c.conn, lengthBytes); err != nil {
+	if _, err := io.ReadFull(r, lengthBytes); err != nil {
 		return 0, nil, err
 	}
 
@@ -622,13 +1091,11 @@ func (c *Connection) readLength() (int, []byte, error) {
 }
 
 // WriteMessage writes an LDAP message to the connection.
+// It may be called from several goroutines; each message is written whole.
 func (c *Connection) WriteMessage(msg *ldap.LDAPMessage) error {
-	c.mu.Lock()
-	if c.closed {
-		c.mu.Unlock()
+	if c.isClosed() {
 		return ErrConnectionClosed
 	}
-	c.mu.Unlock()
 
 	// Encode the message
 	data, err := msg.Encode()
@@ -636,6 +1103,13 @@ func (c *Connection) WriteMessage(msg *ldap.LDAPMessage) error {
 		return err
 	}
 
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if wd := c.wireDump(); wd != nil {
+		wd.record(c, wireOutbound, data, msg, nil)
+	}
+
 	// Write to the connection
 	_, err = c.conn.Write(data)
 	return err
@@ -643,23 +1117,30 @@ func (c *Connection) WriteMessage(msg *ldap.LDAPMessage) error {
 
 // Close closes the connection.
 func (c *Connection) Close() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.closed {
+	if !c.closed.CompareAndSwap(false, true) {
 		return nil
 	}
 
-	c.closed = true
-
 	// Signal done channel
 	if c.done != nil {
 		close(c.done)
 	}
 
+	c.mu.Lock()
+	persistentSearchHandler := c.persistentSearchHandler
+	clientUpdateHandler := c.clientUpdateHandler
+	c.mu.Unlock()
+
 	// Cancel any persistent search sessions
-	if c.persistentSearchHandler != nil {
-		c.persistentSearchHandler.CancelSession(c)
+	if persistentSearchHandler != nil {
+		persistentSearchHandler.CancelSession(c)
+	}
+	if clientUpdateHandler != nil {
+		clientUpdateHandler.CancelSession(c)
+	}
+
+	if wd := c.wireDump(); wd != nil {
+		wd.closeConnection(c.requestID)
 	}
 
 	return c.conn.Close()
@@ -672,30 +1153,35 @@ func (c *Connection) Done() <-chan struct{} {
 
 // isClosed returns whether the connection is closed.
 func (c *Connection) isClosed() bool {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	return c.closed
+	return c.closed.Load()
 }
 
 // BindDN returns the currently bound DN.
 func (c *Connection) BindDN() string {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	return c.bindDN
+	return c.loadState().bindDN
 }
 
 // IsAuthenticated returns whether the connection is authenticated.
 func (c *Connection) IsAuthenticated() bool {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	return c.authenticated
+	return c.loadState().authenticated
 }
 
-// RemoteAddr returns the remote address of the connection.
+// RemoteAddr returns the remote address of the connection, or nil for a
+// connection without a network connection.
 func (c *Connection) RemoteAddr() net.Addr {
+	if c.conn == nil {
+		return nil
+	}
 	return c.conn.RemoteAddr()
 }
 
+// BindFailures returns the number of failed binds on this connection.
+func (c *Connection) BindFailures() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bindFailures
+}
+
 // LocalAddr returns the local address of the connection.
 func (c *Connection) LocalAddr() net.Addr {
 	return c.conn.LocalAddr()
@@ -826,6 +1312,29 @@ func (c *Connection) createSearchEntryResponse(messageID int, entry *SearchEntry
 	}
 }
 
+// createSearchReferenceResponse creates a SearchResultReference message.
+// SearchResultReference ::= [APPLICATION 19] SEQUENCE SIZE (1..MAX) OF uri URI
+func (c *Connection) createSearchReferenceResponse(messageID int, ref *SearchReference) *ldap.LDAPMessage {
+	if ref == nil || len(ref.URIs) == 0 {
+		return nil
+	}
+
+	encoder := ber.NewBEREncoder(128)
+	for _, uri := range ref.URIs {
+		if err := encoder.WriteOctetString([]byte(uri)); err != nil {
+			return nil
+		}
+	}
+
+	return &ldap.LDAPMessage{
+		MessageID: messageID,
+		Operation: &ldap.RawOperation{
+			Tag:  ldap.ApplicationSearchResultReference,
+			Data: encoder.Bytes(),
+		},
+	}
+}
+
 // createAddResponse creates an AddResponse message.
 // AddResponse ::= [APPLICATION 9] LDAPResult
 func (c *Connection) createAddResponse(messageID int, resultCode ldap.ResultCode, matchedDN, diagnosticMessage string) *ldap.LDAPMessage {
@@ -973,7 +1482,7 @@ func (c *Connection) createCompareResponse(messageID int, resultCode ldap.Result
 
 // Logger returns the logger for this connection.
 func (c *Connection) Logger() logging.Logger {
-	return c.logger
+	return c.loadState().logger
 }
 
 // RequestID returns the unique request ID for this connection.
@@ -985,41 +1494,38 @@ func (c *Connection) RequestID() string {
 // If the underlying connection is a TLS connection, it extracts the connection
 // state and client certificate (if provided).
 func (c *Connection) SetTLS(isTLS bool) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.isTLS = isTLS
+	var tlsState *tls.ConnectionState
+	var clientCert *x509.Certificate
 
 	// If TLS is enabled, try to extract TLS state from the connection
 	if isTLS {
 		if tlsConn, ok := c.conn.(*tls.Conn); ok {
 			state := tlsConn.ConnectionState()
-			c.tlsState = &state
+			tlsState = &state
 
 			// Extract client certificate if provided
 			if len(state.PeerCertificates) > 0 {
-				c.clientCert = state.PeerCertificates[0]
+				clientCert = state.PeerCertificates[0]
 			}
 		}
-	} else {
-		c.tlsState = nil
-		c.clientCert = nil
 	}
+
+	c.updateState(func(s *connState) {
+		s.isTLS = isTLS
+		s.tlsState = tlsState
+		s.clientCert = clientCert
+	})
 }
 
 // IsTLS returns whether the connection is using TLS.
 func (c *Connection) IsTLS() bool {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	return c.isTLS
+	return c.loadState().isTLS
 }
 
 // RequireTLS checks if the connection is using TLS and returns an error if not.
 // This is used to enforce TLS for security-sensitive operations like password changes.
 func (c *Connection) RequireTLS() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if !c.isTLS {
+	if !c.loadState().isTLS {
 		return ErrTLSRequired
 	}
 	return nil
@@ -1028,61 +1534,51 @@ func (c *Connection) RequireTLS() error {
 // GetTLSState returns the TLS connection state if the connection is using TLS.
 // Returns nil if the connection is not using TLS.
 func (c *Connection) GetTLSState() *tls.ConnectionState {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	return c.tlsState
+	return c.loadState().tlsState
 }
 
 // GetClientCertificate returns the client certificate if one was provided during
 // the TLS handshake. Returns nil if no client certificate was provided or if
 // the connection is not using TLS.
 func (c *Connection) GetClientCertificate() *x509.Certificate {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	return c.clientCert
+	return c.loadState().clientCert
 }
 
 // GetTLSVersion returns the TLS version being used by the connection.
 // Returns 0 if the connection is not using TLS.
 func (c *Connection) GetTLSVersion() uint16 {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.tlsState == nil {
+	tlsState := c.loadState().tlsState
+	if tlsState == nil {
 		return 0
 	}
-	return c.tlsState.Version
+	return tlsState.Version
 }
 
 // GetCipherSuite returns the cipher suite being used by the TLS connection.
 // Returns 0 if the connection is not using TLS.
 func (c *Connection) GetCipherSuite() uint16 {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.tlsState == nil {
+	tlsState := c.loadState().tlsState
+	if tlsState == nil {
 		return 0
 	}
-	return c.tlsState.CipherSuite
+	return tlsState.CipherSuite
 }
 
 // GetServerName returns the server name indicated by the client during TLS handshake.
 // Returns an empty string if the connection is not using TLS or if SNI was not used.
 func (c *Connection) GetServerName() string {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.tlsState == nil {
+	tlsState := c.loadState().tlsState
+	if tlsState == nil {
 		return ""
 	}
-	return c.tlsState.ServerName
+	return tlsState.ServerName
 }
 
 // SetLogger sets the logger for this connection.
 func (c *Connection) SetLogger(logger logging.Logger) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.logger = logger
+	c.updateState(func(s *connState) {
+		s.logger = logger
+	})
 }
 
 // SetPersistentSearchHandler sets the persistent search handler for this connection.
@@ -1091,3 +1587,10 @@ func (c *Connection) SetPersistentSearchHandler(handler *PersistentSearchHandler
 	defer c.mu.Unlock()
 	c.persistentSearchHandler = handler
 }
+
+// SetClientUpdateHandler sets the client update handler for this connection.
+func (c *Connection) SetClientUpdateHandler(handler *ClientUpdateHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clientUpdateHandler = handler
+}
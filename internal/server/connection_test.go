@@ -3,6 +3,7 @@ package server
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"net"
 	"sync"
@@ -236,11 +237,11 @@ func TestNewConnection(t *testing.T) {
 	if conn.server != server {
 		t.Error("Connection.server not set correctly")
 	}
-	if conn.bindDN != "" {
-		t.Error("Connection.bindDN should be empty initially")
+	if conn.BindDN() != "" {
+		t.Error("Connection.BindDN should be empty initially")
 	}
-	if conn.authenticated {
-		t.Error("Connection.authenticated should be false initially")
+	if conn.IsAuthenticated() {
+		t.Error("Connection.IsAuthenticated should be false initially")
 	}
 	if conn.handler == nil {
 		t.Error("Connection.handler should not be nil")
@@ -366,10 +367,10 @@ func TestConnectionBindDN(t *testing.T) {
 	}
 
 	// Simulate successful bind
-	conn.mu.Lock()
-	conn.bindDN = "cn=admin,dc=example,dc=com"
-	conn.authenticated = true
-	conn.mu.Unlock()
+	conn.updateState(func(s *connState) {
+		s.bindDN = "cn=admin,dc=example,dc=com"
+		s.authenticated = true
+	})
 
 	if conn.BindDN() != "cn=admin,dc=example,dc=com" {
 		t.Error("BindDN not updated correctly")
@@ -486,6 +487,98 @@ func TestConnectionHandleSearchRequest(t *testing.T) {
 	}
 }
 
+func TestConnectionSearchTimeLimit(t *testing.T) {
+	handler := NewHandler()
+	handler.SetMaxTimeLimit(1)
+
+	// The handler finds one entry, then runs until the time limit expires
+	var deadline time.Time
+	handler.SetSearchHandler(func(conn *Connection, req *ldap.SearchRequest) *SearchResult {
+		ctx := conn.Context()
+		deadline, _ = ctx.Deadline()
+		<-ctx.Done()
+		return &SearchResult{
+			OperationResult: OperationResult{ResultCode: ldap.ResultTimeLimitExceeded},
+			Entries:         []*SearchEntry{{DN: "uid=alice,ou=users,dc=example,dc=com"}},
+		}
+	})
+
+	search := ber.NewBEREncoder(128)
+	search.WriteOctetString([]byte("dc=example,dc=com"))
+	search.WriteEnumerated(int64(ldap.ScopeWholeSubtree))
+	search.WriteEnumerated(0)
+	search.WriteInteger(0)
+	search.WriteInteger(3600)
+	search.WriteBoolean(false)
+	search.WriteTaggedValue(7, false, []byte("objectClass"))
+	attrs := search.BeginSequence()
+	search.EndSequence(attrs)
+	msg := &ldap.LDAPMessage{
+		MessageID: 1,
+		Operation: &ldap.RawOperation{Tag: ldap.ApplicationSearchRequest, Data: search.Bytes()},
+	}
+	data, err := msg.Encode()
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	mockConn := newMockConn()
+	mockConn.setReadData(append(data, createUnbindRequestMessage(2)...))
+	conn := NewConnection(mockConn, &Server{Handler: handler})
+
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		conn.Handle()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Handle did not complete")
+	}
+
+	// The requested hour is capped at the maximum of one second
+	if deadline.IsZero() || deadline.Sub(start) > 2*time.Second {
+		t.Errorf("search deadline = %v after start, want about 1s", deadline.Sub(start))
+	}
+
+	// The entry found is returned before the result
+	written := bytes.NewReader(mockConn.getWrittenData())
+	entry, err := readLDAPMessage(written)
+	if err != nil || entry.OperationType() != ldap.ApplicationSearchResultEntry {
+		t.Fatalf("first response = %v, %v, want SearchResultEntry", entry, err)
+	}
+	result, err := readLDAPMessage(written)
+	if err != nil || result.OperationType() != ldap.ApplicationSearchResultDone {
+		t.Fatalf("second response = %v, %v, want SearchResultDone", result, err)
+	}
+	if code, _ := ber.NewBERDecoder(result.Operation.Data).ReadEnumerated(); ldap.ResultCode(code) != ldap.ResultTimeLimitExceeded {
+		t.Errorf("result code = %s, want timeLimitExceeded", ldap.ResultCode(code))
+	}
+}
+
+func TestSearchTimeLimitCap(t *testing.T) {
+	handler := NewHandler()
+	tests := []struct {
+		requested, max int
+		want           time.Duration
+	}{
+		{0, 0, 0},
+		{0, 60, 0},
+		{10, 0, 10 * time.Second},
+		{10, 60, 10 * time.Second},
+		{120, 60, 60 * time.Second},
+	}
+	for _, tt := range tests {
+		handler.SetMaxTimeLimit(tt.max)
+		got := handler.searchLimits("", &ldap.SearchRequest{TimeLimit: tt.requested}).timeLimit
+		if got != tt.want {
+			t.Errorf("searchTimeLimit(%d) with max %d = %v, want %v", tt.requested, tt.max, got, tt.want)
+		}
+	}
+}
+
 func TestConnectionHandleAddRequest(t *testing.T) {
 	mockConn := newMockConn()
 	handler := NewHandler()
@@ -609,6 +702,115 @@ func TestConnectionStateTracking(t *testing.T) {
 	}
 }
 
+// TestConnectionConcurrentOperations runs binds, searches and abandons on
+// one connection from several goroutines, as pipelined requests would be.
+// Run with -race.
+func TestConnectionConcurrentOperations(t *testing.T) {
+	mockConn := newMockConn()
+	handler := NewHandler()
+	handler.SetBindHandler(func(conn *Connection, req *ldap.BindRequest) *OperationResult {
+		return &OperationResult{ResultCode: ldap.ResultSuccess}
+	})
+	handler.SetSearchHandler(func(conn *Connection, req *ldap.SearchRequest) *SearchResult {
+		// Handlers read the connection state while binds replace it
+		_ = conn.AccessContext(req.BaseObject, 0)
+		return &SearchResult{
+			OperationResult: OperationResult{ResultCode: ldap.ResultSuccess},
+			Entries: []*SearchEntry{{
+				DN:         req.BaseObject,
+				Attributes: []ldap.Attribute{{Type: "cn", Values: [][]byte{[]byte(conn.BindDN())}}},
+			}},
+		}
+	})
+	conn := NewConnection(mockConn, &Server{Handler: handler})
+
+	const workers, rounds = 8, 50
+	bindDNs := make(map[string]bool)
+	for w := 0; w < workers; w++ {
+		bindDNs[fmt.Sprintf("uid=user%d,dc=example,dc=com", w)] = true
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			bindDN := fmt.Sprintf("uid=user%d,dc=example,dc=com", w)
+			for i := 0; i < rounds; i++ {
+				id := (w*rounds+i)*3 + 1
+				msgs := []*ldap.LDAPMessage{
+					createBindRequest(id, bindDN, "secret"),
+					createSearchRequest(id+1, "dc=example,dc=com", ldap.ScopeBaseObject),
+					{
+						MessageID: id + 2,
+						Operation: &ldap.RawOperation{Tag: ldap.ApplicationAbandonRequest, Data: []byte{byte(id + 1)}},
+					},
+				}
+				for _, msg := range msgs {
+					if response := conn.dispatchMessage(msg); response != nil {
+						if err := conn.WriteMessage(response); err != nil {
+							t.Errorf("WriteMessage failed: %v", err)
+							return
+						}
+					}
+				}
+				conn.SetTLS(false)
+				_ = conn.IsAuthenticated()
+				_ = conn.GetTLSVersion()
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if !bindDNs[conn.BindDN()] {
+		t.Errorf("BindDN = %q, want one of the bound DNs", conn.BindDN())
+	}
+
+	// Each round writes a bind response, a search entry and a search done,
+	// and the messages must not interleave
+	r := bytes.NewReader(mockConn.getWrittenData())
+	count := 0
+	for r.Len() > 0 {
+		if _, err := readLDAPMessage(r); err != nil {
+			t.Fatalf("message %d is corrupt: %v", count, err)
+		}
+		count++
+	}
+	if want := workers * rounds * 3; count != want {
+		t.Errorf("wrote %d messages, want %d", count, want)
+	}
+}
+
+func TestConnectionBindFailureEvent(t *testing.T) {
+	handler := NewHandler()
+	handler.SetBindHandler(func(conn *Connection, req *ldap.BindRequest) *OperationResult {
+		if string(req.SimplePassword) == "secret" {
+			return &OperationResult{ResultCode: ldap.ResultSuccess}
+		}
+		return &OperationResult{ResultCode: ldap.ResultInvalidCredentials}
+	})
+
+	var events []Event
+	conn := NewConnection(newMockConn(), &Server{
+		Handler: handler,
+		Events:  func(e Event) { events = append(events, e) },
+	})
+
+	conn.dispatchMessage(createBindRequest(1, "uid=alice,dc=example,dc=com", "secret"))
+	if len(events) != 0 {
+		t.Fatalf("successful bind reported %d events", len(events))
+	}
+
+	conn.dispatchMessage(createBindRequest(2, "uid=alice,dc=example,dc=com", "wrong"))
+	if len(events) != 1 {
+		t.Fatalf("failed bind reported %d events, want 1", len(events))
+	}
+	e := events[0]
+	if e.Type != EventBindFailure || e.BindDN != "uid=alice,dc=example,dc=com" || e.ClientAddr != "192.168.1.100" || e.Time.IsZero() {
+		t.Errorf("event = %+v", e)
+	}
+}
+
 func TestConnectionErrorRecovery(t *testing.T) {
 	mockConn := newMockConn()
 	handler := NewHandler()
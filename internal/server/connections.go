@@ -0,0 +1,102 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+)
+
+// disconnectNoticeTimeout bounds the write of a Notice of Disconnection,
+// so that a client that does not read cannot hold its connection open.
+const disconnectNoticeTimeout = 5 * time.Second
+
+// maxProtocolErrors is the number of unsupported operations after which a
+// connection is closed.
+const maxProtocolErrors = 10
+
+// disconnectNotice is the Notice of Disconnection a connection is closed
+// with by the server.
+type disconnectNotice struct {
+	resultCode ldap.ResultCode
+	message    string
+}
+
+// The reasons a server closes a connection on its own.
+var (
+	noticeShutdown       = &disconnectNotice{ldap.ResultUnavailable, "server is shutting down"}
+	noticeTooManyConns   = &disconnectNotice{ldap.ResultBusy, "too many connections"}
+	noticeIdleTimeout    = &disconnectNotice{ldap.ResultOther, "connection idle timeout"}
+	noticeInvalidMessage = &disconnectNotice{ldap.ResultProtocolError, "invalid message"}
+	noticeProtocolErrors = &disconnectNotice{ldap.ResultProtocolError, "too many protocol errors"}
+)
+
+// ConnectionTracker tracks the open connections of a server. It limits
+// their number, and drains them when the server shuts down. A server
+// shares one tracker between all its connections.
+type ConnectionTracker struct {
+	mu       sync.Mutex
+	conns    map[*Connection]struct{}
+	max      int
+	draining bool
+}
+
+// NewConnectionTracker creates a ConnectionTracker allowing max open
+// connections. Zero allows any number.
+func NewConnectionTracker(max int) *ConnectionTracker {
+	return &ConnectionTracker{
+		conns: make(map[*Connection]struct{}),
+		max:   max,
+	}
+}
+
+// SetMax changes the number of open connections allowed. Connections
+// already open are kept.
+func (t *ConnectionTracker) SetMax(max int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.max = max
+}
+
+// Len returns the number of open connections.
+func (t *ConnectionTracker) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.conns)
+}
+
+// Drain asks every open connection to close once its current operation
+// is done, with a Notice of Disconnection. Connections opened afterwards
+// are refused with one.
+func (t *ConnectionTracker) Drain() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.draining = true
+	for c := range t.conns {
+		c.drain()
+	}
+}
+
+// add registers c. It returns the notice to refuse c with if the server is
+// draining or at its limit, or nil.
+func (t *ConnectionTracker) add(c *Connection) *disconnectNotice {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.draining {
+		return noticeShutdown
+	}
+	if t.max > 0 && len(t.conns) >= t.max {
+		return noticeTooManyConns
+	}
+	t.conns[c] = struct{}{}
+	return nil
+}
+
+// remove unregisters c.
+func (t *ConnectionTracker) remove(c *Connection) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.conns, c)
+}
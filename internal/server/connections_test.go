@@ -0,0 +1,178 @@
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/ber"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+)
+
+// pipeClient is the client end of a connection served by Handle.
+type pipeClient struct {
+	t        *testing.T
+	conn     net.Conn
+	reader   *Connection
+	messages chan *ldap.LDAPMessage
+	done     chan struct{}
+}
+
+// servePipe serves one end of a net.Pipe with server and returns the
+// client end. The messages sent by the server are collected until it
+// closes the connection.
+func servePipe(t *testing.T, server *Server) *pipeClient {
+	t.Helper()
+	serverEnd, clientEnd := net.Pipe()
+	t.Cleanup(func() { clientEnd.Close() })
+
+	p := &pipeClient{
+		t:        t,
+		conn:     clientEnd,
+		reader:   NewConnection(clientEnd, nil),
+		messages: make(chan *ldap.LDAPMessage, 64),
+		done:     make(chan struct{}),
+	}
+	go NewConnection(serverEnd, server).Handle()
+	go func() {
+		defer close(p.done)
+		defer close(p.messages)
+		for {
+			msg, err := p.reader.ReadMessage()
+			if err != nil {
+				return
+			}
+			p.messages <- msg
+		}
+	}()
+	return p
+}
+
+// send writes raw to the server.
+func (p *pipeClient) send(raw []byte) {
+	p.t.Helper()
+	if _, err := p.conn.Write(raw); err != nil {
+		p.t.Fatalf("write: %v", err)
+	}
+}
+
+// closed waits for the server to close the connection and returns the
+// messages it sent.
+func (p *pipeClient) closed() []*ldap.LDAPMessage {
+	p.t.Helper()
+	select {
+	case <-p.done:
+	case <-time.After(5 * time.Second):
+		p.t.Fatal("connection not closed by the server")
+	}
+	var msgs []*ldap.LDAPMessage
+	for msg := range p.messages {
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+// checkNotice checks that msgs ends with a Notice of Disconnection with
+// resultCode.
+func checkNotice(t *testing.T, msgs []*ldap.LDAPMessage, resultCode ldap.ResultCode) {
+	t.Helper()
+	if len(msgs) == 0 {
+		t.Fatal("no message sent before the connection was closed")
+	}
+	last := msgs[len(msgs)-1]
+	if last.MessageID != 0 || last.Operation.Tag != ldap.ApplicationExtendedResponse {
+		t.Fatalf("last message = id %d tag %d, want an unsolicited ExtendedResponse",
+			last.MessageID, last.Operation.Tag)
+	}
+
+	decoder := ber.NewBERDecoder(last.Operation.Data)
+	code, err := decoder.ReadEnumerated()
+	if err != nil {
+		t.Fatalf("reading resultCode: %v", err)
+	}
+	if ldap.ResultCode(code) != resultCode {
+		t.Errorf("resultCode = %s, want %s", ldap.ResultCode(code), resultCode)
+	}
+	decoder.ReadOctetString() // matchedDN
+	decoder.ReadOctetString() // diagnosticMessage
+	tag, _, name, err := decoder.ReadTaggedValue()
+	if err != nil || tag != 10 || string(name) != ldap.NoticeOfDisconnectionOID {
+		t.Errorf("responseName = [%d] %q, %v, want %s", tag, name, err, ldap.NoticeOfDisconnectionOID)
+	}
+}
+
+func TestDisconnectNoticeTooManyConnections(t *testing.T) {
+	tracker := NewConnectionTracker(1)
+	if notice := tracker.add(NewConnection(newMockConn(), nil)); notice != nil {
+		t.Fatalf("first connection refused: %s", notice.message)
+	}
+
+	client := servePipe(t, &Server{Connections: tracker})
+	checkNotice(t, client.closed(), ldap.ResultBusy)
+
+	if n := tracker.Len(); n != 1 {
+		t.Errorf("Len() = %d, want 1", n)
+	}
+}
+
+func TestDisconnectNoticeShutdown(t *testing.T) {
+	tracker := NewConnectionTracker(0)
+	server := &Server{Connections: tracker}
+
+	client := servePipe(t, server)
+	deadline := time.Now().Add(5 * time.Second)
+	for tracker.Len() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("connection not tracked")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	tracker.Drain()
+	checkNotice(t, client.closed(), ldap.ResultUnavailable)
+
+	// Connections accepted while draining are refused
+	late := servePipe(t, server)
+	checkNotice(t, late.closed(), ldap.ResultUnavailable)
+
+	deadline = time.Now().Add(5 * time.Second)
+	for tracker.Len() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Len() = %d after drain, want 0", tracker.Len())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDisconnectNoticeIdleTimeout(t *testing.T) {
+	client := servePipe(t, &Server{IdleTimeout: 50 * time.Millisecond})
+	checkNotice(t, client.closed(), ldap.ResultOther)
+}
+
+func TestDisconnectNoticeInvalidMessage(t *testing.T) {
+	client := servePipe(t, &Server{})
+	// A SEQUENCE holding an OCTET STRING instead of a message ID
+	client.send([]byte{0x30, 0x03, 0x04, 0x01, 'A'})
+	checkNotice(t, client.closed(), ldap.ResultProtocolError)
+}
+
+func TestDisconnectNoticeProtocolErrors(t *testing.T) {
+	client := servePipe(t, &Server{})
+
+	// Clients do not send ExtendedResponses
+	for i := 1; i <= maxProtocolErrors; i++ {
+		enc := ber.NewBEREncoder(16)
+		seq := enc.BeginSequence()
+		enc.WriteInteger(int64(i))
+		app := enc.WriteApplicationTag(ldap.ApplicationExtendedResponse, true)
+		enc.EndApplicationTag(app)
+		enc.EndSequence(seq)
+		client.send(enc.Bytes())
+	}
+
+	msgs := client.closed()
+	if len(msgs) != maxProtocolErrors+1 {
+		t.Fatalf("got %d messages, want %d responses and a notice", len(msgs), maxProtocolErrors)
+	}
+	checkNotice(t, msgs, ldap.ResultProtocolError)
+}
@@ -10,6 +10,18 @@ import (
 const (
 	// PagedResultsOID is the OID for Simple Paged Results Control (RFC 2696).
 	PagedResultsOID = "1.2.840.113556.1.4.319"
+	// TreeDeleteOID is the OID for the Tree Delete Control.
+	// It has no value; its presence on a Delete request asks the server to
+	// delete the entry together with all of its subordinates.
+	TreeDeleteOID = "1.2.840.113556.1.4.805"
+	// ShowDeletedOID is the OID for the Show Deleted Control.
+	// It has no value; its presence on a Search request asks the server to
+	// include deleted entries from the recycle bin in the results.
+	ShowDeletedOID = "1.2.840.113556.1.4.417"
+	// AssertionOID is the OID for the Assertion Control (RFC 4528).
+	// Its value is a Filter; the operation is only performed if the filter
+	// matches the target entry, and fails with assertionFailed otherwise.
+	AssertionOID = "1.3.6.1.1.12"
 )
 
 // PagedResultsControl represents the Simple Paged Results Control (RFC 2696).
@@ -124,3 +136,36 @@ func FindPagedResultsControl(controls []ldap.Control) (*PagedResultsControl, err
 	}
 	return nil, nil
 }
+
+// FindTreeDeleteControl searches for a Tree Delete control in a slice of
+// controls. Returns nil if not found.
+func FindTreeDeleteControl(controls []ldap.Control) *ldap.Control {
+	for i := range controls {
+		if controls[i].OID == TreeDeleteOID {
+			return &controls[i]
+		}
+	}
+	return nil
+}
+
+// FindShowDeletedControl searches for a Show Deleted control in a slice of
+// controls. Returns nil if not found.
+func FindShowDeletedControl(controls []ldap.Control) *ldap.Control {
+	for i := range controls {
+		if controls[i].OID == ShowDeletedOID {
+			return &controls[i]
+		}
+	}
+	return nil
+}
+
+// FindAssertionControl searches for an Assertion control in a slice of
+// controls and returns its filter. Returns nil if not found.
+func FindAssertionControl(controls []ldap.Control) (*ldap.SearchFilter, error) {
+	for _, ctrl := range controls {
+		if ctrl.OID == AssertionOID {
+			return ldap.ParseFilter(ctrl.Value)
+		}
+	}
+	return nil, nil
+}
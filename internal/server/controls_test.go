@@ -0,0 +1,33 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/ber"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+)
+
+// TestFindAssertionControl tests parsing the filter of an Assertion
+// control.
+func TestFindAssertionControl(t *testing.T) {
+	encoder := ber.NewBEREncoder(64)
+	encoder.WriteTaggedValue(ldap.FilterTagPresent, false, []byte("mail"))
+
+	f, err := FindAssertionControl([]ldap.Control{
+		{OID: TreeDeleteOID},
+		{OID: AssertionOID, Criticality: true, Value: encoder.Bytes()},
+	})
+	if err != nil {
+		t.Fatalf("FindAssertionControl() error = %v", err)
+	}
+	if f == nil || f.Type != ldap.FilterTagPresent || f.Attribute != "mail" {
+		t.Errorf("FindAssertionControl() = %+v, want present filter on mail", f)
+	}
+
+	if f, err := FindAssertionControl([]ldap.Control{{OID: TreeDeleteOID}}); f != nil || err != nil {
+		t.Errorf("FindAssertionControl() without control = %v, %v, want nil, nil", f, err)
+	}
+	if _, err := FindAssertionControl([]ldap.Control{{OID: AssertionOID}}); err == nil {
+		t.Error("expected an error for an Assertion control without a filter")
+	}
+}
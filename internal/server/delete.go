@@ -19,6 +19,15 @@ type DeleteBackend interface {
 	HasChildren(dn string) (bool, error)
 }
 
+// SubtreeDeleteBackend is implemented by delete backends that support the
+// Tree Delete control.
+type SubtreeDeleteBackend interface {
+	// DeleteSubtree deletes an entry and all of its descendants. If allow is
+	// not nil it is called for every entry before anything is deleted, and
+	// nothing is deleted if it rejects one. Returns the deleted DNs.
+	DeleteSubtree(dn string, allow func(dn string) bool) ([]string, error)
+}
+
 // DeleteConfig holds configuration for the delete handler.
 type DeleteConfig struct {
 	// Backend is the directory backend for entry operations.
@@ -72,11 +81,7 @@ func (h *DeleteHandlerImpl) Handle(conn *Connection, req *ldap.DeleteRequest) *O
 
 	// Step 4: Check ACL delete permission
 	if h.config.ACLEvaluator != nil {
-		bindDN := ""
-		if conn != nil {
-			bindDN = conn.BindDN()
-		}
-		if !h.config.ACLEvaluator.CanDelete(bindDN, dn) {
+		if !h.config.ACLEvaluator.CheckAccess(accessContext(conn, dn, acl.Delete)) {
 			return &OperationResult{
 				ResultCode:        ldap.ResultInsufficientAccessRights,
 				DiagnosticMessage: "insufficient access rights",
@@ -101,7 +106,20 @@ func (h *DeleteHandlerImpl) Handle(conn *Connection, req *ldap.DeleteRequest) *O
 		}
 	}
 
-	// Step 6: Check if entry has children (LDAP doesn't allow deleting non-leaf entries)
+	// Step 6: With the Tree Delete control, delete the entry and its subtree
+	if ctrl := FindTreeDeleteControl(req.Controls); ctrl != nil {
+		if sb, ok := h.config.Backend.(SubtreeDeleteBackend); ok {
+			return h.deleteSubtree(conn, sb, dn)
+		}
+		if ctrl.Criticality {
+			return &OperationResult{
+				ResultCode:        ldap.ResultUnavailableCriticalExtension,
+				DiagnosticMessage: "tree delete not supported",
+			}
+		}
+	}
+
+	// Step 7: Check if entry has children (LDAP doesn't allow deleting non-leaf entries)
 	hasChildren, err := h.config.Backend.HasChildren(dn)
 	if err != nil {
 		return &OperationResult{
@@ -117,7 +135,7 @@ func (h *DeleteHandlerImpl) Handle(conn *Connection, req *ldap.DeleteRequest) *O
 		}
 	}
 
-	// Step 7: Delete the entry
+	// Step 8: Delete the entry
 	if err := h.config.Backend.DeleteEntry(dn); err != nil {
 		// Check for specific error types
 		if strings.Contains(err.Error(), "not found") {
@@ -133,7 +151,52 @@ func (h *DeleteHandlerImpl) Handle(conn *Connection, req *ldap.DeleteRequest) *O
 		}
 	}
 
-	// Step 8: Return success
+	// Step 9: Return success
+	return &OperationResult{
+		ResultCode: ldap.ResultSuccess,
+	}
+}
+
+// deleteSubtree deletes dn and all of its descendants. The bind DN must have
+// delete rights on every entry of the subtree.
+func (h *DeleteHandlerImpl) deleteSubtree(conn *Connection, sb SubtreeDeleteBackend, dn string) *OperationResult {
+	bindDN := ""
+	if conn != nil {
+		bindDN = conn.BindDN()
+	}
+
+	var allow func(string) bool
+	denied := false
+	if h.config.ACLEvaluator != nil {
+		allow = func(entryDN string) bool {
+			if !h.config.ACLEvaluator.CheckAccess(accessContext(conn, entryDN, acl.Delete)) {
+				denied = true
+				return false
+			}
+			return true
+		}
+	}
+
+	deleted, err := sb.DeleteSubtree(dn, allow)
+	if denied {
+		return &OperationResult{
+			ResultCode:        ldap.ResultInsufficientAccessRights,
+			DiagnosticMessage: "insufficient access rights on subtree",
+		}
+	}
+	if conn != nil && len(deleted) > 0 {
+		conn.Logger().Info("subtree deleted",
+			"dn", dn,
+			"bind_dn", bindDN,
+			"entries", len(deleted))
+	}
+	if err != nil {
+		return &OperationResult{
+			ResultCode:        ldap.ResultOperationsError,
+			DiagnosticMessage: "failed to delete subtree: " + err.Error(),
+		}
+	}
+
 	return &OperationResult{
 		ResultCode: ldap.ResultSuccess,
 	}
@@ -142,7 +205,7 @@ func (h *DeleteHandlerImpl) Handle(conn *Connection, req *ldap.DeleteRequest) *O
 // normalizeDNForDelete normalizes a DN for consistent comparison.
 // It converts to lowercase and trims whitespace.
 func normalizeDNForDelete(dn string) string {
-	return strings.ToLower(strings.TrimSpace(dn))
+	return ldap.CanonicalDN(dn)
 }
 
 // findMatchedDNForDelete finds the longest existing parent DN for error reporting.
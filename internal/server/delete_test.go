@@ -3,8 +3,10 @@ package server
 
 import (
 	"errors"
+	"strings"
 	"testing"
 
+	"github.com/KilimcininKorOglu/oba/internal/acl"
 	"github.com/KilimcininKorOglu/oba/internal/ldap"
 	"github.com/KilimcininKorOglu/oba/internal/storage"
 )
@@ -294,3 +296,91 @@ func TestNewDeleteHandler_NilConfig(t *testing.T) {
 		t.Error("NewDeleteHandler(nil) should create default config")
 	}
 }
+
+// mockSubtreeDeleteBackend adds Tree Delete support to mockDeleteBackend.
+type mockSubtreeDeleteBackend struct {
+	*mockDeleteBackend
+}
+
+func (m *mockSubtreeDeleteBackend) DeleteSubtree(dn string, allow func(dn string) bool) ([]string, error) {
+	suffix := "," + normalizeDN(dn)
+	var dns []string
+	for storedDN := range m.entries {
+		normalized := normalizeDN(storedDN)
+		if normalized == normalizeDN(dn) || strings.HasSuffix(normalized, suffix) {
+			dns = append(dns, storedDN)
+		}
+	}
+	if allow != nil {
+		for _, entryDN := range dns {
+			if !allow(entryDN) {
+				return nil, errors.New("insufficient access")
+			}
+		}
+	}
+	for _, entryDN := range dns {
+		delete(m.entries, entryDN)
+	}
+	return dns, nil
+}
+
+// TestDeleteHandlerImpl_TreeDelete tests deleting a subtree with the Tree Delete control.
+func TestDeleteHandlerImpl_TreeDelete(t *testing.T) {
+	aclConfig := acl.NewConfig()
+	aclConfig.SetDefaultPolicy("deny")
+	aclConfig.AddRule(acl.NewACL("*", "cn=admin,dc=example,dc=com", acl.All))
+	// bob may delete ou=sales itself but none of its children
+	aclConfig.AddRule(acl.NewACL("ou=sales,dc=example,dc=com", "uid=bob,ou=users,dc=example,dc=com", acl.Delete).WithScope(acl.ScopeBase))
+	aclEvaluator := acl.NewEvaluator(aclConfig)
+
+	newBackend := func() *mockDeleteBackend {
+		backend := newMockDeleteBackend()
+		backend.addEntry(storage.NewEntry("ou=sales,dc=example,dc=com"))
+		backend.addEntry(storage.NewEntry("ou=users,ou=sales,dc=example,dc=com"))
+		backend.addEntry(storage.NewEntry("uid=carol,ou=users,ou=sales,dc=example,dc=com"))
+		backend.setHasChildren("ou=sales,dc=example,dc=com", true)
+		return backend
+	}
+	treeDelete := []ldap.Control{{OID: TreeDeleteOID, Criticality: true}}
+
+	tests := []struct {
+		name         string
+		subtree      bool
+		bindDN       string
+		controls     []ldap.Control
+		expectedCode ldap.ResultCode
+		expectedLeft int
+	}{
+		{"without control", true, "cn=admin,dc=example,dc=com", nil, ldap.ResultNotAllowedOnNonLeaf, 3},
+		{"with control", true, "cn=admin,dc=example,dc=com", treeDelete, ldap.ResultSuccess, 0},
+		{"no rights on children", true, "uid=bob,ou=users,dc=example,dc=com", treeDelete, ldap.ResultInsufficientAccessRights, 3},
+		{"backend without support", false, "cn=admin,dc=example,dc=com", treeDelete, ldap.ResultUnavailableCriticalExtension, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newBackend()
+			config := &DeleteConfig{
+				Backend:      backend,
+				ACLEvaluator: aclEvaluator,
+			}
+			if tt.subtree {
+				config.Backend = &mockSubtreeDeleteBackend{backend}
+			}
+			handler := NewDeleteHandler(config)
+
+			req := &ldap.DeleteRequest{
+				DN:       "ou=sales,dc=example,dc=com",
+				Controls: tt.controls,
+			}
+			result := handler.Handle(createACLTestConnection(tt.bindDN), req)
+
+			if result.ResultCode != tt.expectedCode {
+				t.Errorf("Handle() ResultCode = %v, want %v", result.ResultCode, tt.expectedCode)
+			}
+			if len(backend.entries) != tt.expectedLeft {
+				t.Errorf("%d entries left, want %d", len(backend.entries), tt.expectedLeft)
+			}
+		})
+	}
+}
@@ -21,6 +21,12 @@
 // The Handle method runs the main message loop, reading LDAP messages,
 // dispatching them to handlers, and sending responses.
 //
+// When the server closes a connection on its own, Handle first sends a
+// Notice of Disconnection (RFC 4511 Section 4.4.1): when the server is
+// shutting down (ConnectionTracker.Drain), has too many connections, the
+// connection stays idle past Server.IdleTimeout, or the client sends a
+// malformed message or too many unsupported operations.
+//
 // # Operation Handlers
 //
 // Custom handlers can be registered for each LDAP operation:
@@ -71,6 +77,27 @@
 //	remote := conn.RemoteAddr()       // Client address
 //	reqID := conn.RequestID()         // Unique request ID for logging
 //
+// # Locking Rules
+//
+// Handlers may run concurrently on one connection, so Connection follows
+// these rules:
+//
+//   - The identity and TLS state (bind DN, authentication, auth method,
+//     TLS state, client certificate and logger) is an immutable snapshot
+//     behind an atomic pointer. Getters read it without locking; bind,
+//     unbind, StartTLS and SetLogger publish a changed copy. A handler
+//     that needs several fields should read them from one snapshot.
+//   - WriteMessage takes the writer lock for the write of one encoded
+//     message only, so messages never interleave on the wire.
+//   - The closed flag is atomic; Close runs its cleanup once.
+//   - The connection mutex guards the remaining bookkeeping fields
+//     (message ID, bind failures, buffered entries, trace context and
+//     session handlers). It is held only to read or update them, never
+//     while a handler runs or the connection reads or writes.
+//
+// No lock is held across a call into a handler, and no two connection
+// locks are held at once.
+//
 // # Logging
 //
 // Each connection has an associated logger with request ID:
@@ -0,0 +1,70 @@
+package server
+
+import "time"
+
+// EventType identifies a server event reported to an EventHook.
+type EventType int
+
+// Server events.
+const (
+	// EventServerStart is reported when the server accepts connections.
+	EventServerStart EventType = iota + 1
+	// EventServerStop is reported when the server shuts down.
+	EventServerStop
+	// EventBindFailure is reported for every failed bind.
+	EventBindFailure
+	// EventReplicationLag is reported when the node falls behind the
+	// cluster commit index.
+	EventReplicationLag
+	// EventStorageFull is reported when the data volume is nearly full.
+	EventStorageFull
+)
+
+// String returns the name of the event type.
+func (t EventType) String() string {
+	switch t {
+	case EventServerStart:
+		return "server_start"
+	case EventServerStop:
+		return "server_stop"
+	case EventBindFailure:
+		return "bind_failure"
+	case EventReplicationLag:
+		return "replication_lag"
+	case EventStorageFull:
+		return "storage_full"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a server event of interest to monitoring systems.
+type Event struct {
+	Type EventType
+	Time time.Time
+	// Message describes the event
+	Message string
+	// BindDN and ClientAddr are set for EventBindFailure
+	BindDN     string
+	ClientAddr string
+	// ReplicationLag is the number of committed log entries not yet
+	// applied, set for EventReplicationLag
+	ReplicationLag uint64
+	// StorageUsedPercent is set for EventStorageFull
+	StorageUsedPercent int
+}
+
+// EventHook receives server events. It is called synchronously from the
+// goroutine that observed the event, so it must not block.
+type EventHook func(Event)
+
+// emitEvent reports event to the event hook of the parent server, if any.
+func (c *Connection) emitEvent(event Event) {
+	if c.server == nil || c.server.Events == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	c.server.Events(event)
+}
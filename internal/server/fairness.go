@@ -0,0 +1,175 @@
+// Package server provides the LDAP server implementation.
+package server
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+)
+
+// Fairness limit names, used as the metrics label of rejected operations
+const (
+	// LimitBufferedEntries limits the search entries a connection holds
+	LimitBufferedEntries = "buffered_entries"
+	// LimitExpensiveSearches limits concurrent expensive searches
+	LimitExpensiveSearches = "expensive_searches"
+	// LimitBindDNOperations limits concurrent operations per bind DN
+	LimitBindDNOperations = "bind_dn_operations"
+)
+
+// FairnessConfig holds limits that keep one client from monopolizing the
+// response writer and the buffer pool. A zero limit is disabled.
+type FairnessConfig struct {
+	// MaxBufferedEntries is the number of search result entries a
+	// connection may hold for writing across its outstanding searches
+	MaxBufferedEntries int
+	// MaxExpensiveSearches is the number of expensive searches that may
+	// run at once across all connections
+	MaxExpensiveSearches int
+	// ExpensiveSearchThreshold is the estimated number of examined entries
+	// above which a search is expensive
+	ExpensiveSearchThreshold int
+	// MaxOperationsPerBindDN is the number of operations a bound DN may
+	// have in progress across all of its connections
+	MaxOperationsPerBindDN int
+}
+
+// SearchEstimator returns the number of entries a search would examine,
+// without running it. Returning 0 means unknown.
+type SearchEstimator func(req *ldap.SearchRequest) int
+
+// Fairness enforces the limits of a FairnessConfig. It is shared by all
+// connections of a server.
+type Fairness struct {
+	config   FairnessConfig
+	estimate SearchEstimator
+
+	// expensive holds a token for each running expensive search
+	expensive chan struct{}
+
+	mu         sync.Mutex
+	operations map[string]int
+}
+
+// NewFairness creates the fairness limits of config. estimate sizes
+// searches for the expensive search limit; without one that limit is not
+// enforced.
+func NewFairness(config FairnessConfig, estimate SearchEstimator) *Fairness {
+	f := &Fairness{
+		config:     config,
+		estimate:   estimate,
+		operations: make(map[string]int),
+	}
+	if config.MaxExpensiveSearches > 0 && estimate != nil {
+		f.expensive = make(chan struct{}, config.MaxExpensiveSearches)
+	}
+	return f
+}
+
+// Config returns the configured limits.
+func (f *Fairness) Config() FairnessConfig {
+	return f.config
+}
+
+// acquireOperation reserves an operation slot for bindDN. Anonymous
+// operations are not limited. The returned release must be called when the
+// operation is done.
+func (f *Fairness) acquireOperation(bindDN string) (release func(), err error) {
+	if f.config.MaxOperationsPerBindDN <= 0 || bindDN == "" {
+		return func() {}, nil
+	}
+
+	key := strings.ToLower(bindDN)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.operations[key] >= f.config.MaxOperationsPerBindDN {
+		return nil, fmt.Errorf("too many operations in progress for %s (limit %d)", bindDN, f.config.MaxOperationsPerBindDN)
+	}
+	f.operations[key]++
+
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.operations[key]--; f.operations[key] <= 0 {
+			delete(f.operations, key)
+		}
+	}, nil
+}
+
+// acquireSearch reserves an expensive search slot if req is estimated to
+// examine more entries than the threshold. The returned release must be
+// called when the search is done.
+func (f *Fairness) acquireSearch(req *ldap.SearchRequest) (release func(), err error) {
+	if f.expensive == nil {
+		return func() {}, nil
+	}
+
+	estimate := f.estimate(req)
+	if estimate <= f.config.ExpensiveSearchThreshold {
+		return func() {}, nil
+	}
+
+	select {
+	case f.expensive <- struct{}{}:
+		return func() { <-f.expensive }, nil
+	default:
+		return nil, fmt.Errorf("too many expensive searches in progress (search examines about %d entries, limit %d concurrent)", estimate, f.config.MaxExpensiveSearches)
+	}
+}
+
+// reserveEntries reserves room for n search result entries in the write
+// buffer of c. The returned release must be called once the entries have
+// been written.
+func (f *Fairness) reserveEntries(c *Connection, n int) (release func(), err error) {
+	if f.config.MaxBufferedEntries <= 0 {
+		return func() {}, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.bufferedEntries+n > f.config.MaxBufferedEntries {
+		return nil, fmt.Errorf("search returned %d entries, more than this connection may buffer (limit %d)", n, f.config.MaxBufferedEntries)
+	}
+	c.bufferedEntries += n
+
+	return func() {
+		c.mu.Lock()
+		c.bufferedEntries -= n
+		c.mu.Unlock()
+	}, nil
+}
+
+// rejectFairness counts an operation rejected by limit and logs it.
+func (c *Connection) rejectFairness(limit string, err error) {
+	if m := c.metrics(); m != nil {
+		m.FairnessRejectionsTotal.WithLabelValues(limit).Inc()
+	}
+	c.Logger().Warn("operation rejected by fairness limit",
+		"limit", limit,
+		"bind_dn", c.BindDN(),
+		"error", err.Error())
+}
+
+// createBusyResponse creates the busy response of the operation of msg.
+func (c *Connection) createBusyResponse(msg *ldap.LDAPMessage, diagnosticMessage string) *ldap.LDAPMessage {
+	switch msg.OperationType() {
+	case ldap.OperationType(ldap.ApplicationSearchRequest):
+		return c.createSearchDoneResponse(msg.MessageID, ldap.ResultBusy, "", diagnosticMessage)
+	case ldap.OperationType(ldap.ApplicationAddRequest):
+		return c.createAddResponse(msg.MessageID, ldap.ResultBusy, "", diagnosticMessage)
+	case ldap.OperationType(ldap.ApplicationDelRequest):
+		return c.createDeleteResponse(msg.MessageID, ldap.ResultBusy, "", diagnosticMessage)
+	case ldap.OperationType(ldap.ApplicationModifyRequest):
+		return c.createModifyResponse(msg.MessageID, ldap.ResultBusy, "", diagnosticMessage)
+	case ldap.OperationType(ldap.ApplicationModifyDNRequest):
+		return c.createModifyDNResponse(msg.MessageID, ldap.ResultBusy, "", diagnosticMessage)
+	case ldap.OperationType(ldap.ApplicationCompareRequest):
+		return c.createCompareResponse(msg.MessageID, ldap.ResultBusy, "", diagnosticMessage)
+	default:
+		return c.createErrorResponse(msg.MessageID, ldap.ResultBusy, diagnosticMessage)
+	}
+}
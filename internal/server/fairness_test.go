@@ -0,0 +1,126 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+)
+
+func TestFairnessOperationsPerBindDN(t *testing.T) {
+	f := NewFairness(FairnessConfig{MaxOperationsPerBindDN: 2}, nil)
+
+	release1, err := f.acquireOperation("cn=app,dc=example,dc=com")
+	if err != nil {
+		t.Fatalf("first operation rejected: %v", err)
+	}
+	if _, err := f.acquireOperation("CN=App,DC=Example,DC=Com"); err != nil {
+		t.Fatalf("second operation rejected: %v", err)
+	}
+	if _, err := f.acquireOperation("cn=app,dc=example,dc=com"); err == nil {
+		t.Fatal("expected third operation of the same DN to be rejected")
+	}
+
+	// Other DNs and anonymous operations have their own budget
+	if _, err := f.acquireOperation("cn=other,dc=example,dc=com"); err != nil {
+		t.Errorf("operation of another DN rejected: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := f.acquireOperation(""); err != nil {
+			t.Fatalf("anonymous operation rejected: %v", err)
+		}
+	}
+
+	release1()
+	if _, err := f.acquireOperation("cn=app,dc=example,dc=com"); err != nil {
+		t.Errorf("operation rejected after release: %v", err)
+	}
+}
+
+func TestFairnessExpensiveSearches(t *testing.T) {
+	estimate := func(req *ldap.SearchRequest) int {
+		if req.BaseObject == "dc=example,dc=com" {
+			return 5000
+		}
+		return 10
+	}
+	f := NewFairness(FairnessConfig{MaxExpensiveSearches: 1, ExpensiveSearchThreshold: 1000}, estimate)
+
+	expensive := &ldap.SearchRequest{BaseObject: "dc=example,dc=com"}
+	cheap := &ldap.SearchRequest{BaseObject: "ou=small,dc=example,dc=com"}
+
+	release, err := f.acquireSearch(expensive)
+	if err != nil {
+		t.Fatalf("first expensive search rejected: %v", err)
+	}
+	if _, err := f.acquireSearch(expensive); err == nil {
+		t.Fatal("expected second expensive search to be rejected")
+	}
+	if _, err := f.acquireSearch(cheap); err != nil {
+		t.Errorf("cheap search rejected: %v", err)
+	}
+
+	release()
+	if _, err := f.acquireSearch(expensive); err != nil {
+		t.Errorf("expensive search rejected after release: %v", err)
+	}
+
+	t.Run("without estimator", func(t *testing.T) {
+		f := NewFairness(FairnessConfig{MaxExpensiveSearches: 1, ExpensiveSearchThreshold: 1}, nil)
+		for i := 0; i < 3; i++ {
+			if _, err := f.acquireSearch(expensive); err != nil {
+				t.Fatalf("search rejected without estimator: %v", err)
+			}
+		}
+	})
+}
+
+func TestConnectionFairnessBufferedEntries(t *testing.T) {
+	handler := NewHandler()
+	handler.SetSearchHandler(func(conn *Connection, req *ldap.SearchRequest) *SearchResult {
+		result := &SearchResult{OperationResult: OperationResult{ResultCode: ldap.ResultSuccess}}
+		n := 2
+		if req.BaseObject == "dc=example,dc=com" {
+			n = 3
+		}
+		for i := 0; i < n; i++ {
+			result.Entries = append(result.Entries, &SearchEntry{DN: "cn=entry,dc=example,dc=com"})
+		}
+		return result
+	})
+	handler.SetFairness(NewFairness(FairnessConfig{MaxBufferedEntries: 2}, nil))
+
+	m := NewMetrics()
+	mockConn := newMockConn()
+	mockConn.setReadData(append(append(
+		createSearchRequestMessage(1, "dc=example,dc=com"),
+		createSearchRequestMessage(2, "ou=users,dc=example,dc=com")...),
+		createUnbindRequestMessage(3)...))
+	NewConnection(mockConn, &Server{Handler: handler, Metrics: m}).Handle()
+
+	entries := make(map[int]int)
+	codes := make(map[int]ldap.ResultCode)
+	r := bytes.NewReader(mockConn.getWrittenData())
+	for r.Len() > 0 {
+		msg, err := readLDAPMessage(r)
+		if err != nil {
+			t.Fatalf("failed to read response: %v", err)
+		}
+		switch msg.Operation.Tag {
+		case ldap.ApplicationSearchResultEntry:
+			entries[msg.MessageID]++
+		case ldap.ApplicationSearchResultDone:
+			codes[msg.MessageID], _ = responseResultCode(msg)
+		}
+	}
+
+	if codes[1] != ldap.ResultBusy || entries[1] != 0 {
+		t.Errorf("search over the budget: code %v with %d entries, want busy with none", codes[1], entries[1])
+	}
+	if codes[2] != ldap.ResultSuccess || entries[2] != 2 {
+		t.Errorf("search within the budget: code %v with %d entries, want success with 2", codes[2], entries[2])
+	}
+	if got := m.FairnessRejectionsTotal.WithLabelValues(LimitBufferedEntries).Value(); got != 1 {
+		t.Errorf("buffered entry rejections = %d, want 1", got)
+	}
+}
@@ -2,6 +2,8 @@
 package server
 
 import (
+	"time"
+
 	"github.com/KilimcininKorOglu/oba/internal/ldap"
 )
 
@@ -23,12 +25,21 @@ type SearchEntry struct {
 	Attributes []ldap.Attribute
 }
 
+// SearchReference represents a search result continuation reference.
+// It is returned for referral entries found within the search scope.
+type SearchReference struct {
+	// URIs contains alternative LDAP URLs for the referenced subtree
+	URIs []string
+}
+
 // SearchResult represents the result of a search operation.
 type SearchResult struct {
 	// OperationResult contains the result code and messages
 	OperationResult
 	// Entries contains the search result entries
 	Entries []*SearchEntry
+	// References contains continuation references that were not chased
+	References []*SearchReference
 }
 
 // BindHandler handles bind requests.
@@ -68,6 +79,20 @@ type Handler struct {
 	modifyDNHandler ModifyDNHandler
 	// compareHandler handles compare requests
 	compareHandler CompareHandler
+	// attributeACL masks unreadable attributes in search results
+	attributeACL AttributeAccessChecker
+	// collective supplies the collective attributes merged into search results
+	collective CollectiveAttributeSource
+	// bindThrottle delays failed binds from source IPs with many failures
+	bindThrottle *BindThrottle
+	// fairness limits how much of the server one client can hold
+	fairness *Fairness
+	// extended dispatches extended requests by OID
+	extended *ExtendedDispatcher
+	// maxTimeLimit caps the time limit of searches in seconds (0 = no cap)
+	maxTimeLimit int
+	// limits holds the search limit profiles (nil if there are none)
+	limits *SearchLimits
 }
 
 // NewHandler creates a new Handler with default handlers.
@@ -118,6 +143,97 @@ func (h *Handler) SetCompareHandler(handler CompareHandler) {
 	h.compareHandler = handler
 }
 
+// SetAttributeACL sets the checker used to remove attributes the bound user
+// may not read from search results. A nil checker disables masking.
+func (h *Handler) SetAttributeACL(checker AttributeAccessChecker) {
+	h.attributeACL = checker
+}
+
+// SetCollectiveAttributes sets the source of the collective attributes
+// merged into search results. A nil source disables collective attributes.
+func (h *Handler) SetCollectiveAttributes(src CollectiveAttributeSource) {
+	h.collective = src
+}
+
+// SetBindThrottle sets the throttle used to tarpit failed binds per source
+// IP. A nil throttle disables throttling.
+func (h *Handler) SetBindThrottle(t *BindThrottle) {
+	h.bindThrottle = t
+}
+
+// BindThrottle returns the bind throttle, or nil if throttling is disabled.
+func (h *Handler) BindThrottle() *BindThrottle {
+	return h.bindThrottle
+}
+
+// SetFairness sets the limits that keep one client from monopolizing the
+// server. A nil value disables them.
+func (h *Handler) SetFairness(f *Fairness) {
+	h.fairness = f
+}
+
+// Fairness returns the fairness limits, or nil if they are disabled.
+func (h *Handler) Fairness() *Fairness {
+	return h.fairness
+}
+
+// SetExtendedDispatcher sets the dispatcher of extended requests. A nil
+// dispatcher rejects every extended request.
+func (h *Handler) SetExtendedDispatcher(d *ExtendedDispatcher) {
+	h.extended = d
+}
+
+// ExtendedDispatcher returns the extended dispatcher, or nil if extended
+// requests are rejected.
+func (h *Handler) ExtendedDispatcher() *ExtendedDispatcher {
+	return h.extended
+}
+
+// SetMaxTimeLimit caps the time limit clients may request for a search, in
+// seconds, like SearchConfig.MaxTimeLimit. Zero leaves requested limits
+// uncapped.
+func (h *Handler) SetMaxTimeLimit(seconds int) {
+	h.maxTimeLimit = seconds
+}
+
+// SetSearchLimits sets the limit profiles of searches. A nil value leaves
+// searches with the requested limits and the maximum time limit.
+func (h *Handler) SetSearchLimits(l *SearchLimits) {
+	h.limits = l
+}
+
+// SearchLimits returns the search limit profiles, or nil if there are none.
+func (h *Handler) SearchLimits() *SearchLimits {
+	return h.limits
+}
+
+// searchLimits returns the limits the search req of bindDN runs with: the
+// requested limits capped by the profile of bindDN. Without a profile
+// limit, a requested time limit is capped by the maximum time limit.
+func (h *Handler) searchLimits(bindDN string, req *ldap.SearchRequest) searchLimit {
+	limit := searchLimit{sizeLimit: req.SizeLimit}
+
+	timeLimit := req.TimeLimit
+	if h.maxTimeLimit > 0 && timeLimit > h.maxTimeLimit {
+		timeLimit = h.maxTimeLimit
+	}
+
+	if h.limits != nil {
+		if p := h.limits.Resolve(bindDN); p != nil {
+			limit.subject = p.Subject
+			if p.SizeLimit >= 0 {
+				limit.sizeLimit = capLimit(req.SizeLimit, p.SizeLimit)
+			}
+			if p.TimeLimit >= 0 {
+				timeLimit = capLimit(req.TimeLimit, p.TimeLimit)
+			}
+		}
+	}
+
+	limit.timeLimit = time.Duration(timeLimit) * time.Second
+	return limit
+}
+
 // HandleBind handles a bind request.
 func (h *Handler) HandleBind(conn *Connection, req *ldap.BindRequest) *OperationResult {
 	if h.bindHandler == nil {
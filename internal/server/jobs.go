@@ -0,0 +1,427 @@
+// Package server provides the LDAP server implementation.
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/logging"
+)
+
+// JobType identifies the operation a job runs.
+type JobType string
+
+// Job types.
+const (
+	JobIndexRebuild JobType = "index-rebuild"
+	JobCompaction   JobType = "compaction"
+)
+
+// JobState is the state of a job.
+type JobState string
+
+// Job states.
+const (
+	JobPending   JobState = "pending"
+	JobRunning   JobState = "running"
+	JobSucceeded JobState = "succeeded"
+	JobFailed    JobState = "failed"
+	JobCanceled  JobState = "canceled"
+)
+
+// Finished reports whether a job in state s has ended.
+func (s JobState) Finished() bool {
+	return s == JobSucceeded || s == JobFailed || s == JobCanceled
+}
+
+// Job manager limits.
+const (
+	// maxFinishedJobs bounds the number of finished jobs kept in the history
+	maxFinishedJobs = 100
+	// maxJobLogLines bounds the log lines kept per job; older lines are dropped
+	maxJobLogLines = 100
+)
+
+// Job manager errors.
+var (
+	ErrJobNotFound      = errors.New("job not found")
+	ErrJobFinished      = errors.New("job has already finished")
+	ErrJobNotCancelable = errors.New("job cannot be canceled")
+	ErrJobResourceBusy  = errors.New("another job is using the resource")
+	ErrJobManagerClosed = errors.New("job manager is closed")
+)
+
+// errJobInterrupted is recorded for jobs that were running when the server
+// stopped without finishing them.
+var errJobInterrupted = errors.New("interrupted by server shutdown")
+
+// JobLogLine is a message logged by a job.
+type JobLogLine struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// Job is a long running maintenance operation.
+type Job struct {
+	ID   string  `json:"id"`
+	Type JobType `json:"type"`
+	// Target is what the job works on, such as the attribute of an index
+	Target string `json:"target,omitempty"`
+	// Resource is the exclusive resource the job holds while it runs
+	Resource   string           `json:"resource,omitempty"`
+	State      JobState         `json:"state"`
+	Cancelable bool             `json:"cancelable"`
+	Progress   map[string]int64 `json:"progress,omitempty"`
+	Logs       []JobLogLine     `json:"logs,omitempty"`
+	CreatedAt  time.Time        `json:"createdAt"`
+	StartedAt  *time.Time       `json:"startedAt,omitempty"`
+	FinishedAt *time.Time       `json:"finishedAt,omitempty"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// clone returns a deep copy of the job.
+func (j *Job) clone() Job {
+	c := *j
+	if j.Progress != nil {
+		c.Progress = make(map[string]int64, len(j.Progress))
+		for k, v := range j.Progress {
+			c.Progress[k] = v
+		}
+	}
+	c.Logs = append([]JobLogLine(nil), j.Logs...)
+	return c
+}
+
+// JobSpec describes a job to start.
+type JobSpec struct {
+	Type   JobType
+	Target string
+	// Resource, if not empty, names a resource only one job may use at a
+	// time. Starting a job fails with ErrJobResourceBusy while another
+	// pending or running job holds it.
+	Resource string
+	// Cancelable marks jobs whose function stops when its context is
+	// canceled.
+	Cancelable bool
+}
+
+// JobFunc is the operation a job runs. It reports progress and log lines
+// through r, and should return ctx.Err() when ctx is canceled.
+type JobFunc func(ctx context.Context, r *JobReporter) error
+
+// JobReporter records the progress and log lines of a running job.
+type JobReporter struct {
+	jm  *JobManager
+	job *Job
+}
+
+// SetProgress sets a progress counter of the job.
+func (r *JobReporter) SetProgress(key string, value int64) {
+	r.jm.mu.Lock()
+	defer r.jm.mu.Unlock()
+
+	if r.job.Progress == nil {
+		r.job.Progress = make(map[string]int64)
+	}
+	r.job.Progress[key] = value
+}
+
+// Logf adds a log line to the job.
+func (r *JobReporter) Logf(format string, args ...interface{}) {
+	r.jm.mu.Lock()
+	defer r.jm.mu.Unlock()
+
+	r.job.Logs = append(r.job.Logs, JobLogLine{
+		Time:    time.Now().UTC(),
+		Message: fmt.Sprintf(format, args...),
+	})
+	if over := len(r.job.Logs) - maxJobLogLines; over > 0 {
+		r.job.Logs = r.job.Logs[over:]
+	}
+}
+
+// JobManager runs admin jobs in the background, keeps their state and
+// history, and serializes jobs that use the same resource. The history is
+// kept in a catalog file, if one is set, so that it survives restarts.
+type JobManager struct {
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	cancels map[string]context.CancelFunc
+	closed  bool
+	wg      sync.WaitGroup
+
+	// catalogFile is the path of the history file, or "" to keep none
+	catalogFile string
+	// saveErr is the error of the last failed catalog save, cleared by the
+	// next successful one
+	saveErr error
+	logger  logging.Logger
+}
+
+// NewJobManager creates a job manager. If catalogFile is not empty the job
+// history is loaded from it and saved to it whenever a job changes state.
+// Jobs the file lists as pending or running were cut short by a shutdown
+// and are marked failed.
+func NewJobManager(catalogFile string) (*JobManager, error) {
+	jm := &JobManager{
+		jobs:        make(map[string]*Job),
+		cancels:     make(map[string]context.CancelFunc),
+		catalogFile: catalogFile,
+	}
+	if catalogFile == "" {
+		return jm, nil
+	}
+
+	data, err := os.ReadFile(catalogFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return jm, nil
+		}
+		return nil, err
+	}
+
+	var jobs []*Job
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("invalid job catalog %s: %w", catalogFile, err)
+	}
+	for _, job := range jobs {
+		if !job.State.Finished() {
+			finished := time.Now().UTC()
+			job.State = JobFailed
+			job.Error = errJobInterrupted.Error()
+			job.FinishedAt = &finished
+		}
+		jm.jobs[job.ID] = job
+	}
+	return jm, nil
+}
+
+// SetLogger sets the logger used to report failures to save the history.
+func (jm *JobManager) SetLogger(logger logging.Logger) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	jm.logger = logger
+}
+
+// SaveError returns the error of the last failed history save, or nil if
+// the last save succeeded.
+func (jm *JobManager) SaveError() error {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	return jm.saveErr
+}
+
+// Start creates a job for spec and runs fn for it in a new goroutine. It
+// returns the job as created, in the pending state.
+func (jm *JobManager) Start(spec JobSpec, fn JobFunc) (Job, error) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	if jm.closed {
+		return Job{}, ErrJobManagerClosed
+	}
+	if spec.Resource != "" {
+		for _, other := range jm.jobs {
+			if other.Resource == spec.Resource && !other.State.Finished() {
+				return Job{}, fmt.Errorf("%w: %s is held by job %s", ErrJobResourceBusy, spec.Resource, other.ID)
+			}
+		}
+	}
+
+	job := &Job{
+		ID:         newJobID(),
+		Type:       spec.Type,
+		Target:     spec.Target,
+		Resource:   spec.Resource,
+		State:      JobPending,
+		Cancelable: spec.Cancelable,
+		CreatedAt:  time.Now().UTC(),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	jm.jobs[job.ID] = job
+	jm.cancels[job.ID] = cancel
+	jm.saveLocked()
+
+	jm.wg.Add(1)
+	go jm.run(ctx, job, fn)
+	return job.clone(), nil
+}
+
+// run runs fn for job and records the result.
+func (jm *JobManager) run(ctx context.Context, job *Job, fn JobFunc) {
+	defer jm.wg.Done()
+
+	jm.mu.Lock()
+	started := time.Now().UTC()
+	job.StartedAt = &started
+	job.State = JobRunning
+	jm.saveLocked()
+	jm.mu.Unlock()
+
+	err := fn(ctx, &JobReporter{jm: jm, job: job})
+
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	finished := time.Now().UTC()
+	job.FinishedAt = &finished
+	switch {
+	case err == nil:
+		job.State = JobSucceeded
+	case ctx.Err() != nil && errors.Is(err, ctx.Err()):
+		job.State = JobCanceled
+	default:
+		job.State = JobFailed
+		job.Error = err.Error()
+	}
+	if jm.closed && job.State == JobCanceled {
+		// Canceled by Close rather than by an operator
+		job.State = JobFailed
+		job.Error = errJobInterrupted.Error()
+	}
+
+	if cancel := jm.cancels[job.ID]; cancel != nil {
+		cancel()
+		delete(jm.cancels, job.ID)
+	}
+	jm.pruneLocked()
+	jm.saveLocked()
+}
+
+// Get returns a copy of the job with the given ID.
+func (jm *JobManager) Get(id string) (Job, bool) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	job, ok := jm.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return job.clone(), true
+}
+
+// List returns copies of all jobs, most recently created first.
+func (jm *JobManager) List() []Job {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	jobs := make([]Job, 0, len(jm.jobs))
+	for _, job := range jm.jobs {
+		jobs = append(jobs, job.clone())
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+			return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+	return jobs
+}
+
+// Cancel asks the job with the given ID to stop. The job is marked
+// canceled once its function returns.
+func (jm *JobManager) Cancel(id string) error {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	job, ok := jm.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+	if job.State.Finished() {
+		return ErrJobFinished
+	}
+	if !job.Cancelable {
+		return ErrJobNotCancelable
+	}
+	jm.cancels[id]()
+	return nil
+}
+
+// Close cancels the running jobs, waits for them to return and saves the
+// history. Jobs cut short are recorded as failed.
+func (jm *JobManager) Close() {
+	jm.mu.Lock()
+	if jm.closed {
+		jm.mu.Unlock()
+		return
+	}
+	jm.closed = true
+	for _, cancel := range jm.cancels {
+		cancel()
+	}
+	jm.mu.Unlock()
+
+	jm.wg.Wait()
+}
+
+// pruneLocked drops the oldest finished jobs beyond maxFinishedJobs. Must
+// be called with jm.mu held.
+func (jm *JobManager) pruneLocked() {
+	var finished []*Job
+	for _, job := range jm.jobs {
+		if job.State.Finished() {
+			finished = append(finished, job)
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].FinishedAt.Before(*finished[j].FinishedAt)
+	})
+	for _, job := range finished[:len(finished)-maxFinishedJobs] {
+		delete(jm.jobs, job.ID)
+	}
+}
+
+// saveLocked writes the job history to the catalog file. A failed write
+// only loses history, so it does not fail the job; it is logged and kept
+// for SaveError. Must be called with jm.mu held.
+func (jm *JobManager) saveLocked() {
+	if jm.catalogFile == "" {
+		return
+	}
+
+	err := jm.writeCatalogLocked()
+	if err != nil && jm.logger != nil {
+		jm.logger.Error("failed to save job history", "file", jm.catalogFile, "error", err)
+	}
+	jm.saveErr = err
+}
+
+// writeCatalogLocked writes the jobs to a temporary file and renames it
+// over the catalog file. Must be called with jm.mu held.
+func (jm *JobManager) writeCatalogLocked() error {
+	jobs := make([]*Job, 0, len(jm.jobs))
+	for _, job := range jm.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.Before(jobs[j].CreatedAt) })
+
+	data, err := json.Marshal(jobs)
+	if err != nil {
+		return err
+	}
+	tmpPath := jm.catalogFile + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, jm.catalogFile)
+}
+
+// newJobID returns a random job ID.
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
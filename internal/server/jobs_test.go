@@ -0,0 +1,225 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitForJobState polls jm until the job reaches a finished state.
+func waitForJobState(t *testing.T, jm *JobManager, id string) Job {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, ok := jm.Get(id)
+		if !ok {
+			t.Fatalf("job %s not found", id)
+		}
+		if job.State.Finished() {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s still %s", id, job.State)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestJobManagerRun(t *testing.T) {
+	jm, err := NewJobManager("")
+	if err != nil {
+		t.Fatalf("NewJobManager() error = %v", err)
+	}
+	defer jm.Close()
+
+	succeeded, err := jm.Start(JobSpec{Type: JobCompaction}, func(ctx context.Context, r *JobReporter) error {
+		r.SetProgress("pages", 7)
+		r.Logf("compacted %d pages", 7)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if succeeded.State != JobPending {
+		t.Errorf("started state = %s, want pending", succeeded.State)
+	}
+
+	failed, err := jm.Start(JobSpec{Type: JobCompaction}, func(ctx context.Context, r *JobReporter) error {
+		return errors.New("disk full")
+	})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	job := waitForJobState(t, jm, succeeded.ID)
+	if job.State != JobSucceeded || job.Progress["pages"] != 7 || len(job.Logs) != 1 {
+		t.Errorf("job = %+v, want succeeded with progress and a log line", job)
+	}
+	if job.StartedAt == nil || job.FinishedAt == nil {
+		t.Errorf("job times not set: %+v", job)
+	}
+
+	job = waitForJobState(t, jm, failed.ID)
+	if job.State != JobFailed || job.Error != "disk full" {
+		t.Errorf("job = %+v, want failed with the error", job)
+	}
+
+	if jobs := jm.List(); len(jobs) != 2 || jobs[0].ID != failed.ID {
+		t.Errorf("List() = %+v, want both jobs, newest first", jobs)
+	}
+}
+
+func TestJobManagerResourceBusy(t *testing.T) {
+	jm, _ := NewJobManager("")
+	defer jm.Close()
+
+	release := make(chan struct{})
+	first, err := jm.Start(JobSpec{Type: JobCompaction, Resource: "compaction"}, func(ctx context.Context, r *JobReporter) error {
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	noop := func(ctx context.Context, r *JobReporter) error { return nil }
+	if _, err := jm.Start(JobSpec{Type: JobCompaction, Resource: "compaction"}, noop); !errors.Is(err, ErrJobResourceBusy) {
+		t.Errorf("Start() error = %v, want ErrJobResourceBusy", err)
+	}
+	if _, err := jm.Start(JobSpec{Type: JobIndexRebuild, Resource: "index:uid"}, noop); err != nil {
+		t.Errorf("Start() of another resource error = %v", err)
+	}
+
+	close(release)
+	waitForJobState(t, jm, first.ID)
+	if _, err := jm.Start(JobSpec{Type: JobCompaction, Resource: "compaction"}, noop); err != nil {
+		t.Errorf("Start() after the resource was released error = %v", err)
+	}
+}
+
+func TestJobManagerCancel(t *testing.T) {
+	jm, _ := NewJobManager("")
+	defer jm.Close()
+
+	wait := func(ctx context.Context, r *JobReporter) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	release := make(chan struct{})
+	defer close(release)
+
+	cancelable, _ := jm.Start(JobSpec{Type: JobIndexRebuild, Cancelable: true}, wait)
+	fixed, _ := jm.Start(JobSpec{Type: JobCompaction}, func(ctx context.Context, r *JobReporter) error {
+		<-release
+		return nil
+	})
+
+	if err := jm.Cancel("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Cancel(missing) error = %v, want ErrJobNotFound", err)
+	}
+	if err := jm.Cancel(fixed.ID); !errors.Is(err, ErrJobNotCancelable) {
+		t.Errorf("Cancel() error = %v, want ErrJobNotCancelable", err)
+	}
+	if err := jm.Cancel(cancelable.ID); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if job := waitForJobState(t, jm, cancelable.ID); job.State != JobCanceled {
+		t.Errorf("state = %s, want canceled", job.State)
+	}
+	if err := jm.Cancel(cancelable.ID); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("Cancel() of a finished job error = %v, want ErrJobFinished", err)
+	}
+}
+
+func TestJobManagerHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+
+	jm, err := NewJobManager(path)
+	if err != nil {
+		t.Fatalf("NewJobManager() error = %v", err)
+	}
+	done, _ := jm.Start(JobSpec{Type: JobCompaction}, func(ctx context.Context, r *JobReporter) error {
+		return errors.New("disk full")
+	})
+	waitForJobState(t, jm, done.ID)
+
+	running, _ := jm.Start(JobSpec{Type: JobIndexRebuild, Target: "uid", Cancelable: true}, func(ctx context.Context, r *JobReporter) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	jm.Close()
+
+	if _, err := jm.Start(JobSpec{Type: JobCompaction}, nil); !errors.Is(err, ErrJobManagerClosed) {
+		t.Errorf("Start() after Close error = %v, want ErrJobManagerClosed", err)
+	}
+
+	reloaded, err := NewJobManager(path)
+	if err != nil {
+		t.Fatalf("NewJobManager() reload error = %v", err)
+	}
+	defer reloaded.Close()
+
+	job, ok := reloaded.Get(done.ID)
+	if !ok || job.State != JobFailed || job.Error != "disk full" {
+		t.Errorf("reloaded job = %+v, want failed with the error", job)
+	}
+	job, ok = reloaded.Get(running.ID)
+	if !ok || job.State != JobFailed || job.Error != errJobInterrupted.Error() || job.Target != "uid" {
+		t.Errorf("reloaded job = %+v, want failed as interrupted", job)
+	}
+}
+
+func TestJobManagerInterruptedOnLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+
+	jm, _ := NewJobManager(path)
+	release := make(chan struct{})
+	running, _ := jm.Start(JobSpec{Type: JobCompaction}, func(ctx context.Context, r *JobReporter) error {
+		<-release
+		return nil
+	})
+	defer func() {
+		close(release)
+		jm.Close()
+	}()
+
+	// The catalog still lists the job as pending or running, as after a
+	// crash
+	reloaded, err := NewJobManager(path)
+	if err != nil {
+		t.Fatalf("NewJobManager() error = %v", err)
+	}
+	job, ok := reloaded.Get(running.ID)
+	if !ok || job.State != JobFailed || job.FinishedAt == nil {
+		t.Errorf("reloaded job = %+v, want failed", job)
+	}
+}
+
+func TestJobManagerSaveError(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "missing")
+	jm, err := NewJobManager(filepath.Join(dir, "jobs.json"))
+	if err != nil {
+		t.Fatalf("NewJobManager() error = %v", err)
+	}
+	defer jm.Close()
+
+	noop := func(ctx context.Context, r *JobReporter) error { return nil }
+	job, _ := jm.Start(JobSpec{Type: JobCompaction}, noop)
+	waitForJobState(t, jm, job.ID)
+	if err := jm.SaveError(); err == nil {
+		t.Error("SaveError() = nil, want the failed write")
+	}
+
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	job, _ = jm.Start(JobSpec{Type: JobCompaction}, noop)
+	waitForJobState(t, jm, job.ID)
+	if err := jm.SaveError(); err != nil {
+		t.Errorf("SaveError() after a successful save = %v, want nil", err)
+	}
+}
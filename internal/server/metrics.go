@@ -0,0 +1,225 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/ber"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/metrics"
+)
+
+// MetricsPath is the path the metrics server serves.
+const MetricsPath = "/metrics"
+
+// Metrics holds the counters and gauges of the LDAP server.
+type Metrics struct {
+	// ConnectionsTotal counts accepted connections
+	ConnectionsTotal *metrics.Counter
+	// ActiveConnections is the number of open connections
+	ActiveConnections *metrics.Gauge
+	// OperationsTotal counts operations by operation and result
+	// ("success" or "failure")
+	OperationsTotal *metrics.CounterVec
+	// BindFailuresTotal counts failed bind attempts
+	BindFailuresTotal *metrics.Counter
+	// FairnessRejectionsTotal counts operations rejected by a fairness
+	// limit, by limit
+	FairnessRejectionsTotal *metrics.CounterVec
+	// BindDuration, SearchDuration, AddDuration, ModifyDuration and
+	// DeleteDuration are the latencies of the operations by result_code,
+	// from reading the request to writing the response
+	BindDuration   *metrics.HistogramVec
+	SearchDuration *metrics.HistogramVec
+	AddDuration    *metrics.HistogramVec
+	ModifyDuration *metrics.HistogramVec
+	DeleteDuration *metrics.HistogramVec
+}
+
+// NewMetrics creates the LDAP server metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		ConnectionsTotal: metrics.NewCounter("oba_ldap_connections_total",
+			"Total number of accepted LDAP connections."),
+		ActiveConnections: metrics.NewGauge("oba_ldap_active_connections",
+			"Number of open LDAP connections."),
+		OperationsTotal: metrics.NewCounterVec("oba_ldap_operations_total",
+			"Total number of LDAP operations by operation and result.", "operation", "result"),
+		BindFailuresTotal: metrics.NewCounter("oba_ldap_bind_failures_total",
+			"Total number of failed LDAP binds."),
+		FairnessRejectionsTotal: metrics.NewCounterVec("oba_ldap_fairness_rejections_total",
+			"Total number of LDAP operations rejected as busy by a fairness limit.", "limit"),
+		BindDuration:   newDurationHistogram("bind"),
+		SearchDuration: newDurationHistogram("search"),
+		AddDuration:    newDurationHistogram("add"),
+		ModifyDuration: newDurationHistogram("modify"),
+		DeleteDuration: newDurationHistogram("delete"),
+	}
+}
+
+// newDurationHistogram creates the latency histogram of an operation.
+func newDurationHistogram(op string) *metrics.HistogramVec {
+	return metrics.NewHistogramVec("oba_ldap_"+op+"_duration_seconds",
+		"Latency of LDAP "+op+" operations in seconds by result code.", metrics.LatencyBuckets, "result_code")
+}
+
+// Register registers all metrics with reg.
+func (m *Metrics) Register(reg metrics.Registerer) error {
+	for _, c := range []metrics.Collector{
+		m.ConnectionsTotal,
+		m.ActiveConnections,
+		m.OperationsTotal,
+		m.BindFailuresTotal,
+		m.FairnessRejectionsTotal,
+		m.BindDuration,
+		m.SearchDuration,
+		m.AddDuration,
+		m.ModifyDuration,
+		m.DeleteDuration,
+	} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// operationNames maps request tags to the operation label.
+var operationNames = map[ldap.OperationType]string{
+	ldap.OperationType(ldap.ApplicationBindRequest):     "bind",
+	ldap.OperationType(ldap.ApplicationUnbindRequest):   "unbind",
+	ldap.OperationType(ldap.ApplicationSearchRequest):   "search",
+	ldap.OperationType(ldap.ApplicationModifyRequest):   "modify",
+	ldap.OperationType(ldap.ApplicationAddRequest):      "add",
+	ldap.OperationType(ldap.ApplicationDelRequest):      "delete",
+	ldap.OperationType(ldap.ApplicationModifyDNRequest): "modifydn",
+	ldap.OperationType(ldap.ApplicationCompareRequest):  "compare",
+	ldap.OperationType(ldap.ApplicationAbandonRequest):  "abandon",
+	ldap.OperationType(ldap.ApplicationExtendedRequest): "extended",
+}
+
+// observeOperation records a dispatched request and its response.
+func (m *Metrics) observeOperation(request, response *ldap.LDAPMessage) {
+	op, ok := operationNames[request.OperationType()]
+	if !ok {
+		op = "unknown"
+	}
+
+	result := "success"
+	if code, ok := responseResultCode(response); ok && !successfulResult(code) {
+		result = "failure"
+		if op == "bind" {
+			m.BindFailuresTotal.Inc()
+		}
+	}
+
+	m.OperationsTotal.WithLabelValues(op, result).Inc()
+}
+
+// observeDuration records the latency of a request with a latency
+// histogram. Operations without one are ignored.
+func (m *Metrics) observeDuration(request, response *ldap.LDAPMessage, elapsed time.Duration) {
+	var h *metrics.HistogramVec
+	switch request.OperationType() {
+	case ldap.OperationType(ldap.ApplicationBindRequest):
+		h = m.BindDuration
+	case ldap.OperationType(ldap.ApplicationSearchRequest):
+		h = m.SearchDuration
+	case ldap.OperationType(ldap.ApplicationAddRequest):
+		h = m.AddDuration
+	case ldap.OperationType(ldap.ApplicationModifyRequest):
+		h = m.ModifyDuration
+	case ldap.OperationType(ldap.ApplicationDelRequest):
+		h = m.DeleteDuration
+	default:
+		return
+	}
+
+	code, ok := responseResultCode(response)
+	if !ok {
+		return
+	}
+	h.WithLabelValues(strconv.Itoa(int(code))).Observe(elapsed.Seconds())
+}
+
+// responseResultCode returns the LDAPResult code at the start of a
+// response. Responses without one, such as abandon, report false.
+func responseResultCode(response *ldap.LDAPMessage) (ldap.ResultCode, bool) {
+	if response == nil || response.Operation == nil {
+		return 0, false
+	}
+	code, err := ber.NewBERDecoder(response.Operation.Data).ReadEnumerated()
+	if err != nil {
+		return 0, false
+	}
+	return ldap.ResultCode(code), true
+}
+
+// successfulResult reports whether code is not an error. Compare results
+// and a SASL bind in progress are successful outcomes.
+func successfulResult(code ldap.ResultCode) bool {
+	switch code {
+	case ldap.ResultSuccess, ldap.ResultCompareFalse, ldap.ResultCompareTrue, ldap.ResultSASLBindInProgress:
+		return true
+	}
+	return false
+}
+
+// MetricsServer is a standalone HTTP server that exposes only the metrics
+// endpoint, for deployments that run without the REST API.
+type MetricsServer struct {
+	addr     string
+	server   *http.Server
+	listener net.Listener
+	mu       sync.Mutex
+}
+
+// NewMetricsServer creates a metrics server that serves the metrics of reg
+// on addr. The server does not listen until Start is called.
+func NewMetricsServer(addr string, reg *metrics.Registry) *MetricsServer {
+	mux := http.NewServeMux()
+	mux.Handle(MetricsPath, reg)
+
+	return &MetricsServer{
+		addr: addr,
+		server: &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+}
+
+// Start listens on the configured address and serves in the background.
+func (s *MetricsServer) Start() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+
+	go s.server.Serve(listener)
+	return nil
+}
+
+// Addr returns the address the server listens on, or nil before Start.
+func (s *MetricsServer) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Stop shuts the server down gracefully.
+func (s *MetricsServer) Stop(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
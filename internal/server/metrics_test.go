@@ -0,0 +1,220 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/metrics"
+)
+
+// scrapeMetrics fetches the metrics endpoint of s.
+func scrapeMetrics(t *testing.T, s *MetricsServer) string {
+	t.Helper()
+
+	resp, err := http.Get("http://" + s.Addr().String() + MetricsPath)
+	if err != nil {
+		t.Fatalf("Failed to scrape metrics: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Scrape status = %d, want 200", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != metrics.ContentType {
+		t.Errorf("Content-Type = %q, want %q", ct, metrics.ContentType)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read metrics: %v", err)
+	}
+	return string(body)
+}
+
+func TestMetricsServerCountsOperations(t *testing.T) {
+	registry := metrics.NewRegistry()
+	m := NewMetrics()
+	if err := m.Register(registry); err != nil {
+		t.Fatalf("Failed to register metrics: %v", err)
+	}
+
+	ms := NewMetricsServer("127.0.0.1:0", registry)
+	if err := ms.Start(); err != nil {
+		t.Fatalf("Failed to start metrics server: %v", err)
+	}
+	defer ms.Stop(context.Background())
+
+	handler := NewHandler()
+	handler.SetBindHandler(func(conn *Connection, req *ldap.BindRequest) *OperationResult {
+		if req.Name == "cn=admin,dc=example,dc=com" {
+			return &OperationResult{ResultCode: ldap.ResultInvalidCredentials}
+		}
+		return &OperationResult{ResultCode: ldap.ResultSuccess}
+	})
+	handler.SetSearchHandler(func(conn *Connection, req *ldap.SearchRequest) *SearchResult {
+		return &SearchResult{OperationResult: OperationResult{ResultCode: ldap.ResultSuccess}}
+	})
+	srv := &Server{Handler: handler, Metrics: m}
+
+	// Two connections: one with binds and searches, one with an add that
+	// fails because no add handler is configured
+	var requests [][]byte
+	requests = append(requests,
+		createBindRequestMessage(1, 3, "", ""),
+		createBindRequestMessage(2, 3, "cn=admin,dc=example,dc=com", "wrong"),
+		createSearchRequestMessage(3, "dc=example,dc=com"),
+		createSearchRequestMessage(4, "dc=example,dc=com"),
+		createSearchRequestMessage(5, "dc=example,dc=com"),
+		createUnbindRequestMessage(6),
+	)
+	runConnection(t, srv, requests)
+	runConnection(t, srv, [][]byte{
+		createAddRequestMessage(1, "cn=test,dc=example,dc=com"),
+		createUnbindRequestMessage(2),
+	})
+
+	body := scrapeMetrics(t, ms)
+
+	for _, want := range []string{
+		"oba_ldap_connections_total 2\n",
+		"oba_ldap_active_connections 0\n",
+		"oba_ldap_bind_failures_total 1\n",
+		`oba_ldap_operations_total{operation="bind",result="success"} 1` + "\n",
+		`oba_ldap_operations_total{operation="bind",result="failure"} 1` + "\n",
+		`oba_ldap_operations_total{operation="search",result="success"} 3` + "\n",
+		`oba_ldap_operations_total{operation="add",result="failure"} 1` + "\n",
+		`oba_ldap_operations_total{operation="unbind",result="success"} 2` + "\n",
+		"# TYPE oba_ldap_operations_total counter\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Metrics missing %q:\n%s", want, body)
+		}
+	}
+}
+
+// TestOperationDurationHistograms tests that the latency of each
+// operation is recorded by result code.
+func TestOperationDurationHistograms(t *testing.T) {
+	m := NewMetrics()
+	success := &OperationResult{ResultCode: ldap.ResultSuccess}
+	handler := NewHandler()
+	handler.SetBindHandler(func(*Connection, *ldap.BindRequest) *OperationResult { return success })
+	handler.SetSearchHandler(func(*Connection, *ldap.SearchRequest) *SearchResult {
+		return &SearchResult{OperationResult: *success}
+	})
+	handler.SetAddHandler(func(*Connection, *ldap.AddRequest) *OperationResult { return success })
+	handler.SetModifyHandler(func(*Connection, *ldap.ModifyRequest) *OperationResult { return success })
+	handler.SetDeleteHandler(func(*Connection, *ldap.DeleteRequest) *OperationResult {
+		return &OperationResult{ResultCode: ldap.ResultNoSuchObject}
+	})
+	srv := &Server{Handler: handler, Metrics: m}
+
+	const n = 100
+	var requests [][]byte
+	for i := 0; i < n; i++ {
+		id := 5*i + 1
+		requests = append(requests,
+			createBindRequestMessage(id, 3, "", ""),
+			createSearchRequestMessage(id+1, "dc=example,dc=com"),
+			createAddRequestMessage(id+2, "cn=test,dc=example,dc=com"),
+			createModifyRequestMessage(id+3, "cn=test,dc=example,dc=com"),
+			createDeleteRequestMessage(id+4, "cn=test,dc=example,dc=com"),
+		)
+	}
+	requests = append(requests, createUnbindRequestMessage(5*n+1))
+	runConnection(t, srv, requests)
+
+	for name, h := range map[string]*metrics.HistogramVec{
+		"bind":   m.BindDuration,
+		"search": m.SearchDuration,
+		"add":    m.AddDuration,
+		"modify": m.ModifyDuration,
+	} {
+		if got := h.WithLabelValues("0").Count(); got != n {
+			t.Errorf("%s duration count = %d, want %d", name, got, n)
+		}
+	}
+	noSuchObject := strconv.Itoa(int(ldap.ResultNoSuchObject))
+	if got := m.DeleteDuration.WithLabelValues(noSuchObject).Count(); got != n {
+		t.Errorf("delete duration count for result code %s = %d, want %d", noSuchObject, got, n)
+	}
+	if got := m.DeleteDuration.WithLabelValues("0").Count(); got != 0 {
+		t.Errorf("delete duration count for success = %d, want 0", got)
+	}
+
+	registry := metrics.NewRegistry()
+	if err := m.Register(registry); err != nil {
+		t.Fatalf("Failed to register metrics: %v", err)
+	}
+	var sb strings.Builder
+	registry.WriteText(&sb)
+	for _, want := range []string{
+		"# TYPE oba_ldap_search_duration_seconds histogram\n",
+		`oba_ldap_bind_duration_seconds_bucket{result_code="0",le="+Inf"} 100` + "\n",
+		`oba_ldap_delete_duration_seconds_count{result_code="32"} 100` + "\n",
+	} {
+		if !strings.Contains(sb.String(), want) {
+			t.Errorf("Metrics missing %q:\n%s", want, sb.String())
+		}
+	}
+}
+
+// runConnection handles the requests on a new connection of srv.
+func runConnection(t *testing.T, srv *Server, requests [][]byte) {
+	t.Helper()
+
+	mockConn := newMockConn()
+	var data []byte
+	for _, req := range requests {
+		data = append(data, req...)
+	}
+	mockConn.setReadData(data)
+
+	done := make(chan struct{})
+	go func() {
+		NewConnection(mockConn, srv).Handle()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Handle did not complete")
+	}
+}
+
+func TestMetricsServerOnlyServesMetrics(t *testing.T) {
+	ms := NewMetricsServer("127.0.0.1:0", metrics.NewRegistry())
+	if ms.Addr() != nil {
+		t.Error("Addr should be nil before Start")
+	}
+	if err := ms.Start(); err != nil {
+		t.Fatalf("Failed to start metrics server: %v", err)
+	}
+	defer ms.Stop(context.Background())
+
+	resp, err := http.Get("http://" + ms.Addr().String() + "/api/v1/health")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestMetricsRegisterDuplicate(t *testing.T) {
+	registry := metrics.NewRegistry()
+	if err := NewMetrics().Register(registry); err != nil {
+		t.Fatalf("Failed to register metrics: %v", err)
+	}
+	if err := NewMetrics().Register(registry); err == nil {
+		t.Error("Expected error registering metrics twice")
+	}
+}
@@ -18,6 +18,8 @@ const (
 	ModifyDelete
 	// ModifyReplace replaces all values of an attribute.
 	ModifyReplace
+	// ModifyIncrement adds a number to the values of an integer attribute.
+	ModifyIncrement
 )
 
 // Modification represents a single modification to an entry.
@@ -92,16 +94,11 @@ func (h *ModifyHandlerImpl) Handle(conn *Connection, req *ldap.ModifyRequest) *O
 
 	// Step 4: Check ACL write permission
 	if h.config.ACLEvaluator != nil {
-		bindDN := ""
-		if conn != nil {
-			bindDN = conn.BindDN()
-		}
-
 		// Get the list of attributes being modified
 		modifiedAttrs := getModifiedAttributes(req.Changes)
 
 		// Create access context with attributes
-		ctx := acl.NewAccessContext(bindDN, dn, acl.Write).WithAttributes(modifiedAttrs...)
+		ctx := accessContext(conn, dn, acl.Write).WithAttributes(modifiedAttrs...)
 
 		if !h.config.ACLEvaluator.CheckAccess(ctx) {
 			return &OperationResult{
@@ -218,6 +215,8 @@ func convertToBackendModifications(changes []ldap.Modification) []Modification {
 			modType = ModifyDelete
 		case ldap.ModifyOperationReplace:
 			modType = ModifyReplace
+		case ldap.ModifyOperationIncrement:
+			modType = ModifyIncrement
 		}
 
 		result[i] = Modification{
@@ -233,7 +232,7 @@ func convertToBackendModifications(changes []ldap.Modification) []Modification {
 // normalizeDNForModify normalizes a DN for consistent comparison.
 // It converts to lowercase and trims whitespace.
 func normalizeDNForModify(dn string) string {
-	return strings.ToLower(strings.TrimSpace(dn))
+	return ldap.CanonicalDN(dn)
 }
 
 // findMatchedDNForModify finds the longest existing parent DN for error reporting.
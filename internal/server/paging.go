@@ -347,6 +347,19 @@ func filterToString(f *ldap.SearchFilter) string {
 	case ldap.FilterTagApproxMatch:
 		return "(" + f.Attribute + "~=" + string(f.Value) + ")"
 
+	case ldap.FilterTagExtensibleMatch:
+		if f.ExtensibleMatch == nil {
+			return "(unknown)"
+		}
+		result := "(" + f.ExtensibleMatch.Type
+		if f.ExtensibleMatch.DNAttributes {
+			result += ":dn"
+		}
+		if f.ExtensibleMatch.MatchingRule != "" {
+			result += ":" + f.ExtensibleMatch.MatchingRule
+		}
+		return result + ":=" + string(f.ExtensibleMatch.MatchValue) + ")"
+
 	default:
 		return "(unknown)"
 	}
@@ -3,7 +3,6 @@ package server
 
 import (
 	"crypto/rand"
-	"strings"
 
 	"github.com/KilimcininKorOglu/oba/internal/ber"
 	"github.com/KilimcininKorOglu/oba/internal/ldap"
@@ -362,7 +361,7 @@ func generateSecurePassword(length int) []byte {
 
 // normalizeDNForCompare normalizes a DN for comparison.
 func normalizeDNForCompare(dn string) string {
-	return strings.ToLower(strings.TrimSpace(dn))
+	return ldap.CanonicalDN(dn)
 }
 
 // SetBackend sets the password backend.
@@ -127,10 +127,10 @@ func TestPasswordModifyHandler_Handle_SelfChange(t *testing.T) {
 	defer server.Close()
 
 	conn := NewConnection(server, nil)
-	conn.mu.Lock()
-	conn.bindDN = "uid=alice,ou=users,dc=example,dc=com"
-	conn.authenticated = true
-	conn.mu.Unlock()
+	conn.updateState(func(s *connState) {
+		s.bindDN = "uid=alice,ou=users,dc=example,dc=com"
+		s.authenticated = true
+	})
 
 	// Create request with old and new password
 	reqValue := encodePasswordModifyRequestForTest(nil, []byte("oldpassword"), []byte("newpassword123"))
@@ -173,10 +173,10 @@ func TestPasswordModifyHandler_Handle_SelfChangeWithoutOldPassword(t *testing.T)
 	defer server.Close()
 
 	conn := NewConnection(server, nil)
-	conn.mu.Lock()
-	conn.bindDN = "uid=alice,ou=users,dc=example,dc=com"
-	conn.authenticated = true
-	conn.mu.Unlock()
+	conn.updateState(func(s *connState) {
+		s.bindDN = "uid=alice,ou=users,dc=example,dc=com"
+		s.authenticated = true
+	})
 
 	// Create request without old password
 	reqValue := encodePasswordModifyRequestForTest(nil, nil, []byte("newpassword123"))
@@ -215,10 +215,10 @@ func TestPasswordModifyHandler_Handle_AdminReset(t *testing.T) {
 	defer server.Close()
 
 	conn := NewConnection(server, nil)
-	conn.mu.Lock()
-	conn.bindDN = "cn=admin,dc=example,dc=com"
-	conn.authenticated = true
-	conn.mu.Unlock()
+	conn.updateState(func(s *connState) {
+		s.bindDN = "cn=admin,dc=example,dc=com"
+		s.authenticated = true
+	})
 
 	// Admin resets alice's password without providing old password
 	reqValue := encodePasswordModifyRequestForTest(
@@ -266,10 +266,10 @@ func TestPasswordModifyHandler_Handle_NonAdminCannotResetOthers(t *testing.T) {
 	defer server.Close()
 
 	conn := NewConnection(server, nil)
-	conn.mu.Lock()
-	conn.bindDN = "uid=alice,ou=users,dc=example,dc=com"
-	conn.authenticated = true
-	conn.mu.Unlock()
+	conn.updateState(func(s *connState) {
+		s.bindDN = "uid=alice,ou=users,dc=example,dc=com"
+		s.authenticated = true
+	})
 
 	// Alice tries to reset Bob's password
 	reqValue := encodePasswordModifyRequestForTest(
@@ -310,10 +310,10 @@ func TestPasswordModifyHandler_Handle_WrongOldPassword(t *testing.T) {
 	defer server.Close()
 
 	conn := NewConnection(server, nil)
-	conn.mu.Lock()
-	conn.bindDN = "uid=alice,ou=users,dc=example,dc=com"
-	conn.authenticated = true
-	conn.mu.Unlock()
+	conn.updateState(func(s *connState) {
+		s.bindDN = "uid=alice,ou=users,dc=example,dc=com"
+		s.authenticated = true
+	})
 
 	// Provide wrong old password
 	reqValue := encodePasswordModifyRequestForTest(nil, []byte("wrongpassword"), []byte("newpassword"))
@@ -351,10 +351,10 @@ func TestPasswordModifyHandler_Handle_GeneratePassword(t *testing.T) {
 	defer server.Close()
 
 	conn := NewConnection(server, nil)
-	conn.mu.Lock()
-	conn.bindDN = "cn=admin,dc=example,dc=com"
-	conn.authenticated = true
-	conn.mu.Unlock()
+	conn.updateState(func(s *connState) {
+		s.bindDN = "cn=admin,dc=example,dc=com"
+		s.authenticated = true
+	})
 
 	// Admin resets password without providing new password (server generates)
 	reqValue := encodePasswordModifyRequestForTest(
@@ -409,10 +409,10 @@ func TestPasswordModifyHandler_Handle_PolicyValidation(t *testing.T) {
 	defer server.Close()
 
 	conn := NewConnection(server, nil)
-	conn.mu.Lock()
-	conn.bindDN = "uid=alice,ou=users,dc=example,dc=com"
-	conn.authenticated = true
-	conn.mu.Unlock()
+	conn.updateState(func(s *connState) {
+		s.bindDN = "uid=alice,ou=users,dc=example,dc=com"
+		s.authenticated = true
+	})
 
 	// Try to set a password that doesn't meet policy (too short)
 	reqValue := encodePasswordModifyRequestForTest(nil, []byte("oldpassword"), []byte("short"))
@@ -590,11 +590,11 @@ func TestPasswordModifyHandler_Handle_RequireTLS(t *testing.T) {
 	defer server.Close()
 
 	conn := NewConnection(server, nil)
-	conn.mu.Lock()
-	conn.bindDN = "uid=alice,dc=example,dc=com"
-	conn.authenticated = true
-	conn.isTLS = false // Not using TLS
-	conn.mu.Unlock()
+	conn.updateState(func(s *connState) {
+		s.bindDN = "uid=alice,dc=example,dc=com"
+		s.authenticated = true
+		s.isTLS = false // Not using TLS
+	})
 
 	req := &ExtendedRequest{
 		OID:   PasswordModifyOID,
@@ -4,6 +4,7 @@ package server
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 
 	"github.com/KilimcininKorOglu/oba/internal/ber"
 	"github.com/KilimcininKorOglu/oba/internal/ldap"
@@ -161,6 +162,9 @@ func (ecn *EntryChangeNotification) ToLDAPControl() (ldap.Control, error) {
 type PersistentSearchBackend interface {
 	// Watch creates a change stream subscription.
 	Watch(filter stream.WatchFilter) *stream.Subscriber
+	// WatchWithBuffer creates a change stream subscription that queues up
+	// to bufferSize events.
+	WatchWithBuffer(filter stream.WatchFilter, bufferSize int) *stream.Subscriber
 	// Unwatch removes a subscription.
 	Unwatch(id stream.SubscriberID)
 	// GetEntry retrieves an entry by DN.
@@ -171,22 +175,55 @@ type PersistentSearchBackend interface {
 
 // PersistentSearchHandler handles persistent search requests.
 type PersistentSearchHandler struct {
-	backend  PersistentSearchBackend
+	backend PersistentSearchBackend
+
 	mu       sync.Mutex
-	sessions map[*Connection]*persistentSearchSession
+	limits   PersistentSearchLimits
+	sessions map[*Connection]map[int]*persistentSearchSession
+	// bindDNs counts the sessions of each bind DN
+	bindDNs map[string]int
+	// dropped counts the notifications dropped by ended sessions and
+	// those already reported to clients
+	dropped uint64
+
+	disconnects atomic.Uint64
 }
 
 type persistentSearchSession struct {
 	subscriber *stream.Subscriber
 	cancel     context.CancelFunc
+	bindDN     string
 }
 
 // NewPersistentSearchHandler creates a new persistent search handler.
 func NewPersistentSearchHandler(backend PersistentSearchBackend) *PersistentSearchHandler {
 	return &PersistentSearchHandler{
 		backend:  backend,
-		sessions: make(map[*Connection]*persistentSearchSession),
+		limits:   PersistentSearchLimits{QueueSize: stream.DefaultBufferSize, OverflowPolicy: OverflowDrop},
+		sessions: make(map[*Connection]map[int]*persistentSearchSession),
+		bindDNs:  make(map[string]int),
+	}
+}
+
+// SetLimits sets the limits of persistent searches started from now on.
+func (h *PersistentSearchHandler) SetLimits(limits PersistentSearchLimits) {
+	if limits.QueueSize <= 0 {
+		limits.QueueSize = stream.DefaultBufferSize
 	}
+	if limits.OverflowPolicy == "" {
+		limits.OverflowPolicy = OverflowDrop
+	}
+
+	h.mu.Lock()
+	h.limits = limits
+	h.mu.Unlock()
+}
+
+// Limits returns the persistent search limits.
+func (h *PersistentSearchHandler) Limits() PersistentSearchLimits {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.limits
 }
 
 // Handle processes a persistent search request.
@@ -224,9 +261,21 @@ func (h *PersistentSearchHandler) Handle(
 	}
 	watchFilter.Operations = ops
 
+	// Reserve a session within the limits
+	bindDN := conn.BindDN()
+	h.mu.Lock()
+	limits := h.limits
+	if limit, err := h.acquire(conn, bindDN); err != nil {
+		h.mu.Unlock()
+		conn.rejectFairness(limit, err)
+		h.sendSearchDone(conn, messageID, ldap.ResultAdminLimitExceeded, err.Error())
+		return
+	}
+
 	// Subscribe to changes
-	sub := h.backend.Watch(watchFilter)
+	sub := h.backend.WatchWithBuffer(watchFilter, limits.QueueSize)
 	if sub == nil {
+		h.mu.Unlock()
 		h.sendSearchDone(conn, messageID, ldap.ResultUnwillingToPerform, "failed to subscribe")
 		return
 	}
@@ -235,22 +284,31 @@ func (h *PersistentSearchHandler) Handle(
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// Store session
-	h.mu.Lock()
-	h.sessions[conn] = &persistentSearchSession{
+	session := &persistentSearchSession{
 		subscriber: sub,
 		cancel:     cancel,
+		bindDN:     bindDN,
 	}
+	h.addSession(conn, messageID, session)
 	h.mu.Unlock()
 
+	// Track the search so Abandon and Cancel can end it
+	op := conn.operations.Register(messageID, cancel)
+
 	// Cleanup on exit
 	defer func() {
 		h.mu.Lock()
-		delete(h.sessions, conn)
+		h.removeSession(conn, messageID, session)
 		h.mu.Unlock()
 		h.backend.Unwatch(sub.ID)
 		cancel()
+		conn.operations.Unregister(messageID)
 	}()
 
+	if limits.OverflowPolicy == OverflowDisconnect {
+		go h.watchOverflow(ctx, conn, sub)
+	}
+
 	// Send initial results if not changesOnly
 	if !ctrl.ChangesOnly {
 		if err := h.sendInitialResults(conn, req, messageID); err != nil {
@@ -264,22 +322,85 @@ func (h *PersistentSearchHandler) Handle(
 		case event, ok := <-sub.Channel:
 			if !ok {
 				// Channel closed
-				h.sendSearchDone(conn, messageID, ldap.ResultSuccess, "")
+				h.finishSearch(conn, op, messageID)
 				return
 			}
+			if event.Operation != stream.OpDelete && event.Entry == nil {
+				continue
+			}
+
+			// Changes dropped since the last notification are reported
+			// with this one
+			var missed uint64
+			if limits.OverflowPolicy == OverflowDrop {
+				h.mu.Lock()
+				missed = sub.ResetDropped()
+				h.dropped += missed
+				h.mu.Unlock()
+			}
 
 			// Send the change as a search result entry
-			if err := h.sendChangeEvent(conn, messageID, &event, ctrl.ReturnECs); err != nil {
+			if err := h.sendChangeEvent(conn, messageID, &event, ctrl.ReturnECs, missed); err != nil {
 				return
 			}
 
 		case <-ctx.Done():
-			h.sendSearchDone(conn, messageID, ldap.ResultSuccess, "")
+			h.finishSearch(conn, op, messageID)
 			return
 		}
 	}
 }
 
+// addSession adds a session of conn. h.mu must be held.
+func (h *PersistentSearchHandler) addSession(conn *Connection, messageID int, session *persistentSearchSession) {
+	sessions := h.sessions[conn]
+	if sessions == nil {
+		sessions = make(map[int]*persistentSearchSession)
+		h.sessions[conn] = sessions
+	}
+	sessions[messageID] = session
+	if session.bindDN != "" {
+		h.bindDNs[bindDNKey(session.bindDN)]++
+	}
+}
+
+// removeSession removes a session of conn and counts the notifications it
+// dropped. h.mu must be held.
+func (h *PersistentSearchHandler) removeSession(conn *Connection, messageID int, session *persistentSearchSession) {
+	sessions := h.sessions[conn]
+	delete(sessions, messageID)
+	if len(sessions) == 0 {
+		delete(h.sessions, conn)
+	}
+	if session.bindDN != "" {
+		key := bindDNKey(session.bindDN)
+		if h.bindDNs[key]--; h.bindDNs[key] <= 0 {
+			delete(h.bindDNs, key)
+		}
+	}
+	h.dropped += session.subscriber.ResetDropped()
+}
+
+// watchOverflow closes conn with a Notice of Disconnection when the queue
+// of sub overflows, until ctx is done. The notice is written with a
+// deadline, which also ends a write stuck on a client that stopped
+// reading.
+func (h *PersistentSearchHandler) watchOverflow(ctx context.Context, conn *Connection, sub *stream.Subscriber) {
+	select {
+	case <-sub.Overflow():
+	case <-ctx.Done():
+		return
+	}
+
+	h.disconnects.Add(1)
+	conn.Logger().Warn("closing connection of slow persistent search consumer",
+		"client", conn.conn.RemoteAddr().String(),
+		"bind_dn", conn.BindDN(),
+		"queue_size", cap(sub.Channel))
+	conn.disconnect(noticeSlowConsumer)
+	conn.Close()
+}
+
 // sendInitialResults sends the initial search results before streaming changes.
 func (h *PersistentSearchHandler) sendInitialResults(conn *Connection, req *ldap.SearchRequest, messageID int) error {
 	iter := h.backend.SearchByDN(req.BaseObject, storage.Scope(req.Scope))
@@ -318,6 +439,7 @@ func (h *PersistentSearchHandler) sendChangeEvent(
 	messageID int,
 	event *stream.ChangeEvent,
 	returnECs bool,
+	missed uint64,
 ) error {
 	// For delete operations, we can't send the entry (it's gone)
 	if event.Operation == stream.OpDelete {
@@ -331,6 +453,9 @@ func (h *PersistentSearchHandler) sendChangeEvent(
 			}
 		}
 		msg := h.createSearchEntryResponse(messageID, searchEntry, ecn)
+		if missed > 0 {
+			msg.Controls = append(msg.Controls, missedChangesControl(missed))
+		}
 		return conn.WriteMessage(msg)
 	}
 
@@ -358,6 +483,9 @@ func (h *PersistentSearchHandler) sendChangeEvent(
 	}
 
 	msg := h.createSearchEntryResponse(messageID, searchEntry, ecn)
+	if missed > 0 {
+		msg.Controls = append(msg.Controls, missedChangesControl(missed))
+	}
 	return conn.WriteMessage(msg)
 }
 
@@ -454,13 +582,26 @@ func (h *PersistentSearchHandler) sendSearchDone(conn *Connection, messageID int
 	conn.WriteMessage(msg)
 }
 
-// CancelSession cancels a persistent search session for a connection.
+// finishSearch sends the SearchResultDone that ends a persistent search:
+// canceled if a Cancel request ended it, none if it was abandoned.
+func (h *PersistentSearchHandler) finishSearch(conn *Connection, op *PendingOperation, messageID int) {
+	code, ok := op.FinalResult(ldap.ResultSuccess)
+	if !ok {
+		return
+	}
+	h.sendSearchDone(conn, messageID, code, "")
+}
+
+// CancelSession cancels the persistent search sessions of a connection.
 func (h *PersistentSearchHandler) CancelSession(conn *Connection) {
 	h.mu.Lock()
-	session, ok := h.sessions[conn]
+	sessions := make([]*persistentSearchSession, 0, len(h.sessions[conn]))
+	for _, session := range h.sessions[conn] {
+		sessions = append(sessions, session)
+	}
 	h.mu.Unlock()
 
-	if ok && session != nil {
+	for _, session := range sessions {
 		session.cancel()
 	}
 }
@@ -469,5 +610,33 @@ func (h *PersistentSearchHandler) CancelSession(conn *Connection) {
 func (h *PersistentSearchHandler) ActiveSessions() int {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	return len(h.sessions)
+
+	count := 0
+	for _, sessions := range h.sessions {
+		count += len(sessions)
+	}
+	return count
+}
+
+// Stats returns a snapshot of the persistent searches.
+func (h *PersistentSearchHandler) Stats() PersistentSearchStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stats := PersistentSearchStats{
+		Dropped:     h.dropped,
+		Disconnects: h.disconnects.Load(),
+	}
+	for _, sessions := range h.sessions {
+		for _, session := range sessions {
+			depth := len(session.subscriber.Channel)
+			stats.Active++
+			stats.QueueDepth += depth
+			if depth > stats.MaxQueueDepth {
+				stats.MaxQueueDepth = depth
+			}
+			stats.Dropped += session.subscriber.DroppedCount()
+		}
+	}
+	return stats
 }
@@ -0,0 +1,151 @@
+// Package server provides the LDAP server implementation.
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/KilimcininKorOglu/oba/internal/ber"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+)
+
+// MissedChangesOID is the OID of the control a persistent search
+// notification carries when changes were dropped from the notification
+// queue before it. Its value is an INTEGER with the number of dropped
+// changes.
+const MissedChangesOID = "1.3.6.1.4.1.61423.2.1"
+
+// Persistent search limit names, used as the fairness metrics label of
+// rejected searches
+const (
+	// LimitPersistentSearchesPerConnection limits the persistent searches
+	// of a connection
+	LimitPersistentSearchesPerConnection = "persistent_searches_per_connection"
+	// LimitPersistentSearchesPerBindDN limits the persistent searches of
+	// a bind DN across its connections
+	LimitPersistentSearchesPerBindDN = "persistent_searches_per_bind_dn"
+)
+
+// OverflowPolicy is what happens to a persistent search whose
+// notification queue is full.
+type OverflowPolicy string
+
+const (
+	// OverflowDrop drops the changes that do not fit and marks the next
+	// notification with the MissedChanges control
+	OverflowDrop OverflowPolicy = "drop"
+	// OverflowDisconnect closes the connection with a Notice of
+	// Disconnection
+	OverflowDisconnect OverflowPolicy = "disconnect"
+)
+
+// noticeSlowConsumer closes the connection of a persistent search that
+// did not keep up with its notifications.
+var noticeSlowConsumer = &disconnectNotice{ldap.ResultAdminLimitExceeded, "persistent search notification queue overflow"}
+
+// PersistentSearchLimits bounds the resources persistent searches hold. A
+// zero count limit is disabled.
+type PersistentSearchLimits struct {
+	// QueueSize is the number of notifications queued for each search.
+	// Changes committed while the queue is full are handled by
+	// OverflowPolicy. Zero uses the change stream default.
+	QueueSize int
+	// OverflowPolicy is applied when a queue is full. Empty means
+	// OverflowDrop.
+	OverflowPolicy OverflowPolicy
+	// MaxPerConnection is the number of persistent searches a connection
+	// may run at once
+	MaxPerConnection int
+	// MaxPerBindDN is the number of persistent searches a bound DN may
+	// run at once across all of its connections
+	MaxPerBindDN int
+}
+
+// PersistentSearchStats is a snapshot of the persistent searches of a
+// handler.
+type PersistentSearchStats struct {
+	// Active is the number of running persistent searches
+	Active int
+	// QueueDepth is the number of notifications queued across all
+	// searches, and MaxQueueDepth the most queued for one search
+	QueueDepth    int
+	MaxQueueDepth int
+	// Dropped counts notifications dropped from full queues
+	Dropped uint64
+	// Disconnects counts connections closed by OverflowDisconnect
+	Disconnects uint64
+}
+
+// acquire reserves a session slot for conn and bindDN. Anonymous searches
+// are only limited per connection. h.mu must be held.
+func (h *PersistentSearchHandler) acquire(conn *Connection, bindDN string) (limit string, err error) {
+	if n := h.limits.MaxPerConnection; n > 0 && len(h.sessions[conn]) >= n {
+		return LimitPersistentSearchesPerConnection,
+			fmt.Errorf("too many persistent searches on this connection (limit %d)", n)
+	}
+	if n := h.limits.MaxPerBindDN; n > 0 && bindDN != "" && h.bindDNs[bindDNKey(bindDN)] >= n {
+		return LimitPersistentSearchesPerBindDN,
+			fmt.Errorf("too many persistent searches for %s (limit %d)", bindDN, n)
+	}
+	return "", nil
+}
+
+// missedChangesControl creates the MissedChanges control for n dropped
+// changes.
+func missedChangesControl(n uint64) ldap.Control {
+	encoder := ber.NewBEREncoder(16)
+	encoder.WriteInteger(int64(n))
+	return ldap.Control{
+		OID:   MissedChangesOID,
+		Value: encoder.Bytes(),
+	}
+}
+
+// bindDNKey returns the key persistent searches are counted under for
+// bindDN.
+func bindDNKey(bindDN string) string {
+	return strings.ToLower(bindDN)
+}
+
+// PersistentSearchCollectorName is the name a PersistentSearchCollector
+// is registered under.
+const PersistentSearchCollectorName = "oba_ldap_persistent_search"
+
+// PersistentSearchCollector is a metrics.Collector that exposes the
+// persistent searches of a handler. The queues are measured once per
+// scrape.
+type PersistentSearchCollector struct {
+	handler *PersistentSearchHandler
+}
+
+// NewPersistentSearchCollector creates a collector for h.
+func NewPersistentSearchCollector(h *PersistentSearchHandler) *PersistentSearchCollector {
+	return &PersistentSearchCollector{handler: h}
+}
+
+// Name implements metrics.Collector. The collector writes several metric
+// families, all prefixed with the name.
+func (c *PersistentSearchCollector) Name() string {
+	return PersistentSearchCollectorName
+}
+
+// WriteText implements metrics.Collector.
+func (c *PersistentSearchCollector) WriteText(w io.Writer) error {
+	stats := c.handler.Stats()
+	bw := bufio.NewWriter(w)
+
+	writeFamily(bw, "oba_ldap_persistent_searches", "Number of running persistent searches.", "gauge", uint64(stats.Active))
+	writeFamily(bw, "oba_ldap_persistent_search_queue_depth", "Number of notifications queued across persistent searches.", "gauge", uint64(stats.QueueDepth))
+	writeFamily(bw, "oba_ldap_persistent_search_queue_depth_max", "Largest number of notifications queued for one persistent search.", "gauge", uint64(stats.MaxQueueDepth))
+	writeFamily(bw, "oba_ldap_persistent_search_dropped_total", "Total number of persistent search notifications dropped from full queues.", "counter", stats.Dropped)
+	writeFamily(bw, "oba_ldap_persistent_search_disconnects_total", "Total number of connections closed for overflowing a persistent search queue.", "counter", stats.Disconnects)
+
+	return bw.Flush()
+}
+
+// writeFamily writes a metric family with a single sample.
+func writeFamily(w io.Writer, name, help, typ string, value uint64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %d\n", name, help, name, typ, name, value)
+}
@@ -0,0 +1,271 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/ber"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// persistentSearch encodes a subtree search with a changes-only
+// Persistent Search control for all change types.
+func persistentSearch(t *testing.T, messageID int, baseDN string) []byte {
+	t.Helper()
+
+	search := ber.NewBEREncoder(128)
+	search.WriteOctetString([]byte(baseDN))
+	search.WriteEnumerated(int64(ldap.ScopeWholeSubtree))
+	search.WriteEnumerated(0)
+	search.WriteInteger(0)
+	search.WriteInteger(0)
+	search.WriteBoolean(false)
+	search.WriteTaggedValue(7, false, []byte("objectClass"))
+	attrs := search.BeginSequence()
+	search.EndSequence(attrs)
+
+	value := ber.NewBEREncoder(16)
+	seq := value.BeginSequence()
+	value.WriteInteger(15)
+	value.WriteBoolean(true)
+	value.WriteBoolean(true)
+	value.EndSequence(seq)
+
+	msg := &ldap.LDAPMessage{
+		MessageID: messageID,
+		Operation: &ldap.RawOperation{Tag: ldap.ApplicationSearchRequest, Data: search.Bytes()},
+		Controls:  []ldap.Control{{OID: PersistentSearchOID, Value: value.Bytes()}},
+	}
+	data, err := msg.Encode()
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	return data
+}
+
+// startPersistentSearchConnection serves a connection bound as bindDN over
+// a pipe and returns the client end.
+func startPersistentSearchConnection(t *testing.T, h *PersistentSearchHandler, bindDN string) net.Conn {
+	t.Helper()
+
+	client, srv := net.Pipe()
+	conn := NewConnection(srv, &Server{Handler: NewHandler()})
+	conn.SetPersistentSearchHandler(h)
+	conn.updateState(func(s *connState) {
+		s.bindDN = bindDN
+		s.authenticated = bindDN != ""
+	})
+	go conn.Handle()
+
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// startPersistentSearch sends a persistent search and waits until the
+// handler runs it.
+func startPersistentSearch(t *testing.T, h *PersistentSearchHandler, client net.Conn, messageID int) {
+	t.Helper()
+
+	want := h.ActiveSessions() + 1
+	if _, err := client.Write(persistentSearch(t, messageID, "dc=example,dc=com")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	waitFor(t, "persistent search to start", func() bool { return h.ActiveSessions() == want })
+}
+
+// waitFor polls cond until it holds, failing the test after 5 seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// readMessage reads the next message.
+func readMessage(t *testing.T, client net.Conn) *ldap.LDAPMessage {
+	t.Helper()
+
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	msg, err := readLDAPMessage(client)
+	if err != nil {
+		t.Fatalf("readLDAPMessage() error = %v", err)
+	}
+	return msg
+}
+
+// addEntries publishes the addition of n entries.
+func addEntries(backend *mockClientUpdateBackend, n int) {
+	for i := 0; i < n; i++ {
+		entry := storage.NewEntry(fmt.Sprintf("cn=entry%d,dc=example,dc=com", i))
+		entry.SetStringAttribute("objectClass", "device")
+		backend.add(entry)
+	}
+}
+
+func TestPersistentSearchConnectionLimit(t *testing.T) {
+	h := NewPersistentSearchHandler(newMockClientUpdateBackend())
+	h.SetLimits(PersistentSearchLimits{MaxPerConnection: 1})
+
+	client := startPersistentSearchConnection(t, h, "")
+	startPersistentSearch(t, h, client, 1)
+
+	if _, err := client.Write(persistentSearch(t, 2, "dc=example,dc=com")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	msg, code := readResultCode(t, client)
+	if msg.MessageID != 2 || msg.OperationType() != ldap.ApplicationSearchResultDone || code != ldap.ResultAdminLimitExceeded {
+		t.Fatalf("got %s %s for message %d, want adminLimitExceeded for message 2", msg.OperationType(), code, msg.MessageID)
+	}
+	if n := h.ActiveSessions(); n != 1 {
+		t.Errorf("ActiveSessions() = %d, want 1", n)
+	}
+
+	// Another connection has its own limit
+	other := startPersistentSearchConnection(t, h, "")
+	startPersistentSearch(t, h, other, 1)
+}
+
+func TestPersistentSearchBindDNLimit(t *testing.T) {
+	h := NewPersistentSearchHandler(newMockClientUpdateBackend())
+	h.SetLimits(PersistentSearchLimits{MaxPerBindDN: 1})
+
+	first := startPersistentSearchConnection(t, h, "cn=sync,dc=example,dc=com")
+	startPersistentSearch(t, h, first, 1)
+
+	// The DN is counted across connections, case-insensitively
+	second := startPersistentSearchConnection(t, h, "CN=Sync,DC=example,DC=com")
+	if _, err := second.Write(persistentSearch(t, 1, "dc=example,dc=com")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, code := readResultCode(t, second); code != ldap.ResultAdminLimitExceeded {
+		t.Fatalf("second search result = %s, want adminLimitExceeded", code)
+	}
+
+	// Other DNs and anonymous clients are not held by it
+	startPersistentSearch(t, h, startPersistentSearchConnection(t, h, "cn=other,dc=example,dc=com"), 1)
+	startPersistentSearch(t, h, startPersistentSearchConnection(t, h, ""), 1)
+
+	// Ending the first search frees its slot
+	first.Close()
+	waitFor(t, "first search to end", func() bool { return h.ActiveSessions() == 2 })
+	startPersistentSearch(t, h, second, 2)
+}
+
+// TestPersistentSearchDropAndMark tests that changes committed while the
+// queue of a search is full are dropped without waiting for the client,
+// and that the client is told how many it missed.
+func TestPersistentSearchDropAndMark(t *testing.T) {
+	backend := newMockClientUpdateBackend()
+	h := NewPersistentSearchHandler(backend)
+	h.SetLimits(PersistentSearchLimits{QueueSize: 2, OverflowPolicy: OverflowDrop})
+
+	client := startPersistentSearchConnection(t, h, "")
+	startPersistentSearch(t, h, client, 1)
+
+	// The client is not reading, so at most one notification is being
+	// written and two are queued
+	const changes = 10
+	done := make(chan struct{})
+	go func() {
+		addEntries(backend, changes)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("committing changes blocked on the persistent search")
+	}
+
+	var delivered, missed uint64
+	for delivered+missed < changes {
+		msg := readMessage(t, client)
+		if msg.OperationType() != ldap.ApplicationSearchResultEntry {
+			t.Fatalf("got %s, want a notification", msg.OperationType())
+		}
+		delivered++
+		for _, ctrl := range msg.Controls {
+			if ctrl.OID != MissedChangesOID {
+				continue
+			}
+			if ctrl.Criticality {
+				t.Error("missed changes control is critical")
+			}
+			n, err := ber.NewBERDecoder(ctrl.Value).ReadInteger()
+			if err != nil || n <= 0 {
+				t.Fatalf("missed changes control value = %x", ctrl.Value)
+			}
+			missed += uint64(n)
+		}
+	}
+	if delivered > 3 || missed < changes-3 {
+		t.Errorf("delivered %d notifications and reported %d missed, want at most 3 delivered", delivered, missed)
+	}
+
+	stats := h.Stats()
+	if stats.Dropped != missed || stats.QueueDepth != 0 || stats.Active != 1 {
+		t.Errorf("Stats() = %+v, want %d dropped and an empty queue", stats, missed)
+	}
+
+	var sb strings.Builder
+	if err := NewPersistentSearchCollector(h).WriteText(&sb); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	for _, sample := range []string{
+		"oba_ldap_persistent_searches 1\n",
+		"oba_ldap_persistent_search_queue_depth 0\n",
+		fmt.Sprintf("oba_ldap_persistent_search_dropped_total %d\n", missed),
+		"oba_ldap_persistent_search_disconnects_total 0\n",
+	} {
+		if !strings.Contains(sb.String(), sample) {
+			t.Errorf("metrics missing %q:\n%s", sample, sb.String())
+		}
+	}
+}
+
+// TestPersistentSearchDisconnectSlowConsumer tests that a client whose
+// queue overflows is sent a Notice of Disconnection and disconnected.
+func TestPersistentSearchDisconnectSlowConsumer(t *testing.T) {
+	backend := newMockClientUpdateBackend()
+	h := NewPersistentSearchHandler(backend)
+	h.SetLimits(PersistentSearchLimits{QueueSize: 2, OverflowPolicy: OverflowDisconnect})
+
+	client := startPersistentSearchConnection(t, h, "")
+	startPersistentSearch(t, h, client, 1)
+	addEntries(backend, 10)
+
+	// Queued notifications may come first
+	var notice *ldap.LDAPMessage
+	for notice == nil {
+		msg := readMessage(t, client)
+		if msg.MessageID == 0 {
+			code, _ := ber.NewBERDecoder(msg.Operation.Data).ReadEnumerated()
+			if msg.OperationType() != ldap.ApplicationExtendedResponse || ldap.ResultCode(code) != noticeSlowConsumer.resultCode {
+				t.Fatalf("got %s %s for message 0, want the notice of disconnection", msg.OperationType(), ldap.ResultCode(code))
+			}
+			notice = msg
+		}
+	}
+
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := readLDAPMessage(client); err != io.EOF {
+		t.Errorf("read after notice error = %v, want EOF", err)
+	}
+
+	waitFor(t, "session to end", func() bool { return h.ActiveSessions() == 0 })
+	if n := backend.broker.SubscriberCount(); n != 0 {
+		t.Errorf("SubscriberCount() = %d after disconnect, want 0", n)
+	}
+	if stats := h.Stats(); stats.Disconnects != 1 {
+		t.Errorf("Stats().Disconnects = %d, want 1", stats.Disconnects)
+	}
+}
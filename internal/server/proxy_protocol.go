@@ -0,0 +1,332 @@
+// Package server provides the LDAP server implementation.
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PROXY protocol errors
+var (
+	// ErrProxyHeaderInvalid is returned when a PROXY protocol header is malformed
+	ErrProxyHeaderInvalid = errors.New("server: invalid PROXY protocol header")
+	// ErrProxyHeaderUntrusted is returned when a PROXY protocol header is sent
+	// by a source that is not a trusted proxy
+	ErrProxyHeaderUntrusted = errors.New("server: PROXY protocol header from untrusted source")
+	// ErrInvalidTrustedProxy is returned when a trusted proxy is not a valid CIDR
+	ErrInvalidTrustedProxy = errors.New("server: invalid trusted proxy CIDR")
+)
+
+// PROXY protocol constants
+const (
+	// proxyV1Prefix starts every PROXY protocol v1 header
+	proxyV1Prefix = "PROXY "
+	// proxyV1MaxLength is the maximum length of a v1 header including CRLF
+	proxyV1MaxLength = 107
+	// proxyV2HeaderLength is the length of the fixed v2 header
+	proxyV2HeaderLength = 16
+)
+
+// proxyV2Signature starts every PROXY protocol v2 header.
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// ProxyProtocolConfig holds configuration for PROXY protocol support.
+type ProxyProtocolConfig struct {
+	// TrustedProxies lists the CIDRs allowed to send PROXY headers
+	TrustedProxies []string
+	// HeaderTimeout bounds the time to receive the PROXY header
+	HeaderTimeout time.Duration
+}
+
+// DefaultProxyProtocolConfig returns a PROXY protocol configuration with sensible defaults.
+func DefaultProxyProtocolConfig() *ProxyProtocolConfig {
+	return &ProxyProtocolConfig{
+		HeaderTimeout: 5 * time.Second,
+	}
+}
+
+// TrustedNetworks parses a list of CIDRs.
+func TrustedNetworks(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidTrustedProxy, cidr)
+		}
+		networks = append(networks, ipNet)
+	}
+	return networks, nil
+}
+
+// IsTrustedAddr returns true if the IP of addr is within networks.
+func IsTrustedAddr(addr net.Addr, networks []*net.IPNet) bool {
+	ip := net.ParseIP(ClientIP(addr))
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range networks {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ProxyListener wraps a listener and reads PROXY protocol v1/v2 headers
+// from connections accepted from trusted proxies. The real client address
+// is then returned by RemoteAddr. Headers from untrusted sources are
+// rejected. Trusted proxies may omit the header, e.g. for health checks.
+//
+// Wrap the plain TCP listener before any TLS listener, since the header
+// precedes the TLS handshake.
+type ProxyListener struct {
+	net.Listener
+	trusted       []*net.IPNet
+	headerTimeout time.Duration
+}
+
+// NewProxyListener creates a new ProxyListener with the given configuration.
+func NewProxyListener(listener net.Listener, config *ProxyProtocolConfig) (*ProxyListener, error) {
+	if config == nil {
+		config = DefaultProxyProtocolConfig()
+	}
+
+	trusted, err := TrustedNetworks(config.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProxyListener{
+		Listener:      listener,
+		trusted:       trusted,
+		headerTimeout: config.HeaderTimeout,
+	}, nil
+}
+
+// Accept waits for and returns the next connection. The PROXY header is
+// read on first use of the connection, so a slow client cannot block
+// the accept loop.
+func (l *ProxyListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return newProxyConn(conn, IsTrustedAddr(conn.RemoteAddr(), l.trusted), l.headerTimeout), nil
+}
+
+// proxyConn is a connection that may start with a PROXY protocol header.
+type proxyConn struct {
+	net.Conn
+	reader        *bufio.Reader
+	trusted       bool
+	headerTimeout time.Duration
+
+	once       sync.Once
+	err        error
+	remoteAddr net.Addr
+}
+
+// newProxyConn wraps conn.
+func newProxyConn(conn net.Conn, trusted bool, headerTimeout time.Duration) *proxyConn {
+	return &proxyConn{
+		Conn:          conn,
+		reader:        bufio.NewReader(conn),
+		trusted:       trusted,
+		headerTimeout: headerTimeout,
+	}
+}
+
+// Read reads data after the PROXY header. It returns the header error if
+// the header was malformed or untrusted.
+func (c *proxyConn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.reader.Read(b)
+}
+
+// RemoteAddr returns the client address from the PROXY header, or the
+// address of the peer if there is none.
+func (c *proxyConn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.remoteAddr != nil {
+		return c.remoteAddr
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// readHeader detects and parses the PROXY header.
+func (c *proxyConn) readHeader() {
+	if c.headerTimeout > 0 {
+		c.Conn.SetReadDeadline(time.Now().Add(c.headerTimeout))
+		defer c.Conn.SetReadDeadline(time.Time{})
+	}
+
+	// LDAP messages start with 0x30 and TLS records with 0x16, so the
+	// first byte is enough to tell whether a header is present.
+	first, err := c.reader.Peek(1)
+	if err != nil {
+		return
+	}
+
+	switch first[0] {
+	case proxyV1Prefix[0], proxyV2Signature[0]:
+	default:
+		return
+	}
+
+	if !c.trusted {
+		c.err = ErrProxyHeaderUntrusted
+		return
+	}
+
+	c.remoteAddr, c.err = readProxyHeader(c.reader)
+}
+
+// readProxyHeader reads a v1 or v2 PROXY header from r. It returns a nil
+// address for headers that carry no client address (UNKNOWN, LOCAL).
+func readProxyHeader(r *bufio.Reader) (net.Addr, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if first[0] == proxyV1Prefix[0] {
+		return readProxyV1(r)
+	}
+	return readProxyV2(r)
+}
+
+// readProxyV1 reads a text PROXY protocol v1 header.
+func readProxyV1(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for len(line) < proxyV1MaxLength {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrProxyHeaderInvalid, err)
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, fmt.Errorf("%w: header not terminated by CRLF", ErrProxyHeaderInvalid)
+	}
+	if !bytes.HasPrefix(line, []byte(proxyV1Prefix)) {
+		return nil, fmt.Errorf("%w: missing PROXY prefix", ErrProxyHeaderInvalid)
+	}
+
+	fields := strings.Split(string(line[len(proxyV1Prefix):len(line)-2]), " ")
+	if fields[0] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: expected 5 fields, got %d", ErrProxyHeaderInvalid, len(fields))
+	}
+
+	ip := net.ParseIP(fields[1])
+	if ip == nil || net.ParseIP(fields[2]) == nil {
+		return nil, fmt.Errorf("%w: invalid address", ErrProxyHeaderInvalid)
+	}
+	switch fields[0] {
+	case "TCP4":
+		if ip.To4() == nil {
+			return nil, fmt.Errorf("%w: TCP4 with IPv6 address", ErrProxyHeaderInvalid)
+		}
+	case "TCP6":
+		if ip.To4() != nil {
+			return nil, fmt.Errorf("%w: TCP6 with IPv4 address", ErrProxyHeaderInvalid)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown protocol %q", ErrProxyHeaderInvalid, fields[0])
+	}
+
+	port, err := parseProxyPort(fields[3])
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parseProxyPort(fields[4]); err != nil {
+		return nil, err
+	}
+
+	return &net.TCPAddr{IP: ip, Port: port}, nil
+}
+
+// parseProxyPort parses a decimal port of a v1 header.
+func parseProxyPort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 0 || port > 65535 || (len(s) > 1 && s[0] == '0') {
+		return 0, fmt.Errorf("%w: invalid port %q", ErrProxyHeaderInvalid, s)
+	}
+	return port, nil
+}
+
+// readProxyV2 reads a binary PROXY protocol v2 header.
+func readProxyV2(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, proxyV2HeaderLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProxyHeaderInvalid, err)
+	}
+	if !bytes.Equal(header[:12], proxyV2Signature) {
+		return nil, fmt.Errorf("%w: bad signature", ErrProxyHeaderInvalid)
+	}
+
+	version := header[12] >> 4
+	command := header[12] & 0x0F
+	if version != 2 {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrProxyHeaderInvalid, version)
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProxyHeaderInvalid, err)
+	}
+
+	switch command {
+	case 0x0:
+		// LOCAL: the proxy's own connection, e.g. a health check
+		return nil, nil
+	case 0x1:
+	default:
+		return nil, fmt.Errorf("%w: unsupported command %d", ErrProxyHeaderInvalid, command)
+	}
+
+	family := header[13] >> 4
+	transport := header[13] & 0x0F
+	if transport != 0x1 {
+		// Only STREAM carries a TCP client address
+		return nil, nil
+	}
+
+	switch family {
+	case 0x1: // AF_INET
+		if len(payload) < 12 {
+			return nil, fmt.Errorf("%w: short IPv4 address block", ErrProxyHeaderInvalid)
+		}
+		return &net.TCPAddr{
+			IP:   net.IP(append([]byte(nil), payload[0:4]...)),
+			Port: int(binary.BigEndian.Uint16(payload[8:10])),
+		}, nil
+	case 0x2: // AF_INET6
+		if len(payload) < 36 {
+			return nil, fmt.Errorf("%w: short IPv6 address block", ErrProxyHeaderInvalid)
+		}
+		return &net.TCPAddr{
+			IP:   net.IP(append([]byte(nil), payload[0:16]...)),
+			Port: int(binary.BigEndian.Uint16(payload[32:34])),
+		}, nil
+	default:
+		// AF_UNSPEC and AF_UNIX carry no usable address
+		return nil, nil
+	}
+}
@@ -0,0 +1,228 @@
+package server
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// proxyV2Header builds a v2 PROXY header for a TCP connection from src.
+func proxyV2Header(command byte, src *net.TCPAddr) []byte {
+	var family byte
+	var payload []byte
+	if ip4 := src.IP.To4(); ip4 != nil {
+		family = 0x11
+		payload = append(payload, ip4...)
+		payload = append(payload, 10, 0, 0, 1)
+		payload = binary.BigEndian.AppendUint16(payload, uint16(src.Port))
+		payload = binary.BigEndian.AppendUint16(payload, 389)
+	} else {
+		family = 0x21
+		payload = append(payload, src.IP.To16()...)
+		payload = append(payload, net.ParseIP("fd00::1").To16()...)
+		payload = binary.BigEndian.AppendUint16(payload, uint16(src.Port))
+		payload = binary.BigEndian.AppendUint16(payload, 389)
+	}
+
+	header := append([]byte(nil), proxyV2Signature...)
+	header = append(header, 0x20|command, family)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(payload)))
+	return append(header, payload...)
+}
+
+// proxyConnPipe returns a proxyConn reading what is written to the returned writer.
+func proxyConnPipe(t *testing.T, trusted bool, data []byte) *proxyConn {
+	t.Helper()
+	client, srv := net.Pipe()
+	t.Cleanup(func() {
+		client.Close()
+		srv.Close()
+	})
+	go func() {
+		client.Write(data)
+		client.Close()
+	}()
+	return newProxyConn(srv, trusted, time.Second)
+}
+
+func TestProxyConnHeaders(t *testing.T) {
+	ldapMessage := []byte{0x30, 0x05, 0x02, 0x01, 0x01, 0x42, 0x00}
+
+	tests := []struct {
+		name     string
+		header   []byte
+		wantAddr string
+	}{
+		{"v1 TCP4", []byte("PROXY TCP4 203.0.113.7 10.0.0.1 51234 389\r\n"), "203.0.113.7:51234"},
+		{"v1 TCP6", []byte("PROXY TCP6 2001:db8::7 fd00::1 51234 389\r\n"), "[2001:db8::7]:51234"},
+		{"v1 UNKNOWN", []byte("PROXY UNKNOWN\r\n"), "pipe"},
+		{"v2 TCP4", proxyV2Header(0x1, &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 51234}), "203.0.113.7:51234"},
+		{"v2 TCP6", proxyV2Header(0x1, &net.TCPAddr{IP: net.ParseIP("2001:db8::7"), Port: 51234}), "[2001:db8::7]:51234"},
+		{"v2 LOCAL", proxyV2Header(0x0, &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 51234}), "pipe"},
+		{"no header", nil, "pipe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := proxyConnPipe(t, true, append(tt.header, ldapMessage...))
+
+			if got := conn.RemoteAddr().String(); got != tt.wantAddr {
+				t.Errorf("RemoteAddr() = %q, want %q", got, tt.wantAddr)
+			}
+
+			data, err := io.ReadAll(conn)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if string(data) != string(ldapMessage) {
+				t.Errorf("data after header = %x, want %x", data, ldapMessage)
+			}
+		})
+	}
+}
+
+func TestProxyConnMalformedHeaders(t *testing.T) {
+	badVersion := proxyV2Header(0x1, &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 1})
+	badVersion[12] = 0x11
+
+	badCommand := proxyV2Header(0x1, &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 1})
+	badCommand[12] = 0x2F
+
+	shortAddress := proxyV2Header(0x1, &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 1})
+	binary.BigEndian.PutUint16(shortAddress[14:16], 4)
+	shortAddress = shortAddress[:20]
+
+	truncated := proxyV2Header(0x1, &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 1})[:20]
+
+	long := []byte("PROXY TCP4 ")
+	for len(long) < 200 {
+		long = append(long, '1')
+	}
+
+	tests := []struct {
+		name   string
+		header []byte
+	}{
+		{"v1 missing CRLF", []byte("PROXY TCP4 203.0.113.7 10.0.0.1 51234 389\n")},
+		{"v1 too long", long},
+		{"v1 bad prefix", []byte("PROXI TCP4 203.0.113.7 10.0.0.1 51234 389\r\n")},
+		{"v1 missing fields", []byte("PROXY TCP4 203.0.113.7 10.0.0.1 51234\r\n")},
+		{"v1 bad protocol", []byte("PROXY UDP4 203.0.113.7 10.0.0.1 51234 389\r\n")},
+		{"v1 bad address", []byte("PROXY TCP4 203.0.113 10.0.0.1 51234 389\r\n")},
+		{"v1 family mismatch", []byte("PROXY TCP4 2001:db8::7 10.0.0.1 51234 389\r\n")},
+		{"v1 bad port", []byte("PROXY TCP4 203.0.113.7 10.0.0.1 70000 389\r\n")},
+		{"v1 leading zero port", []byte("PROXY TCP4 203.0.113.7 10.0.0.1 0389 389\r\n")},
+		{"v1 truncated", []byte("PROXY TCP4 203.0.113.7")},
+		{"v2 bad signature", []byte("\r\n\r\n\x00\r\nQUIZ\n\x21\x11\x00\x00")},
+		{"v2 bad version", badVersion},
+		{"v2 bad command", badCommand},
+		{"v2 short address", shortAddress},
+		{"v2 truncated", truncated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := proxyConnPipe(t, true, tt.header)
+
+			_, err := conn.Read(make([]byte, 16))
+			if !errors.Is(err, ErrProxyHeaderInvalid) {
+				t.Errorf("Read() error = %v, want ErrProxyHeaderInvalid", err)
+			}
+			if got := conn.RemoteAddr().String(); got != "pipe" {
+				t.Errorf("RemoteAddr() = %q, want peer address", got)
+			}
+		})
+	}
+}
+
+func TestProxyConnUntrustedSource(t *testing.T) {
+	headers := map[string][]byte{
+		"v1": []byte("PROXY TCP4 203.0.113.7 10.0.0.1 51234 389\r\n"),
+		"v2": proxyV2Header(0x1, &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 51234}),
+	}
+
+	for name, header := range headers {
+		t.Run(name, func(t *testing.T) {
+			conn := proxyConnPipe(t, false, header)
+
+			_, err := conn.Read(make([]byte, 16))
+			if !errors.Is(err, ErrProxyHeaderUntrusted) {
+				t.Errorf("Read() error = %v, want ErrProxyHeaderUntrusted", err)
+			}
+			if got := conn.RemoteAddr().String(); got != "pipe" {
+				t.Errorf("RemoteAddr() = %q, want peer address", got)
+			}
+		})
+	}
+
+	t.Run("no header", func(t *testing.T) {
+		conn := proxyConnPipe(t, false, []byte{0x30, 0x00})
+		data, err := io.ReadAll(conn)
+		if err != nil || len(data) != 2 {
+			t.Errorf("ReadAll() = %x, %v", data, err)
+		}
+	})
+}
+
+func TestProxyListener(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer inner.Close()
+
+	if _, err := NewProxyListener(inner, &ProxyProtocolConfig{TrustedProxies: []string{"127.0.0.1"}}); !errors.Is(err, ErrInvalidTrustedProxy) {
+		t.Errorf("NewProxyListener() error = %v, want ErrInvalidTrustedProxy", err)
+	}
+
+	tests := []struct {
+		name    string
+		trusted []string
+		wantIP  string
+		wantErr error
+	}{
+		{"trusted", []string{"127.0.0.0/8"}, "203.0.113.7", nil},
+		{"untrusted", []string{"10.0.0.0/8"}, "127.0.0.1", ErrProxyHeaderUntrusted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, err := NewProxyListener(inner, &ProxyProtocolConfig{
+				TrustedProxies: tt.trusted,
+				HeaderTimeout:  time.Second,
+			})
+			if err != nil {
+				t.Fatalf("NewProxyListener() error = %v", err)
+			}
+
+			go func() {
+				client, err := net.Dial("tcp", inner.Addr().String())
+				if err != nil {
+					return
+				}
+				defer client.Close()
+				client.Write([]byte("PROXY TCP4 203.0.113.7 10.0.0.1 51234 389\r\n\x30\x00"))
+				io.Copy(io.Discard, client)
+			}()
+
+			conn, err := listener.Accept()
+			if err != nil {
+				t.Fatalf("Accept() error = %v", err)
+			}
+			defer conn.Close()
+
+			if got := ClientIP(conn.RemoteAddr()); got != tt.wantIP {
+				t.Errorf("RemoteAddr() = %v, want IP %s", conn.RemoteAddr(), tt.wantIP)
+			}
+
+			buf := make([]byte, 2)
+			_, err = io.ReadFull(conn, buf)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Read() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,441 @@
+// Package server provides the LDAP server implementation.
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/ber"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// Referral errors.
+var (
+	// ErrInvalidReferralURL is returned when a referral URI is not a usable LDAP URL.
+	ErrInvalidReferralURL = errors.New("server: invalid referral URL")
+	// ErrReferralHopLimit is returned when chasing exceeds the maximum number of hops.
+	ErrReferralHopLimit = errors.New("server: referral hop limit exceeded")
+	// ErrReferralLoop is returned when a referral points back to an already visited target.
+	ErrReferralLoop = errors.New("server: referral loop detected")
+	// ErrReferralFailed is returned when the referred server rejects the search.
+	ErrReferralFailed = errors.New("server: referral search failed")
+)
+
+// referralObjectClass is the object class of referral entries (RFC 3296).
+const referralObjectClass = "referral"
+
+// referralAttribute holds the LDAP URLs of a referral entry (RFC 3296).
+const referralAttribute = "ref"
+
+// ReferralChaserConfig holds configuration for the ReferralChaser.
+type ReferralChaserConfig struct {
+	// Timeout bounds connecting to and searching a referred server (default: 5 seconds).
+	Timeout time.Duration
+	// MaxHops is the maximum number of nested referrals followed (default: 5).
+	MaxHops int
+	// TLSConfig is used for ldaps:// referrals. If nil, a default config is used.
+	TLSConfig *tls.Config
+}
+
+// DefaultReferralChaserConfig returns the default configuration.
+func DefaultReferralChaserConfig() *ReferralChaserConfig {
+	return &ReferralChaserConfig{
+		Timeout: 5 * time.Second,
+		MaxHops: 5,
+	}
+}
+
+// ReferralChaser follows search continuation references on behalf of clients.
+// Each chase opens a temporary anonymous connection to the referred server,
+// runs the search there and returns the collected entries.
+type ReferralChaser struct {
+	timeout   time.Duration
+	maxHops   int
+	tlsConfig *tls.Config
+}
+
+// NewReferralChaser creates a new ReferralChaser.
+func NewReferralChaser(config *ReferralChaserConfig) *ReferralChaser {
+	defaults := DefaultReferralChaserConfig()
+	if config == nil {
+		config = defaults
+	}
+
+	chaser := &ReferralChaser{
+		timeout:   config.Timeout,
+		maxHops:   config.MaxHops,
+		tlsConfig: config.TLSConfig,
+	}
+	if chaser.timeout <= 0 {
+		chaser.timeout = defaults.Timeout
+	}
+	if chaser.maxHops <= 0 {
+		chaser.maxHops = defaults.MaxHops
+	}
+
+	return chaser
+}
+
+// Chase follows a continuation reference and returns the entries found on the
+// referred server. The URIs are alternatives for the same subtree; they are
+// tried in order until one succeeds. References returned by the referred
+// server are followed up to the configured hop limit.
+func (c *ReferralChaser) Chase(refs []string, req *ldap.SearchRequest) ([]*SearchEntry, error) {
+	return c.chase(refs, req, 0, make(map[string]bool))
+}
+
+// chase follows refs at the given hop depth, tracking visited targets.
+func (c *ReferralChaser) chase(refs []string, req *ldap.SearchRequest, hop int, visited map[string]bool) ([]*SearchEntry, error) {
+	if hop >= c.maxHops {
+		return nil, ErrReferralHopLimit
+	}
+
+	lastErr := fmt.Errorf("%w: no URIs in reference", ErrInvalidReferralURL)
+	for _, ref := range refs {
+		target, err := parseReferralURL(ref)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		key := target.address + "/" + strings.ToLower(target.baseDN)
+		if visited[key] {
+			lastErr = fmt.Errorf("%w: %s", ErrReferralLoop, ref)
+			continue
+		}
+		visited[key] = true
+
+		subReq := target.searchRequest(req)
+		entries, nested, err := c.search(target, subReq)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		for _, nestedRef := range nested {
+			nestedEntries, err := c.chase(nestedRef.URIs, subReq, hop+1, visited)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, nestedEntries...)
+		}
+
+		return entries, nil
+	}
+
+	return nil, lastErr
+}
+
+// search runs a single search against the referred server.
+func (c *ReferralChaser) search(target *referralTarget, req *ldap.SearchRequest) ([]*SearchEntry, []*SearchReference, error) {
+	conn, err := c.dial(target)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		return nil, nil, err
+	}
+
+	data, err := req.Encode()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	const messageID = 1
+	msg := &ldap.LDAPMessage{
+		MessageID: messageID,
+		Operation: &ldap.RawOperation{
+			Tag:  ldap.ApplicationSearchRequest,
+			Data: data,
+		},
+	}
+	encoded, err := msg.Encode()
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := conn.Write(encoded); err != nil {
+		return nil, nil, err
+	}
+
+	var entries []*SearchEntry
+	var refs []*SearchReference
+	for {
+		resp, err := readLDAPMessage(conn)
+		if err != nil {
+			return nil, nil, err
+		}
+		if resp.MessageID != messageID || resp.Operation == nil {
+			continue
+		}
+
+		switch resp.Operation.Tag {
+		case ldap.ApplicationSearchResultEntry:
+			entry, err := parseSearchResultEntry(resp.Operation.Data)
+			if err != nil {
+				return nil, nil, err
+			}
+			entries = append(entries, entry)
+
+		case ldap.ApplicationSearchResultReference:
+			ref, err := parseSearchResultReference(resp.Operation.Data)
+			if err != nil {
+				return nil, nil, err
+			}
+			refs = append(refs, ref)
+
+		case ldap.ApplicationSearchResultDone:
+			code, diagnostic, err := parseResultCode(resp.Operation.Data)
+			if err != nil {
+				return nil, nil, err
+			}
+			c.unbind(conn)
+			if code != ldap.ResultSuccess {
+				return nil, nil, fmt.Errorf("%w: %s: %s %s", ErrReferralFailed, target.address, code.String(), diagnostic)
+			}
+			return entries, refs, nil
+		}
+	}
+}
+
+// dial opens a connection to the referred server.
+func (c *ReferralChaser) dial(target *referralTarget) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: c.timeout}
+	if !target.useTLS {
+		return dialer.Dial("tcp", target.address)
+	}
+
+	tlsConfig := c.tlsConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.ServerName, _, _ = net.SplitHostPort(target.address)
+	}
+	return tls.DialWithDialer(dialer, "tcp", target.address, tlsConfig)
+}
+
+// unbind sends an UnbindRequest; errors are ignored since the connection is closed anyway.
+func (c *ReferralChaser) unbind(conn net.Conn) {
+	msg := &ldap.LDAPMessage{
+		MessageID: 2,
+		Operation: &ldap.RawOperation{Tag: ldap.ApplicationUnbindRequest},
+	}
+	if data, err := msg.Encode(); err == nil {
+		conn.Write(data)
+	}
+}
+
+// referralTarget is a parsed LDAP URL (RFC 4516).
+type referralTarget struct {
+	address string
+	useTLS  bool
+	baseDN  string
+	scope   *ldap.SearchScope
+}
+
+// parseReferralURL parses an ldap:// or ldaps:// URL of the form
+// ldap://host:port/baseDN??scope. The attributes and filter parts are
+// ignored; the original request's attributes and filter are used instead.
+func parseReferralURL(raw string) (*referralTarget, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidReferralURL, raw)
+	}
+
+	target := &referralTarget{}
+	defaultPort := "389"
+	switch strings.ToLower(u.Scheme) {
+	case "ldap":
+	case "ldaps":
+		target.useTLS = true
+		defaultPort = "636"
+	default:
+		return nil, fmt.Errorf("%w: unsupported scheme in %s", ErrInvalidReferralURL, raw)
+	}
+
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("%w: missing host in %s", ErrInvalidReferralURL, raw)
+	}
+	port := u.Port()
+	if port == "" {
+		port = defaultPort
+	}
+	target.address = net.JoinHostPort(u.Hostname(), port)
+	target.baseDN = strings.TrimPrefix(u.Path, "/")
+
+	// RawQuery holds "attributes?scope?filter?extensions"
+	parts := strings.Split(u.RawQuery, "?")
+	if len(parts) > 1 {
+		var scope ldap.SearchScope
+		switch strings.ToLower(parts[1]) {
+		case "":
+			scope = -1
+		case "base":
+			scope = ldap.ScopeBaseObject
+		case "one":
+			scope = ldap.ScopeSingleLevel
+		case "sub":
+			scope = ldap.ScopeWholeSubtree
+		default:
+			return nil, fmt.Errorf("%w: invalid scope in %s", ErrInvalidReferralURL, raw)
+		}
+		if scope >= 0 {
+			target.scope = &scope
+		}
+	}
+
+	return target, nil
+}
+
+// searchRequest builds the request sent to the referred server from the original request.
+func (t *referralTarget) searchRequest(req *ldap.SearchRequest) *ldap.SearchRequest {
+	subReq := *req
+	if t.baseDN != "" {
+		subReq.BaseObject = t.baseDN
+	}
+	if t.scope != nil {
+		subReq.Scope = *t.scope
+	}
+	return &subReq
+}
+
+// referralFromEntry returns a continuation reference if the entry is a
+// referral object, or nil otherwise. For single level searches the URIs
+// are narrowed to base scope as described in RFC 4511 Section 4.5.3.
+func referralFromEntry(entry *storage.Entry, scope ldap.SearchScope) *SearchReference {
+	if entry == nil || !hasObjectClass(entry, referralObjectClass) {
+		return nil
+	}
+
+	var uris []string
+	for name, values := range entry.Attributes {
+		if !strings.EqualFold(name, referralAttribute) {
+			continue
+		}
+		for _, v := range values {
+			uri := strings.TrimSpace(string(v))
+			if uri == "" {
+				continue
+			}
+			if scope == ldap.ScopeSingleLevel && !strings.Contains(uri, "?") {
+				uri += "??base"
+			}
+			uris = append(uris, uri)
+		}
+	}
+	if len(uris) == 0 {
+		return nil
+	}
+
+	return &SearchReference{URIs: uris}
+}
+
+// hasObjectClass returns true if the entry has the given object class.
+func hasObjectClass(entry *storage.Entry, objectClass string) bool {
+	for name, values := range entry.Attributes {
+		if !strings.EqualFold(name, "objectclass") {
+			continue
+		}
+		for _, v := range values {
+			if strings.EqualFold(string(v), objectClass) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isUnderReferral returns true if dn is subordinate to one of the referral DNs.
+func isUnderReferral(dn string, referralDNs []string) bool {
+	normalized := normalizeDN(dn)
+	for _, refDN := range referralDNs {
+		if strings.HasSuffix(normalized, ","+refDN) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSearchResultEntry parses the content of a SearchResultEntry.
+func parseSearchResultEntry(data []byte) (*SearchEntry, error) {
+	decoder := ber.NewBERDecoder(data)
+
+	dn, err := decoder.ReadOctetString()
+	if err != nil {
+		return nil, err
+	}
+	entry := &SearchEntry{DN: string(dn)}
+
+	attrsLen, err := decoder.ExpectSequence()
+	if err != nil {
+		return nil, err
+	}
+	attrsEnd := decoder.Offset() + attrsLen
+	for decoder.Offset() < attrsEnd {
+		if _, err := decoder.ExpectSequence(); err != nil {
+			return nil, err
+		}
+		attrType, err := decoder.ReadOctetString()
+		if err != nil {
+			return nil, err
+		}
+		valsLen, err := decoder.ExpectSet()
+		if err != nil {
+			return nil, err
+		}
+		attr := ldap.Attribute{Type: string(attrType)}
+		valsEnd := decoder.Offset() + valsLen
+		for decoder.Offset() < valsEnd {
+			value, err := decoder.ReadOctetString()
+			if err != nil {
+				return nil, err
+			}
+			attr.Values = append(attr.Values, value)
+		}
+		entry.Attributes = append(entry.Attributes, attr)
+	}
+
+	return entry, nil
+}
+
+// parseSearchResultReference parses the content of a SearchResultReference.
+func parseSearchResultReference(data []byte) (*SearchReference, error) {
+	decoder := ber.NewBERDecoder(data)
+	ref := &SearchReference{}
+	for decoder.Remaining() > 0 {
+		uri, err := decoder.ReadOctetString()
+		if err != nil {
+			return nil, err
+		}
+		ref.URIs = append(ref.URIs, string(uri))
+	}
+	return ref, nil
+}
+
+// parseResultCode parses the result code and diagnostic message of an LDAPResult.
+func parseResultCode(data []byte) (ldap.ResultCode, string, error) {
+	decoder := ber.NewBERDecoder(data)
+
+	code, err := decoder.ReadEnumerated()
+	if err != nil {
+		return 0, "", err
+	}
+	if _, err := decoder.ReadOctetString(); err != nil {
+		return 0, "", err
+	}
+	diagnostic, err := decoder.ReadOctetString()
+	if err != nil {
+		return 0, "", err
+	}
+
+	return ldap.ResultCode(code), string(diagnostic), nil
+}
@@ -0,0 +1,251 @@
+// Package server provides the LDAP server implementation.
+package server
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// startReferralTestServer starts an in-process LDAP server backed by the given
+// search backend and returns its address.
+func startReferralTestServer(t *testing.T, backend *mockSearchBackend) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	handler := NewHandler()
+	handler.SetSearchHandler(CreateSearchHandler(NewSearchHandler(&SearchConfig{
+		Backend: backend,
+	})))
+	srv := &Server{Handler: handler}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go NewConnection(conn, srv).Handle()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func newReferralEntry(dn string, refs ...string) *storage.Entry {
+	entry := storage.NewEntry(dn)
+	entry.SetStringAttribute("objectClass", "referral", "extensibleObject")
+	entry.SetStringAttribute("ref", refs...)
+	return entry
+}
+
+func newPersonStorageEntry(dn, uid string) *storage.Entry {
+	entry := storage.NewEntry(dn)
+	entry.SetStringAttribute("objectClass", "inetOrgPerson", "person", "top")
+	entry.SetStringAttribute("uid", uid)
+	return entry
+}
+
+func entryDNs(entries []*SearchEntry) []string {
+	dns := make([]string, 0, len(entries))
+	for _, e := range entries {
+		dns = append(dns, e.DN)
+	}
+	sort.Strings(dns)
+	return dns
+}
+
+func TestSearchReturnsReferences(t *testing.T) {
+	backend := newMockSearchBackend()
+	backend.addEntry(newPersonStorageEntry("uid=alice,dc=example,dc=com", "alice"))
+	backend.addEntry(newReferralEntry("ou=remote,dc=example,dc=com", "ldap://remote.example.com/ou=remote,dc=example,dc=com"))
+
+	handler := NewSearchHandler(&SearchConfig{Backend: backend})
+
+	result := handler.Handle(nil, &ldap.SearchRequest{
+		BaseObject: "dc=example,dc=com",
+		Scope:      ldap.ScopeSingleLevel,
+	})
+	if result.ResultCode != ldap.ResultSuccess {
+		t.Fatalf("ResultCode = %v", result.ResultCode)
+	}
+	if len(result.Entries) != 1 || result.Entries[0].DN != "uid=alice,dc=example,dc=com" {
+		t.Errorf("unexpected entries: %v", entryDNs(result.Entries))
+	}
+	if len(result.References) != 1 {
+		t.Fatalf("expected 1 reference, got %d", len(result.References))
+	}
+	want := "ldap://remote.example.com/ou=remote,dc=example,dc=com??base"
+	if got := result.References[0].URIs[0]; got != want {
+		t.Errorf("reference URI = %q, want %q", got, want)
+	}
+}
+
+func TestSearchChasesReferrals(t *testing.T) {
+	remote := newMockSearchBackend()
+	remote.addEntry(newPersonStorageEntry("ou=remote,dc=example,dc=com", "remote"))
+	remote.addEntry(newPersonStorageEntry("uid=bob,ou=remote,dc=example,dc=com", "bob"))
+	remote.addEntry(newPersonStorageEntry("uid=carol,ou=remote,dc=example,dc=com", "carol"))
+	remoteAddr := startReferralTestServer(t, remote)
+
+	local := newMockSearchBackend()
+	local.addEntry(newPersonStorageEntry("dc=example,dc=com", "root"))
+	local.addEntry(newPersonStorageEntry("uid=alice,dc=example,dc=com", "alice"))
+	local.addEntry(newReferralEntry("ou=remote,dc=example,dc=com", "ldap://"+remoteAddr+"/ou=remote,dc=example,dc=com"))
+
+	handler := NewSearchHandler(&SearchConfig{
+		Backend:        local,
+		ChaseReferrals: true,
+	})
+
+	result := handler.Handle(nil, &ldap.SearchRequest{
+		BaseObject: "dc=example,dc=com",
+		Scope:      ldap.ScopeWholeSubtree,
+		Filter:     &ldap.SearchFilter{Type: ldap.FilterTagPresent, Attribute: "uid"},
+	})
+	if result.ResultCode != ldap.ResultSuccess {
+		t.Fatalf("ResultCode = %v (%s)", result.ResultCode, result.DiagnosticMessage)
+	}
+	if len(result.References) != 0 {
+		t.Errorf("expected chased references to be removed, got %d", len(result.References))
+	}
+
+	got := entryDNs(result.Entries)
+	want := []string{
+		"dc=example,dc=com",
+		"ou=remote,dc=example,dc=com",
+		"uid=alice,dc=example,dc=com",
+		"uid=bob,ou=remote,dc=example,dc=com",
+		"uid=carol,ou=remote,dc=example,dc=com",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("entries = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("entries[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestReferralChaserFollowsNestedReferences(t *testing.T) {
+	second := newMockSearchBackend()
+	second.addEntry(newPersonStorageEntry("uid=dave,ou=deep,dc=example,dc=com", "dave"))
+	secondAddr := startReferralTestServer(t, second)
+
+	first := newMockSearchBackend()
+	first.addEntry(newPersonStorageEntry("uid=bob,ou=remote,dc=example,dc=com", "bob"))
+	first.addEntry(newReferralEntry("ou=deep,ou=remote,dc=example,dc=com", "ldap://"+secondAddr+"/ou=deep,dc=example,dc=com"))
+	firstAddr := startReferralTestServer(t, first)
+
+	req := &ldap.SearchRequest{BaseObject: "dc=example,dc=com", Scope: ldap.ScopeWholeSubtree}
+	refs := []string{"ldap://" + firstAddr + "/ou=remote,dc=example,dc=com"}
+
+	entries, err := NewReferralChaser(nil).Chase(refs, req)
+	if err != nil {
+		t.Fatalf("Chase() error = %v", err)
+	}
+	got := entryDNs(entries)
+	if len(got) != 2 || got[0] != "uid=bob,ou=remote,dc=example,dc=com" || got[1] != "uid=dave,ou=deep,dc=example,dc=com" {
+		t.Errorf("entries = %v", got)
+	}
+
+	_, err = NewReferralChaser(&ReferralChaserConfig{MaxHops: 1}).Chase(refs, req)
+	if !errors.Is(err, ErrReferralHopLimit) {
+		t.Errorf("expected ErrReferralHopLimit, got %v", err)
+	}
+}
+
+func TestReferralChaserDetectsLoops(t *testing.T) {
+	loop := newMockSearchBackend()
+	addr := startReferralTestServer(t, loop)
+
+	// The referral points back to the server holding it.
+	loop.addEntry(newReferralEntry("ou=loop,dc=example,dc=com", "ldap://"+addr+"/dc=example,dc=com"))
+
+	req := &ldap.SearchRequest{BaseObject: "dc=example,dc=com", Scope: ldap.ScopeWholeSubtree}
+	_, err := NewReferralChaser(nil).Chase([]string{"ldap://" + addr + "/dc=example,dc=com"}, req)
+	if !errors.Is(err, ErrReferralLoop) {
+		t.Errorf("expected ErrReferralLoop, got %v", err)
+	}
+}
+
+func TestReferralChaserUnreachableKeepsReference(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	local := newMockSearchBackend()
+	local.addEntry(newReferralEntry("ou=remote,dc=example,dc=com", "ldap://"+addr+"/ou=remote,dc=example,dc=com"))
+
+	handler := NewSearchHandler(&SearchConfig{
+		Backend:        local,
+		ChaseReferrals: true,
+		ReferralChaser: NewReferralChaser(&ReferralChaserConfig{Timeout: 500 * time.Millisecond}),
+	})
+
+	result := handler.Handle(nil, &ldap.SearchRequest{
+		BaseObject: "dc=example,dc=com",
+		Scope:      ldap.ScopeWholeSubtree,
+	})
+	if result.ResultCode != ldap.ResultSuccess {
+		t.Fatalf("ResultCode = %v", result.ResultCode)
+	}
+	if len(result.References) != 1 {
+		t.Errorf("expected unchased reference to be returned, got %d", len(result.References))
+	}
+}
+
+func TestParseReferralURL(t *testing.T) {
+	tests := []struct {
+		raw      string
+		address  string
+		baseDN   string
+		scope    ldap.SearchScope
+		hasScope bool
+		useTLS   bool
+		wantErr  bool
+	}{
+		{raw: "ldap://host.example.com/dc=example,dc=com", address: "host.example.com:389", baseDN: "dc=example,dc=com"},
+		{raw: "ldaps://host.example.com:1636/ou=a,dc=b", address: "host.example.com:1636", baseDN: "ou=a,dc=b", useTLS: true},
+		{raw: "ldap://host:10389/ou=a%20b,dc=c??one", address: "host:10389", baseDN: "ou=a b,dc=c", scope: ldap.ScopeSingleLevel, hasScope: true},
+		{raw: "ldap://host/dc=c??base?(objectClass=*)", address: "host:389", baseDN: "dc=c", scope: ldap.ScopeBaseObject, hasScope: true},
+		{raw: "http://host/dc=c", wantErr: true},
+		{raw: "ldap:///dc=c", wantErr: true},
+		{raw: "ldap://host/dc=c??bogus", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			target, err := parseReferralURL(tt.raw)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidReferralURL) {
+					t.Errorf("expected ErrInvalidReferralURL, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseReferralURL() error = %v", err)
+			}
+			if target.address != tt.address || target.baseDN != tt.baseDN || target.useTLS != tt.useTLS {
+				t.Errorf("target = %+v", target)
+			}
+			if (target.scope != nil) != tt.hasScope || (tt.hasScope && *target.scope != tt.scope) {
+				t.Errorf("scope = %v, want %v", target.scope, tt.scope)
+			}
+		})
+	}
+}
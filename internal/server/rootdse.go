@@ -174,6 +174,12 @@ func (p *RootDSEProvider) GetSearchEntry() *SearchEntry {
 		Values: [][]byte{[]byte("top")},
 	})
 
+	// The naming contexts are the subordinates of the Root DSE
+	entry.Attributes = append(entry.Attributes, ldap.Attribute{
+		Type:   "hasSubordinates",
+		Values: [][]byte{[]byte("TRUE")},
+	})
+
 	// Add namingContexts
 	if len(dse.NamingContexts) > 0 {
 		values := make([][]byte, len(dse.NamingContexts))
@@ -193,6 +193,13 @@ func TestRootDSEProvider_GetSearchEntry(t *testing.T) {
 		t.Errorf("objectClass = %v, want [top]", values)
 	}
 
+	// Check hasSubordinates
+	if values, ok := attrMap["hasSubordinates"]; !ok {
+		t.Error("hasSubordinates attribute missing")
+	} else if len(values) != 1 || string(values[0]) != "TRUE" {
+		t.Errorf("hasSubordinates = %v, want [TRUE]", values)
+	}
+
 	// Check namingContexts
 	if values, ok := attrMap["namingContexts"]; !ok {
 		t.Error("namingContexts attribute missing")
@@ -37,6 +37,16 @@ type SearchConfig struct {
 	// ACLEvaluator is the ACL evaluator for access control checks.
 	// If nil, no ACL checks are performed.
 	ACLEvaluator *acl.Evaluator
+	// ChaseReferrals enables following continuation references on behalf of
+	// the client. Chased entries are merged into the result; references that
+	// cannot be chased are returned to the client unchanged.
+	ChaseReferrals bool
+	// ReferralChaser follows continuation references when ChaseReferrals is set.
+	// If nil, a chaser with default settings is used.
+	ReferralChaser *ReferralChaser
+	// VirtualAttributeProviders compute attributes that are not stored,
+	// such as hasSubordinates, for the entries a search returns.
+	VirtualAttributeProviders []VirtualAttributeProvider
 }
 
 // NewSearchConfig creates a new SearchConfig with default settings.
@@ -55,6 +65,7 @@ type SearchHandlerImpl struct {
 	evaluator        *filter.Evaluator
 	oneLevelSearcher *OneLevelSearcher
 	subtreeSearcher  *SubtreeSearcher
+	referralChaser   *ReferralChaser
 }
 
 // NewSearchHandler creates a new search handler with the given configuration.
@@ -77,6 +88,13 @@ func NewSearchHandler(config *SearchConfig) *SearchHandlerImpl {
 		handler.subtreeSearcher = NewSubtreeSearcher(sb)
 	}
 
+	if config.ChaseReferrals {
+		handler.referralChaser = config.ReferralChaser
+		if handler.referralChaser == nil {
+			handler.referralChaser = NewReferralChaser(nil)
+		}
+	}
+
 	return handler
 }
 
@@ -105,11 +123,7 @@ func (h *SearchHandlerImpl) Handle(conn *Connection, req *ldap.SearchRequest) *S
 
 	// Check ACL search permission on the base DN
 	if h.config.ACLEvaluator != nil {
-		bindDN := ""
-		if conn != nil {
-			bindDN = conn.BindDN()
-		}
-		if !h.config.ACLEvaluator.CanSearch(bindDN, req.BaseObject) {
+		if !h.config.ACLEvaluator.CheckAccess(accessContext(conn, req.BaseObject, acl.Search)) {
 			return &SearchResult{
 				OperationResult: OperationResult{
 					ResultCode:        ldap.ResultInsufficientAccessRights,
@@ -137,18 +151,41 @@ func (h *SearchHandlerImpl) Handle(conn *Connection, req *ldap.SearchRequest) *S
 		}
 	}
 
+	// Merge entries from chased referrals before access filtering
+	if h.referralChaser != nil && result != nil && len(result.References) > 0 {
+		h.chaseReferrals(conn, req, result)
+	}
+
 	// Filter attributes based on read permission
 	if h.config.ACLEvaluator != nil && result != nil && result.Entries != nil {
-		bindDN := ""
-		if conn != nil {
-			bindDN = conn.BindDN()
-		}
-		result.Entries = h.filterEntriesByACL(bindDN, result.Entries)
+		result.Entries = h.filterEntriesByACL(conn, result.Entries)
 	}
 
 	return result
 }
 
+// chaseReferrals follows the continuation references in result and merges the
+// returned entries. References that fail to chase are kept so the client can
+// follow them itself.
+func (h *SearchHandlerImpl) chaseReferrals(conn *Connection, req *ldap.SearchRequest, result *SearchResult) {
+	var remaining []*SearchReference
+	for _, ref := range result.References {
+		entries, err := h.referralChaser.Chase(ref.URIs, req)
+		if err != nil {
+			if conn != nil {
+				conn.Logger().Warn("referral chasing failed",
+					"base_dn", req.BaseObject,
+					"uris", strings.Join(ref.URIs, " "),
+					"error", err.Error())
+			}
+			remaining = append(remaining, ref)
+			continue
+		}
+		result.Entries = append(result.Entries, entries...)
+	}
+	result.References = remaining
+}
+
 // searchOneLevel performs a one-level scope search (returns immediate children).
 func (h *SearchHandlerImpl) searchOneLevel(conn *Connection, req *ldap.SearchRequest) *SearchResult {
 	if h.oneLevelSearcher == nil {
@@ -159,7 +196,7 @@ func (h *SearchHandlerImpl) searchOneLevel(conn *Connection, req *ldap.SearchReq
 			},
 		}
 	}
-	return h.oneLevelSearcher.Search(req, h.config)
+	return h.oneLevelSearcher.search(conn, req, h.config)
 }
 
 // searchSubtree performs a subtree scope search (returns base and all descendants).
@@ -172,7 +209,7 @@ func (h *SearchHandlerImpl) searchSubtree(conn *Connection, req *ldap.SearchRequ
 			},
 		}
 	}
-	return h.subtreeSearcher.Search(req, h.config)
+	return h.subtreeSearcher.search(conn, req, h.config)
 }
 
 // validateRequest validates the search request parameters.
@@ -234,6 +271,7 @@ func (h *SearchHandlerImpl) searchBase(conn *Connection, req *ldap.SearchRequest
 	}
 
 	// Build the search result entry with attribute selection
+	entry = withVirtualAttributes(conn, entry, req.Attributes, h.config)
 	searchEntry := buildSearchEntry(entry, req.Attributes, req.TypesOnly)
 
 	return &SearchResult{
@@ -303,6 +341,17 @@ func convertSearchFilter(sf *ldap.SearchFilter) *filter.Filter {
 	case ldap.FilterTagApproxMatch:
 		return filter.NewApproxMatchFilter(sf.Attribute, sf.Value)
 
+	case ldap.FilterTagExtensibleMatch:
+		if sf.ExtensibleMatch == nil {
+			return nil
+		}
+		return filter.NewExtensibleMatchFilter(&filter.ExtensibleMatchFilter{
+			MatchingRule: sf.ExtensibleMatch.MatchingRule,
+			Attribute:    sf.ExtensibleMatch.Type,
+			Value:        sf.ExtensibleMatch.MatchValue,
+			DNAttributes: sf.ExtensibleMatch.DNAttributes,
+		})
+
 	default:
 		return nil
 	}
@@ -389,13 +438,7 @@ func selectAttributes(entry *storage.Entry, requestedAttrs []string) map[string]
 
 	// Add specifically requested attributes
 	for _, attrName := range specificAttrs {
-		// Case-insensitive attribute lookup
-		for name, values := range entry.Attributes {
-			if strings.EqualFold(name, attrName) {
-				result[name] = values
-				break
-			}
-		}
+		addMatchingAttributes(result, entry, attrName)
 	}
 
 	return result
@@ -411,6 +454,8 @@ func isOperationalAttribute(name string) bool {
 		"modifiersname":         true,
 		"entrydn":               true,
 		"entryuuid":             true,
+		"entrycsn":              true,
+		"entryversion":          true,
 		"subschemasubentry":     true,
 		"hassubordinates":       true,
 		"numsubordinates":       true,
@@ -430,7 +475,7 @@ func CreateSearchHandler(impl *SearchHandlerImpl) SearchHandler {
 
 // filterEntriesByACL filters search result entries based on read permissions.
 // It removes entries the user cannot read and filters attributes within each entry.
-func (h *SearchHandlerImpl) filterEntriesByACL(bindDN string, entries []*SearchEntry) []*SearchEntry {
+func (h *SearchHandlerImpl) filterEntriesByACL(conn *Connection, entries []*SearchEntry) []*SearchEntry {
 	if h.config.ACLEvaluator == nil {
 		return entries
 	}
@@ -438,12 +483,13 @@ func (h *SearchHandlerImpl) filterEntriesByACL(bindDN string, entries []*SearchE
 	filtered := make([]*SearchEntry, 0, len(entries))
 	for _, entry := range entries {
 		// Check if user can read this entry
-		if !h.config.ACLEvaluator.CanRead(bindDN, entry.DN) {
+		ctx := accessContext(conn, entry.DN, acl.Read)
+		if !h.config.ACLEvaluator.CheckAccess(ctx) {
 			continue
 		}
 
 		// Filter attributes based on read permission
-		filteredEntry := h.filterEntryAttributes(bindDN, entry)
+		filteredEntry := h.filterEntryAttributes(ctx, entry)
 		if filteredEntry != nil {
 			filtered = append(filtered, filteredEntry)
 		}
@@ -453,18 +499,11 @@ func (h *SearchHandlerImpl) filterEntriesByACL(bindDN string, entries []*SearchE
 }
 
 // filterEntryAttributes filters attributes in a search entry based on read permissions.
-func (h *SearchHandlerImpl) filterEntryAttributes(bindDN string, entry *SearchEntry) *SearchEntry {
+func (h *SearchHandlerImpl) filterEntryAttributes(ctx *acl.AccessContext, entry *SearchEntry) *SearchEntry {
 	if h.config.ACLEvaluator == nil || entry == nil {
 		return entry
 	}
 
-	// Create access context for attribute filtering
-	ctx := &acl.AccessContext{
-		BindDN:    bindDN,
-		TargetDN:  entry.DN,
-		Operation: acl.Read,
-	}
-
 	filteredAttrs := make([]ldap.Attribute, 0, len(entry.Attributes))
 	for _, attr := range entry.Attributes {
 		if h.config.ACLEvaluator.CheckAttributeAccess(ctx, attr.Type) {
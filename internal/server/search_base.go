@@ -157,6 +157,17 @@ func ldapFilterToFilter(sf *ldap.SearchFilter) *filter.Filter {
 	case ldap.FilterTagApproxMatch:
 		return filter.NewApproxMatchFilter(sf.Attribute, sf.Value)
 
+	case ldap.FilterTagExtensibleMatch:
+		if sf.ExtensibleMatch == nil {
+			return nil
+		}
+		return filter.NewExtensibleMatchFilter(&filter.ExtensibleMatchFilter{
+			MatchingRule: sf.ExtensibleMatch.MatchingRule,
+			Attribute:    sf.ExtensibleMatch.Type,
+			Value:        sf.ExtensibleMatch.MatchValue,
+			DNAttributes: sf.ExtensibleMatch.DNAttributes,
+		})
+
 	default:
 		return nil
 	}
@@ -189,6 +200,13 @@ func buildSearchEntryFromStorage(entry *storage.Entry, requestedAttrs []string,
 	return searchEntry
 }
 
+// BuildSearchEntry builds a search result entry holding the attributes of
+// entry selected by requestedAttrs. If typesOnly is set, only the attribute
+// types are returned.
+func BuildSearchEntry(entry *storage.Entry, requestedAttrs []string, typesOnly bool) *SearchEntry {
+	return buildSearchEntryFromStorage(entry, requestedAttrs, typesOnly)
+}
+
 // findMatchedDNForBase finds the longest existing parent DN for error reporting.
 // This is used when the base DN doesn't exist to return the closest ancestor.
 func findMatchedDNForBase(dn string) string {
@@ -0,0 +1,121 @@
+// Package server provides the LDAP server implementation.
+package server
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/acl"
+)
+
+// SearchLimitProfile caps the size and time limits of the searches of the
+// clients its subject matches. A limit of 0 removes the cap, and a
+// negative limit keeps the handler's default.
+type SearchLimitProfile struct {
+	// Subject is "anonymous", "authenticated", "*", "group:<DN>" or a
+	// bind DN.
+	Subject string
+	// SizeLimit is the maximum number of entries a search returns.
+	SizeLimit int
+	// TimeLimit is the maximum duration of a search in seconds.
+	TimeLimit int
+}
+
+// SearchLimits resolves the limit profile of a search from its bind DN.
+// The profiles can be replaced while searches run.
+type SearchLimits struct {
+	mu       sync.RWMutex
+	profiles []SearchLimitProfile
+	groups   acl.GroupResolver
+}
+
+// NewSearchLimits creates SearchLimits with the given profiles. Group
+// subjects are resolved with groups and match nobody when it is nil.
+func NewSearchLimits(profiles []SearchLimitProfile, groups acl.GroupResolver) *SearchLimits {
+	l := &SearchLimits{groups: groups}
+	l.SetProfiles(profiles)
+	return l
+}
+
+// SetProfiles replaces the limit profiles.
+func (l *SearchLimits) SetProfiles(profiles []SearchLimitProfile) {
+	profiles = append([]SearchLimitProfile(nil), profiles...)
+
+	l.mu.Lock()
+	l.profiles = profiles
+	l.mu.Unlock()
+}
+
+// Profiles returns a copy of the limit profiles.
+func (l *SearchLimits) Profiles() []SearchLimitProfile {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]SearchLimitProfile(nil), l.profiles...)
+}
+
+// Resolve returns the profile whose subject matches bindDN most
+// specifically, or nil if none does. A bind DN is more specific than a
+// group, a group than anonymous or authenticated, and those than "*".
+// Among equally specific profiles the first one wins.
+func (l *SearchLimits) Resolve(bindDN string) *SearchLimitProfile {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var best *SearchLimitProfile
+	bestRank := -1
+	for i := range l.profiles {
+		rank := l.matchRank(l.profiles[i].Subject, bindDN)
+		if rank > bestRank {
+			p := l.profiles[i]
+			best, bestRank = &p, rank
+		}
+	}
+	return best
+}
+
+// matchRank returns how specifically subject matches bindDN, or -1 if it
+// does not match.
+func (l *SearchLimits) matchRank(subject, bindDN string) int {
+	subject = strings.TrimSpace(subject)
+	switch lower := strings.ToLower(subject); {
+	case lower == "*":
+		return 0
+	case lower == "anonymous":
+		if bindDN == "" {
+			return 1
+		}
+	case lower == "authenticated":
+		if bindDN != "" {
+			return 1
+		}
+	case strings.HasPrefix(lower, acl.GroupSubjectPrefix):
+		groupDN := strings.TrimSpace(subject[len(acl.GroupSubjectPrefix):])
+		if bindDN != "" && l.groups != nil && l.groups.IsMember(groupDN, bindDN) {
+			return 2
+		}
+	default:
+		if bindDN != "" && normalizeDN(subject) == normalizeDN(bindDN) {
+			return 3
+		}
+	}
+	return -1
+}
+
+// searchLimit holds the limits a search runs with.
+type searchLimit struct {
+	// sizeLimit is the maximum number of entries returned (0 = none)
+	sizeLimit int
+	// timeLimit is the maximum duration of the search (0 = none)
+	timeLimit time.Duration
+	// subject is the subject of the profile that applied, if any
+	subject string
+}
+
+// capLimit caps requested by max, treating 0 as no limit for both.
+func capLimit(requested, max int) int {
+	if max > 0 && (requested == 0 || requested > max) {
+		return max
+	}
+	return requested
+}
@@ -0,0 +1,147 @@
+// Package server provides the LDAP server implementation.
+package server
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/ber"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+)
+
+// staticGroups is a GroupResolver backed by a map of group DN to members.
+type staticGroups map[string][]string
+
+func (g staticGroups) IsMember(groupDN, memberDN string) bool {
+	for _, member := range g[normalizeDN(groupDN)] {
+		if normalizeDN(member) == normalizeDN(memberDN) {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	syncDN  = "cn=sync,ou=services,dc=example,dc=com"
+	aliceDN = "uid=alice,ou=users,dc=example,dc=com"
+	bobDN   = "uid=bob,ou=users,dc=example,dc=com"
+)
+
+func newTestSearchLimits() *SearchLimits {
+	groups := staticGroups{"cn=admins,ou=groups,dc=example,dc=com": {bobDN}}
+	return NewSearchLimits([]SearchLimitProfile{
+		{Subject: "*", SizeLimit: 10, TimeLimit: -1},
+		{Subject: "authenticated", SizeLimit: 500, TimeLimit: -1},
+		{Subject: "group:cn=admins,ou=groups,dc=example,dc=com", SizeLimit: 5000, TimeLimit: -1},
+		{Subject: "CN=Sync,OU=Services,DC=Example,DC=Com", SizeLimit: 0, TimeLimit: 600},
+	}, groups)
+}
+
+func TestSearchLimitsResolve(t *testing.T) {
+	limits := newTestSearchLimits()
+
+	tests := []struct {
+		bindDN string
+		want   string
+	}{
+		{"", "*"},
+		{aliceDN, "authenticated"},
+		{bobDN, "group:cn=admins,ou=groups,dc=example,dc=com"},
+		{syncDN, "CN=Sync,OU=Services,DC=Example,DC=Com"},
+	}
+	for _, tt := range tests {
+		p := limits.Resolve(tt.bindDN)
+		if p == nil || p.Subject != tt.want {
+			t.Errorf("Resolve(%q) = %+v, want subject %q", tt.bindDN, p, tt.want)
+		}
+	}
+
+	// Replacing the profiles applies to the next search
+	limits.SetProfiles([]SearchLimitProfile{{Subject: "anonymous", SizeLimit: 1, TimeLimit: 1}})
+	if p := limits.Resolve(aliceDN); p != nil {
+		t.Errorf("Resolve(alice) after SetProfiles = %+v, want nil", p)
+	}
+	if p := limits.Resolve(""); p == nil || p.Subject != "anonymous" {
+		t.Errorf("Resolve(anonymous) after SetProfiles = %+v, want anonymous", p)
+	}
+}
+
+func TestHandlerSearchLimits(t *testing.T) {
+	handler := NewHandler()
+	handler.SetMaxTimeLimit(60)
+	handler.SetSearchLimits(newTestSearchLimits())
+
+	tests := []struct {
+		name                 string
+		bindDN               string
+		sizeLimit, timeLimit int
+		wantSize             int
+		wantTime             time.Duration
+	}{
+		{"user without limits", aliceDN, 0, 0, 500, 0},
+		{"user over the cap", aliceDN, 1000, 120, 500, 60 * time.Second},
+		{"user below the cap", aliceDN, 100, 30, 100, 30 * time.Second},
+		{"sync without limits", syncDN, 0, 0, 0, 600 * time.Second},
+		{"sync over the default", syncDN, 100000, 300, 100000, 300 * time.Second},
+		{"admin", bobDN, 0, 0, 5000, 0},
+	}
+	for _, tt := range tests {
+		got := handler.searchLimits(tt.bindDN, &ldap.SearchRequest{SizeLimit: tt.sizeLimit, TimeLimit: tt.timeLimit})
+		if got.sizeLimit != tt.wantSize || got.timeLimit != tt.wantTime {
+			t.Errorf("%s: searchLimits() = %d entries, %v, want %d entries, %v",
+				tt.name, got.sizeLimit, got.timeLimit, tt.wantSize, tt.wantTime)
+		}
+	}
+}
+
+func TestConnectionSearchSizeLimitProfile(t *testing.T) {
+	handler := NewHandler()
+	handler.SetSearchLimits(NewSearchLimits([]SearchLimitProfile{{Subject: "anonymous", SizeLimit: 2, TimeLimit: -1}}, nil))
+	handler.SetSearchHandler(func(conn *Connection, req *ldap.SearchRequest) *SearchResult {
+		return &SearchResult{
+			OperationResult: OperationResult{ResultCode: ldap.ResultSuccess},
+			Entries: []*SearchEntry{
+				{DN: "uid=a,ou=users,dc=example,dc=com"},
+				{DN: "uid=b,ou=users,dc=example,dc=com"},
+				{DN: "uid=c,ou=users,dc=example,dc=com"},
+			},
+		}
+	})
+
+	mockConn := newMockConn()
+	mockConn.setReadData(append(createSearchRequestMessage(1, "dc=example,dc=com"), createUnbindRequestMessage(2)...))
+	conn := NewConnection(mockConn, &Server{Handler: handler})
+
+	done := make(chan struct{})
+	go func() {
+		conn.Handle()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Handle did not complete")
+	}
+
+	written := bytes.NewReader(mockConn.getWrittenData())
+	entries := 0
+	for {
+		msg, err := readLDAPMessage(written)
+		if err != nil {
+			t.Fatalf("readLDAPMessage() error = %v", err)
+		}
+		if msg.OperationType() == ldap.ApplicationSearchResultEntry {
+			entries++
+			continue
+		}
+		code, _ := ber.NewBERDecoder(msg.Operation.Data).ReadEnumerated()
+		if ldap.ResultCode(code) != ldap.ResultSizeLimitExceeded {
+			t.Errorf("result code = %s, want sizeLimitExceeded", ldap.ResultCode(code))
+		}
+		break
+	}
+	if entries != 2 {
+		t.Errorf("got %d entries, want 2", entries)
+	}
+}
@@ -28,6 +28,11 @@ func NewOneLevelSearcher(backend SearchBackend) *OneLevelSearcher {
 // It iterates over immediate children of the base DN, evaluates the filter,
 // and returns matching entries.
 func (s *OneLevelSearcher) Search(req *ldap.SearchRequest, config *SearchConfig) *SearchResult {
+	return s.search(nil, req, config)
+}
+
+// search performs the search for the client of conn, which may be nil.
+func (s *OneLevelSearcher) search(conn *Connection, req *ldap.SearchRequest, config *SearchConfig) *SearchResult {
 	// Get iterator for one-level scope
 	iter := s.backend.SearchByDN(req.BaseObject, storage.ScopeOneLevel)
 	if iter == nil {
@@ -51,12 +56,13 @@ func (s *OneLevelSearcher) Search(req *ldap.SearchRequest, config *SearchConfig)
 	}
 
 	// Process results with limits
-	return s.processResults(req, config, iter)
+	return s.processResults(conn, req, config, iter)
 }
 
 // processResults iterates over entries and applies filter, size limit, and time limit.
-func (s *OneLevelSearcher) processResults(req *ldap.SearchRequest, config *SearchConfig, iter storage.Iterator) *SearchResult {
+func (s *OneLevelSearcher) processResults(conn *Connection, req *ldap.SearchRequest, config *SearchConfig, iter storage.Iterator) *SearchResult {
 	var entries []*SearchEntry
+	var references []*SearchReference
 	count := 0
 
 	// Calculate effective limits
@@ -90,7 +96,8 @@ func (s *OneLevelSearcher) processResults(req *ldap.SearchRequest, config *Searc
 				OperationResult: OperationResult{
 					ResultCode: ldap.ResultSizeLimitExceeded,
 				},
-				Entries: entries,
+				Entries:    entries,
+				References: references,
 			}
 		}
 
@@ -100,7 +107,8 @@ func (s *OneLevelSearcher) processResults(req *ldap.SearchRequest, config *Searc
 				OperationResult: OperationResult{
 					ResultCode: ldap.ResultTimeLimitExceeded,
 				},
-				Entries: entries,
+				Entries:    entries,
+				References: references,
 			}
 		}
 
@@ -109,12 +117,19 @@ func (s *OneLevelSearcher) processResults(req *ldap.SearchRequest, config *Searc
 			continue
 		}
 
+		// Referral children become continuation references
+		if ref := referralFromEntry(entry, req.Scope); ref != nil {
+			references = append(references, ref)
+			continue
+		}
+
 		// Evaluate filter
 		if !s.matchesFilter(entry, req.Filter) {
 			continue
 		}
 
 		// Build search entry with attribute selection
+		entry = withVirtualAttributes(conn, entry, req.Attributes, config)
 		searchEntry := buildSearchEntryFromStorage(entry, req.Attributes, req.TypesOnly)
 		entries = append(entries, searchEntry)
 		count++
@@ -127,7 +142,8 @@ func (s *OneLevelSearcher) processResults(req *ldap.SearchRequest, config *Searc
 				ResultCode:        ldap.ResultOperationsError,
 				DiagnosticMessage: "error during search iteration",
 			},
-			Entries: entries,
+			Entries:    entries,
+			References: references,
 		}
 	}
 
@@ -135,7 +151,8 @@ func (s *OneLevelSearcher) processResults(req *ldap.SearchRequest, config *Searc
 		OperationResult: OperationResult{
 			ResultCode: ldap.ResultSuccess,
 		},
-		Entries: entries,
+		Entries:    entries,
+		References: references,
 	}
 }
 
@@ -28,6 +28,11 @@ func NewSubtreeSearcher(backend SearchBackend) *SubtreeSearcher {
 // It iterates over the base entry and all its descendants, evaluates the filter,
 // and returns matching entries.
 func (s *SubtreeSearcher) Search(req *ldap.SearchRequest, config *SearchConfig) *SearchResult {
+	return s.search(nil, req, config)
+}
+
+// search performs the search for the client of conn, which may be nil.
+func (s *SubtreeSearcher) search(conn *Connection, req *ldap.SearchRequest, config *SearchConfig) *SearchResult {
 	// Get iterator for subtree scope
 	iter := s.backend.SearchByDN(req.BaseObject, storage.ScopeSubtree)
 	if iter == nil {
@@ -51,12 +56,14 @@ func (s *SubtreeSearcher) Search(req *ldap.SearchRequest, config *SearchConfig)
 	}
 
 	// Process results with limits
-	return s.processResults(req, config, iter)
+	return s.processResults(conn, req, config, iter)
 }
 
 // processResults iterates over entries and applies filter, size limit, and time limit.
-func (s *SubtreeSearcher) processResults(req *ldap.SearchRequest, config *SearchConfig, iter storage.Iterator) *SearchResult {
+func (s *SubtreeSearcher) processResults(conn *Connection, req *ldap.SearchRequest, config *SearchConfig, iter storage.Iterator) *SearchResult {
 	var entries []*SearchEntry
+	var references []*SearchReference
+	var referralDNs []string
 	count := 0
 
 	// Calculate effective limits
@@ -90,7 +97,8 @@ func (s *SubtreeSearcher) processResults(req *ldap.SearchRequest, config *Search
 				OperationResult: OperationResult{
 					ResultCode: ldap.ResultSizeLimitExceeded,
 				},
-				Entries: entries,
+				Entries:    entries,
+				References: references,
 			}
 		}
 
@@ -100,7 +108,8 @@ func (s *SubtreeSearcher) processResults(req *ldap.SearchRequest, config *Search
 				OperationResult: OperationResult{
 					ResultCode: ldap.ResultTimeLimitExceeded,
 				},
-				Entries: entries,
+				Entries:    entries,
+				References: references,
 			}
 		}
 
@@ -109,12 +118,27 @@ func (s *SubtreeSearcher) processResults(req *ldap.SearchRequest, config *Search
 			continue
 		}
 
+		// Entries below a referral are held by the referred server
+		if isUnderReferral(entry.DN, referralDNs) {
+			continue
+		}
+
+		// Referral entries below the base become continuation references
+		if normalizeDN(entry.DN) != normalizeDN(req.BaseObject) {
+			if ref := referralFromEntry(entry, req.Scope); ref != nil {
+				references = append(references, ref)
+				referralDNs = append(referralDNs, normalizeDN(entry.DN))
+				continue
+			}
+		}
+
 		// Evaluate filter
 		if !s.matchesFilter(entry, req.Filter) {
 			continue
 		}
 
 		// Build search entry with attribute selection
+		entry = withVirtualAttributes(conn, entry, req.Attributes, config)
 		searchEntry := buildSearchEntryFromStorage(entry, req.Attributes, req.TypesOnly)
 		entries = append(entries, searchEntry)
 		count++
@@ -127,7 +151,8 @@ func (s *SubtreeSearcher) processResults(req *ldap.SearchRequest, config *Search
 				ResultCode:        ldap.ResultOperationsError,
 				DiagnosticMessage: "error during search iteration",
 			},
-			Entries: entries,
+			Entries:    entries,
+			References: references,
 		}
 	}
 
@@ -135,7 +160,8 @@ func (s *SubtreeSearcher) processResults(req *ldap.SearchRequest, config *Search
 		OperationResult: OperationResult{
 			ResultCode: ldap.ResultSuccess,
 		},
-		Entries: entries,
+		Entries:    entries,
+		References: references,
 	}
 }
 
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/schema"
 	"github.com/KilimcininKorOglu/oba/internal/storage"
 )
 
@@ -1068,6 +1069,87 @@ func TestSelectAttributes(t *testing.T) {
 	}
 }
 
+// TestSelectAttributesWithOptions tests selection of attributes with
+// attribute description options.
+func TestSelectAttributesWithOptions(t *testing.T) {
+	entry := storage.NewEntry("uid=test,dc=example,dc=com")
+	entry.SetStringAttribute("cn", "Test User")
+	entry.SetStringAttribute("cn;lang-fr", "Utilisateur")
+	entry.SetStringAttribute("sn;lang-fr", "Essai")
+	entry.SetStringAttribute("usercertificate", "cert")
+	entry.SetStringAttribute("createtimestamp;lang-fr", "20240101000000Z")
+
+	tests := []struct {
+		name      string
+		requested []string
+		want      []string
+	}{
+		{"base type returns subtypes", []string{"cn"}, []string{"cn", "cn;lang-fr"}},
+		{"option selects subtype", []string{"cn;lang-fr"}, []string{"cn;lang-fr"}},
+		{"binary is a transfer option", []string{"userCertificate;binary"}, []string{"usercertificate;binary"}},
+		{"language range", []string{"*;lang-*"}, []string{"cn;lang-fr", "sn;lang-fr"}},
+		{"unknown option", []string{"cn;lang-de"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, result := range []map[string][][]byte{
+				SelectAttributes(entry, tt.requested),
+				selectAttributes(entry, tt.requested),
+			} {
+				if len(result) != len(tt.want) {
+					t.Errorf("got %d attributes %v, want %v", len(result), result, tt.want)
+				}
+				for _, name := range tt.want {
+					if _, ok := result[name]; !ok {
+						t.Errorf("attribute %s missing from result", name)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestExpandAttributeAliases tests selection of attributes by any of
+// their schema names.
+func TestExpandAttributeAliases(t *testing.T) {
+	s := schema.LoadDefaultSchema()
+	entry := storage.NewEntry("uid=test,dc=example,dc=com")
+	entry.SetStringAttribute("cn", "Test User")
+	entry.SetStringAttribute("cn;lang-fr", "Utilisateur")
+	entry.SetStringAttribute("surname", "User")
+
+	tests := []struct {
+		name      string
+		requested []string
+		want      []string
+	}{
+		{"alias selects primary name", []string{"commonName"}, []string{"cn", "cn;lang-fr"}},
+		{"primary name selects alias", []string{"sn"}, []string{"surname"}},
+		{"options are kept", []string{"commonName;lang-fr"}, []string{"cn;lang-fr"}},
+		{"unknown attribute", []string{"mail"}, nil},
+		{"no attributes", []string{"1.1"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := SelectAttributes(entry, ExpandAttributeAliases(tt.requested, s))
+			if len(result) != len(tt.want) {
+				t.Errorf("got %d attributes %v, want %v", len(result), result, tt.want)
+			}
+			for _, name := range tt.want {
+				if _, ok := result[name]; !ok {
+					t.Errorf("attribute %s missing from result", name)
+				}
+			}
+		})
+	}
+
+	if got := ExpandAttributeAliases([]string{"commonName"}, nil); len(got) != 1 {
+		t.Errorf("ExpandAttributeAliases without schema = %v, want [commonName]", got)
+	}
+}
+
 // TestIsOperationalAttribute tests operational attribute detection.
 func TestIsOperationalAttribute(t *testing.T) {
 	tests := []struct {
@@ -1081,6 +1163,7 @@ func TestIsOperationalAttribute(t *testing.T) {
 		{"modifiersName", "modifiersName", true},
 		{"entryDN", "entryDN", true},
 		{"entryUUID", "entryUUID", true},
+		{"entryVersion", "entryVersion", true},
 		{"uid - not operational", "uid", false},
 		{"cn - not operational", "cn", false},
 		{"mail - not operational", "mail", false},
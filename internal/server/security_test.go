@@ -470,22 +470,25 @@ func createTestConnection(isTLS bool, tlsVersion uint16, cipherSuite uint16, has
 
 	if isTLS {
 		// Manually set TLS state since we can't use a real TLS connection in tests
-		conn.mu.Lock()
-		conn.isTLS = true
-		conn.tlsState = &tls.ConnectionState{
+		tlsState := &tls.ConnectionState{
 			Version:     tlsVersion,
 			CipherSuite: cipherSuite,
 			ServerName:  "test.example.com",
 		}
+		var clientCert *x509.Certificate
 		if hasClientCert {
 			// Create a test certificate
 			cert, err := generateTestTLSCertificate()
 			if err == nil && cert.Leaf != nil {
-				conn.clientCert = cert.Leaf
-				conn.tlsState.PeerCertificates = []*x509.Certificate{cert.Leaf}
+				clientCert = cert.Leaf
+				tlsState.PeerCertificates = []*x509.Certificate{cert.Leaf}
 			}
 		}
-		conn.mu.Unlock()
+		conn.updateState(func(s *connState) {
+			s.isTLS = true
+			s.tlsState = tlsState
+			s.clientCert = clientCert
+		})
 	}
 
 	return conn
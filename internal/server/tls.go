@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 )
 
 // TLS version constants for convenience.
@@ -36,6 +37,8 @@ var (
 	ErrInvalidTLSVersion  = errors.New("invalid TLS version")
 	ErrMinVersionTooHigh  = errors.New("minimum TLS version is higher than maximum")
 	ErrInvalidCipherSuite = errors.New("invalid cipher suite")
+	ErrInsecureCipher     = errors.New("insecure cipher suite")
+	ErrTLS13CipherSuite   = errors.New("TLS 1.3 cipher suites are not configurable")
 	ErrCertFileNotFound   = errors.New("certificate file not found")
 	ErrKeyFileNotFound    = errors.New("private key file not found")
 	ErrInvalidCertPEM     = errors.New("invalid certificate PEM data")
@@ -76,6 +79,11 @@ type TLSConfig struct {
 	// ClientCAPEM is the client CA certificates in PEM format.
 	// Used to build ClientCAs if ClientCAs is nil.
 	ClientCAPEM []byte
+
+	// GetCertificate returns the certificate for each handshake.
+	// If set, it is used instead of the certificate files or PEM data,
+	// so the certificate can be replaced without restarting listeners.
+	GetCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)
 }
 
 // NewTLSConfig creates a new TLSConfig with secure defaults.
@@ -138,6 +146,13 @@ func (c *TLSConfig) WithClientCAPEM(pem []byte) *TLSConfig {
 	return c
 }
 
+// WithGetCertificate sets the callback that returns the certificate for
+// each handshake.
+func (c *TLSConfig) WithGetCertificate(fn func(*tls.ClientHelloInfo) (*tls.Certificate, error)) *TLSConfig {
+	c.GetCertificate = fn
+	return c
+}
+
 // LoadTLSConfig creates a *tls.Config from the TLSConfig.
 // It validates the configuration and loads certificates.
 func LoadTLSConfig(cfg *TLSConfig) (*tls.Config, error) {
@@ -150,10 +165,14 @@ func LoadTLSConfig(cfg *TLSConfig) (*tls.Config, error) {
 		return nil, err
 	}
 
-	// Load certificate
-	cert, err := loadCertificateFromConfig(cfg)
-	if err != nil {
-		return nil, err
+	// Load certificate unless it is provided per handshake
+	var certificates []tls.Certificate
+	if cfg.GetCertificate == nil {
+		cert, err := loadCertificateFromConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		certificates = []tls.Certificate{cert}
 	}
 
 	// Build client CA pool if needed
@@ -177,12 +196,13 @@ func LoadTLSConfig(cfg *TLSConfig) (*tls.Config, error) {
 	}
 
 	tlsConfig := &tls.Config{
-		Certificates: []tls.Certificate{cert},
-		MinVersion:   cfg.MinVersion,
-		MaxVersion:   cfg.MaxVersion,
-		CipherSuites: cipherSuites,
-		ClientAuth:   cfg.ClientAuth,
-		ClientCAs:    clientCAs,
+		Certificates:   certificates,
+		GetCertificate: cfg.GetCertificate,
+		MinVersion:     cfg.MinVersion,
+		MaxVersion:     cfg.MaxVersion,
+		CipherSuites:   cipherSuites,
+		ClientAuth:     cfg.ClientAuth,
+		ClientCAs:      clientCAs,
 	}
 
 	return tlsConfig, nil
@@ -190,6 +210,13 @@ func LoadTLSConfig(cfg *TLSConfig) (*tls.Config, error) {
 
 // LoadCertificate loads a certificate from file paths.
 func LoadCertificate(certFile, keyFile string) (tls.Certificate, error) {
+	return LoadCertificateWithPassphrase(certFile, keyFile, nil)
+}
+
+// LoadCertificateWithPassphrase loads a certificate from file paths. An
+// encrypted private key is decrypted with passphrase, see
+// DecryptPrivateKeyPEM.
+func LoadCertificateWithPassphrase(certFile, keyFile string, passphrase []byte) (tls.Certificate, error) {
 	if certFile == "" {
 		return tls.Certificate{}, ErrNoCertificate
 	}
@@ -205,7 +232,20 @@ func LoadCertificate(certFile, keyFile string) (tls.Certificate, error) {
 		return tls.Certificate{}, ErrKeyFileNotFound
 	}
 
-	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	keyPEM, err = DecryptPrivateKeyPEM(keyPEM, passphrase)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
 	if err != nil {
 		// Try to provide more specific error
 		if isKeyMismatchError(err) {
@@ -372,6 +412,70 @@ func toLower(c byte) byte {
 	return c
 }
 
+// ParseTLSVersion returns the TLS version for a configured name: "tls12"
+// or "tls13". An empty name returns 0, which keeps the default.
+func ParseTLSVersion(name string) (uint16, error) {
+	switch strings.ToLower(name) {
+	case "":
+		return 0, nil
+	case "tls12":
+		return TLSVersion12, nil
+	case "tls13":
+		return TLSVersion13, nil
+	default:
+		return 0, fmt.Errorf("%w: %q", ErrInvalidTLSVersion, name)
+	}
+}
+
+// ParseCipherSuites returns the IDs of the cipher suites with the given
+// names, as reported by CipherSuiteName, such as
+// "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256". Names are case-insensitive.
+// Only secure TLS 1.2 suites are accepted: suites Go reports as insecure
+// fail with ErrInsecureCipher, and TLS 1.3 suites, which cannot be
+// configured, fail with ErrTLS13CipherSuite.
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	ids := make(map[string]uint16)
+	tls13 := make(map[string]bool)
+	for _, suite := range tls.CipherSuites() {
+		if supportsTLS12(suite) {
+			ids[suite.Name] = suite.ID
+		} else {
+			tls13[suite.Name] = true
+		}
+	}
+	insecure := make(map[string]bool)
+	for _, suite := range tls.InsecureCipherSuites() {
+		insecure[suite.Name] = true
+	}
+
+	suites := make([]uint16, 0, len(names))
+	for _, name := range names {
+		upper := strings.ToUpper(name)
+		id, ok := ids[upper]
+		switch {
+		case ok:
+			suites = append(suites, id)
+		case insecure[upper]:
+			return nil, fmt.Errorf("%w: %q", ErrInsecureCipher, name)
+		case tls13[upper]:
+			return nil, fmt.Errorf("%w: %q", ErrTLS13CipherSuite, name)
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrInvalidCipherSuite, name)
+		}
+	}
+	return suites, nil
+}
+
+// supportsTLS12 reports whether suite can be negotiated in TLS 1.2.
+func supportsTLS12(suite *tls.CipherSuite) bool {
+	for _, v := range suite.SupportedVersions {
+		if v == tls.VersionTLS12 {
+			return true
+		}
+	}
+	return false
+}
+
 // GetDefaultCipherSuites returns a copy of the default cipher suites.
 func GetDefaultCipherSuites() []uint16 {
 	result := make([]uint16, len(defaultCipherSuites))
@@ -0,0 +1,251 @@
+package server
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+// Private key decryption errors.
+var (
+	ErrKeyEncrypted        = errors.New("private key is encrypted and no passphrase was given")
+	ErrKeyPassphrase       = errors.New("incorrect private key passphrase")
+	ErrKeyEncryptionScheme = errors.New("unsupported private key encryption")
+)
+
+// PKCS #5 (RFC 8018) and AES object identifiers.
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA224 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 8}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidAES128CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// encryptedPrivateKeyInfo is an encrypted PKCS #8 private key (RFC 5958).
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+// pbes2Params are the parameters of PBES2 (RFC 8018 Appendix A.4).
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+// pbkdf2Params are the parameters of PBKDF2 (RFC 8018 Appendix A.2). The
+// PRF defaults to hmacWithSHA1.
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// DecryptPrivateKeyPEM returns keyPEM with its encrypted private keys
+// decrypted with passphrase. It supports encrypted PKCS #8 keys (ENCRYPTED
+// PRIVATE KEY with PBES2, PBKDF2 and AES-CBC, which OpenSSL writes by
+// default) and legacy OpenSSL encrypted PEM blocks (Proc-Type:
+// 4,ENCRYPTED). Other blocks are returned unchanged.
+func DecryptPrivateKeyPEM(keyPEM, passphrase []byte) ([]byte, error) {
+	var out bytes.Buffer
+	rest := keyPEM
+	for {
+		block, next := pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		rest = next
+
+		encrypted := block.Type == "ENCRYPTED PRIVATE KEY" || x509.IsEncryptedPEMBlock(block)
+		if encrypted && len(passphrase) == 0 {
+			return nil, ErrKeyEncrypted
+		}
+
+		switch {
+		case block.Type == "ENCRYPTED PRIVATE KEY":
+			der, err := decryptPKCS8(block.Bytes, passphrase)
+			if err != nil {
+				return nil, err
+			}
+			block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+		case encrypted:
+			// The legacy format cannot tell a wrong passphrase from a
+			// corrupted key; both are reported as a wrong passphrase
+			der, err := x509.DecryptPEMBlock(block, passphrase)
+			if err != nil {
+				return nil, ErrKeyPassphrase
+			}
+			block = &pem.Block{Type: block.Type, Bytes: der}
+		}
+		if err := pem.Encode(&out, block); err != nil {
+			return nil, err
+		}
+	}
+
+	if out.Len() == 0 {
+		// Not PEM; leave the error to the key parser
+		return keyPEM, nil
+	}
+	return out.Bytes(), nil
+}
+
+// decryptPKCS8 decrypts an encrypted PKCS #8 private key and returns the
+// PKCS #8 encoding of the key.
+func decryptPKCS8(der, passphrase []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, ErrInvalidKeyPEM
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("%w: %s", ErrKeyEncryptionScheme, info.Algorithm.Algorithm)
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, ErrInvalidKeyPEM
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("%w: key derivation %s", ErrKeyEncryptionScheme, params.KeyDerivationFunc.Algorithm)
+	}
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, ErrInvalidKeyPEM
+	}
+	prf, err := pbkdf2PRF(kdf.PRF.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	keyLen, err := aesCBCKeyLength(params.EncryptionScheme.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if kdf.KeyLength != 0 && kdf.KeyLength != keyLen {
+		return nil, ErrInvalidKeyPEM
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil || len(iv) != aes.BlockSize {
+		return nil, ErrInvalidKeyPEM
+	}
+	if kdf.IterationCount <= 0 || len(info.EncryptedData) == 0 || len(info.EncryptedData)%aes.BlockSize != 0 {
+		return nil, ErrInvalidKeyPEM
+	}
+
+	key := pbkdf2(passphrase, kdf.Salt, kdf.IterationCount, keyLen, prf)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, info.EncryptedData)
+
+	// A wrong passphrase shows as bad padding, or rarely as a key that
+	// does not parse
+	plain, ok := unpadPKCS7(plain)
+	if !ok {
+		return nil, ErrKeyPassphrase
+	}
+	if _, err := x509.ParsePKCS8PrivateKey(plain); err != nil {
+		return nil, ErrKeyPassphrase
+	}
+	return plain, nil
+}
+
+// pbkdf2PRF returns the hash of the HMAC pseudorandom function oid.
+func pbkdf2PRF(oid asn1.ObjectIdentifier) (func() hash.Hash, error) {
+	switch {
+	case len(oid) == 0, oid.Equal(oidHMACWithSHA1):
+		return sha1.New, nil
+	case oid.Equal(oidHMACWithSHA224):
+		return sha256.New224, nil
+	case oid.Equal(oidHMACWithSHA256):
+		return sha256.New, nil
+	case oid.Equal(oidHMACWithSHA384):
+		return sha512.New384, nil
+	case oid.Equal(oidHMACWithSHA512):
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("%w: PRF %s", ErrKeyEncryptionScheme, oid)
+	}
+}
+
+// aesCBCKeyLength returns the key length of the AES-CBC cipher oid.
+func aesCBCKeyLength(oid asn1.ObjectIdentifier) (int, error) {
+	switch {
+	case oid.Equal(oidAES128CBC):
+		return 16, nil
+	case oid.Equal(oidAES192CBC):
+		return 24, nil
+	case oid.Equal(oidAES256CBC):
+		return 32, nil
+	default:
+		return 0, fmt.Errorf("%w: cipher %s", ErrKeyEncryptionScheme, oid)
+	}
+}
+
+// pbkdf2 derives a key of keyLen bytes from password (RFC 8018 Section
+// 5.2).
+func pbkdf2(password, salt []byte, iterations, keyLen int, h func() hash.Hash) []byte {
+	prf := hmac.New(h, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+
+	dk := make([]byte, 0, blocks*hashLen)
+	u := make([]byte, hashLen)
+	var counter [4]byte
+	for i := 1; i <= blocks; i++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(counter[:], uint32(i))
+		prf.Write(counter[:])
+		dk = prf.Sum(dk)
+
+		t := dk[len(dk)-hashLen:]
+		copy(u, t)
+		for n := 1; n < iterations; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range u {
+				t[j] ^= u[j]
+			}
+		}
+	}
+	return dk[:keyLen]
+}
+
+// unpadPKCS7 removes PKCS #7 padding, reporting whether it was valid.
+func unpadPKCS7(data []byte) ([]byte, bool) {
+	if len(data) == 0 {
+		return nil, false
+	}
+	n := int(data[len(data)-1])
+	if n == 0 || n > aes.BlockSize || n > len(data) {
+		return nil, false
+	}
+	for _, b := range data[len(data)-n:] {
+		if int(b) != n {
+			return nil, false
+		}
+	}
+	return data[:len(data)-n], true
+}
@@ -8,6 +8,7 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"errors"
 	"math/big"
 	"os"
 	"path/filepath"
@@ -420,6 +421,37 @@ func TestLoadTLSConfig(t *testing.T) {
 }
 
 // TestLoadTLSConfigWithFiles tests loading TLS config from files.
+func TestLoadTLSConfigWithGetCertificate(t *testing.T) {
+	certPEM, keyPEM, err := generateTestCertificate()
+	if err != nil {
+		t.Fatalf("failed to generate test certificate: %v", err)
+	}
+	cert, err := LoadCertificateFromPEM(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("failed to load test certificate: %v", err)
+	}
+
+	cfg := NewTLSConfig().WithGetCertificate(func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return &cert, nil
+	})
+
+	tlsCfg, err := LoadTLSConfig(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(tlsCfg.Certificates) != 0 {
+		t.Errorf("expected no static certificates, got %d", len(tlsCfg.Certificates))
+	}
+	if tlsCfg.GetCertificate == nil {
+		t.Fatal("expected GetCertificate to be set")
+	}
+	got, err := tlsCfg.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil || got != &cert {
+		t.Errorf("GetCertificate() = %v, %v, want the callback's certificate", got, err)
+	}
+}
+
 func TestLoadTLSConfigWithFiles(t *testing.T) {
 	// Create temp directory
 	tempDir, err := os.MkdirTemp("", "tls_test")
@@ -806,6 +838,114 @@ func TestDefaultCipherSuitesAreSecure(t *testing.T) {
 	}
 }
 
+// TestTLSMinVersionRejectsOlderClients tests that a TLS 1.3 only server
+// rejects a TLS 1.2 client.
+func TestTLSMinVersionRejectsOlderClients(t *testing.T) {
+	certPEM, keyPEM, err := generateTestCertificate()
+	if err != nil {
+		t.Fatalf("failed to generate certificate: %v", err)
+	}
+
+	minVersion, err := ParseTLSVersion("tls13")
+	if err != nil {
+		t.Fatalf("ParseTLSVersion() error = %v", err)
+	}
+	serverConfig, err := LoadTLSConfig(NewTLSConfig().WithCertPEM(certPEM, keyPEM).WithMinVersion(minVersion))
+	if err != nil {
+		t.Fatalf("LoadTLSConfig() error = %v", err)
+	}
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	dial := func(maxVersion uint16) error {
+		conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{
+			InsecureSkipVerify: true,
+			MaxVersion:         maxVersion,
+		})
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	}
+
+	if err := dial(tls.VersionTLS12); err == nil {
+		t.Error("TLS 1.2 client connected to a TLS 1.3 only server")
+	}
+	if err := dial(tls.VersionTLS13); err != nil {
+		t.Errorf("TLS 1.3 client failed to connect: %v", err)
+	}
+}
+
+// TestParseTLSVersion tests parsing configured TLS version names.
+func TestParseTLSVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    uint16
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"tls12", TLSVersion12, false},
+		{"TLS13", TLSVersion13, false},
+		{"tls10", 0, true},
+		{"1.3", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTLSVersion(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTLSVersion(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ParseTLSVersion(%q) = 0x%04x, want 0x%04x", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestParseCipherSuites tests parsing cipher suite names.
+func TestParseCipherSuites(t *testing.T) {
+	suites, err := ParseCipherSuites([]string{
+		"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+		"tls_ecdhe_ecdsa_with_chacha20_poly1305_sha256",
+	})
+	if err != nil {
+		t.Fatalf("ParseCipherSuites() error = %v", err)
+	}
+	want := []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256}
+	if len(suites) != len(want) || suites[0] != want[0] || suites[1] != want[1] {
+		t.Errorf("ParseCipherSuites() = %v, want %v", suites, want)
+	}
+
+	rejected := []struct {
+		name string
+		want error
+	}{
+		{"TLS_BOGUS_SUITE", ErrInvalidCipherSuite},
+		{"TLS_RSA_WITH_RC4_128_SHA", ErrInsecureCipher},
+		{"tls_ecdhe_rsa_with_aes_128_cbc_sha256", ErrInsecureCipher},
+		{"TLS_AES_128_GCM_SHA256", ErrTLS13CipherSuite},
+	}
+	for _, tt := range rejected {
+		if _, err := ParseCipherSuites([]string{tt.name}); !errors.Is(err, tt.want) {
+			t.Errorf("ParseCipherSuites(%q) error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
 // containsError checks if err contains or equals target.
 func containsError(err, target error) bool {
 	if err == target {
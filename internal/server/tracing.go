@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/trace"
+)
+
+// TraceContextOID is the OID of the trace context control. Its value is a
+// W3C traceparent, such as
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", and makes the
+// operation part of the caller's trace. The OID is in the UUID based 2.25
+// arc, which needs no registration.
+const TraceContextOID = "2.25.283192622428236913940755731775200070433"
+
+// TracerName is the instrumentation scope of the server's spans.
+const TracerName = "github.com/KilimcininKorOglu/oba/internal/server"
+
+// Span attribute keys
+const (
+	// AttrOperation is the LDAP operation, as in "bind" or "search"
+	AttrOperation = "ldap.operation"
+	// AttrDN is the DN the operation targets
+	AttrDN = "ldap.dn"
+	// AttrMessageID is the LDAP message ID
+	AttrMessageID = "ldap.message_id"
+	// AttrResultCode is the LDAP result code of the response
+	AttrResultCode = "ldap.result_code"
+	// AttrScope is the scope of a search
+	AttrScope = "ldap.scope"
+	// AttrEntriesReturned is the number of entries a search returned
+	AttrEntriesReturned = "ldap.entries_returned"
+)
+
+// FindTraceContextControl returns the span context carried by a trace
+// context control in controls. It returns false if there is no such
+// control or its value is not a valid traceparent.
+func FindTraceContextControl(controls []ldap.Control) (trace.SpanContext, bool) {
+	for _, ctrl := range controls {
+		if ctrl.OID != TraceContextOID {
+			continue
+		}
+		sc, err := trace.ParseTraceparent(string(ctrl.Value))
+		if err != nil {
+			return trace.SpanContext{}, false
+		}
+		return sc, true
+	}
+	return trace.SpanContext{}, false
+}
+
+// OtelMiddleware traces the messages of a connection. For each message it
+// starts a request span, whose parent is the span context of a trace
+// context control when the client sends one and the connection span
+// otherwise. Handlers start operation spans below the request span, and
+// all of them end once the response has been sent.
+type OtelMiddleware struct {
+	tracer trace.Tracer
+}
+
+// NewOtelMiddleware creates an OtelMiddleware that starts spans with a
+// tracer of tp.
+func NewOtelMiddleware(tp trace.TracerProvider) *OtelMiddleware {
+	return &OtelMiddleware{tracer: tp.Tracer(TracerName)}
+}
+
+// startConnection starts the span that covers the whole connection.
+func (m *OtelMiddleware) startConnection(ctx context.Context, c *Connection) (context.Context, trace.Span) {
+	return m.tracer.Start(ctx, "ldap.connection",
+		trace.String("net.peer.addr", c.RemoteAddr().String()))
+}
+
+// startRequest starts the span of one message.
+func (m *OtelMiddleware) startRequest(ctx context.Context, msg *ldap.LDAPMessage) (context.Context, *requestTrace) {
+	if sc, ok := FindTraceContextControl(msg.Controls); ok {
+		ctx = trace.ContextWithRemoteSpanContext(ctx, sc)
+	}
+
+	op, ok := operationNames[msg.OperationType()]
+	if !ok {
+		op = "unknown"
+	}
+	ctx, span := m.tracer.Start(ctx, "ldap.request",
+		trace.String(AttrOperation, op),
+		trace.Int(AttrMessageID, msg.MessageID))
+	return ctx, &requestTrace{tracer: m.tracer, spans: []trace.Span{span}}
+}
+
+// requestTrace holds the spans of the message being handled.
+type requestTrace struct {
+	tracer trace.Tracer
+	spans  []trace.Span
+}
+
+// end records the result code of response on all spans and ends them,
+// innermost first.
+func (t *requestTrace) end(response *ldap.LDAPMessage) {
+	code, ok := responseResultCode(response)
+	for i := len(t.spans) - 1; i >= 0; i-- {
+		if ok {
+			t.spans[i].SetAttributes(trace.Int(AttrResultCode, int(code)))
+		}
+		t.spans[i].End()
+	}
+}
+
+// Context returns the context of the operation being handled. It holds
+// the current span when tracing is enabled, so backends can start child
+// spans from it.
+func (c *Connection) Context() context.Context {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// setContext replaces the context of the connection.
+func (c *Connection) setContext(ctx context.Context) {
+	c.mu.Lock()
+	c.ctx = ctx
+	c.mu.Unlock()
+}
+
+// endTrace ends the spans of the message that was handled and restores the
+// connection context.
+func (c *Connection) endTrace(connCtx context.Context, response *ldap.LDAPMessage) {
+	c.mu.Lock()
+	rt := c.trace
+	c.ctx, c.trace = connCtx, nil
+	c.mu.Unlock()
+
+	if rt != nil {
+		rt.end(response)
+	}
+}
+
+// startOperationSpan starts the span of an operation below the request
+// span. It ends when the response has been sent. Without tracing it does
+// nothing.
+func (c *Connection) startOperationSpan(op, dn string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.trace == nil {
+		return
+	}
+
+	ctx, span := c.trace.tracer.Start(c.ctx, "ldap."+op,
+		trace.String(AttrOperation, op),
+		trace.String(AttrDN, dn))
+	c.ctx = ctx
+	c.trace.spans = append(c.trace.spans, span)
+}
+
+// setSpanAttributes adds attributes to the innermost span of the message
+// being handled. Without tracing it does nothing.
+func (c *Connection) setSpanAttributes(attrs ...trace.Attribute) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.trace == nil {
+		return
+	}
+	c.trace.spans[len(c.trace.spans)-1].SetAttributes(attrs...)
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/trace"
+)
+
+// withControls returns the encoded message data with controls attached.
+func withControls(t *testing.T, data []byte, controls ...ldap.Control) []byte {
+	t.Helper()
+
+	msg, err := ldap.ParseLDAPMessage(data)
+	if err != nil {
+		t.Fatalf("Failed to parse message: %v", err)
+	}
+	msg.Controls = controls
+	encoded, err := msg.Encode()
+	if err != nil {
+		t.Fatalf("Failed to encode message: %v", err)
+	}
+	return encoded
+}
+
+// findSpan returns the finished span with the given name.
+func findSpan(t *testing.T, spans []trace.SpanData, name string) trace.SpanData {
+	t.Helper()
+
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	t.Fatalf("Span %q not found in %d spans", name, len(spans))
+	return trace.SpanData{}
+}
+
+func TestTracingBindAndSearch(t *testing.T) {
+	exporter := trace.NewInMemoryExporter()
+
+	handler := NewHandler()
+	handler.SetBindHandler(func(conn *Connection, req *ldap.BindRequest) *OperationResult {
+		return &OperationResult{ResultCode: ldap.ResultSuccess}
+	})
+	var searchSpan trace.SpanContext
+	handler.SetSearchHandler(func(conn *Connection, req *ldap.SearchRequest) *SearchResult {
+		searchSpan = trace.SpanFromContext(conn.Context()).SpanContext()
+		return &SearchResult{OperationResult: OperationResult{ResultCode: ldap.ResultNoSuchObject}}
+	})
+	srv := &Server{Handler: handler, TracerProvider: trace.NewProvider(exporter)}
+
+	remote, err := trace.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatalf("ParseTraceparent() error = %v", err)
+	}
+
+	runConnection(t, srv, [][]byte{
+		createBindRequestMessage(1, 3, "cn=alice,dc=example,dc=com", "secret"),
+		withControls(t, createSearchRequestMessage(2, "dc=example,dc=com"), ldap.Control{
+			OID:   TraceContextOID,
+			Value: []byte(remote.Traceparent()),
+		}),
+		createUnbindRequestMessage(3),
+	})
+
+	spans := exporter.Spans()
+	if len(spans) != 5 {
+		t.Fatalf("Got %d spans, want 5 (connection, two requests, bind, search)", len(spans))
+	}
+
+	conn := findSpan(t, spans, "ldap.connection")
+	if conn.Parent.IsValid() {
+		t.Error("Connection span should be a root span")
+	}
+
+	bind := findSpan(t, spans, "ldap.bind")
+	var bindRequest trace.SpanData
+	for _, span := range spans {
+		if span.SpanContext.SpanID == bind.Parent.SpanID {
+			bindRequest = span
+		}
+	}
+	if bindRequest.Name != "ldap.request" || bindRequest.Parent != conn.SpanContext {
+		t.Errorf("Bind span parent = %q, want a request span below the connection span", bindRequest.Name)
+	}
+	if dn, _ := bind.Attribute(AttrDN); dn != "cn=alice,dc=example,dc=com" {
+		t.Errorf("Bind span %s = %q", AttrDN, dn)
+	}
+	if code, _ := bind.Attribute(AttrResultCode); code != "0" {
+		t.Errorf("Bind span %s = %q, want 0", AttrResultCode, code)
+	}
+
+	// The search request continues the caller's trace
+	search := findSpan(t, spans, "ldap.search")
+	if search.SpanContext.TraceID != remote.TraceID {
+		t.Errorf("Search trace ID = %s, want %s", search.SpanContext.TraceID, remote.TraceID)
+	}
+	if searchSpan != search.SpanContext {
+		t.Error("Connection context does not hold the search span during the handler")
+	}
+	var searchRequest trace.SpanData
+	for _, span := range spans {
+		if span.SpanContext.SpanID == search.Parent.SpanID {
+			searchRequest = span
+		}
+	}
+	if searchRequest.Name != "ldap.request" || searchRequest.Parent.SpanID != remote.SpanID {
+		t.Error("Search request span should be a child of the remote span")
+	}
+	if op, _ := search.Attribute(AttrOperation); op != "search" {
+		t.Errorf("Search span %s = %q", AttrOperation, op)
+	}
+	if code, _ := search.Attribute(AttrResultCode); code != "32" {
+		t.Errorf("Search span %s = %q, want 32", AttrResultCode, code)
+	}
+	if scope, _ := search.Attribute(AttrScope); scope != ldap.ScopeBaseObject.String() {
+		t.Errorf("Search span %s = %q", AttrScope, scope)
+	}
+	if n, _ := search.Attribute(AttrEntriesReturned); n != "0" {
+		t.Errorf("Search span %s = %q, want 0", AttrEntriesReturned, n)
+	}
+
+	// Spans end innermost first, and the connection span last
+	if spans[0].Name != "ldap.bind" || spans[len(spans)-1].Name != "ldap.connection" {
+		t.Errorf("Unexpected span end order: first %q, last %q", spans[0].Name, spans[len(spans)-1].Name)
+	}
+}
+
+func TestTracingDisabled(t *testing.T) {
+	handler := NewHandler()
+	var ctxSpan trace.Span
+	handler.SetBindHandler(func(conn *Connection, req *ldap.BindRequest) *OperationResult {
+		ctxSpan = trace.SpanFromContext(conn.Context())
+		return &OperationResult{ResultCode: ldap.ResultSuccess}
+	})
+
+	runConnection(t, &Server{Handler: handler}, [][]byte{
+		createBindRequestMessage(1, 3, "", ""),
+		createUnbindRequestMessage(2),
+	})
+
+	if ctxSpan == nil || ctxSpan.SpanContext().IsValid() {
+		t.Error("Connection context should hold no span without a tracer provider")
+	}
+}
+
+func TestFindTraceContextControl(t *testing.T) {
+	valid := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	if _, ok := FindTraceContextControl(nil); ok {
+		t.Error("Expected no span context without controls")
+	}
+	if _, ok := FindTraceContextControl([]ldap.Control{{OID: PagedResultsOID, Value: []byte(valid)}}); ok {
+		t.Error("Expected other controls to be ignored")
+	}
+	if _, ok := FindTraceContextControl([]ldap.Control{{OID: TraceContextOID, Value: []byte("garbage")}}); ok {
+		t.Error("Expected an invalid traceparent to be ignored")
+	}
+	sc, ok := FindTraceContextControl([]ldap.Control{{OID: TraceContextOID, Value: []byte(valid)}})
+	if !ok || sc.Traceparent() != valid || !sc.Remote {
+		t.Errorf("FindTraceContextControl() = %v, %v", sc, ok)
+	}
+}
@@ -54,10 +54,10 @@ func defaultUnbindHandler(conn *Connection, _ *UnbindRequest) error {
 	}
 
 	// Reset authentication state
-	conn.mu.Lock()
-	conn.bindDN = ""
-	conn.authenticated = false
-	conn.mu.Unlock()
+	conn.updateState(func(s *connState) {
+		s.bindDN = ""
+		s.authenticated = false
+	})
 
 	return nil
 }
@@ -90,10 +90,10 @@ func TestDefaultUnbindHandlerResetsState(t *testing.T) {
 	conn := NewConnection(mockConn, nil)
 
 	// Set some authentication state
-	conn.mu.Lock()
-	conn.bindDN = "cn=admin,dc=example,dc=com"
-	conn.authenticated = true
-	conn.mu.Unlock()
+	conn.updateState(func(s *connState) {
+		s.bindDN = "cn=admin,dc=example,dc=com"
+		s.authenticated = true
+	})
 
 	// Verify state is set
 	if conn.BindDN() != "cn=admin,dc=example,dc=com" {
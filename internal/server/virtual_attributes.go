@@ -0,0 +1,139 @@
+// Package server provides the LDAP server implementation.
+package server
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// VirtualAttributeProvider computes attributes of search results that are
+// not stored with the entry. The search handler calls the providers of its
+// SearchConfig for each entry that matched the filter, before the
+// requested attributes are selected.
+type VirtualAttributeProvider interface {
+	// Compute returns the attributes of entry, which holds all of its
+	// stored attributes. conn is the connection of the search, or nil.
+	Compute(entry *SearchEntry, conn *Connection) []ldap.Attribute
+}
+
+// EntryDNProvider computes entryDN (RFC 5020), the DN of the entry.
+type EntryDNProvider struct{}
+
+// Compute implements VirtualAttributeProvider.
+func (EntryDNProvider) Compute(entry *SearchEntry, conn *Connection) []ldap.Attribute {
+	return []ldap.Attribute{{Type: "entryDN", Values: [][]byte{[]byte(entry.DN)}}}
+}
+
+// HasSubordinatesProvider computes hasSubordinates (X.501), TRUE if the
+// entry has children in the DN tree of Backend and FALSE otherwise.
+type HasSubordinatesProvider struct {
+	Backend SearchBackend
+}
+
+// Compute implements VirtualAttributeProvider.
+func (p HasSubordinatesProvider) Compute(entry *SearchEntry, conn *Connection) []ldap.Attribute {
+	count, ok := countChildren(p.Backend, entry.DN, 1)
+	if !ok {
+		return nil
+	}
+	value := "FALSE"
+	if count > 0 {
+		value = "TRUE"
+	}
+	return []ldap.Attribute{{Type: "hasSubordinates", Values: [][]byte{[]byte(value)}}}
+}
+
+// NumSubordinatesProvider computes numSubordinates, the number of children
+// of the entry in the DN tree of Backend. Counting stops at Max children
+// if it is set, so larger containers report Max.
+type NumSubordinatesProvider struct {
+	Backend SearchBackend
+	Max     int
+}
+
+// Compute implements VirtualAttributeProvider.
+func (p NumSubordinatesProvider) Compute(entry *SearchEntry, conn *Connection) []ldap.Attribute {
+	count, ok := countChildren(p.Backend, entry.DN, p.Max)
+	if !ok {
+		return nil
+	}
+	return []ldap.Attribute{{Type: "numSubordinates", Values: [][]byte{[]byte(strconv.Itoa(count))}}}
+}
+
+// DefaultVirtualAttributeProviders returns the providers of entryDN,
+// hasSubordinates and numSubordinates over backend.
+func DefaultVirtualAttributeProviders(backend SearchBackend) []VirtualAttributeProvider {
+	return []VirtualAttributeProvider{
+		EntryDNProvider{},
+		HasSubordinatesProvider{Backend: backend},
+		NumSubordinatesProvider{Backend: backend},
+	}
+}
+
+// countChildren counts the children of dn by walking its level of the DN
+// tree, stopping at limit children unless limit is zero. It reports false
+// if the children could not be read.
+func countChildren(backend SearchBackend, dn string, limit int) (int, bool) {
+	if backend == nil {
+		return 0, false
+	}
+	iter := backend.SearchByDN(dn, storage.ScopeOneLevel)
+	if iter == nil {
+		return 0, false
+	}
+	defer iter.Close()
+
+	count := 0
+	for (limit <= 0 || count < limit) && iter.Next() {
+		count++
+	}
+	return count, iter.Error() == nil
+}
+
+// withVirtualAttributes returns entry with the attributes computed by the
+// virtual attribute providers of config added, replacing stored values of
+// the same name. The computed attributes are operational, so the providers
+// only run when the request names attributes or asks for "+".
+func withVirtualAttributes(conn *Connection, entry *storage.Entry, requestedAttrs []string, config *SearchConfig) *storage.Entry {
+	if config == nil || len(config.VirtualAttributeProviders) == 0 || !requestsNamedAttributes(requestedAttrs) {
+		return entry
+	}
+
+	searchEntry := &SearchEntry{DN: entry.DN}
+	for name, values := range entry.Attributes {
+		searchEntry.Attributes = append(searchEntry.Attributes, ldap.Attribute{Type: name, Values: values})
+	}
+
+	// The stored values are shared, only the attribute map is copied
+	result := &storage.Entry{DN: entry.DN, Attributes: make(map[string][][]byte, len(entry.Attributes)+len(config.VirtualAttributeProviders))}
+	for name, values := range entry.Attributes {
+		result.Attributes[name] = values
+	}
+	for _, provider := range config.VirtualAttributeProviders {
+		for _, attr := range provider.Compute(searchEntry, conn) {
+			for name := range result.Attributes {
+				if strings.EqualFold(name, attr.Type) {
+					delete(result.Attributes, name)
+				}
+			}
+			result.Attributes[attr.Type] = attr.Values
+		}
+	}
+	return result
+}
+
+// requestsNamedAttributes reports whether requestedAttrs holds "+" or an
+// attribute name, rather than only "*" or "1.1".
+func requestsNamedAttributes(requestedAttrs []string) bool {
+	for _, attr := range requestedAttrs {
+		switch strings.TrimSpace(attr) {
+		case "", "*", "1.1":
+		default:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// newVirtualAttributeHandler returns a search handler computing the
+// default virtual attributes over a tree with an OU holding two users and
+// an empty OU.
+func newVirtualAttributeHandler() *SearchHandlerImpl {
+	backend := newMockSearchBackend()
+	for _, dn := range []string{
+		"dc=example,dc=com",
+		"ou=users,dc=example,dc=com",
+		"uid=alice,ou=users,dc=example,dc=com",
+		"uid=bob,ou=users,dc=example,dc=com",
+		"ou=empty,dc=example,dc=com",
+	} {
+		backend.addEntry(storage.NewEntry(dn))
+	}
+
+	config := NewSearchConfig()
+	config.Backend = backend
+	config.VirtualAttributeProviders = DefaultVirtualAttributeProviders(backend)
+	return NewSearchHandler(config)
+}
+
+// attributeValue returns the single value of the attribute name of entry,
+// or "" if the entry does not have it.
+func attributeValue(t *testing.T, entry *SearchEntry, name string) string {
+	t.Helper()
+
+	for _, attr := range entry.Attributes {
+		if attr.Type == name {
+			if len(attr.Values) != 1 {
+				t.Fatalf("%s of %s has %d values, want 1", name, entry.DN, len(attr.Values))
+			}
+			return string(attr.Values[0])
+		}
+	}
+	return ""
+}
+
+func TestHasSubordinates(t *testing.T) {
+	h := newVirtualAttributeHandler()
+
+	result := h.Handle(nil, &ldap.SearchRequest{
+		BaseObject: "dc=example,dc=com",
+		Scope:      ldap.ScopeWholeSubtree,
+		Attributes: []string{"hasSubordinates", "numSubordinates", "entryDN"},
+	})
+	if result.ResultCode != ldap.ResultSuccess {
+		t.Fatalf("Handle() result = %s", result.ResultCode)
+	}
+
+	want := map[string]struct {
+		has string
+		num string
+	}{
+		"dc=example,dc=com":                    {"TRUE", "2"},
+		"ou=users,dc=example,dc=com":           {"TRUE", "2"},
+		"uid=alice,ou=users,dc=example,dc=com": {"FALSE", "0"},
+		"uid=bob,ou=users,dc=example,dc=com":   {"FALSE", "0"},
+		"ou=empty,dc=example,dc=com":           {"FALSE", "0"},
+	}
+	if len(result.Entries) != len(want) {
+		t.Fatalf("Handle() returned %d entries, want %d", len(result.Entries), len(want))
+	}
+	for _, entry := range result.Entries {
+		w := want[entry.DN]
+		if got := attributeValue(t, entry, "hasSubordinates"); got != w.has {
+			t.Errorf("hasSubordinates of %s = %q, want %s", entry.DN, got, w.has)
+		}
+		if got := attributeValue(t, entry, "numSubordinates"); got != w.num {
+			t.Errorf("numSubordinates of %s = %q, want %s", entry.DN, got, w.num)
+		}
+		if got := attributeValue(t, entry, "entryDN"); got != entry.DN {
+			t.Errorf("entryDN of %s = %q", entry.DN, got)
+		}
+		if len(entry.Attributes) != 3 {
+			t.Errorf("%s has attributes %v, want only the requested ones", entry.DN, entry.Attributes)
+		}
+	}
+}
+
+func TestVirtualAttributeSelection(t *testing.T) {
+	h := newVirtualAttributeHandler()
+
+	tests := []struct {
+		attributes []string
+		want       string
+	}{
+		{nil, ""},
+		{[]string{"*"}, ""},
+		{[]string{"1.1"}, ""},
+		{[]string{"+"}, "TRUE"},
+		{[]string{"*", "hassubordinates"}, "TRUE"},
+	}
+	for _, tt := range tests {
+		// Base scope goes through the handler's own base search
+		result := h.Handle(nil, &ldap.SearchRequest{
+			BaseObject: "ou=users,dc=example,dc=com",
+			Scope:      ldap.ScopeBaseObject,
+			Attributes: tt.attributes,
+		})
+		if len(result.Entries) != 1 {
+			t.Fatalf("attributes %v: Handle() returned %d entries", tt.attributes, len(result.Entries))
+		}
+		if got := attributeValue(t, result.Entries[0], "hasSubordinates"); got != tt.want {
+			t.Errorf("attributes %v: hasSubordinates = %q, want %q", tt.attributes, got, tt.want)
+		}
+	}
+}
+
+func TestNumSubordinatesMax(t *testing.T) {
+	backend := newMockSearchBackend()
+	backend.addEntry(storage.NewEntry("ou=users,dc=example,dc=com"))
+	for _, uid := range []string{"a", "b", "c"} {
+		backend.addEntry(storage.NewEntry("uid=" + uid + ",ou=users,dc=example,dc=com"))
+	}
+
+	p := NumSubordinatesProvider{Backend: backend, Max: 2}
+	attrs := p.Compute(&SearchEntry{DN: "ou=users,dc=example,dc=com"}, nil)
+	if len(attrs) != 1 || string(attrs[0].Values[0]) != "2" {
+		t.Errorf("Compute() = %v, want numSubordinates 2", attrs)
+	}
+}
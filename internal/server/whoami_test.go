@@ -80,10 +80,10 @@ func TestWhoAmIHandler_Handle_Authenticated(t *testing.T) {
 
 	// Simulate a successful bind by setting the bindDN
 	testDN := "uid=alice,ou=users,dc=example,dc=com"
-	conn.mu.Lock()
-	conn.bindDN = testDN
-	conn.authenticated = true
-	conn.mu.Unlock()
+	conn.updateState(func(s *connState) {
+		s.bindDN = testDN
+		s.authenticated = true
+	})
 
 	req := &ExtendedRequest{
 		OID: WhoAmIOID,
@@ -153,10 +153,10 @@ func TestWhoAmIHandler_Handle_DifferentDNs(t *testing.T) {
 			defer server.Close()
 
 			conn := NewConnection(server, nil)
-			conn.mu.Lock()
-			conn.bindDN = tt.bindDN
-			conn.authenticated = true
-			conn.mu.Unlock()
+			conn.updateState(func(s *connState) {
+				s.bindDN = tt.bindDN
+				s.authenticated = true
+			})
 
 			req := &ExtendedRequest{
 				OID: WhoAmIOID,
@@ -246,10 +246,10 @@ func TestWhoAmIHandler_DispatcherIntegration(t *testing.T) {
 		defer server.Close()
 
 		conn := NewConnection(server, nil)
-		conn.mu.Lock()
-		conn.bindDN = "cn=admin,dc=example,dc=com"
-		conn.authenticated = true
-		conn.mu.Unlock()
+		conn.updateState(func(s *connState) {
+			s.bindDN = "cn=admin,dc=example,dc=com"
+			s.authenticated = true
+		})
 
 		req := &ExtendedRequest{OID: WhoAmIOID}
 
@@ -278,10 +278,10 @@ func TestWhoAmIHandler_ResponseEncoding(t *testing.T) {
 	defer server.Close()
 
 	conn := NewConnection(server, nil)
-	conn.mu.Lock()
-	conn.bindDN = "uid=test,dc=example,dc=com"
-	conn.authenticated = true
-	conn.mu.Unlock()
+	conn.updateState(func(s *connState) {
+		s.bindDN = "uid=test,dc=example,dc=com"
+		s.authenticated = true
+	})
 
 	req := &ExtendedRequest{OID: WhoAmIOID}
 
@@ -356,10 +356,10 @@ func TestWhoAmIHandler_RequestWithValue(t *testing.T) {
 	defer server.Close()
 
 	conn := NewConnection(server, nil)
-	conn.mu.Lock()
-	conn.bindDN = "cn=test"
-	conn.authenticated = true
-	conn.mu.Unlock()
+	conn.updateState(func(s *connState) {
+		s.bindDN = "cn=test"
+		s.authenticated = true
+	})
 
 	// Request with unexpected value (should be ignored per RFC 4532)
 	req := &ExtendedRequest{
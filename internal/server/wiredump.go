@@ -0,0 +1,696 @@
+// Package server provides the LDAP server implementation.
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/KilimcininKorOglu/oba/internal/acl"
+	"github.com/KilimcininKorOglu/oba/internal/ber"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+)
+
+// Wire dump errors
+var (
+	// ErrWireDumpNoDirectory is returned when no wire dump directory is configured
+	ErrWireDumpNoDirectory = errors.New("server: wire dump directory is not configured")
+	// ErrInvalidWireDumpFilter is returned when a connection filter entry is not an IP or CIDR
+	ErrInvalidWireDumpFilter = errors.New("server: invalid wire dump connection filter")
+)
+
+// DefaultWireDumpMaxBytes is the default size cap of one connection file.
+const DefaultWireDumpMaxBytes = 10 * 1024 * 1024
+
+// wireDumpRedacted replaces redacted values in the dump.
+const wireDumpRedacted = "[redacted]"
+
+// WireDumpConfig holds configuration for dumping LDAP messages to files.
+type WireDumpConfig struct {
+	// Directory receives one file per dumped connection
+	Directory string
+	// MaxBytes caps the size of each connection file (0 uses the default)
+	MaxBytes int64
+	// ConnectionFilter limits dumping to clients in these IPs or CIDRs
+	// (empty dumps all connections)
+	ConnectionFilter []string
+	// Duration disables the dump again after it has been enabled this long
+	// (0 never disables it)
+	Duration time.Duration
+	// RawBER adds the hex encoded BER of each message
+	RawBER bool
+	// IncludePasswords writes bind and password values instead of
+	// redacting them
+	IncludePasswords bool
+}
+
+// WireDumpStatus describes the state of a wire dump.
+type WireDumpStatus struct {
+	// Enabled reports whether messages are being dumped
+	Enabled bool
+	// ExpiresAt is when the dump disables itself (zero if never)
+	ExpiresAt time.Time
+	// Connections is the number of connection files open
+	Connections int
+	// LastError is the last error creating or writing a file, if any
+	LastError string
+	// Config is the configuration in effect
+	Config WireDumpConfig
+}
+
+// WireDump writes the decoded LDAP messages of matching connections to
+// per-connection files for debugging client interoperability. Messages are
+// recorded after TLS decryption, so the dump is readable on LDAPS and
+// StartTLS connections.
+type WireDump struct {
+	mu       sync.Mutex
+	config   WireDumpConfig
+	networks []*net.IPNet
+	enabled  bool
+	expires  time.Time
+	timer    *time.Timer
+	// generation changes on every enable and disable, so a stale expiry
+	// timer does not disable a later dump
+	generation uint64
+	// files maps connection request IDs to their files; a nil file marks
+	// a connection that does not match the filter
+	files map[string]*wireDumpFile
+	// lastErr is the last error creating or writing a file
+	lastErr string
+}
+
+// wireDumpFile is the dump file of one connection.
+type wireDumpFile struct {
+	f         *os.File
+	written   int64
+	truncated bool
+}
+
+// NewWireDump creates a disabled wire dump with the given configuration.
+func NewWireDump(config *WireDumpConfig) (*WireDump, error) {
+	if config.Directory == "" {
+		return nil, ErrWireDumpNoDirectory
+	}
+
+	cfg := *config
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultWireDumpMaxBytes
+	}
+
+	networks, err := parseWireDumpFilter(cfg.ConnectionFilter)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ConnectionFilter = append([]string(nil), cfg.ConnectionFilter...)
+
+	return &WireDump{
+		config:   cfg,
+		networks: networks,
+		files:    make(map[string]*wireDumpFile),
+	}, nil
+}
+
+// parseWireDumpFilter parses connection filter entries given as IPs or CIDRs.
+func parseWireDumpFilter(filter []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(filter))
+	for _, s := range filter {
+		network, err := acl.ParseSourceNetwork(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidWireDumpFilter, s)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// Enable starts dumping connections that match filter. Connections already
+// open are dumped from their next message on. A positive duration disables
+// the dump again once it has passed.
+func (w *WireDump) Enable(filter []string, duration time.Duration, rawBER bool) error {
+	networks, err := parseWireDumpFilter(filter)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(w.config.Directory, 0700); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closeFilesLocked()
+	w.generation++
+	w.enabled = true
+	w.lastErr = ""
+	w.networks = networks
+	w.config.ConnectionFilter = append([]string(nil), filter...)
+	w.config.Duration = duration
+	w.config.RawBER = rawBER
+
+	w.expires = time.Time{}
+	if duration > 0 {
+		w.expires = time.Now().Add(duration)
+		generation := w.generation
+		w.timer = time.AfterFunc(duration, func() { w.expire(generation) })
+	}
+	return nil
+}
+
+// Disable stops dumping and closes all connection files.
+func (w *WireDump) Disable() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.generation++
+	w.enabled = false
+	w.expires = time.Time{}
+	w.closeFilesLocked()
+}
+
+// expire disables the dump enabled as generation.
+func (w *WireDump) expire(generation uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.generation != generation {
+		return
+	}
+	w.generation++
+	w.enabled = false
+	w.expires = time.Time{}
+	w.closeFilesLocked()
+}
+
+// closeFilesLocked closes all connection files and stops the expiry timer.
+// The caller must hold w.mu.
+func (w *WireDump) closeFilesLocked() {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	for id, file := range w.files {
+		if file != nil {
+			file.f.Close()
+		}
+		delete(w.files, id)
+	}
+}
+
+// Status returns the current state of the wire dump.
+func (w *WireDump) Status() WireDumpStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	open := 0
+	for _, file := range w.files {
+		if file != nil {
+			open++
+		}
+	}
+
+	cfg := w.config
+	cfg.ConnectionFilter = append([]string(nil), w.config.ConnectionFilter...)
+	return WireDumpStatus{
+		Enabled:     w.enabled,
+		ExpiresAt:   w.expires,
+		Connections: open,
+		LastError:   w.lastErr,
+		Config:      cfg,
+	}
+}
+
+// Wire dump directions
+const (
+	wireInbound  = "IN"
+	wireOutbound = "OUT"
+)
+
+// record writes one message of c to its dump file. raw is the BER encoding
+// of the message; msg is nil if it could not be decoded, in which case
+// decodeErr holds the reason.
+func (w *WireDump) record(c *Connection, direction string, raw []byte, msg *ldap.LDAPMessage, decodeErr error) {
+	// Read the connection state first; Close holds the connection lock
+	// while it calls closeConnection.
+	remote := ""
+	if addr := c.RemoteAddr(); addr != nil {
+		remote = addr.String()
+	}
+	isTLS := c.IsTLS()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.enabled {
+		return
+	}
+
+	file, seen := w.files[c.requestID]
+	if !seen {
+		file = w.openLocked(c, remote, isTLS)
+		w.files[c.requestID] = file
+	}
+	if file == nil || file.truncated {
+		return
+	}
+
+	entry := formatWireMessage(time.Now(), direction, raw, msg, decodeErr, w.config.RawBER, w.config.IncludePasswords)
+	if file.written+int64(len(entry)) > w.config.MaxBytes {
+		file.truncated = true
+		entry = fmt.Sprintf("# truncated: file reached maxBytes (%d)\n", w.config.MaxBytes)
+	}
+
+	n, err := file.f.WriteString(entry)
+	file.written += int64(n)
+	if err != nil {
+		// Stop writing to a file that failed, but keep the entry so the
+		// connection is not reopened.
+		w.lastErr = err.Error()
+		file.truncated = true
+	}
+}
+
+// openLocked creates the dump file of c, or returns nil if the remote
+// address of c does not match the connection filter or the file cannot be
+// created. The caller must hold w.mu.
+func (w *WireDump) openLocked(c *Connection, remote string, isTLS bool) *wireDumpFile {
+	if len(w.networks) > 0 {
+		host, _, err := net.SplitHostPort(remote)
+		if err != nil {
+			host = remote
+		}
+		ip := net.ParseIP(host)
+		if ip == nil || !matchesNetworks(ip, w.networks) {
+			return nil
+		}
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%s-%s.log", now.UTC().Format("20060102T150405Z"), c.requestID)
+	f, err := os.OpenFile(filepath.Join(w.config.Directory, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		w.lastErr = err.Error()
+		return nil
+	}
+
+	header := fmt.Sprintf("# connection %s client %s tls=%t started %s\n",
+		c.requestID, remote, isTLS, now.UTC().Format(time.RFC3339Nano))
+	n, _ := f.WriteString(header)
+	return &wireDumpFile{f: f, written: int64(n)}
+}
+
+// closeConnection closes the dump file of the connection with requestID.
+func (w *WireDump) closeConnection(requestID string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if file, ok := w.files[requestID]; ok {
+		if file != nil {
+			file.f.Close()
+		}
+		delete(w.files, requestID)
+	}
+}
+
+// matchesNetworks reports whether ip is in any of networks.
+func matchesNetworks(ip net.IP, networks []*net.IPNet) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// wireMessage collects the decoded fields of one dumped message.
+type wireMessage struct {
+	fields           []string
+	includePasswords bool
+	redacted         bool
+}
+
+// add appends a field to the message.
+func (m *wireMessage) add(name string, value string) {
+	m.fields = append(m.fields, name+": "+value)
+}
+
+// secret appends a field whose value is redacted unless passwords are
+// included.
+func (m *wireMessage) secret(name string, value []byte) {
+	if !m.includePasswords {
+		m.redacted = true
+		m.add(name, wireDumpRedacted)
+		return
+	}
+	m.add(name, formatWireValue(value))
+}
+
+// attribute appends an attribute with its values, redacting the values of
+// password attributes.
+func (m *wireMessage) attribute(prefix, name string, values [][]byte) {
+	if len(values) == 0 {
+		m.add(prefix, name)
+		return
+	}
+	if isPasswordAttribute(name) && !m.includePasswords {
+		m.redacted = true
+		m.add(prefix, name+" "+wireDumpRedacted)
+		return
+	}
+
+	formatted := make([]string, len(values))
+	for i, v := range values {
+		formatted[i] = formatWireValue(v)
+	}
+	m.add(prefix, name+" "+strings.Join(formatted, ", "))
+}
+
+// isPasswordAttribute reports whether values of the attribute are redacted.
+func isPasswordAttribute(name string) bool {
+	if i := strings.IndexByte(name, ';'); i >= 0 {
+		name = name[:i]
+	}
+	return strings.EqualFold(name, "userPassword")
+}
+
+// formatWireMessage formats one dump entry.
+func formatWireMessage(now time.Time, direction string, raw []byte, msg *ldap.LDAPMessage, decodeErr error, rawBER, includePasswords bool) string {
+	var sb strings.Builder
+	sb.WriteString(now.UTC().Format(time.RFC3339Nano))
+	sb.WriteString(" ")
+	sb.WriteString(direction)
+
+	m := &wireMessage{includePasswords: includePasswords}
+	if msg == nil {
+		sb.WriteString(" undecodable message\n")
+		if decodeErr != nil {
+			m.add("error", decodeErr.Error())
+		}
+		// The content is unknown, so it may hold a password
+		m.redacted = !includePasswords
+	} else {
+		sb.WriteString(" #")
+		sb.WriteString(strconv.Itoa(msg.MessageID))
+		sb.WriteString(" ")
+		sb.WriteString(msg.OperationType().String())
+		sb.WriteString("\n")
+		describeOperation(m, msg)
+		for _, ctrl := range msg.Controls {
+			m.add("control", fmt.Sprintf("%s critical=%t", ctrl.OID, ctrl.Criticality))
+		}
+	}
+
+	if rawBER {
+		if m.redacted {
+			m.add("raw", wireDumpRedacted)
+		} else {
+			m.add("raw", fmt.Sprintf("% x", raw))
+		}
+	}
+
+	for _, field := range m.fields {
+		sb.WriteString("    ")
+		sb.WriteString(field)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// describeOperation adds the decoded fields of the protocol operation of msg.
+func describeOperation(m *wireMessage, msg *ldap.LDAPMessage) {
+	if msg.Operation == nil {
+		return
+	}
+	data := msg.Operation.Data
+
+	var err error
+	switch msg.OperationType() {
+	case ldap.ApplicationBindRequest:
+		err = describeBindRequest(m, data)
+	case ldap.ApplicationSearchRequest:
+		err = describeSearchRequest(m, data)
+	case ldap.ApplicationSearchResultEntry:
+		err = describeSearchEntry(m, data)
+	case ldap.ApplicationSearchResultReference:
+		err = describeSearchReference(m, data)
+	case ldap.ApplicationModifyRequest:
+		err = describeModifyRequest(m, data)
+	case ldap.ApplicationAddRequest:
+		err = describeAddRequest(m, data)
+	case ldap.ApplicationDelRequest:
+		var req *ldap.DeleteRequest
+		if req, err = ldap.ParseDeleteRequest(data); err == nil {
+			m.add("dn", req.DN)
+		}
+	case ldap.ApplicationModifyDNRequest:
+		var req *ldap.ModifyDNRequest
+		if req, err = ldap.ParseModifyDNRequest(data); err == nil {
+			m.add("dn", req.Entry)
+			m.add("newRDN", req.NewRDN)
+			m.add("deleteOldRDN", strconv.FormatBool(req.DeleteOldRDN))
+			if req.NewSuperior != "" {
+				m.add("newSuperior", req.NewSuperior)
+			}
+		}
+	case ldap.ApplicationCompareRequest:
+		var req *ldap.CompareRequest
+		if req, err = ldap.ParseCompareRequest(data); err == nil {
+			m.add("dn", req.DN)
+			m.attribute("assertion", req.Attribute, [][]byte{req.Value})
+		}
+	case ldap.ApplicationAbandonRequest:
+		var req *ldap.AbandonRequest
+		if req, err = ldap.ParseAbandonRequest(data); err == nil {
+			m.add("abandon", strconv.Itoa(req.MessageID))
+		}
+	case ldap.ApplicationExtendedRequest:
+		var req *ExtendedRequest
+		if req, err = ParseExtendedRequest(data); err == nil {
+			m.add("oid", req.OID)
+			if req.Value != nil {
+				if req.OID == PasswordModifyOID {
+					m.secret("value", req.Value)
+				} else {
+					m.add("value", formatWireValue(req.Value))
+				}
+			}
+		}
+	case ldap.ApplicationBindResponse, ldap.ApplicationSearchResultDone,
+		ldap.ApplicationModifyResponse, ldap.ApplicationAddResponse,
+		ldap.ApplicationDelResponse, ldap.ApplicationModifyDNResponse,
+		ldap.ApplicationCompareResponse:
+		err = describeResult(m, ber.NewBERDecoder(data))
+	case ldap.ApplicationExtendedResponse:
+		err = describeExtendedResponse(m, data)
+	case ldap.ApplicationUnbindRequest:
+	default:
+		m.add("data", fmt.Sprintf("% x", data))
+	}
+
+	if err != nil {
+		m.add("decodeError", err.Error())
+	}
+}
+
+// describeBindRequest adds the fields of a BindRequest.
+func describeBindRequest(m *wireMessage, data []byte) error {
+	req, err := ldap.ParseBindRequest(data)
+	if err != nil {
+		return err
+	}
+
+	m.add("version", strconv.Itoa(req.Version))
+	m.add("name", req.Name)
+	m.add("authentication", req.AuthMethod.String())
+	switch req.AuthMethod {
+	case ldap.AuthMethodSimple:
+		if len(req.SimplePassword) > 0 {
+			m.secret("password", req.SimplePassword)
+		}
+	case ldap.AuthMethodSASL:
+		if req.SASLCredentials != nil {
+			m.add("mechanism", req.SASLCredentials.Mechanism)
+			if req.SASLCredentials.Credentials != nil {
+				m.secret("credentials", req.SASLCredentials.Credentials)
+			}
+		}
+	}
+	return nil
+}
+
+// describeSearchRequest adds the fields of a SearchRequest.
+func describeSearchRequest(m *wireMessage, data []byte) error {
+	req, err := ldap.ParseSearchRequest(data)
+	if err != nil {
+		return err
+	}
+
+	m.add("base", req.BaseObject)
+	m.add("scope", req.Scope.String())
+	m.add("derefAliases", req.DerefAliases.String())
+	m.add("sizeLimit", strconv.Itoa(req.SizeLimit))
+	m.add("timeLimit", strconv.Itoa(req.TimeLimit))
+	m.add("typesOnly", strconv.FormatBool(req.TypesOnly))
+	if req.Filter != nil {
+		m.add("filter", FilterToString(req.Filter))
+	}
+	if len(req.Attributes) > 0 {
+		m.add("attributes", strings.Join(req.Attributes, ", "))
+	}
+	return nil
+}
+
+// describeAddRequest adds the fields of an AddRequest.
+func describeAddRequest(m *wireMessage, data []byte) error {
+	req, err := ldap.ParseAddRequest(data)
+	if err != nil {
+		return err
+	}
+
+	m.add("dn", req.Entry)
+	for _, attr := range req.Attributes {
+		m.attribute("attribute", attr.Type, attr.Values)
+	}
+	return nil
+}
+
+// describeModifyRequest adds the fields of a ModifyRequest.
+func describeModifyRequest(m *wireMessage, data []byte) error {
+	req, err := ldap.ParseModifyRequest(data)
+	if err != nil {
+		return err
+	}
+
+	m.add("dn", req.Object)
+	for _, change := range req.Changes {
+		m.attribute(strings.ToLower(change.Operation.String()), change.Attribute.Type, change.Attribute.Values)
+	}
+	return nil
+}
+
+// describeSearchEntry adds the fields of a SearchResultEntry.
+func describeSearchEntry(m *wireMessage, data []byte) error {
+	decoder := ber.NewBERDecoder(data)
+
+	dn, err := decoder.ReadOctetString()
+	if err != nil {
+		return err
+	}
+	m.add("dn", string(dn))
+
+	attrs, err := decoder.ReadSequenceContents()
+	if err != nil {
+		return err
+	}
+	for attrs.Remaining() > 0 {
+		attr, err := attrs.ReadSequenceContents()
+		if err != nil {
+			return err
+		}
+		name, err := attr.ReadOctetString()
+		if err != nil {
+			return err
+		}
+		vals, err := attr.ReadSetContents()
+		if err != nil {
+			return err
+		}
+
+		var values [][]byte
+		for vals.Remaining() > 0 {
+			v, err := vals.ReadOctetString()
+			if err != nil {
+				return err
+			}
+			values = append(values, v)
+		}
+		m.attribute("attribute", string(name), values)
+	}
+	return nil
+}
+
+// describeSearchReference adds the URIs of a SearchResultReference.
+func describeSearchReference(m *wireMessage, data []byte) error {
+	decoder := ber.NewBERDecoder(data)
+	for decoder.Remaining() > 0 {
+		uri, err := decoder.ReadOctetString()
+		if err != nil {
+			return err
+		}
+		m.add("uri", string(uri))
+	}
+	return nil
+}
+
+// describeResult adds the fields of an LDAPResult read from decoder.
+func describeResult(m *wireMessage, decoder *ber.BERDecoder) error {
+	code, err := decoder.ReadEnumerated()
+	if err != nil {
+		return err
+	}
+	matchedDN, err := decoder.ReadOctetString()
+	if err != nil {
+		return err
+	}
+	diagnostic, err := decoder.ReadOctetString()
+	if err != nil {
+		return err
+	}
+
+	m.add("resultCode", fmt.Sprintf("%d (%s)", code, ldap.ResultCode(code).String()))
+	if len(matchedDN) > 0 {
+		m.add("matchedDN", string(matchedDN))
+	}
+	if len(diagnostic) > 0 {
+		m.add("diagnosticMessage", string(diagnostic))
+	}
+	return nil
+}
+
+// describeExtendedResponse adds the fields of an ExtendedResponse.
+func describeExtendedResponse(m *wireMessage, data []byte) error {
+	decoder := ber.NewBERDecoder(data)
+	if err := describeResult(m, decoder); err != nil {
+		return err
+	}
+
+	for decoder.Remaining() > 0 {
+		tag, _, value, err := decoder.ReadTaggedValue()
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case 10:
+			m.add("oid", string(value))
+		case 11:
+			// Response values may carry a generated password
+			m.secret("value", value)
+		}
+	}
+	return nil
+}
+
+// formatWireValue formats a value as quoted text if it is printable, or as
+// hex otherwise.
+func formatWireValue(v []byte) string {
+	if utf8.Valid(v) {
+		printable := true
+		for _, r := range string(v) {
+			if !unicode.IsPrint(r) {
+				printable = false
+				break
+			}
+		}
+		if printable {
+			return strconv.Quote(string(v))
+		}
+	}
+	return fmt.Sprintf("0x%x", v)
+}
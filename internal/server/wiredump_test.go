@@ -0,0 +1,155 @@
+// Package server provides the LDAP server implementation.
+package server
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runWireDumpSession handles a bind, a search and an unbind on a connection
+// recorded by wd and returns the contents of the dump files.
+func runWireDumpSession(t *testing.T, wd *WireDump, dir string) []string {
+	t.Helper()
+
+	data := createBindRequestMessage(1, 3, "cn=admin,dc=example,dc=com", "s3cret-pw")
+	data = append(data, createSearchRequestMessage(2, "dc=example,dc=com")...)
+	data = append(data, createUnbindRequestMessage(3)...)
+
+	mc := newMockConn()
+	mc.setReadData(data)
+	conn := NewConnection(mc, &Server{Handler: NewHandler(), WireDump: wd})
+	conn.Handle()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.log"))
+	if err != nil {
+		t.Fatalf("Glob() error = %v", err)
+	}
+	var dumps []string
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+		dumps = append(dumps, string(content))
+	}
+	return dumps
+}
+
+func newTestWireDump(t *testing.T, cfg WireDumpConfig) (*WireDump, string) {
+	t.Helper()
+
+	cfg.Directory = filepath.Join(t.TempDir(), "wiredump")
+	wd, err := NewWireDump(&cfg)
+	if err != nil {
+		t.Fatalf("NewWireDump() error = %v", err)
+	}
+	if err := wd.Enable(cfg.ConnectionFilter, cfg.Duration, cfg.RawBER); err != nil {
+		t.Fatalf("Enable() error = %v", err)
+	}
+	return wd, cfg.Directory
+}
+
+func TestWireDumpRecordsConnection(t *testing.T) {
+	wd, dir := newTestWireDump(t, WireDumpConfig{RawBER: true})
+
+	dumps := runWireDumpSession(t, wd, dir)
+	if len(dumps) != 1 {
+		t.Fatalf("got %d dump files, want 1", len(dumps))
+	}
+	dump := dumps[0]
+
+	for _, want := range []string{
+		"client 192.168.1.100:54321",
+		"IN #1 BindRequest",
+		"name: cn=admin,dc=example,dc=com",
+		"password: [redacted]",
+		"OUT #1 BindResponse",
+		"resultCode: ",
+		"IN #2 SearchRequest",
+		"filter: (objectClass=*)",
+		"OUT #2 SearchResultDone",
+		"IN #3 UnbindRequest",
+		"raw: [redacted]",
+		"raw: 30 ",
+	} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("dump does not contain %q:\n%s", want, dump)
+		}
+	}
+	if strings.Contains(dump, "s3cret-pw") {
+		t.Errorf("dump contains the bind password:\n%s", dump)
+	}
+
+	if status := wd.Status(); status.Connections != 0 {
+		t.Errorf("Connections = %d after close, want 0", status.Connections)
+	}
+}
+
+func TestWireDumpIncludePasswords(t *testing.T) {
+	wd, dir := newTestWireDump(t, WireDumpConfig{IncludePasswords: true})
+
+	dumps := runWireDumpSession(t, wd, dir)
+	if len(dumps) != 1 || !strings.Contains(dumps[0], `password: "s3cret-pw"`) {
+		t.Errorf("dump does not contain the bind password: %q", dumps)
+	}
+}
+
+func TestWireDumpConnectionFilter(t *testing.T) {
+	wd, dir := newTestWireDump(t, WireDumpConfig{ConnectionFilter: []string{"10.0.0.0/8"}})
+	if dumps := runWireDumpSession(t, wd, dir); len(dumps) != 0 {
+		t.Errorf("got %d dump files for a filtered client, want 0", len(dumps))
+	}
+
+	wd, dir = newTestWireDump(t, WireDumpConfig{ConnectionFilter: []string{"192.168.1.100"}})
+	if dumps := runWireDumpSession(t, wd, dir); len(dumps) != 1 {
+		t.Errorf("got %d dump files for a matching client, want 1", len(dumps))
+	}
+
+	if err := wd.Enable([]string{"not-an-ip"}, 0, false); !errors.Is(err, ErrInvalidWireDumpFilter) {
+		t.Errorf("Enable() error = %v, want ErrInvalidWireDumpFilter", err)
+	}
+}
+
+func TestWireDumpMaxBytes(t *testing.T) {
+	wd, dir := newTestWireDump(t, WireDumpConfig{MaxBytes: 300})
+
+	dumps := runWireDumpSession(t, wd, dir)
+	if len(dumps) != 1 {
+		t.Fatalf("got %d dump files, want 1", len(dumps))
+	}
+	if !strings.HasSuffix(dumps[0], "# truncated: file reached maxBytes (300)\n") {
+		t.Errorf("dump is not truncated:\n%s", dumps[0])
+	}
+	if strings.Contains(dumps[0], "SearchRequest") {
+		t.Errorf("dump continued past maxBytes:\n%s", dumps[0])
+	}
+}
+
+func TestWireDumpDisable(t *testing.T) {
+	wd, dir := newTestWireDump(t, WireDumpConfig{Duration: 20 * time.Millisecond})
+
+	status := wd.Status()
+	if !status.Enabled || status.ExpiresAt.IsZero() {
+		t.Fatalf("Status() = %+v, want enabled with expiry", status)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for wd.Status().Enabled {
+		if time.Now().After(deadline) {
+			t.Fatal("wire dump did not disable itself")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if dumps := runWireDumpSession(t, wd, dir); len(dumps) != 0 {
+		t.Errorf("got %d dump files after expiry, want 0", len(dumps))
+	}
+
+	if _, err := NewWireDump(&WireDumpConfig{}); !errors.Is(err, ErrWireDumpNoDirectory) {
+		t.Errorf("NewWireDump() error = %v, want ErrWireDumpNoDirectory", err)
+	}
+}
@@ -0,0 +1,229 @@
+// Package storage provides the core storage engine components for ObaDB.
+package storage
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Adaptive hash index defaults.
+const (
+	// DefaultAdaptiveIndexSize is the default number of cached locations.
+	DefaultAdaptiveIndexSize = 1024
+
+	// adaptiveAdmitCount is the number of accesses, since the last decay,
+	// after which an entry is hot enough to be cached.
+	adaptiveAdmitCount = 2
+
+	// adaptiveDecayFactor sets the decay period: access counters are halved
+	// every adaptiveDecayFactor * capacity accesses.
+	adaptiveDecayFactor = 8
+)
+
+// EntryLocation is the storage location of an entry.
+type EntryLocation struct {
+	PageID PageID
+	SlotID uint16
+}
+
+// AdaptiveHashIndex caches the DN to storage location mapping of the most
+// frequently accessed entries, so that lookups for a small hot set of
+// entries skip the DN index.
+//
+// Accesses are counted per DN with Touch. A DN accessed often enough is
+// cached by Add, evicting the coldest cached DN when the index is full.
+// Counters decay by half periodically, and a cached DN whose counter decays
+// to zero is evicted. Writers must call Invalidate whenever the location of
+// a DN changes.
+type AdaptiveHashIndex struct {
+	// hits and misses are first for 64-bit alignment of atomic access
+	hits   uint64
+	misses uint64
+
+	capacity int
+
+	// locations maps each cached DN to its EntryLocation.
+	locations sync.Map
+
+	mu       sync.Mutex
+	counters map[string]uint32
+	cached   int
+	accesses int
+	// epoch is advanced by every invalidation, so that an Add racing with
+	// it does not cache a stale location.
+	epoch uint64
+	// coldest is a cached DN whose counter was the lowest when last
+	// looked for, and coldCount that counter. Counters only grow between
+	// decays, so a DN with at most coldCount accesses cannot evict anyone.
+	coldest   string
+	coldCount uint32
+}
+
+// NewAdaptiveHashIndex creates an adaptive hash index caching at most
+// capacity locations. A non-positive capacity uses
+// DefaultAdaptiveIndexSize.
+func NewAdaptiveHashIndex(capacity int) *AdaptiveHashIndex {
+	if capacity <= 0 {
+		capacity = DefaultAdaptiveIndexSize
+	}
+	return &AdaptiveHashIndex{
+		capacity: capacity,
+		counters: make(map[string]uint32),
+	}
+}
+
+// Lookup returns the cached location of dn. It counts a hit or a miss.
+func (a *AdaptiveHashIndex) Lookup(dn string) (PageID, uint16, bool) {
+	if value, ok := a.locations.Load(dn); ok {
+		atomic.AddUint64(&a.hits, 1)
+		loc := value.(EntryLocation)
+		return loc.PageID, loc.SlotID, true
+	}
+	atomic.AddUint64(&a.misses, 1)
+	return 0, 0, false
+}
+
+// Touch records an access to dn. It reports whether dn is hot but not
+// cached, in which case the caller should resolve its location and Add it.
+func (a *AdaptiveHashIndex) Touch(dn string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.counters[dn] < ^uint32(0) {
+		a.counters[dn]++
+	}
+	a.accesses++
+	if a.accesses >= adaptiveDecayFactor*a.capacity {
+		a.decayLocked()
+	}
+
+	count := a.counters[dn]
+	if count < adaptiveAdmitCount || (a.cached >= a.capacity && count <= a.coldCount) {
+		return false
+	}
+	_, cached := a.locations.Load(dn)
+	return !cached
+}
+
+// Epoch returns the current invalidation epoch, to be passed to Add.
+func (a *AdaptiveHashIndex) Epoch() uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.epoch
+}
+
+// Add caches the location of dn, read from the DN index after Epoch
+// returned epoch. It is ignored if an invalidation happened since, or if
+// dn is colder than every cached DN of a full index.
+func (a *AdaptiveHashIndex) Add(dn string, pageID PageID, slotID uint16, epoch uint64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if epoch != a.epoch {
+		return
+	}
+	count := a.counters[dn]
+	if count < adaptiveAdmitCount {
+		return
+	}
+	if _, cached := a.locations.Load(dn); cached {
+		return
+	}
+
+	if a.cached >= a.capacity {
+		if count <= a.coldCount {
+			return
+		}
+		a.findColdestLocked()
+		if count <= a.coldCount {
+			return
+		}
+		a.locations.Delete(a.coldest)
+		a.cached--
+		a.coldest, a.coldCount = "", 0
+	}
+
+	a.locations.Store(dn, EntryLocation{PageID: pageID, SlotID: slotID})
+	a.cached++
+}
+
+// Invalidate removes dn from the index.
+func (a *AdaptiveHashIndex) Invalidate(dn string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.epoch++
+	if _, ok := a.locations.LoadAndDelete(dn); ok {
+		a.cached--
+	}
+	if a.coldest == dn {
+		a.coldest, a.coldCount = "", 0
+	}
+}
+
+// Clear removes every location and access counter.
+func (a *AdaptiveHashIndex) Clear() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.epoch++
+	a.locations.Range(func(key, _ interface{}) bool {
+		a.locations.Delete(key)
+		return true
+	})
+	a.counters = make(map[string]uint32)
+	a.cached = 0
+	a.accesses = 0
+	a.coldest, a.coldCount = "", 0
+}
+
+// Len returns the number of cached locations.
+func (a *AdaptiveHashIndex) Len() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.cached
+}
+
+// HitRatio returns the fraction of lookups that found a cached location,
+// or 0 before the first lookup.
+func (a *AdaptiveHashIndex) HitRatio() float64 {
+	hits := atomic.LoadUint64(&a.hits)
+	total := hits + atomic.LoadUint64(&a.misses)
+	if total == 0 {
+		return 0
+	}
+	return float64(hits) / float64(total)
+}
+
+// decayLocked halves every access counter, forgetting DNs whose counter
+// reaches zero and evicting them if cached.
+func (a *AdaptiveHashIndex) decayLocked() {
+	for dn, count := range a.counters {
+		count /= 2
+		if count > 0 {
+			a.counters[dn] = count
+			continue
+		}
+		delete(a.counters, dn)
+		if _, ok := a.locations.LoadAndDelete(dn); ok {
+			a.cached--
+		}
+	}
+	a.accesses = 0
+	a.coldest, a.coldCount = "", 0
+}
+
+// findColdestLocked sets coldest to the cached DN with the lowest counter.
+func (a *AdaptiveHashIndex) findColdestLocked() {
+	first := true
+	a.locations.Range(func(key, _ interface{}) bool {
+		dn := key.(string)
+		if count := a.counters[dn]; first || count < a.coldCount {
+			a.coldest, a.coldCount = dn, count
+			first = false
+		}
+		return true
+	})
+}
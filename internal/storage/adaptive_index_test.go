@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+)
+
+// warmAdaptive touches dn n times and adds its location whenever Touch asks.
+func warmAdaptive(a *AdaptiveHashIndex, dn string, n int, pageID PageID) {
+	for i := 0; i < n; i++ {
+		if a.Touch(dn) {
+			a.Add(dn, pageID, 1, a.Epoch())
+		}
+	}
+}
+
+// TestAdaptiveHashIndex_Admission tests that a DN is cached only once hot.
+func TestAdaptiveHashIndex_Admission(t *testing.T) {
+	a := NewAdaptiveHashIndex(4)
+
+	if a.Touch("cn=a") {
+		t.Error("Touch() = true after a single access")
+	}
+	a.Add("cn=a", 7, 3, a.Epoch())
+	if _, _, ok := a.Lookup("cn=a"); ok {
+		t.Error("Add() cached a DN accessed once")
+	}
+
+	if !a.Touch("cn=a") {
+		t.Fatal("Touch() = false for a hot DN")
+	}
+	a.Add("cn=a", 7, 3, a.Epoch())
+
+	pageID, slotID, ok := a.Lookup("cn=a")
+	if !ok || pageID != 7 || slotID != 3 {
+		t.Errorf("Lookup() = %d, %d, %v; want 7, 3, true", pageID, slotID, ok)
+	}
+	if a.Touch("cn=a") {
+		t.Error("Touch() = true for a cached DN")
+	}
+	if a.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", a.Len())
+	}
+}
+
+// TestAdaptiveHashIndex_Invalidate tests that invalidation drops the cached
+// location and rejects locations resolved before it.
+func TestAdaptiveHashIndex_Invalidate(t *testing.T) {
+	a := NewAdaptiveHashIndex(4)
+	warmAdaptive(a, "cn=a", 2, 7)
+
+	a.Invalidate("cn=a")
+	if _, _, ok := a.Lookup("cn=a"); ok {
+		t.Fatal("Lookup() found an invalidated DN")
+	}
+
+	if !a.Touch("cn=a") {
+		t.Fatal("Touch() = false for a hot DN after invalidation")
+	}
+	epoch := a.Epoch()
+	a.Invalidate("cn=a")
+	a.Add("cn=a", 7, 1, epoch)
+	if _, _, ok := a.Lookup("cn=a"); ok {
+		t.Error("Add() cached a location resolved before an invalidation")
+	}
+
+	a.Add("cn=a", 8, 1, a.Epoch())
+	if pageID, _, ok := a.Lookup("cn=a"); !ok || pageID != 8 {
+		t.Errorf("Lookup() = %d, %v; want 8, true", pageID, ok)
+	}
+}
+
+// TestAdaptiveHashIndex_EvictsColdest tests that a full index replaces its
+// coldest DN only with a hotter one.
+func TestAdaptiveHashIndex_EvictsColdest(t *testing.T) {
+	a := NewAdaptiveHashIndex(2)
+	warmAdaptive(a, "cn=hot", 6, 1)
+	warmAdaptive(a, "cn=warm", 3, 2)
+
+	warmAdaptive(a, "cn=new", 3, 3)
+	if _, _, ok := a.Lookup("cn=new"); ok {
+		t.Error("a DN no hotter than the coldest one was cached")
+	}
+
+	warmAdaptive(a, "cn=new", 1, 3)
+	if _, _, ok := a.Lookup("cn=new"); !ok {
+		t.Error("a DN hotter than the coldest one was not cached")
+	}
+	if _, _, ok := a.Lookup("cn=warm"); ok {
+		t.Error("the coldest DN was not evicted")
+	}
+	if _, _, ok := a.Lookup("cn=hot"); !ok {
+		t.Error("the hottest DN was evicted")
+	}
+	if a.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", a.Len())
+	}
+}
+
+// TestAdaptiveHashIndex_Decay tests that DNs no longer accessed are evicted.
+func TestAdaptiveHashIndex_Decay(t *testing.T) {
+	a := NewAdaptiveHashIndex(2)
+	warmAdaptive(a, "cn=old", 2, 1)
+	if _, _, ok := a.Lookup("cn=old"); !ok {
+		t.Fatal("hot DN was not cached")
+	}
+
+	// Two decay periods halve the counter of cn=old from 2 to 0.
+	for i := 0; i < 2*adaptiveDecayFactor*2; i++ {
+		a.Touch(fmt.Sprintf("cn=other%d", i))
+	}
+
+	if _, _, ok := a.Lookup("cn=old"); ok {
+		t.Error("DN was not evicted after its counter decayed")
+	}
+	if a.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", a.Len())
+	}
+}
+
+// TestAdaptiveHashIndex_HitRatio tests the hit ratio and Clear.
+func TestAdaptiveHashIndex_HitRatio(t *testing.T) {
+	a := NewAdaptiveHashIndex(0)
+	if a.HitRatio() != 0 {
+		t.Errorf("HitRatio() = %v before any lookup, want 0", a.HitRatio())
+	}
+
+	warmAdaptive(a, "cn=a", 2, 1)
+	a.Lookup("cn=a")
+	a.Lookup("cn=a")
+	a.Lookup("cn=a")
+	a.Lookup("cn=b")
+	if got := a.HitRatio(); got != 0.75 {
+		t.Errorf("HitRatio() = %v, want 0.75", got)
+	}
+
+	a.Clear()
+	if _, _, ok := a.Lookup("cn=a"); ok {
+		t.Error("Lookup() found a DN after Clear()")
+	}
+	if a.Touch("cn=a") {
+		t.Error("Clear() did not reset the access counters")
+	}
+}
@@ -0,0 +1,115 @@
+package btree
+
+// KeyRef is a key and the entry reference stored under it.
+type KeyRef struct {
+	Key []byte
+	Ref EntryRef
+}
+
+// InsertSorted inserts the pairs of items, which must be sorted by key.
+// Consecutive keys that belong to the same leaf are inserted with one page
+// write, and the internal nodes read on the way to a leaf are reused for
+// the following keys until a split changes them, so a batch in key order
+// reads each node on its path once instead of once per key.
+func (t *BPlusTree) InsertSorted(items []KeyRef) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var path []*BPlusNode
+	var bounds [][]byte
+	for i := 0; i < len(items); i++ {
+		key := items[i].Key
+		if len(key) == 0 {
+			return ErrEmptyKey
+		}
+
+		// Climb to the lowest node whose key range holds the key, since
+		// the keys only grow, and descend from there
+		level := len(path) - 1
+		for level >= 0 && bounds[level] != nil && compareKeys(key, bounds[level]) >= 0 {
+			level--
+		}
+		var err error
+		if level < 0 {
+			path, bounds, err = t.descend(nil, nil, key)
+		} else {
+			path, bounds, err = t.descend(path[:level+1], bounds[:level+1], key)
+		}
+		if err != nil {
+			return err
+		}
+
+		leaf := path[len(path)-1]
+		ref := items[i].Ref
+		idx, _ := leaf.FindKeyIndex(key)
+		leaf.InsertKeyAt(idx, key, &ref, InvalidPageID)
+
+		// A split writes the leaf and changes the nodes above it
+		if leaf.IsFull() || !leaf.FitsInPage() {
+			if err := t.splitLeafAndPropagate(path); err != nil {
+				return err
+			}
+			path, bounds = nil, nil
+			continue
+		}
+
+		// The leaf is written once the next key falls outside of it
+		if i+1 == len(items) || len(items[i+1].Key) == 0 ||
+			(bounds[len(bounds)-1] != nil && compareKeys(items[i+1].Key, bounds[len(bounds)-1]) >= 0) {
+			if err := t.writeNode(leaf); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// descend extends path, which holds the nodes from the root down to an
+// internal node whose key range contains key, to the leaf for key. An
+// empty path starts from the root. bounds holds the separator that bounds
+// the keys of each node of the path from above, or nil for the rightmost
+// node of a level.
+func (t *BPlusTree) descend(path []*BPlusNode, bounds [][]byte, key []byte) ([]*BPlusNode, [][]byte, error) {
+	if len(path) == 0 {
+		if t.root == InvalidPageID {
+			return nil, nil, ErrTreeNotInitialized
+		}
+		root, err := t.readNode(t.root)
+		if err != nil {
+			return nil, nil, err
+		}
+		path = []*BPlusNode{root}
+		bounds = [][]byte{nil}
+	}
+
+	node := path[len(path)-1]
+	upper := bounds[len(bounds)-1]
+	for !node.IsLeaf {
+		if len(node.Children) == 0 {
+			return nil, nil, ErrNodeNotFound
+		}
+
+		// Keys[i] separates Children[i] from Children[i+1]
+		child := len(node.Keys)
+		for i := 0; i < len(node.Keys); i++ {
+			if compareKeys(key, node.Keys[i]) < 0 {
+				child = i
+				break
+			}
+		}
+		if child < len(node.Keys) {
+			upper = node.Keys[child]
+		}
+
+		var err error
+		node, err = t.readNode(node.Children[child])
+		if err != nil {
+			return nil, nil, err
+		}
+		path = append(path, node)
+		bounds = append(bounds, upper)
+	}
+
+	return path, bounds, nil
+}
@@ -0,0 +1,86 @@
+package btree
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+func TestInsertSorted(t *testing.T) {
+	pm, cleanup := createTestPageManager(t)
+	defer cleanup()
+
+	tree, err := NewBPlusTree(pm, 0)
+	if err != nil {
+		t.Fatalf("failed to create B+ tree: %v", err)
+	}
+
+	// Even keys are inserted one at a time, odd keys as a sorted batch
+	numKeys := 4*BPlusLeafCapacity + 10
+	for i := 0; i < numKeys; i += 2 {
+		key := []byte(fmt.Sprintf("key%05d", i))
+		if err := tree.Insert(key, EntryRef{PageID: storage.PageID(i + 1)}); err != nil {
+			t.Fatalf("failed to insert key %d: %v", i, err)
+		}
+	}
+
+	var items []KeyRef
+	for i := 1; i < numKeys; i += 2 {
+		key := []byte(fmt.Sprintf("key%05d", i))
+		items = append(items, KeyRef{Key: key, Ref: EntryRef{PageID: storage.PageID(i + 1)}})
+	}
+	// A duplicate of an existing key lands next to it
+	items = append(items, KeyRef{Key: []byte(fmt.Sprintf("key%05d", numKeys-1)), Ref: EntryRef{PageID: 1}})
+
+	if err := tree.InsertSorted(items); err != nil {
+		t.Fatalf("InsertSorted() error = %v", err)
+	}
+
+	for i := 0; i < numKeys; i++ {
+		refs, err := tree.Search([]byte(fmt.Sprintf("key%05d", i)))
+		if err != nil {
+			t.Fatalf("failed to search key %d: %v", i, err)
+		}
+		want := 1
+		if i == numKeys-1 {
+			want = 2
+		}
+		if len(refs) != want {
+			t.Errorf("key %d has %d references, want %d", i, len(refs), want)
+		}
+	}
+
+	if errs := tree.Verify(); len(errs) != 0 {
+		t.Errorf("Verify() = %v, want no errors", errs)
+	}
+
+	if err := tree.InsertSorted([]KeyRef{{Key: nil}}); err != ErrEmptyKey {
+		t.Errorf("InsertSorted() with empty key error = %v, want %v", err, ErrEmptyKey)
+	}
+}
+
+// TestInsertSortedPageWrites tests that keys sharing a leaf are written
+// with one page write.
+func TestInsertSortedPageWrites(t *testing.T) {
+	pm, cleanup := createTestPageManager(t)
+	defer cleanup()
+
+	tree, err := NewBPlusTree(pm, 0)
+	if err != nil {
+		t.Fatalf("failed to create B+ tree: %v", err)
+	}
+
+	items := make([]KeyRef, 10)
+	for i := range items {
+		items[i] = KeyRef{Key: []byte(fmt.Sprintf("key%05d", i)), Ref: EntryRef{PageID: storage.PageID(i + 1)}}
+	}
+
+	before := pm.Stats().PageWrites
+	if err := tree.InsertSorted(items); err != nil {
+		t.Fatalf("InsertSorted() error = %v", err)
+	}
+	if writes := pm.Stats().PageWrites - before; writes != 1 {
+		t.Errorf("InsertSorted() wrote %d pages, want 1", writes)
+	}
+}
@@ -0,0 +1,88 @@
+package btree
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// benchmarkTreeSize is the number of keys loaded before lookups are measured.
+const benchmarkTreeSize = 20000
+
+// benchmarkKey returns the i-th key of a benchmark tree, in the form of an
+// equality index key.
+func benchmarkKey(i int) []byte {
+	return []byte(fmt.Sprintf("user%08d", i))
+}
+
+// loadBenchmarkTree creates a tree holding benchmarkTreeSize keys.
+func loadBenchmarkTree(b *testing.B) *BPlusTree {
+	pm, cleanup := createTestPageManager(b)
+	b.Cleanup(cleanup)
+
+	tree, err := NewBPlusTree(pm, 0)
+	if err != nil {
+		b.Fatalf("failed to create B+ tree: %v", err)
+	}
+	for i := 0; i < benchmarkTreeSize; i++ {
+		ref := EntryRef{PageID: storage.PageID(i + 1), DN: fmt.Sprintf("uid=user%d,ou=users,dc=example,dc=com", i)}
+		if err := tree.Insert(benchmarkKey(i), ref); err != nil {
+			b.Fatalf("Insert() error = %v", err)
+		}
+	}
+	return tree
+}
+
+// BenchmarkBPlusTreeInsert benchmarks inserting keys in random order.
+func BenchmarkBPlusTreeInsert(b *testing.B) {
+	pm, cleanup := createTestPageManager(b)
+	defer cleanup()
+
+	tree, err := NewBPlusTree(pm, 0)
+	if err != nil {
+		b.Fatalf("failed to create B+ tree: %v", err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		// Multiplying by a large odd number visits every key in a scattered order
+		key := benchmarkKey(int(uint32(i) * 2654435761))
+		if err := tree.Insert(key, EntryRef{PageID: storage.PageID(i + 1)}); err != nil {
+			b.Fatalf("Insert() error = %v", err)
+		}
+	}
+}
+
+// BenchmarkBPlusTreeSearch benchmarks point lookups.
+func BenchmarkBPlusTreeSearch(b *testing.B) {
+	tree := loadBenchmarkTree(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		refs, err := tree.Search(benchmarkKey(i % benchmarkTreeSize))
+		if err != nil || len(refs) != 1 {
+			b.Fatalf("Search() = %d refs, %v", len(refs), err)
+		}
+	}
+}
+
+// BenchmarkBPlusTreeRange benchmarks iterating over 100 consecutive keys.
+func BenchmarkBPlusTreeRange(b *testing.B) {
+	tree := loadBenchmarkTree(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		start := i % (benchmarkTreeSize - 100)
+		refs, err := tree.SearchRange(benchmarkKey(start), benchmarkKey(start+99))
+		if err != nil || len(refs) != 100 {
+			b.Fatalf("SearchRange() = %d refs, %v", len(refs), err)
+		}
+	}
+}
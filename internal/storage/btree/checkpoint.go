@@ -0,0 +1,337 @@
+// Package btree provides B+ Tree implementation for attribute indexing in ObaDB.
+package btree
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// Checkpoint format constants.
+//
+// A checkpoint holds the leaf level of a tree in key order:
+//
+//	header:  magic "OBTC" | version (uint8)
+//	record:  keyLen (uint16) | key | pageID (uint64) | slotID (uint16) | dnLen (uint16) | dn
+//	trailer: keyLen 0 (uint16) | CRC32 of everything before it (uint32)
+//
+// Integers are little endian. Keys are never empty, so a zero key length
+// ends the records.
+const (
+	checkpointMagic   = "OBTC"
+	checkpointVersion = 1
+)
+
+// Checkpoint errors.
+var (
+	ErrInvalidCheckpoint  = errors.New("invalid b+ tree checkpoint")
+	ErrCheckpointChecksum = errors.New("b+ tree checkpoint checksum mismatch")
+)
+
+// Checkpoint writes the keys and entry references of the tree to w in key
+// order. Only the leaf level is written, so a checkpoint is much smaller
+// than the page images of the tree. Use LoadFromCheckpoint to rebuild the
+// tree.
+func (t *BPlusTree) Checkpoint(w io.Writer) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	crc := crc32.NewIEEE()
+	out := io.MultiWriter(bw, crc)
+
+	header := append([]byte(checkpointMagic), checkpointVersion)
+	if _, err := out.Write(header); err != nil {
+		return err
+	}
+
+	if t.root != InvalidPageID {
+		leaf, err := t.findLeftmostLeaf()
+		if err != nil {
+			return err
+		}
+		var buf []byte
+		for {
+			for i, key := range leaf.Keys {
+				buf = appendCheckpointRecord(buf[:0], key, leaf.Values[i])
+				if _, err := out.Write(buf); err != nil {
+					return err
+				}
+			}
+			if leaf.Next == InvalidPageID {
+				break
+			}
+			if leaf, err = t.readNode(leaf.Next); err != nil {
+				return err
+			}
+		}
+	}
+
+	var trailer [6]byte
+	if _, err := out.Write(trailer[:2]); err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(trailer[2:], crc.Sum32())
+	if _, err := bw.Write(trailer[2:]); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// appendCheckpointRecord appends the checkpoint record of key and ref to buf.
+func appendCheckpointRecord(buf, key []byte, ref EntryRef) []byte {
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(key)))
+	buf = append(buf, key...)
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(ref.PageID))
+	buf = binary.LittleEndian.AppendUint16(buf, ref.SlotID)
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(ref.DN)))
+	return append(buf, ref.DN...)
+}
+
+// LoadFromCheckpoint builds a new tree in pm from a checkpoint written by
+// Checkpoint. The tree is built bottom up: leaves are filled in key order
+// and linked, then each internal level is built over the one below it.
+// The tree uses the default order.
+//
+// Returns ErrInvalidCheckpoint if the data is malformed and
+// ErrCheckpointChecksum if it does not match its checksum. Pages written
+// before such an error are not freed.
+func LoadFromCheckpoint(r io.Reader, pm *storage.PageManager) (*BPlusTree, error) {
+	if pm == nil {
+		return nil, ErrInvalidPageManager
+	}
+
+	crc := crc32.NewIEEE()
+	in := io.TeeReader(bufio.NewReader(r), crc)
+
+	header := make([]byte, len(checkpointMagic)+1)
+	if _, err := io.ReadFull(in, header); err != nil {
+		return nil, checkpointReadError(err)
+	}
+	if string(header[:len(checkpointMagic)]) != checkpointMagic || header[len(checkpointMagic)] != checkpointVersion {
+		return nil, ErrInvalidCheckpoint
+	}
+
+	tree := &BPlusTree{
+		root:        InvalidPageID,
+		pageManager: pm,
+		order:       BPlusOrder,
+	}
+	loader := &checkpointLoader{tree: tree}
+
+	var lastKey []byte
+	for {
+		key, ref, err := readCheckpointRecord(in)
+		if err != nil {
+			return nil, err
+		}
+		if key == nil {
+			break
+		}
+		if lastKey != nil && compareKeys(lastKey, key) > 0 {
+			return nil, ErrInvalidCheckpoint
+		}
+		lastKey = key
+		if err := loader.add(key, ref); err != nil {
+			return nil, err
+		}
+	}
+
+	sum := crc.Sum32()
+	var trailer [4]byte
+	if _, err := io.ReadFull(in, trailer[:]); err != nil {
+		return nil, checkpointReadError(err)
+	}
+	if binary.LittleEndian.Uint32(trailer[:]) != sum {
+		return nil, ErrCheckpointChecksum
+	}
+
+	if err := loader.finish(); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// readCheckpointRecord reads one record. It returns a nil key at the end
+// of the records.
+func readCheckpointRecord(r io.Reader) ([]byte, EntryRef, error) {
+	var ref EntryRef
+	var lenBuf [2]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return nil, ref, checkpointReadError(err)
+	}
+	keyLen := binary.LittleEndian.Uint16(lenBuf[:])
+	if keyLen == 0 {
+		return nil, ref, nil
+	}
+	if keyLen > MaxKeySize {
+		return nil, ref, ErrInvalidCheckpoint
+	}
+
+	buf := make([]byte, int(keyLen)+12)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, ref, checkpointReadError(err)
+	}
+	key := buf[:keyLen]
+	fixed := buf[keyLen:]
+	ref.PageID = storage.PageID(binary.LittleEndian.Uint64(fixed[0:8]))
+	ref.SlotID = binary.LittleEndian.Uint16(fixed[8:10])
+
+	dn := make([]byte, binary.LittleEndian.Uint16(fixed[10:12]))
+	if _, err := io.ReadFull(r, dn); err != nil {
+		return nil, ref, checkpointReadError(err)
+	}
+	ref.DN = string(dn)
+	return key, ref, nil
+}
+
+// checkpointReadError turns a premature end of the data into
+// ErrInvalidCheckpoint.
+func checkpointReadError(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrInvalidCheckpoint
+	}
+	return err
+}
+
+// levelEntry is a node of the level being built, with the smallest key
+// below it.
+type levelEntry struct {
+	firstKey []byte
+	pageID   storage.PageID
+}
+
+// checkpointLoader builds a tree from keys arriving in order.
+type checkpointLoader struct {
+	tree   *BPlusTree
+	leaf   *BPlusNode
+	leaves []levelEntry
+}
+
+// add appends key and ref to the current leaf, starting a new leaf when it
+// has no room left.
+func (l *checkpointLoader) add(key []byte, ref EntryRef) error {
+	if l.leaf == nil {
+		leaf, err := l.tree.allocateNode(true)
+		if err != nil {
+			return err
+		}
+		l.leaf = leaf
+	}
+
+	l.leaf.Keys = append(l.leaf.Keys, key)
+	l.leaf.Values = append(l.leaf.Values, ref)
+	if len(l.leaf.Keys) == 1 || (!l.leaf.IsFull() && l.leaf.FitsInPage()) {
+		return nil
+	}
+
+	// The leaf is over its limit: move the new key to a fresh leaf
+	last := len(l.leaf.Keys) - 1
+	l.leaf.Keys = l.leaf.Keys[:last]
+	l.leaf.Values = l.leaf.Values[:last]
+
+	next, err := l.tree.allocateNode(true)
+	if err != nil {
+		return err
+	}
+	l.leaf.Next = next.PageID
+	next.Prev = l.leaf.PageID
+	if err := l.flushLeaf(); err != nil {
+		return err
+	}
+
+	next.Keys = append(next.Keys, key)
+	next.Values = append(next.Values, ref)
+	l.leaf = next
+	return nil
+}
+
+// flushLeaf writes the current leaf and records it for the level above.
+func (l *checkpointLoader) flushLeaf() error {
+	if err := l.tree.writeNode(l.leaf); err != nil {
+		return err
+	}
+	l.leaves = append(l.leaves, levelEntry{firstKey: l.leaf.Keys[0], pageID: l.leaf.PageID})
+	return nil
+}
+
+// finish writes the last leaf and builds the internal levels.
+func (l *checkpointLoader) finish() error {
+	if l.leaf == nil {
+		// An empty tree is a single empty leaf
+		root, err := l.tree.allocateNode(true)
+		if err != nil {
+			return err
+		}
+		if err := l.tree.writeNode(root); err != nil {
+			return err
+		}
+		l.tree.root = root.PageID
+		return nil
+	}
+	if err := l.flushLeaf(); err != nil {
+		return err
+	}
+
+	level := l.leaves
+	for len(level) > 1 {
+		var err error
+		if level, err = l.buildLevel(level); err != nil {
+			return err
+		}
+	}
+	l.tree.root = level[0].pageID
+	return nil
+}
+
+// buildLevel writes the internal nodes over children and returns them.
+// Each node takes as many children as fit; the separator before a child
+// is its smallest key, as when a leaf is split.
+func (l *checkpointLoader) buildLevel(children []levelEntry) ([]levelEntry, error) {
+	var groups [][]levelEntry
+	start := 0
+	node := NewInternalNode(InvalidPageID)
+	for i, child := range children {
+		if i > start {
+			node.Keys = append(node.Keys, child.firstKey)
+		}
+		node.Children = append(node.Children, child.pageID)
+		if i > start && (node.IsFull() || !node.FitsInPage()) {
+			groups = append(groups, children[start:i])
+			start = i
+			node = NewInternalNode(InvalidPageID)
+			node.Children = append(node.Children, child.pageID)
+		}
+	}
+	groups = append(groups, children[start:])
+
+	// An internal node needs at least two children
+	if n := len(groups); n > 1 && len(groups[n-1]) == 1 {
+		prev := groups[n-2]
+		groups[n-2] = prev[:len(prev)-1]
+		groups[n-1] = children[len(children)-2:]
+	}
+
+	parents := make([]levelEntry, 0, len(groups))
+	for _, group := range groups {
+		node, err := l.tree.allocateNode(false)
+		if err != nil {
+			return nil, err
+		}
+		for i, child := range group {
+			if i > 0 {
+				node.Keys = append(node.Keys, child.firstKey)
+			}
+			node.Children = append(node.Children, child.pageID)
+		}
+		if err := l.tree.writeNode(node); err != nil {
+			return nil, err
+		}
+		parents = append(parents, levelEntry{firstKey: group[0].firstKey, pageID: node.PageID})
+	}
+	return parents, nil
+}
@@ -0,0 +1,173 @@
+package btree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// checkpointTree writes a checkpoint of tree and returns it.
+func checkpointTree(t *testing.T, tree *BPlusTree) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := tree.Checkpoint(&buf); err != nil {
+		t.Fatalf("Checkpoint() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// loadCheckpoint rebuilds a tree from data in a new page manager.
+func loadCheckpoint(t *testing.T, data []byte) *BPlusTree {
+	t.Helper()
+
+	pm, cleanup := createTestPageManager(t)
+	t.Cleanup(cleanup)
+
+	tree, err := LoadFromCheckpoint(bytes.NewReader(data), pm)
+	if err != nil {
+		t.Fatalf("LoadFromCheckpoint() error = %v", err)
+	}
+	if errs := tree.Verify(); len(errs) != 0 {
+		t.Fatalf("rebuilt tree failed verification: %v", errs)
+	}
+	return tree
+}
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	pm, cleanup := createTestPageManager(t)
+	defer cleanup()
+
+	tree, err := NewBPlusTree(pm, 0)
+	if err != nil {
+		t.Fatalf("failed to create B+ tree: %v", err)
+	}
+
+	const numKeys = 50000
+	key := func(i int) []byte { return []byte(fmt.Sprintf("key%06d", i)) }
+	ref := func(i int) EntryRef {
+		return EntryRef{PageID: storage.PageID(i + 1), SlotID: uint16(i % 100), DN: fmt.Sprintf("uid=user%d", i)}
+	}
+	for i := 0; i < numKeys; i++ {
+		// Insert in a scattered order so the source tree has split nodes
+		j := (i * 7919) % numKeys
+		if err := tree.Insert(key(j), ref(j)); err != nil {
+			t.Fatalf("failed to insert: %v", err)
+		}
+	}
+
+	data := checkpointTree(t, tree)
+	stats, err := tree.Stats()
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if pages := (stats.LeafNodes + stats.InternalNodes) * storage.PageSize; len(data) >= pages {
+		t.Errorf("checkpoint is %d bytes, not smaller than the %d bytes of pages", len(data), pages)
+	}
+
+	rebuilt := loadCheckpoint(t, data)
+	for i := 0; i < numKeys; i++ {
+		refs, err := rebuilt.Search(key(i))
+		if err != nil {
+			t.Fatalf("Search(%s) error = %v", key(i), err)
+		}
+		if len(refs) != 1 || refs[0] != ref(i) {
+			t.Fatalf("Search(%s) = %v, want [%v]", key(i), refs, ref(i))
+		}
+	}
+
+	// The rebuilt tree checkpoints to the same data
+	if !bytes.Equal(checkpointTree(t, rebuilt), data) {
+		t.Error("checkpoint of the rebuilt tree differs from the original")
+	}
+}
+
+func TestCheckpointDuplicateKeys(t *testing.T) {
+	pm, cleanup := createTestPageManager(t)
+	defer cleanup()
+
+	rebuilt := loadCheckpoint(t, checkpointTree(t, buildVerifyTree(t, pm)))
+	for i := 0; i < 1000; i++ {
+		key := []byte(fmt.Sprintf("key%05d", i))
+		refs, err := rebuilt.Search(key)
+		if err != nil {
+			t.Fatalf("Search(%s) error = %v", key, err)
+		}
+		if len(refs) != 3 {
+			t.Fatalf("Search(%s) returned %d refs, want 3", key, len(refs))
+		}
+	}
+}
+
+func TestCheckpointEmptyTree(t *testing.T) {
+	pm, cleanup := createTestPageManager(t)
+	defer cleanup()
+
+	tree, err := NewBPlusTree(pm, 0)
+	if err != nil {
+		t.Fatalf("failed to create B+ tree: %v", err)
+	}
+
+	rebuilt := loadCheckpoint(t, checkpointTree(t, tree))
+	if !rebuilt.IsEmpty() {
+		t.Error("expected the rebuilt tree to be empty")
+	}
+
+	// The rebuilt tree accepts new keys
+	ref := EntryRef{PageID: 1, DN: "uid=alice"}
+	if err := rebuilt.Insert([]byte("alice"), ref); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	if refs, _ := rebuilt.Search([]byte("alice")); len(refs) != 1 {
+		t.Errorf("Search() returned %d refs, want 1", len(refs))
+	}
+}
+
+func TestLoadFromCheckpointInvalid(t *testing.T) {
+	pm, cleanup := createTestPageManager(t)
+	defer cleanup()
+
+	tree, err := NewBPlusTree(pm, 0)
+	if err != nil {
+		t.Fatalf("failed to create B+ tree: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		if err := tree.Insert([]byte(fmt.Sprintf("key%03d", i)), EntryRef{PageID: storage.PageID(i + 1)}); err != nil {
+			t.Fatalf("failed to insert: %v", err)
+		}
+	}
+	data := checkpointTree(t, tree)
+
+	// Flip a bit in the page ID of the first record
+	corrupt := bytes.Clone(data)
+	corrupt[len(checkpointMagic)+1+2+len("key000")] ^= 0x01
+	badMagic := bytes.Clone(data)
+	badMagic[0] = 'X'
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"empty", nil, ErrInvalidCheckpoint},
+		{"bad magic", badMagic, ErrInvalidCheckpoint},
+		{"truncated", data[:len(data)-10], ErrInvalidCheckpoint},
+		{"no checksum", data[:len(data)-4], ErrInvalidCheckpoint},
+		{"corrupted", corrupt, ErrCheckpointChecksum},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadFromCheckpoint(bytes.NewReader(tt.data), pm)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("LoadFromCheckpoint() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := LoadFromCheckpoint(bytes.NewReader(data), nil); err != ErrInvalidPageManager {
+		t.Errorf("LoadFromCheckpoint(nil) error = %v, want ErrInvalidPageManager", err)
+	}
+}
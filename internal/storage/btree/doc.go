@@ -40,4 +40,15 @@
 //
 //	data := node.Serialize()
 //	node, err := btree.DeserializeNode(data)
+//
+// # Checkpoints
+//
+// A checkpoint stores only the leaf level of a tree, the keys and entry
+// references in key order followed by a CRC32, which is much smaller than
+// the page images of the tree:
+//
+//	err := tree.Checkpoint(w)
+//
+//	// Later, rebuild the tree bottom up in a page manager
+//	tree, err := btree.LoadFromCheckpoint(r, pageManager)
 package btree
@@ -26,11 +26,7 @@ const (
 
 // EntryRef represents a reference to an entry stored in a data page.
 // It contains the page ID, slot ID, and DN where the entry is located.
-type EntryRef struct {
-	PageID storage.PageID // Page containing the entry
-	SlotID uint16         // Slot index within the page
-	DN     string         // Distinguished name of the entry
-}
+type EntryRef = storage.EntryRef
 
 // BPlusNode represents a node in the B+ Tree.
 // It can be either an internal node (containing keys and child pointers)
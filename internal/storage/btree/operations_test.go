@@ -11,7 +11,7 @@ import (
 )
 
 // Helper function to create a temporary page manager for testing.
-func createTestPageManager(t *testing.T) (*storage.PageManager, func()) {
+func createTestPageManager(t testing.TB) (*storage.PageManager, func()) {
 	t.Helper()
 
 	tmpDir, err := os.MkdirTemp("", "btree_test_*")
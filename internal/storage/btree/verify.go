@@ -0,0 +1,147 @@
+// Package btree provides B+ Tree implementation for attribute indexing in ObaDB.
+package btree
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// Errors reported by Verify.
+var (
+	ErrKeyOrder        = errors.New("keys are out of order")
+	ErrKeyOutOfBounds  = errors.New("key is outside the bounds set by the parent")
+	ErrChildCount      = errors.New("internal node child count does not match its keys")
+	ErrNodeRevisited   = errors.New("node is reachable more than once")
+	ErrLeafDepth       = errors.New("leaves are at different depths")
+	ErrLeafChainBroken = errors.New("leaf chain does not match the tree order")
+)
+
+// Verify walks the tree from its root and checks the B+ tree invariants:
+// keys are in order within each node, every key of a child lies between
+// the separators around it in its parent, internal nodes have one child
+// more than keys, no node is reachable twice, all leaves are at the same
+// depth, and the leaf chain links the leaves in key order in both
+// directions. Since duplicate keys may span a split, a key equal to a
+// separator is accepted on either side of it.
+//
+// It returns one error per damaged or inconsistent page. Subtrees below a
+// page that cannot be read are not checked.
+func (t *BPlusTree) Verify() []storage.PageError {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	v := &treeVerifier{
+		tree:    t,
+		file:    filepath.Base(t.pageManager.Path()),
+		visited: make(map[storage.PageID]bool),
+		depth:   -1,
+	}
+	if t.root == InvalidPageID {
+		return nil
+	}
+
+	v.walk(t.root, nil, nil, 0)
+	v.checkLeafChain()
+	return v.errors
+}
+
+// treeVerifier holds the state of a Verify walk.
+type treeVerifier struct {
+	tree    *BPlusTree
+	file    string
+	visited map[storage.PageID]bool
+	// depth is the depth of the first leaf reached, -1 before
+	depth int
+	// leaves are the leaves in key order, as reached by the walk
+	leaves []*BPlusNode
+	errors []storage.PageError
+}
+
+func (v *treeVerifier) report(pageID storage.PageID, err error) {
+	v.errors = append(v.errors, storage.PageError{File: v.file, PageID: pageID, Err: err})
+}
+
+// walk checks the subtree at pageID, whose keys must lie within
+// [lower, upper]. A nil bound is open.
+func (v *treeVerifier) walk(pageID storage.PageID, lower, upper []byte, depth int) {
+	if v.visited[pageID] {
+		v.report(pageID, ErrNodeRevisited)
+		return
+	}
+	v.visited[pageID] = true
+
+	page, err := v.tree.pageManager.ReadPage(pageID)
+	if err != nil {
+		v.report(pageID, err)
+		return
+	}
+	if page.Header.PageType != storage.PageTypeAttrIndex {
+		v.report(pageID, fmt.Errorf("%w: %s page", ErrInvalidNode, page.Header.PageType))
+		return
+	}
+	node, err := NewNodeFromPage(page)
+	if err != nil {
+		v.report(pageID, err)
+		return
+	}
+
+	for i, key := range node.Keys {
+		if i > 0 && compareKeys(node.Keys[i-1], key) > 0 {
+			v.report(pageID, fmt.Errorf("%w: %q before %q", ErrKeyOrder, node.Keys[i-1], key))
+			break
+		}
+		if (lower != nil && compareKeys(key, lower) < 0) || (upper != nil && compareKeys(key, upper) > 0) {
+			v.report(pageID, fmt.Errorf("%w: %q", ErrKeyOutOfBounds, key))
+			break
+		}
+	}
+
+	if node.IsLeaf {
+		if v.depth < 0 {
+			v.depth = depth
+		} else if depth != v.depth {
+			v.report(pageID, fmt.Errorf("%w: %d and %d", ErrLeafDepth, v.depth, depth))
+		}
+		v.leaves = append(v.leaves, node)
+		return
+	}
+
+	if len(node.Children) != len(node.Keys)+1 {
+		v.report(pageID, fmt.Errorf("%w: %d keys, %d children", ErrChildCount, len(node.Keys), len(node.Children)))
+		return
+	}
+	for i, child := range node.Children {
+		childLower, childUpper := lower, upper
+		if i > 0 {
+			childLower = node.Keys[i-1]
+		}
+		if i < len(node.Keys) {
+			childUpper = node.Keys[i]
+		}
+		v.walk(child, childLower, childUpper, depth+1)
+	}
+}
+
+// checkLeafChain checks that the Next and Prev links of the leaves follow
+// the order in which the walk reached them.
+func (v *treeVerifier) checkLeafChain() {
+	for i, leaf := range v.leaves {
+		var prev, next storage.PageID = InvalidPageID, InvalidPageID
+		if i > 0 {
+			prev = v.leaves[i-1].PageID
+		}
+		if i < len(v.leaves)-1 {
+			next = v.leaves[i+1].PageID
+		}
+
+		if leaf.Next != next {
+			v.report(leaf.PageID, fmt.Errorf("%w: next leaf is %d, expected %d", ErrLeafChainBroken, leaf.Next, next))
+		}
+		if leaf.Prev != prev {
+			v.report(leaf.PageID, fmt.Errorf("%w: previous leaf is %d, expected %d", ErrLeafChainBroken, leaf.Prev, prev))
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package btree
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// buildVerifyTree returns a tree of several levels with duplicate keys
+// inserted out of order.
+func buildVerifyTree(t *testing.T, pm *storage.PageManager) *BPlusTree {
+	t.Helper()
+
+	tree, err := NewBPlusTree(pm, 0)
+	if err != nil {
+		t.Fatalf("failed to create B+ tree: %v", err)
+	}
+	for i := 0; i < 3000; i++ {
+		key := []byte(fmt.Sprintf("key%05d", (i*7919)%1000))
+		ref := EntryRef{PageID: storage.PageID(i + 1), DN: fmt.Sprintf("uid=user%d", i)}
+		if err := tree.Insert(key, ref); err != nil {
+			t.Fatalf("failed to insert: %v", err)
+		}
+	}
+	return tree
+}
+
+// firstLeaf returns the leftmost leaf of tree.
+func firstLeaf(t *testing.T, tree *BPlusTree) *BPlusNode {
+	t.Helper()
+
+	leaf, err := tree.findLeftmostLeaf()
+	if err != nil {
+		t.Fatalf("failed to find leftmost leaf: %v", err)
+	}
+	return leaf
+}
+
+// hasVerifyError reports whether errs hold target for pageID.
+func hasVerifyError(errs []storage.PageError, pageID storage.PageID, target error) bool {
+	for _, e := range errs {
+		if e.PageID == pageID && errors.Is(e, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestVerifyValidTree(t *testing.T) {
+	pm, cleanup := createTestPageManager(t)
+	defer cleanup()
+
+	tree := buildVerifyTree(t, pm)
+	if stats, _ := tree.Stats(); stats.Height < 2 {
+		t.Fatalf("expected a tree of several levels, got height %d", stats.Height)
+	}
+
+	if errs := tree.Verify(); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+}
+
+func TestVerifyKeyOrder(t *testing.T) {
+	pm, cleanup := createTestPageManager(t)
+	defer cleanup()
+
+	tree := buildVerifyTree(t, pm)
+	leaf := firstLeaf(t, tree)
+	leaf.Keys[0], leaf.Keys[len(leaf.Keys)-1] = leaf.Keys[len(leaf.Keys)-1], leaf.Keys[0]
+	if err := tree.writeNode(leaf); err != nil {
+		t.Fatalf("failed to write node: %v", err)
+	}
+
+	if errs := tree.Verify(); !hasVerifyError(errs, leaf.PageID, ErrKeyOrder) {
+		t.Errorf("expected key order error on page %d, got %v", leaf.PageID, errs)
+	}
+}
+
+func TestVerifyKeyOutOfBounds(t *testing.T) {
+	pm, cleanup := createTestPageManager(t)
+	defer cleanup()
+
+	tree := buildVerifyTree(t, pm)
+	leaf := firstLeaf(t, tree)
+	// Sorted within the leaf, but above the separator of its parent
+	leaf.Keys[len(leaf.Keys)-1] = []byte("zzz")
+	if err := tree.writeNode(leaf); err != nil {
+		t.Fatalf("failed to write node: %v", err)
+	}
+
+	if errs := tree.Verify(); !hasVerifyError(errs, leaf.PageID, ErrKeyOutOfBounds) {
+		t.Errorf("expected out of bounds error on page %d, got %v", leaf.PageID, errs)
+	}
+}
+
+func TestVerifyLeafChain(t *testing.T) {
+	pm, cleanup := createTestPageManager(t)
+	defer cleanup()
+
+	tree := buildVerifyTree(t, pm)
+	leaf := firstLeaf(t, tree)
+	leaf.Next = InvalidPageID
+	if err := tree.writeNode(leaf); err != nil {
+		t.Fatalf("failed to write node: %v", err)
+	}
+
+	if errs := tree.Verify(); !hasVerifyError(errs, leaf.PageID, ErrLeafChainBroken) {
+		t.Errorf("expected leaf chain error on page %d, got %v", leaf.PageID, errs)
+	}
+}
+
+func TestVerifyNodeRevisited(t *testing.T) {
+	pm, cleanup := createTestPageManager(t)
+	defer cleanup()
+
+	tree := buildVerifyTree(t, pm)
+	root, err := tree.readNode(tree.Root())
+	if err != nil {
+		t.Fatalf("failed to read root: %v", err)
+	}
+	if root.IsLeaf || len(root.Children) < 2 {
+		t.Fatal("expected an internal root with several children")
+	}
+	root.Children[1] = root.Children[0]
+	if err := tree.writeNode(root); err != nil {
+		t.Fatalf("failed to write node: %v", err)
+	}
+
+	if errs := tree.Verify(); !hasVerifyError(errs, root.Children[0], ErrNodeRevisited) {
+		t.Errorf("expected revisited node error on page %d, got %v", root.Children[0], errs)
+	}
+}
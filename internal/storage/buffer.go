@@ -4,6 +4,7 @@ package storage
 import (
 	"errors"
 	"sync"
+	"sync/atomic"
 )
 
 // Buffer pool errors.
@@ -45,7 +46,8 @@ func (bp *BufferPage) PinCount() int {
 
 // BufferPool manages a pool of cached pages with LRU eviction policy.
 // It provides thread-safe access to pages and ensures dirty pages are
-// written before eviction.
+// written before eviction, or queued for a background writer when
+// write-behind is enabled.
 type BufferPool struct {
 	capacity   int
 	pageSize   int
@@ -56,6 +58,23 @@ type BufferPool struct {
 
 	// Callback for flushing dirty pages before eviction
 	flushCallback func(pageID PageID, data []byte) error
+
+	// flushMu serializes flushes so that an older copy of a page queued
+	// for write-behind is never written after a newer one. It is always
+	// acquired before mu.
+	flushMu sync.Mutex
+
+	// Write-behind state, see SetWriteBehind
+	writeBehind   bool
+	maxDirtyPages int
+	pending       map[PageID]*pendingWrite
+	wbWake        chan struct{}
+	wbStop        chan struct{}
+	wbDone        chan struct{}
+
+	// hits and misses count the lookups of Get
+	hits   atomic.Uint64
+	misses atomic.Uint64
 }
 
 // NewBufferPool creates a new buffer pool with the specified capacity and page size.
@@ -73,6 +92,7 @@ func NewBufferPool(capacity int, pageSize int) *BufferPool {
 		pages:      make(map[PageID]*BufferPage),
 		lru:        NewLRUCache(),
 		dirtyPages: make(map[PageID]bool),
+		pending:    make(map[PageID]*pendingWrite),
 	}
 }
 
@@ -86,18 +106,38 @@ func (bp *BufferPool) SetFlushCallback(callback func(pageID PageID, data []byte)
 
 // Get retrieves a page from the buffer pool.
 // Returns the page and true if found, nil and false otherwise.
-// Accessing a page marks it as recently used.
+// Accessing a page marks it as recently used. A page that was evicted
+// but is still queued for write-behind is brought back into the pool,
+// since the copy on disk is stale.
 func (bp *BufferPool) Get(id PageID) (*BufferPage, bool) {
 	bp.mu.Lock()
-	defer bp.mu.Unlock()
-
 	page, exists := bp.pages[id]
-	if !exists {
+	if exists {
+		// Mark as recently accessed
+		bp.lru.Access(id)
+		bp.mu.Unlock()
+		bp.hits.Add(1)
+		return page, true
+	}
+
+	pw, queued := bp.pending[id]
+	if !queued {
+		bp.mu.Unlock()
+		bp.misses.Add(1)
 		return nil, false
 	}
 
-	// Mark as recently accessed
-	bp.lru.Access(id)
+	page, err := bp.insertLocked(id, pw.data)
+	if err != nil {
+		bp.mu.Unlock()
+		bp.misses.Add(1)
+		return nil, false
+	}
+	bp.mu.Unlock()
+	bp.hits.Add(1)
+
+	// A failed flush leaves pages queued; the next flush retries them
+	bp.boundPendingWrites()
 
 	return page, true
 }
@@ -107,16 +147,34 @@ func (bp *BufferPool) Get(id PageID) (*BufferPage, bool) {
 // Returns the buffer page.
 func (bp *BufferPool) Put(id PageID, data []byte) (*BufferPage, error) {
 	bp.mu.Lock()
-	defer bp.mu.Unlock()
 
 	// Check if page already exists
 	if page, exists := bp.pages[id]; exists {
 		// Update existing page
 		copy(page.data, data)
 		bp.lru.Access(id)
+		bp.mu.Unlock()
 		return page, nil
 	}
 
+	page, err := bp.insertLocked(id, data)
+	bp.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bp.boundPendingWrites(); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// insertLocked adds a new page to the pool, evicting a page if the pool
+// is at capacity. A queued write-behind copy of the page is superseded
+// by the new page, which stays dirty until flushed. Must be called with
+// lock held.
+func (bp *BufferPool) insertLocked(id PageID, data []byte) (*BufferPage, error) {
 	// Check if we need to evict
 	if len(bp.pages) >= bp.capacity {
 		if err := bp.evictOneLocked(); err != nil {
@@ -137,6 +195,12 @@ func (bp *BufferPool) Put(id PageID, data []byte) (*BufferPage, error) {
 		pinCount: 0,
 	}
 
+	if _, queued := bp.pending[id]; queued {
+		delete(bp.pending, id)
+		page.dirty = true
+		bp.dirtyPages[id] = true
+	}
+
 	bp.pages[id] = page
 	bp.lru.Access(id)
 
@@ -190,16 +254,25 @@ func (bp *BufferPool) MarkDirty(id PageID) error {
 	return nil
 }
 
-// FlushAll writes all dirty pages using the flush callback.
+// FlushAll writes all dirty pages using the flush callback, including
+// evicted pages still queued for write-behind. It returns once every
+// page has been written, so a checkpoint taken afterwards is complete.
 func (bp *BufferPool) FlushAll() error {
+	bp.flushMu.Lock()
+	defer bp.flushMu.Unlock()
 	bp.mu.Lock()
 	defer bp.mu.Unlock()
 
 	return bp.flushAllLocked()
 }
 
-// flushAllLocked writes all dirty pages. Must be called with lock held.
+// flushAllLocked writes all queued and dirty pages. Must be called with
+// flushMu and lock held.
 func (bp *BufferPool) flushAllLocked() error {
+	if err := bp.flushPendingLocked(); err != nil {
+		return err
+	}
+
 	if bp.flushCallback == nil {
 		// No callback set, just clear dirty flags
 		for id := range bp.dirtyPages {
@@ -230,11 +303,16 @@ func (bp *BufferPool) flushAllLocked() error {
 
 // FlushPage writes a specific dirty page using the flush callback.
 func (bp *BufferPool) FlushPage(id PageID) error {
+	bp.flushMu.Lock()
+	defer bp.flushMu.Unlock()
 	bp.mu.Lock()
 	defer bp.mu.Unlock()
 
 	page, exists := bp.pages[id]
 	if !exists {
+		if pw, queued := bp.pending[id]; queued {
+			return bp.writePendingLocked([]*pendingWrite{pw})
+		}
 		return ErrPageNotFound
 	}
 
@@ -285,8 +363,10 @@ func (bp *BufferPool) evictLocked() (PageID, []byte, bool) {
 		return 0, nil, false
 	}
 
-	// Flush if dirty
-	if page.dirty && bp.flushCallback != nil {
+	// Flush if dirty, or hand the page to the background writer
+	if page.dirty && bp.writeBehind {
+		bp.queueWriteLocked(pageID, page.data)
+	} else if page.dirty && bp.flushCallback != nil {
 		if err := bp.flushCallback(pageID, page.data); err != nil {
 			// Eviction failed due to flush error
 			return 0, nil, false
@@ -318,6 +398,8 @@ func (bp *BufferPool) evictOneLocked() error {
 // If the page is dirty, it will be flushed first.
 // If the page is pinned, an error is returned.
 func (bp *BufferPool) Remove(id PageID) error {
+	bp.flushMu.Lock()
+	defer bp.flushMu.Unlock()
 	bp.mu.Lock()
 	defer bp.mu.Unlock()
 
@@ -374,6 +456,8 @@ func (bp *BufferPool) DirtyPageCount() int {
 // Clear removes all pages from the buffer pool.
 // Dirty pages are flushed first if a callback is set.
 func (bp *BufferPool) Clear() error {
+	bp.flushMu.Lock()
+	defer bp.flushMu.Unlock()
 	bp.mu.Lock()
 	defer bp.mu.Unlock()
 
@@ -391,10 +475,13 @@ func (bp *BufferPool) Clear() error {
 
 // BufferPoolStats contains statistics about the buffer pool.
 type BufferPoolStats struct {
-	Capacity    int
-	Size        int
-	DirtyPages  int
-	PinnedPages int
+	Capacity      int
+	Size          int
+	DirtyPages    int
+	PinnedPages   int
+	PendingWrites int
+	Hits          uint64 // lookups that found the page in the pool
+	Misses        uint64 // lookups that did not
 }
 
 // Stats returns current statistics about the buffer pool.
@@ -410,10 +497,13 @@ func (bp *BufferPool) Stats() BufferPoolStats {
 	}
 
 	return BufferPoolStats{
-		Capacity:    bp.capacity,
-		Size:        len(bp.pages),
-		DirtyPages:  len(bp.dirtyPages),
-		PinnedPages: pinnedCount,
+		Capacity:      bp.capacity,
+		Size:          len(bp.pages),
+		DirtyPages:    len(bp.dirtyPages),
+		PinnedPages:   pinnedCount,
+		PendingWrites: len(bp.pending),
+		Hits:          bp.hits.Load(),
+		Misses:        bp.misses.Load(),
 	}
 }
 
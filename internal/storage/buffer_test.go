@@ -1053,3 +1053,162 @@ func TestBufferPoolEvictReturnsData(t *testing.T) {
 		t.Error("Evicted data mismatch")
 	}
 }
+
+// =============================================================================
+// Write-Behind Tests
+// =============================================================================
+
+func TestBufferPoolWriteBehindDefersEviction(t *testing.T) {
+	bp := NewBufferPool(1, PageSize)
+
+	gate := make(chan struct{})
+	var writes int32
+	bp.SetFlushCallback(func(pageID PageID, data []byte) error {
+		<-gate
+		atomic.AddInt32(&writes, 1)
+		return nil
+	})
+	bp.SetWriteBehind(true, 100)
+
+	data := make([]byte, PageSize)
+	data[0] = 0xAB
+	bp.Put(1, data)
+	bp.MarkDirty(1)
+
+	// Evicting page 1 must not wait for the blocked write
+	if _, err := bp.Put(2, nil); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if got := bp.Stats().PendingWrites; got != 1 {
+		t.Errorf("PendingWrites = %d, want 1", got)
+	}
+
+	// The queued copy is newer than disk, so Get must bring it back
+	page, ok := bp.Get(1)
+	if !ok {
+		t.Fatal("Get should return the queued page")
+	}
+	if page.Data()[0] != 0xAB || !page.IsDirty() {
+		t.Error("Requeued page should keep its data and stay dirty")
+	}
+
+	close(gate)
+	bp.SetWriteBehind(false, 0)
+
+	if bp.WriteBehindEnabled() {
+		t.Error("Write-behind should be disabled")
+	}
+	if got := bp.Stats().PendingWrites; got != 0 {
+		t.Errorf("PendingWrites after disable = %d, want 0", got)
+	}
+}
+
+func TestBufferPoolWriteBehindSortedBatch(t *testing.T) {
+	bp := NewBufferPool(1, PageSize)
+
+	gate := make(chan struct{})
+	entered := make(chan struct{})
+	var mu sync.Mutex
+	var order []PageID
+	bp.SetFlushCallback(func(pageID PageID, data []byte) error {
+		mu.Lock()
+		order = append(order, pageID)
+		first := len(order) == 1
+		mu.Unlock()
+		if first {
+			close(entered)
+			<-gate
+		}
+		return nil
+	})
+	bp.SetWriteBehind(true, 100)
+
+	put := func(id PageID) {
+		bp.Put(id, nil)
+		bp.MarkDirty(id)
+	}
+
+	// The writer takes page 10 and blocks while 7, 3 and 5 are queued
+	put(10)
+	put(7)
+	<-entered
+	put(3)
+	put(5)
+	put(99)
+
+	close(gate)
+	if err := bp.FlushAll(); err != nil {
+		t.Fatalf("FlushAll failed: %v", err)
+	}
+	bp.SetWriteBehind(false, 0)
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []PageID{10, 3, 5, 7, 99}
+	if len(order) != len(want) {
+		t.Fatalf("write order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("write order = %v, want %v", order, want)
+		}
+	}
+}
+
+func TestBufferPoolWriteBehindMaxDirtyPages(t *testing.T) {
+	bp := NewBufferPool(1, PageSize)
+
+	bp.SetFlushCallback(func(pageID PageID, data []byte) error {
+		return nil
+	})
+	bp.SetWriteBehind(true, 3)
+	defer bp.SetWriteBehind(false, 0)
+
+	for i := PageID(1); i <= 20; i++ {
+		bp.Put(i, nil)
+		bp.MarkDirty(i)
+
+		if got := bp.Stats().PendingWrites; got >= 3 {
+			t.Fatalf("PendingWrites = %d after Put(%d), want < 3", got, i)
+		}
+	}
+}
+
+func TestBufferPoolWriteBehindFlushAll(t *testing.T) {
+	bp := NewBufferPool(2, PageSize)
+
+	var mu sync.Mutex
+	written := make(map[PageID]byte)
+	bp.SetFlushCallback(func(pageID PageID, data []byte) error {
+		mu.Lock()
+		written[pageID] = data[0]
+		mu.Unlock()
+		return nil
+	})
+	bp.SetWriteBehind(true, 100)
+	defer bp.SetWriteBehind(false, 0)
+
+	for i := PageID(1); i <= 10; i++ {
+		data := make([]byte, PageSize)
+		data[0] = byte(i)
+		bp.Put(i, data)
+		bp.MarkDirty(i)
+	}
+
+	if err := bp.FlushAll(); err != nil {
+		t.Fatalf("FlushAll failed: %v", err)
+	}
+
+	stats := bp.Stats()
+	if stats.PendingWrites != 0 || stats.DirtyPages != 0 {
+		t.Errorf("after FlushAll pending = %d, dirty = %d, want 0", stats.PendingWrites, stats.DirtyPages)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i := PageID(1); i <= 10; i++ {
+		if written[i] != byte(i) {
+			t.Errorf("page %d written with %d, want %d", i, written[i], i)
+		}
+	}
+}
@@ -140,6 +140,30 @@ func TestReadFileStaleTxID(t *testing.T) {
 	}
 }
 
+func TestReadFileUpTo(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.cache")
+
+	data := []byte("test")
+	if err := WriteFile(path, TypeRadix, data, 10, 100); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	// Older cache is accepted
+	got, header, err := ReadFileUpTo(path, TypeRadix, 200)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if string(got) != "test" || header.LastTxID != 100 {
+		t.Errorf("unexpected data %q at txID %d", got, header.LastTxID)
+	}
+
+	// Cache newer than the limit is rejected
+	if _, _, err := ReadFileUpTo(path, TypeRadix, 50); err != ErrStaleTxID {
+		t.Errorf("expected ErrStaleTxID, got: %v", err)
+	}
+}
+
 func TestReadFileWrongType(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "test.cache")
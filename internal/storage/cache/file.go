@@ -4,6 +4,8 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
 )
 
 // WriteFile writes cache data to a file atomically.
@@ -52,13 +54,32 @@ func WriteFile(path string, cacheType uint8, data []byte, entryCount, lastTxID u
 		return err
 	}
 
-	// Atomic rename
-	return os.Rename(tmpPath, path)
+	// Atomic rename, made durable with the directory
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	return storage.SyncDir(dir)
 }
 
 // ReadFile reads cache data from a file.
 // Returns the data and header if valid, or an error if cache is missing/stale/corrupt.
 func ReadFile(path string, expectedType uint8, expectedTxID uint64) ([]byte, *Header, error) {
+	return readFile(path, func(h *Header) error {
+		return h.Validate(expectedType, expectedTxID)
+	})
+}
+
+// ReadFileUpTo reads cache data from a file written at or before maxTxID.
+// The caller is responsible for replaying changes made after the header's
+// LastTxID.
+func ReadFileUpTo(path string, expectedType uint8, maxTxID uint64) ([]byte, *Header, error) {
+	return readFile(path, func(h *Header) error {
+		return h.ValidateUpTo(expectedType, maxTxID)
+	})
+}
+
+// readFile reads and verifies a cache file using the given header check.
+func readFile(path string, validate func(*Header) error) ([]byte, *Header, error) {
 	// Open file
 	f, err := os.Open(path)
 	if err != nil {
@@ -84,7 +105,7 @@ func ReadFile(path string, expectedType uint8, expectedTxID uint64) ([]byte, *He
 	}
 
 	// Validate header fields
-	if err := header.Validate(expectedType, expectedTxID); err != nil {
+	if err := validate(header); err != nil {
 		return nil, nil, err
 	}
 
@@ -111,6 +111,34 @@ func (h *Header) Deserialize(buf []byte) error {
 
 // Validate validates the header.
 func (h *Header) Validate(expectedType uint8, expectedTxID uint64) error {
+	if err := h.validateFormat(expectedType); err != nil {
+		return err
+	}
+
+	if h.LastTxID != expectedTxID {
+		return ErrStaleTxID
+	}
+
+	return nil
+}
+
+// ValidateUpTo validates the header, accepting any cache written at or
+// before maxTxID. It is used for snapshots that are brought up to date by
+// replaying the WAL.
+func (h *Header) ValidateUpTo(expectedType uint8, maxTxID uint64) error {
+	if err := h.validateFormat(expectedType); err != nil {
+		return err
+	}
+
+	if h.LastTxID > maxTxID {
+		return ErrStaleTxID
+	}
+
+	return nil
+}
+
+// validateFormat checks the magic, version and cache type.
+func (h *Header) validateFormat(expectedType uint8) error {
 	if string(h.Magic[:]) != Magic {
 		return ErrInvalidMagic
 	}
@@ -123,10 +151,6 @@ func (h *Header) Validate(expectedType uint8, expectedTxID uint64) error {
 		return ErrInvalidType
 	}
 
-	if h.LastTxID != expectedTxID {
-		return ErrStaleTxID
-	}
-
 	return nil
 }
 
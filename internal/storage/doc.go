@@ -11,7 +11,7 @@
 //   - Radix tree indexing for DN hierarchy traversal
 //   - B+ tree indexing for attribute-based searches
 //   - Memory-mapped I/O for efficient reads
-//   - Buffer pool with LRU eviction
+//   - Buffer pool with LRU eviction and optional write-behind
 //
 // # Storage Engine Interface
 //
@@ -105,4 +105,23 @@
 //	if err := iter.Error(); err != nil {
 //	    return err
 //	}
+//
+// Index lookups yield EntryRef values in storage order. RefIterator inputs
+// are combined with IntersectionIterator for AND filters, UnionIterator for
+// OR filters and MergeIterator for scans over several sources:
+//
+//	refs := storage.IntersectionIterator(
+//	    storage.SliceRefIterator(mailRefs),
+//	    storage.SliceRefIterator(departmentRefs),
+//	)
+//	defer refs.Close()
+//
+// # Platforms
+//
+// The operating system specific parts are behind build tags: FileLock
+// uses flock on Unix and LockFileEx on Windows, MmapManager maps files with
+// mmap or file mapping objects, and SyncDir fsyncs a directory on Unix and
+// flushes its handle with FlushFileBuffers on Windows. Elsewhere locks are
+// no-ops, SyncDir does nothing and MmapManager reads the file into memory,
+// writing it back on Sync. Run make vet-cross to check the other builds.
 package storage
@@ -1,7 +1,13 @@
 // Package storage provides the core storage engine components for ObaDB.
 package storage
 
-import "strings"
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/trace"
+)
 
 // Scope represents the LDAP search scope.
 type Scope int
@@ -123,6 +129,75 @@ type FilterMatcher interface {
 	Match(entry *Entry) bool
 }
 
+// IndexTerm is an equality or presence assertion that an entry must satisfy
+// for a filter to match, and that may be answered from an attribute index.
+type IndexTerm struct {
+	// Attribute is the attribute name.
+	Attribute string
+
+	// Value is the asserted value, or nil for a presence assertion.
+	Value []byte
+}
+
+// IndexTermProvider is implemented by a FilterMatcher that can list the
+// index terms every matching entry satisfies, such as the equality and
+// presence children of an AND filter. The engine may use them to narrow the
+// candidate set; Match is still applied to every candidate.
+type IndexTermProvider interface {
+	IndexTerms() []IndexTerm
+}
+
+// SpanStarter is implemented by a FilterMatcher of a traced search. The
+// engine starts the spans of its search phases, such as the index lookup,
+// as children of the search span.
+type SpanStarter interface {
+	StartSpan(name string, attrs ...trace.Attribute) trace.Span
+}
+
+// EqualityIndexReader is implemented by a StorageEngine that can tell from
+// an equality index whether an entry holds a value, without reading the
+// entry.
+type EqualityIndexReader interface {
+	// HasIndexedValue reports whether the version of entry dn visible to
+	// tx holds value for attribute, according to the attribute's equality
+	// index. The value must match the stored value exactly. A false result
+	// is not conclusive: the attribute may have no equality index, or the
+	// index may not reflect the visible version.
+	HasIndexedValue(tx interface{}, dn, attribute string, value []byte) (bool, error)
+}
+
+// SearchEstimator is implemented by a StorageEngine that can estimate how
+// many entries a filtered search examines without running it.
+type SearchEstimator interface {
+	// EstimateSearch returns the number of candidate entries a
+	// SearchByFilter for baseDN and f would examine: the size of the index
+	// candidate set when indexes narrow the search, or the number of
+	// entries in the subtree of baseDN otherwise. f may be nil.
+	EstimateSearch(baseDN string, f interface{}) int
+}
+
+// IndexRebuildProgress reports how far an index rebuild has come.
+type IndexRebuildProgress struct {
+	// EntriesScanned is the number of entries read
+	EntriesScanned int
+	// EntriesIndexed is the number of entries with a value for the attribute
+	EntriesIndexed int
+	// KeysWritten is the number of keys written to the new index
+	KeysWritten int
+	// Elapsed is the time since the rebuild started
+	Elapsed time.Duration
+}
+
+// IndexRebuilder is implemented by a StorageEngine that can rebuild an
+// attribute index while it serves requests.
+type IndexRebuilder interface {
+	// RebuildIndex builds a new index for attribute from the entries and
+	// replaces the current one once it has caught up with the changes
+	// committed meanwhile. The current index serves lookups until then.
+	// progress, if not nil, is called as entries are scanned.
+	RebuildIndex(ctx context.Context, attribute string, progress func(IndexRebuildProgress)) (IndexRebuildProgress, error)
+}
+
 // Iterator provides iteration over search results.
 type Iterator interface {
 	// Next advances to the next entry and returns true if successful.
@@ -158,17 +233,39 @@ type EngineStats struct {
 	// ActiveTransactions is the number of active transactions.
 	ActiveTransactions int
 
+	// CommittedTransactions and RolledBackTransactions count the
+	// transactions finished since the database was opened.
+	CommittedTransactions  uint64
+	RolledBackTransactions uint64
+
 	// BufferPoolSize is the number of pages in the buffer pool.
 	BufferPoolSize int
 
 	// DirtyPages is the number of dirty pages in the buffer pool.
 	DirtyPages int
 
+	// BufferPoolHits and BufferPoolMisses count the page lookups in the
+	// buffer pool that found and did not find the page.
+	BufferPoolHits   uint64
+	BufferPoolMisses uint64
+
 	// WALSize is the current WAL size in bytes.
 	WALSize uint64
 
 	// LastCheckpointLSN is the LSN of the last checkpoint.
 	LastCheckpointLSN uint64
+
+	// AdaptiveIndexHitRatio is the fraction of DN lookups served by the
+	// adaptive hash index instead of the DN index.
+	AdaptiveIndexHitRatio float64
+
+	// CorruptPages is the number of pages quarantined after failing
+	// checksum verification. Repair them with fsck or restore a backup.
+	CorruptPages uint64
+
+	// FreeListFragmentation is the fragmentation of the free page list,
+	// from 0.0 (none) to 1.0 (fully fragmented). Compact resets it.
+	FreeListFragmentation float64
 }
 
 // StorageEngine defines the interface for the ObaDB storage engine.
@@ -207,6 +304,12 @@ type StorageEngine interface {
 	// The tx parameter should be a *tx.Transaction.
 	HasChildren(tx interface{}, dn string) (bool, error)
 
+	// CountChildren returns the number of immediate children of the entry
+	// at the given DN, counting no further than limit. A limit of zero or
+	// less counts all children.
+	// The tx parameter should be a *tx.Transaction.
+	CountChildren(tx interface{}, dn string, limit int) (int, error)
+
 	// Search operations
 
 	// SearchByDN searches for entries by DN with the given scope.
@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/btree"
+	"github.com/KilimcininKorOglu/oba/internal/storage/index"
+)
+
+// CheckConsistency verifies that the on-disk structures of the database
+// agree with each other, as after a crash and reopen:
+//
+//   - every DN in the DN index points to a distinct data page within the
+//     data file that holds a readable entry;
+//   - every equality index has a reference to each entry for each of its
+//     values, every presence index one to each entry with the attribute,
+//     and neither refers to a DN that does not exist.
+//
+// It returns the inconsistencies found, or nil.
+func (db *ObaDB) CheckConsistency() []error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if db.closed {
+		return []error{ErrDatabaseClosed}
+	}
+
+	var problems []error
+	report := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	if db.deferredIndexer != nil {
+		if err := db.deferredIndexer.Flush(); err != nil {
+			report("failed to apply queued index updates: %v", err)
+		}
+	}
+
+	totalPages := db.pageManager.TotalPages()
+	owners := make(map[storage.PageID]string)
+	dns := make(map[string]bool)
+	var entries []*index.Entry
+
+	db.radixTree.IterateSubtree("", func(dn string, pageID storage.PageID, slotID uint16) bool {
+		dns[dn] = true
+
+		if pageID == 0 || uint64(pageID) >= totalPages {
+			report("entry %s: page %d is outside the data file (%d pages)", dn, pageID, totalPages)
+			return true
+		}
+		if owner, ok := owners[pageID]; ok {
+			report("entry %s: page %d is also used by %s", dn, pageID, owner)
+			return true
+		}
+		owners[pageID] = dn
+
+		data, err := readEntryData(db.pageManager, pageID)
+		if err == nil {
+			data, err = db.decryptData(data)
+		}
+		var entry *storage.Entry
+		if err == nil {
+			entry, err = deserializeEntry(dn, data)
+		}
+		if err != nil {
+			report("entry %s: page %d: %v", dn, pageID, err)
+			return true
+		}
+
+		entries = append(entries, &index.Entry{DN: dn, Attributes: entry.Attributes})
+		return true
+	})
+
+	for _, attr := range db.indexManager.ListIndexes() {
+		idx, ok := db.indexManager.GetIndex(attr)
+		if !ok {
+			continue
+		}
+		checkIndex(db.indexManager, idx, entries, dns, report)
+	}
+
+	return problems
+}
+
+// checkIndex reports where idx disagrees with entries, the entries of the
+// directory, whose DNs are the keys of dns.
+func checkIndex(im *index.IndexManager, idx *index.Index, entries []*index.Entry, dns map[string]bool, report func(format string, args ...interface{})) {
+	attr := idx.Attribute
+	switch idx.Type {
+	case index.IndexEquality:
+		for _, entry := range entries {
+			for _, value := range entry.GetAttributeWithOptions(attr) {
+				if len(value) == 0 {
+					continue
+				}
+				refs, err := im.Search(attr, value)
+				if err != nil {
+					report("index %s: entry %s: %v", attr, entry.DN, err)
+					break
+				}
+				if !checkIndexRefs(refs, entry.DN, dns, func(ref btree.EntryRef) {
+					report("index %s: value %q refers to missing entry %s", attr, value, ref.DN)
+				}) {
+					report("index %s: entry %s is missing for value %q", attr, entry.DN, value)
+				}
+			}
+		}
+
+	case index.IndexPresence:
+		refs, err := im.SearchPresence(attr)
+		if err != nil {
+			report("index %s: %v", attr, err)
+			return
+		}
+		checkIndexRefs(refs, "", dns, func(ref btree.EntryRef) {
+			report("index %s: presence refers to missing entry %s", attr, ref.DN)
+		})
+
+		present := make(map[string]bool, len(refs))
+		for _, ref := range refs {
+			present[normalizeDN(ref.DN)] = true
+		}
+		for _, entry := range entries {
+			if len(entry.GetAttributeWithOptions(attr)) > 0 && !present[entry.DN] {
+				report("index %s: entry %s is missing", attr, entry.DN)
+			}
+		}
+	}
+}
+
+// checkIndexRefs calls missing for each of refs whose DN is not in dns, and
+// reports whether refs include dn.
+func checkIndexRefs(refs []btree.EntryRef, dn string, dns map[string]bool, missing func(ref btree.EntryRef)) bool {
+	found := false
+	for _, ref := range refs {
+		refDN := normalizeDN(ref.DN)
+		if refDN == dn {
+			found = true
+		} else if !dns[refDN] {
+			missing(ref)
+		}
+	}
+	return found
+}
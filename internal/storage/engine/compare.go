@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"github.com/KilimcininKorOglu/oba/internal/storage/index"
+	"github.com/KilimcininKorOglu/oba/internal/storage/tx"
+)
+
+// HasIndexedValue reports whether the version of entry dn visible to
+// txnIface holds value for attribute, according to the attribute's
+// equality index.
+//
+// Index references can outlive the version they were written for, as when
+// a transaction looks up its own changes and then rolls back, so a
+// reference only counts if it points at the storage location of the
+// visible version. The check uses the
+// version store and does not read the entry's data page.
+func (db *ObaDB) HasIndexedValue(txnIface interface{}, dn, attribute string, value []byte) (bool, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if db.closed {
+		return false, ErrDatabaseClosed
+	}
+	if db.indexManager == nil {
+		return false, nil
+	}
+
+	idx, exists := db.indexManager.GetIndex(attribute)
+	if !exists || idx.Type != index.IndexEquality {
+		return false, nil
+	}
+
+	var snapshot uint64
+	var activeTxID uint64
+	if txn, ok := txnIface.(*tx.Transaction); ok && txn != nil {
+		snapshot = txn.Snapshot
+		activeTxID = txn.ID
+		txn.AddReadDN(normalizeDN(dn))
+	} else {
+		snapshot = db.snapshotManager.CurrentTimestamp()
+	}
+
+	// The transaction's own changes must be visible to the lookup
+	if err := db.applyIndexBatch(activeTxID); err != nil {
+		return false, err
+	}
+
+	if db.deferredIndexer != nil {
+		// Queued updates must be visible to the lookup.
+		if err := db.deferredIndexer.Flush(); err != nil {
+			return false, err
+		}
+	}
+
+	refs, err := db.indexManager.Search(attribute, value)
+	if err != nil {
+		return false, err
+	}
+
+	dn = normalizeDN(dn)
+	for _, ref := range refs {
+		if normalizeDN(ref.DN) != dn {
+			continue
+		}
+
+		version, err := db.versionStore.GetVisibleForTx(dn, snapshot, activeTxID)
+		if err != nil {
+			// Missing or deleted entries are left to the caller.
+			return false, nil
+		}
+		pageID, slotID := version.GetLocation()
+		return pageID == ref.PageID && slotID == ref.SlotID, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// TestHasIndexedValue tests equality index lookups for a single entry,
+// including index references left behind by a rolled-back write.
+func TestHasIndexedValue(t *testing.T) {
+	db, err := Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	populateSearchDB(t, db, 10)
+	dn := "uid=user3,ou=people,dc=example,dc=com"
+
+	tests := []struct {
+		name  string
+		dn    string
+		attr  string
+		value string
+		want  bool
+	}{
+		{"indexed value", dn, "uid", "user3", true},
+		{"attribute name case", "UID=user3,ou=people,dc=example,dc=com", "UID", "user3", true},
+		{"other entry's value", dn, "uid", "user4", false},
+		{"value case", dn, "uid", "USER3", false},
+		{"not indexed", dn, "department", "dept0", false},
+		{"missing entry", "uid=nobody,ou=people,dc=example,dc=com", "uid", "nobody", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := db.HasIndexedValue(nil, tt.dn, tt.attr, []byte(tt.value))
+			if err != nil {
+				t.Fatalf("HasIndexedValue() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("HasIndexedValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A rolled-back write leaves its index reference behind
+	txn, _ := db.Begin()
+	changed := storage.NewEntry(dn)
+	changed.SetStringAttribute("uid", "renamed")
+	if err := db.Put(txn, changed); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if got, _ := db.HasIndexedValue(txn, dn, "uid", []byte("renamed")); !got {
+		t.Error("expected the writing transaction to see its own value")
+	}
+	if err := db.Rollback(txn); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if got, _ := db.HasIndexedValue(nil, dn, "uid", []byte("renamed")); got {
+		t.Error("expected rolled-back value not to be found")
+	}
+}
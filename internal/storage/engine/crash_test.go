@@ -0,0 +1,250 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/faultfs"
+)
+
+// crashSeeds is the number of seeds run for each fault mode. A failing run
+// is reproduced with -run 'TestCrashRecovery/<mode>/seed=<seed>'.
+const crashSeeds = 16
+
+// crashState is the state of the workload entries: the cn of each entry,
+// or "" for an entry that does not exist.
+type crashState map[string]string
+
+func crashOptions() storage.EngineOptions {
+	return storage.DefaultEngineOptions().WithGCEnabled(false)
+}
+
+func crashDN(i int) string {
+	return fmt.Sprintf("uid=user%d,ou=crash,dc=example,dc=com", i)
+}
+
+// createCrashBase creates a database holding the first entries of the
+// workload and closes it cleanly.
+func createCrashBase(t *testing.T, dir string) crashState {
+	t.Helper()
+
+	db, err := Open(dir, crashOptions())
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	state := make(crashState)
+	for i := 0; i < 4; i++ {
+		cn := fmt.Sprintf("base%d", i)
+		if err := crashCommit(db, i, cn); err != nil {
+			t.Fatalf("failed to add base entry: %v", err)
+		}
+		state[crashDN(i)] = cn
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close database: %v", err)
+	}
+	return state
+}
+
+// crashCommit sets the cn of entry i, or deletes it if cn is "", in its own
+// transaction.
+func crashCommit(db *ObaDB, i int, cn string) error {
+	dn := crashDN(i)
+	txn, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if cn == "" {
+		err = db.Delete(txn, dn)
+	} else {
+		entry := storage.NewEntry(dn)
+		entry.SetStringAttribute("objectclass", "person")
+		entry.SetStringAttribute("uid", fmt.Sprintf("user%d", i))
+		entry.SetStringAttribute("cn", cn)
+		err = db.Put(txn, entry)
+	}
+	if err != nil {
+		db.Rollback(txn)
+		return err
+	}
+
+	return db.Commit(txn)
+}
+
+// runCrashWorkload runs a random workload of adds, modifies and deletes on
+// db until it fails or completes. It updates state with every acknowledged
+// commit and returns the operation in progress when the workload failed.
+func runCrashWorkload(db *ObaDB, rng *rand.Rand, state crashState) (pendingDN, pendingCN string, err error) {
+	for op := 0; op < 60; op++ {
+		i := rng.Intn(10)
+		dn := crashDN(i)
+		cn := fmt.Sprintf("cn%d", op)
+		if state[dn] != "" && rng.Intn(3) == 0 {
+			cn = ""
+		}
+
+		if err := crashCommit(db, i, cn); err != nil {
+			return dn, cn, err
+		}
+		state[dn] = cn
+	}
+	return "", "", nil
+}
+
+// countCrashWrites returns the number of writes the workload of seed makes.
+func countCrashWrites(t *testing.T, seed int64) int {
+	t.Helper()
+
+	dir := t.TempDir()
+	state := createCrashBase(t, dir)
+
+	fs := faultfs.New(faultfs.Config{CrashAfter: -1})
+	db, err := Open(dir, crashOptions().WithFileSystem(fs))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if _, _, err := runCrashWorkload(db, rand.New(rand.NewSource(seed)), state); err != nil {
+		t.Fatalf("workload failed without a crash: %v", err)
+	}
+	writes := fs.Writes()
+	db.Close()
+	return writes
+}
+
+// crashAndReopen runs the workload of seed until the fault of mode, then
+// reopens the database and checks its consistency. It returns the reopened
+// database, the acknowledged state and the operation in progress.
+func crashAndReopen(t *testing.T, mode faultfs.Mode, seed int64) (*ObaDB, crashState, crashState, string, string) {
+	t.Helper()
+
+	writes := countCrashWrites(t, seed)
+	rng := rand.New(rand.NewSource(seed))
+	crashAfter := rng.Intn(writes)
+
+	dir := t.TempDir()
+	base := createCrashBase(t, dir)
+	state := make(crashState)
+	for dn, cn := range base {
+		state[dn] = cn
+	}
+
+	fs := faultfs.New(faultfs.Config{Mode: mode, CrashAfter: crashAfter, Seed: seed})
+	db, err := Open(dir, crashOptions().WithFileSystem(fs))
+	if err != nil && !fs.Crashed() {
+		t.Fatalf("seed %d: failed to open database: %v", seed, err)
+	}
+
+	var pendingDN, pendingCN string
+	if err == nil {
+		pendingDN, pendingCN, err = runCrashWorkload(db, rand.New(rand.NewSource(seed)), state)
+		// Errors of the crash are not always wrapped
+		if err != nil && !fs.Crashed() {
+			t.Fatalf("seed %d: workload failed: %v", seed, err)
+		}
+		// The crashed database is abandoned, as by a killed process
+		fs.Crash()
+	}
+	t.Logf("seed %d: crashed after %d of %d writes", seed, crashAfter, writes)
+
+	db, err = Open(dir, crashOptions())
+	if err != nil {
+		t.Fatalf("seed %d: failed to reopen database: %v", seed, err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	for _, problem := range db.CheckConsistency() {
+		t.Errorf("seed %d: %v", seed, problem)
+	}
+
+	return db, base, state, pendingDN, pendingCN
+}
+
+// readCrashState reads the cn of each workload entry from db.
+func readCrashState(t *testing.T, db *ObaDB) crashState {
+	t.Helper()
+
+	txn, err := db.Begin()
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+	defer db.Rollback(txn)
+
+	state := make(crashState)
+	for i := 0; i < 10; i++ {
+		entry, err := db.Get(txn, crashDN(i))
+		if errors.Is(err, ErrEntryNotFound) {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", crashDN(i), err)
+		}
+		state[crashDN(i)] = string(entry.GetAttribute("cn")[0])
+	}
+	return state
+}
+
+func TestCrashRecovery(t *testing.T) {
+	seeds := crashSeeds
+	if testing.Short() {
+		seeds = 4
+	}
+
+	for _, mode := range []faultfs.Mode{faultfs.Crash, faultfs.TornWrite} {
+		t.Run(mode.String(), func(t *testing.T) {
+			for seed := int64(1); seed <= int64(seeds); seed++ {
+				t.Run(fmt.Sprintf("seed=%d", seed), func(t *testing.T) {
+					db, _, want, pendingDN, pendingCN := crashAndReopen(t, mode, seed)
+
+					got := readCrashState(t, db)
+					for i := 0; i < 10; i++ {
+						dn := crashDN(i)
+						// The commit in progress may or may not be durable
+						if dn == pendingDN && got[dn] == pendingCN {
+							continue
+						}
+						if got[dn] != want[dn] {
+							t.Errorf("seed %d: %s has cn %q, want %q", seed, dn, got[dn], want[dn])
+						}
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestCrashRecoveryFsyncLies(t *testing.T) {
+	seeds := crashSeeds
+	if testing.Short() {
+		seeds = 4
+	}
+
+	for seed := int64(1); seed <= int64(seeds); seed++ {
+		t.Run(fmt.Sprintf("seed=%d", seed), func(t *testing.T) {
+			db, base, acknowledged, _, _ := crashAndReopen(t, faultfs.FsyncLies, seed)
+
+			// Nothing was synced, so the database is back at its base
+			// state and the commits acknowledged since are lost
+			got := readCrashState(t, db)
+			for i := 0; i < 10; i++ {
+				dn := crashDN(i)
+				if got[dn] != base[dn] {
+					t.Errorf("seed %d: %s has cn %q, want base %q", seed, dn, got[dn], base[dn])
+				}
+			}
+
+			lost := 0
+			for dn, cn := range acknowledged {
+				if got[dn] != cn {
+					lost++
+				}
+			}
+			t.Logf("seed %d: %d acknowledged entries lost", seed, lost)
+		})
+	}
+}
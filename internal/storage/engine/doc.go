@@ -66,6 +66,16 @@
 //	    // Process entry
 //	}
 //
+// SearchByFilter scans the base subtree and applies the filter to every
+// entry. With SearchConfig.IndexSplitEnabled, the equality and presence
+// children of an AND filter are looked up in their indexes instead, the
+// candidate sets are intersected by storage location, and the full filter
+// is rechecked on each remaining entry:
+//
+//	db.SetSearchConfig(engine.SearchConfig{IndexSplitEnabled: true})
+//
+// The filter must implement storage.IndexTermProvider to be split.
+//
 // # Maintenance
 //
 // Perform maintenance operations:
@@ -2,9 +2,12 @@
 package engine
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/KilimcininKorOglu/oba/internal/storage"
 	"github.com/KilimcininKorOglu/oba/internal/storage/tx"
@@ -228,6 +231,61 @@ func TestPutGetDelete(t *testing.T) {
 	}
 }
 
+// TestPrettyDN tests that entries keep the DN they were stored with while
+// lookups use the normalized form.
+func TestPrettyDN(t *testing.T) {
+	db, err := Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	pretty := "UID=Alice+CN=Alice Smith,OU=Users,DC=Example,DC=Com"
+	entry := storage.NewEntry(pretty)
+	entry.SetStringAttribute("cn", "Alice Smith")
+
+	txn, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	if err := db.Put(txn, entry); err != nil {
+		t.Fatalf("Failed to put entry: %v", err)
+	}
+	if err := db.Commit(txn); err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+
+	txn, err = db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer db.Rollback(txn)
+
+	for _, dn := range []string{
+		pretty,
+		"cn=alice smith+uid=alice,ou=users,dc=example,dc=com",
+		"uid=ALICE + cn=Alice Smith, ou=users, dc=example, dc=com",
+	} {
+		got, err := db.Get(txn, dn)
+		if err != nil {
+			t.Errorf("Get(%q) error = %v", dn, err)
+			continue
+		}
+		if got.DN != pretty {
+			t.Errorf("Get(%q).DN = %q, want %q", dn, got.DN, pretty)
+		}
+	}
+
+	iter := db.SearchByDN(txn, "ou=users,dc=example,dc=com", storage.ScopeOneLevel)
+	defer iter.Close()
+	if !iter.Next() {
+		t.Fatalf("SearchByDN() found no entries, error = %v", iter.Error())
+	}
+	if got := iter.Entry().DN; got != pretty {
+		t.Errorf("SearchByDN() DN = %q, want %q", got, pretty)
+	}
+}
+
 // TestSearchByDN tests searching entries by DN with different scopes.
 func TestSearchByDN(t *testing.T) {
 	dir := t.TempDir()
@@ -325,6 +383,95 @@ func TestIndexManagement(t *testing.T) {
 	}
 }
 
+// TestIndexChangesAppliedOnCommit tests that the index changes of a
+// transaction are applied when it commits and dropped when it rolls back.
+func TestIndexChangesAppliedOnCommit(t *testing.T) {
+	db, err := Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	indexed := func(uid string) int {
+		refs, err := db.indexManager.Search("uid", []byte(uid))
+		if err != nil {
+			t.Fatalf("Search() error = %v", err)
+		}
+		return len(refs)
+	}
+
+	put := func(txn interface{}, uid string) {
+		entry := storage.NewEntry("uid=" + uid + ",dc=example,dc=com")
+		entry.SetStringAttribute("uid", uid)
+		if err := db.Put(txn, entry); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+
+	txn, _ := db.Begin()
+	put(txn, "alice")
+	put(txn, "bob")
+	if n := indexed("alice"); n != 0 {
+		t.Errorf("alice has %d index entries before commit, want 0", n)
+	}
+	if err := db.Commit(txn); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if indexed("alice") != 1 || indexed("bob") != 1 {
+		t.Errorf("index entries after commit: alice %d, bob %d, want 1 each", indexed("alice"), indexed("bob"))
+	}
+
+	txn, _ = db.Begin()
+	put(txn, "carol")
+	if err := db.Rollback(txn); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if n := indexed("carol"); n != 0 {
+		t.Errorf("carol has %d index entries after rollback, want 0", n)
+	}
+}
+
+// TestDeferredIndexing tests that committed entries become visible to index
+// lookups within the flush interval when indexing is deferred.
+func TestDeferredIndexing(t *testing.T) {
+	const interval = 50 * time.Millisecond
+
+	opts := storage.DefaultEngineOptions().
+		WithDeferredIndexing(true).
+		WithDeferredIndexFlushInterval(interval)
+	db, err := Open(t.TempDir(), opts)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	for i := 0; i < 20; i++ {
+		cn := fmt.Sprintf("user%d", i)
+		txn, _ := db.Begin()
+		if err := db.Put(txn, createTestEntry("cn="+cn+",dc=example,dc=com", "person", cn)); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+		if err := db.Commit(txn); err != nil {
+			t.Fatalf("Commit() error = %v", err)
+		}
+
+		deadline := time.Now().Add(2 * interval)
+		for {
+			refs, err := db.indexManager.Search("cn", []byte(cn))
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			if len(refs) == 1 {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%s not indexed within %v", cn, 2*interval)
+			}
+			time.Sleep(interval / 10)
+		}
+	}
+}
+
 // TestCheckpoint tests the Checkpoint operation.
 func TestCheckpoint(t *testing.T) {
 	dir := t.TempDir()
@@ -364,6 +511,85 @@ func TestCheckpoint(t *testing.T) {
 	}
 }
 
+// TestRadixSnapshotReplay tests that DN changes made after the last radix
+// snapshot are replayed from the WAL on startup.
+func TestRadixSnapshotReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	db, err := Open(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	// Enough entries that the tree no longer fits its root page
+	putEntries := func(from, to int) {
+		txIface, err := db.Begin()
+		if err != nil {
+			t.Fatalf("Failed to begin transaction: %v", err)
+		}
+		for i := from; i < to; i++ {
+			dn := fmt.Sprintf("uid=user%d,dc=example,dc=com", i)
+			if err := db.Put(txIface, createTestEntry(dn, "person", dn)); err != nil {
+				t.Fatalf("Failed to put entry: %v", err)
+			}
+		}
+		if err := db.Commit(txIface); err != nil {
+			t.Fatalf("Failed to commit transaction: %v", err)
+		}
+	}
+	putEntries(0, 300)
+
+	if err := db.Checkpoint(); err != nil {
+		t.Fatalf("Failed to checkpoint: %v", err)
+	}
+	cachePath := filepath.Join(dir, CacheDir, RadixCacheFileName)
+	snapshot, err := os.ReadFile(cachePath)
+	if err != nil {
+		t.Fatalf("Failed to read radix snapshot: %v", err)
+	}
+
+	// Changes after the snapshot: 50 inserts and one delete
+	putEntries(300, 350)
+	txIface, _ := db.Begin()
+	if err := db.Delete(txIface, "uid=user0,dc=example,dc=com"); err != nil {
+		t.Fatalf("Failed to delete entry: %v", err)
+	}
+	db.Commit(txIface)
+
+	// An uncommitted insert must not be replayed
+	abandoned, _ := db.Begin()
+	db.Put(abandoned, createTestEntry("uid=ghost,dc=example,dc=com", "person", "ghost"))
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("Failed to close database: %v", err)
+	}
+
+	// Simulate a crash after the checkpoint by restoring the older snapshot
+	if err := os.WriteFile(cachePath, snapshot, 0644); err != nil {
+		t.Fatalf("Failed to restore snapshot: %v", err)
+	}
+
+	db, err = Open(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+
+	tree := db.radixTree
+	if got := tree.EntryCount(); got != 349 {
+		t.Errorf("Expected 349 entries after replay, got %d", got)
+	}
+	if _, _, found := tree.Lookup("uid=user349,dc=example,dc=com"); !found {
+		t.Error("Entry added after the snapshot was not replayed")
+	}
+	if _, _, found := tree.Lookup("uid=user0,dc=example,dc=com"); found {
+		t.Error("Entry deleted after the snapshot is still present")
+	}
+	if _, _, found := tree.Lookup("uid=ghost,dc=example,dc=com"); found {
+		t.Error("Uncommitted entry was replayed")
+	}
+}
+
 // TestStats tests the Stats operation.
 func TestStats(t *testing.T) {
 	dir := t.TempDir()
@@ -403,6 +629,190 @@ func TestStats(t *testing.T) {
 	}
 }
 
+// TestCorruptedEntryPage tests that an entry on a damaged page cannot be
+// read after reopening, and that the page is counted as corrupt.
+func TestCorruptedEntryPage(t *testing.T) {
+	dir := t.TempDir()
+
+	db, err := Open(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	for _, uid := range []string{"alice", "bob"} {
+		txn, err := db.Begin()
+		if err != nil {
+			t.Fatalf("Failed to begin transaction: %v", err)
+		}
+		entry := storage.NewEntry("uid=" + uid + ",dc=example,dc=com")
+		entry.SetStringAttribute("cn", uid)
+		if err := db.Put(txn, entry); err != nil {
+			t.Fatalf("Failed to put entry: %v", err)
+		}
+		if err := db.Commit(txn); err != nil {
+			t.Fatalf("Failed to commit transaction: %v", err)
+		}
+	}
+	pageID, _, found := db.radixTree.Lookup(normalizeDN("uid=bob,dc=example,dc=com"))
+	if !found || pageID == 0 {
+		t.Fatal("Entry has no data page")
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Failed to close database: %v", err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, DataFileName), os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("Failed to open data file: %v", err)
+	}
+	b := make([]byte, 1)
+	offset := int64(pageID)*storage.PageSize + storage.PageHeaderSize + 5
+	if _, err := f.ReadAt(b, offset); err != nil {
+		t.Fatalf("Failed to read page: %v", err)
+	}
+	if _, err := f.WriteAt([]byte{b[0] ^ 0xFF}, offset); err != nil {
+		t.Fatalf("Failed to damage page: %v", err)
+	}
+	f.Close()
+
+	// Without the entry cache, entries are read from their pages
+	if err := os.RemoveAll(filepath.Join(dir, CacheDir)); err != nil {
+		t.Fatalf("Failed to remove caches: %v", err)
+	}
+
+	db, err = Open(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Get(nil, "uid=alice,dc=example,dc=com"); err != nil {
+		t.Errorf("Get of the intact entry failed: %v", err)
+	}
+	if _, err := db.Get(nil, "uid=bob,dc=example,dc=com"); !errors.Is(err, storage.ErrPageCorrupted) {
+		t.Errorf("Get of the damaged entry: error = %v, want ErrPageCorrupted", err)
+	}
+	if got := db.Stats().CorruptPages; got != 1 {
+		t.Errorf("CorruptPages = %d, want 1", got)
+	}
+}
+
+// TestCompactFreeList tests that Compact compacts the free page list.
+func TestCompactFreeList(t *testing.T) {
+	dir := t.TempDir()
+
+	db, err := Open(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// Free every other page of a run, highest first
+	var pages []storage.PageID
+	for i := 0; i < 200; i++ {
+		id, err := db.pageManager.AllocatePage(storage.PageTypeData)
+		if err != nil {
+			t.Fatalf("AllocatePage failed: %v", err)
+		}
+		pages = append(pages, id)
+	}
+	for i := 0; i < len(pages); i++ {
+		if i%2 == 0 {
+			continue
+		}
+		if err := db.pageManager.FreePage(pages[i]); err != nil {
+			t.Fatalf("FreePage failed: %v", err)
+		}
+	}
+	for i := len(pages) - 2; i >= 0; i -= 2 {
+		if err := db.pageManager.FreePage(pages[i]); err != nil {
+			t.Fatalf("FreePage failed: %v", err)
+		}
+	}
+
+	if got := db.Stats().FreeListFragmentation; got < 0.5 {
+		t.Fatalf("FreeListFragmentation = %v before Compact, want a fragmented list", got)
+	}
+
+	if err := db.Compact(); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+
+	if got := db.Stats().FreeListFragmentation; got >= 0.1 {
+		t.Errorf("FreeListFragmentation = %v after Compact, want < 0.1", got)
+	}
+}
+
+// TestAdaptiveIndex tests that lookups of a hot entry bypass the radix tree.
+func TestAdaptiveIndex(t *testing.T) {
+	dir := t.TempDir()
+
+	db, err := Open(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	dn := "uid=hot,dc=example,dc=com"
+	putEntry := func(cn string) {
+		txIface, err := db.Begin()
+		if err != nil {
+			t.Fatalf("Failed to begin transaction: %v", err)
+		}
+		if err := db.Put(txIface, createTestEntry(dn, "person", cn)); err != nil {
+			t.Fatalf("Failed to put entry: %v", err)
+		}
+		if err := db.Commit(txIface); err != nil {
+			t.Fatalf("Failed to commit transaction: %v", err)
+		}
+	}
+	getCN := func() string {
+		txIface, err := db.Begin()
+		if err != nil {
+			t.Fatalf("Failed to begin transaction: %v", err)
+		}
+		defer db.Rollback(txIface)
+
+		entry, err := db.Get(txIface, dn)
+		if err != nil {
+			t.Fatalf("Failed to get entry: %v", err)
+		}
+		return string(entry.GetAttribute("cn")[0])
+	}
+
+	putEntry("Hot")
+	for i := 0; i < 100; i++ {
+		getCN()
+	}
+	if db.adaptiveIndex.Len() != 1 {
+		t.Fatalf("Expected the hot entry to be cached, got %d cached locations", db.adaptiveIndex.Len())
+	}
+
+	// A write invalidates the cached location.
+	putEntry("Updated")
+	if db.adaptiveIndex.Len() != 0 {
+		t.Errorf("Expected the location to be invalidated, got %d cached locations", db.adaptiveIndex.Len())
+	}
+	db.versionStore.Clear()
+	for i := 0; i < 100; i++ {
+		if cn := getCN(); cn != "Updated" {
+			t.Fatalf("Expected cn='Updated', got %q", cn)
+		}
+	}
+
+	// Force the next lookup to disk, with the DN gone from the radix tree.
+	db.versionStore.Clear()
+	if err := db.radixTree.Delete(dn); err != nil {
+		t.Fatalf("Failed to delete from radix tree: %v", err)
+	}
+
+	if cn := getCN(); cn != "Updated" {
+		t.Errorf("Expected cn='Updated', got %q", cn)
+	}
+	if ratio := db.Stats().AdaptiveIndexHitRatio; ratio <= 0 {
+		t.Errorf("Expected a positive adaptive index hit ratio, got %v", ratio)
+	}
+}
+
 // TestRollbackChanges tests that rollback properly undoes changes.
 // TODO: This test is skipped because radix tree entries are not rolled back.
 // The radix tree is updated immediately on Put, but not reverted on Rollback.
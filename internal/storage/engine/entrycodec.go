@@ -0,0 +1,300 @@
+package engine
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// Entry format versions. Version 0 is the original layout, which has no
+// header; every later version starts with entryFormatMagic and the version
+// number. serializeEntry always writes entryFormatVersion, so entries of
+// older versions are rewritten the next time they are modified.
+//
+// Changing the layout means bumping entryFormatVersion, decoding the old
+// version in deserializeEntry, registering the entry migration to the new
+// version and adding a testdata/entry_v<N>.bin fixture.
+const (
+	entryFormatV0      = 0
+	entryFormatV1      = 1
+	entryFormatVersion = entryFormatV1
+)
+
+// entryFormatMagic starts a versioned entry. Read as the DN length of a
+// version 0 entry it would be 4 GiB, which no entry can hold.
+const entryFormatMagic = 0xFFFFFFFF
+
+// entryHeaderSize is the size of the magic and the version.
+const entryHeaderSize = 6
+
+// ErrEntryFormatTooNew is returned for an entry written by a newer version
+// of oba.
+var ErrEntryFormatTooNew = errors.New("entry was written by a newer version of oba")
+
+func init() {
+	RegisterMigration(Migration{
+		Component:   ComponentEntry,
+		From:        entryFormatV0,
+		To:          entryFormatV1,
+		Description: "add a format version to entries; old entries are rewritten when next modified",
+		Mode:        MigrationOnline,
+		Forward: func(ctx context.Context, db *ObaDB) error {
+			// deserializeEntry reads both versions, so nothing is rewritten
+			// here. Recording the version stops older binaries, which cannot
+			// read the new entries, from opening the database.
+			return nil
+		},
+	})
+}
+
+// serializeEntry serializes an entry to bytes in the newest format:
+//
+//	magic       uint32, entryFormatMagic
+//	version     uint16
+//	dnLen       uint32, followed by the DN
+//	attrCount   uint32, followed by the attributes in name order:
+//	  nameLen     uint16, followed by the name
+//	  valueCount  uint32, followed by the values:
+//	    valueLen    uint32, followed by the value
+//	sections    optional blocks up to the end of the data:
+//	  id          uint16
+//	  length      uint32, followed by the payload
+//
+// Readers skip sections they do not know, so a section can be added
+// without breaking older readers of the same version.
+func serializeEntry(entry *storage.Entry) ([]byte, error) {
+	if entry == nil {
+		return nil, ErrInvalidEntry
+	}
+
+	names := make([]string, 0, len(entry.Attributes))
+	size := entryHeaderSize + 4 + len(entry.DN) + 4
+	for name, values := range entry.Attributes {
+		names = append(names, name)
+		size += 2 + len(name) + 4
+		for _, v := range values {
+			size += 4 + len(v)
+		}
+	}
+	sort.Strings(names)
+
+	buf := make([]byte, size)
+	offset := 0
+
+	binary.LittleEndian.PutUint32(buf[offset:], entryFormatMagic)
+	offset += 4
+	binary.LittleEndian.PutUint16(buf[offset:], entryFormatVersion)
+	offset += 2
+
+	binary.LittleEndian.PutUint32(buf[offset:], uint32(len(entry.DN)))
+	offset += 4
+	copy(buf[offset:], entry.DN)
+	offset += len(entry.DN)
+
+	binary.LittleEndian.PutUint32(buf[offset:], uint32(len(names)))
+	offset += 4
+
+	for _, name := range names {
+		values := entry.Attributes[name]
+
+		binary.LittleEndian.PutUint16(buf[offset:], uint16(len(name)))
+		offset += 2
+		copy(buf[offset:], name)
+		offset += len(name)
+
+		binary.LittleEndian.PutUint32(buf[offset:], uint32(len(values)))
+		offset += 4
+
+		for _, v := range values {
+			binary.LittleEndian.PutUint32(buf[offset:], uint32(len(v)))
+			offset += 4
+			copy(buf[offset:], v)
+			offset += len(v)
+		}
+	}
+
+	return buf, nil
+}
+
+// deserializeEntry deserializes an entry from bytes of any format version.
+// The entry gets the DN stored with it, which keeps its original case, or
+// dn if none is stored.
+func deserializeEntry(dn string, data []byte) (*storage.Entry, error) {
+	version := entryDataVersion(data)
+	switch version {
+	case entryFormatV0:
+		return deserializeEntryV0(dn, data)
+	case entryFormatV1:
+		return deserializeEntryV1(dn, data[entryHeaderSize:])
+	default:
+		return nil, fmt.Errorf("%w: format version %d, supported up to %d",
+			ErrEntryFormatTooNew, version, entryFormatVersion)
+	}
+}
+
+// entryDataVersion returns the format version of serialized entry data.
+func entryDataVersion(data []byte) uint16 {
+	if len(data) < entryHeaderSize || binary.LittleEndian.Uint32(data) != entryFormatMagic {
+		return entryFormatV0
+	}
+	return binary.LittleEndian.Uint16(data[4:])
+}
+
+// deserializeEntryV1 decodes the data following the header of a version 1
+// entry.
+func deserializeEntryV1(dn string, data []byte) (*storage.Entry, error) {
+	d := entryDecoder{data: data}
+
+	entry := &storage.Entry{
+		DN:         dn,
+		Attributes: make(map[string][][]byte),
+	}
+	if stored := d.bytes(int(d.uint32())); len(stored) > 0 {
+		entry.DN = string(stored)
+	}
+
+	attrCount := d.uint32()
+	for i := uint32(0); i < attrCount && d.err == nil; i++ {
+		name := string(d.bytes(int(d.uint16())))
+		valueCount := d.uint32()
+		if d.err != nil || int(valueCount) > d.remaining()/4 {
+			return nil, ErrInvalidEntry
+		}
+
+		values := make([][]byte, 0, valueCount)
+		for j := uint32(0); j < valueCount; j++ {
+			value := d.bytes(int(d.uint32()))
+			values = append(values, append([]byte(nil), value...))
+		}
+		entry.Attributes[name] = values
+	}
+
+	// No sections are defined yet; skip those written by newer versions
+	for d.err == nil && d.remaining() > 0 {
+		d.uint16()
+		d.bytes(int(d.uint32()))
+	}
+
+	if d.err != nil {
+		return nil, d.err
+	}
+	return entry, nil
+}
+
+// entryDecoder reads the fields of a versioned entry. Reading past the end
+// of the data sets err to ErrInvalidEntry and returns zero values.
+type entryDecoder struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+func (d *entryDecoder) remaining() int {
+	return len(d.data) - d.offset
+}
+
+func (d *entryDecoder) bytes(n int) []byte {
+	if d.err != nil || n < 0 || n > d.remaining() {
+		d.err = ErrInvalidEntry
+		return nil
+	}
+	b := d.data[d.offset : d.offset+n]
+	d.offset += n
+	return b
+}
+
+func (d *entryDecoder) uint16() uint16 {
+	b := d.bytes(2)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(b)
+}
+
+func (d *entryDecoder) uint32() uint32 {
+	b := d.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+// deserializeEntryV0 decodes a version 0 entry, which has no header. It
+// stops at the first truncated field and returns what it read.
+func deserializeEntryV0(dn string, data []byte) (*storage.Entry, error) {
+	if len(data) < 8 {
+		return nil, ErrInvalidEntry
+	}
+
+	entry := &storage.Entry{
+		DN:         dn,
+		Attributes: make(map[string][][]byte),
+	}
+
+	offset := 0
+	dnLen := binary.LittleEndian.Uint32(data[offset:])
+	offset += 4
+	if dnLen > 0 && offset+int(dnLen) <= len(data) {
+		entry.DN = string(data[offset : offset+int(dnLen)])
+	}
+	offset += int(dnLen)
+
+	if offset+4 > len(data) {
+		return entry, nil
+	}
+
+	attrCount := binary.LittleEndian.Uint32(data[offset:])
+	offset += 4
+
+	for i := uint32(0); i < attrCount && offset < len(data); i++ {
+		if offset+2 > len(data) {
+			break
+		}
+
+		nameLen := binary.LittleEndian.Uint16(data[offset:])
+		offset += 2
+
+		if offset+int(nameLen) > len(data) {
+			break
+		}
+
+		name := string(data[offset : offset+int(nameLen)])
+		offset += int(nameLen)
+
+		if offset+4 > len(data) {
+			break
+		}
+
+		valueCount := binary.LittleEndian.Uint32(data[offset:])
+		offset += 4
+
+		values := make([][]byte, 0, valueCount)
+
+		for j := uint32(0); j < valueCount && offset < len(data); j++ {
+			if offset+4 > len(data) {
+				break
+			}
+
+			valueLen := binary.LittleEndian.Uint32(data[offset:])
+			offset += 4
+
+			if offset+int(valueLen) > len(data) {
+				break
+			}
+
+			value := make([]byte, valueLen)
+			copy(value, data[offset:offset+int(valueLen)])
+			offset += int(valueLen)
+
+			values = append(values, value)
+		}
+
+		entry.Attributes[name] = values
+	}
+
+	return entry, nil
+}
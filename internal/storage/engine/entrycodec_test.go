@@ -0,0 +1,194 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/tx"
+)
+
+// fixtureEntry returns the entry stored in the testdata/entry_v<N>.bin
+// fixtures.
+func fixtureEntry() *storage.Entry {
+	return &storage.Entry{
+		DN: "uid=Alice,ou=People,dc=example,dc=com",
+		Attributes: map[string][][]byte{
+			"objectclass": {[]byte("top"), []byte("person")},
+			"uid":         {[]byte("Alice")},
+			"cn":          {[]byte("Alice Smith")},
+			"jpegphoto":   {{0x00, 0xFF, 0x10, 0x80}},
+			"description": {},
+		},
+	}
+}
+
+// fixturePath returns the path of the fixture of a format version.
+func fixturePath(version int) string {
+	return filepath.Join("testdata", fmt.Sprintf("entry_v%d.bin", version))
+}
+
+// TestEntryFormatFixtures tests that entries written by every format
+// version are read back.
+func TestEntryFormatFixtures(t *testing.T) {
+	want := fixtureEntry()
+
+	for version := 0; version <= entryFormatVersion; version++ {
+		t.Run(fmt.Sprintf("v%d", version), func(t *testing.T) {
+			data, err := os.ReadFile(fixturePath(version))
+			if err != nil {
+				t.Fatalf("Missing fixture for entry format version %d: %v", version, err)
+			}
+			if got := entryDataVersion(data); int(got) != version {
+				t.Fatalf("entryDataVersion() = %d, want %d", got, version)
+			}
+
+			got, err := deserializeEntry("uid=alice,ou=people,dc=example,dc=com", data)
+			if err != nil {
+				t.Fatalf("deserializeEntry() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("deserializeEntry() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+// TestEntryFormatGuard fails if the encoding of entries changes without a
+// new format version. Bump entryFormatVersion, register its migration and
+// add a fixture for it when changing the format.
+func TestEntryFormatGuard(t *testing.T) {
+	want, err := os.ReadFile(fixturePath(entryFormatVersion))
+	if err != nil {
+		t.Fatalf("Missing fixture for entry format version %d: %v", entryFormatVersion, err)
+	}
+
+	got, err := serializeEntry(fixtureEntry())
+	if err != nil {
+		t.Fatalf("serializeEntry() error = %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("serializeEntry() output differs from %s; a format change needs a new entryFormatVersion",
+			fixturePath(entryFormatVersion))
+	}
+
+	if v := defaultMigrations.CurrentVersions()[ComponentEntry]; v != entryFormatVersion {
+		t.Errorf("Entry component version = %d, want entryFormatVersion %d", v, entryFormatVersion)
+	}
+}
+
+func TestDeserializeEntrySections(t *testing.T) {
+	data, err := serializeEntry(fixtureEntry())
+	if err != nil {
+		t.Fatalf("serializeEntry() error = %v", err)
+	}
+
+	// section appends an optional block with the given id and payload
+	section := func(id uint16, payload []byte) []byte {
+		b := binary.LittleEndian.AppendUint16(nil, id)
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(payload)))
+		return append(b, payload...)
+	}
+	join := func(parts ...[]byte) []byte {
+		return bytes.Join(parts, nil)
+	}
+
+	withSections := join(data, section(7, []byte("future")), section(8, nil))
+	got, err := deserializeEntry("", withSections)
+	if err != nil {
+		t.Fatalf("deserializeEntry() with unknown sections error = %v", err)
+	}
+	if !reflect.DeepEqual(got, fixtureEntry()) {
+		t.Errorf("deserializeEntry() with unknown sections = %+v", got)
+	}
+
+	newer := append([]byte(nil), data...)
+	binary.LittleEndian.PutUint16(newer[4:], entryFormatVersion+1)
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"truncated section", join(data, section(7, []byte("future"))[:8]), ErrInvalidEntry},
+		{"truncated section header", join(data, []byte{7}), ErrInvalidEntry},
+		{"truncated attributes", data[:len(data)-2], ErrInvalidEntry},
+		{"newer version", newer, ErrEntryFormatTooNew},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := deserializeEntry("", tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("deserializeEntry() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestEntryRewrittenOnWrite tests that an entry of an older format is read
+// and stored in the newest format when it is next modified.
+func TestEntryRewrittenOnWrite(t *testing.T) {
+	db, err := Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	old, err := os.ReadFile(fixturePath(entryFormatV0))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	dn := normalizeDN(fixtureEntry().DN)
+
+	txn, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	pageID, slotID, err := db.versionStore.CreateVersionWithLocation(txn.(*tx.Transaction), dn, old)
+	if err != nil {
+		t.Fatalf("Failed to store entry: %v", err)
+	}
+	if err := db.radixTree.Insert(dn, pageID, slotID); err != nil {
+		t.Fatalf("Failed to index entry: %v", err)
+	}
+	if err := db.Commit(txn); err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+
+	storedVersion := func() uint16 {
+		version, err := db.versionStore.GetVisible(dn, ^uint64(0))
+		if err != nil {
+			t.Fatalf("Failed to read stored entry: %v", err)
+		}
+		return entryDataVersion(version.GetData())
+	}
+	if v := storedVersion(); v != entryFormatV0 {
+		t.Fatalf("Stored format version = %d, want %d", v, entryFormatV0)
+	}
+
+	entry, err := db.Get(nil, dn)
+	if err != nil {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	entry.SetStringAttribute("description", "rewritten")
+
+	txn, err = db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	if err := db.Put(txn, entry); err != nil {
+		t.Fatalf("Failed to put entry: %v", err)
+	}
+	if err := db.Commit(txn); err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+
+	if v := storedVersion(); v != entryFormatVersion {
+		t.Errorf("Stored format version after a write = %d, want %d", v, entryFormatVersion)
+	}
+}
@@ -0,0 +1,377 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/mvcc"
+	"github.com/KilimcininKorOglu/oba/internal/storage/tx"
+)
+
+// The tests in this file pin down the isolation level of ObaDB by running
+// the classic anomalies with a fixed interleaving of two transactions. A
+// change in any outcome is a change of the isolation level and must be
+// documented in the tx package.
+
+const isolationBase = "ou=oncall,dc=example,dc=com"
+
+// openIsolationDB opens a database with the entries alice and bob below
+// isolationBase, both on call.
+func openIsolationDB(t *testing.T) *ObaDB {
+	t.Helper()
+	db, err := Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	txn, _ := db.Begin()
+	for _, entry := range []*storage.Entry{
+		createTestEntry(isolationBase, "organizationalUnit", "oncall"),
+		onCallEntry("alice", "TRUE"),
+		onCallEntry("bob", "TRUE"),
+	} {
+		if err := db.Put(txn, entry); err != nil {
+			t.Fatalf("Put(%s) error = %v", entry.DN, err)
+		}
+	}
+	if err := db.Commit(txn); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	return db
+}
+
+func onCallEntry(uid, onCall string) *storage.Entry {
+	entry := storage.NewEntry("uid=" + uid + "," + isolationBase)
+	entry.SetStringAttribute("uid", uid)
+	entry.SetStringAttribute("description", onCall)
+	return entry
+}
+
+// onCall returns the description of uid as seen by txn.
+func onCall(t *testing.T, db *ObaDB, txn interface{}, uid string) string {
+	t.Helper()
+	entry, err := db.Get(txn, "uid="+uid+","+isolationBase)
+	if err != nil {
+		t.Fatalf("Get(%s) error = %v", uid, err)
+	}
+	return string(entry.Attributes["description"][0])
+}
+
+func begin(t *testing.T, db *ObaDB, opts tx.TxOptions) *tx.Transaction {
+	t.Helper()
+	txn, err := db.BeginWithOptions(opts)
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	return txn.(*tx.Transaction)
+}
+
+// TestIsolationDirtyRead tests that uncommitted changes are invisible to
+// other transactions.
+func TestIsolationDirtyRead(t *testing.T) {
+	db := openIsolationDB(t)
+
+	t1 := begin(t, db, tx.TxOptions{})
+	t2 := begin(t, db, tx.TxOptions{})
+	if err := db.Put(t1, onCallEntry("alice", "FALSE")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	if got := onCall(t, db, t2, "alice"); got != "TRUE" {
+		t.Errorf("concurrent transaction read %q, want the committed TRUE", got)
+	}
+	if got := onCall(t, db, nil, "alice"); got != "TRUE" {
+		t.Errorf("read without transaction returned %q, want the committed TRUE", got)
+	}
+	if got := onCall(t, db, t1, "alice"); got != "FALSE" {
+		t.Errorf("writer read %q, want its own FALSE", got)
+	}
+
+	// An uncommitted new entry is invisible as well
+	if err := db.Put(t1, onCallEntry("carol", "TRUE")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, err := db.Get(t2, "uid=carol,"+isolationBase); !errors.Is(err, ErrEntryNotFound) {
+		t.Errorf("Get() of uncommitted entry error = %v, want ErrEntryNotFound", err)
+	}
+	if n := countIteratorResults(db.SearchByDN(t2, isolationBase, storage.ScopeSubtree)); n != 3 {
+		t.Errorf("search returned %d entries, want the 3 committed ones", n)
+	}
+
+	db.Rollback(t1)
+	db.Rollback(t2)
+}
+
+// TestIsolationNonRepeatableRead tests that a transaction keeps reading
+// the values of its snapshot when another transaction commits a change.
+func TestIsolationNonRepeatableRead(t *testing.T) {
+	db := openIsolationDB(t)
+
+	t1 := begin(t, db, tx.TxOptions{})
+	if got := onCall(t, db, t1, "alice"); got != "TRUE" {
+		t.Fatalf("first read = %q, want TRUE", got)
+	}
+
+	t2 := begin(t, db, tx.TxOptions{})
+	if err := db.Put(t2, onCallEntry("alice", "FALSE")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := db.Commit(t2); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	if got := onCall(t, db, t1, "alice"); got != "TRUE" {
+		t.Errorf("second read = %q, want TRUE from the snapshot", got)
+	}
+	if got := onCall(t, db, nil, "alice"); got != "FALSE" {
+		t.Errorf("read after commit = %q, want FALSE", got)
+	}
+	db.Rollback(t1)
+}
+
+// TestIsolationLostUpdate tests that the second of two transactions
+// updating the same entry from the same snapshot fails instead of
+// overwriting the first one's update.
+func TestIsolationLostUpdate(t *testing.T) {
+	db := openIsolationDB(t)
+
+	t1 := begin(t, db, tx.TxOptions{})
+	t2 := begin(t, db, tx.TxOptions{})
+	onCall(t, db, t1, "alice")
+	onCall(t, db, t2, "alice")
+
+	if err := db.Put(t1, onCallEntry("alice", "FALSE")); err != nil {
+		t.Fatalf("t1 Put() error = %v", err)
+	}
+	// The first writer still runs, so the second fails at once
+	if err := db.Put(t2, onCallEntry("alice", "MAYBE")); !errors.Is(err, mvcc.ErrVersionConflict) {
+		t.Fatalf("t2 Put() while t1 runs error = %v, want ErrVersionConflict", err)
+	}
+	if err := db.Commit(t1); err != nil {
+		t.Fatalf("t1 Commit() error = %v", err)
+	}
+	// The first writer committed after the snapshot of the second
+	if err := db.Put(t2, onCallEntry("alice", "MAYBE")); !errors.Is(err, mvcc.ErrVersionConflict) {
+		t.Fatalf("t2 Put() after t1 commit error = %v, want ErrVersionConflict", err)
+	}
+	if err := db.Delete(t2, "uid=alice,"+isolationBase); !errors.Is(err, mvcc.ErrVersionConflict) {
+		t.Fatalf("t2 Delete() after t1 commit error = %v, want ErrVersionConflict", err)
+	}
+	db.Rollback(t2)
+
+	if got := onCall(t, db, nil, "alice"); got != "FALSE" {
+		t.Errorf("alice = %q, want t1's FALSE", got)
+	}
+
+	// A transaction that began after the commit can update the entry
+	t3 := begin(t, db, tx.TxOptions{})
+	if err := db.Put(t3, onCallEntry("alice", "MAYBE")); err != nil {
+		t.Fatalf("t3 Put() error = %v", err)
+	}
+	if err := db.Commit(t3); err != nil {
+		t.Fatalf("t3 Commit() error = %v", err)
+	}
+}
+
+// TestIsolationPhantom tests that a subtree search keeps returning the
+// entries of the snapshot when another transaction adds an entry below
+// the search base.
+func TestIsolationPhantom(t *testing.T) {
+	db := openIsolationDB(t)
+
+	t1 := begin(t, db, tx.TxOptions{})
+	if n := countIteratorResults(db.SearchByDN(t1, isolationBase, storage.ScopeSubtree)); n != 3 {
+		t.Fatalf("first search returned %d entries, want 3", n)
+	}
+
+	t2 := begin(t, db, tx.TxOptions{})
+	if err := db.Put(t2, onCallEntry("carol", "TRUE")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := db.Commit(t2); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	if n := countIteratorResults(db.SearchByDN(t1, isolationBase, storage.ScopeSubtree)); n != 3 {
+		t.Errorf("second search returned %d entries, want 3 from the snapshot", n)
+	}
+	if n := countIteratorResults(db.SearchByDN(nil, isolationBase, storage.ScopeSubtree)); n != 4 {
+		t.Errorf("search after commit returned %d entries, want 4", n)
+	}
+	db.Rollback(t1)
+}
+
+// TestIsolationSearchAfterDelete pins down the one place where ObaDB is
+// weaker than snapshot isolation: a delete removes the DN from the radix
+// tree at once, so searches of other transactions stop returning the
+// entry before the delete commits, while Get still finds it.
+func TestIsolationSearchAfterDelete(t *testing.T) {
+	db := openIsolationDB(t)
+
+	t1 := begin(t, db, tx.TxOptions{})
+	t2 := begin(t, db, tx.TxOptions{})
+	if err := db.Delete(t2, "uid=bob,"+isolationBase); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if n := countIteratorResults(db.SearchByDN(t1, isolationBase, storage.ScopeSubtree)); n != 2 {
+		t.Errorf("search returned %d entries, want 2 without the deleted bob", n)
+	}
+	if got := onCall(t, db, t1, "bob"); got != "TRUE" {
+		t.Errorf("bob = %q, want TRUE from the snapshot", got)
+	}
+	db.Rollback(t1)
+	db.Rollback(t2)
+}
+
+// writeSkew runs two transactions that each check that both alice and
+// bob are on call and then take a different one of them off call. It
+// returns the commit errors of both.
+func writeSkew(t *testing.T, db *ObaDB, opts tx.TxOptions) (error, error) {
+	t.Helper()
+	t1 := begin(t, db, opts)
+	t2 := begin(t, db, opts)
+	for _, txn := range []*tx.Transaction{t1, t2} {
+		if onCall(t, db, txn, "alice") != "TRUE" || onCall(t, db, txn, "bob") != "TRUE" {
+			t.Fatal("alice and bob should both be on call")
+		}
+	}
+	if err := db.Put(t1, onCallEntry("alice", "FALSE")); err != nil {
+		t.Fatalf("t1 Put() error = %v", err)
+	}
+	if err := db.Put(t2, onCallEntry("bob", "FALSE")); err != nil {
+		t.Fatalf("t2 Put() error = %v", err)
+	}
+
+	err1 := db.Commit(t1)
+	err2 := db.Commit(t2)
+	if err2 != nil {
+		db.Rollback(t2)
+	}
+	return err1, err2
+}
+
+// TestIsolationWriteSkew tests that snapshot isolation allows write skew:
+// two transactions with disjoint writes both commit although each one's
+// decision was based on a value the other changed.
+func TestIsolationWriteSkew(t *testing.T) {
+	db := openIsolationDB(t)
+
+	err1, err2 := writeSkew(t, db, tx.TxOptions{})
+	if err1 != nil || err2 != nil {
+		t.Fatalf("Commit() errors = %v, %v, want both to commit", err1, err2)
+	}
+	if onCall(t, db, nil, "alice") != "FALSE" || onCall(t, db, nil, "bob") != "FALSE" {
+		t.Error("both alice and bob should be off call after the write skew")
+	}
+}
+
+// TestIsolationSerializable tests that serializable transactions fail to
+// commit when data they read changed after their snapshot.
+func TestIsolationSerializable(t *testing.T) {
+	t.Run("WriteSkew", func(t *testing.T) {
+		db := openIsolationDB(t)
+
+		err1, err2 := writeSkew(t, db, tx.TxOptions{Serializable: true})
+		if err1 != nil {
+			t.Fatalf("t1 Commit() error = %v", err1)
+		}
+		if !errors.Is(err2, tx.ErrSerializationFailure) {
+			t.Fatalf("t2 Commit() error = %v, want ErrSerializationFailure", err2)
+		}
+		if onCall(t, db, nil, "bob") != "TRUE" {
+			t.Error("bob should still be on call")
+		}
+	})
+
+	t.Run("Phantom", func(t *testing.T) {
+		db := openIsolationDB(t)
+
+		t1 := begin(t, db, tx.TxOptions{Serializable: true})
+		countIteratorResults(db.SearchByDN(t1, isolationBase, storage.ScopeSubtree))
+
+		t2 := begin(t, db, tx.TxOptions{})
+		if err := db.Put(t2, onCallEntry("carol", "TRUE")); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+		if err := db.Commit(t2); err != nil {
+			t.Fatalf("Commit() error = %v", err)
+		}
+
+		if err := db.Put(t1, createTestEntry("cn=summary,dc=example,dc=com", "device", "summary")); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+		if err := db.Commit(t1); !errors.Is(err, tx.ErrSerializationFailure) {
+			t.Fatalf("Commit() error = %v, want ErrSerializationFailure", err)
+		}
+		db.Rollback(t1)
+	})
+
+	t.Run("UnrelatedChange", func(t *testing.T) {
+		db := openIsolationDB(t)
+
+		t1 := begin(t, db, tx.TxOptions{Serializable: true})
+		onCall(t, db, t1, "alice")
+
+		t2 := begin(t, db, tx.TxOptions{})
+		if err := db.Put(t2, onCallEntry("bob", "FALSE")); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+		if err := db.Commit(t2); err != nil {
+			t.Fatalf("Commit() error = %v", err)
+		}
+
+		if err := db.Put(t1, onCallEntry("alice", "FALSE")); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+		if err := db.Commit(t1); err != nil {
+			t.Fatalf("Commit() error = %v, want success", err)
+		}
+	})
+}
+
+// TestIsolationCommitBarrier tests the serializable check when two
+// commits race: t1 enters its commit first but is held back until t2 has
+// committed, so t1 is validated against t2's changes.
+func TestIsolationCommitBarrier(t *testing.T) {
+	db := openIsolationDB(t)
+
+	t1 := begin(t, db, tx.TxOptions{Serializable: true})
+	t2 := begin(t, db, tx.TxOptions{Serializable: true})
+	for _, txn := range []*tx.Transaction{t1, t2} {
+		onCall(t, db, txn, "alice")
+		onCall(t, db, txn, "bob")
+	}
+	if err := db.Put(t1, onCallEntry("alice", "FALSE")); err != nil {
+		t.Fatalf("t1 Put() error = %v", err)
+	}
+	if err := db.Put(t2, onCallEntry("bob", "FALSE")); err != nil {
+		t.Fatalf("t2 Put() error = %v", err)
+	}
+
+	reached := make(chan struct{})
+	release := make(chan struct{})
+	db.txManager.SetCommitBarrier(func(txn *tx.Transaction) {
+		if txn.ID == t1.ID {
+			close(reached)
+			<-release
+		}
+	})
+	defer db.txManager.SetCommitBarrier(nil)
+
+	result := make(chan error, 1)
+	go func() { result <- db.Commit(t1) }()
+	<-reached
+
+	if err := db.Commit(t2); err != nil {
+		t.Fatalf("t2 Commit() error = %v", err)
+	}
+	close(release)
+
+	if err := <-result; !errors.Is(err, tx.ErrSerializationFailure) {
+		t.Fatalf("t1 Commit() error = %v, want ErrSerializationFailure", err)
+	}
+	db.Rollback(t1)
+}
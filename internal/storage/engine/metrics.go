@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// StatsCollectorName is the name a StatsCollector is registered under.
+const StatsCollectorName = "obadb"
+
+// StatsCollector is a metrics.Collector that exposes the storage engine
+// statistics of an ObaDB as the obadb_* metric families. The statistics
+// are read once per scrape with Stats, and the index entry counts with
+// IndexEntryCounts.
+type StatsCollector struct {
+	db *ObaDB
+}
+
+// NewStatsCollector creates a collector for db.
+func NewStatsCollector(db *ObaDB) *StatsCollector {
+	return &StatsCollector{db: db}
+}
+
+// Name implements metrics.Collector. The collector writes several metric
+// families, all prefixed with the name.
+func (c *StatsCollector) Name() string {
+	return StatsCollectorName
+}
+
+// WriteText implements metrics.Collector.
+func (c *StatsCollector) WriteText(w io.Writer) error {
+	stats := c.db.Stats()
+	bw := bufio.NewWriter(w)
+
+	writeStatsFamily(bw, "obadb_pages_total", "Number of pages in the database file.", "gauge", stats.TotalPages)
+	writeStatsFamily(bw, "obadb_free_pages", "Number of free pages in the database file.", "gauge", stats.FreePages)
+	writeStatsFamily(bw, "obadb_buffer_pool_hits_total", "Total number of page lookups served by the buffer pool.", "counter", stats.BufferPoolHits)
+	writeStatsFamily(bw, "obadb_buffer_pool_misses_total", "Total number of page lookups missing the buffer pool.", "counter", stats.BufferPoolMisses)
+	writeStatsFamily(bw, "obadb_wal_size_bytes", "Size of the write-ahead log in bytes.", "gauge", stats.WALSize)
+	writeStatsFamily(bw, "obadb_active_transactions", "Number of open transactions.", "gauge", uint64(stats.ActiveTransactions))
+	writeStatsFamily(bw, "obadb_committed_transactions_total", "Total number of committed transactions.", "counter", stats.CommittedTransactions)
+	writeStatsFamily(bw, "obadb_rolled_back_transactions_total", "Total number of rolled back transactions.", "counter", stats.RolledBackTransactions)
+
+	indexEntries := c.db.IndexEntryCounts()
+	attrs := make([]string, 0, len(indexEntries))
+	for attr := range indexEntries {
+		attrs = append(attrs, attr)
+	}
+	sort.Strings(attrs)
+	fmt.Fprintf(bw, "# HELP obadb_index_entries Number of entries of an attribute index.\n# TYPE obadb_index_entries gauge\n")
+	for _, attr := range attrs {
+		fmt.Fprintf(bw, "obadb_index_entries{attr=%q} %d\n", attr, indexEntries[attr])
+	}
+
+	return bw.Flush()
+}
+
+// writeStatsFamily writes a metric family with a single sample.
+func writeStatsFamily(w io.Writer, name, help, typ string, value uint64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %d\n", name, help, name, typ, name, value)
+}
@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/metrics"
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// scrape writes the registry and returns the samples by metric name with
+// labels.
+func scrape(t *testing.T, reg *metrics.Registry) map[string]uint64 {
+	t.Helper()
+
+	var sb strings.Builder
+	if err := reg.WriteText(&sb); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+
+	samples := make(map[string]uint64)
+	for _, line := range strings.Split(sb.String(), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, " ")
+		if !ok {
+			t.Fatalf("malformed sample %q", line)
+		}
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			t.Fatalf("sample %q: %v", line, err)
+		}
+		samples[name] = n
+	}
+	return samples
+}
+
+func TestStatsCollector(t *testing.T) {
+	db, err := Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	for i := 0; i < 3; i++ {
+		txn, err := db.Begin()
+		if err != nil {
+			t.Fatalf("Begin() error = %v", err)
+		}
+		entry := createTestEntry(fmt.Sprintf("cn=user%d,dc=example,dc=com", i), "person", fmt.Sprintf("user%d", i))
+		if err := db.Put(txn, entry); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+		if err := db.Commit(txn); err != nil {
+			t.Fatalf("Commit() error = %v", err)
+		}
+	}
+	txn, _ := db.Begin()
+	db.Rollback(txn)
+
+	reg := metrics.NewRegistry()
+	if err := reg.Register(NewStatsCollector(db)); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	samples := scrape(t, reg)
+	stats := db.Stats()
+
+	want := map[string]uint64{
+		"obadb_pages_total":                    stats.TotalPages,
+		"obadb_free_pages":                     stats.FreePages,
+		"obadb_buffer_pool_hits_total":         stats.BufferPoolHits,
+		"obadb_buffer_pool_misses_total":       stats.BufferPoolMisses,
+		"obadb_wal_size_bytes":                 stats.WALSize,
+		"obadb_active_transactions":            uint64(stats.ActiveTransactions),
+		"obadb_committed_transactions_total":   stats.CommittedTransactions,
+		"obadb_rolled_back_transactions_total": stats.RolledBackTransactions,
+	}
+	for attr, n := range db.IndexEntryCounts() {
+		want[`obadb_index_entries{attr="`+attr+`"}`] = n
+	}
+	for name, value := range want {
+		got, ok := samples[name]
+		if !ok {
+			t.Errorf("%s missing", name)
+		} else if got != value {
+			t.Errorf("%s = %d, want %d", name, got, value)
+		}
+	}
+	if len(samples) != len(want) {
+		t.Errorf("scraped %d samples, want %d", len(samples), len(want))
+	}
+
+	if stats.CommittedTransactions < 3 || stats.RolledBackTransactions < 1 {
+		t.Errorf("committed, rolled back = %d, %d, want at least 3, 1",
+			stats.CommittedTransactions, stats.RolledBackTransactions)
+	}
+	if stats.WALSize == 0 {
+		t.Error("WALSize = 0 after writes")
+	}
+	if got := samples[`obadb_index_entries{attr="cn"}`]; got != 3 {
+		t.Errorf("cn index entries = %d, want 3", got)
+	}
+}
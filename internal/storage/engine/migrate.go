@@ -0,0 +1,585 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// Storage components whose on-disk format version is recorded in the data
+// file header. A format change registers a Migration for its component.
+const (
+	ComponentEntry = "entry"
+	ComponentWAL   = "wal"
+	ComponentIndex = "index"
+	ComponentPage  = storage.PageFormatComponent
+)
+
+// Migration file names.
+const (
+	// MigrationJournalFileName exists while migrations are being applied.
+	// Finding it at open means a migration was interrupted.
+	MigrationJournalFileName = "migrate.journal"
+
+	// MigrationBackupDir holds the copy of the database files that an
+	// interrupted offline migration is rolled back to.
+	MigrationBackupDir = "migrate.backup"
+)
+
+// Migration errors.
+var (
+	ErrInvalidMigration = errors.New("invalid migration")
+	ErrMigrationPending = errors.New("database needs an offline migration; run oba migrate")
+	ErrFormatTooNew     = errors.New("database was written by a newer version of oba")
+)
+
+// migrationFiles are the files an offline migration backs up before it runs.
+var migrationFiles = []string{DataFileName, IndexFileName, WALFileName}
+
+// MigrationMode tells whether a migration can run while the database serves
+// requests.
+type MigrationMode int
+
+// Migration modes.
+const (
+	// MigrationOffline migrations run before Open returns. The database
+	// files are backed up first, and an interrupted migration is rolled
+	// back to the backup and run again at the next open.
+	MigrationOffline MigrationMode = iota
+
+	// MigrationOnline migrations run in the background once the database
+	// is open, so the code reading the component must handle both
+	// versions until they finish. They write through transactions and must
+	// be safe to run again from the start, since an interrupted one is
+	// resumed by running it again at the next open.
+	MigrationOnline
+)
+
+// String returns the name of the mode.
+func (m MigrationMode) String() string {
+	switch m {
+	case MigrationOffline:
+		return "offline"
+	case MigrationOnline:
+		return "online"
+	default:
+		return fmt.Sprintf("MigrationMode(%d)", int(m))
+	}
+}
+
+// Migration converts the on-disk format of a component from one version to
+// the next.
+type Migration struct {
+	// Component is the storage component whose format changes
+	Component string
+	// From is the version the migration starts from
+	From uint32
+	// To is the version the migration produces, From + 1
+	To uint32
+	// Description says what the migration changes
+	Description string
+	// Mode tells whether the migration can run while serving requests
+	Mode MigrationMode
+	// Estimate returns the approximate number of bytes the migration
+	// rewrites. It runs on a read-only database and may be nil.
+	Estimate func(db *ObaDB) (int64, error)
+	// Forward applies the migration. Online migrations must return when
+	// ctx is cancelled, which happens when the database is closed.
+	Forward func(ctx context.Context, db *ObaDB) error
+}
+
+// ID returns the identifier of the migration, such as "entry/0-1".
+func (m Migration) ID() string {
+	return fmt.Sprintf("%s/%d-%d", m.Component, m.From, m.To)
+}
+
+// MigrationRegistry holds the migrations of each component in the order
+// they apply.
+type MigrationRegistry struct {
+	mu         sync.RWMutex
+	migrations []Migration
+	current    map[string]uint32
+}
+
+// NewMigrationRegistry creates an empty MigrationRegistry.
+func NewMigrationRegistry() *MigrationRegistry {
+	return &MigrationRegistry{current: make(map[string]uint32)}
+}
+
+// defaultMigrations holds the migrations registered with RegisterMigration.
+var defaultMigrations = NewMigrationRegistry()
+
+// RegisterMigration registers a migration that Open applies to databases
+// whose component is older than its To version. It is meant to be called
+// from init functions, and panics if the migration is invalid.
+func RegisterMigration(m Migration) {
+	if err := defaultMigrations.Register(m); err != nil {
+		panic(err)
+	}
+}
+
+// Register adds a migration to the registry. The migrations of a component
+// must be registered in order, each starting at the version the previous
+// one produced and the first one at version 0.
+func (r *MigrationRegistry) Register(m Migration) error {
+	if m.Component == "" || len(m.Component) > storage.ComponentNameSize {
+		return fmt.Errorf("%w: invalid component name %q", ErrInvalidMigration, m.Component)
+	}
+	if m.Forward == nil {
+		return fmt.Errorf("%w: %s has no forward function", ErrInvalidMigration, m.ID())
+	}
+	if m.Mode != MigrationOffline && m.Mode != MigrationOnline {
+		return fmt.Errorf("%w: %s has unknown mode %d", ErrInvalidMigration, m.ID(), m.Mode)
+	}
+	if m.To != m.From+1 {
+		return fmt.Errorf("%w: %s must step one version", ErrInvalidMigration, m.ID())
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if current := r.current[m.Component]; m.From != current {
+		return fmt.Errorf("%w: %s does not start at the current version %d", ErrInvalidMigration, m.ID(), current)
+	}
+	r.migrations = append(r.migrations, m)
+	r.current[m.Component] = m.To
+	return nil
+}
+
+// CurrentVersions returns the version of each component that the registered
+// migrations produce. New databases are created at these versions.
+func (r *MigrationRegistry) CurrentVersions() map[string]uint32 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	versions := make(map[string]uint32, len(r.current))
+	for component, version := range r.current {
+		versions[component] = version
+	}
+	return versions
+}
+
+// Pending returns the migrations that a database at versions needs, in the
+// order they were registered.
+func (r *MigrationRegistry) Pending(versions map[string]uint32) ([]Migration, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for component, version := range versions {
+		if version > r.current[component] {
+			return nil, fmt.Errorf("%w: %s format version %d, supported up to %d",
+				ErrFormatTooNew, component, version, r.current[component])
+		}
+	}
+
+	var pending []Migration
+	for _, m := range r.migrations {
+		if m.From >= versions[m.Component] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// splitMigrations splits pending migrations into those that must run before
+// the database opens, up to the last offline one, and the online ones that
+// follow it.
+func splitMigrations(pending []Migration) (offline, online []Migration) {
+	last := -1
+	for i, m := range pending {
+		if m.Mode == MigrationOffline {
+			last = i
+		}
+	}
+	return pending[:last+1], pending[last+1:]
+}
+
+// migrationJournal records the migrations being applied.
+type migrationJournal struct {
+	// Offline are the IDs of the migrations applied before open
+	Offline []string `json:"offline,omitempty"`
+	// Backup is set while the backup that Offline rolls back to is complete
+	Backup bool `json:"backup,omitempty"`
+	// Online is the ID of the online migration running in the background
+	Online string `json:"online,omitempty"`
+}
+
+// PlannedMigration is a pending migration reported by PlanMigrations.
+type PlannedMigration struct {
+	Migration Migration
+	// Bytes is the estimated number of bytes the migration rewrites, or
+	// -1 if it has no estimate
+	Bytes int64
+	// Interrupted is set if the migration was running when the database
+	// last stopped
+	Interrupted bool
+}
+
+// PlanMigrations returns the migrations that opening the database at path
+// would apply, without modifying it.
+func PlanMigrations(path string, opts storage.EngineOptions) ([]PlannedMigration, error) {
+	return planMigrations(path, opts, defaultMigrations)
+}
+
+func planMigrations(path string, opts storage.EngineOptions, migrations *MigrationRegistry) ([]PlannedMigration, error) {
+	versions, err := readComponentVersions(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := migrations.Pending(versions)
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	journal, err := readMigrationJournal(path)
+	if err != nil {
+		return nil, err
+	}
+	interrupted := make(map[string]bool)
+	if journal != nil {
+		for _, id := range journal.Offline {
+			interrupted[id] = true
+		}
+		interrupted[journal.Online] = true
+	}
+
+	opts.ReadOnly = true
+	opts.CreateIfNotExists = false
+	db, err := openWithMigrations(path, opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	plan := make([]PlannedMigration, len(pending))
+	for i, m := range pending {
+		plan[i] = PlannedMigration{Migration: m, Bytes: -1, Interrupted: interrupted[m.ID()]}
+		if m.Estimate != nil {
+			if plan[i].Bytes, err = m.Estimate(db); err != nil {
+				return nil, fmt.Errorf("failed to estimate migration %s: %w", m.ID(), err)
+			}
+		}
+	}
+	return plan, nil
+}
+
+// Migrate opens the database at path, applies every pending migration,
+// online ones included, and closes it. It returns the migrations applied.
+func Migrate(path string, opts storage.EngineOptions) ([]Migration, error) {
+	db, err := Open(path, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	db.migrationWG.Wait()
+	applied, migrationErr := db.appliedMigrations, db.migrationErr
+
+	if err := db.Close(); err != nil {
+		return applied, err
+	}
+	return applied, migrationErr
+}
+
+// prepareMigrations runs before the components of the database open. It
+// rolls back an interrupted offline migration, works out the pending
+// migrations, and backs up the database files if offline ones are among
+// them.
+func (db *ObaDB) prepareMigrations() error {
+	if db.migrations == nil {
+		return nil
+	}
+
+	if _, err := os.Stat(filepath.Join(db.path, DataFileName)); os.IsNotExist(err) {
+		if db.readOnly {
+			return nil
+		}
+		db.newDatabase = true
+		return nil
+	}
+
+	journal, err := readMigrationJournal(db.path)
+	if err != nil {
+		return err
+	}
+	if journal != nil && journal.Backup {
+		if db.readOnly {
+			return ErrMigrationPending
+		}
+		if err := db.rollbackMigrations(); err != nil {
+			return fmt.Errorf("failed to roll back interrupted migration: %w", err)
+		}
+	}
+
+	versions, err := readComponentVersions(db.path, db.options)
+	if err != nil {
+		return err
+	}
+	pending, err := db.migrations.Pending(versions)
+	if err != nil {
+		return err
+	}
+	offline, online := splitMigrations(pending)
+
+	if db.readOnly {
+		// Online migrations are not needed to read the database.
+		if len(offline) > 0 {
+			return ErrMigrationPending
+		}
+		return nil
+	}
+	db.offlineMigrations, db.onlineMigrations = offline, online
+
+	if len(offline) > 0 {
+		return db.backupForMigrations(offline)
+	}
+	return nil
+}
+
+// runMigrations applies the migrations worked out by prepareMigrations
+// once the components are open, and starts the online ones.
+func (db *ObaDB) runMigrations() error {
+	if db.migrations == nil || db.readOnly {
+		return nil
+	}
+
+	if db.newDatabase {
+		for component, version := range db.migrations.CurrentVersions() {
+			if err := db.pageManager.SetComponentVersion(component, version); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, m := range db.offlineMigrations {
+		if err := db.applyMigration(context.Background(), m); err != nil {
+			return err
+		}
+	}
+	if len(db.offlineMigrations) > 0 {
+		if err := db.Checkpoint(); err != nil {
+			return err
+		}
+		if err := db.finishMigrations(); err != nil {
+			return err
+		}
+	}
+
+	if len(db.onlineMigrations) == 0 {
+		// An online migration may have finished without removing its entry
+		return removeMigrationJournal(db.path)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	db.stopMigrations = cancel
+	db.migrationWG.Add(1)
+	go db.runOnlineMigrations(ctx, db.onlineMigrations)
+	return nil
+}
+
+// runOnlineMigrations applies online migrations in the background. A
+// failed or cancelled migration stops the rest; they run again at the next
+// open.
+func (db *ObaDB) runOnlineMigrations(ctx context.Context, migrations []Migration) {
+	defer db.migrationWG.Done()
+
+	for _, m := range migrations {
+		if err := writeMigrationJournal(db.path, &migrationJournal{Online: m.ID()}); err != nil {
+			db.migrationErr = err
+			return
+		}
+		if err := db.applyMigration(ctx, m); err != nil {
+			db.migrationErr = err
+			return
+		}
+	}
+	db.migrationErr = removeMigrationJournal(db.path)
+}
+
+// applyMigration runs a migration and records the version it produces.
+func (db *ObaDB) applyMigration(ctx context.Context, m Migration) error {
+	if err := m.Forward(ctx, db); err != nil {
+		return fmt.Errorf("migration %s failed: %w", m.ID(), err)
+	}
+	if err := db.pageManager.SetComponentVersion(m.Component, m.To); err != nil {
+		return err
+	}
+	db.appliedMigrations = append(db.appliedMigrations, m)
+	return nil
+}
+
+// waitMigrations stops the online migrations and waits for them to return.
+func (db *ObaDB) waitMigrations() {
+	if db.stopMigrations != nil {
+		db.stopMigrations()
+	}
+	db.migrationWG.Wait()
+}
+
+// backupForMigrations copies the database files to the backup directory
+// and journals the offline migrations, which roll back to the copy if they
+// are interrupted.
+func (db *ObaDB) backupForMigrations(migrations []Migration) error {
+	backupDir := filepath.Join(db.path, MigrationBackupDir)
+	if err := os.RemoveAll(backupDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return err
+	}
+
+	for _, name := range migrationFiles {
+		err := copyFileSync(filepath.Join(db.path, name), filepath.Join(backupDir, name))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to back up %s: %w", name, err)
+		}
+	}
+	if err := storage.SyncDir(backupDir); err != nil {
+		return err
+	}
+
+	journal := &migrationJournal{Backup: true}
+	for _, m := range migrations {
+		journal.Offline = append(journal.Offline, m.ID())
+	}
+	return writeMigrationJournal(db.path, journal)
+}
+
+// rollbackMigrations restores the database files from the backup taken
+// before an interrupted offline migration. The caches may describe the
+// migrated files, so they are removed.
+func (db *ObaDB) rollbackMigrations() error {
+	backupDir := filepath.Join(db.path, MigrationBackupDir)
+	for _, name := range migrationFiles {
+		err := copyFileSync(filepath.Join(backupDir, name), filepath.Join(db.path, name))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to restore %s: %w", name, err)
+		}
+	}
+	if err := os.RemoveAll(filepath.Join(db.path, CacheDir)); err != nil {
+		return err
+	}
+	return db.finishMigrations()
+}
+
+// finishMigrations removes the journal, then the backup it refers to.
+func (db *ObaDB) finishMigrations() error {
+	if err := removeMigrationJournal(db.path); err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(db.path, MigrationBackupDir))
+}
+
+// readComponentVersions reads the component versions from the header of
+// the data file at path.
+func readComponentVersions(path string, opts storage.EngineOptions) (map[string]uint32, error) {
+	pm, err := storage.OpenPageManager(filepath.Join(path, DataFileName), storage.Options{
+		PageSize:   opts.PageSize,
+		ReadOnly:   true,
+		FileSystem: opts.FileSystem,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer pm.Close()
+
+	return pm.ComponentVersions()
+}
+
+// readMigrationJournal reads the migration journal of the database at path.
+// It returns nil if there is none.
+func readMigrationJournal(path string) (*migrationJournal, error) {
+	data, err := os.ReadFile(filepath.Join(path, MigrationJournalFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	journal := &migrationJournal{}
+	if err := json.Unmarshal(data, journal); err != nil {
+		return nil, fmt.Errorf("failed to read migration journal: %w", err)
+	}
+	return journal, nil
+}
+
+// writeMigrationJournal replaces the migration journal of the database at
+// path, syncing it before it takes effect.
+func writeMigrationJournal(path string, journal *migrationJournal) error {
+	data, err := json.Marshal(journal)
+	if err != nil {
+		return err
+	}
+
+	journalPath := filepath.Join(path, MigrationJournalFileName)
+	tmpPath := journalPath + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, journalPath); err != nil {
+		return err
+	}
+	return storage.SyncDir(path)
+}
+
+// removeMigrationJournal removes the migration journal of the database at
+// path, if any.
+func removeMigrationJournal(path string) error {
+	err := os.Remove(filepath.Join(path, MigrationJournalFileName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return storage.SyncDir(path)
+}
+
+// copyFileSync copies src to dst and syncs dst.
+func copyFileSync(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,373 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// testComponent is the component the test migrations convert, so that
+// they do not clash with the migrations registered by the engine.
+const testComponent = "test"
+
+// putMarkerMigration returns a migration of testComponent from version
+// from that stores an entry with the given DN and returns err.
+func putMarkerMigration(from uint32, mode MigrationMode, dn string, err error) Migration {
+	return Migration{
+		Component: testComponent,
+		From:      from,
+		To:        from + 1,
+		Mode:      mode,
+		Forward: func(ctx context.Context, db *ObaDB) error {
+			txn, txErr := db.Begin()
+			if txErr != nil {
+				return txErr
+			}
+			if putErr := db.Put(txn, createTestEntry(dn, "person", "marker")); putErr != nil {
+				db.Rollback(txn)
+				return putErr
+			}
+			if commitErr := db.Commit(txn); commitErr != nil {
+				return commitErr
+			}
+			return err
+		},
+	}
+}
+
+// newTestRegistry returns a registry holding the migrations registered by
+// the engine followed by migrations, failing the test if one is rejected.
+func newTestRegistry(t *testing.T, migrations ...Migration) *MigrationRegistry {
+	t.Helper()
+
+	r := NewMigrationRegistry()
+	defaultMigrations.mu.RLock()
+	migrations = append(append([]Migration(nil), defaultMigrations.migrations...), migrations...)
+	defaultMigrations.mu.RUnlock()
+	for _, m := range migrations {
+		if err := r.Register(m); err != nil {
+			t.Fatalf("Failed to register migration %s: %v", m.ID(), err)
+		}
+	}
+	return r
+}
+
+// openMigrated opens the database at dir with the migrations of r.
+func openMigrated(t *testing.T, dir string, r *MigrationRegistry) *ObaDB {
+	t.Helper()
+
+	db, err := openWithMigrations(dir, storage.DefaultEngineOptions(), r)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	return db
+}
+
+// hasEntry reports whether the database holds an entry at dn.
+func hasEntry(t *testing.T, db *ObaDB, dn string) bool {
+	t.Helper()
+
+	txn, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer db.Rollback(txn)
+
+	_, err = db.Get(txn, dn)
+	if errors.Is(err, ErrEntryNotFound) {
+		return false
+	}
+	if err != nil {
+		t.Fatalf("Failed to get %s: %v", dn, err)
+	}
+	return true
+}
+
+// testComponentVersion reads the version of testComponent of the database
+// at dir.
+func testComponentVersion(t *testing.T, dir string) uint32 {
+	t.Helper()
+
+	versions, err := readComponentVersions(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to read component versions: %v", err)
+	}
+	return versions[testComponent]
+}
+
+func TestMigrationRegistryRegister(t *testing.T) {
+	forward := func(ctx context.Context, db *ObaDB) error { return nil }
+
+	tests := []struct {
+		name string
+		m    Migration
+	}{
+		{"no component", Migration{From: 0, To: 1, Forward: forward}},
+		{"no forward", Migration{Component: testComponent, From: 0, To: 1}},
+		{"skips a version", Migration{Component: testComponent, From: 0, To: 2, Forward: forward}},
+		{"gap", Migration{Component: testComponent, From: 1, To: 2, Forward: forward}},
+		{"unknown mode", Migration{Component: testComponent, From: 0, To: 1, Mode: 7, Forward: forward}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := NewMigrationRegistry().Register(tt.m); !errors.Is(err, ErrInvalidMigration) {
+				t.Errorf("Expected ErrInvalidMigration, got %v", err)
+			}
+		})
+	}
+
+	r := newTestRegistry(t,
+		Migration{Component: testComponent, From: 0, To: 1, Forward: forward},
+		Migration{Component: ComponentIndex, From: 0, To: 1, Forward: forward},
+		Migration{Component: testComponent, From: 1, To: 2, Forward: forward},
+	)
+	if v := r.CurrentVersions()[testComponent]; v != 2 {
+		t.Errorf("Expected version 2, got %d", v)
+	}
+
+	pending, err := r.Pending(map[string]uint32{
+		ComponentEntry: entryFormatVersion,
+		ComponentPage:  storage.PageFormatVersion,
+		ComponentWAL:   storage.WALFormatVersion,
+		testComponent:  1,
+	})
+	if err != nil {
+		t.Fatalf("Pending failed: %v", err)
+	}
+	var ids []string
+	for _, m := range pending {
+		ids = append(ids, m.ID())
+	}
+	if len(ids) != 2 || ids[0] != "index/0-1" || ids[1] != "test/1-2" {
+		t.Errorf("Unexpected pending migrations: %v", ids)
+	}
+
+	if _, err := r.Pending(map[string]uint32{testComponent: 3}); !errors.Is(err, ErrFormatTooNew) {
+		t.Errorf("Expected ErrFormatTooNew, got %v", err)
+	}
+}
+
+func TestOpenStampsNewDatabase(t *testing.T) {
+	dir := t.TempDir()
+	r := newTestRegistry(t, putMarkerMigration(0, MigrationOffline, "cn=migrated,dc=example,dc=com", nil))
+
+	db := openMigrated(t, dir, r)
+	if hasEntry(t, db, "cn=migrated,dc=example,dc=com") {
+		t.Error("Expected no migration to run on a new database")
+	}
+	db.Close()
+
+	if v := testComponentVersion(t, dir); v != 1 {
+		t.Errorf("Expected version 1, got %d", v)
+	}
+}
+
+func TestOpenRefusesNewerWALFormat(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	versions, _ := db.pageManager.ComponentVersions()
+	if versions[ComponentWAL] != storage.WALFormatVersion || versions[ComponentPage] != storage.PageFormatVersion {
+		t.Errorf("Expected a new database at the current WAL and page formats, got %v", versions)
+	}
+
+	// As if a newer release had written compressed records in a format
+	// this one cannot read
+	db.pageManager.SetComponentVersion(ComponentWAL, storage.WALFormatVersion+1)
+	db.Close()
+
+	if _, err := Open(dir, storage.DefaultEngineOptions()); !errors.Is(err, ErrFormatTooNew) {
+		t.Errorf("Expected ErrFormatTooNew, got %v", err)
+	}
+}
+
+func TestOpenRunsOfflineMigration(t *testing.T) {
+	dir := t.TempDir()
+	populateRecoveryDB(t, dir, 5)
+
+	r := newTestRegistry(t, putMarkerMigration(0, MigrationOffline, "cn=migrated,dc=example,dc=com", nil))
+	db := openMigrated(t, dir, r)
+	if !hasEntry(t, db, "cn=migrated,dc=example,dc=com") {
+		t.Error("Expected the migration to run")
+	}
+	db.Close()
+
+	if v := testComponentVersion(t, dir); v != 1 {
+		t.Errorf("Expected version 1, got %d", v)
+	}
+	for _, name := range []string{MigrationJournalFileName, MigrationBackupDir} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed, got %v", name, err)
+		}
+	}
+
+	// The migration does not run again
+	db = openMigrated(t, dir, newTestRegistry(t,
+		putMarkerMigration(0, MigrationOffline, "cn=again,dc=example,dc=com", nil)))
+	defer db.Close()
+	if hasEntry(t, db, "cn=again,dc=example,dc=com") {
+		t.Error("Expected the migration to run once")
+	}
+}
+
+func TestOfflineMigrationRollback(t *testing.T) {
+	dir := t.TempDir()
+	populateRecoveryDB(t, dir, 5)
+
+	failed := errors.New("migration failed")
+	r := newTestRegistry(t, putMarkerMigration(0, MigrationOffline, "cn=partial,dc=example,dc=com", failed))
+	if _, err := openWithMigrations(dir, storage.DefaultEngineOptions(), r); !errors.Is(err, failed) {
+		t.Fatalf("Expected the migration error, got %v", err)
+	}
+
+	journal, err := readMigrationJournal(dir)
+	if err != nil || journal == nil || !journal.Backup {
+		t.Fatalf("Expected a journal with a backup, got %+v, %v", journal, err)
+	}
+
+	// The next open rolls back the partial migration and runs it again
+	db := openMigrated(t, dir, newTestRegistry(t,
+		putMarkerMigration(0, MigrationOffline, "cn=migrated,dc=example,dc=com", nil)))
+	defer db.Close()
+
+	if hasEntry(t, db, "cn=partial,dc=example,dc=com") {
+		t.Error("Expected the partial migration to be rolled back")
+	}
+	if !hasEntry(t, db, "cn=migrated,dc=example,dc=com") {
+		t.Error("Expected the migration to run again")
+	}
+	if !hasEntry(t, db, "uid=user3,dc=example,dc=com") {
+		t.Error("Expected the existing entries to survive the rollback")
+	}
+}
+
+func TestOnlineMigration(t *testing.T) {
+	dir := t.TempDir()
+	populateRecoveryDB(t, dir, 5)
+
+	r := newTestRegistry(t, putMarkerMigration(0, MigrationOnline, "cn=migrated,dc=example,dc=com", nil))
+	db := openMigrated(t, dir, r)
+	db.migrationWG.Wait()
+	if db.migrationErr != nil {
+		t.Fatalf("Online migration failed: %v", db.migrationErr)
+	}
+	if !hasEntry(t, db, "cn=migrated,dc=example,dc=com") {
+		t.Error("Expected the migration to run")
+	}
+	db.Close()
+
+	if v := testComponentVersion(t, dir); v != 1 {
+		t.Errorf("Expected version 1, got %d", v)
+	}
+	if _, err := os.Stat(filepath.Join(dir, MigrationJournalFileName)); !os.IsNotExist(err) {
+		t.Errorf("Expected the journal to be removed, got %v", err)
+	}
+}
+
+func TestOnlineMigrationInterrupted(t *testing.T) {
+	dir := t.TempDir()
+	populateRecoveryDB(t, dir, 5)
+
+	started := make(chan struct{})
+	r := newTestRegistry(t, Migration{
+		Component: testComponent,
+		From:      0,
+		To:        1,
+		Mode:      MigrationOnline,
+		Forward: func(ctx context.Context, db *ObaDB) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+
+	db := openMigrated(t, dir, r)
+	<-started
+	if !hasEntry(t, db, "uid=user1,dc=example,dc=com") {
+		t.Error("Expected the database to serve reads during the migration")
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Failed to close database: %v", err)
+	}
+
+	if v := testComponentVersion(t, dir); v != 0 {
+		t.Errorf("Expected version 0, got %d", v)
+	}
+
+	plan, err := planMigrations(dir, storage.DefaultEngineOptions(), r)
+	if err != nil {
+		t.Fatalf("planMigrations failed: %v", err)
+	}
+	if len(plan) != 1 || !plan[0].Interrupted {
+		t.Errorf("Expected one interrupted migration, got %+v", plan)
+	}
+
+	// The next open resumes it
+	db = openMigrated(t, dir, newTestRegistry(t,
+		putMarkerMigration(0, MigrationOnline, "cn=migrated,dc=example,dc=com", nil)))
+	db.migrationWG.Wait()
+	defer db.Close()
+	if !hasEntry(t, db, "cn=migrated,dc=example,dc=com") {
+		t.Error("Expected the migration to resume")
+	}
+}
+
+func TestPlanMigrations(t *testing.T) {
+	dir := t.TempDir()
+	populateRecoveryDB(t, dir, 5)
+
+	m := putMarkerMigration(0, MigrationOffline, "cn=migrated,dc=example,dc=com", nil)
+	m.Estimate = func(db *ObaDB) (int64, error) {
+		return int64(db.Stats().EntryCount) * 100, nil
+	}
+	r := newTestRegistry(t, m, putMarkerMigration(1, MigrationOnline, "cn=online,dc=example,dc=com", nil))
+
+	plan, err := planMigrations(dir, storage.DefaultEngineOptions(), r)
+	if err != nil {
+		t.Fatalf("planMigrations failed: %v", err)
+	}
+	if len(plan) != 2 {
+		t.Fatalf("Expected 2 planned migrations, got %d", len(plan))
+	}
+	if plan[0].Bytes != 500 {
+		t.Errorf("Expected an estimate of 500 bytes, got %d", plan[0].Bytes)
+	}
+	if plan[1].Bytes != -1 {
+		t.Errorf("Expected no estimate, got %d", plan[1].Bytes)
+	}
+
+	// Planning leaves the database untouched
+	if v := testComponentVersion(t, dir); v != 0 {
+		t.Errorf("Expected version 0, got %d", v)
+	}
+	if _, err := os.Stat(filepath.Join(dir, MigrationBackupDir)); !os.IsNotExist(err) {
+		t.Errorf("Expected no backup, got %v", err)
+	}
+}
+
+func TestOpenMigrationErrors(t *testing.T) {
+	dir := t.TempDir()
+	populateRecoveryDB(t, dir, 1)
+
+	offline := newTestRegistry(t, putMarkerMigration(0, MigrationOffline, "cn=migrated,dc=example,dc=com", nil))
+	opts := storage.DefaultEngineOptions().WithReadOnly(true)
+	if _, err := openWithMigrations(dir, opts, offline); !errors.Is(err, ErrMigrationPending) {
+		t.Errorf("Expected ErrMigrationPending for a read-only open, got %v", err)
+	}
+
+	// A database migrated further than the registry knows is refused
+	db := openMigrated(t, dir, newTestRegistry(t,
+		putMarkerMigration(0, MigrationOffline, "cn=one,dc=example,dc=com", nil),
+		putMarkerMigration(1, MigrationOffline, "cn=two,dc=example,dc=com", nil)))
+	db.Close()
+
+	if _, err := openWithMigrations(dir, storage.DefaultEngineOptions(), offline); !errors.Is(err, ErrFormatTooNew) {
+		t.Errorf("Expected ErrFormatTooNew, got %v", err)
+	}
+}
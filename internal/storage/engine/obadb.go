@@ -2,19 +2,23 @@
 package engine
 
 import (
-	"encoding/binary"
+	"context"
 	"errors"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
 	"github.com/KilimcininKorOglu/oba/internal/crypto"
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
 	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/cache"
 	"github.com/KilimcininKorOglu/oba/internal/storage/index"
 	"github.com/KilimcininKorOglu/oba/internal/storage/mvcc"
 	"github.com/KilimcininKorOglu/oba/internal/storage/radix"
 	"github.com/KilimcininKorOglu/oba/internal/storage/tx"
+	"github.com/KilimcininKorOglu/oba/internal/trace"
 )
 
 // File names for ObaDB storage.
@@ -26,18 +30,31 @@ const (
 	RadixCacheFileName = "radix.cache"
 	BTreeCacheFileName = "btree.cache"
 	EntryCacheFileName = "entry.cache"
+
+	// OpenMarkerFileName exists while the database is open for writing.
+	// Finding it at open means the database was not closed cleanly.
+	OpenMarkerFileName = "open.marker"
+
+	// LockFileName is held exclusively by writers while they change the
+	// database files, and shared by OpenReadOnly while it copies them.
+	LockFileName = "LOCK"
 )
 
 // ObaDB errors.
 var (
-	ErrDatabaseClosed    = errors.New("database is closed")
-	ErrDatabaseReadOnly  = errors.New("database is read-only")
-	ErrEntryNotFound     = errors.New("entry not found")
-	ErrEntryExists       = errors.New("entry already exists")
-	ErrInvalidDN         = errors.New("invalid distinguished name")
-	ErrInvalidEntry      = errors.New("invalid entry")
-	ErrTransactionClosed = errors.New("transaction is closed")
-	ErrUIDNotUnique      = errors.New("uid attribute must be unique")
+	ErrDatabaseClosed     = errors.New("database is closed")
+	ErrDatabaseReadOnly   = errors.New("database is read-only")
+	ErrEntryNotFound      = errors.New("entry not found")
+	ErrEntryExists        = errors.New("entry already exists")
+	ErrInvalidDN          = errors.New("invalid distinguished name")
+	ErrInvalidEntry       = errors.New("invalid entry")
+	ErrTransactionClosed  = errors.New("transaction is closed")
+	ErrUIDNotUnique       = errors.New("uid attribute must be unique")
+	ErrEntryUUIDNotUnique = errors.New("entryUUID attribute must be unique")
+
+	// ErrReadOnly is returned by the write methods of a database opened
+	// with OpenReadOnly.
+	ErrReadOnly = ErrDatabaseReadOnly
 )
 
 // ObaDB is the main storage engine implementation.
@@ -50,9 +67,16 @@ type ObaDB struct {
 	versionStore    *mvcc.VersionStore
 	snapshotManager *mvcc.SnapshotManager
 	radixTree       *radix.RadixTree
+	adaptiveIndex   *storage.AdaptiveHashIndex
 	indexManager    *index.IndexManager
+	indexPages      *storage.PageManager
+	deferredIndexer *index.DeferredIndexer
 	gc              *mvcc.GarbageCollector
 
+	// Index changes of open transactions, applied when they commit
+	indexBatches   map[uint64]*index.Batch
+	indexBatchesMu sync.Mutex
+
 	// Additional components
 	bufferPool        *storage.BufferPool
 	checkpointManager *storage.CheckpointManager
@@ -60,18 +84,41 @@ type ObaDB struct {
 	// Encryption
 	encryptionKey *crypto.EncryptionKey
 
+	// Format migrations; see migrate.go
+	migrations        *MigrationRegistry
+	newDatabase       bool
+	offlineMigrations []Migration
+	onlineMigrations  []Migration
+	appliedMigrations []Migration
+	migrationErr      error
+	stopMigrations    context.CancelFunc
+	migrationWG       sync.WaitGroup
+
 	// Configuration
-	options storage.EngineOptions
-	path    string
+	options      storage.EngineOptions
+	searchConfig SearchConfig
+	path         string
+
+	// Cross-process write lock; nil when read-only
+	writeGate *writeGate
 
 	// State
 	closed   bool
 	readOnly bool
+	snapshot bool // opened by OpenReadOnly, on in-memory copies
 	mu       sync.RWMutex
 }
 
-// Open opens or creates an ObaDB database at the given path.
+// Open opens or creates an ObaDB database at the given path. Pending format
+// migrations registered with RegisterMigration are applied: offline ones
+// before Open returns, online ones in the background.
 func Open(path string, opts storage.EngineOptions) (*ObaDB, error) {
+	return openWithMigrations(path, opts, defaultMigrations)
+}
+
+// openWithMigrations opens the database at path, applying the pending
+// migrations of the registry. A nil registry skips migrations.
+func openWithMigrations(path string, opts storage.EngineOptions, migrations *MigrationRegistry) (*ObaDB, error) {
 	// Validate and apply defaults
 	if err := opts.Validate(); err != nil {
 		return nil, err
@@ -85,10 +132,31 @@ func Open(path string, opts storage.EngineOptions) (*ObaDB, error) {
 	}
 
 	db := &ObaDB{
-		options:  opts,
-		path:     path,
-		closed:   false,
-		readOnly: opts.ReadOnly,
+		options:      opts,
+		searchConfig: DefaultSearchConfig(),
+		path:         path,
+		closed:       false,
+		readOnly:     opts.ReadOnly,
+		migrations:   migrations,
+	}
+
+	// Readers wait until the database is open
+	if !opts.ReadOnly {
+		gate, err := openWriteGate(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := gate.enter(); err != nil {
+			gate.close()
+			return nil, err
+		}
+		defer gate.leave()
+		db.writeGate = gate
+	}
+
+	if err := db.prepareMigrations(); err != nil {
+		db.writeGate.close()
+		return nil, err
 	}
 
 	// Initialize components
@@ -97,6 +165,11 @@ func Open(path string, opts storage.EngineOptions) (*ObaDB, error) {
 		return nil, err
 	}
 
+	if err := db.runMigrations(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	return db, nil
 }
 
@@ -117,6 +190,7 @@ func (db *ObaDB) initComponents() error {
 		CreateIfNew:  db.options.CreateIfNotExists,
 		ReadOnly:     db.options.ReadOnly,
 		SyncOnWrite:  db.options.SyncOnWrite,
+		FileSystem:   db.options.FileSystem,
 	}
 
 	db.pageManager, err = storage.OpenPageManager(dataPath, pmOpts)
@@ -127,7 +201,7 @@ func (db *ObaDB) initComponents() error {
 	// 2. Open WAL
 	if !db.options.ReadOnly {
 		walPath := filepath.Join(db.path, WALFileName)
-		db.wal, err = storage.OpenWALWithEncryption(walPath, db.encryptionKey)
+		db.wal, err = storage.OpenWALWithFileSystem(walPath, db.encryptionKey, db.options.FileSystem)
 		if err != nil {
 			return err
 		}
@@ -151,13 +225,21 @@ func (db *ObaDB) initComponents() error {
 	// 5. Create version store
 	db.versionStore = mvcc.NewVersionStore(db.pageManager)
 
-	// 6. Create snapshot manager
+	// 6. Create snapshot manager. Versions loaded from disk are committed
+	// at timestamp 1, and transactions see everything committed when they
+	// begin.
 	db.snapshotManager = mvcc.NewSnapshotManager(db.txManager)
+	db.snapshotManager.SetTimestamp(1)
+	if db.txManager != nil {
+		db.txManager.SetSnapshotSource(db.snapshotManager.CurrentTimestamp)
+	}
 
-	// 7. Initialize or load radix tree
+	// 7. Initialize or load radix tree, with the adaptive hash index
+	// caching the locations of its hottest DNs
 	if err := db.initRadixTree(); err != nil {
 		return err
 	}
+	db.adaptiveIndex = storage.NewAdaptiveHashIndex(db.options.AdaptiveIndexSize)
 
 	// 8. Open index manager
 	indexPath := filepath.Join(db.path, IndexFileName)
@@ -167,18 +249,36 @@ func (db *ObaDB) initComponents() error {
 		CreateIfNew:  db.options.CreateIfNotExists,
 		ReadOnly:     db.options.ReadOnly,
 		SyncOnWrite:  db.options.SyncOnWrite,
+		FileSystem:   db.options.FileSystem,
 	}
 
 	indexPM, err := storage.OpenPageManager(indexPath, indexPMOpts)
 	if err != nil {
 		return err
 	}
+	db.indexPages = indexPM
 
 	db.indexManager, err = index.NewIndexManager(indexPM)
 	if err != nil {
 		return err
 	}
 
+	// Indexes are only saved at close, so rebuild them after a crash
+	if !db.options.ReadOnly {
+		if err := db.markOpen(); err != nil {
+			return err
+		}
+		if err := db.ensureEntryUUIDIndex(); err != nil {
+			return err
+		}
+	}
+
+	if db.options.DeferredIndexing && !db.options.ReadOnly {
+		db.deferredIndexer = index.NewDeferredIndexer(db.indexManager, index.DeferredIndexerConfig{
+			FlushInterval: db.options.DeferredIndexFlushInterval,
+		})
+	}
+
 	// 9. Create checkpoint manager
 	if db.wal != nil {
 		db.checkpointManager = storage.NewCheckpointManager(db.wal, db.pageManager)
@@ -248,12 +348,16 @@ func (db *ObaDB) initRadixTree() error {
 			return err
 		}
 
-		// Try to load cache (faster startup)
-		if cacheErr := db.radixTree.LoadCache(cachePath, txID); cacheErr == nil {
-			// Cache loaded successfully
+		// Read-only databases have no WAL to replay, so only an exact
+		// cache is usable.
+		if db.wal == nil {
+			db.radixTree.LoadCache(cachePath, txID)
 			return nil
 		}
-		// Cache miss or stale - tree already loaded from pages
+
+		// Fast path: load the last snapshot and replay DN changes logged
+		// to the WAL since it was written.
+		db.loadRadixSnapshot(cachePath, txID)
 		return nil
 	}
 
@@ -273,6 +377,36 @@ func (db *ObaDB) initRadixTree() error {
 	return nil
 }
 
+// loadRadixSnapshot restores the radix tree from its snapshot and replays
+// the DN changes logged after it. If no usable snapshot exists the tree keeps
+// the state loaded from its root page.
+func (db *ObaDB) loadRadixSnapshot(path string, txID uint64) {
+	snapshotTxID, err := db.radixTree.LoadSnapshot(path, txID)
+	if err != nil {
+		if errors.Is(err, cache.ErrStaleTxID) && !db.readOnly {
+			// The snapshot is ahead of the WAL, so the WAL was reset after
+			// it was written. Remove it so that it is never replayed
+			// against unrelated records.
+			os.Remove(path)
+		}
+		return
+	}
+
+	if snapshotTxID < txID {
+		db.radixTree.ReplayWAL(db.wal, snapshotTxID)
+	}
+}
+
+// logDNChange appends a DN index change to the WAL so that the radix tree
+// can be replayed on top of its snapshot at startup.
+func (db *ObaDB) logDNChange(record *storage.WALRecord) error {
+	if db.wal == nil {
+		return nil
+	}
+	_, err := db.wal.Append(record)
+	return err
+}
+
 // setupDiskLoader configures the version store to load entries from disk on cache miss.
 func (db *ObaDB) setupDiskLoader() {
 	if db.versionStore == nil || db.radixTree == nil || db.pageManager == nil {
@@ -280,33 +414,63 @@ func (db *ObaDB) setupDiskLoader() {
 	}
 
 	db.versionStore.SetDiskLoader(func(dn string) (*mvcc.Version, storage.PageID, uint16, error) {
-		pageID, slotID, found := db.radixTree.Lookup(dn)
+		pageID, slotID, found := db.lookupDN(dn)
 		if !found || pageID == 0 {
 			return nil, 0, 0, ErrEntryNotFound
 		}
 
-		page, err := db.pageManager.ReadPage(pageID)
+		data, err := readEntryData(db.pageManager, pageID)
 		if err != nil {
 			return nil, 0, 0, err
 		}
 
-		if len(page.Data) < 4 {
-			return nil, 0, 0, ErrInvalidEntry
-		}
-
-		dataLen := int(page.Data[0]) | int(page.Data[1])<<8 | int(page.Data[2])<<16 | int(page.Data[3])<<24
-		if dataLen <= 0 || dataLen+4 > len(page.Data) {
-			return nil, 0, 0, ErrInvalidEntry
-		}
-
-		data := make([]byte, dataLen)
-		copy(data, page.Data[4:4+dataLen])
-
 		version := mvcc.NewCommittedVersion(data, pageID, slotID)
 		return version, pageID, slotID, nil
 	})
 }
 
+// lookupDN returns the storage location of dn from the adaptive hash
+// index, or from the radix tree if it is not cached.
+func (db *ObaDB) lookupDN(dn string) (storage.PageID, uint16, bool) {
+	if pageID, slotID, found := db.adaptiveIndex.Lookup(dn); found {
+		return pageID, slotID, true
+	}
+	return db.radixTree.Lookup(dn)
+}
+
+// touchDN records an access to dn and caches its location in the adaptive
+// hash index once it is hot.
+func (db *ObaDB) touchDN(dn string) {
+	if !db.adaptiveIndex.Touch(dn) {
+		return
+	}
+	epoch := db.adaptiveIndex.Epoch()
+	if pageID, slotID, found := db.radixTree.Lookup(dn); found && pageID != 0 {
+		db.adaptiveIndex.Add(dn, pageID, slotID, epoch)
+	}
+}
+
+// readEntryData reads the serialized entry stored on a data page.
+func readEntryData(pm *storage.PageManager, pageID storage.PageID) ([]byte, error) {
+	page, err := pm.ReadPage(pageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(page.Data) < 4 {
+		return nil, ErrInvalidEntry
+	}
+
+	dataLen := int(page.Data[0]) | int(page.Data[1])<<8 | int(page.Data[2])<<16 | int(page.Data[3])<<24
+	if dataLen <= 0 || dataLen+4 > len(page.Data) {
+		return nil, ErrInvalidEntry
+	}
+
+	data := make([]byte, dataLen)
+	copy(data, page.Data[4:4+dataLen])
+	return data, nil
+}
+
 // preloadHotEntries preloads frequently accessed entries into the cache at startup.
 func (db *ObaDB) preloadHotEntries() error {
 	if db.radixTree == nil || db.versionStore == nil || db.pageManager == nil {
@@ -399,6 +563,10 @@ func (db *ObaDB) preloadHotEntriesAsync() {
 
 // Close closes the database and releases all resources.
 func (db *ObaDB) Close() error {
+	// Online migrations write through transactions, so they must return
+	// before the lock is taken.
+	db.waitMigrations()
+
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
@@ -408,6 +576,12 @@ func (db *ObaDB) Close() error {
 
 	db.closed = true
 
+	if err := db.writeGate.enter(); err != nil {
+		return err
+	}
+	defer db.writeGate.close()
+	defer db.writeGate.leave()
+
 	var errs []error
 
 	// Stop garbage collector
@@ -417,6 +591,13 @@ func (db *ObaDB) Close() error {
 		}
 	}
 
+	// Apply queued index updates
+	if db.deferredIndexer != nil {
+		if err := db.deferredIndexer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
 	// Flush buffer pool
 	if db.bufferPool != nil {
 		if err := db.bufferPool.FlushAll(); err != nil {
@@ -424,22 +605,27 @@ func (db *ObaDB) Close() error {
 		}
 	}
 
-	// Persist radix tree
-	if db.radixTree != nil {
+	// Persist radix tree and save caches, unless the files are copies
+	if db.radixTree != nil && !db.snapshot {
 		if err := db.radixTree.Persist(); err != nil {
 			errs = append(errs, err)
 		}
 	}
+	if !db.snapshot {
+		db.saveCachesInternal()
+	}
 
-	// Save caches before closing
-	db.saveCachesInternal()
-
-	// Close index manager
+	// Close index manager and its file
 	if db.indexManager != nil {
 		if err := db.indexManager.Close(); err != nil {
 			errs = append(errs, err)
 		}
 	}
+	if db.indexPages != nil {
+		if err := db.indexPages.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
 
 	// Close WAL
 	if db.wal != nil {
@@ -459,11 +645,22 @@ func (db *ObaDB) Close() error {
 		return errs[0]
 	}
 
+	if !db.readOnly {
+		if err := os.Remove(filepath.Join(db.path, OpenMarkerFileName)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
 	return nil
 }
 
 // Begin starts a new transaction.
 func (db *ObaDB) Begin() (interface{}, error) {
+	return db.BeginWithOptions(tx.TxOptions{})
+}
+
+// BeginWithOptions starts a new transaction configured by opts.
+func (db *ObaDB) BeginWithOptions(opts tx.TxOptions) (interface{}, error) {
 	db.mu.RLock()
 	defer db.mu.RUnlock()
 
@@ -475,7 +672,7 @@ func (db *ObaDB) Begin() (interface{}, error) {
 		return nil, ErrDatabaseReadOnly
 	}
 
-	return db.txManager.Begin()
+	return db.txManager.BeginWithOptions(opts)
 }
 
 // Commit commits the transaction.
@@ -495,15 +692,90 @@ func (db *ObaDB) Commit(txnIface interface{}) error {
 	if !ok || txn == nil {
 		return tx.ErrNilTransaction
 	}
+	defer db.writeGate.leaveTx(txn.ID)
+
+	return db.txManager.CommitWith(txn, func() error {
+		if txn.Serializable && db.readsChanged(txn) {
+			return tx.ErrSerializationFailure
+		}
 
-	// Get commit timestamp
-	commitTS := db.snapshotManager.AdvanceTimestamp()
+		// The index changes are applied before the versions become
+		// visible, so a reader never misses a committed entry in an index
+		if err := db.applyIndexBatch(txn.ID); err != nil {
+			return err
+		}
 
-	// Commit versions in version store
-	db.versionStore.CommitVersion(txn, commitTS)
+		// Get commit timestamp
+		commitTS := db.snapshotManager.AdvanceTimestamp()
 
-	// Commit transaction
-	return db.txManager.Commit(txn)
+		// Commit versions in version store
+		db.versionStore.CommitVersion(txn, commitTS)
+		return nil
+	})
+}
+
+// readsChanged reports whether another transaction committed a change
+// after the snapshot of the serializable transaction txn to a DN or
+// subtree it read, or an entry holding an attribute value it looked up.
+func (db *ObaDB) readsChanged(txn *tx.Transaction) bool {
+	changed := db.versionStore.CommittedSince(txn.Snapshot, txn.ID)
+	if len(changed) == 0 {
+		return false
+	}
+
+	readDNs := make(map[string]struct{})
+	for _, dn := range txn.GetReadDNs() {
+		readDNs[dn] = struct{}{}
+	}
+	readRanges := txn.GetReadRanges()
+	readValues := txn.GetReadValues()
+
+	for _, dn := range changed {
+		if _, ok := readDNs[dn]; ok {
+			return true
+		}
+		for _, baseDN := range readRanges {
+			if inSubtree(dn, baseDN) {
+				return true
+			}
+		}
+		if len(readValues) > 0 && db.hasAnyValue(dn, readValues) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAnyValue reports whether the latest committed version of the entry at
+// dn holds one of values. Attribute names and values are compared
+// case-insensitively.
+func (db *ObaDB) hasAnyValue(dn string, values []tx.AttributeValue) bool {
+	version, err := db.versionStore.GetVisible(dn, ^uint64(0))
+	if err != nil {
+		return false
+	}
+	data, err := db.decryptData(version.GetData())
+	if err != nil {
+		return false
+	}
+	entry, err := deserializeEntry(dn, data)
+	if err != nil {
+		return false
+	}
+
+	for _, v := range values {
+		for name, entryValues := range entry.Attributes {
+			if !strings.EqualFold(name, v.Attribute) {
+				continue
+			}
+			for _, raw := range entryValues {
+				if strings.EqualFold(strings.TrimSpace(string(raw)), v.Value) {
+					return true
+				}
+			}
+		}
+	}
+	return false
 }
 
 // Rollback aborts the transaction.
@@ -523,6 +795,10 @@ func (db *ObaDB) Rollback(txnIface interface{}) error {
 	if !ok || txn == nil {
 		return tx.ErrNilTransaction
 	}
+	defer db.writeGate.leaveTx(txn.ID)
+
+	// Index changes of the transaction are dropped with it
+	db.discardIndexBatch(txn.ID)
 
 	// Rollback versions in version store
 	db.versionStore.RollbackVersion(txn)
@@ -551,8 +827,12 @@ func (db *ObaDB) Get(txnIface interface{}, dn string) (*storage.Entry, error) {
 	var snapshot uint64
 	var activeTxID uint64
 	if txn, ok := txnIface.(*tx.Transaction); ok && txn != nil {
+		if err := txn.Context().Err(); err != nil {
+			return nil, err
+		}
 		snapshot = txn.Snapshot
 		activeTxID = txn.ID
+		txn.AddReadDN(dn)
 	} else {
 		snapshot = db.snapshotManager.CurrentTimestamp()
 	}
@@ -583,6 +863,8 @@ func (db *ObaDB) Get(txnIface interface{}, dn string) (*storage.Entry, error) {
 		return nil, err
 	}
 
+	db.touchDN(dn)
+
 	return entry, nil
 }
 
@@ -608,15 +890,34 @@ func (db *ObaDB) Put(txnIface interface{}, entry *storage.Entry) error {
 		return ErrInvalidEntry
 	}
 
-	// Normalize DN
+	if err := db.writeGate.enterTx(txn.ID); err != nil {
+		return err
+	}
+
+	// The entry keeps the DN as given and is keyed by its normalized form
+	entry.DN = strings.TrimSpace(entry.DN)
 	dn := normalizeDN(entry.DN)
-	entry.DN = dn
 
 	// Check uid uniqueness
 	if err := db.checkUIDUnique(txn, dn, entry.Attributes); err != nil {
 		return err
 	}
 
+	// Check if entry exists (for index update)
+	var oldEntry *storage.Entry
+	existingVersion, err := db.versionStore.GetVisibleForTx(dn, txn.Snapshot, txn.ID)
+	if err == nil && existingVersion != nil {
+		oldData := existingVersion.GetData()
+		// Decrypt if needed
+		oldData, _ = db.decryptData(oldData)
+		oldEntry, _ = deserializeEntry(dn, oldData)
+	}
+
+	// Assign the entryUUID of a new entry, or keep that of the old one
+	if err := db.assignEntryUUID(txn, dn, entry, oldEntry); err != nil {
+		return err
+	}
+
 	// Serialize entry
 	data, err := serializeEntry(entry)
 	if err != nil {
@@ -629,35 +930,38 @@ func (db *ObaDB) Put(txnIface interface{}, entry *storage.Entry) error {
 		return err
 	}
 
-	// Check if entry exists (for index update)
-	var oldEntry *storage.Entry
-	existingVersion, err := db.versionStore.GetVisibleForTx(dn, txn.Snapshot, txn.ID)
-	if err == nil && existingVersion != nil {
-		oldData := existingVersion.GetData()
-		// Decrypt if needed
-		oldData, _ = db.decryptData(oldData)
-		oldEntry, _ = deserializeEntry(dn, oldData)
-	}
-
 	// Create version in version store and get the storage location
 	pageID, slotID, err := db.versionStore.CreateVersionWithLocation(txn, dn, data)
 	if err != nil {
 		return err
 	}
 
+	// The cached location is dropped after the radix tree changes, so
+	// that a concurrent touchDN cannot cache the old one
+	defer db.adaptiveIndex.Invalidate(dn)
+
 	// Update radix tree if this is a new entry
 	if oldEntry == nil {
 		if err := db.radixTree.Insert(dn, pageID, slotID); err != nil {
 			if err != radix.ErrEntryExists {
 				return err
 			}
+		} else if err := db.logDNChange(radix.NewInsertRecord(txn.ID, dn, pageID, slotID)); err != nil {
+			return err
+		}
+	} else {
+		// The new version is stored on a new page
+		if err := db.radixTree.Update(dn, pageID, slotID); err != nil {
+			if err != radix.ErrEntryNotFound {
+				return err
+			}
+		} else if err := db.logDNChange(radix.NewUpdateRecord(txn.ID, dn, pageID, slotID)); err != nil {
+			return err
 		}
 	}
 
-	// Update indexes with storage location
-	if err := db.updateIndexesWithLocation(oldEntry, entry, pageID, slotID); err != nil {
-		return err
-	}
+	// Queue the index changes with storage location
+	db.updateIndexesWithLocation(txn, oldEntry, entry, pageID, slotID)
 
 	return nil
 }
@@ -684,6 +988,10 @@ func (db *ObaDB) Delete(txnIface interface{}, dn string) error {
 		return ErrInvalidDN
 	}
 
+	if err := db.writeGate.enterTx(txn.ID); err != nil {
+		return err
+	}
+
 	// Normalize DN
 	dn = normalizeDN(dn)
 
@@ -710,19 +1018,20 @@ func (db *ObaDB) Delete(txnIface interface{}, dn string) error {
 		return err
 	}
 
-	// Remove from radix tree
+	// Remove from radix tree, then its cached location
+	defer db.adaptiveIndex.Invalidate(dn)
 	if err := db.radixTree.Delete(dn); err != nil {
 		// Ignore if not found
 		if err != radix.ErrEntryNotFound {
 			return err
 		}
+	} else if err := db.logDNChange(radix.NewDeleteRecord(txn.ID, dn)); err != nil {
+		return err
 	}
 
-	// Update indexes (remove old entry)
+	// Queue the removal of the old entry from the indexes
 	if oldEntry != nil {
-		if err := db.updateIndexes(oldEntry, nil); err != nil {
-			return err
-		}
+		db.updateIndexes(txn, oldEntry, nil)
 	}
 
 	return nil
@@ -744,10 +1053,36 @@ func (db *ObaDB) HasChildren(txnIface interface{}, dn string) (bool, error) {
 	// Normalize DN
 	dn = normalizeDN(dn)
 
+	if txn, ok := txnIface.(*tx.Transaction); ok && txn != nil {
+		txn.AddReadRange(dn)
+	}
+
 	// Use the radix tree to check for children
 	return db.radixTree.HasChildren(dn)
 }
 
+// CountChildren returns the number of immediate children of the entry at
+// the given DN, counting no further than limit.
+func (db *ObaDB) CountChildren(txnIface interface{}, dn string, limit int) (int, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if db.closed {
+		return 0, ErrDatabaseClosed
+	}
+
+	if dn == "" {
+		return 0, ErrInvalidDN
+	}
+
+	dn = normalizeDN(dn)
+	if txn, ok := txnIface.(*tx.Transaction); ok && txn != nil {
+		txn.AddReadRange(dn)
+	}
+
+	return db.radixTree.CountChildren(dn, limit)
+}
+
 // SearchByDN searches for entries by DN with the given scope.
 func (db *ObaDB) SearchByDN(txnIface interface{}, baseDN string, scope storage.Scope) storage.Iterator {
 	db.mu.RLock()
@@ -763,16 +1098,19 @@ func (db *ObaDB) SearchByDN(txnIface interface{}, baseDN string, scope storage.S
 	// Get snapshot info
 	var snapshot uint64
 	var activeTxID uint64
+	ctx := context.Background()
 	if txn, ok := txnIface.(*tx.Transaction); ok && txn != nil {
 		snapshot = txn.Snapshot
 		activeTxID = txn.ID
+		ctx = txn.Context()
+		txn.AddReadRange(baseDN)
 	} else {
 		snapshot = db.snapshotManager.CurrentTimestamp()
 	}
 
 	// Handle empty base DN (iterate all entries)
 	if baseDN == "" && scope == storage.ScopeSubtree {
-		return db.createAllEntriesIterator(snapshot, activeTxID)
+		return db.createAllEntriesIterator(ctx, snapshot, activeTxID)
 	}
 
 	// Convert storage.Scope to radix.Scope
@@ -786,6 +1124,7 @@ func (db *ObaDB) SearchByDN(txnIface interface{}, baseDN string, scope storage.S
 
 	return &dnIterator{
 		db:         db,
+		ctx:        ctx,
 		radixIter:  radixIter,
 		snapshot:   snapshot,
 		activeTxID: activeTxID,
@@ -807,9 +1146,12 @@ func (db *ObaDB) SearchByFilter(txnIface interface{}, baseDN string, f interface
 	// Get snapshot info
 	var snapshot uint64
 	var activeTxID uint64
+	ctx := context.Background()
 	if txn, ok := txnIface.(*tx.Transaction); ok && txn != nil {
 		snapshot = txn.Snapshot
 		activeTxID = txn.ID
+		ctx = txn.Context()
+		txn.AddReadRange(baseDN)
 	} else {
 		snapshot = db.snapshotManager.CurrentTimestamp()
 	}
@@ -828,8 +1170,35 @@ func (db *ObaDB) SearchByFilter(txnIface interface{}, baseDN string, f interface
 		}
 	}
 
+	// Narrow the candidates with indexes when the filter allows it
+	if db.searchConfig.IndexSplitEnabled && filterMatcher != nil {
+		// The transaction's own changes must be visible to the lookup
+		if err := db.applyIndexBatch(activeTxID); err != nil {
+			radixIter.Close()
+			return &errorIterator{err: err}
+		}
+
+		span := startSearchSpan(filterMatcher, "engine.index_lookup")
+		dns, ok := db.indexCandidates(filterMatcher)
+		span.SetAttributes(trace.Bool("index.used", ok), trace.Int("index.candidates", len(dns)))
+		span.End()
+		if ok {
+			radixIter.Close()
+			return &candidateIterator{
+				db:            db,
+				ctx:           ctx,
+				dns:           dns,
+				baseDN:        baseDN,
+				filterMatcher: filterMatcher,
+				snapshot:      snapshot,
+				activeTxID:    activeTxID,
+			}
+		}
+	}
+
 	return &filterIterator{
 		db:            db,
+		ctx:           ctx,
 		radixIter:     radixIter,
 		filterMatcher: filterMatcher,
 		snapshot:      snapshot,
@@ -850,6 +1219,11 @@ func (db *ObaDB) CreateIndex(attribute string, indexType storage.IndexType) erro
 		return ErrDatabaseReadOnly
 	}
 
+	if err := db.writeGate.enter(); err != nil {
+		return err
+	}
+	defer db.writeGate.leave()
+
 	return db.indexManager.CreateIndex(attribute, index.IndexType(indexType))
 }
 
@@ -866,9 +1240,28 @@ func (db *ObaDB) DropIndex(attribute string) error {
 		return ErrDatabaseReadOnly
 	}
 
+	if err := db.writeGate.enter(); err != nil {
+		return err
+	}
+	defer db.writeGate.leave()
+
 	return db.indexManager.DropIndex(attribute)
 }
 
+// ListIndexes returns the names of the indexed attributes, sorted.
+func (db *ObaDB) ListIndexes() []string {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if db.closed || db.indexManager == nil {
+		return nil
+	}
+
+	attrs := db.indexManager.ListIndexes()
+	sort.Strings(attrs)
+	return attrs
+}
+
 // ClearAll removes all in-memory entry state (versions, radix and indexes).
 // This is used by cluster replay startup to rebuild deterministic state from Raft log.
 func (db *ObaDB) ClearAll() error {
@@ -882,6 +1275,11 @@ func (db *ObaDB) ClearAll() error {
 		return ErrDatabaseReadOnly
 	}
 
+	if err := db.writeGate.enter(); err != nil {
+		return err
+	}
+	defer db.writeGate.leave()
+
 	if db.versionStore != nil {
 		db.versionStore.Clear()
 	}
@@ -890,6 +1288,12 @@ func (db *ObaDB) ClearAll() error {
 			return err
 		}
 	}
+	if db.adaptiveIndex != nil {
+		db.adaptiveIndex.Clear()
+	}
+	if db.deferredIndexer != nil {
+		db.deferredIndexer.Discard()
+	}
 	if db.indexManager != nil {
 		if err := db.indexManager.ClearAll(); err != nil {
 			return err
@@ -913,10 +1317,19 @@ func (db *ObaDB) ClearIndexes() error {
 		return ErrDatabaseReadOnly
 	}
 
+	if err := db.writeGate.enter(); err != nil {
+		return err
+	}
+	defer db.writeGate.leave()
+
 	if db.indexManager == nil {
 		return nil
 	}
 
+	if db.deferredIndexer != nil {
+		db.deferredIndexer.Discard()
+	}
+
 	return db.indexManager.ClearAll()
 }
 
@@ -933,6 +1346,11 @@ func (db *ObaDB) Checkpoint() error {
 		return ErrDatabaseReadOnly
 	}
 
+	if err := db.writeGate.enter(); err != nil {
+		return err
+	}
+	defer db.writeGate.leave()
+
 	if db.checkpointManager == nil {
 		return nil
 	}
@@ -942,7 +1360,12 @@ func (db *ObaDB) Checkpoint() error {
 		return err
 	}
 
-	// Sync index manager
+	// Apply queued index updates and sync index manager
+	if db.deferredIndexer != nil {
+		if err := db.deferredIndexer.Flush(); err != nil {
+			return err
+		}
+	}
 	if err := db.indexManager.Sync(); err != nil {
 		return err
 	}
@@ -966,6 +1389,11 @@ func (db *ObaDB) Compact() error {
 		return ErrDatabaseReadOnly
 	}
 
+	if err := db.writeGate.enter(); err != nil {
+		return err
+	}
+	defer db.writeGate.leave()
+
 	// Trigger garbage collection
 	if db.gc != nil {
 		_, err := db.gc.TriggerCollect()
@@ -974,6 +1402,14 @@ func (db *ObaDB) Compact() error {
 		}
 	}
 
+	// Compact the free list, so that pages freed by the collection are
+	// reused in ascending order
+	if db.pageManager != nil {
+		if _, err := db.pageManager.CompactFreeList(); err != nil {
+			return err
+		}
+	}
+
 	// Truncate WAL after checkpoint
 	if db.checkpointManager != nil {
 		if err := db.Checkpoint(); err != nil {
@@ -1002,6 +1438,8 @@ func (db *ObaDB) Stats() *storage.EngineStats {
 		stats.TotalPages = pmStats.TotalPages
 		stats.FreePages = pmStats.FreePages
 		stats.UsedPages = pmStats.UsedPages
+		stats.CorruptPages = pmStats.CorruptPages
+		stats.FreeListFragmentation = db.pageManager.FreeListFragmentation()
 	}
 
 	// Entry count from radix tree
@@ -1009,14 +1447,25 @@ func (db *ObaDB) Stats() *storage.EngineStats {
 		stats.EntryCount = uint64(db.radixTree.EntryCount())
 	}
 
+	// Adaptive hash index hit ratio
+	if db.adaptiveIndex != nil {
+		stats.AdaptiveIndexHitRatio = db.adaptiveIndex.HitRatio()
+	}
+
 	// Index count
 	if db.indexManager != nil {
 		stats.IndexCount = db.indexManager.IndexCount()
 	}
 
-	// Active transactions
+	// Transactions
 	if db.txManager != nil {
 		stats.ActiveTransactions = db.txManager.ActiveCount()
+		stats.CommittedTransactions = db.txManager.CommittedCount()
+		stats.RolledBackTransactions = db.txManager.RolledBackCount()
+	}
+
+	if db.wal != nil {
+		stats.WALSize = db.wal.Size()
 	}
 
 	// Buffer pool stats
@@ -1024,6 +1473,8 @@ func (db *ObaDB) Stats() *storage.EngineStats {
 		bpStats := db.bufferPool.Stats()
 		stats.BufferPoolSize = bpStats.Size
 		stats.DirtyPages = bpStats.DirtyPages
+		stats.BufferPoolHits = bpStats.Hits
+		stats.BufferPoolMisses = bpStats.Misses
 	}
 
 	// Checkpoint LSN
@@ -1034,36 +1485,85 @@ func (db *ObaDB) Stats() *storage.EngineStats {
 	return stats
 }
 
-// updateIndexes updates indexes when an entry is modified.
-func (db *ObaDB) updateIndexes(oldEntry, newEntry *storage.Entry) error {
-	return db.updateIndexesWithLocation(oldEntry, newEntry, 0, 0)
+// IndexEntryCounts returns the number of entries of each index, by
+// attribute. It walks every index tree, so unlike Stats it reads all index
+// pages; it is meant for infrequent callers such as metrics scrapes.
+func (db *ObaDB) IndexEntryCounts() map[string]uint64 {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if db.closed || db.indexManager == nil {
+		return nil
+	}
+	return db.indexManager.EntryCounts()
 }
 
-// updateIndexesWithLocation updates indexes when an entry is modified, with storage location.
-func (db *ObaDB) updateIndexesWithLocation(oldEntry, newEntry *storage.Entry, pageID storage.PageID, slotID uint16) error {
+// updateIndexes queues the index changes of an entry modified by txn.
+func (db *ObaDB) updateIndexes(txn *tx.Transaction, oldEntry, newEntry *storage.Entry) {
+	db.updateIndexesWithLocation(txn, oldEntry, newEntry, 0, 0)
+}
+
+// updateIndexesWithLocation queues the index changes of an entry modified
+// by txn, with storage location. They are applied when txn commits.
+func (db *ObaDB) updateIndexesWithLocation(txn *tx.Transaction, oldEntry, newEntry *storage.Entry, pageID storage.PageID, slotID uint16) {
 	if db.indexManager == nil {
-		return nil
+		return
 	}
 
 	var oldIndexEntry, newIndexEntry *index.Entry
 
 	if oldEntry != nil {
 		oldIndexEntry = &index.Entry{
-			DN:         oldEntry.DN,
+			DN:         normalizeDN(oldEntry.DN),
 			Attributes: oldEntry.Attributes,
 		}
 	}
 
 	if newEntry != nil {
 		newIndexEntry = &index.Entry{
-			DN:         newEntry.DN,
+			DN:         normalizeDN(newEntry.DN),
 			Attributes: newEntry.Attributes,
 			PageID:     pageID,
 			SlotID:     slotID,
 		}
 	}
 
-	return db.indexManager.UpdateIndexes(oldIndexEntry, newIndexEntry)
+	db.indexBatchesMu.Lock()
+	defer db.indexBatchesMu.Unlock()
+
+	batch, ok := db.indexBatches[txn.ID]
+	if !ok {
+		if db.indexBatches == nil {
+			db.indexBatches = make(map[uint64]*index.Batch)
+		}
+		batch = index.NewBatch()
+		db.indexBatches[txn.ID] = batch
+	}
+	batch.ReindexEntry(oldIndexEntry, newIndexEntry)
+}
+
+// applyIndexBatch applies the index changes queued by a transaction, or
+// hands them to the deferred indexer when it is enabled.
+func (db *ObaDB) applyIndexBatch(txID uint64) error {
+	db.indexBatchesMu.Lock()
+	batch := db.indexBatches[txID]
+	delete(db.indexBatches, txID)
+	db.indexBatchesMu.Unlock()
+
+	if batch == nil {
+		return nil
+	}
+	if db.deferredIndexer != nil {
+		return db.deferredIndexer.QueueBatch(batch)
+	}
+	return db.indexManager.ApplyBatch(batch)
+}
+
+// discardIndexBatch drops the index changes queued by a transaction.
+func (db *ObaDB) discardIndexBatch(txID uint64) {
+	db.indexBatchesMu.Lock()
+	delete(db.indexBatches, txID)
+	db.indexBatchesMu.Unlock()
 }
 
 // checkUIDUnique checks if the uid attribute value is unique across all entries.
@@ -1077,13 +1577,16 @@ func (db *ObaDB) checkUIDUnique(txn *tx.Transaction, dn string, attrs map[string
 		return nil // UID uniqueness is enforced for user entries under ou=users
 	}
 
-	// Full scan over subtree to include all users.
-	iter := db.SearchByDN(txn, "", storage.ScopeSubtree)
+	// Full scan over subtree to include all users. A serializable
+	// transaction records the uid instead of the whole directory as read,
+	// so that only a concurrent entry with the same uid conflicts with it.
+	txn.AddReadValue("uid", uidValue)
+	iter := db.createAllEntriesIterator(txn.Context(), txn.Snapshot, txn.ID)
 	defer iter.Close()
 
 	for iter.Next() {
 		existing := iter.Entry()
-		if existing == nil || existing.DN == "" || existing.DN == dn {
+		if existing == nil || existing.DN == "" || normalizeDN(existing.DN) == dn {
 			continue
 		}
 		if !isUsersSubtreeDN(existing.DN) {
@@ -1128,125 +1631,11 @@ func isUsersSubtreeDN(dn string) bool {
 	return strings.Contains(","+normalized, ",ou=users,")
 }
 
-// normalizeDN normalizes a DN for consistent storage and lookup.
+// normalizeDN normalizes a DN for consistent storage and lookup. Entries
+// are keyed by the canonical form, so DNs that differ only in case, spacing
+// or the order of multi-valued RDN components name the same entry.
 func normalizeDN(dn string) string {
-	return strings.TrimSpace(strings.ToLower(dn))
-}
-
-// serializeEntry serializes an entry to bytes.
-func serializeEntry(entry *storage.Entry) ([]byte, error) {
-	if entry == nil {
-		return nil, ErrInvalidEntry
-	}
-
-	size := 4 + len(entry.DN) + 4
-	for name, values := range entry.Attributes {
-		size += 2 + len(name) + 4
-		for _, v := range values {
-			size += 4 + len(v)
-		}
-	}
-
-	buf := make([]byte, size)
-	offset := 0
-
-	binary.LittleEndian.PutUint32(buf[offset:], uint32(len(entry.DN)))
-	offset += 4
-	copy(buf[offset:], entry.DN)
-	offset += len(entry.DN)
-
-	binary.LittleEndian.PutUint32(buf[offset:], uint32(len(entry.Attributes)))
-	offset += 4
-
-	for name, values := range entry.Attributes {
-		binary.LittleEndian.PutUint16(buf[offset:], uint16(len(name)))
-		offset += 2
-		copy(buf[offset:], name)
-		offset += len(name)
-
-		binary.LittleEndian.PutUint32(buf[offset:], uint32(len(values)))
-		offset += 4
-
-		for _, v := range values {
-			binary.LittleEndian.PutUint32(buf[offset:], uint32(len(v)))
-			offset += 4
-			copy(buf[offset:], v)
-			offset += len(v)
-		}
-	}
-
-	return buf, nil
-}
-
-// deserializeEntry deserializes an entry from bytes.
-func deserializeEntry(dn string, data []byte) (*storage.Entry, error) {
-	if len(data) < 8 {
-		return nil, ErrInvalidEntry
-	}
-
-	entry := &storage.Entry{
-		DN:         dn,
-		Attributes: make(map[string][][]byte),
-	}
-
-	offset := 0
-	dnLen := binary.LittleEndian.Uint32(data[offset:])
-	offset += 4 + int(dnLen)
-
-	if offset+4 > len(data) {
-		return entry, nil
-	}
-
-	attrCount := binary.LittleEndian.Uint32(data[offset:])
-	offset += 4
-
-	for i := uint32(0); i < attrCount && offset < len(data); i++ {
-		if offset+2 > len(data) {
-			break
-		}
-
-		nameLen := binary.LittleEndian.Uint16(data[offset:])
-		offset += 2
-
-		if offset+int(nameLen) > len(data) {
-			break
-		}
-
-		name := string(data[offset : offset+int(nameLen)])
-		offset += int(nameLen)
-
-		if offset+4 > len(data) {
-			break
-		}
-
-		valueCount := binary.LittleEndian.Uint32(data[offset:])
-		offset += 4
-
-		values := make([][]byte, 0, valueCount)
-
-		for j := uint32(0); j < valueCount && offset < len(data); j++ {
-			if offset+4 > len(data) {
-				break
-			}
-
-			valueLen := binary.LittleEndian.Uint32(data[offset:])
-			offset += 4
-
-			if offset+int(valueLen) > len(data) {
-				break
-			}
-
-			value := make([]byte, valueLen)
-			copy(value, data[offset:offset+int(valueLen)])
-			offset += int(valueLen)
-
-			values = append(values, value)
-		}
-
-		entry.Attributes[name] = values
-	}
-
-	return entry, nil
+	return ldap.CanonicalDN(dn)
 }
 
 // errorIterator is an iterator that returns an error.
@@ -1262,6 +1651,7 @@ func (it *errorIterator) Close()                {}
 // dnIterator iterates over entries by DN.
 type dnIterator struct {
 	db         *ObaDB
+	ctx        context.Context
 	radixIter  *radix.RadixIterator
 	snapshot   uint64
 	activeTxID uint64
@@ -1275,6 +1665,9 @@ func (it *dnIterator) Next() bool {
 		if !ok {
 			return false
 		}
+		if !checkIteratorContext(it.ctx, &it.err) {
+			return false
+		}
 
 		version, err := it.db.versionStore.GetVisibleForTx(dn, it.snapshot, it.activeTxID)
 		if err != nil {
@@ -1304,9 +1697,21 @@ func (it *dnIterator) Entry() *storage.Entry { return it.current }
 func (it *dnIterator) Error() error          { return it.err }
 func (it *dnIterator) Close()                { it.radixIter.Close() }
 
+// checkIteratorContext sets *errp to the error of ctx, if it is done, and
+// reports whether ctx is still live. The iterators check it before
+// fetching each entry, so that the reads of a canceled transaction stop.
+func checkIteratorContext(ctx context.Context, errp *error) bool {
+	if err := ctx.Err(); err != nil {
+		*errp = err
+		return false
+	}
+	return true
+}
+
 // filterIterator iterates over entries matching a filter.
 type filterIterator struct {
 	db            *ObaDB
+	ctx           context.Context
 	radixIter     *radix.RadixIterator
 	filterMatcher storage.FilterMatcher
 	snapshot      uint64
@@ -1321,6 +1726,9 @@ func (it *filterIterator) Next() bool {
 		if !ok {
 			return false
 		}
+		if !checkIteratorContext(it.ctx, &it.err) {
+			return false
+		}
 
 		version, err := it.db.versionStore.GetVisibleForTx(dn, it.snapshot, it.activeTxID)
 		if err != nil {
@@ -1399,6 +1807,8 @@ func (db *ObaDB) saveCachesInternal() {
 
 // Ensure ObaDB implements StorageEngine interface.
 var _ storage.StorageEngine = (*ObaDB)(nil)
+var _ storage.SearchEstimator = (*ObaDB)(nil)
+var _ storage.IndexRebuilder = (*ObaDB)(nil)
 
 // initEncryption initializes encryption if configured.
 func (db *ObaDB) initEncryption() error {
@@ -1448,7 +1858,7 @@ func (db *ObaDB) decryptData(data []byte) ([]byte, error) {
 }
 
 // createAllEntriesIterator creates an iterator that iterates over all entries in the database.
-func (db *ObaDB) createAllEntriesIterator(snapshot uint64, activeTxID uint64) storage.Iterator {
+func (db *ObaDB) createAllEntriesIterator(ctx context.Context, snapshot uint64, activeTxID uint64) storage.Iterator {
 	// Collect all entries using IterateSubtree
 	var entries []iteratorEntry
 	db.radixTree.IterateSubtree("", func(dn string, pageID storage.PageID, slotID uint16) bool {
@@ -1458,6 +1868,7 @@ func (db *ObaDB) createAllEntriesIterator(snapshot uint64, activeTxID uint64) st
 
 	return &allEntriesIterator{
 		db:         db,
+		ctx:        ctx,
 		entries:    entries,
 		index:      0,
 		snapshot:   snapshot,
@@ -1474,6 +1885,7 @@ type iteratorEntry struct {
 // allEntriesIterator iterates over all entries in the database.
 type allEntriesIterator struct {
 	db         *ObaDB
+	ctx        context.Context
 	entries    []iteratorEntry
 	index      int
 	snapshot   uint64
@@ -1484,6 +1896,9 @@ type allEntriesIterator struct {
 
 func (it *allEntriesIterator) Next() bool {
 	for it.index < len(it.entries) {
+		if !checkIteratorContext(it.ctx, &it.err) {
+			return false
+		}
 		entry := it.entries[it.index]
 		it.index++
 
@@ -0,0 +1,33 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+func init() {
+	RegisterMigration(Migration{
+		Component:   ComponentPage,
+		From:        storage.PageFormatV0,
+		To:          storage.PageFormatV1,
+		Description: "widen page checksums to a 32-bit CRC32C of the header and data; rewrites every page",
+		Mode:        MigrationOffline,
+		Estimate: func(db *ObaDB) (int64, error) {
+			bytes := db.pageManager.Stats().FileSizeBytes
+			if db.indexPages != nil {
+				bytes += db.indexPages.Stats().FileSizeBytes
+			}
+			return bytes, nil
+		},
+		Forward: func(ctx context.Context, db *ObaDB) error {
+			if err := db.pageManager.UpgradePageFormat(); err != nil {
+				return err
+			}
+			if db.indexPages != nil {
+				return db.indexPages.UpgradePageFormat()
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,187 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// OpenReadOnly opens the database at path for reading only, as inspection
+// tools do. It may be used while a server has the database open.
+//
+// The data, index and WAL files are copied into memory, and the caches
+// read, under a shared hold of the lock file, which writers hold
+// exclusively while they change the files; the copy is a consistent
+// snapshot of the last completed write.
+// The WAL is then replayed, and the attribute indexes rebuilt if the
+// database is open or was not closed cleanly, in memory only: nothing in the
+// directory is created or changed, and changes made after OpenReadOnly
+// returns are not seen.
+//
+// The write methods of the returned database return ErrReadOnly.
+// Transactions can be begun to read with a fixed snapshot.
+func OpenReadOnly(path string, opts storage.EngineOptions) (*ObaDB, error) {
+	return openReadOnlyWithMigrations(path, opts, defaultMigrations)
+}
+
+// openReadOnlyWithMigrations is OpenReadOnly with the migrations of the
+// registry, which must not have offline ones pending.
+func openReadOnlyWithMigrations(path string, opts storage.EngineOptions, migrations *MigrationRegistry) (*ObaDB, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	// A lock file that does not exist and cannot be created has never been
+	// opened by a writer.
+	lock, err := storage.OpenFileLock(filepath.Join(path, LockFileName))
+	if err == nil {
+		if err := lock.RLock(); err != nil {
+			lock.Close()
+			return nil, err
+		}
+		defer lock.Close()
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	fs := storage.NewSnapshotFileSystem(opts.FileSystem)
+	for _, name := range []string{DataFileName, IndexFileName, WALFileName} {
+		if err := fs.Load(filepath.Join(path, name)); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	// The components open as for writing, on the copies
+	opts.FileSystem = fs
+	opts.ReadOnly = false
+	opts.CreateIfNotExists = false
+	opts.GCEnabled = false
+	opts.DeferredIndexing = false
+
+	db := &ObaDB{
+		options:      opts,
+		searchConfig: DefaultSearchConfig(),
+		path:         path,
+		readOnly:     true,
+		snapshot:     true,
+		migrations:   migrations,
+	}
+
+	if err := db.prepareMigrations(); err != nil {
+		return nil, err
+	}
+
+	if err := db.initComponents(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// writeGate holds the lock file of a database exclusively while any write
+// of the process is in progress, so that OpenReadOnly never copies the
+// files in the middle of one. The writes of a transaction last from its
+// first Put or Delete to its commit or rollback.
+type writeGate struct {
+	lock   *storage.FileLock
+	mu     sync.Mutex
+	active int
+	txns   map[uint64]bool
+}
+
+// openWriteGate opens the lock file of the database at path.
+func openWriteGate(path string) (*writeGate, error) {
+	lock, err := storage.OpenFileLock(filepath.Join(path, LockFileName))
+	if err != nil {
+		return nil, err
+	}
+	return &writeGate{lock: lock, txns: make(map[uint64]bool)}, nil
+}
+
+// enter starts a write, waiting for readers copying the files.
+func (g *writeGate) enter() error {
+	if g == nil {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.enterLocked()
+}
+
+// enterTx starts the writes of the transaction txID, if they have not
+// started yet.
+func (g *writeGate) enterTx(txID uint64) error {
+	if g == nil {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.txns[txID] {
+		return nil
+	}
+	if err := g.enterLocked(); err != nil {
+		return err
+	}
+	g.txns[txID] = true
+	return nil
+}
+
+func (g *writeGate) enterLocked() error {
+	if g.active == 0 {
+		if err := g.lock.Lock(); err != nil {
+			return err
+		}
+	}
+	g.active++
+	return nil
+}
+
+// leave ends a write started by enter.
+func (g *writeGate) leave() {
+	if g == nil {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.leaveLocked()
+}
+
+// leaveTx ends the writes of the transaction txID, if any.
+func (g *writeGate) leaveTx(txID uint64) {
+	if g == nil {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.txns[txID] {
+		delete(g.txns, txID)
+		g.leaveLocked()
+	}
+}
+
+func (g *writeGate) leaveLocked() {
+	g.active--
+	if g.active == 0 {
+		g.lock.Unlock()
+	}
+}
+
+// close closes the lock file.
+func (g *writeGate) close() error {
+	if g == nil {
+		return nil
+	}
+	return g.lock.Close()
+}
@@ -0,0 +1,213 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// readOnlyRound sets the cn of the first n workload entries to round in one
+// transaction.
+func readOnlyRound(db *ObaDB, n int, round string) error {
+	txn, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		entry := storage.NewEntry(crashDN(i))
+		entry.SetStringAttribute("objectclass", "person")
+		entry.SetStringAttribute("uid", fmt.Sprintf("user%d", i))
+		entry.SetStringAttribute("cn", round)
+		if err := db.Put(txn, entry); err != nil {
+			db.Rollback(txn)
+			return err
+		}
+	}
+	return db.Commit(txn)
+}
+
+// dirState returns the size and modification time of each file in dir.
+func dirState(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	state := make(map[string]string)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		state[path] = fmt.Sprintf("%d %s", info.Size(), info.ModTime())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+	return state
+}
+
+func TestOpenReadOnlySnapshot(t *testing.T) {
+	dir := t.TempDir()
+	populateRecoveryDB(t, dir, 3)
+
+	before := dirState(t, dir)
+	db, err := OpenReadOnly(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("OpenReadOnly() error = %v", err)
+	}
+
+	txn, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if _, err := db.Get(txn, "uid=user2,dc=example,dc=com"); err != nil {
+		t.Errorf("Get() error = %v", err)
+	}
+	if err := db.Put(txn, storage.NewEntry("uid=new,dc=example,dc=com")); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Put() error = %v, want ErrReadOnly", err)
+	}
+	if err := db.Delete(txn, "uid=user0,dc=example,dc=com"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Delete() error = %v, want ErrReadOnly", err)
+	}
+	db.Rollback(txn)
+	if err := db.CreateIndex("mail", storage.IndexEquality); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("CreateIndex() error = %v, want ErrReadOnly", err)
+	}
+	if err := db.Checkpoint(); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Checkpoint() error = %v, want ErrReadOnly", err)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// Nothing in the directory was created or changed
+	after := dirState(t, dir)
+	if len(after) != len(before) {
+		t.Errorf("directory has %d files after a read-only open, want %d", len(after), len(before))
+	}
+	for path, state := range before {
+		if after[path] != state {
+			t.Errorf("%s changed from %q to %q", path, state, after[path])
+		}
+	}
+
+	if _, err := OpenReadOnly(filepath.Join(dir, "missing"), storage.DefaultEngineOptions()); !os.IsNotExist(err) {
+		t.Errorf("OpenReadOnly(missing) error = %v, want not exist", err)
+	}
+}
+
+func TestOpenReadOnlyWhileOpen(t *testing.T) {
+	dir := t.TempDir()
+	writer, err := Open(dir, crashOptions())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer writer.Close()
+
+	if err := readOnlyRound(writer, 5, "first"); err != nil {
+		t.Fatalf("round failed: %v", err)
+	}
+
+	// The DN index of the writer is only in its WAL, which is replayed
+	reader, err := OpenReadOnly(dir, crashOptions())
+	if err != nil {
+		t.Fatalf("OpenReadOnly() error = %v", err)
+	}
+	defer reader.Close()
+
+	if err := readOnlyRound(writer, 5, "second"); err != nil {
+		t.Fatalf("round failed: %v", err)
+	}
+	if err := crashCommit(writer, 0, ""); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+
+	// The reader keeps the view it opened with
+	state := readCrashState(t, reader)
+	for i := 0; i < 5; i++ {
+		if cn := state[crashDN(i)]; cn != "first" {
+			t.Errorf("cn of %s = %q, want first", crashDN(i), cn)
+		}
+	}
+	for _, problem := range reader.CheckConsistency() {
+		t.Error(problem)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, OpenMarkerFileName)); err != nil {
+		t.Errorf("open marker of the writer: %v", err)
+	}
+}
+
+func TestOpenReadOnlyConcurrentWriter(t *testing.T) {
+	const entries = 8
+
+	dir := t.TempDir()
+	writer, err := Open(dir, crashOptions())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer writer.Close()
+	if err := readOnlyRound(writer, entries, "round0"); err != nil {
+		t.Fatalf("round failed: %v", err)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for round := 1; ; round++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if err := readOnlyRound(writer, entries, fmt.Sprintf("round%d", round)); err != nil {
+				t.Errorf("round %d failed: %v", round, err)
+				return
+			}
+			if round%10 == 0 {
+				if err := writer.Checkpoint(); err != nil {
+					t.Errorf("Checkpoint() error = %v", err)
+					return
+				}
+			}
+		}
+	}()
+
+	deadline := time.Now().Add(500 * time.Millisecond)
+	opens := 0
+	for time.Now().Before(deadline) || opens < 3 {
+		reader, err := OpenReadOnly(dir, crashOptions())
+		if err != nil {
+			t.Fatalf("OpenReadOnly() error = %v", err)
+		}
+		opens++
+
+		// Every entry is from the same committed round
+		state := readCrashState(t, reader)
+		round := state[crashDN(0)]
+		for i := 0; i < entries; i++ {
+			cn := state[crashDN(i)]
+			if cn == "" {
+				t.Fatalf("open %d: %s not found", opens, crashDN(i))
+			} else if round == "" {
+				round = cn
+			} else if cn != round {
+				t.Fatalf("open %d: %s is from %s, want %s", opens, crashDN(i), cn, round)
+			}
+		}
+		for _, problem := range reader.CheckConsistency() {
+			t.Errorf("open %d: %v", opens, problem)
+		}
+		reader.Close()
+	}
+
+	close(stop)
+	wg.Wait()
+}
@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/index"
+)
+
+// RebuildIndex implements storage.IndexRebuilder. The new index is built
+// in fresh pages from the entries visible when the rebuild starts, while
+// the current index keeps serving searches and writes. The index changes
+// committed in the meantime are replayed into the new index before it
+// replaces the old one. Canceling ctx stops the rebuild and leaves the
+// current index in place.
+func (db *ObaDB) RebuildIndex(ctx context.Context, attribute string, progress func(storage.IndexRebuildProgress)) (storage.IndexRebuildProgress, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if db.closed {
+		return storage.IndexRebuildProgress{}, ErrDatabaseClosed
+	}
+
+	if db.readOnly || db.txManager == nil {
+		return storage.IndexRebuildProgress{}, ErrDatabaseReadOnly
+	}
+
+	if err := db.writeGate.enter(); err != nil {
+		return storage.IndexRebuildProgress{}, err
+	}
+	defer db.writeGate.leave()
+
+	// The changes of every commit published after the snapshot reach the
+	// indexes after the rebuild began, so the rebuild logs them
+	var rebuild *index.Rebuild
+	var snapshot uint64
+	var err error
+	db.txManager.WithoutPublishing(func() {
+		rebuild, err = db.indexManager.BeginRebuild(attribute)
+		snapshot = db.snapshotManager.CurrentTimestamp()
+	})
+	if err != nil {
+		return storage.IndexRebuildProgress{}, err
+	}
+
+	var dns []string
+	db.radixTree.IterateSubtree("", func(dn string, pageID storage.PageID, slotID uint16) bool {
+		dns = append(dns, dn)
+		return true
+	})
+
+	for i, dn := range dns {
+		if err := ctx.Err(); err != nil {
+			rebuild.Abort()
+			return rebuild.Progress(), err
+		}
+
+		// Entries deleted since the DN walk are skipped
+		version, err := db.versionStore.GetVisibleForTx(dn, snapshot, 0)
+		if err != nil {
+			continue
+		}
+		data, err := db.decryptData(version.GetData())
+		var entry *storage.Entry
+		if err == nil {
+			entry, err = deserializeEntry(dn, data)
+		}
+		if err == nil {
+			err = rebuild.Add(&index.Entry{
+				DN:         dn,
+				Attributes: entry.Attributes,
+				PageID:     version.PageID,
+				SlotID:     version.SlotID,
+			})
+		}
+		if err != nil {
+			rebuild.Abort()
+			return rebuild.Progress(), err
+		}
+
+		if progress != nil && (i+1)%index.RebuildProgressInterval == 0 {
+			progress(rebuild.Progress())
+		}
+	}
+
+	p, err := rebuild.Commit()
+	if err == nil && progress != nil {
+		progress(p)
+	}
+	return p, err
+}
@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+func TestRebuildIndexOnline(t *testing.T) {
+	dir := t.TempDir()
+	populateRecoveryDB(t, dir, 20)
+
+	db, err := Open(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// Entries written during the rebuild reach the new index
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 20; i < 70; i++ {
+			txIface, _ := db.Begin()
+			entry := createTestEntry(fmt.Sprintf("uid=user%d,dc=example,dc=com", i), "person", fmt.Sprintf("User %d", i))
+			entry.SetStringAttribute("uid", fmt.Sprintf("user%d", i))
+			if err := db.Put(txIface, entry); err != nil {
+				t.Errorf("Failed to put entry: %v", err)
+				return
+			}
+			if err := db.Commit(txIface); err != nil {
+				t.Errorf("Failed to commit: %v", err)
+				return
+			}
+		}
+	}()
+
+	var reports int
+	p, err := db.RebuildIndex(context.Background(), "uid", func(storage.IndexRebuildProgress) {
+		reports++
+	})
+	wg.Wait()
+	if err != nil {
+		t.Fatalf("RebuildIndex failed: %v", err)
+	}
+	if p.EntriesScanned < 20 || reports == 0 {
+		t.Errorf("progress = %+v after %d reports", p, reports)
+	}
+
+	for i := 0; i < 70; i++ {
+		refs, err := db.indexManager.Search("uid", []byte(fmt.Sprintf("user%d", i)))
+		if err != nil || len(refs) == 0 {
+			t.Errorf("uid index lookup for user%d = %v, %v; want a ref", i, refs, err)
+		}
+	}
+}
+
+func TestRebuildIndexCanceled(t *testing.T) {
+	dir := t.TempDir()
+	populateRecoveryDB(t, dir, 5)
+
+	db, err := Open(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := db.RebuildIndex(ctx, "uid", nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("RebuildIndex error = %v, want %v", err, context.Canceled)
+	}
+
+	// The current index is kept and a new rebuild can start
+	if refs, err := db.indexManager.Search("uid", []byte("user3")); err != nil || len(refs) != 1 {
+		t.Errorf("uid index lookup for user3 = %v, %v; want one ref", refs, err)
+	}
+	if _, err := db.RebuildIndex(context.Background(), "uid", nil); err != nil {
+		t.Errorf("RebuildIndex after cancel failed: %v", err)
+	}
+}
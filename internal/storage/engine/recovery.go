@@ -0,0 +1,365 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/index"
+	"github.com/KilimcininKorOglu/oba/internal/storage/radix"
+)
+
+// NewRecoveryTool returns a storage.RecoveryTool for the database at path.
+// Its callbacks keep the DN index, its snapshot and the attribute indexes
+// consistent with the repaired data file, so that the database opens
+// normally afterwards. The database must not be open.
+func NewRecoveryTool(path string, opts storage.EngineOptions) (*storage.RecoveryTool, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Only the options and the encryption key of db are used.
+	db := &ObaDB{options: opts, path: path}
+	if err := db.initEncryption(); err != nil {
+		return nil, err
+	}
+
+	tool := storage.NewRecoveryTool(storage.RecoveryToolConfig{
+		DataPath:      filepath.Join(path, DataFileName),
+		WALPath:       filepath.Join(path, WALFileName),
+		PageSize:      opts.PageSize,
+		EncryptionKey: db.encryptionKey,
+	})
+	tool.SetReplayCallback(db.recoverReplay)
+	tool.SetPruneCallback(db.recoverPrune)
+	tool.SetIndexRebuilder(db.recoverRebuildIndex)
+	tool.SetVerifyCallback(db.recoverVerify)
+
+	return tool, nil
+}
+
+// markOpen creates the open marker of the database. If the marker already
+// exists the database was not closed cleanly, and the attribute indexes,
+// which are only saved at close, are rebuilt from the entries first. A
+// database opened by OpenReadOnly rebuilds its copies and creates no marker.
+func (db *ObaDB) markOpen() error {
+	path := filepath.Join(db.path, OpenMarkerFileName)
+
+	if _, err := os.Stat(path); err == nil {
+		entries, err := db.readRecoveryEntries(db.pageManager, db.radixTree, nil)
+		if err != nil {
+			return fmt.Errorf("failed to rebuild indexes after unclean shutdown: %w", err)
+		}
+		for _, attr := range db.indexManager.ListIndexes() {
+			if _, err := db.indexManager.RebuildIndex(attr, entries); err != nil {
+				return fmt.Errorf("failed to rebuild index %s after unclean shutdown: %w", attr, err)
+			}
+		}
+	}
+
+	if db.readOnly {
+		return nil
+	}
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		return err
+	}
+	// The marker and any database files just created survive a crash
+	return storage.SyncDir(db.path)
+}
+
+// recoverReplay folds the DN changes in the WAL into the radix tree before
+// ForceCheckpoint empties it.
+func (db *ObaDB) recoverReplay(pm *storage.PageManager, wal *storage.WAL) error {
+	tree, err := db.loadRecoveryRadix(pm, wal)
+	if err != nil {
+		return err
+	}
+
+	// The WAL is empty once the checkpoint is written, so the snapshot is
+	// saved as of its start.
+	return db.saveRecoveryRadix(tree, 0)
+}
+
+// recoverPrune removes the entries stored on pages that are about to be
+// pruned from the radix tree, then rebuilds every attribute index from the
+// remaining entries.
+func (db *ObaDB) recoverPrune(pm *storage.PageManager, wal *storage.WAL, pages []storage.PageID) error {
+	tree, err := db.loadRecoveryRadix(pm, wal)
+	if err != nil {
+		return err
+	}
+
+	pruned := make(map[storage.PageID]bool, len(pages))
+	for _, id := range pages {
+		pruned[id] = true
+	}
+
+	var dns []string
+	tree.IterateSubtree("", func(dn string, pageID storage.PageID, slotID uint16) bool {
+		if pruned[pageID] {
+			dns = append(dns, dn)
+		}
+		return true
+	})
+
+	for _, dn := range dns {
+		if err := tree.Delete(dn); err != nil {
+			return err
+		}
+	}
+
+	if err := db.saveRecoveryRadix(tree, wal.CurrentLSN()); err != nil {
+		return err
+	}
+
+	entries, err := db.readRecoveryEntries(pm, tree, pruned)
+	if err != nil {
+		return err
+	}
+
+	return db.withRecoveryIndexes(func(im *index.IndexManager) error {
+		for _, attr := range im.ListIndexes() {
+			if _, err := im.RebuildIndex(attr, entries); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// recoverRebuildIndex recreates the index for attr from the entries in the
+// data file.
+func (db *ObaDB) recoverRebuildIndex(pm *storage.PageManager, wal *storage.WAL, attr string, progress func(storage.IndexRebuildProgress)) (storage.IndexRebuildProgress, error) {
+	var p storage.IndexRebuildProgress
+	tree, err := db.loadRecoveryRadix(pm, wal)
+	if err != nil {
+		return p, err
+	}
+
+	entries, err := db.readRecoveryEntries(pm, tree, nil)
+	if err != nil {
+		return p, err
+	}
+
+	err = db.withRecoveryIndexes(func(im *index.IndexManager) error {
+		p, err = im.RebuildIndexWithProgress(attr, entries, progress)
+		return err
+	})
+	return p, err
+}
+
+// recoverVerify checks that every entry in the DN index is readable from
+// its own data page, verifies the index file and the B+ tree of each
+// attribute index, and cross-checks the index references with the entries.
+// The cross-check is skipped after an unclean shutdown, since the indexes
+// are only saved at close and are rebuilt at the next open.
+func (db *ObaDB) recoverVerify(pm *storage.PageManager, wal *storage.WAL, report *storage.VerificationReport) error {
+	addError := func(file string, pageID storage.PageID, err error) {
+		report.Errors = append(report.Errors, storage.PageError{File: file, PageID: pageID, Err: err})
+	}
+
+	tree, err := db.loadRecoveryRadix(pm, wal)
+	if err != nil {
+		addError(DataFileName, pm.Header().RootPages.DNIndex, fmt.Errorf("DN index: %w", err))
+		return nil
+	}
+
+	owners := make(map[storage.PageID]string)
+	dns := make(map[string]bool)
+	var entries []*index.Entry
+
+	tree.IterateSubtree("", func(dn string, pageID storage.PageID, slotID uint16) bool {
+		dns[dn] = true
+		if pageID == 0 {
+			return true
+		}
+		if owner, ok := owners[pageID]; ok {
+			addError(DataFileName, pageID, fmt.Errorf("entry %s: page is also used by %s", dn, owner))
+			return true
+		}
+		owners[pageID] = dn
+
+		page, err := pm.ReadPage(pageID)
+		if err == nil && page.Header.PageType != storage.PageTypeData {
+			err = fmt.Errorf("%w: %s page", ErrInvalidEntry, page.Header.PageType)
+		}
+		var data []byte
+		if err == nil {
+			data, err = readEntryData(pm, pageID)
+		}
+		if err == nil {
+			data, err = db.decryptData(data)
+		}
+		var entry *storage.Entry
+		if err == nil {
+			entry, err = deserializeEntry(dn, data)
+		}
+		if err != nil {
+			addError(DataFileName, pageID, fmt.Errorf("entry %s: %w", dn, err))
+			return true
+		}
+
+		entries = append(entries, &index.Entry{DN: dn, Attributes: entry.Attributes})
+		return true
+	})
+
+	indexPM, err := storage.OpenPageManager(filepath.Join(db.path, IndexFileName), storage.Options{
+		PageSize: db.options.PageSize,
+		ReadOnly: true,
+	})
+	if err != nil {
+		addError(IndexFileName, 0, err)
+		return nil
+	}
+	defer indexPM.Close()
+	report.Merge(indexPM.Verify())
+
+	// The manager is not closed, since closing saves its metadata
+	im, err := index.NewIndexManager(indexPM)
+	if err != nil {
+		addError(IndexFileName, 0, fmt.Errorf("index metadata: %w", err))
+		return nil
+	}
+
+	_, statErr := os.Stat(filepath.Join(db.path, OpenMarkerFileName))
+	unclean := statErr == nil
+	if unclean {
+		report.Warnings = append(report.Warnings,
+			"the database was not closed cleanly; attribute indexes are rebuilt at the next open and were not cross-checked")
+	}
+
+	for _, attr := range im.ListIndexes() {
+		idx, ok := im.GetIndex(attr)
+		if !ok {
+			continue
+		}
+		report.Errors = append(report.Errors, idx.Tree.Verify()...)
+		if unclean {
+			continue
+		}
+		checkIndex(im, idx, entries, dns, func(format string, args ...interface{}) {
+			addError(IndexFileName, idx.RootPageID, fmt.Errorf(format, args...))
+		})
+	}
+
+	return nil
+}
+
+// loadRecoveryRadix loads the radix tree the way Open does, but replays the
+// whole WAL when no snapshot is usable, since the root page only reflects
+// the last checkpoint.
+func (db *ObaDB) loadRecoveryRadix(pm *storage.PageManager, wal *storage.WAL) (*radix.RadixTree, error) {
+	header := pm.Header()
+	if header.RootPages.DNIndex == 0 {
+		return nil, radix.ErrTreeNotInitialized
+	}
+
+	tree, err := radix.NewRadixTreeWithRoot(pm, header.RootPages.DNIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	cachePath := filepath.Join(db.path, CacheDir, RadixCacheFileName)
+	fromLSN, err := tree.LoadSnapshot(cachePath, wal.CurrentLSN())
+	if err != nil {
+		fromLSN = 0
+	}
+
+	if _, err := tree.ReplayWAL(wal, fromLSN); err != nil {
+		return nil, err
+	}
+
+	return tree, nil
+}
+
+// saveRecoveryRadix persists the radix tree and replaces its snapshot with
+// one taken at txID. The entry cache is removed, since it may hold entries
+// that recovery has changed or dropped.
+func (db *ObaDB) saveRecoveryRadix(tree *radix.RadixTree, txID uint64) error {
+	if err := tree.Persist(); err != nil {
+		return err
+	}
+
+	cacheDir := filepath.Join(db.path, CacheDir)
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return err
+	}
+
+	if err := tree.SaveCache(filepath.Join(cacheDir, RadixCacheFileName), txID); err != nil {
+		return err
+	}
+
+	err := os.Remove(filepath.Join(cacheDir, EntryCacheFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// readRecoveryEntries reads every entry in the radix tree from the data
+// file, skipping entries stored on the excluded pages.
+func (db *ObaDB) readRecoveryEntries(pm *storage.PageManager, tree *radix.RadixTree, exclude map[storage.PageID]bool) ([]*index.Entry, error) {
+	var entries []*index.Entry
+	var readErr error
+
+	tree.IterateSubtree("", func(dn string, pageID storage.PageID, slotID uint16) bool {
+		if pageID == 0 || exclude[pageID] {
+			return true
+		}
+
+		data, err := readEntryData(pm, pageID)
+		if err == nil {
+			data, err = db.decryptData(data)
+		}
+		var entry *storage.Entry
+		if err == nil {
+			entry, err = deserializeEntry(dn, data)
+		}
+		if err != nil {
+			readErr = fmt.Errorf("entry %s on page %d: %w", dn, pageID, err)
+			return false
+		}
+
+		entries = append(entries, &index.Entry{
+			DN:         dn,
+			Attributes: entry.Attributes,
+			PageID:     pageID,
+			SlotID:     slotID,
+		})
+		return true
+	})
+
+	return entries, readErr
+}
+
+// withRecoveryIndexes opens the index file, calls fn with its index manager
+// and closes it again.
+func (db *ObaDB) withRecoveryIndexes(fn func(im *index.IndexManager) error) error {
+	pm, err := storage.OpenPageManager(filepath.Join(db.path, IndexFileName), storage.Options{
+		PageSize:    db.options.PageSize,
+		CreateIfNew: false,
+	})
+	if err != nil {
+		return err
+	}
+
+	im, err := index.NewIndexManager(pm)
+	if err != nil {
+		pm.Close()
+		return err
+	}
+
+	err = fn(im)
+
+	if closeErr := im.Close(); err == nil {
+		err = closeErr
+	}
+	if syncErr := pm.Sync(); err == nil {
+		err = syncErr
+	}
+	if closeErr := pm.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,363 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/index"
+)
+
+// populateRecoveryDB writes n entries with a uid to a new database at dir.
+func populateRecoveryDB(t *testing.T, dir string, n int) {
+	t.Helper()
+
+	db, err := Open(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	txIface, _ := db.Begin()
+	for i := 0; i < n; i++ {
+		entry := createTestEntry(fmt.Sprintf("uid=user%d,dc=example,dc=com", i), "person", fmt.Sprintf("User %d", i))
+		entry.SetStringAttribute("uid", fmt.Sprintf("user%d", i))
+		if err := db.Put(txIface, entry); err != nil {
+			t.Fatalf("Failed to put entry: %v", err)
+		}
+	}
+	if err := db.Commit(txIface); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("Failed to close database: %v", err)
+	}
+}
+
+// withTestIndexes opens the index file of the database at dir.
+func withTestIndexes(t *testing.T, dir string, fn func(im *index.IndexManager)) {
+	t.Helper()
+
+	db := &ObaDB{options: storage.DefaultEngineOptions(), path: dir}
+	if err := db.withRecoveryIndexes(func(im *index.IndexManager) error {
+		fn(im)
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to open indexes: %v", err)
+	}
+}
+
+func TestRecoveryToolRebuildIndex(t *testing.T) {
+	dir := t.TempDir()
+	populateRecoveryDB(t, dir, 20)
+
+	// Lose the contents of every index
+	withTestIndexes(t, dir, func(im *index.IndexManager) {
+		if err := im.ClearAll(); err != nil {
+			t.Fatalf("Failed to clear indexes: %v", err)
+		}
+	})
+
+	tool, err := NewRecoveryTool(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to create recovery tool: %v", err)
+	}
+
+	indexed, err := tool.RebuildIndex("UID")
+	if err != nil {
+		t.Fatalf("RebuildIndex failed: %v", err)
+	}
+	if indexed != 20 {
+		t.Errorf("Expected 20 indexed entries, got %d", indexed)
+	}
+
+	withTestIndexes(t, dir, func(im *index.IndexManager) {
+		for i := 0; i < 20; i++ {
+			refs, err := im.Search("uid", []byte(fmt.Sprintf("user%d", i)))
+			if err != nil || len(refs) != 1 {
+				t.Errorf("uid index lookup for user%d = %v, %v; want one ref", i, refs, err)
+			}
+		}
+
+		// Other indexes are left alone
+		if refs, _ := im.Search("cn", []byte("User 0")); len(refs) != 0 {
+			t.Errorf("cn index was rebuilt: %v", refs)
+		}
+	})
+
+	// A missing index is created as an equality index
+	if _, err := tool.RebuildIndex("title"); err != nil {
+		t.Fatalf("RebuildIndex failed: %v", err)
+	}
+	withTestIndexes(t, dir, func(im *index.IndexManager) {
+		idx, ok := im.GetIndex("title")
+		if !ok || idx.Type != index.IndexEquality {
+			t.Errorf("Expected equality index for title, got %+v", idx)
+		}
+	})
+}
+
+func TestRecoveryToolPrunePagesRemovesEntries(t *testing.T) {
+	dir := t.TempDir()
+	populateRecoveryDB(t, dir, 10)
+
+	const damagedDN = "uid=user3,dc=example,dc=com"
+
+	db, err := Open(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	pageID, _, found := db.radixTree.Lookup(damagedDN)
+	if !found {
+		t.Fatalf("Entry %s not found", damagedDN)
+	}
+	db.Close()
+
+	// Physically damage the page holding the entry
+	f, err := os.OpenFile(filepath.Join(dir, DataFileName), os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open data file: %v", err)
+	}
+	garbage := make([]byte, storage.PageSize)
+	for i := range garbage {
+		garbage[i] = 0xEE
+	}
+	if _, err := f.WriteAt(garbage, int64(pageID)*storage.PageSize); err != nil {
+		t.Fatalf("Failed to corrupt page: %v", err)
+	}
+	f.Close()
+
+	tool, err := NewRecoveryTool(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to create recovery tool: %v", err)
+	}
+
+	// Rebuilding reports the damaged entry instead of indexing garbage
+	if _, err := tool.RebuildIndex("uid"); err == nil {
+		t.Error("Expected RebuildIndex to fail on the damaged entry")
+	}
+
+	pruned, err := tool.PrunePages(pageID, pageID)
+	if err != nil {
+		t.Fatalf("PrunePages failed: %v", err)
+	}
+	if pruned != 1 {
+		t.Errorf("Expected 1 pruned page, got %d", pruned)
+	}
+
+	db, err = Open(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+
+	txIface, _ := db.Begin()
+	if _, err := db.Get(txIface, damagedDN); err == nil {
+		t.Errorf("Entry %s still present after pruning its page", damagedDN)
+	}
+	for i := 0; i < 10; i++ {
+		dn := fmt.Sprintf("uid=user%d,dc=example,dc=com", i)
+		if dn == damagedDN {
+			continue
+		}
+		if _, err := db.Get(txIface, dn); err != nil {
+			t.Errorf("Entry %s lost after pruning: %v", dn, err)
+		}
+	}
+	db.Rollback(txIface)
+	if got := db.radixTree.EntryCount(); got != 9 {
+		t.Errorf("Expected 9 entries, got %d", got)
+	}
+	db.Close()
+
+	withTestIndexes(t, dir, func(im *index.IndexManager) {
+		if refs, _ := im.Search("uid", []byte("user3")); len(refs) != 0 {
+			t.Errorf("uid index still references the pruned entry: %v", refs)
+		}
+		if refs, _ := im.Search("uid", []byte("user4")); len(refs) != 1 {
+			t.Errorf("uid index lost user4: %v", refs)
+		}
+	})
+}
+
+func TestRecoveryToolForceCheckpointReplaysDNIndex(t *testing.T) {
+	dir := t.TempDir()
+
+	db, err := Open(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	putEntries := func(from, to int) {
+		txIface, _ := db.Begin()
+		for i := from; i < to; i++ {
+			dn := fmt.Sprintf("uid=user%d,dc=example,dc=com", i)
+			if err := db.Put(txIface, createTestEntry(dn, "person", dn)); err != nil {
+				t.Fatalf("Failed to put entry: %v", err)
+			}
+		}
+		if err := db.Commit(txIface); err != nil {
+			t.Fatalf("Failed to commit: %v", err)
+		}
+	}
+
+	// Enough entries that the tree no longer fits its root page
+	putEntries(0, 300)
+	if err := db.Checkpoint(); err != nil {
+		t.Fatalf("Failed to checkpoint: %v", err)
+	}
+	cachePath := filepath.Join(dir, CacheDir, RadixCacheFileName)
+	snapshot, err := os.ReadFile(cachePath)
+	if err != nil {
+		t.Fatalf("Failed to read radix snapshot: %v", err)
+	}
+
+	putEntries(300, 350)
+	db.Close()
+
+	// Simulate a crash after the checkpoint by restoring the older snapshot
+	if err := os.WriteFile(cachePath, snapshot, 0644); err != nil {
+		t.Fatalf("Failed to restore snapshot: %v", err)
+	}
+
+	tool, err := NewRecoveryTool(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to create recovery tool: %v", err)
+	}
+	if err := tool.ForceCheckpoint(); err != nil {
+		t.Fatalf("ForceCheckpoint failed: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, WALFileName))
+	if err != nil {
+		t.Fatalf("Failed to stat WAL: %v", err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("Expected empty WAL, got %d bytes", info.Size())
+	}
+
+	db, err = Open(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+
+	if got := db.radixTree.EntryCount(); got != 350 {
+		t.Errorf("Expected 350 entries after checkpoint, got %d", got)
+	}
+	if _, _, found := db.radixTree.Lookup("uid=user349,dc=example,dc=com"); !found {
+		t.Error("Entry written after the snapshot was lost")
+	}
+}
+
+func TestRecoveryToolVerify(t *testing.T) {
+	dir := t.TempDir()
+	populateRecoveryDB(t, dir, 20)
+
+	tool, err := NewRecoveryTool(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to create recovery tool: %v", err)
+	}
+
+	report, err := tool.Verify()
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !report.OK() || len(report.Warnings) != 0 {
+		t.Errorf("Expected a clean report, got errors %v, warnings %v", report.Errors, report.Warnings)
+	}
+	if report.PagesChecked < 20 {
+		t.Errorf("Expected at least 20 pages checked, got %d", report.PagesChecked)
+	}
+}
+
+func TestRecoveryToolVerifyDamagedEntry(t *testing.T) {
+	dir := t.TempDir()
+	populateRecoveryDB(t, dir, 10)
+
+	db, err := Open(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	pageID, _, found := db.radixTree.Lookup("uid=user3,dc=example,dc=com")
+	if !found {
+		t.Fatal("Entry not found")
+	}
+	db.Close()
+
+	// Flip the first bytes of the entry, leaving the page header intact
+	f, err := os.OpenFile(filepath.Join(dir, DataFileName), os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open data file: %v", err)
+	}
+	if _, err := f.WriteAt([]byte{0xEE, 0xEE, 0xEE, 0xEE}, int64(pageID)*storage.PageSize+storage.PageHeaderSize); err != nil {
+		t.Fatalf("Failed to corrupt page: %v", err)
+	}
+	f.Close()
+
+	tool, err := NewRecoveryTool(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to create recovery tool: %v", err)
+	}
+	report, err := tool.Verify()
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+
+	var checksum, entry bool
+	for _, e := range report.Errors {
+		if e.File != DataFileName || e.PageID != pageID {
+			t.Errorf("Unexpected error: %v", e)
+			continue
+		}
+		// The entry check finds the page quarantined by the read
+		switch {
+		case errors.Is(e, storage.ErrPageCorrupted):
+			entry = true
+		case errors.Is(e, storage.ErrInvalidChecksum):
+			checksum = true
+		default:
+			t.Errorf("Unexpected error: %v", e)
+		}
+	}
+	if !checksum || !entry {
+		t.Errorf("Expected checksum and entry errors on page %d, got %v", pageID, report.Errors)
+	}
+}
+
+func TestRecoveryToolVerifyIndexMismatch(t *testing.T) {
+	dir := t.TempDir()
+	populateRecoveryDB(t, dir, 10)
+
+	var root storage.PageID
+	withTestIndexes(t, dir, func(im *index.IndexManager) {
+		idx, ok := im.GetIndex("uid")
+		if !ok {
+			t.Fatal("uid index not found")
+		}
+		root = idx.RootPageID
+		if err := idx.Tree.DeleteKey([]byte("user5")); err != nil {
+			t.Fatalf("Failed to delete key: %v", err)
+		}
+	})
+
+	tool, err := NewRecoveryTool(dir, storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to create recovery tool: %v", err)
+	}
+	report, err := tool.Verify()
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+
+	if len(report.Errors) != 1 {
+		t.Fatalf("Expected one error, got %v", report.Errors)
+	}
+	e := report.Errors[0]
+	if e.File != IndexFileName || e.PageID != root || !strings.Contains(e.Error(), "user5") {
+		t.Errorf("Expected the missing uid reference of user5, got %v", e)
+	}
+}
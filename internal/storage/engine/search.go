@@ -0,0 +1,215 @@
+package engine
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/btree"
+	"github.com/KilimcininKorOglu/oba/internal/storage/index"
+	"github.com/KilimcininKorOglu/oba/internal/trace"
+)
+
+// SearchConfig controls how SearchByFilter evaluates filters.
+type SearchConfig struct {
+	// IndexSplitEnabled answers the equality and presence children of an
+	// AND filter from their indexes and intersects the candidate sets, in
+	// the manner of a bitmap index scan. The full filter is rechecked on
+	// every candidate, so children without an index act as post-filters.
+	//
+	// Equality indexes are keyed by the stored value, so an assertion that
+	// differs from the stored value only in case finds no candidates.
+	IndexSplitEnabled bool
+}
+
+// DefaultSearchConfig returns the default search configuration, which scans
+// the base subtree and applies the filter to every entry.
+func DefaultSearchConfig() SearchConfig {
+	return SearchConfig{}
+}
+
+// SetSearchConfig sets the search configuration.
+func (db *ObaDB) SetSearchConfig(cfg SearchConfig) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.searchConfig = cfg
+}
+
+// EstimateSearch implements storage.SearchEstimator. Unindexed searches are
+// estimated from the entry count of the base DN's subtree.
+func (db *ObaDB) EstimateSearch(baseDN string, f interface{}) int {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if db.closed {
+		return 0
+	}
+
+	baseDN = normalizeDN(baseDN)
+	if matcher, ok := f.(storage.FilterMatcher); ok && db.searchConfig.IndexSplitEnabled {
+		if dns, ok := db.indexCandidates(matcher); ok {
+			n := 0
+			for _, dn := range dns {
+				if inSubtree(dn, baseDN) {
+					n++
+				}
+			}
+			return n
+		}
+	}
+
+	if baseDN == "" {
+		return int(db.radixTree.EntryCount())
+	}
+	count, err := db.radixTree.GetSubtreeCount(baseDN)
+	if err != nil {
+		return int(db.radixTree.EntryCount())
+	}
+	return int(count)
+}
+
+// startSearchSpan starts a span for a phase of the search of matcher. It
+// returns a no-op span if the search is not traced.
+func startSearchSpan(matcher storage.FilterMatcher, name string) trace.Span {
+	if starter, ok := matcher.(storage.SpanStarter); ok {
+		return starter.StartSpan(name)
+	}
+	return trace.SpanFromContext(context.Background())
+}
+
+// indexCandidates returns the DNs of the entries that satisfy every indexed
+// term of the matcher, sorted. It returns false if the matcher has no term
+// with a usable index. Must be called with db.mu held.
+func (db *ObaDB) indexCandidates(matcher storage.FilterMatcher) ([]string, bool) {
+	provider, ok := matcher.(storage.IndexTermProvider)
+	if !ok || db.indexManager == nil {
+		return nil, false
+	}
+
+	var sets []storage.RefIterator
+	for _, term := range provider.IndexTerms() {
+		idx, exists := db.indexManager.GetIndex(term.Attribute)
+		if !exists {
+			continue
+		}
+
+		if len(sets) == 0 && db.deferredIndexer != nil {
+			// Queued updates must be visible to the lookup.
+			if err := db.deferredIndexer.Flush(); err != nil {
+				return nil, false
+			}
+		}
+
+		var refs []btree.EntryRef
+		var err error
+		switch {
+		case term.Value == nil && idx.Type == index.IndexPresence:
+			refs, err = db.indexManager.SearchPresence(term.Attribute)
+		case term.Value != nil && idx.Type == index.IndexEquality:
+			refs, err = db.indexManager.Search(term.Attribute, term.Value)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, false
+		}
+
+		// The references may share memory with cached tree nodes
+		refs = append([]btree.EntryRef(nil), refs...)
+		sortEntryRefs(refs)
+		sets = append(sets, storage.SliceRefIterator(refs))
+	}
+
+	if len(sets) == 0 {
+		return nil, false
+	}
+
+	result := storage.IntersectionIterator(sets...)
+	defer result.Close()
+
+	// Stale references from older versions may repeat a DN
+	seen := make(map[string]struct{})
+	var dns []string
+	for result.Next() {
+		dn := normalizeDN(result.Ref().DN)
+		if _, dup := seen[dn]; dup {
+			continue
+		}
+		seen[dn] = struct{}{}
+		dns = append(dns, dn)
+	}
+	sort.Strings(dns)
+
+	return dns, true
+}
+
+// sortEntryRefs sorts references with storage.CompareEntryRefs.
+func sortEntryRefs(refs []btree.EntryRef) {
+	sort.Slice(refs, func(i, j int) bool {
+		return storage.CompareEntryRefs(refs[i], refs[j]) < 0
+	})
+}
+
+// inSubtree reports whether dn is baseDN or below it. Both DNs must be
+// normalized.
+func inSubtree(dn, baseDN string) bool {
+	return baseDN == "" || dn == baseDN || strings.HasSuffix(dn, ","+baseDN)
+}
+
+// candidateIterator iterates over index candidates, rechecking each against
+// the snapshot and the full filter.
+type candidateIterator struct {
+	db            *ObaDB
+	ctx           context.Context
+	dns           []string
+	baseDN        string
+	filterMatcher storage.FilterMatcher
+	snapshot      uint64
+	activeTxID    uint64
+	current       *storage.Entry
+	err           error
+}
+
+func (it *candidateIterator) Next() bool {
+	for len(it.dns) > 0 {
+		dn := it.dns[0]
+		it.dns = it.dns[1:]
+
+		if !inSubtree(dn, it.baseDN) {
+			continue
+		}
+		if !checkIteratorContext(it.ctx, &it.err) {
+			return false
+		}
+
+		version, err := it.db.versionStore.GetVisibleForTx(dn, it.snapshot, it.activeTxID)
+		if err != nil {
+			continue
+		}
+
+		data, err := it.db.decryptData(version.GetData())
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		entry, err := deserializeEntry(dn, data)
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		if !it.filterMatcher.Match(entry) {
+			continue
+		}
+
+		it.current = entry
+		return true
+	}
+	return false
+}
+
+func (it *candidateIterator) Entry() *storage.Entry { return it.current }
+func (it *candidateIterator) Error() error          { return it.err }
+func (it *candidateIterator) Close()                {}
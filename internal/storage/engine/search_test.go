@@ -0,0 +1,232 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/tx"
+)
+
+// andMatcher is an AND of equality assertions, with non-indexable
+// assertions given as attr=value on attributes without an index.
+type andMatcher struct {
+	terms []storage.IndexTerm
+}
+
+func newAndMatcher(assertions ...string) *andMatcher {
+	m := &andMatcher{}
+	for _, a := range assertions {
+		attr, value, _ := strings.Cut(a, "=")
+		m.terms = append(m.terms, storage.IndexTerm{Attribute: attr, Value: []byte(value)})
+	}
+	return m
+}
+
+func (m *andMatcher) Match(entry *storage.Entry) bool {
+	for _, term := range m.terms {
+		found := false
+		for _, v := range entry.Attributes[term.Attribute] {
+			if bytes.Equal(v, term.Value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *andMatcher) IndexTerms() []storage.IndexTerm {
+	return m.terms
+}
+
+// populateSearchDB adds n entries under ou=people with alternating object
+// classes and a department attribute that is not indexed.
+func populateSearchDB(tb testing.TB, db *ObaDB, n int) {
+	tb.Helper()
+
+	txn, err := db.Begin()
+	if err != nil {
+		tb.Fatalf("Begin() error = %v", err)
+	}
+	for _, dn := range []string{"dc=example,dc=com", "ou=people,dc=example,dc=com"} {
+		if err := db.Put(txn, storage.NewEntry(dn)); err != nil {
+			tb.Fatalf("Put(%s) error = %v", dn, err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		entry := storage.NewEntry(fmt.Sprintf("uid=user%d,ou=people,dc=example,dc=com", i))
+		entry.SetStringAttribute("uid", fmt.Sprintf("user%d", i))
+		entry.SetStringAttribute("objectclass", []string{"person", "device"}[i%2])
+		entry.SetStringAttribute("cn", fmt.Sprintf("group%d", i%10))
+		entry.SetStringAttribute("department", fmt.Sprintf("dept%d", i%3))
+		if err := db.Put(txn, entry); err != nil {
+			tb.Fatalf("Put() error = %v", err)
+		}
+	}
+	if err := db.Commit(txn); err != nil {
+		tb.Fatalf("Commit() error = %v", err)
+	}
+}
+
+func searchDNs(t *testing.T, db *ObaDB, baseDN string, m storage.FilterMatcher) []string {
+	t.Helper()
+
+	iter := db.SearchByFilter(nil, baseDN, m)
+	defer iter.Close()
+
+	var dns []string
+	for iter.Next() {
+		dns = append(dns, iter.Entry().DN)
+	}
+	if err := iter.Error(); err != nil {
+		t.Fatalf("SearchByFilter() error = %v", err)
+	}
+	sort.Strings(dns)
+	return dns
+}
+
+// TestSearchByFilterIndexSplit tests that index splitting returns the same
+// entries as a full scan.
+func TestSearchByFilterIndexSplit(t *testing.T) {
+	db, err := Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	populateSearchDB(t, db, 60)
+
+	// Rewrite one entry so the index holds a stale reference to it
+	txn, _ := db.Begin()
+	moved := storage.NewEntry("uid=user6,ou=people,dc=example,dc=com")
+	moved.SetStringAttribute("uid", "user6")
+	moved.SetStringAttribute("objectclass", "device")
+	moved.SetStringAttribute("cn", "group6")
+	moved.SetStringAttribute("department", "dept0")
+	if err := db.Put(txn, moved); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := db.Commit(txn); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		baseDN string
+		filter *andMatcher
+	}{
+		{"two indexed", "dc=example,dc=com", newAndMatcher("cn=group6", "objectclass=person")},
+		{"rewritten entry", "dc=example,dc=com", newAndMatcher("cn=group6", "objectclass=device")},
+		{"indexed and post-filter", "dc=example,dc=com", newAndMatcher("objectclass=device", "department=dept0")},
+		{"single indexed", "dc=example,dc=com", newAndMatcher("uid=user17")},
+		{"no match", "dc=example,dc=com", newAndMatcher("cn=group1", "objectclass=person")},
+		{"outside base", "ou=other,dc=example,dc=com", newAndMatcher("uid=user17")},
+		{"not indexed", "dc=example,dc=com", newAndMatcher("department=dept2")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db.SetSearchConfig(SearchConfig{})
+			want := searchDNs(t, db, tt.baseDN, tt.filter)
+
+			db.SetSearchConfig(SearchConfig{IndexSplitEnabled: true})
+			got := searchDNs(t, db, tt.baseDN, tt.filter)
+
+			if strings.Join(got, ";") != strings.Join(want, ";") {
+				t.Errorf("with index split got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// TestSearchStopsOnCanceledContext tests that canceling the context of a
+// transaction stops its scans before the next entry is fetched.
+func TestSearchStopsOnCanceledContext(t *testing.T) {
+	db, err := Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	populateSearchDB(t, db, 500)
+
+	scans := []struct {
+		name string
+		scan func(txn interface{}) storage.Iterator
+	}{
+		{"by DN", func(txn interface{}) storage.Iterator {
+			return db.SearchByDN(txn, "dc=example,dc=com", storage.ScopeSubtree)
+		}},
+		{"by indexed filter", func(txn interface{}) storage.Iterator {
+			return db.SearchByFilter(txn, "dc=example,dc=com", newAndMatcher("objectclass=person"))
+		}},
+		{"by unindexed filter", func(txn interface{}) storage.Iterator {
+			return db.SearchByFilter(txn, "dc=example,dc=com", newAndMatcher("department=dept0"))
+		}},
+	}
+
+	for _, s := range scans {
+		t.Run(s.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			txn, err := db.BeginWithOptions(tx.TxOptions{Context: ctx})
+			if err != nil {
+				t.Fatalf("BeginWithOptions() error = %v", err)
+			}
+			defer db.Rollback(txn)
+
+			iter := s.scan(txn)
+			defer iter.Close()
+
+			read := 0
+			for iter.Next() {
+				read++
+				if read == 10 {
+					cancel()
+				}
+			}
+			if read != 10 {
+				t.Errorf("read %d entries, want 10", read)
+			}
+			if !errors.Is(iter.Error(), context.Canceled) {
+				t.Errorf("Error() = %v, want %v", iter.Error(), context.Canceled)
+			}
+		})
+	}
+}
+
+// BenchmarkSearchByFilterAnd benchmarks a two-attribute AND query over
+// 100k entries with and without index splitting.
+func BenchmarkSearchByFilterAnd(b *testing.B) {
+	db, err := Open(b.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		b.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	populateSearchDB(b, db, 100000)
+	m := newAndMatcher("cn=group7", "uid=user4217")
+
+	for _, split := range []bool{false, true} {
+		b.Run(fmt.Sprintf("split=%v", split), func(b *testing.B) {
+			db.SetSearchConfig(SearchConfig{IndexSplitEnabled: split})
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				iter := db.SearchByFilter(nil, "dc=example,dc=com", m)
+				for iter.Next() {
+				}
+				iter.Close()
+			}
+		})
+	}
+}
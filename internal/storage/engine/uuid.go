@@ -0,0 +1,206 @@
+package engine
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/tx"
+)
+
+// EntryUUIDAttribute is the attribute holding the unique identifier of an
+// entry (RFC 4530). It is kept in the entryuuid equality index.
+const EntryUUIDAttribute = "entryuuid"
+
+// NewEntryUUID returns a random (version 4) UUID in its lowercase string
+// form, as Put assigns to new entries.
+func NewEntryUUID() (string, error) {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		return "", err
+	}
+
+	// Set version 4 and the RFC 4122 variant
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16]), nil
+}
+
+// entryUUID returns the entryUUID of attrs in lowercase, or "" if it has none.
+func entryUUID(attrs map[string][][]byte) string {
+	for name, values := range attrs {
+		if strings.ToLower(name) != EntryUUIDAttribute {
+			continue
+		}
+		for _, raw := range values {
+			if uuid := strings.ToLower(strings.TrimSpace(string(raw))); uuid != "" {
+				return uuid
+			}
+		}
+	}
+	return ""
+}
+
+// setEntryUUID replaces the entryUUID of entry with uuid.
+func setEntryUUID(entry *storage.Entry, uuid string) {
+	for name := range entry.Attributes {
+		if strings.ToLower(name) == EntryUUIDAttribute {
+			delete(entry.Attributes, name)
+		}
+	}
+	entry.SetStringAttribute(EntryUUIDAttribute, uuid)
+}
+
+// assignEntryUUID gives entry, about to be stored at dn by txn, its
+// entryUUID. An entry that replaces oldEntry keeps the old identifier
+// whatever it carries, since an entryUUID never changes. A new entry gets
+// a random one unless it brings its own, as an imported or renamed entry
+// does, which must not belong to another entry.
+func (db *ObaDB) assignEntryUUID(txn *tx.Transaction, dn string, entry, oldEntry *storage.Entry) error {
+	if oldEntry != nil {
+		if uuid := entryUUID(oldEntry.Attributes); uuid != "" {
+			setEntryUUID(entry, uuid)
+			return nil
+		}
+	}
+
+	if uuid := entryUUID(entry.Attributes); uuid != "" {
+		owner, err := db.findByEntryUUID(txn, uuid)
+		if err != nil {
+			return err
+		}
+		if owner != nil && normalizeDN(owner.DN) != dn {
+			return ErrEntryUUIDNotUnique
+		}
+		setEntryUUID(entry, uuid)
+		return nil
+	}
+
+	uuid, err := NewEntryUUID()
+	if err != nil {
+		return fmt.Errorf("failed to generate entryUUID: %w", err)
+	}
+	setEntryUUID(entry, uuid)
+	return nil
+}
+
+// ensureEntryUUIDIndex builds the entryuuid index of a database created
+// before entryUUID was indexed.
+func (db *ObaDB) ensureEntryUUIDIndex() error {
+	if _, exists := db.indexManager.GetIndex(EntryUUIDAttribute); exists {
+		return nil
+	}
+
+	entries, err := db.readRecoveryEntries(db.pageManager, db.radixTree, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build the entryuuid index: %w", err)
+	}
+	if _, err := db.indexManager.RebuildIndex(EntryUUIDAttribute, entries); err != nil {
+		return fmt.Errorf("failed to build the entryuuid index: %w", err)
+	}
+	return nil
+}
+
+// findByEntryUUID returns the entry visible to txn whose entryUUID is uuid,
+// or nil if there is none. A nil txn reads the latest committed state.
+// Must be called with db.mu held.
+func (db *ObaDB) findByEntryUUID(txn *tx.Transaction, uuid string) (*storage.Entry, error) {
+	if db.indexManager == nil {
+		return nil, nil
+	}
+
+	var snapshot, activeTxID uint64
+	if txn != nil {
+		snapshot = txn.Snapshot
+		activeTxID = txn.ID
+	} else {
+		snapshot = db.snapshotManager.CurrentTimestamp()
+	}
+
+	// The transaction's own and queued changes must be visible to the lookup
+	if err := db.applyIndexBatch(activeTxID); err != nil {
+		return nil, err
+	}
+	if db.deferredIndexer != nil {
+		if err := db.deferredIndexer.Flush(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Without the index, as after it was dropped, every entry is scanned
+	if _, exists := db.indexManager.GetIndex(EntryUUIDAttribute); !exists {
+		return db.scanEntryUUID(txn, uuid)
+	}
+
+	refs, err := db.indexManager.Search(EntryUUIDAttribute, []byte(uuid))
+	if err != nil {
+		return nil, err
+	}
+
+	// Stale references are skipped by checking the visible version
+	for _, ref := range refs {
+		dn := normalizeDN(ref.DN)
+		version, err := db.versionStore.GetVisibleForTx(dn, snapshot, activeTxID)
+		if err != nil {
+			continue
+		}
+		data, err := db.decryptData(version.GetData())
+		if err != nil {
+			return nil, err
+		}
+		entry, err := deserializeEntry(dn, data)
+		if err != nil {
+			return nil, err
+		}
+		if entryUUID(entry.Attributes) == uuid {
+			return entry, nil
+		}
+	}
+	return nil, nil
+}
+
+// scanEntryUUID returns the entry visible to txn whose entryUUID is uuid by
+// scanning every entry, or nil if there is none.
+func (db *ObaDB) scanEntryUUID(txn *tx.Transaction, uuid string) (*storage.Entry, error) {
+	var txnIface interface{}
+	if txn != nil {
+		txnIface = txn
+	}
+	iter := db.SearchByDN(txnIface, "", storage.ScopeSubtree)
+	defer iter.Close()
+
+	for iter.Next() {
+		if entry := iter.Entry(); entry != nil && entryUUID(entry.Attributes) == uuid {
+			return entry, nil
+		}
+	}
+	return nil, iter.Error()
+}
+
+// GetByUUID returns the entry whose entryUUID is uuid, using the entryuuid
+// index instead of a scan. The comparison ignores case. It returns
+// ErrEntryNotFound if no committed entry has the identifier.
+func (db *ObaDB) GetByUUID(uuid string) (*storage.Entry, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if db.closed {
+		return nil, ErrDatabaseClosed
+	}
+
+	uuid = strings.ToLower(strings.TrimSpace(uuid))
+	if uuid == "" {
+		return nil, ErrEntryNotFound
+	}
+
+	entry, err := db.findByEntryUUID(nil, uuid)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, ErrEntryNotFound
+	}
+	return entry, nil
+}
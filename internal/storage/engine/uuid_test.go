@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+// TestEntryUUIDAssignment tests that Put assigns an entryUUID to a new
+// entry and keeps it when the entry is updated or renamed.
+func TestEntryUUIDAssignment(t *testing.T) {
+	db, err := Open(t.TempDir(), storage.DefaultEngineOptions())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	put := func(entry *storage.Entry) error {
+		txn, _ := db.Begin()
+		if err := db.Put(txn, entry); err != nil {
+			db.Rollback(txn)
+			return err
+		}
+		return db.Commit(txn)
+	}
+	get := func(dn string) *storage.Entry {
+		entry, err := db.Get(nil, dn)
+		if err != nil {
+			t.Fatalf("Get(%s) error = %v", dn, err)
+		}
+		return entry
+	}
+
+	dn := "uid=alice,dc=example,dc=com"
+	entry := storage.NewEntry(dn)
+	entry.SetStringAttribute("uid", "alice")
+	if err := put(entry); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	uuid := entryUUID(get(dn).Attributes)
+	if !uuidPattern.MatchString(uuid) {
+		t.Fatalf("entryUUID = %q, want a version 4 UUID", uuid)
+	}
+
+	// Updates keep the identifier, with or without one of their own
+	updated := storage.NewEntry(dn)
+	updated.SetStringAttribute("uid", "alice")
+	updated.SetStringAttribute("mail", "alice@example.com")
+	if err := put(updated); err != nil {
+		t.Fatalf("Put() update error = %v", err)
+	}
+	changed := updated.Clone()
+	changed.SetStringAttribute("entryUUID", "00000000-0000-4000-8000-000000000000")
+	if err := put(changed); err != nil {
+		t.Fatalf("Put() update with entryUUID error = %v", err)
+	}
+	if got := entryUUID(get(dn).Attributes); got != uuid {
+		t.Errorf("entryUUID after update = %q, want %q", got, uuid)
+	}
+
+	// The fast path finds the entry regardless of case
+	found, err := db.GetByUUID(strings.ToUpper(uuid))
+	if err != nil {
+		t.Fatalf("GetByUUID() error = %v", err)
+	}
+	if normalizeDN(found.DN) != normalizeDN(dn) {
+		t.Errorf("GetByUUID() DN = %q, want %q", found.DN, dn)
+	}
+	if _, err := db.GetByUUID("00000000-0000-4000-8000-000000000000"); !errors.Is(err, ErrEntryNotFound) {
+		t.Errorf("GetByUUID() of unknown UUID error = %v, want %v", err, ErrEntryNotFound)
+	}
+
+	// Another entry cannot take the identifier
+	other := storage.NewEntry("uid=bob,dc=example,dc=com")
+	other.SetStringAttribute("entryuuid", uuid)
+	if err := put(other); !errors.Is(err, ErrEntryUUIDNotUnique) {
+		t.Errorf("Put() with a taken entryUUID error = %v, want %v", err, ErrEntryUUIDNotUnique)
+	}
+
+	// A rename moves the identifier with the entry
+	txn, _ := db.Begin()
+	renamed := get(dn).Clone()
+	renamed.DN = "uid=alice2,dc=example,dc=com"
+	if err := db.Delete(txn, dn); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := db.Put(txn, renamed); err != nil {
+		t.Fatalf("Put() renamed error = %v", err)
+	}
+	if err := db.Commit(txn); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	found, err = db.GetByUUID(uuid)
+	if err != nil {
+		t.Fatalf("GetByUUID() after rename error = %v", err)
+	}
+	if normalizeDN(found.DN) != normalizeDN(renamed.DN) {
+		t.Errorf("GetByUUID() after rename DN = %q, want %q", found.DN, renamed.DN)
+	}
+}
@@ -0,0 +1,23 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+func init() {
+	RegisterMigration(Migration{
+		Component:   ComponentWAL,
+		From:        storage.WALFormatV0,
+		To:          storage.WALFormatV1,
+		Description: "allow compressed WAL records; existing records are read as they are",
+		Mode:        MigrationOnline,
+		Forward: func(ctx context.Context, db *ObaDB) error {
+			// Version 0 records are valid version 1 records, so nothing
+			// is rewritten. Recording the version stops older binaries,
+			// which reject compressed records, from opening the database.
+			return nil
+		},
+	})
+}
@@ -0,0 +1,366 @@
+// Package faultfs provides a storage.FileSystem that injects crashes into the
+// file I/O of the storage engine, for testing crash recovery.
+//
+// A FS counts the writes made through it. The write at the crash point fails
+// and every operation after it returns ErrCrashed; what the files hold
+// afterwards depends on the Mode. The test then reopens the files through
+// the operating system, as a restarted process would.
+//
+//	fs := faultfs.New(faultfs.Config{Mode: faultfs.TornWrite, CrashAfter: 40, Seed: seed})
+//	db, _ := engine.Open(dir, opts.WithFileSystem(fs))
+//	// ... run a workload until it fails with faultfs.ErrCrashed ...
+//	db, _ = engine.Open(dir, opts)
+package faultfs
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// ErrCrashed is returned by the write at the crash point and by every
+// operation after it.
+var ErrCrashed = errors.New("faultfs: crashed")
+
+// SectorSize is the unit of torn writes: a sector is written entirely or not
+// at all.
+const SectorSize = 512
+
+// Mode is the kind of fault injected at the crash point.
+type Mode int
+
+// Fault modes.
+const (
+	// Crash stops all I/O at the crash point, as when the process is
+	// killed: the writes before it are kept and the write at the crash
+	// point is lost.
+	Crash Mode = iota
+	// TornWrite is like Crash, but the write at the crash point is
+	// partially kept: a prefix of whole sectors chosen by the seed.
+	TornWrite
+	// FsyncLies makes Sync report success without persisting anything.
+	// At the crash point, as at a power loss, every change made through
+	// the file system is lost.
+	FsyncLies
+)
+
+// String returns the name of the mode.
+func (m Mode) String() string {
+	switch m {
+	case Crash:
+		return "crash"
+	case TornWrite:
+		return "torn-write"
+	case FsyncLies:
+		return "fsync-lies"
+	default:
+		return "unknown"
+	}
+}
+
+// Config configures the faults of a FS.
+type Config struct {
+	// Mode is the fault injected at the crash point
+	Mode Mode
+	// CrashAfter is the number of writes that succeed before the crash
+	// point. A negative value never crashes on its own; see FS.Crash.
+	CrashAfter int
+	// Seed seeds the random choices of the faults
+	Seed int64
+}
+
+// FS is a storage.FileSystem over the operating system's that injects the
+// faults of its Config. Writes, truncations and syncs of all its files are
+// serialized.
+type FS struct {
+	config Config
+
+	mu      sync.Mutex
+	rng     *rand.Rand
+	writes  int
+	crashed bool
+	files   map[*file]struct{}
+}
+
+// New creates a FS injecting the faults of config.
+func New(config Config) *FS {
+	return &FS{
+		config: config,
+		rng:    rand.New(rand.NewSource(config.Seed)),
+		files:  make(map[*file]struct{}),
+	}
+}
+
+// OpenFile implements storage.FileSystem.
+func (fs *FS) OpenFile(name string, flag int, perm os.FileMode) (storage.File, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.crashed {
+		return nil, ErrCrashed
+	}
+
+	f, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	wrapped := &file{fs: fs, f: f}
+	fs.files[wrapped] = struct{}{}
+	return wrapped, nil
+}
+
+// Crash crashes the file system now, unless it already crashed.
+func (fs *FS) Crash() {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if !fs.crashed {
+		fs.crashLocked()
+	}
+}
+
+// Crashed reports whether the file system crashed.
+func (fs *FS) Crashed() bool {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	return fs.crashed
+}
+
+// Writes returns the number of writes that succeeded. Running a workload
+// without a crash point tells the range of useful CrashAfter values.
+func (fs *FS) Writes() int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	return fs.writes
+}
+
+// crashPointLocked reports whether the next write is at the crash point.
+func (fs *FS) crashPointLocked() bool {
+	return fs.config.CrashAfter >= 0 && fs.writes >= fs.config.CrashAfter
+}
+
+// crashLocked discards what the mode loses and closes every file.
+func (fs *FS) crashLocked() {
+	fs.crashed = true
+
+	for f := range fs.files {
+		if fs.config.Mode == FsyncLies {
+			f.revertLocked()
+		}
+		f.f.Close()
+	}
+	fs.files = nil
+}
+
+// tornLengthLocked returns how much of a write of n bytes at off is kept
+// by a torn write: a whole number of sectors, never the entire write.
+func (fs *FS) tornLengthLocked(off int64, n int) int {
+	choices := []int{0}
+	for b := (off/SectorSize + 1) * SectorSize; b < off+int64(n); b += SectorSize {
+		choices = append(choices, int(b-off))
+	}
+	return choices[fs.rng.Intn(len(choices))]
+}
+
+// file is a file of a FS.
+type file struct {
+	fs *FS
+	f  *os.File
+
+	// undo holds the content replaced by each change, oldest first. It is
+	// only kept when syncs lie.
+	undo []undoRecord
+}
+
+// undoRecord restores the file before one change: it truncates the file
+// to size and writes data back at offset.
+type undoRecord struct {
+	size   int64
+	offset int64
+	data   []byte
+}
+
+// ReadAt implements storage.File.
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.fs.crashed {
+		return 0, ErrCrashed
+	}
+	return f.f.ReadAt(p, off)
+}
+
+// WriteAt implements storage.File.
+func (f *file) WriteAt(p []byte, off int64) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.fs.crashed {
+		return 0, ErrCrashed
+	}
+	if f.fs.crashPointLocked() {
+		return f.crashWriteLocked(p, off, f.f.WriteAt)
+	}
+
+	if err := f.saveUndoLocked(off, int64(len(p))); err != nil {
+		return 0, err
+	}
+	f.fs.writes++
+	return f.f.WriteAt(p, off)
+}
+
+// Write implements storage.File.
+func (f *file) Write(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.fs.crashed {
+		return 0, ErrCrashed
+	}
+	off, err := f.f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	write := func(p []byte, _ int64) (int, error) { return f.f.Write(p) }
+	if f.fs.crashPointLocked() {
+		return f.crashWriteLocked(p, off, write)
+	}
+
+	if err := f.saveUndoLocked(off, int64(len(p))); err != nil {
+		return 0, err
+	}
+	f.fs.writes++
+	return write(p, off)
+}
+
+// crashWriteLocked performs the write at the crash point with write and
+// crashes.
+func (f *file) crashWriteLocked(p []byte, off int64, write func([]byte, int64) (int, error)) (int, error) {
+	n := 0
+	if f.fs.config.Mode == TornWrite {
+		n, _ = write(p[:f.fs.tornLengthLocked(off, len(p))], off)
+	}
+	f.fs.crashLocked()
+	return n, ErrCrashed
+}
+
+// Truncate implements storage.File.
+func (f *file) Truncate(size int64) error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.fs.crashed {
+		return ErrCrashed
+	}
+	if f.fs.crashPointLocked() {
+		f.fs.crashLocked()
+		return ErrCrashed
+	}
+
+	if err := f.saveUndoLocked(size, 0); err != nil {
+		return err
+	}
+	f.fs.writes++
+	return f.f.Truncate(size)
+}
+
+// Sync implements storage.File.
+func (f *file) Sync() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.fs.crashed {
+		return ErrCrashed
+	}
+	if f.fs.config.Mode == FsyncLies {
+		return nil
+	}
+	return f.f.Sync()
+}
+
+// Seek implements storage.File.
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.fs.crashed {
+		return 0, ErrCrashed
+	}
+	return f.f.Seek(offset, whence)
+}
+
+// Stat implements storage.File.
+func (f *file) Stat() (os.FileInfo, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.fs.crashed {
+		return nil, ErrCrashed
+	}
+	return f.f.Stat()
+}
+
+// Close implements storage.File.
+func (f *file) Close() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.fs.crashed {
+		return ErrCrashed
+	}
+	delete(f.fs.files, f)
+	return f.f.Close()
+}
+
+// saveUndoLocked records the content that a change of n bytes at off, or a
+// truncation to off when n is 0, is about to replace.
+func (f *file) saveUndoLocked(off, n int64) error {
+	if f.fs.config.Mode != FsyncLies {
+		return nil
+	}
+
+	info, err := f.f.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+
+	end := off + n
+	if n == 0 {
+		end = size
+	}
+	if end > size {
+		end = size
+	}
+
+	record := undoRecord{size: size, offset: off}
+	if end > off {
+		record.data = make([]byte, end-off)
+		if _, err := f.f.ReadAt(record.data, off); err != nil {
+			return err
+		}
+	}
+	f.undo = append(f.undo, record)
+	return nil
+}
+
+// revertLocked undoes every recorded change, newest first.
+func (f *file) revertLocked() {
+	for i := len(f.undo) - 1; i >= 0; i-- {
+		u := f.undo[i]
+		f.f.Truncate(u.size)
+		if len(u.data) > 0 {
+			f.f.WriteAt(u.data, u.offset)
+		}
+	}
+	f.undo = nil
+}
+
+var _ storage.FileSystem = (*FS)(nil)
@@ -0,0 +1,113 @@
+package faultfs
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+func openTestFile(t *testing.T, fs *FS) (string, storage.File) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "data")
+	f, err := fs.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatalf("failed to open file: %v", err)
+	}
+	return path, f
+}
+
+func TestCrashKeepsWritesBeforeCrashPoint(t *testing.T) {
+	fs := New(Config{Mode: Crash, CrashAfter: 2})
+	path, f := openTestFile(t, fs)
+
+	for i, b := range []byte("ab") {
+		if _, err := f.WriteAt([]byte{b}, int64(i)); err != nil {
+			t.Fatalf("write %d failed: %v", i, err)
+		}
+	}
+	if _, err := f.WriteAt([]byte("c"), 2); !errors.Is(err, ErrCrashed) {
+		t.Fatalf("write at the crash point: got %v, want ErrCrashed", err)
+	}
+	if !fs.Crashed() {
+		t.Error("file system should have crashed")
+	}
+	if err := f.Sync(); !errors.Is(err, ErrCrashed) {
+		t.Errorf("sync after the crash: got %v, want ErrCrashed", err)
+	}
+	if _, err := fs.OpenFile(path, os.O_RDONLY, 0); !errors.Is(err, ErrCrashed) {
+		t.Errorf("open after the crash: got %v, want ErrCrashed", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "ab" {
+		t.Errorf("file holds %q, want %q", data, "ab")
+	}
+}
+
+func TestTornWriteKeepsWholeSectors(t *testing.T) {
+	for seed := int64(0); seed < 8; seed++ {
+		fs := New(Config{Mode: TornWrite, CrashAfter: 0, Seed: seed})
+		path, f := openTestFile(t, fs)
+
+		page := bytes.Repeat([]byte{0xAB}, 4*SectorSize)
+		n, err := f.WriteAt(page, 100)
+		if !errors.Is(err, ErrCrashed) {
+			t.Fatalf("seed %d: got %v, want ErrCrashed", seed, err)
+		}
+
+		info, _ := os.Stat(path)
+		if n == 0 {
+			if info.Size() != 0 {
+				t.Errorf("seed %d: nothing written but file has %d bytes", seed, info.Size())
+			}
+			continue
+		}
+		if n >= len(page) {
+			t.Errorf("seed %d: torn write kept the entire write", seed)
+		}
+		if end := 100 + int64(n); end%SectorSize != 0 || info.Size() != end {
+			t.Errorf("seed %d: torn write ends at %d, file size %d", seed, end, info.Size())
+		}
+	}
+}
+
+func TestFsyncLiesRevertsAtCrash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(path, []byte("base"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := New(Config{Mode: FsyncLies, CrashAfter: -1})
+	f, err := fs.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("failed to open file: %v", err)
+	}
+
+	if _, err := f.WriteAt([]byte("BA"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteAt([]byte("extended"), 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Sync(); err != nil {
+		t.Fatalf("lying sync failed: %v", err)
+	}
+	if err := f.Truncate(2); err != nil {
+		t.Fatal(err)
+	}
+
+	fs.Crash()
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "base" {
+		t.Errorf("file holds %q after the crash, want %q", data, "base")
+	}
+	if got := fs.Writes(); got != 3 {
+		t.Errorf("writes = %d, want 3", got)
+	}
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"io"
+	"os"
+)
+
+// File is an open file of the page manager or the WAL. *os.File implements
+// it.
+type File interface {
+	io.ReaderAt
+	io.WriterAt
+	io.Writer
+	io.Seeker
+	Stat() (os.FileInfo, error)
+	Sync() error
+	Truncate(size int64) error
+	Close() error
+}
+
+// SyncDir makes the files created, renamed or removed in the directory dir
+// durable. On Unix it fsyncs the directory; on Windows it flushes the
+// directory handle, the documented equivalent.
+func SyncDir(dir string) error {
+	return syncDir(dir)
+}
+
+// FileSystem opens the files that the page manager and the WAL read and
+// write. Tests use it to inject I/O faults.
+type FileSystem interface {
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+}
+
+// OSFileSystem is the FileSystem of the operating system.
+var OSFileSystem FileSystem = osFileSystem{}
+
+type osFileSystem struct{}
+
+func (osFileSystem) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+// fileSystemOrOS returns fs, or the operating system's if fs is nil.
+func fileSystemOrOS(fs FileSystem) FileSystem {
+	if fs == nil {
+		return OSFileSystem
+	}
+	return fs
+}
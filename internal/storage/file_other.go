@@ -0,0 +1,8 @@
+//go:build !unix && !windows
+
+package storage
+
+// syncDir does nothing on platforms that cannot sync directories.
+func syncDir(dir string) error {
+	return nil
+}
@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSyncDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "data.oba"), nil, 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := SyncDir(dir); err != nil {
+		t.Errorf("SyncDir() error = %v", err)
+	}
+	if err := SyncDir(filepath.Join(dir, "missing")); err == nil {
+		t.Error("SyncDir() of a missing directory succeeded")
+	}
+}
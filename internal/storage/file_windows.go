@@ -0,0 +1,44 @@
+//go:build windows
+
+package storage
+
+import (
+	"syscall"
+)
+
+// errorInvalidFunction is returned by FlushFileBuffers on file systems
+// that cannot flush directories.
+const errorInvalidFunction syscall.Errno = 1
+
+// syncDir flushes the directory dir with FlushFileBuffers. A directory
+// can only be opened with FILE_FLAG_BACKUP_SEMANTICS, and needs write
+// access to be flushed; where that is denied, or the file system cannot
+// flush directories, its journal already orders the metadata changes and
+// nothing is done.
+func syncDir(dir string) error {
+	name, err := syscall.UTF16PtrFromString(dir)
+	if err != nil {
+		return err
+	}
+
+	handle, err := syscall.CreateFile(name,
+		syscall.GENERIC_READ|syscall.GENERIC_WRITE,
+		syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE,
+		nil,
+		syscall.OPEN_EXISTING,
+		syscall.FILE_FLAG_BACKUP_SEMANTICS,
+		0)
+	if err == syscall.ERROR_ACCESS_DENIED {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer syscall.CloseHandle(handle)
+
+	err = syscall.FlushFileBuffers(handle)
+	if err == syscall.ERROR_ACCESS_DENIED || err == errorInvalidFunction {
+		return nil
+	}
+	return err
+}
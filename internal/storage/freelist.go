@@ -3,6 +3,7 @@ package storage
 
 import (
 	"encoding/binary"
+	"sort"
 	"sync"
 )
 
@@ -13,6 +14,23 @@ const FreeListEntrySize = 8
 // Calculated as: (PageSize - PageHeaderSize - 8 bytes for next pointer) / 8 bytes per entry
 const MaxFreeListEntriesPerPage = (PageSize - PageHeaderSize - 8) / FreeListEntrySize
 
+// CompactionStats describes the effect of FreeList.Compact.
+type CompactionStats struct {
+	// FreePages is the number of free pages after compaction.
+	FreePages int
+
+	// Extents is the number of runs of consecutive free pages.
+	Extents int
+
+	// DuplicatesRemoved is the number of repeated page IDs dropped.
+	DuplicatesRemoved int
+
+	// FragmentationBefore and FragmentationAfter are the values of
+	// Fragmentation before and after compaction.
+	FragmentationBefore float64
+	FragmentationAfter  float64
+}
+
 // FreeList manages free pages in the database.
 // It uses a linked list of pages, where each page contains an array of free page IDs.
 // Layout of a free list page:
@@ -230,3 +248,89 @@ func (fl *FreeList) Remove(pageID PageID) bool {
 	}
 	return false
 }
+
+// Compact sorts the free page IDs and drops duplicates and page 0, so
+// that pages are handed out in ascending order, one extent of consecutive
+// pages after another. Pages freed afterwards are handed out first, as
+// before.
+func (fl *FreeList) Compact() (*CompactionStats, error) {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+
+	stats := &CompactionStats{FragmentationBefore: fl.fragmentationLocked()}
+
+	// Sort descending, since Pop takes from the end
+	sort.Slice(fl.freePages, func(i, j int) bool { return fl.freePages[i] > fl.freePages[j] })
+
+	pages := fl.freePages[:0]
+	for _, id := range fl.freePages {
+		if id == 0 || (len(pages) > 0 && pages[len(pages)-1] == id) {
+			stats.DuplicatesRemoved++
+			continue
+		}
+		pages = append(pages, id)
+	}
+	fl.freePages = pages
+	fl.count = uint64(len(pages))
+
+	stats.FreePages = len(pages)
+	stats.Extents = countExtents(pages)
+	stats.FragmentationAfter = fl.fragmentationLocked()
+	return stats, nil
+}
+
+// Fragmentation returns how far the order in which free pages are handed
+// out is from the best order: 0.0 if every allocation continues the
+// extent of consecutive free pages of the previous one where possible,
+// 1.0 if no allocation does. Gaps between extents are not counted, as
+// only moving used pages could close them.
+func (fl *FreeList) Fragmentation() float64 {
+	fl.mu.RLock()
+	defer fl.mu.RUnlock()
+	return fl.fragmentationLocked()
+}
+
+// fragmentationLocked computes Fragmentation. Must be called with the
+// lock held.
+func (fl *FreeList) fragmentationLocked() float64 {
+	n := len(fl.freePages)
+	if n < 2 {
+		return 0
+	}
+
+	// Breaks between consecutive allocations, in Pop order
+	breaks := 0
+	for i := n - 1; i > 0; i-- {
+		if fl.freePages[i-1] != fl.freePages[i]+1 {
+			breaks++
+		}
+	}
+
+	sorted := make([]PageID, n)
+	copy(sorted, fl.freePages)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	extents := countExtents(sorted)
+
+	// At least extents-1 breaks are unavoidable
+	avoidable := n - extents
+	if avoidable <= 0 {
+		return 0
+	}
+	return float64(breaks-(extents-1)) / float64(avoidable)
+}
+
+// countExtents returns the number of runs of consecutive page IDs in
+// sorted, which may be in either order and contain duplicates.
+func countExtents(sorted []PageID) int {
+	if len(sorted) == 0 {
+		return 0
+	}
+	extents := 1
+	for i := 1; i < len(sorted); i++ {
+		prev, id := sorted[i-1], sorted[i]
+		if id != prev && id != prev+1 && id+1 != prev {
+			extents++
+		}
+	}
+	return extents
+}
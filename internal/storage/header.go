@@ -2,9 +2,11 @@
 package storage
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"hash/crc32"
+	"sort"
 )
 
 // File header constants.
@@ -24,6 +26,18 @@ const (
 
 	// FileHeaderReservedSize is the size of reserved space in the header.
 	FileHeaderReservedSize = 4020
+
+	// ComponentNameSize is the maximum length of a component name in the
+	// component version table.
+	ComponentNameSize = 20
+
+	// MaxComponents is the maximum number of components whose version the
+	// header records.
+	MaxComponents = 32
+
+	// componentEntrySize is the size of a component version table entry:
+	// the zero padded name followed by the version.
+	componentEntrySize = ComponentNameSize + 4
 )
 
 // Magic is the magic number for ObaDB files.
@@ -45,7 +59,18 @@ type RootPages struct {
 //   - Bytes 28-35:   RootPages.DNIndex (PageID/uint64)
 //   - Bytes 36-43:   RootPages.DataRoot (PageID/uint64)
 //   - Bytes 44-47:   Checksum (uint32)
-//   - Bytes 48-4095: Reserved
+//   - Bytes 48-4095: Reserved, starting with the component version table
+//
+// The component version table records the on-disk format version of each
+// storage component, so that the engine can tell which migrations a file
+// needs. It is laid out at the start of the reserved space:
+//   - Bytes 48-49:   Entry count (uint16)
+//   - Bytes 50-53:   CRC32 of the entries
+//   - Bytes 54-:     Entries, each a name zero padded to ComponentNameSize
+//     bytes followed by the version (uint32)
+//
+// Files written before the table existed hold no entries, so every
+// component reads as version 0.
 type FileHeader struct {
 	Magic        [4]byte   // "OBA\x00"
 	Version      uint32    // File format version
@@ -63,6 +88,9 @@ var (
 	ErrUnsupportedVersion = errors.New("unsupported file format version")
 	ErrHeaderChecksum     = errors.New("file header checksum mismatch")
 	ErrInvalidHeaderSize  = errors.New("invalid header size")
+	ErrComponentTable     = errors.New("component version table is corrupted")
+	ErrComponentName      = errors.New("invalid component name")
+	ErrTooManyComponents  = errors.New("too many components in version table")
 )
 
 // NewFileHeader creates a new FileHeader with default values.
@@ -250,6 +278,65 @@ func (h *FileHeader) IsVersionSupported() bool {
 	return h.Version > 0 && h.Version <= CurrentVersion
 }
 
+// ComponentVersions returns the format version of each component recorded
+// in the component version table. Components that are not recorded are at
+// version 0.
+func (h *FileHeader) ComponentVersions() (map[string]uint32, error) {
+	count := int(binary.LittleEndian.Uint16(h.Reserved[0:2]))
+	if count > MaxComponents {
+		return nil, ErrComponentTable
+	}
+
+	entries := h.Reserved[6 : 6+count*componentEntrySize]
+	if crc32.ChecksumIEEE(entries) != binary.LittleEndian.Uint32(h.Reserved[2:6]) {
+		return nil, ErrComponentTable
+	}
+
+	versions := make(map[string]uint32, count)
+	for i := 0; i < count; i++ {
+		entry := entries[i*componentEntrySize : (i+1)*componentEntrySize]
+		name := string(bytes.TrimRight(entry[:ComponentNameSize], "\x00"))
+		versions[name] = binary.LittleEndian.Uint32(entry[ComponentNameSize:])
+	}
+	return versions, nil
+}
+
+// SetComponentVersion records the format version of a component in the
+// component version table.
+func (h *FileHeader) SetComponentVersion(name string, version uint32) error {
+	if name == "" || len(name) > ComponentNameSize || bytes.IndexByte([]byte(name), 0) >= 0 {
+		return ErrComponentName
+	}
+
+	versions, err := h.ComponentVersions()
+	if err != nil {
+		return err
+	}
+	versions[name] = version
+	if len(versions) > MaxComponents {
+		return ErrTooManyComponents
+	}
+
+	names := make([]string, 0, len(versions))
+	for n := range versions {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	entries := h.Reserved[6 : 6+len(names)*componentEntrySize]
+	for i := range entries {
+		entries[i] = 0
+	}
+	for i, n := range names {
+		entry := entries[i*componentEntrySize : (i+1)*componentEntrySize]
+		copy(entry, n)
+		binary.LittleEndian.PutUint32(entry[ComponentNameSize:], versions[n])
+	}
+	binary.LittleEndian.PutUint16(h.Reserved[0:2], uint16(len(names)))
+	binary.LittleEndian.PutUint32(h.Reserved[2:6], crc32.ChecksumIEEE(entries))
+	return nil
+}
+
 // ValidateMagicBytes checks if the given bytes represent a valid ObaDB magic number.
 // This is a standalone function for quick file type detection.
 func ValidateMagicBytes(magic [4]byte) bool {
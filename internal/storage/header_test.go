@@ -127,6 +127,61 @@ func TestFileHeaderDeserializeInvalidSize(t *testing.T) {
 // Magic Number Validation Tests
 // =============================================================================
 
+func TestFileHeaderComponentVersions(t *testing.T) {
+	header := NewFileHeader()
+
+	versions, err := header.ComponentVersions()
+	if err != nil {
+		t.Fatalf("ComponentVersions() error = %v", err)
+	}
+	if len(versions) != 0 {
+		t.Errorf("ComponentVersions() = %v, want empty", versions)
+	}
+
+	if err := header.SetComponentVersion("entry", 2); err != nil {
+		t.Fatalf("SetComponentVersion() error = %v", err)
+	}
+	if err := header.SetComponentVersion("index", 1); err != nil {
+		t.Fatalf("SetComponentVersion() error = %v", err)
+	}
+	if err := header.SetComponentVersion("entry", 3); err != nil {
+		t.Fatalf("SetComponentVersion() error = %v", err)
+	}
+
+	buf, err := header.Serialize()
+	if err != nil {
+		t.Fatalf("Serialize() error = %v", err)
+	}
+	decoded := &FileHeader{}
+	if err := decoded.DeserializeAndValidate(buf); err != nil {
+		t.Fatalf("DeserializeAndValidate() error = %v", err)
+	}
+
+	versions, err = decoded.ComponentVersions()
+	if err != nil {
+		t.Fatalf("ComponentVersions() error = %v", err)
+	}
+	if len(versions) != 2 || versions["entry"] != 3 || versions["index"] != 1 {
+		t.Errorf("ComponentVersions() = %v, want entry 3 and index 1", versions)
+	}
+
+	// Damage a version
+	decoded.Reserved[6+ComponentNameSize] ^= 0xFF
+	if _, err := decoded.ComponentVersions(); err != ErrComponentTable {
+		t.Errorf("ComponentVersions() error = %v, want %v", err, ErrComponentTable)
+	}
+}
+
+func TestFileHeaderSetComponentVersionInvalidName(t *testing.T) {
+	header := NewFileHeader()
+
+	for _, name := range []string{"", "a-component-name-that-is-too-long", "nul\x00"} {
+		if err := header.SetComponentVersion(name, 1); err != ErrComponentName {
+			t.Errorf("SetComponentVersion(%q) error = %v, want %v", name, err, ErrComponentName)
+		}
+	}
+}
+
 func TestFileHeaderValidateMagic(t *testing.T) {
 	header := NewFileHeader()
 
@@ -0,0 +1,159 @@
+package index
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/btree"
+)
+
+// Batch collects the index changes of one transaction so that they are
+// applied together when it commits, rather than with a tree descent per
+// value as each entry is written. The entries are copied when queued, so
+// callers may reuse them.
+type Batch struct {
+	updates []IndexUpdate
+}
+
+// NewBatch creates an empty Batch.
+func NewBatch() *Batch {
+	return &Batch{}
+}
+
+// IndexEntry queues an entry to be added to the indexes.
+func (b *Batch) IndexEntry(entry *Entry) {
+	b.ReindexEntry(nil, entry)
+}
+
+// UnindexEntry queues an entry to be removed from the indexes.
+func (b *Batch) UnindexEntry(entry *Entry) {
+	b.ReindexEntry(entry, nil)
+}
+
+// ReindexEntry queues the removal of oldEntry and the addition of newEntry,
+// with the same semantics as IndexManager.UpdateIndexes.
+func (b *Batch) ReindexEntry(oldEntry, newEntry *Entry) {
+	if oldEntry == nil && newEntry == nil {
+		return
+	}
+	b.updates = append(b.updates, IndexUpdate{
+		OldEntry: copyEntry(oldEntry),
+		NewEntry: copyEntry(newEntry),
+	})
+}
+
+// Len returns the number of queued updates.
+func (b *Batch) Len() int {
+	return len(b.updates)
+}
+
+// opKey identifies a key of an index for one entry reference. The tree
+// tells references apart by their location only.
+type opKey struct {
+	key    string
+	pageID storage.PageID
+	slotID uint16
+}
+
+// indexOp is the net change of an index key for one entry reference: a
+// positive delta inserts it, a negative one deletes it.
+type indexOp struct {
+	key   []byte
+	ref   btree.EntryRef
+	delta int
+}
+
+// collectOps returns the net changes the updates make to each index, keyed
+// by attribute. A value removed and added again under the same reference
+// cancels out. Must be called with im.mu held.
+func (im *IndexManager) collectOps(updates []IndexUpdate) map[string]map[opKey]*indexOp {
+	ops := make(map[string]map[opKey]*indexOp)
+
+	record := func(entry *Entry, delta int) {
+		if entry == nil {
+			return
+		}
+		ref := entry.EntryRef()
+		for attr, idx := range im.indexes {
+			for _, key := range indexKeys(idx, entry.GetAttributeWithOptions(attr)) {
+				k := opKey{key: string(key), pageID: ref.PageID, slotID: ref.SlotID}
+				byKey := ops[attr]
+				if byKey == nil {
+					byKey = make(map[opKey]*indexOp)
+					ops[attr] = byKey
+				}
+				op, ok := byKey[k]
+				if !ok {
+					op = &indexOp{key: key}
+					byKey[k] = op
+				}
+				op.ref = ref
+				op.delta += delta
+			}
+		}
+	}
+
+	for _, u := range updates {
+		record(u.OldEntry, -1)
+		record(u.NewEntry, 1)
+	}
+	return ops
+}
+
+// applyOps applies net index changes one index at a time in key order, so
+// consecutive keys share tree pages: deletions first, then insertions with
+// one descent and one write per leaf. A failed index does not stop the
+// others; the first error is returned. Must be called with im.mu held.
+func (im *IndexManager) applyOps(ops map[string]map[opKey]*indexOp) error {
+	attrs := make([]string, 0, len(ops))
+	for attr := range ops {
+		attrs = append(attrs, attr)
+	}
+	sort.Strings(attrs)
+
+	var firstErr error
+	for _, attr := range attrs {
+		idx, exists := im.indexes[attr]
+		if !exists {
+			continue
+		}
+
+		var deletes, inserts []*indexOp
+		for _, op := range ops[attr] {
+			switch {
+			case op.delta < 0:
+				deletes = append(deletes, op)
+			case op.delta > 0:
+				inserts = append(inserts, op)
+			}
+		}
+		sortOps(deletes)
+		sortOps(inserts)
+
+		for _, op := range deletes {
+			// Ignore not found errors during deletion
+			_ = idx.Tree.Delete(op.key, op.ref)
+		}
+
+		items := make([]btree.KeyRef, len(inserts))
+		for i, op := range inserts {
+			items[i] = btree.KeyRef{Key: op.key, Ref: op.ref}
+		}
+		if err := idx.Tree.InsertSorted(items); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// sortOps sorts ops by key, then by entry reference.
+func sortOps(ops []*indexOp) {
+	sort.Slice(ops, func(i, j int) bool {
+		if c := bytes.Compare(ops[i].key, ops[j].key); c != 0 {
+			return c < 0
+		}
+		return storage.CompareEntryRefs(ops[i].ref, ops[j].ref) < 0
+	})
+}
@@ -0,0 +1,155 @@
+package index
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+func TestBatchApply(t *testing.T) {
+	pm, cleanup := createTestPageManager(t)
+	defer cleanup()
+
+	im, err := NewIndexManager(pm)
+	if err != nil {
+		t.Fatalf("NewIndexManager() error = %v", err)
+	}
+
+	alice := newIndexedEntry("uid=alice,dc=example,dc=com", "alice", 1)
+	bob := newIndexedEntry("uid=bob,dc=example,dc=com", "bob", 2)
+	renamed := newIndexedEntry("uid=bob,dc=example,dc=com", "robert", 2)
+
+	b := NewBatch()
+	b.IndexEntry(alice)
+	b.IndexEntry(bob)
+	b.ReindexEntry(bob, renamed)
+	if b.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", b.Len())
+	}
+
+	// Queued changes are not visible before the batch is applied
+	if refs, _ := im.Search("uid", []byte("alice")); len(refs) != 0 {
+		t.Errorf("alice has %d index entries before ApplyBatch, want 0", len(refs))
+	}
+
+	// The entries are copied, so changing them does not affect the batch
+	alice.SetAttribute("uid", [][]byte{[]byte("changed")})
+
+	if err := im.ApplyBatch(b); err != nil {
+		t.Fatalf("ApplyBatch() error = %v", err)
+	}
+
+	tests := []struct {
+		uid  string
+		want int
+	}{
+		{"alice", 1},
+		{"changed", 0},
+		// Added and removed again under the same reference
+		{"bob", 0},
+		{"robert", 1},
+	}
+	for _, tt := range tests {
+		refs, err := im.Search("uid", []byte(tt.uid))
+		if err != nil {
+			t.Fatalf("Search(%s) error = %v", tt.uid, err)
+		}
+		if len(refs) != tt.want {
+			t.Errorf("uid %s has %d index entries, want %d", tt.uid, len(refs), tt.want)
+		}
+	}
+
+	if err := im.ApplyBatch(nil); err != nil {
+		t.Errorf("ApplyBatch(nil) error = %v", err)
+	}
+}
+
+// benchmarkEntry returns the i-th entry of BenchmarkAddWithManyIndexes,
+// with several values in most of the indexed attributes.
+func benchmarkEntry(i int) *Entry {
+	entry := NewEntry(fmt.Sprintf("uid=user%d,ou=users,dc=example,dc=com", i))
+	entry.PageID = storage.PageID(i + 1)
+	entry.SetAttribute("objectclass", [][]byte{
+		[]byte("top"), []byte("person"), []byte("organizationalPerson"), []byte("inetOrgPerson"),
+	})
+	entry.SetAttribute("uid", [][]byte{[]byte(fmt.Sprintf("user%d", i))})
+	entry.SetAttribute("cn", [][]byte{[]byte(fmt.Sprintf("User %d", i))})
+	entry.SetAttribute("sn", [][]byte{[]byte(fmt.Sprintf("Surname%d", i%100))})
+	entry.SetAttribute("mail", [][]byte{
+		[]byte(fmt.Sprintf("user%d@example.com", i)), []byte(fmt.Sprintf("u%d@example.org", i)),
+	})
+	var groups [][]byte
+	for g := 0; g < 8; g++ {
+		groups = append(groups, []byte(fmt.Sprintf("cn=group%d,ou=groups,dc=example,dc=com", (i+g)%16)))
+	}
+	entry.SetAttribute("memberof", groups)
+	entry.SetAttribute("employeenumber", [][]byte{[]byte(fmt.Sprintf("%08d", i))})
+	entry.SetAttribute("departmentnumber", [][]byte{[]byte(fmt.Sprintf("%d", i%10))})
+	return entry
+}
+
+// BenchmarkAddWithManyIndexes measures the index maintenance of adding
+// entries with eight indexed attributes: value by value as before batching,
+// one entry per Batch, and 16 entries per Batch as in a bulk import
+// transaction. Page reads and writes are reported per entry.
+func BenchmarkAddWithManyIndexes(b *testing.B) {
+	run := func(b *testing.B, perTxn int, add func(im *IndexManager, entries []*Entry) error) {
+		pm, cleanup := createTestPageManager(b)
+		b.Cleanup(cleanup)
+
+		im, err := NewIndexManager(pm)
+		if err != nil {
+			b.Fatalf("NewIndexManager() error = %v", err)
+		}
+		for _, attr := range []string{"employeenumber", "departmentnumber"} {
+			if err := im.CreateIndex(attr, IndexEquality); err != nil {
+				b.Fatalf("CreateIndex(%s) error = %v", attr, err)
+			}
+		}
+
+		before := pm.Stats()
+		b.ResetTimer()
+		for i := 0; i < b.N; i += perTxn {
+			var entries []*Entry
+			for j := i; j < i+perTxn && j < b.N; j++ {
+				entries = append(entries, benchmarkEntry(j))
+			}
+			if err := add(im, entries); err != nil {
+				b.Fatalf("add error = %v", err)
+			}
+		}
+		b.StopTimer()
+
+		after := pm.Stats()
+		writes := after.PageWrites - before.PageWrites
+		b.ReportMetric(float64(after.PageReads-before.PageReads)/float64(b.N), "page-reads/op")
+		b.ReportMetric(float64(writes)/float64(b.N), "page-writes/op")
+		b.ReportMetric(float64(writes*uint64(after.PageSize))/float64(b.N), "bytes-written/op")
+	}
+
+	perValue := func(im *IndexManager, entries []*Entry) error {
+		im.mu.Lock()
+		defer im.mu.Unlock()
+		for _, entry := range entries {
+			for attr, idx := range im.indexes {
+				if err := addToIndex(idx, entry.GetAttributeWithOptions(attr), entry.EntryRef()); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	batched := func(im *IndexManager, entries []*Entry) error {
+		batch := NewBatch()
+		for _, entry := range entries {
+			batch.IndexEntry(entry)
+		}
+		return im.ApplyBatch(batch)
+	}
+
+	b.Run("PerValue", func(b *testing.B) { run(b, 1, perValue) })
+	b.Run("Batched", func(b *testing.B) { run(b, 1, batched) })
+	b.Run("Batched16", func(b *testing.B) { run(b, 16, batched) })
+}
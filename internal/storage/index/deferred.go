@@ -0,0 +1,262 @@
+package index
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Deferred indexer defaults.
+const (
+	// DefaultFlushInterval is the default time between deferred index flushes.
+	DefaultFlushInterval = 100 * time.Millisecond
+
+	// DefaultMaxPending is the default number of queued updates that
+	// triggers an early flush.
+	DefaultMaxPending = 1024
+)
+
+// ErrIndexerClosed is returned when updates are queued on a closed
+// DeferredIndexer.
+var ErrIndexerClosed = errors.New("deferred indexer is closed")
+
+// IndexUpdate is a single queued index change. OldEntry is removed from the
+// indexes and NewEntry is added; either may be nil.
+type IndexUpdate struct {
+	OldEntry *Entry
+	NewEntry *Entry
+}
+
+// DeferredIndexerConfig holds configuration for a DeferredIndexer.
+type DeferredIndexerConfig struct {
+	// FlushInterval is the maximum time an update stays queued.
+	FlushInterval time.Duration
+
+	// MaxPending flushes the queue early once this many updates are waiting.
+	MaxPending int
+}
+
+// DefaultDeferredIndexerConfig returns the default deferred indexer configuration.
+func DefaultDeferredIndexerConfig() DeferredIndexerConfig {
+	return DeferredIndexerConfig{
+		FlushInterval: DefaultFlushInterval,
+		MaxPending:    DefaultMaxPending,
+	}
+}
+
+// DeferredIndexer queues index updates and applies them to an IndexManager
+// in batches from a background goroutine, taking index maintenance off the
+// write path. An update is visible in the indexes at most one FlushInterval
+// after it was queued.
+type DeferredIndexer struct {
+	manager *IndexManager
+	config  DeferredIndexerConfig
+
+	mu      sync.Mutex
+	pending []IndexUpdate
+	err     error
+	closed  bool
+
+	// flushMu serializes batch application so updates are applied in the
+	// order they were queued.
+	flushMu sync.Mutex
+
+	kickCh chan struct{}
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// NewDeferredIndexer creates a DeferredIndexer for the given IndexManager and
+// starts its flush goroutine.
+func NewDeferredIndexer(manager *IndexManager, config DeferredIndexerConfig) *DeferredIndexer {
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = DefaultFlushInterval
+	}
+	if config.MaxPending <= 0 {
+		config.MaxPending = DefaultMaxPending
+	}
+
+	d := &DeferredIndexer{
+		manager: manager,
+		config:  config,
+		kickCh:  make(chan struct{}, 1),
+		stopCh:  make(chan struct{}),
+		doneCh:  make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+// IndexEntry queues an entry to be added to the indexes.
+func (d *DeferredIndexer) IndexEntry(entry *Entry) error {
+	return d.UpdateIndexes(nil, entry)
+}
+
+// UnindexEntry queues an entry to be removed from the indexes.
+func (d *DeferredIndexer) UnindexEntry(entry *Entry) error {
+	return d.UpdateIndexes(entry, nil)
+}
+
+// UpdateIndexes queues an index change with the same semantics as
+// IndexManager.UpdateIndexes. The entries are copied, so callers may reuse
+// them once this returns.
+func (d *DeferredIndexer) UpdateIndexes(oldEntry, newEntry *Entry) error {
+	if oldEntry == nil && newEntry == nil {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return ErrIndexerClosed
+	}
+
+	d.pending = append(d.pending, IndexUpdate{
+		OldEntry: copyEntry(oldEntry),
+		NewEntry: copyEntry(newEntry),
+	})
+
+	if len(d.pending) >= d.config.MaxPending {
+		select {
+		case d.kickCh <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// QueueBatch queues the updates of a Batch, which keep their order and are
+// applied together with the rest of the queue.
+func (d *DeferredIndexer) QueueBatch(b *Batch) error {
+	if b == nil || len(b.updates) == 0 {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return ErrIndexerClosed
+	}
+
+	d.pending = append(d.pending, b.updates...)
+
+	if len(d.pending) >= d.config.MaxPending {
+		select {
+		case d.kickCh <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Pending returns the number of queued updates.
+func (d *DeferredIndexer) Pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.pending)
+}
+
+// Flush applies all queued updates now. It returns the first error seen by
+// any flush since the last call.
+func (d *DeferredIndexer) Flush() error {
+	d.flush()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	err := d.err
+	d.err = nil
+	return err
+}
+
+// Discard drops all queued updates. It is used when the indexes are cleared.
+func (d *DeferredIndexer) Discard() {
+	d.flushMu.Lock()
+	defer d.flushMu.Unlock()
+
+	d.mu.Lock()
+	d.pending = nil
+	d.mu.Unlock()
+}
+
+// Close stops the flush goroutine and applies any remaining updates.
+func (d *DeferredIndexer) Close() error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed = true
+	d.mu.Unlock()
+
+	close(d.stopCh)
+	<-d.doneCh
+
+	return d.Flush()
+}
+
+// run flushes the queue every FlushInterval, or earlier when it fills up.
+func (d *DeferredIndexer) run() {
+	defer close(d.doneCh)
+
+	ticker := time.NewTicker(d.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stopCh:
+			return
+		case <-ticker.C:
+			d.flush()
+		case <-d.kickCh:
+			d.flush()
+		}
+	}
+}
+
+// flush takes the queued updates and applies them as one batch.
+func (d *DeferredIndexer) flush() {
+	d.flushMu.Lock()
+	defer d.flushMu.Unlock()
+
+	d.mu.Lock()
+	batch := d.pending
+	d.pending = nil
+	d.mu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+
+	if err := d.manager.ApplyUpdates(batch); err != nil {
+		d.mu.Lock()
+		if d.err == nil {
+			d.err = err
+		}
+		d.mu.Unlock()
+	}
+}
+
+// copyEntry returns a copy of entry that does not share the attribute map
+// or value slices with it.
+func copyEntry(entry *Entry) *Entry {
+	if entry == nil {
+		return nil
+	}
+
+	c := &Entry{
+		DN:         entry.DN,
+		Attributes: make(map[string][][]byte, len(entry.Attributes)),
+		PageID:     entry.PageID,
+		SlotID:     entry.SlotID,
+	}
+	for name, values := range entry.Attributes {
+		copied := make([][]byte, len(values))
+		for i, v := range values {
+			copied[i] = append([]byte(nil), v...)
+		}
+		c.Attributes[name] = copied
+	}
+	return c
+}
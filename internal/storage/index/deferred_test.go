@@ -0,0 +1,115 @@
+package index
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestDeferredIndexer(t *testing.T, interval time.Duration) (*IndexManager, *DeferredIndexer) {
+	t.Helper()
+
+	pm, cleanup := createTestPageManager(t)
+	t.Cleanup(cleanup)
+
+	im, err := NewIndexManager(pm)
+	if err != nil {
+		t.Fatalf("NewIndexManager() error = %v", err)
+	}
+
+	d := NewDeferredIndexer(im, DeferredIndexerConfig{FlushInterval: interval})
+	t.Cleanup(func() { d.Close() })
+	return im, d
+}
+
+func newIndexedEntry(dn, uid string, slot uint16) *Entry {
+	entry := NewEntry(dn)
+	entry.SetAttribute("uid", [][]byte{[]byte(uid)})
+	entry.PageID = 1
+	entry.SlotID = slot
+	return entry
+}
+
+// waitIndexed polls the uid index until it holds want references for uid,
+// failing after timeout.
+func waitIndexed(t *testing.T, im *IndexManager, uid string, want int, timeout time.Duration) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		refs, err := im.Search("uid", []byte(uid))
+		if err != nil {
+			t.Fatalf("Search() error = %v", err)
+		}
+		if len(refs) == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("uid %s has %d index entries after %v, want %d", uid, len(refs), timeout, want)
+		}
+		time.Sleep(timeout / 20)
+	}
+}
+
+func TestDeferredIndexerFlushInterval(t *testing.T) {
+	const interval = 50 * time.Millisecond
+	im, d := newTestDeferredIndexer(t, interval)
+
+	alice := newIndexedEntry("uid=alice,dc=example,dc=com", "alice", 1)
+	if err := d.IndexEntry(alice); err != nil {
+		t.Fatalf("IndexEntry() error = %v", err)
+	}
+
+	// The caller may reuse the entry once it has been queued.
+	alice.Attributes["uid"][0][0] = 'X'
+
+	waitIndexed(t, im, "alice", 1, 2*interval)
+	if d.Pending() != 0 {
+		t.Errorf("Pending() = %d after flush, want 0", d.Pending())
+	}
+
+	renamed := newIndexedEntry("uid=alice,dc=example,dc=com", "alicia", 1)
+	if err := d.UpdateIndexes(newIndexedEntry(alice.DN, "alice", 1), renamed); err != nil {
+		t.Fatalf("UpdateIndexes() error = %v", err)
+	}
+	waitIndexed(t, im, "alicia", 1, 2*interval)
+	waitIndexed(t, im, "alice", 0, 2*interval)
+
+	if err := d.UnindexEntry(renamed); err != nil {
+		t.Fatalf("UnindexEntry() error = %v", err)
+	}
+	waitIndexed(t, im, "alicia", 0, 2*interval)
+}
+
+func TestDeferredIndexerFlushAndClose(t *testing.T) {
+	im, d := newTestDeferredIndexer(t, time.Hour)
+
+	for i := 0; i < 10; i++ {
+		if err := d.IndexEntry(newIndexedEntry("uid=bob,dc=example,dc=com", "bob", uint16(i))); err != nil {
+			t.Fatalf("IndexEntry() error = %v", err)
+		}
+	}
+	if d.Pending() != 10 {
+		t.Fatalf("Pending() = %d, want 10", d.Pending())
+	}
+
+	if err := d.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	waitIndexed(t, im, "bob", 10, 0)
+
+	// Discarded updates are never applied.
+	d.IndexEntry(newIndexedEntry("uid=carol,dc=example,dc=com", "carol", 1))
+	d.Discard()
+	d.IndexEntry(newIndexedEntry("uid=dave,dc=example,dc=com", "dave", 2))
+
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	waitIndexed(t, im, "dave", 1, 0)
+	waitIndexed(t, im, "carol", 0, 0)
+
+	if err := d.IndexEntry(newIndexedEntry("uid=erin,dc=example,dc=com", "erin", 3)); !errors.Is(err, ErrIndexerClosed) {
+		t.Errorf("IndexEntry() after Close error = %v, want ErrIndexerClosed", err)
+	}
+}
@@ -38,16 +38,52 @@
 //	// "alice" is tokenized to: ["ali", "lic", "ice"]
 //	// Search for "*lic*" finds entries containing "lic"
 //
+// A TrigramIndex additionally records the position of every trigram from
+// the start and from the end of the value, so that anchored components
+// resolve to exact keys:
+//
+//	ti, _ := index.NewTrigramIndex(pageManager)
+//	ti.Index([]byte("alice"), ref)
+//
+//	// (cn=ali*) looks up "ali" at position 0 only
+//	refs, _ := ti.Search(index.SubstringQuery{Initial: "ali"})
+//
+// Results are candidates; recheck each value with MatchesSubstring.
+//
 // # Index Maintenance
 //
-// Indexes are updated automatically on entry changes:
+// Index changes are queued in a Batch as entries change and applied
+// together, which the engine does when the transaction commits:
+//
+//	b := index.NewBatch()
 //
 //	// On entry add
-//	manager.IndexEntry(entry)
+//	b.IndexEntry(entry)
 //
 //	// On entry delete
-//	manager.UnindexEntry(entry)
+//	b.UnindexEntry(entry)
 //
 //	// On entry modify
-//	manager.ReindexEntry(oldEntry, newEntry)
+//	b.ReindexEntry(oldEntry, newEntry)
+//
+//	manager.ApplyBatch(b)
+//
+// Applying a batch coalesces the changes to each key and inserts the keys
+// of every index in sorted order, so keys sharing a leaf cost one page
+// write and the nodes above it are read once.
+//
+// # Deferred Indexing
+//
+// A DeferredIndexer takes index maintenance off the commit path. Updates
+// are queued and applied in batches by a background goroutine every
+// FlushInterval, so an entry is visible to index lookups at most one
+// interval after it was written:
+//
+//	d := index.NewDeferredIndexer(manager, index.DefaultDeferredIndexerConfig())
+//	defer d.Close()
+//
+//	d.IndexEntry(entry)
+//	d.Flush() // apply queued updates now
+//
+// The engine uses it when EngineOptions.DeferredIndexing is set.
 package index
@@ -52,6 +52,9 @@ type IndexManager struct {
 
 	// closed indicates if the manager has been closed.
 	closed bool
+
+	// rebuilds holds the running rebuilds by attribute name.
+	rebuilds map[string]*Rebuild
 }
 
 // NewIndexManager creates a new IndexManager with the given PageManager.
@@ -410,6 +413,28 @@ func (im *IndexManager) GetIndex(attr string) (*Index, bool) {
 // UpdateIndexes updates all indexes when an entry is modified.
 // It removes old values and adds new values atomically.
 func (im *IndexManager) UpdateIndexes(oldEntry, newEntry *Entry) error {
+	if oldEntry == nil && newEntry == nil {
+		return nil
+	}
+	return im.ApplyUpdates([]IndexUpdate{{OldEntry: oldEntry, NewEntry: newEntry}})
+}
+
+// ApplyBatch applies the changes queued in a Batch. See ApplyUpdates.
+func (im *IndexManager) ApplyBatch(b *Batch) error {
+	if b == nil || len(b.updates) == 0 {
+		return nil
+	}
+	return im.ApplyUpdates(b.updates)
+}
+
+// ApplyUpdates applies a batch of index changes while holding the manager
+// lock once. The changes are coalesced into one net change per index key
+// and entry reference, then applied to each index in key order, so that
+// keys sharing a leaf are written with one page write. It is used to apply
+// transaction batches and by DeferredIndexer to flush queued updates. A
+// failed index does not stop the rest of the batch; the first error is
+// returned.
+func (im *IndexManager) ApplyUpdates(updates []IndexUpdate) error {
 	im.mu.Lock()
 	defer im.mu.Unlock()
 
@@ -417,129 +442,62 @@ func (im *IndexManager) UpdateIndexes(oldEntry, newEntry *Entry) error {
 		return ErrManagerClosed
 	}
 
-	// Handle deletion (oldEntry != nil, newEntry == nil)
-	if newEntry == nil && oldEntry != nil {
-		return im.removeFromIndexes(oldEntry)
+	if len(im.rebuilds) > 0 {
+		im.logRebuildChanges(updates)
 	}
-
-	// Handle insertion (oldEntry == nil, newEntry != nil)
-	if oldEntry == nil && newEntry != nil {
-		return im.addToIndexes(newEntry)
-	}
-
-	// Handle update (both non-nil)
-	if oldEntry != nil && newEntry != nil {
-		// Remove old values first
-		if err := im.removeFromIndexes(oldEntry); err != nil {
-			return err
-		}
-		// Add new values
-		return im.addToIndexes(newEntry)
-	}
-
-	// Both nil - nothing to do
-	return nil
+	return im.applyOps(im.collectOps(updates))
 }
 
-// addToIndexes adds an entry's attribute values to all relevant indexes.
-func (im *IndexManager) addToIndexes(entry *Entry) error {
-	if entry == nil {
-		return nil
-	}
-
-	ref := entry.EntryRef()
-
-	for attr, idx := range im.indexes {
-		values := entry.GetAttribute(attr)
-		if len(values) == 0 {
+// indexKeys returns the keys under which the values of an attribute are
+// stored in idx: the values themselves for an equality index, a single
+// presence marker, or every substring for a substring index.
+func indexKeys(idx *Index, values [][]byte) [][]byte {
+	var keys [][]byte
+	for _, value := range values {
+		if len(value) == 0 {
 			continue
 		}
 
-		for _, value := range values {
-			if len(value) == 0 {
-				continue
-			}
-
-			// For equality indexes, use the value as the key
-			if idx.Type == IndexEquality {
-				if err := idx.Tree.Insert(value, ref); err != nil {
-					return err
-				}
-			}
-
-			// For presence indexes, use a marker
-			if idx.Type == IndexPresence {
-				if err := idx.Tree.Insert(PresenceMarker, ref); err != nil {
-					return err
-				}
-				break // Only need one entry for presence
-			}
-
-			// For substring indexes, create multiple entries for substrings
-			if idx.Type == IndexSubstring {
-				substrings := generateSubstrings(value)
-				for _, substr := range substrings {
-					if err := idx.Tree.Insert(substr, ref); err != nil {
-						return err
-					}
-				}
-			}
+		switch idx.Type {
+		case IndexEquality:
+			keys = append(keys, value)
+		case IndexPresence:
+			// Only need one entry for presence
+			return [][]byte{PresenceMarker}
+		case IndexSubstring:
+			keys = append(keys, generateSubstrings(value)...)
 		}
 	}
-
-	return nil
+	return keys
 }
 
-// removeFromIndexes removes an entry's attribute values from all relevant indexes.
-func (im *IndexManager) removeFromIndexes(entry *Entry) error {
-	if entry == nil {
-		return nil
-	}
-
-	ref := entry.EntryRef()
-
-	for attr, idx := range im.indexes {
-		values := entry.GetAttribute(attr)
-		if len(values) == 0 {
-			continue
-		}
-
-		for _, value := range values {
-			if len(value) == 0 {
-				continue
-			}
-
-			// For equality indexes, delete the value
-			if idx.Type == IndexEquality {
-				// Ignore not found errors during deletion
-				_ = idx.Tree.Delete(value, ref)
-			}
-
-			// For presence indexes, delete the marker
-			if idx.Type == IndexPresence {
-				_ = idx.Tree.Delete(PresenceMarker, ref)
-				break
-			}
-
-			// For substring indexes, delete all substrings
-			if idx.Type == IndexSubstring {
-				substrings := generateSubstrings(value)
-				for _, substr := range substrings {
-					_ = idx.Tree.Delete(substr, ref)
-				}
-			}
+// addToIndex adds the values of one attribute to its index.
+func addToIndex(idx *Index, values [][]byte, ref btree.EntryRef) error {
+	for _, key := range indexKeys(idx, values) {
+		if err := idx.Tree.Insert(key, ref); err != nil {
+			return err
 		}
 	}
-
 	return nil
 }
 
+// RebuildIndex drops the index for attr and recreates it with the same type
+// from the given entries. Other indexes are not touched. If no index exists
+// for attr, an equality index is created. Returns the number of entries that
+// have a value for attr. See RebuildIndexWithProgress.
+func (im *IndexManager) RebuildIndex(attr string, entries []*Entry) (int, error) {
+	p, err := im.RebuildIndexWithProgress(attr, entries, nil)
+	return p.EntriesIndexed, err
+}
+
 // generateSubstrings generates all substrings of a value for substring indexing.
-// This is used for substring searches like (cn=*admin*).
+// This is used for substring searches like (cn=*admin*). Substrings are folded
+// with FoldSubstring, so lookups must fold their keys as well.
 func generateSubstrings(value []byte) [][]byte {
 	if len(value) == 0 {
 		return nil
 	}
+	value = FoldSubstring(value)
 
 	var substrings [][]byte
 	minLen := 3 // Minimum substring length
@@ -575,6 +533,26 @@ func (im *IndexManager) IndexCount() int {
 	return len(im.indexes)
 }
 
+// EntryCounts returns the number of entries of each index by attribute.
+// It walks the leaves of every index, so it reads all of their pages.
+func (im *IndexManager) EntryCounts() map[string]uint64 {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
+	counts := make(map[string]uint64, len(im.indexes))
+	for attr, idx := range im.indexes {
+		if idx.Tree == nil {
+			continue
+		}
+		stats, err := idx.Tree.Stats()
+		if err != nil {
+			continue
+		}
+		counts[attr] = uint64(stats.TotalEntries)
+	}
+	return counts
+}
+
 // ClearAll clears all data from all indexes.
 // The index structure is preserved, only the data is removed.
 func (im *IndexManager) ClearAll() error {
@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/KilimcininKorOglu/oba/internal/storage"
@@ -11,7 +12,7 @@ import (
 )
 
 // Helper function to create a temporary page manager for testing.
-func createTestPageManager(t *testing.T) (*storage.PageManager, func()) {
+func createTestPageManager(t testing.TB) (*storage.PageManager, func()) {
 	t.Helper()
 
 	tmpDir, err := os.MkdirTemp("", "index_test_*")
@@ -586,6 +587,50 @@ func TestUpdateIndexesModify(t *testing.T) {
 	}
 }
 
+func TestUpdateIndexesAttributeOptions(t *testing.T) {
+	pm, cleanup := createTestPageManager(t)
+	defer cleanup()
+
+	im, err := NewIndexManager(pm)
+	if err != nil {
+		t.Fatalf("failed to create index manager: %v", err)
+	}
+	defer im.Close()
+
+	// Tagged variants are indexed under their base attribute
+	entry := NewEntry("uid=dana,dc=example,dc=com")
+	entry.SetAttribute("cn", [][]byte{[]byte("Dana")})
+	entry.SetAttribute("cn;lang-de", [][]byte{[]byte("Daniela")})
+	entry.SetAttribute("cnx", [][]byte{[]byte("Other")})
+	entry.PageID = 70
+	entry.SlotID = 1
+
+	if err := im.UpdateIndexes(nil, entry); err != nil {
+		t.Fatalf("failed to insert: %v", err)
+	}
+
+	for value, want := range map[string]int{"Dana": 1, "Daniela": 1, "Other": 0} {
+		refs, err := im.Search("cn", []byte(value))
+		if err != nil {
+			t.Fatalf("failed to search: %v", err)
+		}
+		if len(refs) != want {
+			t.Errorf("Search(cn, %q) = %d results, want %d", value, len(refs), want)
+		}
+	}
+
+	if err := im.UpdateIndexes(entry, nil); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	refs, err := im.Search("cn", []byte("Daniela"))
+	if err != nil {
+		t.Fatalf("failed to search after delete: %v", err)
+	}
+	if len(refs) != 0 {
+		t.Errorf("expected tagged value removed from index, got %d results", len(refs))
+	}
+}
+
 // =============================================================================
 // Search Tests
 // =============================================================================
@@ -786,6 +831,43 @@ func TestGenerateSubstrings(t *testing.T) {
 	}
 }
 
+func TestGenerateSubstringsFolded(t *testing.T) {
+	substrings := generateSubstrings([]byte("AdMiN"))
+
+	for _, s := range substrings {
+		if string(s) != strings.ToLower(string(s)) {
+			t.Errorf("substring %q is not case folded", s)
+		}
+	}
+	if len(substrings) == 0 || string(substrings[0]) != "adm" {
+		t.Errorf("expected first substring %q, got %q", "adm", substrings)
+	}
+}
+
+func TestFoldSubstring(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"", ""},
+		{"admin", "admin"},
+		{"Admin User", "admin user"},
+		{"GRÜSSE", "grüsse"},
+		{"İSTANBUL", "istanbul"},
+	}
+
+	for _, tt := range tests {
+		if got := string(FoldSubstring([]byte(tt.value))); got != tt.expected {
+			t.Errorf("FoldSubstring(%q) = %q, want %q", tt.value, got, tt.expected)
+		}
+	}
+
+	// Appending keeps the existing prefix, also on the non-ASCII path
+	if got := string(AppendFoldSubstring([]byte("x:"), []byte("AbÇ"))); got != "x:abç" {
+		t.Errorf("AppendFoldSubstring = %q, want %q", got, "x:abç")
+	}
+}
+
 func TestGenerateSubstringsEmpty(t *testing.T) {
 	substrings := generateSubstrings([]byte{})
 	if len(substrings) != 0 {
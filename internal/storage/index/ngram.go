@@ -2,8 +2,10 @@
 package index
 
 import (
+	"bytes"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 // NgramSize is the default size of n-grams used for substring indexing.
@@ -19,7 +21,7 @@ func GenerateNgrams(s string, n int) []string {
 	}
 
 	// Normalize to lowercase for case-insensitive matching
-	s = strings.ToLower(s)
+	s = string(FoldSubstring([]byte(s)))
 
 	// If string is shorter than n, return the whole string as a single n-gram
 	if len(s) < n {
@@ -35,6 +37,30 @@ func GenerateNgrams(s string, n int) []string {
 	return ngrams
 }
 
+// FoldSubstring returns value lowercased the way caseIgnoreSubstringsMatch
+// compares values. Every substring index folds its keys with it, and the
+// filter evaluator folds values and assertions the same way, so that index
+// candidates agree with evaluation.
+func FoldSubstring(value []byte) []byte {
+	return AppendFoldSubstring(nil, value)
+}
+
+// AppendFoldSubstring appends the folded form of value to dst. ASCII values
+// are folded without decoding runes.
+func AppendFoldSubstring(dst, value []byte) []byte {
+	start := len(dst)
+	for _, c := range value {
+		if c >= utf8.RuneSelf {
+			return append(dst[:start], bytes.ToLower(value)...)
+		}
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		dst = append(dst, c)
+	}
+	return dst
+}
+
 // GenerateNgramsDefault generates n-grams using the default NgramSize.
 func GenerateNgramsDefault(s string) []string {
 	return GenerateNgrams(s, NgramSize)
@@ -0,0 +1,273 @@
+package index
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/btree"
+)
+
+// ErrRebuildInProgress is returned when an index is already being rebuilt.
+var ErrRebuildInProgress = errors.New("index rebuild already in progress")
+
+// RebuildProgressInterval is the number of entries scanned between two
+// progress reports of a rebuild.
+const RebuildProgressInterval = 1000
+
+// Catch-up limits of Rebuild.Commit.
+const (
+	// catchUpRounds is the number of times the logged changes are replayed
+	// without the manager lock before the rest is replayed while holding it
+	catchUpRounds = 8
+	// catchUpSwapThreshold is the number of logged changes small enough to
+	// replay while holding the manager lock
+	catchUpSwapThreshold = 256
+)
+
+// Rebuild builds a replacement for the index of one attribute in fresh
+// pages, while the current index keeps serving lookups and updates. The
+// changes applied to the manager in the meantime are logged, and Commit
+// replays them into the new tree before swapping it in. A Rebuild is used
+// by one goroutine.
+type Rebuild struct {
+	im     *IndexManager
+	attr   string
+	shadow *Index
+
+	// pending holds the changes to the attribute since the rebuild began,
+	// in the order they were applied. It is guarded by im.mu.
+	pending []indexOp
+
+	start    time.Time
+	progress storage.IndexRebuildProgress
+}
+
+// BeginRebuild starts a rebuild of the index for attr. The new index has
+// the type of the current one, or is an equality index if attr has none.
+func (im *IndexManager) BeginRebuild(attr string) (*Rebuild, error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	if im.closed {
+		return nil, ErrManagerClosed
+	}
+
+	attr = strings.ToLower(strings.TrimSpace(attr))
+	if attr == "" || len(attr) > MaxAttributeNameLength {
+		return nil, ErrInvalidAttribute
+	}
+	if _, busy := im.rebuilds[attr]; busy {
+		return nil, ErrRebuildInProgress
+	}
+
+	indexType := IndexEquality
+	if idx, exists := im.indexes[attr]; exists {
+		indexType = idx.Type
+	}
+	tree, err := btree.NewBPlusTree(im.pageManager, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Rebuild{
+		im:   im,
+		attr: attr,
+		shadow: &Index{
+			Attribute:  attr,
+			Type:       indexType,
+			Tree:       tree,
+			RootPageID: tree.Root(),
+		},
+		start: time.Now(),
+	}
+	if im.rebuilds == nil {
+		im.rebuilds = make(map[string]*Rebuild)
+	}
+	im.rebuilds[attr] = r
+	return r, nil
+}
+
+// Add adds scanned entries to the new index.
+func (r *Rebuild) Add(entries ...*Entry) error {
+	for _, entry := range entries {
+		r.progress.EntriesScanned++
+		keys := indexKeys(r.shadow, entry.GetAttributeWithOptions(r.attr))
+		if len(keys) == 0 {
+			continue
+		}
+		r.progress.EntriesIndexed++
+
+		ref := entry.EntryRef()
+		for _, key := range keys {
+			if err := r.shadow.Tree.Insert(key, ref); err != nil {
+				return err
+			}
+			r.progress.KeysWritten++
+		}
+	}
+	return nil
+}
+
+// Progress returns the progress of the rebuild.
+func (r *Rebuild) Progress() storage.IndexRebuildProgress {
+	p := r.progress
+	p.Elapsed = time.Since(r.start)
+	return p
+}
+
+// Commit replays the changes logged since the rebuild began into the new
+// index and replaces the current index with it, freeing the pages of the
+// old one. While the log keeps growing it is replayed without the manager
+// lock, for at most catchUpRounds rounds; the remaining changes are
+// replayed with the lock held, so none is lost before the swap.
+func (r *Rebuild) Commit() (storage.IndexRebuildProgress, error) {
+	im := r.im
+	for round := 0; ; round++ {
+		im.mu.Lock()
+		if im.closed {
+			im.mu.Unlock()
+			return r.Progress(), ErrManagerClosed
+		}
+		pending := r.pending
+		r.pending = nil
+
+		if len(pending) <= catchUpSwapThreshold || round >= catchUpRounds {
+			if err := r.replay(pending); err != nil {
+				r.abortLocked()
+				im.mu.Unlock()
+				return r.Progress(), err
+			}
+			err := im.swapIn(r)
+			im.mu.Unlock()
+			return r.Progress(), err
+		}
+		im.mu.Unlock()
+
+		if err := r.replay(pending); err != nil {
+			r.Abort()
+			return r.Progress(), err
+		}
+	}
+}
+
+// Abort stops the rebuild and frees the pages of the new index. The
+// current index is not changed.
+func (r *Rebuild) Abort() {
+	r.im.mu.Lock()
+	defer r.im.mu.Unlock()
+	r.abortLocked()
+}
+
+// abortLocked is Abort with im.mu held.
+func (r *Rebuild) abortLocked() {
+	if r.im.rebuilds[r.attr] != r {
+		return
+	}
+	delete(r.im.rebuilds, r.attr)
+	_ = r.im.cleanupTreePages(r.shadow.Tree)
+}
+
+// replay applies logged changes to the new index. A key is only inserted
+// for a reference it does not hold yet, since the scan may have added it.
+func (r *Rebuild) replay(ops []indexOp) error {
+	for _, op := range ops {
+		if op.delta < 0 {
+			// Ignore not found errors during deletion
+			_ = r.shadow.Tree.Delete(op.key, op.ref)
+			continue
+		}
+
+		refs, err := r.shadow.Tree.Search(op.key)
+		if err != nil && !errors.Is(err, btree.ErrKeyNotFound) {
+			return err
+		}
+		if containsRef(refs, op.ref) {
+			continue
+		}
+		if err := r.shadow.Tree.Insert(op.key, op.ref); err != nil {
+			return err
+		}
+		r.progress.KeysWritten++
+	}
+	return nil
+}
+
+// containsRef reports whether refs holds ref.
+func containsRef(refs []btree.EntryRef, ref btree.EntryRef) bool {
+	for _, other := range refs {
+		if storage.CompareEntryRefs(other, ref) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// swapIn replaces the index of the rebuilt attribute with the new one and
+// saves the index metadata. Must be called with im.mu held.
+func (im *IndexManager) swapIn(r *Rebuild) error {
+	old := im.indexes[r.attr]
+
+	r.shadow.RootPageID = r.shadow.Tree.Root()
+	im.indexes[r.attr] = r.shadow
+	delete(im.rebuilds, r.attr)
+
+	if old != nil {
+		// The pages of a damaged tree, as after a crash, are leaked
+		_ = im.cleanupTreePages(old.Tree)
+	}
+	return im.saveMetadata()
+}
+
+// logRebuildChanges adds the changes updates make to each attribute being
+// rebuilt to its log. Must be called with im.mu held.
+func (im *IndexManager) logRebuildChanges(updates []IndexUpdate) {
+	for _, r := range im.rebuilds {
+		for _, u := range updates {
+			r.pending = appendEntryOps(r.pending, r.shadow, u.OldEntry, -1)
+			r.pending = appendEntryOps(r.pending, r.shadow, u.NewEntry, 1)
+		}
+	}
+}
+
+// appendEntryOps appends the changes of the keys of entry in idx to ops.
+func appendEntryOps(ops []indexOp, idx *Index, entry *Entry, delta int) []indexOp {
+	if entry == nil {
+		return ops
+	}
+	ref := entry.EntryRef()
+	for _, key := range indexKeys(idx, entry.GetAttributeWithOptions(idx.Attribute)) {
+		ops = append(ops, indexOp{key: key, ref: ref, delta: delta})
+	}
+	return ops
+}
+
+// RebuildIndexWithProgress drops the index for attr and recreates it with
+// the same type from the given entries, calling progress, if not nil,
+// every RebuildProgressInterval entries and at the end. The new index is
+// built in fresh pages and replaces the old one when complete, so a failed
+// rebuild leaves the old index in place. If no index exists for attr, an
+// equality index is created.
+func (im *IndexManager) RebuildIndexWithProgress(attr string, entries []*Entry, progress func(storage.IndexRebuildProgress)) (storage.IndexRebuildProgress, error) {
+	r, err := im.BeginRebuild(attr)
+	if err != nil {
+		return storage.IndexRebuildProgress{}, err
+	}
+
+	for i, entry := range entries {
+		if err := r.Add(entry); err != nil {
+			r.Abort()
+			return r.Progress(), err
+		}
+		if progress != nil && (i+1)%RebuildProgressInterval == 0 {
+			progress(r.Progress())
+		}
+	}
+
+	p, err := r.Commit()
+	if err == nil && progress != nil {
+		progress(p)
+	}
+	return p, err
+}
@@ -0,0 +1,150 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// newUIDEntry returns an entry with the given uid stored at pageID.
+func newUIDEntry(uid string, pageID storage.PageID) *Entry {
+	entry := NewEntry("uid=" + uid + ",ou=users,dc=example,dc=com")
+	entry.SetAttribute("uid", [][]byte{[]byte(uid)})
+	entry.PageID = pageID
+	return entry
+}
+
+func TestRebuildReplaysConcurrentUpdates(t *testing.T) {
+	pm, cleanup := createTestPageManager(t)
+	defer cleanup()
+
+	im, err := NewIndexManager(pm)
+	if err != nil {
+		t.Fatalf("failed to create index manager: %v", err)
+	}
+	defer im.Close()
+
+	alice, bob, carol := newUIDEntry("alice", 1), newUIDEntry("bob", 2), newUIDEntry("carol", 3)
+	for _, entry := range []*Entry{alice, bob} {
+		if err := im.UpdateIndexes(nil, entry); err != nil {
+			t.Fatalf("failed to update indexes: %v", err)
+		}
+	}
+
+	r, err := im.BeginRebuild("uid")
+	if err != nil {
+		t.Fatalf("BeginRebuild() error = %v", err)
+	}
+	if _, err := im.BeginRebuild("UID"); !errors.Is(err, ErrRebuildInProgress) {
+		t.Errorf("second BeginRebuild() error = %v, want %v", err, ErrRebuildInProgress)
+	}
+
+	// Changes made while the entries are scanned reach the new index
+	if err := r.Add(alice); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := im.UpdateIndexes(nil, carol); err != nil {
+		t.Fatalf("failed to update indexes: %v", err)
+	}
+	if err := im.UpdateIndexes(bob, nil); err != nil {
+		t.Fatalf("failed to update indexes: %v", err)
+	}
+	if err := r.Add(bob, carol); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	p, err := r.Commit()
+	if err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if p.EntriesScanned != 3 || p.EntriesIndexed != 3 {
+		t.Errorf("progress = %+v, want 3 entries scanned and indexed", p)
+	}
+
+	for uid, want := range map[string]int{"alice": 1, "bob": 0, "carol": 1} {
+		refs, err := im.Search("uid", []byte(uid))
+		if err != nil {
+			t.Fatalf("Search(%s) error = %v", uid, err)
+		}
+		if len(refs) != want {
+			t.Errorf("Search(%s) = %d refs, want %d", uid, len(refs), want)
+		}
+	}
+
+	// The rebuild is over, so changes are applied to the new index only
+	if err := im.UpdateIndexes(carol, nil); err != nil {
+		t.Fatalf("failed to update indexes: %v", err)
+	}
+	if refs, _ := im.Search("uid", []byte("carol")); len(refs) != 0 {
+		t.Errorf("Search(carol) after delete = %d refs, want 0", len(refs))
+	}
+}
+
+func TestRebuildAbort(t *testing.T) {
+	pm, cleanup := createTestPageManager(t)
+	defer cleanup()
+
+	im, err := NewIndexManager(pm)
+	if err != nil {
+		t.Fatalf("failed to create index manager: %v", err)
+	}
+	defer im.Close()
+
+	if err := im.UpdateIndexes(nil, newUIDEntry("alice", 1)); err != nil {
+		t.Fatalf("failed to update indexes: %v", err)
+	}
+
+	r, err := im.BeginRebuild("uid")
+	if err != nil {
+		t.Fatalf("BeginRebuild() error = %v", err)
+	}
+	r.Abort()
+
+	if refs, _ := im.Search("uid", []byte("alice")); len(refs) != 1 {
+		t.Errorf("Search(alice) after Abort = %d refs, want 1", len(refs))
+	}
+	if _, err := im.BeginRebuild("uid"); err != nil {
+		t.Errorf("BeginRebuild() after Abort error = %v", err)
+	}
+}
+
+func TestRebuildIndexWithProgress(t *testing.T) {
+	pm, cleanup := createTestPageManager(t)
+	defer cleanup()
+
+	im, err := NewIndexManager(pm)
+	if err != nil {
+		t.Fatalf("failed to create index manager: %v", err)
+	}
+	defer im.Close()
+
+	entries := make([]*Entry, 2500)
+	for i := range entries {
+		entries[i] = newUIDEntry(fmt.Sprintf("user%d", i), storage.PageID(i+1))
+	}
+
+	var reports []storage.IndexRebuildProgress
+	p, err := im.RebuildIndexWithProgress("uid", entries, func(p storage.IndexRebuildProgress) {
+		reports = append(reports, p)
+	})
+	if err != nil {
+		t.Fatalf("RebuildIndexWithProgress() error = %v", err)
+	}
+
+	if len(reports) != 3 {
+		t.Fatalf("got %d progress reports, want 3", len(reports))
+	}
+	if reports[0].EntriesScanned != RebuildProgressInterval {
+		t.Errorf("first report scanned %d entries, want %d", reports[0].EntriesScanned, RebuildProgressInterval)
+	}
+	if p.EntriesScanned != 2500 || p.KeysWritten != 2500 || reports[2] != p {
+		t.Errorf("final progress = %+v, last report = %+v", p, reports[2])
+	}
+
+	refs, err := im.Search("uid", []byte("user1234"))
+	if err != nil || len(refs) != 1 || refs[0].PageID != 1235 {
+		t.Errorf("Search(user1234) = %v, %v", refs, err)
+	}
+}
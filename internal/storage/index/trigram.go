@@ -0,0 +1,303 @@
+// Package index provides indexing implementations for ObaDB.
+package index
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/btree"
+)
+
+// TrigramIndex errors.
+var (
+	// ErrPatternTooShort is returned when no component of a substring query
+	// is long enough to contain a trigram. The caller must fall back to a scan.
+	ErrPatternTooShort = errors.New("substring pattern too short for trigram lookup")
+)
+
+// Trigram key layout: a tag byte, the trigram, a big-endian uint16
+// position and the entry reference. Forward keys count positions from the
+// start of the value, reverse keys count from the end, so that both
+// initial and final anchors resolve to a single key prefix. The entry
+// reference keeps every key unique; frequent trigrams would otherwise
+// pile thousands of duplicates onto one key.
+const (
+	trigramSize        = 3
+	trigramKeyForward  = 'F'
+	trigramKeyReverse  = 'R'
+	maxTrigramPosition = 0xFFFF
+)
+
+// SubstringQuery describes an LDAP substring assertion such as
+// (cn=ali*ce*son). Empty components are ignored.
+type SubstringQuery struct {
+	// Initial is the required prefix.
+	Initial string
+	// Any lists substrings that must appear in order.
+	Any []string
+	// Final is the required suffix.
+	Final string
+}
+
+// TrigramIndex is a positional inverted index of value trigrams backed by a
+// B+ tree. Every trigram of a value is stored twice, keyed by its offset
+// from the start and from the end of the value:
+//
+//	"alice" -> F ali 0, F lic 1, F ice 2, R ali 2, R lic 1, R ice 0
+//
+// An unanchored component looks up all forward positions of its trigrams,
+// while initial and final components look up exact positions, which prunes
+// values that only contain the component somewhere else. Values are
+// lowercased, and results are candidates that must be verified with
+// MatchesSubstring.
+type TrigramIndex struct {
+	tree        *btree.BPlusTree
+	pageManager *storage.PageManager
+	mu          sync.RWMutex
+}
+
+// NewTrigramIndex creates a new empty TrigramIndex with the given PageManager.
+func NewTrigramIndex(pm *storage.PageManager) (*TrigramIndex, error) {
+	if pm == nil {
+		return nil, ErrInvalidPageManager
+	}
+
+	tree, err := btree.NewBPlusTree(pm, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TrigramIndex{
+		tree:        tree,
+		pageManager: pm,
+	}, nil
+}
+
+// NewTrigramIndexWithRoot creates a TrigramIndex loading from an existing root page.
+func NewTrigramIndexWithRoot(pm *storage.PageManager, rootPageID storage.PageID) (*TrigramIndex, error) {
+	if pm == nil {
+		return nil, ErrInvalidPageManager
+	}
+
+	tree, err := btree.NewBPlusTreeWithRoot(pm, rootPageID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TrigramIndex{
+		tree:        tree,
+		pageManager: pm,
+	}, nil
+}
+
+// Index adds the trigrams of value for the given entry reference.
+// Values shorter than a trigram are not indexed, since no searchable
+// substring query can match them.
+func (ti *TrigramIndex) Index(value []byte, ref btree.EntryRef) error {
+	if len(value) == 0 {
+		return ErrEmptyValue
+	}
+
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+
+	for _, key := range valueTrigramKeys(value) {
+		if err := ti.tree.Insert(appendRefKey(key, ref), ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remove removes the trigrams of value for the given entry reference.
+func (ti *TrigramIndex) Remove(value []byte, ref btree.EntryRef) error {
+	if len(value) == 0 {
+		return ErrEmptyValue
+	}
+
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+
+	for _, key := range valueTrigramKeys(value) {
+		if err := ti.tree.Delete(appendRefKey(key, ref), ref); err != nil && err != btree.ErrKeyNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// Search returns the entries whose indexed values may match q. Every
+// trigram of every component is looked up and the results intersected,
+// smallest first. Returns ErrPatternTooShort if no component has a trigram.
+func (ti *TrigramIndex) Search(q SubstringQuery) ([]btree.EntryRef, error) {
+	ti.mu.RLock()
+	defer ti.mu.RUnlock()
+
+	var sets [][]btree.EntryRef
+	lookup := func(refs []btree.EntryRef, err error) error {
+		if err != nil {
+			return err
+		}
+		sets = append(sets, dedupeRefs(refs))
+		return nil
+	}
+
+	initial := FoldSubstring([]byte(q.Initial))
+	for i := 0; i+trigramSize <= len(initial) && i < maxTrigramPosition; i++ {
+		key := trigramKey(trigramKeyForward, initial[i:i+trigramSize], i)
+		if err := lookup(ti.tree.SearchPrefix(key)); err != nil {
+			return nil, err
+		}
+	}
+
+	final := FoldSubstring([]byte(q.Final))
+	for i := 0; i+trigramSize <= len(final); i++ {
+		pos := len(final) - trigramSize - i
+		if pos >= maxTrigramPosition {
+			continue
+		}
+		key := trigramKey(trigramKeyReverse, final[i:i+trigramSize], pos)
+		if err := lookup(ti.tree.SearchPrefix(key)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, component := range q.Any {
+		component := FoldSubstring([]byte(component))
+		for _, trigram := range uniqueTrigrams(component) {
+			prefix := append([]byte{trigramKeyForward}, trigram...)
+			if err := lookup(ti.tree.SearchPrefix(prefix)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(sets) == 0 {
+		return nil, ErrPatternTooShort
+	}
+
+	sort.Slice(sets, func(i, j int) bool { return len(sets[i]) < len(sets[j]) })
+	result := sets[0]
+	for _, set := range sets[1:] {
+		if len(result) == 0 {
+			break
+		}
+		result = intersectRefs(result, set)
+	}
+	return result, nil
+}
+
+// Root returns the root page ID of the underlying B+ tree.
+func (ti *TrigramIndex) Root() storage.PageID {
+	return ti.tree.Root()
+}
+
+// IsEmpty returns true if the index has no entries.
+func (ti *TrigramIndex) IsEmpty() bool {
+	return ti.tree.IsEmpty()
+}
+
+// Stats returns statistics about the underlying B+ tree.
+func (ti *TrigramIndex) Stats() (btree.TreeStats, error) {
+	return ti.tree.Stats()
+}
+
+// MatchesSubstring reports whether value matches q, ignoring case. It is
+// the recheck applied to TrigramIndex candidates.
+func MatchesSubstring(value []byte, q SubstringQuery) bool {
+	value = FoldSubstring(value)
+
+	initial := FoldSubstring([]byte(q.Initial))
+	if !bytes.HasPrefix(value, initial) {
+		return false
+	}
+	rest := value[len(initial):]
+
+	final := FoldSubstring([]byte(q.Final))
+	if len(rest) < len(final) || !bytes.HasSuffix(rest, final) {
+		return false
+	}
+	rest = rest[:len(rest)-len(final)]
+
+	for _, component := range q.Any {
+		component := FoldSubstring([]byte(component))
+		i := bytes.Index(rest, component)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(component):]
+	}
+	return true
+}
+
+// valueTrigramKeys returns the forward and reverse key prefixes of every
+// trigram of value.
+func valueTrigramKeys(value []byte) [][]byte {
+	value = FoldSubstring(value)
+	if len(value) < trigramSize {
+		return nil
+	}
+
+	n := len(value) - trigramSize + 1
+	keys := make([][]byte, 0, 2*n)
+	for i := 0; i < n; i++ {
+		trigram := value[i : i+trigramSize]
+		keys = append(keys,
+			trigramKey(trigramKeyForward, trigram, i),
+			trigramKey(trigramKeyReverse, trigram, n-1-i))
+	}
+	return keys
+}
+
+// trigramKey encodes a tagged, positioned trigram key prefix. Positions
+// beyond maxTrigramPosition are clamped; lookups never use clamped positions.
+func trigramKey(tag byte, trigram []byte, pos int) []byte {
+	if pos > maxTrigramPosition {
+		pos = maxTrigramPosition
+	}
+	key := make([]byte, 0, 1+trigramSize+2)
+	key = append(key, tag)
+	key = append(key, trigram...)
+	return binary.BigEndian.AppendUint16(key, uint16(pos))
+}
+
+// appendRefKey appends the identity of ref to a trigram key prefix.
+func appendRefKey(key []byte, ref btree.EntryRef) []byte {
+	key = binary.BigEndian.AppendUint64(key, uint64(ref.PageID))
+	key = binary.BigEndian.AppendUint16(key, ref.SlotID)
+	return append(key, ref.DN...)
+}
+
+// uniqueTrigrams returns the distinct trigrams of s.
+func uniqueTrigrams(s []byte) [][]byte {
+	var trigrams [][]byte
+	seen := make(map[string]struct{})
+	for i := 0; i+trigramSize <= len(s); i++ {
+		t := s[i : i+trigramSize]
+		if _, ok := seen[string(t)]; ok {
+			continue
+		}
+		seen[string(t)] = struct{}{}
+		trigrams = append(trigrams, t)
+	}
+	return trigrams
+}
+
+// dedupeRefs returns a copy of refs without duplicates. A value that
+// contains a trigram more than once yields one reference per position.
+func dedupeRefs(refs []btree.EntryRef) []btree.EntryRef {
+	seen := make(map[btree.EntryRef]struct{}, len(refs))
+	result := make([]btree.EntryRef, 0, len(refs))
+	for _, ref := range refs {
+		if _, ok := seen[ref]; ok {
+			continue
+		}
+		seen[ref] = struct{}{}
+		result = append(result, ref)
+	}
+	return result
+}
@@ -0,0 +1,224 @@
+// Package index provides indexing implementations for ObaDB.
+package index
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/btree"
+)
+
+// setupTrigramTest creates a PageManager and TrigramIndex for testing.
+func setupTrigramTest(tb testing.TB, initialPages int) (*TrigramIndex, func()) {
+	tb.Helper()
+
+	tmpDir, err := os.MkdirTemp("", "trigram_test")
+	if err != nil {
+		tb.Fatalf("Failed to create temp dir: %v", err)
+	}
+
+	opts := storage.DefaultOptions()
+	opts.InitialPages = initialPages
+	pm, err := storage.OpenPageManager(filepath.Join(tmpDir, "test.oba"), opts)
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		tb.Fatalf("Failed to create PageManager: %v", err)
+	}
+
+	ti, err := NewTrigramIndex(pm)
+	if err != nil {
+		pm.Close()
+		os.RemoveAll(tmpDir)
+		tb.Fatalf("NewTrigramIndex failed: %v", err)
+	}
+
+	return ti, func() {
+		pm.Close()
+		os.RemoveAll(tmpDir)
+	}
+}
+
+// trigramRefDNs returns the sorted DNs of refs.
+func trigramRefDNs(refs []btree.EntryRef) []string {
+	dns := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		dns = append(dns, ref.DN)
+	}
+	sort.Strings(dns)
+	return dns
+}
+
+// TestTrigramIndexSearch tests candidate lookup for anchored and unanchored queries.
+func TestTrigramIndexSearch(t *testing.T) {
+	ti, cleanup := setupTrigramTest(t, 100)
+	defer cleanup()
+
+	values := []string{"alice", "Malice", "slice", "icecream", "bob", "alicia"}
+	for i, v := range values {
+		ref := btree.EntryRef{PageID: storage.PageID(i + 1), DN: v}
+		if err := ti.Index([]byte(v), ref); err != nil {
+			t.Fatalf("Index(%q) failed: %v", v, err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query SubstringQuery
+		want  []string
+	}{
+		{"any", SubstringQuery{Any: []string{"lic"}}, []string{"Malice", "alice", "alicia", "slice"}},
+		{"initial", SubstringQuery{Initial: "ali"}, []string{"alice", "alicia"}},
+		{"final", SubstringQuery{Final: "ice"}, []string{"Malice", "alice", "slice"}},
+		{"initial and final", SubstringQuery{Initial: "mal", Final: "ice"}, []string{"Malice"}},
+		{"case insensitive", SubstringQuery{Initial: "ALI"}, []string{"alice", "alicia"}},
+		{"long any", SubstringQuery{Any: []string{"cecr"}}, []string{"icecream"}},
+		{"no match", SubstringQuery{Any: []string{"xyz"}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refs, err := ti.Search(tt.query)
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			got := trigramRefDNs(refs)
+			if len(got) == 0 {
+				got = nil
+			}
+			if !stringSliceEqual(got, tt.want) {
+				t.Errorf("Search(%+v) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestTrigramIndexPatternTooShort tests that queries without a trigram are rejected.
+func TestTrigramIndexPatternTooShort(t *testing.T) {
+	ti, cleanup := setupTrigramTest(t, 100)
+	defer cleanup()
+
+	_, err := ti.Search(SubstringQuery{Initial: "al", Any: []string{"c"}, Final: "e"})
+	if err != ErrPatternTooShort {
+		t.Errorf("expected ErrPatternTooShort, got %v", err)
+	}
+}
+
+// TestTrigramIndexRemove tests that removed values are no longer found.
+func TestTrigramIndexRemove(t *testing.T) {
+	ti, cleanup := setupTrigramTest(t, 100)
+	defer cleanup()
+
+	alice := btree.EntryRef{PageID: 1, DN: "alice"}
+	malice := btree.EntryRef{PageID: 2, DN: "malice"}
+	ti.Index([]byte("alice"), alice)
+	ti.Index([]byte("malice"), malice)
+
+	if err := ti.Remove([]byte("alice"), alice); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+
+	refs, err := ti.Search(SubstringQuery{Any: []string{"lic"}})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if got := trigramRefDNs(refs); !stringSliceEqual(got, []string{"malice"}) {
+		t.Errorf("after Remove got %v, want [malice]", got)
+	}
+
+	if err := ti.Index(nil, alice); err != ErrEmptyValue {
+		t.Errorf("expected ErrEmptyValue, got %v", err)
+	}
+}
+
+// TestMatchesSubstring tests the candidate recheck.
+func TestMatchesSubstring(t *testing.T) {
+	tests := []struct {
+		value string
+		query SubstringQuery
+		want  bool
+	}{
+		{"alice", SubstringQuery{Any: []string{"lic"}}, true},
+		{"alice", SubstringQuery{Initial: "ALI"}, true},
+		{"alice", SubstringQuery{Final: "ice"}, true},
+		{"alice", SubstringQuery{Initial: "al", Final: "ice"}, true},
+		{"alice", SubstringQuery{Initial: "ali", Final: "ice"}, false},
+		{"abcabc", SubstringQuery{Any: []string{"bc", "ab"}}, true},
+		{"abcab", SubstringQuery{Any: []string{"ca", "bc"}}, false},
+		{"alice", SubstringQuery{Initial: "lic"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchesSubstring([]byte(tt.value), tt.query); got != tt.want {
+			t.Errorf("MatchesSubstring(%q, %+v) = %v, want %v", tt.value, tt.query, got, tt.want)
+		}
+	}
+}
+
+// benchmarkTrigramValues returns n values of which every twentieth (5%)
+// contains the word "needle".
+func benchmarkTrigramValues(n int) [][]byte {
+	values := make([][]byte, n)
+	for i := range values {
+		if i%20 == 0 {
+			values[i] = []byte(fmt.Sprintf("user%06d needle", i))
+		} else {
+			values[i] = []byte(fmt.Sprintf("user%06d person", i))
+		}
+	}
+	return values
+}
+
+// BenchmarkTrigramIndexSearch compares (cn=*needle*) through the trigram
+// index against a full scan of 100k values at 5% selectivity. The index is
+// built once, outside the timed sub-benchmarks.
+func BenchmarkTrigramIndexSearch(b *testing.B) {
+	const n = 100000
+	values := benchmarkTrigramValues(n)
+	query := SubstringQuery{Any: []string{"needle"}}
+	want := n / 20
+
+	ti, cleanup := setupTrigramTest(b, 1000)
+	defer cleanup()
+
+	for i, v := range values {
+		if err := ti.Index(v, btree.EntryRef{PageID: storage.PageID(i + 1)}); err != nil {
+			b.Fatalf("Index failed: %v", err)
+		}
+	}
+
+	b.Run("FullScan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			matches := 0
+			for _, v := range values {
+				if MatchesSubstring(v, query) {
+					matches++
+				}
+			}
+			if matches != want {
+				b.Fatalf("full scan found %d, want %d", matches, want)
+			}
+		}
+	})
+
+	b.Run("TrigramIndex", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			refs, err := ti.Search(query)
+			if err != nil {
+				b.Fatalf("Search failed: %v", err)
+			}
+			matches := 0
+			for _, ref := range refs {
+				if MatchesSubstring(values[ref.PageID-1], query) {
+					matches++
+				}
+			}
+			if matches != want {
+				b.Fatalf("index found %d, want %d", matches, want)
+			}
+		}
+	})
+}
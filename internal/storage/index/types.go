@@ -90,6 +90,25 @@ func (e *Entry) GetAttribute(name string) [][]byte {
 	return nil
 }
 
+// GetAttributeWithOptions returns the values of the given attribute
+// together with those of its variants with attribute options, so that
+// "description" also returns the values of "description;lang-de".
+// Indexes of an attribute cover all of its variants.
+func (e *Entry) GetAttributeWithOptions(name string) [][]byte {
+	if e.Attributes == nil {
+		return nil
+	}
+	name = strings.ToLower(name)
+	var values [][]byte
+	for k, v := range e.Attributes {
+		k = strings.ToLower(k)
+		if k == name || (strings.HasPrefix(k, name) && k[len(name)] == ';') {
+			values = append(values, v...)
+		}
+	}
+	return values
+}
+
 // HasAttribute returns true if the entry has the given attribute.
 func (e *Entry) HasAttribute(name string) bool {
 	if e.Attributes == nil {
@@ -146,6 +165,7 @@ func DefaultIndexedAttributes() []string {
 		"sn",
 		"mail",
 		"memberof",
+		"entryuuid",
 	}
 }
 
@@ -0,0 +1,239 @@
+package storage
+
+import "strings"
+
+// EntryRef is a reference to an entry stored in a data page, as kept by
+// the attribute indexes.
+type EntryRef struct {
+	PageID PageID // Page containing the entry
+	SlotID uint16 // Slot index within the page
+	DN     string // Distinguished name of the entry
+}
+
+// CompareEntryRefs orders references by storage location, then DN. It
+// returns -1, 0 or 1.
+func CompareEntryRefs(a, b EntryRef) int {
+	switch {
+	case a.PageID < b.PageID:
+		return -1
+	case a.PageID > b.PageID:
+		return 1
+	case a.SlotID < b.SlotID:
+		return -1
+	case a.SlotID > b.SlotID:
+		return 1
+	default:
+		return strings.Compare(a.DN, b.DN)
+	}
+}
+
+// RefIterator provides iteration over entry references. The combinators
+// below require references in CompareEntryRefs order and produce them in
+// the same order.
+type RefIterator interface {
+	// Next advances to the next reference and returns true if successful.
+	Next() bool
+
+	// Ref returns the current reference.
+	Ref() EntryRef
+
+	// Error returns any error encountered during iteration.
+	Error() error
+
+	// Close releases resources held by the iterator.
+	Close()
+}
+
+// SliceRefIterator returns an iterator over refs, which must be sorted.
+func SliceRefIterator(refs []EntryRef) RefIterator {
+	return &sliceRefIterator{refs: refs, pos: -1}
+}
+
+type sliceRefIterator struct {
+	refs []EntryRef
+	pos  int
+}
+
+func (it *sliceRefIterator) Next() bool {
+	if it.pos+1 >= len(it.refs) {
+		it.pos = len(it.refs)
+		return false
+	}
+	it.pos++
+	return true
+}
+
+func (it *sliceRefIterator) Ref() EntryRef { return it.refs[it.pos] }
+func (it *sliceRefIterator) Error() error  { return nil }
+func (it *sliceRefIterator) Close()        {}
+
+// MergeIterator returns the references of all iters in one sorted
+// sequence, keeping duplicates. It suits subtree scans that read the same
+// range from several sources.
+func MergeIterator(iters ...RefIterator) RefIterator {
+	return &mergeIterator{heads: newRefHeads(iters)}
+}
+
+// UnionIterator returns the references present in any of iters, each once.
+// It answers OR filters from one index per child.
+func UnionIterator(iters ...RefIterator) RefIterator {
+	return &mergeIterator{heads: newRefHeads(iters), distinct: true}
+}
+
+// IntersectionIterator returns the references present in every one of
+// iters. It answers AND filters from one index per child. With no
+// iterators the result is empty.
+func IntersectionIterator(iters ...RefIterator) RefIterator {
+	return &intersectionIterator{heads: newRefHeads(iters)}
+}
+
+// refHeads holds the input iterators of a combinator together with their
+// current references.
+type refHeads struct {
+	iters   []RefIterator
+	refs    []EntryRef
+	valid   []bool
+	started bool
+	err     error
+}
+
+func newRefHeads(iters []RefIterator) *refHeads {
+	return &refHeads{
+		iters: iters,
+		refs:  make([]EntryRef, len(iters)),
+		valid: make([]bool, len(iters)),
+	}
+}
+
+// advance moves input i to its next reference. It returns false when the
+// input is exhausted or failed.
+func (h *refHeads) advance(i int) bool {
+	h.valid[i] = h.iters[i].Next()
+	if h.valid[i] {
+		h.refs[i] = h.iters[i].Ref()
+		return true
+	}
+	if err := h.iters[i].Error(); err != nil && h.err == nil {
+		h.err = err
+	}
+	return false
+}
+
+// start positions every input on its first reference.
+func (h *refHeads) start() {
+	h.started = true
+	for i := range h.iters {
+		h.advance(i)
+	}
+}
+
+func (h *refHeads) close() {
+	for _, it := range h.iters {
+		it.Close()
+	}
+}
+
+// mergeIterator merges sorted inputs, dropping equal references when
+// distinct is set.
+type mergeIterator struct {
+	heads    *refHeads
+	distinct bool
+	current  EntryRef
+	emitted  bool
+}
+
+func (it *mergeIterator) Next() bool {
+	h := it.heads
+	if !h.started {
+		h.start()
+	}
+	for h.err == nil {
+		smallest := -1
+		for i := range h.iters {
+			if h.valid[i] && (smallest < 0 || CompareEntryRefs(h.refs[i], h.refs[smallest]) < 0) {
+				smallest = i
+			}
+		}
+		if smallest < 0 {
+			return false
+		}
+
+		ref := h.refs[smallest]
+		h.advance(smallest)
+		if it.distinct && it.emitted && CompareEntryRefs(ref, it.current) == 0 {
+			continue
+		}
+		it.current = ref
+		it.emitted = true
+		return true
+	}
+	return false
+}
+
+func (it *mergeIterator) Ref() EntryRef { return it.current }
+func (it *mergeIterator) Error() error  { return it.heads.err }
+func (it *mergeIterator) Close()        { it.heads.close() }
+
+// intersectionIterator leapfrogs sorted inputs: every input is advanced to
+// the largest current reference until all of them agree.
+type intersectionIterator struct {
+	heads   *refHeads
+	current EntryRef
+	done    bool
+}
+
+func (it *intersectionIterator) Next() bool {
+	h := it.heads
+	if it.done || len(h.iters) == 0 {
+		return false
+	}
+	if !h.started {
+		h.start()
+	} else if !h.advance(0) {
+		it.done = true
+		return false
+	}
+
+	for {
+		for i := range h.iters {
+			if !h.valid[i] {
+				it.done = true
+				return false
+			}
+		}
+
+		largest := 0
+		for i := 1; i < len(h.iters); i++ {
+			if CompareEntryRefs(h.refs[i], h.refs[largest]) > 0 {
+				largest = i
+			}
+		}
+
+		agreed := true
+		for i := range h.iters {
+			for h.valid[i] && CompareEntryRefs(h.refs[i], h.refs[largest]) < 0 {
+				h.advance(i)
+			}
+			if !h.valid[i] {
+				it.done = true
+				return false
+			}
+			if CompareEntryRefs(h.refs[i], h.refs[largest]) != 0 {
+				agreed = false
+			}
+		}
+		if agreed {
+			// Inputs may repeat a reference; consume the repeats
+			it.current = h.refs[0]
+			for i := 1; i < len(h.iters); i++ {
+				for h.advance(i) && CompareEntryRefs(h.refs[i], it.current) == 0 {
+				}
+			}
+			return true
+		}
+	}
+}
+
+func (it *intersectionIterator) Ref() EntryRef { return it.current }
+func (it *intersectionIterator) Error() error  { return it.heads.err }
+func (it *intersectionIterator) Close()        { it.heads.close() }
@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func refsOf(pages ...PageID) []EntryRef {
+	refs := make([]EntryRef, len(pages))
+	for i, p := range pages {
+		refs[i] = EntryRef{PageID: p, DN: fmt.Sprintf("cn=%d", p)}
+	}
+	return refs
+}
+
+func drain(t *testing.T, it RefIterator) []PageID {
+	t.Helper()
+	defer it.Close()
+	var pages []PageID
+	for it.Next() {
+		pages = append(pages, it.Ref().PageID)
+	}
+	if err := it.Error(); err != nil {
+		t.Fatalf("Error() = %v", err)
+	}
+	return pages
+}
+
+func samePages(a, b []PageID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// TestIntersectionIterator tests that the intersection of two sets of ten
+// references with five in common yields those five, in order.
+func TestIntersectionIterator(t *testing.T) {
+	a := SliceRefIterator(refsOf(1, 2, 3, 4, 5, 6, 7, 8, 9, 10))
+	b := SliceRefIterator(refsOf(2, 4, 6, 8, 10, 11, 12, 13, 14, 15))
+
+	got := drain(t, IntersectionIterator(a, b))
+	if want := []PageID{2, 4, 6, 8, 10}; !samePages(got, want) {
+		t.Errorf("IntersectionIterator() = %v, want %v", got, want)
+	}
+}
+
+// TestIntersectionIteratorEdgeCases tests intersections of three inputs,
+// repeated references, empty inputs and no inputs.
+func TestIntersectionIteratorEdgeCases(t *testing.T) {
+	tests := []struct {
+		name  string
+		iters []RefIterator
+		want  []PageID
+	}{
+		{
+			name: "three inputs",
+			iters: []RefIterator{
+				SliceRefIterator(refsOf(1, 3, 5, 7, 9)),
+				SliceRefIterator(refsOf(3, 4, 5, 9)),
+				SliceRefIterator(refsOf(2, 3, 9, 10)),
+			},
+			want: []PageID{3, 9},
+		},
+		{
+			name: "repeated references",
+			iters: []RefIterator{
+				SliceRefIterator(refsOf(1, 2, 2, 3)),
+				SliceRefIterator(refsOf(2, 2, 3)),
+			},
+			want: []PageID{2, 3},
+		},
+		{
+			name: "empty input",
+			iters: []RefIterator{
+				SliceRefIterator(refsOf(1, 2)),
+				SliceRefIterator(nil),
+			},
+		},
+		{name: "no inputs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := drain(t, IntersectionIterator(tt.iters...)); !samePages(got, tt.want) {
+				t.Errorf("IntersectionIterator() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestUnionIterator tests that the union yields every reference once, in
+// order.
+func TestUnionIterator(t *testing.T) {
+	got := drain(t, UnionIterator(
+		SliceRefIterator(refsOf(1, 4, 6)),
+		SliceRefIterator(refsOf(2, 4, 7)),
+		SliceRefIterator(refsOf(4, 6, 8)),
+	))
+	if want := []PageID{1, 2, 4, 6, 7, 8}; !samePages(got, want) {
+		t.Errorf("UnionIterator() = %v, want %v", got, want)
+	}
+}
+
+// TestMergeIterator tests that the merge yields every reference of every
+// input, in order.
+func TestMergeIterator(t *testing.T) {
+	got := drain(t, MergeIterator(
+		SliceRefIterator(refsOf(1, 4, 6)),
+		SliceRefIterator(refsOf(2, 4)),
+	))
+	if want := []PageID{1, 2, 4, 4, 6}; !samePages(got, want) {
+		t.Errorf("MergeIterator() = %v, want %v", got, want)
+	}
+}
+
+type failingRefIterator struct {
+	err    error
+	closed bool
+}
+
+func (it *failingRefIterator) Next() bool    { return false }
+func (it *failingRefIterator) Ref() EntryRef { return EntryRef{} }
+func (it *failingRefIterator) Error() error  { return it.err }
+func (it *failingRefIterator) Close()        { it.closed = true }
+
+// TestRefIteratorError tests that the combinators report the error of an
+// input and close their inputs.
+func TestRefIteratorError(t *testing.T) {
+	errRead := errors.New("read failed")
+	combinators := map[string]func(...RefIterator) RefIterator{
+		"merge":        MergeIterator,
+		"union":        UnionIterator,
+		"intersection": IntersectionIterator,
+	}
+
+	for name, combine := range combinators {
+		t.Run(name, func(t *testing.T) {
+			failing := &failingRefIterator{err: errRead}
+			it := combine(SliceRefIterator(refsOf(1, 2)), failing)
+			for it.Next() {
+			}
+			if !errors.Is(it.Error(), errRead) {
+				t.Errorf("Error() = %v, want %v", it.Error(), errRead)
+			}
+			it.Close()
+			if !failing.closed {
+				t.Error("expected inputs to be closed")
+			}
+		})
+	}
+}
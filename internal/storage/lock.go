@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"os"
+)
+
+// FileLock is an advisory lock on a file, shared by every process that
+// opens the file. It may be held shared by any number of holders, or
+// exclusively by one.
+type FileLock struct {
+	file *os.File
+}
+
+// OpenFileLock opens the lock file at path, creating it if needed. A lock
+// file that cannot be created, as in a read-only directory, is opened for
+// reading, which is enough to lock it.
+func OpenFileLock(path string) (*FileLock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		if file, err = os.Open(path); err != nil {
+			return nil, err
+		}
+	}
+	return &FileLock{file: file}, nil
+}
+
+// Lock acquires the lock exclusively, waiting for the other holders to
+// release it.
+func (l *FileLock) Lock() error {
+	return lockFile(l.file, true)
+}
+
+// RLock acquires the lock shared, waiting for an exclusive holder to
+// release it.
+func (l *FileLock) RLock() error {
+	return lockFile(l.file, false)
+}
+
+// Unlock releases the lock.
+func (l *FileLock) Unlock() error {
+	return unlockFile(l.file)
+}
+
+// Close closes the lock file, releasing the lock if it is held.
+func (l *FileLock) Close() error {
+	return l.file.Close()
+}
@@ -0,0 +1,19 @@
+//go:build !unix && !windows
+
+// Package storage provides the core storage engine components for ObaDB.
+package storage
+
+import (
+	"os"
+)
+
+// lockFile does nothing on platforms without advisory file locks, where a
+// database must only be opened by one process at a time.
+func lockFile(file *os.File, exclusive bool) error {
+	return nil
+}
+
+// unlockFile does nothing on platforms without advisory file locks.
+func unlockFile(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,28 @@
+//go:build unix || darwin || linux
+
+// Package storage provides the core storage engine components for ObaDB.
+package storage
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile locks file with flock, shared or exclusive.
+func lockFile(file *os.File, exclusive bool) error {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	for {
+		err := syscall.Flock(int(file.Fd()), how)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
+
+// unlockFile releases the flock of file.
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
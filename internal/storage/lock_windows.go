@@ -0,0 +1,55 @@
+//go:build windows
+
+// Package storage provides the core storage engine components for ObaDB.
+package storage
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	procLockFileEx   = modkernel32.NewProc("LockFileEx")
+	procUnlockFileEx = modkernel32.NewProc("UnlockFileEx")
+)
+
+const lockfileExclusiveLock = 0x02
+
+// lockFile locks the first byte of file with LockFileEx, shared or
+// exclusive.
+func lockFile(file *os.File, exclusive bool) error {
+	var flags uint32
+	if exclusive {
+		flags = lockfileExclusiveLock
+	}
+	var overlapped syscall.Overlapped
+	ret, _, err := procLockFileEx.Call(
+		file.Fd(),
+		uintptr(flags),
+		0,
+		1,
+		0,
+		uintptr(unsafe.Pointer(&overlapped)),
+	)
+	if ret == 0 {
+		return err
+	}
+	return nil
+}
+
+// unlockFile releases the lock of file.
+func unlockFile(file *os.File) error {
+	var overlapped syscall.Overlapped
+	ret, _, err := procUnlockFileEx.Call(
+		file.Fd(),
+		0,
+		1,
+		0,
+		uintptr(unsafe.Pointer(&overlapped)),
+	)
+	if ret == 0 {
+		return err
+	}
+	return nil
+}
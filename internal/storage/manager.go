@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"sync"
+	"sync/atomic"
 )
 
 // Default options for PageManager.
@@ -38,6 +40,9 @@ type Options struct {
 	CreateIfNew  bool // Create file if it doesn't exist
 	ReadOnly     bool // Open in read-only mode
 	SyncOnWrite  bool // Sync to disk after each write
+
+	// FileSystem opens the file (nil = the operating system's)
+	FileSystem FileSystem
 }
 
 // DefaultOptions returns the default PageManager options.
@@ -53,7 +58,7 @@ func DefaultOptions() Options {
 
 // PageManager handles page allocation, deallocation, and I/O operations.
 type PageManager struct {
-	file        *os.File
+	file        File
 	header      *FileHeader
 	pageSize    int
 	totalPages  uint64
@@ -63,6 +68,22 @@ type PageManager struct {
 	readOnly    bool
 	syncOnWrite bool
 	closed      bool
+
+	// pageFormat is the format the pages of the file are read and written
+	// in, as recorded in its header.
+	pageFormat uint32
+
+	// pageReads and pageWrites count the page I/O since the file was opened.
+	pageReads  atomic.Uint64
+	pageWrites atomic.Uint64
+
+	// verified holds the pages whose checksum was verified or which were
+	// written since the file was opened; they are not verified again.
+	// quarantined holds the pages that failed verification; they can no
+	// longer be read, written or freed.
+	checkMu     sync.RWMutex
+	verified    map[PageID]bool
+	quarantined map[PageID]*PageCorruptedError
 }
 
 // OpenPageManager opens or creates a page manager for the given file path.
@@ -80,6 +101,8 @@ func OpenPageManager(path string, opts Options) (*PageManager, error) {
 		path:        path,
 		readOnly:    opts.ReadOnly,
 		syncOnWrite: opts.SyncOnWrite,
+		verified:    make(map[PageID]bool),
+		quarantined: make(map[PageID]*PageCorruptedError),
 	}
 
 	// Check if file exists
@@ -101,7 +124,7 @@ func OpenPageManager(path string, opts Options) (*PageManager, error) {
 		}
 	}
 
-	pm.file, err = os.OpenFile(path, flags, 0644)
+	pm.file, err = fileSystemOrOS(opts.FileSystem).OpenFile(path, flags, 0644)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
@@ -140,11 +163,43 @@ func (pm *PageManager) loadExisting() error {
 	pm.totalPages = pm.header.TotalPages
 	pm.pageSize = int(pm.header.PageSize)
 
+	versions, err := pm.header.ComponentVersions()
+	if err != nil {
+		return fmt.Errorf("invalid header: %w", err)
+	}
+	pm.pageFormat = versions[PageFormatComponent]
+	if pm.pageFormat > PageFormatVersion {
+		return fmt.Errorf("%w: version %d, supported up to %d", ErrPageFormatTooNew, pm.pageFormat, PageFormatVersion)
+	}
+
+	// The header is only saved on Sync and Close: after a crash the file
+	// has pages past its count
+	info, err := pm.file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+	if pages := uint64(info.Size()) / uint64(pm.pageSize); pages > pm.totalPages {
+		pm.totalPages = pages
+	}
+
 	// Load free list
 	if err := pm.loadFreeList(); err != nil {
 		return fmt.Errorf("failed to load free list: %w", err)
 	}
 
+	// The free list on disk goes stale as soon as a page is allocated.
+	// Drop it until Close saves the current one, so that after a crash
+	// its pages are leaked rather than handed out twice.
+	if !pm.readOnly && pm.header.FreeListHead != 0 {
+		pm.header.FreeListHead = 0
+		if err := pm.saveHeaderLocked(); err != nil {
+			return fmt.Errorf("failed to save header: %w", err)
+		}
+		if err := pm.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync file: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -158,11 +213,14 @@ func (pm *PageManager) loadFreeList() error {
 
 	pm.freeList.SetHead(pm.header.FreeListHead)
 
-	// Read all free list pages
+	// Read all free list pages. A chain that loops is cut where it does,
+	// leaking the pages it lost rather than hanging; Verify reports it.
 	var pages []*Page
+	visited := make(map[PageID]bool)
 	currentPageID := pm.header.FreeListHead
 
-	for currentPageID != 0 {
+	for currentPageID != 0 && !visited[currentPageID] {
+		visited[currentPageID] = true
 		page, err := pm.readPageInternal(currentPageID)
 		if err != nil {
 			return err
@@ -185,6 +243,10 @@ func (pm *PageManager) initializeNew(initialPages int) error {
 	pm.header.PageSize = uint32(pm.pageSize)
 	pm.header.TotalPages = uint64(initialPages)
 	pm.totalPages = uint64(initialPages)
+	pm.pageFormat = PageFormatVersion
+	if err := pm.header.SetComponentVersion(PageFormatComponent, PageFormatVersion); err != nil {
+		return err
+	}
 
 	// Write header
 	headerBuf, err := pm.header.Serialize()
@@ -427,6 +489,10 @@ func (pm *PageManager) FreePage(id PageID) error {
 		return ErrPageAlreadyFree
 	}
 
+	if err := pm.quarantineError(id); err != nil {
+		return err
+	}
+
 	// Clear the page
 	page := NewPage(id, PageTypeFree)
 	if err := pm.writePageInternal(page); err != nil {
@@ -461,8 +527,13 @@ func (pm *PageManager) readPageInternal(id PageID) (*Page, error) {
 		return nil, ErrPageOutOfRange
 	}
 
+	if err := pm.quarantineError(id); err != nil {
+		return nil, err
+	}
+
 	offset := int64(id) * int64(pm.pageSize)
 	buf := make([]byte, pm.pageSize)
+	pm.pageReads.Add(1)
 
 	n, err := pm.file.ReadAt(buf, offset)
 	if err != nil && err != io.EOF {
@@ -474,13 +545,82 @@ func (pm *PageManager) readPageInternal(id PageID) (*Page, error) {
 	}
 
 	page := &Page{}
-	if err := page.Deserialize(buf); err != nil {
+	if err := page.deserialize(buf, pm.pageFormat); err != nil {
 		return nil, fmt.Errorf("failed to deserialize page %d: %w", id, err)
 	}
 
+	if err := pm.verifyPage(id, page, buf); err != nil {
+		return nil, err
+	}
+
 	return page, nil
 }
 
+// verifyPage verifies the checksum of a page read from disk, unless it was
+// verified or written before. A page that fails is quarantined. Pages that
+// were never written, which are all zero, pass.
+func (pm *PageManager) verifyPage(id PageID, page *Page, buf []byte) error {
+	pm.checkMu.RLock()
+	verified := pm.verified[id]
+	pm.checkMu.RUnlock()
+	if verified || isZeroPage(buf) {
+		return nil
+	}
+
+	err := page.verifyChecksum(pm.pageFormat)
+
+	pm.checkMu.Lock()
+	defer pm.checkMu.Unlock()
+	if err != nil {
+		corrupted := err.(*PageCorruptedError)
+		corrupted.PageID = id
+		pm.quarantined[id] = corrupted
+		return corrupted
+	}
+	pm.verified[id] = true
+	return nil
+}
+
+// quarantineError returns the error that quarantined the page, or nil.
+func (pm *PageManager) quarantineError(id PageID) error {
+	pm.checkMu.RLock()
+	defer pm.checkMu.RUnlock()
+	if err, ok := pm.quarantined[id]; ok {
+		return err
+	}
+	return nil
+}
+
+// corruptPageCount returns the number of quarantined pages.
+func (pm *PageManager) corruptPageCount() uint64 {
+	pm.checkMu.RLock()
+	defer pm.checkMu.RUnlock()
+	return uint64(len(pm.quarantined))
+}
+
+// releaseQuarantine lifts the quarantine of a page about to be
+// overwritten.
+func (pm *PageManager) releaseQuarantine(id PageID) {
+	pm.checkMu.Lock()
+	defer pm.checkMu.Unlock()
+	delete(pm.quarantined, id)
+}
+
+// QuarantinedPages returns the pages that failed checksum verification,
+// in ascending order. They stay quarantined until the file is repaired or
+// restored from a backup and reopened.
+func (pm *PageManager) QuarantinedPages() []PageID {
+	pm.checkMu.RLock()
+	defer pm.checkMu.RUnlock()
+
+	ids := make([]PageID, 0, len(pm.quarantined))
+	for id := range pm.quarantined {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
 // ReadPages reads multiple pages from disk in a single operation.
 // This is more efficient than calling ReadPage multiple times.
 func (pm *PageManager) ReadPages(ids []PageID) ([]*Page, error) {
@@ -539,16 +679,25 @@ func (pm *PageManager) writePageInternal(page *Page) error {
 		return ErrPageOutOfRange
 	}
 
+	if err := pm.quarantineError(page.Header.PageID); err != nil {
+		return err
+	}
+
 	offset := int64(page.Header.PageID) * int64(pm.pageSize)
 
-	buf, err := page.Serialize()
-	if err != nil {
+	buf := make([]byte, PageSize)
+	if err := page.serializeTo(buf, pm.pageFormat); err != nil {
 		return fmt.Errorf("failed to serialize page: %w", err)
 	}
 
 	if _, err := pm.file.WriteAt(buf, offset); err != nil {
 		return fmt.Errorf("failed to write page %d: %w", page.Header.PageID, err)
 	}
+	pm.pageWrites.Add(1)
+
+	pm.checkMu.Lock()
+	pm.verified[page.Header.PageID] = true
+	pm.checkMu.Unlock()
 
 	if pm.syncOnWrite {
 		if err := pm.file.Sync(); err != nil {
@@ -596,6 +745,38 @@ func (pm *PageManager) FreePageCount() uint64 {
 	return pm.freeList.Count()
 }
 
+// CompactFreeList compacts the free list so that free pages are allocated
+// in ascending order. See FreeList.Compact.
+func (pm *PageManager) CompactFreeList() (*CompactionStats, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if pm.closed {
+		return nil, ErrFileClosed
+	}
+
+	if pm.readOnly {
+		return nil, errors.New("cannot compact free list in read-only mode")
+	}
+
+	return pm.freeList.Compact()
+}
+
+// FreeListFragmentation returns the fragmentation of the free list. See
+// FreeList.Fragmentation.
+func (pm *PageManager) FreeListFragmentation() float64 {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.freeList.Fragmentation()
+}
+
+// isFree reports whether the page is on the free list.
+func (pm *PageManager) isFree(id PageID) bool {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.freeList.Contains(id)
+}
+
 // PageSize returns the page size in bytes.
 func (pm *PageManager) PageSize() int {
 	return pm.pageSize
@@ -636,6 +817,100 @@ func (pm *PageManager) UpdateHeader(header FileHeader) error {
 	return pm.saveHeaderLocked()
 }
 
+// ComponentVersions returns the format versions of the storage components
+// recorded in the file header.
+func (pm *PageManager) ComponentVersions() (map[string]uint32, error) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	if pm.closed {
+		return nil, ErrFileClosed
+	}
+	return pm.header.ComponentVersions()
+}
+
+// SetComponentVersion records the format version of a storage component in
+// the file header and saves it to disk.
+func (pm *PageManager) SetComponentVersion(component string, version uint32) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if pm.closed {
+		return ErrFileClosed
+	}
+	if pm.readOnly {
+		return errors.New("cannot update header in read-only mode")
+	}
+
+	if err := pm.header.SetComponentVersion(component, version); err != nil {
+		return err
+	}
+	return pm.saveHeaderLocked()
+}
+
+// PageFormat returns the format the pages of the file are in.
+func (pm *PageManager) PageFormat() uint32 {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.pageFormat
+}
+
+// UpgradePageFormat rewrites every page of the file in PageFormatVersion
+// and records the format in the file header. A page that fails the
+// checksum of its current format stops the upgrade with a
+// *PageCorruptedError, as rewriting it would hide the damage.
+func (pm *PageManager) UpgradePageFormat() error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if pm.closed {
+		return ErrFileClosed
+	}
+	if pm.readOnly {
+		return errors.New("cannot upgrade page format in read-only mode")
+	}
+	if pm.pageFormat == PageFormatVersion {
+		return nil
+	}
+
+	buf := make([]byte, pm.pageSize)
+	page := &Page{}
+	for id := PageID(1); uint64(id) < pm.totalPages; id++ {
+		offset := int64(id) * int64(pm.pageSize)
+		if _, err := pm.file.ReadAt(buf, offset); err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read page %d: %w", id, err)
+		}
+		if isZeroPage(buf) {
+			continue
+		}
+
+		if err := page.deserialize(buf, pm.pageFormat); err != nil {
+			return fmt.Errorf("failed to deserialize page %d: %w", id, err)
+		}
+		if err := page.verifyChecksum(pm.pageFormat); err != nil {
+			corrupted := err.(*PageCorruptedError)
+			corrupted.PageID = id
+			return corrupted
+		}
+		if err := page.serializeTo(buf, PageFormatVersion); err != nil {
+			return fmt.Errorf("failed to serialize page %d: %w", id, err)
+		}
+		if _, err := pm.file.WriteAt(buf, offset); err != nil {
+			return fmt.Errorf("failed to write page %d: %w", id, err)
+		}
+		pm.pageWrites.Add(1)
+	}
+
+	if err := pm.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync file: %w", err)
+	}
+	pm.pageFormat = PageFormatVersion
+	if err := pm.header.SetComponentVersion(PageFormatComponent, PageFormatVersion); err != nil {
+		return err
+	}
+	return pm.saveHeaderLocked()
+}
+
 // Stats returns statistics about the page manager.
 type Stats struct {
 	TotalPages    uint64
@@ -643,6 +918,9 @@ type Stats struct {
 	UsedPages     uint64
 	PageSize      int
 	FileSizeBytes int64
+	PageReads     uint64
+	PageWrites    uint64
+	CorruptPages  uint64 // pages quarantined after failing verification
 }
 
 // Stats returns current statistics.
@@ -657,5 +935,8 @@ func (pm *PageManager) Stats() Stats {
 		UsedPages:     pm.totalPages - freeCount - 1, // -1 for header
 		PageSize:      pm.pageSize,
 		FileSizeBytes: int64(pm.totalPages) * int64(pm.pageSize),
+		PageReads:     pm.pageReads.Load(),
+		PageWrites:    pm.pageWrites.Load(),
+		CorruptPages:  pm.corruptPageCount(),
 	}
 }
@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
 	"sync"
@@ -194,6 +196,61 @@ func TestFreeListConcurrency(t *testing.T) {
 	}
 }
 
+func TestFreeListCompact(t *testing.T) {
+	fl := NewFreeList()
+	for _, id := range []PageID{9, 3, 4, 0, 8, 3, 1, 10} {
+		fl.Push(id)
+	}
+
+	stats, err := fl.Compact()
+	if err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	if stats.FreePages != 6 || stats.Extents != 3 || stats.DuplicatesRemoved != 2 {
+		t.Errorf("Compact() stats = %+v, want 6 pages, 3 extents, 2 duplicates", stats)
+	}
+	if stats.FragmentationBefore == 0 || stats.FragmentationAfter != 0 {
+		t.Errorf("fragmentation %v -> %v, want > 0 -> 0", stats.FragmentationBefore, stats.FragmentationAfter)
+	}
+	if fl.Count() != 6 {
+		t.Errorf("Count() = %d, want 6", fl.Count())
+	}
+
+	// Pages are handed out in ascending order
+	for _, want := range []PageID{1, 3, 4, 8, 9, 10} {
+		if id, ok := fl.Pop(); !ok || id != want {
+			t.Fatalf("Pop() = %d, %v, want %d", id, ok, want)
+		}
+	}
+}
+
+func TestFreeListFragmentation(t *testing.T) {
+	tests := []struct {
+		name  string
+		pages []PageID // in push order
+		want  float64
+	}{
+		{"empty", nil, 0},
+		{"single page", []PageID{5}, 0},
+		{"pop order ascending", []PageID{4, 3, 2, 1}, 0},
+		{"pop order descending", []PageID{1, 2, 3, 4}, 1},
+		{"separate extents", []PageID{10, 7, 4, 1}, 0},
+		{"one avoidable break of two", []PageID{2, 1, 3}, 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fl := NewFreeList()
+			for _, id := range tt.pages {
+				fl.Push(id)
+			}
+			if got := fl.Fragmentation(); got != tt.want {
+				t.Errorf("Fragmentation() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 // =============================================================================
 // PageManager Tests
 // =============================================================================
@@ -406,6 +463,291 @@ func TestPageManagerFreePageReuse(t *testing.T) {
 	}
 }
 
+func TestPageManagerCompactFreeList(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.oba")
+	pm, err := OpenPageManager(path, DefaultOptions())
+	if err != nil {
+		t.Fatalf("OpenPageManager failed: %v", err)
+	}
+	defer pm.Close()
+
+	// Interleave 1000 allocations with frees that scatter the freed pages
+	// across the file, so that consecutive frees are never neighbours
+	var used []PageID
+	for i := 0; i < 1000; i++ {
+		id, err := pm.AllocatePage(PageTypeData)
+		if err != nil {
+			t.Fatalf("AllocatePage failed: %v", err)
+		}
+		used = append(used, id)
+
+		if i%3 == 2 {
+			victim := (i * 7919) % len(used)
+			if err := pm.FreePage(used[victim]); err != nil {
+				t.Fatalf("FreePage failed: %v", err)
+			}
+			used = append(used[:victim], used[victim+1:]...)
+		}
+	}
+	for i := 0; i < len(used); i += 2 {
+		if err := pm.FreePage(used[i]); err != nil {
+			t.Fatalf("FreePage failed: %v", err)
+		}
+	}
+
+	before := pm.FreeListFragmentation()
+	if before < 0.5 {
+		t.Fatalf("FreeListFragmentation() = %v before compaction, want a fragmented list", before)
+	}
+
+	stats, err := pm.CompactFreeList()
+	if err != nil {
+		t.Fatalf("CompactFreeList failed: %v", err)
+	}
+	if stats.FragmentationBefore != before {
+		t.Errorf("FragmentationBefore = %v, want %v", stats.FragmentationBefore, before)
+	}
+	if after := pm.FreeListFragmentation(); after >= 0.1 {
+		t.Errorf("FreeListFragmentation() = %v after compaction, want < 0.1", after)
+	}
+	if uint64(stats.FreePages) != pm.FreePageCount() {
+		t.Errorf("stats.FreePages = %d, FreePageCount() = %d", stats.FreePages, pm.FreePageCount())
+	}
+
+	// Allocations now walk the free pages in ascending order
+	prev := PageID(0)
+	for i := 0; i < 100; i++ {
+		id, err := pm.AllocatePage(PageTypeData)
+		if err != nil {
+			t.Fatalf("AllocatePage failed: %v", err)
+		}
+		if id <= prev {
+			t.Fatalf("allocation %d returned page %d after %d", i, id, prev)
+		}
+		prev = id
+	}
+}
+
+func TestPageManagerQuarantine(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.oba")
+	pm, err := OpenPageManager(path, DefaultOptions())
+	if err != nil {
+		t.Fatalf("OpenPageManager failed: %v", err)
+	}
+
+	var ids []PageID
+	for i := 0; i < 2; i++ {
+		id, err := pm.AllocatePage(PageTypeData)
+		if err != nil {
+			t.Fatalf("AllocatePage failed: %v", err)
+		}
+		page := NewPage(id, PageTypeData)
+		copy(page.Data, []byte("entry data"))
+		if err := pm.WritePage(page); err != nil {
+			t.Fatalf("WritePage failed: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := pm.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Flip a data byte of the second page on disk
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	if _, err := f.WriteAt([]byte{0xFF}, int64(ids[1])*PageSize+PageHeaderSize+2); err != nil {
+		t.Fatalf("WriteAt failed: %v", err)
+	}
+	f.Close()
+
+	pm, err = OpenPageManager(path, DefaultOptions())
+	if err != nil {
+		t.Fatalf("OpenPageManager failed: %v", err)
+	}
+	defer pm.Close()
+
+	if _, err := pm.ReadPage(ids[0]); err != nil {
+		t.Errorf("ReadPage of the intact page failed: %v", err)
+	}
+
+	_, err = pm.ReadPage(ids[1])
+	var corrupted *PageCorruptedError
+	if !errors.As(err, &corrupted) || corrupted.PageID != ids[1] || corrupted.Expected == corrupted.Actual {
+		t.Fatalf("ReadPage of the corrupted page: error = %v, want *PageCorruptedError for page %d", err, ids[1])
+	}
+
+	// The page stays quarantined
+	if _, err := pm.ReadPage(ids[1]); !errors.Is(err, ErrPageCorrupted) {
+		t.Errorf("second ReadPage: error = %v, want ErrPageCorrupted", err)
+	}
+	if pages, _ := pm.ReadPages(ids); pages[0] == nil || pages[1] != nil {
+		t.Errorf("ReadPages returned %v, want only the intact page", pages)
+	}
+	if err := pm.WritePage(NewPage(ids[1], PageTypeData)); !errors.Is(err, ErrPageCorrupted) {
+		t.Errorf("WritePage: error = %v, want ErrPageCorrupted", err)
+	}
+	if err := pm.FreePage(ids[1]); !errors.Is(err, ErrPageCorrupted) {
+		t.Errorf("FreePage: error = %v, want ErrPageCorrupted", err)
+	}
+
+	if got := pm.QuarantinedPages(); len(got) != 1 || got[0] != ids[1] {
+		t.Errorf("QuarantinedPages() = %v, want [%d]", got, ids[1])
+	}
+	if got := pm.Stats().CorruptPages; got != 1 {
+		t.Errorf("Stats().CorruptPages = %d, want 1", got)
+	}
+}
+
+// writeV0File rewrites the pages of the file at path in PageFormatV0, as
+// an earlier version wrote them.
+func writeV0File(t *testing.T, path string) {
+	t.Helper()
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	defer f.Close()
+
+	info, _ := f.Stat()
+	buf := make([]byte, PageSize)
+	for id := PageID(1); int64(id)*PageSize < info.Size(); id++ {
+		page := &Page{}
+		f.ReadAt(buf, int64(id)*PageSize)
+		if isZeroPage(buf) {
+			continue
+		}
+		if err := page.Deserialize(buf); err != nil {
+			t.Fatalf("Deserialize failed: %v", err)
+		}
+		page.serializeTo(buf, PageFormatV0)
+		f.WriteAt(buf, int64(id)*PageSize)
+	}
+
+	header := &FileHeader{}
+	headerBuf := make([]byte, FileHeaderSize)
+	f.ReadAt(headerBuf, 0)
+	if err := header.DeserializeAndValidate(headerBuf); err != nil {
+		t.Fatalf("DeserializeAndValidate failed: %v", err)
+	}
+	header.SetComponentVersion(PageFormatComponent, PageFormatV0)
+	headerBuf, _ = header.Serialize()
+	f.WriteAt(headerBuf, 0)
+}
+
+func TestPageManagerUpgradePageFormat(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.oba")
+	pm, err := OpenPageManager(path, DefaultOptions())
+	if err != nil {
+		t.Fatalf("OpenPageManager failed: %v", err)
+	}
+	if got := pm.PageFormat(); got != PageFormatVersion {
+		t.Errorf("PageFormat() of a new file = %d, want %d", got, PageFormatVersion)
+	}
+
+	var ids []PageID
+	for i := 0; i < 3; i++ {
+		id, _ := pm.AllocatePage(PageTypeData)
+		page := NewPage(id, PageTypeData)
+		copy(page.Data, []byte("entry data"))
+		if err := pm.WritePage(page); err != nil {
+			t.Fatalf("WritePage failed: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	pm.Close()
+	writeV0File(t, path)
+
+	pm, err = OpenPageManager(path, DefaultOptions())
+	if err != nil {
+		t.Fatalf("OpenPageManager failed: %v", err)
+	}
+	if got := pm.PageFormat(); got != PageFormatV0 {
+		t.Errorf("PageFormat() = %d, want %d", got, PageFormatV0)
+	}
+	if _, err := pm.ReadPage(ids[0]); err != nil {
+		t.Errorf("ReadPage of a version 0 page failed: %v", err)
+	}
+	if err := pm.UpgradePageFormat(); err != nil {
+		t.Fatalf("UpgradePageFormat failed: %v", err)
+	}
+	pm.Close()
+
+	pm, err = OpenPageManager(path, DefaultOptions())
+	if err != nil {
+		t.Fatalf("OpenPageManager failed: %v", err)
+	}
+	defer pm.Close()
+	if got := pm.PageFormat(); got != PageFormatV1 {
+		t.Errorf("PageFormat() after the upgrade = %d, want %d", got, PageFormatV1)
+	}
+	for _, id := range ids {
+		page, err := pm.ReadPage(id)
+		if err != nil {
+			t.Fatalf("ReadPage(%d) after the upgrade failed: %v", id, err)
+		}
+		if !bytes.HasPrefix(page.Data, []byte("entry data")) {
+			t.Errorf("page %d data = %q, want the original data", id, page.Data[:10])
+		}
+	}
+}
+
+func TestPageManagerUpgradePageFormatCorrupt(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.oba")
+	pm, _ := OpenPageManager(path, DefaultOptions())
+	id, _ := pm.AllocatePage(PageTypeData)
+	pm.WritePage(NewPage(id, PageTypeData))
+	pm.Close()
+	writeV0File(t, path)
+
+	f, _ := os.OpenFile(path, os.O_RDWR, 0)
+	f.WriteAt([]byte{0xFF}, int64(id)*PageSize+PageHeaderSize+2)
+	f.Close()
+
+	pm, err := OpenPageManager(path, DefaultOptions())
+	if err != nil {
+		t.Fatalf("OpenPageManager failed: %v", err)
+	}
+	defer pm.Close()
+
+	// Rewriting the page would give the damaged data a valid checksum
+	var corrupted *PageCorruptedError
+	if err := pm.UpgradePageFormat(); !errors.As(err, &corrupted) || corrupted.PageID != id {
+		t.Errorf("UpgradePageFormat error = %v, want *PageCorruptedError for page %d", err, id)
+	}
+	if got := pm.PageFormat(); got != PageFormatV0 {
+		t.Errorf("PageFormat() after a failed upgrade = %d, want %d", got, PageFormatV0)
+	}
+}
+
+func TestOpenPageManagerPageFormatTooNew(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.oba")
+	pm, _ := OpenPageManager(path, DefaultOptions())
+	pm.header.SetComponentVersion(PageFormatComponent, PageFormatVersion+1)
+	pm.Close()
+
+	if _, err := OpenPageManager(path, DefaultOptions()); !errors.Is(err, ErrPageFormatTooNew) {
+		t.Errorf("OpenPageManager error = %v, want ErrPageFormatTooNew", err)
+	}
+}
+
 func TestPageManagerFreePageErrors(t *testing.T) {
 	dir := createTempDir(t)
 	defer os.RemoveAll(dir)
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"io"
+)
+
+// mapHeap reads the file into memory, for platforms without mmap. Writes
+// to the region reach the file when it is synced or unmapped.
+func (m *MmapManager) mapHeap() error {
+	if m.data != nil {
+		return ErrMmapAlreadyMapped
+	}
+
+	data := make([]byte, m.size)
+	if _, err := m.file.ReadAt(data, 0); err != nil && err != io.EOF {
+		return err
+	}
+
+	m.data = data
+	return nil
+}
+
+// unmapHeap writes the region read by mapHeap back to the file and
+// releases it.
+func (m *MmapManager) unmapHeap() error {
+	if m.data == nil {
+		return nil
+	}
+
+	err := m.writeBack()
+	m.data = nil
+	return err
+}
+
+// syncHeap writes the region read by mapHeap back to the file and syncs
+// it.
+func (m *MmapManager) syncHeap() error {
+	if m.data == nil {
+		return ErrMmapNotMapped
+	}
+
+	if err := m.writeBack(); err != nil {
+		return err
+	}
+	return m.file.Sync()
+}
+
+// writeBack writes the region to the file, unless it is read-only.
+func (m *MmapManager) writeBack() error {
+	if m.readOnly {
+		return nil
+	}
+	_, err := m.file.WriteAt(m.data, 0)
+	return err
+}
@@ -0,0 +1,72 @@
+//go:build !unix && !windows
+
+// Package storage provides the core storage engine components for ObaDB.
+package storage
+
+// mapFile reads the file into memory, as there is no mmap.
+func (m *MmapManager) mapFile() error {
+	return m.mapHeap()
+}
+
+// unmapFile writes the region back to the file and releases it.
+func (m *MmapManager) unmapFile() error {
+	return m.unmapHeap()
+}
+
+// syncFile writes the region back to the file and syncs it.
+func (m *MmapManager) syncFile() error {
+	return m.syncHeap()
+}
+
+// Advise is a no-op without mmap.
+func (m *MmapManager) Advise(advice int) error {
+	return nil
+}
+
+// MadviseSequential is a no-op without mmap.
+func (m *MmapManager) MadviseSequential() error {
+	return nil
+}
+
+// MadviseRandom is a no-op without mmap.
+func (m *MmapManager) MadviseRandom() error {
+	return nil
+}
+
+// MadviseWillNeed is a no-op without mmap.
+func (m *MmapManager) MadviseWillNeed() error {
+	return nil
+}
+
+// MadviseDontNeed is a no-op without mmap.
+func (m *MmapManager) MadviseDontNeed() error {
+	return nil
+}
+
+// Lock is a no-op without mmap; the region is ordinary memory.
+func (m *MmapManager) Lock() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return ErrMmapClosed
+	}
+	if m.data == nil {
+		return ErrMmapNotMapped
+	}
+	return nil
+}
+
+// Unlock is a no-op without mmap.
+func (m *MmapManager) Unlock() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return ErrMmapClosed
+	}
+	if m.data == nil {
+		return ErrMmapNotMapped
+	}
+	return nil
+}
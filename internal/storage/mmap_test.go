@@ -766,3 +766,54 @@ func BenchmarkMmapRandomRead(b *testing.B) {
 		}
 	}
 }
+
+// TestMmapHeapFallback tests the read-based mapping used on platforms
+// without mmap.
+func TestMmapHeapFallback(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "heap.db"))
+	if err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteAt([]byte("hello"), 0); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	mm := &MmapManager{file: file, size: PageSize, pageSize: PageSize}
+	if err := mm.mapHeap(); err != nil {
+		t.Fatalf("mapHeap() error = %v", err)
+	}
+	if !bytes.Equal(mm.data[:5], []byte("hello")) {
+		t.Errorf("mapped data = %q, want the file contents", mm.data[:5])
+	}
+
+	copy(mm.data[PageSize-5:], "world")
+	if err := mm.syncHeap(); err != nil {
+		t.Fatalf("syncHeap() error = %v", err)
+	}
+	buf := make([]byte, 5)
+	if _, err := file.ReadAt(buf, PageSize-5); err != nil || string(buf) != "world" {
+		t.Errorf("file after sync = %q, %v, want world", buf, err)
+	}
+
+	copy(mm.data, "HELLO")
+	if err := mm.unmapHeap(); err != nil {
+		t.Fatalf("unmapHeap() error = %v", err)
+	}
+	if _, err := file.ReadAt(buf, 0); err != nil || string(buf) != "HELLO" {
+		t.Errorf("file after unmap = %q, %v, want HELLO", buf, err)
+	}
+
+	// A read-only mapping never writes the file
+	mm.readOnly = true
+	if err := mm.mapHeap(); err != nil {
+		t.Fatalf("mapHeap() error = %v", err)
+	}
+	copy(mm.data, "xxxxx")
+	if err := mm.unmapHeap(); err != nil {
+		t.Fatalf("unmapHeap() error = %v", err)
+	}
+	if _, err := file.ReadAt(buf, 0); err != nil || string(buf) != "HELLO" {
+		t.Errorf("file after read-only unmap = %q, %v, want HELLO", buf, err)
+	}
+}
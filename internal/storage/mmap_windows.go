@@ -75,7 +75,7 @@ func (m *MmapManager) mapFile() error {
 	m.mapHandle = mapHandle
 
 	// Create slice from mapped memory
-	m.data = unsafe.Slice((*byte)(unsafe.Pointer(addr)), m.size)
+	m.data = unsafe.Slice((*byte)(*(*unsafe.Pointer)(unsafe.Pointer(&addr))), m.size)
 
 	return nil
 }
@@ -180,3 +180,20 @@ func TestVersionStoreCacheLargeData(t *testing.T) {
 		t.Errorf("EntryCount() = %d, want 100", vs2.EntryCount())
 	}
 }
+
+func TestLoadCommittedVersionKeepsExisting(t *testing.T) {
+	vs := NewVersionStore(nil)
+	dn := "cn=user1,dc=example,dc=com"
+
+	// A background preload must not replace a version written since open
+	vs.LoadCommittedVersion(dn, []byte("newer"), storage.PageID(2), 0)
+	vs.LoadCommittedVersion(dn, []byte("stale"), storage.PageID(1), 0)
+
+	version := vs.GetLatestVersion(dn)
+	if version == nil {
+		t.Fatal("version not found")
+	}
+	if string(version.GetData()) != "newer" {
+		t.Errorf("data = %q, want %q", version.GetData(), "newer")
+	}
+}
@@ -2,6 +2,7 @@
 package mvcc
 
 import (
+	"errors"
 	"sync"
 
 	"github.com/KilimcininKorOglu/oba/internal/storage"
@@ -92,6 +93,11 @@ func (vs *VersionStore) GetVisibleForTx(dn string, snapshot uint64, activeTxID u
 			}
 			current = current.GetPrev()
 		}
+
+		// The chain starts at the version that was on disk, so the entry
+		// did not exist at the snapshot. The cache and the disk only hold
+		// the latest version, which is not visible either.
+		return nil, ErrVersionNotFound
 	}
 
 	// Check cache
@@ -109,6 +115,9 @@ func (vs *VersionStore) GetVisibleForTx(dn string, snapshot uint64, activeTxID u
 	// Cache miss - try to load from disk
 	if vs.diskLoader != nil {
 		version, pageID, slotID, err := vs.diskLoader(dn)
+		if errors.Is(err, storage.ErrPageCorrupted) {
+			return nil, err
+		}
 		if err != nil {
 			return nil, ErrVersionNotFound
 		}
@@ -159,6 +168,12 @@ func (vs *VersionStore) CreateVersionWithLocation(txn *tx.Transaction, dn string
 	vs.activeWriters[dn] = txn.ID
 	vs.writerMu.Unlock()
 
+	// A version committed after the snapshot would be overwritten unseen
+	if vs.CommittedAfter(dn, txn.Snapshot, txn.ID) {
+		vs.clearActiveWriter(dn, txn.ID)
+		return 0, 0, ErrVersionConflict
+	}
+
 	// Allocate a page for the new version data
 	pageID, slotID, err := vs.allocateStorage(data)
 	if err != nil {
@@ -231,6 +246,9 @@ func (vs *VersionStore) DeleteVersion(txn *tx.Transaction, dn string) error {
 		// If still not found, try disk loader
 		if latestVersion == nil && vs.diskLoader != nil {
 			version, _, _, err := vs.diskLoader(dn)
+			if errors.Is(err, storage.ErrPageCorrupted) {
+				return err
+			}
 			if err == nil && version != nil {
 				latestVersion = version
 			}
@@ -250,6 +268,11 @@ func (vs *VersionStore) DeleteVersion(txn *tx.Transaction, dn string) error {
 	vs.activeWriters[dn] = txn.ID
 	vs.writerMu.Unlock()
 
+	if vs.CommittedAfter(dn, txn.Snapshot, txn.ID) {
+		vs.clearActiveWriter(dn, txn.ID)
+		return ErrVersionConflict
+	}
+
 	// Get the storage location from the latest version
 	pageID, slotID := latestVersion.GetLocation()
 
@@ -366,6 +389,7 @@ func (vs *VersionStore) allocateStorage(data []byte) (storage.PageID, uint16, er
 		page.Data[3] = byte(len(data) >> 24)
 		copy(page.Data[4:], data)
 		page.Header.ItemCount = 1
+		page.Header.FreeSpace = uint16(len(page.Data) - 4 - len(data))
 
 		if err := vs.pageManager.WritePage(page); err != nil {
 			return 0, 0, err
@@ -385,6 +409,42 @@ func (vs *VersionStore) clearActiveWriter(dn string, txID uint64) {
 	}
 }
 
+// CommittedAfter reports whether another transaction than txID committed
+// a version of dn with a timestamp later than snapshot. A transaction
+// writing dn in that case would lose the other transaction's update.
+func (vs *VersionStore) CommittedAfter(dn string, snapshot uint64, txID uint64) bool {
+	vs.mu.RLock()
+	current := vs.versions[dn]
+	vs.mu.RUnlock()
+
+	return committedAfter(current, snapshot, txID)
+}
+
+// CommittedSince returns the DNs for which another transaction than txID
+// committed a version with a timestamp later than snapshot.
+func (vs *VersionStore) CommittedSince(snapshot uint64, txID uint64) []string {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+
+	var dns []string
+	for dn, current := range vs.versions {
+		if committedAfter(current, snapshot, txID) {
+			dns = append(dns, dn)
+		}
+	}
+	return dns
+}
+
+// committedAfter reports whether the latest committed version in the chain
+// starting at current was committed after snapshot by another transaction
+// than txID.
+func committedAfter(current *Version, snapshot uint64, txID uint64) bool {
+	for current != nil && !current.IsCommitted() {
+		current = current.GetPrev()
+	}
+	return current != nil && current.GetTxID() != txID && current.GetCommitTS() > snapshot
+}
+
 // GetLatestVersion returns the latest version for a DN (regardless of visibility).
 // This is useful for debugging and testing.
 func (vs *VersionStore) GetLatestVersion(dn string) *Version {
@@ -395,12 +455,16 @@ func (vs *VersionStore) GetLatestVersion(dn string) *Version {
 
 // LoadCommittedVersion loads a committed version from disk into the version store.
 // This is used during database initialization to restore persisted data.
+// An existing in-memory version is newer than the copy on disk and is kept,
+// since preloading runs in the background while writes may already happen.
 func (vs *VersionStore) LoadCommittedVersion(dn string, data []byte, pageID storage.PageID, slotID uint16) {
 	version := NewVersion(0, data, pageID, slotID)
 	version.CommitTS = 1 // Mark as committed with timestamp 1
 
 	vs.mu.Lock()
-	vs.versions[dn] = version
+	if _, exists := vs.versions[dn]; !exists {
+		vs.versions[dn] = version
+	}
 	vs.mu.Unlock()
 }
 
@@ -440,7 +504,7 @@ func (vs *VersionStore) HasEntry(dn string) bool {
 	return exists
 }
 
-// Clear removes all versions from the store.
+// Clear removes all versions from the store, including the entry cache.
 // This is useful for testing.
 func (vs *VersionStore) Clear() {
 	vs.mu.Lock()
@@ -451,6 +515,9 @@ func (vs *VersionStore) Clear() {
 
 	vs.versions = make(map[string]*Version)
 	vs.activeWriters = make(map[string]uint64)
+	if vs.cache != nil {
+		vs.cache.Clear()
+	}
 }
 
 // GarbageCollect removes old versions that are no longer visible to any active snapshot.
@@ -380,6 +380,7 @@ func TestVersionStoreDelete(t *testing.T) {
 
 	// Delete the entry
 	tx2, _ := txMgr.Begin()
+	tx2.Snapshot = 100
 	err = vs.DeleteVersion(tx2, dn)
 	if err != nil {
 		t.Fatalf("DeleteVersion failed: %v", err)
@@ -452,11 +453,13 @@ func TestVersionStoreMultipleVersions(t *testing.T) {
 
 	// Create version 2
 	tx2, _ := txMgr.Begin()
+	tx2.Snapshot = 100
 	vs.CreateVersion(tx2, dn, []byte("version 2"))
 	vs.CommitVersion(tx2, 200)
 
 	// Create version 3
 	tx3, _ := txMgr.Begin()
+	tx3.Snapshot = 200
 	vs.CreateVersion(tx3, dn, []byte("version 3"))
 	vs.CommitVersion(tx3, 300)
 
@@ -520,8 +523,15 @@ func TestVersionStoreWriteConflict(t *testing.T) {
 	// Let's commit tx1 first
 	vs.CommitVersion(tx1, 100)
 
-	// Now tx2 should be able to create a version
+	// tx2 started before tx1 committed, so writing would lose tx1's update
+	err = vs.CreateVersion(tx2, dn, []byte("tx2 data"))
+	if err != ErrVersionConflict {
+		t.Errorf("expected ErrVersionConflict for a snapshot before tx1 commit, got %v", err)
+	}
+
+	// A transaction whose snapshot includes tx1's commit can write
 	tx3, _ := txMgr.Begin()
+	tx3.Snapshot = 100
 	err = vs.CreateVersion(tx3, dn, []byte("tx3 data"))
 	if err != nil {
 		t.Errorf("tx3 CreateVersion should succeed after tx1 commit, got %v", err)
@@ -542,6 +552,7 @@ func TestVersionStoreGarbageCollect(t *testing.T) {
 	// Create multiple versions
 	for i := 1; i <= 5; i++ {
 		txn, _ := txMgr.Begin()
+		txn.Snapshot = uint64((i - 1) * 100)
 		vs.CreateVersion(txn, dn, []byte("version"))
 		vs.CommitVersion(txn, uint64(i*100))
 	}
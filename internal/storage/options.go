@@ -62,6 +62,26 @@ type EngineOptions struct {
 	// If set, takes precedence over EncryptionKeyFile.
 	// Warning: Prefer EncryptionKeyFile for production use.
 	EncryptionKey []byte
+
+	// DeferredIndexing moves attribute index updates off the write path.
+	// Updates are batched and applied in the background, so a committed
+	// entry may take up to DeferredIndexFlushInterval to become visible to
+	// index lookups.
+	// Default: false.
+	DeferredIndexing bool
+
+	// DeferredIndexFlushInterval is the time between deferred index flushes.
+	// Default: 100 milliseconds.
+	DeferredIndexFlushInterval time.Duration
+
+	// FileSystem opens the data, index and WAL files.
+	// Default: nil (the operating system's).
+	FileSystem FileSystem
+
+	// AdaptiveIndexSize is the number of DN locations of the most
+	// frequently accessed entries cached by the adaptive hash index.
+	// Default: 1024.
+	AdaptiveIndexSize int
 }
 
 // DefaultEngineOptions returns the default engine options.
@@ -79,6 +99,9 @@ func DefaultEngineOptions() EngineOptions {
 		GCEnabled:          true,
 		MaxOpenFiles:       1000,
 		InitialPages:       16,
+
+		DeferredIndexFlushInterval: 100 * time.Millisecond,
+		AdaptiveIndexSize:          DefaultAdaptiveIndexSize,
 	}
 }
 
@@ -108,6 +131,14 @@ func (o *EngineOptions) Validate() error {
 		o.InitialPages = 16
 	}
 
+	if o.DeferredIndexFlushInterval <= 0 {
+		o.DeferredIndexFlushInterval = 100 * time.Millisecond
+	}
+
+	if o.AdaptiveIndexSize <= 0 {
+		o.AdaptiveIndexSize = DefaultAdaptiveIndexSize
+	}
+
 	return nil
 }
 
@@ -176,3 +207,29 @@ func (o EngineOptions) WithEncryptionKey(key []byte) EngineOptions {
 	o.EncryptionKey = key
 	return o
 }
+
+// WithDeferredIndexing enables or disables deferred index updates.
+func (o EngineOptions) WithDeferredIndexing(enabled bool) EngineOptions {
+	o.DeferredIndexing = enabled
+	return o
+}
+
+// WithDeferredIndexFlushInterval sets the deferred index flush interval.
+func (o EngineOptions) WithDeferredIndexFlushInterval(interval time.Duration) EngineOptions {
+	o.DeferredIndexFlushInterval = interval
+	return o
+}
+
+// WithAdaptiveIndexSize sets the number of locations cached by the
+// adaptive hash index.
+func (o EngineOptions) WithAdaptiveIndexSize(size int) EngineOptions {
+	o.AdaptiveIndexSize = size
+	return o
+}
+
+// WithFileSystem sets the file system the data, index and WAL files are
+// opened through.
+func (o EngineOptions) WithFileSystem(fs FileSystem) EngineOptions {
+	o.FileSystem = fs
+	return o
+}
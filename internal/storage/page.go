@@ -4,6 +4,7 @@ package storage
 import (
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"hash/crc32"
 )
 
@@ -66,21 +67,45 @@ const (
 // PageID represents a unique identifier for a page.
 type PageID uint64
 
+// MaxPageID is the largest page ID the page header can hold.
+const MaxPageID PageID = 1<<48 - 1
+
+// Page formats. The format of the pages of a file is recorded in its file
+// header as the version of PageFormatComponent. A file is read and written
+// in its format until PageManager.UpgradePageFormat rewrites it.
+const (
+	// PageFormatV0 has a 64-bit page ID and a 16-bit checksum, the CRC32 of
+	// the data truncated to 16 bits.
+	PageFormatV0 uint32 = 0
+	// PageFormatV1 has a 48-bit page ID and a 32-bit checksum, the CRC32C
+	// of the header fields and the data.
+	PageFormatV1 uint32 = 1
+	// PageFormatVersion is the format new files are written in.
+	PageFormatVersion = PageFormatV1
+)
+
+// PageFormatComponent is the name the page format is recorded under in the
+// component version table of the file header.
+const PageFormatComponent = "page"
+
 // PageHeader represents the header of each page (first 16 bytes).
 // Layout:
-//   - Bytes 0-7:   PageID (uint64)
-//   - Byte 8:      PageType (uint8)
-//   - Byte 9:      Flags (uint8)
-//   - Bytes 10-11: ItemCount (uint16)
-//   - Bytes 12-13: FreeSpace (uint16)
-//   - Bytes 14-15: Checksum (uint16)
+//   - Bytes 0-5:   PageID (uint48)
+//   - Byte 6:      PageType (uint8)
+//   - Byte 7:      Flags (uint8)
+//   - Bytes 8-9:   ItemCount (uint16)
+//   - Bytes 10-11: FreeSpace (uint16)
+//   - Bytes 12-15: Checksum (uint32)
+//
+// In PageFormatV0 the PageID takes bytes 0-7, the other fields follow in
+// the same order and the checksum takes bytes 14-15.
 type PageHeader struct {
 	PageID    PageID   // This page's ID
 	PageType  PageType // Data, Index, Free, Overflow
 	Flags     PageFlag // Dirty, Pinned, etc.
 	ItemCount uint16   // Number of items in page
 	FreeSpace uint16   // Bytes of free space
-	Checksum  uint16   // CRC16 of page content
+	Checksum  uint32   // CRC32C of header and data
 }
 
 // Errors for page operations.
@@ -90,8 +115,31 @@ var (
 	ErrInvalidPageType     = errors.New("invalid page type")
 	ErrInsufficientSpace   = errors.New("insufficient space in page")
 	ErrPageHeaderCorrupted = errors.New("page header corrupted")
+	ErrPageCorrupted       = errors.New("page is corrupted")
+	ErrPageFormatTooNew    = errors.New("page format is newer than supported")
 )
 
+// castagnoli is the CRC32C table used for page checksums.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// PageCorruptedError is returned for a page whose checksum does not match
+// its content. It matches ErrPageCorrupted and ErrInvalidChecksum.
+type PageCorruptedError struct {
+	PageID   PageID
+	Expected uint32 // checksum stored in the page header
+	Actual   uint32 // checksum of the page content
+}
+
+// Error returns the page and both checksums.
+func (e *PageCorruptedError) Error() string {
+	return fmt.Sprintf("page %d is corrupted: checksum %#08x, content %#08x", e.PageID, e.Expected, e.Actual)
+}
+
+// Is reports whether target is ErrPageCorrupted or ErrInvalidChecksum.
+func (e *PageCorruptedError) Is(target error) bool {
+	return target == ErrPageCorrupted || target == ErrInvalidChecksum
+}
+
 // NewPageHeader creates a new PageHeader with the given parameters.
 func NewPageHeader(pageID PageID, pageType PageType) *PageHeader {
 	return &PageHeader{
@@ -107,16 +155,37 @@ func NewPageHeader(pageID PageID, pageType PageType) *PageHeader {
 // Serialize writes the PageHeader to a byte slice.
 // The slice must be at least PageHeaderSize bytes.
 func (h *PageHeader) Serialize(buf []byte) error {
+	return h.serialize(buf, PageFormatVersion)
+}
+
+// serialize writes the PageHeader to buf in the given page format.
+func (h *PageHeader) serialize(buf []byte, format uint32) error {
 	if len(buf) < PageHeaderSize {
 		return ErrInvalidPageSize
 	}
 
-	binary.LittleEndian.PutUint64(buf[0:8], uint64(h.PageID))
-	buf[8] = byte(h.PageType)
-	buf[9] = byte(h.Flags)
-	binary.LittleEndian.PutUint16(buf[10:12], h.ItemCount)
-	binary.LittleEndian.PutUint16(buf[12:14], h.FreeSpace)
-	binary.LittleEndian.PutUint16(buf[14:16], h.Checksum)
+	if format == PageFormatV0 {
+		binary.LittleEndian.PutUint64(buf[0:8], uint64(h.PageID))
+		buf[8] = byte(h.PageType)
+		buf[9] = byte(h.Flags)
+		binary.LittleEndian.PutUint16(buf[10:12], h.ItemCount)
+		binary.LittleEndian.PutUint16(buf[12:14], h.FreeSpace)
+		binary.LittleEndian.PutUint16(buf[14:16], uint16(h.Checksum))
+		return nil
+	}
+
+	if h.PageID > MaxPageID {
+		return ErrPageOutOfRange
+	}
+
+	var id [8]byte
+	binary.LittleEndian.PutUint64(id[:], uint64(h.PageID))
+	copy(buf[0:6], id[:6])
+	buf[6] = byte(h.PageType)
+	buf[7] = byte(h.Flags)
+	binary.LittleEndian.PutUint16(buf[8:10], h.ItemCount)
+	binary.LittleEndian.PutUint16(buf[10:12], h.FreeSpace)
+	binary.LittleEndian.PutUint32(buf[12:16], h.Checksum)
 
 	return nil
 }
@@ -124,16 +193,33 @@ func (h *PageHeader) Serialize(buf []byte) error {
 // Deserialize reads the PageHeader from a byte slice.
 // The slice must be at least PageHeaderSize bytes.
 func (h *PageHeader) Deserialize(buf []byte) error {
+	return h.deserialize(buf, PageFormatVersion)
+}
+
+// deserialize reads the PageHeader from buf in the given page format.
+func (h *PageHeader) deserialize(buf []byte, format uint32) error {
 	if len(buf) < PageHeaderSize {
 		return ErrInvalidPageSize
 	}
 
-	h.PageID = PageID(binary.LittleEndian.Uint64(buf[0:8]))
-	h.PageType = PageType(buf[8])
-	h.Flags = PageFlag(buf[9])
-	h.ItemCount = binary.LittleEndian.Uint16(buf[10:12])
-	h.FreeSpace = binary.LittleEndian.Uint16(buf[12:14])
-	h.Checksum = binary.LittleEndian.Uint16(buf[14:16])
+	if format == PageFormatV0 {
+		h.PageID = PageID(binary.LittleEndian.Uint64(buf[0:8]))
+		h.PageType = PageType(buf[8])
+		h.Flags = PageFlag(buf[9])
+		h.ItemCount = binary.LittleEndian.Uint16(buf[10:12])
+		h.FreeSpace = binary.LittleEndian.Uint16(buf[12:14])
+		h.Checksum = uint32(binary.LittleEndian.Uint16(buf[14:16]))
+		return nil
+	}
+
+	var id [8]byte
+	copy(id[:6], buf[0:6])
+	h.PageID = PageID(binary.LittleEndian.Uint64(id[:]))
+	h.PageType = PageType(buf[6])
+	h.Flags = PageFlag(buf[7])
+	h.ItemCount = binary.LittleEndian.Uint16(buf[8:10])
+	h.FreeSpace = binary.LittleEndian.Uint16(buf[10:12])
+	h.Checksum = binary.LittleEndian.Uint32(buf[12:16])
 
 	return nil
 }
@@ -203,30 +289,28 @@ func NewPage(pageID PageID, pageType PageType) *Page {
 // Returns a new byte slice of PageSize bytes.
 func (p *Page) Serialize() ([]byte, error) {
 	buf := make([]byte, PageSize)
-
-	// Calculate checksum before serializing header
-	p.Header.Checksum = p.CalculateChecksum()
-
-	if err := p.Header.Serialize(buf[:PageHeaderSize]); err != nil {
+	if err := p.serializeTo(buf, PageFormatVersion); err != nil {
 		return nil, err
 	}
-
-	copy(buf[PageHeaderSize:], p.Data)
-
 	return buf, nil
 }
 
 // SerializeTo writes the entire page to an existing byte slice.
 // The slice must be at least PageSize bytes.
 func (p *Page) SerializeTo(buf []byte) error {
+	return p.serializeTo(buf, PageFormatVersion)
+}
+
+// serializeTo writes the entire page to buf in the given page format.
+func (p *Page) serializeTo(buf []byte, format uint32) error {
 	if len(buf) < PageSize {
 		return ErrInvalidPageSize
 	}
 
 	// Calculate checksum before serializing header
-	p.Header.Checksum = p.CalculateChecksum()
+	p.Header.Checksum = p.checksum(format)
 
-	if err := p.Header.Serialize(buf[:PageHeaderSize]); err != nil {
+	if err := p.Header.serialize(buf[:PageHeaderSize], format); err != nil {
 		return err
 	}
 
@@ -238,11 +322,16 @@ func (p *Page) SerializeTo(buf []byte) error {
 // Deserialize reads the entire page from a byte slice.
 // The slice must be at least PageSize bytes.
 func (p *Page) Deserialize(buf []byte) error {
+	return p.deserialize(buf, PageFormatVersion)
+}
+
+// deserialize reads the entire page from buf in the given page format.
+func (p *Page) deserialize(buf []byte, format uint32) error {
 	if len(buf) < PageSize {
 		return ErrInvalidPageSize
 	}
 
-	if err := p.Header.Deserialize(buf[:PageHeaderSize]); err != nil {
+	if err := p.Header.deserialize(buf[:PageHeaderSize], format); err != nil {
 		return err
 	}
 
@@ -255,12 +344,27 @@ func (p *Page) Deserialize(buf []byte) error {
 	return nil
 }
 
-// CalculateChecksum computes the CRC16 checksum of the page data.
-// Uses CRC32 internally and truncates to 16 bits for the page header.
-func (p *Page) CalculateChecksum() uint16 {
-	// Use CRC32 and truncate to 16 bits
-	crc := crc32.ChecksumIEEE(p.Data)
-	return uint16(crc & 0xFFFF)
+// CalculateChecksum computes the checksum of the page: the CRC32C of the
+// header fields before the checksum and of the data. The in-memory flags
+// are left out, as they change without the page being rewritten.
+func (p *Page) CalculateChecksum() uint32 {
+	return p.checksum(PageFormatVersion)
+}
+
+// checksum computes the checksum of the page in the given page format.
+// PageFormatV0 pages carry the CRC32 of the data truncated to 16 bits.
+func (p *Page) checksum(format uint32) uint32 {
+	if format == PageFormatV0 {
+		return crc32.ChecksumIEEE(p.Data) & 0xFFFF
+	}
+
+	var header [PageHeaderSize]byte
+	h := p.Header
+	h.Flags &^= PageFlagDirty | PageFlagPinned
+	h.serialize(header[:], format)
+
+	crc := crc32.Checksum(header[:12], castagnoli)
+	return crc32.Update(crc, castagnoli, p.Data)
 }
 
 // ValidateChecksum verifies the page checksum matches the stored value.
@@ -268,17 +372,28 @@ func (p *Page) ValidateChecksum() bool {
 	return p.Header.Checksum == p.CalculateChecksum()
 }
 
+// VerifyChecksum returns a *PageCorruptedError if the page checksum does
+// not match its content.
+func (p *Page) VerifyChecksum() error {
+	return p.verifyChecksum(PageFormatVersion)
+}
+
+// verifyChecksum verifies the checksum of a page read in the given page
+// format.
+func (p *Page) verifyChecksum(format uint32) error {
+	if actual := p.checksum(format); actual != p.Header.Checksum {
+		return &PageCorruptedError{PageID: p.Header.PageID, Expected: p.Header.Checksum, Actual: actual}
+	}
+	return nil
+}
+
 // DeserializeAndValidate reads the page and validates its checksum.
 func (p *Page) DeserializeAndValidate(buf []byte) error {
 	if err := p.Deserialize(buf); err != nil {
 		return err
 	}
 
-	if !p.ValidateChecksum() {
-		return ErrInvalidChecksum
-	}
-
-	return nil
+	return p.VerifyChecksum()
 }
 
 // UsableSpace returns the amount of usable space in the page data area.
@@ -2,6 +2,9 @@ package storage
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
 	"testing"
 )
 
@@ -320,11 +323,83 @@ func TestPageDeserializeAndValidate(t *testing.T) {
 	buf[PageHeaderSize] = 0xFF // Modify first data byte
 
 	corrupted := &Page{}
-	if err := corrupted.DeserializeAndValidate(buf); err != ErrInvalidChecksum {
+	if err := corrupted.DeserializeAndValidate(buf); !errors.Is(err, ErrInvalidChecksum) {
 		t.Errorf("DeserializeAndValidate should return ErrInvalidChecksum for corrupted data, got %v", err)
 	}
 }
 
+func TestPageChecksumCoversHeader(t *testing.T) {
+	page := NewPage(7, PageTypeData)
+	page.Header.ItemCount = 3
+	copy(page.Data, []byte("entry data"))
+
+	buf, err := page.Serialize()
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+
+	// The in-memory flags are not covered
+	page.Header.SetDirty()
+	page.Header.SetPinned()
+	if !page.ValidateChecksum() {
+		t.Error("ValidateChecksum failed after setting the dirty and pinned flags")
+	}
+
+	for _, pos := range []int{0, 6, 8, 10} {
+		corrupt := append([]byte(nil), buf...)
+		corrupt[pos] ^= 0x01
+
+		var corrupted *PageCorruptedError
+		err := (&Page{}).DeserializeAndValidate(corrupt)
+		if !errors.As(err, &corrupted) || !errors.Is(err, ErrPageCorrupted) {
+			t.Errorf("header byte %d flipped: error = %v, want *PageCorruptedError", pos, err)
+			continue
+		}
+		if corrupted.Expected == corrupted.Actual {
+			t.Errorf("header byte %d flipped: expected and actual checksums are both %#08x", pos, corrupted.Expected)
+		}
+	}
+}
+
+func TestPageFormatV0(t *testing.T) {
+	// Version 0 pages have a 64-bit page ID and the truncated CRC32 of
+	// the data
+	buf := make([]byte, PageSize)
+	binary.LittleEndian.PutUint64(buf[0:8], 7)
+	buf[8] = byte(PageTypeData)
+	binary.LittleEndian.PutUint16(buf[10:12], 1)
+	copy(buf[PageHeaderSize:], []byte("entry data"))
+	binary.LittleEndian.PutUint16(buf[14:16], uint16(crc32.ChecksumIEEE(buf[PageHeaderSize:])))
+
+	page := &Page{}
+	if err := page.deserialize(buf, PageFormatV0); err != nil {
+		t.Fatalf("deserialize failed: %v", err)
+	}
+	if page.Header.PageID != 7 || page.Header.PageType != PageTypeData || page.Header.ItemCount != 1 {
+		t.Errorf("header = %+v, want page 7, data, 1 item", page.Header)
+	}
+	if err := page.verifyChecksum(PageFormatV0); err != nil {
+		t.Errorf("verifyChecksum of a version 0 page failed: %v", err)
+	}
+
+	// Rewritten in the current format, the page carries the full checksum
+	upgraded, err := page.Serialize()
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	if err := (&Page{}).DeserializeAndValidate(upgraded); err != nil {
+		t.Errorf("DeserializeAndValidate of the upgraded page failed: %v", err)
+	}
+
+	buf[PageHeaderSize] ^= 0x01
+	if err := page.deserialize(buf, PageFormatV0); err != nil {
+		t.Fatalf("deserialize failed: %v", err)
+	}
+	if err := page.verifyChecksum(PageFormatV0); !errors.Is(err, ErrPageCorrupted) {
+		t.Errorf("corrupted version 0 page: error = %v, want ErrPageCorrupted", err)
+	}
+}
+
 func TestPageUsableSpace(t *testing.T) {
 	page := NewPage(1, PageTypeData)
 
@@ -418,7 +493,11 @@ func TestConstants(t *testing.T) {
 // =============================================================================
 
 func TestPageWithMaxPageID(t *testing.T) {
-	maxID := PageID(^uint64(0))
+	if _, err := NewPage(MaxPageID+1, PageTypeData).Serialize(); !errors.Is(err, ErrPageOutOfRange) {
+		t.Errorf("Serialize of page %d error = %v, want ErrPageOutOfRange", MaxPageID+1, err)
+	}
+
+	maxID := MaxPageID
 	page := NewPage(maxID, PageTypeData)
 
 	buf, err := page.Serialize()
@@ -0,0 +1,93 @@
+package radix
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// benchmarkTreeSize is the number of DNs loaded before lookups are measured.
+const benchmarkTreeSize = 100000
+
+// benchmarkDN returns the i-th DN of a benchmark tree. Entries are spread
+// over organizational units of 100 entries each.
+func benchmarkDN(i int) string {
+	return fmt.Sprintf("uid=user%d,ou=team%d,ou=users,dc=example,dc=com", i, i/100)
+}
+
+// loadBenchmarkTree creates a tree holding benchmarkTreeSize DNs.
+func loadBenchmarkTree(b *testing.B) *RadixTree {
+	pm, cleanup := createTestPageManager(b)
+	b.Cleanup(cleanup)
+
+	tree, err := NewRadixTree(pm)
+	if err != nil {
+		b.Fatalf("failed to create radix tree: %v", err)
+	}
+	for i := 0; i < benchmarkTreeSize; i++ {
+		if err := tree.Insert(benchmarkDN(i), storage.PageID(i+1), 0); err != nil {
+			b.Fatalf("Insert() error = %v", err)
+		}
+	}
+	return tree
+}
+
+// BenchmarkRadixTreeInsert benchmarks inserting new DNs.
+func BenchmarkRadixTreeInsert(b *testing.B) {
+	pm, cleanup := createTestPageManager(b)
+	defer cleanup()
+
+	tree, err := NewRadixTree(pm)
+	if err != nil {
+		b.Fatalf("failed to create radix tree: %v", err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := tree.Insert(benchmarkDN(i), storage.PageID(i+1), 0); err != nil {
+			b.Fatalf("Insert() error = %v", err)
+		}
+	}
+}
+
+// BenchmarkRadixTreeLookup benchmarks DN lookups.
+func BenchmarkRadixTreeLookup(b *testing.B) {
+	tree := loadBenchmarkTree(b)
+	dns := make([]string, 1024)
+	for i := range dns {
+		dns[i] = benchmarkDN(i * 97 % benchmarkTreeSize)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, _, found := tree.Lookup(dns[i%len(dns)]); !found {
+			b.Fatalf("Lookup(%s) not found", dns[i%len(dns)])
+		}
+	}
+}
+
+// BenchmarkRadixTreeIterateOneLevel benchmarks listing the 100 children of
+// an organizational unit.
+func BenchmarkRadixTreeIterateOneLevel(b *testing.B) {
+	tree := loadBenchmarkTree(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		count := 0
+		base := fmt.Sprintf("ou=team%d,ou=users,dc=example,dc=com", i%(benchmarkTreeSize/100))
+		err := tree.IterateOneLevelChildren(base, func(string, storage.PageID, uint16) bool {
+			count++
+			return true
+		})
+		if err != nil || count != 100 {
+			b.Fatalf("IterateOneLevelChildren() = %d entries, %v", count, err)
+		}
+	}
+}
@@ -9,6 +9,10 @@ import (
 	"github.com/KilimcininKorOglu/oba/internal/storage/cache"
 )
 
+// snapshotMagic prefixes the compact snapshot format. Caches without it use
+// the legacy format and are still readable.
+const snapshotMagic = "RDX2"
+
 // Cache errors.
 var (
 	ErrCacheCorrupt = errors.New("cache data corrupt")
@@ -34,157 +38,216 @@ func (t *RadixTree) SaveCache(path string, txID uint64) error {
 		return nil
 	}
 
-	// Collect all nodes in BFS order
-	nodes := t.collectAllNodes()
-	if len(nodes) == 0 {
-		return nil
-	}
-
-	// Serialize nodes
-	data, err := t.serializeNodes(nodes)
-	if err != nil {
-		return err
-	}
+	data, count := t.serializeSnapshot()
 
 	// Write cache file
-	return cache.WriteFile(path, cache.TypeRadix, data, uint64(len(nodes)), txID)
+	return cache.WriteFile(path, cache.TypeRadix, data, uint64(count), txID)
 }
 
-// LoadCache loads the radix tree from a cache file.
+// LoadCache loads the radix tree from a cache file written at exactly txID.
 func (t *RadixTree) LoadCache(path string, expectedTxID uint64) error {
 	data, header, err := cache.ReadFile(path, cache.TypeRadix, expectedTxID)
 	if err != nil {
 		return err
 	}
+	return t.loadSnapshotData(data, header)
+}
 
-	t.mu.Lock()
-	defer t.mu.Unlock()
+// LoadSnapshot loads the radix tree from a cache file written at or before
+// maxTxID and returns the transaction ID it was written at. Changes made
+// after that point must be replayed with ReplayWAL.
+func (t *RadixTree) LoadSnapshot(path string, maxTxID uint64) (uint64, error) {
+	data, header, err := cache.ReadFileUpTo(path, cache.TypeRadix, maxTxID)
+	if err != nil {
+		return 0, err
+	}
+	if err := t.loadSnapshotData(data, header); err != nil {
+		return 0, err
+	}
+	return header.LastTxID, nil
+}
 
-	// Deserialize nodes
-	root, err := t.deserializeNodes(data, int(header.EntryCount))
+// loadSnapshotData replaces the tree with the nodes decoded from data.
+func (t *RadixTree) loadSnapshotData(data []byte, header *cache.Header) error {
+	var root *Node
+	var err error
+	if bytes.HasPrefix(data, []byte(snapshotMagic)) {
+		root, err = decodeSnapshot(data[len(snapshotMagic):], header.EntryCount)
+	} else {
+		root, err = t.deserializeLegacyNodes(data, int(header.EntryCount))
+	}
 	if err != nil {
 		return err
 	}
 
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	t.root = root
 	t.clearDirty()
 	return nil
 }
 
-// collectAllNodes collects all nodes in BFS order.
-func (t *RadixTree) collectAllNodes() []*Node {
-	if t.root == nil {
-		return nil
-	}
+// serializeSnapshot encodes the tree in breadth-first order and returns the
+// data and node count.
+// Format:
+//   - Magic ("RDX2")
+//   - Per node, root first:
+//   - ParentIdx (uvarint, omitted for the root)
+//   - KeyLen (uvarint) and Key
+//   - Flags (uint8) - bit 0: HasEntry
+//   - PageID and SlotID (uvarint each, only if HasEntry)
+//
+// Children and subtree counts are not stored; they are rebuilt from the
+// parent indices, which always point to an earlier node.
+func (t *RadixTree) serializeSnapshot() ([]byte, int) {
+	buf := make([]byte, 0, 64*int(t.root.SubtreeCount+1))
+	buf = append(buf, snapshotMagic...)
 
-	nodes := make([]*Node, 0)
 	queue := []*Node{t.root}
+	parents := []int{-1}
+	for i := 0; i < len(queue); i++ {
+		node := queue[i]
+		if parents[i] >= 0 {
+			buf = binary.AppendUvarint(buf, uint64(parents[i]))
+		}
+		buf = binary.AppendUvarint(buf, uint64(len(node.Key)))
+		buf = append(buf, node.Key...)
 
-	for len(queue) > 0 {
-		node := queue[0]
-		queue = queue[1:]
-		nodes = append(nodes, node)
+		if node.HasEntry {
+			buf = append(buf, 0x01)
+			buf = binary.AppendUvarint(buf, uint64(node.PageID))
+			buf = binary.AppendUvarint(buf, uint64(node.SlotID))
+		} else {
+			buf = append(buf, 0x00)
+		}
 
-		// Add children to queue
-		children := node.GetChildren()
-		queue = append(queue, children...)
+		for _, child := range node.GetChildren() {
+			queue = append(queue, child)
+			parents = append(parents, i)
+		}
 	}
 
-	return nodes
+	return buf, len(queue)
 }
 
-// serializeNodes serializes nodes to bytes.
-// Format per node:
-//   - KeyLen (uint16)
-//   - Key ([]byte)
-//   - Flags (uint8) - bit 0: HasEntry
-//   - PageID (uint64)
-//   - SlotID (uint16)
-//   - SubtreeCount (uint32)
-//   - ParentIdx (int32) - index in array, -1 for root
-//   - ChildCount (uint16)
-//   - ChildIndices ([]int32) - indices of children in array
-func (t *RadixTree) serializeNodes(nodes []*Node) ([]byte, error) {
-	// Build node index map
-	nodeIndex := make(map[*Node]int32)
-	for i, node := range nodes {
-		nodeIndex[node] = int32(i)
+// decodeSnapshot rebuilds a tree from serializeSnapshot output.
+// Nodes are allocated in one slice and keys share one string, which keeps
+// startup allocations and per-node overhead low. Leaf nodes keep nil child
+// maps until a child is added.
+func decodeSnapshot(data []byte, count uint64) (*Node, error) {
+	if count == 0 {
+		return NewRootNode(), nil
+	}
+	// Every node takes at least two bytes, which bounds a corrupt count.
+	if count > uint64(len(data)) {
+		return nil, ErrCacheCorrupt
 	}
 
-	var buf bytes.Buffer
+	nodes := make([]Node, count)
+	parents := make([]uint32, count)
+	keyEnds := make([]uint32, count)
+	childCounts := make([]uint32, count)
 
-	// Write node count
-	if err := binary.Write(&buf, binary.LittleEndian, uint32(len(nodes))); err != nil {
-		return nil, err
-	}
+	var keys bytes.Buffer
+	keys.Grow(len(data))
 
-	for _, node := range nodes {
-		// Key length and key
-		keyBytes := []byte(node.Key)
-		if err := binary.Write(&buf, binary.LittleEndian, uint16(len(keyBytes))); err != nil {
-			return nil, err
-		}
-		if _, err := buf.Write(keyBytes); err != nil {
-			return nil, err
+	pos := 0
+	readUvarint := func() (uint64, bool) {
+		v, n := binary.Uvarint(data[pos:])
+		if n <= 0 {
+			return 0, false
 		}
+		pos += n
+		return v, true
+	}
 
-		// Flags
-		flags := uint8(0)
-		if node.HasEntry {
-			flags |= 0x01
+	for i := range nodes {
+		if i > 0 {
+			parent, ok := readUvarint()
+			if !ok || parent >= uint64(i) {
+				return nil, ErrCacheCorrupt
+			}
+			parents[i] = uint32(parent)
+			childCounts[parent]++
 		}
-		if err := buf.WriteByte(flags); err != nil {
-			return nil, err
+
+		keyLen, ok := readUvarint()
+		if !ok || keyLen > uint64(len(data)-pos) {
+			return nil, ErrCacheCorrupt
 		}
+		keys.Write(data[pos : pos+int(keyLen)])
+		keyEnds[i] = uint32(keys.Len())
+		pos += int(keyLen)
 
-		// PageID
-		if err := binary.Write(&buf, binary.LittleEndian, uint64(node.PageID)); err != nil {
-			return nil, err
+		if pos >= len(data) {
+			return nil, ErrCacheCorrupt
 		}
+		flags := data[pos]
+		pos++
 
-		// SlotID
-		if err := binary.Write(&buf, binary.LittleEndian, node.SlotID); err != nil {
-			return nil, err
+		if flags&0x01 != 0 {
+			pageID, ok := readUvarint()
+			if !ok {
+				return nil, ErrCacheCorrupt
+			}
+			slotID, ok := readUvarint()
+			if !ok || slotID > 0xFFFF {
+				return nil, ErrCacheCorrupt
+			}
+			nodes[i].HasEntry = true
+			nodes[i].PageID = storage.PageID(pageID)
+			nodes[i].SlotID = uint16(slotID)
 		}
+	}
+	if pos != len(data) {
+		return nil, ErrCacheCorrupt
+	}
 
-		// SubtreeCount
-		if err := binary.Write(&buf, binary.LittleEndian, node.SubtreeCount); err != nil {
-			return nil, err
+	allKeys := keys.String()
+	start := uint32(0)
+	for i := range nodes {
+		nodes[i].Key = allKeys[start:keyEnds[i]]
+		start = keyEnds[i]
+		if childCounts[i] > 0 {
+			nodes[i].Children = make(map[byte]*Node)
+			nodes[i].ChildrenByKey = make(map[string]*Node, childCounts[i])
 		}
+	}
 
-		// ParentIdx
-		parentIdx := int32(-1)
-		if node.Parent != nil {
-			if idx, ok := nodeIndex[node.Parent]; ok {
-				parentIdx = idx
-			}
+	// Link children to parents, then sum subtree counts bottom-up.
+	for i := 1; i < len(nodes); i++ {
+		node := &nodes[i]
+		parent := &nodes[parents[i]]
+		node.Parent = parent
+		if len(node.Key) == 0 {
+			continue
 		}
-		if err := binary.Write(&buf, binary.LittleEndian, parentIdx); err != nil {
-			return nil, err
+		if _, exists := parent.Children[node.Key[0]]; !exists {
+			parent.Children[node.Key[0]] = node
 		}
-
-		// Children
-		children := node.GetChildren()
-		if err := binary.Write(&buf, binary.LittleEndian, uint16(len(children))); err != nil {
-			return nil, err
+		parent.ChildrenByKey[node.Key] = node
+	}
+	for i := len(nodes) - 1; i >= 0; i-- {
+		if nodes[i].HasEntry {
+			nodes[i].SubtreeCount++
 		}
-
-		for _, child := range children {
-			childIdx := int32(-1)
-			if idx, ok := nodeIndex[child]; ok {
-				childIdx = idx
-			}
-			if err := binary.Write(&buf, binary.LittleEndian, childIdx); err != nil {
-				return nil, err
-			}
+		if i > 0 {
+			nodes[parents[i]].SubtreeCount += nodes[i].SubtreeCount
 		}
 	}
 
-	return buf.Bytes(), nil
+	root := &nodes[0]
+	if root.Children == nil {
+		root.ensureChildMaps()
+	}
+	return root, nil
 }
 
-// deserializeNodes deserializes nodes from bytes.
-func (t *RadixTree) deserializeNodes(data []byte, expectedCount int) (*Node, error) {
+// deserializeLegacyNodes deserializes nodes written in the original cache
+// format, which stored child indices explicitly and limited nodes to 65535
+// children.
+func (t *RadixTree) deserializeLegacyNodes(data []byte, expectedCount int) (*Node, error) {
 	if len(data) < 4 {
 		return nil, ErrCacheCorrupt
 	}
@@ -1,11 +1,15 @@
 package radix
 
 import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/KilimcininKorOglu/oba/internal/storage"
+	"github.com/KilimcininKorOglu/oba/internal/storage/cache"
 )
 
 func TestRadixCacheSaveLoad(t *testing.T) {
@@ -198,3 +202,193 @@ func TestRadixCacheLargeTree(t *testing.T) {
 		t.Errorf("entry count mismatch: expected %d, got %d", tree.EntryCount(), tree2.EntryCount())
 	}
 }
+
+func TestRadixCacheWideNode(t *testing.T) {
+	dir := t.TempDir()
+
+	pm, err := storage.OpenPageManager(filepath.Join(dir, "data.oba"), storage.Options{
+		PageSize:    4096,
+		CreateIfNew: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create page manager: %v", err)
+	}
+	defer pm.Close()
+
+	tree, err := NewRadixTree(pm)
+	if err != nil {
+		t.Fatalf("failed to create radix tree: %v", err)
+	}
+
+	// More children than the legacy format could represent
+	const count = 70000
+	for i := 0; i < count; i++ {
+		dn := fmt.Sprintf("uid=user%d,ou=users,dc=example,dc=com", i)
+		if err := tree.Insert(dn, storage.PageID(i+1), uint16(i%100)); err != nil {
+			t.Fatalf("failed to insert %s: %v", dn, err)
+		}
+	}
+
+	cachePath := filepath.Join(dir, "radix.cache")
+	if err := tree.SaveCache(cachePath, 1); err != nil {
+		t.Fatalf("failed to save cache: %v", err)
+	}
+
+	tree2, _ := NewRadixTree(pm)
+	if err := tree2.LoadCache(cachePath, 1); err != nil {
+		t.Fatalf("failed to load cache: %v", err)
+	}
+
+	if tree2.EntryCount() != count {
+		t.Errorf("entry count mismatch: expected %d, got %d", count, tree2.EntryCount())
+	}
+	pageID, slotID, found := tree2.Lookup("uid=user69999,ou=users,dc=example,dc=com")
+	if !found || pageID != 70000 || slotID != 99 {
+		t.Errorf("lookup after load: found=%v page=%d slot=%d", found, pageID, slotID)
+	}
+
+	// Loaded leaves accept children and deletions
+	if err := tree2.Insert("cn=device,uid=user1,ou=users,dc=example,dc=com", 1, 0); err != nil {
+		t.Fatalf("failed to insert under loaded leaf: %v", err)
+	}
+	if err := tree2.Delete("uid=user2,ou=users,dc=example,dc=com"); err != nil {
+		t.Fatalf("failed to delete loaded entry: %v", err)
+	}
+	if tree2.EntryCount() != count {
+		t.Errorf("entry count after changes: expected %d, got %d", count, tree2.EntryCount())
+	}
+}
+
+func TestRadixCacheLegacyFormat(t *testing.T) {
+	dir := t.TempDir()
+
+	pm, err := storage.OpenPageManager(filepath.Join(dir, "data.oba"), storage.Options{
+		PageSize:    4096,
+		CreateIfNew: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create page manager: %v", err)
+	}
+	defer pm.Close()
+
+	// Root with a single "dc=com" entry child, in the original layout
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint32(2))
+	writeNode := func(key string, hasEntry bool, pageID uint64, parent int32, children []int32) {
+		binary.Write(&buf, binary.LittleEndian, uint16(len(key)))
+		buf.WriteString(key)
+		flags := uint8(0)
+		if hasEntry {
+			flags = 1
+		}
+		buf.WriteByte(flags)
+		binary.Write(&buf, binary.LittleEndian, pageID)
+		binary.Write(&buf, binary.LittleEndian, uint16(0))
+		binary.Write(&buf, binary.LittleEndian, uint32(0))
+		binary.Write(&buf, binary.LittleEndian, parent)
+		binary.Write(&buf, binary.LittleEndian, uint16(len(children)))
+		for _, c := range children {
+			binary.Write(&buf, binary.LittleEndian, c)
+		}
+	}
+	writeNode("", false, 0, -1, []int32{1})
+	writeNode("dc=com", true, 7, 0, nil)
+
+	cachePath := filepath.Join(dir, "radix.cache")
+	if err := cache.WriteFile(cachePath, cache.TypeRadix, buf.Bytes(), 2, 5); err != nil {
+		t.Fatalf("failed to write cache: %v", err)
+	}
+
+	tree, _ := NewRadixTree(pm)
+	if err := tree.LoadCache(cachePath, 5); err != nil {
+		t.Fatalf("failed to load legacy cache: %v", err)
+	}
+	if pageID, _, found := tree.Lookup("dc=com"); !found || pageID != 7 {
+		t.Errorf("legacy lookup: found=%v page=%d", found, pageID)
+	}
+}
+
+func TestRadixSnapshotReplayWAL(t *testing.T) {
+	dir := t.TempDir()
+
+	pm, err := storage.OpenPageManager(filepath.Join(dir, "data.oba"), storage.Options{
+		PageSize:    4096,
+		CreateIfNew: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create page manager: %v", err)
+	}
+	defer pm.Close()
+
+	wal, err := storage.OpenWAL(filepath.Join(dir, "wal.oba"))
+	if err != nil {
+		t.Fatalf("failed to open WAL: %v", err)
+	}
+	defer wal.Close()
+
+	tree, _ := NewRadixTree(pm)
+	tree.Insert("dc=com", 1, 0)
+	tree.Insert("dc=example,dc=com", 2, 0)
+
+	cachePath := filepath.Join(dir, "radix.cache")
+	snapshotLSN := wal.CurrentLSN()
+	if err := tree.SaveCache(cachePath, snapshotLSN); err != nil {
+		t.Fatalf("failed to save cache: %v", err)
+	}
+
+	appendRecords := func(records ...*storage.WALRecord) {
+		for _, r := range records {
+			if _, err := wal.Append(r); err != nil {
+				t.Fatalf("failed to append WAL record: %v", err)
+			}
+		}
+	}
+	appendRecords(
+		storage.NewWALRecord(0, 1, storage.WALBegin),
+		NewInsertRecord(1, "ou=users,dc=example,dc=com", 3, 4),
+		NewDeleteRecord(1, "dc=com"),
+		NewUpdateRecord(1, "dc=example,dc=com", 8, 1),
+		storage.NewWALRecord(0, 1, storage.WALCommit),
+		storage.NewWALRecord(0, 2, storage.WALBegin),
+		NewInsertRecord(2, "ou=aborted,dc=example,dc=com", 5, 0),
+		storage.NewWALRecord(0, 2, storage.WALAbort),
+		NewInsertRecord(3, "ou=pending,dc=example,dc=com", 6, 0),
+	)
+
+	// A snapshot newer than the WAL is rejected
+	tree2, _ := NewRadixTree(pm)
+	if _, err := tree2.LoadSnapshot(cachePath, snapshotLSN-1); err == nil {
+		t.Error("expected error for snapshot ahead of the WAL")
+	}
+
+	txID, err := tree2.LoadSnapshot(cachePath, wal.CurrentLSN())
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	if txID != snapshotLSN {
+		t.Errorf("snapshot txID = %d, want %d", txID, snapshotLSN)
+	}
+
+	applied, err := tree2.ReplayWAL(wal, txID)
+	if err != nil {
+		t.Fatalf("failed to replay WAL: %v", err)
+	}
+	if applied != 3 {
+		t.Errorf("applied = %d, want 3", applied)
+	}
+
+	if pageID, slotID, found := tree2.Lookup("ou=users,dc=example,dc=com"); !found || pageID != 3 || slotID != 4 {
+		t.Errorf("replayed insert: found=%v page=%d slot=%d", found, pageID, slotID)
+	}
+	if _, _, found := tree2.Lookup("dc=com"); found {
+		t.Error("replayed delete left dc=com in the tree")
+	}
+	if pageID, slotID, found := tree2.Lookup("dc=example,dc=com"); !found || pageID != 8 || slotID != 1 {
+		t.Errorf("replayed update: found=%v page=%d slot=%d", found, pageID, slotID)
+	}
+	for _, dn := range []string{"ou=aborted,dc=example,dc=com", "ou=pending,dc=example,dc=com"} {
+		if _, _, found := tree2.Lookup(dn); found {
+			t.Errorf("%s should not be replayed", dn)
+		}
+	}
+}
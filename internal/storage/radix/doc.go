@@ -42,6 +42,22 @@
 //	    pageID, slotID := iter.Location()
 //	}
 //
+// # Persistence
+//
+// Small trees fit in their DN index page. Larger trees are restored at
+// startup from a snapshot in the cache directory, written at checkpoint and
+// close time. DN inserts and deletes are also logged to the WAL as WALDNIndex
+// records, so an older snapshot is brought up to date with ReplayWAL instead
+// of being discarded:
+//
+//	txID, err := tree.LoadSnapshot(path, wal.CurrentLSN())
+//	if err == nil {
+//	    tree.ReplayWAL(wal, txID)
+//	}
+//
+// Snapshot nodes are decoded into one allocation with shared key storage,
+// and leaf nodes have no child maps until a child is added.
+//
 // # DN Parsing
 //
 // The package includes utilities for parsing and normalizing DNs:
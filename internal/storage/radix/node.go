@@ -111,14 +111,17 @@ func (n *Node) AddChild(child *Node) {
 	}
 	firstByte := child.Key[0]
 	child.Parent = n
+	n.ensureChildMaps()
+
+	// A replaced child no longer contributes to the subtree count
+	if previous, exists := n.ChildrenByKey[child.Key]; exists {
+		n.subtractSubtreeCount(previous.SubtreeCount)
+	}
 
 	// Check if there's already a child with the same first byte
 	existing, exists := n.Children[firstByte]
 	if exists && existing.Key != child.Key {
 		// Multiple children with same first byte - use ChildrenByKey
-		if n.ChildrenByKey == nil {
-			n.ChildrenByKey = make(map[string]*Node)
-		}
 		// Move existing to ChildrenByKey if not already there
 		if _, inByKey := n.ChildrenByKey[existing.Key]; !inByKey {
 			n.ChildrenByKey[existing.Key] = existing
@@ -127,12 +130,12 @@ func (n *Node) AddChild(child *Node) {
 	} else {
 		n.Children[firstByte] = child
 		// Also add to ChildrenByKey for consistency
-		if n.ChildrenByKey == nil {
-			n.ChildrenByKey = make(map[string]*Node)
-		}
 		n.ChildrenByKey[child.Key] = child
 	}
-	n.updateSubtreeCount()
+
+	// Adjust incrementally so that adding to a node with many children
+	// does not rescan all siblings.
+	n.SubtreeCount += child.SubtreeCount
 }
 
 // RemoveChild removes a child node from this node.
@@ -160,7 +163,7 @@ func (n *Node) RemoveChild(key string) *Node {
 				}
 			}
 
-			n.updateSubtreeCount()
+			n.subtractSubtreeCount(child.SubtreeCount)
 			return child
 		}
 	}
@@ -173,10 +176,30 @@ func (n *Node) RemoveChild(key string) *Node {
 	}
 	delete(n.Children, firstByte)
 	child.Parent = nil
-	n.updateSubtreeCount()
+	n.subtractSubtreeCount(child.SubtreeCount)
 	return child
 }
 
+// ensureChildMaps allocates the child maps. Leaf nodes loaded from a
+// snapshot leave them nil to save memory.
+func (n *Node) ensureChildMaps() {
+	if n.Children == nil {
+		n.Children = make(map[byte]*Node)
+	}
+	if n.ChildrenByKey == nil {
+		n.ChildrenByKey = make(map[string]*Node)
+	}
+}
+
+// subtractSubtreeCount lowers the subtree count without wrapping below zero.
+func (n *Node) subtractSubtreeCount(count uint32) {
+	if n.SubtreeCount >= count {
+		n.SubtreeCount -= count
+	} else {
+		n.SubtreeCount = 0
+	}
+}
+
 // GetChild returns the child node with the given key, or nil if not found.
 func (n *Node) GetChild(key string) *Node {
 	if len(key) == 0 {
@@ -246,25 +269,6 @@ func (n *Node) ChildCount() int {
 	return len(n.Children)
 }
 
-// updateSubtreeCount recalculates the subtree count based on children.
-func (n *Node) updateSubtreeCount() {
-	count := uint32(0)
-	if n.HasEntry {
-		count = 1
-	}
-	// Use ChildrenByKey if available for accurate count
-	if n.ChildrenByKey != nil && len(n.ChildrenByKey) > 0 {
-		for _, child := range n.ChildrenByKey {
-			count += child.SubtreeCount
-		}
-	} else {
-		for _, child := range n.Children {
-			count += child.SubtreeCount
-		}
-	}
-	n.SubtreeCount = count
-}
-
 // RecalculateSubtreeCount recursively recalculates subtree counts.
 func (n *Node) RecalculateSubtreeCount() uint32 {
 	count := uint32(0)
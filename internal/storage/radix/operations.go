@@ -414,6 +414,46 @@ func (t *RadixTree) IterateOneLevelChildren(baseDN string, iterator EntryIterato
 	return nil
 }
 
+// CountChildren returns the number of direct children of the given DN that
+// hold an entry. Counting stops at limit; a limit of zero or less counts all
+// children.
+func (t *RadixTree) CountChildren(dn string, limit int) (int, error) {
+	components, err := ParseDN(dn)
+	if err != nil {
+		return 0, err
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	node := t.findNode(components)
+	if node == nil {
+		return 0, nil
+	}
+
+	count := 0
+	if len(node.ChildrenByKey) > 0 {
+		for _, child := range node.ChildrenByKey {
+			if child.HasEntry {
+				count++
+				if count == limit {
+					break
+				}
+			}
+		}
+	} else {
+		for _, child := range node.Children {
+			if child.HasEntry {
+				count++
+				if count == limit {
+					break
+				}
+			}
+		}
+	}
+	return count, nil
+}
+
 // HasChildren returns true if the given DN has any children.
 func (t *RadixTree) HasChildren(dn string) (bool, error) {
 	components, err := ParseDN(dn)
@@ -9,7 +9,7 @@ import (
 )
 
 // Helper function to create a temporary page manager for testing.
-func createTestPageManager(t *testing.T) (*storage.PageManager, func()) {
+func createTestPageManager(t testing.TB) (*storage.PageManager, func()) {
 	t.Helper()
 
 	tmpDir, err := os.MkdirTemp("", "radix_test_*")
@@ -894,6 +894,52 @@ func TestRadixTreeHasChildren(t *testing.T) {
 	}
 }
 
+func TestRadixTreeCountChildren(t *testing.T) {
+	pm, cleanup := createTestPageManager(t)
+	defer cleanup()
+
+	tree, err := NewRadixTree(pm)
+	if err != nil {
+		t.Fatalf("failed to create radix tree: %v", err)
+	}
+
+	dns := []string{
+		"ou=users,dc=example,dc=com",
+		"uid=alice,ou=users,dc=example,dc=com",
+		"uid=bob,ou=users,dc=example,dc=com",
+		"uid=carol,ou=users,dc=example,dc=com",
+		"cn=phone,uid=alice,ou=users,dc=example,dc=com",
+	}
+	for i, dn := range dns {
+		if err := tree.Insert(dn, storage.PageID(i+1), 1); err != nil {
+			t.Fatalf("failed to insert %s: %v", dn, err)
+		}
+	}
+
+	tests := []struct {
+		dn    string
+		limit int
+		want  int
+	}{
+		{"ou=users,dc=example,dc=com", 0, 3},
+		{"ou=users,dc=example,dc=com", 10, 3},
+		{"ou=users,dc=example,dc=com", 2, 2},
+		{"ou=users,dc=example,dc=com", 1, 1},
+		{"uid=alice,ou=users,dc=example,dc=com", 0, 1},
+		{"uid=bob,ou=users,dc=example,dc=com", 0, 0},
+		{"ou=missing,dc=example,dc=com", 0, 0},
+	}
+	for _, tt := range tests {
+		got, err := tree.CountChildren(tt.dn, tt.limit)
+		if err != nil {
+			t.Fatalf("CountChildren(%q, %d) error: %v", tt.dn, tt.limit, err)
+		}
+		if got != tt.want {
+			t.Errorf("CountChildren(%q, %d) = %d, want %d", tt.dn, tt.limit, got, tt.want)
+		}
+	}
+}
+
 func TestRadixTreeGetParent(t *testing.T) {
 	pm, cleanup := createTestPageManager(t)
 	defer cleanup()
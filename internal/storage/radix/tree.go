@@ -105,6 +105,13 @@ func (t *RadixTree) loadRoot() error {
 
 // persistRoot persists the root node to the root page.
 func (t *RadixTree) persistRoot() error {
+	// Every entry needs at least one node, so a tree with more entries than
+	// fit in a page cannot be persisted. Bail out before walking the tree to
+	// keep inserts into large trees O(k).
+	if t.root != nil && t.root.SubtreeCount > MaxNodesPerPage {
+		return ErrTooManyNodes
+	}
+
 	buf, err := SerializeToPage(t.root, t.rootPageID)
 	if err != nil {
 		return err
@@ -0,0 +1,113 @@
+package radix
+
+import (
+	"errors"
+
+	"github.com/KilimcininKorOglu/oba/internal/storage"
+)
+
+// DN change operations stored in the first byte of a WALDNIndex record.
+const (
+	dnOpInsert byte = 1
+	dnOpDelete byte = 2
+	dnOpUpdate byte = 3
+)
+
+// WAL replay errors.
+var (
+	ErrInvalidDNRecord = errors.New("invalid DN index WAL record")
+)
+
+// NewInsertRecord returns the WAL record that logs the insertion of dn at
+// the given location.
+func NewInsertRecord(txID uint64, dn string, pageID storage.PageID, slotID uint16) *storage.WALRecord {
+	record := storage.NewWALRecord(0, txID, storage.WALDNIndex)
+	record.PageID = pageID
+	record.Offset = slotID
+	record.NewData = append([]byte{dnOpInsert}, dn...)
+	return record
+}
+
+// NewUpdateRecord returns the WAL record that logs the move of dn to a new
+// location.
+func NewUpdateRecord(txID uint64, dn string, pageID storage.PageID, slotID uint16) *storage.WALRecord {
+	record := NewInsertRecord(txID, dn, pageID, slotID)
+	record.NewData[0] = dnOpUpdate
+	return record
+}
+
+// NewDeleteRecord returns the WAL record that logs the removal of dn.
+func NewDeleteRecord(txID uint64, dn string) *storage.WALRecord {
+	record := storage.NewWALRecord(0, txID, storage.WALDNIndex)
+	record.NewData = append([]byte{dnOpDelete}, dn...)
+	return record
+}
+
+// ReplayWAL applies the DN changes of committed transactions logged at or
+// after fromLSN. It is used after LoadSnapshot to bring the tree up to date
+// without rebuilding it. Changes already present in the snapshot are skipped.
+// Returns the number of changes applied.
+func (t *RadixTree) ReplayWAL(wal *storage.WAL, fromLSN uint64) (int, error) {
+	pending := make(map[uint64][]*storage.WALRecord)
+	applied := 0
+
+	it := wal.Iterator(fromLSN)
+	for it.Next() {
+		record, err := it.Record()
+		if err != nil {
+			return applied, err
+		}
+
+		switch record.Type {
+		case storage.WALDNIndex:
+			pending[record.TxID] = append(pending[record.TxID], record)
+
+		case storage.WALCommit:
+			for _, change := range pending[record.TxID] {
+				if err := t.applyDNRecord(change); err != nil {
+					return applied, err
+				}
+				applied++
+			}
+			delete(pending, record.TxID)
+
+		case storage.WALAbort:
+			delete(pending, record.TxID)
+		}
+	}
+
+	return applied, it.Error()
+}
+
+// applyDNRecord applies a single WALDNIndex record to the tree.
+func (t *RadixTree) applyDNRecord(record *storage.WALRecord) error {
+	if len(record.NewData) < 2 {
+		return ErrInvalidDNRecord
+	}
+	dn := string(record.NewData[1:])
+
+	switch record.NewData[0] {
+	case dnOpInsert:
+		err := t.Insert(dn, record.PageID, record.Offset)
+		if err != nil && !errors.Is(err, ErrEntryExists) {
+			return err
+		}
+	case dnOpUpdate:
+		err := t.Update(dn, record.PageID, record.Offset)
+		if errors.Is(err, ErrEntryNotFound) {
+			err = t.Insert(dn, record.PageID, record.Offset)
+		}
+		if err != nil {
+			return err
+		}
+	case dnOpDelete:
+		err := t.Delete(dn)
+		if err != nil && !errors.Is(err, ErrEntryNotFound) {
+			return err
+		}
+	default:
+		return ErrInvalidDNRecord
+	}
+
+	return nil
+}
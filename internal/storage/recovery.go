@@ -3,6 +3,7 @@ package storage
 
 import (
 	"errors"
+	"fmt"
 	"sort"
 	"sync"
 )
@@ -247,6 +248,10 @@ func (r *Recovery) redo() error {
 
 		// Redo the update by applying the new data
 		if err := r.redoUpdate(record); err != nil {
+			// A damaged page needs repair before the WAL can be replayed
+			if errors.Is(err, ErrPageCorrupted) {
+				return fmt.Errorf("%w: %w", ErrRecoveryFailed, err)
+			}
 			// Log error but continue - page might not exist yet
 			continue
 		}
@@ -268,6 +273,10 @@ func (r *Recovery) redoUpdate(record *WALRecord) error {
 
 	// Read the page
 	page, err := r.pageManager.ReadPage(record.PageID)
+	if errors.Is(err, ErrPageCorrupted) {
+		// Do not apply the change over a damaged page
+		return fmt.Errorf("LSN %d: %w", record.LSN, err)
+	}
 	if err != nil {
 		// Page doesn't exist, skip
 		return nil
@@ -336,6 +345,9 @@ func (r *Recovery) undo() error {
 	// Undo each record
 	for _, record := range undoRecords {
 		if err := r.undoUpdate(record); err != nil {
+			if errors.Is(err, ErrPageCorrupted) {
+				return fmt.Errorf("%w: %w", ErrRecoveryFailed, err)
+			}
 			// Log error but continue
 			continue
 		}
@@ -369,6 +381,10 @@ func (r *Recovery) undoUpdate(record *WALRecord) error {
 
 	// Read the page
 	page, err := r.pageManager.ReadPage(record.PageID)
+	if errors.Is(err, ErrPageCorrupted) {
+		// Do not apply the change over a damaged page
+		return fmt.Errorf("LSN %d: %w", record.LSN, err)
+	}
 	if err != nil {
 		// Page doesn't exist, skip
 		return nil
@@ -2,8 +2,12 @@
 package storage
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -219,6 +223,67 @@ func TestRecoveryCommittedTransaction(t *testing.T) {
 	}
 }
 
+// TestRecoveryCorruptedPage tests that recovery stops at a damaged page
+// instead of replaying the WAL onto it.
+func TestRecoveryCorruptedPage(t *testing.T) {
+	tmpDir := t.TempDir()
+	walPath := filepath.Join(tmpDir, "test.wal")
+	dataPath := filepath.Join(tmpDir, "test.db")
+
+	wal, err := OpenWAL(walPath)
+	if err != nil {
+		t.Fatalf("Failed to open WAL: %v", err)
+	}
+	pm, err := OpenPageManager(dataPath, DefaultOptions())
+	if err != nil {
+		wal.Close()
+		t.Fatalf("Failed to open PageManager: %v", err)
+	}
+
+	pageID, err := pm.AllocatePage(PageTypeData)
+	if err != nil {
+		t.Fatalf("Failed to allocate page: %v", err)
+	}
+	for _, record := range []*WALRecord{
+		NewWALRecord(0, 1, WALBegin),
+		NewWALUpdateRecord(0, 1, pageID, 0, []byte("old data"), []byte("new data")),
+		NewWALRecord(0, 1, WALCommit),
+	} {
+		if _, err := wal.Append(record); err != nil {
+			t.Fatalf("Failed to append record: %v", err)
+		}
+	}
+	wal.Sync()
+	wal.Close()
+	pm.Close()
+
+	// Damage the page the update applies to
+	f, err := os.OpenFile(dataPath, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("Failed to open data file: %v", err)
+	}
+	if _, err := f.WriteAt([]byte{0xFF}, int64(pageID)*PageSize+PageHeaderSize+100); err != nil {
+		t.Fatalf("Failed to damage page: %v", err)
+	}
+	f.Close()
+
+	wal, err = OpenWAL(walPath)
+	if err != nil {
+		t.Fatalf("Failed to reopen WAL: %v", err)
+	}
+	defer wal.Close()
+	pm, err = OpenPageManager(dataPath, DefaultOptions())
+	if err != nil {
+		t.Fatalf("Failed to reopen PageManager: %v", err)
+	}
+	defer pm.Close()
+
+	err = NewRecovery(wal, pm).Recover()
+	if !errors.Is(err, ErrRecoveryFailed) || !errors.Is(err, ErrPageCorrupted) {
+		t.Errorf("Recover() error = %v, want ErrRecoveryFailed and ErrPageCorrupted", err)
+	}
+}
+
 // TestRecoveryUncommittedTransaction tests recovery rolls back uncommitted transactions.
 func TestRecoveryUncommittedTransaction(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -1009,6 +1074,117 @@ func TestRecoveryCleanup(t *testing.T) {
 	}
 }
 
+// TestRecoveryAfterWriteBehindCrash tests that pages still held by a
+// write-behind buffer pool at crash time are restored from the WAL.
+func TestRecoveryAfterWriteBehindCrash(t *testing.T) {
+	tmpDir := t.TempDir()
+	walPath := filepath.Join(tmpDir, "test.wal")
+	dataPath := filepath.Join(tmpDir, "test.db")
+
+	wal, err := OpenWAL(walPath)
+	if err != nil {
+		t.Fatalf("Failed to open WAL: %v", err)
+	}
+
+	pm, err := OpenPageManager(dataPath, DefaultOptions())
+	if err != nil {
+		wal.Close()
+		t.Fatalf("Failed to open PageManager: %v", err)
+	}
+
+	// The background writer blocks until the crash, so neither queued
+	// nor cached dirty pages ever reach disk
+	gate := make(chan struct{})
+	var crashed atomic.Bool
+	bp := NewBufferPool(2, PageSize-PageHeaderSize)
+	bp.SetFlushCallback(func(pageID PageID, data []byte) error {
+		<-gate
+		if crashed.Load() {
+			return ErrFileClosed
+		}
+		return pm.WritePage(&Page{Header: PageHeader{PageID: pageID, PageType: PageTypeData}, Data: data})
+	})
+	bp.SetWriteBehind(true, 100)
+	defer func() {
+		close(gate)
+		bp.SetWriteBehind(false, 0)
+	}()
+
+	var pageIDs []PageID
+	for i := 0; i < 3; i++ {
+		pageID, err := pm.AllocatePage(PageTypeData)
+		if err != nil {
+			t.Fatalf("Failed to allocate page: %v", err)
+		}
+		pageIDs = append(pageIDs, pageID)
+
+		page, err := pm.ReadPage(pageID)
+		if err != nil {
+			t.Fatalf("Failed to read page: %v", err)
+		}
+		if _, err := bp.Put(pageID, page.Data); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+
+		txID := uint64(i + 1)
+		newData := []byte(fmt.Sprintf("write-behind %d", i))
+		wal.Append(NewWALRecord(0, txID, WALBegin))
+		wal.Append(NewWALUpdateRecord(0, txID, pageID, 0, make([]byte, len(newData)), newData))
+		wal.Append(NewWALRecord(0, txID, WALCommit))
+
+		cached, _ := bp.Get(pageID)
+		copy(cached.Data(), newData)
+		bp.MarkDirty(pageID)
+	}
+
+	if got := bp.Stats().PendingWrites; got != 1 {
+		t.Errorf("Expected 1 queued page before crash, got %d", got)
+	}
+
+	// Crash: the WAL is durable, the buffer pool is lost
+	wal.Sync()
+	crashed.Store(true)
+	wal.Close()
+	pm.Close()
+
+	wal, err = OpenWAL(walPath)
+	if err != nil {
+		t.Fatalf("Failed to reopen WAL: %v", err)
+	}
+	defer wal.Close()
+
+	pm, err = OpenPageManager(dataPath, DefaultOptions())
+	if err != nil {
+		t.Fatalf("Failed to reopen PageManager: %v", err)
+	}
+	defer pm.Close()
+
+	for i, pageID := range pageIDs {
+		page, err := pm.ReadPage(pageID)
+		if err != nil {
+			t.Fatalf("Failed to read page %d: %v", pageID, err)
+		}
+		if bytes.HasPrefix(page.Data, []byte(fmt.Sprintf("write-behind %d", i))) {
+			t.Fatalf("Page %d was written before the crash", pageID)
+		}
+	}
+
+	if err := NewRecovery(wal, pm).Recover(); err != nil {
+		t.Fatalf("Recovery failed: %v", err)
+	}
+
+	for i, pageID := range pageIDs {
+		page, err := pm.ReadPage(pageID)
+		if err != nil {
+			t.Fatalf("Failed to read page %d: %v", pageID, err)
+		}
+		want := []byte(fmt.Sprintf("write-behind %d", i))
+		if !bytes.HasPrefix(page.Data, want) {
+			t.Errorf("Page %d = %q, want prefix %q", pageID, page.Data[:len(want)], want)
+		}
+	}
+}
+
 // Ensure test files are cleaned up
 func TestMain(m *testing.M) {
 	code := m.Run()
@@ -0,0 +1,365 @@
+// Package storage provides the core storage engine components for ObaDB.
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/KilimcininKorOglu/oba/internal/crypto"
+)
+
+// Recovery tool errors.
+var (
+	ErrInvalidPageRange = errors.New("invalid page range")
+	ErrProtectedPage    = errors.New("page range includes a root page")
+	ErrNoIndexRebuilder = errors.New("index rebuild is not supported")
+)
+
+// RecoveryToolConfig holds the configuration for a RecoveryTool.
+type RecoveryToolConfig struct {
+	// DataPath is the path of the data file.
+	DataPath string
+
+	// WALPath is the path of the write-ahead log.
+	WALPath string
+
+	// PageSize is the page size of the data file. Zero selects the default.
+	PageSize int
+
+	// EncryptionKey decrypts WAL records. Nil if encryption is disabled.
+	EncryptionKey *crypto.EncryptionKey
+}
+
+// WALDumpRecord is the JSON form of a WAL record written by DumpWAL.
+// Records that cannot be decoded carry an Error and no record fields.
+type WALDumpRecord struct {
+	FileOffset int64  `json:"fileOffset"`
+	LSN        uint64 `json:"lsn,omitempty"`
+	TxID       uint64 `json:"txId,omitempty"`
+	Type       string `json:"type,omitempty"`
+	PageID     PageID `json:"pageId,omitempty"`
+	Offset     uint16 `json:"offset,omitempty"`
+	Compressed bool   `json:"compressed,omitempty"`
+	OldData    string `json:"oldData,omitempty"`
+	NewData    string `json:"newData,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// RecoveryTool implements offline inspection and repair actions for a
+// database that cannot be opened. Each action opens the files it needs and
+// closes them before returning, so actions can be run one after another.
+//
+// The tool works at the page and WAL level. Structures built on top of the
+// data file, such as the DN index and attribute indexes, are kept consistent
+// through callbacks set by the storage engine.
+type RecoveryTool struct {
+	config RecoveryToolConfig
+
+	// replay applies logical WAL records before the WAL is truncated.
+	replay func(pm *PageManager, wal *WAL) error
+
+	// prune drops references to pages that are about to be freed.
+	prune func(pm *PageManager, wal *WAL, pages []PageID) error
+
+	// rebuildIndex recreates an attribute index from the primary data.
+	rebuildIndex func(pm *PageManager, wal *WAL, attr string, progress func(IndexRebuildProgress)) (IndexRebuildProgress, error)
+
+	// verify checks the structures stored in the data file.
+	verify func(pm *PageManager, wal *WAL, report *VerificationReport) error
+}
+
+// NewRecoveryTool creates a new RecoveryTool with the given configuration.
+func NewRecoveryTool(config RecoveryToolConfig) *RecoveryTool {
+	return &RecoveryTool{config: config}
+}
+
+// SetReplayCallback sets the callback that ForceCheckpoint runs after
+// page-level recovery, while the WAL still holds the records since the last
+// checkpoint. It is used to fold logical records into on-disk structures.
+func (rt *RecoveryTool) SetReplayCallback(callback func(pm *PageManager, wal *WAL) error) {
+	rt.replay = callback
+}
+
+// SetPruneCallback sets the callback that PrunePages runs with the pages it
+// is about to free, so that references to them can be removed first.
+func (rt *RecoveryTool) SetPruneCallback(callback func(pm *PageManager, wal *WAL, pages []PageID) error) {
+	rt.prune = callback
+}
+
+// SetIndexRebuilder sets the callback that RebuildIndex uses to recreate an
+// attribute index. It reports its progress to progress, which may be nil,
+// and returns the final progress.
+func (rt *RecoveryTool) SetIndexRebuilder(callback func(pm *PageManager, wal *WAL, attr string, progress func(IndexRebuildProgress)) (IndexRebuildProgress, error)) {
+	rt.rebuildIndex = callback
+}
+
+// SetVerifyCallback sets the callback that Verify runs after the page level
+// checks of the data file, to check the structures stored in its pages. It
+// adds its findings to the report.
+func (rt *RecoveryTool) SetVerifyCallback(callback func(pm *PageManager, wal *WAL, report *VerificationReport) error) {
+	rt.verify = callback
+}
+
+// DumpWAL writes each WAL record to w as a line of JSON. Page data is base64
+// encoded. The file is read without modification, so records after a corrupt
+// one are not lost. Dumping stops at the first record that cannot be decoded,
+// which is reported with an error line. Returns the number of valid records.
+func (rt *RecoveryTool) DumpWAL(w io.Writer) (int, error) {
+	file, err := os.Open(rt.config.WALPath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	enc := json.NewEncoder(w)
+	fileSize := info.Size()
+	count := 0
+
+	var offset int64
+	for offset < fileSize {
+		record, recordLen, err := rt.readWALRecord(file, offset, fileSize)
+		if err != nil {
+			if err := enc.Encode(WALDumpRecord{FileOffset: offset, Error: err.Error()}); err != nil {
+				return count, err
+			}
+			break
+		}
+
+		if err := enc.Encode(newWALDumpRecord(offset, record)); err != nil {
+			return count, err
+		}
+
+		count++
+		offset += WALRecordLengthSize + int64(recordLen)
+	}
+
+	return count, nil
+}
+
+// readWALRecord reads and validates the WAL record at offset.
+func (rt *RecoveryTool) readWALRecord(file *os.File, offset, fileSize int64) (*WALRecord, uint32, error) {
+	if fileSize-offset < WALRecordLengthSize {
+		return nil, 0, ErrWALRecordLength
+	}
+
+	lengthBuf := make([]byte, WALRecordLengthSize)
+	if _, err := file.ReadAt(lengthBuf, offset); err != nil {
+		return nil, 0, err
+	}
+
+	recordLen := binary.LittleEndian.Uint32(lengthBuf)
+	if recordLen == 0 || recordLen > uint32(WALBufferSize) ||
+		int64(recordLen) > fileSize-offset-WALRecordLengthSize {
+		return nil, 0, ErrWALRecordLength
+	}
+
+	recordBuf := make([]byte, recordLen)
+	if _, err := file.ReadAt(recordBuf, offset+WALRecordLengthSize); err != nil {
+		return nil, 0, err
+	}
+
+	if rt.config.EncryptionKey != nil {
+		decrypted, err := rt.config.EncryptionKey.Decrypt(recordBuf)
+		if err != nil {
+			return nil, 0, err
+		}
+		recordBuf = decrypted
+	}
+
+	record := &WALRecord{}
+	if err := record.DeserializeAndValidate(recordBuf); err != nil {
+		return nil, 0, err
+	}
+
+	return record, recordLen, nil
+}
+
+// newWALDumpRecord converts a WAL record to its JSON form.
+func newWALDumpRecord(offset int64, record *WALRecord) WALDumpRecord {
+	dump := WALDumpRecord{
+		FileOffset: offset,
+		LSN:        record.LSN,
+		TxID:       record.TxID,
+		Type:       record.Type.String(),
+		PageID:     record.PageID,
+		Offset:     record.Offset,
+		Compressed: record.Compressed,
+	}
+	if len(record.OldData) > 0 {
+		dump.OldData = base64.StdEncoding.EncodeToString(record.OldData)
+	}
+	if len(record.NewData) > 0 {
+		dump.NewData = base64.StdEncoding.EncodeToString(record.NewData)
+	}
+	return dump
+}
+
+// ForceCheckpoint replays the WAL into the data file, writes a checkpoint
+// and truncates the WAL. Records after the first corrupt one are discarded.
+// Committed changes are redone and uncommitted ones undone, leaving a clean
+// data file and an empty WAL.
+func (rt *RecoveryTool) ForceCheckpoint() (err error) {
+	pm, wal, err := rt.open()
+	if err != nil {
+		return err
+	}
+	defer rt.close(pm, wal, &err)
+
+	if err := NewRecovery(wal, pm).Recover(); err != nil {
+		return err
+	}
+
+	if rt.replay != nil {
+		if err := rt.replay(pm, wal); err != nil {
+			return err
+		}
+	}
+
+	cm := NewCheckpointManager(wal, pm)
+	if err := cm.Checkpoint(); err != nil {
+		return err
+	}
+
+	return cm.TruncateWAL()
+}
+
+// PrunePages frees the pages from through to inclusive, dropping whatever
+// they hold. It is used when specific pages are physically damaged. The
+// header page and the root pages recorded in the file header cannot be
+// pruned. Pages that are already free are skipped. Returns the number of
+// pages freed.
+func (rt *RecoveryTool) PrunePages(from, to PageID) (pruned int, err error) {
+	pm, wal, err := rt.open()
+	if err != nil {
+		return 0, err
+	}
+	defer rt.close(pm, wal, &err)
+
+	if from == 0 || from > to || uint64(to) >= pm.TotalPages() {
+		return 0, ErrInvalidPageRange
+	}
+
+	header := pm.Header()
+	for _, root := range []PageID{header.RootPages.DNIndex, header.RootPages.DataRoot} {
+		if root != 0 && root >= from && root <= to {
+			return 0, ErrProtectedPage
+		}
+	}
+
+	var pages []PageID
+	for id := from; id <= to; id++ {
+		if !pm.isFree(id) {
+			pages = append(pages, id)
+		}
+	}
+
+	if len(pages) == 0 {
+		return 0, nil
+	}
+
+	if rt.prune != nil {
+		if err := rt.prune(pm, wal, pages); err != nil {
+			return 0, err
+		}
+	}
+
+	for _, id := range pages {
+		// Freeing overwrites the page, which repairs it if quarantined
+		pm.releaseQuarantine(id)
+		if err := pm.FreePage(id); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(pages), nil
+}
+
+// RebuildIndex drops the index for attr and recreates it from the primary
+// data. Returns the number of entries indexed.
+func (rt *RecoveryTool) RebuildIndex(attr string) (int, error) {
+	p, err := rt.RebuildIndexWithProgress(attr, nil)
+	return p.EntriesIndexed, err
+}
+
+// RebuildIndexWithProgress is like RebuildIndex, but calls progress, if not
+// nil, as the entries are scanned. The new index is written to fresh pages
+// and replaces the old one only once complete.
+func (rt *RecoveryTool) RebuildIndexWithProgress(attr string, progress func(IndexRebuildProgress)) (p IndexRebuildProgress, err error) {
+	if rt.rebuildIndex == nil {
+		return p, ErrNoIndexRebuilder
+	}
+
+	pm, wal, err := rt.open()
+	if err != nil {
+		return p, err
+	}
+	defer rt.close(pm, wal, &err)
+
+	return rt.rebuildIndex(pm, wal, attr, progress)
+}
+
+// Verify checks the integrity of the data file without modifying it, then
+// runs the verify callback. The file is opened read-only, so its free list
+// chain is checked as it is on disk. An error is returned only if the check
+// could not run; the problems found are in the report.
+func (rt *RecoveryTool) Verify() (*VerificationReport, error) {
+	pm, err := OpenPageManager(rt.config.DataPath, Options{
+		PageSize: rt.config.PageSize,
+		ReadOnly: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer pm.Close()
+
+	report := pm.Verify()
+	if rt.verify == nil {
+		return report, nil
+	}
+
+	wal, err := OpenWALWithEncryption(rt.config.WALPath, rt.config.EncryptionKey)
+	if err != nil {
+		return report, err
+	}
+	defer wal.Close()
+
+	return report, rt.verify(pm, wal, report)
+}
+
+// open opens the data file and the WAL for a repair action.
+func (rt *RecoveryTool) open() (*PageManager, *WAL, error) {
+	pm, err := OpenPageManager(rt.config.DataPath, Options{
+		PageSize:    rt.config.PageSize,
+		CreateIfNew: false,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wal, err := OpenWALWithEncryption(rt.config.WALPath, rt.config.EncryptionKey)
+	if err != nil {
+		pm.Close()
+		return nil, nil, err
+	}
+
+	return pm, wal, nil
+}
+
+// close syncs and closes the files opened by open. The first failure is
+// stored in errp unless the action already failed.
+func (rt *RecoveryTool) close(pm *PageManager, wal *WAL, errp *error) {
+	for _, fn := range []func() error{wal.Sync, wal.Close, pm.Sync, pm.Close} {
+		if err := fn(); err != nil && *errp == nil {
+			*errp = err
+		}
+	}
+}
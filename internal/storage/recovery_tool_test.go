@@ -0,0 +1,326 @@
+// Package storage provides the core storage engine components for ObaDB.
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// newRecoveryToolFixture creates a data file with n data pages and a WAL,
+// returning a RecoveryTool for them and the allocated page IDs in order.
+func newRecoveryToolFixture(t *testing.T, n int) (*RecoveryTool, []PageID) {
+	t.Helper()
+
+	dir := t.TempDir()
+	dataPath := filepath.Join(dir, "data.oba")
+	walPath := filepath.Join(dir, "wal.oba")
+
+	pm, err := OpenPageManager(dataPath, DefaultOptions())
+	if err != nil {
+		t.Fatalf("Failed to open PageManager: %v", err)
+	}
+
+	pages := make([]PageID, n)
+	for i := range pages {
+		pages[i], err = pm.AllocatePage(PageTypeData)
+		if err != nil {
+			t.Fatalf("Failed to allocate page: %v", err)
+		}
+	}
+	if err := pm.Close(); err != nil {
+		t.Fatalf("Failed to close PageManager: %v", err)
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i] < pages[j] })
+
+	wal, err := OpenWAL(walPath)
+	if err != nil {
+		t.Fatalf("Failed to open WAL: %v", err)
+	}
+	if err := wal.Close(); err != nil {
+		t.Fatalf("Failed to close WAL: %v", err)
+	}
+
+	return NewRecoveryTool(RecoveryToolConfig{DataPath: dataPath, WALPath: walPath}), pages
+}
+
+// appendWALRecords appends records to the WAL of the tool.
+func appendWALRecords(t *testing.T, rt *RecoveryTool, records ...*WALRecord) {
+	t.Helper()
+
+	wal, err := OpenWAL(rt.config.WALPath)
+	if err != nil {
+		t.Fatalf("Failed to open WAL: %v", err)
+	}
+	for _, record := range records {
+		if _, err := wal.Append(record); err != nil {
+			t.Fatalf("Failed to append record: %v", err)
+		}
+	}
+	if err := wal.Close(); err != nil {
+		t.Fatalf("Failed to close WAL: %v", err)
+	}
+}
+
+// corruptWALTail appends a record frame with a bad checksum to the WAL.
+func corruptWALTail(t *testing.T, rt *RecoveryTool) {
+	t.Helper()
+
+	f, err := os.OpenFile(rt.config.WALPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open WAL file: %v", err)
+	}
+	defer f.Close()
+
+	frame := []byte{40, 0, 0, 0}
+	frame = append(frame, bytes.Repeat([]byte{0xAB}, 40)...)
+	if _, err := f.Write(frame); err != nil {
+		t.Fatalf("Failed to corrupt WAL: %v", err)
+	}
+}
+
+func TestRecoveryToolDumpWAL(t *testing.T) {
+	rt, pages := newRecoveryToolFixture(t, 1)
+
+	appendWALRecords(t, rt,
+		NewWALRecord(0, 7, WALBegin),
+		NewWALUpdateRecord(0, 7, pages[0], 16, []byte("old"), []byte("new")),
+		NewWALRecord(0, 7, WALCommit),
+	)
+	corruptWALTail(t, rt)
+
+	before, err := os.Stat(rt.config.WALPath)
+	if err != nil {
+		t.Fatalf("Failed to stat WAL: %v", err)
+	}
+
+	var out bytes.Buffer
+	count, err := rt.DumpWAL(&out)
+	if err != nil {
+		t.Fatalf("DumpWAL failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 records, got %d", count)
+	}
+
+	var dumped []WALDumpRecord
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var record WALDumpRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("Invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		dumped = append(dumped, record)
+	}
+
+	if len(dumped) != 4 {
+		t.Fatalf("Expected 3 records and an error line, got %d lines", len(dumped))
+	}
+
+	wantTypes := []string{"Begin", "Update", "Commit"}
+	for i, want := range wantTypes {
+		if dumped[i].Type != want || dumped[i].TxID != 7 || dumped[i].LSN != uint64(i+1) {
+			t.Errorf("Record %d = %+v, want type %s, txId 7, lsn %d", i, dumped[i], want, i+1)
+		}
+	}
+
+	update := dumped[1]
+	if update.PageID != pages[0] || update.Offset != 16 {
+		t.Errorf("Update location = %d/%d, want %d/16", update.PageID, update.Offset, pages[0])
+	}
+	if update.OldData != "b2xk" || update.NewData != "bmV3" {
+		t.Errorf("Update data = %q/%q, want base64 of old/new", update.OldData, update.NewData)
+	}
+
+	if dumped[3].Error == "" {
+		t.Error("Expected an error line for the corrupt record")
+	}
+
+	// The dump must not truncate the corrupt tail
+	after, err := os.Stat(rt.config.WALPath)
+	if err != nil {
+		t.Fatalf("Failed to stat WAL: %v", err)
+	}
+	if after.Size() != before.Size() {
+		t.Errorf("WAL size changed from %d to %d", before.Size(), after.Size())
+	}
+}
+
+func TestRecoveryToolForceCheckpoint(t *testing.T) {
+	rt, pages := newRecoveryToolFixture(t, 2)
+
+	// Transaction 1 committed but its page write was lost; transaction 2
+	// never committed but its page write reached disk.
+	appendWALRecords(t, rt,
+		NewWALRecord(0, 1, WALBegin),
+		NewWALUpdateRecord(0, 1, pages[0], 0, []byte("old data"), []byte("new data")),
+		NewWALRecord(0, 1, WALCommit),
+		NewWALRecord(0, 2, WALBegin),
+		NewWALUpdateRecord(0, 2, pages[1], 0, []byte("original"), []byte("uncommit")),
+	)
+	corruptWALTail(t, rt)
+
+	pm, err := OpenPageManager(rt.config.DataPath, DefaultOptions())
+	if err != nil {
+		t.Fatalf("Failed to open PageManager: %v", err)
+	}
+	page, _ := pm.ReadPage(pages[1])
+	copy(page.Data, "uncommit")
+	if err := pm.WritePage(page); err != nil {
+		t.Fatalf("Failed to write page: %v", err)
+	}
+	pm.Close()
+
+	replayed := false
+	rt.SetReplayCallback(func(pm *PageManager, wal *WAL) error {
+		replayed = true
+		if wal.CurrentLSN() < 6 {
+			t.Errorf("Replay saw WAL at LSN %d, want the records before the corrupt tail", wal.CurrentLSN())
+		}
+		return nil
+	})
+
+	if err := rt.ForceCheckpoint(); err != nil {
+		t.Fatalf("ForceCheckpoint failed: %v", err)
+	}
+	if !replayed {
+		t.Error("Replay callback was not called")
+	}
+
+	pm, err = OpenPageManager(rt.config.DataPath, DefaultOptions())
+	if err != nil {
+		t.Fatalf("Failed to reopen PageManager: %v", err)
+	}
+	defer pm.Close()
+
+	page, _ = pm.ReadPage(pages[0])
+	if got := string(page.Data[:8]); got != "new data" {
+		t.Errorf("Committed page = %q, want %q", got, "new data")
+	}
+	page, _ = pm.ReadPage(pages[1])
+	if got := string(page.Data[:8]); got != "original" {
+		t.Errorf("Uncommitted page = %q, want %q", got, "original")
+	}
+
+	info, err := os.Stat(rt.config.WALPath)
+	if err != nil {
+		t.Fatalf("Failed to stat WAL: %v", err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("Expected empty WAL after checkpoint, got %d bytes", info.Size())
+	}
+}
+
+func TestRecoveryToolPrunePages(t *testing.T) {
+	rt, pages := newRecoveryToolFixture(t, 4)
+
+	// Reading a damaged page quarantines it, which must not stop pruning
+	var notified []PageID
+	rt.SetPruneCallback(func(pm *PageManager, wal *WAL, pages []PageID) error {
+		notified = append(notified, pages...)
+		for _, id := range pages {
+			if _, err := pm.ReadPage(id); !errors.Is(err, ErrPageCorrupted) {
+				t.Errorf("ReadPage(%d) error = %v, want ErrPageCorrupted", id, err)
+			}
+		}
+		return nil
+	})
+
+	// Damage the middle pages
+	f, err := os.OpenFile(rt.config.DataPath, os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open data file: %v", err)
+	}
+	garbage := bytes.Repeat([]byte{0xFF}, PageSize*2)
+	if _, err := f.WriteAt(garbage, int64(pages[1])*PageSize); err != nil {
+		t.Fatalf("Failed to corrupt pages: %v", err)
+	}
+	f.Close()
+
+	pruned, err := rt.PrunePages(pages[1], pages[2])
+	if err != nil {
+		t.Fatalf("PrunePages failed: %v", err)
+	}
+	if pruned != 2 {
+		t.Errorf("Expected 2 pruned pages, got %d", pruned)
+	}
+	if len(notified) != 2 || notified[0] != pages[1] || notified[1] != pages[2] {
+		t.Errorf("Prune callback got %v, want [%d %d]", notified, pages[1], pages[2])
+	}
+
+	pm, err := OpenPageManager(rt.config.DataPath, DefaultOptions())
+	if err != nil {
+		t.Fatalf("Failed to reopen PageManager: %v", err)
+	}
+	for _, id := range pages[1:3] {
+		page, err := pm.ReadPage(id)
+		if err != nil {
+			t.Fatalf("Failed to read pruned page %d: %v", id, err)
+		}
+		if page.Header.PageType != PageTypeFree {
+			t.Errorf("Page %d type = %v, want free", id, page.Header.PageType)
+		}
+		if !page.ValidateChecksum() {
+			t.Errorf("Pruned page %d has an invalid checksum", id)
+		}
+	}
+	pm.Close()
+
+	// Pruning the same range again frees nothing
+	notified = nil
+	pruned, err = rt.PrunePages(pages[1], pages[2])
+	if err != nil {
+		t.Fatalf("PrunePages failed: %v", err)
+	}
+	if pruned != 0 || notified != nil {
+		t.Errorf("Expected nothing pruned, got %d pages (callback %v)", pruned, notified)
+	}
+}
+
+func TestRecoveryToolPrunePagesInvalid(t *testing.T) {
+	rt, pages := newRecoveryToolFixture(t, 2)
+
+	pm, err := OpenPageManager(rt.config.DataPath, DefaultOptions())
+	if err != nil {
+		t.Fatalf("Failed to open PageManager: %v", err)
+	}
+	header := pm.Header()
+	header.RootPages.DNIndex = pages[1]
+	if err := pm.UpdateHeader(header); err != nil {
+		t.Fatalf("Failed to update header: %v", err)
+	}
+	total := PageID(pm.TotalPages())
+	pm.Close()
+
+	tests := []struct {
+		name     string
+		from, to PageID
+		want     error
+	}{
+		{"header page", 0, pages[0], ErrInvalidPageRange},
+		{"reversed", pages[1], pages[0], ErrInvalidPageRange},
+		{"past end", pages[0], total + 10, ErrInvalidPageRange},
+		{"root page", pages[0], pages[1], ErrProtectedPage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := rt.PrunePages(tt.from, tt.to); !errors.Is(err, tt.want) {
+				t.Errorf("PrunePages(%d, %d) = %v, want %v", tt.from, tt.to, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecoveryToolRebuildIndexWithoutRebuilder(t *testing.T) {
+	rt, _ := newRecoveryToolFixture(t, 1)
+
+	if _, err := rt.RebuildIndex("uid"); !errors.Is(err, ErrNoIndexRebuilder) {
+		t.Errorf("RebuildIndex = %v, want %v", err, ErrNoIndexRebuilder)
+	}
+}
@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SnapshotFileSystem is a FileSystem held in memory. Load copies a file into
+// it; from then on the copy is read and written in place of the file, which
+// is never modified. Files opened with os.O_CREATE that were not loaded are
+// created in memory.
+type SnapshotFileSystem struct {
+	base  FileSystem
+	mu    sync.Mutex
+	files map[string]*memData
+}
+
+// NewSnapshotFileSystem returns an empty SnapshotFileSystem that loads files
+// through base (nil = the operating system's).
+func NewSnapshotFileSystem(base FileSystem) *SnapshotFileSystem {
+	return &SnapshotFileSystem{
+		base:  fileSystemOrOS(base),
+		files: make(map[string]*memData),
+	}
+}
+
+// Load copies the file at name into memory. A file that does not exist
+// returns an error satisfying os.IsNotExist.
+func (fs *SnapshotFileSystem) Load(name string) error {
+	file, err := fs.base.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	data := make([]byte, info.Size())
+	if _, err := file.ReadAt(data, 0); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	fs.mu.Lock()
+	fs.files[name] = &memData{data: data}
+	fs.mu.Unlock()
+	return nil
+}
+
+// Size returns the number of bytes held in memory.
+func (fs *SnapshotFileSystem) Size() int64 {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	var size int64
+	for _, d := range fs.files {
+		d.mu.RLock()
+		size += int64(len(d.data))
+		d.mu.RUnlock()
+	}
+	return size
+}
+
+// OpenFile opens the in-memory copy of name.
+func (fs *SnapshotFileSystem) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	d, ok := fs.files[name]
+	if !ok {
+		if flag&os.O_CREATE == 0 {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		}
+		d = &memData{}
+		fs.files[name] = d
+	}
+	if flag&os.O_TRUNC != 0 {
+		d.mu.Lock()
+		d.data = d.data[:0]
+		d.mu.Unlock()
+	}
+	return &memFile{name: name, data: d}, nil
+}
+
+// memData is the content of an in-memory file, shared by its open handles.
+type memData struct {
+	mu   sync.RWMutex
+	data []byte
+}
+
+// memFile is an open handle of an in-memory file.
+type memFile struct {
+	name   string
+	data   *memData
+	offset int64
+	closed bool
+}
+
+func (f *memFile) ReadAt(p []byte, off int64) (int, error) {
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	f.data.mu.RLock()
+	defer f.data.mu.RUnlock()
+
+	if off >= int64(len(f.data.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.data.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *memFile) WriteAt(p []byte, off int64) (int, error) {
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	f.data.mu.Lock()
+	defer f.data.mu.Unlock()
+
+	if end := off + int64(len(p)); end > int64(len(f.data.data)) {
+		f.data.data = growData(f.data.data, end)
+	}
+	return copy(f.data.data[off:], p), nil
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	n, err := f.WriteAt(p, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		f.data.mu.RLock()
+		offset += int64(len(f.data.data))
+		f.data.mu.RUnlock()
+	}
+	if offset < 0 {
+		return 0, errors.New("negative seek offset")
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *memFile) Stat() (os.FileInfo, error) {
+	if f.closed {
+		return nil, os.ErrClosed
+	}
+	f.data.mu.RLock()
+	defer f.data.mu.RUnlock()
+	return memFileInfo{name: filepath.Base(f.name), size: int64(len(f.data.data))}, nil
+}
+
+func (f *memFile) Sync() error {
+	if f.closed {
+		return os.ErrClosed
+	}
+	return nil
+}
+
+func (f *memFile) Truncate(size int64) error {
+	if f.closed {
+		return os.ErrClosed
+	}
+	f.data.mu.Lock()
+	defer f.data.mu.Unlock()
+
+	if size > int64(len(f.data.data)) {
+		f.data.data = growData(f.data.data, size)
+	} else {
+		f.data.data = f.data.data[:size]
+	}
+	return nil
+}
+
+func (f *memFile) Close() error {
+	if f.closed {
+		return os.ErrClosed
+	}
+	f.closed = true
+	return nil
+}
+
+// growData extends data with zeros to size bytes.
+func growData(data []byte, size int64) []byte {
+	if size <= int64(cap(data)) {
+		old := len(data)
+		data = data[:size]
+		clear(data[old:])
+		return data
+	}
+	grown := make([]byte, size, size+size/4)
+	copy(grown, data)
+	return grown
+}
+
+// memFileInfo describes an in-memory file.
+type memFileInfo struct {
+	name string
+	size int64
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return i.size }
+func (i memFileInfo) Mode() os.FileMode  { return 0644 }
+func (i memFileInfo) ModTime() time.Time { return time.Time{} }
+func (i memFileInfo) IsDir() bool        { return false }
+func (i memFileInfo) Sys() interface{}   { return nil }
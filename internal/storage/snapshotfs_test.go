@@ -0,0 +1,114 @@
+// Package storage provides the core storage engine components for ObaDB.
+package storage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSnapshotFileSystem(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.oba")
+	if err := os.WriteFile(path, []byte("original"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	fs := NewSnapshotFileSystem(nil)
+	if err := fs.Load(path); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if err := fs.Load(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("Load(missing) error = %v, want not exist", err)
+	}
+
+	// Changes to the file after Load are not seen
+	if err := os.WriteFile(path, []byte("changed!"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	f, err := fs.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	buf := make([]byte, 8)
+	if _, err := f.ReadAt(buf, 0); err != nil || string(buf) != "original" {
+		t.Fatalf("ReadAt() = %q, %v, want original", buf, err)
+	}
+
+	// Writes go to the copy only
+	if _, err := f.WriteAt([]byte("copy"), 6); err != nil {
+		t.Fatalf("WriteAt() error = %v", err)
+	}
+	if info, _ := f.Stat(); info.Size() != 10 {
+		t.Errorf("Size() = %d, want 10", info.Size())
+	}
+	if err := f.Truncate(4); err != nil {
+		t.Fatalf("Truncate() error = %v", err)
+	}
+	if err := f.Truncate(6); err != nil {
+		t.Fatalf("Truncate() error = %v", err)
+	}
+	buf = make([]byte, 6)
+	f.ReadAt(buf, 0)
+	if !bytes.Equal(buf, []byte("orig\x00\x00")) {
+		t.Errorf("content after truncate = %q", buf)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "changed!" {
+		t.Errorf("file on disk = %q, want it untouched", data)
+	}
+
+	// Files that were not loaded are created in memory
+	walPath := filepath.Join(dir, "wal.oba")
+	if _, err := fs.OpenFile(walPath, os.O_RDWR, 0); !os.IsNotExist(err) {
+		t.Errorf("OpenFile(not loaded) error = %v, want not exist", err)
+	}
+	if _, err := fs.OpenFile(walPath, os.O_RDWR|os.O_CREATE, 0644); err != nil {
+		t.Fatalf("OpenFile(O_CREATE) error = %v", err)
+	}
+	if _, err := os.Stat(walPath); !os.IsNotExist(err) {
+		t.Errorf("Stat(wal) error = %v, want it not created on disk", err)
+	}
+}
+
+func TestFileLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "LOCK")
+
+	writer, err := OpenFileLock(path)
+	if err != nil {
+		t.Fatalf("OpenFileLock() error = %v", err)
+	}
+	defer writer.Close()
+	reader, err := OpenFileLock(path)
+	if err != nil {
+		t.Fatalf("OpenFileLock() error = %v", err)
+	}
+	defer reader.Close()
+
+	if err := writer.Lock(); err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+
+	locked := make(chan error, 1)
+	go func() { locked <- reader.RLock() }()
+
+	select {
+	case err := <-locked:
+		t.Fatalf("RLock() returned %v while the lock was held exclusively", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := writer.Unlock(); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	select {
+	case err := <-locked:
+		if err != nil {
+			t.Fatalf("RLock() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RLock() did not return after Unlock")
+	}
+}
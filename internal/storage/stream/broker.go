@@ -40,12 +40,19 @@ func NewBroker() *Broker {
 // Subscribe creates a new subscription with the given filter.
 // Returns a Subscriber that receives matching events on its Channel.
 func (b *Broker) Subscribe(filter WatchFilter) *Subscriber {
+	return b.SubscribeWithBuffer(filter, DefaultBufferSize)
+}
+
+// SubscribeWithBuffer creates a subscription whose Channel buffers up to
+// bufferSize events. Publish never waits for a subscriber: events that do
+// not fit are dropped and counted.
+func (b *Broker) SubscribeWithBuffer(filter WatchFilter, bufferSize int) *Subscriber {
 	if b.closed.Load() {
 		return nil
 	}
 
 	id := SubscriberID(b.nextID.Add(1))
-	sub := NewSubscriber(id, filter, DefaultBufferSize)
+	sub := NewSubscriber(id, filter, bufferSize)
 	b.subscribers.Store(id, sub)
 	b.subscriberCount.Add(1)
 	return sub
@@ -1,6 +1,10 @@
 package stream
 
-import "strings"
+import (
+	"strings"
+
+	"github.com/KilimcininKorOglu/oba/internal/ldap"
+)
 
 // Scope constants for watch filters.
 const (
@@ -46,8 +50,8 @@ func (f *WatchFilter) Matches(event *ChangeEvent) bool {
 		return true
 	}
 
-	dn := strings.ToLower(event.DN)
-	baseDN := strings.ToLower(f.BaseDN)
+	dn := ldap.CanonicalDN(event.DN)
+	baseDN := ldap.CanonicalDN(f.BaseDN)
 
 	switch f.Scope {
 	case ScopeBase:
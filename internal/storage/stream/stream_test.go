@@ -151,6 +151,32 @@ func TestSubscriberBackpressure(t *testing.T) {
 	}
 }
 
+func TestSubscriberOverflow(t *testing.T) {
+	sub := NewSubscriber(1, WatchFilter{}, 1)
+	defer sub.Close()
+
+	sub.Send(ChangeEvent{DN: "cn=1"})
+	select {
+	case <-sub.Overflow():
+		t.Fatal("Overflow() signaled before a drop")
+	default:
+	}
+
+	// Several drops are signaled once
+	sub.Send(ChangeEvent{DN: "cn=2"})
+	sub.Send(ChangeEvent{DN: "cn=3"})
+	select {
+	case <-sub.Overflow():
+	default:
+		t.Fatal("Overflow() not signaled after a drop")
+	}
+	select {
+	case <-sub.Overflow():
+		t.Error("Overflow() signaled twice")
+	default:
+	}
+}
+
 func TestSubscriberClose(t *testing.T) {
 	sub := NewSubscriber(1, WatchFilter{}, 2)
 
@@ -277,6 +303,43 @@ func TestBrokerPublish(t *testing.T) {
 	}
 }
 
+// TestBrokerPublishFullSubscriber tests that Publish does not wait for a
+// subscriber that is not reading.
+func TestBrokerPublishFullSubscriber(t *testing.T) {
+	broker := NewBroker()
+	defer broker.Close()
+
+	slow := broker.SubscribeWithBuffer(WatchFilter{}, 2)
+	defer broker.Unsubscribe(slow.ID)
+	fast := broker.Subscribe(WatchFilter{})
+	defer broker.Unsubscribe(fast.ID)
+
+	if cap(slow.Channel) != 2 {
+		t.Fatalf("cap(Channel) = %d, want 2", cap(slow.Channel))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			broker.Publish(ChangeEvent{Operation: OpInsert, DN: "cn=test"})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish() blocked on a full subscriber")
+	}
+
+	if len(slow.Channel) != 2 || slow.DroppedCount() != 8 {
+		t.Errorf("slow subscriber queued %d and dropped %d, want 2 and 8", len(slow.Channel), slow.DroppedCount())
+	}
+	if len(fast.Channel) != 10 || fast.DroppedCount() != 0 {
+		t.Errorf("fast subscriber queued %d and dropped %d, want 10 and 0", len(fast.Channel), fast.DroppedCount())
+	}
+}
+
 func TestBrokerPublishWithFilter(t *testing.T) {
 	broker := NewBroker()
 	defer broker.Close()
@@ -19,8 +19,9 @@ type Subscriber struct {
 	// Created is when the subscription was created.
 	Created time.Time
 
-	dropped atomic.Uint64
-	closed  atomic.Bool
+	dropped  atomic.Uint64
+	closed   atomic.Bool
+	overflow chan struct{}
 }
 
 // NewSubscriber creates a new subscriber with the given filter and buffer size.
@@ -29,10 +30,11 @@ func NewSubscriber(id SubscriberID, filter WatchFilter, bufferSize int) *Subscri
 		bufferSize = DefaultBufferSize
 	}
 	return &Subscriber{
-		ID:      id,
-		Filter:  filter,
-		Channel: make(chan ChangeEvent, bufferSize),
-		Created: time.Now(),
+		ID:       id,
+		Filter:   filter,
+		Channel:  make(chan ChangeEvent, bufferSize),
+		Created:  time.Now(),
+		overflow: make(chan struct{}, 1),
 	}
 }
 
@@ -47,10 +49,21 @@ func (s *Subscriber) Send(event ChangeEvent) bool {
 		return true
 	default:
 		s.dropped.Add(1)
+		select {
+		case s.overflow <- struct{}{}:
+		default:
+		}
 		return false
 	}
 }
 
+// Overflow returns a channel that receives a value when an event is
+// dropped because Channel is full. Drops that happen before the value is
+// received are signaled once.
+func (s *Subscriber) Overflow() <-chan struct{} {
+	return s.overflow
+}
+
 // Close closes the subscriber's channel.
 // Safe to call multiple times.
 func (s *Subscriber) Close() {
@@ -6,7 +6,7 @@
 //
 //   - Atomicity: All-or-nothing operations via WAL
 //   - Consistency: Schema validation before commit
-//   - Isolation: MVCC-based snapshot isolation
+//   - Isolation: MVCC-based snapshot isolation, optionally serializable
 //   - Durability: WAL fsync on commit
 //
 // # Transaction Lifecycle
@@ -44,12 +44,53 @@
 //	tx.WriteSet  // Pages modified by this transaction
 //	tx.ReadSet   // Pages read by this transaction
 //
+// # Isolation Level
+//
+// A transaction reads the snapshot of the database taken when it began:
+// the versions committed before it, plus its own writes. The storage
+// engine sets the snapshot source with SetSnapshotSource and publishes
+// the versions of a commit through CommitWith, so a snapshot never sees
+// half of a commit. The anomalies behave as follows:
+//
+//   - Dirty read: prevented. Uncommitted versions are visible only to
+//     their own transaction.
+//   - Non-repeatable read: prevented. Repeated reads return the snapshot
+//     version.
+//   - Lost update: prevented. Writing an entry that another transaction is
+//     writing, or committed after the snapshot, fails with
+//     mvcc.ErrVersionConflict (first committer wins).
+//   - Phantom: prevented for added entries. Subtree searches skip entries
+//     committed after the snapshot. A deleted entry, however, leaves the
+//     search results of all transactions as soon as it is deleted, before
+//     the delete commits; reading it by DN still returns it.
+//   - Write skew: allowed. Two transactions that read the same entries and
+//     write different ones both commit.
+//
+// This is snapshot isolation. Transactions begun with
+// TxOptions{Serializable: true} also record the DNs they read, the
+// subtrees they search or check for children, and the attribute values
+// they look up in the whole directory, such as a uid checked for
+// uniqueness. Their commit fails with ErrSerializationFailure if another
+// transaction committed a change to any of them after the snapshot, which
+// prevents write skew:
+//
+//	tx, err := manager.BeginWithOptions(TxOptions{Serializable: true})
+//
+// The backend runs its check-then-act operations, adding and deleting
+// entries, in serializable transactions.
+//
+// The engine's isolation tests pin down each of these outcomes.
+//
 // # Conflict Detection
 //
-// Write-write conflicts are detected at commit time:
+// Write-write conflicts on entries are detected when the entry is
+// written. At commit time, the page write set is checked against the
+// other active transactions:
 //
-//	if manager.HasConflict(tx) {
+//	if err := manager.Commit(tx); err == ErrWriteConflict {
 //	    manager.Rollback(tx)
-//	    return ErrConflict
 //	}
+//
+// SetCommitBarrier lets tests hold a commit back to force an interleaving
+// of concurrent commits.
 package tx
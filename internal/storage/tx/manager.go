@@ -2,6 +2,7 @@
 package tx
 
 import (
+	"context"
 	"errors"
 	"sync"
 	"sync/atomic"
@@ -19,8 +20,27 @@ var (
 	ErrWriteConflict  = errors.New("write conflict detected")
 	ErrNilWAL         = errors.New("WAL is nil")
 	ErrNilTransaction = errors.New("transaction is nil")
+
+	// ErrSerializationFailure is returned by the commit of a serializable
+	// transaction when another transaction committed a change to data it
+	// read after its snapshot was taken. The transaction must be rolled
+	// back and may be retried.
+	ErrSerializationFailure = errors.New("serialization failure")
 )
 
+// TxOptions configures a transaction started with BeginWithOptions.
+type TxOptions struct {
+	// Serializable records the DNs and subtrees the transaction reads and
+	// fails its commit with ErrSerializationFailure if any of them changed
+	// after its snapshot. This prevents the write skew anomaly that plain
+	// snapshot isolation allows.
+	Serializable bool
+
+	// Context, if set, bounds the reads of the transaction: the engine
+	// stops reading entries once it is done and returns its error.
+	Context context.Context
+}
+
 // TxManager manages transaction lifecycle: begin, commit, and rollback.
 // It assigns unique transaction IDs, tracks active transactions, and
 // manages the commit protocol.
@@ -39,6 +59,21 @@ type TxManager struct {
 
 	// commitMu serializes commits to prevent conflicts.
 	commitMu sync.Mutex
+
+	// snapshotSource returns the snapshot timestamp of a new transaction.
+	// If nil, the transaction ID is used.
+	snapshotSource func() uint64
+
+	// visibilityMu keeps Begin from taking a snapshot while a commit is
+	// publishing its versions.
+	visibilityMu sync.RWMutex
+
+	// commitBarrier, if set, is called at the start of every commit.
+	commitBarrier func(tx *Transaction)
+
+	// committed and rolledBack count finished transactions.
+	committed  atomic.Uint64
+	rolledBack atomic.Uint64
 }
 
 // NewTxManager creates a new transaction manager with the given WAL.
@@ -50,9 +85,38 @@ func NewTxManager(wal *storage.WAL) *TxManager {
 	}
 }
 
+// SetSnapshotSource sets the function that returns the snapshot timestamp
+// of new transactions, normally the last commit timestamp of the version
+// store. It must be set before any transaction begins.
+func (tm *TxManager) SetSnapshotSource(source func() uint64) {
+	tm.snapshotSource = source
+}
+
+// SetCommitBarrier sets a function called at the start of every commit,
+// before the commit is serialized with the others. Tests use it to hold a
+// commit back while other transactions run, to force an interleaving.
+func (tm *TxManager) SetCommitBarrier(barrier func(tx *Transaction)) {
+	tm.mu.Lock()
+	tm.commitBarrier = barrier
+	tm.mu.Unlock()
+}
+
+// WithoutPublishing calls fn while no commit is publishing its versions, so
+// that fn sees every commit either fully published or not at all.
+func (tm *TxManager) WithoutPublishing(fn func()) {
+	tm.visibilityMu.RLock()
+	defer tm.visibilityMu.RUnlock()
+	fn()
+}
+
 // Begin starts a new transaction and returns it.
 // The transaction is assigned a unique, monotonically increasing ID.
 func (tm *TxManager) Begin() (*Transaction, error) {
+	return tm.BeginWithOptions(TxOptions{})
+}
+
+// BeginWithOptions starts a new transaction configured by opts.
+func (tm *TxManager) BeginWithOptions(opts TxOptions) (*Transaction, error) {
 	if tm.wal == nil {
 		return nil, ErrNilWAL
 	}
@@ -65,6 +129,13 @@ func (tm *TxManager) Begin() (*Transaction, error) {
 
 	// Create the transaction
 	tx := NewTransaction(txID, startLSN)
+	tx.Serializable = opts.Serializable
+	tx.ctx = opts.Context
+	if tm.snapshotSource != nil {
+		tm.visibilityMu.RLock()
+		tx.Snapshot = tm.snapshotSource()
+		tm.visibilityMu.RUnlock()
+	}
 
 	// Write BEGIN record to WAL
 	beginRecord := storage.NewWALRecord(0, txID, storage.WALBegin)
@@ -89,6 +160,16 @@ func (tm *TxManager) Begin() (*Transaction, error) {
 // 4. Mark transaction as committed
 // 5. Remove from active transactions
 func (tm *TxManager) Commit(tx *Transaction) error {
+	return tm.CommitWith(tx, nil)
+}
+
+// CommitWith commits the transaction like Commit, calling publish after
+// the write set is validated and before the commit record is written.
+// Commits are serialized, so publish can validate the transaction against
+// the ones committed before it and then make its changes visible. No
+// transaction begins while publish runs. If publish fails, the
+// transaction stays active and must be rolled back.
+func (tm *TxManager) CommitWith(tx *Transaction, publish func() error) error {
 	if tx == nil {
 		return ErrNilTransaction
 	}
@@ -102,6 +183,13 @@ func (tm *TxManager) Commit(tx *Transaction) error {
 		return ErrTxNotActive
 	}
 
+	tm.mu.RLock()
+	barrier := tm.commitBarrier
+	tm.mu.RUnlock()
+	if barrier != nil {
+		barrier(tx)
+	}
+
 	// Serialize commits to prevent conflicts
 	tm.commitMu.Lock()
 	defer tm.commitMu.Unlock()
@@ -120,6 +208,15 @@ func (tm *TxManager) Commit(tx *Transaction) error {
 		return err
 	}
 
+	if publish != nil {
+		tm.visibilityMu.Lock()
+		err := publish()
+		tm.visibilityMu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
 	// Write COMMIT record to WAL
 	commitRecord := storage.NewWALRecord(0, tx.ID, storage.WALCommit)
 	_, err := tm.wal.Append(commitRecord)
@@ -139,6 +236,7 @@ func (tm *TxManager) Commit(tx *Transaction) error {
 	tm.mu.Lock()
 	delete(tm.activeTx, tx.ID)
 	tm.mu.Unlock()
+	tm.committed.Add(1)
 
 	return nil
 }
@@ -194,6 +292,7 @@ func (tm *TxManager) Rollback(tx *Transaction) error {
 	tm.mu.Lock()
 	delete(tm.activeTx, tx.ID)
 	tm.mu.Unlock()
+	tm.rolledBack.Add(1)
 
 	return nil
 }
@@ -226,6 +325,18 @@ func (tm *TxManager) ActiveCount() int {
 	return len(tm.activeTx)
 }
 
+// CommittedCount returns the number of transactions committed since the
+// manager was created.
+func (tm *TxManager) CommittedCount() uint64 {
+	return tm.committed.Load()
+}
+
+// RolledBackCount returns the number of transactions rolled back since
+// the manager was created.
+func (tm *TxManager) RolledBackCount() uint64 {
+	return tm.rolledBack.Load()
+}
+
 // validateWriteSet checks for write conflicts with other transactions.
 // In a simple implementation, we check if any page in the write set
 // is also in another active transaction's write set.
@@ -795,3 +795,61 @@ func TestGetActiveTransactionsReturnsClones(t *testing.T) {
 		t.Errorf("original transaction should not be modified, got %d pages", len(original.GetWriteSet()))
 	}
 }
+
+// TestTxManagerBeginWithOptions tests the snapshot source and the read
+// tracking of serializable transactions.
+func TestTxManagerBeginWithOptions(t *testing.T) {
+	wal, cleanup := testWAL(t)
+	defer cleanup()
+
+	tm := NewTxManager(wal)
+	tm.SetSnapshotSource(func() uint64 { return 42 })
+
+	plain, err := tm.Begin()
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if plain.Snapshot != 42 {
+		t.Errorf("Snapshot = %d, want 42 from the snapshot source", plain.Snapshot)
+	}
+	plain.AddReadDN("uid=alice,dc=example,dc=com")
+	plain.AddReadRange("dc=example,dc=com")
+	if len(plain.GetReadDNs()) != 0 || len(plain.GetReadRanges()) != 0 {
+		t.Error("reads of a plain transaction should not be recorded")
+	}
+
+	serializable, err := tm.BeginWithOptions(TxOptions{Serializable: true})
+	if err != nil {
+		t.Fatalf("BeginWithOptions() error = %v", err)
+	}
+	serializable.AddReadDN("uid=alice,dc=example,dc=com")
+	serializable.AddReadDN("uid=alice,dc=example,dc=com")
+	serializable.AddReadRange("dc=example,dc=com")
+	if got := serializable.GetReadDNs(); len(got) != 1 {
+		t.Errorf("GetReadDNs() = %v, want one DN", got)
+	}
+	if got := serializable.GetReadRanges(); len(got) != 1 || got[0] != "dc=example,dc=com" {
+		t.Errorf("GetReadRanges() = %v, want [dc=example,dc=com]", got)
+	}
+}
+
+// TestCommitWithPublishError tests that a failed publish leaves the
+// transaction active without writing a commit record.
+func TestCommitWithPublishError(t *testing.T) {
+	wal, cleanup := testWAL(t)
+	defer cleanup()
+
+	tm := NewTxManager(wal)
+	tx, _ := tm.Begin()
+
+	err := tm.CommitWith(tx, func() error { return ErrSerializationFailure })
+	if err != ErrSerializationFailure {
+		t.Fatalf("CommitWith() error = %v, want ErrSerializationFailure", err)
+	}
+	if !tx.IsActive() {
+		t.Error("transaction should stay active after a failed publish")
+	}
+	if err := tm.Rollback(tx); err != nil {
+		t.Errorf("Rollback() error = %v", err)
+	}
+}
@@ -2,6 +2,7 @@
 package tx
 
 import (
+	"context"
 	"sync"
 	"time"
 
@@ -58,10 +59,37 @@ type Transaction struct {
 	// Snapshot is the snapshot timestamp for MVCC.
 	Snapshot uint64
 
+	// Serializable is set for transactions begun with
+	// TxOptions.Serializable. Their reads are recorded in readDNs,
+	// readRanges and readValues and validated at commit.
+	Serializable bool
+
+	// readDNs contains the DNs read by a serializable transaction.
+	readDNs map[string]struct{}
+
+	// readRanges contains the base DNs of the subtrees searched by a
+	// serializable transaction.
+	readRanges []string
+
+	// readValues contains the attribute values a serializable transaction
+	// looked up in the whole directory.
+	readValues []AttributeValue
+
+	// ctx bounds the reads of the transaction (nil = unbounded).
+	ctx context.Context
+
 	// mu protects concurrent access to the transaction.
 	mu sync.RWMutex
 }
 
+// AttributeValue is an attribute value that a serializable transaction
+// looked up in the whole directory, such as a uid it checked for
+// uniqueness.
+type AttributeValue struct {
+	Attribute string
+	Value     string
+}
+
 // NewTransaction creates a new transaction with the given ID and start LSN.
 func NewTransaction(id, startLSN uint64) *Transaction {
 	return &Transaction{
@@ -75,6 +103,15 @@ func NewTransaction(id, startLSN uint64) *Transaction {
 	}
 }
 
+// Context returns the context bounding the reads of the transaction, set
+// with TxOptions.Context. It is never nil.
+func (tx *Transaction) Context() context.Context {
+	if tx.ctx == nil {
+		return context.Background()
+	}
+	return tx.ctx
+}
+
 // IsActive returns true if the transaction is still active.
 func (tx *Transaction) IsActive() bool {
 	tx.mu.RLock()
@@ -110,6 +147,91 @@ func (tx *Transaction) AddToReadSet(pageID storage.PageID) {
 	tx.ReadSet = append(tx.ReadSet, pageID)
 }
 
+// AddReadDN records that a serializable transaction read dn. It does
+// nothing for other transactions.
+func (tx *Transaction) AddReadDN(dn string) {
+	if !tx.Serializable {
+		return
+	}
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.readDNs == nil {
+		tx.readDNs = make(map[string]struct{})
+	}
+	tx.readDNs[dn] = struct{}{}
+}
+
+// AddReadRange records that a serializable transaction searched the
+// subtree below baseDN. It does nothing for other transactions.
+func (tx *Transaction) AddReadRange(baseDN string) {
+	if !tx.Serializable {
+		return
+	}
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	for _, r := range tx.readRanges {
+		if r == baseDN {
+			return
+		}
+	}
+	tx.readRanges = append(tx.readRanges, baseDN)
+}
+
+// AddReadValue records that a serializable transaction looked up the
+// entries with the given attribute value. It does nothing for other
+// transactions.
+func (tx *Transaction) AddReadValue(attribute, value string) {
+	if !tx.Serializable {
+		return
+	}
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	read := AttributeValue{Attribute: attribute, Value: value}
+	for _, v := range tx.readValues {
+		if v == read {
+			return
+		}
+	}
+	tx.readValues = append(tx.readValues, read)
+}
+
+// GetReadDNs returns the DNs read by a serializable transaction.
+func (tx *Transaction) GetReadDNs() []string {
+	tx.mu.RLock()
+	defer tx.mu.RUnlock()
+
+	result := make([]string, 0, len(tx.readDNs))
+	for dn := range tx.readDNs {
+		result = append(result, dn)
+	}
+	return result
+}
+
+// GetReadRanges returns the base DNs of the subtrees searched by a
+// serializable transaction.
+func (tx *Transaction) GetReadRanges() []string {
+	tx.mu.RLock()
+	defer tx.mu.RUnlock()
+
+	result := make([]string, len(tx.readRanges))
+	copy(result, tx.readRanges)
+	return result
+}
+
+// GetReadValues returns the attribute values looked up by a serializable
+// transaction.
+func (tx *Transaction) GetReadValues() []AttributeValue {
+	tx.mu.RLock()
+	defer tx.mu.RUnlock()
+
+	result := make([]AttributeValue, len(tx.readValues))
+	copy(result, tx.readValues)
+	return result
+}
+
 // AddToWriteSet adds a page to the transaction's write set.
 func (tx *Transaction) AddToWriteSet(pageID storage.PageID) {
 	tx.mu.Lock()
@@ -178,13 +300,14 @@ func (tx *Transaction) Clone() *Transaction {
 	defer tx.mu.RUnlock()
 
 	clone := &Transaction{
-		ID:        tx.ID,
-		State:     tx.State,
-		StartTime: tx.StartTime,
-		StartLSN:  tx.StartLSN,
-		Snapshot:  tx.Snapshot,
-		ReadSet:   make([]storage.PageID, len(tx.ReadSet)),
-		WriteSet:  make([]storage.PageID, len(tx.WriteSet)),
+		ID:           tx.ID,
+		State:        tx.State,
+		StartTime:    tx.StartTime,
+		StartLSN:     tx.StartLSN,
+		Snapshot:     tx.Snapshot,
+		Serializable: tx.Serializable,
+		ReadSet:      make([]storage.PageID, len(tx.ReadSet)),
+		WriteSet:     make([]storage.PageID, len(tx.WriteSet)),
 	}
 	copy(clone.ReadSet, tx.ReadSet)
 	copy(clone.WriteSet, tx.WriteSet)
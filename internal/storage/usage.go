@@ -0,0 +1,84 @@
+// Package storage provides the core storage engine components for ObaDB.
+package storage
+
+import (
+	"io"
+)
+
+// PageTypeStats is the space used by the pages of one type.
+type PageTypeStats struct {
+	// Count is the number of pages
+	Count int
+	// TotalBytes is the size of the pages in the file
+	TotalBytes int64
+	// AverageOccupancy is the fraction of the page data area in use,
+	// averaged over the pages, from 0 to 1. It is taken from the free
+	// space recorded in each page header.
+	AverageOccupancy float64
+}
+
+// PageUsageReport breaks down the pages of a file by type.
+type PageUsageReport struct {
+	// ByType holds the stats of each page type found in the file. Pages
+	// that were never written are counted as PageTypeFree.
+	ByType map[PageType]PageTypeStats
+	// PageSize is the page size of the file
+	PageSize int
+	// TotalPages is the number of pages in the file, including the header
+	// page, which is not counted under any type
+	TotalPages uint64
+	// Unreadable is the number of pages whose header could not be read
+	Unreadable int
+}
+
+// UsageReport walks the pages of the file and reports the space used by
+// each page type. Like Verify it reads the file directly and does not
+// modify it, so it can run on a file opened read-only. Pages are not
+// checksummed; use Verify to find damaged pages.
+func (pm *PageManager) UsageReport() *PageUsageReport {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	report := &PageUsageReport{
+		ByType:     make(map[PageType]PageTypeStats),
+		PageSize:   pm.pageSize,
+		TotalPages: pm.totalPages,
+	}
+	if pm.closed {
+		return report
+	}
+
+	occupancy := make(map[PageType]float64)
+	buf := make([]byte, pm.pageSize)
+	page := &Page{}
+	for id := PageID(1); uint64(id) < pm.totalPages; id++ {
+		if _, err := pm.file.ReadAt(buf, int64(id)*int64(pm.pageSize)); err != nil && err != io.EOF {
+			report.Unreadable++
+			continue
+		}
+
+		pageType, used := PageTypeFree, 0.0
+		if !isZeroPage(buf) {
+			if err := page.deserialize(buf, pm.pageFormat); err != nil || page.Header.PageType > PageTypeWAL {
+				report.Unreadable++
+				continue
+			}
+			pageType = page.Header.PageType
+			if size := len(page.Data); size > 0 && int(page.Header.FreeSpace) < size {
+				used = float64(size-int(page.Header.FreeSpace)) / float64(size)
+			}
+		}
+
+		stats := report.ByType[pageType]
+		stats.Count++
+		stats.TotalBytes += int64(pm.pageSize)
+		report.ByType[pageType] = stats
+		occupancy[pageType] += used
+	}
+
+	for pageType, stats := range report.ByType {
+		stats.AverageOccupancy = occupancy[pageType] / float64(stats.Count)
+		report.ByType[pageType] = stats
+	}
+	return report
+}
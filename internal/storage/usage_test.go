@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestUsageReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.oba")
+	pm, err := OpenPageManager(path, DefaultOptions())
+	if err != nil {
+		t.Fatalf("OpenPageManager() error = %v", err)
+	}
+
+	// Three data pages, a quarter full, and two index pages of each kind,
+	// half full
+	layout := []struct {
+		pageType PageType
+		count    int
+		used     int
+	}{
+		{PageTypeData, 3, (PageSize - PageHeaderSize) / 4},
+		{PageTypeDNIndex, 2, (PageSize - PageHeaderSize) / 2},
+		{PageTypeAttrIndex, 2, (PageSize - PageHeaderSize) / 2},
+	}
+	var data []PageID
+	for _, l := range layout {
+		for i := 0; i < l.count; i++ {
+			id, err := pm.AllocatePage(l.pageType)
+			if err != nil {
+				t.Fatalf("AllocatePage() error = %v", err)
+			}
+			page := NewPage(id, l.pageType)
+			page.Header.FreeSpace = uint16(PageSize - PageHeaderSize - l.used)
+			if err := pm.WritePage(page); err != nil {
+				t.Fatalf("WritePage() error = %v", err)
+			}
+			if l.pageType == PageTypeData {
+				data = append(data, id)
+			}
+		}
+	}
+	if err := pm.FreePage(data[2]); err != nil {
+		t.Fatalf("FreePage() error = %v", err)
+	}
+
+	report := pm.UsageReport()
+	total := report.TotalPages
+	pm.Close()
+
+	want := map[PageType]struct {
+		count     int
+		occupancy float64
+	}{
+		PageTypeData:      {2, 0.25},
+		PageTypeDNIndex:   {2, 0.5},
+		PageTypeAttrIndex: {2, 0.5},
+	}
+	counted := 0
+	for pageType, stats := range report.ByType {
+		counted += stats.Count
+		if stats.TotalBytes != int64(stats.Count)*PageSize {
+			t.Errorf("%s: TotalBytes = %d for %d pages", pageType, stats.TotalBytes, stats.Count)
+		}
+		w, ok := want[pageType]
+		if !ok {
+			continue
+		}
+		if stats.Count != w.count {
+			t.Errorf("%s: Count = %d, want %d", pageType, stats.Count, w.count)
+		}
+		if d := stats.AverageOccupancy - w.occupancy; d < -0.001 || d > 0.001 {
+			t.Errorf("%s: AverageOccupancy = %v, want %v", pageType, stats.AverageOccupancy, w.occupancy)
+		}
+	}
+
+	// The freed page and the preallocated ones are free and empty
+	free := report.ByType[PageTypeFree]
+	if free.Count < 1 || free.AverageOccupancy != 0 {
+		t.Errorf("Free: %+v, want at least the freed page and no occupancy", free)
+	}
+	if uint64(counted)+1 != total || report.Unreadable != 0 {
+		t.Errorf("counted %d pages of %d with %d unreadable, want every page but the header", counted, total, report.Unreadable)
+	}
+
+	// A read-only open reports the same from disk
+	ro, err := OpenPageManager(path, Options{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("OpenPageManager() error = %v", err)
+	}
+	defer ro.Close()
+	if got := ro.UsageReport().ByType[PageTypeData].Count; got != 2 {
+		t.Errorf("read-only Data count = %d, want 2", got)
+	}
+}
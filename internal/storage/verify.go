@@ -0,0 +1,219 @@
+// Package storage provides the core storage engine components for ObaDB.
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// Errors reported by Verify.
+var (
+	ErrPageIDMismatch  = errors.New("page header holds the ID of another page")
+	ErrFreeListCycle   = errors.New("free list chain loops back to a page already visited")
+	ErrFreeListCorrupt = errors.New("free list is corrupted")
+	ErrFreePageInUse   = errors.New("page is on the free list but holds data")
+	ErrDuplicateFree   = errors.New("page is on the free list more than once")
+	ErrRootOutOfRange  = errors.New("root page is outside the file")
+)
+
+// PageError is a problem found on one page of a file by Verify.
+type PageError struct {
+	// File is the base name of the file holding the page
+	File string
+	// PageID is the page the problem was found on
+	PageID PageID
+	// Err describes the problem
+	Err error
+}
+
+// Error returns the file, page and problem.
+func (e PageError) Error() string {
+	return fmt.Sprintf("%s page %d: %v", e.File, e.PageID, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e PageError) Unwrap() error {
+	return e.Err
+}
+
+// VerificationReport is the result of an offline integrity check.
+type VerificationReport struct {
+	// Errors are the damaged pages and broken invariants
+	Errors []PageError
+	// Warnings are irregularities that do not lose data, such as pages
+	// leaked by a crash
+	Warnings []string
+	// PagesChecked is the number of pages read
+	PagesChecked int
+	// FreePages is the number of distinct pages on the free lists
+	FreePages int
+}
+
+// OK reports whether no errors were found.
+func (r *VerificationReport) OK() bool {
+	return len(r.Errors) == 0
+}
+
+// Merge adds the findings of other to r.
+func (r *VerificationReport) Merge(other *VerificationReport) {
+	r.Errors = append(r.Errors, other.Errors...)
+	r.Warnings = append(r.Warnings, other.Warnings...)
+	r.PagesChecked += other.PagesChecked
+	r.FreePages += other.FreePages
+}
+
+// Verify checks the integrity of the file without modifying it:
+//
+//   - the file header validates and its root pages are within the file;
+//   - every written page records its own ID and a known page type in its
+//     header, and its checksum matches its content;
+//   - the free list chain on disk ends without looping, and holds only
+//     free list pages;
+//   - every page on the free list is within the file, listed once, and
+//     not holding data.
+//
+// Pages that were never written, which are all zero, are skipped. Verify
+// only knows about pages; the structures stored in them are checked by
+// their owners. Open the file read-only, since a writable open drops the
+// free list chain on disk.
+func (pm *PageManager) Verify() *VerificationReport {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	report := &VerificationReport{}
+	file := filepath.Base(pm.path)
+	addError := func(id PageID, err error) {
+		report.Errors = append(report.Errors, PageError{File: file, PageID: id, Err: err})
+	}
+
+	if pm.closed {
+		addError(0, ErrFileClosed)
+		return report
+	}
+
+	header := pm.verifyHeader(addError)
+	report.PagesChecked++
+
+	if info, err := pm.file.Stat(); err == nil {
+		if pages := uint64(info.Size()) / uint64(pm.pageSize); header != nil && pages > header.TotalPages {
+			report.Warnings = append(report.Warnings, fmt.Sprintf(
+				"%s holds %d pages but its header records %d; the file was not closed cleanly", file, pages, header.TotalPages))
+		}
+	}
+
+	free := pm.verifyFreeList(report, header, addError)
+
+	buf := make([]byte, pm.pageSize)
+	page := &Page{}
+	for id := PageID(1); uint64(id) < pm.totalPages; id++ {
+		report.PagesChecked++
+
+		if _, err := pm.file.ReadAt(buf, int64(id)*int64(pm.pageSize)); err != nil && err != io.EOF {
+			addError(id, err)
+			continue
+		}
+		if isZeroPage(buf) {
+			continue
+		}
+		if err := page.deserialize(buf, pm.pageFormat); err != nil {
+			addError(id, err)
+			continue
+		}
+
+		switch {
+		case page.Header.PageID != id:
+			addError(id, fmt.Errorf("%w: %d", ErrPageIDMismatch, page.Header.PageID))
+			continue
+		case page.Header.PageType > PageTypeWAL:
+			addError(id, fmt.Errorf("%w: %d", ErrInvalidPageType, page.Header.PageType))
+			continue
+		case page.verifyChecksum(pm.pageFormat) != nil:
+			addError(id, ErrInvalidChecksum)
+			continue
+		}
+
+		if free[id] && page.Header.PageType != PageTypeFree {
+			addError(id, fmt.Errorf("%w: %s page", ErrFreePageInUse, page.Header.PageType))
+		}
+	}
+
+	return report
+}
+
+// verifyHeader re-reads and validates the file header. It returns nil if
+// the header is damaged.
+func (pm *PageManager) verifyHeader(addError func(PageID, error)) *FileHeader {
+	buf := make([]byte, FileHeaderSize)
+	if _, err := pm.file.ReadAt(buf, 0); err != nil {
+		addError(0, err)
+		return nil
+	}
+
+	header := &FileHeader{}
+	if err := header.DeserializeAndValidate(buf); err != nil {
+		addError(0, err)
+		return nil
+	}
+
+	for _, root := range []PageID{header.RootPages.DNIndex, header.RootPages.DataRoot} {
+		if uint64(root) >= pm.totalPages {
+			addError(0, fmt.Errorf("%w: %d", ErrRootOutOfRange, root))
+		}
+	}
+	return header
+}
+
+// verifyFreeList walks the free list chain recorded in the header and
+// checks the free pages held in memory. It returns the free pages.
+func (pm *PageManager) verifyFreeList(report *VerificationReport, header *FileHeader, addError func(PageID, error)) map[PageID]bool {
+	if header != nil {
+		visited := make(map[PageID]bool)
+		for id := header.FreeListHead; id != 0; {
+			if uint64(id) >= pm.totalPages {
+				addError(id, fmt.Errorf("%w: next page is outside the file", ErrFreeListCorrupt))
+				break
+			}
+			if visited[id] {
+				addError(id, ErrFreeListCycle)
+				break
+			}
+			visited[id] = true
+
+			page, err := pm.readPageInternal(id)
+			if err != nil {
+				addError(id, fmt.Errorf("%w: %v", ErrFreeListCorrupt, err))
+				break
+			}
+			if page.Header.PageType != PageTypeFree {
+				addError(id, fmt.Errorf("%w: chain holds a %s page", ErrFreeListCorrupt, page.Header.PageType))
+				break
+			}
+			if page.Header.ItemCount > MaxFreeListEntriesPerPage {
+				addError(id, fmt.Errorf("%w: %d entries", ErrFreeListCorrupt, page.Header.ItemCount))
+			}
+			id = GetNextPageID(page)
+		}
+	}
+
+	free := make(map[PageID]bool)
+	for _, id := range pm.freeList.PeekAll() {
+		switch {
+		case id == 0 || uint64(id) >= pm.totalPages:
+			addError(id, fmt.Errorf("%w: free page is outside the file", ErrFreeListCorrupt))
+		case free[id]:
+			addError(id, ErrDuplicateFree)
+		default:
+			free[id] = true
+		}
+	}
+	report.FreePages = len(free)
+	return free
+}
+
+// isZeroPage reports whether a page was never written.
+func isZeroPage(buf []byte) bool {
+	return len(bytes.TrimLeft(buf, "\x00")) == 0
+}
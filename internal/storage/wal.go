@@ -34,7 +34,7 @@ var (
 // All modifications are logged to the WAL before being applied to data pages,
 // ensuring atomicity and durability.
 type WAL struct {
-	file       *os.File
+	file       File
 	path       string
 	currentLSN uint64
 	buffer     []byte
@@ -47,6 +47,12 @@ type WAL struct {
 
 	// Encryption key (nil if encryption is disabled)
 	encryptionKey *crypto.EncryptionKey
+
+	// compression selects how record data is compressed
+	compression WALCompression
+
+	// bytesWritten counts the bytes appended since the WAL was opened
+	bytesWritten uint64
 }
 
 // OpenWAL opens or creates a WAL file at the given path.
@@ -56,7 +62,13 @@ func OpenWAL(path string) (*WAL, error) {
 
 // OpenWALWithEncryption opens or creates a WAL file with optional encryption.
 func OpenWALWithEncryption(path string, encryptionKey *crypto.EncryptionKey) (*WAL, error) {
-	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	return OpenWALWithFileSystem(path, encryptionKey, nil)
+}
+
+// OpenWALWithFileSystem is like OpenWALWithEncryption, and opens the WAL
+// file through fs (nil = the operating system's).
+func OpenWALWithFileSystem(path string, encryptionKey *crypto.EncryptionKey, fs FileSystem) (*WAL, error) {
+	file, err := fileSystemOrOS(fs).OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
 	if err != nil {
 		return nil, err
 	}
@@ -189,8 +201,18 @@ func (w *WAL) recover() error {
 	return nil
 }
 
+// SetCompression sets how the data of records appended from now on is
+// compressed. Records are read back the same way whether they are
+// compressed or not, so the setting can change between runs.
+func (w *WAL) SetCompression(c WALCompression) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.compression = c
+}
+
 // Append writes a WAL record and returns its LSN.
-// The record's LSN field will be set to the assigned LSN.
+// The record's LSN field will be set to the assigned LSN, and its
+// Compressed field to whether its data was compressed.
 func (w *WAL) Append(record *WALRecord) (uint64, error) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
@@ -202,6 +224,11 @@ func (w *WAL) Append(record *WALRecord) (uint64, error) {
 	// Assign LSN
 	record.LSN = w.currentLSN
 
+	// Compress the data of large records; compression happens before
+	// encryption, which leaves nothing to compress
+	record.Compressed = w.compression == WALCompressionFast &&
+		len(record.OldData)+len(record.NewData) >= WALCompressionThreshold
+
 	// Serialize record
 	recordBuf, err := record.Serialize()
 	if err != nil {
@@ -243,6 +270,7 @@ func (w *WAL) Append(record *WALRecord) (uint64, error) {
 
 	// Add to index
 	w.lsnIndex[record.LSN] = indexOffset
+	w.bytesWritten += uint64(totalSize)
 
 	// Increment LSN
 	lsn := w.currentLSN
@@ -463,6 +491,30 @@ func (w *WAL) Iterator(startLSN uint64) *WALIterator {
 	}
 }
 
+// BytesWritten returns the number of bytes appended to the WAL since it
+// was opened, including the length prefixes. Truncation does not reduce it.
+func (w *WAL) BytesWritten() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.bytesWritten
+}
+
+// Size returns the size of the WAL in bytes, including records that are
+// buffered but not yet written to the file.
+func (w *WAL) Size() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0
+	}
+	info, err := w.file.Stat()
+	if err != nil {
+		return 0
+	}
+	return uint64(info.Size()) + uint64(w.bufferPos)
+}
+
 // CurrentLSN returns the next LSN that will be assigned.
 func (w *WAL) CurrentLSN() uint64 {
 	w.mu.Lock()
@@ -0,0 +1,196 @@
+// Package storage provides the core storage engine components for ObaDB.
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// WALCompression selects how WAL record payloads are compressed.
+type WALCompression uint8
+
+const (
+	// WALCompressionOff writes every record uncompressed.
+	WALCompressionOff WALCompression = iota
+	// WALCompressionFast compresses the payload of records of at least
+	// WALCompressionThreshold bytes with an LZ4 style block compressor.
+	WALCompressionFast
+)
+
+// WALCompressionThreshold is the smallest payload (old and new data
+// together) that is compressed. Smaller records, such as transaction
+// control records, are always written as they are.
+const WALCompressionThreshold = 256
+
+// ErrWALDecompress is returned for a compressed record whose payload does
+// not decompress to the lengths in its header.
+var ErrWALDecompress = errors.New("WAL record decompression failed")
+
+// String returns the name of c.
+func (c WALCompression) String() string {
+	switch c {
+	case WALCompressionOff:
+		return "off"
+	case WALCompressionFast:
+		return "fast"
+	default:
+		return "unknown"
+	}
+}
+
+// LZ4 block format parameters. A match is at least lz4MinMatch bytes, the
+// last lz4LastLiterals bytes of a block are always literals and no match
+// starts in the last lz4MatchLimit bytes.
+const (
+	lz4MinMatch     = 4
+	lz4LastLiterals = 5
+	lz4MatchLimit   = 12
+	lz4MaxOffset    = 65535
+	lz4HashLog      = 12
+)
+
+// compressBlock compresses src in the LZ4 block format. It returns nil if
+// src is too short to compress.
+func compressBlock(src []byte) []byte {
+	if len(src) <= lz4MatchLimit {
+		return nil
+	}
+
+	dst := make([]byte, 0, len(src))
+	var table [1 << lz4HashLog]int32 // position + 1 of the last occurrence
+
+	anchor := 0
+	limit := len(src) - lz4MatchLimit
+	for i := 0; i < limit; {
+		seq := binary.LittleEndian.Uint32(src[i:])
+		h := (seq * 2654435761) >> (32 - lz4HashLog)
+		candidate := int(table[h]) - 1
+		table[h] = int32(i + 1)
+
+		if candidate < 0 || i-candidate > lz4MaxOffset ||
+			binary.LittleEndian.Uint32(src[candidate:]) != seq {
+			i++
+			continue
+		}
+
+		length := lz4MinMatch
+		for i+length < len(src)-lz4LastLiterals && src[candidate+length] == src[i+length] {
+			length++
+		}
+
+		dst = appendLZ4Sequence(dst, src[anchor:i], i-candidate, length)
+		i += length
+		anchor = i
+	}
+
+	return appendLZ4Sequence(dst, src[anchor:], 0, 0)
+}
+
+// appendLZ4Sequence appends a sequence of literals followed by a match of
+// length bytes at offset. The last sequence of a block has no match.
+func appendLZ4Sequence(dst, literals []byte, offset, length int) []byte {
+	matchLength := 0
+	if length > 0 {
+		matchLength = length - lz4MinMatch
+	}
+
+	token := byte(min(len(literals), 15))<<4 | byte(min(matchLength, 15))
+	dst = append(dst, token)
+	dst = appendLZ4Length(dst, len(literals))
+	dst = append(dst, literals...)
+
+	if length > 0 {
+		dst = binary.LittleEndian.AppendUint16(dst, uint16(offset))
+		dst = appendLZ4Length(dst, matchLength)
+	}
+	return dst
+}
+
+// appendLZ4Length appends the bytes extending a length that does not fit
+// the 4 bits of the token.
+func appendLZ4Length(dst []byte, n int) []byte {
+	if n < 15 {
+		return dst
+	}
+	for n -= 15; n >= 255; n -= 255 {
+		dst = append(dst, 255)
+	}
+	return append(dst, byte(n))
+}
+
+// decompressBlock decompresses an LZ4 block that holds exactly size bytes.
+func decompressBlock(src []byte, size int) ([]byte, error) {
+	dst := make([]byte, 0, size)
+
+	pos := 0
+	for pos < len(src) {
+		token := src[pos]
+		pos++
+
+		literals, n, err := readLZ4Length(src[pos:], int(token>>4))
+		if err != nil {
+			return nil, err
+		}
+		pos += n
+		if literals > len(src)-pos || literals > size-len(dst) {
+			return nil, ErrWALDecompress
+		}
+		dst = append(dst, src[pos:pos+literals]...)
+		pos += literals
+
+		// The last sequence ends after its literals
+		if pos == len(src) {
+			break
+		}
+
+		if len(src)-pos < 2 {
+			return nil, ErrWALDecompress
+		}
+		offset := int(binary.LittleEndian.Uint16(src[pos:]))
+		pos += 2
+		if offset == 0 || offset > len(dst) {
+			return nil, ErrWALDecompress
+		}
+
+		length, n, err := readLZ4Length(src[pos:], int(token&15))
+		if err != nil {
+			return nil, err
+		}
+		pos += n
+		length += lz4MinMatch
+		if length > size-len(dst) {
+			return nil, ErrWALDecompress
+		}
+
+		// Copy byte by byte, as the match may overlap its own output
+		start := len(dst) - offset
+		for i := 0; i < length; i++ {
+			dst = append(dst, dst[start+i])
+		}
+	}
+
+	if len(dst) != size {
+		return nil, ErrWALDecompress
+	}
+	return dst, nil
+}
+
+// readLZ4Length reads the bytes extending the 4-bit length n from the
+// token. It returns the length and the number of bytes read.
+func readLZ4Length(src []byte, n int) (int, int, error) {
+	if n < 15 {
+		return n, 0, nil
+	}
+	read := 0
+	for {
+		if read >= len(src) {
+			return 0, 0, ErrWALDecompress
+		}
+		b := src[read]
+		read++
+		n += int(b)
+		if b != 255 {
+			return n, read, nil
+		}
+	}
+}
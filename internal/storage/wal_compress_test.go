@@ -0,0 +1,223 @@
+// Package storage provides the core storage engine components for ObaDB.
+package storage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// pageImage returns a page-sized buffer shaped like a data page: entries
+// with salted password hashes filling two thirds of it, followed by free
+// space.
+func pageImage(seed int) []byte {
+	rng := rand.New(rand.NewSource(int64(seed)))
+	salt := make([]byte, 24)
+
+	buf := make([]byte, PageSize)
+	var entries bytes.Buffer
+	for i := 0; entries.Len() < PageSize*2/3; i++ {
+		rng.Read(salt)
+		uid := seed*100 + i
+		fmt.Fprintf(&entries, "uid=user%05d,ou=users,dc=example,dc=com objectClass=inetOrgPerson cn=User %05d mail=user%05d@example.com userPassword={SSHA}%s\n",
+			uid, uid, uid, base64.StdEncoding.EncodeToString(salt))
+	}
+	copy(buf, entries.Bytes())
+	return buf
+}
+
+// TestCompressBlockRoundTrip tests that compressed blocks decompress to
+// their input.
+func TestCompressBlockRoundTrip(t *testing.T) {
+	random := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"short", []byte("abcdefghijklmnopq")},
+		{"zeros", make([]byte, 8192)},
+		{"run longer than 270", bytes.Repeat([]byte("a"), 1000)},
+		{"repeated phrase", bytes.Repeat([]byte("cn=alice,ou=users,"), 200)},
+		{"page image", pageImage(0)},
+		{"random", random},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed := compressBlock(tt.data)
+			if compressed == nil {
+				t.Fatal("compressBlock() = nil")
+			}
+			got, err := decompressBlock(compressed, len(tt.data))
+			if err != nil {
+				t.Fatalf("decompressBlock() error = %v", err)
+			}
+			if !bytes.Equal(got, tt.data) {
+				t.Error("decompressed data differs from the input")
+			}
+		})
+	}
+
+	if compressBlock([]byte("tiny")) != nil {
+		t.Error("compressBlock() of a tiny input should be nil")
+	}
+}
+
+// TestDecompressBlockRejectsBadInput tests that truncated blocks and
+// blocks of the wrong size fail.
+func TestDecompressBlockRejectsBadInput(t *testing.T) {
+	data := bytes.Repeat([]byte("cn=alice,ou=users,"), 50)
+	compressed := compressBlock(data)
+
+	if _, err := decompressBlock(compressed, len(data)-1); !errors.Is(err, ErrWALDecompress) {
+		t.Errorf("short size: error = %v, want ErrWALDecompress", err)
+	}
+	if _, err := decompressBlock(compressed, len(data)+1); !errors.Is(err, ErrWALDecompress) {
+		t.Errorf("long size: error = %v, want ErrWALDecompress", err)
+	}
+	if _, err := decompressBlock(compressed[:len(compressed)/2], len(data)); err == nil {
+		t.Error("truncated block: expected an error")
+	}
+}
+
+// TestWALRecordCompressed tests the compressed form of WAL records.
+func TestWALRecordCompressed(t *testing.T) {
+	record := NewWALUpdateRecord(7, 3, PageID(12), 0, pageImage(0), pageImage(1))
+	record.Compressed = true
+
+	buf, err := record.Serialize()
+	if err != nil {
+		t.Fatalf("Serialize() error = %v", err)
+	}
+	if !record.Compressed {
+		t.Fatal("Compressed cleared for compressible data")
+	}
+	if len(buf) >= record.Size()/2 {
+		t.Errorf("compressed record is %d bytes, uncompressed %d", len(buf), record.Size())
+	}
+
+	got := &WALRecord{}
+	if err := got.DeserializeAndValidate(buf); err != nil {
+		t.Fatalf("DeserializeAndValidate() error = %v", err)
+	}
+	if !got.Compressed || got.Type != WALUpdate || got.LSN != 7 || got.PageID != 12 {
+		t.Errorf("header = %+v", got)
+	}
+	if !bytes.Equal(got.OldData, record.OldData) || !bytes.Equal(got.NewData, record.NewData) {
+		t.Error("data differs after the round trip")
+	}
+	if !got.ValidateChecksum() {
+		t.Error("ValidateChecksum() = false for a compressed record")
+	}
+
+	// Data that does not compress is written as it is
+	random := make([]byte, 1024)
+	rand.New(rand.NewSource(1)).Read(random)
+	plain := NewWALUpdateRecord(8, 3, PageID(12), 0, nil, random)
+	plain.Compressed = true
+	buf, err = plain.Serialize()
+	if err != nil {
+		t.Fatalf("Serialize() error = %v", err)
+	}
+	if plain.Compressed || len(buf) != plain.Size() {
+		t.Errorf("incompressible record: Compressed = %v, size %d, want false, %d", plain.Compressed, len(buf), plain.Size())
+	}
+}
+
+// TestWALRecordCompressedCorruption tests that a corrupted compressed
+// record fails the checksum check before it is decompressed.
+func TestWALRecordCompressedCorruption(t *testing.T) {
+	record := NewWALUpdateRecord(7, 3, PageID(12), 0, nil, pageImage(0))
+	record.Compressed = true
+	buf, err := record.Serialize()
+	if err != nil {
+		t.Fatalf("Serialize() error = %v", err)
+	}
+
+	for _, pos := range []int{
+		WALRecordHeaderSize + walCompressedLengthSize, // first token
+		len(buf) / 2, // inside the data
+		len(buf) - 1, // last literal
+	} {
+		corrupt := append([]byte(nil), buf...)
+		corrupt[pos] ^= 0xFF
+		if err := (&WALRecord{}).DeserializeAndValidate(corrupt); !errors.Is(err, ErrWALRecordChecksum) {
+			t.Errorf("byte %d flipped: error = %v, want ErrWALRecordChecksum", pos, err)
+		}
+	}
+}
+
+// TestWALCompression tests that a WAL with compression enabled writes
+// fewer bytes and reads its records back, also after reopening it
+// without compression.
+func TestWALCompression(t *testing.T) {
+	walSize := func(c WALCompression) (string, int64) {
+		path := filepath.Join(t.TempDir(), "test.wal")
+		wal, err := OpenWAL(path)
+		if err != nil {
+			t.Fatalf("OpenWAL() error = %v", err)
+		}
+		wal.SetCompression(c)
+		for i := 0; i < 20; i++ {
+			if _, err := wal.Append(NewWALRecord(0, uint64(i), WALBegin)); err != nil {
+				t.Fatalf("Append() error = %v", err)
+			}
+			if _, err := wal.Append(NewWALUpdateRecord(0, uint64(i), PageID(i), 0, pageImage(i), pageImage(i+1))); err != nil {
+				t.Fatalf("Append() error = %v", err)
+			}
+		}
+		if err := wal.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Stat() error = %v", err)
+		}
+		return path, info.Size()
+	}
+
+	_, plainSize := walSize(WALCompressionOff)
+	path, compressedSize := walSize(WALCompressionFast)
+	if compressedSize >= plainSize/2 {
+		t.Errorf("compressed WAL is %d bytes, uncompressed %d", compressedSize, plainSize)
+	}
+
+	wal, err := OpenWAL(path)
+	if err != nil {
+		t.Fatalf("OpenWAL() error = %v", err)
+	}
+	defer wal.Close()
+	if wal.CurrentLSN() != 41 {
+		t.Fatalf("CurrentLSN() = %d after reopening, want 41", wal.CurrentLSN())
+	}
+
+	updates := 0
+	iter := wal.Iterator(1)
+	for iter.Next() {
+		record, err := iter.Record()
+		if err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+		if record.Type != WALUpdate {
+			if record.Compressed {
+				t.Errorf("control record %d compressed", record.LSN)
+			}
+			continue
+		}
+		i := int(record.PageID)
+		if !record.Compressed || !bytes.Equal(record.OldData, pageImage(i)) || !bytes.Equal(record.NewData, pageImage(i+1)) {
+			t.Errorf("update record %d read back wrong", record.LSN)
+		}
+		updates++
+	}
+	if updates != 20 {
+		t.Errorf("read %d update records, want 20", updates)
+	}
+}
@@ -19,12 +19,37 @@ const (
 	//   - Bytes 27-28: OldDataLen (uint16)
 	//   - Bytes 29-30: NewDataLen (uint16)
 	//   - Bytes 31-34: Checksum (uint32)
+	//
+	// The data lengths are those of the uncompressed data. A compressed
+	// record has WALRecordCompressed set in its type byte and is followed
+	// by the length of the compressed data (uint32) and the old and new
+	// data compressed together. The checksum covers the compressed bytes.
 	WALRecordHeaderSize = 35
 
+	// WALRecordCompressed is the flag bit of the type byte marking a
+	// record with compressed data.
+	WALRecordCompressed = 0x80
+
+	// walCompressedLengthSize is the size of the compressed data length
+	// following the header of a compressed record.
+	walCompressedLengthSize = 4
+
 	// MaxWALDataSize is the maximum size for old/new data in a WAL record.
 	MaxWALDataSize = 65535
 )
 
+// WAL formats, recorded in the data file header as the version of the
+// engine's wal component.
+const (
+	// WALFormatV0 records are never compressed.
+	WALFormatV0 uint32 = 0
+	// WALFormatV1 records may be compressed, marked with
+	// WALRecordCompressed.
+	WALFormatV1 uint32 = 1
+	// WALFormatVersion is the format the WAL is written in.
+	WALFormatVersion = WALFormatV1
+)
+
 // WALType represents the type of a WAL record.
 type WALType uint8
 
@@ -39,6 +64,9 @@ const (
 	WALUpdate
 	// WALCheckpoint marks a checkpoint in the WAL.
 	WALCheckpoint
+	// WALDNIndex records a DN index change, used to replay the radix tree
+	// on top of its last snapshot.
+	WALDNIndex
 )
 
 // String returns the string representation of a WALType.
@@ -54,6 +82,8 @@ func (t WALType) String() string {
 		return "Update"
 	case WALCheckpoint:
 		return "Checkpoint"
+	case WALDNIndex:
+		return "DNIndex"
 	default:
 		return "Unknown"
 	}
@@ -71,6 +101,11 @@ type WALRecord struct {
 	OldData  []byte  // Before image (for undo)
 	NewData  []byte  // After image (for redo)
 	Checksum uint32  // CRC32 of record
+
+	// Compressed makes Serialize compress the old and new data. It is
+	// cleared when compression would not make the record smaller, and set
+	// by Deserialize for a record read in compressed form.
+	Compressed bool
 }
 
 // Errors for WAL record operations.
@@ -109,14 +144,23 @@ func NewWALUpdateRecord(lsn, txID uint64, pageID PageID, offset uint16, oldData,
 	}
 }
 
-// Size returns the total serialized size of the WAL record.
+// Size returns the total serialized size of the uncompressed WAL record.
 func (r *WALRecord) Size() int {
 	return WALRecordHeaderSize + len(r.OldData) + len(r.NewData)
 }
 
 // Serialize writes the WAL record to a byte slice.
-// Returns a new byte slice containing the serialized record.
+// Returns a new byte slice containing the serialized record, with the data
+// compressed if Compressed is set and compression makes it smaller.
 func (r *WALRecord) Serialize() ([]byte, error) {
+	if r.Compressed {
+		buf, err := r.serializeCompressed()
+		if err != nil || buf != nil {
+			return buf, err
+		}
+		r.Compressed = false
+	}
+
 	size := r.Size()
 	buf := make([]byte, size)
 	if err := r.SerializeTo(buf); err != nil {
@@ -125,7 +169,44 @@ func (r *WALRecord) Serialize() ([]byte, error) {
 	return buf, nil
 }
 
-// SerializeTo writes the WAL record to an existing byte slice.
+// serializeCompressed returns the record in compressed form, or nil if
+// compression does not make it smaller.
+func (r *WALRecord) serializeCompressed() ([]byte, error) {
+	if len(r.OldData) > MaxWALDataSize || len(r.NewData) > MaxWALDataSize {
+		return nil, ErrWALDataTooLarge
+	}
+
+	data := make([]byte, 0, len(r.OldData)+len(r.NewData))
+	data = append(append(data, r.OldData...), r.NewData...)
+	compressed := compressBlock(data)
+	if compressed == nil || walCompressedLengthSize+len(compressed) >= len(data) {
+		return nil, nil
+	}
+
+	size := WALRecordHeaderSize + walCompressedLengthSize + len(compressed)
+	buf := make([]byte, size)
+	r.putHeader(buf)
+	buf[16] |= WALRecordCompressed
+	binary.LittleEndian.PutUint32(buf[WALRecordHeaderSize:], uint32(len(compressed)))
+	copy(buf[WALRecordHeaderSize+walCompressedLengthSize:], compressed)
+
+	r.Checksum = r.calculateChecksumFromBuffer(buf)
+	binary.LittleEndian.PutUint32(buf[31:35], r.Checksum)
+	return buf, nil
+}
+
+// putHeader writes the header fields other than the checksum to buf.
+func (r *WALRecord) putHeader(buf []byte) {
+	binary.LittleEndian.PutUint64(buf[0:8], r.LSN)
+	binary.LittleEndian.PutUint64(buf[8:16], r.TxID)
+	buf[16] = byte(r.Type)
+	binary.LittleEndian.PutUint64(buf[17:25], uint64(r.PageID))
+	binary.LittleEndian.PutUint16(buf[25:27], r.Offset)
+	binary.LittleEndian.PutUint16(buf[27:29], uint16(len(r.OldData)))
+	binary.LittleEndian.PutUint16(buf[29:31], uint16(len(r.NewData)))
+}
+
+// SerializeTo writes the uncompressed WAL record to an existing byte slice.
 // The slice must be at least Size() bytes.
 func (r *WALRecord) SerializeTo(buf []byte) error {
 	size := r.Size()
@@ -138,13 +219,7 @@ func (r *WALRecord) SerializeTo(buf []byte) error {
 	}
 
 	// Write header fields
-	binary.LittleEndian.PutUint64(buf[0:8], r.LSN)
-	binary.LittleEndian.PutUint64(buf[8:16], r.TxID)
-	buf[16] = byte(r.Type)
-	binary.LittleEndian.PutUint64(buf[17:25], uint64(r.PageID))
-	binary.LittleEndian.PutUint16(buf[25:27], r.Offset)
-	binary.LittleEndian.PutUint16(buf[27:29], uint16(len(r.OldData)))
-	binary.LittleEndian.PutUint16(buf[29:31], uint16(len(r.NewData)))
+	r.putHeader(buf)
 
 	// Write data
 	offset := WALRecordHeaderSize
@@ -163,27 +238,59 @@ func (r *WALRecord) SerializeTo(buf []byte) error {
 	return nil
 }
 
-// Deserialize reads the WAL record from a byte slice.
+// serializedSize returns the size of the serialized record at the start
+// of buf, as given by its header.
+func serializedSize(buf []byte) (int, error) {
+	if len(buf) < WALRecordHeaderSize {
+		return 0, ErrWALRecordTooSmall
+	}
+
+	if buf[16]&WALRecordCompressed != 0 {
+		if len(buf) < WALRecordHeaderSize+walCompressedLengthSize {
+			return 0, ErrWALRecordTooSmall
+		}
+		compressedLen := binary.LittleEndian.Uint32(buf[WALRecordHeaderSize:])
+		if compressedLen > MaxWALDataSize*2 {
+			return 0, ErrWALDataTooLarge
+		}
+		return WALRecordHeaderSize + walCompressedLengthSize + int(compressedLen), nil
+	}
+
+	oldDataLen := binary.LittleEndian.Uint16(buf[27:29])
+	newDataLen := binary.LittleEndian.Uint16(buf[29:31])
+	return WALRecordHeaderSize + int(oldDataLen) + int(newDataLen), nil
+}
+
+// Deserialize reads the WAL record from a byte slice, decompressing its
+// data if it is compressed.
 // The slice must be at least WALRecordHeaderSize bytes.
 func (r *WALRecord) Deserialize(buf []byte) error {
-	if len(buf) < WALRecordHeaderSize {
+	// Calculate total size needed
+	totalSize, err := serializedSize(buf)
+	if err != nil {
+		return err
+	}
+	if len(buf) < totalSize {
 		return ErrWALRecordTooSmall
 	}
 
 	// Read header fields
 	r.LSN = binary.LittleEndian.Uint64(buf[0:8])
 	r.TxID = binary.LittleEndian.Uint64(buf[8:16])
-	r.Type = WALType(buf[16])
+	r.Type = WALType(buf[16] &^ WALRecordCompressed)
 	r.PageID = PageID(binary.LittleEndian.Uint64(buf[17:25]))
 	r.Offset = binary.LittleEndian.Uint16(buf[25:27])
 	oldDataLen := binary.LittleEndian.Uint16(buf[27:29])
 	newDataLen := binary.LittleEndian.Uint16(buf[29:31])
 	r.Checksum = binary.LittleEndian.Uint32(buf[31:35])
-
-	// Calculate total size needed
-	totalSize := WALRecordHeaderSize + int(oldDataLen) + int(newDataLen)
-	if len(buf) < totalSize {
-		return ErrWALRecordTooSmall
+	r.Compressed = buf[16]&WALRecordCompressed != 0
+
+	if r.Compressed {
+		data, err := decompressBlock(buf[WALRecordHeaderSize+walCompressedLengthSize:totalSize], int(oldDataLen)+int(newDataLen))
+		if err != nil {
+			return err
+		}
+		buf = append(buf[:WALRecordHeaderSize:WALRecordHeaderSize], data...)
 	}
 
 	// Read data
@@ -242,19 +349,24 @@ func (r *WALRecord) ValidateChecksum() bool {
 	return r.Checksum == expected
 }
 
-// DeserializeAndValidate reads the record and validates its checksum.
+// DeserializeAndValidate validates the checksum of the record and reads
+// it. The checksum is checked before compressed data is decompressed.
 func (r *WALRecord) DeserializeAndValidate(buf []byte) error {
-	if err := r.Deserialize(buf); err != nil {
+	size, err := serializedSize(buf)
+	if err != nil {
 		return err
 	}
+	if len(buf) < size {
+		return ErrWALRecordTooSmall
+	}
 
 	// Validate checksum
-	expected := r.calculateChecksumFromBuffer(buf[:r.Size()])
-	if r.Checksum != expected {
+	stored := binary.LittleEndian.Uint32(buf[31:35])
+	if stored != r.calculateChecksumFromBuffer(buf[:size]) {
 		return ErrWALRecordChecksum
 	}
 
-	return nil
+	return r.Deserialize(buf)
 }
 
 // IsTransactionControl returns true if this is a transaction control record.
@@ -276,6 +388,8 @@ func (r *WALRecord) Clone() *WALRecord {
 		PageID:   r.PageID,
 		Offset:   r.Offset,
 		Checksum: r.Checksum,
+
+		Compressed: r.Compressed,
 	}
 
 	if r.OldData != nil {
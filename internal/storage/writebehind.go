@@ -0,0 +1,194 @@
+// Package storage provides the core storage engine components for ObaDB.
+package storage
+
+import (
+	"sort"
+)
+
+// pendingWrite is a copy of an evicted dirty page waiting to be written
+// by the background writer.
+type pendingWrite struct {
+	id   PageID
+	data []byte
+}
+
+// SetWriteBehind enables or disables write-behind for dirty pages.
+//
+// With write-behind enabled, evicting a dirty page no longer writes it
+// synchronously. The page is copied to a queue, and a background
+// goroutine writes queued pages in page ID order so that disk writes are
+// mostly sequential. Once maxDirtyPages pages are queued, the caller that
+// evicted the last one flushes the queue synchronously, which bounds the
+// memory held by the queue. A maxDirtyPages of zero or less defaults to
+// the pool capacity.
+//
+// Queued pages are not durable until written. FlushAll, and therefore a
+// checkpoint, waits for them; after a crash they are recovered from the
+// WAL like any other unflushed page.
+//
+// Disabling write-behind stops the background goroutine and writes all
+// queued pages before returning.
+func (bp *BufferPool) SetWriteBehind(enabled bool, maxDirtyPages int) {
+	if maxDirtyPages <= 0 {
+		maxDirtyPages = bp.capacity
+	}
+
+	bp.mu.Lock()
+	if enabled {
+		bp.maxDirtyPages = maxDirtyPages
+		if !bp.writeBehind {
+			bp.writeBehind = true
+			bp.wbWake = make(chan struct{}, 1)
+			bp.wbStop = make(chan struct{})
+			bp.wbDone = make(chan struct{})
+			go bp.writeBehindLoop(bp.wbWake, bp.wbStop, bp.wbDone)
+		}
+		bp.mu.Unlock()
+		return
+	}
+
+	stop, done := bp.wbStop, bp.wbDone
+	bp.wbWake, bp.wbStop, bp.wbDone = nil, nil, nil
+	bp.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+
+	bp.flushMu.Lock()
+	defer bp.flushMu.Unlock()
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	// Pages that fail to write stay queued for the next FlushAll
+	bp.flushPendingLocked()
+	bp.writeBehind = false
+}
+
+// WriteBehindEnabled returns true if write-behind is enabled.
+func (bp *BufferPool) WriteBehindEnabled() bool {
+	bp.mu.RLock()
+	defer bp.mu.RUnlock()
+	return bp.writeBehind
+}
+
+// writeBehindLoop writes queued pages whenever it is woken, until stopped.
+func (bp *BufferPool) writeBehindLoop(wake, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-wake:
+			// A failed write leaves pages queued for the next pass
+			bp.flushPending()
+		}
+	}
+}
+
+// queueWriteLocked queues a copy of an evicted dirty page and wakes the
+// background writer. Must be called with lock held.
+func (bp *BufferPool) queueWriteLocked(id PageID, data []byte) {
+	pageData := make([]byte, len(data))
+	copy(pageData, data)
+	bp.pending[id] = &pendingWrite{id: id, data: pageData}
+
+	if bp.wbWake != nil {
+		select {
+		case bp.wbWake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// boundPendingWrites flushes the write-behind queue synchronously if it
+// has reached maxDirtyPages. Must be called without lock held.
+func (bp *BufferPool) boundPendingWrites() error {
+	bp.mu.RLock()
+	full := bp.writeBehind && len(bp.pending) >= bp.maxDirtyPages
+	bp.mu.RUnlock()
+
+	if !full {
+		return nil
+	}
+	return bp.flushPending()
+}
+
+// flushPending writes all queued pages in page ID order. The pool lock is
+// released while writing, so readers and evictions are not blocked.
+func (bp *BufferPool) flushPending() error {
+	bp.flushMu.Lock()
+	defer bp.flushMu.Unlock()
+
+	bp.mu.RLock()
+	batch := bp.sortedPendingLocked()
+	callback := bp.flushCallback
+	bp.mu.RUnlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	var err error
+	if callback != nil {
+		for i, pw := range batch {
+			if err = callback(pw.id, pw.data); err != nil {
+				batch = batch[:i]
+				break
+			}
+		}
+	}
+
+	bp.mu.Lock()
+	bp.dequeueLocked(batch)
+	bp.mu.Unlock()
+
+	return err
+}
+
+// flushPendingLocked writes all queued pages in page ID order. Must be
+// called with flushMu and lock held.
+func (bp *BufferPool) flushPendingLocked() error {
+	return bp.writePendingLocked(bp.sortedPendingLocked())
+}
+
+// writePendingLocked writes the given queued pages. Must be called with
+// flushMu and lock held.
+func (bp *BufferPool) writePendingLocked(batch []*pendingWrite) error {
+	var err error
+	if bp.flushCallback != nil {
+		for i, pw := range batch {
+			if err = bp.flushCallback(pw.id, pw.data); err != nil {
+				batch = batch[:i]
+				break
+			}
+		}
+	}
+
+	bp.dequeueLocked(batch)
+	return err
+}
+
+// dequeueLocked removes written pages from the queue. A page that was
+// queued again while being written keeps its newer entry. Must be called
+// with lock held.
+func (bp *BufferPool) dequeueLocked(written []*pendingWrite) {
+	for _, pw := range written {
+		if bp.pending[pw.id] == pw {
+			delete(bp.pending, pw.id)
+		}
+	}
+}
+
+// sortedPendingLocked returns the queued pages ordered by page ID.
+// Must be called with lock held.
+func (bp *BufferPool) sortedPendingLocked() []*pendingWrite {
+	batch := make([]*pendingWrite, 0, len(bp.pending))
+	for _, pw := range bp.pending {
+		batch = append(batch, pw)
+	}
+	sort.Slice(batch, func(i, j int) bool { return batch[i].id < batch[j].id })
+	return batch
+}
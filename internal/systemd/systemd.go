@@ -0,0 +1,180 @@
+// Package systemd integrates the server with the systemd service manager:
+// readiness and watchdog notifications (sd_notify) and socket activation
+// (LISTEN_FDS). The protocols are implemented directly, so the server does
+// not depend on a systemd library. Outside Linux every function does
+// nothing and no socket is ever activated.
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Notification states (sd_notify(3)).
+const (
+	// Ready tells the service manager that startup is complete. Units with
+	// Type=notify are not started until it is sent.
+	Ready = "READY=1"
+	// Stopping tells the service manager that the service is shutting
+	// down.
+	Stopping = "STOPPING=1"
+	// Watchdog is the keepalive sent when WatchdogSec is configured.
+	Watchdog = "WATCHDOG=1"
+)
+
+// Status returns a notification setting the status line shown by
+// systemctl status.
+func Status(status string) string {
+	return "STATUS=" + status
+}
+
+// Notify sends states to the service manager, in one datagram. It does
+// nothing when the service manager does not listen for notifications
+// ($NOTIFY_SOCKET is unset).
+func Notify(states ...string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" || len(states) == 0 {
+		return nil
+	}
+	if err := notify(socket, strings.Join(states, "\n")); err != nil {
+		return fmt.Errorf("systemd: notify: %w", err)
+	}
+	return nil
+}
+
+// WatchdogInterval returns the watchdog timeout configured by WatchdogSec,
+// or zero when the watchdog is disabled or meant for another process.
+func WatchdogInterval() time.Duration {
+	if !supported {
+		return 0
+	}
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// RunWatchdog sends Watchdog keepalives at half the watchdog timeout until
+// ctx is done. It returns at once when the watchdog is disabled, and with
+// the error of the first keepalive that cannot be sent.
+func RunWatchdog(ctx context.Context) error {
+	interval := WatchdogInterval()
+	if interval <= 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := Notify(Watchdog); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Listeners is the set of listening sockets passed by socket activation.
+// Servers take their listener from the set instead of binding their
+// address.
+type Listeners struct {
+	sockets []activatedSocket
+}
+
+// activatedSocket is a listening socket with its FileDescriptorName.
+type activatedSocket struct {
+	name     string
+	listener net.Listener
+}
+
+// ActivatedListeners returns the listening sockets passed by systemd, or
+// an empty set when the process was not socket activated. The activation
+// variables are removed from the environment, so that processes started
+// by the server do not take the sockets for theirs.
+func ActivatedListeners() (*Listeners, error) {
+	files, names := listenFDs()
+	return newListeners(files, names)
+}
+
+// newListeners creates the set of listeners for the sockets files, named
+// by names. The files are closed.
+func newListeners(files []*os.File, names []string) (*Listeners, error) {
+	l := &Listeners{}
+	for i, f := range files {
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			for _, f := range files[i+1:] {
+				f.Close()
+			}
+			l.Close()
+			return nil, fmt.Errorf("systemd: activated socket %s: %w", f.Name(), err)
+		}
+
+		socket := activatedSocket{listener: ln}
+		if i < len(names) {
+			socket.name = names[i]
+		}
+		l.sockets = append(l.sockets, socket)
+	}
+	return l, nil
+}
+
+// Take removes from the set and returns the listener of the server named
+// name, configured to listen on address. The socket whose
+// FileDescriptorName is name is preferred; otherwise a socket listening on
+// the port of address is taken. It returns nil when no socket matches.
+func (l *Listeners) Take(name, address string) net.Listener {
+	for i, socket := range l.sockets {
+		if socket.name == name {
+			return l.take(i)
+		}
+	}
+
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil
+	}
+	for i, socket := range l.sockets {
+		if addr, ok := socket.listener.Addr().(*net.TCPAddr); ok && strconv.Itoa(addr.Port) == port {
+			return l.take(i)
+		}
+	}
+	return nil
+}
+
+// take removes the socket at index i and returns its listener.
+func (l *Listeners) take(i int) net.Listener {
+	ln := l.sockets[i].listener
+	l.sockets = append(l.sockets[:i], l.sockets[i+1:]...)
+	return ln
+}
+
+// Len returns the number of listeners not taken.
+func (l *Listeners) Len() int {
+	return len(l.sockets)
+}
+
+// Close closes the listeners not taken.
+func (l *Listeners) Close() error {
+	var first error
+	for _, socket := range l.sockets {
+		if err := socket.listener.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	l.sockets = nil
+	return first
+}
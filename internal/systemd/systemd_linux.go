@@ -0,0 +1,62 @@
+package systemd
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// supported reports whether the platform runs systemd.
+const supported = true
+
+// listenFDsStart is the first file descriptor passed by socket activation.
+const listenFDsStart = 3
+
+// notify writes state to the notification socket, a unix datagram socket.
+// Names starting with @ are in the abstract namespace.
+func notify(socket, state string) error {
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// listenFDs returns the sockets passed by socket activation, and their
+// names. The sockets are ours when LISTEN_PID is our process ID.
+func listenFDs() ([]*os.File, []string) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+
+	var names []string
+	if v := os.Getenv("LISTEN_FDNAMES"); v != "" {
+		names = strings.Split(v, ":")
+	}
+
+	files := make([]*os.File, 0, n)
+	for fd := listenFDsStart; fd < listenFDsStart+n; fd++ {
+		syscall.CloseOnExec(fd)
+		files = append(files, os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd)))
+	}
+	return files, names
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package systemd
+
+import "os"
+
+// supported reports whether the platform runs systemd.
+const supported = false
+
+// notify does nothing outside Linux.
+func notify(socket, state string) error {
+	return nil
+}
+
+// listenFDs returns no sockets outside Linux.
+func listenFDs() ([]*os.File, []string) {
+	return nil, nil
+}
@@ -0,0 +1,196 @@
+//go:build linux
+
+package systemd
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// listenNotify listens on a notification socket and sets NOTIFY_SOCKET.
+func listenNotify(t *testing.T) *net.UnixConn {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("ListenUnixgram() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+	return conn
+}
+
+// readNotify returns the next notification received on conn.
+func readNotify(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("reading notification: %v", err)
+	}
+	return string(buf[:n])
+}
+
+func TestNotify(t *testing.T) {
+	conn := listenNotify(t)
+
+	if err := Notify(Ready, Status("serving")); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if got, want := readNotify(t, conn), "READY=1\nSTATUS=serving"; got != want {
+		t.Errorf("notification = %q, want %q", got, want)
+	}
+}
+
+func TestNotifyAbstractSocket(t *testing.T) {
+	name := "@oba-test-" + strconv.Itoa(os.Getpid())
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: name, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("abstract sockets unavailable: %v", err)
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", name)
+
+	if err := Notify(Stopping); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if got := readNotify(t, conn); got != Stopping {
+		t.Errorf("notification = %q, want %q", got, Stopping)
+	}
+}
+
+func TestNotifyWithoutSocket(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if err := Notify(Ready); err != nil {
+		t.Errorf("Notify() error = %v, want nil without NOTIFY_SOCKET", err)
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+	tests := []struct {
+		name string
+		usec string
+		pid  string
+		want time.Duration
+	}{
+		{"disabled", "", "", 0},
+		{"enabled", "30000000", "", 30 * time.Second},
+		{"this process", "2000000", pid, 2 * time.Second},
+		{"other process", "2000000", "1", 0},
+		{"invalid", "soon", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("WATCHDOG_USEC", tt.usec)
+			t.Setenv("WATCHDOG_PID", tt.pid)
+			if got := WatchdogInterval(); got != tt.want {
+				t.Errorf("WatchdogInterval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunWatchdog(t *testing.T) {
+	conn := listenNotify(t)
+	t.Setenv("WATCHDOG_USEC", "20000")
+	t.Setenv("WATCHDOG_PID", "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- RunWatchdog(ctx) }()
+
+	for i := 0; i < 2; i++ {
+		if got := readNotify(t, conn); got != Watchdog {
+			t.Errorf("keepalive = %q, want %q", got, Watchdog)
+		}
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("RunWatchdog() error = %v", err)
+	}
+}
+
+// activatedFile returns the file of a new TCP listener, as passed by
+// socket activation, and its port.
+func activatedFile(t *testing.T) (*os.File, int) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer ln.Close()
+	f, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatalf("File() error = %v", err)
+	}
+	return f, ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestListenersTake(t *testing.T) {
+	ldapFile, ldapPort := activatedFile(t)
+	restFile, restPort := activatedFile(t)
+	otherFile, _ := activatedFile(t)
+
+	l, err := newListeners([]*os.File{ldapFile, restFile, otherFile}, []string{"oba.socket", "rest", "oba.socket"})
+	if err != nil {
+		t.Fatalf("newListeners() error = %v", err)
+	}
+	defer l.Close()
+
+	// By FileDescriptorName, whatever the configured port
+	rest := l.Take("rest", ":8080")
+	if rest == nil || rest.Addr().(*net.TCPAddr).Port != restPort {
+		t.Fatalf("Take(rest) = %v, want the socket named rest", rest)
+	}
+	// By port
+	ldap := l.Take("ldap", ":"+strconv.Itoa(ldapPort))
+	if ldap == nil || ldap.Addr().(*net.TCPAddr).Port != ldapPort {
+		t.Fatalf("Take(ldap) = %v, want the socket on port %d", ldap, ldapPort)
+	}
+	if ln := l.Take("ldaps", ":636"); ln != nil {
+		t.Errorf("Take(ldaps) = %v, want nil", ln.Addr())
+	}
+	if n := l.Len(); n != 1 {
+		t.Errorf("Len() = %d, want 1", n)
+	}
+
+	// Taken listeners accept connections
+	go func() {
+		if c, err := net.Dial("tcp", ldap.Addr().String()); err == nil {
+			c.Close()
+		}
+	}()
+	c, err := ldap.Accept()
+	if err != nil {
+		t.Fatalf("Accept() error = %v", err)
+	}
+	c.Close()
+	ldap.Close()
+	rest.Close()
+}
+
+func TestActivatedListenersOtherProcess(t *testing.T) {
+	t.Setenv("LISTEN_PID", "1")
+	t.Setenv("LISTEN_FDS", "1")
+	t.Setenv("LISTEN_FDNAMES", "ldap")
+
+	l, err := ActivatedListeners()
+	if err != nil {
+		t.Fatalf("ActivatedListeners() error = %v", err)
+	}
+	if l.Len() != 0 {
+		t.Errorf("Len() = %d, want no sockets of another process", l.Len())
+	}
+	for _, name := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+		if _, ok := os.LookupEnv(name); ok {
+			t.Errorf("%s still set", name)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OTLPConfig holds configuration for the OTLP exporter.
+type OTLPConfig struct {
+	Endpoint      string        // Collector URL, e.g. http://localhost:4318
+	ServiceName   string        // service.name resource attribute
+	BatchSize     int           // Spans sent in one request
+	QueueSize     int           // Spans buffered before new ones are dropped
+	FlushInterval time.Duration // Longest time a span waits in the queue
+	Timeout       time.Duration // Timeout of one export request
+}
+
+// DefaultOTLPConfig returns default exporter configuration.
+func DefaultOTLPConfig(endpoint string) OTLPConfig {
+	return OTLPConfig{
+		Endpoint:      endpoint,
+		ServiceName:   "oba",
+		BatchSize:     512,
+		QueueSize:     2048,
+		FlushInterval: 5 * time.Second,
+		Timeout:       10 * time.Second,
+	}
+}
+
+// OTLPExporter sends spans to an OpenTelemetry collector with OTLP over
+// HTTP using the JSON encoding. Spans are queued and sent in batches from
+// a background goroutine; when the queue is full new spans are dropped.
+type OTLPExporter struct {
+	config OTLPConfig
+	url    string
+	client *http.Client
+
+	queue   chan SpanData
+	flush   chan chan struct{}
+	done    chan struct{}
+	stopped sync.Once
+	wg      sync.WaitGroup
+	dropped atomic.Uint64
+	failed  atomic.Uint64
+}
+
+// NewOTLPExporter creates an exporter and starts its background goroutine.
+// A collector base URL without a path is sent to the standard /v1/traces
+// path.
+func NewOTLPExporter(config OTLPConfig) (*OTLPExporter, error) {
+	u, err := url.Parse(config.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("trace: invalid OTLP endpoint %q", config.Endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+
+	defaults := DefaultOTLPConfig(config.Endpoint)
+	if config.ServiceName == "" {
+		config.ServiceName = defaults.ServiceName
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaults.BatchSize
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaults.QueueSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = defaults.FlushInterval
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaults.Timeout
+	}
+
+	e := &OTLPExporter{
+		config: config,
+		url:    u.String(),
+		client: &http.Client{Timeout: config.Timeout},
+		queue:  make(chan SpanData, config.QueueSize),
+		flush:  make(chan chan struct{}),
+		done:   make(chan struct{}),
+	}
+	e.wg.Add(1)
+	go e.run()
+	return e, nil
+}
+
+// ExportSpan queues span for export. It never blocks.
+func (e *OTLPExporter) ExportSpan(span SpanData) {
+	select {
+	case <-e.done:
+		e.dropped.Add(1)
+		return
+	default:
+	}
+	select {
+	case e.queue <- span:
+	default:
+		e.dropped.Add(1)
+	}
+}
+
+// Flush sends the queued spans and waits until they have been sent or ctx
+// is done.
+func (e *OTLPExporter) Flush(ctx context.Context) error {
+	ack := make(chan struct{})
+	select {
+	case e.flush <- ack:
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown sends the queued spans and stops the exporter. Spans exported
+// afterwards are dropped.
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	e.stopped.Do(func() { close(e.done) })
+
+	finished := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Dropped returns the number of spans dropped because the queue was full
+// or the exporter was shut down.
+func (e *OTLPExporter) Dropped() uint64 {
+	return e.dropped.Load()
+}
+
+// Failed returns the number of spans whose export request failed.
+func (e *OTLPExporter) Failed() uint64 {
+	return e.failed.Load()
+}
+
+// run collects queued spans into batches and sends them.
+func (e *OTLPExporter) run() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, e.config.BatchSize)
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.send(batch); err != nil {
+			e.failed.Add(uint64(len(batch)))
+		}
+		batch = batch[:0]
+	}
+	drain := func() {
+		for {
+			select {
+			case span := <-e.queue:
+				batch = append(batch, span)
+				if len(batch) >= e.config.BatchSize {
+					send()
+				}
+			default:
+				send()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) >= e.config.BatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case ack := <-e.flush:
+			drain()
+			close(ack)
+		case <-e.done:
+			drain()
+			return
+		}
+	}
+}
+
+// send posts spans to the collector.
+func (e *OTLPExporter) send(spans []SpanData) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("trace: OTLP export failed: %s", resp.Status)
+	}
+	return nil
+}
+
+// OTLP/HTTP JSON request body, see
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue string `json:"stringValue"`
+	}
+)
+
+// OTLP span kinds
+const (
+	otlpKindInternal = 1
+	otlpKindServer   = 2
+)
+
+// request builds the export request of spans, grouped by scope.
+func (e *OTLPExporter) request(spans []SpanData) otlpRequest {
+	var scopes []otlpScopeSpans
+	index := make(map[string]int)
+	for _, span := range spans {
+		i, ok := index[span.Scope]
+		if !ok {
+			i = len(scopes)
+			index[span.Scope] = i
+			scopes = append(scopes, otlpScopeSpans{Scope: otlpScope{Name: span.Scope}})
+		}
+		scopes[i].Spans = append(scopes[i].Spans, otlpSpanOf(span))
+	}
+
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpAttribute{
+			{Key: "service.name", Value: otlpValue{StringValue: e.config.ServiceName}},
+		}},
+		ScopeSpans: scopes,
+	}}}
+}
+
+// otlpSpanOf converts span to its OTLP form. Spans without a local parent
+// are the server side of a request.
+func otlpSpanOf(span SpanData) otlpSpan {
+	s := otlpSpan{
+		TraceID:           span.SpanContext.TraceID.String(),
+		SpanID:            span.SpanContext.SpanID.String(),
+		Name:              span.Name,
+		Kind:              otlpKindInternal,
+		StartTimeUnixNano: strconv.FormatInt(span.StartTime.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+	}
+	if span.Parent.IsValid() {
+		s.ParentSpanID = span.Parent.SpanID.String()
+	}
+	if !span.Parent.IsValid() || span.Parent.Remote {
+		s.Kind = otlpKindServer
+	}
+	for _, attr := range span.Attributes {
+		s.Attributes = append(s.Attributes, otlpAttribute{Key: attr.Key, Value: otlpValue{StringValue: attr.Value}})
+	}
+	return s
+}
@@ -0,0 +1,133 @@
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOTLPExporter(t *testing.T) {
+	var mu sync.Mutex
+	var requests []otlpRequest
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %s %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req otlpRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+	}))
+	defer collector.Close()
+
+	cfg := DefaultOTLPConfig(collector.URL)
+	cfg.ServiceName = "oba-test"
+	exporter, err := NewOTLPExporter(cfg)
+	if err != nil {
+		t.Fatalf("NewOTLPExporter() error = %v", err)
+	}
+
+	tracer := NewProvider(exporter).Tracer("test")
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child", Int("n", 2))
+	child.End()
+	root.End()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := exporter.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if err := exporter.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	rs := requests[0].ResourceSpans[0]
+	if got := rs.Resource.Attributes[0].Value.StringValue; got != "oba-test" {
+		t.Errorf("service.name = %q, want oba-test", got)
+	}
+	spans := rs.ScopeSpans[0].Spans
+	if rs.ScopeSpans[0].Scope.Name != "test" || len(spans) != 2 {
+		t.Fatalf("unexpected scope spans %+v", rs.ScopeSpans)
+	}
+	if spans[0].Name != "child" || spans[0].ParentSpanID != spans[1].SpanID || spans[0].Kind != otlpKindInternal {
+		t.Errorf("unexpected child span %+v", spans[0])
+	}
+	if spans[1].ParentSpanID != "" || spans[1].Kind != otlpKindServer || spans[1].TraceID != spans[0].TraceID {
+		t.Errorf("unexpected root span %+v", spans[1])
+	}
+	if len(spans[0].Attributes) != 1 || spans[0].Attributes[0].Value.StringValue != "2" {
+		t.Errorf("unexpected attributes %+v", spans[0].Attributes)
+	}
+
+	exporter.ExportSpan(SpanData{Name: "late"})
+	if exporter.Dropped() != 1 {
+		t.Errorf("Dropped() = %d, want 1", exporter.Dropped())
+	}
+}
+
+func TestOTLPExporterInvalidEndpoint(t *testing.T) {
+	for _, endpoint := range []string{"", "localhost:4318", "ftp://collector"} {
+		if _, err := NewOTLPExporter(DefaultOTLPConfig(endpoint)); err == nil {
+			t.Errorf("NewOTLPExporter(%q) succeeded, want error", endpoint)
+		}
+	}
+}
+
+func TestSamplers(t *testing.T) {
+	var low, high TraceID
+	high[8] = 0xff
+
+	if !TraceIDRatio(0.5).ShouldSample(SpanContext{}, low) {
+		t.Error("expected a low trace ID to be sampled at ratio 0.5")
+	}
+	if TraceIDRatio(0.5).ShouldSample(SpanContext{}, high) {
+		t.Error("expected a high trace ID not to be sampled at ratio 0.5")
+	}
+	if TraceIDRatio(0).ShouldSample(SpanContext{}, low) || !TraceIDRatio(1).ShouldSample(SpanContext{}, high) {
+		t.Error("unexpected decision at ratio 0 or 1")
+	}
+
+	parent := SpanContext{TraceID: high, SpanID: SpanID{1}, TraceFlags: FlagsSampled}
+	if !ParentBased(TraceIDRatio(0)).ShouldSample(parent, high) {
+		t.Error("expected a sampled parent to be followed")
+	}
+	parent.TraceFlags = 0
+	if ParentBased(AlwaysSample()).ShouldSample(parent, high) {
+		t.Error("expected an unsampled parent to be followed")
+	}
+}
+
+func TestProviderSampler(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	tracer := NewProvider(exporter).WithSampler(ParentBased(TraceIDRatio(0))).Tracer("test")
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+	root.End()
+
+	if root.SpanContext().IsSampled() || !root.SpanContext().IsValid() {
+		t.Errorf("root span context = %+v, want valid and not sampled", root.SpanContext())
+	}
+	if child.SpanContext().TraceID != root.SpanContext().TraceID {
+		t.Error("expected the child to continue the trace")
+	}
+	if n := len(exporter.Spans()); n != 0 {
+		t.Errorf("exported %d spans, want 0", n)
+	}
+}
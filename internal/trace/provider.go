@@ -0,0 +1,190 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+	"time"
+)
+
+// SpanData is the record of a finished span handed to an exporter.
+type SpanData struct {
+	// Name is the span name.
+	Name string
+	// Scope is the name of the tracer that started the span.
+	Scope string
+	// SpanContext identifies the span.
+	SpanContext SpanContext
+	// Parent identifies the parent span; it is invalid for root spans.
+	Parent SpanContext
+	// Attributes are the span attributes in the order first set.
+	Attributes []Attribute
+	// StartTime and EndTime bound the span.
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// Attribute returns the value of the attribute key and whether it is set.
+func (d SpanData) Attribute(key string) (string, bool) {
+	for _, attr := range d.Attributes {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// SpanExporter receives finished spans.
+type SpanExporter interface {
+	// ExportSpan exports a finished span. It must not block for long, as
+	// it is called when the span ends.
+	ExportSpan(span SpanData)
+}
+
+// Provider is a TracerProvider that records sampled spans and passes them
+// to an exporter when they end. By default a span is sampled if its parent
+// is, and new traces are always sampled.
+type Provider struct {
+	exporter SpanExporter
+	sampler  Sampler
+}
+
+// NewProvider creates a Provider that exports to exporter.
+func NewProvider(exporter SpanExporter) *Provider {
+	return &Provider{exporter: exporter, sampler: ParentBased(AlwaysSample())}
+}
+
+// WithSampler sets the sampler that decides which new spans are recorded.
+func (p *Provider) WithSampler(sampler Sampler) *Provider {
+	p.sampler = sampler
+	return p
+}
+
+// Tracer returns the tracer for an instrumentation scope.
+func (p *Provider) Tracer(name string) Tracer {
+	return &tracer{provider: p, scope: name}
+}
+
+type tracer struct {
+	provider *Provider
+	scope    string
+}
+
+// Start starts a span as a child of the parent in ctx.
+func (t *tracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	parent := parentFromContext(ctx)
+	var sc SpanContext
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID
+	} else {
+		rand.Read(sc.TraceID[:])
+	}
+	rand.Read(sc.SpanID[:])
+	if t.provider.sampler.ShouldSample(parent, sc.TraceID) {
+		sc.TraceFlags = FlagsSampled
+	}
+
+	if !sc.IsSampled() {
+		span := noopSpan{sc: sc}
+		return ContextWithSpan(ctx, span), span
+	}
+
+	span := &recordingSpan{
+		exporter: t.provider.exporter,
+		data: SpanData{
+			Name:        name,
+			Scope:       t.scope,
+			SpanContext: sc,
+			Parent:      parent,
+			StartTime:   time.Now(),
+		},
+	}
+	span.SetAttributes(attrs...)
+	return ContextWithSpan(ctx, span), span
+}
+
+// recordingSpan is a sampled span. It is safe for concurrent use.
+type recordingSpan struct {
+	exporter SpanExporter
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+func (s *recordingSpan) SpanContext() SpanContext {
+	return s.data.SpanContext
+}
+
+func (s *recordingSpan) SetAttributes(attrs ...Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+
+next:
+	for _, attr := range attrs {
+		for i := range s.data.Attributes {
+			if s.data.Attributes[i].Key == attr.Key {
+				s.data.Attributes[i].Value = attr.Value
+				continue next
+			}
+		}
+		s.data.Attributes = append(s.data.Attributes, attr)
+	}
+}
+
+func (s *recordingSpan) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.EndTime = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	if s.exporter != nil {
+		s.exporter.ExportSpan(data)
+	}
+}
+
+// InMemoryExporter keeps finished spans in memory. It is meant for tests.
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// NewInMemoryExporter creates an empty InMemoryExporter.
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+// ExportSpan stores span.
+func (e *InMemoryExporter) ExportSpan(span SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+// Spans returns the stored spans in the order they ended.
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	spans := make([]SpanData, len(e.spans))
+	copy(spans, e.spans)
+	return spans
+}
+
+// Reset discards the stored spans.
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
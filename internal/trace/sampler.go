@@ -0,0 +1,58 @@
+package trace
+
+import "encoding/binary"
+
+// Sampler decides whether a new span is sampled.
+type Sampler interface {
+	// ShouldSample reports whether a span with traceID and parent is
+	// sampled. The parent is invalid for root spans.
+	ShouldSample(parent SpanContext, traceID TraceID) bool
+}
+
+// AlwaysSample returns a sampler that samples every span.
+func AlwaysSample() Sampler {
+	return ratioSampler{bound: ^uint64(0), always: true}
+}
+
+// TraceIDRatio returns a sampler that samples the given fraction of traces.
+// The decision depends only on the trace ID, so all processes sampling
+// with the same ratio agree. Ratios of 1 or more sample every trace, and
+// ratios of 0 or less none.
+func TraceIDRatio(ratio float64) Sampler {
+	switch {
+	case ratio >= 1:
+		return AlwaysSample()
+	case ratio <= 0:
+		return ratioSampler{}
+	}
+	return ratioSampler{bound: uint64(ratio*(1<<63)) << 1}
+}
+
+type ratioSampler struct {
+	bound  uint64
+	always bool
+}
+
+func (s ratioSampler) ShouldSample(_ SpanContext, traceID TraceID) bool {
+	if s.always {
+		return true
+	}
+	return binary.BigEndian.Uint64(traceID[8:]) < s.bound
+}
+
+// ParentBased returns a sampler that follows the sampling decision of the
+// parent span and uses root for spans without a parent.
+func ParentBased(root Sampler) Sampler {
+	return parentSampler{root: root}
+}
+
+type parentSampler struct {
+	root Sampler
+}
+
+func (s parentSampler) ShouldSample(parent SpanContext, traceID TraceID) bool {
+	if parent.IsValid() {
+		return parent.IsSampled()
+	}
+	return s.root.ShouldSample(parent, traceID)
+}
@@ -0,0 +1,239 @@
+// Package trace provides distributed tracing with W3C trace context
+// propagation. Its API follows the shape of the OpenTelemetry trace API
+// (TracerProvider, Tracer, Span, SpanContext) but uses only the standard
+// library, so the server does not depend on an OpenTelemetry module.
+package trace
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidTraceparent is returned when a traceparent value is malformed.
+var ErrInvalidTraceparent = errors.New("trace: invalid traceparent")
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+// IsValid reports whether the trace ID is not all zero.
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+// String returns the trace ID as lowercase hex.
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+// IsValid reports whether the span ID is not all zero.
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+// String returns the span ID as lowercase hex.
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// TraceFlags are the trace flags of a span context.
+type TraceFlags byte
+
+// FlagsSampled is set when the trace is sampled.
+const FlagsSampled TraceFlags = 0x01
+
+// IsSampled reports whether the sampled flag is set.
+func (f TraceFlags) IsSampled() bool {
+	return f&FlagsSampled != 0
+}
+
+// SpanContext identifies a span and carries the state propagated to
+// other processes.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	TraceFlags TraceFlags
+	// Remote is set for span contexts received from another process.
+	Remote bool
+}
+
+// IsValid reports whether both the trace and span IDs are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// IsSampled reports whether the span is sampled.
+func (sc SpanContext) IsSampled() bool {
+	return sc.TraceFlags.IsSampled()
+}
+
+// Traceparent returns the span context as a W3C traceparent value.
+func (sc SpanContext) Traceparent() string {
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + hex.EncodeToString([]byte{byte(sc.TraceFlags)})
+}
+
+// ParseTraceparent parses a W3C traceparent value:
+//
+//	version "-" trace-id "-" parent-id "-" trace-flags
+//
+// Versions other than 00 are accepted as long as the first four fields
+// are well formed, as the specification requires. The returned span
+// context is marked remote.
+func ParseTraceparent(s string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	if parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	if _, err := hex.DecodeString(parts[0]); err != nil {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+
+	var sc SpanContext
+	if !decodeLowerHex(sc.TraceID[:], parts[1]) || !decodeLowerHex(sc.SpanID[:], parts[2]) {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	var flags [1]byte
+	if !decodeLowerHex(flags[:], parts[3]) {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	sc.TraceFlags = TraceFlags(flags[0])
+	sc.Remote = true
+
+	if !sc.IsValid() {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	return sc, nil
+}
+
+// decodeLowerHex decodes lowercase hex s into dst.
+func decodeLowerHex(dst []byte, s string) bool {
+	if strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+// Attribute is a key-value pair describing a span.
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// String returns a string attribute.
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Bool returns a boolean attribute.
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: strconv.FormatBool(value)}
+}
+
+// Int returns an integer attribute.
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: strconv.Itoa(value)}
+}
+
+// TracerProvider provides tracers.
+type TracerProvider interface {
+	// Tracer returns the tracer for an instrumentation scope.
+	Tracer(name string) Tracer
+}
+
+// Tracer starts spans.
+type Tracer interface {
+	// Start starts a span as a child of the span in ctx, or of the remote
+	// span context in ctx if there is no span. It returns a context that
+	// holds the new span.
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is a single traced operation.
+type Span interface {
+	// SpanContext returns the identity of the span.
+	SpanContext() SpanContext
+	// SetAttributes adds attributes to the span, replacing values of
+	// existing keys.
+	SetAttributes(attrs ...Attribute)
+	// End finishes the span. Calls after the first have no effect.
+	End()
+}
+
+type spanKey struct{}
+
+type remoteKey struct{}
+
+// ContextWithSpan returns a copy of ctx that holds span.
+func ContextWithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the span in ctx, or a no-op span.
+func SpanFromContext(ctx context.Context) Span {
+	if ctx != nil {
+		if span, ok := ctx.Value(spanKey{}).(Span); ok {
+			return span
+		}
+	}
+	return noopSpan{}
+}
+
+// ContextWithRemoteSpanContext returns a copy of ctx that holds a span
+// context received from another process. Spans started from the returned
+// context become its children.
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	sc.Remote = true
+	ctx = context.WithValue(ctx, spanKey{}, Span(nil))
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// parentFromContext returns the span context new spans started from ctx
+// are children of. It is invalid if ctx holds neither a span nor a remote
+// span context.
+func parentFromContext(ctx context.Context) SpanContext {
+	if ctx == nil {
+		return SpanContext{}
+	}
+	if span, ok := ctx.Value(spanKey{}).(Span); ok && span != nil {
+		return span.SpanContext()
+	}
+	if sc, ok := ctx.Value(remoteKey{}).(SpanContext); ok {
+		return sc
+	}
+	return SpanContext{}
+}
+
+// NewNoopTracerProvider returns a provider whose spans record nothing.
+func NewNoopTracerProvider() TracerProvider {
+	return noopProvider{}
+}
+
+type noopProvider struct{}
+
+func (noopProvider) Tracer(string) Tracer { return noopTracer{} }
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, _ string, _ ...Attribute) (context.Context, Span) {
+	span := noopSpan{sc: parentFromContext(ctx)}
+	return ContextWithSpan(ctx, span), span
+}
+
+// noopSpan carries its parent's span context so that propagation still
+// works when tracing is disabled.
+type noopSpan struct {
+	sc SpanContext
+}
+
+func (s noopSpan) SpanContext() SpanContext { return s.sc }
+func (noopSpan) SetAttributes(...Attribute) {}
+func (noopSpan) End()                       {}
@@ -0,0 +1,134 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		in      string
+		wantErr bool
+	}{
+		{in: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{in: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"},
+		{in: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future"},
+		{in: "", wantErr: true},
+		{in: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", wantErr: true},
+		{in: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", wantErr: true},
+		{in: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", wantErr: true},
+		{in: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", wantErr: true},
+		{in: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", wantErr: true},
+		{in: "00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01", wantErr: true},
+		{in: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902bz-01", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		sc, err := ParseTraceparent(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidTraceparent) {
+				t.Errorf("ParseTraceparent(%q) error = %v, want ErrInvalidTraceparent", tt.in, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseTraceparent(%q) error = %v", tt.in, err)
+			continue
+		}
+		if !sc.IsValid() || !sc.Remote {
+			t.Errorf("ParseTraceparent(%q) = %+v, want a valid remote span context", tt.in, sc)
+		}
+		if got := sc.Traceparent(); got[3:] != tt.in[3:55] {
+			t.Errorf("Traceparent() = %q, want fields of %q", got, tt.in)
+		}
+	}
+}
+
+func TestProviderSpanHierarchy(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	tracer := NewProvider(exporter).Tracer("test")
+
+	ctx, root := tracer.Start(context.Background(), "root", String("a", "1"))
+	_, child := tracer.Start(ctx, "child", Int("n", 2))
+	child.SetAttributes(Int("n", 3), String("b", "x"))
+	child.End()
+	child.End()
+	root.End()
+
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("Got %d spans, want 2", len(spans))
+	}
+	if spans[0].Name != "child" || spans[1].Name != "root" {
+		t.Fatalf("Unexpected span order %q, %q", spans[0].Name, spans[1].Name)
+	}
+	if spans[1].Parent.IsValid() {
+		t.Error("Root span should have no parent")
+	}
+	if spans[0].Parent != spans[1].SpanContext {
+		t.Error("Child span parent should be the root span")
+	}
+	if spans[0].SpanContext.TraceID != spans[1].SpanContext.TraceID {
+		t.Error("Child span should share the trace ID")
+	}
+	if n, _ := spans[0].Attribute("n"); n != "3" {
+		t.Errorf("Attribute n = %q, want 3", n)
+	}
+	if len(spans[0].Attributes) != 2 {
+		t.Errorf("Attributes = %v, want n and b", spans[0].Attributes)
+	}
+	if spans[0].EndTime.Before(spans[0].StartTime) {
+		t.Error("EndTime before StartTime")
+	}
+
+	exporter.Reset()
+	if len(exporter.Spans()) != 0 {
+		t.Error("Reset should discard spans")
+	}
+}
+
+func TestProviderRemoteParent(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	tracer := NewProvider(exporter).Tracer("test")
+
+	sampled, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx, outer := tracer.Start(context.Background(), "outer")
+	_, span := tracer.Start(ContextWithRemoteSpanContext(ctx, sampled), "server")
+	span.End()
+	outer.End()
+
+	spans := exporter.Spans()
+	if len(spans) != 2 || spans[0].Parent != sampled {
+		t.Fatalf("Span parent = %+v, want the remote span context", spans[0].Parent)
+	}
+
+	// Spans of traces the caller did not sample are not recorded, but
+	// still carry the trace ID
+	exporter.Reset()
+	unsampled, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	_, span = tracer.Start(ContextWithRemoteSpanContext(context.Background(), unsampled), "server")
+	span.End()
+	if len(exporter.Spans()) != 0 {
+		t.Error("Unsampled span was exported")
+	}
+	if span.SpanContext().TraceID != unsampled.TraceID {
+		t.Error("Unsampled span should keep the trace ID")
+	}
+}
+
+func TestNoopTracerProvider(t *testing.T) {
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := ContextWithRemoteSpanContext(context.Background(), remote)
+
+	_, span := NewNoopTracerProvider().Tracer("test").Start(ctx, "op")
+	span.SetAttributes(String("a", "b"))
+	span.End()
+	if span.SpanContext() != remote {
+		t.Error("No-op span should carry the parent span context")
+	}
+
+	if SpanFromContext(context.Background()).SpanContext().IsValid() {
+		t.Error("SpanFromContext without a span should return an invalid span context")
+	}
+}
@@ -3,6 +3,7 @@
 package tests
 
 import (
+	"context"
 	"net"
 	"testing"
 	"time"
@@ -79,7 +80,7 @@ func setupCompatTestData(t *testing.T, srv *TestServer) {
 	alice.SetAttribute("mail", "alice@test.com")
 	alice.SetAttribute("description", "Test user Alice")
 	alice.SetAttribute("telephoneNumber", "+1-555-0101")
-	if err := be.Add(alice); err != nil {
+	if err := be.Add(context.Background(), alice); err != nil {
 		t.Fatalf("failed to add alice: %v", err)
 	}
 
@@ -90,7 +91,7 @@ func setupCompatTestData(t *testing.T, srv *TestServer) {
 	bob.SetAttribute("sn", "Jones")
 	bob.SetAttribute("mail", "bob@test.com")
 	bob.SetAttribute("description", "Test user Bob")
-	if err := be.Add(bob); err != nil {
+	if err := be.Add(context.Background(), bob); err != nil {
 		t.Fatalf("failed to add bob: %v", err)
 	}
 
@@ -101,7 +102,7 @@ func setupCompatTestData(t *testing.T, srv *TestServer) {
 	charlie.SetAttribute("sn", "Brown")
 	charlie.SetAttribute("mail", "charlie@test.com")
 	charlie.SetAttribute("description", "Admin user Charlie")
-	if err := be.Add(charlie); err != nil {
+	if err := be.Add(context.Background(), charlie); err != nil {
 		t.Fatalf("failed to add charlie: %v", err)
 	}
 
@@ -110,7 +111,7 @@ func setupCompatTestData(t *testing.T, srv *TestServer) {
 	admins.SetAttribute("objectclass", "groupOfNames", "top")
 	admins.SetAttribute("cn", "admins")
 	admins.SetAttribute("member", "uid=alice,ou=users,dc=test,dc=com", "uid=charlie,ou=users,dc=test,dc=com")
-	if err := be.Add(admins); err != nil {
+	if err := be.Add(context.Background(), admins); err != nil {
 		t.Fatalf("failed to add admins group: %v", err)
 	}
 }
@@ -756,7 +757,7 @@ func testDNHandling(t *testing.T, srv *TestServer) {
 		spaceEntry.SetAttribute("objectclass", "inetOrgPerson", "person", "top")
 		spaceEntry.SetAttribute("cn", "Test User")
 		spaceEntry.SetAttribute("sn", "User")
-		if err := be.Add(spaceEntry); err != nil {
+		if err := be.Add(context.Background(), spaceEntry); err != nil {
 			t.Fatalf("failed to add entry with spaces: %v", err)
 		}
 
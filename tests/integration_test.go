@@ -3,6 +3,7 @@
 package tests
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -340,7 +341,7 @@ func createAddHandler(be *backend.ObaBackend) server.AddHandler {
 func createDeleteHandler(be *backend.ObaBackend) server.DeleteHandler {
 	return func(conn *server.Connection, req *ldap.DeleteRequest) *server.OperationResult {
 		// Check for children
-		hasChildren, err := be.HasChildren(req.DN)
+		hasChildren, err := be.HasChildren(context.Background(), req.DN)
 		if err != nil {
 			return &server.OperationResult{
 				ResultCode:        ldap.ResultOperationsError,
@@ -355,7 +356,7 @@ func createDeleteHandler(be *backend.ObaBackend) server.DeleteHandler {
 		}
 
 		// Delete entry
-		err = be.Delete(req.DN)
+		err = be.Delete(context.Background(), req.DN)
 		if err != nil {
 			resultCode := ldap.ResultOperationsError
 			if err == backend.ErrEntryNotFound {
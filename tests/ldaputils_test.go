@@ -4,6 +4,7 @@ package tests
 
 import (
 	"bytes"
+	"context"
 	"os/exec"
 	"strings"
 	"testing"
@@ -74,7 +75,7 @@ func setupLdapUtilsTestData(t *testing.T, srv *TestServer) {
 	alice.SetAttribute("cn", "Alice Smith")
 	alice.SetAttribute("sn", "Smith")
 	alice.SetAttribute("mail", "alice@test.com")
-	if err := be.Add(alice); err != nil {
+	if err := be.Add(context.Background(), alice); err != nil {
 		t.Fatalf("failed to add alice: %v", err)
 	}
 
@@ -84,7 +85,7 @@ func setupLdapUtilsTestData(t *testing.T, srv *TestServer) {
 	bob.SetAttribute("cn", "Bob Jones")
 	bob.SetAttribute("sn", "Jones")
 	bob.SetAttribute("mail", "bob@test.com")
-	if err := be.Add(bob); err != nil {
+	if err := be.Add(context.Background(), bob); err != nil {
 		t.Fatalf("failed to add bob: %v", err)
 	}
 
@@ -93,7 +94,7 @@ func setupLdapUtilsTestData(t *testing.T, srv *TestServer) {
 	admins.SetAttribute("objectclass", "groupOfNames", "top")
 	admins.SetAttribute("cn", "admins")
 	admins.SetAttribute("member", "uid=alice,ou=users,dc=test,dc=com")
-	if err := be.Add(admins); err != nil {
+	if err := be.Add(context.Background(), admins); err != nil {
 		t.Fatalf("failed to add admins group: %v", err)
 	}
 }
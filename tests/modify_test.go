@@ -2,6 +2,7 @@
 package tests
 
 import (
+	"context"
 	"net"
 	"testing"
 	"time"
@@ -155,7 +156,7 @@ func testModifyEntryAddAttribute(t *testing.T, srv *TestServer) {
 	entry.SetAttribute("uid", "modifytest")
 	entry.SetAttribute("cn", "Modify Test")
 	entry.SetAttribute("sn", "Test")
-	if err := be.Add(entry); err != nil {
+	if err := be.Add(context.Background(), entry); err != nil {
 		t.Fatalf("failed to add test entry: %v", err)
 	}
 
@@ -306,7 +307,7 @@ func testDeleteEntry(t *testing.T, srv *TestServer) {
 	entry.SetAttribute("uid", "deletetest")
 	entry.SetAttribute("cn", "Delete Test")
 	entry.SetAttribute("sn", "Test")
-	if err := be.Add(entry); err != nil {
+	if err := be.Add(context.Background(), entry); err != nil {
 		t.Fatalf("failed to add test entry: %v", err)
 	}
 
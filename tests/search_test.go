@@ -2,6 +2,7 @@
 package tests
 
 import (
+	"context"
 	"net"
 	"testing"
 	"time"
@@ -73,7 +74,7 @@ func setupSearchTestData(t *testing.T, srv *TestServer) {
 	alice.SetAttribute("cn", "Alice Smith")
 	alice.SetAttribute("sn", "Smith")
 	alice.SetAttribute("mail", "alice@test.com")
-	if err := be.Add(alice); err != nil {
+	if err := be.Add(context.Background(), alice); err != nil {
 		t.Fatalf("failed to add alice: %v", err)
 	}
 
@@ -83,7 +84,7 @@ func setupSearchTestData(t *testing.T, srv *TestServer) {
 	bob.SetAttribute("cn", "Bob Jones")
 	bob.SetAttribute("sn", "Jones")
 	bob.SetAttribute("mail", "bob@test.com")
-	if err := be.Add(bob); err != nil {
+	if err := be.Add(context.Background(), bob); err != nil {
 		t.Fatalf("failed to add bob: %v", err)
 	}
 }